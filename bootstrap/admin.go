@@ -0,0 +1,361 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/crontab"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+// newUserCommand 用户管理子命令
+func newUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users.",
+	}
+
+	var (
+		email, password, nick string
+		groupID               uint
+	)
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create an active user, a random password is generated if not specified.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createUser(email, password, nick, groupID)
+		},
+	}
+	create.Flags().StringVar(&email, "email", "", "Email of the user.")
+	create.Flags().StringVar(&password, "password", "", "Password of the user, random if empty.")
+	create.Flags().StringVar(&nick, "nick", "", "Nickname of the user, defaults to the name part of email.")
+	create.Flags().UintVar(&groupID, "group", 0, "ID of the user group, defaults to the default group of registration.")
+	_ = create.MarkFlagRequired("email")
+
+	var uid uint
+	reset := &cobra.Command{
+		Use:   "reset-password",
+		Short: "Reset password of a user found by ID or email, a random password is generated if not specified.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return resetPassword(uid, email, password)
+		},
+	}
+	reset.Flags().UintVar(&uid, "id", 0, "ID of the user.")
+	reset.Flags().StringVar(&email, "email", "", "Email of the user.")
+	reset.Flags().StringVar(&password, "password", "", "New password, random if empty.")
+
+	cmd.AddCommand(create, reset)
+	return cmd
+}
+
+func createUser(email, password, nick string, groupID uint) error {
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return fmt.Errorf("invalid email %q", email)
+	}
+
+	if _, err := model.GetUserByEmail(email); err == nil {
+		return fmt.Errorf("user with email %q already exists", email)
+	}
+
+	if groupID == 0 {
+		groupID = uint(model.GetIntSetting("default_group", 2))
+	}
+
+	if _, err := model.GetGroupByID(groupID); err != nil {
+		return fmt.Errorf("user group %d not found: %w", groupID, err)
+	}
+
+	if nick == "" {
+		nick = strings.Split(email, "@")[0]
+	}
+
+	generated := password == ""
+	if generated {
+		password = util.RandStringRunes(12)
+	}
+
+	user := model.NewUser()
+	user.Email = email
+	user.Nick = nick
+	user.GroupID = groupID
+	user.Status = model.Active
+	if err := user.SetPassword(password); err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := model.DB.Create(&user).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	util.Log().Info("User %q created with ID %d.", email, user.ID)
+	if generated {
+		printPassword(password)
+	}
+
+	return nil
+}
+
+func resetPassword(uid uint, email, password string) error {
+	var (
+		user model.User
+		err  error
+	)
+
+	switch {
+	case uid > 0:
+		user, err = model.GetUserByID(uid)
+	case email != "":
+		user, err = model.GetUserByEmail(email)
+	default:
+		return errors.New("either --id or --email is required")
+	}
+
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	generated := password == ""
+	if generated {
+		password = util.RandStringRunes(12)
+	}
+
+	if err := user.SetPassword(password); err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+
+	if err := user.Update(map[string]interface{}{"password": user.Password}); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	util.Log().Info("Password of user %q reset.", user.Email)
+	if generated {
+		printPassword(password)
+	}
+
+	return nil
+}
+
+func printPassword(password string) {
+	c := color.New(color.FgWhite).Add(color.BgBlack).Add(color.Bold)
+	util.Log().Info("Password: " + c.Sprint(password))
+}
+
+// newPolicyCommand 存储策略管理子命令
+func newPolicyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Manage storage policies.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List all storage policies.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policies, err := model.GetPolicies()
+			if err != nil {
+				return fmt.Errorf("failed to list storage policies: %w", err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tTYPE\tSERVER\tBUCKET\tMAX SIZE")
+			for _, policy := range policies {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%d\n",
+					policy.ID, policy.Name, policy.Type, policy.Server, policy.BucketName, policy.MaxSize)
+			}
+			return w.Flush()
+		},
+	})
+
+	return cmd
+}
+
+// newTaskCommand 任务管理子命令
+func newTaskCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "task",
+		Short: "Manage background tasks.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "retry <id>...",
+		Short: "Retry failed tasks and wait for them to finish.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			failed := 0
+			for _, arg := range args {
+				id, err := strconv.ParseUint(arg, 10, 32)
+				if err != nil {
+					return fmt.Errorf("invalid task ID %q", arg)
+				}
+
+				if err := retryTask(uint(id)); err != nil {
+					util.Log().Error("Failed to retry task %d: %s", id, err)
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d task(s) failed", failed, len(args))
+			}
+
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+// retryTask 重置失败的任务并同步执行
+func retryTask(id uint) error {
+	record, err := model.GetTasksByID(id)
+	if err != nil {
+		return fmt.Errorf("task not found: %w", err)
+	}
+
+	if record.Status != task.Error {
+		return errors.New("only failed tasks can be retried")
+	}
+
+	if err := model.DB.Model(record).UpdateColumns(map[string]interface{}{
+		"status":   task.Queued,
+		"progress": task.PendingProgress,
+		"error":    "",
+		"retries":  0,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to reset task: %w", err)
+	}
+
+	job, err := task.GetJobFromModel(record)
+	if err != nil {
+		return err
+	}
+
+	if job == nil {
+		return errors.New("task type does not support retrying")
+	}
+
+	util.Log().Info("Retrying task %d...", id)
+	(&task.GeneralWorker{}).Do(job)
+	if jobErr := job.GetError(); jobErr != nil {
+		return fmt.Errorf("%s %s", jobErr.Msg, jobErr.Error)
+	}
+
+	util.Log().Info("Task %d complete.", id)
+	return nil
+}
+
+// newCleanupCommand 清理子命令
+func newCleanupCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Clean up unused data.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "orphans",
+		Short: "Delete records whose file or folder no longer exists, and expired share access logs.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := crontab.CleanupOrphans(); err != nil {
+				return err
+			}
+
+			util.Log().Info("Orphan records cleaned up.")
+			return nil
+		},
+	})
+
+	var (
+		policyID              uint
+		dryRun, removeMissing bool
+		grace                 time.Duration
+	)
+	blobs := &cobra.Command{
+		Use:   "blobs",
+		Short: "Delete physical files without file records, and report records whose physical files are missing.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return collectOrphanBlobs(policyID, filesystem.BlobGCOptions{
+				DryRun:        dryRun,
+				RemoveMissing: removeMissing,
+				GracePeriod:   grace,
+			})
+		},
+	}
+	blobs.Flags().UintVar(&policyID, "policy", 0, "ID of the storage policy to scan, all policies if not specified.")
+	blobs.Flags().BoolVar(&dryRun, "dry-run", false, "Only print the report without deleting anything.")
+	blobs.Flags().BoolVar(&removeMissing, "remove-missing", false, "Also delete file records whose physical files are missing.")
+	blobs.Flags().DurationVar(&grace, "grace", 24*time.Hour, "Ignore physical files and records modified within this period.")
+	cmd.AddCommand(blobs)
+
+	return cmd
+}
+
+// collectOrphanBlobs 扫描存储策略中的孤立物理文件并输出报告
+func collectOrphanBlobs(policyID uint, opts filesystem.BlobGCOptions) error {
+	var policies []model.Policy
+	if policyID > 0 {
+		policy, err := model.GetPolicyByID(policyID)
+		if err != nil {
+			return fmt.Errorf("storage policy not found: %w", err)
+		}
+		policies = append(policies, policy)
+	} else {
+		var err error
+		if policies, err = model.GetPolicies(); err != nil {
+			return fmt.Errorf("failed to list storage policies: %w", err)
+		}
+	}
+
+	fs, err := filesystem.NewAnonymousFileSystem()
+	if err != nil {
+		return fmt.Errorf("failed to initialize filesystem: %w", err)
+	}
+	defer fs.Recycle()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROOT\tSCANNED\tORPHANS\tORPHAN SIZE\tMISSING\tDELETED\tREMOVED\tFAILED\tERROR")
+	failed := 0
+	var samples []string
+	for i := range policies {
+		report, err := fs.CollectOrphanBlobs(context.Background(), &policies[i], opts)
+		if err != nil {
+			report.Error = err.Error()
+			failed++
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%s\n", report.PolicyID, report.PolicyName, report.Root,
+			report.Scanned, report.Orphans, report.OrphanSize, report.Missing, report.Deleted, report.Removed, report.Failed, report.Error)
+		for _, source := range report.OrphanSamples {
+			samples = append(samples, fmt.Sprintf("[%d] orphan: %s", report.PolicyID, source))
+		}
+		for _, id := range report.MissingSamples {
+			samples = append(samples, fmt.Sprintf("[%d] missing: file #%d", report.PolicyID, id))
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if opts.DryRun && len(samples) > 0 {
+		fmt.Println()
+		fmt.Println(strings.Join(samples, "\n"))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d storage policies failed to scan", failed, len(policies))
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package bootstrap
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// runCommand 以给定参数执行命令行子命令
+func runCommand(args ...string) error {
+	root := newRootCommand()
+	root.SetArgs(args)
+	return root.Execute()
+}
+
+func TestResetPassword(t *testing.T) {
+	a := assert.New(t)
+
+	// 未指定用户
+	a.Error(runCommand("user", "reset-password", "--password", "123456"))
+
+	// 用户不存在
+	a.Error(runCommand("user", "reset-password", "--id", "233", "--password", "123456"))
+
+	// 按 ID 重设
+	{
+		a.NoError(runCommand("user", "reset-password", "--id", "1", "--password", "reset-by-id"))
+		user, err := model.GetUserByID(1)
+		a.NoError(err)
+		ok, _ := user.CheckPassword("reset-by-id")
+		a.True(ok)
+	}
+
+	// 按邮箱重设
+	{
+		admin, _ := model.GetUserByID(1)
+		a.NoError(runCommand("user", "reset-password", "--email", admin.Email, "--password", "reset-by-email"))
+		user, err := model.GetUserByID(1)
+		a.NoError(err)
+		ok, _ := user.CheckPassword("reset-by-email")
+		a.True(ok)
+	}
+}
+
+func TestCleanupOrphans(t *testing.T) {
+	a := assert.New(t)
+
+	file := &model.File{Name: "orphan.jpg", UserID: 1, SourceName: "orphan.jpg", PolicyID: 1}
+	a.NoError(model.DB.Create(file).Error)
+	a.NoError(model.DB.Create(&model.Photo{FileID: file.ID, UserID: 1}).Error)
+	a.NoError(model.DB.Create(&model.Photo{FileID: file.ID + 1000, UserID: 1}).Error)
+
+	a.NoError(runCommand("cleanup", "orphans"))
+
+	var count int
+	model.DB.Model(&model.Photo{}).Where("file_id = ?", file.ID).Count(&count)
+	a.Equal(1, count)
+	model.DB.Model(&model.Photo{}).Where("file_id = ?", file.ID+1000).Count(&count)
+	a.Equal(0, count)
+}
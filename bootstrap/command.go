@@ -0,0 +1,132 @@
+package bootstrap
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/models/scripts"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+// InitCommand 初始化命令行子命令，与独立 Worker 相同只加载执行任务所需的组件，
+// 不启动定时任务，也不恢复任务队列，以免与运行中的服务重复执行
+func InitCommand(path string) {
+	InitApplication()
+	conf.Init(path)
+	if conf.SystemConfig.Mode != "master" {
+		util.Log().Panic("Commands can only run with master config.")
+	}
+
+	scripts.Init()
+	initTaskComponents()
+}
+
+// RunCommand 运行命令行子命令
+func RunCommand(args []string) {
+	root := newRootCommand()
+	root.SetArgs(args)
+	if err := root.Execute(); err != nil {
+		util.Log().Error("%s", err)
+		os.Exit(1)
+	}
+}
+
+// IsCommand 返回 name 是否为命令行子命令，其他位置参数不会进入命令行模式
+func IsCommand(name string) bool {
+	if name == "help" {
+		return true
+	}
+
+	for _, cmd := range newRootCommand().Commands() {
+		if cmd.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newRootCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "cloudreve",
+		Short:         "Manage Cloudreve directly against the configured database.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	root.AddCommand(
+		// 沿用标准库 flag 解析参数的子命令
+		&cobra.Command{
+			Use:                "regenerate-thumbs",
+			Short:              "Regenerate thumbnails by storage policy, user or file extension.",
+			DisableFlagParsing: true,
+			Run: func(cmd *cobra.Command, args []string) {
+				regenerateThumbs(args)
+			},
+		},
+		&cobra.Command{
+			Use:                "migrate [status|up|down]",
+			Short:              "List, apply or roll back database migrations.",
+			DisableFlagParsing: true,
+			Run: func(cmd *cobra.Command, args []string) {
+				runMigrate(args)
+			},
+		},
+		newUserCommand(),
+		newPolicyCommand(),
+		newTaskCommand(),
+		newCleanupCommand(),
+	)
+
+	return root
+}
+
+// regenerateThumbs 按存储策略、用户或扩展名重新生成缩略图，以初始管理员的身份创建任务并同步执行
+func regenerateThumbs(args []string) {
+	var (
+		policyID, uid uint
+		exts          string
+		rate          int
+	)
+
+	flags := flag.NewFlagSet("regenerate-thumbs", flag.ExitOnError)
+	flags.UintVar(&policyID, "policy", 0, "ID of the storage policy, 0 for all policies.")
+	flags.UintVar(&uid, "user", 0, "ID of the user, 0 for all users.")
+	flags.StringVar(&exts, "ext", "", "Comma-separated file extensions, empty for all files.")
+	flags.IntVar(&rate, "rate", 0, "Maximum number of files processed per second, 0 for unlimited.")
+	_ = flags.Parse(args)
+
+	extensions := make([]string, 0)
+	for _, ext := range strings.Split(exts, ",") {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); ext != "" {
+			extensions = append(extensions, ext)
+		}
+	}
+
+	admin, err := model.GetUserByID(1)
+	if err != nil {
+		util.Log().Error("Failed to get admin user: %s", err)
+		return
+	}
+
+	job, err := task.NewThumbTask(&admin, policyID, uid, extensions, rate)
+	if err != nil {
+		util.Log().Error("Failed to create thumb task: %s", err)
+		return
+	}
+
+	(&task.GeneralWorker{}).Do(job)
+	if jobErr := job.GetError(); jobErr != nil {
+		util.Log().Error("Failed to regenerate thumbnails: %s %s", jobErr.Msg, jobErr.Error)
+		return
+	}
+
+	props := job.(*task.ThumbTask).TaskProps
+	util.Log().Info("Finish regenerating thumbnails: %d scanned, %d regenerated, %d skipped, %d failed.",
+		props.Scanned, props.Regenerated, props.Skipped, props.Failed)
+}
@@ -0,0 +1,21 @@
+package bootstrap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCommand(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(IsCommand("user"))
+	a.True(IsCommand("cleanup"))
+	a.True(IsCommand("migrate"))
+	a.True(IsCommand("help"))
+
+	// 其他位置参数仍启动服务
+	a.False(IsCommand(""))
+	a.False(IsCommand("conf.ini"))
+	a.False(IsCommand("reset-password"))
+}
@@ -5,13 +5,20 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/models/scripts"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/automation"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/crontab"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/fulltext"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/pathlock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 	"github.com/cloudreve/Cloudreve/v3/pkg/wopi"
 	"github.com/gin-gonic/gin"
 	"io/fs"
@@ -43,6 +50,20 @@ func Init(path string, statics fs.FS) {
 				cache.Init()
 			},
 		},
+		{
+			"both",
+			func() {
+				tracing.Init(conf.LogConfig.OTLPEndpoint, conf.LogConfig.ServiceName)
+			},
+		},
+		{
+			"master",
+			func() {
+				ratelimit.Init()
+				pathlock.Init()
+				instance.Init()
+			},
+		},
 		{
 			"slave",
 			func() {
@@ -91,6 +112,24 @@ func Init(path string, statics fs.FS) {
 				email.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				automation.Init()
+			},
+		},
+		{
+			"master",
+			func() {
+				webhook.Init()
+			},
+		},
+		{
+			"master",
+			func() {
+				fulltext.Init()
+			},
+		},
 		{
 			"master",
 			func() {
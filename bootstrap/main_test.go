@@ -0,0 +1,13 @@
+package bootstrap
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// TestMain 初始化内存数据库
+func TestMain(m *testing.M) {
+	model.Init()
+	m.Run()
+}
@@ -0,0 +1,95 @@
+package bootstrap
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/models/scripts"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// InitMigrate 初始化 migrate 子命令，只连接数据库，不自动执行迁移
+func InitMigrate(path string) {
+	InitApplication()
+	conf.Init(path)
+	if conf.SystemConfig.Mode != "master" {
+		util.Log().Panic("Database migration can only run with master config.")
+	}
+
+	cache.Init()
+	scripts.Init()
+	model.Connect()
+}
+
+// runMigrate 查看、执行或回滚数据库迁移
+func runMigrate(args []string) {
+	action := "status"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	var (
+		target string
+		steps  int
+	)
+
+	flags := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	flags.StringVar(&target, "to", "", "Version to migrate up to, empty for the latest version.")
+	flags.IntVar(&steps, "steps", 1, "Number of migrations to roll back.")
+	_ = flags.Parse(args)
+
+	var (
+		done []string
+		verb string
+		err  error
+	)
+
+	switch action {
+	case "status":
+		printMigrationStatus()
+		return
+	case "up":
+		done, err = model.Migrate(target)
+		verb = "applied"
+	case "down":
+		done, err = model.Rollback(steps)
+		verb = "rolled back"
+	default:
+		util.Log().Error("Unknown migrate action %q, available actions: status, up, down.", action)
+		os.Exit(1)
+	}
+
+	for _, version := range done {
+		util.Log().Info("Migration %q %s.", version, verb)
+	}
+
+	if err != nil {
+		util.Log().Error("Failed to migrate database: %s", err)
+		os.Exit(1)
+	}
+
+	if len(done) == 0 {
+		util.Log().Info("Nothing to migrate.")
+	}
+}
+
+func printMigrationStatus() {
+	states, err := model.MigrationStatus()
+	if err != nil {
+		util.Log().Error("Failed to list database migrations: %s", err)
+		os.Exit(1)
+	}
+
+	for _, state := range states {
+		appliedAt := "pending"
+		if state.Applied {
+			appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+
+		fmt.Printf("%-32s %-20s %s\n", state.Version, appliedAt, state.Description)
+	}
+}
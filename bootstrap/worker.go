@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/automation"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/fulltext"
+	"github.com/cloudreve/Cloudreve/v3/pkg/pathlock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
+)
+
+// InitWorker 初始化独立的任务 Worker 进程，只加载执行任务所需的组件，
+// 不启动定时任务，也不恢复本地任务队列
+func InitWorker(path string) {
+	InitApplication()
+	conf.Init(path)
+	if conf.SystemConfig.Mode != "master" {
+		util.Log().Panic("Task worker can only run with master config.")
+	}
+
+	initTaskComponents()
+}
+
+// initTaskComponents 加载执行任务所需的组件
+func initTaskComponents() {
+	cache.Init()
+	ratelimit.Init()
+	pathlock.Init()
+	model.Init()
+	cluster.Init()
+	email.Init()
+	automation.Init()
+	webhook.Init()
+	fulltext.Init()
+	auth.Init()
+}
+
+// RunWorker 从 Redis 认领并执行任务，收到退出信号后等待执行中的任务完成再退出
+func RunWorker(id string, concurrency int) {
+	store, ok := cache.Store.(*cache.RedisStore)
+	if !ok {
+		util.Log().Error("Task worker requires Redis, please configure Redis in the config file.")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	go func() {
+		sig := <-sigChan
+		util.Log().Info("Signal %s received, waiting for running tasks...", sig)
+		cancel()
+	}()
+
+	if err := task.NewStreamWorker(store.Pool(), id, concurrency).Run(ctx); err != nil {
+		util.Log().Error("Task worker exited: %s", err)
+	}
+}
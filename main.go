@@ -5,7 +5,6 @@ import (
 	_ "embed"
 	"flag"
 	"io/fs"
-	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,14 +16,23 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/plugin"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ftp"
+	"github.com/cloudreve/Cloudreve/v3/pkg/graceful"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sftp"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/routers"
 )
 
 var (
-	isEject    bool
-	confPath   string
-	scriptName string
+	isEject           bool
+	confPath          string
+	scriptName        string
+	isWorker          bool
+	workerID          string
+	workerConcurrency int
 )
 
 //go:embed assets.zip
@@ -36,8 +44,26 @@ func init() {
 	flag.StringVar(&confPath, "c", util.RelativePath("conf.ini"), "Path to the config file.")
 	flag.BoolVar(&isEject, "eject", false, "Eject all embedded static files.")
 	flag.StringVar(&scriptName, "database-script", "", "Name of database util script.")
+	flag.BoolVar(&isWorker, "worker", false, "Run as a standalone task worker.")
+	flag.StringVar(&workerID, "worker-id", "", "ID of the task worker, random if empty.")
+	flag.IntVar(&workerConcurrency, "worker-concurrency", 2, "Maximum number of tasks executed in parallel by the task worker.")
 	flag.Parse()
 
+	if isWorker {
+		bootstrap.InitWorker(confPath)
+		return
+	}
+
+	if flag.Arg(0) == "migrate" {
+		bootstrap.InitMigrate(confPath)
+		return
+	}
+
+	if bootstrap.IsCommand(flag.Arg(0)) {
+		bootstrap.InitCommand(confPath)
+		return
+	}
+
 	staticFS = bootstrap.NewFS(staticZip)
 	bootstrap.Init(confPath, staticFS)
 }
@@ -50,6 +76,12 @@ func main() {
 		}
 	}()
 
+	if isWorker {
+		// 作为独立的任务 Worker 运行
+		bootstrap.RunWorker(workerID, workerConcurrency)
+		return
+	}
+
 	if isEject {
 		// 开始导出内置静态资源文件
 		bootstrap.Eject(staticFS)
@@ -62,39 +94,84 @@ func main() {
 		return
 	}
 
+	if bootstrap.IsCommand(flag.Arg(0)) {
+		// 运行子命令
+		bootstrap.RunCommand(flag.Args())
+		return
+	}
+
 	api := routers.InitRouter()
 	api.TrustedPlatform = conf.SystemConfig.ProxyHeader
 	server := &http.Server{Handler: api}
+	servers := []*http.Server{server}
+	graceful.ReusePort = conf.SystemConfig.ReusePort
+
+	// 如果启用了独立监听的S3兼容接口
+	if conf.SystemConfig.Mode == "master" && conf.S3Config.Listen != "" {
+		s3Server := &http.Server{Handler: routers.InitS3Router()}
+		servers = append(servers, s3Server)
+		go func() {
+			util.Log().Info("S3 API listening to %q", conf.S3Config.Listen)
+			if err := serve(s3Server, "tcp", conf.S3Config.Listen, false); err != nil {
+				util.Log().Error("Failed to listen to %q: %s", conf.S3Config.Listen, err)
+			}
+		}()
+	}
 
 	// 收到信号后关闭服务器
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
-	go shutdown(sigChan, server)
+	signals := []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+	if graceful.RestartSignal != nil {
+		signals = append(signals, graceful.RestartSignal)
+	}
+	signal.Notify(sigChan, signals...)
+	go shutdown(sigChan, servers)
 
 	defer func() {
 		<-sigChan
 	}()
 
+	// 如果启用了内置SFTP服务
+	if conf.SystemConfig.Mode == "master" && conf.SFTPConfig.Listen != "" {
+		sftpServer, err := sftp.NewServer(conf.SFTPConfig.HostKey)
+		if err != nil {
+			util.Log().Error("Failed to initialize SFTP server: %s", err)
+		} else {
+			go func() {
+				util.Log().Info("SFTP server listening to %q", conf.SFTPConfig.Listen)
+				if err := sftpServer.ListenAndServe(conf.SFTPConfig.Listen); err != nil && !graceful.Closing() {
+					util.Log().Error("Failed to listen to %q: %s", conf.SFTPConfig.Listen, err)
+				}
+			}()
+		}
+	}
+
+	// 如果启用了内置FTP服务
+	if conf.SystemConfig.Mode == "master" && conf.FTPConfig.Listen != "" {
+		ftpServer, err := ftp.NewServer(conf.FTPConfig.CertPath, conf.FTPConfig.KeyPath)
+		if err != nil {
+			util.Log().Error("Failed to initialize FTP server: %s", err)
+		} else {
+			go func() {
+				util.Log().Info("FTP server listening to %q", conf.FTPConfig.Listen)
+				if err := ftpServer.ListenAndServe(conf.FTPConfig.Listen); err != nil && !graceful.Closing() {
+					util.Log().Error("Failed to listen to %q: %s", conf.FTPConfig.Listen, err)
+				}
+			}()
+		}
+	}
+
 	// 如果启用了SSL
 	if conf.SSLConfig.CertPath != "" {
 		util.Log().Info("Listening to %q", conf.SSLConfig.Listen)
-		server.Addr = conf.SSLConfig.Listen
-		if err := server.ListenAndServeTLS(conf.SSLConfig.CertPath, conf.SSLConfig.KeyPath); err != nil {
+		if err := serve(server, "tcp", conf.SSLConfig.Listen, true); err != nil {
 			util.Log().Error("Failed to listen to %q: %s", conf.SSLConfig.Listen, err)
-			return
 		}
+		return
 	}
 
 	// 如果启用了Unix
 	if conf.UnixConfig.Listen != "" {
-		// delete socket file before listening
-		if _, err := os.Stat(conf.UnixConfig.Listen); err == nil {
-			if err = os.Remove(conf.UnixConfig.Listen); err != nil {
-				util.Log().Error("Failed to delete socket file: %s", err)
-				return
-			}
-		}
-
 		util.Log().Info("Listening to %q", conf.UnixConfig.Listen)
 		if err := RunUnix(server); err != nil {
 			util.Log().Error("Failed to listen to %q: %s", conf.UnixConfig.Listen, err)
@@ -103,20 +180,53 @@ func main() {
 	}
 
 	util.Log().Info("Listening to %q", conf.SystemConfig.Listen)
-	server.Addr = conf.SystemConfig.Listen
-	if err := server.ListenAndServe(); err != nil {
+	if err := serve(server, "tcp", conf.SystemConfig.Listen, false); err != nil {
 		util.Log().Error("Failed to listen to %q: %s", conf.SystemConfig.Listen, err)
 	}
 }
 
+// serve 在给定地址上运行服务器，优先使用平滑重启时从旧进程继承的监听
+func serve(server *http.Server, network, address string, tls bool) error {
+	listener, err := graceful.Listen(network, address)
+	if err != nil {
+		return err
+	}
+
+	if tls {
+		err = server.ServeTLS(listener, conf.SSLConfig.CertPath, conf.SSLConfig.KeyPath)
+	} else {
+		err = server.Serve(listener)
+	}
+
+	if err == http.ErrServerClosed {
+		return nil
+	}
+
+	return err
+}
+
 func RunUnix(server *http.Server) error {
-	listener, err := net.Listen("unix", conf.UnixConfig.Listen)
+	// delete socket file before listening, unless it is inherited from the old process
+	if !graceful.IsInherited("unix", conf.UnixConfig.Listen) {
+		if _, err := os.Stat(conf.UnixConfig.Listen); err == nil {
+			if err = os.Remove(conf.UnixConfig.Listen); err != nil {
+				return err
+			}
+		}
+	}
+
+	listener, err := graceful.Listen("unix", conf.UnixConfig.Listen)
 	if err != nil {
 		return err
 	}
 
 	defer listener.Close()
-	defer os.Remove(conf.UnixConfig.Listen)
+	defer func() {
+		// the new process keeps serving on the socket file
+		if !graceful.Restarting() {
+			os.Remove(conf.UnixConfig.Listen)
+		}
+	}()
 
 	if conf.UnixConfig.Perm > 0 {
 		err = os.Chmod(conf.UnixConfig.Listen, os.FileMode(conf.UnixConfig.Perm))
@@ -130,12 +240,32 @@ func RunUnix(server *http.Server) error {
 		}
 	}
 
-	return server.Serve(listener)
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
 }
 
-func shutdown(sigChan chan os.Signal, server *http.Server) {
-	sig := <-sigChan
-	util.Log().Info("Signal %s received, shutting down server...", sig)
+func shutdown(sigChan chan os.Signal, servers []*http.Server) {
+	for sig := range sigChan {
+		if graceful.RestartSignal == nil || sig != graceful.RestartSignal {
+			util.Log().Info("Signal %s received, shutting down server...", sig)
+			break
+		}
+
+		// Persist in-memory cache before the new process restores it
+		persistCache()
+		pid, err := graceful.Restart()
+		if err != nil {
+			util.Log().Error("Failed to start new process: %s", err)
+			continue
+		}
+
+		util.Log().Info("Signal %s received, new process %d started, shutting down current process...", sig, pid)
+		break
+	}
+
 	ctx := context.Background()
 	if conf.SystemConfig.GracePeriod != 0 {
 		var cancel context.CancelFunc
@@ -143,16 +273,33 @@ func shutdown(sigChan chan os.Signal, server *http.Server) {
 		defer cancel()
 	}
 
-	// Shutdown http server
-	err := server.Shutdown(ctx)
-	if err != nil {
-		util.Log().Error("Failed to shutdown server: %s", err)
+	// Stop accepting new connections and wait for in-flight requests
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			util.Log().Error("Failed to shutdown server: %s", err)
+		}
+	}
+	graceful.CloseAll()
+
+	// Wait for running tasks, unfinished ones are checkpointed for next start
+	if err := task.Shutdown(ctx); err != nil {
+		util.Log().Warning("Failed to wait for running tasks: %s", err)
 	}
 
+	// Stop storage driver plugins
+	plugin.Default.Kill()
+
+	// Flush pending trace spans
+	tracing.Default.Shutdown()
+
 	// Persist in-memory cache
+	persistCache()
+
+	close(sigChan)
+}
+
+func persistCache() {
 	if err := cache.Store.Persist(filepath.Join(model.GetSettingByName("temp_path"), cache.DefaultCacheFile)); err != nil {
 		util.Log().Warning("Failed to persist cache: %s", err)
 	}
-
-	close(sigChan)
 }
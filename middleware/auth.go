@@ -13,11 +13,14 @@ import (
 	"github.com/qiniu/go-sdk/v7/auth/qbox"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -47,21 +50,135 @@ func SignRequired(authInstance auth.Auth) gin.HandlerFunc {
 	}
 }
 
-// CurrentUser 获取登录用户
+// apiTokenSessionOnly 涉及账户安全、不可使用访问令牌访问的路由前缀
+var apiTokenSessionOnly = []string{
+	"/api/v3/user/setting",
+	"/api/v3/user/tokens",
+	"/api/v3/user/session",
+	"/api/v3/user/authn",
+	"/api/v3/user/2fa",
+	// 应用密码可通过 WebDAV、S3 等协议读写全部文件，且不随访问令牌吊销
+	"/api/v3/webdav",
+}
+
+// apiTokenScope 返回请求所需的访问令牌权限范围，为空时表示不可使用访问令牌
+func apiTokenScope(c *gin.Context) string {
+	path := strings.TrimSuffix(c.Request.URL.Path, "/")
+	if path == "/api/v3/user" {
+		return ""
+	}
+
+	for _, prefix := range apiTokenSessionOnly {
+		if strings.HasPrefix(path, prefix) {
+			return ""
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/api/v3/admin"):
+		return model.APITokenScopeAdmin
+	case strings.HasPrefix(path, "/api/v3/share"):
+		return model.APITokenScopeShare
+	case c.Request.Method == "GET" || c.Request.Method == "HEAD" || c.Request.Method == "OPTIONS":
+		return model.APITokenScopeRead
+	}
+
+	return model.APITokenScopeWrite
+}
+
+// currentTokenUser 使用 Authorization 头中的访问令牌认证用户
+func currentTokenUser(c *gin.Context, raw string) bool {
+	token, err := model.GetAPIToken(raw)
+	if err != nil || token.Expired(time.Now()) {
+		c.JSON(200, serializer.Err(serializer.CodeCredentialInvalid, "Invalid or expired API token", err))
+		c.Abort()
+		return false
+	}
+
+	scope := apiTokenScope(c)
+	if scope == "" || !token.HasScope(scope) {
+		c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "API token does not have the required scope", nil))
+		c.Abort()
+		return false
+	}
+
+	user, err := model.GetActiveUserByID(token.UserID)
+	if err != nil {
+		c.JSON(200, serializer.Err(serializer.CodeCredentialInvalid, "Invalid or expired API token", err))
+		c.Abort()
+		return false
+	}
+
+	if err := token.Touch(time.Now()); err != nil {
+		util.Log().Warning("Failed to update last used time of API token %d: %s", token.ID, err)
+	}
+
+	c.Set("user", &user)
+	c.Set("api_token", &token)
+	stats.UserActive(user.ID)
+	return true
+}
+
+// CurrentUser 获取登录用户，未登录时可使用 Authorization 头中的访问令牌认证
 func CurrentUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if raw := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); strings.HasPrefix(raw, model.APITokenPrefix) {
+			if currentTokenUser(c, raw) {
+				c.Next()
+			}
+			return
+		}
+
 		session := sessions.Default(c)
 		uid := session.Get("user_id")
 		if uid != nil {
 			user, err := model.GetActiveUserByID(uid)
-			if err == nil {
+			if err == nil && checkLoginSession(c, session, &user) {
 				c.Set("user", &user)
+				stats.UserActive(user.ID)
 			}
 		}
 		c.Next()
 	}
 }
 
+// checkLoginSession 检查登录会话是否有效，已吊销或无法识别的会话退出登录。
+// 未携带令牌的会话是启用登录会话管理前登录的，登记为新的登录会话
+func checkLoginSession(c *gin.Context, session sessions.Session, user *model.User) bool {
+	token, _ := session.Get(model.LoginSessionKey).(string)
+	if token == "" {
+		ua := c.Request.UserAgent()
+		record, err := model.NewLoginSession(user.ID, c.ClientIP(), ua, util.DescribeUserAgent(ua))
+		if err != nil {
+			util.Log().Warning("Failed to create login session: %s", err)
+			return true
+		}
+
+		util.SetSession(c, map[string]interface{}{model.LoginSessionKey: record.Token})
+		return true
+	}
+
+	if model.LoginSessionCached(token, user.ID) {
+		return true
+	}
+
+	// 登录时即登记会话，找不到记录说明会话已被吊销并清理
+	record, err := model.GetLoginSession(token)
+	if err != nil || record.UserID != user.ID || record.Revoked() {
+		session.Delete("user_id")
+		session.Delete(model.LoginSessionKey)
+		if err := session.Save(); err != nil {
+			util.Log().Warning("Failed to clear revoked session: %s", err)
+		}
+		return false
+	}
+
+	if err := record.Touch(c.ClientIP(), time.Now()); err != nil {
+		util.Log().Warning("Failed to update login session: %s", err)
+	}
+	return true
+}
+
 // AuthRequired 需要登录
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -77,6 +194,29 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
+// TwoFactorRequired 用户组强制开启二步验证时，尚未设置的用户只能访问 exempt 中的路由。
+// exempt 中的项为路由路径，或以 "方法 路径" 的形式仅放行指定方法
+func TwoFactorRequired(exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*model.User)
+		if !user.Group.OptionsSerialized.Require2FA || len(user.TwoFactorMethods()) > 0 {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + c.FullPath()
+		for _, path := range exempt {
+			if c.FullPath() == path || route == path {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(200, serializer.Err(serializer.Code2FARequired, "Two-factor authentication is required by your group", nil))
+		c.Abort()
+	}
+}
+
 // WebDAVAuth 验证WebDAV登录及权限
 func WebDAVAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -60,10 +61,137 @@ func TestCurrentUser(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "deleted_at", "email", "options"}).
 		AddRow(1, nil, "admin@cloudreve.org", "{}")
 	mock.ExpectQuery("^SELECT (.+)").WillReturnRows(rows)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)login_sessions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 	CurrentUser()(c)
 	user, _ = c.Get("user")
 	asserts.NotNil(user)
 	asserts.NoError(mock.ExpectationsWereMet())
+	token, _ := util.GetSession(c, model.LoginSessionKey).(string)
+	asserts.NotEmpty(token)
+
+	// 登录会话已校验过，不再查询数据库
+	c, _ = gin.CreateTestContext(rec)
+	c.Request, _ = http.NewRequest("GET", "/test", nil)
+	sessionFunc(c)
+	util.SetSession(c, map[string]interface{}{"user_id": 1, model.LoginSessionKey: token})
+	mock.ExpectQuery("^SELECT (.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at", "email", "options"}).
+		AddRow(1, nil, "admin@cloudreve.org", "{}"))
+	CurrentUser()(c)
+	user, _ = c.Get("user")
+	asserts.NotNil(user)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 登录会话已被吊销
+	c, _ = gin.CreateTestContext(rec)
+	c.Request, _ = http.NewRequest("GET", "/test", nil)
+	sessionFunc(c)
+	util.SetSession(c, map[string]interface{}{"user_id": 1, model.LoginSessionKey: "revoked"})
+	mock.ExpectQuery("^SELECT (.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at", "email", "options"}).
+		AddRow(1, nil, "admin@cloudreve.org", "{}"))
+	mock.ExpectQuery("SELECT(.+)login_sessions(.+)").WithArgs("revoked").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "deleted_at"}).AddRow(1, 1, time.Now()))
+	CurrentUser()(c)
+	user, _ = c.Get("user")
+	asserts.Nil(user)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Nil(util.GetSession(c, "user_id"))
+
+	// 登录会话已被清理，无法识别的令牌视为已吊销
+	c, _ = gin.CreateTestContext(rec)
+	c.Request, _ = http.NewRequest("GET", "/test", nil)
+	sessionFunc(c)
+	util.SetSession(c, map[string]interface{}{"user_id": 1, model.LoginSessionKey: "purged"})
+	mock.ExpectQuery("^SELECT (.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at", "email", "options"}).
+		AddRow(1, nil, "admin@cloudreve.org", "{}"))
+	mock.ExpectQuery("SELECT(.+)login_sessions(.+)").WithArgs("purged").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "deleted_at"}))
+	CurrentUser()(c)
+	user, _ = c.Get("user")
+	asserts.Nil(user)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Nil(util.GetSession(c, "user_id"))
+	asserts.Nil(util.GetSession(c, model.LoginSessionKey))
+}
+
+func TestCurrentUser_APIToken(t *testing.T) {
+	asserts := assert.New(t)
+	tokenRows := func(scopes string, expires interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "scopes", "expires_at", "last_used_at"}).
+			AddRow(1, 1, scopes, expires, time.Now())
+	}
+	request := func(method, path string) (*gin.Context, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest(method, path, nil)
+		c.Request.Header.Set("Authorization", "Bearer crt_token")
+		Session("233")(c)
+		return c, rec
+	}
+
+	// 令牌不存在
+	{
+		c, rec := request("GET", "/api/v3/directory")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnError(errors.New("not found"))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), strconv.Itoa(serializer.CodeCredentialInvalid))
+	}
+
+	// 令牌已过期
+	{
+		c, _ := request("GET", "/api/v3/directory")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnRows(tokenRows("read", time.Now().Add(-time.Hour)))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+	}
+
+	// 权限范围不足
+	{
+		c, rec := request("POST", "/api/v3/file/create")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnRows(tokenRows("read", nil))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), strconv.Itoa(serializer.CodeNoPermissionErr))
+	}
+
+	// 账户安全相关接口不可使用令牌
+	{
+		c, _ := request("PATCH", "/api/v3/user/setting/password")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnRows(tokenRows("read,write,admin", nil))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+	}
+
+	// 应用密码管理接口不可使用令牌
+	{
+		c, rec := request("GET", "/api/v3/webdav/accounts")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnRows(tokenRows("read", nil))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), strconv.Itoa(serializer.CodeNoPermissionErr))
+	}
+
+	// 成功
+	{
+		c, _ := request("GET", "/api/v3/directory")
+		mock.ExpectQuery("SELECT(.+)api_tokens(.+)").WillReturnRows(tokenRows("write", nil))
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "options"}).AddRow(1, "admin@cloudreve.org", "{}"))
+		CurrentUser()(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(c.IsAborted())
+		user, _ := c.Get("user")
+		asserts.EqualValues(1, user.(*model.User).ID)
+		_, ok := c.Get("api_token")
+		asserts.True(ok)
+	}
 }
 
 func TestAuthRequired(t *testing.T) {
@@ -88,6 +216,51 @@ func TestAuthRequired(t *testing.T) {
 	asserts.NotNil(c)
 }
 
+func TestTwoFactorRequired(t *testing.T) {
+	asserts := assert.New(t)
+	router := gin.New()
+	enforced := &model.User{}
+	enforced.Group.OptionsSerialized.Require2FA = true
+	router.Use(func(c *gin.Context) {
+		switch c.GetHeader("User") {
+		case "enforced":
+			c.Set("user", enforced)
+		case "totp":
+			c.Set("user", &model.User{TwoFactor: "secret", Group: enforced.Group})
+		default:
+			c.Set("user", &model.User{})
+		}
+	}, TwoFactorRequired("/setting/:option", "DELETE /session"))
+	router.GET("/file", func(c *gin.Context) { c.Status(204) })
+	router.GET("/setting/:option", func(c *gin.Context) { c.Status(204) })
+	router.GET("/session", func(c *gin.Context) { c.Status(204) })
+	router.DELETE("/session", func(c *gin.Context) { c.Status(204) })
+
+	for _, testCase := range []struct {
+		user, method, path string
+		allowed            bool
+	}{
+		{"", "GET", "/file", true},
+		{"totp", "GET", "/file", true},
+		{"enforced", "GET", "/file", false},
+		{"enforced", "GET", "/setting/2fa", true},
+		// 未设置二步验证的用户仍可退出登录
+		{"enforced", "DELETE", "/session", true},
+		{"enforced", "GET", "/session", false},
+	} {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(testCase.method, testCase.path, nil)
+		req.Header.Set("User", testCase.user)
+		router.ServeHTTP(rec, req)
+		if testCase.allowed {
+			asserts.Equal(204, rec.Code, testCase)
+		} else {
+			asserts.Equal(200, rec.Code, testCase)
+			asserts.Contains(rec.Body.String(), "40080")
+		}
+	}
+}
+
 func TestSignRequired(t *testing.T) {
 	asserts := assert.New(t)
 	rec := httptest.NewRecorder()
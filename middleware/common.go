@@ -75,3 +75,22 @@ func MobileRequestOnly() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// TusVersion 支持的 tus 可续传上传协议版本
+const TusVersion = "1.0.0"
+
+// TusResumable 为 tus 协议请求添加协议版本响应头，拒绝版本不受支持的请求
+func TusResumable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", TusVersion)
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Tus-Version", TusVersion)
+		} else if c.GetHeader("Tus-Resumable") != TusVersion {
+			c.Header("Tus-Version", TusVersion)
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+
+		c.Next()
+	}
+}
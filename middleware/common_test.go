@@ -103,3 +103,41 @@ func TestStaticResourceCache(t *testing.T) {
 	TestFunc(c)
 	a.Contains(c.Writer.Header().Get("Cache-Control"), "public, max-age")
 }
+
+func TestTusResumable(t *testing.T) {
+	a := assert.New(t)
+	TestFunc := TusResumable()
+
+	// OPTIONS 请求无需协议版本
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("OPTIONS", "/api/v3/file/tus", nil)
+		TestFunc(c)
+		a.False(c.IsAborted())
+		a.Equal(TusVersion, rec.Header().Get("Tus-Resumable"))
+		a.Equal(TusVersion, rec.Header().Get("Tus-Version"))
+	}
+
+	// 版本不受支持
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("PATCH", "/api/v3/file/tus/1", nil)
+		c.Request.Header.Set("Tus-Resumable", "0.2.2")
+		TestFunc(c)
+		a.True(c.IsAborted())
+		a.Equal(http.StatusPreconditionFailed, rec.Code)
+		a.Equal(TusVersion, rec.Header().Get("Tus-Version"))
+	}
+
+	// 版本正确
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("PATCH", "/api/v3/file/tus/1", nil)
+		c.Request.Header.Set("Tus-Resumable", TusVersion)
+		TestFunc(c)
+		a.False(c.IsAborted())
+	}
+}
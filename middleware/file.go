@@ -23,8 +23,39 @@ func ValidateSourceLink() gin.HandlerFunc {
 			return
 		}
 
-		sourceLink.Downloaded()
-		c.Set("source_link", sourceLink)
-		c.Next()
+		useSourceLink(c, sourceLink)
 	}
 }
+
+// ValidateSourceLinkSlug 检查自定义标识的外链是否有效
+func ValidateSourceLinkSlug() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sourceLink, err := model.GetSourceLinkBySlug(c.Param("slug"))
+		if err != nil || sourceLink.File.ID == 0 {
+			c.JSON(200, serializer.Err(serializer.CodeFileNotFound, "", nil))
+			c.Abort()
+			return
+		}
+
+		useSourceLink(c, sourceLink)
+	}
+}
+
+// useSourceLink 检查外链的有效期与来源限制，通过后记录访问
+func useSourceLink(c *gin.Context, sourceLink *model.SourceLink) {
+	if sourceLink.IsExpired() {
+		c.JSON(200, serializer.Err(serializer.CodeFileNotFound, "Link expired", nil))
+		c.Abort()
+		return
+	}
+
+	if sourceLink.Referers != "" && !sourceLink.AllowReferer(c.Request.Referer()) {
+		c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "Referer not allowed", nil))
+		c.Abort()
+		return
+	}
+
+	sourceLink.Downloaded()
+	c.Set("source_link", sourceLink)
+	c.Next()
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestValidateSourceLink(t *testing.T) {
@@ -54,4 +55,63 @@ func TestValidateSourceLink(t *testing.T) {
 		a.NoError(mock.ExpectationsWereMet())
 	}
 
+	// 已过期
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("object_id", 1)
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id", "expires"}).AddRow(1, 2, time.Now().Add(-time.Hour)))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		testFunc(c)
+		a.True(c.IsAborted())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 来源不在白名单中
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/f/x/", nil)
+		c.Request.Header.Set("Referer", "https://evil.com/")
+		c.Set("object_id", 1)
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id", "referers"}).AddRow(1, 2, "example.com"))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		testFunc(c)
+		a.True(c.IsAborted())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestValidateSourceLinkSlug(t *testing.T) {
+	a := assert.New(t)
+	rec := httptest.NewRecorder()
+	testFunc := ValidateSourceLinkSlug()
+
+	// 不存在
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "logo"}}
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs("logo").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		testFunc(c)
+		a.True(c.IsAborted())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/f/s/logo", nil)
+		c.Request.Header.Set("Referer", "https://example.com/")
+		c.Params = gin.Params{{Key: "slug", Value: "logo"}}
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs("logo").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id", "referers"}).AddRow(1, 2, "example.com"))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)source_links").WillReturnResult(sqlmock.NewResult(1, 1))
+		testFunc(c)
+		a.False(c.IsAborted())
+		a.NoError(mock.ExpectationsWereMet())
+		_, ok := c.Get("source_link")
+		a.True(ok)
+	}
 }
@@ -42,7 +42,13 @@ func FrontendFileHandler() gin.HandlerFunc {
 		if strings.HasPrefix(path, "/api") ||
 			strings.HasPrefix(path, "/custom") ||
 			strings.HasPrefix(path, "/dav") ||
+			strings.HasPrefix(path, "/caldav") ||
+			strings.HasPrefix(path, "/carddav") ||
+			strings.HasPrefix(path, "/.well-known/caldav") ||
+			strings.HasPrefix(path, "/.well-known/carddav") ||
 			strings.HasPrefix(path, "/f") ||
+			strings.HasPrefix(path, "/s3/") ||
+			strings.HasPrefix(path, "/site/") ||
 			path == "/manifest.json" {
 			c.Next()
 			return
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader 客户端用于标识重试请求的请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyInFlight 正在处理中的幂等请求
+var idempotencyInFlight sync.Map
+
+// idempotentResponse 缓存的首次请求响应
+type idempotentResponse struct {
+	// Fingerprint 请求体摘要，用于识别以相同幂等键发送的不同请求
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+func init() {
+	gob.Register(idempotentResponse{})
+}
+
+// idempotencyRecorder 记录写入的响应内容
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// idempotencyCacheKey 幂等键仅在同一用户的同一接口下有效
+func idempotencyCacheKey(c *gin.Context, key string) string {
+	var uid uint
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			uid = u.ID
+		}
+	}
+
+	scope := sha1.Sum([]byte(fmt.Sprintf("%d|%s|%s|%s", uid, c.Request.Method, c.FullPath(), key)))
+	return "idempotency_" + hex.EncodeToString(scope[:])
+}
+
+// Idempotent 客户端携带 Idempotency-Key 请求头时，保存首次请求的响应，
+// 在有效期内以相同幂等键重试时直接返回保存的响应，不再重复执行操作
+func Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.JSON(200, serializer.ParamErr("Idempotency key is too long", nil))
+			c.Abort()
+			return
+		}
+
+		cacheKey := idempotencyCacheKey(c, key)
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				c.JSON(200, serializer.ParamErr("Failed to read request body", err))
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		fingerprint := sha1.Sum(body)
+
+		if _, loaded := idempotencyInFlight.LoadOrStore(cacheKey, true); loaded {
+			c.JSON(200, serializer.Err(serializer.CodeConflict, "A request with the same idempotency key is being processed", nil))
+			c.Abort()
+			return
+		}
+		defer idempotencyInFlight.Delete(cacheKey)
+
+		if cached, ok := cache.Get(cacheKey); ok {
+			res := cached.(idempotentResponse)
+			if res.Fingerprint != hex.EncodeToString(fingerprint[:]) {
+				c.JSON(200, serializer.ParamErr("Idempotency key is already used by another request", nil))
+				c.Abort()
+				return
+			}
+
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(res.Status, res.ContentType, res.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 服务端错误允许客户端重试
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		// 业务错误以 HTTP 200 返回，仅保存操作成功的响应，失败后客户端仍可重试
+		var res serializer.Response
+		if err := json.Unmarshal(recorder.body.Bytes(), &res); err != nil || res.Code != 0 {
+			return
+		}
+
+		if err := cache.Set(cacheKey, idempotentResponse{
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		}, model.GetIntSetting("idempotency_ttl", 86400)); err != nil {
+			util.Log().Warning("Failed to save idempotent response: %s", err)
+		}
+	}
+}
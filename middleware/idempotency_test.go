@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotent(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_idempotency_ttl", "60", 0)
+
+	calls := 0
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		user := &model.User{}
+		user.ID = 1
+		c.Set("user", user)
+	})
+	router.POST("/test", Idempotent(), func(c *gin.Context) {
+		calls++
+		c.JSON(200, gin.H{"calls": calls})
+	})
+	router.POST("/fail", Idempotent(), func(c *gin.Context) {
+		calls++
+		c.JSON(200, serializer.Err(serializer.CodeIOFailed, "failed", nil))
+	})
+	request := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/test", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未携带幂等键
+	{
+		request("", "")
+		request("", "")
+		a.Equal(2, calls)
+	}
+
+	// 首次请求
+	{
+		rec := request("key1", "body")
+		a.Equal(3, calls)
+		a.Contains(rec.Body.String(), `"calls":3`)
+		a.Empty(rec.Header().Get("Idempotent-Replayed"))
+	}
+
+	// 重试时返回首次响应
+	{
+		rec := request("key1", "body")
+		a.Equal(3, calls)
+		a.Contains(rec.Body.String(), `"calls":3`)
+		a.Equal("true", rec.Header().Get("Idempotent-Replayed"))
+		a.Contains(rec.Header().Get("Content-Type"), "application/json")
+	}
+
+	// 相同幂等键用于不同请求
+	{
+		rec := request("key1", "another")
+		a.Equal(3, calls)
+		a.Contains(rec.Body.String(), "already used")
+	}
+
+	// 幂等键过长
+	{
+		rec := request(strings.Repeat("a", 256), "")
+		a.Equal(3, calls)
+		a.Contains(rec.Body.String(), "too long")
+	}
+
+	// 失败的响应不保存，重试时重新执行
+	{
+		for i := 0; i < 2; i++ {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/fail", bytes.NewBufferString("body"))
+			req.Header.Set(IdempotencyKeyHeader, "key3")
+			router.ServeHTTP(rec, req)
+			a.Contains(rec.Body.String(), "failed")
+			a.Empty(rec.Header().Get("Idempotent-Replayed"))
+		}
+		a.Equal(5, calls)
+	}
+
+	// 请求正在处理中
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("POST", "/test", nil)
+		c.Request.Header.Set(IdempotencyKeyHeader, "key2")
+		cacheKey := idempotencyCacheKey(c, "key2")
+		idempotencyInFlight.Store(cacheKey, true)
+		Idempotent()(c)
+		idempotencyInFlight.Delete(cacheKey)
+		a.True(c.IsAborted())
+		a.Contains(rec.Body.String(), "being processed")
+	}
+}
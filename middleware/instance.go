@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteUploadSession 集群模式下，将上传会话的请求转发给持有该会话分片的实例
+func RouteUploadSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !instance.Enabled() || c.GetHeader(instance.ForwardedHeader) != "" {
+			c.Next()
+			return
+		}
+
+		// 会话不存在时交由后续处理返回错误
+		sessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + c.Param("sessionId"))
+		if !ok {
+			c.Next()
+			return
+		}
+
+		session := sessionRaw.(serializer.UploadSession)
+		if session.Instance == "" || session.Instance == instance.ID() {
+			c.Next()
+			return
+		}
+
+		target, ok := instance.Lookup(session.Instance)
+		if !ok {
+			c.JSON(200, serializer.Err(serializer.CodeNodeOffline, "Instance holding this upload session is offline", nil))
+			c.Abort()
+			return
+		}
+
+		instance.Forward(c, target)
+		c.Abort()
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (closeNotifyRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestRouteUploadSession(t *testing.T) {
+	asserts := assert.New(t)
+	TestFunc := RouteUploadSession()
+	newContext := func(sessionID string) (*gin.Context, *httptest.ResponseRecorder) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(closeNotifyRecorder{rec})
+		c.Params = []gin.Param{{Key: "sessionId", Value: sessionID}}
+		c.Request, _ = http.NewRequest("PATCH", "/api/v3/file/tus/"+sessionID, nil)
+		return c, rec
+	}
+
+	cache.Set(filesystem.UploadSessionCachePrefix+"local", serializer.UploadSession{Instance: "node1"}, 0)
+	cache.Set(filesystem.UploadSessionCachePrefix+"remote", serializer.UploadSession{Instance: "node2"}, 0)
+
+	// 未启用集群模式
+	{
+		c, _ := newContext("remote")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	conf.SystemConfig.ClusterMode = true
+	conf.SystemConfig.InstanceID = "node1"
+	defer func() {
+		conf.SystemConfig.ClusterMode = false
+		conf.SystemConfig.InstanceID = ""
+	}()
+
+	// 已被转发的请求
+	{
+		c, _ := newContext("remote")
+		c.Request.Header.Set(instance.ForwardedHeader, "node2")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 会话不存在
+	{
+		c, _ := newContext("not_exist")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 由本实例持有
+	{
+		c, _ := newContext("local")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 持有会话的实例已离线
+	{
+		c, rec := newContext("remote")
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), "50010")
+	}
+
+	// 转发给持有会话的实例
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Header.Get(instance.ForwardedHeader)))
+		}))
+		defer server.Close()
+		cache.Set("instance_node2", server.URL, 0)
+
+		c, rec := newContext("remote")
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal("node1", rec.Body.String())
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// PublicFolderAvailable 检查公开目录是否可用
+func PublicFolderAvailable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		public, err := model.GetPublicFolderBySlug(c.Param("slug"))
+		if err == nil {
+			err = public.Load()
+		}
+
+		if err != nil {
+			c.JSON(200, serializer.Err(serializer.CodeNotFound, "Public folder not found", err))
+			c.Abort()
+			return
+		}
+
+		c.Set("public_folder", &public)
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicFolderAvailable(t *testing.T) {
+	asserts := assert.New(t)
+	testFunc := PublicFolderAvailable()
+
+	// 公开目录不存在
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "mirror"}}
+		mock.ExpectQuery("SELECT(.+)public_folders(.+)").WillReturnError(errors.New("not found"))
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+	}
+
+	// 所有者不可用
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "mirror"}}
+		mock.ExpectQuery("SELECT(.+)public_folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "owner_id"}).AddRow(1, 2, 3))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("not found"))
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		_, ok := c.Get("public_folder")
+		asserts.False(ok)
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit 按接口类别限制请求频率，需在 CurrentUser 之后使用。
+// 认证类接口按 IP 限制；其他接口登录用户按用户组配置限制，
+// 未登录请求按 Authorization 凭证或 IP 限制。
+func RateLimit(class string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, limit := rateLimitKey(c, class)
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		res, err := ratelimit.Default.Take(key, ratelimit.PerMinute(limit))
+		if err != nil {
+			// 限流器不可用时放行请求
+			util.Log().Warning("Failed to take rate limit token for %q: %s", key, err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, serializer.Err(serializer.CodeTooManyRequests, "Too many requests, please try again later", nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey 返回请求对应的令牌桶标识和每分钟最大请求数
+func rateLimitKey(c *gin.Context, class string) (string, int) {
+	if class == ratelimit.ClassAuth {
+		return fmt.Sprintf("%s_ip_%s", class, c.ClientIP()), model.GetIntSetting("rate_limit_auth", 20)
+	}
+
+	if class == ratelimit.ClassGuest {
+		return fmt.Sprintf("%s_ip_%s", class, c.ClientIP()), model.GetIntSetting("rate_limit_guest", 60)
+	}
+
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok && u.ID > 0 {
+			return fmt.Sprintf("%s_user_%d", class, u.ID), u.Group.OptionsSerialized.RateLimit
+		}
+	}
+
+	limit := model.GetIntSetting("rate_limit_anonymous", 0)
+	if token := c.GetHeader("Authorization"); token != "" {
+		sum := sha1.Sum([]byte(token))
+		return fmt.Sprintf("%s_token_%s", class, hex.EncodeToString(sum[:])), limit
+	}
+
+	return fmt.Sprintf("%s_ip_%s", class, c.ClientIP()), limit
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
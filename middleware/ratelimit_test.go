@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	a := assert.New(t)
+	ratelimit.Default = ratelimit.NewMemoryLimiter()
+
+	// 未开启限制
+	{
+		cache.Set("setting_rate_limit_anonymous", "0", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		RateLimit(ratelimit.ClassAPI)(c)
+		a.False(c.IsAborted())
+		a.Empty(rec.Header().Get("RateLimit-Limit"))
+	}
+
+	// 认证接口按 IP 限制
+	{
+		cache.Set("setting_rate_limit_auth", "1", 0)
+		for i, aborted := range []bool{false, true} {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request, _ = http.NewRequest("POST", "/test", nil)
+			c.Request.RemoteAddr = "1.2.3.4:80"
+			RateLimit(ratelimit.ClassAuth)(c)
+			a.Equal(aborted, c.IsAborted(), i)
+			a.Equal("1", rec.Header().Get("RateLimit-Limit"))
+			if aborted {
+				a.Equal(http.StatusTooManyRequests, rec.Code)
+				a.Equal("60", rec.Header().Get("Retry-After"))
+			}
+		}
+	}
+
+	// 游客访问按 IP 限制，不受登录状态影响
+	{
+		cache.Set("setting_rate_limit_guest", "1", 0)
+		user := &model.User{}
+		user.ID = 1
+		for i, aborted := range []bool{false, true} {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			c.Request.RemoteAddr = "1.2.3.5:80"
+			c.Set("user", user)
+			RateLimit(ratelimit.ClassGuest)(c)
+			a.Equal(aborted, c.IsAborted(), i)
+		}
+	}
+
+	// 登录用户按用户组配置限制
+	{
+		user := &model.User{}
+		user.ID = 1
+		user.Group.OptionsSerialized.RateLimit = 2
+		for i, aborted := range []bool{false, false, true} {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			c.Set("user", user)
+			RateLimit(ratelimit.ClassAPI)(c)
+			a.Equal(aborted, c.IsAborted(), i)
+		}
+	}
+
+	// 未登录请求按凭证区分
+	{
+		cache.Set("setting_rate_limit_anonymous", "1", 0)
+		for i, token := range []string{"a", "b", ""} {
+			rec := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(rec)
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			c.Request.Header.Set("Authorization", token)
+			RateLimit(ratelimit.ClassAPI)(c)
+			a.False(c.IsAborted(), i)
+			a.Equal("0", rec.Header().Get("RateLimit-Remaining"))
+		}
+	}
+}
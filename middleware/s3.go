@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+// s3Abort 返回 S3 错误响应并终止请求
+func s3Abort(c *gin.Context, err error) {
+	s3.Err(err).Write(c.Writer, c.Request)
+	c.Abort()
+}
+
+// S3Auth 校验 S3 请求签名。访问密钥 ID 为用户邮箱，密钥为其任一 WebDAV 账户的密码，
+// 账户的根目录与只读设置同样生效
+func S3Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("X-Amz-Request-Id", uuid.Must(uuid.NewV4()).String())
+
+		sig, err := s3.ParseSignature(c.Request, time.Now())
+		if err != nil {
+			s3Abort(c, err)
+			return
+		}
+
+		expectedUser, err := model.GetActiveUserByEmail(sig.AccessKey)
+		if err != nil {
+			s3Abort(c, s3.ErrInvalidAccessKeyID)
+			return
+		}
+
+		var webdav *model.Webdav
+		accounts := model.ListWebDAVAccounts(expectedUser.ID)
+		for i := range accounts {
+			if sig.Verify(c.Request, accounts[i].Password) {
+				webdav = &accounts[i]
+				break
+			}
+		}
+
+		if webdav == nil {
+			s3Abort(c, s3.ErrSignatureDoesNotMatch)
+			return
+		}
+
+		// 用户组已启用WebDAV？
+		if !expectedUser.Group.WebDAVEnabled {
+			s3Abort(c, s3.ErrAccessDenied)
+			return
+		}
+
+		// 只读账户仅可读取
+		if webdav.Readonly && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			s3Abort(c, s3.ErrAccessDenied)
+			return
+		}
+
+		body, err := sig.Body(c.Request, webdav.Password)
+		if err != nil {
+			s3Abort(c, err)
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(body)
+
+		c.Set("user", &expectedUser)
+		c.Set("webdav", webdav)
+		c.Next()
+	}
+}
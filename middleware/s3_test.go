@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+const s3EmptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// signS3Request 使用 AWS Signature Version 4 签名 GET 请求
+func signS3Request(r *http.Request, accessKey, secret string) {
+	now := time.Now().UTC()
+	date := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/us-east-1/s3/aws4_request"
+	r.Header.Set("X-Amz-Date", date)
+	r.Header.Set("X-Amz-Content-Sha256", s3EmptySHA256)
+
+	canonical := strings.Join([]string{
+		r.Method, r.URL.Path, "",
+		"host:" + r.Host + "\nx-amz-content-sha256:" + s3EmptySHA256 + "\nx-amz-date:" + date + "\n",
+		"host;x-amz-content-sha256;x-amz-date", s3EmptySHA256,
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + secret)
+	for _, part := range strings.Split(scope, "/") {
+		key = s3HMAC(key, part)
+	}
+
+	r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		",SignedHeaders=host;x-amz-content-sha256;x-amz-date,Signature="+hex.EncodeToString(s3HMAC(key, stringToSign)))
+}
+
+func TestS3Auth(t *testing.T) {
+	a := assert.New(t)
+	AuthFunc := S3Auth()
+
+	// 未签名
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/s3/", nil)
+		AuthFunc(c)
+		a.True(c.IsAborted())
+		a.Equal(http.StatusForbidden, rec.Code)
+		a.Contains(rec.Body.String(), "<Code>AccessDenied</Code>")
+		a.NotEmpty(rec.Header().Get("X-Amz-Request-Id"))
+	}
+
+	// 用户不存在
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/s3/", nil)
+		signS3Request(c.Request, "who@cloudreve.org", "secret")
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		AuthFunc(c)
+		a.NoError(mock.ExpectationsWereMet())
+		a.True(c.IsAborted())
+		a.Contains(rec.Body.String(), "<Code>InvalidAccessKeyId</Code>")
+	}
+
+	// 密钥错误
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/s3/", nil)
+		signS3Request(c.Request, "who@cloudreve.org", "wrong")
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(1, "who@cloudreve.org", 1, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "web_dav_enabled"}).AddRow(1, true))
+		mock.ExpectQuery("SELECT(.+)webdavs(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, "secret"))
+		AuthFunc(c)
+		a.NoError(mock.ExpectationsWereMet())
+		a.True(c.IsAborted())
+		a.Contains(rec.Body.String(), "<Code>SignatureDoesNotMatch</Code>")
+	}
+
+	// 只读账户写入
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("DELETE", "/s3/bucket/key", nil)
+		signS3Request(c.Request, "who@cloudreve.org", "secret")
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(1, "who@cloudreve.org", 1, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "web_dav_enabled"}).AddRow(1, true))
+		mock.ExpectQuery("SELECT(.+)webdavs(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "password", "readonly"}).AddRow(1, "secret", true))
+		AuthFunc(c)
+		a.NoError(mock.ExpectationsWereMet())
+		a.True(c.IsAborted())
+		a.Equal(http.StatusForbidden, rec.Code)
+	}
+
+	// 正常
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/s3/", nil)
+		signS3Request(c.Request, "who@cloudreve.org", "secret")
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "group_id", "options"}).AddRow(1, "who@cloudreve.org", 1, "{}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "web_dav_enabled"}).AddRow(1, true))
+		mock.ExpectQuery("SELECT(.+)webdavs(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, "other").AddRow(2, "secret"))
+		AuthFunc(c)
+		a.NoError(mock.ExpectationsWereMet())
+		a.False(c.IsAborted())
+		_, ok := c.Get("user")
+		a.True(ok)
+		webdav, ok := c.Get("webdav")
+		a.True(ok)
+		a.EqualValues(2, webdav.(*model.Webdav).ID)
+	}
+}
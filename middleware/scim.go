@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/scim"
+	"github.com/gin-gonic/gin"
+)
+
+// SCIMAuth 校验身份提供方的 Bearer Token，未设定 Token 时 SCIM 接口不可用
+func SCIMAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := model.GetSettingByName("scim_token")
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(provided)) != 1 {
+			res := scim.Err(http.StatusUnauthorized, "", "invalid or missing bearer token")
+			c.JSON(res.Status, res.Body)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
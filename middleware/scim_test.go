@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCIMAuth(t *testing.T) {
+	asserts := assert.New(t)
+	testFunc := SCIMAuth()
+
+	// 未设定 Token
+	{
+		cache.Set("setting_scim_token", "", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/scim/v2/Users", nil)
+		c.Request.Header.Set("Authorization", "Bearer ")
+		testFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusUnauthorized, rec.Code)
+	}
+
+	// Token 错误
+	{
+		cache.Set("setting_scim_token", "secret", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/scim/v2/Users", nil)
+		c.Request.Header.Set("Authorization", "Bearer wrong")
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 通过
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/scim/v2/Users", nil)
+		c.Request.Header.Set("Authorization", "Bearer secret")
+		testFunc(c)
+		asserts.False(c.IsAborted())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -36,7 +37,7 @@ func Session(secret string) gin.HandlerFunc {
 	// Also set Secure: true if using SSL, you should though
 	Store.Options(sessions.Options{
 		HttpOnly: true,
-		MaxAge:   60 * 86400,
+		MaxAge:   int(model.LoginSessionMaxAge.Seconds()),
 		Path:     "/",
 		SameSite: sameSiteMode,
 		Secure:   conf.CORSConfig.Secure,
@@ -74,6 +74,22 @@ func ShareCanPreview() gin.HandlerFunc {
 	}
 }
 
+// ShareReadable 检查分享是否允许读取，投递分享仅允许上传
+func ShareReadable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if share, ok := c.Get("share"); ok {
+			if !share.(*model.Share).UploadOnly {
+				c.Next()
+				return
+			}
+			c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "This share only accepts uploads", nil))
+			c.Abort()
+			return
+		}
+		c.Abort()
+	}
+}
+
 // CheckShareUnlocked 检查分享是否已解锁
 func CheckShareUnlocked() gin.HandlerFunc {
 	return func(c *gin.Context) {
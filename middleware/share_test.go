@@ -80,6 +80,35 @@ func TestShareCanPreview(t *testing.T) {
 	}
 }
 
+func TestShareReadable(t *testing.T) {
+	asserts := assert.New(t)
+	rec := httptest.NewRecorder()
+	testFunc := ShareReadable()
+
+	// 无分享上下文
+	{
+		c, _ := gin.CreateTestContext(rec)
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 普通分享
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{})
+		testFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 投递分享
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{UploadOnly: true})
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
 func TestCheckShareUnlocked(t *testing.T) {
 	asserts := assert.New(t)
 	rec := httptest.NewRecorder()
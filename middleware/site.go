@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+)
+
+// StaticSiteAvailable 检查静态网站是否可用，不可用时返回纯文本 404 而非 JSON
+func StaticSiteAvailable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !model.IsTrueVal(model.GetSettingByName("static_site_enabled")) {
+			c.String(http.StatusNotFound, "Site not found")
+			c.Abort()
+			return
+		}
+
+		site, err := model.GetStaticSiteBySlug(c.Param("slug"))
+		if err == nil {
+			err = site.Load()
+		}
+
+		if err != nil {
+			c.String(http.StatusNotFound, "Site not found")
+			c.Abort()
+			return
+		}
+
+		c.Set("static_site", &site)
+		c.Next()
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticSiteAvailable(t *testing.T) {
+	asserts := assert.New(t)
+	testFunc := StaticSiteAvailable()
+
+	// 功能未开启
+	{
+		cache.Set("setting_static_site_enabled", "0", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "blog"}}
+		testFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(404, rec.Code)
+	}
+
+	cache.Set("setting_static_site_enabled", "1", 0)
+
+	// 网站不存在
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "blog"}}
+		mock.ExpectQuery("SELECT(.+)static_sites(.+)").WillReturnError(errors.New("not found"))
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Equal(404, rec.Code)
+	}
+
+	// 所有者不可用
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = gin.Params{{Key: "slug", Value: "blog"}}
+		mock.ExpectQuery("SELECT(.+)static_sites(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "owner_id"}).AddRow(1, 2, 3))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("not found"))
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		_, ok := c.Get("static_site")
+		asserts.False(ok)
+	}
+}
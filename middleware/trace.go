@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// 上游传入的请求 ID 只接受有限长度的安全字符，避免污染日志
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestTracing 为请求分配请求 ID 及追踪信息，写入上下文与响应头，
+// 启用追踪导出时记录请求的 Span
+func RequestTracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		trace := &util.RequestTrace{SpanID: tracing.NewSpanID()}
+		parent := ""
+		if traceID, spanID, ok := tracing.ParseTraceparent(c.GetHeader(tracing.TraceparentHeader)); ok {
+			trace.TraceID, parent = traceID, spanID
+		} else {
+			trace.TraceID = tracing.NewTraceID()
+		}
+
+		trace.RequestID = c.GetHeader(tracing.RequestIDHeader)
+		if !requestIDPattern.MatchString(trace.RequestID) {
+			trace.RequestID = trace.TraceID
+		}
+
+		c.Set(util.RequestTraceKey, trace)
+		c.Request = c.Request.WithContext(util.WithRequestTrace(c.Request.Context(), trace))
+		c.Header(tracing.RequestIDHeader, trace.RequestID)
+
+		span := tracing.StartSpan(trace, c.Request.Method, tracing.SpanKindServer)
+		c.Next()
+
+		if span != nil {
+			// Span 使用请求自身的 ID，上游的 Span 作为父级
+			span.SpanID, span.ParentSpanID = trace.SpanID, parent
+			route := c.FullPath()
+			if route != "" {
+				span.Name += " " + route
+			}
+
+			span.Attributes["http.method"] = c.Request.Method
+			span.Attributes["http.route"] = route
+			span.Attributes["http.target"] = c.Request.URL.Path
+			span.Attributes["http.status_code"] = c.Writer.Status()
+			span.Attributes["http.client_ip"] = c.ClientIP()
+			span.Attributes["cloudreve.request_id"] = trace.RequestID
+			span.Finish(c.Writer.Status() >= 500)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type traceExporterMock struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (e *traceExporterMock) Export(spans []*tracing.Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestRequestTracing(t *testing.T) {
+	asserts := assert.New(t)
+	TestFunc := RequestTracing()
+
+	// 未传入追踪信息，生成新的请求 ID
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/site/ping", nil)
+		c.Request.Header.Set(tracing.RequestIDHeader, "invalid id\n")
+		TestFunc(c)
+		trace := util.TraceFromContext(c)
+		asserts.NotNil(trace)
+		asserts.Len(trace.TraceID, 32)
+		asserts.Equal(trace.TraceID, trace.RequestID)
+		asserts.Equal(trace, util.TraceFromContext(c.Request.Context()))
+		asserts.Equal(trace.RequestID, rec.Header().Get(tracing.RequestIDHeader))
+	}
+
+	// 沿用上游的请求 ID 及 Trace，并导出请求的 Span
+	{
+		exporter := &traceExporterMock{}
+		tracing.Default = tracing.NewTracer(exporter)
+
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/api/v3/site/ping", nil)
+		c.Request.Header.Set(tracing.RequestIDHeader, "upstream-id")
+		c.Request.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		TestFunc(c)
+		tracing.Default.Shutdown()
+		tracing.Default = nil
+
+		trace := util.TraceFromContext(c)
+		asserts.Equal("upstream-id", trace.RequestID)
+		asserts.Equal("4bf92f3577b34da6a3ce929d0e0e4736", trace.TraceID)
+		asserts.Equal("upstream-id", rec.Header().Get(tracing.RequestIDHeader))
+		asserts.Len(exporter.spans, 1)
+		asserts.Equal(trace.SpanID, exporter.spans[0].SpanID)
+		asserts.Equal("00f067aa0ba902b7", exporter.spans[0].ParentSpanID)
+		asserts.Equal(http.StatusOK, exporter.spans[0].Attributes["http.status_code"])
+		asserts.Equal(tracing.SpanKindServer, exporter.spans[0].Kind)
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// 举报处理状态
+const (
+	// AbuseReportPending 待处理
+	AbuseReportPending = iota
+	// AbuseReportDisabled 已禁用被举报的分享及文件
+	AbuseReportDisabled
+	// AbuseReportCleared 已驳回，或已解除禁用
+	AbuseReportCleared
+)
+
+// AbuseReport 访客对分享链接的举报，如侵权投诉（DMCA）
+type AbuseReport struct {
+	gorm.Model
+	ShareID     uint   `gorm:"index:abuse_report_share"`
+	OwnerID     uint   `gorm:"index:abuse_report_owner"`
+	Reason      string `gorm:"size:32"`
+	Description string `gorm:"type:text"`
+	// Contact 举报人的联系方式
+	Contact string
+	IP      string
+	Status  int `gorm:"index:abuse_report_status"`
+	// Note 管理员的处理说明，会在通知邮件中告知分享者
+	Note string `gorm:"type:text"`
+}
+
+// Create 创建举报
+func (report *AbuseReport) Create() error {
+	return DB.Create(report).Error
+}
+
+// GetAbuseReportByID 根据ID查找举报
+func GetAbuseReportByID(id uint) (*AbuseReport, error) {
+	var report AbuseReport
+	result := DB.First(&report, id)
+	return &report, result.Error
+}
+
+// ResolveAbuseReports 将举报及同一分享其他待处理的举报标记为给定的处理状态
+func ResolveAbuseReports(shareID, reportID uint, status int, note string) error {
+	return DB.Model(&AbuseReport{}).
+		Where("id = ? or (share_id = ? and status = ?)", reportID, shareID, AbuseReportPending).
+		UpdateColumns(map[string]interface{}{
+			"status": status,
+			"note":   note,
+		}).Error
+}
+
+// CountPendingAbuseReportsByIP 统计某一 IP 对分享尚未处理的举报数，用于避免重复举报
+func CountPendingAbuseReportsByIP(shareID uint, ip string) (int, error) {
+	total := 0
+	result := DB.Model(&AbuseReport{}).
+		Where("share_id = ? and ip = ? and status = ?", shareID, ip, AbuseReportPending).Count(&total)
+	return total, result.Error
+}
+
+// SetShareDisabled 禁用或恢复分享，分享的是文件时同时禁用或恢复该文件的下载
+func SetShareDisabled(share *Share, disabled bool) error {
+	tx := DB.Begin()
+	if err := tx.Model(share).UpdateColumn("disabled", disabled).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if !share.IsDir {
+		if err := tx.Model(&File{}).Where("id = ?", share.SourceID).UpdateColumn("disabled", disabled).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	share.Disabled = disabled
+	return nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbuseReport_Create(t *testing.T) {
+	asserts := assert.New(t)
+	report := AbuseReport{ShareID: 1, Reason: "copyright"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)abuse_reports(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(report.Create())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, report.ID)
+}
+
+func TestGetAbuseReportByID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)abuse_reports(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "share_id"}).AddRow(1, 2))
+	report, err := GetAbuseReportByID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, report.ShareID)
+}
+
+func TestResolveAbuseReports(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)abuse_reports(.+)").
+		WithArgs("note", AbuseReportDisabled, 3, 2, AbuseReportPending).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	asserts.NoError(ResolveAbuseReports(2, 3, AbuseReportDisabled, "note"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestCountPendingAbuseReportsByIP(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)").
+		WithArgs(1, "127.0.0.1", AbuseReportPending).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	total, err := CountPendingAbuseReportsByIP(1, "127.0.0.1")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal(2, total)
+}
+
+func TestSetShareDisabled(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 分享文件，同时禁用文件
+	{
+		share := &Share{SourceID: 3}
+		share.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WithArgs(true, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(true, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SetShareDisabled(share, true))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(share.Disabled)
+	}
+
+	// 分享目录
+	{
+		share := &Share{SourceID: 3, IsDir: true, Disabled: true}
+		share.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WithArgs(false, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SetShareDisabled(share, false))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(share.Disabled)
+	}
+
+	// 失败
+	{
+		share := &Share{SourceID: 3}
+		share.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(SetShareDisabled(share, true))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
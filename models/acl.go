@@ -0,0 +1,159 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 目录 ACL 权限位
+const (
+	ACLRead = 1 << iota
+	ACLWrite
+	ACLDelete
+	ACLShare
+
+	ACLAll = ACLRead | ACLWrite | ACLDelete | ACLShare
+)
+
+// aclPermissionNames 权限位的名称，按权限位顺序排列
+var aclPermissionNames = []string{"read", "write", "delete", "share"}
+
+// 目录 ACL 授权对象类型
+const (
+	ACLPrincipalUser  = "user"
+	ACLPrincipalGroup = "group"
+)
+
+// 目录 ACL 效果
+const (
+	ACLAllow = "allow"
+	ACLDeny  = "deny"
+)
+
+// FolderACL 目录访问控制条目，对目录及其子对象生效
+type FolderACL struct {
+	gorm.Model
+	FolderID      uint   `gorm:"index:folder_acl_folder"`
+	OwnerID       uint   `gorm:"index:folder_acl_owner"`
+	PrincipalType string `gorm:"size:16"`
+	PrincipalID   uint
+	Effect        string `gorm:"size:16"`
+	Permissions   int
+}
+
+// ParseACLPermissions 将权限名称列表转换为权限位，存在未知名称时返回错误
+func ParseACLPermissions(names []string) (int, error) {
+	perms := 0
+	for _, name := range names {
+		found := false
+		for i, known := range aclPermissionNames {
+			if name == known {
+				perms |= 1 << i
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return 0, fmt.Errorf("unknown permission %q", name)
+		}
+	}
+
+	return perms, nil
+}
+
+// PermissionNames 返回条目包含的权限名称
+func (acl *FolderACL) PermissionNames() []string {
+	names := make([]string, 0, len(aclPermissionNames))
+	for i, name := range aclPermissionNames {
+		if acl.Permissions&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Create 创建 ACL 条目
+func (acl *FolderACL) Create() (uint, error) {
+	if err := DB.Create(acl).Error; err != nil {
+		return 0, err
+	}
+
+	return acl.ID, nil
+}
+
+// Matches 条目是否适用于给定用户
+func (acl *FolderACL) Matches(user *User) bool {
+	switch acl.PrincipalType {
+	case ACLPrincipalUser:
+		return acl.PrincipalID == user.ID
+	case ACLPrincipalGroup:
+		return acl.PrincipalID == user.GroupID
+	}
+
+	return false
+}
+
+// ListFolderACLs 列出目录上直接设置的 ACL 条目
+func ListFolderACLs(folderID, uid uint) ([]FolderACL, error) {
+	var acls []FolderACL
+	result := DB.Where("folder_id = ? and owner_id = ?", folderID, uid).Order("id").Find(&acls)
+	return acls, result.Error
+}
+
+// DeleteFolderACL 删除用户目录上的 ACL 条目
+func DeleteFolderACL(id, uid uint) (int64, error) {
+	result := DB.Unscoped().Where("id = ? and owner_id = ?", id, uid).Delete(&FolderACL{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteOrphanFolderACLs 删除目录已被彻底删除的 ACL 条目
+func DeleteOrphanFolderACLs() error {
+	return DB.Unscoped().Where("folder_id not in (?)",
+		DB.Unscoped().Model(&Folder{}).Select("id").QueryExpr()).Delete(&FolderACL{}).Error
+}
+
+// EvaluateFolderACL 评估用户对目录的权限 perm，ids 为目录自身及上级目录的 ID，由近及远排列。
+// 设置了匹配条目的最近一级目录决定结果，同一目录中拒绝优先于允许；没有匹配的条目时 decided 为 false
+func EvaluateFolderACL(ids []uint, user *User, perm int) (allowed, decided bool, err error) {
+	if len(ids) == 0 {
+		return false, false, nil
+	}
+
+	var acls []FolderACL
+	if err := DB.Where("folder_id in (?)", ids).Find(&acls).Error; err != nil {
+		return false, false, err
+	}
+
+	byFolder := make(map[uint][]FolderACL, len(acls))
+	for _, acl := range acls {
+		if acl.Permissions&perm != 0 && acl.Matches(user) {
+			byFolder[acl.FolderID] = append(byFolder[acl.FolderID], acl)
+		}
+	}
+
+	for _, id := range ids {
+		entries, ok := byFolder[id]
+		if !ok {
+			continue
+		}
+
+		allowed := false
+		for _, acl := range entries {
+			if acl.Effect == ACLDeny {
+				return false, true, nil
+			}
+
+			// 允许条目须覆盖全部所需权限
+			if acl.Permissions&perm == perm {
+				allowed = true
+			}
+		}
+
+		return allowed, true, nil
+	}
+
+	return false, false, nil
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseACLPermissions(t *testing.T) {
+	a := assert.New(t)
+
+	perms, err := ParseACLPermissions([]string{"read", "share"})
+	a.NoError(err)
+	a.Equal(ACLRead|ACLShare, perms)
+
+	_, err = ParseACLPermissions([]string{"read", "execute"})
+	a.Error(err)
+
+	acl := &FolderACL{Permissions: ACLWrite | ACLDelete}
+	a.Equal([]string{"write", "delete"}, acl.PermissionNames())
+}
+
+func TestFolderACL_Matches(t *testing.T) {
+	a := assert.New(t)
+	user := &User{GroupID: 3}
+	user.ID = 2
+
+	a.True((&FolderACL{PrincipalType: ACLPrincipalUser, PrincipalID: 2}).Matches(user))
+	a.False((&FolderACL{PrincipalType: ACLPrincipalUser, PrincipalID: 3}).Matches(user))
+	a.True((&FolderACL{PrincipalType: ACLPrincipalGroup, PrincipalID: 3}).Matches(user))
+	a.False((&FolderACL{PrincipalType: "unknown", PrincipalID: 2}).Matches(user))
+}
+
+func TestEvaluateFolderACL(t *testing.T) {
+	a := assert.New(t)
+	user := &User{GroupID: 3}
+	user.ID = 2
+	columns := []string{"id", "folder_id", "principal_type", "principal_id", "effect", "permissions"}
+
+	// 没有上级目录
+	{
+		_, decided, err := EvaluateFolderACL(nil, user, ACLRead)
+		a.NoError(err)
+		a.False(decided)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnError(errors.New("error"))
+		_, _, err := EvaluateFolderACL([]uint{1}, user, ACLRead)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 没有匹配的条目
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, ACLPrincipalUser, 5, ACLDeny, ACLRead).
+			AddRow(2, 1, ACLPrincipalUser, 2, ACLDeny, ACLWrite))
+		_, decided, err := EvaluateFolderACL([]uint{1}, user, ACLRead)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.False(decided)
+	}
+
+	// 最近的目录决定结果
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 2, ACLPrincipalGroup, 3, ACLDeny, ACLAll).
+			AddRow(2, 1, ACLPrincipalUser, 2, ACLAllow, ACLRead|ACLWrite))
+		allowed, decided, err := EvaluateFolderACL([]uint{1, 2}, user, ACLWrite)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.True(decided)
+		a.True(allowed)
+	}
+
+	// 继承上级目录
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 2, ACLPrincipalGroup, 3, ACLDeny, ACLAll))
+		allowed, decided, err := EvaluateFolderACL([]uint{1, 2}, user, ACLWrite)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.True(decided)
+		a.False(allowed)
+	}
+
+	// 同一目录中拒绝优先
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, ACLPrincipalUser, 2, ACLAllow, ACLAll).
+			AddRow(2, 1, ACLPrincipalGroup, 3, ACLDeny, ACLDelete))
+		allowed, decided, err := EvaluateFolderACL([]uint{1}, user, ACLDelete)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.True(decided)
+		a.False(allowed)
+	}
+}
+
+func TestFolderACL_Manage(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)folder_acls(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	id, err := (&FolderACL{FolderID: 1, OwnerID: 1}).Create()
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(3, id)
+
+	mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WithArgs(1, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	acls, err := ListFolderACLs(1, 1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(acls, 1)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)folder_acls(.+)").WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := DeleteFolderACL(3, 1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(1, affected)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)folder_acls(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(DeleteOrphanFolderACLs())
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 用户动态类型
+const (
+	ActivityUpload = "upload"
+	ActivityDelete = "delete"
+	ActivityShare  = "share"
+)
+
+// Activity 用户动态，记录用户发起的上传、删除、分享等操作
+type Activity struct {
+	gorm.Model
+	UserID uint   `gorm:"index:activity_user"`
+	Type   string `gorm:"size:16"`
+	// ObjectType、ObjectID 操作的文件或目录
+	ObjectType string `gorm:"size:16"`
+	ObjectID   uint
+	Name       string
+	Size       uint64
+	// ShareID 创建的分享，仅分享动态有效
+	ShareID uint
+	IP      string `gorm:"size:64"`
+}
+
+// RecordActivities 批量写入用户动态
+func RecordActivities(activities []Activity) error {
+	tx := DB.Begin()
+	for i := range activities {
+		if err := tx.Create(&activities[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// ListActivities 分页列出用户动态，最新的在前，activityType 为空时列出所有类型
+func ListActivities(uid uint, activityType string, page, pageSize int) ([]Activity, int, error) {
+	var (
+		activities []Activity
+		total      int
+	)
+
+	tx := DB.Model(&Activity{}).Where("user_id = ?", uid)
+	if activityType != "" {
+		tx = tx.Where("type = ?", activityType)
+	}
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := tx.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&activities)
+	return activities, total, result.Error
+}
+
+// DeleteActivitiesByUserID 彻底删除用户的所有动态，返回删除的条数
+func DeleteActivitiesByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Activity{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteActivitiesBefore 彻底删除 before 之前的用户动态
+func DeleteActivitiesBefore(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("created_at < ?", before).Delete(&Activity{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordActivities(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		a.NoError(RecordActivities([]Activity{{Type: ActivityUpload}, {Type: ActivityDelete}}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(RecordActivities([]Activity{{Type: ActivityShare}}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListActivities(t *testing.T) {
+	a := assert.New(t)
+
+	// 所有类型
+	{
+		mock.ExpectQuery("SELECT count(.+)activities(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)activities(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(3, ActivityUpload).AddRow(2, ActivityShare))
+		res, total, err := ListActivities(1, "", 1, 2)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(3, total)
+		a.Len(res, 2)
+	}
+
+	// 按类型筛选
+	{
+		mock.ExpectQuery("SELECT count(.+)activities(.+)").
+			WithArgs(1, ActivityDelete).
+			WillReturnError(errors.New("error"))
+		_, _, err := ListActivities(1, ActivityDelete, 1, 2)
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteActivitiesBefore(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	n, err := DeleteActivitiesBefore(time.Now())
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(3, n)
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// APITokenPrefix 访问令牌前缀，用于与其他 Bearer 签名区分
+	APITokenPrefix = "crt_"
+
+	APITokenScopeRead  = "read"
+	APITokenScopeWrite = "write"
+	APITokenScopeShare = "share"
+	APITokenScopeAdmin = "admin"
+
+	// apiTokenTouchInterval 最后使用时间的最小更新间隔
+	apiTokenTouchInterval = time.Minute
+)
+
+// APITokenScopes 所有可用的访问令牌权限范围
+var APITokenScopes = []string{APITokenScopeRead, APITokenScopeWrite, APITokenScopeShare, APITokenScopeAdmin}
+
+// APIToken 用户创建的个人访问令牌，数据库中只保存令牌的摘要
+type APIToken struct {
+	gorm.Model
+	UserID uint   `gorm:"index"`
+	Name   string `gorm:"size:255"`
+	Digest string `gorm:"size:64;unique_index"`
+	// Scopes 以逗号分隔的权限范围
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// GenerateAPIToken 生成新的访问令牌明文
+func GenerateAPIToken() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return APITokenPrefix + hex.EncodeToString(buf), nil
+}
+
+// HashAPIToken 计算访问令牌的摘要
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create 创建访问令牌
+func (token *APIToken) Create() error {
+	return DB.Create(token).Error
+}
+
+// ScopeList 返回令牌的权限范围列表
+func (token *APIToken) ScopeList() []string {
+	if token.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(token.Scopes, ",")
+}
+
+// HasScope 返回令牌是否具有给定权限范围，write 包含 read
+func (token *APIToken) HasScope(scope string) bool {
+	for _, s := range token.ScopeList() {
+		if s == scope || (scope == APITokenScopeRead && s == APITokenScopeWrite) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired 返回令牌是否已过期
+func (token *APIToken) Expired(now time.Time) bool {
+	return token.ExpiresAt != nil && now.After(*token.ExpiresAt)
+}
+
+// Touch 更新最后使用时间，距上次更新不足一分钟时跳过，避免每次请求都写入数据库
+func (token *APIToken) Touch(now time.Time) error {
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < apiTokenTouchInterval {
+		return nil
+	}
+
+	token.LastUsedAt = &now
+	return DB.Model(token).UpdateColumn("last_used_at", now).Error
+}
+
+// GetAPIToken 用令牌明文获取访问令牌
+func GetAPIToken(token string) (APIToken, error) {
+	var res APIToken
+	result := DB.Where("digest = ?", HashAPIToken(token)).First(&res)
+	return res, result.Error
+}
+
+// ListAPITokensByUser 列出用户创建的访问令牌
+func ListAPITokensByUser(uid uint) ([]APIToken, error) {
+	var tokens []APIToken
+	result := DB.Where("user_id = ?", uid).Order("id desc").Find(&tokens)
+	return tokens, result.Error
+}
+
+// CountAPITokensByUser 统计用户创建的访问令牌数量
+func CountAPITokensByUser(uid uint) (int, error) {
+	count := 0
+	result := DB.Model(&APIToken{}).Where("user_id = ?", uid).Count(&count)
+	return count, result.Error
+}
+
+// DeleteAPIToken 删除用户的访问令牌
+func DeleteAPIToken(id, uid uint) (int64, error) {
+	result := DB.Where("id = ? and user_id = ?", id, uid).Delete(&APIToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,106 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIToken_HasScope(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.False((&APIToken{}).HasScope(APITokenScopeRead))
+	asserts.True((&APIToken{Scopes: "read"}).HasScope(APITokenScopeRead))
+	asserts.False((&APIToken{Scopes: "read"}).HasScope(APITokenScopeWrite))
+	asserts.True((&APIToken{Scopes: "write"}).HasScope(APITokenScopeRead))
+	asserts.True((&APIToken{Scopes: "read,share"}).HasScope(APITokenScopeShare))
+	asserts.False((&APIToken{Scopes: "read,share"}).HasScope(APITokenScopeAdmin))
+}
+
+func TestAPIToken_Expired(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	asserts.False((&APIToken{}).Expired(now))
+	asserts.False((&APIToken{ExpiresAt: &future}).Expired(now))
+	asserts.True((&APIToken{ExpiresAt: &past}).Expired(now))
+}
+
+func TestAPIToken_Touch(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	// 间隔内不更新
+	{
+		recent := now.Add(-10 * time.Second)
+		token := &APIToken{Model: gorm.Model{ID: 1}, LastUsedAt: &recent}
+		asserts.NoError(token.Touch(now))
+		asserts.Equal(recent, *token.LastUsedAt)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 数据库错误
+	{
+		token := &APIToken{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)api_tokens(.+)last_used_at(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(token.Touch(now))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		old := now.Add(-time.Hour)
+		token := &APIToken{Model: gorm.Model{ID: 1}, LastUsedAt: &old}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)api_tokens(.+)last_used_at(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(token.Touch(now))
+		asserts.Equal(now, *token.LastUsedAt)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetAPIToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)api_tokens(.+)").
+		WithArgs(HashAPIToken("crt_token")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scopes"}).AddRow(1, 2, "read"))
+	token, err := GetAPIToken("crt_token")
+	asserts.NoError(err)
+	asserts.EqualValues(2, token.UserID)
+	asserts.Equal([]string{"read"}, token.ScopeList())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeleteAPIToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)api_tokens(.+)").WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := DeleteAPIToken(1, 2)
+	asserts.NoError(err)
+	asserts.EqualValues(1, affected)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestGenerateAPIToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	token, err := GenerateAPIToken()
+	asserts.NoError(err)
+	asserts.True(strings.HasPrefix(token, APITokenPrefix))
+	asserts.Len(token, len(APITokenPrefix)+40)
+	asserts.Len(HashAPIToken(token), 64)
+}
@@ -0,0 +1,196 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// 审计事件对象类型
+const (
+	AuditTargetUser         = "user"
+	AuditTargetGroup        = "group"
+	AuditTargetFolder       = "folder"
+	AuditTargetPublicFolder = "public_folder"
+	AuditTargetAutomation   = "automation"
+	AuditTargetSchedule     = "schedule"
+	AuditTargetPolicy       = "policy"
+	AuditTargetFile         = "file"
+	AuditTargetShare        = "share"
+	AuditTargetSetting      = "setting"
+	AuditTargetNode         = "node"
+	AuditTargetTask         = "task"
+	AuditTargetInvite       = "invite"
+	AuditTargetReport       = "report"
+	AuditTargetNotification = "notification"
+)
+
+// ErrAuditLogReadOnly 审计日志写入后不可修改或删除
+var ErrAuditLogReadOnly = errors.New("audit log is append-only")
+
+// auditMask 敏感字段变更时用于替代实际取值
+const auditMask = "******"
+
+// auditMaskedFields 只记录是否变更、不记录取值的敏感字段，按小写的字段名包含的关键字匹配
+var auditMaskedFields = []string{"pass", "secret", "token", "key", "twofactor"}
+
+// auditIgnoredFields 比较变更时忽略的字段，Options、Aria2Options 为对应
+// OptionsSerialized 序列化后的冗余字段
+var auditIgnoredFields = map[string]bool{
+	"UpdatedAt":    true,
+	"Options":      true,
+	"Aria2Options": true,
+}
+
+// AuditLog 审计日志，只追加不修改
+type AuditLog struct {
+	gorm.Model
+	ActorID    uint   `gorm:"index:actor_id"`
+	Action     string `gorm:"size:64;index:action"`
+	TargetType string `gorm:"size:32;index:audit_target"`
+	TargetID   uint   `gorm:"index:audit_target"`
+	Detail     string `gorm:"type:text"`
+	// Diff 对象变更前后的字段差异，JSON 格式的 map[string]AuditChange
+	Diff string `gorm:"type:text"`
+	IP   string `gorm:"size:64"`
+}
+
+// AuditChange 字段变更前后的值，新建对象时 Before 为空，删除对象时 After 为空
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Create 写入审计日志
+func (log *AuditLog) Create() error {
+	return DB.Create(log).Error
+}
+
+// BeforeUpdate 禁止修改审计日志
+func (log *AuditLog) BeforeUpdate() error {
+	return ErrAuditLogReadOnly
+}
+
+// BeforeDelete 禁止删除审计日志
+func (log *AuditLog) BeforeDelete() error {
+	return ErrAuditLogReadOnly
+}
+
+// RecordAudit 记录审计事件，detail 会被序列化为 JSON，写入失败时仅记录警告
+func RecordAudit(actor uint, action, targetType string, targetID uint, detail interface{}) {
+	RecordAuditChange(actor, "", action, targetType, targetID, detail, nil, nil)
+}
+
+// RecordAuditChange 记录包含对象变更前后差异的审计事件，before 或 after 为 nil
+// 时分别视为新建或删除对象
+func RecordAuditChange(actor uint, ip, action, targetType string, targetID uint, detail, before, after interface{}) {
+	log := &AuditLog{
+		ActorID:    actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         ip,
+	}
+
+	if detail != nil {
+		raw, _ := json.Marshal(detail)
+		log.Detail = string(raw)
+	}
+
+	if before != nil || after != nil {
+		if diff := AuditDiff(before, after); len(diff) > 0 {
+			raw, _ := json.Marshal(diff)
+			log.Diff = string(raw)
+		}
+	}
+
+	if err := log.Create(); err != nil {
+		util.Log().Warning("Failed to record audit event %q: %s", action, err)
+	}
+}
+
+// AuditDiff 比较对象 JSON 序列化后的各个字段，返回发生变化的字段，
+// 嵌套对象的字段名以 . 连接
+func AuditDiff(before, after interface{}) map[string]AuditChange {
+	old := make(map[string]interface{})
+	flattenAuditFields("", before, old)
+	current := make(map[string]interface{})
+	flattenAuditFields("", after, current)
+
+	diff := make(map[string]AuditChange)
+	for k, v := range old {
+		if n, ok := current[k]; !ok || !reflect.DeepEqual(v, n) {
+			diff[k] = AuditChange{Before: v, After: current[k]}
+		}
+	}
+
+	for k, v := range current {
+		if _, ok := old[k]; !ok {
+			diff[k] = AuditChange{After: v}
+		}
+	}
+
+	for k, change := range diff {
+		if isAuditMaskedField(k[strings.LastIndex(k, ".")+1:]) {
+			if change.Before != nil {
+				change.Before = auditMask
+			}
+			if change.After != nil {
+				change.After = auditMask
+			}
+			diff[k] = change
+		}
+	}
+
+	return diff
+}
+
+// isAuditMaskedField 判断字段是否为敏感字段
+func isAuditMaskedField(name string) bool {
+	name = strings.ToLower(name)
+	for _, keyword := range auditMaskedFields {
+		if strings.Contains(name, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flattenAuditFields 将对象序列化后的字段展开到 out 中
+func flattenAuditFields(prefix string, v interface{}, out map[string]interface{}) {
+	if v == nil {
+		return
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		// 非对象类型，整体作为一个字段
+		var value interface{}
+		_ = json.Unmarshal(raw, &value)
+		out[strings.TrimSuffix(prefix, ".")] = value
+		return
+	}
+
+	for k, value := range fields {
+		if prefix == "" && auditIgnoredFields[k] {
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenAuditFields(prefix+k+".", nested, out)
+			continue
+		}
+
+		out[prefix+k] = value
+	}
+}
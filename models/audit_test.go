@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAudit(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "account.purge", AuditTargetUser, 2, `{"shares":3}`, "", "").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		RecordAudit(1, "account.purge", AuditTargetUser, 2, map[string]int{"shares": 3})
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		RecordAudit(1, "account.purge", AuditTargetUser, 2, nil)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestRecordAuditChange(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "group.update", AuditTargetGroup, 2, "",
+			`{"Name":{"before":"old","after":"new"}}`, "127.0.0.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	RecordAuditChange(1, "127.0.0.1", "group.update", AuditTargetGroup, 2, nil, Group{Name: "old"}, Group{Name: "new"})
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAuditDiff(t *testing.T) {
+	a := assert.New(t)
+
+	// 修改
+	{
+		before := Policy{Name: "a", SecretKey: "secret1", OptionsSerialized: PolicyOption{Token: "t1", FileType: []string{"jpg"}}}
+		after := Policy{Name: "b", SecretKey: "secret2", OptionsSerialized: PolicyOption{Token: "t1", FileType: []string{"png"}}}
+		diff := AuditDiff(before, after)
+		a.Len(diff, 3)
+		a.Equal(AuditChange{Before: "a", After: "b"}, diff["Name"])
+		a.Equal(AuditChange{Before: auditMask, After: auditMask}, diff["SecretKey"])
+		a.Equal(AuditChange{Before: []interface{}{"jpg"}, After: []interface{}{"png"}}, diff["OptionsSerialized.file_type"])
+	}
+
+	// 新建
+	{
+		diff := AuditDiff(nil, map[string]string{"smtpPass": "123", "siteName": "Cloudreve"})
+		a.Len(diff, 2)
+		a.Equal(AuditChange{After: auditMask}, diff["smtpPass"])
+		a.Equal(AuditChange{After: "Cloudreve"}, diff["siteName"])
+	}
+
+	// 删除
+	{
+		diff := AuditDiff(map[string]interface{}{"id": 1}, nil)
+		a.Equal(AuditChange{Before: float64(1)}, diff["id"])
+	}
+
+	// 无变化
+	{
+		a.Empty(AuditDiff(Group{Name: "a"}, Group{Name: "a"}))
+	}
+}
+
+func TestAuditLog_AppendOnly(t *testing.T) {
+	a := assert.New(t)
+	log := &AuditLog{}
+	log.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	a.ErrorIs(DB.Model(log).Update("action", "changed").Error, ErrAuditLogReadOnly)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	a.ErrorIs(DB.Delete(log).Error, ErrAuditLogReadOnly)
+	a.NoError(mock.ExpectationsWereMet())
+}
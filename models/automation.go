@@ -0,0 +1,116 @@
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 自动化规则触发事件
+const (
+	// AutomationEventUpload 文件上传完成或内容被更新
+	AutomationEventUpload = "upload"
+	// AutomationEventMove 文件被移动或重命名
+	AutomationEventMove = "move"
+	// AutomationEventDelete 文件被删除
+	AutomationEventDelete = "delete"
+)
+
+// 自动化规则动作
+const (
+	AutomationActionRename = "rename"
+	AutomationActionMove   = "move"
+	AutomationActionTag    = "tag"
+	AutomationActionNotify = "notify"
+	AutomationActionReject = "reject"
+	AutomationActionHTTP   = "http"
+)
+
+// Automation 管理员定义的自动化规则，在用户文件发生变化时执行
+type Automation struct {
+	gorm.Model
+	Name    string
+	Enabled bool
+	// Event 触发事件
+	Event string `gorm:"size:16"`
+	// GroupID 适用的用户组，0 表示所有用户组
+	GroupID uint
+	// Priority 执行顺序，越小越先执行
+	Priority int
+	Script   string `gorm:"type:text"`
+
+	// 数据库忽略字段
+	ScriptSerialized AutomationScript `gorm:"-"`
+}
+
+// AutomationScript 自动化规则的匹配条件与动作
+type AutomationScript struct {
+	Match   AutomationMatch    `json:"match"`
+	Actions []AutomationAction `json:"actions"`
+}
+
+// AutomationMatch 匹配条件，为零值的条件不参与匹配
+type AutomationMatch struct {
+	// Name 文件名通配符，不区分大小写
+	Name string `json:"name,omitempty"`
+	// Path 所在目录通配符，匹配所在目录或其任一上级目录，不区分大小写
+	Path string `json:"path,omitempty"`
+	// MinSize 最小文件大小
+	MinSize uint64 `json:"min_size,omitempty"`
+	// MaxSize 最大文件大小
+	MaxSize uint64 `json:"max_size,omitempty"`
+}
+
+// AutomationAction 自动化动作
+type AutomationAction struct {
+	Type string `json:"type"`
+	// Value 动作参数，如新文件名模板、目标目录、标签、通知内容、拒绝原因
+	Value string `json:"value,omitempty"`
+	// URL HTTP 动作的请求地址
+	URL string `json:"url,omitempty"`
+	// Secret HTTP 动作的签名密钥
+	Secret string `json:"secret,omitempty"`
+}
+
+// AfterFind 找到自动化规则后的钩子
+func (automation *Automation) AfterFind() (err error) {
+	if automation.Script != "" {
+		err = json.Unmarshal([]byte(automation.Script), &automation.ScriptSerialized)
+	}
+
+	return err
+}
+
+// BeforeSave 保存自动化规则前的钩子
+func (automation *Automation) BeforeSave() (err error) {
+	scriptValue, err := json.Marshal(&automation.ScriptSerialized)
+	automation.Script = string(scriptValue)
+	return err
+}
+
+// GetAutomationByID 用ID获取自动化规则
+func GetAutomationByID(id uint) (Automation, error) {
+	var automation Automation
+	result := DB.First(&automation, id)
+	return automation, result.Error
+}
+
+// ListAutomations 列出所有自动化规则
+func ListAutomations() ([]Automation, error) {
+	var automations []Automation
+	result := DB.Order("priority asc, id asc").Find(&automations)
+	return automations, result.Error
+}
+
+// GetEnabledAutomations 列出适用于用户组的已启用自动化规则，按执行顺序排列
+func GetEnabledAutomations(groupID uint) ([]Automation, error) {
+	var automations []Automation
+	result := DB.Where("enabled = ? and group_id in (?)", true, []uint{0, groupID}).
+		Order("priority asc, id asc").Find(&automations)
+	return automations, result.Error
+}
+
+// DeleteAutomation 删除自动化规则
+func DeleteAutomation(id uint) error {
+	return DB.Delete(&Automation{}, id).Error
+}
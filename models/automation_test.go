@@ -0,0 +1,57 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnabledAutomations(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 出错
+	{
+		mock.ExpectQuery("SELECT(.+)automations(.+)").WithArgs(true, 0, 2).WillReturnError(errors.New("error"))
+		_, err := GetEnabledAutomations(2)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功，解析规则定义
+	{
+		mock.ExpectQuery("SELECT(.+)automations(.+)").WithArgs(true, 0, 2).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "event", "script"}).
+				AddRow(1, AutomationEventUpload, `{"match":{"name":"*.pdf"},"actions":[{"type":"move","value":"/Finance"}]}`).
+				AddRow(2, AutomationEventDelete, ""),
+		)
+		automations, err := GetEnabledAutomations(2)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(automations, 2)
+		asserts.Equal("*.pdf", automations[0].ScriptSerialized.Match.Name)
+		asserts.Equal(AutomationActionMove, automations[0].ScriptSerialized.Actions[0].Type)
+		asserts.Empty(automations[1].ScriptSerialized.Actions)
+	}
+}
+
+func TestAutomation_BeforeSave(t *testing.T) {
+	asserts := assert.New(t)
+	automation := &Automation{ScriptSerialized: AutomationScript{
+		Actions: []AutomationAction{{Type: AutomationActionTag, Value: "invoice"}},
+	}}
+
+	asserts.NoError(automation.BeforeSave())
+	asserts.Equal(`{"match":{},"actions":[{"type":"tag","value":"invoice"}]}`, automation.Script)
+}
+
+func TestDeleteAutomation(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)automations(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteAutomation(1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// 变更类型
+const (
+	ChangeCreate = "create"
+	ChangeModify = "modify"
+	ChangeDelete = "delete"
+	ChangeMove   = "move"
+)
+
+// 变更对象类型
+const (
+	ChangeObjectFile   = "file"
+	ChangeObjectFolder = "folder"
+)
+
+// Change 用户空间内对象的变更日志，供同步客户端增量拉取
+type Change struct {
+	gorm.Model
+	UserID     uint   `gorm:"index:change_user"`
+	Type       string `gorm:"size:16"`
+	ObjectType string `gorm:"size:16"`
+	ObjectID   uint
+	Name       string
+	ParentID   uint
+	// 移动、重命名前的父目录及名称
+	OldParentID uint
+	OldName     string
+	Size        uint64
+
+	// Placeholder 是否为上传会话创建的占位文件，仅在变更钩子中使用
+	Placeholder bool `gorm:"-"`
+}
+
+// RecordChanges 批量写入变更记录
+func RecordChanges(changes []Change) error {
+	tx := DB.Begin()
+	for i := range changes {
+		if err := tx.Create(&changes[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// ListChanges 列出用户在 cursor 之后的变更记录，按发生顺序排列
+func ListChanges(uid, cursor uint, limit int) ([]Change, error) {
+	var changes []Change
+	result := DB.Where("user_id = ? AND id > ?", uid, cursor).Order("id asc").Limit(limit).Find(&changes)
+	return changes, result.Error
+}
+
+// DeleteChangesByUserID 彻底删除用户的所有变更记录，返回删除的条数
+func DeleteChangesByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Change{})
+	return result.RowsAffected, result.Error
+}
+
+// GetLatestChangeID 返回用户最新一条变更记录的ID，没有记录时返回0
+func GetLatestChangeID(uid uint) uint {
+	var change Change
+	if err := DB.Where("user_id = ?", uid).Order("id desc").First(&change).Error; err != nil {
+		return 0
+	}
+
+	return change.ID
+}
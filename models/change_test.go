@@ -0,0 +1,76 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordChanges(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		a.NoError(RecordChanges([]Change{{Type: ChangeCreate}, {Type: ChangeDelete}}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(RecordChanges([]Change{{Type: ChangeCreate}}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListChanges(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)changes(.+)").
+		WithArgs(1, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(11, ChangeCreate).AddRow(12, ChangeMove))
+	res, err := ListChanges(1, 10, 2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(res, 2)
+	a.EqualValues(12, res[1].ID)
+}
+
+func TestGetLatestChangeID(t *testing.T) {
+	a := assert.New(t)
+
+	// 无记录
+	{
+		mock.ExpectQuery("SELECT(.+)changes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		a.EqualValues(0, GetLatestChangeID(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 有记录
+	{
+		mock.ExpectQuery("SELECT(.+)changes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		a.EqualValues(5, GetLatestChangeID(1))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteChangesByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)changes(.+)").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	n, err := DeleteChangesByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, n)
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 协作者对共享目录的权限
+const (
+	CollaborationRead  = "read"
+	CollaborationWrite = "write"
+)
+
+// Collaboration 将目录直接共享给其他注册用户的记录
+type Collaboration struct {
+	gorm.Model
+	FolderID   uint   `gorm:"unique_index:folder_user"`
+	OwnerID    uint   `gorm:"index:collaboration_owner"`
+	UserID     uint   `gorm:"unique_index:folder_user;index:collaboration_user"`
+	Permission string `gorm:"size:16"`
+
+	// 数据库忽略字段
+	Folder *Folder `gorm:"-"`
+	Owner  *User   `gorm:"-"`
+	User   *User   `gorm:"-"`
+	// MountName 在「与我共享」虚拟目录下的名称
+	MountName string `gorm:"-"`
+}
+
+// Create 创建共享记录，同一目录已共享给该用户时更新权限
+func (collab *Collaboration) Create() error {
+	var existed Collaboration
+	err := DB.Where("folder_id = ? and user_id = ?", collab.FolderID, collab.UserID).First(&existed).Error
+	if err == nil {
+		collab.ID = existed.ID
+		return DB.Model(&existed).Update("permission", collab.Permission).Error
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	return DB.Create(collab).Error
+}
+
+// Writable 协作者是否可以修改共享目录中的内容
+func (collab *Collaboration) Writable() bool {
+	return collab.Permission == CollaborationWrite
+}
+
+// Permissions 没有匹配的目录 ACL 时协作者拥有的权限
+func (collab *Collaboration) Permissions() int {
+	if collab.Writable() {
+		return ACLRead | ACLWrite | ACLDelete
+	}
+
+	return ACLRead
+}
+
+// ListCollaborationsByOwner 列出用户共享出去的目录
+func ListCollaborationsByOwner(uid uint) ([]Collaboration, error) {
+	var collabs []Collaboration
+	result := DB.Where("owner_id = ?", uid).Order("id").Find(&collabs)
+	return collabs, result.Error
+}
+
+// ListCollaborationsByFolder 列出目录的所有协作者
+func ListCollaborationsByFolder(folderID, uid uint) ([]Collaboration, error) {
+	var collabs []Collaboration
+	result := DB.Where("folder_id = ? and owner_id = ?", folderID, uid).Order("id").Find(&collabs)
+	return collabs, result.Error
+}
+
+// ListSharedWithUser 列出共享给用户且仍可访问的目录，并为其分配不重复的挂载名称
+func ListSharedWithUser(uid uint) ([]Collaboration, error) {
+	var collabs []Collaboration
+	if err := DB.Where("user_id = ?", uid).Order("id").Find(&collabs).Error; err != nil {
+		return nil, err
+	}
+
+	res := make([]Collaboration, 0, len(collabs))
+	names := make(map[string]bool, len(collabs))
+	for _, collab := range collabs {
+		owner, err := GetActiveUserByID(collab.OwnerID)
+		if err != nil {
+			continue
+		}
+
+		var folder Folder
+		if err := DB.Where("id = ? and owner_id = ?", collab.FolderID, collab.OwnerID).First(&folder).Error; err != nil {
+			continue
+		}
+
+		// 共享根目录时以所有者昵称命名
+		name := folder.Name
+		if folder.ParentID == nil {
+			name = owner.Nick
+		}
+
+		collab.MountName = name
+		for i := 2; names[collab.MountName]; i++ {
+			collab.MountName = fmt.Sprintf("%s (%d)", name, i)
+		}
+
+		names[collab.MountName] = true
+		collab.Owner = &owner
+		collab.Folder = &folder
+		res = append(res, collab)
+	}
+
+	return res, nil
+}
+
+// DeleteCollaboration 删除共享记录，仅所有者或协作者本人可以删除
+func DeleteCollaboration(id, uid uint) (int64, error) {
+	result := DB.Unscoped().Where("id = ? and (owner_id = ? or user_id = ?)", id, uid, uid).Delete(&Collaboration{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteOrphanCollaborations 删除目录已被彻底删除的共享记录
+func DeleteOrphanCollaborations() error {
+	return DB.Unscoped().Where("folder_id not in (?)",
+		DB.Unscoped().Model(&Folder{}).Select("id").QueryExpr()).Delete(&Collaboration{}).Error
+}
@@ -0,0 +1,137 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollaboration_Create(t *testing.T) {
+	a := assert.New(t)
+
+	// 新建
+	{
+		collab := &Collaboration{FolderID: 1, OwnerID: 1, UserID: 2, Permission: CollaborationRead}
+		mock.ExpectQuery("SELECT(.+)collaborations(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)collaborations(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+		a.NoError(collab.Create())
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(3, collab.ID)
+	}
+
+	// 已存在，更新权限
+	{
+		collab := &Collaboration{FolderID: 1, OwnerID: 1, UserID: 2, Permission: CollaborationWrite}
+		mock.ExpectQuery("SELECT(.+)collaborations(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "permission"}).AddRow(3, CollaborationRead))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)collaborations(.+)permission(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(collab.Create())
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(3, collab.ID)
+	}
+
+	// 查询失败
+	{
+		collab := &Collaboration{FolderID: 1, UserID: 2}
+		mock.ExpectQuery("SELECT(.+)collaborations(.+)").WillReturnError(errors.New("error"))
+		a.Error(collab.Create())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCollaboration_Writable(t *testing.T) {
+	a := assert.New(t)
+	a.False((&Collaboration{Permission: CollaborationRead}).Writable())
+	a.True((&Collaboration{Permission: CollaborationWrite}).Writable())
+	a.Equal(ACLRead, (&Collaboration{Permission: CollaborationRead}).Permissions())
+	a.Equal(ACLRead|ACLWrite|ACLDelete, (&Collaboration{Permission: CollaborationWrite}).Permissions())
+}
+
+func TestListCollaborations(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)collaborations(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	collabs, err := ListCollaborationsByOwner(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(collabs, 1)
+
+	mock.ExpectQuery("SELECT(.+)collaborations(.+)").WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	collabs, err = ListCollaborationsByFolder(2, 1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(collabs, 1)
+}
+
+func TestListSharedWithUser(t *testing.T) {
+	a := assert.New(t)
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)collaborations(.+)").WillReturnError(errors.New("error"))
+		_, err := ListSharedWithUser(2)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 同名目录、根目录、所有者不可用
+	{
+		mock.ExpectQuery("SELECT(.+)collaborations(.+)").WithArgs(2).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "folder_id", "owner_id", "permission"}).
+				AddRow(1, 10, 1, CollaborationRead).
+				AddRow(2, 20, 3, CollaborationWrite).
+				AddRow(3, 30, 4, CollaborationRead).
+				AddRow(4, 40, 5, CollaborationRead),
+		)
+		// 第一条
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "nick"}).AddRow(1, "a"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(10, "Docs", 1))
+		// 第二条，同名
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "nick"}).AddRow(3, "b"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(20, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(20, "Docs", 2))
+		// 第三条，根目录
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "nick"}).AddRow(4, "c"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(30, 4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(30, "/", nil))
+		// 第四条，所有者不可用
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		collabs, err := ListSharedWithUser(2)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Len(collabs, 3)
+		a.Equal("Docs", collabs[0].MountName)
+		a.Equal("Docs (2)", collabs[1].MountName)
+		a.Equal("c", collabs[2].MountName)
+		a.EqualValues(3, collabs[1].Owner.ID)
+		a.EqualValues(20, collabs[1].Folder.ID)
+	}
+}
+
+func TestDeleteCollaboration(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)collaborations(.+)").WithArgs(1, 2, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := DeleteCollaboration(1, 2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(1, affected)
+}
+
+func TestDeleteOrphanCollaborations(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)collaborations(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(DeleteOrphanCollaborations())
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -12,6 +12,16 @@ var defaultSettings = []Setting{
 	{Name: "siteName", Value: `Cloudreve`, Type: "basic"},
 	{Name: "register_enabled", Value: `1`, Type: "register"},
 	{Name: "default_group", Value: `2`, Type: "register"},
+	{Name: "register_invite_only", Value: `0`, Type: "register"},
+	{Name: "register_email_domain_allow", Value: ``, Type: "register"},
+	{Name: "register_email_domain_deny", Value: ``, Type: "register"},
+	{Name: "invite_user_enabled", Value: `0`, Type: "register"},
+	{Name: "invite_user_max", Value: `5`, Type: "register"},
+	{Name: "invite_user_expires", Value: `604800`, Type: "register"},
+	{Name: "scim_token", Value: ``, Type: "scim"},
+	{Name: "scim_group_mapping", Value: `[]`, Type: "scim"},
+	{Name: "directory_group_rules", Value: `[]`, Type: "directory"},
+	{Name: "directory_default_group", Value: `0`, Type: "directory"},
 	{Name: "siteKeywords", Value: `Cloudreve, cloud storage`, Type: "basic"},
 	{Name: "siteDes", Value: `Cloudreve`, Type: "basic"},
 	{Name: "siteTitle", Value: `Inclusive cloud storage for everyone`, Type: "basic"},
@@ -27,16 +37,31 @@ var defaultSettings = []Setting{
 	{Name: "smtpPass", Value: ``, Type: "mail"},
 	{Name: "smtpEncryption", Value: `0`, Type: "mail"},
 	{Name: "maxEditSize", Value: `52428800`, Type: "file_edit"},
+	{Name: "caldav_root", Value: `/Calendars`, Type: "dav"},
+	{Name: "carddav_root", Value: `/Contacts`, Type: "dav"},
+	{Name: "ftp_passive_ports", Value: `50000-50100`, Type: "ftp"},
+	{Name: "ftp_public_host", Value: ``, Type: "ftp"},
 	{Name: "archive_timeout", Value: `600`, Type: "timeout"},
 	{Name: "download_timeout", Value: `600`, Type: "timeout"},
+	{Name: "download_max_timeout", Value: `86400`, Type: "timeout"},
+	{Name: "download_bind_ip", Value: `0`, Type: "download"},
+	{Name: "image_process_enabled", Value: `0`, Type: "image_process"},
+	{Name: "image_process_max_size", Value: `4096`, Type: "image_process"},
+	{Name: "image_process_max_pixels", Value: `40000000`, Type: "image_process"},
+	{Name: "image_process_max_file_size", Value: `52428800`, Type: "image_process"},
+	{Name: "image_process_cache_ttl", Value: `604800`, Type: "image_process"},
 	{Name: "preview_timeout", Value: `600`, Type: "timeout"},
 	{Name: "doc_preview_timeout", Value: `600`, Type: "timeout"},
 	{Name: "upload_session_timeout", Value: `86400`, Type: "timeout"},
+	{Name: "idempotency_ttl", Value: `86400`, Type: "timeout"},
 	{Name: "slave_api_timeout", Value: `60`, Type: "timeout"},
 	{Name: "slave_node_retry", Value: `3`, Type: "slave"},
 	{Name: "slave_ping_interval", Value: `60`, Type: "slave"},
 	{Name: "slave_recover_interval", Value: `120`, Type: "slave"},
 	{Name: "slave_transfer_timeout", Value: `172800`, Type: "timeout"},
+	{Name: "aria2_balancer", Value: `RoundRobin`, Type: "aria2"},
+	{Name: "aria2_node_retry", Value: `3`, Type: "aria2"},
+	{Name: "aria2_recover_interval", Value: `600`, Type: "aria2"},
 	{Name: "onedrive_monitor_timeout", Value: `600`, Type: "timeout"},
 	{Name: "share_download_session_timeout", Value: `2073600`, Type: "timeout"},
 	{Name: "onedrive_callback_check", Value: `20`, Type: "timeout"},
@@ -45,6 +70,10 @@ var defaultSettings = []Setting{
 	{Name: "onedrive_source_timeout", Value: `1800`, Type: "timeout"},
 	{Name: "reset_after_upload_failed", Value: `0`, Type: "upload"},
 	{Name: "use_temp_chunk_buffer", Value: `1`, Type: "upload"},
+	{Name: "instant_upload_cross_user", Value: `0`, Type: "upload"},
+	{Name: "manifest_max_entries", Value: `100000`, Type: "upload"},
+	{Name: "proxy_parallel_connections", Value: `1`, Type: "download"},
+	{Name: "proxy_parallel_chunk_size", Value: `4194304`, Type: "download"},
 	{Name: "login_captcha", Value: `0`, Type: "login"},
 	{Name: "reg_captcha", Value: `0`, Type: "login"},
 	{Name: "email_active", Value: `0`, Type: "register"},
@@ -69,9 +98,18 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "defaultTheme", Value: `#3f51b5`, Type: "basic"},
 	{Name: "themes", Value: `{"#3f51b5":{"palette":{"primary":{"main":"#3f51b5"},"secondary":{"main":"#f50057"}}},"#2196f3":{"palette":{"primary":{"main":"#2196f3"},"secondary":{"main":"#FFC107"}}},"#673AB7":{"palette":{"primary":{"main":"#673AB7"},"secondary":{"main":"#2196F3"}}},"#E91E63":{"palette":{"primary":{"main":"#E91E63"},"secondary":{"main":"#42A5F5","contrastText":"#fff"}}},"#FF5722":{"palette":{"primary":{"main":"#FF5722"},"secondary":{"main":"#3F51B5"}}},"#FFC107":{"palette":{"primary":{"main":"#FFC107"},"secondary":{"main":"#26C6DA"}}},"#8BC34A":{"palette":{"primary":{"main":"#8BC34A","contrastText":"#fff"},"secondary":{"main":"#FF8A65","contrastText":"#fff"}}},"#009688":{"palette":{"primary":{"main":"#009688"},"secondary":{"main":"#4DD0E1","contrastText":"#fff"}}},"#607D8B":{"palette":{"primary":{"main":"#607D8B"},"secondary":{"main":"#F06292"}}},"#795548":{"palette":{"primary":{"main":"#795548"},"secondary":{"main":"#4CAF50","contrastText":"#fff"}}}}`, Type: "basic"},
 	{Name: "max_worker_num", Value: `10`, Type: "task"},
+	{Name: "task_max_retry", Value: `3`, Type: "task"},
+	{Name: "task_worker_types", Value: ``, Type: "task"},
 	{Name: "max_parallel_transfer", Value: `4`, Type: "task"},
+	{Name: "export_require_approval", Value: `0`, Type: "task"},
+	{Name: "export_part_size", Value: `1073741824`, Type: "task"},
+	{Name: "export_expires", Value: `604800`, Type: "timeout"},
+	{Name: "account_deletion_grace", Value: `604800`, Type: "timeout"},
+	{Name: "fetch_allow_private", Value: `0`, Type: "task"},
+	{Name: "fetch_timeout", Value: `3600`, Type: "timeout"},
 	{Name: "secret_key", Value: util.RandStringRunes(256), Type: "auth"},
 	{Name: "temp_path", Value: "temp", Type: "path"},
+	{Name: "driver_plugin_path", Value: "plugins", Type: "path"},
 	{Name: "avatar_path", Value: "avatar", Type: "path"},
 	{Name: "avatar_size", Value: "2097152", Type: "avatar"},
 	{Name: "avatar_size_l", Value: "200", Type: "avatar"},
@@ -81,7 +119,48 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "share_view_method", Value: "list", Type: "view"},
 	{Name: "cron_garbage_collect", Value: "@hourly", Type: "cron"},
 	{Name: "cron_recycle_upload_session", Value: "@every 1h30m", Type: "cron"},
+	{Name: "cron_collect_stats", Value: "@hourly", Type: "cron"},
+	{Name: "cron_stats_report", Value: "0 8 * * 1", Type: "cron"},
+	{Name: "cron_directory_sync", Value: "0 3 * * *", Type: "cron"},
+	{Name: "cron_trash_purge", Value: "@every 1h", Type: "cron"},
+	{Name: "cron_policy_health_check", Value: "@every 5m", Type: "cron"},
+	{Name: "cron_webhook_retry", Value: "@every 1m", Type: "cron"},
+	{Name: "cron_orphan_cleanup", Value: "@daily", Type: "cron"},
+	{Name: "cron_thumb_regenerate", Value: "", Type: "cron"},
+	{Name: "policy_health_check_timeout", Value: "10", Type: "timeout"},
+	{Name: "policy_health_failure_threshold", Value: "3", Type: "policy"},
+	{Name: "stats_report_to", Value: "", Type: "mail"},
+	{Name: "mail_provider", Value: "smtp", Type: "mail"},
+	{Name: "mail_sendgrid_key", Value: "", Type: "mail"},
+	{Name: "mail_mailgun_endpoint", Value: "https://api.mailgun.net", Type: "mail"},
+	{Name: "mail_mailgun_domain", Value: "", Type: "mail"},
+	{Name: "mail_mailgun_key", Value: "", Type: "mail"},
+	{Name: "mail_max_attempts", Value: "3", Type: "mail"},
+	{Name: "mail_retry_interval", Value: "30", Type: "mail"},
+	{Name: "notify_share_interval", Value: "3600", Type: "notification"},
+	{Name: "notify_quota_threshold", Value: "90", Type: "notification"},
+	{Name: "notify_quota_interval", Value: "86400", Type: "notification"},
+	{Name: "mail_share_accessed_template", Value: `<p>{userName}，您好：</p><p>您的分享 <b>{shareName}</b> 于 {time} 被 {visitor} 访问。</p><p>如不希望继续收到此类通知，可前往 <a href="{siteUrl}">{siteTitle}</a> 的个人设置中关闭。</p>`, Type: "mail_template"},
+	{Name: "mail_task_finished_template", Value: `<p>{userName}，您好：</p><p>您创建的{taskType}任务已{status}。</p><p>{detail}</p><p>可前往 <a href="{siteUrl}">{siteTitle}</a> 查看任务详情。</p>`, Type: "mail_template"},
+	{Name: "mail_quota_almost_full_template", Value: `<p>{userName}，您好：</p><p>您的存储空间已使用 {percent}%（{used} / {total}），空间用尽后将无法继续上传文件。</p><p>请前往 <a href="{siteUrl}">{siteTitle}</a> 清理文件或扩充容量。</p>`, Type: "mail_template"},
 	{Name: "authn_enabled", Value: "0", Type: "authn"},
+	{Name: "oidc_enabled", Value: "0", Type: "oidc"},
+	{Name: "oidc_display_name", Value: "SSO", Type: "oidc"},
+	{Name: "oidc_issuer", Value: "", Type: "oidc"},
+	{Name: "oidc_client_id", Value: "", Type: "oidc"},
+	{Name: "oidc_client_secret", Value: "", Type: "oidc"},
+	{Name: "oidc_scopes", Value: "openid email profile", Type: "oidc"},
+	{Name: "oidc_email_claim", Value: "email", Type: "oidc"},
+	{Name: "oidc_nick_claim", Value: "name", Type: "oidc"},
+	{Name: "oidc_auto_register", Value: "1", Type: "oidc"},
+	{Name: "oidc_default_group", Value: "0", Type: "oidc"},
+	{Name: "api_token_max", Value: "10", Type: "api_token"},
+	{Name: "webhook_user_enabled", Value: "0", Type: "webhook"},
+	{Name: "e2ee_enabled", Value: "0", Type: "e2ee"},
+	{Name: "webhook_user_max", Value: "10", Type: "webhook"},
+	{Name: "webhook_max_attempts", Value: "5", Type: "webhook"},
+	{Name: "webhook_retry_interval", Value: "30", Type: "webhook"},
+	{Name: "webhook_log_retention", Value: "604800", Type: "webhook"},
 	{Name: "captcha_type", Value: "normal", Type: "captcha"},
 	{Name: "captcha_height", Value: "60", Type: "captcha"},
 	{Name: "captcha_width", Value: "240", Type: "captcha"},
@@ -114,13 +193,43 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "thumb_vips_exts", Value: "csv,mat,img,hdr,pbm,pgm,ppm,pfm,pnm,svg,svgz,j2k,jp2,jpt,j2c,jpc,gif,png,jpg,jpeg,jpe,webp,tif,tiff,fits,fit,fts,exr,jxl,pdf,heic,heif,avif,svs,vms,vmu,ndpi,scn,mrxs,svslide,bif,raw", Type: "thumb"},
 	{Name: "thumb_ffmpeg_seek", Value: "00:00:01.00", Type: "thumb"},
 	{Name: "thumb_ffmpeg_path", Value: "ffmpeg", Type: "thumb"},
+	{Name: "thumb_ffmpeg_max_task_count", Value: "1", Type: "thumb"},
 	{Name: "thumb_ffmpeg_exts", Value: "3g2,3gp,asf,asx,avi,divx,flv,m2ts,m2v,m4v,mkv,mov,mp4,mpeg,mpg,mts,mxf,ogv,rm,swf,webm,wmv", Type: "thumb"},
 	{Name: "thumb_libreoffice_path", Value: "soffice", Type: "thumb"},
 	{Name: "thumb_libreoffice_enabled", Value: "0", Type: "thumb"},
 	{Name: "thumb_libreoffice_exts", Value: "md,ods,ots,fods,uos,xlsx,xml,xls,xlt,dif,dbf,html,slk,csv,xlsm,docx,dotx,doc,dot,rtf,xlsm,xlst,xls,xlw,xlc,xlt,pptx,ppsx,potx,pomx,ppt,pps,ppm,pot,pom", Type: "thumb"},
+	{Name: "thumb_poppler_path", Value: "pdftoppm", Type: "thumb"},
+	{Name: "thumb_poppler_enabled", Value: "0", Type: "thumb"},
+	{Name: "thumb_poppler_exts", Value: "pdf", Type: "thumb"},
+	{Name: "thumb_music_cover_enabled", Value: "1", Type: "thumb"},
+	{Name: "thumb_music_cover_exts", Value: "mp3,flac", Type: "thumb"},
+	{Name: "hls_enabled", Value: "0", Type: "hls"},
+	{Name: "hls_exts", Value: "avi,flv,m4v,mkv,mov,mp4,mpeg,mpg,ts,webm,wmv", Type: "hls"},
+	{Name: "hls_segment_time", Value: "6", Type: "hls"},
+	{Name: "hls_ffmpeg_args", Value: "-c:v libx264 -preset veryfast -crf 23 -c:a aac -b:a 128k", Type: "hls"},
 	{Name: "thumb_proxy_enabled", Value: "0", Type: "thumb"},
 	{Name: "thumb_proxy_policy", Value: "[]", Type: "thumb"},
 	{Name: "thumb_max_src_size", Value: "31457280", Type: "thumb"},
+	{Name: "media_meta_enabled", Value: "0", Type: "media_meta"},
+	{Name: "media_meta_ffprobe_path", Value: "ffprobe", Type: "media_meta"},
+	{Name: "media_meta_exts", Value: "3g2,3gp,asf,asx,avi,divx,flv,m2ts,m2v,m4v,mkv,mov,mp4,mpeg,mpg,mts,mxf,ogv,rm,swf,webm,wmv", Type: "media_meta"},
+	{Name: "media_meta_timeout", Value: "60", Type: "media_meta"},
+	{Name: "exif_enabled", Value: "1", Type: "exif"},
+	{Name: "exif_exts", Value: "jpg,jpeg,tif,tiff", Type: "exif"},
+	{Name: "music_meta_enabled", Value: "1", Type: "music_meta"},
+	{Name: "music_meta_exts", Value: "mp3,flac", Type: "music_meta"},
+	{Name: "torrent_trackers", Value: "", Type: "torrent"},
+	{Name: "rate_limit_auth", Value: "20", Type: "ratelimit"},
+	{Name: "rate_limit_anonymous", Value: "0", Type: "ratelimit"},
+	{Name: "rate_limit_guest", Value: "60", Type: "ratelimit"},
+	{Name: "share_password_rate_limit", Value: "10", Type: "share"},
+	{Name: "share_password_max_attempts", Value: "5", Type: "share"},
+	{Name: "share_password_lock_duration", Value: "900", Type: "share"},
+	{Name: "share_access_log_keep_days", Value: "90", Type: "share"},
+	{Name: "team_max_per_user", Value: "3", Type: "team"},
+	{Name: "team_default_storage", Value: "1073741824", Type: "team"},
+	{Name: "static_site_enabled", Value: "0", Type: "static_site"},
+	{Name: "static_site_cache_max_age", Value: "600", Type: "static_site"},
 	{Name: "pwa_small_icon", Value: "/static/img/favicon.ico", Type: "pwa"},
 	{Name: "pwa_medium_icon", Value: "/static/img/logo192.png", Type: "pwa"},
 	{Name: "pwa_large_icon", Value: "/static/img/logo512.png", Type: "pwa"},
@@ -128,12 +237,21 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "pwa_theme_color", Value: "#000000", Type: "pwa"},
 	{Name: "pwa_background_color", Value: "#ffffff", Type: "pwa"},
 	{Name: "office_preview_service", Value: "https://view.officeapps.live.com/op/view.aspx?src={$src}", Type: "preview"},
+	{Name: "preview_handlers", Value: `[{"exts":["jpg","jpeg","png","gif","bmp","webp","svg","ico","avif"],"mimes":["image/*"],"strategy":"inline"},{"exts":["mp4","mkv","webm","avi","mov","m3u8","flv"],"mimes":["video/*"],"strategy":"inline"},{"exts":["mp3","flac","ape","wav","aac","ogg","m4a"],"mimes":["audio/*"],"strategy":"inline"},{"exts":["pdf"],"strategy":"inline"},{"exts":["md","markdown"],"strategy":"markdown"},{"exts":["epub"],"strategy":"epub"},{"exts":["py"],"strategy":"code","language":"python"},{"exts":["js","jsx","mjs"],"strategy":"code","language":"javascript"},{"exts":["ts","tsx"],"strategy":"code","language":"typescript"},{"exts":["sh","bash"],"strategy":"code","language":"shell"},{"exts":["bat","cmd"],"strategy":"code","language":"bat"},{"exts":["c","h"],"strategy":"code","language":"c"},{"exts":["cpp","hpp","cc"],"strategy":"code","language":"cpp"},{"exts":["cs"],"strategy":"code","language":"csharp"},{"exts":["html","htm"],"strategy":"code","language":"html"},{"exts":["yaml","yml"],"strategy":"code","language":"yaml"},{"exts":["go","java","php","css","less","lua","sql","xml","json","ini","rs","kt","swift","rb","dockerfile"],"strategy":"code"},{"exts":["txt","log","conf"],"mimes":["text/*"],"strategy":"text"}]`, Type: "preview"},
 	{Name: "show_app_promotion", Value: "1", Type: "mobile"},
 	{Name: "public_resource_maxage", Value: "86400", Type: "timeout"},
 	{Name: "wopi_enabled", Value: "0", Type: "wopi"},
 	{Name: "wopi_endpoint", Value: "", Type: "wopi"},
 	{Name: "wopi_max_size", Value: "52428800", Type: "wopi"},
 	{Name: "wopi_session_timeout", Value: "36000", Type: "wopi"},
+	{Name: "abuse_report_enabled", Value: "1", Type: "share"},
+	{Name: "antivirus_clamd_address", Value: "tcp://127.0.0.1:3310", Type: "antivirus"},
+	{Name: "antivirus_timeout", Value: "60", Type: "antivirus"},
+	{Name: "antivirus_max_size", Value: "26214400", Type: "antivirus"},
+	{Name: "antivirus_action", Value: "reject", Type: "antivirus"},
+	{Name: "activity_retention", Value: "7776000", Type: "timeout"},
+	{Name: "notification_retention", Value: "7776000", Type: "timeout"},
+	{Name: "login_session_timeout", Value: "5184000", Type: "timeout"},
 }
 
 func InitSlaveDefaults() {
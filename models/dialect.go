@@ -0,0 +1,115 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// defaultMySQLPort 配置文件中数据库端口的默认值
+	defaultMySQLPort = 3306
+
+	// defaultPostgresPort PostgreSQL 的默认端口
+	defaultPostgresPort = 5432
+)
+
+// isPostgres 返回当前数据库是否为 PostgreSQL
+func isPostgres() bool {
+	return DB != nil && DB.Dialect().GetName() == "postgres"
+}
+
+// LikeOperator 返回不区分大小写的模糊匹配运算符。MySQL 的默认排序规则及 SQLite 的
+// LIKE 均不区分大小写，PostgreSQL 的 LIKE 区分大小写，需使用 ILIKE
+func LikeOperator() string {
+	if isPostgres() {
+		return "ILIKE"
+	}
+
+	return "LIKE"
+}
+
+// Like 返回字段不区分大小写模糊匹配的查询条件
+func Like(column string) string {
+	return column + " " + LikeOperator() + " ?"
+}
+
+// EqualFold 返回字段与参数不区分大小写相等的查询条件，用于邮箱等不区分大小写的字段
+func EqualFold(column string) string {
+	if isPostgres() {
+		return "LOWER(" + column + ") = LOWER(?)"
+	}
+
+	return column + " = ?"
+}
+
+// postgresDSN 根据配置生成 PostgreSQL 连接字符串
+func postgresDSN() string {
+	port := conf.DatabaseConfig.Port
+	if port == defaultMySQLPort {
+		// 未指定端口时，配置中的默认值为 MySQL 端口
+		port = defaultPostgresPort
+	}
+
+	sslMode := conf.DatabaseConfig.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	params := [][2]string{
+		{"host", conf.DatabaseConfig.Host},
+		{"port", fmt.Sprint(port)},
+		{"user", conf.DatabaseConfig.User},
+		{"password", conf.DatabaseConfig.Password},
+		{"dbname", conf.DatabaseConfig.Name},
+		{"sslmode", sslMode},
+	}
+
+	dsn := make([]string, 0, len(params))
+	for _, param := range params {
+		if param[1] == "" {
+			continue
+		}
+
+		dsn = append(dsn, param[0]+"="+quotePostgresParam(param[1]))
+	}
+
+	return strings.Join(dsn, " ")
+}
+
+// quotePostgresParam 转义连接字符串中的参数值，包含空格或引号的值需加引号
+func quotePostgresParam(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// legacyIndexes 早期版本中与其他数据表重名的索引。PostgreSQL 及 SQLite 中索引名在
+// 整个库内唯一，重名的索引无法创建，现已更名
+var legacyIndexes = map[interface{}][]string{
+	&FolderACL{}:     {"folder_id", "owner_id"},
+	&Change{}:        {"user_id"},
+	&Collaboration{}: {"owner_id", "user_id"},
+	&Team{}:          {"user_id", "owner_id"},
+	&TeamMember{}:    {"user_id"},
+}
+
+// dropLegacyIndexes 删除已更名的旧索引
+func dropLegacyIndexes() {
+	for model, indexes := range legacyIndexes {
+		tableName := DB.NewScope(model).TableName()
+		for _, index := range indexes {
+			if !DB.Dialect().HasIndex(tableName, index) {
+				continue
+			}
+
+			if err := DB.Model(model).RemoveIndex(index).Error; err != nil {
+				util.Log().Warning("Failed to drop legacy index %q of table %q: %s", index, tableName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// usePostgresMock 使用 PostgreSQL 方言的数据库 Mock
+func usePostgresMock(t *testing.T) (sqlmock.Sqlmock, func()) {
+	db, pgMock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DB, _ = gorm.Open("postgres", db)
+	return pgMock, func() {
+		db.Close()
+		DB = mockDB
+	}
+}
+
+func TestLike(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("LIKE", LikeOperator())
+	asserts.Equal("name LIKE ?", Like("name"))
+	asserts.Equal("email = ?", EqualFold("email"))
+
+	_, reset := usePostgresMock(t)
+	defer reset()
+	asserts.Equal("ILIKE", LikeOperator())
+	asserts.Equal("name ILIKE ?", Like("name"))
+	asserts.Equal("LOWER(email) = LOWER(?)", EqualFold("email"))
+}
+
+func TestGetUserByEmail_Postgres(t *testing.T) {
+	asserts := assert.New(t)
+	pgMock, reset := usePostgresMock(t)
+	defer reset()
+
+	pgMock.ExpectQuery(`SELECT(.+)users(.+)LOWER\(email\) = LOWER\(\$1\)`).
+		WithArgs("Admin@cloudreve.org").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "admin@cloudreve.org"))
+	user, err := GetUserByEmail("Admin@cloudreve.org")
+	asserts.NoError(err)
+	asserts.NoError(pgMock.ExpectationsWereMet())
+	asserts.EqualValues(1, user.ID)
+}
+
+func TestPostgresDSN(t *testing.T) {
+	asserts := assert.New(t)
+	origin := *conf.DatabaseConfig
+	defer func() { *conf.DatabaseConfig = origin }()
+
+	// 默认端口及 sslmode
+	conf.DatabaseConfig.Host = "127.0.0.1"
+	conf.DatabaseConfig.Port = 3306
+	conf.DatabaseConfig.User = "cloudreve"
+	conf.DatabaseConfig.Password = ""
+	conf.DatabaseConfig.Name = "cloudreve"
+	conf.DatabaseConfig.SSLMode = ""
+	asserts.Equal("host=127.0.0.1 port=5432 user=cloudreve dbname=cloudreve sslmode=disable", postgresDSN())
+
+	// 需转义的密码
+	conf.DatabaseConfig.Host = "/var/run/postgresql"
+	conf.DatabaseConfig.Port = 6432
+	conf.DatabaseConfig.Password = `it's a \secret`
+	conf.DatabaseConfig.SSLMode = "require"
+	asserts.Equal(`host=/var/run/postgresql port=6432 user=cloudreve password='it\'s a \\secret' dbname=cloudreve sslmode=require`, postgresDSN())
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// DirectoryClaims 用户最近一次登录时由身份提供方提供的属性，用于重新计算用户组
+type DirectoryClaims struct {
+	// Source 属性来源，如 oidc、saml、ldap
+	Source string `json:"source"`
+	// Values 属性名到属性值的映射
+	Values   map[string][]string `json:"values"`
+	SyncedAt time.Time           `json:"synced_at"`
+}
+
+// ListDirectoryUsers 按ID顺序列出 ID 大于 after 的外部目录用户
+func ListDirectoryUsers(after uint, limit int) ([]User, error) {
+	var users []User
+	result := DB.Where("id > ? and external_id <> ?", after, "").Order("id").Limit(limit).Find(&users)
+	return users, result.Error
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDirectoryUsers(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		_, err := ListDirectoryUsers(0, 10)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)external_id(.+)").WithArgs(5, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "external_id"}).AddRow(6, "ext"))
+		users, err := ListDirectoryUsers(5, 10)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(users, 1)
+	}
+}
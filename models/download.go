@@ -25,6 +25,7 @@ type Download struct {
 	UserID         uint   // 发起者UID
 	TaskID         uint   // 对应的转存任务ID
 	NodeID         uint   // 处理任务的节点ID
+	PolicyID       uint   // 转存使用的存储策略ID，为 0 时使用用户默认策略
 
 	// 关联模型
 	User *User `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -95,6 +96,26 @@ func GetDownloadsByStatusAndUser(page, uid uint, status ...int) []Download {
 	return tasks
 }
 
+// CountDownloadsByNode 统计节点上处于给定状态的下载任务数
+func CountDownloadsByNode(nodeID uint, status ...int) int {
+	var count int
+	DB.Model(&Download{}).Where("node_id = ? and status in (?)", nodeID, status).Count(&count)
+	return count
+}
+
+// GetDownloadsByUserID 列出用户的所有离线下载记录
+func GetDownloadsByUserID(uid uint) ([]Download, error) {
+	var tasks []Download
+	result := DB.Where("user_id = ?", uid).Find(&tasks)
+	return tasks, result.Error
+}
+
+// DeleteDownloadsByUserID 彻底删除用户的所有离线下载记录，返回删除的条数
+func DeleteDownloadsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Download{})
+	return result.RowsAffected, result.Error
+}
+
 // GetDownloadByGid 根据GID和用户ID查找下载
 func GetDownloadByGid(gid string, uid uint) (*Download, error) {
 	download := &Download{}
@@ -110,6 +110,16 @@ func TestGetDownloadsByStatus(t *testing.T) {
 	asserts.Len(res, 2)
 }
 
+func TestGetDownloadsByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)downloads(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"gid"}).AddRow("0"))
+	res, err := GetDownloadsByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(res, 1)
+}
+
 func TestGetDownloadByGid(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -162,6 +172,14 @@ func TestGetDownloadsByStatusAndUser(t *testing.T) {
 	}
 }
 
+func TestCountDownloadsByNode(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)").WithArgs(1, 1, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	asserts.Equal(3, CountDownloadsByNode(1, 1, 2))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestDownload_Delete(t *testing.T) {
 	asserts := assert.New(t)
 	share := Download{}
@@ -188,3 +206,17 @@ func TestDownload_GetNodeID(t *testing.T) {
 	record.NodeID = 5
 	a.EqualValues(5, record.GetNodeID())
 }
+
+func TestDeleteDownloadsByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)downloads(.+)").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	n, err := DeleteDownloadsByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, n)
+}
@@ -0,0 +1,147 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// 加密名称所属的对象类型
+const (
+	EncryptedNameFile   = "file"
+	EncryptedNameFolder = "dir"
+)
+
+// UserKeyPair 用户的端到端加密密钥对，私钥由客户端使用用户口令加密后上传，
+// 服务端不保存任何明文密钥
+type UserKeyPair struct {
+	gorm.Model
+	UserID     uint   `gorm:"unique_index"`
+	PublicKey  string `gorm:"type:text"`
+	PrivateKey string `gorm:"type:text"`
+}
+
+// EncryptedFolder 端到端加密目录，此目录及其子目录下文件的内容与名称均由客户端加密
+type EncryptedFolder struct {
+	gorm.Model
+	FolderID  uint `gorm:"unique_index"`
+	OwnerID   uint `gorm:"index"`
+	Algorithm string
+}
+
+// FolderKeyEnvelope 使用接收方公钥或分享密钥包装后的目录密钥
+type FolderKeyEnvelope struct {
+	gorm.Model
+	EncryptedFolderID uint `gorm:"index"`
+	// UserID 接收方用户，分享链接的信封为 0
+	UserID uint `gorm:"index"`
+	// ShareID 信封所属的分享链接，用户的信封为 0
+	ShareID  uint   `gorm:"index"`
+	Envelope string `gorm:"type:text"`
+}
+
+// EncryptedName 加密目录中对象的加密名称
+type EncryptedName struct {
+	gorm.Model
+	EncryptedFolderID uint   `gorm:"index"`
+	ObjectType        string `gorm:"size:8;unique_index:idx_encrypted_name_object"`
+	ObjectID          uint   `gorm:"unique_index:idx_encrypted_name_object"`
+	Name              string `gorm:"type:text"`
+}
+
+// GetUserKeyPair 获取用户的密钥对
+func GetUserKeyPair(uid uint) (UserKeyPair, error) {
+	var pair UserKeyPair
+	result := DB.Where("user_id = ?", uid).First(&pair)
+	return pair, result.Error
+}
+
+// SaveUserKeyPair 创建或替换用户的密钥对
+func SaveUserKeyPair(uid uint, publicKey, privateKey string) (UserKeyPair, error) {
+	pair := UserKeyPair{UserID: uid}
+	result := DB.Where("user_id = ?", uid).
+		Assign(UserKeyPair{PublicKey: publicKey, PrivateKey: privateKey}).
+		FirstOrCreate(&pair)
+	return pair, result.Error
+}
+
+// Create 创建加密目录
+func (folder *EncryptedFolder) Create() error {
+	return DB.Create(folder).Error
+}
+
+// GetFolderAncestors 返回目录及其所有上级目录的 ID，依次为目录本身、上级目录直至根目录
+func GetFolderAncestors(id uint) ([]uint, error) {
+	res := []uint{id}
+	for {
+		var folder Folder
+		if err := DB.Select("id, parent_id").First(&folder, id).Error; err != nil {
+			return nil, err
+		}
+
+		if folder.ParentID == nil {
+			return res, nil
+		}
+
+		id = *folder.ParentID
+		res = append(res, id)
+	}
+}
+
+// GetEncryptedFolderByFolderID 获取目录所在的加密目录，目录本身或任一上级目录
+// 启用了端到端加密时返回对应记录
+func GetEncryptedFolderByFolderID(id uint) (EncryptedFolder, error) {
+	ancestors, err := GetFolderAncestors(id)
+	if err != nil {
+		return EncryptedFolder{}, err
+	}
+
+	return GetEncryptedFolderInAncestors(ancestors)
+}
+
+// GetEncryptedFolderInAncestors 在给定的目录链中查找启用了端到端加密的目录
+func GetEncryptedFolderInAncestors(ancestors []uint) (EncryptedFolder, error) {
+	var folder EncryptedFolder
+	result := DB.Where("folder_id in (?)", ancestors).First(&folder)
+	return folder, result.Error
+}
+
+// GetFolderKeyEnvelope 获取用户 uid 或分享 shareID 的目录密钥信封
+func GetFolderKeyEnvelope(encryptedID, uid, shareID uint) (FolderKeyEnvelope, error) {
+	var envelope FolderKeyEnvelope
+	result := DB.Where("encrypted_folder_id = ? and user_id = ? and share_id = ?", encryptedID, uid, shareID).
+		First(&envelope)
+	return envelope, result.Error
+}
+
+// SaveFolderKeyEnvelope 创建或替换目录密钥信封
+func SaveFolderKeyEnvelope(encryptedID, uid, shareID uint, envelope string) error {
+	res := FolderKeyEnvelope{EncryptedFolderID: encryptedID, UserID: uid, ShareID: shareID}
+	return DB.Where("encrypted_folder_id = ? and user_id = ? and share_id = ?", encryptedID, uid, shareID).
+		Assign(FolderKeyEnvelope{Envelope: envelope}).
+		FirstOrCreate(&res).Error
+}
+
+// DeleteFolderKeyEnvelope 删除用户 uid 或分享 shareID 的目录密钥信封，返回删除的条数
+func DeleteFolderKeyEnvelope(encryptedID, uid, shareID uint) (int64, error) {
+	result := DB.Unscoped().Where("encrypted_folder_id = ? and user_id = ? and share_id = ?", encryptedID, uid, shareID).
+		Delete(&FolderKeyEnvelope{})
+	return result.RowsAffected, result.Error
+}
+
+// SaveEncryptedName 创建或替换对象的加密名称
+func SaveEncryptedName(encryptedID uint, objectType string, objectID uint, name string) error {
+	res := EncryptedName{EncryptedFolderID: encryptedID, ObjectType: objectType, ObjectID: objectID}
+	return DB.Where("object_type = ? and object_id = ?", objectType, objectID).
+		Assign(EncryptedName{EncryptedFolderID: encryptedID, Name: name}).
+		FirstOrCreate(&res).Error
+}
+
+// GetEncryptedNames 获取给定对象的加密名称
+func GetEncryptedNames(objectType string, ids []uint) ([]EncryptedName, error) {
+	var names []EncryptedName
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	result := DB.Where("object_type = ? and object_id in (?)", objectType, ids).Find(&names)
+	return names, result.Error
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFolderAncestors(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		_, err := GetFolderAncestors(3)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(3, 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		res, err := GetFolderAncestors(3)
+		asserts.NoError(err)
+		asserts.Equal([]uint{3, 2, 1}, res)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetEncryptedFolderByFolderID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	mock.ExpectQuery("SELECT(.+)encrypted_folders(.+)").WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "algorithm"}).AddRow(5, 1, "AES-GCM"))
+	res, err := GetEncryptedFolderByFolderID(2)
+	asserts.NoError(err)
+	asserts.EqualValues(5, res.ID)
+	asserts.EqualValues(1, res.FolderID)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSaveFolderKeyEnvelope(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 创建
+	{
+		mock.ExpectQuery("SELECT(.+)folder_key_envelopes(.+)").WithArgs(1, 2, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)folder_key_envelopes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SaveFolderKeyEnvelope(1, 2, 0, "envelope"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 替换
+	{
+		mock.ExpectQuery("SELECT(.+)folder_key_envelopes(.+)").WithArgs(1, 2, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "envelope"}).AddRow(1, "old"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folder_key_envelopes(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SaveFolderKeyEnvelope(1, 2, 0, "envelope"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetEncryptedNames(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 列表为空
+	{
+		res, err := GetEncryptedNames(EncryptedNameFile, []uint{})
+		asserts.NoError(err)
+		asserts.Empty(res)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)encrypted_names(.+)").WithArgs(EncryptedNameFile, 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "object_id", "name"}).AddRow(1, 1, "enc"))
+		res, err := GetEncryptedNames(EncryptedNameFile, []uint{1, 2})
+		asserts.NoError(err)
+		asserts.Len(res, 1)
+		asserts.Equal("enc", res[0].Name)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -27,6 +29,13 @@ type File struct {
 	PolicyID        uint
 	UploadSessionID *string `gorm:"index:session_id;unique_index:session_only_one"`
 	Metadata        string  `gorm:"type:text"`
+	// SHA256 文件内容的 SHA-256，为空表示尚未计算或内容已变更
+	SHA256 string `gorm:"size:64;index:sha256"`
+	// ScanStatus 病毒扫描状态，ScanResult 为检出的病毒名称或扫描失败原因
+	ScanStatus string `gorm:"size:16;index:scan_status"`
+	ScanResult string
+	// Disabled 因举报被管理员禁用，禁用后拒绝下载
+	Disabled bool
 
 	// 关联模型
 	Policy Policy `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -46,8 +55,48 @@ const (
 	ThumbSidecarMetadataKey = "thumb_sidecar"
 
 	ChecksumMetadataKey = "webdav_checksum"
+
+	MediaMetaMetadataKey = "media_meta"
+
+	// TagsMetadataKey 自动化规则添加的文件标签，以逗号分隔
+	TagsMetadataKey = "tags"
+
+	// ContentVersionMetadataKey 文件内容版本，每次覆盖写入后递增
+	ContentVersionMetadataKey = "content_version"
+
+	// UploadReservedMetadataKey 上传会话占位文件预留的容量
+	UploadReservedMetadataKey = "upload_reserved"
+)
+
+// 病毒扫描状态
+const (
+	ScanStatusNone        = ""
+	ScanStatusClean       = "clean"
+	ScanStatusQuarantined = "quarantined"
+	ScanStatusReleased    = "released"
+	ScanStatusSkipped     = "skipped"
+	ScanStatusFailed      = "failed"
 )
 
+// HLS 转码相关元信息
+const (
+	HLSStatusProcessing = "processing"
+	HLSStatusReady      = "ready"
+	HLSStatusFailed     = "failed"
+
+	HLSStatusMetadataKey   = "hls_status"
+	HLSPathMetadataKey     = "hls_path"
+	HLSSegmentsMetadataKey = "hls_segments"
+	// HLSVersionMetadataKey 转码时的文件内容版本
+	HLSVersionMetadataKey = "hls_version"
+
+	// HLSPlaylistName 转码结果的播放列表文件名
+	HLSPlaylistName = "index.m3u8"
+)
+
+// ErrFileChanged 文件记录在操作期间被修改
+var ErrFileChanged = errors.New("file record changed during the operation")
+
 func init() {
 	// 注册缓存用到的复杂结构
 	gob.Register(File{})
@@ -70,6 +119,11 @@ func (file *File) Create() error {
 		return err
 	}
 
+	if err := changeFolderSize(tx, file.FolderID, "+", file.Size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
@@ -107,6 +161,30 @@ func (folder *Folder) GetChildFile(name string) (*File, error) {
 	return &file, result.Error
 }
 
+// GetChildFileCaseInsensitive 查找目录下忽略大小写后名为name的子文件，
+// 存在多个时优先返回名称完全一致的文件
+func (folder *Folder) GetChildFileCaseInsensitive(name string) (*File, error) {
+	var files []File
+	if err := DB.Where("folder_id = ? AND LOWER(name) = LOWER(?)", folder.ID, name).Find(&files).Error; err != nil {
+		return &File{}, err
+	}
+
+	if len(files) == 0 {
+		return &File{}, gorm.ErrRecordNotFound
+	}
+
+	res := &files[0]
+	for i := range files {
+		if files[i].Name == name {
+			res = &files[i]
+			break
+		}
+	}
+
+	res.Position = path.Join(folder.Position, folder.Name)
+	return res, nil
+}
+
 // GetChildFiles 查找目录下子文件
 func (folder *Folder) GetChildFiles() ([]File, error) {
 	var files []File
@@ -137,6 +215,41 @@ func GetFilesByIDsFromTX(tx *gorm.DB, ids []uint, uid uint) ([]File, error) {
 	return files, result.Error
 }
 
+// ListFilesAfter 按 ID 顺序列出 cursor 之后已上传完成的文件，policyID、uid 为 0
+// 或 exts 为空时不参与筛选
+func ListFilesAfter(cursor uint, limit int, policyID, uid uint, exts []string) ([]File, error) {
+	result := DB.Where("id > ? and upload_session_id is NULL", cursor)
+	if policyID > 0 {
+		result = result.Where("policy_id = ?", policyID)
+	}
+
+	if uid > 0 {
+		result = result.Where("user_id = ?", uid)
+	}
+
+	if len(exts) > 0 {
+		conditions := make([]string, len(exts))
+		args := make([]interface{}, len(exts))
+		for i, ext := range exts {
+			conditions[i] = Like("name")
+			args[i] = "%." + strings.TrimPrefix(ext, ".")
+		}
+		result = result.Where(strings.Join(conditions, " or "), args...)
+	}
+
+	var files []File
+	err := result.Order("id asc").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// ListPolicyFilesAfter 按 ID 顺序列出存储策略中 cursor 之后的全部文件记录，
+// 包括上传中及已软删除的文件
+func ListPolicyFilesAfter(policyID, cursor uint, limit int) ([]File, error) {
+	var files []File
+	result := DB.Unscoped().Where("policy_id = ? and id > ?", policyID, cursor).Order("id asc").Limit(limit).Find(&files)
+	return files, result.Error
+}
+
 // GetFilesByKeywords 根据关键字搜索文件,
 // UID为0表示忽略用户，只根据文件ID检索. 如果 parents 非空， 则只限制在 parent 包含的目录下搜索
 func GetFilesByKeywords(uid uint, parents []uint, keywords ...interface{}) ([]File, error) {
@@ -148,7 +261,7 @@ func GetFilesByKeywords(uid uint, parents []uint, keywords ...interface{}) ([]Fi
 
 	// 生成查询条件
 	for i := 0; i < len(keywords); i++ {
-		conditions += "name like ?"
+		conditions += Like("name")
 		if i != len(keywords)-1 {
 			conditions += " or "
 		}
@@ -194,6 +307,28 @@ func GetUploadPlaceholderFiles(uid uint) []*File {
 	return files
 }
 
+// GetFilesByUserID 获取用户的所有文件
+func GetFilesByUserID(uid uint) ([]File, error) {
+	var files []File
+	result := DB.Where("user_id = ?", uid).Find(&files)
+	return files, result.Error
+}
+
+// SumFileSizeByUserID 统计用户所有文件的总大小，包括回收站中的文件
+func SumFileSizeByUserID(uid uint) (uint64, error) {
+	var total uint64
+	row := DB.Unscoped().Model(&File{}).Where("user_id = ?", uid).Select("COALESCE(SUM(size), 0)").Row()
+	err := row.Scan(&total)
+	return total, err
+}
+
+// CountFilesByUserID 统计用户的文件数，回收站中的文件同样计入
+func CountFilesByUserID(uid uint) (int, error) {
+	var count int
+	err := DB.Unscoped().Model(&File{}).Where("user_id = ?", uid).Count(&count).Error
+	return count, err
+}
+
 // GetPolicy 获取文件所属策略
 func (file *File) GetPolicy() *Policy {
 	if file.Policy.Model.ID == 0 {
@@ -202,7 +337,17 @@ func (file *File) GetPolicy() *Policy {
 	return &file.Policy
 }
 
-// RemoveFilesWithSoftLinks 去除给定的文件列表中有软链接的文件
+// sourceLock 串行化物理文件引用的检查与变更
+var sourceLock sync.Mutex
+
+// LockSources 锁定物理文件引用，返回解锁函数。检查物理文件是否仍被引用并删除、
+// 或将文件改为引用其他物理文件时应持有此锁，避免删除刚被其他文件引用的物理文件
+func LockSources() func() {
+	sourceLock.Lock()
+	return sourceLock.Unlock
+}
+
+// RemoveFilesWithSoftLinks 去除给定的文件列表中有软链接或被历史版本引用的文件
 func RemoveFilesWithSoftLinks(files []File) ([]File, error) {
 	// 结果值
 	filteredFiles := make([]File, 0)
@@ -215,11 +360,20 @@ func RemoveFilesWithSoftLinks(files []File) ([]File, error) {
 	filesWithSoftLinks := make([]File, 0)
 	for _, file := range files {
 		var softLinkFile File
-		res := DB.
+		// 回收站中的文件同样引用源文件
+		res := DB.Unscoped().
 			Where("source_name = ? and policy_id = ? and id != ?", file.SourceName, file.PolicyID, file.ID).
 			First(&softLinkFile)
 		if res.Error == nil {
 			filesWithSoftLinks = append(filesWithSoftLinks, softLinkFile)
+			continue
+		}
+
+		// 历史版本同样引用源文件
+		var version FileVersion
+		res = DB.Where("source_name = ? and policy_id = ?", file.SourceName, file.PolicyID).First(&version)
+		if res.Error == nil {
+			filesWithSoftLinks = append(filesWithSoftLinks, File{SourceName: version.SourceName, PolicyID: version.PolicyID})
 		}
 	}
 
@@ -250,37 +404,63 @@ func RemoveFilesWithSoftLinks(files []File) ([]File, error) {
 // DeleteFiles 批量删除文件记录并归还容量
 func DeleteFiles(files []*File, uid uint) error {
 	tx := DB.Begin()
+	if err := deleteFiles(tx, files, uid); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// deleteFiles 在 tx 中删除文件记录，uid 大于 0 时同时扣除用户已用容量
+func deleteFiles(tx *gorm.DB, files []*File, uid uint) error {
 	user := &User{}
 	user.ID = uid
 	var size uint64
+	// 各目录中被删除文件的总大小
+	folderSizes := make(map[uint]uint64)
+	var folderIDs []uint
 	for _, file := range files {
 		if uid > 0 && file.UserID != uid {
-			tx.Rollback()
 			return errors.New("user id not consistent")
 		}
 
 		result := tx.Unscoped().Where("size = ?", file.Size).Delete(file)
 		if result.Error != nil {
-			tx.Rollback()
 			return result.Error
 		}
 
 		if result.RowsAffected == 0 {
-			tx.Rollback()
 			return errors.New("file size is dirty")
 		}
 
 		size += file.Size
+		if _, ok := folderSizes[file.FolderID]; !ok {
+			folderIDs = append(folderIDs, file.FolderID)
+		}
+		folderSizes[file.FolderID] += file.Size
+
+		// 释放未完成的上传会话预留的容量
+		if reserved := file.ReservedSize(); reserved > 0 {
+			owner := &User{}
+			owner.ID = file.UserID
+			if err := owner.ReleaseStorage(tx, reserved); err != nil {
+				return err
+			}
+		}
 	}
 
-	if uid > 0 {
-		if err := user.ChangeStorage(tx, "-", size); err != nil {
-			tx.Rollback()
+	for _, folderID := range folderIDs {
+		if err := changeFolderSize(tx, folderID, "-", folderSizes[folderID]); err != nil {
 			return err
 		}
 	}
 
-	return tx.Commit().Error
+	if uid > 0 {
+		return user.ChangeStorage(tx, "-", size)
+	}
+
+	return nil
 }
 
 // GetFilesByParentIDs 根据父目录ID查找文件
@@ -336,6 +516,28 @@ func (file *File) UpdateMetadata(data map[string]string) error {
 	return DB.Model(&file).Set("gorm:association_autoupdate", false).UpdateColumns(File{Metadata: string(metaValue)}).Error
 }
 
+// UpdateScanResult 更新文件的病毒扫描状态
+func (file *File) UpdateScanResult(status, result string) error {
+	file.ScanStatus = status
+	file.ScanResult = result
+	return DB.Model(&file).Set("gorm:association_autoupdate", false).UpdateColumns(map[string]interface{}{
+		"scan_status": status,
+		"scan_result": result,
+	}).Error
+}
+
+// IsQuarantined 文件是否因检出病毒而被隔离
+func (file *File) IsQuarantined() bool {
+	return file.ScanStatus == ScanStatusQuarantined
+}
+
+// ReleaseQuarantinedFiles 解除文件的隔离状态，返回解除的文件数
+func ReleaseQuarantinedFiles(ids []uint) (int64, error) {
+	result := DB.Model(&File{}).Where("id in (?) and scan_status = ?", ids, ScanStatusQuarantined).
+		UpdateColumn("scan_status", ScanStatusReleased)
+	return result.RowsAffected, result.Error
+}
+
 // UpdateSize 更新文件的大小信息
 // TODO: 全局锁
 func (file *File) UpdateSize(value uint64) error {
@@ -356,12 +558,18 @@ func (file *File) UpdateSize(value uint64) error {
 		return err
 	}
 
+	if err := file.bumpVersion(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if res := tx.Model(&file).
 		Where("size = ?", file.Size).
 		Set("gorm:association_autoupdate", false).
 		Updates(map[string]interface{}{
 			"size":     value,
 			"metadata": file.Metadata,
+			"sha256":   "",
 		}); res.Error != nil {
 		tx.Rollback()
 		return res.Error
@@ -372,10 +580,38 @@ func (file *File) UpdateSize(value uint64) error {
 		return err
 	}
 
+	if err := changeFolderSize(tx, file.FolderID, operator, sizeDelta); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	file.Size = value
+	file.SHA256 = ""
 	return tx.Commit().Error
 }
 
+// UpdateSHA256 记录文件内容的 SHA-256
+func (file *File) UpdateSHA256(value string) error {
+	file.SHA256 = value
+	return DB.Model(file).UpdateColumn("sha256", value).Error
+}
+
+// GetFileBySHA256 查找存储策略中内容相同的最早上传的文件，before 不为 0 时只查找 ID 小于
+// before 的文件，uid 不为 0 时只查找此用户的文件
+func GetFileBySHA256(policyID uint, hash string, size uint64, before, uid uint) (File, error) {
+	var file File
+	tx := DB.Where("policy_id = ? and sha256 = ? and size = ? and upload_session_id is NULL", policyID, hash, size)
+	if before > 0 {
+		tx = tx.Where("id < ?", before)
+	}
+	if uid > 0 {
+		tx = tx.Where("user_id = ?", uid)
+	}
+
+	result := tx.Order("id asc").First(&file)
+	return file, result.Error
+}
+
 // UpdateSourceName 更新文件的源文件名
 func (file *File) UpdateSourceName(value string) error {
 	if err := file.resetThumb(); err != nil {
@@ -388,17 +624,122 @@ func (file *File) UpdateSourceName(value string) error {
 	}).Error
 }
 
+// ChangePolicy 将文件及共用同一物理文件的副本切换至新的存储策略下的物理文件，
+// 仅在这些记录未被并发修改时生效。缩略图与 HLS 转码结果仍位于原存储策略中，需重新生成
+func (file *File) ChangePolicy(policyID uint, sourceName string) error {
+	tx := DB.Begin()
+	var files []File
+	if err := tx.Where("policy_id = ? and source_name = ?", file.PolicyID, file.SourceName).Find(&files).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	found := false
+	for i := range files {
+		meta := make(map[string]string, len(files[i].MetadataSerialized))
+		for k, v := range files[i].MetadataSerialized {
+			switch k {
+			case ThumbStatusMetadataKey, ThumbSidecarMetadataKey, HLSStatusMetadataKey, HLSPathMetadataKey,
+				HLSSegmentsMetadataKey, HLSVersionMetadataKey:
+			default:
+				meta[k] = v
+			}
+		}
+
+		metaValue, err := json.Marshal(&meta)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		res := tx.Model(&files[i]).
+			Where("policy_id = ? and source_name = ?", file.PolicyID, file.SourceName).
+			Set("gorm:association_autoupdate", false).
+			Updates(map[string]interface{}{
+				"policy_id":   policyID,
+				"source_name": sourceName,
+				"metadata":    string(metaValue),
+			})
+		if res.Error != nil {
+			tx.Rollback()
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			tx.Rollback()
+			return ErrFileChanged
+		}
+
+		if files[i].ID == file.ID {
+			found = true
+			file.MetadataSerialized = meta
+			file.Metadata = string(metaValue)
+		}
+	}
+
+	if !found {
+		tx.Rollback()
+		return ErrFileChanged
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	file.PolicyID = policyID
+	file.SourceName = sourceName
+	file.Policy = Policy{}
+	return nil
+}
+
 func (file *File) PopChunkToFile(lastModified *time.Time, picInfo string) error {
 	file.UploadSessionID = nil
 	if lastModified != nil {
 		file.UpdatedAt = *lastModified
 	}
 
-	return DB.Model(file).UpdateColumns(map[string]interface{}{
+	reserved := file.ReservedSize()
+	if reserved == 0 {
+		return DB.Model(file).UpdateColumns(map[string]interface{}{
+			"upload_session_id": file.UploadSessionID,
+			"updated_at":        file.UpdatedAt,
+			"pic_info":          picInfo,
+		}).Error
+	}
+
+	// 上传完成后释放上传会话预留的容量，实际大小已计入已用容量
+	delete(file.MetadataSerialized, UploadReservedMetadataKey)
+	metaValue, err := json.Marshal(&file.MetadataSerialized)
+	if err != nil {
+		return err
+	}
+	file.Metadata = string(metaValue)
+
+	tx := DB.Begin()
+	if err := tx.Model(file).UpdateColumns(map[string]interface{}{
 		"upload_session_id": file.UploadSessionID,
 		"updated_at":        file.UpdatedAt,
 		"pic_info":          picInfo,
-	}).Error
+		"metadata":          file.Metadata,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	user := &User{}
+	user.ID = file.UserID
+	if err := user.ReleaseStorage(tx, reserved); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ReservedSize 返回上传会话占位文件预留的容量
+func (file *File) ReservedSize() uint64 {
+	reserved, _ := strconv.ParseUint(file.MetadataSerialized[UploadReservedMetadataKey], 10, 64)
+	return reserved
 }
 
 // CanCopy 返回文件是否可被复制
@@ -407,10 +748,10 @@ func (file *File) CanCopy() bool {
 }
 
 // CreateOrGetSourceLink creates a SourceLink model. If the given model exists, the existing
-// model will be returned.
+// model will be returned. Links with an expiration time are never reused.
 func (file *File) CreateOrGetSourceLink() (*SourceLink, error) {
 	res := &SourceLink{}
-	err := DB.Set("gorm:auto_preload", true).Where("file_id = ?", file.ID).Find(&res).Error
+	err := DB.Set("gorm:auto_preload", true).Where("file_id = ? and expires is null", file.ID).Find(&res).Error
 	if err == nil && res.ID > 0 {
 		return res, nil
 	}
@@ -436,6 +777,41 @@ func (file *File) resetThumb() error {
 	return err
 }
 
+// ResetThumb 清除文件的缩略图状态，缩略图将在下次获取时重新生成
+func (file *File) ResetThumb() error {
+	if _, ok := file.MetadataSerialized[ThumbStatusMetadataKey]; !ok {
+		return nil
+	}
+
+	if err := file.resetThumb(); err != nil {
+		return err
+	}
+
+	return DB.Model(file).Set("gorm:association_autoupdate", false).UpdateColumns(File{Metadata: file.Metadata}).Error
+}
+
+// Version 返回文件内容版本，用于覆盖写入时检查客户端读取后文件是否已被修改
+func (file *File) Version() string {
+	if version, ok := file.MetadataSerialized[ContentVersionMetadataKey]; ok {
+		return version
+	}
+
+	return "0"
+}
+
+// bumpVersion 递增文件内容版本
+func (file *File) bumpVersion() error {
+	if file.MetadataSerialized == nil {
+		file.MetadataSerialized = make(map[string]string)
+	}
+
+	version, _ := strconv.ParseUint(file.Version(), 10, 64)
+	file.MetadataSerialized[ContentVersionMetadataKey] = strconv.FormatUint(version+1, 10)
+	metaValue, err := json.Marshal(&file.MetadataSerialized)
+	file.Metadata = string(metaValue)
+	return err
+}
+
 /*
 	实现 webdav.FileInfo 接口
 */
@@ -466,6 +842,37 @@ func (file *File) ShouldLoadThumb() bool {
 	return file.MetadataSerialized[ThumbStatusMetadataKey] != ThumbStatusNotAvailable
 }
 
+// HLSStatus 返回文件的 HLS 转码状态，文件内容变更后之前的转码结果失效
+func (file *File) HLSStatus() string {
+	if file.MetadataSerialized[HLSVersionMetadataKey] != file.Version() {
+		return ""
+	}
+
+	return file.MetadataSerialized[HLSStatusMetadataKey]
+}
+
+// HLSSegmentName 返回第 i 个 HLS 分片的文件名
+func HLSSegmentName(i int) string {
+	return fmt.Sprintf("seg_%05d.ts", i)
+}
+
+// HLSFiles 返回转码结果的播放列表及所有分片的存储路径
+func (file *File) HLSFiles() []string {
+	dir := file.MetadataSerialized[HLSPathMetadataKey]
+	if dir == "" {
+		return nil
+	}
+
+	segments, _ := strconv.Atoi(file.MetadataSerialized[HLSSegmentsMetadataKey])
+	res := make([]string, 0, segments+1)
+	res = append(res, path.Join(dir, HLSPlaylistName))
+	for i := 0; i < segments; i++ {
+		res = append(res, path.Join(dir, HLSSegmentName(i)))
+	}
+
+	return res
+}
+
 // return sidecar thumb file name
 func (file *File) ThumbFile() string {
 	return file.SourceName + GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+)
+
+// FileContent 数据库全文检索后端保存的文件文本内容
+type FileContent struct {
+	FileID    uint   `gorm:"primary_key;auto_increment:false"`
+	UserID    uint   `gorm:"index:file_content_user"`
+	Content   string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
+
+// SaveFileContent 写入或替换文件的文本内容
+func SaveFileContent(content *FileContent) error {
+	tx := DB.Begin()
+	if err := tx.Where("file_id = ?", content.FileID).Delete(&FileContent{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(content).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteFileContents 删除文件的文本内容
+func DeleteFileContents(fileIDs []uint) error {
+	return DB.Where("file_id in (?)", fileIDs).Delete(&FileContent{}).Error
+}
+
+// SearchFileContents 在用户的文件中搜索内容包含全部关键词的文件，返回文件ID
+func SearchFileContents(uid uint, keywords []string, limit int) ([]uint, error) {
+	var ids []uint
+	query := DB.Model(&FileContent{}).Where("user_id = ?", uid)
+	for _, keyword := range keywords {
+		query = query.Where(Like("content"), "%"+keyword+"%")
+	}
+
+	result := query.Order("updated_at desc").Limit(limit).Pluck("file_id", &ids)
+	return ids, result.Error
+}
+
+// DeleteOrphanFileContents 删除所属文件已不存在的文本内容，回收站中的文件仍保留内容
+func DeleteOrphanFileContents() error {
+	return DB.Where("file_id not in (?)", DB.Unscoped().Model(&File{}).Select("id").QueryExpr()).
+		Delete(&FileContent{}).Error
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveFileContent(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)file_contents(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(SaveFileContent(&FileContent{FileID: 1, UserID: 2, Content: "hello"}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除旧内容失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1).WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(SaveFileContent(&FileContent{FileID: 1, UserID: 2, Content: "hello"}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)file_contents(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(SaveFileContent(&FileContent{FileID: 1, UserID: 2, Content: "hello"}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteFileContents(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	a.NoError(DeleteFileContents([]uint{1, 2}))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestSearchFileContents(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT file_id FROM(.+)file_contents(.+)").
+		WithArgs(1, "%foo%", "%bar%").
+		WillReturnRows(sqlmock.NewRows([]string{"file_id"}).AddRow(3).AddRow(2))
+	res, err := SearchFileContents(1, []string{"foo", "bar"}, 10)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Equal([]uint{3, 2}, res)
+}
+
+func TestDeleteOrphanFileContents(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		a.NoError(DeleteOrphanFileContents())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(DeleteOrphanFileContents())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -325,20 +325,52 @@ func TestRemoveFilesWithSoftLinks(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs("1.txt", 23, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("1.txt", 23).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs("2.txt", 24, 2).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("2.txt", 24).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		file, err := RemoveFilesWithSoftLinks(files)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
 		asserts.Equal(files, file)
 	}
 
+	// 第一个被历史版本引用
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs("1.txt", 23, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("1.txt", 23).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "policy_id", "source_name"}).
+					AddRow(5, 23, "1.txt"),
+			)
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs("2.txt", 24, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("2.txt", 24).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		file, err := RemoveFilesWithSoftLinks(files)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(files[1:], file)
+	}
+
 	// 第二个是软链
 	{
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs("1.txt", 23, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("1.txt", 23).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs("2.txt", 24, 2).
 			WillReturnRows(
@@ -362,6 +394,9 @@ func TestRemoveFilesWithSoftLinks(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs("2.txt", 24, 2).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs("2.txt", 24).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		file, err := RemoveFilesWithSoftLinks(files)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
@@ -446,6 +481,36 @@ func TestDeleteFiles(t *testing.T) {
 		a.NoError(err)
 	}
 
+	// 成功，按目录扣除总大小
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("DELETE(.+)").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(5, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(3, 3, 5).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)storage(.+)").WithArgs(uint64(3), sqlmock.AnyArg(), uint(1)).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		err := DeleteFiles([]*File{{Size: 1, UserID: 1, FolderID: 5}, {Size: 2, UserID: 1, FolderID: 5}}, 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+
+	// 成功，释放上传会话预留的容量
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WithArgs(10, 10, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)storage(.+)").WithArgs(uint64(0), sqlmock.AnyArg(), uint(1)).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		err := DeleteFiles([]*File{{UserID: 1, MetadataSerialized: map[string]string{UploadReservedMetadataKey: "10"}}}, 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+
 	// 成功,  关联用户不存在
 	{
 		mock.ExpectBegin()
@@ -565,7 +630,7 @@ func TestFile_UpdateSize(t *testing.T) {
 	{
 		file := File{Size: 10}
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 11, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 11, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)storage(.+)+(.+)").WithArgs(uint64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -577,7 +642,7 @@ func TestFile_UpdateSize(t *testing.T) {
 	{
 		file := File{Size: 10}
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 8, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 8, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)storage(.+)-(.+)").WithArgs(uint64(2), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 
@@ -589,7 +654,7 @@ func TestFile_UpdateSize(t *testing.T) {
 	{
 		file := File{Size: 10}
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 8, sqlmock.AnyArg(), 10).WillReturnError(errors.New("error"))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 8, sqlmock.AnyArg(), 10).WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
 
 		a.Error(file.UpdateSize(8))
@@ -600,7 +665,7 @@ func TestFile_UpdateSize(t *testing.T) {
 	{
 		file := File{Size: 10}
 		mock.ExpectBegin()
-		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 8, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 8, sqlmock.AnyArg(), 10).WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)storage(.+)-(.+)").WithArgs(uint64(2), sqlmock.AnyArg()).WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
 
@@ -609,6 +674,104 @@ func TestFile_UpdateSize(t *testing.T) {
 	}
 }
 
+func TestFile_UpdateSHA256(t *testing.T) {
+	a := assert.New(t)
+	file := File{}
+	file.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WithArgs("hash", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	a.NoError(file.UpdateSHA256("hash"))
+	a.NoError(mock.ExpectationsWereMet())
+	a.Equal("hash", file.SHA256)
+}
+
+func TestFile_UpdateScanResult(t *testing.T) {
+	a := assert.New(t)
+	file := File{}
+	file.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)files(.+)scan_(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	a.NoError(file.UpdateScanResult(ScanStatusQuarantined, "Eicar"))
+	a.NoError(mock.ExpectationsWereMet())
+	a.True(file.IsQuarantined())
+	a.Equal("Eicar", file.ScanResult)
+}
+
+func TestReleaseQuarantinedFiles(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)files(.+)scan_status(.+)").WithArgs(ScanStatusReleased, 1, 2, ScanStatusQuarantined).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	affected, err := ReleaseQuarantinedFiles([]uint{1, 2})
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(1, affected)
+}
+
+func TestGetFileBySHA256(t *testing.T) {
+	a := assert.New(t)
+
+	// 不限用户
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)upload_session_id is NULL(.+)ORDER BY id asc(.+)").
+			WithArgs(1, "hash", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(2, "src"))
+		file, err := GetFileBySHA256(1, "hash", 10, 0, 0)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("src", file.SourceName)
+	}
+
+	// 限定 ID 及用户
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)id < (.+)user_id = (.+)").
+			WithArgs(1, "hash", 10, 5, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetFileBySHA256(1, "hash", 10, 5, 3)
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFile_Version(t *testing.T) {
+	a := assert.New(t)
+	file := File{}
+	a.Equal("0", file.Version())
+
+	a.NoError(file.bumpVersion())
+	a.Equal("1", file.Version())
+	a.Equal(`{"content_version":"1"}`, file.Metadata)
+
+	a.NoError(file.bumpVersion())
+	a.Equal("2", file.Version())
+}
+
+func TestFile_HLS(t *testing.T) {
+	a := assert.New(t)
+	file := File{MetadataSerialized: map[string]string{}}
+	a.Equal("", file.HLSStatus())
+	a.Nil(file.HLSFiles())
+
+	file.MetadataSerialized = map[string]string{
+		HLSStatusMetadataKey:   HLSStatusReady,
+		HLSVersionMetadataKey:  "0",
+		HLSPathMetadataKey:     "hls/1_abc",
+		HLSSegmentsMetadataKey: "2",
+	}
+	a.Equal(HLSStatusReady, file.HLSStatus())
+	a.Equal([]string{"hls/1_abc/index.m3u8", "hls/1_abc/seg_00000.ts", "hls/1_abc/seg_00001.ts"}, file.HLSFiles())
+
+	// 文件内容变更后转码结果失效
+	a.NoError(file.bumpVersion())
+	a.Equal("", file.HLSStatus())
+	a.Len(file.HLSFiles(), 3)
+}
+
 func TestFile_PopChunkToFile(t *testing.T) {
 	a := assert.New(t)
 	timeNow := time.Now()
@@ -617,6 +780,24 @@ func TestFile_PopChunkToFile(t *testing.T) {
 	mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 	a.NoError(file.PopChunkToFile(&timeNow, "1,1"))
+
+	// 释放预留的容量
+	{
+		file := File{
+			Model:              gorm.Model{ID: 2},
+			UserID:             1,
+			MetadataSerialized: map[string]string{UploadReservedMetadataKey: "10"},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("{}", "1,1", sqlmock.AnyArg(), nil, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)reserved_storage(.+)").WithArgs(10, 10, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(file.PopChunkToFile(&timeNow, "1,1"))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(0, file.ReservedSize())
+	}
 }
 
 func TestFile_CanCopy(t *testing.T) {
@@ -783,3 +964,168 @@ func TestFile_ThumbFile(t *testing.T) {
 
 	a.Equal("test._thumb", file.ThumbFile())
 }
+
+func TestGetFilesByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)files(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	files, err := GetFilesByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(files, 2)
+}
+
+func TestCountFilesByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)files(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := CountFilesByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal(3, count)
+}
+
+func TestSumFileSizeByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(100))
+		total, err := SumFileSizeByUserID(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(100, total)
+	}
+
+	// 出错
+	{
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WillReturnError(errors.New("error"))
+		_, err := SumFileSizeByUserID(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestListFilesAfter(t *testing.T) {
+	a := assert.New(t)
+
+	// 无筛选
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4).AddRow(5))
+		files, err := ListFilesAfter(3, 2, 0, 0, nil)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Len(files, 2)
+	}
+
+	// 按策略、用户、扩展名筛选
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(0, 1, 2, "%.jpg", "%.png").
+			WillReturnError(errors.New("error"))
+		files, err := ListFilesAfter(0, 10, 1, 2, []string{"jpg", ".png"})
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Len(files, 0)
+	}
+}
+
+func TestListPolicyFilesAfter(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at"}).AddRow(4, time.Now()).AddRow(5, nil))
+	files, err := ListPolicyFilesAfter(1, 3, 2)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Len(files, 2)
+	a.NotNil(files[0].DeletedAt)
+}
+
+func TestFile_ResetThumb(t *testing.T) {
+	a := assert.New(t)
+
+	// 未生成过缩略图
+	{
+		file := &File{Model: gorm.Model{ID: 1}}
+		a.NoError(file.ResetThumb())
+	}
+
+	// 清除状态
+	{
+		file := &File{
+			Model:              gorm.Model{ID: 1},
+			MetadataSerialized: map[string]string{ThumbStatusMetadataKey: ThumbStatusNotAvailable},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(file.ResetThumb())
+		a.NoError(mock.ExpectationsWereMet())
+		a.NotContains(file.MetadataSerialized, ThumbStatusMetadataKey)
+	}
+}
+
+func TestFile_ChangePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功，副本一同迁移
+	{
+		file := &File{PolicyID: 1, SourceName: "old", Policy: Policy{Type: "local"}}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "old").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "policy_id", "source_name", "metadata"}).
+				AddRow(1, 1, "old", `{"thumb_status":"exist","thumb_sidecar":"true","hls_status":"ready","tags":"a"}`).
+				AddRow(2, 1, "old", ""))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"tags":"a"}`, 2, "new", sqlmock.AnyArg(), 1, 1, "old").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{}`, 2, "new", sqlmock.AnyArg(), 2, 1, "old").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(file.ChangePolicy(2, "new"))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(2, file.PolicyID)
+		a.Equal("new", file.SourceName)
+		a.Equal(map[string]string{"tags": "a"}, file.MetadataSerialized)
+		a.EqualValues(0, file.Policy.ID)
+	}
+
+	// 文件已被修改
+	{
+		file := &File{PolicyID: 1, SourceName: "old"}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "old").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "policy_id", "source_name"}).AddRow(1, 1, "old"))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		a.ErrorIs(file.ChangePolicy(2, "new"), ErrFileChanged)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(1, file.PolicyID)
+	}
+
+	// 记录不存在
+	{
+		file := &File{PolicyID: 1, SourceName: "old"}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "old").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+		a.ErrorIs(file.ChangePolicy(2, "new"), ErrFileChanged)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		file := &File{PolicyID: 1, SourceName: "old"}
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(file.ChangePolicy(2, "new"))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// FileVersion 文件被覆盖前保留的历史版本
+type FileVersion struct {
+	gorm.Model
+	FileID     uint `gorm:"index:file_version_file"`
+	UserID     uint
+	PolicyID   uint
+	SourceName string `gorm:"type:text"`
+	Size       uint64
+}
+
+// GetFileVersions 列出文件的历史版本，按创建时间从新到旧排序
+func GetFileVersions(fileID uint) ([]FileVersion, error) {
+	var versions []FileVersion
+	result := DB.Where("file_id = ?", fileID).Order("id desc").Find(&versions)
+	return versions, result.Error
+}
+
+// GetFileVersion 查找文件的某个历史版本
+func GetFileVersion(id, fileID uint) (*FileVersion, error) {
+	version := &FileVersion{}
+	result := DB.Where("id = ? and file_id = ?", id, fileID).First(version)
+	return version, result.Error
+}
+
+// GetFileVersionsByFileIDs 列出多个文件的全部历史版本
+func GetFileVersionsByFileIDs(fileIDs []uint) ([]FileVersion, error) {
+	var versions []FileVersion
+	result := DB.Where("file_id in (?)", fileIDs).Find(&versions)
+	return versions, result.Error
+}
+
+// GetFileVersionSourcesByPolicy 列出存储策略中全部历史版本的源文件名
+func GetFileVersionSourcesByPolicy(policyID uint) ([]string, error) {
+	var sources []string
+	result := DB.Model(&FileVersion{}).Where("policy_id = ?", policyID).Pluck("source_name", &sources)
+	return sources, result.Error
+}
+
+// ArchiveFileVersion 将文件原有的内容记为历史版本，并将文件指向新的源文件。
+// 历史版本计入用户已用容量
+func ArchiveFileVersion(version *FileVersion, sourceName string) error {
+	tx := DB.Begin()
+	if err := tx.Create(version).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&File{}).Where("id = ?", version.FileID).
+		UpdateColumn("source_name", sourceName).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	user := User{}
+	user.ID = version.UserID
+	if err := user.ChangeStorage(tx, "+", version.Size); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// RestoreVersion 将文件内容恢复为 version，当前内容改为记入该历史版本
+func (file *File) RestoreVersion(version *FileVersion) error {
+	if err := file.resetThumb(); err != nil {
+		return err
+	}
+
+	if err := file.bumpVersion(); err != nil {
+		return err
+	}
+
+	tx := DB.Begin()
+	sourceName, size, policyID := file.SourceName, file.Size, file.PolicyID
+	if err := tx.Model(&File{}).Where("id = ?", file.ID).Updates(map[string]interface{}{
+		"source_name": version.SourceName,
+		"size":        version.Size,
+		"policy_id":   version.PolicyID,
+		"metadata":    file.Metadata,
+		"sha256":      "",
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&FileVersion{}).Where("id = ?", version.ID).Updates(map[string]interface{}{
+		"source_name": sourceName,
+		"size":        size,
+		"policy_id":   policyID,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 目录总大小只统计文件当前内容的大小
+	operator, delta := "+", version.Size-size
+	if version.Size < size {
+		operator, delta = "-", size-version.Size
+	}
+	if err := changeFolderSize(tx, file.FolderID, operator, delta); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	file.SourceName, file.Size, file.PolicyID, file.Policy = version.SourceName, version.Size, version.PolicyID, Policy{}
+	file.SHA256 = ""
+	version.SourceName, version.Size, version.PolicyID = sourceName, size, policyID
+	return nil
+}
+
+// DeleteFileVersions 删除历史版本记录，并释放其占用的用户容量
+func DeleteFileVersions(versions []FileVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(versions))
+	sizes := make(map[uint]uint64)
+	for i, version := range versions {
+		ids[i] = version.ID
+		sizes[version.UserID] += version.Size
+	}
+
+	tx := DB.Begin()
+	if err := tx.Unscoped().Where("id in (?)", ids).Delete(&FileVersion{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for uid, size := range sizes {
+		if err := tx.Model(&User{}).Where("id = ?", uid).
+			Update("storage", gorm.Expr("case when storage > ? then storage - ? else 0 end", size, size)).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
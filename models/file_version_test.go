@@ -0,0 +1,153 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFileVersions(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}).AddRow(2, 1).AddRow(1, 1))
+	res, err := GetFileVersions(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(res, 2)
+	a.EqualValues(2, res[0].ID)
+}
+
+func TestGetFileVersion(t *testing.T) {
+	a := assert.New(t)
+
+	// 存在
+	{
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}).AddRow(2, 1))
+		res, err := GetFileVersion(2, 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(2, res.ID)
+	}
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WithArgs(2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}))
+		_, err := GetFileVersion(2, 3)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+}
+
+func TestGetFileVersionSourcesByPolicy(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"source_name"}).AddRow("1/a").AddRow("1/b"))
+	res, err := GetFileVersionSourcesByPolicy(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Equal([]string{"1/a", "1/b"}, res)
+}
+
+func TestArchiveFileVersion(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("new", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)storage(.+)").WithArgs(10, sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		version := &FileVersion{FileID: 1, UserID: 2, SourceName: "old", Size: 10}
+		a.NoError(ArchiveFileVersion(version, "new"))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(1, version.ID)
+	}
+
+	// 更新文件失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(ArchiveFileVersion(&FileVersion{FileID: 1, UserID: 2, Size: 10}, "new"))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFile_RestoreVersion(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		file := &File{Model: gorm.Model{ID: 1}, SourceName: "new", Size: 20, PolicyID: 1}
+		version := &FileVersion{Model: gorm.Model{ID: 2}, SourceName: "old", Size: 10, PolicyID: 2}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(file.RestoreVersion(version))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("old", file.SourceName)
+		a.EqualValues(10, file.Size)
+		a.EqualValues(2, file.PolicyID)
+		a.Equal("1", file.Version())
+		a.Equal("new", version.SourceName)
+		a.EqualValues(20, version.Size)
+		a.EqualValues(1, version.PolicyID)
+	}
+
+	// 失败
+	{
+		file := &File{Model: gorm.Model{ID: 1}, SourceName: "new"}
+		version := &FileVersion{Model: gorm.Model{ID: 2}, SourceName: "old"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)file_versions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(file.RestoreVersion(version))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("new", file.SourceName)
+		a.Equal("old", version.SourceName)
+	}
+}
+
+func TestDeleteFileVersions(t *testing.T) {
+	a := assert.New(t)
+
+	// 空列表
+	{
+		a.NoError(DeleteFileVersions(nil))
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE(.+)users(.+)storage(.+)").WithArgs(15, 15, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(DeleteFileVersions([]FileVersion{
+			{Model: gorm.Model{ID: 1}, UserID: 1, Size: 10},
+			{Model: gorm.Model{ID: 2}, UserID: 1, Size: 5},
+		}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_versions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(DeleteFileVersions([]FileVersion{{Model: gorm.Model{ID: 1}, UserID: 1, Size: 10}}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
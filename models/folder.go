@@ -16,6 +16,8 @@ type Folder struct {
 	Name     string `gorm:"unique_index:idx_only_one_name"`
 	ParentID *uint  `gorm:"index:parent_id;unique_index:idx_only_one_name"`
 	OwnerID  uint   `gorm:"index:owner_id"`
+	// Size 目录及其所有子目录下文件的总大小，随文件操作增量维护
+	Size uint64
 
 	// 数据库忽略字段
 	Position      string `gorm:"-"`
@@ -47,6 +49,50 @@ func (folder *Folder) GetChild(name string) (*Folder, error) {
 	return &resFolder, err
 }
 
+// GetChildCaseInsensitive 返回folder下忽略大小写后名为name的子目录，
+// 存在多个时优先返回名称完全一致的目录，不存在则返回错误
+func (folder *Folder) GetChildCaseInsensitive(name string) (*Folder, error) {
+	var folders []Folder
+	err := DB.
+		Where("parent_id = ? AND owner_id = ? AND LOWER(name) = LOWER(?)", folder.ID, folder.OwnerID, name).
+		Find(&folders).Error
+	if err != nil {
+		return &Folder{}, err
+	}
+
+	if len(folders) == 0 {
+		return &Folder{}, gorm.ErrRecordNotFound
+	}
+
+	res := &folders[0]
+	for i := range folders {
+		if folders[i].Name == name {
+			res = &folders[i]
+			break
+		}
+	}
+
+	// 将子目录的路径传递下去
+	res.Position = path.Join(folder.Position, folder.Name)
+	return res, nil
+}
+
+// HasChildCaseInsensitive 忽略大小写时，folder下是否存在除 except 以外名为name的子目录或子文件
+func (folder *Folder) HasChildCaseInsensitive(name string, isDir bool, except uint) bool {
+	var count int
+	if isDir {
+		DB.Model(&Folder{}).
+			Where("parent_id = ? AND owner_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", folder.ID, folder.OwnerID, name, except).
+			Count(&count)
+	} else {
+		DB.Model(&File{}).
+			Where("folder_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", folder.ID, name, except).
+			Count(&count)
+	}
+
+	return count > 0
+}
+
 // TraceRoot 向上递归查找父目录
 func (folder *Folder) TraceRoot() error {
 	if folder.ParentID == nil {
@@ -80,6 +126,99 @@ func (folder *Folder) GetChildFolder() ([]Folder, error) {
 	return folders, result.Error
 }
 
+// ChildFilter 分批遍历子项目时的筛选条件，为零值的条件不参与筛选
+type ChildFilter struct {
+	// Keyword 名称包含的关键字
+	Keyword string
+	// MinSize 最小文件大小，仅对文件生效
+	MinSize uint64
+	// MaxSize 最大文件大小，仅对文件生效
+	MaxSize uint64
+}
+
+// apply 将筛选条件应用到查询
+func (filter *ChildFilter) apply(db *gorm.DB, isFile bool) *gorm.DB {
+	if filter == nil {
+		return db
+	}
+
+	if filter.Keyword != "" {
+		db = db.Where(Like("name"), "%"+filter.Keyword+"%")
+	}
+
+	if isFile && filter.MinSize > 0 {
+		db = db.Where("size >= ?", filter.MinSize)
+	}
+
+	if isFile && filter.MaxSize > 0 {
+		db = db.Where("size <= ?", filter.MaxSize)
+	}
+
+	return db
+}
+
+// WalkChildFolders 按 ID 顺序分批遍历满足条件的子目录，每批最多 batch 个，
+// fn 返回错误时终止遍历
+func (folder *Folder) WalkChildFolders(batch int, filter *ChildFilter, fn func([]Folder) error) error {
+	var lastID uint
+	for {
+		var folders []Folder
+		result := filter.apply(DB.Where("parent_id = ? and id > ?", folder.ID, lastID), false).
+			Order("id asc").Limit(batch).Find(&folders)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(folders) == 0 {
+			return nil
+		}
+
+		for i := 0; i < len(folders); i++ {
+			folders[i].Position = path.Join(folder.Position, folder.Name)
+		}
+
+		if err := fn(folders); err != nil {
+			return err
+		}
+
+		if len(folders) < batch {
+			return nil
+		}
+		lastID = folders[len(folders)-1].ID
+	}
+}
+
+// WalkChildFiles 按 ID 顺序分批遍历满足条件的子文件，每批最多 batch 个，
+// fn 返回错误时终止遍历
+func (folder *Folder) WalkChildFiles(batch int, filter *ChildFilter, fn func([]File) error) error {
+	var lastID uint
+	for {
+		var files []File
+		result := filter.apply(DB.Where("folder_id = ? and id > ?", folder.ID, lastID), true).
+			Order("id asc").Limit(batch).Find(&files)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(files) == 0 {
+			return nil
+		}
+
+		for i := 0; i < len(files); i++ {
+			files[i].Position = path.Join(folder.Position, folder.Name)
+		}
+
+		if err := fn(files); err != nil {
+			return err
+		}
+
+		if len(files) < batch {
+			return nil
+		}
+		lastID = files[len(files)-1].ID
+	}
+}
+
 // GetRecursiveChildFolder 查找所有递归子目录，包括自身
 func GetRecursiveChildFolder(dirs []uint, uid uint, includeSelf bool) ([]Folder, error) {
 	folders := make([]Folder, 0, len(dirs))
@@ -128,6 +267,209 @@ func GetRecursiveChildFolder(dirs []uint, uid uint, includeSelf bool) ([]Folder,
 	return folders, err
 }
 
+// GetFolderSize 统计目录及其所有子目录下文件的总大小
+func GetFolderSize(id, uid uint) (uint64, error) {
+	folders, err := GetRecursiveChildFolder([]uint{id}, uid, true)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(folders) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(folders))
+	for i := range folders {
+		ids[i] = folders[i].ID
+	}
+
+	var total uint64
+	row := DB.Model(&File{}).Where("folder_id in (?)", ids).Select("COALESCE(SUM(size), 0)").Row()
+	err = row.Scan(&total)
+	return total, err
+}
+
+// AncestorIDs 返回目录自身及所有上级目录的ID，由近及远排列
+func (folder *Folder) AncestorIDs() ([]uint, error) {
+	ids := []uint{folder.ID}
+	parentID := folder.ParentID
+
+	// 最大回溯65535层
+	for i := 0; parentID != nil && i < 65535; i++ {
+		var parent Folder
+		if err := DB.Select("id, parent_id").
+			Where("id = ? AND owner_id = ?", *parentID, folder.OwnerID).
+			First(&parent).Error; err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, parent.ID)
+		parentID = parent.ParentID
+	}
+
+	return ids, nil
+}
+
+// folderChainIDs 在 tx 中返回 id 目录自身及所有上级目录的ID，由近及远排列。
+// 回收站中的目录同样参与回溯，上级目录记录缺失时回溯到此为止
+func folderChainIDs(tx *gorm.DB, id uint) ([]uint, error) {
+	ids := make([]uint, 0, 8)
+
+	// 最大回溯65535层
+	for i := 0; id != 0 && i < 65535; i++ {
+		var folder Folder
+		err := tx.Unscoped().Select("id, parent_id").Where("id = ?", id).First(&folder).Error
+		if gorm.IsRecordNotFoundError(err) {
+			break
+		}
+
+		if err != nil {
+			return ids, err
+		}
+
+		ids = append(ids, folder.ID)
+		if folder.ParentID == nil {
+			break
+		}
+		id = *folder.ParentID
+	}
+
+	return ids, nil
+}
+
+// updateFolderSize 在 tx 中增加或减少 ids 目录记录的总大小，减少时不低于0
+func updateFolderSize(tx *gorm.DB, ids []uint, operator string, size uint64) error {
+	if len(ids) == 0 || size == 0 {
+		return nil
+	}
+
+	expr := gorm.Expr("size + ?", size)
+	if operator == "-" {
+		expr = gorm.Expr("CASE WHEN size > ? THEN size - ? ELSE 0 END", size, size)
+	}
+
+	return tx.Unscoped().Model(&Folder{}).Where("id in (?)", ids).UpdateColumn("size", expr).Error
+}
+
+// changeFolderSize 在 tx 中增加或减少 id 目录及其所有上级目录的总大小
+func changeFolderSize(tx *gorm.DB, id uint, operator string, size uint64) error {
+	if id == 0 || size == 0 {
+		return nil
+	}
+
+	ids, err := folderChainIDs(tx, id)
+	if err != nil {
+		return err
+	}
+
+	return updateFolderSize(tx, ids, operator, size)
+}
+
+// moveFolderSize 在 tx 中将 size 大小的内容从 src 目录计入 dst 目录，
+// 两者共同的上级目录总大小不变
+func moveFolderSize(tx *gorm.DB, src, dst uint, size uint64) error {
+	if src == dst || size == 0 {
+		return nil
+	}
+
+	srcIDs, err := folderChainIDs(tx, src)
+	if err != nil {
+		return err
+	}
+
+	dstIDs, err := folderChainIDs(tx, dst)
+	if err != nil {
+		return err
+	}
+
+	common := make(map[uint]bool, len(srcIDs))
+	for _, id := range srcIDs {
+		common[id] = true
+	}
+
+	var added []uint
+	for _, id := range dstIDs {
+		if common[id] {
+			delete(common, id)
+			continue
+		}
+		added = append(added, id)
+	}
+
+	var removed []uint
+	for _, id := range srcIDs {
+		if common[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	if err := updateFolderSize(tx, removed, "-", size); err != nil {
+		return err
+	}
+
+	return updateFolderSize(tx, added, "+", size)
+}
+
+// FolderSizeDrift 记录的总大小与实际值不一致的目录
+type FolderSizeDrift struct {
+	ID       uint   `json:"id"`
+	Recorded uint64 `json:"recorded"`
+	Actual   uint64 `json:"actual"`
+}
+
+// GetFolderSizeDrifts 按用户的文件重新统计其所有目录的总大小，返回与记录不一致的目录
+func GetFolderSizeDrifts(uid uint) ([]FolderSizeDrift, error) {
+	var folders []Folder
+	if err := DB.Select("id, parent_id, size").Where("owner_id = ?", uid).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.Model(&File{}).Where("user_id = ?", uid).
+		Select("folder_id, COALESCE(SUM(size), 0)").Group("folder_id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	parents := make(map[uint]uint, len(folders))
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			parents[folder.ID] = *folder.ParentID
+		}
+	}
+
+	// 将每个目录下的文件大小累加到其所有上级目录
+	actual := make(map[uint]uint64, len(folders))
+	for rows.Next() {
+		var (
+			folderID uint
+			size     uint64
+		)
+		if err := rows.Scan(&folderID, &size); err != nil {
+			return nil, err
+		}
+
+		for i := 0; folderID != 0 && i < 65535; i++ {
+			actual[folderID] += size
+			folderID = parents[folderID]
+		}
+	}
+
+	drifts := make([]FolderSizeDrift, 0)
+	for _, folder := range folders {
+		if folder.Size != actual[folder.ID] {
+			drifts = append(drifts, FolderSizeDrift{ID: folder.ID, Recorded: folder.Size, Actual: actual[folder.ID]})
+		}
+	}
+
+	return drifts, nil
+}
+
+// FixFolderSize 将目录记录的总大小修正为实际值
+func FixFolderSize(drift FolderSizeDrift) error {
+	return DB.Model(&Folder{}).Where("id = ?", drift.ID).UpdateColumn("size", drift.Actual).Error
+}
+
 // DeleteFolderByIDs 根据给定ID批量删除目录记录
 func DeleteFolderByIDs(ids []uint) error {
 	result := DB.Where("id in (?)", ids).Unscoped().Delete(&Folder{})
@@ -141,78 +483,147 @@ func GetFoldersByIDs(ids []uint, uid uint) ([]Folder, error) {
 	return folders, result.Error
 }
 
-// MoveOrCopyFileTo 将此目录下的files移动或复制至dstFolder，
-// 返回此操作新增的容量
-func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy bool) (uint64, error) {
-	// 已复制文件的总大小
-	var copiedSize uint64
+// GetFoldersByUserID 列出用户的所有目录
+func GetFoldersByUserID(uid uint) ([]Folder, error) {
+	var folders []Folder
+	result := DB.Where("owner_id = ?", uid).Find(&folders)
+	return folders, result.Error
+}
 
-	if isCopy {
-		// 检索出要复制的文件
-		var originFiles = make([]File, 0, len(files))
-		if err := DB.Where(
-			"id in (?) and user_id = ? and folder_id = ?",
-			files,
-			folder.OwnerID,
-			folder.ID,
-		).Find(&originFiles).Error; err != nil {
-			return 0, err
+// FolderPaths 根据父目录关系计算每个目录的完整路径
+func FolderPaths(folders []Folder) map[uint]string {
+	byID := make(map[uint]*Folder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	paths := make(map[uint]string, len(folders))
+	var resolve func(folder *Folder) string
+	resolve = func(folder *Folder) string {
+		if p, ok := paths[folder.ID]; ok {
+			return p
 		}
 
-		// 复制文件记录
-		for _, oldFile := range originFiles {
-			if !oldFile.CanCopy() {
-				util.Log().Warning("Cannot copy file %q because it's being uploaded now, skipping...", oldFile.Name)
-				continue
+		p := "/"
+		if folder.ParentID != nil {
+			if parent, ok := byID[*folder.ParentID]; ok {
+				p = path.Join(resolve(parent), folder.Name)
 			}
+		}
 
-			oldFile.Model = gorm.Model{}
-			oldFile.FolderID = dstFolder.ID
-			oldFile.UserID = dstFolder.OwnerID
+		paths[folder.ID] = p
+		return p
+	}
 
-			// webdav目标名重置
-			if dstFolder.WebdavDstName != "" {
-				oldFile.Name = dstFolder.WebdavDstName
-			}
+	for i := range folders {
+		resolve(&folders[i])
+	}
 
-			if err := DB.Create(&oldFile).Error; err != nil {
-				return copiedSize, err
-			}
+	return paths
+}
 
-			copiedSize += oldFile.Size
-		}
+// MoveOrCopyFileTo 将此目录下的files移动或复制至dstFolder，
+// 返回此操作新增的容量
+func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy bool) (uint64, error) {
+	if isCopy {
+		return folder.copyFilesTo(DB, files, dstFolder)
+	}
 
-	} else {
-		var updates = map[string]interface{}{
-			"folder_id": dstFolder.ID,
+	return 0, folder.moveFilesTo(DB, files, dstFolder)
+}
+
+// copyFilesTo 在 tx 中复制此目录下的 files 文件到 dstFolder，返回已复制文件的总大小
+func (folder *Folder) copyFilesTo(tx *gorm.DB, files []uint, dstFolder *Folder) (uint64, error) {
+	// 已复制文件的总大小
+	var copiedSize uint64
+
+	// 检索出要复制的文件
+	var originFiles = make([]File, 0, len(files))
+	if err := tx.Where(
+		"id in (?) and user_id = ? and folder_id = ?",
+		files,
+		folder.OwnerID,
+		folder.ID,
+	).Find(&originFiles).Error; err != nil {
+		return 0, err
+	}
+
+	// 复制文件记录
+	for _, oldFile := range originFiles {
+		if !oldFile.CanCopy() {
+			util.Log().Warning("Cannot copy file %q because it's being uploaded now, skipping...", oldFile.Name)
+			continue
 		}
+
+		oldFile.Model = gorm.Model{}
+		oldFile.FolderID = dstFolder.ID
+		oldFile.UserID = dstFolder.OwnerID
+
 		// webdav目标名重置
 		if dstFolder.WebdavDstName != "" {
-			updates["name"] = dstFolder.WebdavDstName
+			oldFile.Name = dstFolder.WebdavDstName
 		}
 
-		// 更改顶级要移动文件的父目录指向
-		err := DB.Model(File{}).Where(
+		if err := tx.Create(&oldFile).Error; err != nil {
+			return copiedSize, err
+		}
+
+		copiedSize += oldFile.Size
+	}
+
+	if err := changeFolderSize(tx, dstFolder.ID, "+", copiedSize); err != nil {
+		return copiedSize, err
+	}
+
+	return copiedSize, nil
+}
+
+// moveFilesTo 在 tx 中将此目录下的 files 文件移动到 dstFolder
+func (folder *Folder) moveFilesTo(tx *gorm.DB, files []uint, dstFolder *Folder) error {
+	var updates = map[string]interface{}{
+		"folder_id": dstFolder.ID,
+	}
+	// webdav目标名重置
+	if dstFolder.WebdavDstName != "" {
+		updates["name"] = dstFolder.WebdavDstName
+	}
+
+	// 统计要移动文件的总大小，计入目标目录
+	if folder.ID != dstFolder.ID {
+		var size uint64
+		if err := tx.Model(File{}).Where(
 			"id in (?) and user_id = ? and folder_id = ?",
 			files,
 			folder.OwnerID,
 			folder.ID,
-		).
-			Update(updates).
-			Error
-		if err != nil {
-			return 0, err
+		).Select("COALESCE(SUM(size), 0)").Row().Scan(&size); err != nil {
+			return err
 		}
 
+		if err := moveFolderSize(tx, folder.ID, dstFolder.ID, size); err != nil {
+			return err
+		}
 	}
 
-	return copiedSize, nil
-
+	// 更改顶级要移动文件的父目录指向
+	return tx.Model(File{}).Where(
+		"id in (?) and user_id = ? and folder_id = ?",
+		files,
+		folder.OwnerID,
+		folder.ID,
+	).
+		Update(updates).
+		Error
 }
 
 // CopyFolderTo 将此目录及其子目录及文件递归复制至dstFolder
 // 返回此操作新增的容量
 func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint64, err error) {
+	return folder.copyFolderTo(DB, folderID, dstFolder)
+}
+
+// copyFolderTo 在 tx 中将此目录下的 folderID 目录递归复制至 dstFolder
+func (folder *Folder) copyFolderTo(tx *gorm.DB, folderID uint, dstFolder *Folder) (size uint64, err error) {
 	// 列出所有子目录
 	subFolders, err := GetRecursiveChildFolder([]uint{folderID}, folder.OwnerID, true)
 	if err != nil {
@@ -249,7 +660,7 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 		folder.Model = gorm.Model{}
 		folder.ParentID = &newID
 		folder.OwnerID = dstFolder.OwnerID
-		if err = DB.Create(&folder).Error; err != nil {
+		if err = tx.Create(&folder).Error; err != nil {
 			return size, err
 		}
 		// 记录新的ID以便其子目录使用
@@ -259,7 +670,7 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 
 	// 复制文件
 	var originFiles = make([]File, 0, len(subFolderIDs))
-	if err := DB.Where(
+	if err := tx.Where(
 		"user_id = ? and folder_id in (?)",
 		folder.OwnerID,
 		subFolderIDs,
@@ -277,13 +688,18 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 		oldFile.Model = gorm.Model{}
 		oldFile.FolderID = newIDCache[oldFile.FolderID]
 		oldFile.UserID = dstFolder.OwnerID
-		if err := DB.Create(&oldFile).Error; err != nil {
+		if err := tx.Create(&oldFile).Error; err != nil {
 			return size, err
 		}
 
 		size += oldFile.Size
 	}
 
+	// 复制的目录记录已包含各自的总大小，只需计入目标目录
+	if err := changeFolderSize(tx, dstFolder.ID, "+", size); err != nil {
+		return size, err
+	}
+
 	return size, nil
 
 }
@@ -291,7 +707,11 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 // MoveFolderTo 将folder目录下的dirs子目录复制或移动到dstFolder，
 // 返回此过程中增加的容量
 func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
+	return folder.moveFolderTo(DB, dirs, dstFolder)
+}
 
+// moveFolderTo 在 tx 中将此目录下的 dirs 子目录移动到 dstFolder
+func (folder *Folder) moveFolderTo(tx *gorm.DB, dirs []uint, dstFolder *Folder) error {
 	// 如果目标位置为待移动的目录，会导致 parent 为自己
 	// 造成死循环且无法被除搜索以外的组件展示
 	if folder.OwnerID == dstFolder.OwnerID && util.ContainsUint(dirs, dstFolder.ID) {
@@ -306,16 +726,257 @@ func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
 		updates["name"] = dstFolder.WebdavDstName
 	}
 
+	// 统计要移动目录的总大小，计入目标目录
+	if folder.ID != dstFolder.ID {
+		var size uint64
+		if err := tx.Model(Folder{}).Where(
+			"id in (?) and owner_id = ? and parent_id = ?",
+			dirs,
+			folder.OwnerID,
+			folder.ID,
+		).Select("COALESCE(SUM(size), 0)").Row().Scan(&size); err != nil {
+			return err
+		}
+
+		if err := moveFolderSize(tx, folder.ID, dstFolder.ID, size); err != nil {
+			return err
+		}
+	}
+
 	// 更改顶级要移动目录的父目录指向
-	err := DB.Model(Folder{}).Where(
+	return tx.Model(Folder{}).Where(
 		"id in (?) and owner_id = ? and parent_id = ?",
 		dirs,
 		folder.OwnerID,
 		folder.ID,
 	).Update(updates).Error
+}
 
-	return err
+// ObjectError 批量操作中导致事务回滚的对象
+type ObjectError struct {
+	ID    uint
+	IsDir bool
+	Err   error
+}
+
+func (e *ObjectError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ObjectError) Unwrap() error {
+	return e.Err
+}
+
+// MoveObjectsTo 在事务中将此目录下的 dirs、files 移动到 dstFolder，
+// 任一对象移动失败时全部回滚，并返回 *ObjectError 指明出错的对象
+func (folder *Folder) MoveObjectsTo(dirs, files []uint, dstFolder *Folder) error {
+	tx := DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	for _, dir := range dirs {
+		if err := folder.moveFolderTo(tx, []uint{dir}, dstFolder); err != nil {
+			tx.Rollback()
+			return &ObjectError{ID: dir, IsDir: true, Err: err}
+		}
+	}
+
+	for _, file := range files {
+		if err := folder.moveFilesTo(tx, []uint{file}, dstFolder); err != nil {
+			tx.Rollback()
+			return &ObjectError{ID: file, Err: err}
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// CopyObjectsTo 在事务中将此目录下的 dirs、files 复制到 dstFolder，并增加目标用户的已用容量。
+// 任一对象复制失败时全部回滚，并返回 *ObjectError 指明出错的对象；成功时返回新增的容量
+func (folder *Folder) CopyObjectsTo(dirs, files []uint, dstFolder *Folder) (uint64, error) {
+	tx := DB.Begin()
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	var size uint64
+	for _, dir := range dirs {
+		copied, err := folder.copyFolderTo(tx, dir, dstFolder)
+		if err != nil {
+			tx.Rollback()
+			return 0, &ObjectError{ID: dir, IsDir: true, Err: err}
+		}
+		size += copied
+	}
+
+	for _, file := range files {
+		copied, err := folder.copyFilesTo(tx, []uint{file}, dstFolder)
+		if err != nil {
+			tx.Rollback()
+			return 0, &ObjectError{ID: file, Err: err}
+		}
+		size += copied
+	}
+
+	if size > 0 {
+		owner := &User{}
+		owner.ID = dstFolder.OwnerID
+		if err := owner.ChangeStorage(tx, "+", size); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// DeleteObjects 在事务中删除文件记录、目录记录及其对应的分享，并扣除用户已用容量
+func DeleteObjects(files []*File, dirs []uint, uid uint) error {
+	tx := DB.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if err := deleteFiles(tx, files, uid); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(files) > 0 {
+		fileIDs := make([]uint, len(files))
+		for i, file := range files {
+			fileIDs[i] = file.ID
+		}
+
+		if err := tx.Where("source_id in (?) and is_dir = ?", fileIDs, false).Delete(&Share{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(dirs) > 0 {
+		if err := tx.Where("id in (?)", dirs).Unscoped().Delete(&Folder{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := tx.Where("source_id in (?) and is_dir = ?", dirs, true).Delete(&Share{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// TransferObjects 在事务中将对象转移给其他用户，dirs、files 为移动到 dstFolder 下的顶层对象，
+// allDirs、allFiles 为包含子对象在内的所有对象，size 为所有文件的总大小
+func TransferObjects(srcUID uint, dstFolder *Folder, dirs, files, allDirs, allFiles []uint, size uint64) error {
+	tx := DB.Begin()
+	dstUID := dstFolder.OwnerID
+
+	if len(allFiles) > 0 {
+		if err := tx.Model(&File{}).Where("id in (?) and user_id = ?", allFiles, srcUID).
+			Update("user_id", dstUID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 顶层对象移出原目录，总大小计入目标目录
+	if err := moveSizeByParent(tx, &File{}, "folder_id", files, dstFolder.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := moveSizeByParent(tx, &Folder{}, "parent_id", dirs, dstFolder.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(files) > 0 {
+		if err := tx.Model(&File{}).Where("id in (?)", files).
+			Update("folder_id", dstFolder.ID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(allDirs) > 0 {
+		if err := tx.Model(&Folder{}).Where("id in (?) and owner_id = ?", allDirs, srcUID).
+			Update("owner_id", dstUID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(dirs) > 0 {
+		if err := tx.Model(&Folder{}).Where("id in (?)", dirs).
+			Update("parent_id", dstFolder.ID).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if size > 0 {
+		src, dst := &User{}, &User{}
+		src.ID, dst.ID = srcUID, dstUID
+		if err := src.ChangeStorage(tx, "-", size); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := dst.ChangeStorage(tx, "+", size); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// moveSizeByParent 在 tx 中按原父目录统计 ids 对象的总大小，并将其从原父目录计入 dst 目录，
+// column 为对象指向父目录的字段
+func moveSizeByParent(tx *gorm.DB, value interface{}, column string, ids []uint, dst uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.Model(value).Where("id in (?)", ids).
+		Select(column + ", COALESCE(SUM(size), 0)").Group(column).Rows()
+	if err != nil {
+		return err
+	}
+
+	sizes := make(map[uint]uint64)
+	var parents []uint
+	for rows.Next() {
+		var (
+			parent uint
+			size   uint64
+		)
+		if err := rows.Scan(&parent, &size); err != nil {
+			rows.Close()
+			return err
+		}
+
+		parents = append(parents, parent)
+		sizes[parent] = size
+	}
+	rows.Close()
+
+	for _, parent := range parents {
+		if err := moveFolderSize(tx, parent, dst, sizes[parent]); err != nil {
+			return err
+		}
+	}
 
+	return nil
 }
 
 // Rename 重命名目录
@@ -79,6 +79,65 @@ func TestFolder_GetChild(t *testing.T) {
 	}
 }
 
+func TestFolder_GetChildCaseInsensitive(t *testing.T) {
+	asserts := assert.New(t)
+	folder := Folder{
+		Model:   gorm.Model{ID: 5},
+		OwnerID: 1,
+		Name:    "/",
+	}
+
+	// 优先返回名称完全一致的目录
+	{
+		mock.ExpectQuery("SELECT(.+)LOWER(.+)").
+			WithArgs(5, 1, "Docs").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "docs").AddRow(2, "Docs"))
+		sub, err := folder.GetChildCaseInsensitive("Docs")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, sub.ID)
+		asserts.Equal("/", sub.Position)
+	}
+
+	// 保留原始大小写
+	{
+		mock.ExpectQuery("SELECT(.+)LOWER(.+)").
+			WithArgs(5, 1, "DOCS").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "docs"))
+		sub, err := folder.GetChildCaseInsensitive("DOCS")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("docs", sub.Name)
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)LOWER(.+)").
+			WithArgs(5, 1, "sub").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		_, err := folder.GetChildCaseInsensitive("sub")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(gorm.ErrRecordNotFound, err)
+	}
+}
+
+func TestFolder_HasChildCaseInsensitive(t *testing.T) {
+	asserts := assert.New(t)
+	folder := Folder{OwnerID: 1}
+	folder.ID = 5
+
+	mock.ExpectQuery("SELECT count(.+)folders(.+)LOWER(.+)").
+		WithArgs(5, 1, "Docs", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	asserts.True(folder.HasChildCaseInsensitive("Docs", true, 3))
+
+	mock.ExpectQuery("SELECT count(.+)files(.+)LOWER(.+)").
+		WithArgs(5, "a.txt", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	asserts.False(folder.HasChildCaseInsensitive("a.txt", false, 3))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestFolder_GetChildFolder(t *testing.T) {
 	asserts := assert.New(t)
 	folder := &Folder{
@@ -202,6 +261,18 @@ func TestGetFoldersByIDs(t *testing.T) {
 	}
 }
 
+func TestGetFoldersByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	folders, err := GetFoldersByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(folders, 2)
+}
+
 func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 	asserts := assert.New(t)
 	// 当前目录
@@ -237,6 +308,12 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
+		// 计入目标目录总大小
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(30, 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		storage, err := folder.MoveOrCopyFileTo(
 			[]uint{1, 2, 3},
 			&dstFolder,
@@ -298,6 +375,8 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 
 	// 移动文件 成功
 	{
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WithArgs(1, 2, 1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 1).
@@ -315,6 +394,8 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 
 	// 移动文件 出错
 	{
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WithArgs(1, 2, 1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 1).
@@ -386,6 +467,13 @@ func TestFolder_CopyFolderTo(t *testing.T) {
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(6, 1))
 		mock.ExpectCommit()
 
+		// 计入目标目录总大小
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(30, 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
 		size, err := parFolder.CopyFolderTo(2, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
@@ -512,6 +600,23 @@ func TestFolder_MoveOrCopyFolderTo_Move(t *testing.T) {
 
 	// 成功
 	{
+		// 总大小从原目录计入目标目录，共同的上级目录不变
+		mock.ExpectQuery("SELECT(.+)SUM(.+)folders(.+)").WithArgs(1, 2, 1, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(20))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(9, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(20, 20, 9).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(20, 10).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 9).
@@ -620,3 +725,410 @@ func TestFolder_Rename(t *testing.T) {
 		asserts.Error(err)
 	}
 }
+
+func TestTransferObjects(t *testing.T) {
+	asserts := assert.New(t)
+	dst := &Folder{OwnerID: 2}
+	dst.ID = 10
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(sqlmock.AnyArg(), 2, 1, 2, 1).WillReturnResult(sqlmock.NewResult(0, 2))
+		// 顶层对象的总大小从原目录计入目标目录
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"folder_id", "sum"}).AddRow(5, 10))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(5, nil))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(10, 10, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(10, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)GROUP BY(.+)").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"parent_id", "sum"}).AddRow(5, 0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(10, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(2, sqlmock.AnyArg(), 3, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(10, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(20, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(20, sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		err := TransferObjects(1, dst, []uint{3}, []uint{1}, []uint{3}, []uint{1, 2}, 20)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := TransferObjects(1, dst, nil, []uint{1}, nil, []uint{1}, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestFolder_MoveObjectsTo(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{OwnerID: 1}
+	folder.ID = 9
+	dst := &Folder{OwnerID: 1}
+	dst.ID = 10
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)SUM(.+)folders(.+)").WithArgs(1, 1, 9).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(10, sqlmock.AnyArg(), 1, 1, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WithArgs(2, 1, 9).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(10, sqlmock.AnyArg(), 2, 1, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WithArgs(3, 1, 9).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(10, sqlmock.AnyArg(), 3, 1, 9).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(folder.MoveObjectsTo([]uint{1}, []uint{2, 3}, dst))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 其中一个文件失败，全部回滚
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)SUM(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := folder.MoveObjectsTo([]uint{1}, []uint{2, 3}, dst)
+		asserts.NoError(mock.ExpectationsWereMet())
+		var objectErr *ObjectError
+		asserts.True(errors.As(err, &objectErr))
+		asserts.EqualValues(2, objectErr.ID)
+		asserts.False(objectErr.IsDir)
+	}
+
+	// 移动目录到自身
+	{
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		err := folder.MoveObjectsTo([]uint{10}, nil, dst)
+		asserts.NoError(mock.ExpectationsWereMet())
+		var objectErr *ObjectError
+		asserts.True(errors.As(err, &objectErr))
+		asserts.EqualValues(10, objectErr.ID)
+		asserts.True(objectErr.IsDir)
+	}
+}
+
+func TestFolder_CopyObjectsTo(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{OwnerID: 1}
+	folder.ID = 9
+	dst := &Folder{OwnerID: 1}
+	dst.ID = 10
+
+	// 成功，增加容量
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2, 1, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(2, 10))
+		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(10, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3, 1, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(3, 20))
+		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(6, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(20, 10).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(30, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		size, err := folder.CopyObjectsTo(nil, []uint{2, 3}, dst)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(30, size)
+	}
+
+	// 插入失败，全部回滚
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(2, 10))
+		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(3, 20))
+		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		size, err := folder.CopyObjectsTo(nil, []uint{2, 3}, dst)
+		asserts.NoError(mock.ExpectationsWereMet())
+		var objectErr *ObjectError
+		asserts.True(errors.As(err, &objectErr))
+		asserts.EqualValues(3, objectErr.ID)
+		asserts.EqualValues(0, size)
+	}
+}
+
+func TestDeleteObjects(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(10, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WithArgs(sqlmock.AnyArg(), 2, false).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE(.+)folders(.+)").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WithArgs(sqlmock.AnyArg(), 3, true).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		file := &File{UserID: 1, Size: 10}
+		file.ID = 2
+		asserts.NoError(DeleteObjects([]*File{file}, []uint{3}, 1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除目录失败，文件记录一并回滚
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE(.+)folders(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		file := &File{UserID: 1, Size: 10}
+		file.ID = 2
+		asserts.Error(DeleteObjects([]*File{file}, []uint{3}, 1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFolder_WalkChildFiles(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{Model: gorm.Model{ID: 1}, Name: "docs", Position: "/"}
+	filter := &ChildFilter{Keyword: "report", MinSize: 10}
+
+	// 分批遍历，最后一批不满时结束
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)LIMIT 2").
+			WithArgs(1, 0, "%report%", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "report1.txt").AddRow(5, "report2.txt"))
+		mock.ExpectQuery("SELECT(.+)files(.+)LIMIT 2").
+			WithArgs(1, 5, "%report%", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(8, "report3.txt"))
+		var names []string
+		err := folder.WalkChildFiles(2, filter, func(files []File) error {
+			for _, file := range files {
+				asserts.Equal("/docs", file.Position)
+				names = append(names, file.Name)
+			}
+			return nil
+		})
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]string{"report1.txt", "report2.txt", "report3.txt"}, names)
+	}
+
+	// 回调出错时终止
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "report1.txt").AddRow(5, "report2.txt"))
+		err := folder.WalkChildFiles(2, nil, func(files []File) error {
+			return errors.New("error")
+		})
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询出错
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(folder.WalkChildFiles(2, nil, func(files []File) error { return nil }))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFolder_WalkChildFolders(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{Model: gorm.Model{ID: 1}, Name: "/"}
+
+	// 文件大小条件不作用于目录
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(1, 0, "%sub%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "sub"))
+	var count int
+	err := folder.WalkChildFolders(2, &ChildFilter{Keyword: "sub", MinSize: 10}, func(folders []Folder) error {
+		count += len(folders)
+		asserts.Equal("/", folders[0].Position)
+		return nil
+	})
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(1, count)
+}
+
+func TestGetFolderSize(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").
+			WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(30))
+		size, err := GetFolderSize(1, 1)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(30, size)
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		size, err := GetFolderSize(1, 1)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(0, size)
+	}
+}
+
+func TestFolder_AncestorIDs(t *testing.T) {
+	asserts := assert.New(t)
+	parentID := uint(2)
+	folder := &Folder{Model: gorm.Model{ID: 3}, ParentID: &parentID, OwnerID: 1}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		ids, err := folder.AncestorIDs()
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]uint{3, 2, 1}, ids)
+	}
+
+	// 上级目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(2, 1).
+			WillReturnError(errors.New("error"))
+		_, err := folder.AncestorIDs()
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestChangeFolderSize(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 大小为0，无需更新
+	{
+		asserts.NoError(changeFolderSize(DB, 1, "+", 0))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 回溯至上级目录缺失为止
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(3, 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)CASE WHEN(.+)").WithArgs(10, 10, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(changeFolderSize(DB, 3, "-", 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3).WillReturnError(errors.New("error"))
+		asserts.Error(changeFolderSize(DB, 3, "+", 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestMoveFolderSize(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 同一目录内移动
+	{
+		asserts.NoError(moveFolderSize(DB, 2, 2, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 移动到子目录，只计入新增的上级目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(3, 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(10, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(moveFolderSize(DB, 2, 3, 10))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetFolderSizeDrifts(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size"}).
+				AddRow(1, nil, 30).AddRow(2, 1, 10).AddRow(3, 2, 10).AddRow(4, 1, 0))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"folder_id", "sum"}).AddRow(1, 5).AddRow(3, 20))
+		drifts, err := GetFolderSizeDrifts(1)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]FolderSizeDrift{
+			{ID: 1, Recorded: 30, Actual: 25},
+			{ID: 2, Recorded: 10, Actual: 20},
+			{ID: 3, Recorded: 10, Actual: 20},
+		}, drifts)
+	}
+
+	// 统计文件失败
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).WillReturnError(errors.New("error"))
+		_, err := GetFolderSizeDrifts(1)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFolderPaths(t *testing.T) {
+	asserts := assert.New(t)
+	root, docs := uint(1), uint(2)
+	paths := FolderPaths([]Folder{
+		{Model: gorm.Model{ID: 3}, Name: "work", ParentID: &docs},
+		{Model: gorm.Model{ID: 1}, Name: "/"},
+		{Model: gorm.Model{ID: 2}, Name: "docs", ParentID: &root},
+	})
+	asserts.Equal("/", paths[1])
+	asserts.Equal("/docs", paths[2])
+	asserts.Equal("/docs/work", paths[3])
+}
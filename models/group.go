@@ -36,6 +36,14 @@ type GroupOption struct {
 	Aria2BatchSize   int                    `json:"aria2_batch,omitempty"`
 	AdvanceDelete    bool                   `json:"advance_delete,omitempty"`
 	WebDAVProxy      bool                   `json:"webdav_proxy,omitempty"`
+	TorrentCreate    bool                   `json:"torrent_create,omitempty"`     // 制作种子
+	RateLimit        int                    `json:"rate_limit,omitempty"`         // 每分钟最大请求数，0 为不限制
+	TrashRetention   int                    `json:"trash_retention,omitempty"`    // 回收站保留天数，0 为不开启回收站
+	UploadSpeedLimit int                    `json:"upload_speed_limit,omitempty"` // 上传限速，单位为字节每秒，0 为不限制
+	Require2FA       bool                   `json:"require_2fa,omitempty"`        // 强制开启二步验证
+	URLFetch         bool                   `json:"url_fetch,omitempty"`          // 从 URL 下载
+	MaxFiles         int                    `json:"max_files,omitempty"`          // 最大文件数，0 为不限制
+	MaxDepth         int                    `json:"max_depth,omitempty"`          // 最大目录层级，0 为不限制
 }
 
 // GetGroupByID 用ID获取用户组
@@ -82,3 +90,30 @@ func (group *Group) SerializePolicyList() (err error) {
 	group.Options = string(optionsValue)
 	return err
 }
+
+// AddMembers 将指定用户移入此用户组，初始用户不受影响
+func (group *Group) AddMembers(uids []uint) error {
+	if len(uids) == 0 {
+		return nil
+	}
+
+	return DB.Model(&User{}).Where("id in (?) and id <> 1", uids).Update("group_id", group.ID).Error
+}
+
+// RemoveMembers 将此用户组中的指定用户移入 fallback 用户组，uids 为 nil 时移出所有成员，
+// 初始用户不受影响
+func (group *Group) RemoveMembers(uids []uint, fallback uint) error {
+	if fallback == group.ID {
+		return nil
+	}
+
+	tx := DB.Model(&User{}).Where("group_id = ? and id <> 1", group.ID)
+	if uids != nil {
+		if len(uids) == 0 {
+			return nil
+		}
+		tx = tx.Where("id in (?)", uids)
+	}
+
+	return tx.Update("group_id", fallback).Error
+}
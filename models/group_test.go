@@ -75,3 +75,45 @@ func TestGroup_BeforeSave(t *testing.T) {
 	}
 
 }
+
+func TestGroup_AddMembers(t *testing.T) {
+	asserts := assert.New(t)
+	group := &Group{Model: gorm.Model{ID: 2}}
+
+	// 无用户
+	asserts.NoError(group.AddMembers(nil))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)users(.+)group_id(.+)").WithArgs(2, sqlmock.AnyArg(), 3, 4).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+	asserts.NoError(group.AddMembers([]uint{3, 4}))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestGroup_RemoveMembers(t *testing.T) {
+	asserts := assert.New(t)
+	group := &Group{Model: gorm.Model{ID: 4}}
+
+	// 移入自身
+	asserts.NoError(group.RemoveMembers(nil, 4))
+
+	// 空列表
+	asserts.NoError(group.RemoveMembers([]uint{}, 2))
+
+	// 移出指定成员
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)users(.+)group_id(.+)").WithArgs(2, sqlmock.AnyArg(), 4, 5).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(group.RemoveMembers([]uint{5}, 2))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 移出所有成员
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)users(.+)group_id(.+)").WithArgs(2, sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectCommit()
+	asserts.NoError(group.RemoveMembers(nil, 2))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -19,8 +19,16 @@ import (
 // DB 数据库链接单例
 var DB *gorm.DB
 
-// Init 初始化 MySQL 链接
+// Init 初始化数据库连接并执行迁移
 func Init() {
+	Connect()
+
+	//执行迁移
+	migration()
+}
+
+// Connect 初始化数据库连接，不执行迁移
+func Connect() {
 	util.Log().Info("Initializing database connection...")
 
 	var (
@@ -43,12 +51,7 @@ func Init() {
 			// 未指定数据库或者明确指定为 sqlite 时，使用 SQLite 数据库
 			db, err = gorm.Open("sqlite", util.RelativePath(conf.DatabaseConfig.DBFile))
 		case "postgres":
-			db, err = gorm.Open(confDBType, fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
-				conf.DatabaseConfig.Host,
-				conf.DatabaseConfig.User,
-				conf.DatabaseConfig.Password,
-				conf.DatabaseConfig.Name,
-				conf.DatabaseConfig.Port))
+			db, err = gorm.Open(confDBType, postgresDSN())
 		case "mysql", "mssql":
 			var host string
 			if conf.DatabaseConfig.UnixSocket {
@@ -100,7 +103,4 @@ func Init() {
 	db.DB().SetConnMaxLifetime(time.Second * 30)
 
 	DB = db
-
-	//执行迁移
-	migration()
 }
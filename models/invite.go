@@ -0,0 +1,92 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Invite 注册邀请码
+type Invite struct {
+	gorm.Model
+	Code      string `gorm:"size:32;unique_index"`
+	CreatorID uint   `gorm:"index"`
+	// GroupID 注册后所属的用户组，为 0 时使用默认用户组
+	GroupID uint
+	// ExtraStorage 注册后附加的存储容量
+	ExtraStorage uint64
+	// MaxUses 最大使用次数，为 0 时不限制
+	MaxUses   int
+	Uses      int
+	ExpiresAt *time.Time
+}
+
+// Create 创建邀请码
+func (invite *Invite) Create() error {
+	return DB.Create(invite).Error
+}
+
+// Usable 邀请码是否仍可使用
+func (invite *Invite) Usable(now time.Time) bool {
+	if invite.ExpiresAt != nil && now.After(*invite.ExpiresAt) {
+		return false
+	}
+
+	return invite.MaxUses == 0 || invite.Uses < invite.MaxUses
+}
+
+// Use 占用一次使用次数，次数已用尽时返回 false
+func (invite *Invite) Use() (bool, error) {
+	result := DB.Model(&Invite{}).Where("id = ? and (max_uses = 0 or uses < max_uses)", invite.ID).
+		Update("uses", gorm.Expr("uses + 1"))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	invite.Uses++
+	return true, nil
+}
+
+// Release 归还一次使用次数，用于注册失败时回滚
+func (invite *Invite) Release() error {
+	return DB.Model(&Invite{}).Where("id = ? and uses > 0", invite.ID).
+		Update("uses", gorm.Expr("uses - 1")).Error
+}
+
+// GetInviteByCode 用邀请码获取邀请
+func GetInviteByCode(code string) (Invite, error) {
+	var invite Invite
+	result := DB.Where("code = ?", code).First(&invite)
+	return invite, result.Error
+}
+
+// ListInvitesByCreator 列出用户创建的邀请码
+func ListInvitesByCreator(uid uint) ([]Invite, error) {
+	var invites []Invite
+	result := DB.Where("creator_id = ?", uid).Order("id desc").Find(&invites)
+	return invites, result.Error
+}
+
+// CountActiveInvitesByCreator 统计用户创建的仍可使用的邀请码数量
+func CountActiveInvitesByCreator(uid uint, now time.Time) (int, error) {
+	count := 0
+	result := DB.Model(&Invite{}).
+		Where("creator_id = ? and (max_uses = 0 or uses < max_uses) and (expires_at is NULL or expires_at > ?)", uid, now).
+		Count(&count)
+	return count, result.Error
+}
+
+// DeleteInvite 删除邀请码，uid 不为 0 时只删除该用户创建的邀请码
+func DeleteInvite(id, uid uint) (int64, error) {
+	tx := DB.Where("id = ?", id)
+	if uid > 0 {
+		tx = tx.Where("creator_id = ?", uid)
+	}
+
+	result := tx.Delete(&Invite{})
+	return result.RowsAffected, result.Error
+}
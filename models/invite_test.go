@@ -0,0 +1,113 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvite_Usable(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	asserts.True((&Invite{}).Usable(now))
+	asserts.True((&Invite{MaxUses: 2, Uses: 1, ExpiresAt: &future}).Usable(now))
+	asserts.False((&Invite{MaxUses: 1, Uses: 1}).Usable(now))
+	asserts.False((&Invite{ExpiresAt: &past}).Usable(now))
+}
+
+func TestInvite_Use(t *testing.T) {
+	asserts := assert.New(t)
+	invite := &Invite{Model: gorm.Model{ID: 1}, MaxUses: 1}
+
+	// 数据库错误
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)invites(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		ok, err := invite.Use()
+		asserts.Error(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 次数已用尽
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)invites(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := invite.Use()
+		asserts.NoError(err)
+		asserts.False(ok)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)invites(.+)uses(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		ok, err := invite.Use()
+		asserts.NoError(err)
+		asserts.True(ok)
+		asserts.Equal(1, invite.Uses)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 归还
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)invites(.+)uses(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(invite.Release())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetInviteByCode(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)invites(.+)").WithArgs("code").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "code"))
+	invite, err := GetInviteByCode("code")
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, invite.ID)
+}
+
+func TestListInvitesByCreator(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)invites(.+)creator_id(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	invites, err := ListInvitesByCreator(1)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(invites, 2)
+
+	mock.ExpectQuery("SELECT count(.+)invites(.+)").WithArgs(1, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := CountActiveInvitesByCreator(1, time.Now())
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(3, count)
+}
+
+func TestDeleteInvite(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)invites(.+)").WithArgs(sqlmock.AnyArg(), 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	n, err := DeleteInvite(1, 2)
+	asserts.NoError(err)
+	asserts.EqualValues(1, n)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// listingVersionPrefix 目录列表版本号的缓存前缀，后缀为对象所有者的 UID，
+// 为 0 时对应无法确定所有者的变更，所有用户的目录列表都会受影响
+const listingVersionPrefix = "listing_version_"
+
+var (
+	fileType   = reflect.TypeOf(File{})
+	folderType = reflect.TypeOf(Folder{})
+	policyType = reflect.TypeOf(Policy{})
+)
+
+func init() {
+	gorm.DefaultCallback.Create().After("gorm:create").Register("cloudreve:listing_version", bumpListingVersion)
+	gorm.DefaultCallback.Update().After("gorm:update").Register("cloudreve:listing_version", bumpListingVersion)
+	gorm.DefaultCallback.Delete().After("gorm:delete").Register("cloudreve:listing_version", bumpListingVersion)
+}
+
+// bumpListingVersion 文件、目录或存储策略记录写入后重新生成相关的目录列表版本号。
+// 通过 UpdateColumn 等不触发模型钩子的方式更新时同样生效
+func bumpListingVersion(scope *gorm.Scope) {
+	if scope.HasError() {
+		return
+	}
+
+	var owner string
+	switch scope.GetModelStruct().ModelType {
+	case fileType:
+		owner = "UserID"
+	case folderType:
+		owner = "OwnerID"
+	case policyType:
+	default:
+		return
+	}
+
+	var uid uint
+	if owner != "" && scope.IndirectValue().Kind() == reflect.Struct {
+		if field, ok := scope.FieldByName(owner); ok {
+			uid, _ = field.Field.Interface().(uint)
+		}
+	}
+
+	_ = cache.Set(listingVersionPrefix+strconv.FormatUint(uint64(uid), 10), util.RandStringRunes(16), 0)
+}
+
+// ListingVersion 返回用户 uid 所拥有目录的列表版本号，用户的文件或目录记录发生任何变更后改变
+func ListingVersion(uid uint) string {
+	keys := []string{"0", strconv.FormatUint(uint64(uid), 10)}
+	values, _ := cache.Store.Gets(keys, listingVersionPrefix)
+
+	version := ""
+	for _, key := range keys {
+		value, ok := values[key].(string)
+		if !ok {
+			// 缓存丢失时生成新的版本号，之前的 ETag 随之失效
+			value = util.RandStringRunes(16)
+			_ = cache.Set(listingVersionPrefix+key, value, 0)
+		}
+		version += value + "."
+	}
+
+	return version[:len(version)-1]
+}
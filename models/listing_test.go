@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListingVersion(t *testing.T) {
+	asserts := assert.New(t)
+	version := ListingVersion(1)
+	asserts.Equal(version, ListingVersion(1))
+	other := ListingVersion(2)
+
+	// 已知所有者的文件变更只影响所有者
+	{
+		file := File{Model: gorm.Model{ID: 1}, UserID: 1}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(file.UpdatePicInfo("1,1"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEqual(version, ListingVersion(1))
+		asserts.Equal(other, ListingVersion(2))
+	}
+
+	// 无法确定所有者的变更影响所有用户
+	{
+		version = ListingVersion(1)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DB.Model(&Folder{}).Where("id in (?)", []uint{1}).UpdateColumn("size", 1).Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEqual(version, ListingVersion(1))
+		asserts.NotEqual(other, ListingVersion(2))
+	}
+
+	// 其他记录的变更不影响
+	{
+		version = ListingVersion(1)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tags(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DB.Model(&Tag{}).Where("id = ?", 1).UpdateColumn("name", "1").Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(version, ListingVersion(1))
+	}
+}
@@ -0,0 +1,134 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// LoginSessionKey 会话中保存登录会话令牌的键
+	LoginSessionKey = "login_session"
+
+	// loginSessionCachePrefix 已校验的登录会话令牌的缓存前缀
+	loginSessionCachePrefix = "login_session_"
+
+	// loginSessionTouchInterval 最后活跃时间的最小更新间隔，期间不再查询数据库校验会话
+	loginSessionTouchInterval = 5 * time.Minute
+
+	// LoginSessionMaxAge 会话 Cookie 的有效期，已吊销的登录会话至少保留这么久
+	LoginSessionMaxAge = 60 * 24 * time.Hour
+)
+
+// LoginSession 用户的登录会话，用于列出和吊销已登录的设备。会话被吊销后软删除，
+// 以便区分已吊销的会话与尚未登记的会话
+type LoginSession struct {
+	gorm.Model
+	UserID     uint   `gorm:"index:login_session_user"`
+	Token      string `gorm:"size:64;unique_index:login_session_token"`
+	Device     string
+	IP         string `gorm:"size:64"`
+	UserAgent  string `gorm:"type:text"`
+	LastActive time.Time
+}
+
+// NewLoginSession 为用户登记新的登录会话
+func NewLoginSession(uid uint, ip, userAgent, device string) (*LoginSession, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+
+	session := &LoginSession{
+		UserID:     uid,
+		Token:      hex.EncodeToString(buf),
+		Device:     device,
+		IP:         ip,
+		UserAgent:  userAgent,
+		LastActive: time.Now(),
+	}
+
+	if err := DB.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	session.cache()
+	return session, nil
+}
+
+// GetLoginSession 根据令牌查找登录会话，包括已吊销的会话
+func GetLoginSession(token string) (*LoginSession, error) {
+	var session LoginSession
+	result := DB.Unscoped().Where("token = ?", token).First(&session)
+	return &session, result.Error
+}
+
+// GetLoginSessionByID 查找用户未吊销的登录会话
+func GetLoginSessionByID(id, uid uint) (*LoginSession, error) {
+	var session LoginSession
+	result := DB.Where("id = ? and user_id = ?", id, uid).First(&session)
+	return &session, result.Error
+}
+
+// ListLoginSessionsByUser 列出用户未吊销的登录会话，最近活跃的在前
+func ListLoginSessionsByUser(uid uint) ([]LoginSession, error) {
+	var sessions []LoginSession
+	result := DB.Where("user_id = ?", uid).Order("last_active desc").Find(&sessions)
+	return sessions, result.Error
+}
+
+// LoginSessionCached 返回令牌是否属于用户且在最近一次校验的有效期内
+func LoginSessionCached(token string, uid uint) bool {
+	cached, ok := cache.Get(loginSessionCachePrefix + token)
+	return ok && cached == uid
+}
+
+// Revoked 返回登录会话是否已被吊销
+func (session *LoginSession) Revoked() bool {
+	return session.DeletedAt != nil
+}
+
+// Touch 更新最后活跃时间及 IP，并缓存校验结果
+func (session *LoginSession) Touch(ip string, now time.Time) error {
+	if now.Sub(session.LastActive) >= loginSessionTouchInterval || session.IP != ip {
+		session.LastActive = now
+		session.IP = ip
+		if err := DB.Model(session).UpdateColumns(map[string]interface{}{
+			"last_active": now,
+			"ip":          ip,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	session.cache()
+	return nil
+}
+
+// Revoke 吊销登录会话
+func (session *LoginSession) Revoke() error {
+	cache.Deletes([]string{session.Token}, loginSessionCachePrefix)
+	return DB.Delete(session).Error
+}
+
+func (session *LoginSession) cache() {
+	_ = cache.Set(loginSessionCachePrefix+session.Token, session.UserID, int(loginSessionTouchInterval.Seconds()))
+}
+
+// DeleteLoginSessionsByUserID 彻底删除用户的所有登录会话，返回删除的条数
+func DeleteLoginSessionsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&LoginSession{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteInactiveLoginSessions 彻底删除在 before 之后未活跃的登录会话，
+// 已吊销的会话在 revokedBefore 之前吊销的才会删除
+func DeleteInactiveLoginSessions(before, revokedBefore time.Time) (int64, error) {
+	result := DB.Unscoped().
+		Where("(deleted_at IS NULL AND last_active < ?) OR deleted_at < ?", before, revokedBefore).
+		Delete(&LoginSession{})
+	return result.RowsAffected, result.Error
+}
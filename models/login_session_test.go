@@ -0,0 +1,130 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLoginSession(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)login_sessions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		session, err := NewLoginSession(1, "127.0.0.1", "ua", "Chrome")
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(session.Token, 64)
+		asserts.Equal("Chrome", session.Device)
+		asserts.True(LoginSessionCached(session.Token, 1))
+		asserts.False(LoginSessionCached(session.Token, 2))
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)login_sessions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		session, err := NewLoginSession(1, "127.0.0.1", "ua", "Chrome")
+		asserts.Error(err)
+		asserts.Nil(session)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetLoginSession(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)login_sessions(.+)").
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "deleted_at"}).AddRow(1, 2, time.Now()))
+	session, err := GetLoginSession("token")
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(2, session.UserID)
+	asserts.True(session.Revoked())
+}
+
+func TestLoginSession_Touch(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	// 间隔内且 IP 未变化，不更新
+	{
+		session := &LoginSession{Model: gorm.Model{ID: 1}, UserID: 1, Token: "touch1", IP: "1.1.1.1", LastActive: now.Add(-time.Minute)}
+		asserts.NoError(session.Touch("1.1.1.1", now))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(LoginSessionCached("touch1", 1))
+	}
+
+	// IP 变化
+	{
+		session := &LoginSession{Model: gorm.Model{ID: 1}, UserID: 1, Token: "touch2", IP: "1.1.1.1", LastActive: now.Add(-time.Minute)}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)login_sessions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(session.Touch("2.2.2.2", now))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("2.2.2.2", session.IP)
+		asserts.Equal(now, session.LastActive)
+	}
+
+	// 数据库错误
+	{
+		session := &LoginSession{Model: gorm.Model{ID: 1}, UserID: 1, Token: "touch3", LastActive: now.Add(-time.Hour)}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)login_sessions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(session.Touch("", now))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(LoginSessionCached("touch3", 1))
+	}
+}
+
+func TestLoginSession_Revoke(t *testing.T) {
+	asserts := assert.New(t)
+	session := &LoginSession{Model: gorm.Model{ID: 1}, UserID: 1, Token: "revoke"}
+	session.cache()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)login_sessions(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(session.Revoke())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.False(LoginSessionCached("revoke", 1))
+}
+
+func TestListLoginSessionsByUser(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)login_sessions(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	sessions, err := ListLoginSessionsByUser(1)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(sessions, 2)
+}
+
+func TestDeleteInactiveLoginSessions(t *testing.T) {
+	asserts := assert.New(t)
+	before := time.Now()
+	revokedBefore := before.Add(-LoginSessionMaxAge)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)login_sessions(.+)deleted_at IS NULL(.+)last_active(.+)deleted_at <(.+)").
+		WithArgs(before, revokedBefore).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	deleted, err := DeleteInactiveLoginSessions(before, revokedBefore)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(2, deleted)
+}
@@ -2,6 +2,12 @@ package model
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/cloudreve/Cloudreve/v3/models/scripts/invoker"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
@@ -9,39 +15,311 @@ import (
 	"github.com/fatih/color"
 	"github.com/hashicorp/go-version"
 	"github.com/jinzhu/gorm"
-	"sort"
-	"strings"
 )
 
-// 是否需要迁移
-func needMigration() bool {
-	var setting Setting
-	return DB.Where("name = ?", "db_version_"+conf.RequiredDBVersion).First(&setting).Error != nil
+// Migration 版本化的数据库迁移，按版本号的字典序依次执行
+type Migration struct {
+	Version     string
+	Description string
+	Up          func(db *gorm.DB) error
+	// Down 回滚迁移，为空时表示不可回滚
+	Down func(db *gorm.DB) error
+}
+
+// SchemaMigration 已执行的数据库迁移记录
+type SchemaMigration struct {
+	Version     string `gorm:"primary_key;size:64"`
+	Description string
+	AppliedAt   time.Time
+}
+
+// MigrationState 数据库迁移的执行状态
+type MigrationState struct {
+	Version     string     `json:"version"`
+	Description string     `json:"description"`
+	Applied     bool       `json:"applied"`
+	AppliedAt   *time.Time `json:"applied_at,omitempty"`
+}
+
+var (
+	ErrMigrationNotFound     = errors.New("migration version not found")
+	ErrMigrationIrreversible = errors.New("migration is irreversible")
+)
+
+// migrations 所有数据库迁移，新增数据表或字段时在末尾追加新的迁移，不要修改已发布的迁移
+var migrations = []Migration{
+	{
+		Version:     "0001_initial_schema",
+		Description: "Create initial schema and default records",
+		Up:          initialSchema,
+	},
+	{
+		Version:     "0002_user_daily_stats",
+		Description: "Add per-user daily usage statistics and download traffic",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&DailyStat{}, &UserDailyStat{}).Error
+		},
+		Down: func(db *gorm.DB) error {
+			if err := db.DropTableIfExists(&UserDailyStat{}).Error; err != nil {
+				return err
+			}
+			return db.Model(&DailyStat{}).DropColumn("download_bytes").Error
+		},
+	},
+	{
+		Version:     "0003_notification_settings",
+		Description: "Add email provider, retry and notification settings",
+		Up: func(db *gorm.DB) error {
+			return addSettings(db, notificationSettings)
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Unscoped().Where("name in (?)", notificationSettings).Delete(&Setting{}).Error
+		},
+	},
+	{
+		Version:     "0004_notifications",
+		Description: "Add in-app notifications",
+		Up: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&Notification{}).Error; err != nil {
+				return err
+			}
+			return addSettings(db, []string{"notification_retention"})
+		},
+		Down: func(db *gorm.DB) error {
+			if err := db.DropTableIfExists(&Notification{}).Error; err != nil {
+				return err
+			}
+			return db.Unscoped().Where("name = ?", "notification_retention").Delete(&Setting{}).Error
+		},
+	},
 }
 
-// 执行数据迁移
+// 执行数据迁移。全新安装或配置中开启了 AutoMigrate 时，启动时自动执行待执行的迁移，
+// 否则需通过 migrate 子命令执行
 func migration() {
-	// 确认是否需要执行迁移
-	if !needMigration() {
-		util.Log().Info("Database version fulfilled, skip schema migration.")
+	if err := adoptLegacySchema(); err != nil {
+		util.Log().Panic("Failed to adopt existing database: %s", err)
+	}
+
+	pending, err := PendingMigrations()
+	if err != nil {
+		util.Log().Panic("Failed to list database migrations: %s", err)
+	}
+
+	if len(pending) == 0 {
+		util.Log().Info("Database schema is up to date, skip schema migration.")
 		return
+	}
+
+	if !conf.DatabaseConfig.AutoMigrate && DB.HasTable(&User{}) {
+		util.Log().Panic("%d database migration(s) pending, please run \"cloudreve migrate up\" first, "+
+			"or set \"AutoMigrate = true\" in [Database] section of the config file.", len(pending))
+	}
+
+	if _, err := Migrate(""); err != nil {
+		util.Log().Panic("Failed to migrate database: %s", err)
+	}
+}
+
+// adoptLegacySchema 由早期版本在启动时自动迁移、且版本与当前一致的数据库，补齐初始迁移中
+// 之后新增的数据表及默认记录后，将初始迁移记为已执行
+func adoptLegacySchema() error {
+	applied, err := appliedMigrations()
+	if err != nil || len(applied) > 0 {
+		return err
+	}
+
+	var setting Setting
+	if DB.Where("name = ?", "db_version_"+conf.RequiredDBVersion).First(&setting).Error != nil {
+		return nil
+	}
+
+	baseline := migrations[0]
+	util.Log().Info("Existing database of version %s found, apply and mark migration %q as applied.", conf.RequiredDBVersion, baseline.Version)
+	if err := baseline.Up(DB); err != nil {
+		return fmt.Errorf("failed to apply migration %q: %w", baseline.Version, err)
+	}
+
+	record := SchemaMigration{Version: baseline.Version, Description: baseline.Description, AppliedAt: time.Now()}
+	if err := DB.Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to record migration %q: %w", baseline.Version, err)
+	}
+
+	return nil
+}
+
+// appliedMigrations 列出已执行的迁移
+func appliedMigrations() (map[string]SchemaMigration, error) {
+	if err := DB.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+
+	var records []SchemaMigration
+	if err := DB.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// sortedMigrations 返回按版本号排序的迁移
+func sortedMigrations() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return sorted
+}
+
+// MigrationStatus 列出所有迁移及其执行状态
+func MigrationStatus() ([]MigrationState, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := sortedMigrations()
+	states := make([]MigrationState, len(sorted))
+	for i, m := range sorted {
+		states[i] = MigrationState{Version: m.Version, Description: m.Description}
+		if record, ok := applied[m.Version]; ok {
+			appliedAt := record.AppliedAt
+			states[i].Applied = true
+			states[i].AppliedAt = &appliedAt
+		}
+	}
+
+	return states, nil
+}
+
+// PendingMigrations 列出尚未执行的迁移
+func PendingMigrations() ([]Migration, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]Migration, 0)
+	for _, m := range sortedMigrations() {
+		if _, ok := applied[m.Version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+
+	return pending, nil
+}
+
+// Migrate 依次执行待执行的迁移直到 target 版本，target 为空时执行全部，返回执行的版本
+func Migrate(target string) ([]string, error) {
+	if target != "" && !migrationExists(target) {
+		return nil, ErrMigrationNotFound
+	}
 
+	pending, err := PendingMigrations()
+	if err != nil {
+		return nil, err
 	}
 
-	util.Log().Info("Start initializing database schema...")
+	done := make([]string, 0, len(pending))
+	defer clearCacheAfterMigration(&done)
+
+	for _, m := range pending {
+		if target != "" && m.Version > target {
+			break
+		}
+
+		util.Log().Info("Applying database migration %q: %s", m.Version, m.Description)
+		if err := m.Up(DB); err != nil {
+			return done, fmt.Errorf("failed to apply migration %q: %w", m.Version, err)
+		}
+
+		record := SchemaMigration{Version: m.Version, Description: m.Description, AppliedAt: time.Now()}
+		if err := DB.Create(&record).Error; err != nil {
+			return done, fmt.Errorf("failed to record migration %q: %w", m.Version, err)
+		}
+
+		done = append(done, m.Version)
+	}
+
+	return done, nil
+}
+
+// Rollback 按执行的逆序回滚最近 steps 个已执行的迁移，返回回滚的版本
+func Rollback(steps int) ([]string, error) {
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := sortedMigrations()
+	done := make([]string, 0, steps)
+	defer clearCacheAfterMigration(&done)
+
+	for i := len(sorted) - 1; i >= 0 && len(done) < steps; i-- {
+		m := sorted[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if m.Down == nil {
+			return done, fmt.Errorf("failed to roll back migration %q: %w", m.Version, ErrMigrationIrreversible)
+		}
+
+		util.Log().Info("Rolling back database migration %q: %s", m.Version, m.Description)
+		if err := m.Down(DB); err != nil {
+			return done, fmt.Errorf("failed to roll back migration %q: %w", m.Version, err)
+		}
+
+		if err := DB.Where("version = ?", m.Version).Delete(&SchemaMigration{}).Error; err != nil {
+			return done, fmt.Errorf("failed to delete record of migration %q: %w", m.Version, err)
+		}
+
+		done = append(done, m.Version)
+	}
+
+	return done, nil
+}
+
+func migrationExists(version string) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clearCacheAfterMigration 执行过迁移后清除所有缓存，避免读到迁移前的设置
+func clearCacheAfterMigration(done *[]string) {
+	if len(*done) == 0 {
+		return
+	}
 
-	// 清除所有缓存
 	if instance, ok := cache.Store.(*cache.RedisStore); ok {
 		instance.DeleteAll()
 	}
+}
 
-	// 自动迁移模式
+// initialSchema 初始的数据库结构及默认记录。已有数据库执行时只补充缺失的数据表、字段及默认记录
+func initialSchema(db *gorm.DB) error {
 	if conf.DatabaseConfig.Type == "mysql" {
-		DB = DB.Set("gorm:table_options", "ENGINE=InnoDB")
+		db = db.Set("gorm:table_options", "ENGINE=InnoDB")
+	}
+
+	if err := db.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
+		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &SourceLink{}, &Change{}, &PolicyUsage{}, &AuditLog{}, &DailyStat{}, &Invite{}, &PublicFolder{}, &Automation{}, &WebdavLock{}, &WebdavProp{}, &ResumableUpload{}, &FileVersion{}, &Trash{}, &FileContent{}, &APIToken{}, &Webhook{}, &WebhookDelivery{}, &UserKeyPair{}, &EncryptedFolder{}, &FolderKeyEnvelope{}, &EncryptedName{}, &ShareAccessLog{}, &ShareFileDownload{}, &ShareUploadCount{}, &Collaboration{}, &Team{}, &TeamMember{}, &FolderACL{}, &StaticSite{}, &Photo{}, &MusicTrack{}, &Playlist{}, &AbuseReport{}, &LoginSession{}, &Activity{}).Error; err != nil {
+		return err
 	}
 
-	DB.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
-		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &SourceLink{})
+	// 删除已更名的旧索引
+	dropLegacyIndexes()
 
 	// 创建初始存储策略
 	addDefaultPolicy()
@@ -61,8 +339,7 @@ func migration() {
 	// 执行数据库升级脚本
 	execUpgradeScripts()
 
-	util.Log().Info("Finish initializing database schema.")
-
+	return nil
 }
 
 func addDefaultPolicy() {
@@ -93,6 +370,27 @@ func addDefaultSettings() {
 	}
 }
 
+// notificationSettings 0003_notification_settings 迁移新增的设置项
+var notificationSettings = []string{
+	"mail_provider", "mail_sendgrid_key", "mail_mailgun_endpoint", "mail_mailgun_domain", "mail_mailgun_key",
+	"mail_max_attempts", "mail_retry_interval", "notify_share_interval", "notify_quota_threshold",
+	"notify_quota_interval", "mail_share_accessed_template", "mail_task_finished_template",
+	"mail_quota_almost_full_template",
+}
+
+// addSettings 添加尚不存在的默认设置项 names
+func addSettings(db *gorm.DB, names []string) error {
+	for _, value := range defaultSettings {
+		if !util.ContainsString(names, value.Name) {
+			continue
+		}
+		if err := db.Where(Setting{Name: value.Name}).FirstOrCreate(&value).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func addDefaultGroups() {
 	_, err := GetGroupByID(1)
 	// 未找到初始管理组时，则创建
@@ -112,6 +410,7 @@ func addDefaultGroups() {
 				Aria2BatchSize:   50,
 				RedirectedSource: true,
 				AdvanceDelete:    true,
+				TorrentCreate:    true,
 			},
 		}
 		if err := DB.Create(&defaultAdminGroup).Error; err != nil {
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
@@ -19,3 +20,152 @@ func TestMigration(t *testing.T) {
 	conf.DatabaseConfig.Type = "mysql"
 	DB = mockDB
 }
+
+// useMigrations 使用内存数据库及给定的迁移
+func useMigrations(list []Migration) func() {
+	origin := migrations
+	conf.DatabaseConfig.Type = "sqlite"
+	DB, _ = gorm.Open("sqlite", ":memory:")
+	migrations = list
+	return func() {
+		migrations = origin
+		conf.DatabaseConfig.Type = "mysql"
+		DB.Close()
+		DB = mockDB
+	}
+}
+
+func TestMigrateAndRollback(t *testing.T) {
+	asserts := assert.New(t)
+	var log []string
+	newMigration := func(version string, reversible bool) Migration {
+		m := Migration{
+			Version: version,
+			Up: func(db *gorm.DB) error {
+				log = append(log, "up "+version)
+				return nil
+			},
+		}
+		if reversible {
+			m.Down = func(db *gorm.DB) error {
+				log = append(log, "down "+version)
+				return nil
+			}
+		}
+		return m
+	}
+	defer useMigrations([]Migration{
+		newMigration("0002_b", true),
+		newMigration("0001_a", false),
+		newMigration("0003_c", true),
+	})()
+
+	// 目标版本不存在
+	_, err := Migrate("0004_d")
+	asserts.ErrorIs(err, ErrMigrationNotFound)
+
+	// 执行到指定版本
+	done, err := Migrate("0002_b")
+	asserts.NoError(err)
+	asserts.Equal([]string{"0001_a", "0002_b"}, done)
+
+	states, err := MigrationStatus()
+	asserts.NoError(err)
+	asserts.Len(states, 3)
+	asserts.True(states[0].Applied)
+	asserts.True(states[1].Applied)
+	asserts.False(states[2].Applied)
+	asserts.NotNil(states[1].AppliedAt)
+
+	// 执行剩余迁移
+	done, err = Migrate("")
+	asserts.NoError(err)
+	asserts.Equal([]string{"0003_c"}, done)
+	pending, err := PendingMigrations()
+	asserts.NoError(err)
+	asserts.Empty(pending)
+
+	// 回滚，遇到不可回滚的迁移时停止
+	done, err = Rollback(3)
+	asserts.ErrorIs(err, ErrMigrationIrreversible)
+	asserts.Equal([]string{"0003_c", "0002_b"}, done)
+	pending, _ = PendingMigrations()
+	asserts.Len(pending, 2)
+
+	asserts.Equal([]string{"up 0001_a", "up 0002_b", "up 0003_c", "down 0003_c", "down 0002_b"}, log)
+}
+
+func TestMigrate_Failed(t *testing.T) {
+	asserts := assert.New(t)
+	defer useMigrations([]Migration{
+		{Version: "0001_a", Up: func(db *gorm.DB) error { return nil }},
+		{Version: "0002_b", Up: func(db *gorm.DB) error { return errors.New("error") }},
+	})()
+
+	done, err := Migrate("")
+	asserts.Error(err)
+	asserts.Equal([]string{"0001_a"}, done)
+	pending, _ := PendingMigrations()
+	asserts.Len(pending, 1)
+	asserts.Equal("0002_b", pending[0].Version)
+}
+
+func TestMigration_Pending(t *testing.T) {
+	asserts := assert.New(t)
+	applied := false
+	defer useMigrations([]Migration{
+		{Version: "0001_a", Up: func(db *gorm.DB) error { applied = true; return nil }},
+	})()
+
+	// 已有数据库且未开启自动迁移
+	DB.AutoMigrate(&User{})
+	asserts.Panics(func() {
+		migration()
+	})
+	asserts.False(applied)
+
+	// 开启自动迁移
+	conf.DatabaseConfig.AutoMigrate = true
+	defer func() { conf.DatabaseConfig.AutoMigrate = false }()
+	asserts.NotPanics(func() {
+		migration()
+	})
+	asserts.True(applied)
+}
+
+func TestAdoptLegacySchema(t *testing.T) {
+	asserts := assert.New(t)
+	applied := false
+	defer useMigrations([]Migration{
+		{Version: "0001_a", Up: func(db *gorm.DB) error { applied = true; return nil }},
+	})()
+
+	// 由早期版本自动迁移的数据库
+	DB.AutoMigrate(&User{}, &Setting{})
+	DB.Create(&Setting{Name: "db_version_" + conf.RequiredDBVersion, Value: "installed", Type: "version"})
+	asserts.NotPanics(func() {
+		migration()
+	})
+	asserts.True(applied)
+	pending, _ := PendingMigrations()
+	asserts.Empty(pending)
+}
+
+func TestAdoptLegacySchema_CreateMissingTables(t *testing.T) {
+	asserts := assert.New(t)
+	defer useMigrations(migrations)()
+
+	// 3.8.3 自动迁移的数据库，缺少之后新增的数据表
+	DB.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{})
+	DB.Create(&Setting{Name: "db_version_" + conf.RequiredDBVersion, Value: "installed", Type: "version"})
+	asserts.False(DB.HasTable(&LoginSession{}))
+
+	asserts.NoError(adoptLegacySchema())
+	asserts.True(DB.HasTable(&LoginSession{}))
+	asserts.True(DB.HasTable(&Activity{}))
+
+	applied, err := appliedMigrations()
+	asserts.NoError(err)
+	asserts.Contains(applied, migrations[0].Version)
+	asserts.Len(applied, 1)
+}
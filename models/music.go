@@ -0,0 +1,201 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MusicTrack 从音频标签中提取的音乐信息
+type MusicTrack struct {
+	FileID      uint `gorm:"primary_key;auto_increment:false"`
+	UserID      uint `gorm:"index:music_track_user"`
+	Title       string
+	Artist      string `gorm:"index:music_track_artist"`
+	Album       string `gorm:"index:music_track_album"`
+	AlbumArtist string
+	Genre       string
+	Year        int
+	TrackNo     int
+	Disc        int
+	HasCover    bool
+	UpdatedAt   time.Time
+
+	// 数据库忽略字段
+	File *File `gorm:"-"`
+}
+
+// MusicArtist 艺术家及其作品统计
+type MusicArtist struct {
+	Artist string
+	Albums int
+	Tracks int
+}
+
+// MusicAlbum 专辑及其曲目统计，Cover 为带有封面的曲目文件ID
+type MusicAlbum struct {
+	Album       string
+	AlbumArtist string
+	Year        int
+	Tracks      int
+	Cover       uint
+}
+
+// Playlist 用户创建的播放列表
+type Playlist struct {
+	gorm.Model
+	UserID uint   `gorm:"index:playlist_user"`
+	Name   string `gorm:"size:255"`
+	// Files 按播放顺序排列的文件ID，以逗号分隔
+	Files string `gorm:"type:text"`
+}
+
+// SaveMusicTrack 写入或替换文件的音乐信息，未设置专辑艺术家时使用曲目艺术家
+func SaveMusicTrack(track *MusicTrack) error {
+	if track.AlbumArtist == "" {
+		track.AlbumArtist = track.Artist
+	}
+
+	tx := DB.Begin()
+	if err := tx.Where("file_id = ?", track.FileID).Delete(&MusicTrack{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(track).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// musicTrackQuery 构建用户曲目的查询，回收站中的文件被排除
+func musicTrackQuery(uid uint) *gorm.DB {
+	return DB.Model(&MusicTrack{}).
+		Joins("inner join files on files.id = music_tracks.file_id and files.deleted_at is null").
+		Where("music_tracks.user_id = ?", uid)
+}
+
+// ListMusicArtists 列出用户曲目中的艺术家
+func ListMusicArtists(uid uint) ([]MusicArtist, error) {
+	var artists []MusicArtist
+	result := musicTrackQuery(uid).
+		Select("music_tracks.artist as artist, count(distinct music_tracks.album) as albums, count(*) as tracks").
+		Group("music_tracks.artist").Order("music_tracks.artist").Scan(&artists)
+	return artists, result.Error
+}
+
+// ListMusicAlbums 列出用户曲目中的专辑，artist 不为空时仅列出该艺术家参与的专辑
+func ListMusicAlbums(uid uint, artist string) ([]MusicAlbum, error) {
+	var albums []MusicAlbum
+	query := musicTrackQuery(uid)
+	if artist != "" {
+		query = query.Where("music_tracks.artist = ? or music_tracks.album_artist = ?", artist, artist)
+	}
+
+	result := query.
+		Select("music_tracks.album as album, music_tracks.album_artist as album_artist, "+
+			"max(music_tracks.year) as year, count(*) as tracks, "+
+			"max(case when music_tracks.has_cover = ? then music_tracks.file_id else 0 end) as cover", true).
+		Group("music_tracks.album, music_tracks.album_artist").
+		Order("music_tracks.album_artist, music_tracks.album").Scan(&albums)
+	return albums, result.Error
+}
+
+// ListMusicTracks 按专辑、碟片和音轨号顺序列出用户的曲目，条件为空时不生效
+func ListMusicTracks(uid uint, artist, album string) ([]MusicTrack, error) {
+	var tracks []MusicTrack
+	query := musicTrackQuery(uid)
+	if artist != "" {
+		query = query.Where("music_tracks.artist = ? or music_tracks.album_artist = ?", artist, artist)
+	}
+	if album != "" {
+		query = query.Where("music_tracks.album = ?", album)
+	}
+
+	result := query.Select("music_tracks.*").
+		Order("music_tracks.album, music_tracks.disc, music_tracks.track_no, music_tracks.title").Find(&tracks)
+	return tracks, result.Error
+}
+
+// GetMusicTracksByFileIDs 根据文件ID查找用户的曲目
+func GetMusicTracksByFileIDs(ids []uint, uid uint) ([]MusicTrack, error) {
+	var tracks []MusicTrack
+	result := DB.Where("file_id in (?) and user_id = ?", ids, uid).Find(&tracks)
+	return tracks, result.Error
+}
+
+// DeleteOrphanMusicTracks 删除所属文件已不存在的音乐信息，回收站中的文件仍保留
+func DeleteOrphanMusicTracks() error {
+	return DB.Where("file_id not in (?)", DB.Unscoped().Model(&File{}).Select("id").QueryExpr()).
+		Delete(&MusicTrack{}).Error
+}
+
+// FileIDs 返回播放列表中的文件ID
+func (playlist *Playlist) FileIDs() []uint {
+	if playlist.Files == "" {
+		return []uint{}
+	}
+
+	parts := strings.Split(playlist.Files, ",")
+	ids := make([]uint, 0, len(parts))
+	for _, part := range parts {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	return ids
+}
+
+// SetFileIDs 设置播放列表中的文件ID
+func (playlist *Playlist) SetFileIDs(ids []uint) {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	playlist.Files = strings.Join(parts, ",")
+}
+
+// Create 创建播放列表
+func (playlist *Playlist) Create() error {
+	return DB.Create(playlist).Error
+}
+
+// Update 更新播放列表的名称和文件
+func (playlist *Playlist) Update() error {
+	return DB.Model(playlist).UpdateColumns(map[string]interface{}{
+		"name":  playlist.Name,
+		"files": playlist.Files,
+	}).Error
+}
+
+// GetPlaylistByID 根据ID查找用户的播放列表
+func GetPlaylistByID(id, uid uint) (*Playlist, error) {
+	var playlist Playlist
+	result := DB.Where("id = ? and user_id = ?", id, uid).First(&playlist)
+	return &playlist, result.Error
+}
+
+// ListPlaylists 列出用户的播放列表
+func ListPlaylists(uid uint) ([]Playlist, error) {
+	var playlists []Playlist
+	result := DB.Where("user_id = ?", uid).Order("id").Find(&playlists)
+	return playlists, result.Error
+}
+
+// DeletePlaylist 删除用户的播放列表
+func DeletePlaylist(id, uid uint) (int64, error) {
+	result := DB.Unscoped().Where("id = ? and user_id = ?", id, uid).Delete(&Playlist{})
+	return result.RowsAffected, result.Error
+}
+
+// DeletePlaylistsByUserID 彻底删除用户的所有播放列表，返回删除的条数
+func DeletePlaylistsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Playlist{})
+	return result.RowsAffected, result.Error
+}
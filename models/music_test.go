@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveMusicTrack(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功，专辑艺术家回退为曲目艺术家
+	{
+		track := &MusicTrack{FileID: 1, UserID: 2, Artist: "artist"}
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)music_tracks(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)music_tracks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(SaveMusicTrack(track))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("artist", track.AlbumArtist)
+	}
+
+	// 删除旧记录失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)music_tracks(.+)").WithArgs(1).WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(SaveMusicTrack(&MusicTrack{FileID: 1, UserID: 2}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListMusicArtists(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT music_tracks.artist as artist(.+)inner join files(.+)GROUP BY music_tracks.artist(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"artist", "albums", "tracks"}).AddRow("a", 2, 10))
+	res, err := ListMusicArtists(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Equal([]MusicArtist{{Artist: "a", Albums: 2, Tracks: 10}}, res)
+}
+
+func TestListMusicAlbums(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT music_tracks.album as album(.+)GROUP BY music_tracks.album, music_tracks.album_artist(.+)").
+		WithArgs(true, 1, "a", "a").
+		WillReturnRows(sqlmock.NewRows([]string{"album", "album_artist", "year", "tracks", "cover"}).AddRow("b", "a", 2020, 3, 5))
+	res, err := ListMusicAlbums(1, "a")
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Equal([]MusicAlbum{{Album: "b", AlbumArtist: "a", Year: 2020, Tracks: 3, Cover: 5}}, res)
+}
+
+func TestListMusicTracks(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT music_tracks.\\*(.+)music_tracks.album = (.+)ORDER BY music_tracks.album, music_tracks.disc(.+)").
+		WithArgs(1, "b").
+		WillReturnRows(sqlmock.NewRows([]string{"file_id", "title"}).AddRow(1, "t1").AddRow(2, "t2"))
+	res, err := ListMusicTracks(1, "", "b")
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(res, 2)
+	a.Equal("t2", res[1].Title)
+}
+
+func TestDeleteOrphanMusicTracks(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)music_tracks(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(DeleteOrphanMusicTracks())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestPlaylist_FileIDs(t *testing.T) {
+	a := assert.New(t)
+	playlist := &Playlist{}
+	a.Equal([]uint{}, playlist.FileIDs())
+
+	playlist.SetFileIDs([]uint{3, 1, 2})
+	a.Equal("3,1,2", playlist.Files)
+	a.Equal([]uint{3, 1, 2}, playlist.FileIDs())
+
+	playlist.Files = "1,x,2"
+	a.Equal([]uint{1, 2}, playlist.FileIDs())
+}
+
+func TestPlaylist_Update(t *testing.T) {
+	a := assert.New(t)
+	playlist := &Playlist{Name: "n", Files: "1"}
+	playlist.ID = 1
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)playlists(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(playlist.Update())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeletePlaylist(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)playlists(.+)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := DeletePlaylist(1, 2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(1, affected)
+}
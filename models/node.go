@@ -36,6 +36,14 @@ type Aria2Option struct {
 	Interval int `json:"interval,omitempty"`
 	// RPC API 请求超时
 	Timeout int `json:"timeout,omitempty"`
+	// 下载器后端，为空时使用 aria2 RPC
+	Backend string `json:"backend,omitempty"`
+	// 内置 BitTorrent 下载器的监听端口，为 0 时随机选择
+	ListenPort int `json:"listen_port,omitempty"`
+	// 下载完成后的做种分享率，达到后停止做种
+	SeedRatio float64 `json:"seed_ratio,omitempty"`
+	// 下载完成后的做种时长，单位为分钟，达到后停止做种
+	SeedTime int `json:"seed_time,omitempty"`
 }
 
 type NodeStatus int
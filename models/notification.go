@@ -0,0 +1,92 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 站内通知类型
+const (
+	NotificationTaskFinished = "task_finished"
+	NotificationShared       = "shared"
+	NotificationAnnouncement = "announcement"
+)
+
+// Notification 站内通知
+type Notification struct {
+	gorm.Model
+	UserID  uint   `gorm:"index:notification_user"`
+	Type    string `gorm:"size:32"`
+	Title   string
+	Content string `gorm:"type:text"`
+	// Link 点击通知后前往的页面，为空时不跳转
+	Link   string
+	ReadAt *time.Time
+}
+
+// Create 创建通知
+func (notification *Notification) Create() error {
+	return DB.Create(notification).Error
+}
+
+// Read 通知是否已读
+func (notification *Notification) Read() bool {
+	return notification.ReadAt != nil
+}
+
+// ListNotifications 分页列出用户的通知，最新的在前，unread 为 true 时仅列出未读通知
+func ListNotifications(uid uint, unread bool, page, pageSize int) ([]Notification, int, error) {
+	var (
+		notifications []Notification
+		total         int
+	)
+
+	tx := DB.Model(&Notification{}).Where("user_id = ?", uid)
+	if unread {
+		tx = tx.Where("read_at is null")
+	}
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := tx.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&notifications)
+	return notifications, total, result.Error
+}
+
+// CountUnreadNotifications 统计用户的未读通知数
+func CountUnreadNotifications(uid uint) (int, error) {
+	var total int
+	result := DB.Model(&Notification{}).Where("user_id = ? and read_at is null", uid).Count(&total)
+	return total, result.Error
+}
+
+// MarkNotificationsRead 将用户的通知标记为已读，ids 为空时标记全部未读通知，返回更新的条数
+func MarkNotificationsRead(uid uint, ids []uint) (int64, error) {
+	tx := DB.Model(&Notification{}).Where("user_id = ? and read_at is null", uid)
+	if len(ids) > 0 {
+		tx = tx.Where("id in (?)", ids)
+	}
+
+	result := tx.Update("read_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+// DeleteNotification 彻底删除用户的通知
+func DeleteNotification(id, uid uint) (int64, error) {
+	result := DB.Unscoped().Where("id = ? and user_id = ?", id, uid).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteNotificationsByUserID 彻底删除用户的所有通知，返回删除的条数
+func DeleteNotificationsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteReadNotificationsBefore 彻底删除 before 之前创建的已读通知
+func DeleteReadNotificationsBefore(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("created_at < ? and read_at is not null", before).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
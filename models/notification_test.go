@@ -0,0 +1,120 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotification_Create(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+	notification := &Notification{UserID: 1, Type: NotificationAnnouncement}
+	a.NoError(notification.Create())
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(5, notification.ID)
+	a.False(notification.Read())
+}
+
+func TestListNotifications(t *testing.T) {
+	a := assert.New(t)
+
+	// 全部通知
+	{
+		mock.ExpectQuery("SELECT count(.+)notifications(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)notifications(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "read_at"}).AddRow(3, nil).AddRow(2, time.Now()))
+		res, total, err := ListNotifications(1, false, 1, 2)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(3, total)
+		a.Len(res, 2)
+		a.False(res[0].Read())
+		a.True(res[1].Read())
+	}
+
+	// 仅未读
+	{
+		mock.ExpectQuery("SELECT count(.+)notifications(.+)read_at is null(.+)").
+			WithArgs(1).
+			WillReturnError(errors.New("error"))
+		_, _, err := ListNotifications(1, true, 1, 2)
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCountUnreadNotifications(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)notifications(.+)read_at is null(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	n, err := CountUnreadNotifications(1)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Equal(2, n)
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	a := assert.New(t)
+
+	// 全部标记为已读
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)notifications(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectCommit()
+		n, err := MarkNotificationsRead(1, nil)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(4, n)
+	}
+
+	// 指定通知
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)notifications(.+)id in(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, 2, 3).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		n, err := MarkNotificationsRead(1, []uint{2, 3})
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(2, n)
+	}
+}
+
+func TestDeleteNotification(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)notifications(.+)").WithArgs(2, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	n, err := DeleteNotification(2, 1)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(1, n)
+}
+
+func TestDeleteReadNotificationsBefore(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)notifications(.+)read_at is not null(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	n, err := DeleteReadNotificationsBefore(time.Now())
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(3, n)
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Photo 从图像 EXIF 中提取的照片信息，用于按时间线和地点浏览照片
+type Photo struct {
+	FileID    uint      `gorm:"primary_key;auto_increment:false"`
+	UserID    uint      `gorm:"index:photo_user"`
+	TakenAt   time.Time `gorm:"index:photo_taken_at"`
+	Latitude  *float64
+	Longitude *float64
+	Make      string
+	Model     string
+	LensModel string
+	Width     int
+	Height    int
+	UpdatedAt time.Time
+
+	// 数据库忽略字段
+	File *File `gorm:"-"`
+}
+
+// PhotoFilter 照片筛选条件，为空的条件不生效
+type PhotoFilter struct {
+	From   *time.Time
+	To     *time.Time
+	MinLat *float64
+	MaxLat *float64
+	MinLng *float64
+	MaxLng *float64
+}
+
+// SavePhoto 写入或替换文件的照片信息
+func SavePhoto(photo *Photo) error {
+	tx := DB.Begin()
+	if err := tx.Where("file_id = ?", photo.FileID).Delete(&Photo{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(photo).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// photoQuery 构建用户照片的查询，回收站中的文件被排除
+func photoQuery(uid uint, filter *PhotoFilter) *gorm.DB {
+	query := DB.Model(&Photo{}).
+		Joins("inner join files on files.id = photos.file_id and files.deleted_at is null").
+		Where("photos.user_id = ?", uid)
+	if filter.From != nil {
+		query = query.Where("photos.taken_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("photos.taken_at < ?", *filter.To)
+	}
+	if filter.MinLat != nil {
+		query = query.Where("photos.latitude >= ?", *filter.MinLat)
+	}
+	if filter.MaxLat != nil {
+		query = query.Where("photos.latitude <= ?", *filter.MaxLat)
+	}
+	if filter.MinLng != nil {
+		query = query.Where("photos.longitude >= ?", *filter.MinLng)
+	}
+	if filter.MaxLng != nil {
+		query = query.Where("photos.longitude <= ?", *filter.MaxLng)
+	}
+
+	return query
+}
+
+// ListPhotos 按拍摄时间倒序分页列出用户的照片
+func ListPhotos(uid uint, filter *PhotoFilter, page, pageSize int) ([]Photo, int, error) {
+	var (
+		photos []Photo
+		total  int
+	)
+	query := photoQuery(uid, filter)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := query.Select("photos.*").Order("photos.taken_at desc, photos.file_id desc").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&photos)
+	return photos, total, result.Error
+}
+
+// ListPhotoTimes 列出用户全部照片的拍摄时间，用于统计相册
+func ListPhotoTimes(uid uint, filter *PhotoFilter) ([]time.Time, error) {
+	var times []time.Time
+	result := photoQuery(uid, filter).Order("photos.taken_at desc").Pluck("photos.taken_at", &times)
+	return times, result.Error
+}
+
+// DeleteOrphanPhotos 删除所属文件已不存在的照片信息，回收站中的文件仍保留
+func DeleteOrphanPhotos() error {
+	return DB.Where("file_id not in (?)", DB.Unscoped().Model(&File{}).Select("id").QueryExpr()).
+		Delete(&Photo{}).Error
+}
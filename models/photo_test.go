@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavePhoto(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)photos(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)photos(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(SavePhoto(&Photo{FileID: 1, UserID: 2, TakenAt: time.Now()}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)photos(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)photos(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(SavePhoto(&Photo{FileID: 1, UserID: 2, TakenAt: time.Now()}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListPhotos(t *testing.T) {
+	a := assert.New(t)
+	from := time.Unix(1600000000, 0)
+	minLat := 30.0
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT count(.+)photos(.+)inner join files(.+)deleted_at is null(.+)user_id(.+)taken_at >=(.+)latitude >=(.+)").
+			WithArgs(1, from, minLat).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT photos.\\*(.+)ORDER BY photos.taken_at desc(.+)").
+			WithArgs(1, from, minLat).
+			WillReturnRows(sqlmock.NewRows([]string{"file_id", "user_id"}).AddRow(5, 1).AddRow(4, 1))
+		res, total, err := ListPhotos(1, &PhotoFilter{From: &from, MinLat: &minLat}, 2, 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Equal(3, total)
+		a.Len(res, 2)
+		a.EqualValues(5, res[0].FileID)
+	}
+
+	// 统计失败
+	{
+		mock.ExpectQuery("SELECT count(.+)").WillReturnError(errors.New("error"))
+		res, _, err := ListPhotos(1, &PhotoFilter{}, 1, 10)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+		a.Nil(res)
+	}
+}
+
+func TestListPhotoTimes(t *testing.T) {
+	a := assert.New(t)
+	to := time.Unix(1600000000, 0)
+	now := time.Now()
+	mock.ExpectQuery("SELECT photos.taken_at FROM(.+)photos(.+)taken_at <(.+)").
+		WithArgs(1, to).
+		WillReturnRows(sqlmock.NewRows([]string{"taken_at"}).AddRow(now))
+	res, err := ListPhotoTimes(1, &PhotoFilter{To: &to})
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(res, 1)
+}
+
+func TestDeleteOrphanPhotos(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)photos(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(DeleteOrphanPhotos())
+	a.NoError(mock.ExpectationsWereMet())
+}
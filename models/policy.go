@@ -71,6 +71,44 @@ type PolicyOption struct {
 	S3ForcePathStyle bool `json:"s3_path_style"`
 	// File extensions that support thumbnail generation using native policy API.
 	ThumbExts []string `json:"thumb_exts,omitempty"`
+	// DisableVideoThumb 不为此策略下的视频文件生成 ffmpeg 缩略图
+	DisableVideoThumb bool `json:"disable_video_thumb,omitempty"`
+	// HLSCachePath 视频 HLS 转码结果在存储端的存放目录，为空时使用 hls
+	HLSCachePath string `json:"hls_cache_path,omitempty"`
+	// 每月流量预算，单位为字节，0 为不限制
+	TrafficBudget uint64 `json:"traffic_budget,omitempty"`
+	// 每月请求次数预算，0 为不限制
+	RequestBudget uint64 `json:"request_budget,omitempty"`
+	// 用量达到预算的这些百分比时发送提醒
+	BudgetAlerts []int `json:"budget_alerts,omitempty"`
+	// 预算耗尽后是否禁用匿名直链等功能
+	BudgetDegrade bool `json:"budget_degrade,omitempty"`
+	// Plugin 存储驱动插件名称
+	Plugin string `json:"plugin,omitempty"`
+	// PluginOptions 传递给存储驱动插件的自定义参数
+	PluginOptions map[string]string `json:"plugin_options,omitempty"`
+	// MaxNameLength 文件名最大字符数，0 为不限制
+	MaxNameLength int `json:"max_name_length,omitempty"`
+	// ReservedNames 不允许使用的文件名（不含扩展名，忽略大小写），如 CON、NUL
+	ReservedNames []string `json:"reserved_names,omitempty"`
+	// CaseInsensitive 使用此策略的用户按忽略大小写的方式解析路径，保留原始大小写存储
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+	// MaxVersions 文件被覆盖时保留的历史版本数，0 为不保留
+	MaxVersions int `json:"max_versions,omitempty"`
+	// ReadonlyOnFailure 健康检查失败时拒绝新的上传
+	ReadonlyOnFailure bool `json:"readonly_on_failure,omitempty"`
+	// FailoverPolicyID 健康检查失败时新上传的文件改为存放至此存储策略，须与当前策略类型相同
+	FailoverPolicyID uint `json:"failover_policy_id,omitempty"`
+	// Encryption 是否在服务端加密存储文件内容，仅支持本机存储策略
+	Encryption bool `json:"encryption,omitempty"`
+	// EncryptionKeys Base64 编码的主密钥，最后一个为当前使用的密钥，
+	// 轮换后旧密钥仍需保留以解密此前上传的文件
+	EncryptionKeys []string `json:"encryption_keys,omitempty"`
+	// Dedup 上传完成后计算文件内容的 SHA-256，内容相同的文件共用同一物理文件，
+	// 计算时需从存储端读回文件内容
+	Dedup bool `json:"dedup,omitempty"`
+	// Antivirus 上传完成后使用 ClamAV 扫描文件
+	Antivirus bool `json:"antivirus,omitempty"`
 }
 
 func init() {
@@ -116,6 +154,13 @@ func (policy *Policy) BeforeSave() (err error) {
 	return err
 }
 
+// GetPolicies 列出所有存储策略
+func GetPolicies() ([]Policy, error) {
+	var policies []Policy
+	result := DB.Find(&policies)
+	return policies, result.Error
+}
+
 // SerializeOptions 将序列后的Option写入到数据库字段
 func (policy *Policy) SerializeOptions() (err error) {
 	optionsValue, err := json.Marshal(&policy.OptionsSerialized)
@@ -184,9 +229,14 @@ func (policy *Policy) IsDirectlyPreview() bool {
 	return policy.Type == "local"
 }
 
+// IsRelayedDownload 返回此策略的文件下载是否由服务端输出，否则重定向至存储端
+func (policy *Policy) IsRelayedDownload() bool {
+	return policy.Type == "local" || policy.Type == "plugin"
+}
+
 // IsTransitUpload 返回此策略上传给定size文件时是否需要服务端中转
 func (policy *Policy) IsTransitUpload(size uint64) bool {
-	return policy.Type == "local"
+	return policy.Type == "local" || policy.Type == "plugin"
 }
 
 // IsThumbGenerateNeeded 返回此策略是否需要在上传后生成缩略图
@@ -200,7 +250,7 @@ func (policy *Policy) IsUploadPlaceholderWithSize() bool {
 		return true
 	}
 
-	if util.ContainsString([]string{"onedrive", "oss", "qiniu", "cos", "s3"}, policy.Type) {
+	if util.ContainsString([]string{"onedrive", "oss", "qiniu", "cos", "s3", "b2"}, policy.Type) {
 		return policy.OptionsSerialized.PlaceholderWithSize
 	}
 
@@ -231,6 +281,55 @@ func (policy *Policy) ClearCache() {
 	cache.Deletes([]string{strconv.FormatUint(uint64(policy.ID), 10)}, "policy_")
 }
 
+// HasBudget 是否设置了每月用量预算
+func (policy *Policy) HasBudget() bool {
+	return policy.OptionsSerialized.TrafficBudget > 0 || policy.OptionsSerialized.RequestBudget > 0
+}
+
+// BudgetPercent 返回用量占预算的百分比，流量与请求次数取较高者
+func (policy *Policy) BudgetPercent(usage *PolicyUsage) int {
+	percent := 0
+	if budget := policy.OptionsSerialized.TrafficBudget; budget > 0 {
+		percent = int(usage.Traffic * 100 / budget)
+	}
+
+	if budget := policy.OptionsSerialized.RequestBudget; budget > 0 {
+		if requestPercent := int(usage.Requests * 100 / budget); requestPercent > percent {
+			percent = requestPercent
+		}
+	}
+
+	return percent
+}
+
+// BudgetAlertLevel 返回用量已达到的最高提醒阈值，未达到任何阈值时返回 0
+func (policy *Policy) BudgetAlertLevel(usage *PolicyUsage) int {
+	thresholds := policy.OptionsSerialized.BudgetAlerts
+	if len(thresholds) == 0 {
+		thresholds = []int{80, 100}
+	}
+
+	percent := policy.BudgetPercent(usage)
+	level := 0
+	for _, threshold := range thresholds {
+		if threshold > level && percent >= threshold {
+			level = threshold
+		}
+	}
+
+	return level
+}
+
+// BudgetExhausted 本月预算是否已耗尽
+func (policy *Policy) BudgetExhausted(usage *PolicyUsage) bool {
+	return policy.HasBudget() && policy.BudgetPercent(usage) >= 100
+}
+
+// Encrypted 返回存储策略是否加密存储文件内容，仅本机存储策略支持加密
+func (policy *Policy) Encrypted() bool {
+	return policy.Type == "local" && policy.OptionsSerialized.Encryption
+}
+
 // CouldProxyThumb return if proxy thumbs is allowed for this policy.
 func (policy *Policy) CouldProxyThumb() bool {
 	if policy.Type == "local" || !IsTrueVal(GetSettingByName("thumb_proxy_enabled")) {
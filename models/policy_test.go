@@ -45,6 +45,17 @@ func TestGetPolicyByID(t *testing.T) {
 
 }
 
+func TestGetPolicies(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a").AddRow(2, "b"))
+	policies, err := GetPolicies()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(policies, 2)
+	asserts.Equal("b", policies[1].Name)
+}
+
 func TestPolicy_BeforeSave(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -180,6 +191,16 @@ func TestPolicy_IsDirectlyPreview(t *testing.T) {
 	asserts.False(policy.IsDirectlyPreview())
 }
 
+func TestPolicy_IsRelayedDownload(t *testing.T) {
+	asserts := assert.New(t)
+	policy := Policy{Type: "local"}
+	asserts.True(policy.IsRelayedDownload())
+	policy.Type = "plugin"
+	asserts.True(policy.IsRelayedDownload())
+	policy.Type = "s3"
+	asserts.False(policy.IsRelayedDownload())
+}
+
 func TestPolicy_ClearCache(t *testing.T) {
 	asserts := assert.New(t)
 	cache.Set("policy_202", 1, 0)
@@ -216,6 +237,10 @@ func TestPolicy_Props(t *testing.T) {
 	asserts.False(policy.IsUploadPlaceholderWithSize())
 	policy.Type = "remote"
 	asserts.True(policy.IsUploadPlaceholderWithSize())
+	policy.Type = "plugin"
+	asserts.True(policy.IsTransitUpload(4))
+	asserts.False(policy.IsThumbGenerateNeeded())
+	asserts.False(policy.IsUploadPlaceholderWithSize())
 }
 
 func TestPolicy_UpdateAccessKeyAndClearCache(t *testing.T) {
@@ -233,6 +258,14 @@ func TestPolicy_UpdateAccessKeyAndClearCache(t *testing.T) {
 	a.False(ok)
 }
 
+func TestPolicy_Encrypted(t *testing.T) {
+	a := assert.New(t)
+
+	a.False((&Policy{Type: "local"}).Encrypted())
+	a.True((&Policy{Type: "local", OptionsSerialized: PolicyOption{Encryption: true}}).Encrypted())
+	a.False((&Policy{Type: "remote", OptionsSerialized: PolicyOption{Encryption: true}}).Encrypted())
+}
+
 func TestPolicy_CouldProxyThumb(t *testing.T) {
 	a := assert.New(t)
 	p := &Policy{Type: "local"}
@@ -267,3 +300,38 @@ func TestPolicy_CouldProxyThumb(t *testing.T) {
 
 	cache.Deletes([]string{"thumb_proxy_enabled", "thumb_proxy_policy"}, "setting_")
 }
+
+func TestPolicy_Budget(t *testing.T) {
+	a := assert.New(t)
+	p := &Policy{}
+
+	// 未设置预算
+	{
+		a.False(p.HasBudget())
+		a.False(p.BudgetExhausted(&PolicyUsage{Traffic: 100}))
+	}
+
+	// 按流量与请求次数中较高者计算
+	{
+		p.OptionsSerialized.TrafficBudget = 1000
+		p.OptionsSerialized.RequestBudget = 10
+		a.True(p.HasBudget())
+		a.Equal(50, p.BudgetPercent(&PolicyUsage{Traffic: 500, Requests: 2}))
+		a.Equal(90, p.BudgetPercent(&PolicyUsage{Traffic: 500, Requests: 9}))
+		a.True(p.BudgetExhausted(&PolicyUsage{Requests: 10}))
+	}
+
+	// 默认提醒阈值
+	{
+		a.Equal(0, p.BudgetAlertLevel(&PolicyUsage{Traffic: 799}))
+		a.Equal(80, p.BudgetAlertLevel(&PolicyUsage{Traffic: 800}))
+		a.Equal(100, p.BudgetAlertLevel(&PolicyUsage{Traffic: 2000}))
+	}
+
+	// 自定义提醒阈值
+	{
+		p.OptionsSerialized.BudgetAlerts = []int{90, 50}
+		a.Equal(50, p.BudgetAlertLevel(&PolicyUsage{Traffic: 800}))
+		a.Equal(90, p.BudgetAlertLevel(&PolicyUsage{Traffic: 2000}))
+	}
+}
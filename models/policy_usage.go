@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PolicyUsage 存储策略每月的流量与请求用量
+type PolicyUsage struct {
+	gorm.Model
+	PolicyID uint   `gorm:"unique_index:policy_month"`
+	Month    string `gorm:"unique_index:policy_month;size:7"`
+	Traffic  uint64
+	Requests uint64
+	// AlertLevel 本月已发送过提醒的最高用量百分比阈值
+	AlertLevel int
+}
+
+// UsageMonth 返回用量统计所属的月份
+func UsageMonth(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// GetPolicyUsage 获取存储策略在指定月份的用量，尚无记录时返回空用量
+func GetPolicyUsage(policyID uint, month string) (*PolicyUsage, error) {
+	usage := &PolicyUsage{PolicyID: policyID, Month: month}
+	result := DB.Where("policy_id = ? and month = ?", policyID, month).First(usage)
+	if gorm.IsRecordNotFoundError(result.Error) {
+		return usage, nil
+	}
+
+	return usage, result.Error
+}
+
+// ConsumePolicyUsage 累加存储策略在指定月份的用量，返回累加后的用量
+func ConsumePolicyUsage(policyID uint, month string, traffic uint64) (*PolicyUsage, error) {
+	usage := &PolicyUsage{}
+	if err := DB.Where(PolicyUsage{PolicyID: policyID, Month: month}).FirstOrCreate(usage).Error; err != nil {
+		return nil, err
+	}
+
+	if err := DB.Model(usage).UpdateColumns(map[string]interface{}{
+		"traffic":  gorm.Expr("traffic + ?", traffic),
+		"requests": gorm.Expr("requests + ?", 1),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	usage.Traffic += traffic
+	usage.Requests++
+	return usage, nil
+}
+
+// RaiseAlertLevel 将已提醒阈值提升至 level，返回是否由本次调用完成提升，
+// 用于保证同一阈值只发送一次提醒
+func (usage *PolicyUsage) RaiseAlertLevel(level int) (bool, error) {
+	result := DB.Model(&PolicyUsage{}).Where("id = ? and alert_level < ?", usage.ID, level).
+		UpdateColumn("alert_level", level)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	usage.AlertLevel = level
+	return result.RowsAffected > 0, nil
+}
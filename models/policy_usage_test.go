@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageMonth(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("2023-02", UsageMonth(time.Date(2023, 2, 28, 23, 0, 0, 0, time.Local)))
+}
+
+func TestGetPolicyUsage(t *testing.T) {
+	a := assert.New(t)
+
+	// 存在记录
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").
+			WithArgs(1, "2023-02").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "traffic", "requests"}).AddRow(1, 10, 2))
+		usage, err := GetPolicyUsage(1, "2023-02")
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(10, usage.Traffic)
+		a.EqualValues(2, usage.Requests)
+	}
+
+	// 无记录
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").WillReturnError(gorm.ErrRecordNotFound)
+		usage, err := GetPolicyUsage(1, "2023-02")
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(1, usage.PolicyID)
+		a.EqualValues(0, usage.Traffic)
+	}
+
+	// 出错
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").WillReturnError(errors.New("error"))
+		_, err := GetPolicyUsage(1, "2023-02")
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+}
+
+func TestConsumePolicyUsage(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "month", "traffic", "requests"}).AddRow(1, 1, "2023-02", 10, 2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)policy_usages(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		usage, err := ConsumePolicyUsage(1, "2023-02", 5)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(15, usage.Traffic)
+		a.EqualValues(3, usage.Requests)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").WillReturnError(errors.New("error"))
+		_, err := ConsumePolicyUsage(1, "2023-02", 5)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 更新失败
+	{
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)policy_usages(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		_, err := ConsumePolicyUsage(1, "2023-02", 5)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+}
+
+func TestPolicyUsage_RaiseAlertLevel(t *testing.T) {
+	a := assert.New(t)
+	usage := &PolicyUsage{}
+	usage.ID = 1
+
+	// 提升成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)policy_usages(.+)").WithArgs(80, 1, 80).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		raised, err := usage.RaiseAlertLevel(80)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.True(raised)
+		a.Equal(80, usage.AlertLevel)
+	}
+
+	// 已被其他请求提升
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)policy_usages(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		raised, err := usage.RaiseAlertLevel(100)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.False(raised)
+	}
+}
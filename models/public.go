@@ -0,0 +1,57 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// PublicFolder 可供游客只读浏览的公开目录
+type PublicFolder struct {
+	gorm.Model
+	// Slug 公开访问路径中的标识
+	Slug     string `gorm:"size:64;unique_index"`
+	FolderID uint   `gorm:"unique_index"`
+	OwnerID  uint   `gorm:"index"`
+
+	// 数据库忽略字段
+	Folder *Folder `gorm:"-"`
+	Owner  *User   `gorm:"-"`
+}
+
+// Create 创建公开目录
+func (public *PublicFolder) Create() error {
+	return DB.Create(public).Error
+}
+
+// Load 加载公开目录对应的目录与所有者，所有者不可用时返回错误
+func (public *PublicFolder) Load() error {
+	owner, err := GetActiveUserByID(public.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	var folder Folder
+	if err := DB.Where("id = ? and owner_id = ?", public.FolderID, public.OwnerID).First(&folder).Error; err != nil {
+		return err
+	}
+
+	public.Owner = &owner
+	public.Folder = &folder
+	return nil
+}
+
+// GetPublicFolderBySlug 用标识获取公开目录
+func GetPublicFolderBySlug(slug string) (PublicFolder, error) {
+	var public PublicFolder
+	result := DB.Where("slug = ?", slug).First(&public)
+	return public, result.Error
+}
+
+// ListPublicFolders 列出所有公开目录
+func ListPublicFolders() ([]PublicFolder, error) {
+	var publics []PublicFolder
+	result := DB.Order("id").Find(&publics)
+	return publics, result.Error
+}
+
+// DeletePublicFolder 取消目录公开
+func DeletePublicFolder(id uint) error {
+	return DB.Unscoped().Where("id = ?", id).Delete(&PublicFolder{}).Error
+}
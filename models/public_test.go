@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicFolder_Load(t *testing.T) {
+	asserts := assert.New(t)
+	public := &PublicFolder{FolderID: 2, OwnerID: 1}
+
+	// 所有者不可用
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(public.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).WillReturnError(errors.New("error"))
+		asserts.Error(public.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(public.Folder)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "mirror"))
+		asserts.NoError(public.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, public.Owner.ID)
+		asserts.Equal("mirror", public.Folder.Name)
+	}
+}
+
+func TestGetPublicFolderBySlug(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)public_folders(.+)").WithArgs("mirror").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, "mirror"))
+	public, err := GetPublicFolderBySlug("mirror")
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, public.ID)
+
+	mock.ExpectQuery("SELECT(.+)public_folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	publics, err := ListPublicFolders()
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(publics, 2)
+}
+
+func TestDeletePublicFolder(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)public_folders(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(DeletePublicFolder(1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,34 @@
+package model
+
+// FolderQuota 目录容量配额，限制目录及其子目录下文件的总大小
+type FolderQuota struct {
+	FolderID uint   `json:"folder_id"`
+	Size     uint64 `json:"size"`
+}
+
+// FolderQuota 获取用户为目录设定的容量配额
+func (user *User) FolderQuota(folderID uint) (uint64, bool) {
+	for _, quota := range user.OptionsSerialized.FolderQuotas {
+		if quota.FolderID == folderID {
+			return quota.Size, true
+		}
+	}
+
+	return 0, false
+}
+
+// SetFolderQuota 设定目录的容量配额，size 为 0 时取消配额，需调用 UpdateOptions 保存
+func (user *User) SetFolderQuota(folderID uint, size uint64) {
+	quotas := make([]FolderQuota, 0, len(user.OptionsSerialized.FolderQuotas)+1)
+	for _, quota := range user.OptionsSerialized.FolderQuotas {
+		if quota.FolderID != folderID {
+			quotas = append(quotas, quota)
+		}
+	}
+
+	if size > 0 {
+		quotas = append(quotas, FolderQuota{FolderID: folderID, Size: size})
+	}
+
+	user.OptionsSerialized.FolderQuotas = quotas
+}
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_SetFolderQuota(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{}
+
+	// 设定
+	user.SetFolderQuota(2, 100)
+	user.SetFolderQuota(3, 200)
+	quota, ok := user.FolderQuota(2)
+	asserts.True(ok)
+	asserts.EqualValues(100, quota)
+
+	// 更新
+	user.SetFolderQuota(2, 300)
+	quota, _ = user.FolderQuota(2)
+	asserts.EqualValues(300, quota)
+	asserts.Len(user.OptionsSerialized.FolderQuotas, 2)
+
+	// 取消
+	user.SetFolderQuota(3, 0)
+	_, ok = user.FolderQuota(3)
+	asserts.False(ok)
+	asserts.Len(user.OptionsSerialized.FolderQuotas, 1)
+}
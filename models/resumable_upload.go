@@ -0,0 +1,59 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ResumableUpload 可续传（tus）上传记录，保存已接收的字节数，使上传在缓存失效
+// 或服务重启后仍可继续
+type ResumableUpload struct {
+	gorm.Model
+	SessionID string `gorm:"size:64;unique_index:resumable_upload_session"`
+	UserID    uint   `gorm:"index:resumable_upload_user"`
+	// Length 声明的文件总大小
+	Length uint64
+	// Offset 已接收的字节数
+	Offset uint64
+	// Session 序列化后的上传会话
+	Session   string `gorm:"type:text"`
+	ExpiresAt time.Time
+}
+
+// Create 创建可续传上传记录
+func (upload *ResumableUpload) Create() error {
+	return DB.Create(upload).Error
+}
+
+// GetResumableUpload 根据上传会话 ID 查找用户未过期的可续传上传记录
+func GetResumableUpload(sessionID string, uid uint) (*ResumableUpload, error) {
+	upload := &ResumableUpload{}
+	result := DB.Where("session_id = ? and user_id = ? and expires_at > ?", sessionID, uid, time.Now()).
+		First(upload)
+	return upload, result.Error
+}
+
+// GetActiveResumableUploadSessions 列出所有未过期的可续传上传会话 ID
+func GetActiveResumableUploadSessions() ([]string, error) {
+	var sessions []string
+	result := DB.Model(&ResumableUpload{}).Where("expires_at > ?", time.Now()).Pluck("session_id", &sessions)
+	return sessions, result.Error
+}
+
+// UpdateOffset 更新已接收的字节数及过期时间
+func (upload *ResumableUpload) UpdateOffset(offset uint64, expiresAt time.Time) error {
+	upload.Offset = offset
+	upload.ExpiresAt = expiresAt
+	return DB.Model(upload).Updates(map[string]interface{}{"offset": offset, "expires_at": expiresAt}).Error
+}
+
+// DeleteResumableUpload 删除可续传上传记录
+func DeleteResumableUpload(sessionID string) error {
+	return DB.Unscoped().Where("session_id = ?", sessionID).Delete(&ResumableUpload{}).Error
+}
+
+// DeleteExpiredResumableUploads 删除所有已过期的可续传上传记录
+func DeleteExpiredResumableUploads() error {
+	return DB.Unscoped().Where("expires_at <= ?", time.Now()).Delete(&ResumableUpload{}).Error
+}
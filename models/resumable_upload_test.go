@@ -0,0 +1,91 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumableUpload_Create(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)resumable_uploads(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	upload := &ResumableUpload{SessionID: "session", UserID: 1, Length: 10}
+	a.NoError(upload.Create())
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(1, upload.ID)
+}
+
+func TestGetResumableUpload(t *testing.T) {
+	a := assert.New(t)
+
+	// 找到
+	{
+		mock.ExpectQuery("SELECT(.+)resumable_uploads(.+)").WithArgs("session", 1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "length", "offset"}).AddRow(1, 10, 5))
+		upload, err := GetResumableUpload("session", 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(10, upload.Length)
+		a.EqualValues(5, upload.Offset)
+	}
+
+	// 未找到
+	{
+		mock.ExpectQuery("SELECT(.+)resumable_uploads(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetResumableUpload("session", 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.True(gorm.IsRecordNotFoundError(err))
+	}
+}
+
+func TestGetActiveResumableUploadSessions(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)session_id(.+)resumable_uploads(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("1").AddRow("2"))
+	sessions, err := GetActiveResumableUploadSessions()
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Equal([]string{"1", "2"}, sessions)
+}
+
+func TestResumableUpload_UpdateOffset(t *testing.T) {
+	a := assert.New(t)
+	expires := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)resumable_uploads(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	upload := &ResumableUpload{Model: gorm.Model{ID: 1}}
+	a.NoError(upload.UpdateOffset(5, expires))
+	a.NoError(mock.ExpectationsWereMet())
+	a.EqualValues(5, upload.Offset)
+	a.Equal(expires, upload.ExpiresAt)
+}
+
+func TestDeleteResumableUpload(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)resumable_uploads(.+)").WithArgs("session").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(DeleteResumableUpload("session"))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeleteExpiredResumableUploads(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)resumable_uploads(.+)").WillReturnError(errors.New("error"))
+	mock.ExpectRollback()
+	a.Error(DeleteExpiredResumableUploads())
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// RetentionRule 由管理员设定的保留规则，受保护的对象在期限内或法律保全期间不能被删除
+type RetentionRule struct {
+	// 规则作用的目录，为 0 时作用于用户的所有文件
+	FolderID uint `json:"folder_id,omitempty"`
+	// 文件自创建起至少保留的天数
+	Days int `json:"days,omitempty"`
+	// 法律保全，开启后不限期禁止删除
+	LegalHold bool   `json:"legal_hold,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Locks 返回创建于 created 的对象在 now 时刻是否受此规则保护
+func (rule *RetentionRule) Locks(created, now time.Time) bool {
+	if rule.LegalHold {
+		return true
+	}
+
+	return rule.Days > 0 && now.Before(created.AddDate(0, 0, rule.Days))
+}
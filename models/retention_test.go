@@ -0,0 +1,18 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionRule_Locks(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	a.False((&RetentionRule{}).Locks(now, now))
+	a.True((&RetentionRule{LegalHold: true}).Locks(now.AddDate(-10, 0, 0), now))
+	a.True((&RetentionRule{Days: 30}).Locks(now.AddDate(0, 0, -29), now))
+	a.False((&RetentionRule{Days: 30}).Locks(now.AddDate(0, 0, -31), now))
+}
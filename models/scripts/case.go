@@ -0,0 +1,46 @@
+package scripts
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ListCaseConflicts 列出仅大小写不同的同名对象，在启用忽略大小写的路径解析前，
+// 这些对象只有一个可以通过路径访问，需要用户或管理员手动重命名
+type ListCaseConflicts int
+
+type caseConflict struct {
+	OwnerID  uint
+	ParentID uint
+	Name     string
+	Count    int
+}
+
+// Run 运行脚本检查所有目录和文件
+func (script ListCaseConflicts) Run(ctx context.Context) {
+	var folders []caseConflict
+	model.DB.Model(&model.Folder{}).
+		Select("owner_id, parent_id, LOWER(name) as name, count(*) as count").
+		Group("owner_id, parent_id, LOWER(name)").
+		Having("count(*) > 1").
+		Scan(&folders)
+	for _, conflict := range folders {
+		util.Log().Warning("User %d has %d folders named %q (case-insensitive) under folder %d.",
+			conflict.OwnerID, conflict.Count, conflict.Name, conflict.ParentID)
+	}
+
+	var files []caseConflict
+	model.DB.Model(&model.File{}).
+		Select("user_id as owner_id, folder_id as parent_id, LOWER(name) as name, count(*) as count").
+		Group("user_id, folder_id, LOWER(name)").
+		Having("count(*) > 1").
+		Scan(&files)
+	for _, conflict := range files {
+		util.Log().Warning("User %d has %d files named %q (case-insensitive) under folder %d.",
+			conflict.OwnerID, conflict.Count, conflict.Name, conflict.ParentID)
+	}
+
+	util.Log().Info("Found %d folder and %d file case conflict(s).", len(folders), len(files))
+}
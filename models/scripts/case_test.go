@@ -0,0 +1,21 @@
+package scripts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListCaseConflicts_Run(t *testing.T) {
+	asserts := assert.New(t)
+	script := ListCaseConflicts(0)
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)GROUP BY(.+)HAVING(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "parent_id", "name", "count"}).AddRow(1, 2, "docs", 2))
+	mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY(.+)HAVING(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "parent_id", "name", "count"}))
+	script.Run(context.Background())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
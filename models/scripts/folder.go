@@ -0,0 +1,35 @@
+package scripts
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+type FolderSizeCalibration int
+
+// Run 运行脚本校准所有用户目录的总大小
+func (script FolderSizeCalibration) Run(ctx context.Context) {
+	// 列出所有用户
+	var res []model.User
+	model.DB.Model(&model.User{}).Find(&res)
+
+	for _, user := range res {
+		drifts, err := model.GetFolderSizeDrifts(user.ID)
+		if err != nil {
+			util.Log().Warning("Failed to calculate folder sizes for user %q: %s", user.Email, err)
+			continue
+		}
+
+		for _, drift := range drifts {
+			if err := model.FixFolderSize(drift); err != nil {
+				util.Log().Warning("Failed to calibrate size of folder %d: %s", drift.ID, err)
+			}
+		}
+
+		if len(drifts) > 0 {
+			util.Log().Info("Calibrate size of %d folder(s) for user %q.", len(drifts), user.Email)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package scripts
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderSizeCalibration_Run(t *testing.T) {
+	asserts := assert.New(t)
+	script := FolderSizeCalibration(0)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@a.com").AddRow(2, "b@b.com"))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size"}).AddRow(1, nil, 0).AddRow(2, 1, 0))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"folder_id", "sum"}).AddRow(2, 10))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(10, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2).WillReturnError(errors.New("error"))
+	script.Run(context.Background())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -20,9 +20,9 @@ func (script UserStorageCalibration) Run(ctx context.Context) {
 
 	// 逐个检查容量
 	for _, user := range res {
-		// 计算正确的容量
+		// 计算正确的容量，回收站中的文件同样占用容量
 		var total storageResult
-		model.DB.Model(&model.File{}).Where("user_id = ?", user.ID).Select("sum(size) as total").Scan(&total)
+		model.DB.Unscoped().Model(&model.File{}).Where("user_id = ?", user.ID).Select("sum(size) as total").Scan(&total)
 		// 更新用户的容量
 		if user.Storage != total.Total {
 			util.Log().Info("Calibrate used storage for user %q, from %d to %d.", user.Email,
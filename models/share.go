@@ -26,6 +26,14 @@ type Share struct {
 	Expires         *time.Time // 过期时间，空值表示无过期时间
 	PreviewEnabled  bool       // 是否允许直接预览
 	SourceName      string     `gorm:"index:source"` // 用于搜索的字段
+	MaxTraffic      uint64     // 最大下载流量（字节），0 为不限制
+	Traffic         uint64     // 已下载的流量（字节）
+	FileDownloads   int        // 每个文件的最大下载次数，0 为不限制
+	UploadOnly      bool       // 是否为仅允许上传的投递分享
+	UploadMaxSize   uint64     // 投递单个文件的最大大小（字节），0 为不限制
+	UploadExts      string     // 投递允许的扩展名，逗号分隔，空值为不限制
+	UploadLimit     int        // 每个访客的最大投递次数，0 为不限制
+	Disabled        bool       // 是否因举报被管理员禁用
 
 	// 数据库忽略字段
 	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -57,14 +65,24 @@ func GetShareByHashID(hashID string) *Share {
 	return &share
 }
 
+// GetShareByID 根据ID查找分享
+func GetShareByID(id uint) (*Share, error) {
+	var share Share
+	result := DB.First(&share, id)
+	return &share, result.Error
+}
+
 // IsAvailable 返回此分享是否可用（是否过期）
 func (share *Share) IsAvailable() bool {
-	if share.RemainDownloads == 0 {
+	if share.Disabled || share.RemainDownloads == 0 {
 		return false
 	}
 	if share.Expires != nil && time.Now().After(*share.Expires) {
 		return false
 	}
+	if share.MaxTraffic > 0 && share.Traffic >= share.MaxTraffic {
+		return false
+	}
 
 	// 检查创建者状态
 	if share.Creator().Status != Active {
@@ -196,6 +214,26 @@ func DeleteShareBySourceIDs(sources []uint, isDir bool) error {
 	return DB.Where("source_id in (?) and is_dir = ?", sources, isDir).Delete(&Share{}).Error
 }
 
+// GetSharesBySourceIDs 根据原始资源类型和ID列出UID下的分享
+func GetSharesBySourceIDs(uid uint, sources []uint, isDir bool) ([]Share, error) {
+	var shares []Share
+	result := DB.Where("user_id = ? and source_id in (?) and is_dir = ?", uid, sources, isDir).Find(&shares)
+	return shares, result.Error
+}
+
+// GetSharesByUserID 列出UID下的所有分享
+func GetSharesByUserID(uid uint) ([]Share, error) {
+	var shares []Share
+	result := DB.Where("user_id = ?", uid).Find(&shares)
+	return shares, result.Error
+}
+
+// DeleteSharesByUserID 彻底删除UID下的所有分享，返回删除的条数
+func DeleteSharesByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Share{})
+	return result.RowsAffected, result.Error
+}
+
 // ListShares 列出UID下的分享
 func ListShares(uid uint, page, pageSize int, order string, publicOnly bool) ([]Share, int) {
 	var (
@@ -235,7 +273,7 @@ func SearchShares(page, pageSize int, order, keywords string) ([]Share, int) {
 	}
 
 	dbChain := DB
-	dbChain = dbChain.Where("password = ? and remain_downloads <> 0 and (expires is NULL or expires > ?) and source_name like ?", "", time.Now(), "%"+strings.Join(availableList, "%")+"%")
+	dbChain = dbChain.Where("password = ? and remain_downloads <> 0 and (expires is NULL or expires > ?) and "+Like("source_name"), "", time.Now(), "%"+strings.Join(availableList, "%")+"%")
 
 	// 计算总数用于分页
 	dbChain.Model(&Share{}).Count(&total)
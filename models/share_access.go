@@ -0,0 +1,115 @@
+package model
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// 分享访问记录的操作类型
+const (
+	ShareAccessView           = "view"
+	ShareAccessUnlock         = "unlock"
+	ShareAccessPasswordFailed = "password_failed"
+	ShareAccessLocked         = "locked"
+	ShareAccessDownload       = "download"
+	ShareAccessUpload         = "upload"
+)
+
+// ErrSharePasswordLocked 密码错误次数过多，暂时禁止尝试
+var ErrSharePasswordLocked = errors.New("too many incorrect password attempts")
+
+// ShareAccessLog 分享访问记录
+type ShareAccessLog struct {
+	gorm.Model
+	ShareID uint   `gorm:"index:share_id"`
+	Action  string `gorm:"size:32"`
+	IP      string `gorm:"size:64"`
+	UA      string `gorm:"size:255"`
+}
+
+// Create 写入访问记录
+func (log *ShareAccessLog) Create() error {
+	return DB.Create(log).Error
+}
+
+// RecordShareAccess 记录来自当前请求的分享访问，写入失败时仅记录警告
+func RecordShareAccess(c *gin.Context, shareID uint, action string) {
+	log := &ShareAccessLog{
+		ShareID: shareID,
+		Action:  action,
+		IP:      c.ClientIP(),
+		UA:      c.Request.UserAgent(),
+	}
+
+	if len(log.UA) > 255 {
+		log.UA = log.UA[:255]
+	}
+
+	if err := log.Create(); err != nil {
+		util.Log().Warning("Failed to record access of share %d: %s", shareID, err)
+	}
+}
+
+// ListShareAccessLogs 分页列出分享的访问记录，最近的记录在前
+func ListShareAccessLogs(shareID uint, page, pageSize int) ([]ShareAccessLog, int, error) {
+	var (
+		logs  []ShareAccessLog
+		total int
+	)
+
+	dbChain := DB.Model(&ShareAccessLog{}).Where("share_id = ?", shareID)
+	if err := dbChain.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	result := dbChain.Order("id desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&logs)
+	return logs, total, result.Error
+}
+
+// DeleteShareAccessLogsBefore 彻底删除给定时间之前的访问记录，返回删除的条数
+func DeleteShareAccessLogsBefore(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("created_at < ?", before).Delete(&ShareAccessLog{})
+	return result.RowsAffected, result.Error
+}
+
+// TryPassword 尝试使用密码解锁分享。同一 IP 连续输错 share_password_max_attempts 次后，
+// share_password_lock_duration 秒内的尝试均返回 ErrSharePasswordLocked
+func (share *Share) TryPassword(password, ip string) (bool, error) {
+	lockKey := fmt.Sprintf("share_password_lock_%d_%s", share.ID, ip)
+	if _, locked := cache.Get(lockKey); locked {
+		return false, ErrSharePasswordLocked
+	}
+
+	failKey := fmt.Sprintf("share_password_fail_%d_%s", share.ID, ip)
+	if subtle.ConstantTimeCompare([]byte(password), []byte(share.Password)) == 1 {
+		cache.Deletes([]string{failKey}, "")
+		return true, nil
+	}
+
+	maxAttempts := GetIntSetting("share_password_max_attempts", 5)
+	if maxAttempts <= 0 {
+		return false, nil
+	}
+
+	failed := 1
+	if count, ok := cache.Get(failKey); ok {
+		failed += count.(int)
+	}
+
+	duration := GetIntSetting("share_password_lock_duration", 900)
+	if failed >= maxAttempts {
+		cache.Set(lockKey, true, duration)
+		cache.Deletes([]string{failKey}, "")
+		return false, ErrSharePasswordLocked
+	}
+
+	cache.Set(failKey, failed, duration)
+	return false, nil
+}
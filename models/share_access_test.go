@@ -0,0 +1,125 @@
+package model
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordShareAccess(t *testing.T) {
+	a := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "192.168.1.1:1234"
+	c.Request.Header.Set("User-Agent", "test")
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)share_access_logs(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, ShareAccessView, "192.168.1.1", "test").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		RecordShareAccess(c, 1, ShareAccessView)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)share_access_logs(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		RecordShareAccess(c, 1, ShareAccessDownload)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListShareAccessLogs(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT count(.+)share_access_logs(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)share_access_logs(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "action"}).AddRow(2, ShareAccessDownload).AddRow(1, ShareAccessView))
+		logs, total, err := ListShareAccessLogs(1, 1, 10)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Equal(2, total)
+		a.Len(logs, 2)
+		a.Equal(ShareAccessDownload, logs[0].Action)
+	}
+
+	// 失败
+	{
+		mock.ExpectQuery("SELECT count(.+)share_access_logs(.+)").WillReturnError(errors.New("error"))
+		_, _, err := ListShareAccessLogs(1, 1, 10)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+}
+
+func TestDeleteShareAccessLogsBefore(t *testing.T) {
+	a := assert.New(t)
+	before := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)share_access_logs(.+)").WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	affected, err := DeleteShareAccessLogsBefore(before)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(3, affected)
+}
+
+func TestShare_TryPassword(t *testing.T) {
+	a := assert.New(t)
+	share := &Share{Model: gorm.Model{ID: 542}, Password: "password"}
+	cache.Set("setting_share_password_max_attempts", "2", 0)
+	cache.Set("setting_share_password_lock_duration", "60", 0)
+
+	// 密码正确
+	ok, err := share.TryPassword("password", "1.1.1.1")
+	a.NoError(err)
+	a.True(ok)
+
+	// 第一次输错
+	ok, err = share.TryPassword("wrong", "1.1.1.1")
+	a.NoError(err)
+	a.False(ok)
+
+	// 达到上限后锁定，正确的密码也被拒绝
+	ok, err = share.TryPassword("wrong", "1.1.1.1")
+	a.ErrorIs(err, ErrSharePasswordLocked)
+	a.False(ok)
+	_, err = share.TryPassword("password", "1.1.1.1")
+	a.ErrorIs(err, ErrSharePasswordLocked)
+
+	// 不影响其他 IP
+	ok, err = share.TryPassword("password", "2.2.2.2")
+	a.NoError(err)
+	a.True(ok)
+
+	// 输对后重新计数
+	_, err = share.TryPassword("wrong", "2.2.2.2")
+	a.NoError(err)
+	_, err = share.TryPassword("password", "2.2.2.2")
+	a.NoError(err)
+	_, err = share.TryPassword("wrong", "2.2.2.2")
+	a.NoError(err)
+
+	// 不限制尝试次数
+	cache.Set("setting_share_password_max_attempts", "0", 0)
+	for i := 0; i < 3; i++ {
+		_, err = share.TryPassword("wrong", "3.3.3.3")
+		a.NoError(err)
+	}
+}
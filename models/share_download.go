@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrShareTrafficExceeded 超出分享的最大下载流量
+	ErrShareTrafficExceeded = errors.New("share traffic limit exceeded")
+	// ErrShareFileDownloadsExceeded 文件已达到分享的最大下载次数
+	ErrShareFileDownloadsExceeded = errors.New("file download limit of the share exceeded")
+)
+
+// ShareFileDownload 分享中单个文件的下载计数
+type ShareFileDownload struct {
+	gorm.Model
+	ShareID   uint `gorm:"unique_index:share_file"`
+	FileID    uint `gorm:"unique_index:share_file"`
+	Downloads int
+}
+
+// Limited 返回分享是否设定了流量或单文件下载次数限制
+func (share *Share) Limited() bool {
+	return share.MaxTraffic > 0 || share.FileDownloads > 0
+}
+
+// ConsumeDownload 检查分享的流量和单文件下载次数限制，通过后计入一次文件下载
+func (share *Share) ConsumeDownload(file *File) error {
+	if share.MaxTraffic > 0 && share.Traffic+file.Size > share.MaxTraffic {
+		return ErrShareTrafficExceeded
+	}
+
+	counter := ShareFileDownload{}
+	err := DB.Where("share_id = ? and file_id = ?", share.ID, file.ID).First(&counter).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	if share.FileDownloads > 0 && counter.Downloads >= share.FileDownloads {
+		return ErrShareFileDownloadsExceeded
+	}
+
+	tx := DB.Begin()
+	if err := tx.Model(share).UpdateColumn("traffic", gorm.Expr("traffic + ?", file.Size)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if counter.ID == 0 {
+		counter = ShareFileDownload{ShareID: share.ID, FileID: file.ID, Downloads: 1}
+		if err := tx.Create(&counter).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	} else if err := tx.Model(&counter).UpdateColumn("downloads", gorm.Expr("downloads + ?", 1)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	share.Traffic += file.Size
+	return tx.Commit().Error
+}
+
+// GetShareFileDownloads 列出分享中各文件的下载计数
+func GetShareFileDownloads(shareID uint) ([]ShareFileDownload, error) {
+	var counters []ShareFileDownload
+	result := DB.Where("share_id = ?", shareID).Order("downloads desc").Find(&counters)
+	return counters, result.Error
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_Limited(t *testing.T) {
+	a := assert.New(t)
+	a.False((&Share{}).Limited())
+	a.True((&Share{MaxTraffic: 1}).Limited())
+	a.True((&Share{FileDownloads: 1}).Limited())
+}
+
+func TestShare_ConsumeDownload(t *testing.T) {
+	a := assert.New(t)
+	file := &File{Model: gorm.Model{ID: 2}, Size: 10}
+
+	// 超出流量限制
+	{
+		share := &Share{Model: gorm.Model{ID: 1}, MaxTraffic: 15, Traffic: 6}
+		a.ErrorIs(share.ConsumeDownload(file), ErrShareTrafficExceeded)
+	}
+
+	// 超出单文件下载次数
+	{
+		share := &Share{Model: gorm.Model{ID: 1}, FileDownloads: 2}
+		mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "downloads"}).AddRow(1, 2))
+		a.ErrorIs(share.ConsumeDownload(file), ErrShareFileDownloadsExceeded)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 首次下载，创建计数
+	{
+		share := &Share{Model: gorm.Model{ID: 1}, MaxTraffic: 20, Traffic: 10}
+		mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "downloads"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)traffic(.+)").WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)share_file_downloads(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(share.ConsumeDownload(file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(20, share.Traffic)
+	}
+
+	// 已有计数
+	{
+		share := &Share{Model: gorm.Model{ID: 1}, FileDownloads: 2}
+		mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "downloads"}).AddRow(3, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)traffic(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)share_file_downloads(.+)downloads(.+)").WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(share.ConsumeDownload(file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 更新失败
+	{
+		share := &Share{Model: gorm.Model{ID: 1}}
+		mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "downloads"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)traffic(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(share.ConsumeDownload(file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(0, share.Traffic)
+	}
+
+	// 查询失败
+	{
+		share := &Share{Model: gorm.Model{ID: 1}}
+		mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").WillReturnError(errors.New("error"))
+		a.Error(share.ConsumeDownload(file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetShareFileDownloads(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)share_file_downloads(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "file_id", "downloads"}).AddRow(1, 2, 3))
+	counters, err := GetShareFileDownloads(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(counters, 1)
+	a.Equal(3, counters[0].Downloads)
+}
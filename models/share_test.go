@@ -80,6 +80,12 @@ func TestShare_IsAvailable(t *testing.T) {
 		asserts.False(share.IsAvailable())
 	}
 
+	// 已被管理员禁用
+	{
+		share := Share{RemainDownloads: -1, Disabled: true}
+		asserts.False(share.IsAvailable())
+	}
+
 	// 时效过期
 	{
 		expires := time.Unix(10, 10)
@@ -90,6 +96,16 @@ func TestShare_IsAvailable(t *testing.T) {
 		asserts.False(share.IsAvailable())
 	}
 
+	// 流量用尽
+	{
+		share := Share{
+			RemainDownloads: -1,
+			MaxTraffic:      10,
+			Traffic:         10,
+		}
+		asserts.False(share.IsAvailable())
+	}
+
 	// 源对象为目录，但不存在
 	{
 		share := Share{
@@ -295,6 +311,42 @@ func TestShare_UpdateAndDelete(t *testing.T) {
 
 }
 
+func TestGetSharesBySourceIDs(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WithArgs(1, 2, 3, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_id"}).AddRow(1, 2).AddRow(2, 3))
+		res, err := GetSharesBySourceIDs(1, []uint{2, 3}, false)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(res, 2)
+	}
+
+	// 失败
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnError(errors.New("error"))
+		res, err := GetSharesBySourceIDs(1, []uint{2}, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Len(res, 0)
+	}
+}
+
+func TestGetSharesByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)shares(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	res, err := GetSharesByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(res, 1)
+}
+
 func TestListShares(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -319,3 +371,17 @@ func TestSearchShares(t *testing.T) {
 	asserts.Len(res, 1)
 	asserts.Equal(1, total)
 }
+
+func TestDeleteSharesByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)shares(.+)").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	n, err := DeleteSharesByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, n)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrShareUploadTooLarge 投递的文件超出大小限制
+	ErrShareUploadTooLarge = errors.New("file is too large for this share")
+	// ErrShareUploadExtNotAllowed 投递的文件类型不被允许
+	ErrShareUploadExtNotAllowed = errors.New("file type is not allowed for this share")
+	// ErrShareUploadLimitExceeded 访客的投递次数已达上限
+	ErrShareUploadLimitExceeded = errors.New("upload limit of the share exceeded")
+)
+
+// ShareUploadCount 访客向投递分享上传文件的次数
+type ShareUploadCount struct {
+	gorm.Model
+	ShareID uint   `gorm:"unique_index:share_visitor"`
+	Visitor string `gorm:"size:64;unique_index:share_visitor"`
+	Uploads int
+}
+
+// UploadExtList 返回投递允许的扩展名列表
+func (share *Share) UploadExtList() []string {
+	if share.UploadExts == "" {
+		return nil
+	}
+
+	exts := strings.Split(strings.ToLower(share.UploadExts), ",")
+	res := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		if ext = strings.TrimPrefix(strings.TrimSpace(ext), "."); ext != "" {
+			res = append(res, ext)
+		}
+	}
+
+	return res
+}
+
+// ValidateUpload 检查投递的文件是否符合分享的大小和类型限制
+func (share *Share) ValidateUpload(name string, size uint64) error {
+	if share.UploadMaxSize > 0 && size > share.UploadMaxSize {
+		return ErrShareUploadTooLarge
+	}
+
+	if exts := share.UploadExtList(); len(exts) > 0 && !util.IsInExtensionList(exts, name) {
+		return ErrShareUploadExtNotAllowed
+	}
+
+	return nil
+}
+
+// CheckUploadLimit 获取访客的投递计数，已达到投递次数上限时返回 ErrShareUploadLimitExceeded
+func (share *Share) CheckUploadLimit(visitor string) (ShareUploadCount, error) {
+	var counter ShareUploadCount
+	err := DB.Where("share_id = ? and visitor = ?", share.ID, visitor).First(&counter).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return counter, err
+	}
+
+	if share.UploadLimit > 0 && counter.Uploads >= share.UploadLimit {
+		return counter, ErrShareUploadLimitExceeded
+	}
+
+	return counter, nil
+}
+
+// UploadedBy 访客投递成功后增加其投递计数
+func (share *Share) UploadedBy(counter *ShareUploadCount, visitor string) error {
+	if counter.ID == 0 {
+		*counter = ShareUploadCount{ShareID: share.ID, Visitor: visitor, Uploads: 1}
+		return DB.Create(counter).Error
+	}
+
+	counter.Uploads++
+	return DB.Model(counter).UpdateColumn("uploads", gorm.Expr("uploads + ?", 1)).Error
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_UploadExtList(t *testing.T) {
+	a := assert.New(t)
+	a.Nil((&Share{}).UploadExtList())
+	a.Equal([]string{"doc", "pdf", "zip"}, (&Share{UploadExts: "DOC, .pdf,,zip"}).UploadExtList())
+}
+
+func TestShare_ValidateUpload(t *testing.T) {
+	a := assert.New(t)
+	share := &Share{}
+	a.NoError(share.ValidateUpload("a.exe", 1<<30))
+
+	share.UploadMaxSize = 10
+	share.UploadExts = "pdf,docx"
+	a.ErrorIs(share.ValidateUpload("a.pdf", 11), ErrShareUploadTooLarge)
+	a.ErrorIs(share.ValidateUpload("a.exe", 1), ErrShareUploadExtNotAllowed)
+	a.ErrorIs(share.ValidateUpload("pdf", 1), ErrShareUploadExtNotAllowed)
+	a.NoError(share.ValidateUpload("a.PDF", 10))
+}
+
+func TestShare_CheckUploadLimit(t *testing.T) {
+	a := assert.New(t)
+	share := &Share{Model: gorm.Model{ID: 1}, UploadLimit: 2}
+
+	// 首次投递
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_counts(.+)").WithArgs(1, "ip_1.1.1.1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uploads"}))
+		counter, err := share.CheckUploadLimit("ip_1.1.1.1")
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.EqualValues(0, counter.ID)
+	}
+
+	// 达到上限
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_counts(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uploads"}).AddRow(1, 2))
+		_, err := share.CheckUploadLimit("ip_1.1.1.1")
+		a.NoError(mock.ExpectationsWereMet())
+		a.ErrorIs(err, ErrShareUploadLimitExceeded)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)share_upload_counts(.+)").WillReturnError(errors.New("error"))
+		_, err := share.CheckUploadLimit("ip_1.1.1.1")
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+}
+
+func TestShare_UploadedBy(t *testing.T) {
+	a := assert.New(t)
+	share := &Share{Model: gorm.Model{ID: 1}}
+
+	// 创建计数
+	{
+		counter := &ShareUploadCount{}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)share_upload_counts(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectCommit()
+		a.NoError(share.UploadedBy(counter, "user_1"))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(5, counter.ID)
+		a.Equal(1, counter.Uploads)
+		a.Equal("user_1", counter.Visitor)
+	}
+
+	// 增加计数
+	{
+		counter := &ShareUploadCount{Model: gorm.Model{ID: 5}, Uploads: 1}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)share_upload_counts(.+)uploads(.+)").WithArgs(1, 5).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(share.UploadedBy(counter, "user_1"))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import "github.com/jinzhu/gorm"
+
+// StaticSite 以静态网站形式公开的目录
+type StaticSite struct {
+	gorm.Model
+	// Slug 访问路径中的标识
+	Slug     string `gorm:"size:64;unique_index"`
+	FolderID uint   `gorm:"unique_index"`
+	OwnerID  uint   `gorm:"index"`
+
+	// 数据库忽略字段
+	Folder *Folder `gorm:"-"`
+	Owner  *User   `gorm:"-"`
+}
+
+// Create 创建静态网站
+func (site *StaticSite) Create() error {
+	return DB.Create(site).Error
+}
+
+// Load 加载静态网站对应的目录与所有者，所有者不可用时返回错误
+func (site *StaticSite) Load() error {
+	owner, err := GetActiveUserByID(site.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	var folder Folder
+	if err := DB.Where("id = ? and owner_id = ?", site.FolderID, site.OwnerID).First(&folder).Error; err != nil {
+		return err
+	}
+
+	site.Owner = &owner
+	site.Folder = &folder
+	return nil
+}
+
+// GetStaticSiteBySlug 用标识获取静态网站
+func GetStaticSiteBySlug(slug string) (StaticSite, error) {
+	var site StaticSite
+	result := DB.Where("slug = ?", slug).First(&site)
+	return site, result.Error
+}
+
+// ListStaticSitesByUser 列出用户的静态网站
+func ListStaticSitesByUser(uid uint) ([]StaticSite, error) {
+	var sites []StaticSite
+	result := DB.Where("owner_id = ?", uid).Order("id").Find(&sites)
+	return sites, result.Error
+}
+
+// DeleteStaticSite 取消用户目录的静态网站
+func DeleteStaticSite(id, uid uint) (int64, error) {
+	result := DB.Unscoped().Where("id = ? and owner_id = ?", id, uid).Delete(&StaticSite{})
+	return result.RowsAffected, result.Error
+}
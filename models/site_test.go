@@ -0,0 +1,73 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticSite_Load(t *testing.T) {
+	asserts := assert.New(t)
+	site := &StaticSite{FolderID: 2, OwnerID: 1}
+
+	// 所有者不可用
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(site.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).WillReturnError(errors.New("error"))
+		asserts.Error(site.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(site.Folder)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "blog"))
+		asserts.NoError(site.Load())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, site.Owner.ID)
+		asserts.Equal("blog", site.Folder.Name)
+	}
+}
+
+func TestGetStaticSiteBySlug(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)static_sites(.+)").WithArgs("blog").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, "blog"))
+	site, err := GetStaticSiteBySlug("blog")
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, site.ID)
+
+	mock.ExpectQuery("SELECT(.+)static_sites(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	sites, err := ListStaticSitesByUser(1)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(sites, 2)
+}
+
+func TestDeleteStaticSite(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)static_sites(.+)").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := DeleteStaticSite(1, 2)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, affected)
+}
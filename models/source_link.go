@@ -5,6 +5,8 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/jinzhu/gorm"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // SourceLink represent a shared file source link
@@ -14,6 +16,15 @@ type SourceLink struct {
 	Name      string // name of the file while creating the source link, for annotation
 	Downloads int    // 下载数
 
+	// Slug 自定义链接标识，为空时仅可通过 ID 访问
+	Slug *string `gorm:"size:64;unique_index"`
+	// Expires 过期时间，为空时永久有效
+	Expires *time.Time
+	// Referers 允许的来源域名，以逗号分隔，为空时不限制
+	Referers string `gorm:"type:text"`
+	// LastAccessed 最后访问时间
+	LastAccessed *time.Time
+
 	// 关联模型
 	File File `gorm:"save_associations:false:false"`
 }
@@ -28,6 +39,79 @@ func (s *SourceLink) Link() (string, error) {
 	return baseURL.ResolveReference(linkPath).String(), nil
 }
 
+// SlugLink 获取自定义标识的链接地址，未设置标识时返回空字符串
+func (s *SourceLink) SlugLink() string {
+	if s.Slug == nil || *s.Slug == "" {
+		return ""
+	}
+
+	return GetSiteURL().ResolveReference(&url.URL{Path: "/f/s/" + *s.Slug}).String()
+}
+
+// IsExpired 链接是否已过期
+func (s *SourceLink) IsExpired() bool {
+	return s.Expires != nil && time.Now().After(*s.Expires)
+}
+
+// RefererList 获取允许的来源域名列表
+func (s *SourceLink) RefererList() []string {
+	res := make([]string, 0)
+	for _, host := range strings.Split(s.Referers, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			res = append(res, host)
+		}
+	}
+
+	return res
+}
+
+// AllowReferer 检查请求来源是否在白名单中，白名单中的 *.example.com 匹配其所有子域名。
+// 设置了白名单时，没有来源的请求同样被拒绝
+func (s *SourceLink) AllowReferer(referer string) bool {
+	hosts := s.RefererList()
+	if len(hosts) == 0 {
+		return true
+	}
+
+	refererURL, err := url.Parse(referer)
+	if err != nil || refererURL.Hostname() == "" {
+		return false
+	}
+
+	requestHost := strings.ToLower(refererURL.Hostname())
+	for _, host := range hosts {
+		host = strings.ToLower(host)
+		if strings.HasPrefix(host, "*.") {
+			if strings.HasSuffix(requestHost, host[1:]) {
+				return true
+			}
+		} else if requestHost == host {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Create 创建外链
+func (s *SourceLink) Create() error {
+	return DB.Create(s).Error
+}
+
+// Update 更新外链的标识、过期时间和来源白名单
+func (s *SourceLink) Update() error {
+	return DB.Model(s).UpdateColumns(map[string]interface{}{
+		"slug":     s.Slug,
+		"expires":  s.Expires,
+		"referers": s.Referers,
+	}).Error
+}
+
+// Delete 删除外链
+func (s *SourceLink) Delete() error {
+	return DB.Unscoped().Delete(s).Error
+}
+
 // GetTasksByID queries source link based on ID
 func GetSourceLinkByID(id interface{}) (*SourceLink, error) {
 	link := &SourceLink{}
@@ -40,8 +124,56 @@ func GetSourceLinkByID(id interface{}) (*SourceLink, error) {
 	return link, result.Error
 }
 
+// GetSourceLinkBySlug 用自定义标识获取外链
+func GetSourceLinkBySlug(slug string) (*SourceLink, error) {
+	link := &SourceLink{}
+	result := DB.Where("slug = ?", slug).First(link)
+	files, _ := GetFilesByIDs([]uint{link.FileID}, 0)
+	if len(files) > 0 {
+		link.File = files[0]
+	}
+
+	return link, result.Error
+}
+
+// ListSourceLinksByUser 列出用户文件的所有外链，并填充对应的文件
+func ListSourceLinksByUser(uid uint) ([]SourceLink, error) {
+	var links []SourceLink
+	result := DB.Where("file_id in (?)", DB.Model(&File{}).Select("id").Where("user_id = ?", uid).QueryExpr()).
+		Order("id desc").Find(&links)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	fileIDs := make([]uint, 0, len(links))
+	for _, link := range links {
+		fileIDs = append(fileIDs, link.FileID)
+	}
+
+	if len(fileIDs) == 0 {
+		return links, nil
+	}
+
+	files, _ := GetFilesByIDs(fileIDs, uid)
+	fileMap := make(map[uint]File, len(files))
+	for _, file := range files {
+		fileMap[file.ID] = file
+	}
+
+	for i := range links {
+		links[i].File = fileMap[links[i].FileID]
+	}
+
+	return links, nil
+}
+
 // Viewed 增加访问次数
 func (s *SourceLink) Downloaded() {
+	now := time.Now()
 	s.Downloads++
-	DB.Model(s).UpdateColumn("downloads", gorm.Expr("downloads + ?", 1))
+	s.LastAccessed = &now
+	DB.Model(s).UpdateColumns(map[string]interface{}{
+		"downloads":     gorm.Expr("downloads + ?", 1),
+		"last_accessed": now,
+	})
 }
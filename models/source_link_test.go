@@ -2,8 +2,10 @@ package model
 
 import (
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestSourceLink_Link(t *testing.T) {
@@ -50,3 +52,102 @@ func TestSourceLink_Downloaded(t *testing.T) {
 	s.Downloaded()
 	a.NoError(mock.ExpectationsWereMet())
 }
+
+func TestSourceLink_IsExpired(t *testing.T) {
+	a := assert.New(t)
+	s := &SourceLink{}
+	a.False(s.IsExpired())
+
+	past := time.Now().Add(-time.Minute)
+	s.Expires = &past
+	a.True(s.IsExpired())
+
+	future := time.Now().Add(time.Minute)
+	s.Expires = &future
+	a.False(s.IsExpired())
+}
+
+func TestSourceLink_AllowReferer(t *testing.T) {
+	a := assert.New(t)
+	s := &SourceLink{}
+
+	// 未设置白名单
+	a.True(s.AllowReferer(""))
+	a.Empty(s.RefererList())
+
+	s.Referers = "example.com, *.cloudreve.org,"
+	a.Equal([]string{"example.com", "*.cloudreve.org"}, s.RefererList())
+	a.True(s.AllowReferer("https://example.com/page"))
+	a.True(s.AllowReferer("https://EXAMPLE.com:8080/page"))
+	a.True(s.AllowReferer("https://docs.cloudreve.org/"))
+	a.False(s.AllowReferer("https://cloudreve.org/"))
+	a.False(s.AllowReferer("https://notexample.com/"))
+	a.False(s.AllowReferer(""))
+	a.False(s.AllowReferer("://"))
+}
+
+func TestSourceLink_SlugLink(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+	s := &SourceLink{}
+	a.Empty(s.SlugLink())
+
+	slug := "logo"
+	s.Slug = &slug
+	a.Equal("https://cloudreve.org/f/s/logo", s.SlugLink())
+}
+
+func TestGetSourceLinkBySlug(t *testing.T) {
+	a := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs("logo").WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}).AddRow(1, 2))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+
+	res, err := GetSourceLinkBySlug("logo")
+	a.NoError(err)
+	a.EqualValues(2, res.File.ID)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListSourceLinksByUser(t *testing.T) {
+	a := assert.New(t)
+
+	// 没有外链
+	{
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}))
+		res, err := ListSourceLinksByUser(1)
+		a.NoError(err)
+		a.Empty(res)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)source_links(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}).AddRow(3, 2).AddRow(4, 5))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2, 5, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a.png"))
+		res, err := ListSourceLinksByUser(1)
+		a.NoError(err)
+		a.Len(res, 2)
+		a.Equal("a.png", res[0].File.Name)
+		a.EqualValues(0, res[1].File.ID)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestSourceLink_UpdateAndDelete(t *testing.T) {
+	a := assert.New(t)
+	s := &SourceLink{}
+	s.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)source_links(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(s.Update())
+	a.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)source_links(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(s.Delete())
+	a.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,180 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DailyStat 站点每日统计，PolicyID 为 0 的记录为全站数据，其余为对应存储策略的数据
+type DailyStat struct {
+	gorm.Model
+	Date          string `gorm:"unique_index:date_policy;size:10"`
+	PolicyID      uint   `gorm:"unique_index:date_policy"`
+	ActiveUsers   uint64
+	Registrations uint64
+	Uploads       uint64
+	UploadBytes   uint64
+	Downloads     uint64
+	// DownloadBytes 按被下载文件大小估算的下载流量
+	DownloadBytes uint64
+	ShareHits     uint64
+	// Storage 当日结束时的已用存储空间
+	Storage uint64
+}
+
+// UserDailyStat 用户每日的上传、下载用量，下载计入被下载文件的所有者
+type UserDailyStat struct {
+	gorm.Model
+	Date          string `gorm:"unique_index:user_stat_date_user;size:10"`
+	UserID        uint   `gorm:"unique_index:user_stat_date_user"`
+	Uploads       uint64
+	UploadBytes   uint64
+	Downloads     uint64
+	DownloadBytes uint64
+}
+
+// UserStatSum 一段时间内用户用量的合计
+type UserStatSum struct {
+	UserID        uint
+	Uploads       uint64
+	UploadBytes   uint64
+	Downloads     uint64
+	DownloadBytes uint64
+}
+
+// PolicyFileStat 按存储策略分组的文件数量与大小
+type PolicyFileStat struct {
+	PolicyID uint
+	Count    uint64
+	Size     uint64
+}
+
+// UserFileStat 按用户分组的文件数量与大小
+type UserFileStat struct {
+	UserID uint
+	Count  uint64
+	Size   uint64
+}
+
+// StatDate 返回统计记录所属的日期
+func StatDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// SaveDailyStat 写入统计记录，incr 中的字段在原值上累加，set 中的字段直接覆盖
+func SaveDailyStat(date string, policyID uint, incr map[string]uint64, set map[string]interface{}) error {
+	stat := &DailyStat{}
+	if err := DB.Where(DailyStat{Date: date, PolicyID: policyID}).FirstOrCreate(stat).Error; err != nil {
+		return err
+	}
+
+	return updateStat(stat, incr, set)
+}
+
+// SaveUserDailyStat 写入用户统计记录，incr 中的字段在原值上累加，set 中的字段直接覆盖
+func SaveUserDailyStat(date string, uid uint, incr map[string]uint64, set map[string]interface{}) error {
+	stat := &UserDailyStat{}
+	if err := DB.Where(UserDailyStat{Date: date, UserID: uid}).FirstOrCreate(stat).Error; err != nil {
+		return err
+	}
+
+	return updateStat(stat, incr, set)
+}
+
+func updateStat(stat interface{}, incr map[string]uint64, set map[string]interface{}) error {
+	updates := make(map[string]interface{}, len(incr)+len(set))
+	for column, delta := range incr {
+		if delta > 0 {
+			updates[column] = gorm.Expr(column+" + ?", delta)
+		}
+	}
+	for column, value := range set {
+		updates[column] = value
+	}
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	return DB.Model(stat).Updates(updates).Error
+}
+
+// ListDailyStats 列出 [from, to] 日期范围内指定存储策略的统计记录，按日期排列
+func ListDailyStats(policyID uint, from, to string) ([]DailyStat, error) {
+	var stats []DailyStat
+	result := DB.Where("policy_id = ? and date >= ? and date <= ?", policyID, from, to).
+		Order("date asc").Find(&stats)
+	return stats, result.Error
+}
+
+// SumUserStats 按用户合计 [from, to] 日期范围内的用量，uids 不为空时只统计给定的用户。
+// orderBy 不为空时按该字段从大到小取前 limit 个用户，须为 UserDailyStat 的用量字段名，由调用方校验
+func SumUserStats(from, to string, uids []uint, orderBy string, limit int) ([]UserStatSum, error) {
+	var sums []UserStatSum
+	tx := DB.Model(&UserDailyStat{}).
+		Select("user_id, SUM(uploads) as uploads, SUM(upload_bytes) as upload_bytes, "+
+			"SUM(downloads) as downloads, SUM(download_bytes) as download_bytes").
+		Where("date >= ? and date <= ?", from, to)
+	if len(uids) > 0 {
+		tx = tx.Where("user_id in (?)", uids)
+	}
+
+	tx = tx.Group("user_id")
+	if orderBy != "" {
+		tx = tx.Order(orderBy + " desc").Limit(limit)
+	}
+
+	result := tx.Scan(&sums)
+	return sums, result.Error
+}
+
+// ListUsersByStorage 按已用容量从大到小列出前 limit 个用户
+func ListUsersByStorage(limit int) ([]User, error) {
+	var users []User
+	result := DB.Order("storage desc").Limit(limit).Find(&users)
+	return users, result.Error
+}
+
+// ListUsersByIDs 根据 ID 列出用户
+func ListUsersByIDs(ids []uint) ([]User, error) {
+	var users []User
+	result := DB.Where("id in (?)", ids).Find(&users)
+	return users, result.Error
+}
+
+// CountUsersCreatedBetween 统计 [start, end) 时间内注册的用户数
+func CountUsersCreatedBetween(start, end time.Time) (uint64, error) {
+	var count uint64
+	result := DB.Model(&User{}).Where("created_at >= ? and created_at < ?", start, end).Count(&count)
+	return count, result.Error
+}
+
+// SumFilesCreatedBetween 按存储策略统计 [start, end) 时间内创建的文件
+func SumFilesCreatedBetween(start, end time.Time) ([]PolicyFileStat, error) {
+	var stats []PolicyFileStat
+	result := DB.Model(&File{}).
+		Select("policy_id, count(*) as count, COALESCE(SUM(size), 0) as size").
+		Where("created_at >= ? and created_at < ?", start, end).
+		Group("policy_id").Scan(&stats)
+	return stats, result.Error
+}
+
+// SumUserFilesCreatedBetween 按用户统计 [start, end) 时间内创建的文件
+func SumUserFilesCreatedBetween(start, end time.Time) ([]UserFileStat, error) {
+	var stats []UserFileStat
+	result := DB.Model(&File{}).
+		Select("user_id, count(*) as count, COALESCE(SUM(size), 0) as size").
+		Where("created_at >= ? and created_at < ?", start, end).
+		Group("user_id").Scan(&stats)
+	return stats, result.Error
+}
+
+// SumFilesByPolicy 按存储策略统计所有文件
+func SumFilesByPolicy() ([]PolicyFileStat, error) {
+	var stats []PolicyFileStat
+	result := DB.Model(&File{}).
+		Select("policy_id, count(*) as count, COALESCE(SUM(size), 0) as size").
+		Group("policy_id").Scan(&stats)
+	return stats, result.Error
+}
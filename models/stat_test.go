@@ -0,0 +1,160 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatDate(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("2023-02-28", StatDate(time.Date(2023, 2, 28, 23, 0, 0, 0, time.Local)))
+}
+
+func TestSaveDailyStat(t *testing.T) {
+	a := assert.New(t)
+
+	// 查找记录失败
+	{
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnError(errors.New("error"))
+		a.Error(SaveDailyStat("2023-02-28", 0, nil, nil))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 无需更新
+	{
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		a.NoError(SaveDailyStat("2023-02-28", 0, map[string]uint64{"downloads": 0}, nil))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 累加与覆盖
+	{
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)downloads(.+)downloads \\+(.+)storage(.+)").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(SaveDailyStat("2023-02-28", 1, map[string]uint64{"downloads": 2}, map[string]interface{}{"storage": 10}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestSaveUserDailyStat(t *testing.T) {
+	a := assert.New(t)
+
+	// 查找记录失败
+	{
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").WillReturnError(errors.New("error"))
+		a.Error(SaveUserDailyStat("2023-02-28", 1, nil, nil))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 累加与覆盖
+	{
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)user_daily_stats(.+)download_bytes \\+(.+)uploads(.+)").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(SaveUserDailyStat("2023-02-28", 1, map[string]uint64{"download_bytes": 2}, map[string]interface{}{"uploads": 3}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestSumUserStats(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)GROUP BY user_id ORDER BY download_bytes desc LIMIT 10").
+		WithArgs("2023-02-01", "2023-02-28").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "downloads", "download_bytes"}).AddRow(2, 1, 100).AddRow(1, 3, 10))
+	sums, err := SumUserStats("2023-02-01", "2023-02-28", nil, "download_bytes", 10)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(sums, 2)
+	a.EqualValues(2, sums[0].UserID)
+	a.EqualValues(100, sums[0].DownloadBytes)
+
+	// 指定用户
+	mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)user_id in(.+)GROUP BY user_id$").
+		WithArgs("2023-02-01", "2023-02-28", 1, 2).
+		WillReturnError(errors.New("error"))
+	_, err = SumUserStats("2023-02-01", "2023-02-28", []uint{1, 2}, "", 0)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Error(err)
+}
+
+func TestListUsersByStorage(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)ORDER BY storage desc LIMIT 5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(2, 100).AddRow(1, 10))
+	users, err := ListUsersByStorage(5)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(users, 2)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	users, err = ListUsersByIDs([]uint{1, 2})
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(users, 2)
+}
+
+func TestListDailyStats(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)daily_stats(.+)").
+		WithArgs(0, "2023-02-01", "2023-02-28").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date"}).AddRow(1, "2023-02-01").AddRow(2, "2023-02-02"))
+	stats, err := ListDailyStats(0, "2023-02-01", "2023-02-28")
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(stats, 2)
+}
+
+func TestCountUsersCreatedBetween(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := CountUsersCreatedBetween(time.Now(), time.Now())
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(3, count)
+}
+
+func TestSumFiles(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY policy_id").
+		WillReturnRows(sqlmock.NewRows([]string{"policy_id", "count", "size"}).AddRow(1, 2, 10).AddRow(2, 1, 5))
+	stats, err := SumFilesCreatedBetween(time.Now(), time.Now())
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(stats, 2)
+	a.EqualValues(10, stats[0].Size)
+
+	mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY policy_id").
+		WillReturnRows(sqlmock.NewRows([]string{"policy_id", "count", "size"}).AddRow(1, 2, 10))
+	stats, err = SumFilesByPolicy()
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(stats, 1)
+	a.EqualValues(2, stats[0].Count)
+
+	mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY user_id").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "count", "size"}).AddRow(1, 2, 10))
+	userStats, err := SumUserFilesCreatedBetween(time.Now(), time.Now())
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(userStats, 1)
+	a.EqualValues(1, userStats[0].UserID)
+}
@@ -38,6 +38,12 @@ func DeleteTagByID(id, uid uint) error {
 	return result.Error
 }
 
+// DeleteTagsByUserID 彻底删除用户的所有标签，返回删除的条数
+func DeleteTagsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Tag{})
+	return result.RowsAffected, result.Error
+}
+
 // GetTagsByUID 根据用户ID查找标签
 func GetTagsByUID(uid uint) ([]Tag, error) {
 	var tag []Tag
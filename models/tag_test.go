@@ -61,3 +61,17 @@ func TestGetTagsByID(t *testing.T) {
 	asserts.NoError(err)
 	asserts.EqualValues("tag", res.Name)
 }
+
+func TestDeleteTagsByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)tags(.+)").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	n, err := DeleteTagsByUserID(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, n)
+}
@@ -14,6 +14,7 @@ type Task struct {
 	Progress int    // 进度
 	Error    string `gorm:"type:text"` // 错误信息
 	Props    string `gorm:"type:text"` // 任务属性
+	Retries  int    // 因服务重启中断后重新执行的次数
 }
 
 // Create 创建任务记录
@@ -25,19 +26,53 @@ func (task *Task) Create() (uint, error) {
 	return task.ID, nil
 }
 
+// TaskHook 任务状态、进度、错误信息或属性更新后的钩子，column 为更新的字段
+type TaskHook func(task *Task, column string)
+
+// taskHooks 由其他模块注册的任务更新钩子
+var taskHooks []TaskHook
+
+// RegisterTaskHook 注册任务更新钩子，应在初始化时调用
+func RegisterTaskHook(hook TaskHook) {
+	taskHooks = append(taskHooks, hook)
+}
+
 // SetStatus 设定任务状态
 func (task *Task) SetStatus(status int) error {
-	return DB.Model(task).Select("status").Updates(map[string]interface{}{"status": status}).Error
+	return task.update("status", status)
 }
 
 // SetProgress 设定任务进度
 func (task *Task) SetProgress(progress int) error {
-	return DB.Model(task).Select("progress").Updates(map[string]interface{}{"progress": progress}).Error
+	return task.update("progress", progress)
 }
 
 // SetError 设定错误信息
 func (task *Task) SetError(err string) error {
-	return DB.Model(task).Select("error").Updates(map[string]interface{}{"error": err}).Error
+	return task.update("error", err)
+}
+
+// SetProps 更新任务属性
+func (task *Task) SetProps(props string) error {
+	return task.update("props", props)
+}
+
+// update 更新单个字段，成功后触发任务更新钩子
+func (task *Task) update(column string, value interface{}) error {
+	if err := DB.Model(task).Select(column).Updates(map[string]interface{}{column: value}).Error; err != nil {
+		return err
+	}
+
+	for _, hook := range taskHooks {
+		hook(task, column)
+	}
+	return nil
+}
+
+// IncreaseRetries 增加任务的重新执行次数
+func (task *Task) IncreaseRetries() error {
+	task.Retries++
+	return DB.Model(task).Select("retries").Updates(map[string]interface{}{"retries": task.Retries}).Error
 }
 
 // GetTasksByStatus 根据状态检索任务
@@ -47,6 +82,19 @@ func GetTasksByStatus(status ...int) []Task {
 	return tasks
 }
 
+// GetTasksByUserID 列出用户的所有任务
+func GetTasksByUserID(uid uint) ([]Task, error) {
+	var tasks []Task
+	result := DB.Where("user_id = ?", uid).Find(&tasks)
+	return tasks, result.Error
+}
+
+// DeleteTasksByUserID 彻底删除用户除 except 之外的所有任务，返回删除的条数
+func DeleteTasksByUserID(uid, except uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ? and id <> ?", uid, except).Delete(&Task{})
+	return result.RowsAffected, result.Error
+}
+
 // GetTasksByID 根据ID检索任务
 func GetTasksByID(id interface{}) (*Task, error) {
 	task := &Task{}
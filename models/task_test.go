@@ -71,6 +71,71 @@ func TestTask_SetProgress(t *testing.T) {
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestTask_SetProps(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{
+		Model: gorm.Model{ID: 1},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.SetProps("{}"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRegisterTaskHook(t *testing.T) {
+	asserts := assert.New(t)
+	var (
+		updated *Task
+		columns []string
+	)
+	RegisterTaskHook(func(task *Task, column string) {
+		updated = task
+		columns = append(columns, column)
+	})
+	defer func() { taskHooks = nil }()
+
+	task := Task{
+		Model: gorm.Model{ID: 1},
+	}
+
+	// 成功，触发钩子
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(task.SetStatus(4))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(&task, updated)
+		asserts.Equal(4, updated.Status)
+		asserts.Equal([]string{"status"}, columns)
+	}
+
+	// 失败，不触发钩子
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(task.SetProgress(2))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]string{"status"}, columns)
+	}
+}
+
+func TestTask_IncreaseRetries(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{
+		Model:   gorm.Model{ID: 1},
+		Retries: 1,
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)retries(.+)").WithArgs(2, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.IncreaseRetries())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(2, task.Retries)
+}
+
 func TestGetTasksByID(t *testing.T) {
 	asserts := assert.New(t)
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
@@ -102,3 +167,29 @@ func TestGetTasksByStatus(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 	a.Len(res, 1)
 }
+
+func TestGetTasksByUserID(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)tasks(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res, err := GetTasksByUserID(1)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(res, 2)
+}
+
+func TestDeleteTasksByUserID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)tasks(.+)").
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	n, err := DeleteTasksByUserID(1, 2)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, n)
+}
@@ -0,0 +1,207 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// 团队成员角色
+const (
+	TeamOwner  = "owner"
+	TeamEditor = "editor"
+	TeamViewer = "viewer"
+)
+
+// ErrTeamLimitReached 用户拥有的团队数量已达上限
+var ErrTeamLimitReached = errors.New("maximum number of teams reached")
+
+// Team 团队空间，文件归属于团队专用的存储账户
+type Team struct {
+	gorm.Model
+	Name string `gorm:"size:255"`
+	// UserID 团队存储账户 ID，团队的文件、分享均归属于此账户
+	UserID     uint `gorm:"index:team_account"`
+	OwnerID    uint `gorm:"index:team_owner"`
+	MaxStorage uint64
+
+	// 数据库忽略字段
+	// Role 当前用户在团队中的角色
+	Role string `gorm:"-"`
+	// MountName 在「团队」虚拟目录下的名称
+	MountName string `gorm:"-"`
+}
+
+// TeamMember 团队成员
+type TeamMember struct {
+	gorm.Model
+	TeamID uint   `gorm:"unique_index:team_user"`
+	UserID uint   `gorm:"unique_index:team_user;index:team_member_user"`
+	Role   string `gorm:"size:16"`
+
+	// 数据库忽略字段
+	User *User `gorm:"-"`
+}
+
+// Create 为用户创建团队及其存储账户，创建者成为团队所有者
+func (team *Team) Create(owner *User) error {
+	var count int
+	if err := DB.Model(&Team{}).Where("owner_id = ?", owner.ID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count >= GetIntSetting("team_max_per_user", 3) {
+		return ErrTeamLimitReached
+	}
+
+	team.OwnerID = owner.ID
+	team.MaxStorage = uint64(GetIntSetting("team_default_storage", 1073741824))
+
+	tx := DB.Begin()
+
+	// 存储账户不设密码，无法登录
+	account := NewUser()
+	account.Email = fmt.Sprintf("team-%s@teams.local", util.RandStringRunes(16))
+	account.Nick = team.Name
+	account.Status = TeamAccount
+	account.GroupID = owner.GroupID
+	if err := tx.Create(&account).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	team.UserID = account.ID
+	if err := tx.Create(team).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Create(&TeamMember{TeamID: team.ID, UserID: owner.ID, Role: TeamOwner}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Account 获取团队存储账户，容量以团队设定为准
+func (team *Team) Account() (User, error) {
+	account, err := GetUserByID(team.UserID)
+	if err != nil {
+		return account, err
+	}
+
+	account.Group.MaxStorage = team.MaxStorage
+	account.OptionsSerialized.ExtraStorage = 0
+	return account, nil
+}
+
+// Members 列出团队成员
+func (team *Team) Members() ([]TeamMember, error) {
+	var members []TeamMember
+	result := DB.Where("team_id = ?", team.ID).Order("id").Find(&members)
+	return members, result.Error
+}
+
+// SetMember 添加团队成员，已是成员时更新角色
+func (team *Team) SetMember(uid uint, role string) error {
+	var existed TeamMember
+	err := DB.Where("team_id = ? and user_id = ?", team.ID, uid).First(&existed).Error
+	if err == nil {
+		return DB.Model(&existed).Update("role", role).Error
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+
+	return DB.Create(&TeamMember{TeamID: team.ID, UserID: uid, Role: role}).Error
+}
+
+// RemoveMember 移除团队成员，不能移除所有者
+func (team *Team) RemoveMember(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("team_id = ? and user_id = ? and role <> ?", team.ID, uid, TeamOwner).
+		Delete(&TeamMember{})
+	return result.RowsAffected, result.Error
+}
+
+// Delete 删除团队及其成员记录，存储账户需另行清理
+func (team *Team) Delete() error {
+	tx := DB.Begin()
+	if err := tx.Unscoped().Where("team_id = ?", team.ID).Delete(&TeamMember{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(team).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CanWrite 当前用户是否可以修改团队中的文件
+func (team *Team) CanWrite() bool {
+	return team.Role == TeamOwner || team.Role == TeamEditor
+}
+
+// CanManage 当前用户是否可以管理团队成员与设置
+func (team *Team) CanManage() bool {
+	return team.Role == TeamOwner
+}
+
+// Permissions 没有匹配的目录 ACL 时当前用户在团队空间中拥有的权限
+func (team *Team) Permissions() int {
+	if team.CanWrite() {
+		return ACLAll
+	}
+
+	return ACLRead
+}
+
+// GetTeamByMember 获取用户所在的团队，并填充用户在其中的角色
+func GetTeamByMember(id, uid uint) (*Team, error) {
+	var member TeamMember
+	if err := DB.Where("team_id = ? and user_id = ?", id, uid).First(&member).Error; err != nil {
+		return nil, err
+	}
+
+	var team Team
+	if err := DB.First(&team, id).Error; err != nil {
+		return nil, err
+	}
+
+	team.Role = member.Role
+	return &team, nil
+}
+
+// ListTeamsByUser 列出用户所在的团队，并为其分配不重复的挂载名称
+func ListTeamsByUser(uid uint) ([]Team, error) {
+	var members []TeamMember
+	if err := DB.Where("user_id = ?", uid).Order("team_id").Find(&members).Error; err != nil {
+		return nil, err
+	}
+
+	res := make([]Team, 0, len(members))
+	names := make(map[string]bool, len(members))
+	for _, member := range members {
+		var team Team
+		if err := DB.First(&team, member.TeamID).Error; err != nil {
+			continue
+		}
+
+		team.Role = member.Role
+		team.MountName = team.Name
+		for i := 2; names[team.MountName]; i++ {
+			team.MountName = fmt.Sprintf("%s (%d)", team.Name, i)
+		}
+
+		names[team.MountName] = true
+		res = append(res, team)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeam_Create(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_team_max_per_user", "1", 0)
+	cache.Set("setting_team_default_storage", "10", 0)
+	owner := &User{GroupID: 2}
+	owner.ID = 1
+
+	// 已达上限
+	{
+		mock.ExpectQuery("SELECT count(.+)teams(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		team := &Team{Name: "t"}
+		a.Equal(ErrTeamLimitReached, team.Create(owner))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT count(.+)teams(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)users(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectExec("INSERT(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(6, 1))
+		mock.ExpectExec("INSERT(.+)teams(.+)").WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec("INSERT(.+)team_members(.+)").WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectCommit()
+		team := &Team{Name: "t"}
+		a.NoError(team.Create(owner))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(7, team.ID)
+		a.EqualValues(5, team.UserID)
+		a.EqualValues(1, team.OwnerID)
+		a.EqualValues(10, team.MaxStorage)
+	}
+
+	// 创建存储账户失败
+	{
+		mock.ExpectQuery("SELECT count(.+)teams(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		team := &Team{Name: "t"}
+		a.Error(team.Create(owner))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestTeam_Account(t *testing.T) {
+	a := assert.New(t)
+	team := &Team{UserID: 5, MaxStorage: 10}
+
+	mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "storage", "options"}).
+		AddRow(5, 3, `{"extra_storage":100}`))
+	account, err := team.Account()
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(10, account.MaxStorage())
+	a.EqualValues(7, account.GetRemainingCapacity())
+}
+
+func TestTeam_SetMember(t *testing.T) {
+	a := assert.New(t)
+	team := &Team{}
+	team.ID = 1
+
+	// 新成员
+	mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)team_members(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	a.NoError(team.SetMember(2, TeamViewer))
+	a.NoError(mock.ExpectationsWereMet())
+
+	// 更新角色
+	mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, TeamViewer))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)team_members(.+)role(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(team.SetMember(2, TeamEditor))
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestTeam_RemoveMember(t *testing.T) {
+	a := assert.New(t)
+	team := &Team{}
+	team.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)team_members(.+)").WithArgs(1, 2, TeamOwner).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	affected, err := team.RemoveMember(2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.EqualValues(1, affected)
+}
+
+func TestTeam_Delete(t *testing.T) {
+	a := assert.New(t)
+	team := &Team{}
+	team.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)team_members(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE(.+)teams(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(team.Delete())
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestTeam_Roles(t *testing.T) {
+	a := assert.New(t)
+	owner, editor, viewer := &Team{Role: TeamOwner}, &Team{Role: TeamEditor}, &Team{Role: TeamViewer}
+
+	a.True(owner.CanWrite())
+	a.True(owner.CanManage())
+	a.True(editor.CanWrite())
+	a.False(editor.CanManage())
+	a.False(viewer.CanWrite())
+	a.False(viewer.CanManage())
+	a.Equal(ACLAll, editor.Permissions())
+	a.Equal(ACLRead, viewer.Permissions())
+}
+
+func TestGetTeamByMember(t *testing.T) {
+	a := assert.New(t)
+
+	// 不是成员
+	{
+		mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetTeamByMember(1, 2)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "role"}).AddRow(3, TeamEditor))
+		mock.ExpectQuery("SELECT(.+)teams(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "t"))
+		team, err := GetTeamByMember(1, 2)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Equal(TeamEditor, team.Role)
+		a.Equal("t", team.Name)
+	}
+}
+
+func TestListTeamsByUser(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(2).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "team_id", "role"}).
+			AddRow(1, 1, TeamOwner).
+			AddRow(2, 2, TeamViewer).
+			AddRow(3, 3, TeamEditor),
+	)
+	mock.ExpectQuery("SELECT(.+)teams(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "t"))
+	mock.ExpectQuery("SELECT(.+)teams(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "t"))
+	// 团队已删除
+	mock.ExpectQuery("SELECT(.+)teams(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	teams, err := ListTeamsByUser(2)
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+	a.Len(teams, 2)
+	a.Equal("t", teams[0].MountName)
+	a.Equal(TeamOwner, teams[0].Role)
+	a.Equal("t (2)", teams[1].MountName)
+	a.Equal(TeamViewer, teams[1].Role)
+}
@@ -0,0 +1,200 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	// TrashObjectFile 回收站中的文件
+	TrashObjectFile = "file"
+	// TrashObjectFolder 回收站中的目录
+	TrashObjectFolder = "folder"
+)
+
+// Trash 回收站记录，对应一个被删除的顶层文件或目录。
+// 对象及其子对象被软删除，顶层对象移出原目录并以记录ID命名，以免占用原目录中的名称
+type Trash struct {
+	gorm.Model
+	UserID     uint `gorm:"index:trash_user"`
+	ObjectType string
+	ObjectID   uint
+	// Name 对象原名称
+	Name string
+	// ParentID 对象原父目录ID
+	ParentID uint
+	// Path 对象原父目录路径
+	Path string `gorm:"type:text"`
+	// Size 对象包含的文件总大小
+	Size uint64
+}
+
+// TrashObjects 在事务中创建回收站记录，并软删除 dirs、files 指定的全部对象
+func TrashObjects(items []Trash, dirs, files []uint) error {
+	tx := DB.Begin()
+	for i := range items {
+		if err := tx.Create(&items[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if err := items[i].detach(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(files) > 0 {
+		if err := tx.Where("id in (?)", files).Delete(&File{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(dirs) > 0 {
+		if err := tx.Where("id in (?)", dirs).Delete(&Folder{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// GetTrashByUserID 列出用户回收站中的记录，按删除时间从新到旧排序
+func GetTrashByUserID(uid uint) ([]Trash, error) {
+	var items []Trash
+	result := DB.Where("user_id = ?", uid).Order("id desc").Find(&items)
+	return items, result.Error
+}
+
+// GetTrash 查找用户回收站中的记录
+func GetTrash(id, uid uint) (*Trash, error) {
+	item := &Trash{}
+	result := DB.Where("id = ? and user_id = ?", id, uid).First(item)
+	return item, result.Error
+}
+
+// GetExpiredTrash 列出用户在 before 之前删除的回收站记录
+func GetExpiredTrash(uid uint, before time.Time) ([]Trash, error) {
+	var items []Trash
+	result := DB.Where("user_id = ? and created_at < ?", uid, before).Find(&items)
+	return items, result.Error
+}
+
+// GetTrashUserIDs 列出回收站不为空的用户ID
+func GetTrashUserIDs() ([]uint, error) {
+	var ids []uint
+	result := DB.Model(&Trash{}).Group("user_id").Pluck("user_id", &ids)
+	return ids, result.Error
+}
+
+// Objects 列出记录对应的顶层对象及其全部子对象
+func (item *Trash) Objects() ([]Folder, []File, error) {
+	var (
+		folders []Folder
+		files   []File
+	)
+
+	if item.ObjectType == TrashObjectFile {
+		err := DB.Unscoped().Where("id = ? and user_id = ?", item.ObjectID, item.UserID).Find(&files).Error
+		return folders, files, err
+	}
+
+	// 逐层列出子目录
+	parents := []uint{item.ObjectID}
+	if err := DB.Unscoped().Where("id = ? and owner_id = ?", item.ObjectID, item.UserID).Find(&folders).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for len(parents) > 0 && len(folders) > 0 {
+		var children []Folder
+		if err := DB.Unscoped().Where("parent_id in (?) and owner_id = ?", parents, item.UserID).Find(&children).Error; err != nil {
+			return nil, nil, err
+		}
+
+		parents = make([]uint, len(children))
+		for i, child := range children {
+			parents[i] = child.ID
+		}
+		folders = append(folders, children...)
+	}
+
+	if len(folders) == 0 {
+		return folders, files, nil
+	}
+
+	ids := make([]uint, len(folders))
+	for i, folder := range folders {
+		ids[i] = folder.ID
+	}
+
+	err := DB.Unscoped().Where("folder_id in (?) and user_id = ?", ids, item.UserID).Find(&files).Error
+	return folders, files, err
+}
+
+// Restore 在事务中将对象恢复到 parent 目录下，并删除回收站记录。
+// dirs、files 为记录对应的全部对象
+func (item *Trash) Restore(parent uint, dirs, files []uint) error {
+	tx := DB.Begin()
+	if len(files) > 0 {
+		if err := tx.Unscoped().Model(&File{}).Where("id in (?)", files).UpdateColumn("deleted_at", nil).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if len(dirs) > 0 {
+		if err := tx.Unscoped().Model(&Folder{}).Where("id in (?)", dirs).UpdateColumn("deleted_at", nil).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := item.attach(tx, parent); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Delete(item).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Delete 删除回收站记录
+func (item *Trash) Delete() error {
+	return DB.Unscoped().Delete(item).Error
+}
+
+// detach 将顶层对象移出原目录，并从原目录的总大小中扣除
+func (item *Trash) detach(tx *gorm.DB) error {
+	if err := changeFolderSize(tx, item.ParentID, "-", item.Size); err != nil {
+		return err
+	}
+
+	return item.move(tx, 0, strconv.FormatUint(uint64(item.ID), 10))
+}
+
+// attach 将顶层对象以原名称移入 parent 目录，并计入 parent 目录的总大小
+func (item *Trash) attach(tx *gorm.DB, parent uint) error {
+	if err := changeFolderSize(tx, parent, "+", item.Size); err != nil {
+		return err
+	}
+
+	return item.move(tx, parent, item.Name)
+}
+
+func (item *Trash) move(tx *gorm.DB, parent uint, name string) error {
+	if item.ObjectType == TrashObjectFile {
+		return tx.Unscoped().Model(&File{}).Where("id = ?", item.ObjectID).
+			UpdateColumns(map[string]interface{}{"folder_id": parent, "name": name}).Error
+	}
+
+	return tx.Unscoped().Model(&Folder{}).Where("id = ?", item.ObjectID).
+		UpdateColumns(map[string]interface{}{"parent_id": parent, "name": name}).Error
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashObjects(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)trashes(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs("5", 0, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)trashes(.+)").WillReturnResult(sqlmock.NewResult(6, 1))
+		// 从原目录的总大小中扣除
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(4, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(10, 10, 4).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(0, "6", 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 2))
+		mock.ExpectExec("UPDATE(.+)folders(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		items := []Trash{
+			{UserID: 1, ObjectType: TrashObjectFolder, ObjectID: 2, Name: "dir"},
+			{UserID: 1, ObjectType: TrashObjectFile, ObjectID: 1, Name: "a.txt", ParentID: 4, Size: 10},
+		}
+		a.NoError(TrashObjects(items, []uint{2}, []uint{1, 3}))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(5, items[0].ID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)trashes(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(TrashObjects([]Trash{{ObjectType: TrashObjectFile, ObjectID: 1}}, nil, []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetTrash(t *testing.T) {
+	a := assert.New(t)
+
+	// 列出用户回收站
+	{
+		mock.ExpectQuery("SELECT(.+)trashes(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+		res, err := GetTrashByUserID(1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Len(res, 2)
+	}
+
+	// 查找单条记录
+	{
+		mock.ExpectQuery("SELECT(.+)trashes(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetTrash(2, 1)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 过期记录
+	{
+		before := time.Now()
+		mock.ExpectQuery("SELECT(.+)trashes(.+)").WithArgs(1, before).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		res, err := GetExpiredTrash(1, before)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Len(res, 1)
+	}
+
+	// 回收站不为空的用户
+	{
+		mock.ExpectQuery("SELECT(.+)trashes(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1).AddRow(3))
+		res, err := GetTrashUserIDs()
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Equal([]uint{1, 3}, res)
+	}
+}
+
+func TestTrash_Objects(t *testing.T) {
+	a := assert.New(t)
+
+	// 文件
+	{
+		item := &Trash{UserID: 1, ObjectType: TrashObjectFile, ObjectID: 3}
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		folders, files, err := item.Objects()
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Empty(folders)
+		a.Len(files, 1)
+	}
+
+	// 目录
+	{
+		item := &Trash{UserID: 1, ObjectType: TrashObjectFolder, ObjectID: 2}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(4, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2, 4, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		folders, files, err := item.Objects()
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Len(folders, 2)
+		a.Len(files, 1)
+	}
+
+	// 目录已不存在
+	{
+		item := &Trash{UserID: 1, ObjectType: TrashObjectFolder, ObjectID: 2}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		folders, files, err := item.Objects()
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Empty(folders)
+		a.Empty(files)
+	}
+}
+
+func TestTrash_Restore(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		item := &Trash{Model: gorm.Model{ID: 5}, ObjectType: TrashObjectFolder, ObjectID: 2, Name: "dir"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs("dir", 7, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE(.+)trashes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(item.Restore(7, []uint{2}, []uint{3}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		item := &Trash{Model: gorm.Model{ID: 5}, ObjectType: TrashObjectFile, ObjectID: 3, Name: "a.txt"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(item.Restore(7, nil, []uint{3}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
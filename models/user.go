@@ -22,6 +22,10 @@ const (
 	Baned
 	// OveruseBaned 超额使用被封禁
 	OveruseBaned
+	// PendingDeletion 用户申请注销，等待清理数据
+	PendingDeletion
+	// TeamAccount 团队空间的存储账户，不可登录
+	TeamAccount
 )
 
 // User 用户模型
@@ -39,6 +43,12 @@ type User struct {
 	Options   string `json:"-" gorm:"size:4294967295"`
 	Authn     string `gorm:"size:4294967295"`
 
+	// ExternalID 用户在外部身份提供方中的标识
+	ExternalID string `gorm:"size:255;index"`
+
+	// ReservedStorage 进行中的上传会话预留的容量
+	ReservedStorage uint64
+
 	// 关联模型
 	Group  Group  `gorm:"save_associations:false:false"`
 	Policy Policy `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -55,6 +65,46 @@ func init() {
 type UserOption struct {
 	ProfileOff     bool   `json:"profile_off,omitempty"`
 	PreferredTheme string `json:"preferred_theme,omitempty"`
+	// 管理员设定的保留规则
+	Retention []RetentionRule `json:"retention,omitempty"`
+	// 外部目录提供的属性
+	Directory *DirectoryClaims `json:"directory,omitempty"`
+	// 用户组容量之外附加的存储容量
+	ExtraStorage uint64 `json:"extra_storage,omitempty"`
+	// 目录容量配额
+	FolderQuotas []FolderQuota `json:"folder_quotas,omitempty"`
+	// 管理员为此用户单独设定的下载、上传限速，单位为字节每秒，0 为使用用户组设定
+	SpeedLimit       int `json:"speed_limit,omitempty"`
+	UploadSpeedLimit int `json:"upload_speed_limit,omitempty"`
+	// 用户对各类邮件通知的订阅设定，未设定的类型使用默认值
+	Notifications map[string]bool `json:"notifications,omitempty"`
+}
+
+// NotificationEnabled 返回用户是否订阅了 kind 类型的通知，未设定时返回 fallback
+func (user *User) NotificationEnabled(kind string, fallback bool) bool {
+	if enabled, ok := user.OptionsSerialized.Notifications[kind]; ok {
+		return enabled
+	}
+
+	return fallback
+}
+
+// DownloadSpeedLimit 返回用户的下载限速，单位为字节每秒，0 为不限制
+func (user *User) DownloadSpeedLimit() int {
+	if user.OptionsSerialized.SpeedLimit > 0 {
+		return user.OptionsSerialized.SpeedLimit
+	}
+
+	return user.Group.SpeedLimit
+}
+
+// UploadSpeedLimit 返回用户的上传限速，单位为字节每秒，0 为不限制
+func (user *User) UploadSpeedLimit() int {
+	if user.OptionsSerialized.UploadSpeedLimit > 0 {
+		return user.OptionsSerialized.UploadSpeedLimit
+	}
+
+	return user.Group.OptionsSerialized.UploadSpeedLimit
 }
 
 // Root 获取用户的根目录
@@ -99,6 +149,43 @@ func (user *User) ChangeStorage(tx *gorm.DB, operator string, size uint64) error
 	return tx.Model(user).Update("storage", gorm.Expr("storage "+operator+" ?", size)).Error
 }
 
+// ReserveStorage 为上传会话预留容量，已用与已预留容量之和不超过总容量时预留成功。
+// 检查与预留在同一条语句中完成，并发的上传会话不会同时通过检查
+func (user *User) ReserveStorage(size uint64) (bool, error) {
+	if size == 0 {
+		return true, nil
+	}
+
+	total := user.MaxStorage()
+	if total < size {
+		return false, nil
+	}
+
+	result := DB.Model(&User{}).
+		Where("id = ? and storage + reserved_storage <= ?", user.ID, total-size).
+		UpdateColumn("reserved_storage", gorm.Expr("reserved_storage + ?", size))
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	user.ReservedStorage += size
+	return true, nil
+}
+
+// ReleaseStorage 在 tx 中释放用户预留的容量
+func (user *User) ReleaseStorage(tx *gorm.DB, size uint64) error {
+	if size == 0 {
+		return nil
+	}
+
+	return tx.Model(&User{}).Where("id = ?", user.ID).UpdateColumn("reserved_storage",
+		gorm.Expr("CASE WHEN reserved_storage > ? THEN reserved_storage - ? ELSE 0 END", size, size)).Error
+}
+
 // IncreaseStorageWithoutCheck 忽略可用容量，增加用户已用容量
 func (user *User) IncreaseStorageWithoutCheck(size uint64) {
 	if size == 0 {
@@ -109,13 +196,18 @@ func (user *User) IncreaseStorageWithoutCheck(size uint64) {
 
 }
 
-// GetRemainingCapacity 获取剩余配额
+// MaxStorage 获取用户的总容量
+func (user *User) MaxStorage() uint64 {
+	return user.Group.MaxStorage + user.OptionsSerialized.ExtraStorage
+}
+
+// GetRemainingCapacity 获取扣除已用及预留容量后的剩余配额
 func (user *User) GetRemainingCapacity() uint64 {
-	total := user.Group.MaxStorage
-	if total <= user.Storage {
+	total := user.MaxStorage()
+	if total <= user.Storage+user.ReservedStorage {
 		return 0
 	}
-	return total - user.Storage
+	return total - user.Storage - user.ReservedStorage
 }
 
 // GetPolicyID 获取用户当前的存储策略ID
@@ -150,17 +242,31 @@ func GetActiveUserByOpenID(openid string) (User, error) {
 // GetUserByEmail 用Email获取用户
 func GetUserByEmail(email string) (User, error) {
 	var user User
-	result := DB.Set("gorm:auto_preload", true).Where("email = ?", email).First(&user)
+	result := DB.Set("gorm:auto_preload", true).Where(EqualFold("email"), email).First(&user)
+	return user, result.Error
+}
+
+// GetUserByExternalID 用外部身份提供方中的标识获取用户
+func GetUserByExternalID(externalID string) (User, error) {
+	var user User
+	result := DB.Set("gorm:auto_preload", true).Where("external_id = ?", externalID).First(&user)
 	return user, result.Error
 }
 
 // GetActiveUserByEmail 用Email获取可登录用户
 func GetActiveUserByEmail(email string) (User, error) {
 	var user User
-	result := DB.Set("gorm:auto_preload", true).Where("status = ? and email = ?", Active, email).First(&user)
+	result := DB.Set("gorm:auto_preload", true).Where("status = ? and "+EqualFold("email"), Active, email).First(&user)
 	return user, result.Error
 }
 
+// ListUsersAfter 按 ID 顺序列出 ID 大于 cursor 的用户，用于分批遍历所有用户
+func ListUsersAfter(cursor uint, limit int) ([]User, error) {
+	var users []User
+	result := DB.Where("id > ?", cursor).Order("id asc").Limit(limit).Find(&users)
+	return users, result.Error
+}
+
 // NewUser 返回一个新的空 User
 func NewUser() User {
 	options := UserOption{}
@@ -276,6 +382,11 @@ func (user *User) SetStatus(status int) {
 	DB.Model(&user).Update("status", status)
 }
 
+// Delete 彻底删除用户记录
+func (user *User) Delete() error {
+	return DB.Unscoped().Delete(user).Error
+}
+
 // Update 更新用户
 func (user *User) Update(val map[string]interface{}) error {
 	return DB.Model(user).Updates(val).Error
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
@@ -11,6 +12,12 @@ import (
 	"github.com/duo-labs/webauthn/webauthn"
 )
 
+// 二步验证方式
+const (
+	TwoFactorTOTP  = "totp"
+	TwoFactorAuthn = "authn"
+)
+
 /*
 	`webauthn.User` 接口的实现
 */
@@ -43,6 +50,10 @@ func (user User) WebAuthnIcon() string {
 // WebAuthnCredentials 获得已注册的验证器凭证
 func (user User) WebAuthnCredentials() []webauthn.Credential {
 	var res []webauthn.Credential
+	if user.Authn == "" {
+		return res
+	}
+
 	err := json.Unmarshal([]byte(user.Authn), &res)
 	if err != nil {
 		fmt.Println(err)
@@ -50,6 +61,39 @@ func (user User) WebAuthnCredentials() []webauthn.Credential {
 	return res
 }
 
+// TwoFactorMethods 返回用户可用于二步验证的方式，未开启 WebAuthn 时不含已注册的验证器
+func (user *User) TwoFactorMethods() []string {
+	methods := make([]string, 0, 2)
+	if user.TwoFactor != "" {
+		methods = append(methods, TwoFactorTOTP)
+	}
+
+	if IsTrueVal(GetSettingByName("authn_enabled")) && len(user.WebAuthnCredentials()) > 0 {
+		methods = append(methods, TwoFactorAuthn)
+	}
+
+	return methods
+}
+
+// UpdateAuthn 验证成功后更新验证器的签名计数
+func (user *User) UpdateAuthn(credential *webauthn.Credential) error {
+	exists := user.WebAuthnCredentials()
+	for i := 0; i < len(exists); i++ {
+		if bytes.Equal(exists[i].ID, credential.ID) {
+			exists[i].Authenticator.SignCount = credential.Authenticator.SignCount
+			res, err := json.Marshal(exists)
+			if err != nil {
+				return err
+			}
+
+			user.Authn = string(res)
+			return DB.Model(user).Update("authn", user.Authn).Error
+		}
+	}
+
+	return nil
+}
+
 // RegisterAuthn 添加新的验证器
 func (user *User) RegisterAuthn(credential *webauthn.Credential) error {
 	exists := user.WebAuthnCredentials()
@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -98,3 +99,48 @@ func TestUser_RemoveAuthn(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 	}
 }
+
+func TestUser_TwoFactorMethods(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{
+		Authn: `[{"ID":"MTIz","Authenticator":{"SignCount":1}}]`,
+	}
+
+	cache.Set("setting_authn_enabled", "0", 0)
+	asserts.Empty(user.TwoFactorMethods())
+
+	cache.Set("setting_authn_enabled", "1", 0)
+	asserts.Equal([]string{TwoFactorAuthn}, user.TwoFactorMethods())
+
+	user.TwoFactor = "secret"
+	asserts.Equal([]string{TwoFactorTOTP, TwoFactorAuthn}, user.TwoFactorMethods())
+
+	user.Authn = ""
+	asserts.Equal([]string{TwoFactorTOTP}, user.TwoFactorMethods())
+}
+
+func TestUser_UpdateAuthn(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{
+		Model: gorm.Model{ID: 1},
+		Authn: `[{"ID":"MTIz","Authenticator":{"SignCount":1}}]`,
+	}
+
+	// 验证器不存在
+	{
+		asserts.NoError(user.UpdateAuthn(&webauthn.Credential{ID: []byte("456")}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 更新签名计数
+	{
+		credential := &webauthn.Credential{ID: []byte("123")}
+		credential.Authenticator.SignCount = 5
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.UpdateAuthn(credential))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(5, user.WebAuthnCredentials()[0].Authenticator.SignCount)
+	}
+}
@@ -240,6 +240,80 @@ func TestUser_GetRemainingCapacity(t *testing.T) {
 	newUser.Group.MaxStorage = 100
 	newUser.Storage = 200
 	asserts.Equal(uint64(0), newUser.GetRemainingCapacity())
+
+	// 扣除预留容量
+	newUser.Group.MaxStorage = 100
+	newUser.Storage = 10
+	newUser.ReservedStorage = 30
+	asserts.Equal(uint64(60), newUser.GetRemainingCapacity())
+}
+
+func TestUser_ReserveStorage(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Model: gorm.Model{ID: 1}}
+	user.Group.MaxStorage = 100
+
+	// 预留零
+	{
+		ok, err := user.ReserveStorage(0)
+		asserts.True(ok)
+		asserts.NoError(err)
+	}
+
+	// 超出总容量
+	{
+		ok, err := user.ReserveStorage(101)
+		asserts.False(ok)
+		asserts.NoError(err)
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WithArgs(40, 1, 60).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		ok, err := user.ReserveStorage(40)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(ok)
+		asserts.NoError(err)
+		asserts.EqualValues(40, user.ReservedStorage)
+	}
+
+	// 剩余容量不足
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WithArgs(70, 1, 30).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		ok, err := user.ReserveStorage(70)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(ok)
+		asserts.NoError(err)
+		asserts.EqualValues(40, user.ReservedStorage)
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		ok, err := user.ReserveStorage(10)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(ok)
+		asserts.Error(err)
+	}
+}
+
+func TestUser_ReleaseStorage(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Model: gorm.Model{ID: 1}}
+
+	asserts.NoError(user.ReleaseStorage(DB, 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WithArgs(40, 40, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(user.ReleaseStorage(DB, 40))
+	asserts.NoError(mock.ExpectationsWereMet())
 }
 
 func TestUser_DeductionCapacity(t *testing.T) {
@@ -363,6 +437,27 @@ func TestGetUserByEmail(t *testing.T) {
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestGetUserByExternalID(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)external_id(.+)").WithArgs("ext-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "external_id"}).AddRow(1, "ext-1"))
+	user, err := GetUserByExternalID("ext-1")
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, user.ID)
+}
+
+func TestUser_MaxStorage(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Group: Group{MaxStorage: 10}}
+	asserts.EqualValues(10, user.MaxStorage())
+
+	user.OptionsSerialized.ExtraStorage = 5
+	asserts.EqualValues(15, user.MaxStorage())
+	asserts.EqualValues(15, user.GetRemainingCapacity())
+}
+
 func TestUser_AfterCreate(t *testing.T) {
 	asserts := assert.New(t)
 	user := User{Model: gorm.Model{ID: 1}}
@@ -425,6 +520,39 @@ func TestUser_SetStatus(t *testing.T) {
 	asserts.Equal(Baned, user.Status)
 }
 
+func TestUser_SpeedLimit(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	asserts.Equal(0, user.DownloadSpeedLimit())
+	asserts.Equal(0, user.UploadSpeedLimit())
+
+	// 使用用户组设定
+	user.Group.SpeedLimit = 10
+	user.Group.OptionsSerialized.UploadSpeedLimit = 20
+	asserts.Equal(10, user.DownloadSpeedLimit())
+	asserts.Equal(20, user.UploadSpeedLimit())
+
+	// 用户单独设定优先
+	user.OptionsSerialized.SpeedLimit = 30
+	user.OptionsSerialized.UploadSpeedLimit = 40
+	asserts.Equal(30, user.DownloadSpeedLimit())
+	asserts.Equal(40, user.UploadSpeedLimit())
+}
+
+func TestUser_NotificationEnabled(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+
+	// 未设定时使用默认值
+	asserts.True(user.NotificationEnabled("task_finished", true))
+	asserts.False(user.NotificationEnabled("task_finished", false))
+
+	// 用户设定优先
+	user.OptionsSerialized.Notifications = map[string]bool{"task_finished": false}
+	asserts.False(user.NotificationEnabled("task_finished", true))
+	asserts.True(user.NotificationEnabled("share_accessed", true))
+}
+
 func TestUser_UpdateOptions(t *testing.T) {
 	asserts := assert.New(t)
 	user := User{}
@@ -436,3 +564,27 @@ func TestUser_UpdateOptions(t *testing.T) {
 	asserts.NoError(user.UpdateOptions())
 	asserts.NoError(mock.ExpectationsWereMet())
 }
+
+func TestListUsersAfter(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)id > (.+)ORDER BY id asc LIMIT 10").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6).AddRow(7))
+	users, err := ListUsersAfter(5, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 2)
+}
+
+func TestUser_Delete(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	user.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(user.Delete())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
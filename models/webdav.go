@@ -42,6 +42,12 @@ func DeleteWebDAVAccountByID(id, uid uint) {
 	DB.Where("user_id = ? and id = ?", uid, id).Delete(&Webdav{})
 }
 
+// DeleteWebDAVAccountsByUserID 彻底删除用户的所有账号，返回删除的条数
+func DeleteWebDAVAccountsByUserID(uid uint) (int64, error) {
+	result := DB.Unscoped().Where("user_id = ?", uid).Delete(&Webdav{})
+	return result.RowsAffected, result.Error
+}
+
 // UpdateWebDAVAccountByID 根据账户ID和UID更新账户
 func UpdateWebDAVAccountByID(id, uid uint, updates map[string]interface{}) {
 	DB.Model(&Webdav{Model: gorm.Model{ID: id}, UserID: uid}).Updates(updates)
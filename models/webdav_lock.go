@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrWebdavLocked 资源已被其他 WebDAV 锁锁定
+var ErrWebdavLocked = errors.New("resource is locked")
+
+// WebdavLock WebDAV 锁，存储于数据库中以便在重启后及多个实例间保持有效
+type WebdavLock struct {
+	gorm.Model
+	Token  string `gorm:"size:64;unique_index:webdav_lock_token"`
+	UserID uint   `gorm:"index:webdav_lock_user"`
+	// Root 锁定的资源路径
+	Root string `gorm:"type:text"`
+	// ZeroDepth 是否仅锁定资源本身，为否时同时锁定所有下级资源
+	ZeroDepth bool
+	OwnerXML  string `gorm:"type:text"`
+	// ExpiresAt 过期时间，为空时永不过期
+	ExpiresAt *time.Time
+}
+
+// Covers 锁是否作用于给定路径
+func (lock *WebdavLock) Covers(name string) bool {
+	if lock.Root == name {
+		return true
+	}
+
+	if lock.ZeroDepth {
+		return false
+	}
+
+	return lock.Root == "/" || strings.HasPrefix(name, lock.Root+"/")
+}
+
+// conflicts 锁是否与另一个锁冲突
+func (lock *WebdavLock) conflicts(other *WebdavLock) bool {
+	return lock.Covers(other.Root) || (!other.ZeroDepth && other.Covers(lock.Root))
+}
+
+// activeWebdavLocks 筛选用户在 now 时未过期的锁
+func activeWebdavLocks(db *gorm.DB, uid uint, now time.Time) *gorm.DB {
+	return db.Where("user_id = ? and (expires_at is NULL or expires_at > ?)", uid, now)
+}
+
+// GetActiveWebdavLocks 列出用户所有未过期的锁
+func GetActiveWebdavLocks(uid uint, now time.Time) ([]WebdavLock, error) {
+	var locks []WebdavLock
+	result := activeWebdavLocks(DB, uid, now).Find(&locks)
+	return locks, result.Error
+}
+
+// GetActiveWebdavLockByToken 根据令牌查找用户未过期的锁
+func GetActiveWebdavLockByToken(uid uint, token string, now time.Time) (*WebdavLock, error) {
+	lock := &WebdavLock{}
+	result := activeWebdavLocks(DB, uid, now).Where("token = ?", token).First(lock)
+	return lock, result.Error
+}
+
+// CreateWebdavLock 清理用户已过期的锁，并在没有冲突时创建锁，存在冲突时返回 ErrWebdavLocked
+func CreateWebdavLock(lock *WebdavLock, now time.Time) error {
+	tx := DB.Begin()
+	if err := tx.Unscoped().Where("user_id = ? and expires_at <= ?", lock.UserID, now).
+		Delete(&WebdavLock{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var locks []WebdavLock
+	if err := activeWebdavLocks(tx, lock.UserID, now).Find(&locks).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range locks {
+		if locks[i].conflicts(lock) {
+			tx.Rollback()
+			return ErrWebdavLocked
+		}
+	}
+
+	if err := tx.Create(lock).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Refresh 更新锁的过期时间
+func (lock *WebdavLock) Refresh(expiresAt *time.Time) error {
+	lock.ExpiresAt = expiresAt
+	return DB.Model(lock).Update("expires_at", expiresAt).Error
+}
+
+// DeleteWebdavLock 删除用户未过期的锁，返回是否找到了该锁
+func DeleteWebdavLock(uid uint, token string, now time.Time) (bool, error) {
+	result := activeWebdavLocks(DB.Unscoped(), uid, now).Where("token = ?", token).Delete(&WebdavLock{})
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebdavLock_Covers(t *testing.T) {
+	a := assert.New(t)
+
+	lock := &WebdavLock{Root: "/dir"}
+	a.True(lock.Covers("/dir"))
+	a.True(lock.Covers("/dir/a/b.txt"))
+	a.False(lock.Covers("/dir2"))
+	a.False(lock.Covers("/"))
+
+	lock.ZeroDepth = true
+	a.True(lock.Covers("/dir"))
+	a.False(lock.Covers("/dir/a"))
+
+	root := &WebdavLock{Root: "/"}
+	a.True(root.Covers("/any"))
+}
+
+func TestWebdavLock_conflicts(t *testing.T) {
+	a := assert.New(t)
+
+	a.True((&WebdavLock{Root: "/a", ZeroDepth: true}).conflicts(&WebdavLock{Root: "/a", ZeroDepth: true}))
+	a.True((&WebdavLock{Root: "/a"}).conflicts(&WebdavLock{Root: "/a/b", ZeroDepth: true}))
+	a.True((&WebdavLock{Root: "/a/b", ZeroDepth: true}).conflicts(&WebdavLock{Root: "/a"}))
+	a.False((&WebdavLock{Root: "/a/b", ZeroDepth: true}).conflicts(&WebdavLock{Root: "/a", ZeroDepth: true}))
+	a.False((&WebdavLock{Root: "/a"}).conflicts(&WebdavLock{Root: "/b"}))
+}
+
+func TestCreateWebdavLock(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_locks(.+)").WithArgs(1, now).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("SELECT(.+)webdav_locks(.+)").WithArgs(1, now).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "root", "zero_depth"}).AddRow(1, "/b", false))
+		mock.ExpectExec("INSERT(.+)webdav_locks(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		lock := &WebdavLock{UserID: 1, Root: "/a", Token: "token"}
+		a.NoError(CreateWebdavLock(lock, now))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(2, lock.ID)
+	}
+
+	// 冲突
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_locks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT(.+)webdav_locks(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "root", "zero_depth"}).AddRow(1, "/", false))
+		mock.ExpectRollback()
+		lock := &WebdavLock{UserID: 1, Root: "/a", Token: "token"}
+		a.ErrorIs(CreateWebdavLock(lock, now), ErrWebdavLocked)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 清理过期锁失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_locks(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(CreateWebdavLock(&WebdavLock{UserID: 1}, now))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestGetActiveWebdavLockByToken(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	mock.ExpectQuery("SELECT(.+)webdav_locks(.+)").WithArgs(1, now, "token").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "token"}).AddRow(1, "token"))
+	lock, err := GetActiveWebdavLockByToken(1, "token", now)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Equal("token", lock.Token)
+}
+
+func TestWebdavLock_Refresh(t *testing.T) {
+	a := assert.New(t)
+	lock := &WebdavLock{}
+	lock.ID = 1
+	expiresAt := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)webdav_locks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	a.NoError(lock.Refresh(&expiresAt))
+	a.NoError(mock.ExpectationsWereMet())
+	a.Equal(&expiresAt, lock.ExpiresAt)
+}
+
+func TestDeleteWebdavLock(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	// 找到
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_locks(.+)").WithArgs(1, now, "token").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		found, err := DeleteWebdavLock(1, "token", now)
+		a.NoError(err)
+		a.True(found)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未找到
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_locks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		found, err := DeleteWebdavLock(1, "token", now)
+		a.NoError(err)
+		a.False(found)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// WebdavProp 客户端通过 WebDAV PROPPATCH 设置的自定义属性
+type WebdavProp struct {
+	gorm.Model
+	// ObjectID 文件或目录的 ID，由 IsFolder 区分
+	ObjectID uint `gorm:"index:webdav_prop_object"`
+	IsFolder bool `gorm:"index:webdav_prop_object"`
+	// Namespace、Name 属性的命名空间与名称
+	Namespace string `gorm:"type:text"`
+	Name      string `gorm:"type:text"`
+	Lang      string
+	InnerXML  string `gorm:"type:text"`
+}
+
+// WebdavPropPatch 自定义属性变更，Remove 为真时删除属性，否则设定属性
+type WebdavPropPatch struct {
+	Remove bool
+	Prop   WebdavProp
+}
+
+// GetWebdavProps 列出文件或目录的所有自定义属性
+func GetWebdavProps(objectID uint, isFolder bool) ([]WebdavProp, error) {
+	var props []WebdavProp
+	result := DB.Where("object_id = ? and is_folder = ?", objectID, isFolder).Find(&props)
+	return props, result.Error
+}
+
+// PatchWebdavProps 依次应用文件或目录的自定义属性变更，全部成功或全部失败
+func PatchWebdavProps(objectID uint, isFolder bool, patches []WebdavPropPatch) error {
+	tx := DB.Begin()
+	for _, patch := range patches {
+		if err := tx.Unscoped().
+			Where("object_id = ? and is_folder = ? and namespace = ? and name = ?", objectID, isFolder, patch.Prop.Namespace, patch.Prop.Name).
+			Delete(&WebdavProp{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if patch.Remove {
+			continue
+		}
+
+		prop := patch.Prop
+		prop.ObjectID = objectID
+		prop.IsFolder = isFolder
+		if err := tx.Create(&prop).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteOrphanWebdavProps 删除所属文件或目录已不存在的自定义属性，回收站中的对象仍保留属性
+func DeleteOrphanWebdavProps() error {
+	if err := DB.Unscoped().Where("is_folder = ? and object_id not in (?)", false,
+		DB.Unscoped().Model(&File{}).Select("id").QueryExpr()).Delete(&WebdavProp{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Unscoped().Where("is_folder = ? and object_id not in (?)", true,
+		DB.Unscoped().Model(&Folder{}).Select("id").QueryExpr()).Delete(&WebdavProp{}).Error
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWebdavProps(t *testing.T) {
+	a := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)webdav_props(.+)").WithArgs(1, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "namespace", "name"}).AddRow(1, "urn:test", "color"))
+	props, err := GetWebdavProps(1, true)
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Len(props, 1)
+	a.Equal("color", props[0].Name)
+}
+
+func TestPatchWebdavProps(t *testing.T) {
+	a := assert.New(t)
+	patches := []WebdavPropPatch{
+		{Prop: WebdavProp{Namespace: "urn:test", Name: "color", InnerXML: "red"}},
+		{Remove: true, Prop: WebdavProp{Namespace: "urn:test", Name: "size"}},
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").WithArgs(1, false, "urn:test", "color").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)webdav_props(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").WithArgs(1, false, "urn:test", "size").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		a.NoError(PatchWebdavProps(1, false, patches))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)webdav_props(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(PatchWebdavProps(1, false, patches))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteOrphanWebdavProps(t *testing.T) {
+	a := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		a.NoError(DeleteOrphanWebdavProps())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.Error(DeleteOrphanWebdavProps())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Webhook 事件
+const (
+	WebhookEventUpload        = "file.uploaded"
+	WebhookEventDelete        = "file.deleted"
+	WebhookEventShareCreate   = "share.created"
+	WebhookEventShareDownload = "share.downloaded"
+)
+
+// Webhook 投递状态
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// webhookDeliveryErrorMaxLen 投递记录中保存的错误信息最大长度
+const webhookDeliveryErrorMaxLen = 1024
+
+// WebhookEvents 所有可订阅的事件
+var WebhookEvents = []string{WebhookEventUpload, WebhookEventDelete, WebhookEventShareCreate, WebhookEventShareDownload}
+
+// Webhook 接收事件通知的地址，UserID 为 0 时由管理员创建，接收所有用户的事件
+type Webhook struct {
+	gorm.Model
+	UserID  uint   `gorm:"index"`
+	Name    string `gorm:"size:255"`
+	URL     string `gorm:"type:text"`
+	Secret  string
+	Enabled bool
+	// Events 以逗号分隔的订阅事件
+	Events string
+}
+
+// WebhookDelivery 事件投递记录
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID    uint   `gorm:"index"`
+	Event        string `gorm:"size:32"`
+	Payload      string `gorm:"type:text"`
+	Status       string `gorm:"size:16;index"`
+	Attempts     int
+	ResponseCode int
+	Error        string `gorm:"type:text"`
+	NextRetryAt  *time.Time
+}
+
+// Create 创建 Webhook
+func (webhook *Webhook) Create() error {
+	return DB.Create(webhook).Error
+}
+
+// EventList 返回订阅的事件列表
+func (webhook *Webhook) EventList() []string {
+	if webhook.Events == "" {
+		return []string{}
+	}
+	return strings.Split(webhook.Events, ",")
+}
+
+// Subscribed 返回是否订阅了给定事件
+func (webhook *Webhook) Subscribed(event string) bool {
+	for _, e := range webhook.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWebhookByID 用ID获取 Webhook
+func GetWebhookByID(id uint) (Webhook, error) {
+	var webhook Webhook
+	result := DB.First(&webhook, id)
+	return webhook, result.Error
+}
+
+// GetUserWebhook 用ID获取用户创建的 Webhook，uid 为 0 时获取管理员创建的 Webhook
+func GetUserWebhook(id, uid uint) (Webhook, error) {
+	var webhook Webhook
+	result := DB.Where("id = ? and user_id = ?", id, uid).First(&webhook)
+	return webhook, result.Error
+}
+
+// ListWebhooksByUser 列出用户创建的 Webhook，uid 为 0 时列出管理员创建的 Webhook
+func ListWebhooksByUser(uid uint) ([]Webhook, error) {
+	var webhooks []Webhook
+	result := DB.Where("user_id = ?", uid).Order("id desc").Find(&webhooks)
+	return webhooks, result.Error
+}
+
+// CountWebhooksByUser 统计用户创建的 Webhook 数量
+func CountWebhooksByUser(uid uint) (int, error) {
+	count := 0
+	result := DB.Model(&Webhook{}).Where("user_id = ?", uid).Count(&count)
+	return count, result.Error
+}
+
+// GetSubscribedWebhooks 列出接收给定用户事件的已启用 Webhook，包括管理员创建的 Webhook
+func GetSubscribedWebhooks(uid uint, event string) ([]Webhook, error) {
+	var webhooks []Webhook
+	result := DB.Where("enabled = ? and (user_id = 0 or user_id = ?)", true, uid).Find(&webhooks)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	res := make([]Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(event) {
+			res = append(res, webhook)
+		}
+	}
+	return res, nil
+}
+
+// DeleteWebhook 删除用户创建的 Webhook 及其投递记录
+func DeleteWebhook(id, uid uint) (int64, error) {
+	result := DB.Where("id = ? and user_id = ?", id, uid).Delete(&Webhook{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.RowsAffected, result.Error
+	}
+
+	return result.RowsAffected, DB.Unscoped().Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error
+}
+
+// Create 创建投递记录
+func (delivery *WebhookDelivery) Create() error {
+	return DB.Create(delivery).Error
+}
+
+// Finish 记录一次投递的结果，失败时若 nextRetry 不为空则等待重试，否则标记为失败
+func (delivery *WebhookDelivery) Finish(code int, err error, nextRetry *time.Time) error {
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	delivery.NextRetryAt = nil
+	delivery.Error = ""
+	delivery.Status = WebhookDeliverySucceeded
+	if err != nil {
+		delivery.Error = err.Error()
+		if len(delivery.Error) > webhookDeliveryErrorMaxLen {
+			delivery.Error = delivery.Error[:webhookDeliveryErrorMaxLen]
+		}
+
+		delivery.Status = WebhookDeliveryFailed
+		if nextRetry != nil {
+			delivery.Status = WebhookDeliveryPending
+			delivery.NextRetryAt = nextRetry
+		}
+	}
+
+	return DB.Model(delivery).Updates(map[string]interface{}{
+		"attempts":      delivery.Attempts,
+		"response_code": delivery.ResponseCode,
+		"error":         delivery.Error,
+		"status":        delivery.Status,
+		"next_retry_at": delivery.NextRetryAt,
+	}).Error
+}
+
+// ListWebhookDeliveries 列出 Webhook 最近的投递记录
+func ListWebhookDeliveries(webhookID uint, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	result := DB.Where("webhook_id = ?", webhookID).Order("id desc").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// GetDueWebhookDeliveries 列出到达重试时间的投递记录
+func GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	result := DB.Where("status = ? and next_retry_at <= ?", WebhookDeliveryPending, now).
+		Order("id asc").Limit(limit).Find(&deliveries)
+	return deliveries, result.Error
+}
+
+// DeleteWebhookDeliveriesBefore 彻底删除给定时间之前的投递记录，返回删除的条数
+func DeleteWebhookDeliveriesBefore(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("created_at < ? and status <> ?", before, WebhookDeliveryPending).
+		Delete(&WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
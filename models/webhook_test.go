@@ -0,0 +1,117 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhook_Subscribed(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.False((&Webhook{}).Subscribed(WebhookEventUpload))
+	asserts.True((&Webhook{Events: "file.uploaded,share.created"}).Subscribed(WebhookEventShareCreate))
+	asserts.False((&Webhook{Events: "file.uploaded"}).Subscribed(WebhookEventDelete))
+}
+
+func TestGetSubscribedWebhooks(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").WillReturnError(errors.New("error"))
+		_, err := GetSubscribedWebhooks(1, WebhookEventUpload)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 按事件过滤
+	{
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").WithArgs(true, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).
+				AddRow(1, "file.uploaded").
+				AddRow(2, "file.deleted,file.uploaded").
+				AddRow(3, "share.created"))
+		res, err := GetSubscribedWebhooks(1, WebhookEventUpload)
+		asserts.NoError(err)
+		asserts.Len(res, 2)
+		asserts.EqualValues(2, res[1].ID)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不存在
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhooks(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		affected, err := DeleteWebhook(1, 2)
+		asserts.NoError(err)
+		asserts.EqualValues(0, affected)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功，同时删除投递记录
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhooks(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webhook_deliveries(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+		affected, err := DeleteWebhook(1, 2)
+		asserts.NoError(err)
+		asserts.EqualValues(1, affected)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestWebhookDelivery_Finish(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		delivery := &WebhookDelivery{Model: gorm.Model{ID: 1}, Error: "previous"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(delivery.Finish(200, nil, nil))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(WebhookDeliverySucceeded, delivery.Status)
+		asserts.Equal(1, delivery.Attempts)
+		asserts.Empty(delivery.Error)
+	}
+
+	// 失败，等待重试
+	{
+		next := time.Now().Add(time.Minute)
+		delivery := &WebhookDelivery{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(delivery.Finish(500, errors.New(strings.Repeat("e", 2000)), &next))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(WebhookDeliveryPending, delivery.Status)
+		asserts.Len(delivery.Error, webhookDeliveryErrorMaxLen)
+		asserts.Equal(&next, delivery.NextRetryAt)
+	}
+
+	// 失败，不再重试
+	{
+		delivery := &WebhookDelivery{Model: gorm.Model{ID: 1}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(delivery.Finish(0, errors.New("timeout"), nil))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(WebhookDeliveryFailed, delivery.Status)
+	}
+}
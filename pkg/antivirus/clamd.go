@@ -0,0 +1,182 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// chunkSize INSTREAM 每个数据块的大小
+const chunkSize = 64 * 1024
+
+var (
+	// ErrSizeLimitExceeded 文件超出 clamd 的 StreamMaxLength 限制
+	ErrSizeLimitExceeded = errors.New("file exceeds clamd stream size limit")
+	// ErrUnexpectedReply 无法识别的 clamd 响应
+	ErrUnexpectedReply = errors.New("unexpected clamd reply")
+)
+
+// Result 扫描结果
+type Result struct {
+	// Infected 是否检出病毒
+	Infected bool
+	// Signature 检出的病毒特征名称
+	Signature string
+}
+
+// Client ClamAV 守护进程 clamd 的客户端
+type Client struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClient 创建 clamd 客户端，addr 形如 tcp://127.0.0.1:3310 或 unix:///var/run/clamav/clamd.ctl，
+// 不带协议前缀时视为 TCP 地址
+func NewClient(addr string, timeout time.Duration) (*Client, error) {
+	client := &Client{network: "tcp", address: addr, timeout: timeout}
+	if strings.HasPrefix(addr, "unix://") {
+		client.network = "unix"
+		client.address = strings.TrimPrefix(addr, "unix://")
+	} else {
+		client.address = strings.TrimPrefix(addr, "tcp://")
+	}
+
+	if client.address == "" {
+		return nil, fmt.Errorf("invalid clamd address %q", addr)
+	}
+
+	return client, nil
+}
+
+// dial 建立连接，连接在 ctx 结束或超时后失效
+func (c *Client) dial(ctx context.Context) (net.Conn, func(), error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+
+	if c.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	return conn, func() {
+		close(done)
+		_ = conn.Close()
+	}, nil
+}
+
+// readReply 读取以 NULL 结尾的响应
+func readReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(errors.Is(err, io.EOF) && reply != "") {
+		return "", fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// Ping 检查 clamd 是否可用
+func (c *Client) Ping(ctx context.Context) error {
+	conn, closer, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+
+	if reply != "PONG" {
+		return fmt.Errorf("%w: %q", ErrUnexpectedReply, reply)
+	}
+
+	return nil
+}
+
+// Scan 通过 INSTREAM 命令将 r 中的内容发送给 clamd 扫描
+func (c *Client) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	conn, closer, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer closer()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// 超出大小限制时 clamd 会回复错误并关闭连接
+				if reply, replyErr := readReply(conn); replyErr == nil && reply != "" {
+					return parseReply(reply)
+				}
+				return nil, fmt.Errorf("failed to send file content: %w", err)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read file content: %w", readErr)
+		}
+	}
+
+	// 长度为 0 的数据块表示结束
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, fmt.Errorf("failed to finish stream: %w", err)
+	}
+
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseReply(reply)
+}
+
+// parseReply 解析扫描响应，如 "stream: OK"、"stream: Eicar-Signature FOUND"
+func parseReply(reply string) (*Result, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case strings.HasPrefix(reply, "INSTREAM size limit exceeded"):
+		return nil, ErrSizeLimitExceeded
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd error: %s", strings.TrimSuffix(reply, " ERROR"))
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnexpectedReply, reply)
+}
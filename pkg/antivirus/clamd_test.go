@@ -0,0 +1,157 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClamd 模拟 clamd，检出包含 VIRUS 的内容
+func fakeClamd(t *testing.T, limit int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString(0)
+				if err != nil {
+					return
+				}
+
+				switch command {
+				case "zPING\x00":
+					conn.Write([]byte("PONG\x00"))
+				case "zINSTREAM\x00":
+					var content bytes.Buffer
+					for {
+						var size uint32
+						if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+							return
+						}
+						if size == 0 {
+							break
+						}
+						if content.Len()+int(size) > limit {
+							conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+							return
+						}
+						if _, err := io.CopyN(&content, reader, int64(size)); err != nil {
+							return
+						}
+					}
+
+					if strings.Contains(content.String(), "VIRUS") {
+						conn.Write([]byte("stream: Test-Signature FOUND\x00"))
+					} else {
+						conn.Write([]byte("stream: OK\x00"))
+					}
+				default:
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}(conn)
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestNewClient(t *testing.T) {
+	a := assert.New(t)
+
+	client, err := NewClient("unix:///var/run/clamd.ctl", time.Second)
+	a.NoError(err)
+	a.Equal("unix", client.network)
+	a.Equal("/var/run/clamd.ctl", client.address)
+
+	client, err = NewClient("tcp://127.0.0.1:3310", time.Second)
+	a.NoError(err)
+	a.Equal("tcp", client.network)
+	a.Equal("127.0.0.1:3310", client.address)
+
+	client, err = NewClient("127.0.0.1:3310", time.Second)
+	a.NoError(err)
+	a.Equal("127.0.0.1:3310", client.address)
+
+	client, err = NewClient("unix://", time.Second)
+	a.Error(err)
+	a.Nil(client)
+}
+
+func TestClient_Scan(t *testing.T) {
+	a := assert.New(t)
+	client, err := NewClient(fakeClamd(t, 200*1024), 5*time.Second)
+	a.NoError(err)
+
+	a.NoError(client.Ping(context.Background()))
+
+	// 正常文件，跨越多个数据块
+	{
+		res, err := client.Scan(context.Background(), bytes.NewReader(bytes.Repeat([]byte("a"), chunkSize*2+10)))
+		a.NoError(err)
+		a.False(res.Infected)
+	}
+
+	// 检出病毒
+	{
+		res, err := client.Scan(context.Background(), strings.NewReader("hello VIRUS"))
+		a.NoError(err)
+		a.True(res.Infected)
+		a.Equal("Test-Signature", res.Signature)
+	}
+
+	// 超出大小限制
+	{
+		res, err := client.Scan(context.Background(), bytes.NewReader(make([]byte, 300*1024)))
+		a.ErrorIs(err, ErrSizeLimitExceeded)
+		a.Nil(res)
+	}
+
+	// 无法连接
+	{
+		client, _ := NewClient("tcp://127.0.0.1:1", time.Second)
+		res, err := client.Scan(context.Background(), strings.NewReader(""))
+		a.Error(err)
+		a.Nil(res)
+		a.Error(client.Ping(context.Background()))
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	a := assert.New(t)
+
+	res, err := parseReply("stream: OK")
+	a.NoError(err)
+	a.False(res.Infected)
+
+	res, err = parseReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	a.NoError(err)
+	a.True(res.Infected)
+	a.Equal("Win.Test.EICAR_HDB-1", res.Signature)
+
+	res, err = parseReply("stream: Can't allocate memory ERROR")
+	a.Error(err)
+	a.Nil(res)
+
+	res, err = parseReply("what")
+	a.ErrorIs(err, ErrUnexpectedReply)
+	a.Nil(res)
+}
@@ -35,7 +35,7 @@ func GetLoadBalancer() balancer.Balancer {
 // Init 初始化
 func Init(isReload bool, pool cluster.Pool, mqClient mq.MQ) {
 	Lock.Lock()
-	LB = balancer.NewBalancer("RoundRobin")
+	LB = common.NewLoadBalancer(model.GetSettingByName("aria2_balancer"))
 	Lock.Unlock()
 
 	if !isReload {
@@ -2,6 +2,7 @@ package aria2
 
 import (
 	"database/sql"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mocks"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/stretchr/testify/assert"
@@ -33,11 +34,13 @@ func TestInit(t *testing.T) {
 	mockPool := &mocks.NodePoolMock{}
 	mockPool.On("GetNodeByID", testMock.Anything).Return(nil)
 	mockQueue := mq.NewMQ()
+	cache.Set("setting_aria2_balancer", "LeastActive", 0)
 
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	Init(false, mockPool, mockQueue)
 	a.NoError(mock.ExpectationsWereMet())
 	mockPool.AssertExpectations(t)
+	a.NotNil(GetLoadBalancer())
 }
 
 func TestTestRPCConnection(t *testing.T) {
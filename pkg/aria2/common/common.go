@@ -22,8 +22,17 @@ type Aria2 interface {
 	GetConfig() model.Aria2Option
 	// 删除临时下载文件
 	DeleteTempFile(*model.Download) error
+	// Seed 使用本地已有的文件为种子做种
+	Seed(torrent, dir string) (string, error)
 }
 
+const (
+	// BackendRPC 通过 RPC 调用外部 aria2
+	BackendRPC = "rpc"
+	// BackendEmbedded 使用内置的 BitTorrent 下载器
+	BackendEmbedded = "embedded"
+)
+
 const (
 	// URLTask 从URL添加的任务
 	URLTask = iota
@@ -95,6 +104,11 @@ func (instance *DummyAria2) DeleteTempFile(src *model.Download) error {
 	return ErrNotEnabled
 }
 
+// Seed 返回未开启错误
+func (instance *DummyAria2) Seed(torrent, dir string) (string, error) {
+	return "", ErrNotEnabled
+}
+
 // GetStatus 将给定的状态字符串转换为状态标识数字
 func GetStatus(status rpc.StatusInfo) int {
 	switch status.Status {
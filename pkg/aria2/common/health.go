@@ -0,0 +1,127 @@
+package common
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/balancer"
+)
+
+// Health 离线下载节点的健康状态，连续请求失败的节点在恢复间隔内不参与负载均衡
+var Health = NewHealthTracker()
+
+// HealthTracker 记录离线下载节点的连续失败次数
+type HealthTracker struct {
+	lock      sync.Mutex
+	failures  map[uint]int
+	downUntil map[uint]time.Time
+}
+
+// NewHealthTracker 新建节点健康状态记录
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{
+		failures:  make(map[uint]int),
+		downUntil: make(map[uint]time.Time),
+	}
+}
+
+// Fail 记录一次请求失败，连续失败次数达到阈值后将节点标记为不健康
+func (h *HealthTracker) Fail(id uint) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.failures[id]++
+	if h.failures[id] >= model.GetIntSetting("aria2_node_retry", 3) {
+		h.markUnhealthy(id)
+	}
+}
+
+// Succeed 记录一次请求成功，清除节点的失败记录
+func (h *HealthTracker) Succeed(id uint) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.failures, id)
+	delete(h.downUntil, id)
+}
+
+// MarkUnhealthy 立即将节点标记为不健康
+func (h *HealthTracker) MarkUnhealthy(id uint) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.markUnhealthy(id)
+}
+
+func (h *HealthTracker) markUnhealthy(id uint) {
+	h.failures[id] = 0
+	h.downUntil[id] = time.Now().Add(time.Duration(model.GetIntSetting("aria2_recover_interval", 600)) * time.Second)
+}
+
+// IsHealthy 返回节点是否健康，超过恢复间隔的节点会重新参与负载均衡
+func (h *HealthTracker) IsHealthy(id uint) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	until, ok := h.downUntil[id]
+	if !ok {
+		return true
+	}
+
+	if time.Now().After(until) {
+		delete(h.downUntil, id)
+		return true
+	}
+
+	return false
+}
+
+// healthyBalancer 过滤掉不健康节点后再进行负载均衡
+type healthyBalancer struct {
+	balancer.Balancer
+	health *HealthTracker
+}
+
+// NewLoadBalancer 根据策略标识新建离线下载节点使用的负载均衡器，
+// LeastActive 选择进行中任务最少的节点，其余策略使用轮询
+func NewLoadBalancer(strategy string) balancer.Balancer {
+	var lb balancer.Balancer
+	if strategy == "LeastActive" {
+		lb = balancer.NewLeastActive(activeDownloads)
+	} else {
+		lb = balancer.NewBalancer(strategy)
+	}
+
+	return &healthyBalancer{Balancer: lb, health: Health}
+}
+
+// NextPeer 从健康节点中选出下一节点
+func (b *healthyBalancer) NextPeer(nodes interface{}) (error, interface{}) {
+	v := reflect.ValueOf(nodes)
+	if v.Kind() != reflect.Slice {
+		return balancer.ErrInputNotSlice, nil
+	}
+
+	healthy := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		node := v.Index(i).Interface()
+		if n, ok := node.(interface{ ID() uint }); ok && !b.health.IsHealthy(n.ID()) {
+			continue
+		}
+		healthy = append(healthy, node)
+	}
+
+	return b.Balancer.NextPeer(healthy)
+}
+
+// activeDownloads 返回节点上未完成的下载任务数
+func activeDownloads(peer interface{}) int {
+	node, ok := peer.(interface{ ID() uint })
+	if !ok {
+		return 0
+	}
+
+	return model.CountDownloadsByNode(node.ID(), Ready, Downloading, Paused, Seeding)
+}
@@ -0,0 +1,75 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/balancer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type nodeStub uint
+
+func (n nodeStub) ID() uint {
+	return uint(n)
+}
+
+func TestHealthTracker(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_aria2_node_retry", "2", 0)
+	cache.Set("setting_aria2_recover_interval", "600", 0)
+	h := NewHealthTracker()
+
+	// 连续失败达到阈值
+	a.True(h.IsHealthy(1))
+	h.Fail(1)
+	a.True(h.IsHealthy(1))
+	h.Fail(1)
+	a.False(h.IsHealthy(1))
+
+	// 成功后恢复
+	h.Succeed(1)
+	a.True(h.IsHealthy(1))
+
+	// 中途成功会清除失败记录
+	h.Fail(1)
+	h.Succeed(1)
+	h.Fail(1)
+	a.True(h.IsHealthy(1))
+
+	// 超过恢复间隔后重新参与负载均衡
+	cache.Set("setting_aria2_recover_interval", "0", 0)
+	h.MarkUnhealthy(2)
+	a.True(h.IsHealthy(2))
+}
+
+func TestHealthyBalancer_NextPeer(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_aria2_recover_interval", "600", 0)
+	h := NewHealthTracker()
+	lb := &healthyBalancer{Balancer: balancer.NewBalancer("RoundRobin"), health: h}
+	a.IsType(&healthyBalancer{}, NewLoadBalancer("LeastActive"))
+
+	// not slice
+	{
+		err, _ := lb.NextPeer("s")
+		a.Equal(balancer.ErrInputNotSlice, err)
+	}
+
+	// 跳过不健康节点
+	{
+		h.MarkUnhealthy(1)
+		for i := 0; i < 3; i++ {
+			err, res := lb.NextPeer([]nodeStub{1, 2})
+			a.NoError(err)
+			a.Equal(nodeStub(2), res)
+		}
+	}
+
+	// 全部节点不健康
+	{
+		h.MarkUnhealthy(2)
+		err, _ := lb.NextPeer([]nodeStub{1, 2})
+		a.Equal(balancer.ErrNoAvaliableNode, err)
+	}
+}
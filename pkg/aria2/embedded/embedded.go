@@ -0,0 +1,491 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/anacrolix/torrent/storage"
+	"github.com/anacrolix/torrent/types"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// maxTorrentFileSize 从 URL 下载的种子文件最大大小
+	maxTorrentFileSize = 10 << 20
+	// defaultFetchTimeout 未配置超时时间时下载种子文件的超时时间
+	defaultFetchTimeout = 10 * time.Second
+)
+
+// 与 aria2 一致的任务状态
+const (
+	statusWaiting  = "waiting"
+	statusActive   = "active"
+	statusComplete = "complete"
+	statusRemoved  = "removed"
+	statusError    = "error"
+)
+
+var (
+	ErrNotInitialized    = errors.New("embedded downloader is not initialized")
+	ErrTaskNotFound      = errors.New("download task not found")
+	ErrTaskExisted       = errors.New("torrent is already being downloaded")
+	ErrUnsupportedSource = errors.New("embedded downloader only supports magnet links and torrent files")
+	ErrMetadataNotReady  = errors.New("torrent metadata is not ready")
+)
+
+// newClientConfig 生成 BitTorrent 客户端的默认配置
+var newClientConfig = torrent.NewDefaultClientConfig
+
+// Client 内置的 BitTorrent 下载器，无需部署 aria2 即可进行磁力链及种子离线下载
+type Client struct {
+	client *torrent.Client
+	option model.Aria2Option
+	tasks  map[string]*entry
+	lock   sync.RWMutex
+}
+
+// entry 下载器中的单个任务
+type entry struct {
+	gid  string
+	dir  string
+	t    *torrent.Torrent
+	seed bool // 为本地已有文件做种，不受做种限制
+
+	// 以下字段受 Client.lock 保护
+	status      string
+	errMsg      string
+	selected    map[int]bool // 选中下载的文件序号，从 1 开始，为空时下载全部
+	completedAt time.Time
+	lastRead    int64
+	lastAt      time.Time
+	last        rpc.StatusInfo
+}
+
+// New 使用节点的离线下载配置新建内置下载器
+func New(option model.Aria2Option) *Client {
+	return &Client{
+		option: option,
+		tasks:  make(map[string]*entry),
+	}
+}
+
+// Init 启动 BitTorrent 客户端，已启动时会先关闭原有客户端
+func (c *Client) Init() error {
+	c.Close()
+
+	tempPath := util.RelativePath(c.option.TempPath)
+	if err := os.MkdirAll(tempPath, 0744); err != nil {
+		return err
+	}
+
+	cfg := newClientConfig()
+	cfg.Seed = true
+	cfg.ListenPort = c.option.ListenPort
+	cfg.DataDir = tempPath
+	cfg.DefaultStorage = newStorage(tempPath)
+
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to start embedded downloader: %w", err)
+	}
+
+	c.lock.Lock()
+	c.client = client
+	c.lock.Unlock()
+	return nil
+}
+
+// Close 关闭 BitTorrent 客户端，已有的任务会在下次查询状态时恢复
+func (c *Client) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	c.tasks = make(map[string]*entry)
+}
+
+// CreateTask 创建磁力链或种子下载任务
+func (c *Client) CreateTask(task *model.Download, options map[string]interface{}) (string, error) {
+	guid, _ := uuid.NewV4()
+	dir := util.RelativePath(filepath.Join(c.option.TempPath, "embedded", guid.String()))
+	return c.add(newGID(), task.Source, dir, nil)
+}
+
+// Seed 使用本地已有的文件为种子做种
+func (c *Client) Seed(torrentPath, dir string) (string, error) {
+	mi, err := metainfo.LoadFromFile(torrentPath)
+	if err != nil {
+		return "", err
+	}
+
+	spec, err := torrent.TorrentSpecFromMetaInfoErr(mi)
+	if err != nil {
+		return "", err
+	}
+
+	e := &entry{gid: newGID(), dir: dir, seed: true}
+	if err := c.addSpec(e, spec); err != nil {
+		return "", err
+	}
+
+	return e.gid, nil
+}
+
+// Status 返回与 aria2 格式一致的任务状态，重启后丢失的任务会从原下载地址恢复
+func (c *Client) Status(task *model.Download) (rpc.StatusInfo, error) {
+	c.lock.RLock()
+	e, ok := c.tasks[task.GID]
+	c.lock.RUnlock()
+
+	if !ok {
+		if task.Source == "" || task.Parent == "" {
+			return rpc.StatusInfo{}, ErrTaskNotFound
+		}
+
+		var selected map[int]bool
+		for _, file := range task.StatusInfo.Files {
+			if selected == nil {
+				selected = make(map[int]bool)
+			}
+			index, _ := strconv.Atoi(file.Index)
+			selected[index] = file.Selected == "true"
+		}
+
+		if _, err := c.add(task.GID, task.Source, task.Parent, selected); err != nil {
+			return rpc.StatusInfo{}, err
+		}
+
+		c.lock.RLock()
+		e = c.tasks[task.GID]
+		c.lock.RUnlock()
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.refresh(e), nil
+}
+
+// Cancel 取消下载任务，已下载的文件由 DeleteTempFile 清理
+func (c *Client) Cancel(task *model.Download) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.tasks[task.GID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if e.status != statusComplete && e.status != statusRemoved {
+		c.refresh(e)
+		e.t.Drop()
+		e.status = statusRemoved
+	}
+
+	return nil
+}
+
+// Select 选择要下载的文件，序号从 1 开始
+func (c *Client) Select(task *model.Download, files []int) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e, ok := c.tasks[task.GID]
+	if !ok {
+		return ErrTaskNotFound
+	}
+
+	if e.t.Info() == nil {
+		return ErrMetadataNotReady
+	}
+
+	e.selected = make(map[int]bool, len(files))
+	for _, index := range files {
+		e.selected[index] = true
+	}
+	applySelection(e.t, e.selected)
+
+	return nil
+}
+
+// GetConfig 返回节点的离线下载配置
+func (c *Client) GetConfig() model.Aria2Option {
+	return c.option
+}
+
+// DeleteTempFile 停止任务并删除临时下载目录
+func (c *Client) DeleteTempFile(task *model.Download) error {
+	c.lock.Lock()
+	if e, ok := c.tasks[task.GID]; ok {
+		if e.status != statusComplete && e.status != statusRemoved {
+			e.t.Drop()
+		}
+		delete(c.tasks, task.GID)
+	}
+	c.lock.Unlock()
+
+	go func(src string) {
+		if err := os.RemoveAll(src); err != nil {
+			util.Log().Warning("Failed to delete temp download folder: %q: %s", src, err)
+		}
+	}(task.Parent)
+
+	return nil
+}
+
+// add 从磁力链或种子文件地址添加任务
+func (c *Client) add(gid, source, dir string, selected map[int]bool) (string, error) {
+	var (
+		spec *torrent.TorrentSpec
+		err  error
+	)
+	if strings.HasPrefix(source, "magnet:") {
+		spec, err = torrent.TorrentSpecFromMagnetUri(source)
+	} else {
+		spec, err = c.fetchTorrent(source)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	e := &entry{gid: gid, dir: dir, selected: selected}
+	if err := c.addSpec(e, spec); err != nil {
+		return "", err
+	}
+
+	return gid, nil
+}
+
+// addSpec 将种子加入 BitTorrent 客户端，元数据就绪后开始下载选中的文件
+func (c *Client) addSpec(e *entry, spec *torrent.TorrentSpec) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client == nil {
+		return ErrNotInitialized
+	}
+
+	if _, ok := c.client.Torrent(spec.InfoHash); ok {
+		return ErrTaskExisted
+	}
+
+	spec.Storage = newStorage(e.dir)
+	t, _, err := c.client.AddTorrentSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	e.t = t
+	e.status = statusWaiting
+	c.tasks[e.gid] = e
+
+	go func() {
+		select {
+		case <-t.GotInfo():
+		case <-t.Closed():
+			return
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		applySelection(t, e.selected)
+	}()
+
+	return nil
+}
+
+// fetchTorrent 下载并解析种子文件
+func (c *Client) fetchTorrent(source string) (*torrent.TorrentSpec, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return nil, ErrUnsupportedSource
+	}
+
+	timeout := defaultFetchTimeout
+	if c.option.Timeout > 0 {
+		timeout = time.Duration(c.option.Timeout) * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch torrent file, status code %d", resp.StatusCode)
+	}
+
+	mi, err := metainfo.Load(io.LimitReader(resp.Body, maxTorrentFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSource, err)
+	}
+
+	return torrent.TorrentSpecFromMetaInfoErr(mi)
+}
+
+// refresh 更新并返回任务状态，下载完成且达到做种限制后停止任务，调用方需持有 Client.lock
+func (c *Client) refresh(e *entry) rpc.StatusInfo {
+	if e.status == statusComplete || e.status == statusRemoved || e.status == statusError {
+		status := e.last
+		status.Status = e.status
+		status.ErrorMessage = e.errMsg
+		status.DownloadSpeed = "0"
+		status.UploadSpeed = "0"
+		return status
+	}
+
+	status := rpc.StatusInfo{
+		Gid:             e.gid,
+		Status:          statusWaiting,
+		TotalLength:     "0",
+		CompletedLength: "0",
+		UploadLength:    "0",
+		DownloadSpeed:   "0",
+		UploadSpeed:     "0",
+		InfoHash:        e.t.InfoHash().HexString(),
+		Dir:             e.dir,
+		Files:           []rpc.FileInfo{},
+	}
+
+	info := e.t.Info()
+	if info == nil {
+		e.last = status
+		return status
+	}
+
+	e.status = statusActive
+	status.Status = statusActive
+	status.PieceLength = strconv.FormatInt(info.PieceLength, 10)
+	status.NumPieces = strconv.Itoa(e.t.NumPieces())
+	status.BitTorrent.Info.Name = info.Name
+	status.BitTorrent.Mode = "single"
+	if len(info.Files) > 0 {
+		status.BitTorrent.Mode = "multi"
+	}
+
+	var total, completed int64
+	for i, file := range e.t.Files() {
+		selected := e.selected == nil || e.selected[i+1]
+		fileCompleted := file.BytesCompleted()
+		status.Files = append(status.Files, rpc.FileInfo{
+			Index:           strconv.Itoa(i + 1),
+			Path:            filePath(e.dir, info, file),
+			Length:          strconv.FormatInt(file.Length(), 10),
+			CompletedLength: strconv.FormatInt(fileCompleted, 10),
+			Selected:        strconv.FormatBool(selected),
+		})
+
+		if selected {
+			total += file.Length()
+			completed += fileCompleted
+		}
+	}
+
+	stats := e.t.Stats()
+	uploaded := stats.BytesWrittenData.Int64()
+	read := stats.BytesReadUsefulData.Int64()
+	now := time.Now()
+	if !e.lastAt.IsZero() {
+		if elapsed := now.Sub(e.lastAt).Seconds(); elapsed > 0 {
+			status.DownloadSpeed = strconv.FormatInt(int64(float64(read-e.lastRead)/elapsed), 10)
+		}
+	}
+	e.lastRead, e.lastAt = read, now
+
+	status.TotalLength = strconv.FormatInt(total, 10)
+	status.CompletedLength = strconv.FormatInt(completed, 10)
+	status.UploadLength = strconv.FormatInt(uploaded, 10)
+	status.NumSeeders = strconv.Itoa(stats.ConnectedSeeders)
+	status.Connections = strconv.Itoa(stats.ActivePeers)
+	status.Seeder = strconv.FormatBool(total == completed)
+	e.last = status
+
+	if total != completed {
+		return status
+	}
+
+	if e.completedAt.IsZero() {
+		e.completedAt = now
+	}
+
+	if !e.seed && seedingFinished(c.option, uploaded, total, now.Sub(e.completedAt)) {
+		e.t.Drop()
+		e.status = statusComplete
+		status.Status = statusComplete
+		status.DownloadSpeed = "0"
+		e.last = status
+	}
+
+	return status
+}
+
+// seedingFinished 下载完成后是否已达到做种限制，分享率与时长任一达到即停止做种
+func seedingFinished(option model.Aria2Option, uploaded, total int64, elapsed time.Duration) bool {
+	if option.SeedRatio <= 0 && option.SeedTime <= 0 {
+		return true
+	}
+
+	if option.SeedRatio > 0 && total > 0 && float64(uploaded)/float64(total) >= option.SeedRatio {
+		return true
+	}
+
+	return option.SeedTime > 0 && elapsed >= time.Duration(option.SeedTime)*time.Minute
+}
+
+// applySelection 按选中的文件设置下载优先级，selected 为空时下载全部文件
+func applySelection(t *torrent.Torrent, selected map[int]bool) {
+	if t.Info() == nil {
+		return
+	}
+
+	for i, file := range t.Files() {
+		if selected == nil || selected[i+1] {
+			file.SetPriority(types.PiecePriorityNormal)
+		} else {
+			file.SetPriority(types.PiecePriorityNone)
+		}
+	}
+}
+
+// filePath 返回文件在下载目录中的路径，与 aria2 的目录结构一致
+func filePath(dir string, info *metainfo.Info, file *torrent.File) string {
+	return filepath.Join(append([]string{dir, info.Name}, file.FileInfo().Path...)...)
+}
+
+// newStorage 将种子数据存储在 dir 目录中，文件完整性在加入任务时重新校验
+func newStorage(dir string) storage.ClientImplCloser {
+	return storage.NewFileOpts(storage.NewFileClientOpts{
+		ClientBaseDir:   dir,
+		PieceCompletion: storage.NewMapPieceCompletion(),
+	})
+}
+
+// newGID 生成与 aria2 格式一致的 16 位十六进制任务 ID
+func newGID() string {
+	guid, _ := uuid.NewV4()
+	return strings.ReplaceAll(guid.String(), "-", "")[:16]
+}
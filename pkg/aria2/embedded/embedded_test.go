@@ -0,0 +1,198 @@
+package embedded
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/bencode"
+	"github.com/anacrolix/torrent/metainfo"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	newClientConfig = func() *torrent.ClientConfig {
+		cfg := torrent.NewDefaultClientConfig()
+		cfg.NoDHT = true
+		cfg.DisableTrackers = true
+		cfg.NoDefaultPortForwarding = true
+		cfg.DisableIPv6 = true
+		cfg.ListenHost = func(string) string { return "127.0.0.1" }
+		return cfg
+	}
+}
+
+// testTorrent 在 dir 中创建测试文件及其种子，返回种子文件路径
+func testTorrent(t *testing.T, dir string) string {
+	data := filepath.Join(dir, "data", "hello.txt")
+	if err := os.MkdirAll(filepath.Dir(data), 0744); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(data, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info := metainfo.Info{PieceLength: 16 << 10}
+	if err := info.BuildFromFilePath(data); err != nil {
+		t.Fatal(err)
+	}
+
+	mi := metainfo.MetaInfo{}
+	mi.InfoBytes, _ = bencode.Marshal(info)
+
+	torrentPath := filepath.Join(dir, "hello.torrent")
+	file, err := os.Create(torrentPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if err := mi.Write(file); err != nil {
+		t.Fatal(err)
+	}
+
+	return torrentPath
+}
+
+// waitStatus 等待任务状态满足条件
+func waitStatus(t *testing.T, c *Client, task *model.Download, cond func(rpc.StatusInfo) bool) rpc.StatusInfo {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status, err := c.Status(task)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cond(status) {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected status %+v", status)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestSeedingFinished(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(seedingFinished(model.Aria2Option{}, 0, 10, 0))
+	a.False(seedingFinished(model.Aria2Option{SeedRatio: 1}, 5, 10, time.Hour))
+	a.True(seedingFinished(model.Aria2Option{SeedRatio: 1}, 10, 10, 0))
+	a.False(seedingFinished(model.Aria2Option{SeedTime: 10}, 0, 10, 5*time.Minute))
+	a.True(seedingFinished(model.Aria2Option{SeedTime: 10}, 0, 10, 10*time.Minute))
+	a.True(seedingFinished(model.Aria2Option{SeedRatio: 2, SeedTime: 10}, 20, 10, 0))
+}
+
+func TestClient_NotInitialized(t *testing.T) {
+	a := assert.New(t)
+	c := New(model.Aria2Option{Interval: 5})
+
+	a.Equal(5, c.GetConfig().Interval)
+	a.Len(newGID(), 16)
+
+	_, err := c.CreateTask(&model.Download{Source: "magnet:?xt=urn:btih:0123456789abcdef0123456789abcdef01234567"}, nil)
+	a.ErrorIs(err, ErrNotInitialized)
+
+	_, err = c.CreateTask(&model.Download{Source: "ftp://example.com/a.torrent"}, nil)
+	a.ErrorIs(err, ErrUnsupportedSource)
+
+	_, err = c.Status(&model.Download{GID: "gid"})
+	a.ErrorIs(err, ErrTaskNotFound)
+	a.ErrorIs(c.Cancel(&model.Download{GID: "gid"}), ErrTaskNotFound)
+	a.ErrorIs(c.Select(&model.Download{GID: "gid"}, []int{1}), ErrTaskNotFound)
+	a.NoError(c.DeleteTempFile(&model.Download{GID: "gid"}))
+}
+
+func TestClient_Seed(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	torrentPath := testTorrent(t, dir)
+
+	c := New(model.Aria2Option{TempPath: filepath.Join(dir, "temp")})
+	a.NoError(c.Init())
+	defer c.Close()
+
+	gid, err := c.Seed(torrentPath, filepath.Join(dir, "data"))
+	a.NoError(err)
+	task := &model.Download{GID: gid}
+
+	// 校验本地文件后开始做种，不受做种限制
+	status := waitStatus(t, c, task, func(status rpc.StatusInfo) bool {
+		return status.Status == statusActive && status.Seeder == "true"
+	})
+	a.Equal(common.Seeding, common.GetStatus(status))
+	a.Equal("11", status.TotalLength)
+	a.Equal("single", status.BitTorrent.Mode)
+	a.Len(status.Files, 1)
+	a.Equal(filepath.Join(dir, "data", "hello.txt"), status.Files[0].Path)
+
+	// 重复添加
+	_, err = c.Seed(torrentPath, filepath.Join(dir, "data"))
+	a.ErrorIs(err, ErrTaskExisted)
+
+	// 取消
+	a.NoError(c.Cancel(task))
+	status, err = c.Status(task)
+	a.NoError(err)
+	a.Equal(common.Canceled, common.GetStatus(status))
+}
+
+func TestClient_CreateTask(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	torrentPath := testTorrent(t, dir)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/hello.torrent" {
+			w.Write([]byte("not a torrent"))
+			return
+		}
+		http.ServeFile(w, r, torrentPath)
+	}))
+	defer server.Close()
+
+	c := New(model.Aria2Option{TempPath: filepath.Join(dir, "temp")})
+	a.NoError(c.Init())
+	defer c.Close()
+
+	// 不是种子文件
+	{
+		_, err := c.CreateTask(&model.Download{Source: server.URL + "/a.txt"}, nil)
+		a.ErrorIs(err, ErrUnsupportedSource)
+	}
+
+	// 无可用节点，保持下载中
+	{
+		gid, err := c.CreateTask(&model.Download{Source: server.URL + "/hello.torrent"}, nil)
+		a.NoError(err)
+		task := &model.Download{GID: gid}
+		status := waitStatus(t, c, task, func(status rpc.StatusInfo) bool {
+			return status.Status == statusActive
+		})
+		a.Equal(common.Downloading, common.GetStatus(status))
+		a.Equal("0", status.CompletedLength)
+		a.NoError(c.Select(task, []int{1}))
+
+		task.Parent = status.Dir
+		a.NoError(c.DeleteTempFile(task))
+		_, err = c.Status(&model.Download{GID: gid})
+		a.ErrorIs(err, ErrTaskNotFound)
+	}
+
+	// 重启后从原地址恢复，已下载完成且无需做种
+	{
+		task := &model.Download{GID: newGID(), Source: server.URL + "/hello.torrent", Parent: filepath.Join(dir, "data")}
+		status := waitStatus(t, c, task, func(status rpc.StatusInfo) bool {
+			return status.Status == statusComplete
+		})
+		a.Equal(common.Complete, common.GetStatus(status))
+		a.Equal(task.GID, status.Gid)
+		a.Equal("11", status.CompletedLength)
+		a.NoError(c.Cancel(task))
+	}
+}
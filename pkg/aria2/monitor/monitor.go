@@ -15,6 +15,8 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
@@ -26,6 +28,8 @@ type Monitor struct {
 
 	notifier <-chan mq.Message
 	node     cluster.Node
+	pool     cluster.Pool
+	mqClient mq.MQ
 	retried  int
 }
 
@@ -37,6 +41,8 @@ func NewMonitor(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
 		Task:     task,
 		notifier: make(chan mq.Message),
 		node:     pool.GetNodeByID(task.GetNodeID()),
+		pool:     pool,
+		mqClient: mqClient,
 	}
 
 	if monitor.node != nil {
@@ -44,7 +50,7 @@ func NewMonitor(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
 		go monitor.Loop(mqClient)
 
 		monitor.notifier = mqClient.Subscribe(monitor.Task.GID, 0)
-	} else {
+	} else if !monitor.requeue() {
 		monitor.setErrorStatus(errors.New("node not avaliable"))
 	}
 }
@@ -77,12 +83,17 @@ func (monitor *Monitor) Update() bool {
 
 	if err != nil {
 		monitor.retried++
+		common.Health.Fail(monitor.Task.GetNodeID())
 		util.Log().Warning("Cannot get status of download task %q: %s", monitor.Task.GID, err)
 
-		// 十次重试后认定为任务失败
+		// 十次重试后认定为任务失败，尚未开始转存的任务转移到其他节点重新下载
 		if monitor.retried > MAX_RETRY {
 			util.Log().Warning("Cannot get status of download task %q，exceed maximum retry threshold: %s",
 				monitor.Task.GID, err)
+			if monitor.requeue() {
+				return true
+			}
+
 			monitor.setErrorStatus(err)
 			monitor.RemoveTempFolder()
 			return true
@@ -91,6 +102,7 @@ func (monitor *Monitor) Update() bool {
 		return false
 	}
 	monitor.retried = 0
+	common.Health.Succeed(monitor.Task.GetNodeID())
 
 	// 磁力链下载需要跟随
 	if len(status.FollowedBy) > 0 {
@@ -162,6 +174,7 @@ func (monitor *Monitor) UpdateTaskInfo(status rpc.StatusInfo) error {
 	if err := monitor.Task.Save(); err != nil {
 		return err
 	}
+	monitor.publish()
 
 	if originSize != monitor.Task.TotalSize {
 		// 文件大小更新后，对文件限制等进行校验
@@ -190,6 +203,15 @@ func (monitor *Monitor) ValidateFile() error {
 	}
 	defer fs.Recycle()
 
+	// 按任务指定的存储策略校验
+	if monitor.Task.PolicyID != 0 {
+		policy, err := model.GetPolicyByID(monitor.Task.PolicyID)
+		if err != nil {
+			return err
+		}
+		fs.Policy = &policy
+	}
+
 	// 创建上下文环境
 	file := &fsctx.FileStream{
 		Size: monitor.Task.TotalSize,
@@ -207,7 +229,7 @@ func (monitor *Monitor) ValidateFile() error {
 			fileSize, _ := strconv.ParseUint(fileInfo.Length, 10, 64)
 			file := &fsctx.FileStream{
 				Size: fileSize,
-				Name: filepath.Base(fileInfo.Path),
+				Name: filesystem.NormalizeName(filepath.Base(fileInfo.Path)),
 			}
 			if err := filesystem.HookValidateFile(context.Background(), fs, file); err != nil {
 				return err
@@ -290,6 +312,7 @@ func (monitor *Monitor) transfer(pool task.Pool) bool {
 		true,
 		monitor.node.ID(),
 		sizes,
+		monitor.Task.PolicyID,
 	)
 	if err != nil {
 		monitor.setErrorStatus(err)
@@ -307,8 +330,71 @@ func (monitor *Monitor) transfer(pool task.Pool) bool {
 	return false
 }
 
+// requeue 将原节点不可用的任务转移到其他健康节点重新下载，返回是否转移成功
+func (monitor *Monitor) requeue() bool {
+	if monitor.pool == nil || monitor.Task.TaskID != 0 || monitor.Task.Source == "" {
+		return false
+	}
+
+	origin := monitor.Task.GetNodeID()
+	common.Health.MarkUnhealthy(origin)
+
+	lb := common.NewLoadBalancer(model.GetSettingByName("aria2_balancer"))
+	err, node := monitor.pool.BalanceNodeByFeature("aria2", lb)
+	if err != nil || node.ID() == origin {
+		return false
+	}
+
+	var groupOptions map[string]interface{}
+	if user := monitor.Task.GetOwner(); user != nil {
+		groupOptions = user.Group.OptionsSerialized.Aria2Options
+	}
+
+	gid, err := node.GetAria2Instance().CreateTask(monitor.Task, groupOptions)
+	if err != nil {
+		util.Log().Warning("Failed to requeue download task %q to node %d: %s", monitor.Task.GID, node.ID(), err)
+		return false
+	}
+
+	// 尽量清理原节点上的临时文件
+	if monitor.node != nil {
+		monitor.RemoveTempFolder()
+	}
+
+	util.Log().Info("Download task %q requeued from node %d to node %d as %q.", monitor.Task.GID, origin, node.ID(), gid)
+	monitor.Task.GID = gid
+	monitor.Task.NodeID = node.ID()
+	monitor.Task.Status = common.Ready
+	monitor.Task.Parent = ""
+	monitor.Task.TotalSize = 0
+	monitor.Task.DownloadedSize = 0
+	monitor.Task.Speed = 0
+	monitor.Task.Error = ""
+	monitor.Task.Attrs = ""
+	monitor.Task.StatusInfo = rpc.StatusInfo{}
+	if err := monitor.Task.Save(); err != nil {
+		util.Log().Warning("Failed to save requeued download task %q: %s", gid, err)
+		return false
+	}
+
+	NewMonitor(monitor.Task, monitor.pool, monitor.mqClient)
+	return true
+}
+
 func (monitor *Monitor) setErrorStatus(err error) {
 	monitor.Task.Status = common.Error
 	monitor.Task.Error = err.Error()
-	monitor.Task.Save()
+	if monitor.Task.Save() == nil {
+		monitor.publish()
+	}
+}
+
+// publish 向任务创建者已连接的客户端推送最新的下载状态
+func (monitor *Monitor) publish() {
+	if monitor.Task.UserID == 0 {
+		return
+	}
+
+	notification.Publish(monitor.Task.UserID, notification.EventDownload,
+		serializer.BuildDownloading(monitor.Task, int(monitor.Interval/time.Second)))
 }
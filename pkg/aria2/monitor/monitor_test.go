@@ -4,14 +4,18 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mocks"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	testMock "github.com/stretchr/testify/mock"
@@ -28,6 +32,9 @@ func TestMain(m *testing.M) {
 		panic("An error was not expected when opening a stub database connection")
 	}
 	model.DB, _ = gorm.Open("mysql", db)
+	cache.Set("setting_aria2_node_retry", "3", 0)
+	cache.Set("setting_aria2_recover_interval", "600", 0)
+	cache.Set("setting_aria2_balancer", "RoundRobin", 0)
 	defer db.Close()
 	m.Run()
 }
@@ -253,6 +260,42 @@ func TestMonitor_UpdateActive(t *testing.T) {
 	mockNode.AssertExpectations(t)
 }
 
+func TestMonitor_UpdatePublish(t *testing.T) {
+	a := assert.New(t)
+	mockAria2 := &mocks.Aria2Mock{}
+	mockAria2.On("Status", testMock.Anything).Return(rpc.StatusInfo{
+		Status:          "active",
+		TotalLength:     "10",
+		CompletedLength: "5",
+		Dir:             "/tmp/1",
+	}, nil)
+	mockNode := &mocks.NodeMock{}
+	mockNode.On("GetAria2Instance").Return(mockAria2)
+	m := &Monitor{
+		node:     mockNode,
+		Interval: 5 * time.Second,
+		Task:     &model.Download{Model: gorm.Model{ID: 1}, UserID: 1, TotalSize: 10},
+	}
+	events := mq.GlobalMQ.Subscribe(notification.Topic(1), 1)
+	defer mq.GlobalMQ.Unsubscribe(notification.Topic(1), events)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	a.False(m.Update())
+	a.NoError(mock.ExpectationsWereMet())
+	select {
+	case msg := <-events:
+		a.Equal(notification.EventDownload, msg.Event)
+		res := msg.Content.(serializer.DownloadListResponse)
+		a.EqualValues(5, res.Downloaded)
+		a.Equal(5, res.UpdateInterval)
+		a.Empty(res.Info.Dir)
+	case <-time.After(time.Second):
+		t.Fatal("download status not published")
+	}
+}
+
 func TestMonitor_UpdateRemoved(t *testing.T) {
 	a := assert.New(t)
 	mockAria2 := &mocks.Aria2Mock{}
@@ -384,6 +427,7 @@ func TestMonitor_ValidateFile(t *testing.T) {
 	{
 		m.Task.StatusInfo.Files = []rpc.FileInfo{
 			{
+				Path:     "/downloads/1.txt",
 				Length:   "100",
 				Selected: "true",
 			},
@@ -399,6 +443,15 @@ func TestMonitor_ValidateFile(t *testing.T) {
 		}
 		a.NoError(m.ValidateFile())
 	}
+
+	// single file too big for specified policy
+	{
+		m.Task.PolicyID = 536
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type", "max_size"}).AddRow(536, "local", 99))
+		a.Equal(filesystem.ErrFileSizeTooBig, m.ValidateFile())
+		a.NoError(mock.ExpectationsWereMet())
+		m.Task.PolicyID = 0
+	}
 }
 
 func TestMonitor_Complete(t *testing.T) {
@@ -445,3 +498,68 @@ func TestMonitor_Complete(t *testing.T) {
 	mockNode.AssertExpectations(t)
 	mockPool.AssertExpectations(t)
 }
+
+func TestMonitor_Requeue(t *testing.T) {
+	a := assert.New(t)
+	mockMQ := mq.NewMQ()
+	originAria2 := &mocks.Aria2Mock{}
+	originAria2.On("DeleteTempFile", testMock.Anything).Return(nil)
+	originNode := &mocks.NodeMock{}
+	originNode.On("ID").Return(uint(1))
+	originNode.On("GetAria2Instance").Return(originAria2)
+	newTask := func() *model.Download {
+		return &model.Download{
+			Model:  gorm.Model{ID: 1},
+			NodeID: 1,
+			GID:    "gid1",
+			Source: "http://example.com/a.zip",
+			Parent: "/temp/gid1",
+		}
+	}
+
+	// 未指定节点池或已开始转存
+	{
+		m := &Monitor{node: originNode, Task: newTask()}
+		a.False(m.requeue())
+		m.pool = &mocks.NodePoolMock{}
+		m.Task.TaskID = 1
+		a.False(m.requeue())
+	}
+
+	// 没有其他可用节点
+	{
+		mockPool := &mocks.NodePoolMock{}
+		mockPool.On("BalanceNodeByFeature", "aria2", testMock.Anything).Return(nil, originNode)
+		m := &Monitor{node: originNode, pool: mockPool, mqClient: mockMQ, Task: newTask()}
+		a.False(m.requeue())
+		a.False(common.Health.IsHealthy(1))
+		mockPool.AssertExpectations(t)
+	}
+
+	// 转移到其他节点
+	{
+		newAria2 := &mocks.Aria2Mock{}
+		newAria2.On("CreateTask", testMock.Anything, testMock.Anything).Return("gid2", nil)
+		newAria2.On("GetConfig").Return(model.Aria2Option{Interval: 100})
+		newAria2.On("Status", testMock.Anything).Return(rpc.StatusInfo{}, errors.New("error"))
+		newNode := &mocks.NodeMock{}
+		newNode.On("ID").Return(uint(2))
+		newNode.On("GetAria2Instance").Return(newAria2)
+		mockPool := &mocks.NodePoolMock{}
+		mockPool.On("BalanceNodeByFeature", "aria2", testMock.Anything).Return(nil, newNode)
+		mockPool.On("GetNodeByID", uint(2)).Return(newNode)
+		m := &Monitor{node: originNode, pool: mockPool, mqClient: mockMQ, Task: newTask()}
+
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)downloads(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.True(m.requeue())
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("gid2", m.Task.GID)
+		a.EqualValues(2, m.Task.NodeID)
+		a.Equal(common.Ready, m.Task.Status)
+		a.Empty(m.Task.Parent)
+		mockPool.AssertExpectations(t)
+	}
+}
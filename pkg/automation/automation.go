@@ -0,0 +1,294 @@
+package automation
+
+import (
+	"context"
+	"path"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Init 注册自动化规则使用的文件系统钩子
+func Init() {
+	filesystem.RegisterFileValidator(HookValidateUpload)
+	filesystem.RegisterChangeHook(HookChanges)
+}
+
+// Object 触发规则的文件
+type Object struct {
+	// File 文件模型，删除事件时为 nil
+	File *model.File
+	ID   uint
+	Name string
+	// Dir 文件所在目录
+	Dir  string
+	Size uint64
+}
+
+// job 待执行的规则
+type job struct {
+	event  string
+	change model.Change
+	rules  []model.Automation
+}
+
+// HookValidateUpload 上传前执行包含拒绝动作的上传规则
+func HookValidateUpload(ctx context.Context, fs *filesystem.FileSystem, file fsctx.FileHeader) error {
+	if fs.User == nil || fs.User.ID == 0 {
+		return nil
+	}
+
+	automations, err := model.GetEnabledAutomations(fs.User.GroupID)
+	if err != nil {
+		util.Log().Warning("Failed to list automations: %s", err)
+		return nil
+	}
+
+	fileInfo := file.Info()
+	for _, rule := range automations {
+		if rule.Event != model.AutomationEventUpload {
+			continue
+		}
+
+		reason, ok := hasReject(rule.ScriptSerialized.Actions)
+		if !ok || !Match(&rule.ScriptSerialized.Match, fileInfo.FileName, fileInfo.VirtualPath, fileInfo.Size) {
+			continue
+		}
+
+		if reason == "" {
+			reason = "File is rejected by automation rule"
+		}
+		return serializer.NewError(serializer.CodeRejectedByAutomation, reason, nil)
+	}
+
+	return nil
+}
+
+// HookChanges 根据用户发起的文件变更，在后台执行匹配的规则
+func HookChanges(ctx context.Context, fs *filesystem.FileSystem, changes []model.Change) {
+	if fs.User == nil || fs.User.ID == 0 {
+		return
+	}
+
+	automations, err := model.GetEnabledAutomations(fs.User.GroupID)
+	if err != nil {
+		util.Log().Warning("Failed to list automations: %s", err)
+		return
+	}
+
+	if len(automations) == 0 {
+		return
+	}
+
+	jobs := make([]job, 0, len(changes))
+	for _, change := range changes {
+		if change.ObjectType != model.ChangeObjectFile {
+			continue
+		}
+
+		event := changeEvent(change)
+		rules := make([]model.Automation, 0, len(automations))
+		for _, rule := range automations {
+			if rule.Event == event {
+				rules = append(rules, rule)
+			}
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+
+		// 上传会话创建的占位文件在上传完成后才触发规则
+		if change.Type == model.ChangeCreate {
+			files, err := model.GetFilesByIDs([]uint{change.ObjectID}, fs.User.ID)
+			if err != nil || len(files) == 0 || files[0].UploadSessionID != nil {
+				continue
+			}
+		}
+
+		jobs = append(jobs, job{event: event, change: change, rules: rules})
+	}
+
+	if len(jobs) == 0 {
+		return
+	}
+
+	user := *fs.User
+	go run(&user, jobs)
+}
+
+// changeEvent 返回变更对应的事件
+func changeEvent(change model.Change) string {
+	switch change.Type {
+	case model.ChangeCreate, model.ChangeModify:
+		return model.AutomationEventUpload
+	case model.ChangeMove:
+		return model.AutomationEventMove
+	case model.ChangeDelete:
+		return model.AutomationEventDelete
+	}
+
+	return ""
+}
+
+// run 依次执行规则。规则产生的变更不会再次触发规则
+func run(user *model.User, jobs []job) {
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		util.Log().Warning("Failed to create filesystem for automation: %s", err)
+		return
+	}
+	defer fs.Recycle()
+
+	ctx := context.Background()
+	for _, job := range jobs {
+		object, err := loadObject(user.ID, &job.change)
+		if err != nil {
+			util.Log().Debug("Skip automation for object %d: %s", job.change.ObjectID, err)
+			continue
+		}
+
+		for i := range job.rules {
+			rule := &job.rules[i]
+			if !Match(&rule.ScriptSerialized.Match, object.Name, object.Dir, object.Size) {
+				continue
+			}
+
+			executor := &Executor{FS: fs, Rule: rule, Event: job.event, Object: object}
+			stop, err := executor.Run(ctx, rule.ScriptSerialized.Actions, true)
+			if err != nil {
+				util.Log().Warning("Automation %q failed on %q: %s", rule.Name, path.Join(object.Dir, object.Name), err)
+			}
+
+			if stop {
+				break
+			}
+		}
+	}
+}
+
+// loadObject 读取变更对应的文件信息
+func loadObject(uid uint, change *model.Change) (*Object, error) {
+	object := &Object{ID: change.ObjectID, Name: change.Name, Size: change.Size}
+	if change.Type != model.ChangeDelete {
+		files, err := model.GetFilesByIDs([]uint{change.ObjectID}, uid)
+		if err != nil || len(files) == 0 {
+			return nil, filesystem.ErrObjectNotExist
+		}
+
+		object.File = &files[0]
+		object.Name, object.Size = object.File.Name, object.File.Size
+		change.ParentID = object.File.FolderID
+	}
+
+	dir, err := folderPath(change.ParentID, uid)
+	if err != nil && change.Type != model.ChangeDelete {
+		return nil, err
+	}
+
+	object.Dir = dir
+	return object, nil
+}
+
+// folderPath 返回目录的完整路径
+func folderPath(id, uid uint) (string, error) {
+	folders, err := model.GetFoldersByIDs([]uint{id}, uid)
+	if err != nil || len(folders) == 0 {
+		return "", filesystem.ErrPathNotExist
+	}
+
+	if err := folders[0].TraceRoot(); err != nil {
+		return "", err
+	}
+
+	return path.Join(folders[0].Position, folders[0].Name), nil
+}
+
+// Executor 规则动作执行器，只能操作触发规则的文件
+type Executor struct {
+	FS     *filesystem.FileSystem
+	Rule   *model.Automation
+	Event  string
+	Object *Object
+}
+
+// Run 依次执行动作，返回是否中止后续规则
+func (e *Executor) Run(ctx context.Context, actions []model.AutomationAction, allowHTTP bool) (bool, error) {
+	for _, action := range actions {
+		stop, err := e.apply(ctx, action, allowHTTP)
+		if err != nil || stop {
+			return stop, err
+		}
+	}
+
+	return false, nil
+}
+
+// apply 执行单个动作
+func (e *Executor) apply(ctx context.Context, action model.AutomationAction, allowHTTP bool) (bool, error) {
+	file := e.Object.File
+	if file == nil && action.Type != model.AutomationActionNotify && action.Type != model.AutomationActionHTTP {
+		return false, nil
+	}
+
+	switch action.Type {
+	case model.AutomationActionRename:
+		name := Render(action.Value, file.Name, time.Now())
+		if name == file.Name {
+			return false, nil
+		}
+
+		if err := e.FS.Rename(ctx, nil, []uint{file.ID}, name); err != nil {
+			return false, err
+		}
+		file.Name, e.Object.Name = name, name
+	case model.AutomationActionMove:
+		dst := path.Clean(action.Value)
+		if dst == e.Object.Dir {
+			return false, nil
+		}
+
+		if _, err := e.FS.CreateDirectory(ctx, dst); err != nil {
+			return false, err
+		}
+
+		if err := e.FS.Move(ctx, nil, []uint{file.ID}, e.Object.Dir, dst); err != nil {
+			return false, err
+		}
+		e.Object.Dir = dst
+	case model.AutomationActionTag:
+		tags := mergeTags(file.MetadataSerialized[model.TagsMetadataKey], action.Value)
+		if err := file.UpdateMetadata(map[string]string{model.TagsMetadataKey: tags}); err != nil {
+			return false, err
+		}
+	case model.AutomationActionNotify:
+		message := Render(action.Value, e.Object.Name, time.Now())
+		title, body := email.NewAutomationEmail(e.FS.User.Nick, e.Rule.Name, path.Join(e.Object.Dir, e.Object.Name), message)
+		if err := email.Send(e.FS.User.Email, title, body); err != nil {
+			return false, err
+		}
+	case model.AutomationActionReject:
+		if err := e.FS.Delete(ctx, nil, []uint{file.ID}, false, false); err != nil {
+			return false, err
+		}
+		return true, nil
+	case model.AutomationActionHTTP:
+		if !allowHTTP {
+			return false, nil
+		}
+
+		actions, err := e.callHTTP(ctx, action)
+		if err != nil {
+			return false, err
+		}
+
+		return e.Run(ctx, actions, false)
+	}
+
+	return false, nil
+}
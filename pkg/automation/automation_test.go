@@ -0,0 +1,231 @@
+package automation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestValidate(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 正常
+	{
+		script := &model.AutomationScript{
+			Match: model.AutomationMatch{Name: "*.pdf", Path: "/Incoming*"},
+			Actions: []model.AutomationAction{
+				{Type: model.AutomationActionMove, Value: "/Finance"},
+				{Type: model.AutomationActionTag, Value: "invoice"},
+				{Type: model.AutomationActionHTTP, URL: "https://hooks.example.com/invoice"},
+			},
+		}
+		asserts.NoError(Validate(model.AutomationEventUpload, script, true))
+		asserts.Error(Validate(model.AutomationEventUpload, script, false))
+	}
+
+	// 未知事件
+	asserts.Error(Validate("rename", &model.AutomationScript{}, true))
+
+	// 删除事件不能操作文件
+	asserts.Error(Validate(model.AutomationEventDelete, &model.AutomationScript{
+		Actions: []model.AutomationAction{{Type: model.AutomationActionMove, Value: "/Trash"}},
+	}, true))
+
+	// 仅上传事件可拒绝
+	asserts.Error(Validate(model.AutomationEventMove, &model.AutomationScript{
+		Actions: []model.AutomationAction{{Type: model.AutomationActionReject}},
+	}, true))
+
+	// 参数不合法
+	for _, action := range []model.AutomationAction{
+		{Type: model.AutomationActionRename},
+		{Type: model.AutomationActionMove, Value: "Finance"},
+		{Type: model.AutomationActionHTTP, URL: "ftp://example.com"},
+		{Type: "exec", Value: "rm -rf /"},
+	} {
+		asserts.Error(Validate(model.AutomationEventUpload, &model.AutomationScript{
+			Actions: []model.AutomationAction{action},
+		}, true), action.Type)
+	}
+
+	// 匹配条件不合法
+	asserts.Error(Validate(model.AutomationEventUpload, &model.AutomationScript{
+		Match: model.AutomationMatch{Name: "[pdf"},
+	}, true))
+	asserts.Error(Validate(model.AutomationEventUpload, &model.AutomationScript{
+		Match: model.AutomationMatch{MinSize: 10, MaxSize: 5},
+	}, true))
+}
+
+func TestMatch(t *testing.T) {
+	asserts := assert.New(t)
+	match := &model.AutomationMatch{Name: "*.pdf", Path: "/incoming*", MinSize: 10, MaxSize: 100}
+
+	asserts.True(Match(match, "Invoice.PDF", "/Incoming/2023", 50))
+	asserts.False(Match(match, "invoice.docx", "/Incoming", 50))
+	asserts.True(Match(match, "invoice.pdf", "/incoming-2023", 50))
+	asserts.False(Match(match, "invoice.pdf", "/Finance", 50))
+	asserts.False(Match(match, "invoice.pdf", "/Finance/Incoming", 50))
+	asserts.False(Match(match, "invoice.pdf", "/Incoming", 5))
+	asserts.False(Match(match, "invoice.pdf", "/Incoming", 500))
+
+	// 无条件
+	asserts.True(Match(&model.AutomationMatch{}, "any", "/", 0))
+}
+
+func TestRender(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Date(2023, 3, 5, 8, 9, 10, 0, time.UTC)
+
+	asserts.Equal("20230305_invoice.pdf", Render("{date}_{name}", "invoice.pdf", now))
+	asserts.Equal("invoice-080910.pdf", Render("{name_without_ext}-{time}{ext}", "invoice.pdf", now))
+	asserts.Equal("2023/03/05", Render("{year}/{month}/{day}", "invoice.pdf", now))
+}
+
+func TestMergeTags(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("invoice", mergeTags("", "invoice"))
+	asserts.Equal("invoice,finance", mergeTags("invoice", " finance, invoice"))
+}
+
+func TestHookValidateUpload(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &filesystem.FileSystem{User: &model.User{}}
+	fs.User.ID = 1
+	fs.User.GroupID = 2
+	file := &fsctx.FileStream{Name: "setup.exe", VirtualPath: "/Downloads", Size: 10}
+	rules := sqlmock.NewRows([]string{"id", "event", "script"}).
+		AddRow(1, model.AutomationEventUpload, `{"match":{"name":"*.pdf"},"actions":[{"type":"reject"}]}`).
+		AddRow(2, model.AutomationEventMove, `{"match":{"name":"*.exe"},"actions":[{"type":"notify","value":"moved"}]}`).
+		AddRow(3, model.AutomationEventUpload, `{"match":{"name":"*.exe"},"actions":[{"type":"reject","value":"Executables are not allowed"}]}`)
+
+	// 被拒绝
+	{
+		mock.ExpectQuery("SELECT(.+)automations(.+)").WillReturnRows(rules)
+		err := HookValidateUpload(context.Background(), fs, file)
+		asserts.NoError(mock.ExpectationsWereMet())
+		var appErr serializer.AppError
+		asserts.True(errors.As(err, &appErr))
+		asserts.Equal(serializer.CodeRejectedByAutomation, appErr.Code)
+		asserts.Equal("Executables are not allowed", appErr.Msg)
+	}
+
+	// 未匹配
+	{
+		mock.ExpectQuery("SELECT(.+)automations(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "event", "script"}).
+			AddRow(1, model.AutomationEventUpload, `{"match":{"name":"*.pdf"},"actions":[{"type":"reject"}]}`))
+		asserts.NoError(HookValidateUpload(context.Background(), fs, file))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 读取规则失败时放行
+	{
+		mock.ExpectQuery("SELECT(.+)automations(.+)").WillReturnError(errors.New("error"))
+		asserts.NoError(HookValidateUpload(context.Background(), fs, file))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 匿名用户
+	asserts.NoError(HookValidateUpload(context.Background(), &filesystem.FileSystem{User: &model.User{}}, file))
+}
+
+func TestChangeEvent(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal(model.AutomationEventUpload, changeEvent(model.Change{Type: model.ChangeCreate}))
+	asserts.Equal(model.AutomationEventUpload, changeEvent(model.Change{Type: model.ChangeModify}))
+	asserts.Equal(model.AutomationEventMove, changeEvent(model.Change{Type: model.ChangeMove}))
+	asserts.Equal(model.AutomationEventDelete, changeEvent(model.Change{Type: model.ChangeDelete}))
+}
+
+func TestExecutor_CallHTTP(t *testing.T) {
+	asserts := assert.New(t)
+	var (
+		payload   Payload
+		signature string
+		reply     string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		_ = json.Unmarshal(body, &payload)
+		asserts.Equal(Sign("secret", body), signature)
+		w.Write([]byte(reply))
+	}))
+	defer server.Close()
+
+	fs := &filesystem.FileSystem{User: &model.User{Email: "user@cloudreve.org"}}
+	fs.User.ID = 1
+	executor := &Executor{
+		FS:     fs,
+		Rule:   &model.Automation{Name: "invoices"},
+		Event:  model.AutomationEventUpload,
+		Object: &Object{ID: 2, Name: "invoice.pdf", Dir: "/Incoming", Size: 10},
+	}
+	action := model.AutomationAction{Type: model.AutomationActionHTTP, URL: server.URL, Secret: "secret"}
+
+	// 返回后续动作
+	{
+		reply = `{"actions":[{"type":"tag","value":"invoice"}]}`
+		actions, err := executor.callHTTP(context.Background(), action)
+		asserts.NoError(err)
+		asserts.Equal([]model.AutomationAction{{Type: model.AutomationActionTag, Value: "invoice"}}, actions)
+		asserts.Equal("upload", payload.Event)
+		asserts.Equal("invoices", payload.Automation)
+		asserts.Equal("invoice.pdf", payload.Object.Name)
+		asserts.Equal("/Incoming", payload.Object.Dir)
+		asserts.Equal("user@cloudreve.org", payload.User.Email)
+	}
+
+	// 空响应
+	{
+		reply = ""
+		actions, err := executor.callHTTP(context.Background(), action)
+		asserts.NoError(err)
+		asserts.Empty(actions)
+	}
+
+	// 不允许嵌套 HTTP 动作
+	{
+		reply = `{"actions":[{"type":"http","url":"https://example.com"}]}`
+		_, err := executor.callHTTP(context.Background(), action)
+		asserts.Error(err)
+	}
+
+	// 响应格式错误
+	{
+		reply = `not json`
+		_, err := executor.callHTTP(context.Background(), action)
+		asserts.Error(err)
+	}
+}
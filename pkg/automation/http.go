@@ -0,0 +1,115 @@
+package automation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// httpTimeout HTTP 动作的请求超时时间
+const httpTimeout = 10 * time.Second
+
+// SignatureHeader HTTP 动作请求的签名头，值为请求正文的 HMAC-SHA256
+const SignatureHeader = auth.CrHeaderPrefix + "Automation-Signature"
+
+// client 发送 HTTP 动作请求的客户端
+var client = request.NewClient()
+
+// Payload HTTP 动作发送的事件信息
+type Payload struct {
+	Event      string        `json:"event"`
+	Automation string        `json:"automation"`
+	User       PayloadUser   `json:"user"`
+	Object     PayloadObject `json:"object"`
+	Time       time.Time     `json:"time"`
+}
+
+// PayloadUser 触发事件的用户
+type PayloadUser struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Nick  string `json:"nick"`
+}
+
+// PayloadObject 触发事件的文件
+type PayloadObject struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Dir  string `json:"dir"`
+	Size uint64 `json:"size"`
+}
+
+// Reply HTTP 动作的响应，可返回需要继续执行的动作，不能再包含 HTTP 动作
+type Reply struct {
+	Actions []model.AutomationAction `json:"actions"`
+}
+
+// Sign 计算请求正文签名
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// callHTTP 将事件发送至 HTTP 动作地址，返回响应中的后续动作
+func (e *Executor) callHTTP(ctx context.Context, action model.AutomationAction) ([]model.AutomationAction, error) {
+	body, err := json.Marshal(&Payload{
+		Event:      e.Event,
+		Automation: e.Rule.Name,
+		User: PayloadUser{
+			ID:    hashid.HashID(e.FS.User.ID, hashid.UserID),
+			Email: e.FS.User.Email,
+			Nick:  e.FS.User.Nick,
+		},
+		Object: PayloadObject{
+			ID:   hashid.HashID(e.Object.ID, hashid.FileID),
+			Name: e.Object.Name,
+			Dir:  e.Object.Dir,
+			Size: e.Object.Size,
+		},
+		Time: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{"Content-Type": {"application/json"}}
+	if action.Secret != "" {
+		header.Set(SignatureHeader, Sign(action.Secret, body))
+	}
+
+	res, err := client.Request("POST", action.URL, bytes.NewReader(body),
+		request.WithContext(ctx),
+		request.WithTimeout(httpTimeout),
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(body))),
+	).CheckHTTPResponse(http.StatusOK).GetResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	var reply Reply
+	if res == "" {
+		return reply.Actions, nil
+	}
+
+	if err := json.Unmarshal([]byte(res), &reply); err != nil {
+		return nil, err
+	}
+
+	if err := validateActions(e.Event, eventActions[e.Event], reply.Actions, false); err != nil {
+		return nil, err
+	}
+
+	return reply.Actions, nil
+}
@@ -0,0 +1,155 @@
+package automation
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// eventActions 各事件可使用的动作
+var eventActions = map[string][]string{
+	model.AutomationEventUpload: {
+		model.AutomationActionRename, model.AutomationActionMove, model.AutomationActionTag,
+		model.AutomationActionNotify, model.AutomationActionReject, model.AutomationActionHTTP,
+	},
+	model.AutomationEventMove: {
+		model.AutomationActionRename, model.AutomationActionMove, model.AutomationActionTag,
+		model.AutomationActionNotify, model.AutomationActionHTTP,
+	},
+	model.AutomationEventDelete: {
+		model.AutomationActionNotify, model.AutomationActionHTTP,
+	},
+}
+
+// Validate 检查规则定义是否合法。allowHTTP 为 false 时不允许 HTTP 动作，
+// 用于校验 HTTP 动作返回的后续动作
+func Validate(event string, script *model.AutomationScript, allowHTTP bool) error {
+	allowed, ok := eventActions[event]
+	if !ok {
+		return fmt.Errorf("unknown event %q", event)
+	}
+
+	if script.Match.Name != "" {
+		if _, err := path.Match(script.Match.Name, ""); err != nil {
+			return fmt.Errorf("invalid name pattern: %w", err)
+		}
+	}
+
+	if script.Match.Path != "" {
+		if _, err := path.Match(script.Match.Path, ""); err != nil {
+			return fmt.Errorf("invalid path pattern: %w", err)
+		}
+	}
+
+	if script.Match.MaxSize > 0 && script.Match.MaxSize < script.Match.MinSize {
+		return errors.New("max_size must not be less than min_size")
+	}
+
+	return validateActions(event, allowed, script.Actions, allowHTTP)
+}
+
+// validateActions 检查动作列表是否合法
+func validateActions(event string, allowed []string, actions []model.AutomationAction, allowHTTP bool) error {
+	for i, action := range actions {
+		if !util.ContainsString(allowed, action.Type) || (!allowHTTP && action.Type == model.AutomationActionHTTP) {
+			return fmt.Errorf("action #%d: %q is not available for event %q", i, action.Type, event)
+		}
+
+		switch action.Type {
+		case model.AutomationActionRename, model.AutomationActionTag, model.AutomationActionNotify:
+			if strings.TrimSpace(action.Value) == "" {
+				return fmt.Errorf("action #%d: value is required", i)
+			}
+		case model.AutomationActionMove:
+			if !path.IsAbs(action.Value) {
+				return fmt.Errorf("action #%d: destination must be an absolute path", i)
+			}
+		case model.AutomationActionHTTP:
+			target, err := url.Parse(action.URL)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+				return fmt.Errorf("action #%d: invalid url", i)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Match 判断文件是否满足匹配条件，dir 为文件所在目录
+func Match(match *model.AutomationMatch, name, dir string, size uint64) bool {
+	if match.Name != "" {
+		if ok, _ := path.Match(strings.ToLower(match.Name), strings.ToLower(name)); !ok {
+			return false
+		}
+	}
+
+	if match.Path != "" && !matchDir(strings.ToLower(match.Path), strings.ToLower(dir)) {
+		return false
+	}
+
+	if size < match.MinSize || (match.MaxSize > 0 && size > match.MaxSize) {
+		return false
+	}
+
+	return true
+}
+
+// matchDir 目录或其任一上级目录是否匹配通配符
+func matchDir(pattern, dir string) bool {
+	for {
+		if ok, _ := path.Match(pattern, dir); ok {
+			return true
+		}
+
+		if dir == "/" || dir == "." || dir == "" {
+			return false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// Render 替换模板中的文件信息变量
+func Render(template, name string, now time.Time) string {
+	ext := filepath.Ext(name)
+	return util.Replace(map[string]string{
+		"{name}":             name,
+		"{name_without_ext}": strings.TrimSuffix(name, ext),
+		"{ext}":              ext,
+		"{date}":             now.Format("20060102"),
+		"{time}":             now.Format("150405"),
+		"{year}":             now.Format("2006"),
+		"{month}":            now.Format("01"),
+		"{day}":              now.Format("02"),
+	}, template)
+}
+
+// hasReject 动作列表中是否包含拒绝动作，返回拒绝原因
+func hasReject(actions []model.AutomationAction) (string, bool) {
+	for _, action := range actions {
+		if action.Type == model.AutomationActionReject {
+			return action.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// mergeTags 将标签合并入已有的标签列表
+func mergeTags(existing, tags string) string {
+	res := make([]string, 0)
+	for _, tag := range strings.Split(existing+","+tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !util.ContainsString(res, tag) {
+			res = append(res, tag)
+		}
+	}
+
+	return strings.Join(res, ",")
+}
@@ -9,6 +9,8 @@ func NewBalancer(strategy string) Balancer {
 	switch strategy {
 	case "RoundRobin":
 		return &RoundRobin{}
+	case "LeastActive":
+		return &LeastActive{}
 	default:
 		return &RoundRobin{}
 	}
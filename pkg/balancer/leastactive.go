@@ -0,0 +1,46 @@
+package balancer
+
+import (
+	"reflect"
+)
+
+// LeastActive 选择活跃任务数最少的节点，任务数相同时按轮询顺序选择
+type LeastActive struct {
+	// Load 返回节点当前的活跃任务数，为空时退化为轮询
+	Load func(peer interface{}) int
+
+	rr RoundRobin
+}
+
+// NewLeastActive 使用给定的负载统计方法新建最少活跃任务负载均衡器
+func NewLeastActive(load func(peer interface{}) int) *LeastActive {
+	return &LeastActive{Load: load}
+}
+
+// NextPeer 返回活跃任务数最少的节点
+func (l *LeastActive) NextPeer(nodes interface{}) (error, interface{}) {
+	v := reflect.ValueOf(nodes)
+	if v.Kind() != reflect.Slice {
+		return ErrInputNotSlice, nil
+	}
+	if v.Len() == 0 {
+		return ErrNoAvaliableNode, nil
+	}
+
+	// 从轮询位置开始查找，避免任务数相同时总是选中第一个节点
+	start := l.rr.NextIndex(v.Len())
+	if l.Load == nil {
+		return nil, v.Index(start).Interface()
+	}
+
+	best, bestLoad := start, -1
+	for i := 0; i < v.Len(); i++ {
+		index := (start + i) % v.Len()
+		load := l.Load(v.Index(index).Interface())
+		if bestLoad < 0 || load < bestLoad {
+			best, bestLoad = index, load
+		}
+	}
+
+	return nil, v.Index(best).Interface()
+}
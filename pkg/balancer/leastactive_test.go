@@ -0,0 +1,49 @@
+package balancer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeastActive_NextPeer(t *testing.T) {
+	a := assert.New(t)
+	loads := map[string]int{"a": 3, "b": 1, "c": 1}
+	l := NewLeastActive(func(peer interface{}) int {
+		return loads[peer.(string)]
+	})
+
+	// not slice
+	{
+		err, _ := l.NextPeer("s")
+		a.Equal(ErrInputNotSlice, err)
+	}
+
+	// no nodes
+	{
+		err, _ := l.NextPeer([]string{})
+		a.Equal(ErrNoAvaliableNode, err)
+	}
+
+	// 任务数相同的节点轮流选中
+	{
+		nodes := []string{"a", "b", "c"}
+		err, res := l.NextPeer(nodes)
+		a.NoError(err)
+		a.Equal("b", res.(string))
+		err, res = l.NextPeer(nodes)
+		a.NoError(err)
+		a.Equal("c", res.(string))
+		err, res = l.NextPeer(nodes)
+		a.NoError(err)
+		a.Equal("b", res.(string))
+	}
+
+	// 未指定负载统计方法时退化为轮询
+	{
+		l := &LeastActive{}
+		err, res := l.NextPeer([]string{"a", "b"})
+		a.NoError(err)
+		a.Equal("b", res.(string))
+	}
+}
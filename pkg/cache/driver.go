@@ -16,6 +16,10 @@ var Store Driver = NewMemoStore()
 
 // Init 初始化缓存
 func Init() {
+	if conf.SystemConfig.ClusterMode && conf.RedisConfig.Server == "" {
+		util.Log().Panic("Cluster mode requires Redis to share state between instances.")
+	}
+
 	if conf.RedisConfig.Server != "" && gin.Mode() != gin.TestMode {
 		Store = NewRedisStore(
 			10,
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -58,6 +59,13 @@ func TestInit(t *testing.T) {
 	asserts.NotPanics(func() {
 		Init()
 	})
+
+	// 集群模式未配置 Redis
+	conf.SystemConfig.ClusterMode = true
+	defer func() { conf.SystemConfig.ClusterMode = false }()
+	asserts.Panics(func() {
+		Init()
+	})
 }
 
 func TestInitSlaveOverwrites(t *testing.T) {
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// LockPrefix 分布式锁的键前缀
+const LockPrefix = "lock_"
+
+// Locker 支持互斥锁的缓存存储容器，多个实例共享同一 Redis 时可用于跨实例互斥
+type Locker interface {
+	// 在键不存在时以 token 加锁，ttl为过期时间，单位为秒；返回是否加锁成功
+	Lock(key, token string, ttl int) (bool, error)
+
+	// 仅当锁仍由 token 持有时解锁
+	Unlock(key, token string) error
+}
+
+// TryLock 尝试获取名为 name 的锁，成功时返回用于解锁的令牌。缓存存储容器不支持
+// 加锁时总是成功
+func TryLock(name string, ttl int) (string, bool) {
+	locker, ok := Store.(Locker)
+	if !ok {
+		return "", true
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false
+	}
+
+	token := hex.EncodeToString(buf)
+	locked, err := locker.Lock(LockPrefix+name, token, ttl)
+	if err != nil || !locked {
+		return "", false
+	}
+
+	return token, true
+}
+
+// Unlock 释放 TryLock 获取的锁
+func Unlock(name, token string) error {
+	if locker, ok := Store.(Locker); ok {
+		return locker.Unlock(LockPrefix+name, token)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoStore_Lock(t *testing.T) {
+	asserts := assert.New(t)
+	store := NewMemoStore()
+
+	locked, err := store.Lock("lock", "token1", 10)
+	asserts.NoError(err)
+	asserts.True(locked)
+
+	// 已被持有
+	locked, err = store.Lock("lock", "token2", 10)
+	asserts.NoError(err)
+	asserts.False(locked)
+
+	// 令牌不匹配，不解锁
+	asserts.NoError(store.Unlock("lock", "token2"))
+	_, ok := store.Get("lock")
+	asserts.True(ok)
+
+	asserts.NoError(store.Unlock("lock", "token1"))
+	locked, _ = store.Lock("lock", "token2", 10)
+	asserts.True(locked)
+
+	// 锁已过期
+	store.Store.Store("expired", itemWithTTL{Value: "token1", Expires: 1})
+	locked, _ = store.Lock("expired", "token2", 10)
+	asserts.True(locked)
+}
+
+func TestTryLock(t *testing.T) {
+	asserts := assert.New(t)
+	origin := Store
+	defer func() { Store = origin }()
+	Store = NewMemoStore()
+
+	token, ok := TryLock("job", 10)
+	asserts.True(ok)
+	asserts.NotEmpty(token)
+
+	_, ok = TryLock("job", 10)
+	asserts.False(ok)
+
+	asserts.NoError(Unlock("job", token))
+	_, ok = TryLock("job", 10)
+	asserts.True(ok)
+}
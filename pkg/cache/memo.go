@@ -13,6 +13,9 @@ import (
 // MemoStore 内存存储驱动
 type MemoStore struct {
 	Store *sync.Map
+
+	// lockMu 保证加解锁时检查与写入的原子性
+	lockMu sync.Mutex
 }
 
 // item 存储的对象
@@ -116,6 +119,31 @@ func (store *MemoStore) Delete(keys []string, prefix string) error {
 	return nil
 }
 
+// Lock 以 token 加锁
+func (store *MemoStore) Lock(key, token string, ttl int) (bool, error) {
+	store.lockMu.Lock()
+	defer store.lockMu.Unlock()
+
+	if _, ok := getValue(store.Store.Load(key)); ok {
+		return false, nil
+	}
+
+	store.Store.Store(key, newItem(token, ttl))
+	return true, nil
+}
+
+// Unlock 解锁
+func (store *MemoStore) Unlock(key, token string) error {
+	store.lockMu.Lock()
+	defer store.lockMu.Unlock()
+
+	if value, ok := getValue(store.Store.Load(key)); ok && value == token {
+		store.Store.Delete(key)
+	}
+
+	return nil
+}
+
 // Persist write memory store into cache
 func (store *MemoStore) Persist(path string) error {
 	persisted := make(map[string]itemWithTTL)
@@ -75,6 +75,11 @@ func NewRedisStore(size int, network, address, user, password, database string)
 	}
 }
 
+// Pool 返回底层的 Redis 连接池
+func (store *RedisStore) Pool() *redis.Pool {
+	return store.pool
+}
+
 // Set 存储值
 func (store *RedisStore) Set(key string, value interface{}, ttl int) error {
 	rc := store.pool.Get()
@@ -216,6 +221,37 @@ func (store *RedisStore) DeleteAll() error {
 	return err
 }
 
+// unlockScript 仅当锁的值与令牌一致时删除锁
+var unlockScript = redis.NewScript(1, `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`)
+
+// Lock 以 token 加锁
+func (store *RedisStore) Lock(key, token string, ttl int) (bool, error) {
+	rc := store.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return false, rc.Err()
+	}
+
+	_, err := redis.String(rc.Do("SET", key, token, "NX", "EX", ttl))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Unlock 解锁
+func (store *RedisStore) Unlock(key, token string) error {
+	rc := store.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return rc.Err()
+	}
+
+	_, err := unlockScript.Do(rc, key, token)
+	return err
+}
+
 // Persist Dummy implementation
 func (store *RedisStore) Persist(path string) error {
 	return nil
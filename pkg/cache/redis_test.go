@@ -322,3 +322,40 @@ func TestRedisStore_Delete(t *testing.T) {
 		asserts.Error(err)
 	}
 }
+
+func TestRedisStore_Lock(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	store := &RedisStore{pool: pool}
+
+	// 加锁成功
+	{
+		cmd := conn.Command("SET", "lock", "token", "NX", "EX", 10).Expect("OK")
+		locked, err := store.Lock("lock", "token", 10)
+		asserts.NoError(err)
+		asserts.True(locked)
+		asserts.Equal(1, conn.Stats(cmd))
+	}
+
+	// 已被持有
+	{
+		conn.Clear()
+		conn.Command("SET", "lock", "token", "NX", "EX", 10).Expect(nil)
+		locked, err := store.Lock("lock", "token", 10)
+		asserts.NoError(err)
+		asserts.False(locked)
+	}
+
+	// 命令执行失败
+	{
+		conn.Clear()
+		conn.Command("SET", "lock", "token", "NX", "EX", 10).ExpectError(errors.New("error"))
+		locked, err := store.Lock("lock", "token", 10)
+		asserts.Error(err)
+		asserts.False(locked)
+	}
+}
@@ -6,7 +6,8 @@ import (
 )
 
 var (
-	ErrFeatureNotExist = errors.New("No nodes in nodepool match the feature specificed")
-	ErrIlegalPath      = errors.New("path out of boundary of setting temp folder")
-	ErrMasterNotFound  = serializer.NewError(serializer.CodeMasterNotFound, "Unknown master node id", nil)
+	ErrFeatureNotExist  = errors.New("No nodes in nodepool match the feature specificed")
+	ErrIlegalPath       = errors.New("path out of boundary of setting temp folder")
+	ErrMasterNotFound   = serializer.NewError(serializer.CodeMasterNotFound, "Unknown master node id", nil)
+	ErrSeedNotSupported = errors.New("seeding is not supported on slave node")
 )
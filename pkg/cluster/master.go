@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/embedded"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
@@ -28,6 +29,7 @@ const (
 type MasterNode struct {
 	Model    *model.Node
 	aria2RPC rpcService
+	embedded *embedded.Client
 	lock     sync.RWMutex
 }
 
@@ -57,13 +59,39 @@ func (node *MasterNode) Init(nodeModel *model.Node) {
 
 	node.lock.RLock()
 	if node.Model.Aria2Enabled {
+		backend := node.Model.Aria2OptionsSerialized.Backend
 		node.lock.RUnlock()
+		if backend == common.BackendEmbedded {
+			node.initEmbedded()
+			return
+		}
+
 		node.aria2RPC.Init()
 		return
 	}
 	node.lock.RUnlock()
 }
 
+// initEmbedded 启动内置下载器，已启动时先关闭原有下载器
+func (node *MasterNode) initEmbedded() error {
+	node.lock.Lock()
+	defer node.lock.Unlock()
+
+	if node.embedded != nil {
+		node.embedded.Close()
+		node.embedded = nil
+	}
+
+	client := embedded.New(node.Model.Aria2OptionsSerialized)
+	if err := client.Init(); err != nil {
+		util.Log().Warning("Failed to initialize embedded downloader: %s", err)
+		return err
+	}
+
+	node.embedded = client
+	return nil
+}
+
 func (node *MasterNode) ID() uint {
 	node.lock.RLock()
 	defer node.lock.RUnlock()
@@ -116,6 +144,13 @@ func (node *MasterNode) Kill() {
 	if node.aria2RPC.Caller != nil {
 		node.aria2RPC.Caller.Close()
 	}
+
+	node.lock.Lock()
+	defer node.lock.Unlock()
+	if node.embedded != nil {
+		node.embedded.Close()
+		node.embedded = nil
+	}
 }
 
 // GetAria2Instance 获取主机Aria2实例
@@ -127,6 +162,17 @@ func (node *MasterNode) GetAria2Instance() common.Aria2 {
 		return &common.DummyAria2{}
 	}
 
+	if node.Model.Aria2OptionsSerialized.Backend == common.BackendEmbedded {
+		if node.embedded == nil {
+			node.lock.RUnlock()
+			node.initEmbedded()
+			return &common.DummyAria2{}
+		}
+
+		defer node.lock.RUnlock()
+		return node.embedded
+	}
+
 	if !node.aria2RPC.Initialized {
 		node.lock.RUnlock()
 		node.aria2RPC.Init()
@@ -217,6 +263,19 @@ func (r *rpcService) CreateTask(task *model.Download, groupOptions map[string]in
 	return gid, nil
 }
 
+func (r *rpcService) Seed(torrent, dir string) (string, error) {
+	// 开启完整性校验，使 aria2 直接识别已存在的文件并开始做种
+	options := make(map[string]interface{}, len(r.options.Options)+3)
+	for k, v := range r.options.Options {
+		options[k] = v
+	}
+	options["dir"] = dir
+	options["check-integrity"] = "true"
+	options["seed-ratio"] = "0.0"
+
+	return r.Caller.AddTorrent(torrent, options)
+}
+
 func (r *rpcService) Status(task *model.Download) (rpc.StatusInfo, error) {
 	res, err := r.Caller.TellStatus(task.GID)
 	if err != nil {
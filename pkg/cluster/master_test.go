@@ -3,6 +3,8 @@ package cluster
 import (
 	"context"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/embedded"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -89,6 +91,30 @@ func TestMasterNode_GetAria2Instance(t *testing.T) {
 	a.NotNil(m.GetAria2Instance())
 }
 
+func TestMasterNode_GetAria2Instance_Embedded(t *testing.T) {
+	a := assert.New(t)
+	m := &MasterNode{}
+	m.Init(&model.Node{
+		Aria2Enabled: true,
+		Aria2OptionsSerialized: model.Aria2Option{
+			Backend:    common.BackendEmbedded,
+			TempPath:   t.TempDir(),
+			ListenPort: -1,
+		},
+	})
+
+	// 无法启动内置下载器
+	a.Nil(m.embedded)
+	_, ok := m.GetAria2Instance().(*common.DummyAria2)
+	a.True(ok)
+
+	// 已启动
+	m.embedded = embedded.New(m.Model.Aria2OptionsSerialized)
+	a.Equal(m.embedded, m.GetAria2Instance())
+	m.Kill()
+	a.Nil(m.embedded)
+}
+
 func TestRpcService_Init(t *testing.T) {
 	a := assert.New(t)
 	m := &MasterNode{
@@ -384,6 +384,10 @@ func (s *slaveCaller) GetConfig() model.Aria2Option {
 	return s.parent.Model.Aria2OptionsSerialized
 }
 
+func (s *slaveCaller) Seed(torrent, dir string) (string, error) {
+	return "", ErrSeedNotSupported
+}
+
 func (s *slaveCaller) DeleteTempFile(task *model.Download) error {
 	s.parent.lock.RLock()
 	defer s.parent.lock.RUnlock()
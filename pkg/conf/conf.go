@@ -18,6 +18,10 @@ type database struct {
 	Port        int
 	Charset     string
 	UnixSocket  bool
+	// SSLMode PostgreSQL 连接的 sslmode，默认为 disable
+	SSLMode string
+	// AutoMigrate 启动时自动执行待执行的数据库迁移，全新安装时总是执行
+	AutoMigrate bool
 }
 
 // system 系统通用配置
@@ -29,6 +33,14 @@ type system struct {
 	HashIDSalt    string
 	GracePeriod   int    `validate:"gte=0"`
 	ProxyHeader   string `validate:"required_with=Listen"`
+	// ReusePort 监听时设置 SO_REUSEPORT，允许新旧进程同时监听同一端口
+	ReusePort bool
+	// ClusterMode 集群模式，多个主机实例共享 Redis 及数据库，部署在负载均衡之后
+	ClusterMode bool
+	// InstanceID 集群中本实例的唯一标识，留空时使用主机名
+	InstanceID string
+	// InternalURL 集群中其他实例访问本实例的地址，用于转发本实例持有的上传会话
+	InternalURL string `validate:"required_if=ClusterMode true"`
 }
 
 type ssl struct {
@@ -42,6 +54,36 @@ type unix struct {
 	Perm   uint32
 }
 
+// s3 独立监听的S3兼容接口配置
+type s3 struct {
+	Listen string
+}
+
+// sftp 内置SFTP服务配置
+type sftp struct {
+	Listen  string
+	HostKey string
+}
+
+// ftp 内置FTP服务配置
+type ftp struct {
+	Listen   string
+	CertPath string
+	KeyPath  string `validate:"required_with=CertPath"`
+}
+
+// fullText 全文检索配置
+type fullText struct {
+	// Backend 索引后端，为空时不开启全文检索
+	Backend  string `validate:"omitempty,eq=database|eq=elasticsearch"`
+	Endpoint string `validate:"required_if=Backend elasticsearch"`
+	Index    string
+	User     string
+	Password string
+	// MaxSize 提取内容的文件大小上限
+	MaxSize uint64
+}
+
 // slave 作为slave存储端配置
 type slave struct {
 	Secret          string `validate:"omitempty,gte=64"`
@@ -58,6 +100,16 @@ type redis struct {
 	DB       string
 }
 
+// log 日志及追踪配置
+type log struct {
+	// Level 日志等级，为空时按 Debug 选项决定
+	Level  string `validate:"omitempty,eq=error|eq=warning|eq=info|eq=debug"`
+	Format string `validate:"eq=text|eq=json"`
+	// OTLPEndpoint OpenTelemetry 追踪数据的 OTLP/HTTP 接收地址，为空时不导出
+	OTLPEndpoint string
+	ServiceName  string
+}
+
 // 跨域配置
 type cors struct {
 	AllowOrigins     []string
@@ -116,6 +168,11 @@ func Init(path string) {
 		"Redis":      RedisConfig,
 		"CORS":       CORSConfig,
 		"Slave":      SlaveConfig,
+		"S3":         S3Config,
+		"SFTP":       SFTPConfig,
+		"FTP":        FTPConfig,
+		"FullText":   FullTextConfig,
+		"Log":        LogConfig,
 	}
 	for sectionName, sectionStruct := range sections {
 		err = mapSection(sectionName, sectionStruct)
@@ -136,6 +193,12 @@ func Init(path string) {
 		util.Log()
 	}
 
+	// 日志格式及等级
+	_ = util.SetLogFormat(LogConfig.Format)
+	if LogConfig.Level != "" {
+		_ = util.SetLogLevel(LogConfig.Level)
+	}
+
 }
 
 // mapSection 将配置文件的 Section 映射到结构体上
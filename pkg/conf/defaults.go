@@ -25,6 +25,12 @@ var SystemConfig = &system{
 	ProxyHeader: "X-Forwarded-For",
 }
 
+// LogConfig 日志及追踪配置
+var LogConfig = &log{
+	Format:      "text",
+	ServiceName: "cloudreve",
+}
+
 // CORSConfig 跨域配置
 var CORSConfig = &cors{
 	AllowOrigins:     []string{"UNSET"},
@@ -52,4 +58,26 @@ var UnixConfig = &unix{
 	Listen: "",
 }
 
+// S3Config 独立监听的S3兼容接口配置，Listen 为空时仅可通过主站的 /s3 路径访问
+var S3Config = &s3{
+	Listen: "",
+}
+
+// SFTPConfig 内置SFTP服务配置，Listen 为空时不开启，HostKey 不存在时自动生成
+var SFTPConfig = &sftp{
+	Listen:  "",
+	HostKey: "sftp_host_key",
+}
+
+// FTPConfig 内置FTP服务配置，Listen 为空时不开启，配置证书后支持 FTPS
+var FTPConfig = &ftp{
+	Listen: "",
+}
+
+// FullTextConfig 全文检索配置，Backend 可选 database、elasticsearch
+var FullTextConfig = &fullText{
+	Index:   "cloudreve",
+	MaxSize: 20 << 20,
+}
+
 var OptionOverwrite = map[string]interface{}{}
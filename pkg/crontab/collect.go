@@ -2,6 +2,7 @@ package crontab
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,16 +14,29 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
-func garbageCollect() {
+func garbageCollect() error {
 	// 清理打包下载产生的临时文件
 	collectArchiveFile()
 
+	// 清理过期的用户数据导出
+	collectExportFile()
+
+	// 清理过期未完成的 S3 分片上传
+	collectS3Multipart()
+
+	// 清理过期的图像处理缓存
+	collectImageProcessCache()
+
+	// 清理过期的用户动态、已读通知及长期未活跃的登录会话
+	collectActivities()
+
 	// 清理过期的内置内存缓存
 	if store, ok := cache.Store.(*cache.MemoStore); ok {
 		collectCache(store)
 	}
 
 	util.Log().Info("Crontab job \"cron_garbage_collect\" complete.")
+	return nil
 }
 
 func collectArchiveFile() {
@@ -51,19 +65,105 @@ func collectArchiveFile() {
 
 }
 
+func collectExportFile() {
+	tempPath := util.RelativePath(model.GetSettingByName("temp_path"))
+	expires := model.GetIntSetting("export_expires", 604800)
+
+	// 每个导出任务的分卷存放在单独的目录中
+	root := filepath.Join(tempPath, "export")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			util.Log().Debug("Crontab job cannot list temp export folder: %s", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || !strings.HasPrefix(entry.Name(), "export_") ||
+			time.Now().Sub(info.ModTime()).Seconds() <= float64(expires) {
+			continue
+		}
+
+		path := filepath.Join(root, entry.Name())
+		util.Log().Debug("Delete expired user data export %q.", path)
+		if err := os.RemoveAll(path); err != nil {
+			util.Log().Debug("Failed to delete export folder %q: %s", path, err)
+		}
+	}
+}
+
+func collectS3Multipart() {
+	tempPath := util.RelativePath(model.GetSettingByName("temp_path"))
+	expires := model.GetIntSetting("upload_session_timeout", 86400)
+
+	// 每个分片上传的分片存放在单独的目录中
+	root := filepath.Join(tempPath, "s3")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			util.Log().Debug("Crontab job cannot list temp S3 multipart folder: %s", err)
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || time.Now().Sub(info.ModTime()).Seconds() <= float64(expires) {
+			continue
+		}
+
+		path := filepath.Join(root, entry.Name())
+		util.Log().Debug("Delete expired S3 multipart upload %q.", path)
+		if err := os.RemoveAll(path); err != nil {
+			util.Log().Debug("Failed to delete S3 multipart upload folder %q: %s", path, err)
+		}
+	}
+}
+
+func collectImageProcessCache() {
+	expires := model.GetIntSetting("image_process_cache_ttl", 604800)
+
+	root := filesystem.ImageProcessCacheDir()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && time.Now().Sub(info.ModTime()).Seconds() > float64(expires) {
+			util.Log().Debug("Delete expired image process cache %q.", path)
+			if err := os.Remove(path); err != nil {
+				util.Log().Debug("Failed to delete image process cache %q: %s", path, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil && !os.IsNotExist(err) {
+		util.Log().Debug("Crontab job cannot list image process cache folder: %s", err)
+	}
+}
+
 func collectCache(store *cache.MemoStore) {
 	util.Log().Debug("Cleanup memory cache.")
 	store.GarbageCollect()
 }
 
-func uploadSessionCollect() {
+func uploadSessionCollect() error {
 	placeholders := model.GetUploadPlaceholderFiles(0)
 
+	// 可续传上传的会话保存在数据库中，不依赖缓存
+	if err := model.DeleteExpiredResumableUploads(); err != nil {
+		util.Log().Warning("Failed to delete expired resumable uploads: %s", err)
+	}
+
+	resumable, err := model.GetActiveResumableUploadSessions()
+	if err != nil {
+		return fmt.Errorf("failed to list resumable uploads: %w", err)
+	}
+
 	// 将过期的上传会话按照用户分组
 	userToFiles := make(map[uint][]uint)
 	for _, file := range placeholders {
 		_, sessionExist := cache.Get(filesystem.UploadSessionCachePrefix + *file.UploadSessionID)
-		if sessionExist {
+		if sessionExist || util.ContainsString(resumable, *file.UploadSessionID) {
 			continue
 		}
 
@@ -96,4 +196,28 @@ func uploadSessionCollect() {
 	}
 
 	util.Log().Info("Crontab job \"cron_recycle_upload_session\" complete.")
+	return nil
+}
+
+func collectActivities() {
+	retention := model.GetIntSetting("activity_retention", 7776000)
+	if retention > 0 {
+		if _, err := model.DeleteActivitiesBefore(time.Now().Add(-time.Duration(retention) * time.Second)); err != nil {
+			util.Log().Warning("Failed to delete expired activities: %s", err)
+		}
+	}
+
+	retention = model.GetIntSetting("notification_retention", 7776000)
+	if retention > 0 {
+		if _, err := model.DeleteReadNotificationsBefore(time.Now().Add(-time.Duration(retention) * time.Second)); err != nil {
+			util.Log().Warning("Failed to delete expired notifications: %s", err)
+		}
+	}
+
+	timeout := model.GetIntSetting("login_session_timeout", 5184000)
+	// 已吊销的会话保留至对应 Cookie 过期，之后无法识别的令牌同样视为已吊销
+	now := time.Now()
+	if _, err := model.DeleteInactiveLoginSessions(now.Add(-time.Duration(timeout)*time.Second), now.Add(-model.LoginSessionMaxAge)); err != nil {
+		util.Log().Warning("Failed to delete inactive login sessions: %s", err)
+	}
 }
@@ -0,0 +1,33 @@
+package crontab
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// policyHealthCheck 探测所有存储策略的可用性
+func policyHealthCheck() error {
+	policies, err := model.GetPolicies()
+	if err != nil {
+		return fmt.Errorf("failed to list storage policies: %w", err)
+	}
+
+	fs, err := filesystem.NewAnonymousFileSystem()
+	if err != nil {
+		return fmt.Errorf("failed to initialize filesystem: %w", err)
+	}
+	defer fs.Recycle()
+
+	for i := range policies {
+		health := fs.CheckPolicyHealth(context.Background(), &policies[i])
+		if health.Error != "" {
+			util.Log().Debug("Health check of storage policy %q failed: %s", policies[i].Name, health.Error)
+		}
+	}
+
+	return nil
+}
@@ -2,18 +2,20 @@ package crontab
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/directory"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
-	"github.com/robfig/cron/v3"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 )
 
-// Cron 定时任务
-var Cron *cron.Cron
+// crontabLockTTL 集群中定时任务锁的有效期，单位为秒，需小于最短的执行间隔
+const crontabLockTTL = 50
 
 // Reload 重新启动定时任务
 func Reload() {
-	if Cron != nil {
-		Cron.Stop()
-	}
 	Init()
 }
 
@@ -24,24 +26,79 @@ func Init() {
 	options := model.GetSettingByNames(
 		"cron_garbage_collect",
 		"cron_recycle_upload_session",
+		"cron_collect_stats",
+		"cron_stats_report",
+		"cron_directory_sync",
+		"cron_trash_purge",
+		"cron_policy_health_check",
+		"cron_webhook_retry",
+		"cron_orphan_cleanup",
+		"cron_thumb_regenerate",
 	)
-	Cron := cron.New()
+
+	task.DefaultScheduler.Reset()
 	for k, v := range options {
-		var handler func()
+		var handler func() error
 		switch k {
 		case "cron_garbage_collect":
 			handler = garbageCollect
 		case "cron_recycle_upload_session":
 			handler = uploadSessionCollect
+		case "cron_collect_stats":
+			handler = withoutError(stats.Run)
+		case "cron_stats_report":
+			handler = withoutError(stats.SendReport)
+		case "cron_directory_sync":
+			handler = withoutError(directory.Sync)
+		case "cron_trash_purge":
+			handler = trashPurge
+		case "cron_policy_health_check":
+			handler = policyHealthCheck
+		case "cron_webhook_retry":
+			handler = withoutError(webhook.RetryPending)
+		case "cron_orphan_cleanup":
+			handler = orphanCleanup
+		case "cron_thumb_regenerate":
+			handler = thumbRegenerate
 		default:
 			util.Log().Warning("Unknown crontab job type %q, skipping...", k)
 			continue
 		}
 
-		if _, err := Cron.AddFunc(v, handler); err != nil {
+		// 清理的临时文件位于各实例本地，其余任务在集群中只需一个实例执行
+		if k != "cron_garbage_collect" {
+			handler = exclusive(k, handler)
+		}
+
+		if err := task.DefaultScheduler.Register(k, v, handler); err != nil {
 			util.Log().Warning("Failed to start crontab job %q: %s", k, err)
 		}
 
 	}
-	Cron.Start()
+	task.DefaultScheduler.Start()
+}
+
+// exclusive 集群模式下，同一时刻触发的任务只由抢到锁的实例执行。锁不主动释放，
+// 以免执行较快时其他实例因时钟偏差再次执行
+func exclusive(name string, handler func() error) func() error {
+	return func() error {
+		if !instance.Enabled() {
+			return handler()
+		}
+
+		if _, ok := cache.TryLock("crontab_"+name, crontabLockTTL); !ok {
+			util.Log().Debug("Crontab job %q is running on another instance, skipping...", name)
+			return nil
+		}
+
+		return handler()
+	}
+}
+
+// withoutError 包装自行记录错误日志的任务
+func withoutError(handler func()) func() error {
+	return func() error {
+		handler()
+		return nil
+	}
 }
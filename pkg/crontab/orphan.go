@@ -0,0 +1,80 @@
+package crontab
+
+import (
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// orphanCleanup 定时清理孤立的附属记录
+func orphanCleanup() error {
+	if err := CleanupOrphans(); err != nil {
+		return err
+	}
+
+	util.Log().Info("Crontab job \"cron_orphan_cleanup\" complete.")
+	return nil
+}
+
+// CleanupOrphans 清理所属文件或目录已被彻底删除的附属记录，以及过期的分享访问记录
+func CleanupOrphans() error {
+	// WebDAV 自定义属性
+	if err := model.DeleteOrphanWebdavProps(); err != nil {
+		return fmt.Errorf("failed to delete orphan WebDAV properties: %w", err)
+	}
+
+	// 全文检索的文本内容
+	if err := model.DeleteOrphanFileContents(); err != nil {
+		return fmt.Errorf("failed to delete orphan file contents: %w", err)
+	}
+
+	// 目录直接共享记录
+	if err := model.DeleteOrphanCollaborations(); err != nil {
+		return fmt.Errorf("failed to delete orphan collaborations: %w", err)
+	}
+
+	// 目录 ACL 条目
+	if err := model.DeleteOrphanFolderACLs(); err != nil {
+		return fmt.Errorf("failed to delete orphan folder ACLs: %w", err)
+	}
+
+	// 照片 EXIF 信息
+	if err := model.DeleteOrphanPhotos(); err != nil {
+		return fmt.Errorf("failed to delete orphan photos: %w", err)
+	}
+
+	// 音乐标签信息
+	if err := model.DeleteOrphanMusicTracks(); err != nil {
+		return fmt.Errorf("failed to delete orphan music tracks: %w", err)
+	}
+
+	// 过期的分享访问记录
+	keepDays := model.GetIntSetting("share_access_log_keep_days", 90)
+	if keepDays > 0 {
+		before := time.Now().AddDate(0, 0, -keepDays)
+		if _, err := model.DeleteShareAccessLogsBefore(before); err != nil {
+			return fmt.Errorf("failed to delete expired share access logs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// thumbRegenerate 以初始管理员身份提交全站缩略图重新生成任务
+func thumbRegenerate() error {
+	admin, err := model.GetUserByID(1)
+	if err != nil {
+		return fmt.Errorf("failed to find admin user: %w", err)
+	}
+
+	job, err := task.NewThumbTask(&admin, 0, 0, nil, 0)
+	if err != nil {
+		return fmt.Errorf("failed to create thumb task: %w", err)
+	}
+
+	task.TaskPoll.Submit(job)
+	return nil
+}
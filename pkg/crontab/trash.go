@@ -0,0 +1,58 @@
+package crontab
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// trashPurge 按用户组的回收站保留天数清除过期的回收站对象
+func trashPurge() error {
+	uids, err := model.GetTrashUserIDs()
+	if err != nil {
+		return fmt.Errorf("failed to list users with trash: %w", err)
+	}
+
+	for _, uid := range uids {
+		user, err := model.GetUserByID(uid)
+		if err != nil {
+			util.Log().Warning("Owner of the trash cannot be found: %s", err)
+			continue
+		}
+
+		// 用户组关闭回收站后，清除全部回收站对象
+		before := time.Now()
+		if retention := user.Group.OptionsSerialized.TrashRetention; retention > 0 {
+			before = before.AddDate(0, 0, -retention)
+		}
+
+		items, err := model.GetExpiredTrash(uid, before)
+		if err != nil {
+			util.Log().Warning("Failed to list expired trash of user %d: %s", uid, err)
+			continue
+		}
+
+		if len(items) == 0 {
+			continue
+		}
+
+		fs, err := filesystem.NewFileSystem(&user)
+		if err != nil {
+			util.Log().Warning("Failed to initialize filesystem: %s", err)
+			continue
+		}
+
+		if err := fs.PurgeTrash(context.Background(), items); err != nil {
+			util.Log().Warning("Failed to purge trash of user %d: %s", uid, err)
+		}
+
+		fs.Recycle()
+	}
+
+	util.Log().Info("Crontab job \"cron_trash_purge\" complete.")
+	return nil
+}
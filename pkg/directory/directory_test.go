@@ -0,0 +1,179 @@
+package directory
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestEvaluate(t *testing.T) {
+	asserts := assert.New(t)
+	rules, err := ParseRules(`[
+		{"source":"ldap","claim":"memberOf","value":"cn=admins,*","group":1},
+		{"claim":"groups","value":"eng-*","group":4},
+		{"claim":"groups","value":"*","group":5}
+	]`)
+	asserts.NoError(err)
+	asserts.Len(rules, 3)
+
+	// 无属性
+	asserts.EqualValues(2, Evaluate(rules, nil, 2))
+
+	// 来源不匹配
+	asserts.EqualValues(2, Evaluate(rules, &model.DirectoryClaims{
+		Source: SourceOIDC,
+		Values: map[string][]string{"memberOf": {"cn=admins,dc=example"}},
+	}, 2))
+
+	// 靠前的规则优先
+	asserts.EqualValues(1, Evaluate(rules, &model.DirectoryClaims{
+		Source: SourceLDAP,
+		Values: map[string][]string{"MEMBEROF": {"CN=Admins,dc=example"}, "groups": {"eng-core"}},
+	}, 2))
+	asserts.EqualValues(4, Evaluate(rules, &model.DirectoryClaims{
+		Source: SourceOIDC,
+		Values: map[string][]string{"groups": {"sales", "eng-core"}},
+	}, 2))
+	asserts.EqualValues(5, Evaluate(rules, &model.DirectoryClaims{
+		Source: SourceSAML,
+		Values: map[string][]string{"groups": {"sales"}},
+	}, 2))
+
+	// 格式错误
+	_, err = ParseRules("{")
+	asserts.Error(err)
+}
+
+func TestReevaluate(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_directory_group_rules", `[{"claim":"groups","value":"eng","group":4}]`, 0)
+	cache.Set("setting_directory_default_group", "0", 0)
+	claims := &model.DirectoryClaims{Values: map[string][]string{"groups": {"eng"}}}
+
+	// 未保存属性
+	{
+		changed, err := Reevaluate(&model.User{Model: gorm.Model{ID: 2}})
+		asserts.NoError(err)
+		asserts.False(changed)
+	}
+
+	// 初始用户
+	{
+		user := &model.User{Model: gorm.Model{ID: 1}, OptionsSerialized: model.UserOption{Directory: claims}}
+		changed, err := Reevaluate(user)
+		asserts.NoError(err)
+		asserts.False(changed)
+	}
+
+	// 未匹配且无默认用户组
+	{
+		user := &model.User{Model: gorm.Model{ID: 2}, GroupID: 2, OptionsSerialized: model.UserOption{
+			Directory: &model.DirectoryClaims{Values: map[string][]string{"groups": {"sales"}}},
+		}}
+		changed, err := Reevaluate(user)
+		asserts.NoError(err)
+		asserts.False(changed)
+	}
+
+	// 更新失败
+	{
+		user := &model.User{Model: gorm.Model{ID: 2}, GroupID: 2, OptionsSerialized: model.UserOption{Directory: claims}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		changed, err := Reevaluate(user)
+		asserts.Error(err)
+		asserts.False(changed)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		user := &model.User{Model: gorm.Model{ID: 2}, GroupID: 2, OptionsSerialized: model.UserOption{Directory: claims}}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)group_id(.+)").WithArgs(4, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		changed, err := Reevaluate(user)
+		asserts.NoError(err)
+		asserts.True(changed)
+		asserts.EqualValues(4, user.GroupID)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestLogin(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_directory_group_rules", `[]`, 0)
+	cache.Set("setting_directory_default_group", "3", 0)
+	user := &model.User{Model: gorm.Model{ID: 2}, GroupID: 3}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)users(.+)options(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(Login(user, &model.DirectoryClaims{Source: SourceOIDC}))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NotNil(user.OptionsSerialized.Directory)
+	asserts.False(user.OptionsSerialized.Directory.SyncedAt.IsZero())
+}
+
+func TestSync(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 规则格式错误
+	{
+		cache.Set("setting_directory_group_rules", `{`, 0)
+		Sync()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 列出用户失败
+	{
+		cache.Set("setting_directory_group_rules", `[]`, 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		Sync()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		cache.Set("setting_directory_default_group", "4", 0)
+		mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(0, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "group_id", "options"}).
+				AddRow(2, 2, `{"directory":{"source":"ldap"}}`).
+				AddRow(3, 2, `{}`))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)group_id(.+)").WithArgs(4, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Sync()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,77 @@
+package directory
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// 属性来源
+const (
+	SourceOIDC = "oidc"
+	SourceSAML = "saml"
+	SourceLDAP = "ldap"
+)
+
+// Rule 用户组分配规则，规则按列表顺序匹配，越靠前优先级越高
+type Rule struct {
+	// Source 适用的属性来源，为空时适用于所有来源
+	Source string `json:"source,omitempty"`
+	// Claim 属性名，如 OIDC 的 groups、LDAP 的 memberOf，不区分大小写
+	Claim string `json:"claim"`
+	// Value 匹配属性值的通配符表达式，不区分大小写
+	Value string `json:"value"`
+	// Group 匹配后分配的用户组ID
+	Group uint `json:"group"`
+}
+
+// Match 判断规则是否匹配给定的属性
+func (rule *Rule) Match(claims *model.DirectoryClaims) bool {
+	if rule.Source != "" && !strings.EqualFold(rule.Source, claims.Source) {
+		return false
+	}
+
+	pattern := strings.ToLower(rule.Value)
+	for name, values := range claims.Values {
+		if !strings.EqualFold(name, rule.Claim) {
+			continue
+		}
+
+		for _, value := range values {
+			if ok, _ := path.Match(pattern, strings.ToLower(value)); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ParseRules 解析规则设置
+func ParseRules(raw string) ([]Rule, error) {
+	var rules []Rule
+	if strings.TrimSpace(raw) == "" {
+		return rules, nil
+	}
+
+	err := json.Unmarshal([]byte(raw), &rules)
+	return rules, err
+}
+
+// Evaluate 返回首个匹配规则对应的用户组，均不匹配时返回 fallback。
+// fallback 为 0 时表示保持用户当前的用户组
+func Evaluate(rules []Rule, claims *model.DirectoryClaims, fallback uint) uint {
+	if claims == nil {
+		return fallback
+	}
+
+	for i := range rules {
+		if rules[i].Match(claims) {
+			return rules[i].Group
+		}
+	}
+
+	return fallback
+}
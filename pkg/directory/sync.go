@@ -0,0 +1,109 @@
+package directory
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// syncBatchSize 每批同步的用户数
+const syncBatchSize = 100
+
+// loadRules 读取规则与默认用户组设置
+func loadRules() ([]Rule, uint, error) {
+	rules, err := ParseRules(model.GetSettingByName("directory_group_rules"))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return rules, uint(model.GetIntSetting("directory_default_group", 0)), nil
+}
+
+// Login 在用户通过外部身份提供方登录时调用，保存最新的属性并重新分配用户组。
+// 调用方需为用户设定 ExternalID，以便定期同步
+func Login(user *model.User, claims *model.DirectoryClaims) error {
+	claims.SyncedAt = time.Now()
+	user.OptionsSerialized.Directory = claims
+	if err := user.UpdateOptions(); err != nil {
+		return err
+	}
+
+	_, err := Reevaluate(user)
+	return err
+}
+
+// Reevaluate 根据已保存的属性重新计算用户组，返回用户组是否发生变化。
+// 初始用户与未保存属性的用户不受影响
+func Reevaluate(user *model.User) (bool, error) {
+	if user.OptionsSerialized.Directory == nil {
+		return false, nil
+	}
+
+	rules, fallback, err := loadRules()
+	if err != nil {
+		return false, err
+	}
+
+	return reevaluate(user, rules, fallback)
+}
+
+// reevaluate 按给定规则重新计算用户组
+func reevaluate(user *model.User, rules []Rule, fallback uint) (bool, error) {
+	if user.ID == 1 || user.OptionsSerialized.Directory == nil {
+		return false, nil
+	}
+
+	group := Evaluate(rules, user.OptionsSerialized.Directory, fallback)
+	if group == 0 || group == user.GroupID {
+		return false, nil
+	}
+
+	previous := user.GroupID
+	if err := user.Update(map[string]interface{}{"group_id": group}); err != nil {
+		return false, err
+	}
+
+	user.GroupID = group
+	model.RecordAudit(0, "directory.group.assign", model.AuditTargetUser, user.ID,
+		map[string]uint{"from": previous, "to": group})
+	return true, nil
+}
+
+// Sync 按当前规则重新计算所有外部目录用户（ExternalID 不为空）的用户组
+func Sync() {
+	rules, fallback, err := loadRules()
+	if err != nil {
+		util.Log().Warning("Failed to parse directory group rules: %s", err)
+		return
+	}
+
+	var after uint
+	changed := 0
+	for {
+		users, err := model.ListDirectoryUsers(after, syncBatchSize)
+		if err != nil {
+			util.Log().Warning("Failed to list directory users: %s", err)
+			return
+		}
+
+		for i := range users {
+			ok, err := reevaluate(&users[i], rules, fallback)
+			if err != nil {
+				util.Log().Warning("Failed to re-evaluate group of user %d: %s", users[i].ID, err)
+				continue
+			}
+
+			if ok {
+				changed++
+			}
+		}
+
+		if len(users) < syncBatchSize {
+			break
+		}
+		after = users[len(users)-1].ID
+	}
+
+	util.Log().Info("Directory group sync complete, %d users updated.", changed)
+}
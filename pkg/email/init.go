@@ -2,11 +2,19 @@ package email
 
 import (
 	"sync"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
+// 可选的邮件服务商
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSendGrid = "sendgrid"
+	ProviderMailgun  = "mailgun"
+)
+
 // Client 默认的邮件发送客户端
 var Client Driver
 
@@ -21,32 +29,61 @@ func Init() {
 
 	if Client != nil {
 		Client.Close()
+		Client = nil
+	}
+
+	provider, err := NewProvider(model.GetSettingByNameWithDefault("mail_provider", ProviderSMTP))
+	if err != nil {
+		util.Log().Warning("Failed to initialize email provider: %s", err)
+		return
 	}
 
-	// 读取SMTP设置
+	Client = NewQueue(provider,
+		model.GetIntSetting("mail_max_attempts", 3),
+		time.Duration(model.GetIntSetting("mail_retry_interval", 30))*time.Second,
+	)
+}
+
+// NewProvider 根据站点设置新建名为 name 的邮件服务商
+func NewProvider(name string) (Provider, error) {
 	options := model.GetSettingByNames(
 		"fromName",
 		"fromAdress",
-		"smtpHost",
 		"replyTo",
-		"smtpUser",
-		"smtpPass",
-		"smtpEncryption",
 	)
-	port := model.GetIntSetting("smtpPort", 25)
-	keepAlive := model.GetIntSetting("mail_keepalive", 30)
-
-	client := NewSMTPClient(SMTPConfig{
-		Name:       options["fromName"],
-		Address:    options["fromAdress"],
-		ReplyTo:    options["replyTo"],
-		Host:       options["smtpHost"],
-		Port:       port,
-		User:       options["smtpUser"],
-		Password:   options["smtpPass"],
-		Keepalive:  keepAlive,
-		Encryption: model.IsTrueVal(options["smtpEncryption"]),
-	})
-
-	Client = client
+	sender := Sender{
+		Name:    options["fromName"],
+		Address: options["fromAdress"],
+		ReplyTo: options["replyTo"],
+	}
+
+	switch name {
+	case ProviderSMTP:
+		// 读取SMTP设置
+		options := model.GetSettingByNames(
+			"smtpHost",
+			"smtpUser",
+			"smtpPass",
+			"smtpEncryption",
+		)
+		return NewSMTPClient(SMTPConfig{
+			Name:       sender.Name,
+			Address:    sender.Address,
+			ReplyTo:    sender.ReplyTo,
+			Host:       options["smtpHost"],
+			Port:       model.GetIntSetting("smtpPort", 25),
+			User:       options["smtpUser"],
+			Password:   options["smtpPass"],
+			Keepalive:  model.GetIntSetting("mail_keepalive", 30),
+			Encryption: model.IsTrueVal(options["smtpEncryption"]),
+		}), nil
+	case ProviderSendGrid:
+		return NewSendGridClient(sender, model.GetSettingByName("mail_sendgrid_key")), nil
+	case ProviderMailgun:
+		options := model.GetSettingByNames("mail_mailgun_endpoint", "mail_mailgun_domain", "mail_mailgun_key")
+		return NewMailgunClient(sender, options["mail_mailgun_endpoint"], options["mail_mailgun_domain"],
+			options["mail_mailgun_key"]), nil
+	}
+
+	return nil, ErrUnknownProvider
 }
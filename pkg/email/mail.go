@@ -3,8 +3,12 @@ package email
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
+// providerTimeout 通过 HTTP API 投递邮件的超时时间
+const providerTimeout = 15 * time.Second
+
 // Driver 邮件发送驱动
 type Driver interface {
 	// Close 关闭驱动
@@ -13,11 +17,37 @@ type Driver interface {
 	Send(to, title, body string) error
 }
 
+// Provider 邮件服务商，同步投递单封邮件，由发送队列负责排队及重试
+type Provider interface {
+	// Deliver 投递邮件
+	Deliver(msg *Message) error
+	// Close 释放服务商持有的连接等资源
+	Close()
+}
+
+// Message 待发送的邮件
+type Message struct {
+	To    string
+	Title string
+	Body  string
+	// Attempts 已尝试投递的次数
+	Attempts int
+}
+
+// Sender 发件人设定
+type Sender struct {
+	Name    string // 发送者名
+	Address string // 发送者地址
+	ReplyTo string // 回复地址
+}
+
 var (
 	// ErrChanNotOpen 邮件队列未开启
 	ErrChanNotOpen = errors.New("email queue is not started")
 	// ErrNoActiveDriver 无可用邮件发送服务
 	ErrNoActiveDriver = errors.New("no avaliable email provider")
+	// ErrUnknownProvider 未知的邮件服务商
+	ErrUnknownProvider = errors.New("unknown email provider")
 )
 
 // Send 发送邮件
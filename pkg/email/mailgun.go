@@ -0,0 +1,69 @@
+package email
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// Mailgun 通过 Mailgun HTTP API 发送邮件
+type Mailgun struct {
+	Sender Sender
+	// Endpoint API 地址，欧洲区域为 https://api.eu.mailgun.net
+	Endpoint string
+	Domain   string
+	APIKey   string
+	client   request.Client
+}
+
+// NewMailgunClient 新建Mailgun服务商
+func NewMailgunClient(sender Sender, endpoint, domain, apiKey string) *Mailgun {
+	return &Mailgun{
+		Sender:   sender,
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		Domain:   domain,
+		APIKey:   apiKey,
+		client:   request.NewClient(),
+	}
+}
+
+// Deliver 投递邮件
+func (client *Mailgun) Deliver(msg *Message) error {
+	form := url.Values{}
+	form.Set("from", (&mail.Address{Name: client.Sender.Name, Address: client.Sender.Address}).String())
+	form.Set("to", msg.To)
+	form.Set("subject", msg.Title)
+	form.Set("html", msg.Body)
+	if client.Sender.ReplyTo != "" {
+		form.Set("h:Reply-To", client.Sender.ReplyTo)
+	}
+	body := form.Encode()
+
+	header := http.Header{}
+	header.Set("Authorization", "Basic "+basicAuth("api", client.APIKey))
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	_, err := client.client.Request("POST",
+		fmt.Sprintf("%s/v3/%s/messages", client.Endpoint, url.PathEscape(client.Domain)),
+		strings.NewReader(body),
+		request.WithContext(context.Background()),
+		request.WithTimeout(providerTimeout),
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(body))),
+	).CheckHTTPResponse(http.StatusOK).GetResponse()
+	return err
+}
+
+// Close 关闭服务商，Mailgun 无需释放资源
+func (client *Mailgun) Close() {
+}
+
+func basicAuth(user, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+}
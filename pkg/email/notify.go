@@ -0,0 +1,110 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"html"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 通知类型
+const (
+	NotifyShareAccessed   = "share_accessed"
+	NotifyTaskFinished    = "task_finished"
+	NotifyQuotaAlmostFull = "quota_almost_full"
+)
+
+// Notification 用户可订阅的邮件通知
+type Notification struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	// Default 用户未设定时是否发送
+	Default bool `json:"default"`
+}
+
+// Notifications 所有邮件通知类型，模板保存在 mail_<type>_template 设置项中
+var Notifications = []Notification{
+	{Type: NotifyShareAccessed, Title: "分享被访问", Default: false},
+	{Type: NotifyTaskFinished, Title: "任务已完成", Default: false},
+	{Type: NotifyQuotaAlmostFull, Title: "存储空间即将用尽", Default: true},
+}
+
+// ErrUnknownNotification 未知的通知类型
+var ErrUnknownNotification = errors.New("unknown notification type")
+
+// GetNotification 获取类型为 kind 的通知
+func GetNotification(kind string) (Notification, bool) {
+	for _, notification := range Notifications {
+		if notification.Type == kind {
+			return notification, true
+		}
+	}
+	return Notification{}, false
+}
+
+// NotificationPreferences 获取用户对所有通知类型的订阅状态
+func NotificationPreferences(user *model.User) map[string]bool {
+	res := make(map[string]bool, len(Notifications))
+	for _, notification := range Notifications {
+		res[notification.Type] = user.NotificationEnabled(notification.Type, notification.Default)
+	}
+	return res
+}
+
+// NewNotificationEmail 使用 kind 的模板新建通知邮件，vars 中的值会被转义
+func NewNotificationEmail(kind, userName string, vars map[string]string) (string, string, error) {
+	notification, ok := GetNotification(kind)
+	if !ok {
+		return "", "", ErrUnknownNotification
+	}
+
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_"+kind+"_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	for key, value := range vars {
+		replace["{"+key+"}"] = html.EscapeString(value)
+	}
+
+	return fmt.Sprintf("【%s】%s", options["siteName"], notification.Title),
+		util.Replace(replace, options["mail_"+kind+"_template"]), nil
+}
+
+// Notify 向订阅了 kind 通知的用户发送通知邮件
+func Notify(user *model.User, kind string, vars map[string]string) error {
+	notification, ok := GetNotification(kind)
+	if !ok {
+		return ErrUnknownNotification
+	}
+
+	if user.Email == "" || !user.NotificationEnabled(kind, notification.Default) {
+		return nil
+	}
+
+	title, body, err := NewNotificationEmail(kind, user.Nick, vars)
+	if err != nil {
+		return err
+	}
+
+	return Send(user.Email, title, body)
+}
+
+// NotifyThrottled 与 Notify 相同，但 interval 秒内同一 key 只发送一次
+func NotifyThrottled(user *model.User, kind, key string, interval int, vars map[string]string) error {
+	key = fmt.Sprintf("notify_%s_%d_%s", kind, user.ID, key)
+	if _, ok := cache.Get(key); ok {
+		return nil
+	}
+
+	if err := cache.Set(key, true, interval); err != nil {
+		return err
+	}
+
+	return Notify(user, kind, vars)
+}
@@ -0,0 +1,105 @@
+package email
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Queue 邮件发送队列，逐封交由服务商投递，失败后按指数退避重新入队
+type Queue struct {
+	provider Provider
+	// MaxAttempts 每封邮件最多尝试投递的次数
+	MaxAttempts int
+	// RetryInterval 首次重试的间隔，之后每次翻倍
+	RetryInterval time.Duration
+
+	ch     chan *Message
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewQueue 新建并启动服务商 provider 的发送队列
+func NewQueue(provider Provider, maxAttempts int, retryInterval time.Duration) *Queue {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	queue := &Queue{
+		provider:      provider,
+		MaxAttempts:   maxAttempts,
+		RetryInterval: retryInterval,
+		ch:            make(chan *Message, 30),
+	}
+
+	go queue.run()
+	return queue
+}
+
+// Send 将邮件加入发送队列
+func (queue *Queue) Send(to, title, body string) error {
+	return queue.enqueue(&Message{To: to, Title: title, Body: body})
+}
+
+// Close 关闭发送队列，等待重试的邮件将被丢弃
+func (queue *Queue) Close() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if !queue.closed {
+		queue.closed = true
+		close(queue.ch)
+	}
+}
+
+func (queue *Queue) enqueue(msg *Message) error {
+	queue.mu.RLock()
+	defer queue.mu.RUnlock()
+
+	if queue.closed {
+		return ErrChanNotOpen
+	}
+
+	queue.ch <- msg
+	return nil
+}
+
+func (queue *Queue) run() {
+	defer queue.provider.Close()
+
+	for msg := range queue.ch {
+		queue.deliver(msg)
+	}
+
+	util.Log().Debug("Email queue closing...")
+}
+
+// deliver 投递一封邮件，失败时安排重试
+func (queue *Queue) deliver(msg *Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			util.Log().Error("Exception while sending email to %q: %s", msg.To, err)
+		}
+	}()
+
+	msg.Attempts++
+	err := queue.provider.Deliver(msg)
+	if err == nil {
+		util.Log().Debug("Email sent.")
+		return
+	}
+
+	if msg.Attempts >= queue.MaxAttempts {
+		util.Log().Warning("Failed to send email to %q after %d attempt(s): %s", msg.To, msg.Attempts, err)
+		return
+	}
+
+	delay := queue.RetryInterval << uint(msg.Attempts-1)
+	util.Log().Warning("Failed to send email to %q: %s, retry in %s.", msg.To, err, delay)
+	time.AfterFunc(delay, func() {
+		if err := queue.enqueue(msg); err != nil {
+			util.Log().Warning("Email to %q dropped: %s", msg.To, err)
+		}
+	})
+}
@@ -0,0 +1,85 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// sendGridEndpoint SendGrid 邮件发送 API
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGrid 通过 SendGrid Web API 发送邮件
+type SendGrid struct {
+	Sender Sender
+	APIKey string
+	client request.Client
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// NewSendGridClient 新建SendGrid服务商
+func NewSendGridClient(sender Sender, apiKey string) *SendGrid {
+	return &SendGrid{
+		Sender: sender,
+		APIKey: apiKey,
+		client: request.NewClient(),
+	}
+}
+
+// Deliver 投递邮件
+func (client *SendGrid) Deliver(msg *Message) error {
+	req := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: client.Sender.Address, Name: client.Sender.Name},
+		Subject:          msg.Title,
+		Content:          []sendGridContent{{Type: "text/html", Value: msg.Body}},
+	}
+	if client.Sender.ReplyTo != "" {
+		req.ReplyTo = &sendGridAddress{Email: client.Sender.ReplyTo, Name: client.Sender.Name}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+client.APIKey)
+	header.Set("Content-Type", "application/json")
+
+	_, err = client.client.Request("POST", sendGridEndpoint, bytes.NewReader(body),
+		request.WithContext(context.Background()),
+		request.WithTimeout(providerTimeout),
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(body))),
+	).CheckHTTPResponse(http.StatusAccepted).GetResponse()
+	return err
+}
+
+// Close 关闭服务商，SendGrid 无需释放资源
+func (client *SendGrid) Close() {
+}
@@ -1,6 +1,7 @@
 package email
 
 import (
+	"sync"
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -10,8 +11,11 @@ import (
 // SMTP SMTP协议发送邮件
 type SMTP struct {
 	Config SMTPConfig
-	ch     chan *mail.Message
-	chOpen bool
+	dialer *mail.Dialer
+
+	mu     sync.Mutex
+	sender mail.SendCloser
+	idle   *time.Timer
 }
 
 // SMTPConfig SMTP发送配置
@@ -27,94 +31,76 @@ type SMTPConfig struct {
 	Keepalive  int    // SMTP 连接保留时长
 }
 
-// NewSMTPClient 新建SMTP发送队列
+// NewSMTPClient 新建SMTP服务商
 func NewSMTPClient(config SMTPConfig) *SMTP {
-	client := &SMTP{
+	d := mail.NewDialer(config.Host, config.Port, config.User, config.Password)
+	d.Timeout = time.Duration(config.Keepalive+5) * time.Second
+	// 是否启用 SSL
+	d.SSL = config.Encryption
+	d.StartTLSPolicy = mail.OpportunisticStartTLS
+
+	return &SMTP{
 		Config: config,
-		ch:     make(chan *mail.Message, 30),
-		chOpen: false,
+		dialer: d,
 	}
-
-	client.Init()
-
-	return client
 }
 
-// Send 发送邮件
-func (client *SMTP) Send(to, title, body string) error {
-	if !client.chOpen {
-		return ErrChanNotOpen
-	}
+// Deliver 投递邮件，连接在空闲 Keepalive 秒后关闭
+func (client *SMTP) Deliver(msg *Message) error {
 	m := mail.NewMessage()
 	m.SetAddressHeader("From", client.Config.Address, client.Config.Name)
 	m.SetAddressHeader("Reply-To", client.Config.ReplyTo, client.Config.Name)
-	m.SetHeader("To", to)
-	m.SetHeader("Subject", title)
-	m.SetBody("text/html", body)
-	client.ch <- m
+	m.SetHeader("To", msg.To)
+	m.SetHeader("Subject", msg.Title)
+	m.SetBody("text/html", msg.Body)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.sender == nil {
+		s, err := client.dialer.Dial()
+		if err != nil {
+			return err
+		}
+		client.sender = s
+	}
+
+	if err := mail.Send(client.sender, m); err != nil {
+		// 连接可能已失效，下次投递时重新建立
+		client.closeSender()
+		return err
+	}
+
+	if client.idle != nil {
+		client.idle.Stop()
+	}
+	client.idle = time.AfterFunc(time.Duration(client.Config.Keepalive)*time.Second, func() {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		client.closeSender()
+	})
+
 	return nil
 }
 
-// Close 关闭发送队列
+// Close 关闭SMTP连接
 func (client *SMTP) Close() {
-	if client.ch != nil {
-		close(client.ch)
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.idle != nil {
+		client.idle.Stop()
 	}
+	client.closeSender()
 }
 
-// Init 初始化发送队列
-func (client *SMTP) Init() {
-	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				client.chOpen = false
-				util.Log().Error("Exception while sending email: %s, queue will be reset in 10 seconds.", err)
-				time.Sleep(time.Duration(10) * time.Second)
-				client.Init()
-			}
-		}()
-
-		d := mail.NewDialer(client.Config.Host, client.Config.Port, client.Config.User, client.Config.Password)
-		d.Timeout = time.Duration(client.Config.Keepalive+5) * time.Second
-		client.chOpen = true
-		// 是否启用 SSL
-		d.SSL = false
-		if client.Config.Encryption {
-			d.SSL = true
-		}
-		d.StartTLSPolicy = mail.OpportunisticStartTLS
-
-		var s mail.SendCloser
-		var err error
-		open := false
-		for {
-			select {
-			case m, ok := <-client.ch:
-				if !ok {
-					util.Log().Debug("Email queue closing...")
-					client.chOpen = false
-					return
-				}
-				if !open {
-					if s, err = d.Dial(); err != nil {
-						panic(err)
-					}
-					open = true
-				}
-				if err := mail.Send(s, m); err != nil {
-					util.Log().Warning("Failed to send email: %s", err)
-				} else {
-					util.Log().Debug("Email sent.")
-				}
-			// 长时间没有新邮件，则关闭SMTP连接
-			case <-time.After(time.Duration(client.Config.Keepalive) * time.Second):
-				if open {
-					if err := s.Close(); err != nil {
-						util.Log().Warning("Failed to close SMTP connection: %s", err)
-					}
-					open = false
-				}
-			}
-		}
-	}()
+func (client *SMTP) closeSender() {
+	if client.sender == nil {
+		return
+	}
+
+	if err := client.sender.Close(); err != nil {
+		util.Log().Warning("Failed to close SMTP connection: %s", err)
+	}
+	client.sender = nil
 }
@@ -2,6 +2,7 @@ package email
 
 import (
 	"fmt"
+	"html"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -21,6 +22,61 @@ func NewActivationEmail(userName, activateURL string) (string, string) {
 		util.Replace(replace, options["mail_activation_template"])
 }
 
+// NewArchiveReadyEmail 新建压缩文件已就绪通知邮件
+func NewArchiveReadyEmail(userName, fileName string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL")
+	return fmt.Sprintf("【%s】压缩文件已就绪", options["siteName"]),
+		fmt.Sprintf("%s，您好：<br/>您预约的压缩任务已完成，文件 %s 已保存至您的空间，可前往 <a href=\"%s\">%s</a> 下载。",
+			html.EscapeString(userName), html.EscapeString(fileName), options["siteURL"], options["siteName"])
+}
+
+// NewBudgetAlertEmail 新建存储策略用量提醒邮件
+func NewBudgetAlertEmail(policyName, month string, percent int) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL")
+	return fmt.Sprintf("【%s】存储策略用量提醒", options["siteName"]),
+		fmt.Sprintf("存储策略 %s 在 %s 的用量已达到预算的 %d%%，请前往 <a href=\"%s\">%s</a> 管理面板查看。",
+			html.EscapeString(policyName), month, percent, options["siteURL"], options["siteName"])
+}
+
+// NewAutomationEmail 新建自动化规则通知邮件
+func NewAutomationEmail(userName, ruleName, filePath, message string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL")
+	return fmt.Sprintf("【%s】%s", options["siteName"], ruleName),
+		fmt.Sprintf("%s，您好：<br/>%s<br/>文件：%s<br/>可前往 <a href=\"%s\">%s</a> 查看。",
+			html.EscapeString(userName), html.EscapeString(message), html.EscapeString(filePath),
+			options["siteURL"], options["siteName"])
+}
+
+// NewModerationEmail 新建分享被禁用或恢复的通知邮件
+func NewModerationEmail(userName, shareName string, disabled bool, note string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL")
+	action := "已因举报被管理员禁用，分享链接及文件下载暂不可用"
+	title := "分享已被禁用"
+	if !disabled {
+		action = "已由管理员恢复，可正常访问"
+		title = "分享已恢复"
+	}
+
+	body := fmt.Sprintf("%s，您好：<br/>您的分享 %s %s。", html.EscapeString(userName), html.EscapeString(shareName), action)
+	if note != "" {
+		body += fmt.Sprintf("<br/>处理说明：%s", html.EscapeString(note))
+	}
+
+	return fmt.Sprintf("【%s】%s", options["siteName"], title),
+		body + fmt.Sprintf("<br/>如有疑问，请前往 <a href=\"%s\">%s</a> 联系管理员。", options["siteURL"], options["siteName"])
+}
+
+// NewStatsReportEmail 新建站点统计报告邮件
+func NewStatsReportEmail(from, to string, summary map[string]uint64) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL")
+	return fmt.Sprintf("【%s】站点统计报告 %s ~ %s", options["siteName"], from, to),
+		fmt.Sprintf("%s ~ %s 站点统计：<br/>活跃用户（人次）：%d<br/>新注册用户：%d<br/>上传文件：%d 个，共 %d 字节<br/>"+
+			"下载次数：%d，流量约 %d 字节<br/>分享访问次数：%d<br/>当前存储用量：%d 字节<br/>详细数据请前往 <a href=\"%s\">%s</a> 管理面板查看。",
+			from, to, summary["active_users"], summary["registrations"], summary["uploads"],
+			summary["upload_bytes"], summary["downloads"], summary["download_bytes"], summary["share_hits"],
+			summary["storage"], options["siteURL"], options["siteName"])
+}
+
 // NewResetEmail 新建重设密码邮件
 func NewResetEmail(userName, resetURL string) (string, string) {
 	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_reset_pwd_template")
@@ -0,0 +1,111 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+)
+
+// UseActor 以 actor 的身份访问文件系统，操作受目录 ACL 限制，没有匹配的 ACL 时拥有 perms 权限
+func (fs *FileSystem) UseActor(actor *model.User, perms int) {
+	fs.Actor = actor
+	fs.ActorPermissions = perms
+	fs.Use("BeforeUpload", HookCheckUploadPermission)
+}
+
+// CheckPermission 检查操作者对目录的权限，目录 ACL 沿上级目录继承。
+// 未设置操作者或操作者即为所有者时不做限制
+func (fs *FileSystem) CheckPermission(folder *model.Folder, perm int) error {
+	if fs.Actor == nil || fs.Actor.ID == fs.User.ID {
+		return nil
+	}
+
+	ids, err := folder.AncestorIDs()
+	if err != nil {
+		return ErrObjectNotExist.WithError(err)
+	}
+
+	allowed, decided, err := model.EvaluateFolderACL(ids, fs.Actor, perm)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	if !decided {
+		allowed = fs.ActorPermissions&perm == perm
+	}
+
+	if !allowed {
+		return ErrACLDenied
+	}
+
+	return nil
+}
+
+// CheckPathPermission 检查操作者对路径 dir 的权限，目录尚不存在时检查最近的已存在上级目录
+func (fs *FileSystem) CheckPathPermission(dir string, perm int) error {
+	if fs.Actor == nil {
+		return nil
+	}
+
+	for dir = path.Clean("/" + dir); ; dir = path.Dir(dir) {
+		if exist, folder := fs.IsPathExist(dir); exist {
+			return fs.CheckPermission(folder, perm)
+		}
+
+		if dir == "/" {
+			return ErrPathNotExist
+		}
+	}
+}
+
+// CheckObjectsPermission 检查操作者对给定目录及文件所在目录的权限
+func (fs *FileSystem) CheckObjectsPermission(dirs, files []uint, perm int) error {
+	if fs.Actor == nil {
+		return nil
+	}
+
+	folders, err := model.GetFoldersByIDs(dirs, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	for i := range folders {
+		if err := fs.CheckPermission(&folders[i], perm); err != nil {
+			return err
+		}
+	}
+
+	fileObjects, err := model.GetFilesByIDs(files, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	for i := range fileObjects {
+		if err := fs.checkFilePermission(&fileObjects[i], perm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkFilePermission 检查操作者对文件所在目录的权限
+func (fs *FileSystem) checkFilePermission(file *model.File, perm int) error {
+	if fs.Actor == nil {
+		return nil
+	}
+
+	parents, err := model.GetFoldersByIDs([]uint{file.FolderID}, file.UserID)
+	if err != nil || len(parents) == 0 {
+		return ErrObjectNotExist.WithError(err)
+	}
+
+	return fs.CheckPermission(&parents[0], perm)
+}
+
+// HookCheckUploadPermission 检查操作者对上传目标目录的写入权限
+func HookCheckUploadPermission(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+	return fs.CheckPathPermission(file.Info().VirtualPath, model.ACLWrite)
+}
@@ -0,0 +1,84 @@
+package filesystem
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_CheckPermission(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	fs.User.ID = 1
+	folder := &model.Folder{OwnerID: 1}
+	folder.ID = 2
+	columns := []string{"id", "folder_id", "principal_type", "principal_id", "effect", "permissions"}
+
+	// 未设置操作者
+	asserts.NoError(fs.CheckPermission(folder, model.ACLWrite))
+
+	// 操作者为所有者
+	fs.UseActor(fs.User, 0)
+	asserts.NoError(fs.CheckPermission(folder, model.ACLWrite))
+
+	actor := &model.User{}
+	actor.ID = 3
+	fs.UseActor(actor, model.ACLRead)
+
+	// 没有匹配的 ACL，使用默认权限
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns))
+		asserts.NoError(fs.CheckPermission(folder, model.ACLRead))
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns))
+		asserts.Equal(ErrACLDenied, fs.CheckPermission(folder, model.ACLWrite))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// ACL 允许
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 2, model.ACLPrincipalUser, 3, model.ACLAllow, model.ACLWrite))
+		asserts.NoError(fs.CheckPermission(folder, model.ACLWrite))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// ACL 拒绝
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 2, model.ACLPrincipalUser, 3, model.ACLDeny, model.ACLRead))
+		asserts.Equal(ErrACLDenied, fs.CheckPermission(folder, model.ACLRead))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(fs.CheckPermission(folder, model.ACLRead))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFileSystem_CheckObjectsPermission(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	fs.User.ID = 1
+	columns := []string{"id", "folder_id", "principal_type", "principal_id", "effect", "permissions"}
+
+	// 未设置操作者
+	asserts.NoError(fs.CheckObjectsPermission([]uint{2}, []uint{3}, model.ACLDelete))
+
+	actor := &model.User{}
+	actor.ID = 3
+	fs.UseActor(actor, model.ACLRead)
+
+	// 文件所在目录拒绝删除
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "user_id"}).AddRow(3, 2, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
+	mock.ExpectQuery("SELECT(.+)folder_acls(.+)").WillReturnRows(sqlmock.NewRows(columns))
+	asserts.Equal(ErrACLDenied, fs.CheckObjectsPermission(nil, []uint{3}, model.ACLDelete))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,57 @@
+package filesystem
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// NewActivityHook 返回将用户发起的上传、删除写入用户动态的变更钩子，ip 为发起请求的来源
+func NewActivityHook(ip string) ChangeHook {
+	return func(ctx context.Context, fs *FileSystem, changes []model.Change) {
+		if fs.User == nil || fs.User.ID == 0 {
+			return
+		}
+
+		activities := make([]model.Activity, 0, len(changes))
+		for _, change := range changes {
+			activityType := changeActivity(change)
+			if activityType == "" {
+				continue
+			}
+
+			activities = append(activities, model.Activity{
+				UserID:     change.UserID,
+				Type:       activityType,
+				ObjectType: change.ObjectType,
+				ObjectID:   change.ObjectID,
+				Name:       change.Name,
+				Size:       change.Size,
+				IP:         ip,
+			})
+		}
+
+		if len(activities) == 0 {
+			return
+		}
+
+		if err := model.RecordActivities(activities); err != nil {
+			util.Log().Warning("Failed to record activities: %s", err)
+		}
+	}
+}
+
+// changeActivity 返回变更对应的用户动态类型，上传会话创建的占位文件在上传完成后才记录
+func changeActivity(change model.Change) string {
+	switch {
+	case change.Type == model.ChangeDelete:
+		return model.ActivityDelete
+	case change.ObjectType != model.ChangeObjectFile:
+		return ""
+	case change.Type == model.ChangeCreate && !change.Placeholder, change.Type == model.ChangeModify:
+		return model.ActivityUpload
+	}
+
+	return ""
+}
@@ -0,0 +1,58 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangeActivity(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal(model.ActivityUpload, changeActivity(model.Change{Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile}))
+	asserts.Equal(model.ActivityUpload, changeActivity(model.Change{Type: model.ChangeModify, ObjectType: model.ChangeObjectFile}))
+	asserts.Equal("", changeActivity(model.Change{Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile, Placeholder: true}))
+	asserts.Equal(model.ActivityDelete, changeActivity(model.Change{Type: model.ChangeDelete, ObjectType: model.ChangeObjectFolder}))
+	asserts.Equal("", changeActivity(model.Change{Type: model.ChangeCreate, ObjectType: model.ChangeObjectFolder}))
+	asserts.Equal("", changeActivity(model.Change{Type: model.ChangeMove, ObjectType: model.ChangeObjectFile}))
+}
+
+func TestNewActivityHook(t *testing.T) {
+	asserts := assert.New(t)
+	hook := NewActivityHook("127.0.0.1")
+
+	// 匿名用户
+	{
+		hook(context.Background(), &FileSystem{User: &model.User{}}, []model.Change{{Type: model.ChangeDelete}})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 没有需要记录的变更
+	{
+		fs := &FileSystem{User: &model.User{}}
+		fs.User.ID = 1
+		hook(context.Background(), fs, []model.Change{{Type: model.ChangeMove, ObjectType: model.ChangeObjectFile}})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 记录上传、删除
+	{
+		fs := &FileSystem{User: &model.User{}}
+		fs.User.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)activities(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1, model.ActivityUpload, model.ChangeObjectFile, 2, "a.txt", 10, 0, "127.0.0.1").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT(.+)activities(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		hook(context.Background(), fs, []model.Change{
+			{UserID: 1, Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile, ObjectID: 2, Name: "a.txt", Size: 10},
+			{UserID: 1, Type: model.ChangeMove, ObjectType: model.ChangeObjectFile},
+			{UserID: 1, Type: model.ChangeDelete, ObjectType: model.ChangeObjectFolder, ObjectID: 3},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
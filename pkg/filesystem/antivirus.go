@@ -0,0 +1,109 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/antivirus"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     病毒扫描相关
+   ================
+*/
+
+// 检出病毒后的处理方式
+const (
+	AntivirusActionReject     = "reject"
+	AntivirusActionQuarantine = "quarantine"
+)
+
+// ScanFile 使用 ClamAV 扫描文件并记录扫描结果。检出病毒时，按设置删除文件并返回
+// ErrFileInfected，或将文件隔离；扫描本身失败时不影响上传，仅记录失败原因
+func (fs *FileSystem) ScanFile(ctx context.Context, file *model.File) error {
+	opts := model.GetSettingByNames("antivirus_clamd_address", "antivirus_action")
+	maxSize := model.GetIntSetting("antivirus_max_size", 26214400)
+	if maxSize > 0 && file.Size > uint64(maxSize) {
+		return file.UpdateScanResult(model.ScanStatusSkipped, "")
+	}
+
+	timeout := time.Duration(model.GetIntSetting("antivirus_timeout", 60)) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := fs.scanContent(ctx, opts["antivirus_clamd_address"], timeout, file.SourceName)
+	if err != nil {
+		util.LogCtx(ctx).Warning("Failed to scan file %q: %s", file.Name, err)
+		return file.UpdateScanResult(model.ScanStatusFailed, err.Error())
+	}
+
+	if !result.Infected {
+		return file.UpdateScanResult(model.ScanStatusClean, "")
+	}
+
+	util.LogCtx(ctx).Warning("Virus %q found in file %q of user %d.", result.Signature, file.Name, file.UserID)
+	if opts["antivirus_action"] == AntivirusActionQuarantine {
+		return file.UpdateScanResult(model.ScanStatusQuarantined, result.Signature)
+	}
+
+	// 拒绝上传，删除文件记录，仍被其他文件引用的物理文件予以保留
+	if err := fs.deleteInfected(ctx, file); err != nil {
+		return err
+	}
+
+	fs.emitChanges(ctx, fileChange(model.ChangeDelete, file))
+	return ErrFileInfected.WithError(fmt.Errorf("virus found: %s", result.Signature))
+}
+
+// deleteInfected 删除感染病毒的文件记录，物理文件不再被引用时一并删除
+func (fs *FileSystem) deleteInfected(ctx context.Context, file *model.File) error {
+	unlock := model.LockSources()
+	defer unlock()
+
+	orphans, err := model.RemoveFilesWithSoftLinks([]model.File{*file})
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	if err := model.DeleteFiles([]*model.File{file}, file.UserID); err != nil {
+		return ErrDBDeleteObjects.WithError(err)
+	}
+
+	if len(orphans) > 0 {
+		if _, err := fs.Handler.Delete(ctx, []string{file.SourceName}); err != nil {
+			util.LogCtx(ctx).Warning("Failed to delete infected file %q: %s", file.SourceName, err)
+		}
+	}
+
+	return nil
+}
+
+// scanContent 读取物理文件并发送给 clamd 扫描
+func (fs *FileSystem) scanContent(ctx context.Context, address string, timeout time.Duration, source string) (*antivirus.Result, error) {
+	client, err := antivirus.NewClient(address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := fs.Handler.Get(ctx, source)
+	if err != nil {
+		return nil, ErrIO.WithError(err)
+	}
+	defer rs.Close()
+
+	return client.Scan(ctx, rs)
+}
+
+// HookAntivirusScan 上传完成后扫描启用了病毒扫描的存储策略中的文件
+func HookAntivirusScan(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || !file.GetPolicy().OptionsSerialized.Antivirus {
+		return nil
+	}
+
+	return fs.ScanFile(ctx, file)
+}
@@ -0,0 +1,182 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+// fakeClamd 模拟 clamd，读取完 INSTREAM 数据后返回 reply
+func fakeClamd(t *testing.T, reply string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				if _, err := reader.ReadString(0); err != nil {
+					return
+				}
+
+				for {
+					size := make([]byte, 4)
+					if _, err := io.ReadFull(reader, size); err != nil {
+						return
+					}
+					n := int64(size[0])<<24 | int64(size[1])<<16 | int64(size[2])<<8 | int64(size[3])
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(ioutil.Discard, reader, n); err != nil {
+						return
+					}
+				}
+
+				conn.Write([]byte(reply + "\x00"))
+			}(conn)
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestFileSystem_ScanFile(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_antivirus_max_size", "100", 0)
+	cache.Set("setting_antivirus_timeout", "5", 0)
+	cache.Set("setting_antivirus_action", AntivirusActionReject, 0)
+	defer cache.Deletes([]string{"antivirus_max_size", "antivirus_timeout", "antivirus_clamd_address", "antivirus_action"}, "setting_")
+
+	newFile := func(size uint64) *model.File {
+		file := &model.File{Name: "a.exe", SourceName: "1/a.exe", Size: size, UserID: 1}
+		file.ID = 1
+		return file
+	}
+	newFs := func(content string) (*FileSystem, *FileHeaderMock) {
+		handler := new(FileHeaderMock)
+		handler.On("Get", testMock.Anything, "1/a.exe").Return(MockRSC{rs: strings.NewReader(content)}, nil)
+		return &FileSystem{User: &model.User{}, Handler: handler}, handler
+	}
+
+	// 超出大小限制，跳过扫描
+	{
+		fs, handler := newFs("")
+		file := newFile(101)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.ScanFile(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(model.ScanStatusSkipped, file.ScanStatus)
+		handler.AssertNotCalled(t, "Get", testMock.Anything, testMock.Anything)
+	}
+
+	// 无法连接 clamd，不影响上传
+	{
+		cache.Set("setting_antivirus_clamd_address", "tcp://127.0.0.1:1", 0)
+		fs, _ := newFs("content")
+		file := newFile(7)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.ScanFile(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(model.ScanStatusFailed, file.ScanStatus)
+		a.NotEmpty(file.ScanResult)
+	}
+
+	// 未检出病毒
+	{
+		cache.Set("setting_antivirus_clamd_address", fakeClamd(t, "stream: OK"), 0)
+		fs, _ := newFs("content")
+		file := newFile(7)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.ScanFile(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(model.ScanStatusClean, file.ScanStatus)
+	}
+
+	cache.Set("setting_antivirus_clamd_address", fakeClamd(t, "stream: Eicar-Signature FOUND"), 0)
+
+	// 检出病毒，隔离
+	{
+		cache.Set("setting_antivirus_action", AntivirusActionQuarantine, 0)
+		fs, _ := newFs("virus")
+		file := newFile(5)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.ScanFile(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.True(file.IsQuarantined())
+		a.Equal("Eicar-Signature", file.ScanResult)
+	}
+
+	// 检出病毒，拒绝时删除记录失败
+	{
+		cache.Set("setting_antivirus_action", AntivirusActionReject, 0)
+		fs, handler := newFs("virus")
+		file := newFile(5)
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.ErrorIs(fs.ScanFile(context.Background(), file), ErrDBDeleteObjects)
+		a.NoError(mock.ExpectationsWereMet())
+		handler.AssertNotCalled(t, "Delete", testMock.Anything, testMock.Anything)
+	}
+
+	// 检出病毒，拒绝并删除文件
+	{
+		fs, handler := newFs("virus")
+		handler.On("Delete", testMock.Anything, []string{"1/a.exe"}).Return([]string{}, nil)
+		file := newFile(5)
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.ErrorIs(fs.ScanFile(context.Background(), file), ErrFileInfected)
+		a.NoError(mock.ExpectationsWereMet())
+		handler.AssertExpectations(t)
+	}
+}
+
+func TestHookAntivirusScan(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 无文件模型
+	a.NoError(HookAntivirusScan(context.Background(), fs, &fsctx.FileStream{}))
+
+	// 存储策略未启用
+	file := &model.File{Policy: model.Policy{Type: "mock"}}
+	file.Policy.ID = 1
+	a.NoError(HookAntivirusScan(context.Background(), fs, &fsctx.FileStream{Model: file}))
+}
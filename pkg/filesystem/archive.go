@@ -3,6 +3,7 @@ package filesystem
 import (
 	"archive/zip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -10,10 +11,12 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/mholt/archiver/v4"
@@ -70,45 +73,53 @@ func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs,
 		files[i].Position = ""
 	}
 
-	// 创建压缩文件Writer
+	// 创建压缩文件Writer，内容直接写入 writer，不产生临时文件
 	zipWriter := zip.NewWriter(writer)
 	defer zipWriter.Close()
 
-	ctx = reqContext
-
-	// 压缩各个目录及文件
+	// 压缩各个目录及文件，请求取消或写入失败时中止
 	for i := 0; i < len(folders); i++ {
-		select {
-		case <-reqContext.Done():
-			// 取消压缩请求
-			return ErrClientCanceled
-		default:
-			fs.doCompress(reqContext, nil, &folders[i], zipWriter, isArchive)
+		if err := fs.doCompress(reqContext, nil, &folders[i], zipWriter, isArchive); err != nil {
+			return err
 		}
-
 	}
 	for i := 0; i < len(files); i++ {
-		select {
-		case <-reqContext.Done():
-			// 取消压缩请求
-			return ErrClientCanceled
-		default:
-			fs.doCompress(reqContext, &files[i], nil, zipWriter, isArchive)
+		if err := fs.doCompress(reqContext, &files[i], nil, zipWriter, isArchive); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *model.Folder, zipWriter *zip.Writer, isArchive bool) {
+// contextReader 上下文取消后读取即返回错误，用于及时中止远程文件的传输
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if r.ctx.Err() != nil {
+		return 0, ErrClientCanceled
+	}
+	return r.r.Read(p)
+}
+
+// doCompress 将文件或目录写入压缩文件，无法读取的文件将被跳过
+func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *model.Folder, zipWriter *zip.Writer, isArchive bool) error {
+	if ctx.Err() != nil {
+		// 取消压缩请求
+		return ErrClientCanceled
+	}
+
 	// 如果对象是文件
 	if file != nil {
 		// 切换上传策略
 		fs.Policy = file.GetPolicy()
 		err := fs.DispatchHandler()
 		if err != nil {
-			util.Log().Warning("Failed to compress file %q: %s", file.Name, err)
-			return
+			util.LogCtx(ctx).Warning("Failed to compress file %q: %s", file.Name, err)
+			return nil
 		}
 
 		// 获取文件内容
@@ -117,8 +128,8 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 			file.SourceName,
 		)
 		if err != nil {
-			util.Log().Debug("Failed to open %q: %s", file.Name, err)
-			return
+			util.LogCtx(ctx).Debug("Failed to open %q: %s", file.Name, err)
+			return nil
 		}
 		if closer, ok := fileToZip.(io.Closer); ok {
 			defer closer.Close()
@@ -140,17 +151,22 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 
 		writer, err := zipWriter.CreateHeader(header)
 		if err != nil {
-			return
+			return err
 		}
 
-		_, err = io.Copy(writer, fileToZip)
+		// 已写入的条目不完整时压缩文件即已损坏，中止后续处理
+		if _, err := io.Copy(writer, contextReader{ctx: ctx, r: fileToZip}); err != nil {
+			return fmt.Errorf("failed to compress file %q: %w", file.Name, err)
+		}
 	} else if folder != nil {
 		// 对象是目录
 		// 获取子文件
 		subFiles, err := folder.GetChildFiles()
 		if err == nil && len(subFiles) > 0 {
 			for i := 0; i < len(subFiles); i++ {
-				fs.doCompress(ctx, &subFiles[i], nil, zipWriter, isArchive)
+				if err := fs.doCompress(ctx, &subFiles[i], nil, zipWriter, isArchive); err != nil {
+					return err
+				}
 			}
 
 		}
@@ -158,10 +174,14 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 		subFolders, err := folder.GetChildFolder()
 		if err == nil && len(subFolders) > 0 {
 			for i := 0; i < len(subFolders); i++ {
-				fs.doCompress(ctx, nil, &subFolders[i], zipWriter, isArchive)
+				if err := fs.doCompress(ctx, nil, &subFolders[i], zipWriter, isArchive); err != nil {
+					return err
+				}
 			}
 		}
 	}
+
+	return nil
 }
 
 // Decompress 解压缩给定压缩文件到dst目录
@@ -176,7 +196,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		// 结束时删除临时压缩文件
 		if tempZipFilePath != "" {
 			if err := os.Remove(tempZipFilePath); err != nil {
-				util.Log().Warning("Failed to delete temp archive file %q: %s", tempZipFilePath, err)
+				util.LogCtx(ctx).Warning("Failed to delete temp archive file %q: %s", tempZipFilePath, err)
 			}
 		}
 	}()
@@ -197,7 +217,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 
 	zipFile, err := util.CreatNestedFile(tempZipFilePath)
 	if err != nil {
-		util.Log().Warning("Failed to create temp archive file %q: %s", tempZipFilePath, err)
+		util.LogCtx(ctx).Warning("Failed to create temp archive file %q: %s", tempZipFilePath, err)
 		tempZipFilePath = ""
 		return err
 	}
@@ -206,7 +226,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 	// 下载前先判断是否是可解压的格式
 	format, readStream, err := archiver.Identify(fs.FileTarget[0].SourceName, fileStream)
 	if err != nil {
-		util.Log().Warning("Failed to detect compressed format of file %q: %s", fs.FileTarget[0].SourceName, err)
+		util.LogCtx(ctx).Warning("Failed to detect compressed format of file %q: %s", fs.FileTarget[0].SourceName, err)
 		return err
 	}
 
@@ -228,7 +248,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 	if isZip {
 		_, err = io.Copy(zipFile, readStream)
 		if err != nil {
-			util.Log().Warning("Failed to write temp archive file %q: %s", tempZipFilePath, err)
+			util.LogCtx(ctx).Warning("Failed to write temp archive file %q: %s", tempZipFilePath, err)
 			return err
 		}
 
@@ -253,6 +273,9 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		worker <- i
 	}
 
+	// 容量耗尽后不再继续解压后续文件
+	var quotaExceeded int32
+
 	// 上传文件函数
 	uploadFunc := func(fileStream io.ReadCloser, size int64, savePath, rawPath string) {
 		defer func() {
@@ -261,7 +284,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 				wg.Done()
 			}
 			if err := recover(); err != nil {
-				util.Log().Warning("Error while uploading files inside of archive file.")
+				util.LogCtx(ctx).Warning("Error while uploading files inside of archive file.")
 				fmt.Println(err)
 			}
 		}()
@@ -274,17 +297,25 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		}, true)
 		fileStream.Close()
 		if err != nil {
-			util.Log().Debug("Failed to upload file %q in archive file: %s, skipping...", rawPath, err)
+			var appErr serializer.AppError
+			if errors.As(err, &appErr) && appErr.Code == serializer.CodeInsufficientCapacity {
+				atomic.StoreInt32(&quotaExceeded, 1)
+			}
+			util.LogCtx(ctx).Debug("Failed to upload file %q in archive file: %s, skipping...", rawPath, err)
 		}
 	}
 
 	// 解压缩文件，回调函数如果出错会停止解压的下一步进行，全部return nil
 	err = extractor.Extract(ctx, reader, nil, func(ctx context.Context, f archiver.File) error {
+		if atomic.LoadInt32(&quotaExceeded) == 1 {
+			return ErrInsufficientCapacity
+		}
+
 		rawPath := util.FormSlash(f.NameInArchive)
 		savePath := path.Join(dst, rawPath)
 		// 路径是否合法
 		if !strings.HasPrefix(savePath, util.FillSlash(path.Clean(dst))) {
-			util.Log().Warning("%s: illegal file path", f.NameInArchive)
+			util.LogCtx(ctx).Warning("%s: illegal file path", f.NameInArchive)
 			return nil
 		}
 
@@ -297,7 +328,7 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		// 上传文件
 		fileStream, err := f.Open()
 		if err != nil {
-			util.Log().Warning("Failed to open file %q in archive file: %s, skipping...", rawPath, err)
+			util.LogCtx(ctx).Warning("Failed to open file %q in archive file: %s, skipping...", rawPath, err)
 			return nil
 		}
 
@@ -311,6 +342,9 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 		return nil
 	})
 	wg.Wait()
+	if err == nil && atomic.LoadInt32(&quotaExceeded) == 1 {
+		err = ErrInsufficientCapacity
+	}
 	return err
 
 }
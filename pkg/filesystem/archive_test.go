@@ -1,12 +1,14 @@
 package filesystem
 
 import (
+	"archive/zip"
 	"bytes"
 	"context"
 	"errors"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	testMock "github.com/stretchr/testify/mock"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -119,6 +121,90 @@ func TestFileSystem_Compress(t *testing.T) {
 
 }
 
+type failedWriter struct{}
+
+func (failedWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func TestFileSystem_CompressAbort(t *testing.T) {
+	asserts := assert.New(t)
+	fs := FileSystem{
+		User: &model.User{Model: gorm.Model{ID: 1}},
+	}
+
+	source, err := ioutil.TempFile("", "compress")
+	asserts.NoError(err)
+	defer os.Remove(source.Name())
+	_, err = source.WriteString(strings.Repeat("cloudreve", 1024))
+	asserts.NoError(err)
+	source.Close()
+	asserts.NoError(cache.Set("policy_1", model.Policy{Type: "local"}, -1))
+
+	// 写入失败时中止
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, 2, 1).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id", "size"}).
+					AddRow(1, "1.txt", source.Name(), 1, 9216).
+					AddRow(2, "2.txt", source.Name(), 1, 9216),
+			)
+		err := fs.Compress(context.Background(), failedWriter{}, []uint{}, []uint{1, 2}, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 传输过程中请求取消
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		r := contextReader{ctx: ctx, r: strings.NewReader("content")}
+		n, err := r.Read(make([]byte, 3))
+		asserts.NoError(err)
+		asserts.Equal(3, n)
+
+		cancel()
+		_, err = r.Read(make([]byte, 3))
+		asserts.Equal(ErrClientCanceled, err)
+	}
+}
+
+func TestFileSystem_DecompressQuotaExceeded(t *testing.T) {
+	asserts := assert.New(t)
+	dir, err := ioutil.TempDir("", "decompress")
+	asserts.NoError(err)
+	defer os.RemoveAll(dir)
+
+	// 创建包含多个文件的压缩包
+	archivePath := filepath.Join(dir, "1.zip")
+	archiveFile, err := os.Create(archivePath)
+	asserts.NoError(err)
+	zipWriter := zip.NewWriter(archiveFile)
+	for _, name := range []string{"a.txt", "b.txt", "sub/c.txt"} {
+		w, err := zipWriter.Create(name)
+		asserts.NoError(err)
+		_, err = w.Write([]byte("content"))
+		asserts.NoError(err)
+	}
+	asserts.NoError(zipWriter.Close())
+	asserts.NoError(archiveFile.Close())
+
+	asserts.NoError(cache.Set("setting_temp_path", dir, -1))
+	asserts.NoError(cache.Set("setting_max_parallel_transfer", "1", -1))
+	asserts.NoError(cache.Set("policy_1", model.Policy{Type: "local"}, -1))
+
+	// 用户容量已用尽
+	fs := FileSystem{
+		User: &model.User{
+			Model:  gorm.Model{ID: 1},
+			Policy: model.Policy{Type: "local"},
+		},
+		FileTarget: []model.File{{Name: "1.zip", SourceName: archivePath, PolicyID: 1}},
+	}
+	err = fs.Decompress(context.Background(), "/1.zip", "/", "")
+	asserts.True(errors.Is(err, ErrInsufficientCapacity))
+}
+
 type MockNopRSC string
 
 func (m MockNopRSC) Read(b []byte) (int, error) {
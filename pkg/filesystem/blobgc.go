@@ -0,0 +1,249 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     孤立文件回收
+   ================
+*/
+
+const (
+	// blobScanBatchSize 读取文件记录时每批读取的条数
+	blobScanBatchSize = 500
+	// blobDeleteBatchSize 每次请求存储端删除的物理文件数
+	blobDeleteBatchSize = 100
+	// blobReportMaxSamples 报告中最多记录的孤立文件及缺失文件样例数
+	blobReportMaxSamples = 100
+)
+
+// BlobGCOptions 孤立文件回收选项
+type BlobGCOptions struct {
+	// DryRun 仅生成报告，不删除任何物理文件及记录
+	DryRun bool
+	// RemoveMissing 删除物理文件已丢失的文件记录
+	RemoveMissing bool
+	// GracePeriod 最后修改时间在此期间内的物理文件及记录不做处理，避免误删上传中的文件
+	GracePeriod time.Duration
+}
+
+// BlobReport 存储策略的孤立文件扫描报告
+type BlobReport struct {
+	PolicyID   uint   `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	// Root 扫描的存储端目录，由存储策略的目录命名规则确定
+	Root string `json:"root"`
+	// Scanned 扫描的物理文件数
+	Scanned int `json:"scanned"`
+	// Orphans 没有对应文件记录的物理文件数及总大小
+	Orphans    int    `json:"orphans"`
+	OrphanSize uint64 `json:"orphan_size"`
+	// Missing 物理文件已丢失的文件记录数
+	Missing int `json:"missing"`
+	// Deleted、Removed 已删除的孤立物理文件数及文件记录数，Failed 为删除失败的物理文件数
+	Deleted int `json:"deleted"`
+	Removed int `json:"removed"`
+	Failed  int `json:"failed"`
+	// 孤立物理文件路径及缺失文件记录 ID 的样例，各最多记录 blobReportMaxSamples 个
+	OrphanSamples  []string `json:"orphan_samples,omitempty"`
+	MissingSamples []uint   `json:"missing_samples,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// CollectOrphanBlobs 扫描存储策略中没有对应文件记录的物理文件，以及物理文件已丢失的
+// 文件记录，按选项删除后返回报告。先列取存储端再读取记录，列取期间新建的记录不会被误判
+func (fs *FileSystem) CollectOrphanBlobs(ctx context.Context, policy *model.Policy, opts BlobGCOptions) (*BlobReport, error) {
+	report := &BlobReport{PolicyID: policy.ID, PolicyName: policy.Name}
+	root := blobRoot(policy)
+	if root == "" {
+		return report, ErrBlobRootUnknown
+	}
+	report.Root = root
+
+	fs.Policy = policy
+	if err := fs.DispatchHandler(); err != nil {
+		return report, err
+	}
+
+	objects, err := fs.Handler.List(ctx, root, true)
+	if err != nil {
+		return report, ErrIO.WithError(err)
+	}
+
+	deadline := time.Now().Add(-opts.GracePeriod)
+	existed := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		if !object.IsDir {
+			existed[blobKey(policy, object.Source)] = true
+		}
+	}
+
+	referenced, missing, err := scanBlobReferences(policy, root, existed, deadline)
+	if err != nil {
+		return report, ErrDBListObjects.WithError(err)
+	}
+
+	var orphans []string
+	for _, object := range objects {
+		if object.IsDir {
+			continue
+		}
+
+		report.Scanned++
+		if referenced[blobKey(policy, object.Source)] || object.LastModify.After(deadline) {
+			continue
+		}
+
+		report.Orphans++
+		report.OrphanSize += object.Size
+		if len(report.OrphanSamples) < blobReportMaxSamples {
+			report.OrphanSamples = append(report.OrphanSamples, object.Source)
+		}
+		orphans = append(orphans, object.Source)
+	}
+
+	report.Missing = len(missing)
+	for i := 0; i < len(missing) && i < blobReportMaxSamples; i++ {
+		report.MissingSamples = append(report.MissingSamples, missing[i].ID)
+	}
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	for i := 0; i < len(orphans); i += blobDeleteBatchSize {
+		end := i + blobDeleteBatchSize
+		if end > len(orphans) {
+			end = len(orphans)
+		}
+
+		batch := orphans[i:end]
+		failed, err := fs.Handler.Delete(ctx, batch)
+		if err != nil {
+			util.LogCtx(ctx).Warning("Failed to delete %d orphan object(s) in policy %q: %s", len(failed), policy.Name, err)
+		}
+		report.Failed += len(failed)
+		report.Deleted += len(batch) - len(failed)
+	}
+
+	if opts.RemoveMissing && len(missing) > 0 {
+		removed, err := removeMissingFiles(missing)
+		report.Removed = removed
+		if err != nil {
+			return report, ErrDBDeleteObjects.WithError(err)
+		}
+	}
+
+	return report, nil
+}
+
+// scanBlobReferences 读取存储策略的全部文件记录及历史版本，返回被引用的物理文件，
+// 以及根目录下物理文件已丢失的文件记录
+func scanBlobReferences(policy *model.Policy, root string, existed map[string]bool,
+	deadline time.Time) (map[string]bool, []*model.File, error) {
+	referenced := make(map[string]bool)
+	rootKey := blobKey(policy, root)
+	var missing []*model.File
+
+	cursor := uint(0)
+	for {
+		files, err := model.ListPolicyFilesAfter(policy.ID, cursor, blobScanBatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for i := range files {
+			file := &files[i]
+			cursor = file.ID
+
+			// 上传中及回收站中的文件同样占用物理文件
+			key := blobKey(policy, file.SourceName)
+			referenced[key] = true
+			referenced[blobKey(policy, file.ThumbFile())] = true
+			for _, hls := range file.HLSFiles() {
+				referenced[blobKey(policy, hls)] = true
+			}
+
+			if file.DeletedAt == nil && file.UploadSessionID == nil && !existed[key] &&
+				strings.HasPrefix(key, rootKey+"/") && file.UpdatedAt.Before(deadline) {
+				missing = append(missing, file)
+			}
+		}
+
+		if len(files) < blobScanBatchSize {
+			break
+		}
+	}
+
+	versions, err := model.GetFileVersionSourcesByPolicy(policy.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, source := range versions {
+		referenced[blobKey(policy, source)] = true
+	}
+
+	return referenced, missing, nil
+}
+
+// removeMissingFiles 按所有者删除物理文件已丢失的文件记录，返回删除的记录数
+func removeMissingFiles(files []*model.File) (int, error) {
+	byUser := make(map[uint][]*model.File)
+	for _, file := range files {
+		byUser[file.UserID] = append(byUser[file.UserID], file)
+	}
+
+	removed := 0
+	for uid, userFiles := range byUser {
+		if err := model.DeleteFiles(userFiles, uid); err != nil {
+			return removed, err
+		}
+		removed += len(userFiles)
+	}
+
+	return removed, nil
+}
+
+// blobRoot 返回存储策略目录命名规则中不含变量的前缀目录，无法确定时返回空字符串。
+// 只扫描该目录，避免误删存储端中不属于本系统的文件
+func blobRoot(policy *model.Policy) string {
+	rule := filepath.ToSlash(policy.DirNameRule)
+	if i := strings.Index(rule, "{"); i >= 0 {
+		rule = rule[:i]
+		if !strings.HasSuffix(rule, "/") {
+			rule = path.Dir(rule)
+		}
+	}
+
+	rule = strings.TrimSuffix(path.Clean(rule), "/")
+	if rule == "." || rule == "" || rule == "/" {
+		return ""
+	}
+
+	return rule
+}
+
+// blobKey 将物理文件路径转换为统一的格式以便比较。本机存储策略中的绝对路径位于
+// 程序目录下时转换为相对路径，与文件记录中的源文件名一致
+func blobKey(policy *model.Policy, source string) string {
+	if policy.Type == "local" && filepath.IsAbs(source) {
+		base := util.RelativePath("")
+		if rel, err := filepath.Rel(base, source); err == nil && rel != ".." &&
+			!strings.HasPrefix(rel, ".."+string(os.PathSeparator)) {
+			source = rel
+		}
+		return path.Clean(filepath.ToSlash(source))
+	}
+
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(source)), "/")
+}
@@ -0,0 +1,142 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func TestFileSystem_CollectOrphanBlobs(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_thumb_file_suffix", "._thumb", 0)
+	policy := &model.Policy{Type: "mock", DirNameRule: "uploads/{uid}/{path}"}
+	policy.ID = 1
+	old := time.Now().Add(-48 * time.Hour)
+	objects := []response.Object{
+		{Source: "uploads/1", IsDir: true},
+		{Source: "uploads/1/a.txt", Size: 1, LastModify: old},
+		{Source: "uploads/1/a.txt._thumb", Size: 1, LastModify: old},
+		{Source: "uploads/1/v1", Size: 1, LastModify: old},
+		{Source: "uploads/1/orphan", Size: 10, LastModify: old},
+		{Source: "uploads/1/new", Size: 1, LastModify: time.Now()},
+	}
+	expectReferences := func() {
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "source_name", "updated_at"}).
+				AddRow(1, 1, "uploads/1/a.txt", old).
+				AddRow(2, 1, "uploads/1/lost", old).
+				AddRow(3, 1, "uploads/1/lost2", time.Now()).
+				AddRow(4, 1, "other/lost", old))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"source_name"}).AddRow("uploads/1/v1"))
+	}
+
+	// 无法确定根目录
+	{
+		fs := &FileSystem{User: &model.User{}}
+		_, err := fs.CollectOrphanBlobs(context.Background(), &model.Policy{Type: "mock", DirNameRule: "{uid}"}, BlobGCOptions{})
+		a.ErrorIs(err, ErrBlobRootUnknown)
+	}
+
+	// 列取失败
+	{
+		handler := new(FileHeaderMock)
+		handler.On("List", testMock.Anything, "uploads", true).Return([]response.Object{}, errors.New("error"))
+		fs := &FileSystem{User: &model.User{}, Handler: handler}
+		_, err := fs.CollectOrphanBlobs(context.Background(), policy, BlobGCOptions{})
+		a.ErrorIs(err, ErrIO)
+	}
+
+	// 仅生成报告
+	{
+		handler := new(FileHeaderMock)
+		handler.On("List", testMock.Anything, "uploads", true).Return(objects, nil)
+		fs := &FileSystem{User: &model.User{}, Handler: handler}
+		expectReferences()
+		report, err := fs.CollectOrphanBlobs(context.Background(), policy, BlobGCOptions{DryRun: true, RemoveMissing: true, GracePeriod: time.Hour})
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("uploads", report.Root)
+		a.Equal(5, report.Scanned)
+		a.Equal(1, report.Orphans)
+		a.EqualValues(10, report.OrphanSize)
+		a.Equal([]string{"uploads/1/orphan"}, report.OrphanSamples)
+		a.Equal(1, report.Missing)
+		a.Equal([]uint{2}, report.MissingSamples)
+		a.Zero(report.Deleted)
+		handler.AssertNotCalled(t, "Delete", testMock.Anything, testMock.Anything)
+	}
+
+	// 删除孤立文件及缺失的文件记录
+	{
+		handler := new(FileHeaderMock)
+		handler.On("List", testMock.Anything, "uploads", true).Return(objects, nil)
+		handler.On("Delete", testMock.Anything, []string{"uploads/1/orphan"}).Return([]string{}, nil)
+		fs := &FileSystem{User: &model.User{}, Handler: handler}
+		expectReferences()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		report, err := fs.CollectOrphanBlobs(context.Background(), policy, BlobGCOptions{RemoveMissing: true, GracePeriod: time.Hour})
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(1, report.Deleted)
+		a.Equal(1, report.Removed)
+		a.Zero(report.Failed)
+		handler.AssertExpectations(t)
+	}
+
+	// 物理文件删除失败
+	{
+		handler := new(FileHeaderMock)
+		handler.On("List", testMock.Anything, "uploads", true).Return(objects, nil)
+		handler.On("Delete", testMock.Anything, []string{"uploads/1/orphan"}).Return([]string{"uploads/1/orphan"}, errors.New("error"))
+		fs := &FileSystem{User: &model.User{}, Handler: handler}
+		expectReferences()
+		report, err := fs.CollectOrphanBlobs(context.Background(), policy, BlobGCOptions{GracePeriod: time.Hour})
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Zero(report.Deleted)
+		a.Equal(1, report.Failed)
+		a.Zero(report.Removed)
+	}
+}
+
+func TestBlobRoot(t *testing.T) {
+	a := assert.New(t)
+	testCases := map[string]string{
+		"uploads/{uid}/{path}": "uploads",
+		"/uploads/{date}":      "/uploads",
+		"data/files":           "data/files",
+		"data/up_{uid}":        "data",
+		"up_{uid}":             "",
+		"{uid}/{path}":         "",
+		"/":                    "",
+		"":                     "",
+	}
+	for rule, expected := range testCases {
+		a.Equal(expected, blobRoot(&model.Policy{DirNameRule: rule}), rule)
+	}
+}
+
+func TestBlobKey(t *testing.T) {
+	a := assert.New(t)
+	local := &model.Policy{Type: "local"}
+	remote := &model.Policy{Type: "oss"}
+
+	a.Equal("uploads/1/a", blobKey(local, "uploads/1/a"))
+	a.Equal("uploads/1/a", blobKey(local, filepath.Join(util.RelativePath("uploads"), "1", "a")))
+	a.Equal("/outside/a", blobKey(local, "/outside/a"))
+	a.Equal("uploads/1/a", blobKey(remote, "/uploads//1/a"))
+}
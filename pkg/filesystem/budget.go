@@ -0,0 +1,54 @@
+package filesystem
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// chargeBudget 为当前存储策略记录一次访问及按文件大小预计的流量，
+// 用量达到提醒阈值时通知管理员；预算耗尽且开启降级时拒绝匿名访问
+func (fs *FileSystem) chargeBudget(file *model.File) error {
+	policy := fs.Policy
+	if policy == nil || !policy.HasBudget() {
+		return nil
+	}
+
+	month := model.UsageMonth(time.Now())
+	if policy.OptionsSerialized.BudgetDegrade && (fs.User == nil || fs.User.ID == 0) {
+		usage, err := model.GetPolicyUsage(policy.ID, month)
+		if err == nil && policy.BudgetExhausted(usage) {
+			return ErrBudgetExhausted
+		}
+	}
+
+	usage, err := model.ConsumePolicyUsage(policy.ID, month, file.Size)
+	if err != nil {
+		util.Log().Warning("Failed to record usage of policy %q: %s", policy.Name, err)
+		return nil
+	}
+
+	if level := policy.BudgetAlertLevel(usage); level > usage.AlertLevel {
+		if raised, err := usage.RaiseAlertLevel(level); err == nil && raised {
+			go sendBudgetAlert(policy.Name, month, level)
+		}
+	}
+
+	return nil
+}
+
+// sendBudgetAlert 向初始管理员发送存储策略用量提醒
+func sendBudgetAlert(policyName, month string, percent int) {
+	admin, err := model.GetUserByID(1)
+	if err != nil {
+		util.Log().Warning("Failed to find admin user for budget alert: %s", err)
+		return
+	}
+
+	title, body := email.NewBudgetAlertEmail(policyName, month, percent)
+	if err := email.Send(admin.Email, title, body); err != nil {
+		util.Log().Warning("Failed to send budget alert of policy %q: %s", policyName, err)
+	}
+}
@@ -0,0 +1,60 @@
+package filesystem
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_ChargeBudget(t *testing.T) {
+	a := assert.New(t)
+	file := &model.File{Size: 10}
+
+	// 未设置预算
+	{
+		fs := &FileSystem{User: &model.User{}, Policy: &model.Policy{}}
+		a.NoError(fs.chargeBudget(file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	policy := &model.Policy{}
+	policy.ID = 1
+	policy.OptionsSerialized.RequestBudget = 10
+	policy.OptionsSerialized.BudgetDegrade = true
+
+	// 预算耗尽，拒绝匿名访问
+	{
+		fs := &FileSystem{User: &model.User{}, Policy: policy}
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "requests"}).AddRow(1, 10))
+		a.ErrorIs(fs.chargeBudget(file), ErrBudgetExhausted)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 记录用量
+	{
+		user := &model.User{}
+		user.ID = 1
+		fs := &FileSystem{User: user, Policy: policy}
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "requests", "traffic"}).AddRow(1, 1, 10))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)policy_usages(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.chargeBudget(file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 记录失败时不影响访问
+	{
+		user := &model.User{}
+		user.ID = 1
+		fs := &FileSystem{User: user, Policy: policy}
+		mock.ExpectQuery("SELECT(.+)policy_usages(.+)").WillReturnError(errors.New("error"))
+		a.NoError(fs.chargeBudget(file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,39 @@
+package filesystem
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// SharedRootName 「与我共享」虚拟目录的名称，其下挂载其他用户共享给当前用户的目录
+const SharedRootName = "Shared with me"
+
+// SplitSharedPath 拆分位于「与我共享」虚拟目录下的路径，返回挂载名称和挂载点内的路径。
+// 路径为虚拟目录本身时 mount 为空，不在虚拟目录下时 ok 为 false
+func SplitSharedPath(p string) (mount, rest string, ok bool) {
+	return splitVirtualPath(SharedRootName, p)
+}
+
+// FindCollaboration 按挂载名称查找共享记录
+func FindCollaboration(collabs []model.Collaboration, mount string) *model.Collaboration {
+	for i := range collabs {
+		if collabs[i].MountName == mount {
+			return &collabs[i]
+		}
+	}
+
+	return nil
+}
+
+// NewCollaborationFileSystem 以共享目录所有者的身份创建文件系统，根目录限定为共享的目录
+func NewCollaborationFileSystem(collab *model.Collaboration) (*FileSystem, error) {
+	fs, err := NewFileSystem(collab.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	root := *collab.Folder
+	root.Position = ""
+	root.Name = "/"
+	fs.Root = &root
+	return fs, nil
+}
@@ -0,0 +1,74 @@
+package filesystem
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitSharedPath(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不在虚拟目录下
+	_, _, ok := SplitSharedPath("/")
+	asserts.False(ok)
+	_, _, ok = SplitSharedPath("/Shared with me2/a")
+	asserts.False(ok)
+
+	// 虚拟目录本身
+	mount, rest, ok := SplitSharedPath("/Shared with me")
+	asserts.True(ok)
+	asserts.Equal("", mount)
+	asserts.Equal("/", rest)
+
+	// 挂载点
+	mount, rest, ok = SplitSharedPath("/Shared with me/Docs (2)/")
+	asserts.True(ok)
+	asserts.Equal("Docs (2)", mount)
+	asserts.Equal("/", rest)
+
+	// 挂载点内的路径
+	mount, rest, ok = SplitSharedPath("/Shared with me/Docs/a/b.txt")
+	asserts.True(ok)
+	asserts.Equal("Docs", mount)
+	asserts.Equal("/a/b.txt", rest)
+}
+
+func TestFindCollaboration(t *testing.T) {
+	asserts := assert.New(t)
+	collabs := []model.Collaboration{{MountName: "Docs"}, {MountName: "Docs (2)"}}
+
+	asserts.Equal(&collabs[1], FindCollaboration(collabs, "Docs (2)"))
+	asserts.Nil(FindCollaboration(collabs, "Photos"))
+}
+
+func TestNewCollaborationFileSystem(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &model.Folder{Name: "Docs", Position: "/a", OwnerID: 1}
+	folder.ID = 2
+	collab := &model.Collaboration{
+		Folder: folder,
+		Owner:  &model.User{Policy: model.Policy{Type: "local"}},
+	}
+	collab.Owner.ID = 1
+
+	// 成功
+	{
+		fs, err := NewCollaborationFileSystem(collab)
+		asserts.NoError(err)
+		asserts.EqualValues(1, fs.User.ID)
+		asserts.EqualValues(2, fs.Root.ID)
+		asserts.Equal("/", fs.Root.Name)
+		asserts.Equal("", fs.Root.Position)
+		// 不修改原始目录
+		asserts.Equal("Docs", folder.Name)
+	}
+
+	// 存储策略无效
+	{
+		collab.Owner.Policy.Type = "unknown"
+		_, err := NewCollaborationFileSystem(collab)
+		asserts.Error(err)
+	}
+}
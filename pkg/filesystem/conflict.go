@@ -0,0 +1,63 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+)
+
+// ConflictName 生成冲突副本的文件名，如 report (conflict 2023-03-05 080910).docx
+func ConflictName(name string, now time.Time) string {
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s (conflict %s)%s", strings.TrimSuffix(name, ext), now.Format("2006-01-02 150405"), ext)
+}
+
+// maxAvailableNameTries 查找不重名的文件名时最多尝试的编号
+const maxAvailableNameTries = 100
+
+// AvailableName 返回 dir 下不与已有对象重名的文件名，重名时依次尝试 report (1).docx、report (2).docx 等
+func (fs *FileSystem) AvailableName(dir, name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 1; i <= maxAvailableNameTries; i++ {
+		fullPath := path.Join(dir, candidate)
+		if exist, _ := fs.IsFileExist(fullPath); !exist {
+			if exist, _ := fs.IsPathExist(fullPath); !exist {
+				return candidate
+			}
+		}
+
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	return ConflictName(name, time.Now())
+}
+
+// SaveConflictCopy 客户端基于旧版本覆盖 origin 时，将写入的内容另存为同目录下的冲突副本
+func (fs *FileSystem) SaveConflictCopy(ctx context.Context, origin *model.File, file *fsctx.FileStream) (*model.File, error) {
+	parent, ok := fs.folderPath(origin.FolderID)
+	if !ok {
+		return nil, ErrPathNotExist
+	}
+
+	file.Name = ConflictName(origin.Name, time.Now())
+	file.VirtualPath = parent
+	file.Mode = 0
+	if err := fs.UploadFromStream(ctx, file, true); err != nil {
+		return nil, err
+	}
+
+	copied, ok := file.Model.(*model.File)
+	if !ok {
+		return nil, ErrObjectNotExist
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,56 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflictName(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Date(2023, 3, 5, 8, 9, 10, 0, time.UTC)
+
+	asserts.Equal("report (conflict 2023-03-05 080910).docx", ConflictName("report.docx", now))
+	asserts.Equal("README (conflict 2023-03-05 080910)", ConflictName("README", now))
+	asserts.Equal("a.tar (conflict 2023-03-05 080910).gz", ConflictName("a.tar.gz", now))
+}
+
+func TestFileSystem_AvailableName(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	fs.Root = &model.Folder{OwnerID: 1}
+	fs.Root.ID = 1
+
+	// 不重名
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a.txt").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1, "a.txt").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	asserts.Equal("a.txt", fs.AvailableName("/", "a.txt"))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 与文件、目录重名
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a.txt").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a (1).txt").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1, "a (1).txt").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a (2).txt").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1, "a (2).txt").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	asserts.Equal("a (2).txt", fs.AvailableName("/", "a.txt"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestFileSystem_SaveConflictCopy(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	fs.User.ID = 1
+
+	// 所在目录不存在
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err := fs.SaveConflictCopy(context.Background(), &model.File{Name: "report.docx", FolderID: 2}, &fsctx.FileStream{})
+	asserts.Equal(ErrPathNotExist, err)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
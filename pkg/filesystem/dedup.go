@@ -0,0 +1,180 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+	 内容去重相关
+   ================
+*/
+
+// HashContent 计算物理文件内容的 SHA-256，加密存储的文件计算的是明文的哈希
+func (fs *FileSystem) HashContent(ctx context.Context, source string) (string, error) {
+	rs, err := fs.Handler.Get(ctx, source)
+	if err != nil {
+		return "", ErrIO.WithError(err)
+	}
+	defer rs.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, rs); err != nil {
+		return "", ErrIO.WithError(err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Deduplicate 计算并记录文件内容的 SHA-256，存储策略中已有更早上传的相同内容时，
+// 将文件指向已有的物理文件，并删除不再被引用的原物理文件
+func (fs *FileSystem) Deduplicate(ctx context.Context, file *model.File) error {
+	hash, err := fs.HashContent(ctx, file.SourceName)
+	if err != nil {
+		return err
+	}
+
+	if err := file.UpdateSHA256(hash); err != nil {
+		return ErrDBMoveObjects.WithError(err)
+	}
+
+	// 与删除文件互斥，避免引用正在被删除的物理文件
+	unlock := model.LockSources()
+	defer unlock()
+
+	// 只引用 ID 更小的文件，并发上传相同内容时不会互相引用
+	existing, err := model.GetFileBySHA256(file.PolicyID, hash, file.Size, file.ID, 0)
+	if err != nil || existing.SourceName == file.SourceName {
+		return nil
+	}
+
+	previous := *file
+	previous.MetadataSerialized = make(map[string]string, len(file.MetadataSerialized))
+	for k, v := range file.MetadataSerialized {
+		previous.MetadataSerialized[k] = v
+	}
+
+	if err := file.UpdateSourceName(existing.SourceName); err != nil {
+		return ErrDBMoveObjects.WithError(err)
+	}
+	file.SourceName = existing.SourceName
+
+	// 原物理文件仍被其他文件引用时保留
+	orphans, err := model.RemoveFilesWithSoftLinks([]model.File{previous})
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	sources := []string{previous.SourceName}
+	if model.IsTrueVal(previous.MetadataSerialized[model.ThumbSidecarMetadataKey]) {
+		sources = append(sources, previous.ThumbFile())
+	}
+
+	if failed, err := fs.Handler.Delete(ctx, sources); err != nil {
+		util.LogCtx(ctx).Warning("Failed to delete duplicated physical file %v: %s", failed, err)
+	}
+
+	return nil
+}
+
+// HookDeduplicate 上传完成后异步计算文件内容的 SHA-256 并去重
+func HookDeduplicate(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || !file.GetPolicy().OptionsSerialized.Dedup {
+		return nil
+	}
+
+	// 上传流程仍会继续使用文件对象，异步任务中重新读取文件记录
+	user := fs.User
+	fileID := file.ID
+	policy := *file.GetPolicy()
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				util.LogCtx(ctx).Warning("Panic while deduplicating file %d: %v", fileID, err)
+			}
+		}()
+
+		files, err := model.GetFilesByIDs([]uint{fileID}, 0)
+		if err != nil || len(files) == 0 {
+			return
+		}
+
+		dedupFs, err := NewFileSystem(user)
+		if err != nil {
+			util.LogCtx(ctx).Warning("Failed to initialize filesystem for deduplication: %s", err)
+			return
+		}
+		defer dedupFs.Recycle()
+
+		files[0].Policy = policy
+		dedupFs.Policy = &files[0].Policy
+		if err := dedupFs.DispatchHandler(); err != nil {
+			util.LogCtx(ctx).Warning("Failed to dispatch policy handler for deduplication: %s", err)
+			return
+		}
+
+		if err := dedupFs.Deduplicate(context.Background(), &files[0]); err != nil {
+			util.LogCtx(ctx).Warning("Failed to deduplicate file %q: %s", files[0].Name, err)
+		}
+	}()
+
+	return nil
+}
+
+// InstantUpload 秒传：当前存储策略中已有内容相同的文件时，直接引用其物理文件创建新文件，
+// 客户端无需上传文件内容
+func (fs *FileSystem) InstantUpload(ctx context.Context, file *fsctx.FileStream, hash string) (*model.File, error) {
+	if !fs.Policy.OptionsSerialized.Dedup {
+		return nil, ErrInstantUploadMiss
+	}
+
+	// 默认只查找用户自己的文件，避免通过哈希探测其他用户是否存有某一文件
+	uid := fs.User.ID
+	if model.IsTrueVal(model.GetSettingByName("instant_upload_cross_user")) {
+		uid = 0
+	}
+
+	blob, err := model.GetFileBySHA256(fs.Policy.ID, hash, file.Size, 0, uid)
+	if err != nil {
+		return nil, ErrInstantUploadMiss.WithError(err)
+	}
+
+	file.Mode = fsctx.Nop
+	file.SavePath = blob.SourceName
+	if file.File == nil {
+		file.File = ioutil.NopCloser(strings.NewReader(""))
+	}
+
+	fs.Use("BeforeUpload", HookValidateFile)
+	fs.Use("BeforeUpload", HookValidateCapacity)
+	fs.Use("BeforeUpload", HookValidateGroupLimits)
+	fs.Use("AfterUpload", GenericAfterUpload)
+	fs.Use("AfterUpload", func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+		if newFile, ok := fileHeader.Info().Model.(*model.File); ok {
+			if err := newFile.UpdateSHA256(hash); err != nil {
+				util.LogCtx(ctx).Warning("Failed to save content hash of file %q: %s", newFile.Name, err)
+			}
+		}
+		return nil
+	})
+
+	if err := fs.Upload(ctx, file); err != nil {
+		return nil, err
+	}
+
+	newFile, _ := file.Model.(*model.File)
+	return newFile, nil
+}
@@ -0,0 +1,255 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+// helloSHA256 "hello" 的 SHA-256
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func TestFileSystem_HashContent(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 无法读取
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "a.txt").Return(MockRSC{}, errors.New("error"))
+		fs.Handler = testHandler
+		_, err := fs.HashContent(context.Background(), "a.txt")
+		a.ErrorIs(err, ErrIO)
+	}
+
+	// 成功
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "a.txt").Return(MockRSC{rs: strings.NewReader("hello")}, nil)
+		fs.Handler = testHandler
+		hash, err := fs.HashContent(context.Background(), "a.txt")
+		a.NoError(err)
+		a.Equal(helloSHA256, hash)
+	}
+}
+
+func TestFileSystem_Deduplicate(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	cache.Set("setting_thumb_file_suffix", "._thumb", 0)
+	newFile := func() *model.File {
+		file := &model.File{
+			Name:       "a.txt",
+			SourceName: "src/a.txt",
+			PolicyID:   1,
+			Size:       5,
+			MetadataSerialized: map[string]string{
+				model.ThumbSidecarMetadataKey: "true",
+			},
+		}
+		file.ID = 2
+		return file
+	}
+	newHandler := func() *FileHeaderMock {
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "src/a.txt").Return(MockRSC{rs: strings.NewReader("hello")}, nil)
+		fs.Handler = testHandler
+		return testHandler
+	}
+
+	// 没有内容相同的文件
+	{
+		testHandler := newHandler()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WithArgs(helloSHA256, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, helloSHA256, 5, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		file := newFile()
+		a.NoError(fs.Deduplicate(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(helloSHA256, file.SHA256)
+		a.Equal("src/a.txt", file.SourceName)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 改为引用已有的物理文件，原物理文件仍被引用
+	{
+		testHandler := newHandler()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "src/b.txt"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)source_name(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs("src/a.txt", 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_name", "policy_id"}).AddRow(3, "src/a.txt", 1))
+		file := newFile()
+		a.NoError(fs.Deduplicate(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("src/b.txt", file.SourceName)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 删除不再被引用的原物理文件及缩略图
+	{
+		testHandler := newHandler()
+		testHandler.On("Delete", testMock.Anything, []string{"src/a.txt", "src/a.txt._thumb"}).Return([]string{}, nil)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "src/b.txt"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)source_name(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		file := newFile()
+		a.NoError(fs.Deduplicate(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("src/b.txt", file.SourceName)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 原物理文件仍被历史版本引用
+	{
+		testHandler := newHandler()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "src/b.txt"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)source_name(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WithArgs("src/a.txt", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_name", "policy_id"}).AddRow(1, "src/a.txt", 1))
+		file := newFile()
+		a.NoError(fs.Deduplicate(context.Background(), file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("src/b.txt", file.SourceName)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 已引用相同的物理文件
+	{
+		testHandler := newHandler()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "src/a.txt"))
+		a.NoError(fs.Deduplicate(context.Background(), newFile()))
+		a.NoError(mock.ExpectationsWereMet())
+		testHandler.AssertExpectations(t)
+	}
+
+	// 更新源文件失败
+	{
+		newHandler()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "src/b.txt"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)source_name(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		a.ErrorIs(fs.Deduplicate(context.Background(), newFile()), ErrDBMoveObjects)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestHookDeduplicate(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 存储策略未开启去重
+	{
+		file := &model.File{Policy: model.Policy{Type: "local"}}
+		file.Policy.ID = 1
+		a.NoError(HookDeduplicate(context.Background(), fs, &fsctx.FileStream{Model: file}))
+	}
+
+	// 不是文件
+	{
+		a.NoError(HookDeduplicate(context.Background(), fs, &fsctx.FileStream{}))
+	}
+}
+
+func TestFileSystem_InstantUpload(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_instant_upload_cross_user", "0", 0)
+	testHandler := new(FileHeaderMock)
+	fs := &FileSystem{
+		User: &model.User{
+			Model: gorm.Model{ID: 1},
+			Group: model.Group{MaxStorage: 10},
+		},
+		Policy:  &model.Policy{Type: "mock"},
+		Handler: testHandler,
+	}
+	fs.Policy.ID = 1
+	newStream := func() *fsctx.FileStream {
+		return &fsctx.FileStream{Name: "a.txt", Size: 5, VirtualPath: "/"}
+	}
+
+	// 存储策略未开启去重
+	{
+		_, err := fs.InstantUpload(context.Background(), newStream(), helloSHA256)
+		a.ErrorIs(err, ErrInstantUploadMiss)
+	}
+
+	fs.Policy.OptionsSerialized.Dedup = true
+
+	// 未找到内容相同的文件
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, helloSHA256, 5, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := fs.InstantUpload(context.Background(), newStream(), helloSHA256)
+		a.ErrorIs(err, ErrInstantUploadMiss)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 容量不足
+	{
+		fs.Hooks = nil
+		fs.User.Group.MaxStorage = 1
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(2, "src/b.txt"))
+		_, err := fs.InstantUpload(context.Background(), newStream(), helloSHA256)
+		a.ErrorIs(err, ErrInsufficientCapacity)
+		a.NoError(mock.ExpectationsWereMet())
+		fs.User.Group.MaxStorage = 10
+	}
+
+	// 允许引用其他用户的文件，成功
+	{
+		fs.Hooks = nil
+		cache.Set("setting_instant_upload_cross_user", "1", 0)
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, helloSHA256, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(2, "src/b.txt"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)sha256(.+)").WithArgs(helloSHA256, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		file, err := fs.InstantUpload(context.Background(), newStream(), helloSHA256)
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal("src/b.txt", file.SourceName)
+		a.Equal(helloSHA256, file.SHA256)
+		testHandler.AssertNotCalled(t, "Put", testMock.Anything, testMock.Anything)
+	}
+}
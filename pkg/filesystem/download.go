@@ -0,0 +1,100 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// DownloadOptions 创建下载会话的选项
+type DownloadOptions struct {
+	// TTL 下载地址有效期（秒），为 0 时使用系统设定，不超过 download_max_timeout
+	TTL int64
+	// IP 下载地址绑定的客户端 IP，为空时不限制
+	IP string
+	// OneTime 下载地址是否仅可使用一次
+	OneTime bool
+}
+
+// NewDownloadOptions 从请求参数 ttl、bind_ip、once 中读取下载会话选项，
+// 站点开启 download_bind_ip 时总是绑定客户端 IP
+func NewDownloadOptions(c *gin.Context) *DownloadOptions {
+	opts := &DownloadOptions{}
+	opts.TTL, _ = strconv.ParseInt(c.Query("ttl"), 10, 64)
+	opts.OneTime, _ = strconv.ParseBool(c.Query("once"))
+
+	bindIP, _ := strconv.ParseBool(c.Query("bind_ip"))
+	if bindIP || model.IsTrueVal(model.GetSettingByName("download_bind_ip")) {
+		opts.IP = c.ClientIP()
+	}
+
+	return opts
+}
+
+// WithDownloadOptions 将下载会话选项写入上下文
+func WithDownloadOptions(ctx context.Context, c *gin.Context) context.Context {
+	return context.WithValue(ctx, fsctx.DownloadOptionsCtx, NewDownloadOptions(c))
+}
+
+// ttl 返回下载地址的有效期，fallback 为未指定时使用的默认值
+func (opts *DownloadOptions) ttl(fallback int64) int64 {
+	if opts.TTL <= 0 {
+		return fallback
+	}
+
+	if max := int64(model.GetIntSetting("download_max_timeout", 86400)); opts.TTL > max {
+		return max
+	}
+
+	return opts.TTL
+}
+
+// signDownloadSession 缓存下载会话并返回签名后的下载地址，本机存储策略配置了 CDN 时使用 CDN 地址
+func (fs *FileSystem) signDownloadSession(session *serializer.DownloadSession, ttl int64) (string, error) {
+	sessionID := util.RandStringRunes(16)
+	if err := cache.Set("download_"+sessionID, *session, int(ttl)); err != nil {
+		return "", serializer.NewError(serializer.CodeCacheOperation, "Failed to create download session", err)
+	}
+
+	signedURI, err := auth.SignURI(auth.General, fmt.Sprintf("/api/v3/file/download/%s", sessionID), ttl)
+	if err != nil {
+		return "", serializer.NewError(serializer.CodeEncryptError, "Failed to sign url", err)
+	}
+
+	baseURL := model.GetSiteURL()
+	if fs.Policy != nil && fs.Policy.Type == "local" && fs.Policy.BaseURL != "" {
+		cdnURL, err := url.Parse(fs.Policy.BaseURL)
+		if err != nil {
+			return "", err
+		}
+		baseURL = cdnURL
+	}
+
+	return baseURL.ResolveReference(signedURI).String(), nil
+}
+
+// GetRedirectedDownloadURL 获取下载会话中文件在存储端的下载地址，仅用于不由服务端输出下载的存储策略，
+// speed 为创建下载会话时记录的限速
+func (fs *FileSystem) GetRedirectedDownloadURL(ctx context.Context, speed int) (string, error) {
+	if err := fs.resetPolicyToFirstFile(ctx); err != nil {
+		return "", err
+	}
+
+	ctx = context.WithValue(ctx, fsctx.FileModelCtx, fs.FileTarget[0])
+	ttl := int64(model.GetIntSetting("download_timeout", 60))
+	source, err := fs.Handler.Source(ctx, fs.FileTarget[0].SourceName, ttl, true, speed)
+	if err != nil {
+		return "", serializer.NewError(serializer.CodeNotSet, "Failed to get source link", err)
+	}
+
+	return source, nil
+}
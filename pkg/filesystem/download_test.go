@@ -0,0 +1,117 @@
+package filesystem
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDownloadOptions(t *testing.T) {
+	a := assert.New(t)
+
+	// 默认不绑定 IP
+	{
+		cache.Set("setting_download_bind_ip", "0", 0)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("PUT", "/api/v3/file/download/1", nil)
+		opts := NewDownloadOptions(c)
+		a.EqualValues(0, opts.TTL)
+		a.Empty(opts.IP)
+		a.False(opts.OneTime)
+	}
+
+	// 请求参数
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("PUT", "/api/v3/file/download/1?ttl=120&once=1&bind_ip=true", nil)
+		c.Request.RemoteAddr = "10.0.0.1:1234"
+		opts := NewDownloadOptions(c)
+		a.EqualValues(120, opts.TTL)
+		a.Equal("10.0.0.1", opts.IP)
+		a.True(opts.OneTime)
+	}
+
+	// 站点强制绑定 IP
+	{
+		cache.Set("setting_download_bind_ip", "1", 0)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("PUT", "/api/v3/file/download/1", nil)
+		c.Request.RemoteAddr = "10.0.0.2:1234"
+		a.Equal("10.0.0.2", NewDownloadOptions(c).IP)
+		cache.Set("setting_download_bind_ip", "0", 0)
+	}
+}
+
+func TestDownloadOptions_ttl(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_download_max_timeout", "3600", 0)
+
+	a.EqualValues(600, (&DownloadOptions{}).ttl(600))
+	a.EqualValues(120, (&DownloadOptions{TTL: 120}).ttl(600))
+	a.EqualValues(3600, (&DownloadOptions{TTL: 7200}).ttl(600))
+}
+
+func TestFileSystem_GetDownloadURLWithOptions(t *testing.T) {
+	a := assert.New(t)
+	fs := FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	auth.General = auth.HMACAuth{SecretKey: []byte("123")}
+	cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+	cache.Set("setting_download_timeout", "20", 0)
+	cache.Deletes([]string{"536"}, "policy_")
+	defer cache.Deletes([]string{"536"}, "policy_")
+
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policy_id"}).AddRow(1, "1.txt", 536))
+	mock.ExpectQuery("SELECT(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(536, "s3"))
+
+	ctx := context.WithValue(context.Background(), fsctx.DownloadOptionsCtx, &DownloadOptions{IP: "10.0.0.1", OneTime: true})
+	downloadURL, err := fs.GetDownloadURL(ctx, 1, "download_timeout")
+	a.NoError(mock.ExpectationsWereMet())
+	a.NoError(err)
+
+	// 非本机存储策略同样经由签名地址下载
+	a.True(strings.HasPrefix(downloadURL, "https://cloudreve.org/api/v3/file/download/"))
+	parsed, err := url.Parse(downloadURL)
+	a.NoError(err)
+	a.NotEmpty(parsed.Query().Get("sign"))
+
+	sessionRaw, ok := cache.Get("download_" + strings.TrimPrefix(parsed.Path, "/api/v3/file/download/"))
+	a.True(ok)
+	session := sessionRaw.(serializer.DownloadSession)
+	a.EqualValues(1, session.File.ID)
+	a.Equal("10.0.0.1", session.IP)
+	a.True(session.OneTime)
+}
+
+func TestFileSystem_signDownloadSession(t *testing.T) {
+	a := assert.New(t)
+	auth.General = auth.HMACAuth{SecretKey: []byte("123")}
+	cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+
+	// 本机存储策略使用 CDN 地址
+	{
+		fs := FileSystem{Policy: &model.Policy{Type: "local", BaseURL: "https://cdn.cloudreve.org"}}
+		res, err := fs.signDownloadSession(&serializer.DownloadSession{}, 10)
+		a.NoError(err)
+		a.True(strings.HasPrefix(res, "https://cdn.cloudreve.org/api/v3/file/download/"))
+	}
+
+	// CDN 地址无效
+	{
+		fs := FileSystem{Policy: &model.Policy{Type: "local", BaseURL: string([]byte{0x7f})}}
+		_, err := fs.signDownloadSession(&serializer.DownloadSession{}, 10)
+		a.Error(err)
+	}
+}
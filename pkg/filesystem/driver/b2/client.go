@@ -0,0 +1,493 @@
+package b2
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// CredentialCachePrefix 账户凭证缓存前缀
+	CredentialCachePrefix = "b2_"
+
+	authorizeEndpoint = "https://api.backblazeb2.com/b2api/v2/b2_authorize_account"
+	apiVersionPath    = "/b2api/v2/"
+
+	// credentialTTL 账户凭证缓存时间，B2 凭证有效期为 24 小时
+	credentialTTL = 23 * 3600
+
+	// sha1AtEnd 在请求正文末尾附加 SHA1 的标记
+	sha1AtEnd = "hex_digits_at_end"
+)
+
+var (
+	// ErrBucketNotFound 存储桶不存在或无权访问
+	ErrBucketNotFound = errors.New("bucket not found or not accessible with this application key")
+)
+
+// Client Backblaze B2 原生 API 客户端
+type Client struct {
+	Policy     *model.Policy
+	Credential *Credential
+	Endpoint   string
+	Request    request.Client
+}
+
+// NewClient 根据存储策略获取新的 client，AccessKey 为 keyID，SecretKey 为 applicationKey
+func NewClient(policy *model.Policy) *Client {
+	return &Client{
+		Policy:   policy,
+		Endpoint: authorizeEndpoint,
+		Request:  request.NewClient(),
+	}
+}
+
+// Authorize 获取账户凭证，优先使用缓存
+func (client *Client) Authorize(ctx context.Context) error {
+	if client.Credential != nil {
+		return nil
+	}
+
+	if cached, ok := cache.Get(CredentialCachePrefix + client.Policy.AccessKey); ok {
+		credential := cached.(Credential)
+		if credential.BucketID != "" {
+			client.Credential = &credential
+			return nil
+		}
+	}
+
+	res := client.Request.Request(
+		"GET",
+		client.Endpoint,
+		nil,
+		request.WithContext(ctx),
+		request.WithHeader(http.Header{
+			"Authorization": {"Basic " + base64.StdEncoding.EncodeToString(
+				[]byte(client.Policy.AccessKey+":"+client.Policy.SecretKey))},
+		}),
+	)
+
+	credential := &Credential{}
+	if err := decodeResponse(res, credential); err != nil {
+		return err
+	}
+
+	client.Credential = credential
+	bucketID, err := client.resolveBucketID(ctx)
+	if err != nil {
+		client.Credential = nil
+		return err
+	}
+
+	credential.BucketID = bucketID
+	cache.Set(CredentialCachePrefix+client.Policy.AccessKey, *credential, credentialTTL)
+	return nil
+}
+
+// resolveBucketID 获取存储策略对应存储桶的 ID
+func (client *Client) resolveBucketID(ctx context.Context) (string, error) {
+	// 限定了存储桶的 Key 直接返回可访问的存储桶
+	if client.Credential.Allowed.BucketID != "" {
+		if client.Credential.Allowed.BucketName != client.Policy.BucketName {
+			return "", ErrBucketNotFound
+		}
+
+		return client.Credential.Allowed.BucketID, nil
+	}
+
+	res := &ListBucketsResponse{}
+	if err := client.call(ctx, "b2_list_buckets", map[string]interface{}{
+		"accountId":  client.Credential.AccountID,
+		"bucketName": client.Policy.BucketName,
+	}, res); err != nil {
+		return "", err
+	}
+
+	for _, bucket := range res.Buckets {
+		if bucket.BucketName == client.Policy.BucketName {
+			return bucket.BucketID, nil
+		}
+	}
+
+	return "", ErrBucketNotFound
+}
+
+// call 调用 B2 接口，凭证过期时重新获取并重试一次
+func (client *Client) call(ctx context.Context, api string, body, res interface{}) error {
+	if err := client.Authorize(ctx); err != nil {
+		return err
+	}
+
+	err := client.doCall(ctx, api, body, res)
+	var respErr *RespError
+	if errors.As(err, &respErr) && respErr.Code == "expired_auth_token" {
+		client.Credential = nil
+		cache.Deletes([]string{client.Policy.AccessKey}, CredentialCachePrefix)
+		if err := client.Authorize(ctx); err != nil {
+			return err
+		}
+
+		err = client.doCall(ctx, api, body, res)
+	}
+
+	return err
+}
+
+func (client *Client) doCall(ctx context.Context, api string, body, res interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp := client.Request.Request(
+		"POST",
+		client.Credential.APIURL+apiVersionPath+api,
+		bytes.NewReader(data),
+		request.WithContext(ctx),
+		request.WithContentLength(int64(len(data))),
+		request.WithHeader(http.Header{
+			"Authorization": {client.Credential.AuthorizationToken},
+			"Content-Type":  {"application/json"},
+		}),
+		request.WithTPSLimit(
+			fmt.Sprintf("policy_%d", client.Policy.ID),
+			client.Policy.OptionsSerialized.TPSLimit,
+			client.Policy.OptionsSerialized.TPSLimitBurst,
+		),
+	)
+
+	return decodeResponse(resp, res)
+}
+
+// decodeResponse 解析接口响应，非 2xx 状态码时返回 RespError
+func decodeResponse(resp *request.Response, res interface{}) error {
+	body, err := resp.GetResponse()
+	if err != nil {
+		return err
+	}
+
+	if resp.Response.StatusCode < 200 || resp.Response.StatusCode >= 300 {
+		respErr := &RespError{}
+		if err := json.Unmarshal([]byte(body), respErr); err != nil {
+			util.Log().Debug("B2 returns unknown response: %s", body)
+			return fmt.Errorf("unexpected status code %d", resp.Response.StatusCode)
+		}
+
+		return respErr
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(body), res)
+}
+
+// GetUploadURL 获取上传单个文件的地址
+func (client *Client) GetUploadURL(ctx context.Context) (*UploadURL, error) {
+	if err := client.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &UploadURL{}
+	if err := client.call(ctx, "b2_get_upload_url", map[string]string{
+		"bucketId": client.Credential.BucketID,
+	}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// StartLargeFile 创建大文件分片上传，返回文件 ID
+func (client *Client) StartLargeFile(ctx context.Context, name, contentType string) (string, error) {
+	if err := client.Authorize(ctx); err != nil {
+		return "", err
+	}
+
+	res := &FileInfo{}
+	if err := client.call(ctx, "b2_start_large_file", map[string]string{
+		"bucketId":    client.Credential.BucketID,
+		"fileName":    name,
+		"contentType": contentType,
+	}, res); err != nil {
+		return "", err
+	}
+
+	return res.FileID, nil
+}
+
+// GetUploadPartURL 获取上传大文件分片的地址
+func (client *Client) GetUploadPartURL(ctx context.Context, fileID string) (*UploadURL, error) {
+	res := &UploadURL{}
+	if err := client.call(ctx, "b2_get_upload_part_url", map[string]string{
+		"fileId": fileID,
+	}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// ListParts 列出大文件已上传的全部分片
+func (client *Client) ListParts(ctx context.Context, fileID string) ([]PartInfo, error) {
+	var (
+		parts []PartInfo
+		start = 1
+	)
+
+	for {
+		res := &ListPartsResponse{}
+		if err := client.call(ctx, "b2_list_parts", map[string]interface{}{
+			"fileId":          fileID,
+			"startPartNumber": start,
+			"maxPartCount":    1000,
+		}, res); err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, res.Parts...)
+		if res.NextPartNumber == nil {
+			return parts, nil
+		}
+
+		start = *res.NextPartNumber
+	}
+}
+
+// FinishLargeFile 按已上传分片的顺序完成大文件上传
+func (client *Client) FinishLargeFile(ctx context.Context, fileID string, sha1s []string) (*FileInfo, error) {
+	res := &FileInfo{}
+	if err := client.call(ctx, "b2_finish_large_file", map[string]interface{}{
+		"fileId":        fileID,
+		"partSha1Array": sha1s,
+	}, res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// CancelLargeFile 取消大文件上传，删除已上传的分片
+func (client *Client) CancelLargeFile(ctx context.Context, fileID string) error {
+	return client.call(ctx, "b2_cancel_large_file", map[string]string{
+		"fileId": fileID,
+	}, nil)
+}
+
+// ListFileNames 列出以 prefix 开头的文件，delimiter 不为空时不递归列出
+func (client *Client) ListFileNames(ctx context.Context, prefix, delimiter string) ([]FileInfo, error) {
+	if err := client.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	var (
+		files []FileInfo
+		body  = map[string]interface{}{
+			"bucketId":     client.Credential.BucketID,
+			"prefix":       prefix,
+			"maxFileCount": 1000,
+		}
+	)
+	if delimiter != "" {
+		body["delimiter"] = delimiter
+	}
+
+	for {
+		res := &ListFilesResponse{}
+		if err := client.call(ctx, "b2_list_file_names", body, res); err != nil {
+			return nil, err
+		}
+
+		files = append(files, res.Files...)
+		if res.NextFileName == nil {
+			return files, nil
+		}
+
+		body["startFileName"] = *res.NextFileName
+	}
+}
+
+// GetFileInfo 获取指定文件名的最新版本信息
+func (client *Client) GetFileInfo(ctx context.Context, name string) (*FileInfo, error) {
+	if err := client.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	res := &ListFilesResponse{}
+	if err := client.call(ctx, "b2_list_file_names", map[string]interface{}{
+		"bucketId":      client.Credential.BucketID,
+		"startFileName": name,
+		"maxFileCount":  1,
+	}, res); err != nil {
+		return nil, err
+	}
+
+	if len(res.Files) == 0 || res.Files[0].FileName != name || res.Files[0].Action != "upload" {
+		return nil, errors.New("file not found")
+	}
+
+	return &res.Files[0], nil
+}
+
+// DeleteFile 删除指定文件名的所有版本
+func (client *Client) DeleteFile(ctx context.Context, name string) error {
+	if err := client.Authorize(ctx); err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"bucketId":      client.Credential.BucketID,
+		"startFileName": name,
+		"prefix":        name,
+		"maxFileCount":  100,
+	}
+
+	for {
+		res := &ListFilesResponse{}
+		if err := client.call(ctx, "b2_list_file_versions", body, res); err != nil {
+			return err
+		}
+
+		for _, file := range res.Files {
+			if file.FileName != name {
+				return nil
+			}
+
+			if err := client.call(ctx, "b2_delete_file_version", map[string]string{
+				"fileName": file.FileName,
+				"fileId":   file.FileID,
+			}, nil); err != nil {
+				return err
+			}
+		}
+
+		if res.NextFileName == nil || *res.NextFileName != name {
+			return nil
+		}
+
+		body["startFileId"] = *res.NextFileID
+	}
+}
+
+// GetDownloadAuthorization 获取下载私有存储桶中文件的授权
+func (client *Client) GetDownloadAuthorization(ctx context.Context, name string, ttl int64, disposition string) (string, error) {
+	if err := client.Authorize(ctx); err != nil {
+		return "", err
+	}
+
+	body := map[string]interface{}{
+		"bucketId":               client.Credential.BucketID,
+		"fileNamePrefix":         name,
+		"validDurationInSeconds": ttl,
+	}
+	if disposition != "" {
+		body["b2ContentDisposition"] = disposition
+	}
+
+	res := &DownloadAuthorization{}
+	if err := client.call(ctx, "b2_get_download_authorization", body, res); err != nil {
+		return "", err
+	}
+
+	return res.AuthorizationToken, nil
+}
+
+// UpdateCORS 替换存储桶的跨域规则
+func (client *Client) UpdateCORS(ctx context.Context, rules []CORSRule) error {
+	if err := client.Authorize(ctx); err != nil {
+		return err
+	}
+
+	return client.call(ctx, "b2_update_bucket", map[string]interface{}{
+		"accountId": client.Credential.AccountID,
+		"bucketId":  client.Credential.BucketID,
+		"corsRules": rules,
+	}, nil)
+}
+
+// Upload 将 size 字节的内容上传到 target 指定的上传地址，partNumber 大于 0 时
+// 作为大文件的分片上传。SHA1 在读取内容的同时计算并附加在正文末尾，返回内容的 SHA1
+func (client *Client) Upload(ctx context.Context, target *UploadURL, name string, partNumber int,
+	content io.Reader, size int64, contentType string) (string, error) {
+	hasher := sha1.New()
+	body := io.MultiReader(io.TeeReader(io.LimitReader(content, size), hasher), &sha1Suffix{hash: hasher})
+
+	header := http.Header{
+		"Authorization":     {target.AuthorizationToken},
+		"X-Bz-Content-Sha1": {sha1AtEnd},
+	}
+	if partNumber > 0 {
+		header.Set("X-Bz-Part-Number", strconv.Itoa(partNumber))
+	} else {
+		header.Set("X-Bz-File-Name", EscapeFileName(name))
+		header.Set("Content-Type", contentType)
+	}
+
+	resp := client.Request.Request(
+		"POST",
+		target.UploadURL,
+		body,
+		request.WithContext(ctx),
+		request.WithContentLength(size+sha1.Size*2),
+		request.WithHeader(header),
+		request.WithTimeout(time.Duration(0)),
+	)
+	if err := decodeResponse(resp, nil); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// DownloadURL 返回按文件名下载的地址
+func (client *Client) DownloadURL(name string) string {
+	return client.Credential.DownloadURL + "/file/" + url.PathEscape(client.Policy.BucketName) + "/" + EscapeFileName(name)
+}
+
+// EscapeFileName 按 B2 的要求对文件名进行 URL 编码，保留路径分隔符
+func EscapeFileName(name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// sha1Suffix 在内容读取完成后输出其 SHA1 的十六进制字符串
+type sha1Suffix struct {
+	hash   hash.Hash
+	digest []byte
+}
+
+func (s *sha1Suffix) Read(p []byte) (int, error) {
+	if s.digest == nil {
+		s.digest = []byte(hex.EncodeToString(s.hash.Sum(nil)))
+	}
+
+	if len(s.digest) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, s.digest)
+	s.digest = s.digest[n:]
+	return n, nil
+}
@@ -0,0 +1,367 @@
+package b2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/chunk"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/chunk/backoff"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// minPartSize B2 大文件分片的最小大小
+const minPartSize = 5 << 20
+
+var chunkRetrySleep = time.Duration(5) * time.Second
+
+// Driver Backblaze B2 适配器
+type Driver struct {
+	Policy     *model.Policy
+	Client     *Client
+	HTTPClient request.Client
+}
+
+// NewDriver 从存储策略初始化新的Driver实例
+func NewDriver(policy *model.Policy) (*Driver, error) {
+	if policy.OptionsSerialized.ChunkSize == 0 {
+		policy.OptionsSerialized.ChunkSize = 100 << 20 // 100 MB
+	} else if policy.OptionsSerialized.ChunkSize < minPartSize {
+		policy.OptionsSerialized.ChunkSize = minPartSize
+	}
+
+	return &Driver{
+		Policy:     policy,
+		Client:     NewClient(policy),
+		HTTPClient: request.NewClient(),
+	}, nil
+}
+
+// List 列出给定路径下的文件
+func (handler *Driver) List(ctx context.Context, base string, recursive bool) ([]response.Object, error) {
+	base = strings.TrimPrefix(base, "/")
+	if base != "" {
+		base += "/"
+	}
+
+	delimiter := "/"
+	if recursive {
+		delimiter = ""
+	}
+
+	files, err := handler.Client.ListFileNames(ctx, base, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]response.Object, 0, len(files))
+	for _, file := range files {
+		rel := strings.TrimPrefix(file.FileName, base)
+		switch file.Action {
+		case "folder":
+			rel = strings.TrimSuffix(rel, "/")
+			res = append(res, response.Object{
+				Name:         path.Base(rel),
+				RelativePath: rel,
+				IsDir:        true,
+				LastModify:   time.Now(),
+			})
+		case "upload":
+			res = append(res, response.Object{
+				Name:         path.Base(file.FileName),
+				Source:       file.FileName,
+				RelativePath: rel,
+				Size:         file.ContentLength,
+				LastModify:   time.UnixMilli(file.UploadTimestamp),
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// Get 获取文件
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	// 获取文件源地址
+	downloadURL, err := handler.Source(ctx, path, int64(model.GetIntSetting("preview_timeout", 60)), false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		if rs, ok := request.GetParallelReader(ctx, handler.HTTPClient, downloadURL, int64(file.Size),
+			request.WithHeader(
+				http.Header{"Cache-Control": {"no-cache", "no-store", "must-revalidate"}},
+			),
+			request.WithTimeout(time.Duration(0)),
+		); ok {
+			rs.SetFirstFakeChunk()
+			return rs, nil
+		}
+	}
+
+	// 获取文件数据流
+	resp, err := handler.HTTPClient.Request(
+		"GET",
+		downloadURL,
+		nil,
+		request.WithContext(ctx),
+		request.WithHeader(
+			http.Header{"Cache-Control": {"no-cache", "no-store", "must-revalidate"}},
+		),
+		request.WithTimeout(time.Duration(0)),
+	).CheckHTTPResponse(200).GetRSCloser()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.SetFirstFakeChunk()
+
+	// 尝试自主获取文件大小
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		resp.SetContentLength(int64(file.Size))
+	}
+
+	return resp, nil
+}
+
+// Put 将文件流保存到指定目录，超过分片大小的文件使用大文件接口分片上传
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+
+	fileInfo := file.Info()
+	if fileInfo.Size <= handler.Policy.OptionsSerialized.ChunkSize {
+		target, err := handler.Client.GetUploadURL(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = handler.Client.Upload(ctx, target, fileInfo.SavePath, 0, file, int64(fileInfo.Size), fileInfo.DetectMimeType())
+		return err
+	}
+
+	fileID, err := handler.Client.StartLargeFile(ctx, fileInfo.SavePath, fileInfo.DetectMimeType())
+	if err != nil {
+		return fmt.Errorf("failed to start large file: %w", err)
+	}
+
+	target, err := handler.Client.GetUploadPartURL(ctx, fileID)
+	if err != nil {
+		handler.Client.CancelLargeFile(context.Background(), fileID)
+		return err
+	}
+
+	chunks := chunk.NewChunkGroup(file, handler.Policy.OptionsSerialized.ChunkSize, &backoff.ConstantBackoff{
+		Max:   model.GetIntSetting("chunk_retries", 5),
+		Sleep: chunkRetrySleep,
+	}, model.IsTrueVal(model.GetSettingByName("use_temp_chunk_buffer")))
+
+	sha1s := make([]string, 0, chunks.Num())
+	for chunks.Next() {
+		if err := chunks.Process(func(c *chunk.ChunkGroup, content io.Reader) error {
+			sum, err := handler.Client.Upload(ctx, target, fileInfo.SavePath, c.Index()+1, content, c.Length(), "")
+			if err != nil {
+				return err
+			}
+
+			sha1s = append(sha1s, sum)
+			return nil
+		}); err != nil {
+			handler.Client.CancelLargeFile(context.Background(), fileID)
+			return err
+		}
+	}
+
+	if _, err := handler.Client.FinishLargeFile(ctx, fileID, sha1s); err != nil {
+		handler.Client.CancelLargeFile(context.Background(), fileID)
+		return fmt.Errorf("failed to finish large file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete 删除一个或多个文件，
+// 返回未删除的文件，及遇到的最后一个错误
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	var (
+		failed  = make([]string, 0, len(files))
+		lastErr error
+	)
+
+	for _, file := range files {
+		if err := handler.Client.DeleteFile(ctx, file); err != nil {
+			failed = append(failed, file)
+			lastErr = err
+		}
+	}
+
+	return failed, lastErr
+}
+
+// Thumb 获取文件缩略图，B2 不支持图像处理
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	return nil, driver.ErrorThumbNotSupported
+}
+
+// Source 获取外链URL
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	// 尝试从上下文获取文件名
+	fileName := ""
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		fileName = file.Name
+	}
+
+	if err := handler.Client.Authorize(ctx); err != nil {
+		return "", err
+	}
+
+	finalURL, err := url.Parse(handler.Client.DownloadURL(path))
+	if err != nil {
+		return "", err
+	}
+
+	disposition := ""
+	if isDownload {
+		disposition = "attachment; filename=\"" + url.PathEscape(fileName) + "\""
+	}
+
+	query := finalURL.Query()
+	if disposition != "" {
+		query.Set("b2ContentDisposition", disposition)
+	}
+
+	// 私有存储桶需要下载授权
+	if handler.Policy.IsPrivate {
+		token, err := handler.Client.GetDownloadAuthorization(ctx, path, ttl, disposition)
+		if err != nil {
+			return "", err
+		}
+
+		query.Set("Authorization", token)
+	}
+	finalURL.RawQuery = query.Encode()
+
+	// 将下载地址域名换成用户自定义的加速域名（如果有）
+	if handler.Policy.BaseURL != "" {
+		cdnURL, err := url.Parse(handler.Policy.BaseURL)
+		if err != nil {
+			return "", err
+		}
+		finalURL.Host = cdnURL.Host
+		finalURL.Scheme = cdnURL.Scheme
+	}
+
+	return finalURL.String(), nil
+}
+
+// Token 获取上传地址和认证Token，超过分片大小的文件以大文件分片上传，
+// 客户端上传完成后请求回调地址
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	// 检查文件是否存在
+	fileInfo := file.Info()
+	if _, err := handler.Meta(ctx, fileInfo.SavePath); err == nil {
+		return nil, fmt.Errorf("file already exist")
+	}
+
+	// 生成回调地址
+	siteURL := model.GetSiteURL()
+	apiBaseURI, _ := url.Parse("/api/v3/callback/b2/" + uploadSession.Key)
+	apiURL := siteURL.ResolveReference(apiBaseURI)
+
+	credential := &serializer.UploadCredential{
+		SessionID: uploadSession.Key,
+		Callback:  apiURL.String(),
+		Path:      EscapeFileName(fileInfo.SavePath),
+	}
+
+	var (
+		target *UploadURL
+		err    error
+	)
+	if fileInfo.Size <= handler.Policy.OptionsSerialized.ChunkSize {
+		target, err = handler.Client.GetUploadURL(ctx)
+	} else {
+		uploadSession.UploadID, err = handler.Client.StartLargeFile(ctx, fileInfo.SavePath, fileInfo.DetectMimeType())
+		if err != nil {
+			return nil, fmt.Errorf("failed to start large file: %w", err)
+		}
+
+		credential.UploadID = uploadSession.UploadID
+		credential.ChunkSize = handler.Policy.OptionsSerialized.ChunkSize
+		target, err = handler.Client.GetUploadPartURL(ctx, uploadSession.UploadID)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	credential.UploadURLs = []string{target.UploadURL}
+	credential.Credential = target.AuthorizationToken
+	return credential, nil
+}
+
+// CancelToken 取消上传凭证，删除未完成大文件已上传的分片
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	if uploadSession.UploadID == "" {
+		return nil
+	}
+
+	return handler.Client.CancelLargeFile(ctx, uploadSession.UploadID)
+}
+
+// CompleteUpload 完成客户端的大文件分片上传
+func (handler *Driver) CompleteUpload(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	if uploadSession.UploadID == "" {
+		return nil
+	}
+
+	parts, err := handler.Client.ListParts(ctx, uploadSession.UploadID)
+	if err != nil {
+		return err
+	}
+
+	if len(parts) == 0 {
+		return errors.New("no uploaded parts")
+	}
+
+	sha1s := make([]string, len(parts))
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return fmt.Errorf("part %d is missing", i+1)
+		}
+		sha1s[i] = part.ContentSha1
+	}
+
+	_, err = handler.Client.FinishLargeFile(ctx, uploadSession.UploadID, sha1s)
+	return err
+}
+
+// Meta 获取文件信息
+func (handler *Driver) Meta(ctx context.Context, path string) (*FileInfo, error) {
+	return handler.Client.GetFileInfo(ctx, path)
+}
+
+// CORS 创建跨域策略
+func (handler *Driver) CORS() error {
+	return handler.Client.UpdateCORS(context.Background(), []CORSRule{
+		{
+			CorsRuleName:      "cloudreve",
+			AllowedOrigins:    []string{"*"},
+			AllowedOperations: []string{"b2_download_file_by_name", "b2_upload_file", "b2_upload_part"},
+			AllowedHeaders:    []string{"*"},
+			ExposeHeaders:     []string{"x-bz-content-sha1"},
+			MaxAgeSeconds:     3600,
+		},
+	})
+}
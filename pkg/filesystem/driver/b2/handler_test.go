@@ -0,0 +1,351 @@
+package b2
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeB2 模拟 B2 原生 API 的测试服务端
+type fakeB2 struct {
+	sync.Mutex
+	server    *httptest.Server
+	token     string
+	authCount int
+	files     map[string][]byte
+	large     map[string]map[int][]byte
+	largeName map[string]string
+}
+
+func newFakeB2() *fakeB2 {
+	f := &fakeB2{
+		token:     "token1",
+		files:     make(map[string][]byte),
+		large:     make(map[string]map[int][]byte),
+		largeName: make(map[string]string),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+	return f
+}
+
+func (f *fakeB2) fail(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(RespError{Status: status, Code: code, Message: code})
+}
+
+func (f *fakeB2) handle(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	api := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiVersionPath), "/")
+	body, _ := ioutil.ReadAll(r.Body)
+
+	if api == "b2_authorize_account" {
+		if r.Header.Get("Authorization") != "Basic a2V5OnNlY3JldA==" {
+			f.fail(w, 401, "unauthorized")
+			return
+		}
+		f.authCount++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"accountId":          "account",
+			"authorizationToken": f.token,
+			"apiUrl":             f.server.URL,
+			"downloadUrl":        f.server.URL,
+		})
+		return
+	}
+
+	if r.Header.Get("Authorization") != f.token {
+		f.fail(w, 401, "expired_auth_token")
+		return
+	}
+
+	// 上传请求正文末尾附加了 SHA1
+	if api == "upload" || api == "upload_part" {
+		content, sum := body[:len(body)-40], string(body[len(body)-40:])
+		expected := sha1.Sum(content)
+		if r.Header.Get("X-Bz-Content-Sha1") != sha1AtEnd || sum != hex.EncodeToString(expected[:]) {
+			f.fail(w, 400, "bad_request")
+			return
+		}
+
+		if api == "upload" {
+			f.files[r.Header.Get("X-Bz-File-Name")] = content
+		} else {
+			part, _ := strconv.Atoi(r.Header.Get("X-Bz-Part-Number"))
+			f.large["large1"][part] = content
+		}
+		w.Write([]byte("{}"))
+		return
+	}
+
+	var req map[string]interface{}
+	json.Unmarshal(body, &req)
+	var res interface{} = map[string]interface{}{}
+
+	switch api {
+	case "b2_list_buckets":
+		res = ListBucketsResponse{Buckets: []Bucket{{BucketID: "bucket1", BucketName: req["bucketName"].(string)}}}
+	case "b2_get_upload_url":
+		res = UploadURL{UploadURL: f.server.URL + "/upload", AuthorizationToken: f.token}
+	case "b2_start_large_file":
+		f.large["large1"] = make(map[int][]byte)
+		f.largeName["large1"] = req["fileName"].(string)
+		res = FileInfo{FileID: "large1"}
+	case "b2_get_upload_part_url":
+		res = UploadURL{FileID: "large1", UploadURL: f.server.URL + "/upload_part", AuthorizationToken: f.token}
+	case "b2_list_parts":
+		parts := ListPartsResponse{}
+		for number, content := range f.large[req["fileId"].(string)] {
+			sum := sha1.Sum(content)
+			parts.Parts = append(parts.Parts, PartInfo{PartNumber: number, ContentLength: uint64(len(content)), ContentSha1: hex.EncodeToString(sum[:])})
+		}
+		sort.Slice(parts.Parts, func(i, j int) bool { return parts.Parts[i].PartNumber < parts.Parts[j].PartNumber })
+		res = parts
+	case "b2_finish_large_file":
+		id := req["fileId"].(string)
+		var content []byte
+		for i := 1; i <= len(f.large[id]); i++ {
+			content = append(content, f.large[id][i]...)
+		}
+		if len(req["partSha1Array"].([]interface{})) != len(f.large[id]) {
+			f.fail(w, 400, "bad_request")
+			return
+		}
+		f.files[f.largeName[id]] = content
+		delete(f.large, id)
+	case "b2_cancel_large_file":
+		delete(f.large, req["fileId"].(string))
+	case "b2_list_file_names", "b2_list_file_versions":
+		list := ListFilesResponse{Files: []FileInfo{}}
+		names := make([]string, 0, len(f.files))
+		for name := range f.files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		prefix, _ := req["prefix"].(string)
+		start, _ := req["startFileName"].(string)
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix) && name >= start {
+				list.Files = append(list.Files, FileInfo{FileID: "id_" + name, FileName: name, ContentLength: uint64(len(f.files[name])), Action: "upload"})
+			}
+		}
+		res = list
+	case "b2_delete_file_version":
+		delete(f.files, req["fileName"].(string))
+	case "b2_get_download_authorization":
+		res = DownloadAuthorization{AuthorizationToken: "download_" + req["fileNamePrefix"].(string)}
+	case "b2_update_bucket":
+	default:
+		f.fail(w, 400, "bad_request")
+		return
+	}
+
+	json.NewEncoder(w).Encode(res)
+}
+
+func newTestDriver(f *fakeB2) *Driver {
+	cache.Deletes([]string{"key"}, CredentialCachePrefix)
+	handler, _ := NewDriver(&model.Policy{AccessKey: "key", SecretKey: "secret", BucketName: "bucket"})
+	handler.Client.Endpoint = f.server.URL + apiVersionPath + "b2_authorize_account"
+	return handler
+}
+
+func TestClient_Authorize(t *testing.T) {
+	a := assert.New(t)
+	f := newFakeB2()
+	defer f.server.Close()
+	handler := newTestDriver(f)
+
+	// 获取凭证并解析存储桶
+	a.NoError(handler.Client.Authorize(context.Background()))
+	a.Equal("bucket1", handler.Client.Credential.BucketID)
+	a.Equal(1, f.authCount)
+
+	// 使用缓存的凭证
+	handler.Client.Credential = nil
+	a.NoError(handler.Client.Authorize(context.Background()))
+	a.Equal(1, f.authCount)
+
+	// 凭证过期后重新获取
+	f.token = "token2"
+	_, err := handler.Client.GetUploadURL(context.Background())
+	a.NoError(err)
+	a.Equal(2, f.authCount)
+
+	// 密钥错误
+	{
+		handler := newTestDriver(f)
+		handler.Policy.SecretKey = "wrong"
+		err := handler.Client.Authorize(context.Background())
+		a.Error(err)
+		a.Equal("unauthorized", err.(*RespError).Code)
+	}
+}
+
+func TestDriver_Put(t *testing.T) {
+	a := assert.New(t)
+	f := newFakeB2()
+	defer f.server.Close()
+	handler := newTestDriver(f)
+	cache.Set("setting_chunk_retries", "1", 0)
+	cache.Set("setting_use_temp_chunk_buffer", "false", 0)
+
+	// 单次上传
+	{
+		a.NoError(handler.Put(context.Background(), &fsctx.FileStream{
+			File:     ioutil.NopCloser(strings.NewReader("hello")),
+			Size:     5,
+			SavePath: "dir/a b.txt",
+		}))
+		a.Equal("hello", string(f.files["dir/a%20b.txt"]))
+	}
+
+	// 分片上传
+	{
+		handler.Policy.OptionsSerialized.ChunkSize = 4
+		a.NoError(handler.Put(context.Background(), &fsctx.FileStream{
+			File:     ioutil.NopCloser(strings.NewReader("0123456789")),
+			Size:     10,
+			SavePath: "large.bin",
+		}))
+		a.Equal("0123456789", string(f.files["large.bin"]))
+		a.Empty(f.large)
+	}
+}
+
+func TestDriver_DeleteAndList(t *testing.T) {
+	a := assert.New(t)
+	f := newFakeB2()
+	defer f.server.Close()
+	handler := newTestDriver(f)
+	f.files["dir/a.txt"] = []byte("a")
+	f.files["dir/sub/b.txt"] = []byte("bb")
+	f.files["other.txt"] = []byte("c")
+
+	res, err := handler.List(context.Background(), "/dir", true)
+	a.NoError(err)
+	a.Len(res, 2)
+	a.Equal("sub/b.txt", res[1].RelativePath)
+	a.EqualValues(2, res[1].Size)
+
+	failed, err := handler.Delete(context.Background(), []string{"dir/a.txt", "other.txt"})
+	a.NoError(err)
+	a.Empty(failed)
+	a.Len(f.files, 1)
+}
+
+func TestDriver_Source(t *testing.T) {
+	a := assert.New(t)
+	f := newFakeB2()
+	defer f.server.Close()
+	handler := newTestDriver(f)
+
+	// 公有存储桶
+	{
+		res, err := handler.Source(context.Background(), "dir/a b.txt", 60, false, 0)
+		a.NoError(err)
+		a.Equal(f.server.URL+"/file/bucket/dir/a%20b.txt", res)
+	}
+
+	// 私有存储桶下载
+	{
+		handler.Policy.IsPrivate = true
+		ctx := context.WithValue(context.Background(), fsctx.FileModelCtx, model.File{Name: "a.txt"})
+		res, err := handler.Source(ctx, "dir/a.txt", 60, true, 0)
+		a.NoError(err)
+		a.Contains(res, "Authorization=download_dir%2Fa.txt")
+		a.Contains(res, "b2ContentDisposition=")
+	}
+
+	// 加速域名
+	{
+		handler.Policy.IsPrivate = false
+		handler.Policy.BaseURL = "https://cdn.cloudreve.org"
+		res, err := handler.Source(context.Background(), "a.txt", 60, false, 0)
+		a.NoError(err)
+		a.Equal("https://cdn.cloudreve.org/file/bucket/a.txt", res)
+	}
+}
+
+func TestDriver_Token(t *testing.T) {
+	a := assert.New(t)
+	f := newFakeB2()
+	defer f.server.Close()
+	handler := newTestDriver(f)
+	cache.Set("setting_siteURL", "http://test.cloudreve.org", 0)
+
+	// 文件已存在
+	{
+		f.files["exist.txt"] = []byte("a")
+		_, err := handler.Token(context.Background(), 10, &serializer.UploadSession{Key: "key"}, &fsctx.FileStream{SavePath: "exist.txt", Size: 1})
+		a.Error(err)
+	}
+
+	// 单次上传
+	{
+		res, err := handler.Token(context.Background(), 10, &serializer.UploadSession{Key: "key"}, &fsctx.FileStream{SavePath: "a.txt", Size: 1})
+		a.NoError(err)
+		a.Equal([]string{f.server.URL + "/upload"}, res.UploadURLs)
+		a.Equal(f.token, res.Credential)
+		a.Equal("http://test.cloudreve.org/api/v3/callback/b2/key", res.Callback)
+		a.Empty(res.UploadID)
+	}
+
+	// 分片上传并完成
+	{
+		handler.Policy.OptionsSerialized.ChunkSize = 4
+		session := &serializer.UploadSession{Key: "key", SavePath: "large.bin"}
+		res, err := handler.Token(context.Background(), 10, session, &fsctx.FileStream{SavePath: "large.bin", Size: 6})
+		a.NoError(err)
+		a.Equal("large1", res.UploadID)
+		a.Equal("large1", session.UploadID)
+		a.EqualValues(4, res.ChunkSize)
+
+		target := &UploadURL{UploadURL: res.UploadURLs[0], AuthorizationToken: res.Credential}
+		for i, part := range []string{"0123", "45"} {
+			_, err := handler.Client.Upload(context.Background(), target, "", i+1, strings.NewReader(part), int64(len(part)), "")
+			a.NoError(err)
+		}
+
+		a.NoError(handler.CompleteUpload(context.Background(), session))
+		info, err := handler.Meta(context.Background(), "large.bin")
+		a.NoError(err)
+		a.EqualValues(6, info.ContentLength)
+	}
+
+	// 取消分片上传
+	{
+		session := &serializer.UploadSession{Key: "key"}
+		_, err := handler.Token(context.Background(), 10, session, &fsctx.FileStream{SavePath: "cancel.bin", Size: 6})
+		a.NoError(err)
+		a.NoError(handler.CancelToken(context.Background(), session))
+		a.Empty(f.large)
+
+		// 未上传分片时无法完成
+		a.NoError(handler.CancelToken(context.Background(), &serializer.UploadSession{}))
+		f.large["large1"] = map[int][]byte{}
+		a.Error(handler.CompleteUpload(context.Background(), session))
+	}
+}
+
+func TestEscapeFileName(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("dir/a%20b%3F.txt", EscapeFileName("dir/a b?.txt"))
+	a.Equal("%E4%B8%AD%E6%96%87/a", EscapeFileName("中文/a"))
+}
@@ -0,0 +1,102 @@
+package b2
+
+import (
+	"encoding/gob"
+	"fmt"
+)
+
+// RespError 接口返回的错误
+type RespError struct {
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现error接口
+func (err RespError) Error() string {
+	return fmt.Sprintf("b2 error %d (%s): %s", err.Status, err.Code, err.Message)
+}
+
+// Credential b2_authorize_account 返回的账户凭证
+type Credential struct {
+	AccountID           string `json:"accountId"`
+	AuthorizationToken  string `json:"authorizationToken"`
+	APIURL              string `json:"apiUrl"`
+	DownloadURL         string `json:"downloadUrl"`
+	RecommendedPartSize uint64 `json:"recommendedPartSize"`
+	Allowed             struct {
+		BucketID   string `json:"bucketId"`
+		BucketName string `json:"bucketName"`
+	} `json:"allowed"`
+
+	// BucketID 存储策略对应存储桶的 ID
+	BucketID string `json:"-"`
+}
+
+// Bucket 存储桶信息
+type Bucket struct {
+	BucketID   string `json:"bucketId"`
+	BucketName string `json:"bucketName"`
+	BucketType string `json:"bucketType"`
+}
+
+// ListBucketsResponse b2_list_buckets 响应
+type ListBucketsResponse struct {
+	Buckets []Bucket `json:"buckets"`
+}
+
+// UploadURL b2_get_upload_url、b2_get_upload_part_url 返回的上传地址
+type UploadURL struct {
+	FileID             string `json:"fileId,omitempty"`
+	UploadURL          string `json:"uploadUrl"`
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+// FileInfo 文件信息
+type FileInfo struct {
+	FileID          string `json:"fileId"`
+	FileName        string `json:"fileName"`
+	ContentLength   uint64 `json:"contentLength"`
+	ContentSha1     string `json:"contentSha1"`
+	Action          string `json:"action"`
+	UploadTimestamp int64  `json:"uploadTimestamp"`
+}
+
+// ListFilesResponse b2_list_file_names、b2_list_file_versions 响应
+type ListFilesResponse struct {
+	Files        []FileInfo `json:"files"`
+	NextFileName *string    `json:"nextFileName"`
+	NextFileID   *string    `json:"nextFileId"`
+}
+
+// PartInfo 大文件的分片信息
+type PartInfo struct {
+	PartNumber    int    `json:"partNumber"`
+	ContentLength uint64 `json:"contentLength"`
+	ContentSha1   string `json:"contentSha1"`
+}
+
+// ListPartsResponse b2_list_parts 响应
+type ListPartsResponse struct {
+	Parts          []PartInfo `json:"parts"`
+	NextPartNumber *int       `json:"nextPartNumber"`
+}
+
+// DownloadAuthorization b2_get_download_authorization 响应
+type DownloadAuthorization struct {
+	AuthorizationToken string `json:"authorizationToken"`
+}
+
+// CORSRule 存储桶跨域规则
+type CORSRule struct {
+	CorsRuleName      string   `json:"corsRuleName"`
+	AllowedOrigins    []string `json:"allowedOrigins"`
+	AllowedOperations []string `json:"allowedOperations"`
+	AllowedHeaders    []string `json:"allowedHeaders"`
+	ExposeHeaders     []string `json:"exposeHeaders"`
+	MaxAgeSeconds     int      `json:"maxAgeSeconds"`
+}
+
+func init() {
+	gob.Register(Credential{})
+}
@@ -155,6 +155,15 @@ func (handler Driver) Get(ctx context.Context, path string) (response.RSCloser,
 		return nil, err
 	}
 
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		if rs, ok := request.GetParallelReader(ctx, handler.HTTPClient, downloadURL, int64(file.Size),
+			request.WithTimeout(time.Duration(0)),
+		); ok {
+			rs.SetFirstFakeChunk()
+			return rs, nil
+		}
+	}
+
 	// 获取文件数据流
 	resp, err := handler.HTTPClient.Request(
 		"GET",
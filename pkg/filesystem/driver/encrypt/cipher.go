@@ -0,0 +1,344 @@
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+/*
+加密文件格式：
+
+	header | chunk 0 | chunk 1 | ... | chunk n
+
+header 固定为 headerSize 字节，依次为魔数、主密钥版本、包装数据密钥使用
+的 nonce 及由主密钥加密的数据密钥。每个文件使用随机生成的数据密钥，明文
+按 ChunkSize 分块，每块使用随机 nonce 以 AES-GCM 加密，块序号作为附加数据
+防止块被调换。块的密文长度固定，因此可根据明文偏移定位到对应的块
+*/
+
+const (
+	// ChunkSize 明文分块大小，分片上传的分片大小须为其整数倍
+	ChunkSize = 64 << 10
+
+	keySize       = 32
+	nonceSize     = 12
+	tagSize       = 16
+	chunkOverhead = nonceSize + tagSize
+	encChunkSize  = ChunkSize + chunkOverhead
+	magic         = "CRE1"
+	headerSize    = len(magic) + 4 + nonceSize + keySize + tagSize
+)
+
+var (
+	ErrNoMasterKey     = errors.New("no master key is configured for the encrypted storage policy")
+	ErrUnknownKey      = errors.New("master key of the encrypted file is not available")
+	ErrInvalidHeader   = errors.New("invalid header of encrypted file")
+	ErrCorruptedChunk  = errors.New("encrypted file is corrupted")
+	ErrUnalignedOffset = errors.New("upload offset of encrypted file must be a multiple of 64 KiB")
+)
+
+// GenerateKey 生成 Base64 编码的主密钥
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// newAEAD 使用 key 创建 AES-GCM 实例
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// masterKey 解析版本为 version 的主密钥，版本从 1 开始
+func masterKey(keys []string, version uint32) ([]byte, error) {
+	if version == 0 || int(version) > len(keys) {
+		return nil, ErrUnknownKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(keys[version-1])
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("invalid master key of version %d", version)
+	}
+	return key, nil
+}
+
+// newHeader 生成新的数据密钥，返回使用当前主密钥包装后的文件头及数据密钥
+func newHeader(keys []string) ([]byte, []byte, error) {
+	if len(keys) == 0 {
+		return nil, nil, ErrNoMasterKey
+	}
+
+	version := uint32(len(keys))
+	master, err := masterKey(keys, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := make([]byte, len(magic)+4+nonceSize, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], version)
+	nonce := header[len(magic)+4:]
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(dek); err != nil {
+		return nil, nil, err
+	}
+
+	// 魔数及版本作为附加数据，防止被篡改
+	header = aead.Seal(header, nonce, dek, header[:len(magic)+4])
+	return header, dek, nil
+}
+
+// openHeader 解析文件头，返回数据密钥
+func openHeader(keys []string, header []byte) ([]byte, error) {
+	if len(header) != headerSize || string(header[:len(magic)]) != magic {
+		return nil, ErrInvalidHeader
+	}
+
+	master, err := masterKey(keys, binary.BigEndian.Uint32(header[len(magic):]))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(master)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := header[len(magic)+4 : len(magic)+4+nonceSize]
+	dek, err := aead.Open(nil, nonce, header[len(magic)+4+nonceSize:], header[:len(magic)+4])
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+	return dek, nil
+}
+
+// chunkAAD 返回块序号对应的附加数据
+func chunkAAD(index uint64) []byte {
+	aad := make([]byte, 8)
+	binary.BigEndian.PutUint64(aad, index)
+	return aad
+}
+
+// EncryptedSize 返回明文长度为 size 的块序列加密后的长度，不包含文件头
+func EncryptedSize(size uint64) uint64 {
+	res := size / ChunkSize * encChunkSize
+	if rem := size % ChunkSize; rem > 0 {
+		res += rem + chunkOverhead
+	}
+	return res
+}
+
+// plainSize 返回长度为 size 的块序列对应的明文长度，不包含文件头
+func plainSize(size uint64) (uint64, error) {
+	res := size / encChunkSize * ChunkSize
+	if rem := size % encChunkSize; rem > 0 {
+		if rem <= chunkOverhead {
+			return 0, ErrCorruptedChunk
+		}
+		res += rem - chunkOverhead
+	}
+	return res, nil
+}
+
+// encryptReader 将明文流加密为块序列
+type encryptReader struct {
+	src   io.Reader
+	aead  cipher.AEAD
+	index uint64
+	plain []byte
+	buf   []byte
+	err   error
+}
+
+func newEncryptReader(src io.Reader, dek []byte, index uint64, prefix []byte) (*encryptReader, error) {
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptReader{
+		src:   src,
+		aead:  aead,
+		index: index,
+		plain: make([]byte, ChunkSize),
+		buf:   prefix,
+	}, nil
+}
+
+// Read 实现 io.Reader
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		n, err := io.ReadFull(r.src, r.plain)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		r.err = err
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		if n > 0 {
+			nonce := make([]byte, nonceSize, encChunkSize)
+			if _, err := rand.Read(nonce); err != nil {
+				return 0, err
+			}
+			r.buf = r.aead.Seal(nonce, nonce, r.plain[:n], chunkAAD(r.index))
+			r.index++
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// decryptReader 解密块序列，支持按明文偏移定位
+type decryptReader struct {
+	src        io.ReadSeeker
+	closer     io.Closer
+	aead       cipher.AEAD
+	size       uint64
+	pos        uint64
+	chunkIndex uint64
+	chunk      []byte
+	loaded     bool
+	enc        []byte
+}
+
+func newDecryptReader(src io.ReadSeeker, closer io.Closer, keys []string) (*decryptReader, error) {
+	total, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if total < int64(headerSize) {
+		return nil, ErrInvalidHeader
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, err
+	}
+
+	dek, err := openHeader(keys, header)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := plainSize(uint64(total) - uint64(headerSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:    src,
+		closer: closer,
+		aead:   aead,
+		size:   size,
+		enc:    make([]byte, encChunkSize),
+	}, nil
+}
+
+// load 读取并解密给定序号的块
+func (r *decryptReader) load(index uint64) error {
+	if r.loaded && r.chunkIndex == index {
+		return nil
+	}
+
+	if _, err := r.src.Seek(int64(headerSize)+int64(index*encChunkSize), io.SeekStart); err != nil {
+		return err
+	}
+
+	n, err := io.ReadFull(r.src, r.enc)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	if n <= chunkOverhead {
+		return ErrCorruptedChunk
+	}
+
+	r.loaded = false
+	r.chunk, err = r.aead.Open(r.chunk[:0], r.enc[:nonceSize], r.enc[nonceSize:n], chunkAAD(index))
+	if err != nil {
+		return ErrCorruptedChunk
+	}
+
+	r.chunkIndex = index
+	r.loaded = true
+	return nil
+}
+
+// Read 实现 io.Reader
+func (r *decryptReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	if err := r.load(r.pos / ChunkSize); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, r.chunk[r.pos%ChunkSize:])
+	r.pos += uint64(n)
+	return n, nil
+}
+
+// Seek 实现 io.Seeker，偏移量为明文偏移
+func (r *decryptReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = int64(r.pos) + offset
+	case io.SeekEnd:
+		pos = int64(r.size) + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.pos = uint64(pos)
+	return pos, nil
+}
+
+// Close 实现 io.Closer
+func (r *decryptReader) Close() error {
+	return r.closer.Close()
+}
@@ -0,0 +1,179 @@
+package encrypt
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// Driver 为存储策略适配器提供静态加密，写入时加密、读取时解密
+type Driver struct {
+	Handler driver.Handler
+	Policy  *model.Policy
+}
+
+// truncater 支持截断物理文件的存储策略适配器
+type truncater interface {
+	Truncate(ctx context.Context, src string, size uint64) error
+}
+
+// NewDriver 使用存储策略的主密钥包装适配器
+func NewDriver(handler driver.Handler, policy *model.Policy) *Driver {
+	return &Driver{
+		Handler: handler,
+		Policy:  policy,
+	}
+}
+
+// Put 加密文件流后交由下层适配器保存
+func (d *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+
+	var (
+		header []byte
+		dek    []byte
+		err    error
+	)
+
+	if fileInfo.Mode&fsctx.Append == fsctx.Append && fileInfo.AppendStart > 0 {
+		if fileInfo.AppendStart%ChunkSize != 0 {
+			return ErrUnalignedOffset
+		}
+
+		// 续传时沿用已上传部分的数据密钥
+		dek, err = d.readKey(ctx, fileInfo.SavePath)
+		if err != nil {
+			return fmt.Errorf("failed to read key of uploaded chunks: %w", err)
+		}
+	} else {
+		header, dek, err = newHeader(d.Policy.OptionsSerialized.EncryptionKeys)
+		if err != nil {
+			return err
+		}
+	}
+
+	index := fileInfo.AppendStart / ChunkSize
+	reader, err := newEncryptReader(file, dek, index, header)
+	if err != nil {
+		return err
+	}
+
+	appendStart := uint64(0)
+	if fileInfo.AppendStart > 0 {
+		appendStart = uint64(headerSize) + EncryptedSize(fileInfo.AppendStart)
+	}
+
+	return d.Handler.Put(ctx, &fsctx.FileStream{
+		Mode:            fileInfo.Mode,
+		LastModified:    fileInfo.LastModified,
+		Metadata:        fileInfo.Metadata,
+		File:            io.NopCloser(reader),
+		Size:            uint64(len(header)) + EncryptedSize(fileInfo.Size),
+		VirtualPath:     fileInfo.VirtualPath,
+		Name:            fileInfo.FileName,
+		MimeType:        fileInfo.MimeType,
+		SavePath:        fileInfo.SavePath,
+		UploadSessionID: fileInfo.UploadSessionID,
+		AppendStart:     appendStart,
+		Model:           fileInfo.Model,
+		Src:             fileInfo.Src,
+	})
+}
+
+// readKey 读取已保存文件的数据密钥
+func (d *Driver) readKey(ctx context.Context, path string) ([]byte, error) {
+	src, err := d.Handler.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	return openHeader(d.Policy.OptionsSerialized.EncryptionKeys, header)
+}
+
+// Get 获取解密后的文件内容
+func (d *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	src, err := d.Handler.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.decrypt(src)
+}
+
+// decrypt 包装加密的文件流
+func (d *Driver) decrypt(src response.RSCloser) (response.RSCloser, error) {
+	reader, err := newDecryptReader(src, src, d.Policy.OptionsSerialized.EncryptionKeys)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("failed to decrypt file: %w", err)
+	}
+
+	return reader, nil
+}
+
+// Thumb 获取解密后的缩略图
+func (d *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	res, err := d.Handler.Thumb(ctx, file)
+	if err != nil || res.Redirect || res.Content == nil {
+		return res, err
+	}
+
+	res.Content, err = d.decrypt(res.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// Truncate 将文件截断至明文长度 size，用于分片上传失败后的回滚
+func (d *Driver) Truncate(ctx context.Context, src string, size uint64) error {
+	handler, ok := d.Handler.(truncater)
+	if !ok {
+		return nil
+	}
+
+	if size == 0 {
+		return handler.Truncate(ctx, src, 0)
+	}
+
+	return handler.Truncate(ctx, src, uint64(headerSize)+EncryptedSize(size))
+}
+
+// Delete 删除一个或多个文件
+func (d *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	return d.Handler.Delete(ctx, files)
+}
+
+// Source 获取外链/下载地址
+func (d *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	return d.Handler.Source(ctx, path, ttl, isDownload, speed)
+}
+
+// Token 获取上传凭证
+func (d *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	return d.Handler.Token(ctx, ttl, uploadSession, file)
+}
+
+// CancelToken 取消上传凭证
+func (d *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return d.Handler.CancelToken(ctx, uploadSession)
+}
+
+// List 列出文件，返回的文件大小为加密后的大小
+func (d *Driver) List(ctx context.Context, path string, recursive bool) ([]response.Object, error) {
+	return d.Handler.List(ctx, path, recursive)
+}
@@ -0,0 +1,293 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryHandler 将文件保存在内存中的适配器
+type memoryHandler struct {
+	files map[string][]byte
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+func (h *memoryHandler) Put(ctx context.Context, file fsctx.FileHeader) error {
+	info := file.Info()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	if uint64(len(content)) != info.Size {
+		return errors.New("unexpected size")
+	}
+
+	if info.Mode&fsctx.Append == fsctx.Append {
+		old := h.files[info.SavePath]
+		if uint64(len(old)) < info.AppendStart {
+			return errors.New("size of unfinished uploaded chunks is not as expected")
+		}
+		content = append(old[:info.AppendStart:info.AppendStart], content...)
+	}
+
+	h.files[info.SavePath] = content
+	return nil
+}
+
+func (h *memoryHandler) Delete(ctx context.Context, files []string) ([]string, error) {
+	for _, file := range files {
+		delete(h.files, file)
+	}
+	return []string{}, nil
+}
+
+func (h *memoryHandler) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	content, ok := h.files[path]
+	if !ok {
+		return nil, errors.New("not exist")
+	}
+	return memoryFile{bytes.NewReader(content)}, nil
+}
+
+func (h *memoryHandler) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	content, err := h.Get(ctx, file.ThumbFile())
+	if err != nil {
+		return nil, err
+	}
+	return &response.ContentResponse{Content: content}, nil
+}
+
+func (h *memoryHandler) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	return "", nil
+}
+
+func (h *memoryHandler) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	return &serializer.UploadCredential{}, nil
+}
+
+func (h *memoryHandler) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return nil
+}
+
+func (h *memoryHandler) List(ctx context.Context, path string, recursive bool) ([]response.Object, error) {
+	return nil, nil
+}
+
+func (h *memoryHandler) Truncate(ctx context.Context, src string, size uint64) error {
+	h.files[src] = h.files[src][:size]
+	return nil
+}
+
+func newTestDriver(t *testing.T, keys int) (*Driver, *memoryHandler) {
+	policy := &model.Policy{Type: "local"}
+	policy.OptionsSerialized.Encryption = true
+	for i := 0; i < keys; i++ {
+		key, err := GenerateKey()
+		assert.NoError(t, err)
+		policy.OptionsSerialized.EncryptionKeys = append(policy.OptionsSerialized.EncryptionKeys, key)
+	}
+
+	handler := &memoryHandler{files: make(map[string][]byte)}
+	return NewDriver(handler, policy), handler
+}
+
+func randomContent(size int) []byte {
+	content := make([]byte, size)
+	rand.Read(content)
+	return content
+}
+
+func put(d *Driver, path string, content []byte, mode fsctx.WriteMode, start uint64) error {
+	return d.Put(context.Background(), &fsctx.FileStream{
+		File:        io.NopCloser(bytes.NewReader(content)),
+		Size:        uint64(len(content)),
+		SavePath:    path,
+		Mode:        mode,
+		AppendStart: start,
+	})
+}
+
+func get(d *Driver, path string) ([]byte, error) {
+	file, err := d.Get(context.Background(), path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func TestGenerateKey(t *testing.T) {
+	asserts := assert.New(t)
+
+	key, err := GenerateKey()
+	asserts.NoError(err)
+	raw, err := masterKey([]string{key}, 1)
+	asserts.NoError(err)
+	asserts.Len(raw, keySize)
+
+	_, err = masterKey([]string{key}, 2)
+	asserts.Equal(ErrUnknownKey, err)
+	_, err = masterKey([]string{"invalid"}, 1)
+	asserts.Error(err)
+}
+
+func TestEncryptedSize(t *testing.T) {
+	asserts := assert.New(t)
+
+	for _, size := range []uint64{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3 * ChunkSize} {
+		res, err := plainSize(EncryptedSize(size))
+		asserts.NoError(err)
+		asserts.Equal(size, res)
+	}
+
+	_, err := plainSize(chunkOverhead)
+	asserts.Equal(ErrCorruptedChunk, err)
+}
+
+func TestDriver_PutGet(t *testing.T) {
+	asserts := assert.New(t)
+	d, handler := newTestDriver(t, 1)
+
+	// 空文件
+	{
+		asserts.NoError(put(d, "empty", []byte{}, fsctx.Overwrite, 0))
+		asserts.Len(handler.files["empty"], headerSize)
+		content, err := get(d, "empty")
+		asserts.NoError(err)
+		asserts.Empty(content)
+	}
+
+	// 多个分块
+	{
+		plain := randomContent(2*ChunkSize + 100)
+		asserts.NoError(put(d, "file", plain, fsctx.Overwrite, 0))
+		asserts.Len(handler.files["file"], headerSize+int(EncryptedSize(uint64(len(plain)))))
+		asserts.False(bytes.Contains(handler.files["file"], plain[:64]))
+
+		content, err := get(d, "file")
+		asserts.NoError(err)
+		asserts.Equal(plain, content)
+
+		// 按明文偏移读取
+		file, err := d.Get(context.Background(), "file")
+		asserts.NoError(err)
+		size, err := file.Seek(0, io.SeekEnd)
+		asserts.NoError(err)
+		asserts.EqualValues(len(plain), size)
+		_, err = file.Seek(ChunkSize-10, io.SeekStart)
+		asserts.NoError(err)
+		part := make([]byte, 20)
+		_, err = io.ReadFull(file, part)
+		asserts.NoError(err)
+		asserts.Equal(plain[ChunkSize-10:ChunkSize+10], part)
+		asserts.NoError(file.Close())
+	}
+
+	// 未设置主密钥
+	{
+		d.Policy.OptionsSerialized.EncryptionKeys = nil
+		asserts.Equal(ErrNoMasterKey, put(d, "file", []byte("test"), fsctx.Overwrite, 0))
+	}
+}
+
+func TestDriver_PutAppend(t *testing.T) {
+	asserts := assert.New(t)
+	d, handler := newTestDriver(t, 1)
+	plain := randomContent(3*ChunkSize + 10)
+
+	asserts.NoError(put(d, "file", plain[:2*ChunkSize], fsctx.Append, 0))
+	asserts.NoError(put(d, "file", plain[2*ChunkSize:], fsctx.Append, 2*ChunkSize))
+	content, err := get(d, "file")
+	asserts.NoError(err)
+	asserts.Equal(plain, content)
+
+	// 重传分片时覆盖已上传的部分
+	asserts.NoError(put(d, "file", plain[2*ChunkSize:], fsctx.Append, 2*ChunkSize))
+	content, err = get(d, "file")
+	asserts.NoError(err)
+	asserts.Equal(plain, content)
+
+	// 回滚至第一个分片
+	asserts.NoError(d.Truncate(context.Background(), "file", 2*ChunkSize))
+	content, err = get(d, "file")
+	asserts.NoError(err)
+	asserts.Equal(plain[:2*ChunkSize], content)
+	asserts.NoError(d.Truncate(context.Background(), "file", 0))
+	asserts.Empty(handler.files["file"])
+
+	// 偏移未对齐
+	asserts.Equal(ErrUnalignedOffset, put(d, "file", plain, fsctx.Append, 100))
+}
+
+func TestDriver_KeyRotation(t *testing.T) {
+	asserts := assert.New(t)
+	d, handler := newTestDriver(t, 1)
+
+	asserts.NoError(put(d, "old", []byte("old file"), fsctx.Overwrite, 0))
+
+	key, err := GenerateKey()
+	asserts.NoError(err)
+	d.Policy.OptionsSerialized.EncryptionKeys = append(d.Policy.OptionsSerialized.EncryptionKeys, key)
+	asserts.NoError(put(d, "new", []byte("new file"), fsctx.Overwrite, 0))
+	asserts.EqualValues(1, binary.BigEndian.Uint32(handler.files["old"][len(magic):]))
+	asserts.EqualValues(2, binary.BigEndian.Uint32(handler.files["new"][len(magic):]))
+
+	// 旧文件仍可使用旧密钥解密
+	content, err := get(d, "old")
+	asserts.NoError(err)
+	asserts.Equal("old file", string(content))
+	content, err = get(d, "new")
+	asserts.NoError(err)
+	asserts.Equal("new file", string(content))
+
+	// 密钥丢失
+	d.Policy.OptionsSerialized.EncryptionKeys = d.Policy.OptionsSerialized.EncryptionKeys[:1]
+	_, err = get(d, "new")
+	asserts.ErrorIs(err, ErrUnknownKey)
+}
+
+func TestDriver_Tampered(t *testing.T) {
+	asserts := assert.New(t)
+	d, handler := newTestDriver(t, 1)
+
+	asserts.NoError(put(d, "file", []byte("content"), fsctx.Overwrite, 0))
+	handler.files["file"][headerSize+nonceSize] ^= 1
+	_, err := get(d, "file")
+	asserts.ErrorIs(err, ErrCorruptedChunk)
+
+	handler.files["plain"] = []byte(strings.Repeat("a", headerSize+10))
+	_, err = get(d, "plain")
+	asserts.ErrorIs(err, ErrInvalidHeader)
+}
+
+func TestDriver_Thumb(t *testing.T) {
+	asserts := assert.New(t)
+	d, _ := newTestDriver(t, 1)
+	cache.Set("setting_thumb_file_suffix", "._thumb", 0)
+	file := &model.File{SourceName: "file"}
+
+	asserts.NoError(put(d, file.ThumbFile(), []byte("thumb"), fsctx.Overwrite, 0))
+	res, err := d.Thumb(context.Background(), file)
+	asserts.NoError(err)
+	content, err := io.ReadAll(res.Content)
+	asserts.NoError(err)
+	asserts.Equal("thumb", string(content))
+}
@@ -89,7 +89,7 @@ func (client *Client) ListChildren(ctx context.Context, path string) ([]FileInfo
 		}
 		if retried < ListRetry {
 			retried++
-			util.Log().Debug("Failed to list path %q: %s, will retry in 5 seconds.", path, err)
+			util.LogCtx(ctx).Debug("Failed to list path %q: %s, will retry in 5 seconds.", path, err)
 			time.Sleep(time.Duration(5) * time.Second)
 			return client.ListChildren(context.WithValue(ctx, fsctx.RetryCtx, retried), path)
 		}
@@ -571,12 +571,12 @@ func (client *Client) request(ctx context.Context, method string, url string, bo
 	if res.Response.StatusCode < 200 || res.Response.StatusCode >= 300 {
 		decodeErr = json.Unmarshal([]byte(respBody), &errResp)
 		if decodeErr != nil {
-			util.Log().Debug("Onedrive returns unknown response: %s", respBody)
+			util.LogCtx(ctx).Debug("Onedrive returns unknown response: %s", respBody)
 			return "", sysError(decodeErr)
 		}
 
 		if res.Response.StatusCode == 429 {
-			util.Log().Warning("OneDrive request is throttled.")
+			util.LogCtx(ctx).Warning("OneDrive request is throttled.")
 			return "", backoff.NewRetryableErrorFromHeader(&errResp, res.Response.Header)
 		}
 
@@ -0,0 +1,277 @@
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// handshakeTimeout 等待插件报告监听地址的超时时间
+const handshakeTimeout = 10 * time.Second
+
+// ErrPluginExited 插件进程已退出
+var ErrPluginExited = errors.New("driver plugin exited")
+
+// Client 宿主侧的插件连接，对应一个插件进程
+type Client struct {
+	Name string
+
+	cmd    *exec.Cmd
+	rpc    *rpc.Client
+	exited chan struct{}
+
+	mu sync.Mutex
+	// initialized 已初始化的存储策略及其配置版本
+	initialized map[uint]time.Time
+}
+
+// Start 启动插件进程并建立连接
+func Start(name, path string) (*Client, error) {
+	token := util.RandStringRunes(32)
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(),
+		MagicCookieKey+"="+MagicCookieValue,
+		TokenKey+"="+token,
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start driver plugin %q: %w", name, err)
+	}
+
+	client := &Client{
+		Name:        name,
+		cmd:         cmd,
+		exited:      make(chan struct{}),
+		initialized: make(map[uint]time.Time),
+	}
+
+	go client.log(stderr)
+	go func() {
+		err := cmd.Wait()
+		util.Log().Warning("Driver plugin %q exited: %v", name, err)
+		close(client.exited)
+	}()
+
+	addr, err := client.handshake(bufio.NewReader(stdout))
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	if err := client.connect(addr, token); err != nil {
+		client.Kill()
+		return nil, err
+	}
+
+	util.Log().Info("Driver plugin %q started.", name)
+	return client, nil
+}
+
+// connect 连接插件 RPC 服务并发送凭证
+func (c *Client) connect(addr, token string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to driver plugin %q: %w", c.Name, err)
+	}
+
+	if _, err := io.WriteString(conn, token+"\n"); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.rpc = rpc.NewClient(conn)
+	return nil
+}
+
+// handshake 读取插件报告的协议版本与监听地址，其余输出记录为日志
+func (c *Client) handshake(stdout *bufio.Reader) (string, error) {
+	result := make(chan string, 1)
+	go func() {
+		line, _ := stdout.ReadString('\n')
+		result <- strings.TrimSpace(line)
+		c.log(stdout)
+	}()
+
+	var line string
+	select {
+	case line = <-result:
+	case <-c.exited:
+		return "", ErrPluginExited
+	case <-time.After(handshakeTimeout):
+		return "", fmt.Errorf("timeout waiting for handshake of driver plugin %q", c.Name)
+	}
+
+	return parseHandshake(line)
+}
+
+// parseHandshake 解析形如 "1|tcp|127.0.0.1:1234" 的握手信息
+func parseHandshake(line string) (string, error) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid driver plugin handshake %q", line)
+	}
+
+	version, err := strconv.Atoi(parts[0])
+	if err != nil || version != ProtocolVersion {
+		return "", fmt.Errorf("unsupported driver plugin protocol version %q, expected %d", parts[0], ProtocolVersion)
+	}
+
+	if parts[1] != "tcp" {
+		return "", fmt.Errorf("unsupported driver plugin network %q", parts[1])
+	}
+
+	return parts[2], nil
+}
+
+// log 将插件输出记录为日志
+func (c *Client) log(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		util.Log().Debug("[Plugin %s] %s", c.Name, scanner.Text())
+	}
+}
+
+// Exited 插件进程是否已退出
+func (c *Client) Exited() bool {
+	select {
+	case <-c.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// Kill 结束插件进程
+func (c *Client) Kill() {
+	if c.rpc != nil {
+		c.rpc.Close()
+	}
+
+	if c.cmd != nil && c.cmd.Process != nil && !c.Exited() {
+		_ = c.cmd.Process.Kill()
+	}
+}
+
+// call 调用插件方法
+func (c *Client) call(method string, args, reply interface{}) error {
+	if c.Exited() {
+		return ErrPluginExited
+	}
+
+	return remoteError(c.rpc.Call(rpcService+"."+method, args, reply))
+}
+
+// Init 使用存储策略配置初始化插件，配置未变化时跳过
+func (c *Client) Init(config *Config, version time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if current, ok := c.initialized[config.PolicyID]; ok && current.Equal(version) {
+		return nil
+	}
+
+	if err := c.call("Init", &InitArgs{Config: *config}, &struct{}{}); err != nil {
+		return err
+	}
+
+	c.initialized[config.PolicyID] = version
+	return nil
+}
+
+// Create 开始写入，返回写入会话ID
+func (c *Client) Create(policy uint, path string, offset uint64, overwrite bool) (string, error) {
+	var session string
+	err := c.call("Create", &CreateArgs{Policy: policy, Path: path, Offset: offset, Overwrite: overwrite}, &session)
+	return session, err
+}
+
+// Write 向写入会话写入数据
+func (c *Client) Write(session string, data []byte) (int, error) {
+	var n int
+	err := c.call("Write", &WriteArgs{Session: session, Data: data}, &n)
+	return n, err
+}
+
+// Commit 完成写入
+func (c *Client) Commit(session string) error {
+	return c.call("Commit", session, &struct{}{})
+}
+
+// Abort 放弃写入
+func (c *Client) Abort(session string) error {
+	return c.call("Abort", session, &struct{}{})
+}
+
+// Delete 删除对象
+func (c *Client) Delete(policy uint, paths []string) ([]string, error) {
+	var reply DeleteReply
+	if err := c.call("Delete", &DeleteArgs{Policy: policy, Paths: paths}, &reply); err != nil {
+		return paths, err
+	}
+
+	if reply.Error != "" {
+		return reply.Failed, errors.New(reply.Error)
+	}
+
+	return reply.Failed, nil
+}
+
+// Stat 获取对象信息
+func (c *Client) Stat(policy uint, path string) (*Object, error) {
+	var object Object
+	if err := c.call("Stat", &PathArgs{Policy: policy, Path: path}, &object); err != nil {
+		return nil, err
+	}
+
+	return &object, nil
+}
+
+// ReadAt 从 offset 处读取数据，读到末尾时返回 io.EOF
+func (c *Client) ReadAt(policy uint, path string, p []byte, offset int64) (int, error) {
+	var reply ReadReply
+	if err := c.call("ReadAt", &ReadArgs{Policy: policy, Path: path, Offset: offset, Length: len(p)}, &reply); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, reply.Data)
+	if reply.EOF {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+// Source 获取外链
+func (c *Client) Source(policy uint, path string, ttl int64, isDownload bool) (string, error) {
+	var url string
+	err := c.call("Source", &SourceArgs{Policy: policy, Path: path, TTL: ttl, IsDownload: isDownload}, &url)
+	return url, err
+}
+
+// List 列取对象
+func (c *Client) List(policy uint, path string, recursive bool) ([]Object, error) {
+	var objects []Object
+	err := c.call("List", &ListArgs{Policy: policy, Path: path, Recursive: recursive}, &objects)
+	return objects, err
+}
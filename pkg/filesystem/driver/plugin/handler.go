@@ -0,0 +1,227 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// chunkSize 单次 RPC 调用传输的数据大小
+const chunkSize = 1 << 20
+
+// Driver 插件存储策略适配器，上传由 Cloudreve 中转
+type Driver struct {
+	Policy *model.Policy
+	Client *Client
+}
+
+// NewDriver 启动存储策略指定的插件并返回适配器
+func NewDriver(policy *model.Policy) (*Driver, error) {
+	client, err := Default.Get(policy.OptionsSerialized.Plugin)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDriver(policy, client)
+}
+
+func newDriver(policy *model.Policy, client *Client) (*Driver, error) {
+	config := &Config{
+		PolicyID:   policy.ID,
+		Name:       policy.Name,
+		Server:     policy.Server,
+		BucketName: policy.BucketName,
+		IsPrivate:  policy.IsPrivate,
+		BaseURL:    policy.BaseURL,
+		AccessKey:  policy.AccessKey,
+		SecretKey:  policy.SecretKey,
+		Options:    policy.OptionsSerialized.PluginOptions,
+	}
+
+	if err := client.Init(config, policy.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &Driver{Policy: policy, Client: client}, nil
+}
+
+// Put 将文件流分块写入插件，上下文关闭时放弃写入
+func (handler *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
+	defer file.Close()
+	fileInfo := file.Info()
+
+	var offset uint64
+	if fileInfo.Mode&fsctx.Append == fsctx.Append {
+		offset = fileInfo.AppendStart
+	}
+
+	session, err := handler.Client.Create(handler.Policy.ID, fileInfo.SavePath, offset,
+		fileInfo.Mode&fsctx.Overwrite == fsctx.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			_ = handler.Client.Abort(session)
+			return err
+		}
+
+		n, readErr := io.ReadFull(file, buf)
+		if n > 0 {
+			if _, err := handler.Client.Write(session, buf[:n]); err != nil {
+				_ = handler.Client.Abort(session)
+				return err
+			}
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+
+		if readErr != nil {
+			_ = handler.Client.Abort(session)
+			return readErr
+		}
+	}
+
+	return handler.Client.Commit(session)
+}
+
+// Truncate 将对象截断至 size，用于分片上传失败后的回滚
+func (handler *Driver) Truncate(ctx context.Context, src string, size uint64) error {
+	session, err := handler.Client.Create(handler.Policy.ID, src, size, true)
+	if err != nil {
+		return err
+	}
+
+	return handler.Client.Commit(session)
+}
+
+// Delete 删除一个或多个文件
+func (handler *Driver) Delete(ctx context.Context, files []string) ([]string, error) {
+	return handler.Client.Delete(handler.Policy.ID, files)
+}
+
+// Get 获取文件内容
+func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
+	object, err := handler.Client.Stat(handler.Policy.ID, path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{
+		SectionReader: io.NewSectionReader(&readerAt{handler: handler, path: path}, 0, int64(object.Size)),
+	}, nil
+}
+
+// Thumb 插件不提供缩略图，已由 Cloudreve 代理生成的缩略图从插件读取
+func (handler *Driver) Thumb(ctx context.Context, file *model.File) (*response.ContentResponse, error) {
+	if file.MetadataSerialized[model.ThumbStatusMetadataKey] != model.ThumbStatusExist {
+		return nil, driver.ErrorThumbNotSupported
+	}
+
+	thumb, err := handler.Get(ctx, file.ThumbFile())
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.ContentResponse{
+		Redirect: false,
+		Content:  thumb,
+	}, nil
+}
+
+// Source 获取外链URL，插件未提供地址时由 Cloudreve 中转
+func (handler *Driver) Source(ctx context.Context, path string, ttl int64, isDownload bool, speed int) (string, error) {
+	url, err := handler.Client.Source(handler.Policy.ID, path, ttl, isDownload)
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return "", err
+	}
+
+	if url != "" {
+		return url, nil
+	}
+
+	return local.Driver{Policy: handler.Policy}.Source(ctx, path, ttl, isDownload, speed)
+}
+
+// Token 上传由 Cloudreve 中转，返回上传会话信息
+func (handler *Driver) Token(ctx context.Context, ttl int64, uploadSession *serializer.UploadSession, file fsctx.FileHeader) (*serializer.UploadCredential, error) {
+	return &serializer.UploadCredential{
+		SessionID: uploadSession.Key,
+		ChunkSize: handler.Policy.OptionsSerialized.ChunkSize,
+	}, nil
+}
+
+// CancelToken 取消上传凭证
+func (handler *Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
+	return nil
+}
+
+// List 列取文件
+func (handler *Driver) List(ctx context.Context, path string, recursive bool) ([]response.Object, error) {
+	objects, err := handler.Client.List(handler.Policy.ID, path, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]response.Object, 0, len(objects))
+	for _, object := range objects {
+		res = append(res, response.Object{
+			Name:         object.Name,
+			RelativePath: object.RelativePath,
+			Source:       object.Source,
+			Size:         object.Size,
+			IsDir:        object.IsDir,
+			LastModify:   object.LastModify,
+		})
+	}
+
+	return res, nil
+}
+
+// readerAt 通过插件读取对象的指定区间
+type readerAt struct {
+	handler *Driver
+	path    string
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	read := 0
+	for read < len(p) {
+		end := read + chunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		n, err := r.handler.Client.ReadAt(r.handler.Policy.ID, r.path, p[read:end], off+int64(read))
+		read += n
+		if err != nil {
+			return read, err
+		}
+
+		if n == 0 {
+			return read, io.ErrUnexpectedEOF
+		}
+	}
+
+	return read, nil
+}
+
+// reader 可寻址的对象读取流
+type reader struct {
+	*io.SectionReader
+}
+
+func (r *reader) Close() error {
+	return nil
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// namePattern 合法的插件名称
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Loader 插件加载器，插件进程在首次使用时启动，退出后再次使用时重新启动
+type Loader struct {
+	// Dir 返回插件目录
+	Dir func() string
+
+	mu      sync.Mutex
+	clients map[string]*Client
+}
+
+// Default 默认插件加载器，插件目录由 driver_plugin_path 设置指定
+var Default = NewLoader(func() string {
+	return util.RelativePath(model.GetSettingByNameWithDefault("driver_plugin_path", "plugins"))
+})
+
+// NewLoader 新建插件加载器
+func NewLoader(dir func() string) *Loader {
+	return &Loader{
+		Dir:     dir,
+		clients: make(map[string]*Client),
+	}
+}
+
+// Available 列出插件目录下可用的插件名称
+func (l *Loader) Available() ([]string, error) {
+	entries, err := os.ReadDir(l.Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := strings.TrimSuffix(entry.Name(), executableExt())
+		if !strings.HasPrefix(name, ExecutablePrefix) {
+			continue
+		}
+
+		name = strings.TrimPrefix(name, ExecutablePrefix)
+		if namePattern.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Get 获取插件连接，插件未运行时启动插件
+func (l *Loader) Get(name string) (*Client, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid driver plugin name %q", name)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if client, ok := l.clients[name]; ok && !client.Exited() {
+		return client, nil
+	}
+
+	path := filepath.Join(l.Dir(), ExecutablePrefix+name+executableExt())
+	if !util.Exists(path) {
+		return nil, fmt.Errorf("driver plugin %q not found in %q", name, l.Dir())
+	}
+
+	client, err := Start(name, path)
+	if err != nil {
+		return nil, err
+	}
+
+	l.clients[name] = client
+	return client, nil
+}
+
+// Kill 结束所有插件进程
+func (l *Loader) Kill() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for name, client := range l.clients {
+		client.Kill()
+		delete(l.clients, name)
+	}
+}
+
+// executableExt 当前平台可执行文件扩展名
+func executableExt() string {
+	if runtime.GOOS == "windows" {
+		return ".exe"
+	}
+
+	return ""
+}
@@ -0,0 +1,356 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryBackend 测试用的内存存储
+type memoryBackend struct {
+	mu      sync.Mutex
+	config  *Config
+	objects map[string][]byte
+}
+
+type memoryWriter struct {
+	backend *memoryBackend
+	path    string
+	buf     *bytes.Buffer
+}
+
+func (w *memoryWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+func (w *memoryWriter) Commit() error {
+	w.backend.mu.Lock()
+	defer w.backend.mu.Unlock()
+	w.backend.objects[w.path] = w.buf.Bytes()
+	return nil
+}
+
+func (w *memoryWriter) Abort() error {
+	return nil
+}
+
+func (b *memoryBackend) Create(path string, offset uint64, overwrite bool) (Writer, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	existing, ok := b.objects[path]
+	if ok && !overwrite {
+		return nil, errors.New("object existed")
+	}
+
+	if offset > uint64(len(existing)) {
+		return nil, errors.New("offset out of range")
+	}
+
+	buf := bytes.NewBuffer(append([]byte{}, existing[:offset]...))
+	return &memoryWriter{backend: b, path: path, buf: buf}, nil
+}
+
+func (b *memoryBackend) Delete(paths []string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, path := range paths {
+		delete(b.objects, path)
+	}
+	return []string{}, nil
+}
+
+func (b *memoryBackend) Stat(path string) (*Object, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[path]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return &Object{Name: path, Source: path, Size: uint64(len(data))}, nil
+}
+
+func (b *memoryBackend) ReadAt(path string, p []byte, offset int64) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, ok := b.objects[path]
+	if !ok {
+		return 0, ErrNotExist
+	}
+	return bytes.NewReader(data).ReadAt(p, offset)
+}
+
+func (b *memoryBackend) Source(path string, ttl int64, isDownload bool) (string, error) {
+	return b.config.Server + "/" + path, nil
+}
+
+func (b *memoryBackend) List(path string, recursive bool) ([]Object, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	res := make([]Object, 0, len(b.objects))
+	for name, data := range b.objects {
+		if strings.HasPrefix(name, path) {
+			res = append(res, Object{Name: name, RelativePath: strings.TrimPrefix(name, path), Size: uint64(len(data))})
+		}
+	}
+	return res, nil
+}
+
+// startTestServer 在当前进程中启动插件服务并返回连接
+func startTestServer(t *testing.T, factory Factory) *Client {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go serve(listener, "token", factory)
+
+	client := &Client{Name: "memory", exited: make(chan struct{}), initialized: make(map[uint]time.Time)}
+	if err := client.connect(listener.Addr().String(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Kill)
+	return client
+}
+
+func TestParseHandshake(t *testing.T) {
+	asserts := assert.New(t)
+
+	addr, err := parseHandshake("1|tcp|127.0.0.1:5000")
+	asserts.NoError(err)
+	asserts.Equal("127.0.0.1:5000", addr)
+
+	_, err = parseHandshake("2|tcp|127.0.0.1:5000")
+	asserts.Error(err)
+
+	_, err = parseHandshake("1|unix|/tmp/plugin.sock")
+	asserts.Error(err)
+
+	_, err = parseHandshake("hello world")
+	asserts.Error(err)
+}
+
+func TestServe(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未由宿主启动
+	os.Unsetenv(MagicCookieKey)
+	asserts.Error(Serve(nil))
+}
+
+func TestDriver(t *testing.T) {
+	asserts := assert.New(t)
+	backend := &memoryBackend{objects: make(map[string][]byte)}
+	inits := 0
+	client := startTestServer(t, func(config *Config) (Backend, error) {
+		inits++
+		backend.config = config
+		return backend, nil
+	})
+
+	policy := &model.Policy{Server: "https://storage.example.com"}
+	policy.ID = 1
+	policy.OptionsSerialized.PluginOptions = map[string]string{"region": "moon"}
+	handler, err := newDriver(policy, client)
+	asserts.NoError(err)
+	asserts.Equal("moon", backend.config.Options["region"])
+
+	// 配置未修改时不重复初始化
+	_, err = newDriver(policy, client)
+	asserts.NoError(err)
+	asserts.Equal(1, inits)
+
+	ctx := context.Background()
+
+	// 写入首个分片
+	{
+		err := handler.Put(ctx, &fsctx.FileStream{
+			File:     io.NopCloser(strings.NewReader("hello ")),
+			SavePath: "1/file.txt",
+			Size:     6,
+			Mode:     fsctx.Append,
+		})
+		asserts.NoError(err)
+	}
+
+	// 追加分片
+	{
+		err := handler.Put(ctx, &fsctx.FileStream{
+			File:        io.NopCloser(strings.NewReader("world")),
+			SavePath:    "1/file.txt",
+			Size:        5,
+			Mode:        fsctx.Append | fsctx.Overwrite,
+			AppendStart: 6,
+		})
+		asserts.NoError(err)
+	}
+
+	// 已存在且不允许覆盖
+	{
+		err := handler.Put(ctx, &fsctx.FileStream{
+			File:     io.NopCloser(strings.NewReader("again")),
+			SavePath: "1/file.txt",
+		})
+		asserts.Error(err)
+	}
+
+	// 读取
+	{
+		rs, err := handler.Get(ctx, "1/file.txt")
+		asserts.NoError(err)
+		content, err := io.ReadAll(rs)
+		asserts.NoError(err)
+		asserts.Equal("hello world", string(content))
+
+		_, err = rs.Seek(6, io.SeekStart)
+		asserts.NoError(err)
+		content, err = io.ReadAll(rs)
+		asserts.NoError(err)
+		asserts.Equal("world", string(content))
+		asserts.NoError(rs.Close())
+	}
+
+	// 不存在的对象
+	{
+		_, err := handler.Get(ctx, "1/not_exist.txt")
+		asserts.Equal(ErrNotExist, err)
+	}
+
+	// 截断
+	{
+		asserts.NoError(handler.Truncate(ctx, "1/file.txt", 5))
+		asserts.Equal("hello", string(backend.objects["1/file.txt"]))
+	}
+
+	// 列取
+	{
+		objects, err := handler.List(ctx, "1/", true)
+		asserts.NoError(err)
+		asserts.Len(objects, 1)
+		asserts.Equal("file.txt", objects[0].RelativePath)
+		asserts.EqualValues(5, objects[0].Size)
+	}
+
+	// 外链
+	{
+		url, err := handler.Source(ctx, "1/file.txt", 60, false, 0)
+		asserts.NoError(err)
+		asserts.Equal("https://storage.example.com/1/file.txt", url)
+	}
+
+	// 缩略图未生成
+	{
+		_, err := handler.Thumb(ctx, &model.File{})
+		asserts.ErrorIs(err, driver.ErrorThumbNotSupported)
+	}
+
+	// 删除
+	{
+		failed, err := handler.Delete(ctx, []string{"1/file.txt"})
+		asserts.NoError(err)
+		asserts.Empty(failed)
+		asserts.Empty(backend.objects)
+	}
+
+	// 上下文已取消
+	{
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		err := handler.Put(canceled, &fsctx.FileStream{
+			File:     io.NopCloser(strings.NewReader("content")),
+			SavePath: "1/canceled.txt",
+		})
+		asserts.ErrorIs(err, context.Canceled)
+		asserts.Empty(backend.objects)
+	}
+}
+
+func TestDriver_NotInitialized(t *testing.T) {
+	asserts := assert.New(t)
+	client := startTestServer(t, func(config *Config) (Backend, error) {
+		return nil, errors.New("invalid config")
+	})
+
+	policy := &model.Policy{}
+	policy.ID = 2
+	_, err := newDriver(policy, client)
+	asserts.EqualError(err, "invalid config")
+
+	_, err = client.Stat(2, "file.txt")
+	asserts.Error(err)
+}
+
+func TestClient_Token(t *testing.T) {
+	asserts := assert.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	asserts.NoError(err)
+	defer listener.Close()
+	go serve(listener, "token", func(config *Config) (Backend, error) {
+		return &memoryBackend{}, nil
+	})
+
+	// 凭证错误时连接被关闭
+	client := &Client{Name: "memory", exited: make(chan struct{}), initialized: make(map[uint]time.Time)}
+	asserts.NoError(client.connect(listener.Addr().String(), "wrong"))
+	defer client.Kill()
+	asserts.Error(client.Init(&Config{PolicyID: 1}, time.Now()))
+}
+
+func TestLoader(t *testing.T) {
+	asserts := assert.New(t)
+	dir := t.TempDir()
+	loader := NewLoader(func() string { return dir })
+
+	// 目录为空
+	{
+		names, err := loader.Available()
+		asserts.NoError(err)
+		asserts.Empty(names)
+	}
+
+	for _, name := range []string{"cloudreve-driver-sftp", "cloudreve-driver-b2", "readme.md", "cloudreve-driver-Bad!"} {
+		asserts.NoError(os.WriteFile(filepath.Join(dir, name+executableExt()), []byte{}, 0755))
+	}
+	asserts.NoError(os.Mkdir(filepath.Join(dir, "cloudreve-driver-dir"), 0755))
+
+	// 列出插件
+	{
+		names, err := loader.Available()
+		asserts.NoError(err)
+		asserts.Equal([]string{"b2", "sftp"}, names)
+	}
+
+	// 插件名称不合法
+	{
+		_, err := loader.Get("../sftp")
+		asserts.Error(err)
+	}
+
+	// 插件不存在
+	{
+		_, err := loader.Get("webdav")
+		asserts.Error(err)
+	}
+
+	// 目录不存在
+	{
+		loader := NewLoader(func() string { return filepath.Join(dir, "not_exist") })
+		names, err := loader.Available()
+		asserts.NoError(err)
+		asserts.Empty(names)
+	}
+}
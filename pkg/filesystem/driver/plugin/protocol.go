@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// ProtocolVersion 插件协议版本，协议发生不兼容变更时递增
+	ProtocolVersion = 1
+
+	// ExecutablePrefix 插件可执行文件名前缀，文件名剩余部分为插件名称
+	ExecutablePrefix = "cloudreve-driver-"
+
+	// MagicCookieKey 与 MagicCookieValue 用于确认插件由 Cloudreve 启动，
+	// 避免用户直接运行插件
+	MagicCookieKey   = "CLOUDREVE_DRIVER_PLUGIN"
+	MagicCookieValue = "5b1c8f8a3e0d4c7b9a26e1f0d3c4b5a6"
+
+	// TokenKey 启动插件时传递连接凭证的环境变量
+	TokenKey = "CLOUDREVE_DRIVER_PLUGIN_TOKEN"
+
+	// rpcService 插件注册的 RPC 服务名
+	rpcService = "Plugin"
+)
+
+var (
+	// ErrNotExist 对象不存在，Backend 实现应返回此错误以便宿主识别
+	ErrNotExist = errors.New("object not exist")
+	// ErrNotSupported 插件不支持此操作
+	ErrNotSupported = errors.New("operation not supported")
+)
+
+// Config 传递给插件的存储策略配置
+type Config struct {
+	// PolicyID 存储策略ID
+	PolicyID   uint
+	Name       string
+	Server     string
+	BucketName string
+	IsPrivate  bool
+	BaseURL    string
+	AccessKey  string
+	SecretKey  string
+	// Options 存储策略中设定的插件自定义参数
+	Options map[string]string
+}
+
+// Object 插件列取或查询的对象
+type Object struct {
+	Name string
+	// RelativePath 相对于列取路径的路径
+	RelativePath string
+	// Source 对象在存储端的完整路径
+	Source     string
+	Size       uint64
+	IsDir      bool
+	LastModify time.Time
+}
+
+// Writer 写入中的对象，Commit 或 Abort 后不再使用
+type Writer interface {
+	io.Writer
+	// Commit 完成写入，对象此后可被读取
+	Commit() error
+	// Abort 放弃写入，清理临时数据
+	Abort() error
+}
+
+// Backend 插件需要实现的存储驱动接口，存储路径均使用 / 分隔
+type Backend interface {
+	// Create 打开 path 用于写入，数据从 offset 处开始写入，对象已有的 offset
+	// 之后的数据会被丢弃。offset 大于对象现有大小时应返回错误。
+	// overwrite 为 false 且对象已存在时应返回错误
+	Create(path string, offset uint64, overwrite bool) (Writer, error)
+
+	// Delete 删除一个或多个对象，返回删除失败的路径列表及遇到的最后一个错误，
+	// 对象不存在时视为删除成功
+	Delete(paths []string) ([]string, error)
+
+	// Stat 获取对象信息，对象不存在时返回 ErrNotExist
+	Stat(path string) (*Object, error)
+
+	// ReadAt 从 offset 处读取至多 len(p) 字节，语义与 io.ReaderAt 一致
+	ReadAt(path string, p []byte, offset int64) (int, error)
+
+	// Source 获取对象的外链或下载地址。返回空字符串时由 Cloudreve 中转下载
+	Source(path string, ttl int64, isDownload bool) (string, error)
+
+	// List 列取 path 下的对象，不包含 path 本身
+	List(path string, recursive bool) ([]Object, error)
+}
+
+// Factory 根据存储策略配置创建 Backend，每个存储策略对应一个 Backend
+type Factory func(config *Config) (Backend, error)
+
+// InitArgs 初始化存储策略参数
+type InitArgs struct {
+	Config Config
+}
+
+// CreateArgs 开始写入参数
+type CreateArgs struct {
+	Policy    uint
+	Path      string
+	Offset    uint64
+	Overwrite bool
+}
+
+// WriteArgs 写入数据参数
+type WriteArgs struct {
+	Session string
+	Data    []byte
+}
+
+// DeleteArgs 删除对象参数
+type DeleteArgs struct {
+	Policy uint
+	Paths  []string
+}
+
+// DeleteReply 删除对象结果
+type DeleteReply struct {
+	Failed []string
+	Error  string
+}
+
+// PathArgs 单个对象操作参数
+type PathArgs struct {
+	Policy uint
+	Path   string
+}
+
+// ReadArgs 读取数据参数
+type ReadArgs struct {
+	Policy uint
+	Path   string
+	Offset int64
+	Length int
+}
+
+// ReadReply 读取数据结果
+type ReadReply struct {
+	Data []byte
+	EOF  bool
+}
+
+// SourceArgs 获取外链参数
+type SourceArgs struct {
+	Policy     uint
+	Path       string
+	TTL        int64
+	IsDownload bool
+}
+
+// ListArgs 列取对象参数
+type ListArgs struct {
+	Policy    uint
+	Path      string
+	Recursive bool
+}
+
+// remoteError 将插件返回的错误还原为已知错误
+func remoteError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch err.Error() {
+	case ErrNotExist.Error():
+		return ErrNotExist
+	case ErrNotSupported.Error():
+		return ErrNotSupported
+	}
+
+	return err
+}
@@ -0,0 +1,252 @@
+package plugin
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gofrs/uuid"
+)
+
+// Serve 在插件进程中运行 RPC 服务，插件的 main 函数应调用此方法，直到宿主进程退出。
+// 服务监听本机随机端口，并通过标准输出向宿主报告协议版本与地址
+func Serve(factory Factory) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return errors.New("this binary is a Cloudreve storage driver plugin and is not meant to be executed directly")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	fmt.Printf("%d|tcp|%s\n", ProtocolVersion, listener.Addr().String())
+	return serve(listener, os.Getenv(TokenKey), factory)
+}
+
+// serve 接受宿主连接，连接需先发送凭证行
+func serve(listener net.Listener, token string, factory Factory) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(rpcService, newRPCServer(factory)); err != nil {
+		return err
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			reader := bufio.NewReader(conn)
+			line, err := reader.ReadString('\n')
+			if err != nil || subtle.ConstantTimeCompare([]byte(strings.TrimSpace(line)), []byte(token)) != 1 {
+				conn.Close()
+				return
+			}
+
+			server.ServeConn(&bufferedConn{Conn: conn, reader: reader})
+		}(conn)
+	}
+}
+
+// bufferedConn 读取凭证行后继续使用已缓冲的数据
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (conn *bufferedConn) Read(p []byte) (int, error) {
+	return conn.reader.Read(p)
+}
+
+// RPCServer 插件侧的 RPC 服务，仅供 net/rpc 调用
+type RPCServer struct {
+	factory  Factory
+	mu       sync.Mutex
+	backends map[uint]Backend
+	writers  map[string]Writer
+}
+
+func newRPCServer(factory Factory) *RPCServer {
+	return &RPCServer{
+		factory:  factory,
+		backends: make(map[uint]Backend),
+		writers:  make(map[string]Writer),
+	}
+}
+
+// backend 获取存储策略对应的 Backend
+func (s *RPCServer) backend(policy uint) (Backend, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backend, ok := s.backends[policy]
+	if !ok {
+		return nil, fmt.Errorf("policy %d is not initialized", policy)
+	}
+
+	return backend, nil
+}
+
+// popWriter 取出写入会话
+func (s *RPCServer) popWriter(session string) (Writer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writer, ok := s.writers[session]
+	if !ok {
+		return nil, fmt.Errorf("write session %q not exist", session)
+	}
+
+	delete(s.writers, session)
+	return writer, nil
+}
+
+// Init 使用存储策略配置创建 Backend，已存在时替换
+func (s *RPCServer) Init(args *InitArgs, reply *struct{}) error {
+	backend, err := s.factory(&args.Config)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.backends[args.Config.PolicyID] = backend
+	s.mu.Unlock()
+	return nil
+}
+
+// Create 开始写入，返回写入会话ID
+func (s *RPCServer) Create(args *CreateArgs, reply *string) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	writer, err := backend.Create(args.Path, args.Offset, args.Overwrite)
+	if err != nil {
+		return err
+	}
+
+	session := uuid.Must(uuid.NewV4()).String()
+	s.mu.Lock()
+	s.writers[session] = writer
+	s.mu.Unlock()
+
+	*reply = session
+	return nil
+}
+
+// Write 写入数据
+func (s *RPCServer) Write(args *WriteArgs, reply *int) error {
+	s.mu.Lock()
+	writer, ok := s.writers[args.Session]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("write session %q not exist", args.Session)
+	}
+
+	n, err := writer.Write(args.Data)
+	*reply = n
+	return err
+}
+
+// Commit 完成写入
+func (s *RPCServer) Commit(session string, reply *struct{}) error {
+	writer, err := s.popWriter(session)
+	if err != nil {
+		return err
+	}
+
+	return writer.Commit()
+}
+
+// Abort 放弃写入
+func (s *RPCServer) Abort(session string, reply *struct{}) error {
+	writer, err := s.popWriter(session)
+	if err != nil {
+		return err
+	}
+
+	return writer.Abort()
+}
+
+// Delete 删除对象
+func (s *RPCServer) Delete(args *DeleteArgs, reply *DeleteReply) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	failed, err := backend.Delete(args.Paths)
+	reply.Failed = failed
+	if err != nil {
+		reply.Error = err.Error()
+	}
+
+	return nil
+}
+
+// Stat 获取对象信息
+func (s *RPCServer) Stat(args *PathArgs, reply *Object) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	object, err := backend.Stat(args.Path)
+	if err != nil {
+		return err
+	}
+
+	*reply = *object
+	return nil
+}
+
+// ReadAt 读取数据
+func (s *RPCServer) ReadAt(args *ReadArgs, reply *ReadReply) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, args.Length)
+	n, err := backend.ReadAt(args.Path, buf, args.Offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	reply.Data = buf[:n]
+	reply.EOF = err == io.EOF
+	return nil
+}
+
+// Source 获取外链
+func (s *RPCServer) Source(args *SourceArgs, reply *string) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	*reply, err = backend.Source(args.Path, args.TTL, args.IsDownload)
+	return err
+}
+
+// List 列取对象
+func (s *RPCServer) List(args *ListArgs, reply *[]Object) error {
+	backend, err := s.backend(args.Policy)
+	if err != nil {
+		return err
+	}
+
+	*reply, err = backend.List(args.Path, args.Recursive)
+	return err
+}
@@ -124,8 +124,21 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 		return nil, err
 	}
 
-	// 获取文件数据流
 	client := request.NewClient()
+
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		if rs, ok := request.GetParallelReader(ctx, client, downloadURL, int64(file.Size),
+			request.WithHeader(
+				http.Header{"Cache-Control": {"no-cache", "no-store", "must-revalidate"}},
+			),
+			request.WithTimeout(time.Duration(0)),
+		); ok {
+			rs.SetFirstFakeChunk()
+			return rs, nil
+		}
+	}
+
+	// 获取文件数据流
 	resp, err := client.Request(
 		"GET",
 		downloadURL,
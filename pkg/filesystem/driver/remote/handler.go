@@ -120,7 +120,7 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 	// 尝试获取速度限制
 	speedLimit := 0
 	if user, ok := ctx.Value(fsctx.UserCtx).(model.User); ok {
-		speedLimit = user.Group.SpeedLimit
+		speedLimit = user.DownloadSpeedLimit()
 	}
 
 	// 获取文件源地址
@@ -129,6 +129,17 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 		return nil, err
 	}
 
+	// 文件大小已知且未限速时，尝试使用多个连接并行获取
+	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok && speedLimit == 0 {
+		if rs, ok := request.GetParallelReader(ctx, handler.Client, downloadURL, int64(file.Size),
+			request.WithTimeout(time.Duration(0)),
+			request.WithMasterMeta(),
+		); ok {
+			rs.SetFirstFakeChunk()
+			return rs, nil
+		}
+	}
+
 	// 获取文件数据流
 	resp, err := handler.Client.Request(
 		"GET",
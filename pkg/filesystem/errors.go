@@ -22,5 +22,58 @@ var (
 	ErrIO                       = serializer.NewError(serializer.CodeIOFailed, "Failed to read file data", nil)
 	ErrDBListObjects            = serializer.NewError(serializer.CodeDBError, "Failed to list object records", nil)
 	ErrDBDeleteObjects          = serializer.NewError(serializer.CodeDBError, "Failed to delete object records", nil)
+	ErrDBMoveObjects            = serializer.NewError(serializer.CodeDBError, "Failed to move object records", nil)
 	ErrOneObjectOnly            = serializer.ParamErr("You can only copy one object at the same time", nil)
+	ErrBudgetExhausted          = serializer.NewError(serializer.CodePolicyNotAllowed, "Monthly budget of this storage policy is exhausted", nil)
+	ErrRetentionLocked          = serializer.NewError(serializer.CodeRetentionLocked, "Object is protected by retention rules", nil)
+	ErrObjectLocked             = serializer.NewError(serializer.CodeObjectLocked, "Object is being modified by another operation, please try again later", nil)
+	ErrFolderQuotaExceeded      = serializer.NewError(serializer.CodeFolderQuotaExceeded, "Folder quota exceeded", nil)
+	ErrImageProcessFailed       = serializer.NewError(serializer.CodeImageProcessFailed, "Failed to process image", nil)
+	ErrFileInfected             = serializer.NewError(serializer.CodeFileInfected, "File is infected", nil)
+	ErrFileQuarantined          = serializer.NewError(serializer.CodeFileQuarantined, "File is quarantined by antivirus", nil)
+	ErrFileDisabled             = serializer.NewError(serializer.CodeFileDisabled, "File is disabled by administrator", nil)
+	ErrHLSNotReady              = serializer.NewError(serializer.CodeNotFound, "Transcoded video is not ready", nil)
+	ErrPolicyUnavailable        = serializer.NewError(serializer.CodePolicyNotAllowed, "Storage policy is temporarily unavailable for uploading", nil)
+	ErrInstantUploadMiss        = serializer.NewError(serializer.CodeInstantUploadMiss, "No file with the same content is found", nil)
+	ErrFileCountExceeded        = serializer.NewError(serializer.CodeFileCountExceeded, "Maximum number of files exceeded", nil)
+	ErrPathDepthExceeded        = serializer.NewError(serializer.CodePathDepthExceeded, "Maximum folder depth exceeded", nil)
+	ErrACLDenied                = serializer.NewError(serializer.CodeNoPermissionErr, "Permission denied by folder ACL", nil)
+	ErrBlobRootUnknown          = serializer.NewError(serializer.CodeParamErr, "Cannot determine the storage root from the naming rule of the policy", nil)
 )
+
+// ItemError 批量操作中单个对象的错误
+type ItemError struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"is_dir"`
+	Error string `json:"error"`
+}
+
+// BatchError 批量操作未完全成功时的逐项错误报告
+type BatchError struct {
+	Code int
+	Msg  string
+	// RolledBack 为 true 时所有对象均已回滚，未做任何变更
+	RolledBack bool
+	Items      []ItemError
+	RawError   error
+}
+
+func (err *BatchError) Error() string {
+	return err.Msg
+}
+
+func (err *BatchError) Unwrap() error {
+	return err.RawError
+}
+
+// Response 转换为包含逐项错误的响应
+func (err *BatchError) Response() serializer.Response {
+	return serializer.Response{
+		Code: err.Code,
+		Msg:  err.Msg,
+		Data: map[string]interface{}{
+			"rolled_back": err.RolledBack,
+			"items":       err.Items,
+		},
+	}
+}
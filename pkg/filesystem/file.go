@@ -30,11 +30,15 @@ func (r lrs) Read(p []byte) (int, error) {
 	return r.r.Read(p)
 }
 
-// withSpeedLimit 给原有的ReadSeeker加上限速
+// withSpeedLimit 按文件系统所有者的下载限速给原有的ReadSeeker加上限速
 func (fs *FileSystem) withSpeedLimit(rs response.RSCloser) response.RSCloser {
-	// 如果用户组有速度限制，就返回限制流速的ReaderSeeker
-	if fs.User.Group.SpeedLimit != 0 {
-		speed := fs.User.Group.SpeedLimit
+	return WithDownloadSpeedLimit(rs, fs.User.DownloadSpeedLimit())
+}
+
+// WithDownloadSpeedLimit 给原有的ReadSeeker加上限速，speed 为每秒字节数，为 0 时返回原始流
+func WithDownloadSpeedLimit(rs response.RSCloser, speed int) response.RSCloser {
+	// 如果有速度限制，就返回限制流速的ReaderSeeker
+	if speed != 0 {
 		bucket := ratelimit.NewBucketWithRate(float64(speed), int64(speed))
 		lrs := lrs{rs, ratelimit.Reader(rs, bucket)}
 		return lrs
@@ -44,6 +48,22 @@ func (fs *FileSystem) withSpeedLimit(rs response.RSCloser) response.RSCloser {
 
 }
 
+// lrc 限速后的上传数据流
+type lrc struct {
+	io.Reader
+	io.Closer
+}
+
+// WithUploadSpeedLimit 给上传数据流加上限速，speed 为每秒字节数，为 0 时返回原始流
+func WithUploadSpeedLimit(r io.ReadCloser, speed int) io.ReadCloser {
+	if speed <= 0 {
+		return r
+	}
+
+	bucket := ratelimit.NewBucketWithRate(float64(speed), int64(speed))
+	return lrc{ratelimit.Reader(r, bucket), r}
+}
+
 // AddFile 新增文件记录
 func (fs *FileSystem) AddFile(ctx context.Context, parent *model.Folder, file fsctx.FileHeader) (*model.File, error) {
 	// 添加文件记录前的钩子
@@ -68,12 +88,13 @@ func (fs *FileSystem) AddFile(ctx context.Context, parent *model.Folder, file fs
 
 	if err != nil {
 		if err := fs.Trigger(ctx, "AfterValidateFailed", file); err != nil {
-			util.Log().Debug("AfterValidateFailed hook execution failed: %s", err)
+			util.LogCtx(ctx).Debug("AfterValidateFailed hook execution failed: %s", err)
 		}
 		return nil, ErrFileExisted.WithError(err)
 	}
 
 	fs.User.Storage += newFile.Size
+	fs.emitChanges(ctx, fileChange(model.ChangeCreate, &newFile))
 	return &newFile, nil
 }
 
@@ -192,6 +213,9 @@ func (fs *FileSystem) deleteGroupedFile(ctx context.Context, files map[uint][]*m
 			if model.IsTrueVal(toBeDeletedFiles[i].MetadataSerialized[model.ThumbSidecarMetadataKey]) {
 				thumbs = append(thumbs, toBeDeletedFiles[i].ThumbFile())
 			}
+
+			// HLS 转码结果同样作为附属文件删除
+			thumbs = append(thumbs, toBeDeletedFiles[i].HLSFiles()...)
 		}
 
 		// 切换上传策略
@@ -205,7 +229,7 @@ func (fs *FileSystem) deleteGroupedFile(ctx context.Context, files map[uint][]*m
 		// 取消上传会话
 		for _, upSession := range uploadSessions {
 			if err := fs.Handler.CancelToken(ctx, upSession); err != nil {
-				util.Log().Warning("Failed to cancel upload session for %q: %s", upSession.Name, err)
+				util.LogCtx(ctx).Warning("Failed to cancel upload session for %q: %s", upSession.Name, err)
 			}
 
 			cache.Deletes([]string{upSession.Key}, UploadSessionCachePrefix)
@@ -240,7 +264,8 @@ func (fs *FileSystem) GroupFileByPolicy(ctx context.Context, files []model.File)
 	return policyGroup
 }
 
-// GetDownloadURL 创建文件下载链接, timeout 为数据库中存储过期时间的字段
+// GetDownloadURL 创建下载会话并返回经签名的下载地址, timeout 为数据库中存储过期时间的字段。
+// 所有存储策略的下载均经由本机签名地址发起，会话选项由上下文中的 DownloadOptions 指定
 func (fs *FileSystem) GetDownloadURL(ctx context.Context, id uint, timeout string) (string, error) {
 	err := fs.resetFileIDIfNotExist(ctx, id)
 	if err != nil {
@@ -248,19 +273,32 @@ func (fs *FileSystem) GetDownloadURL(ctx context.Context, id uint, timeout strin
 	}
 	fileTarget := &fs.FileTarget[0]
 
-	// 生成下載地址
-	ttl := model.GetIntSetting(timeout, 60)
-	source, err := fs.SignURL(
-		ctx,
-		fileTarget,
-		int64(ttl),
-		true,
-	)
-	if err != nil {
+	if err := fs.chargeBudget(fileTarget); err != nil {
 		return "", err
 	}
 
-	return source, nil
+	ttl := int64(model.GetIntSetting(timeout, 60))
+	session := serializer.DownloadSession{
+		File:       *fileTarget,
+		OneTime:    fs.User.Group.OptionsSerialized.OneTimeDownload,
+		SpeedLimit: fs.User.DownloadSpeedLimit(),
+	}
+	if opts, ok := ctx.Value(fsctx.DownloadOptionsCtx).(*DownloadOptions); ok {
+		ttl = opts.ttl(ttl)
+		session.IP = opts.IP
+		session.OneTime = session.OneTime || opts.OneTime
+	}
+
+	return fs.signDownloadSession(&session, ttl)
+}
+
+// GetTargetFile 查找待操作的文件并检查读取权限
+func (fs *FileSystem) GetTargetFile(ctx context.Context, id uint) (*model.File, error) {
+	if err := fs.resetFileIDIfNotExist(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return &fs.FileTarget[0], nil
 }
 
 // GetSource 获取可直接访问文件的外链地址
@@ -298,9 +336,13 @@ func (fs *FileSystem) SignURL(ctx context.Context, file *model.File, ttl int64,
 		return "", err
 	}
 
+	if err := fs.chargeBudget(file); err != nil {
+		return "", err
+	}
+
 	// 签名最终URL
 	// 生成外链地址
-	source, err := fs.Handler.Source(ctx, fs.FileTarget[0].SourceName, ttl, isDownload, fs.User.Group.SpeedLimit)
+	source, err := fs.Handler.Source(ctx, fs.FileTarget[0].SourceName, ttl, isDownload, fs.User.DownloadSpeedLimit())
 	if err != nil {
 		return "", serializer.NewError(serializer.CodeNotSet, "Failed to get source link", err)
 	}
@@ -319,6 +361,14 @@ func (fs *FileSystem) ResetFileIfNotExist(ctx context.Context, path string) erro
 		fs.FileTarget = []model.File{*file}
 	}
 
+	if err := fs.checkFilePermission(&fs.FileTarget[0], model.ACLRead); err != nil {
+		return err
+	}
+
+	if err := fs.checkFileBlocked(&fs.FileTarget[0]); err != nil {
+		return err
+	}
+
 	// 将当前存储策略重设为文件使用的
 	return fs.resetPolicyToFirstFile(ctx)
 }
@@ -341,10 +391,35 @@ func (fs *FileSystem) resetFileIDIfNotExist(ctx context.Context, id uint) error
 		}
 	}
 
+	if err := fs.checkFilePermission(&fs.FileTarget[0], model.ACLRead); err != nil {
+		return err
+	}
+
+	if err := fs.checkFileBlocked(&fs.FileTarget[0]); err != nil {
+		return err
+	}
+
 	// 将当前存储策略重设为文件使用的
 	return fs.resetPolicyToFirstFile(ctx)
 }
 
+// checkFileBlocked 被隔离或被禁用的文件仅管理员可以读取
+func (fs *FileSystem) checkFileBlocked(file *model.File) error {
+	if !file.IsQuarantined() && !file.Disabled {
+		return nil
+	}
+
+	if fs.User.Group.ID == 1 || fs.User.ID == 1 {
+		return nil
+	}
+
+	if file.Disabled {
+		return ErrFileDisabled
+	}
+
+	return ErrFileQuarantined
+}
+
 // resetPolicyToFirstFile 将当前存储策略重设为第一个目标文件文件使用的
 func (fs *FileSystem) resetPolicyToFirstFile(ctx context.Context) error {
 	if len(fs.FileTarget) == 0 {
@@ -366,6 +441,54 @@ func (fs *FileSystem) resetPolicyToFirstFile(ctx context.Context) error {
 
 // Search 搜索文件
 func (fs *FileSystem) Search(ctx context.Context, keywords ...interface{}) ([]serializer.Object, error) {
+	parents, err := fs.searchParents()
+	if err != nil {
+		return nil, err
+	}
+
+	files, _ := model.GetFilesByKeywords(fs.User.ID, parents, keywords...)
+	fs.SetTargetFile(&files)
+
+	return fs.listObjects(ctx, "/", files, nil, nil), nil
+}
+
+// SearchByIDs 列出 ids 中位于根目录下的文件，保持 ids 的顺序，用于展示全文检索等外部搜索的结果
+func (fs *FileSystem) SearchByIDs(ctx context.Context, ids []uint) ([]serializer.Object, error) {
+	if len(ids) == 0 {
+		return []serializer.Object{}, nil
+	}
+
+	parents, err := fs.searchParents()
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := model.GetFilesByIDs(ids, fs.User.ID)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	byID := make(map[uint]model.File, len(found))
+	for _, file := range found {
+		if len(parents) == 0 || util.ContainsUint(parents, file.FolderID) {
+			byID[file.ID] = file
+		}
+	}
+
+	files := make([]model.File, 0, len(byID))
+	for _, id := range ids {
+		if file, ok := byID[id]; ok {
+			files = append(files, file)
+			delete(byID, id)
+		}
+	}
+	fs.SetTargetFile(&files)
+
+	return fs.listObjects(ctx, "/", files, nil, nil), nil
+}
+
+// searchParents 限定了根目录时，返回根目录及其所有子目录的ID
+func (fs *FileSystem) searchParents() ([]uint, error) {
 	parents := make([]uint, 0)
 
 	// 如果限定了根目录，则只在这个根目录下搜索。
@@ -380,8 +503,5 @@ func (fs *FileSystem) Search(ctx context.Context, keywords ...interface{}) ([]se
 		}
 	}
 
-	files, _ := model.GetFilesByKeywords(fs.User.ID, parents, keywords...)
-	fs.SetTargetFile(&files)
-
-	return fs.listObjects(ctx, "/", files, nil, nil), nil
+	return parents, nil
 }
@@ -3,7 +3,11 @@ package filesystem
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"net/url"
 	"os"
+	"path"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -51,6 +55,8 @@ func TestFileSystem_AddFile(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	mock.ExpectExec("UPDATE(.+)folders(.+)size(.+)").WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	f, err := fs.AddFile(context.Background(), &folder, &file)
@@ -188,6 +194,21 @@ func TestFileSystem_GetDownloadContent(t *testing.T) {
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestWithUploadSpeedLimit(t *testing.T) {
+	asserts := assert.New(t)
+	body := ioutil.NopCloser(strings.NewReader("123456"))
+
+	// 不限速时返回原始流
+	asserts.Equal(body, WithUploadSpeedLimit(body, 0))
+
+	limited := WithUploadSpeedLimit(body, 1024)
+	asserts.NotEqual(body, limited)
+	content, err := ioutil.ReadAll(limited)
+	asserts.NoError(err)
+	asserts.Equal("123456", string(content))
+	asserts.NoError(limited.Close())
+}
+
 func TestFileSystem_GroupFileByPolicy(t *testing.T) {
 	asserts := assert.New(t)
 	ctx := context.Background()
@@ -447,7 +468,7 @@ func TestFileSystem_GetDownloadURL(t *testing.T) {
 	asserts := assert.New(t)
 	ctx := context.Background()
 	fs := FileSystem{
-		User: &model.User{Model: gorm.Model{ID: 1}},
+		User: &model.User{Model: gorm.Model{ID: 1}, Group: model.Group{SpeedLimit: 1024}},
 	}
 	auth.General = auth.HMACAuth{SecretKey: []byte("123")}
 
@@ -471,6 +492,13 @@ func TestFileSystem_GetDownloadURL(t *testing.T) {
 		asserts.NoError(err)
 		asserts.NotEmpty(downloadURL)
 		fs.CleanTargets()
+
+		// 会话记录创建者的下载限速
+		u, err := url.Parse(downloadURL)
+		asserts.NoError(err)
+		session, ok := cache.Get("download_" + path.Base(u.Path))
+		asserts.True(ok)
+		asserts.Equal(1024, session.(serializer.DownloadSession).SpeedLimit)
 	}
 
 	// 文件不存在
@@ -651,6 +679,17 @@ func TestFileSystem_ResetFileIDIfNotExist(t *testing.T) {
 		},
 	}
 	asserts.Equal(ErrObjectNotExist, fs.resetFileIDIfNotExist(ctx, 1))
+
+	// 被隔离的文件
+	fs = FileSystem{
+		User:       &model.User{Model: gorm.Model{ID: 2}, Group: model.Group{Model: gorm.Model{ID: 2}}},
+		FileTarget: []model.File{{ScanStatus: model.ScanStatusQuarantined}},
+	}
+	asserts.Equal(ErrFileQuarantined, fs.resetFileIDIfNotExist(context.Background(), 1))
+
+	// 被禁用的文件
+	fs.FileTarget = []model.File{{Disabled: true}}
+	asserts.Equal(ErrFileDisabled, fs.resetFileIDIfNotExist(context.Background(), 1))
 }
 
 func TestFileSystem_Search(t *testing.T) {
@@ -667,3 +706,39 @@ func TestFileSystem_Search(t *testing.T) {
 	asserts.NoError(err)
 	asserts.Len(res, 1)
 }
+
+func TestFileSystem_SearchByIDs(t *testing.T) {
+	asserts := assert.New(t)
+	ctx := context.Background()
+	fs := &FileSystem{
+		User: &model.User{},
+	}
+	fs.User.ID = 1
+
+	// 无结果
+	{
+		res, err := fs.SearchByIDs(ctx, nil)
+		asserts.NoError(err)
+		asserts.Empty(res)
+	}
+
+	// 保持结果顺序
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3, 2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "b.txt").AddRow(3, "c.txt"))
+		res, err := fs.SearchByIDs(ctx, []uint{3, 2})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(res, 2)
+		asserts.Equal("c.txt", res[0].Name)
+		asserts.Equal("b.txt", res[1].Name)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3, 1).WillReturnError(errors.New("error"))
+		_, err := fs.SearchByIDs(ctx, []uint{3})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
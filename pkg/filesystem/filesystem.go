@@ -7,11 +7,14 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/b2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/cos"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/encrypt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/googledrive"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/onedrive"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/oss"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/plugin"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/qiniu"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/remote"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/s3"
@@ -46,6 +49,10 @@ type FileSystem struct {
 	DirTarget []model.Folder
 	// 相对根目录
 	Root *model.Folder
+	// 以其他用户身份访问此文件系统时的操作者，为空时不检查目录 ACL
+	Actor *model.User
+	// 没有匹配的目录 ACL 时操作者拥有的权限
+	ActorPermissions int
 	// 互斥锁
 	Lock sync.Mutex
 
@@ -53,6 +60,8 @@ type FileSystem struct {
 	   钩子函数
 	*/
 	Hooks map[string][]Hook
+	// 对象变更钩子
+	ChangeHooks []ChangeHook
 
 	/*
 	   文件系统处理适配器
@@ -82,8 +91,11 @@ func (fs *FileSystem) reset() {
 	fs.CleanTargets()
 	fs.Policy = nil
 	fs.Hooks = nil
+	fs.ChangeHooks = nil
 	fs.Handler = nil
 	fs.Root = nil
+	fs.Actor = nil
+	fs.ActorPermissions = 0
 	fs.Lock = sync.Mutex{}
 	fs.recycleLock = sync.Mutex{}
 }
@@ -94,6 +106,9 @@ func NewFileSystem(user *model.User) (*FileSystem, error) {
 	fs.User = user
 	fs.Policy = &fs.User.Policy
 
+	// 后台任务等产生的对象变更同样写入变更日志，供同步客户端增量拉取
+	fs.OnChange(HookRecordChanges)
+
 	// 分配存储策略适配器
 	err := fs.DispatchHandler()
 
@@ -122,6 +137,20 @@ func NewAnonymousFileSystem() (*FileSystem, error) {
 
 // DispatchHandler 根据存储策略分配文件适配器
 func (fs *FileSystem) DispatchHandler() error {
+	if err := fs.dispatchHandler(); err != nil {
+		return err
+	}
+
+	// 启用静态加密的存储策略，由加密适配器包装
+	if fs.Policy.Encrypted() {
+		fs.Handler = encrypt.NewDriver(fs.Handler, fs.Policy)
+	}
+
+	return nil
+}
+
+// dispatchHandler 根据存储策略类型创建适配器
+func (fs *FileSystem) dispatchHandler() error {
 	if fs.Policy == nil {
 		return errors.New("未设置存储策略")
 	}
@@ -177,10 +206,22 @@ func (fs *FileSystem) DispatchHandler() error {
 		handler, err := s3.NewDriver(currentPolicy)
 		fs.Handler = handler
 		return err
+	case "b2":
+		handler, err := b2.NewDriver(currentPolicy)
+		fs.Handler = handler
+		return err
 	case "googledrive":
 		handler, err := googledrive.NewDriver(currentPolicy)
 		fs.Handler = handler
 		return err
+	case "plugin":
+		handler, err := plugin.NewDriver(currentPolicy)
+		if err != nil {
+			return err
+		}
+
+		fs.Handler = handler
+		return nil
 	default:
 		return ErrUnknownPolicyType
 	}
@@ -195,6 +236,17 @@ func NewFileSystemFromContext(c *gin.Context) (*FileSystem, error) {
 		return NewAnonymousFileSystem()
 	}
 	fs, err := NewFileSystem(user.(*model.User))
+	if err == nil {
+		// 记录用户发起的对象变更产生的用户动态
+		ip := ""
+		if c.Request != nil {
+			ip = c.ClientIP()
+		}
+		fs.OnChange(NewActivityHook(ip))
+		for _, hook := range contextChangeHooks {
+			fs.OnChange(hook)
+		}
+	}
 	return fs, err
 }
 
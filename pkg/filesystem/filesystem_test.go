@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/shadow/masterinslave"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/encrypt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/remote"
 	"github.com/gin-gonic/gin"
@@ -41,6 +43,17 @@ func TestNewFileSystem(t *testing.T) {
 	user.Policy.Type = "unknown"
 	fs, err = NewFileSystem(&user)
 	asserts.Error(err)
+
+	// 后台任务使用的文件系统同样写入变更日志
+	user.ID = 1
+	user.Policy.Type = "mock"
+	fs, err = NewFileSystem(&user)
+	asserts.NoError(err)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	fs.emitChanges(context.Background(), model.Change{Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile, Name: "task.txt"})
+	asserts.NoError(mock.ExpectationsWereMet())
 }
 
 func TestNewFileSystemFromContext(t *testing.T) {
@@ -88,6 +101,12 @@ func TestDispatchHandler(t *testing.T) {
 	err = fs.DispatchHandler()
 	asserts.NoError(err)
 
+	// 启用加密
+	fs.Policy = &model.Policy{Type: "local", OptionsSerialized: model.PolicyOption{Encryption: true}}
+	err = fs.DispatchHandler()
+	asserts.NoError(err)
+	asserts.IsType(&encrypt.Driver{}, fs.Handler)
+
 	fs.Policy = &model.Policy{Type: "remote"}
 	err = fs.DispatchHandler()
 	asserts.NoError(err)
@@ -115,6 +134,10 @@ func TestDispatchHandler(t *testing.T) {
 	fs.Policy = &model.Policy{Type: "s3"}
 	err = fs.DispatchHandler()
 	asserts.NoError(err)
+
+	fs.Policy = &model.Policy{Type: "b2"}
+	err = fs.DispatchHandler()
+	asserts.NoError(err)
 }
 
 func TestNewFileSystemFromCallback(t *testing.T) {
@@ -41,4 +41,6 @@ const (
 	WebDAVCtx
 	// WebDAV反代Url
 	WebDAVProxyUrlCtx
+	// DownloadOptionsCtx 创建下载会话的选项
+	DownloadOptionsCtx
 )
@@ -0,0 +1,114 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/gob"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+   存储策略健康检查
+   ================
+*/
+
+const policyHealthCachePrefix = "policy_health_"
+
+// PolicyHealth 存储策略最近一次健康检查的结果
+type PolicyHealth struct {
+	PolicyID uint `json:"policy_id"`
+	// Healthy 连续失败次数未达到阈值时仍视为可用
+	Healthy bool `json:"healthy"`
+	// Latency 探测耗时，单位为毫秒
+	Latency   int64     `json:"latency"`
+	Error     string    `json:"error,omitempty"`
+	Failures  int       `json:"failures"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+func init() {
+	// 注册缓存用到的复杂结构
+	gob.Register(PolicyHealth{})
+}
+
+// GetPolicyHealth 获取存储策略最近一次健康检查的结果，尚未检查时返回 false
+func GetPolicyHealth(id uint) (PolicyHealth, bool) {
+	if health, ok := cache.Get(policyHealthCachePrefix + strconv.FormatUint(uint64(id), 10)); ok {
+		return health.(PolicyHealth), true
+	}
+
+	return PolicyHealth{}, false
+}
+
+// IsPolicyHealthy 返回存储策略是否可用，尚未检查过的策略视为可用
+func IsPolicyHealthy(id uint) bool {
+	health, ok := GetPolicyHealth(id)
+	return !ok || health.Healthy
+}
+
+// CheckPolicyHealth 通过列取存储端根目录探测凭证及存储端的可用性，并记录结果
+func (fs *FileSystem) CheckPolicyHealth(ctx context.Context, policy *model.Policy) PolicyHealth {
+	previous, checked := GetPolicyHealth(policy.ID)
+	health := PolicyHealth{PolicyID: policy.ID, Healthy: true}
+
+	start := time.Now()
+	err := fs.probePolicy(ctx, policy)
+	health.Latency = time.Since(start).Milliseconds()
+	health.CheckedAt = time.Now()
+
+	if err != nil {
+		health.Error = err.Error()
+		health.Failures = previous.Failures + 1
+		health.Healthy = health.Failures < model.GetIntSetting("policy_health_failure_threshold", 3)
+	}
+
+	if checked && previous.Healthy != health.Healthy {
+		if health.Healthy {
+			util.LogCtx(ctx).Info("Storage policy %q is available again.", policy.Name)
+		} else {
+			util.LogCtx(ctx).Warning("Storage policy %q is unavailable: %s", policy.Name, health.Error)
+		}
+	}
+
+	_ = cache.Set(policyHealthCachePrefix+strconv.FormatUint(uint64(policy.ID), 10), health, 0)
+	return health
+}
+
+func (fs *FileSystem) probePolicy(ctx context.Context, policy *model.Policy) error {
+	fs.Policy = policy
+	if err := fs.DispatchHandler(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(model.GetIntSetting("policy_health_check_timeout", 10))*time.Second)
+	defer cancel()
+
+	_, err := fs.Handler.List(ctx, "", false)
+	return err
+}
+
+// applyPolicyHealth 当前存储策略不可用时，为新上传的文件切换至同类型的备用存储策略，
+// 无可用的备用策略且开启只读时拒绝上传
+func (fs *FileSystem) applyPolicyHealth() error {
+	if fs.Policy == nil || IsPolicyHealthy(fs.Policy.ID) {
+		return nil
+	}
+
+	if backupID := fs.Policy.OptionsSerialized.FailoverPolicyID; backupID > 0 && IsPolicyHealthy(backupID) {
+		backup, err := model.GetPolicyByID(backupID)
+		if err == nil && backup.Type == fs.Policy.Type {
+			fs.Policy = &backup
+			return fs.DispatchHandler()
+		}
+	}
+
+	if fs.Policy.OptionsSerialized.ReadonlyOnFailure {
+		return ErrPolicyUnavailable
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func TestFileSystem_CheckPolicyHealth(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_policy_health_failure_threshold", "2", 0)
+	policy := &model.Policy{Type: "mock"}
+	policy.ID = 51
+
+	// 尚未检查
+	_, ok := GetPolicyHealth(51)
+	a.False(ok)
+	a.True(IsPolicyHealthy(51))
+
+	// 检查成功
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("List", testMock.Anything, "", false).Return([]response.Object{}, nil)
+		fs := &FileSystem{User: &model.User{}, Handler: testHandler}
+		health := fs.CheckPolicyHealth(context.Background(), policy)
+		testHandler.AssertExpectations(t)
+		a.True(health.Healthy)
+		a.Empty(health.Error)
+		a.False(health.CheckedAt.IsZero())
+	}
+
+	// 连续失败达到阈值后不可用
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("List", testMock.Anything, "", false).Return([]response.Object{}, errors.New("error"))
+		fs := &FileSystem{User: &model.User{}, Handler: testHandler}
+		health := fs.CheckPolicyHealth(context.Background(), policy)
+		a.True(health.Healthy)
+		a.Equal(1, health.Failures)
+		a.Equal("error", health.Error)
+
+		health = fs.CheckPolicyHealth(context.Background(), policy)
+		a.False(health.Healthy)
+		a.Equal(2, health.Failures)
+		a.False(IsPolicyHealthy(51))
+	}
+
+	// 恢复
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("List", testMock.Anything, "", false).Return([]response.Object{}, nil)
+		fs := &FileSystem{User: &model.User{}, Handler: testHandler}
+		health := fs.CheckPolicyHealth(context.Background(), policy)
+		a.True(health.Healthy)
+		a.Equal(0, health.Failures)
+		a.True(IsPolicyHealthy(51))
+	}
+
+	// 无法分配适配器
+	{
+		unknown := &model.Policy{Type: "unknown"}
+		unknown.ID = 52
+		fs := &FileSystem{User: &model.User{}}
+		health := fs.CheckPolicyHealth(context.Background(), unknown)
+		a.Equal(1, health.Failures)
+		a.NotEmpty(health.Error)
+	}
+}
+
+func TestFileSystem_ApplyPolicyHealth(t *testing.T) {
+	a := assert.New(t)
+	unhealthy := PolicyHealth{Healthy: false}
+	cache.Set(policyHealthCachePrefix+"61", unhealthy, 0)
+	cache.Set(policyHealthCachePrefix+"63", unhealthy, 0)
+	backup := model.Policy{Type: "mock", Name: "backup"}
+	backup.ID = 62
+	cache.Set("policy_62", backup, 0)
+	cache.Set("policy_63", model.Policy{Type: "mock"}, 0)
+	other := model.Policy{Type: "local"}
+	other.ID = 64
+	cache.Set("policy_64", other, 0)
+
+	newPolicy := func(id, failover uint, readonly bool) *model.Policy {
+		policy := &model.Policy{Type: "mock", OptionsSerialized: model.PolicyOption{
+			FailoverPolicyID:  failover,
+			ReadonlyOnFailure: readonly,
+		}}
+		policy.ID = id
+		return policy
+	}
+
+	// 策略可用
+	{
+		fs := &FileSystem{Policy: newPolicy(60, 62, true)}
+		a.NoError(fs.applyPolicyHealth())
+		a.EqualValues(60, fs.Policy.ID)
+	}
+
+	// 切换至备用策略
+	{
+		fs := &FileSystem{Policy: newPolicy(61, 62, true)}
+		a.NoError(fs.applyPolicyHealth())
+		a.Equal("backup", fs.Policy.Name)
+	}
+
+	// 备用策略同样不可用
+	{
+		fs := &FileSystem{Policy: newPolicy(61, 63, true)}
+		a.ErrorIs(fs.applyPolicyHealth(), ErrPolicyUnavailable)
+	}
+
+	// 备用策略类型不同
+	{
+		fs := &FileSystem{Policy: newPolicy(61, 64, true)}
+		a.ErrorIs(fs.applyPolicyHealth(), ErrPolicyUnavailable)
+		a.EqualValues(61, fs.Policy.ID)
+	}
+
+	// 未开启只读
+	{
+		fs := &FileSystem{Policy: newPolicy(61, 0, false)}
+		a.NoError(fs.applyPolicyHealth())
+	}
+
+	// 上传时拒绝写入
+	{
+		fs := &FileSystem{Policy: newPolicy(61, 0, true), User: &model.User{}}
+		err := fs.Upload(context.Background(), &fsctx.FileStream{Name: "a.txt"})
+		a.ErrorIs(err, ErrPolicyUnavailable)
+	}
+
+	// 更新原有文件时检查文件所在策略
+	{
+		fs := &FileSystem{Policy: newPolicy(60, 0, true), User: &model.User{}}
+		origin := model.File{PolicyID: 61, Policy: *newPolicy(61, 0, true)}
+		ctx := context.WithValue(context.Background(), fsctx.FileModelCtx, origin)
+		err := fs.Upload(ctx, &fsctx.FileStream{Name: "a.txt"})
+		a.ErrorIs(err, ErrPolicyUnavailable)
+	}
+}
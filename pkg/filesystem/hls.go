@@ -0,0 +1,186 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+/* ================
+     HLS 转码相关
+   ================
+*/
+
+// CanTranscodeHLS 返回文件是否可以转码为 HLS
+func CanTranscodeHLS(file *model.File) bool {
+	opts := model.GetSettingByNames("hls_enabled", "hls_exts")
+	return model.IsTrueVal(opts["hls_enabled"]) &&
+		util.IsInExtensionList(strings.Split(opts["hls_exts"], ","), file.Name)
+}
+
+// SetHLSStatus 更新文件当前内容版本的 HLS 转码状态
+func SetHLSStatus(file *model.File, status string) error {
+	return file.UpdateMetadata(map[string]string{
+		model.HLSStatusMetadataKey:  status,
+		model.HLSVersionMetadataKey: file.Version(),
+	})
+}
+
+// hlsCacheDir 返回本次转码结果在存储端的存放目录，每次转码使用新的目录，
+// 避免播放中的旧分片被覆盖
+func hlsCacheDir(policy *model.Policy, file *model.File) string {
+	root := policy.OptionsSerialized.HLSCachePath
+	if root == "" {
+		root = "hls"
+	}
+
+	return path.Join(root, fmt.Sprintf("%d_%s", file.ID, util.RandStringRunes(8)))
+}
+
+// TranscodeHLS 使用 ffmpeg 将视频转码为 HLS 分片，并上传至文件所在存储策略
+func (fs *FileSystem) TranscodeHLS(ctx context.Context, file *model.File) error {
+	fs.Policy = file.GetPolicy()
+	if err := fs.DispatchHandler(); err != nil {
+		return err
+	}
+
+	opts := model.GetSettingByNames("thumb_ffmpeg_path", "hls_segment_time", "hls_ffmpeg_args", "temp_path")
+	outputDir := filepath.Join(util.RelativePath(opts["temp_path"]), "hls", uuid.Must(uuid.NewV4()).String())
+	if err := os.MkdirAll(outputDir, 0700); err != nil {
+		return fmt.Errorf("failed to create temp folder: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	// 未加密的本机文件直接读取，其他存储策略由 ffmpeg 从源地址读取
+	var input string
+	if conf.SystemConfig.Mode == "slave" || (fs.Policy.Type == "local" && !fs.Policy.Encrypted()) {
+		input = util.RelativePath(file.SourceName)
+	} else {
+		source, err := fs.Handler.Source(ctx, file.SourceName, int64(model.GetIntSetting("preview_timeout", 60)), false, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get source url of %q: %w", file.Name, err)
+		}
+		input = source
+	}
+
+	args := append([]string{"-i", input}, strings.Fields(opts["hls_ffmpeg_args"])...)
+	args = append(args, "-f", "hls", "-hls_time", opts["hls_segment_time"], "-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, "seg_%05d.ts"),
+		filepath.Join(outputDir, model.HLSPlaylistName))
+	cmd := exec.CommandContext(ctx, opts["thumb_ffmpeg_path"], args...)
+
+	var stdErr bytes.Buffer
+	cmd.Stderr = &stdErr
+	if err := cmd.Run(); err != nil {
+		util.LogCtx(ctx).Warning("Failed to invoke ffmpeg: %s", stdErr.String())
+		return fmt.Errorf("failed to invoke ffmpeg: %w", err)
+	}
+
+	segments := 0
+	for {
+		if _, err := os.Stat(filepath.Join(outputDir, model.HLSSegmentName(segments))); err != nil {
+			break
+		}
+		segments++
+	}
+
+	if segments == 0 {
+		return fmt.Errorf("no segment generated for %q", file.Name)
+	}
+
+	// 上传播放列表及分片
+	dir := hlsCacheDir(fs.Policy, file)
+	names := []string{model.HLSPlaylistName}
+	for i := 0; i < segments; i++ {
+		names = append(names, model.HLSSegmentName(i))
+	}
+
+	uploaded := make([]string, 0, len(names))
+	for _, name := range names {
+		dst := path.Join(dir, name)
+		if err := fs.uploadHLSFile(ctx, filepath.Join(outputDir, name), dst); err != nil {
+			_, _ = fs.Handler.Delete(context.Background(), uploaded)
+			return fmt.Errorf("failed to upload %q: %w", name, err)
+		}
+		uploaded = append(uploaded, dst)
+	}
+
+	// 删除之前的转码结果
+	if previous := file.HLSFiles(); len(previous) > 0 {
+		if _, err := fs.Handler.Delete(context.Background(), previous); err != nil {
+			util.LogCtx(ctx).Warning("Failed to delete previous HLS segments of %q: %s", file.Name, err)
+		}
+	}
+
+	return file.UpdateMetadata(map[string]string{
+		model.HLSStatusMetadataKey:   model.HLSStatusReady,
+		model.HLSVersionMetadataKey:  file.Version(),
+		model.HLSPathMetadataKey:     dir,
+		model.HLSSegmentsMetadataKey: strconv.Itoa(segments),
+	})
+}
+
+func (fs *FileSystem) uploadHLSFile(ctx context.Context, src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return fs.Handler.Put(ctx, &fsctx.FileStream{
+		Mode:     fsctx.Overwrite,
+		File:     file,
+		Seeker:   file,
+		Size:     uint64(info.Size()),
+		SavePath: dst,
+	})
+}
+
+// GetHLSContent 获取已转码视频的播放列表或分片内容
+func (fs *FileSystem) GetHLSContent(ctx context.Context, file *model.File, name string) (response.RSCloser, error) {
+	if file.HLSStatus() != model.HLSStatusReady {
+		return nil, ErrHLSNotReady
+	}
+
+	target := ""
+	for _, hlsFile := range file.HLSFiles() {
+		if path.Base(hlsFile) == name {
+			target = hlsFile
+			break
+		}
+	}
+
+	if target == "" {
+		return nil, ErrObjectNotExist
+	}
+
+	fs.Policy = file.GetPolicy()
+	if err := fs.DispatchHandler(); err != nil {
+		return nil, err
+	}
+
+	rs, err := fs.Handler.Get(ctx, target)
+	if err != nil {
+		return nil, ErrIO.WithError(err)
+	}
+
+	return rs, nil
+}
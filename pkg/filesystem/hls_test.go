@@ -0,0 +1,47 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTranscodeHLS(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_hls_exts", "mp4,mkv", 0)
+
+	cache.Set("setting_hls_enabled", "0", 0)
+	asserts.False(CanTranscodeHLS(&model.File{Name: "a.mp4"}))
+
+	cache.Set("setting_hls_enabled", "1", 0)
+	asserts.True(CanTranscodeHLS(&model.File{Name: "a.MKV"}))
+	asserts.False(CanTranscodeHLS(&model.File{Name: "a.txt"}))
+}
+
+func TestFileSystem_GetHLSContent(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 尚未转码
+	{
+		_, err := fs.GetHLSContent(context.Background(), &model.File{}, model.HLSPlaylistName)
+		asserts.Equal(ErrHLSNotReady, err)
+	}
+
+	// 分片不存在
+	{
+		file := &model.File{MetadataSerialized: map[string]string{
+			model.HLSStatusMetadataKey:   model.HLSStatusReady,
+			model.HLSVersionMetadataKey:  "0",
+			model.HLSPathMetadataKey:     "hls/1_abc",
+			model.HLSSegmentsMetadataKey: "1",
+		}}
+		_, err := fs.GetHLSContent(context.Background(), file, "seg_00001.ts")
+		asserts.Equal(ErrObjectNotExist, err)
+		_, err = fs.GetHLSContent(context.Background(), file, "../../conf.ini")
+		asserts.Equal(ErrObjectNotExist, err)
+	}
+}
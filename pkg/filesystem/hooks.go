@@ -5,7 +5,6 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
-	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -47,7 +46,7 @@ func (fs *FileSystem) Trigger(ctx context.Context, name string, file fsctx.FileH
 		for _, hook := range hooks {
 			err := hook(ctx, fs, file)
 			if err != nil {
-				util.Log().Warning("Failed to execute hook：%s", err)
+				util.LogCtx(ctx).Warning("Failed to execute hook：%s", err)
 				return err
 			}
 		}
@@ -55,6 +54,14 @@ func (fs *FileSystem) Trigger(ctx context.Context, name string, file fsctx.FileH
 	return nil
 }
 
+// fileValidators 由其他模块注册，在 HookValidateFile 内置检验通过后执行的检验
+var fileValidators []Hook
+
+// RegisterFileValidator 注册上传文件的额外检验，应在初始化时调用
+func RegisterFileValidator(hook Hook) {
+	fileValidators = append(fileValidators, hook)
+}
+
 // HookValidateFile 一系列对文件检验的集合
 func HookValidateFile(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 	fileInfo := file.Info()
@@ -74,6 +81,12 @@ func HookValidateFile(ctx context.Context, fs *FileSystem, file fsctx.FileHeader
 		return ErrFileExtensionNotAllowed
 	}
 
+	for _, validator := range fileValidators {
+		if err := validator(ctx, fs, file); err != nil {
+			return err
+		}
+	}
+
 	return nil
 
 }
@@ -89,33 +102,90 @@ func HookResetPolicy(ctx context.Context, fs *FileSystem, file fsctx.FileHeader)
 	return fs.DispatchHandler()
 }
 
-// HookValidateCapacity 验证用户容量
+// HookValidateCapacity 验证用户容量及目标目录的容量配额
 func HookValidateCapacity(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 	// 验证并扣除容量
 	if fs.User.GetRemainingCapacity() < file.Info().Size {
 		return ErrInsufficientCapacity
 	}
+	return fs.checkUploadQuota(file.Info().VirtualPath, file.Info().Size)
+}
+
+// HookValidateGroupLimits 验证用户组的最大文件数及新文件所在目录的层级
+func HookValidateGroupLimits(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+	if err := fs.checkPathDepth(file.Info().VirtualPath); err != nil {
+		return err
+	}
+
+	max := fs.User.Group.OptionsSerialized.MaxFiles
+	if max <= 0 {
+		return nil
+	}
+
+	count, err := model.CountFilesByUserID(fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	if count >= max {
+		return ErrFileCountExceeded
+	}
+
 	return nil
 }
 
-// HookValidateCapacityDiff 根据原有文件和新文件的大小验证用户容量
+// HookValidateCapacityDiff 根据原有文件和新文件的大小验证用户容量及所在目录的容量配额
 func HookValidateCapacityDiff(ctx context.Context, fs *FileSystem, newFile fsctx.FileHeader) error {
 	originFile := ctx.Value(fsctx.FileModelCtx).(model.File)
 	newFileSize := newFile.Info().Size
 
 	if newFileSize > originFile.Size {
-		return HookValidateCapacity(ctx, fs, newFile)
+		if fs.User.GetRemainingCapacity() < newFileSize {
+			return ErrInsufficientCapacity
+		}
+
+		if len(fs.User.OptionsSerialized.FolderQuotas) > 0 {
+			folders, _ := model.GetFoldersByIDs([]uint{originFile.FolderID}, fs.User.ID)
+			if len(folders) > 0 {
+				return fs.checkFolderQuota(&folders[0], nil, func() (uint64, error) {
+					return newFileSize - originFile.Size, nil
+				})
+			}
+		}
 	}
 
 	return nil
 }
 
+// HookReleaseStorage 释放为上传会话预留的 size 字节容量，用于占位文件创建失败时，
+// 多次触发时仅释放一次
+func HookReleaseStorage(size uint64) Hook {
+	released := false
+	return func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+		if released {
+			return nil
+		}
+
+		if err := fs.User.ReleaseStorage(model.DB, size); err != nil {
+			return err
+		}
+		released = true
+
+		if fs.User.ReservedStorage > size {
+			fs.User.ReservedStorage -= size
+		} else {
+			fs.User.ReservedStorage = 0
+		}
+		return nil
+	}
+}
+
 // HookDeleteTempFile 删除已保存的临时文件
 func HookDeleteTempFile(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 	// 删除临时文件
 	_, err := fs.Handler.Delete(ctx, []string{file.Info().SavePath})
 	if err != nil {
-		util.Log().Warning("Failed to clean-up temp files: %s", err)
+		util.LogCtx(ctx).Warning("Failed to clean-up temp files: %s", err)
 	}
 
 	return nil
@@ -174,6 +244,9 @@ func GenericAfterUpdate(ctx context.Context, fs *FileSystem, newFile fsctx.FileH
 		return err
 	}
 
+	change := fileChange(model.ChangeModify, &originFile)
+	change.Size = newFile.Info().Size
+	fs.emitChanges(ctx, change)
 	return nil
 }
 
@@ -218,6 +291,7 @@ func GenericAfterUpload(ctx context.Context, fs *FileSystem, fileHeader fsctx.Fi
 		return ErrInsertFileRecord
 	}
 	fileHeader.SetModel(file)
+	fs.notifyStorageUsage()
 
 	return nil
 }
@@ -228,10 +302,15 @@ func HookClearFileHeaderSize(ctx context.Context, fs *FileSystem, fileHeader fsc
 	return nil
 }
 
+// truncater 支持截断物理文件的存储策略适配器
+type truncater interface {
+	Truncate(ctx context.Context, src string, size uint64) error
+}
+
 // HookTruncateFileTo 将物理文件截断至 size
 func HookTruncateFileTo(size uint64) Hook {
 	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
-		if handler, ok := fs.Handler.(local.Driver); ok {
+		if handler, ok := fs.Handler.(truncater); ok {
 			return handler.Truncate(ctx, fileHeader.Info().SavePath, size)
 		}
 
@@ -260,7 +339,12 @@ func HookPopPlaceholderToFile(picInfo string) Hook {
 	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
 		fileInfo := fileHeader.Info()
 		fileModel := fileInfo.Model.(*model.File)
-		return fileModel.PopChunkToFile(fileInfo.LastModified, picInfo)
+		if err := fileModel.PopChunkToFile(fileInfo.LastModified, picInfo); err != nil {
+			return err
+		}
+
+		fs.emitChanges(ctx, fileChange(model.ChangeModify, fileModel))
+		return nil
 	}
 }
 
@@ -281,6 +281,49 @@ func TestHookValidateCapacity(t *testing.T) {
 	}
 }
 
+func TestHookValidateGroupLimits(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	file := &fsctx.FileStream{VirtualPath: "/a/b"}
+	ctx := context.Background()
+
+	// 未设定限制
+	{
+		a.NoError(HookValidateGroupLimits(ctx, fs, file))
+	}
+
+	// 超出最大目录层级
+	{
+		fs.User.Group.OptionsSerialized.MaxDepth = 1
+		a.ErrorIs(HookValidateGroupLimits(ctx, fs, file), ErrPathDepthExceeded)
+		fs.User.Group.OptionsSerialized.MaxDepth = 2
+	}
+
+	// 超出最大文件数
+	{
+		fs.User.Group.OptionsSerialized.MaxFiles = 2
+		mock.ExpectQuery("SELECT count(.+)files(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		a.ErrorIs(HookValidateGroupLimits(ctx, fs, file), ErrFileCountExceeded)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 统计失败
+	{
+		mock.ExpectQuery("SELECT count(.+)files(.+)").WillReturnError(errors.New("error"))
+		a.Error(HookValidateGroupLimits(ctx, fs, file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 通过
+	{
+		mock.ExpectQuery("SELECT count(.+)files(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		a.NoError(HookValidateGroupLimits(ctx, fs, file))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
 func TestHookValidateCapacityDiff(t *testing.T) {
 	a := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -360,7 +403,7 @@ func TestHookClearFileSize(t *testing.T) {
 		)
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)files(.+)").
-			WithArgs("", 0, sqlmock.AnyArg(), 1, 10).
+			WithArgs(`{"content_version":"1"}`, "", 0, sqlmock.AnyArg(), 1, 10).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)users(.+)").
 			WithArgs(10, sqlmock.AnyArg()).
@@ -429,7 +472,7 @@ func TestGenericAfterUpdate(t *testing.T) {
 		fs.Handler = handlerMock
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)files(.+)").
-			WithArgs("", 10, sqlmock.AnyArg(), 1, 0).
+			WithArgs(`{"content_version":"1"}`, "", 10, sqlmock.AnyArg(), 1, 0).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)users(.+)").
 			WithArgs(10, sqlmock.AnyArg()).
@@ -462,7 +505,7 @@ func TestGenericAfterUpdate(t *testing.T) {
 
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").
-			WithArgs("", 10, sqlmock.AnyArg(), 1, 0).
+			WithArgs(`{"content_version":"1"}`, "", 10, sqlmock.AnyArg(), 1, 0).
 			WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
 
@@ -604,7 +647,7 @@ func TestHookChunkUploaded(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 20, sqlmock.AnyArg(), 1, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 20, sqlmock.AnyArg(), 1, 0).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("UPDATE(.+)users(.+)").
 		WithArgs(20, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -625,7 +668,7 @@ func TestHookChunkUploadFailed(t *testing.T) {
 	}
 
 	mock.ExpectBegin()
-	mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs("", 10, sqlmock.AnyArg(), 1, 0).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(`{"content_version":"1"}`, "", 10, sqlmock.AnyArg(), 1, 0).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("UPDATE(.+)users(.+)").
 		WithArgs(10, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
@@ -706,3 +749,18 @@ func TestNewWebdavAfterUploadHook(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 
 }
+
+func TestHookReleaseStorage(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}, ReservedStorage: 30}}
+	hook := HookReleaseStorage(10)
+
+	// 仅释放一次
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)reserved_storage(.+)").WithArgs(10, 10, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(hook(context.Background(), fs, &fsctx.FileStream{}))
+	asserts.NoError(hook(context.Background(), fs, &fsctx.FileStream{}))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(20, fs.User.ReservedStorage)
+}
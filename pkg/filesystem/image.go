@@ -132,20 +132,29 @@ func (fs *FileSystem) generateThumbnail(ctx context.Context, file *model.File) e
 	}
 	defer source.Close()
 
-	// Provide file source path for local policy files
+	// Provide file source path for unencrypted local policy files
 	src := ""
-	if conf.SystemConfig.Mode == "slave" || file.GetPolicy().Type == "local" {
+	if conf.SystemConfig.Mode == "slave" || (file.GetPolicy().Type == "local" && !file.GetPolicy().Encrypted()) {
 		src = file.SourceName
 	}
 
-	thumbRes, err := thumb.Generators.Generate(ctx, source, src, file.Name, model.GetSettingByNames(
+	options := model.GetSettingByNames(
 		"thumb_width",
 		"thumb_height",
 		"thumb_builtin_enabled",
 		"thumb_vips_enabled",
 		"thumb_ffmpeg_enabled",
 		"thumb_libreoffice_enabled",
-	))
+		"thumb_poppler_enabled",
+		"thumb_music_cover_enabled",
+	)
+
+	// 存储策略可单独关闭视频缩略图
+	if file.GetPolicy().OptionsSerialized.DisableVideoThumb {
+		options["thumb_ffmpeg_enabled"] = "0"
+	}
+
+	thumbRes, err := thumb.Generators.Generate(ctx, source, src, file.Name, options)
 	if err != nil {
 		_ = updateThumbStatus(file, model.ThumbStatusNotAvailable)
 		return fmt.Errorf("failed to generate thumb for %q: %w", file.Name, err)
@@ -175,7 +184,7 @@ func (fs *FileSystem) generateThumbnail(ctx context.Context, file *model.File) e
 	}
 
 	if model.IsTrueVal(model.GetSettingByName("thumb_gc_after_gen")) {
-		util.Log().Debug("generateThumbnail runtime.GC")
+		util.LogCtx(ctx).Debug("generateThumbnail runtime.GC")
 		runtime.GC()
 	}
 
@@ -190,6 +199,47 @@ func (fs *FileSystem) generateThumbnail(ctx context.Context, file *model.File) e
 	return nil
 }
 
+// RegenerateThumbnail 清除文件的缩略图状态并重新生成缩略图，返回是否生成了缩略图。
+// 由存储策略原生处理缩略图的文件不做处理
+func (fs *FileSystem) RegenerateThumbnail(ctx context.Context, file *model.File) (bool, error) {
+	fs.CleanTargets()
+	defer fs.CleanTargets()
+	fs.SetTargetFile(&[]model.File{*file})
+	if err := fs.resetPolicyToFirstFile(ctx); err != nil {
+		return false, err
+	}
+
+	// 之前标记为不可用的文件也会重新尝试生成
+	if err := file.ResetThumb(); err != nil {
+		return false, err
+	}
+
+	w, h := fs.GenerateThumbnailSize(0, 0)
+	ctx = context.WithValue(ctx, fsctx.ThumbSizeCtx, [2]uint{w, h})
+	ctx = context.WithValue(ctx, fsctx.FileModelCtx, *file)
+	res, err := fs.Handler.Thumb(ctx, file)
+	switch {
+	case errors.Is(err, driver.ErrorThumbNotExist):
+	case errors.Is(err, driver.ErrorThumbNotSupported) && fs.Policy.CouldProxyThumb():
+	case err == nil:
+		// 缩略图由存储端生成
+		if !res.Redirect && res.Content != nil {
+			res.Content.Close()
+		}
+		return false, nil
+	case errors.Is(err, driver.ErrorThumbNotSupported):
+		return false, nil
+	default:
+		return false, err
+	}
+
+	if err := fs.generateThumbnail(ctx, file); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // GenerateThumbnailSize 获取要生成的缩略图的尺寸
 func (fs *FileSystem) GenerateThumbnailSize(w, h int) (uint, uint) {
 	return uint(model.GetIntSetting("thumb_width", 400)), uint(model.GetIntSetting("thumb_height", 300))
@@ -216,3 +266,145 @@ func updateThumbStatus(file *model.File, status string) error {
 
 	return nil
 }
+
+const (
+	// ThumbStateReady 缩略图可用
+	ThumbStateReady = "ready"
+	// ThumbStatePending 缩略图正在后台生成
+	ThumbStatePending = "pending"
+	// ThumbStateNotAvailable 缩略图不可用
+	ThumbStateNotAvailable = "not_available"
+)
+
+// ThumbState 批量获取缩略图时单个文件的缩略图状态
+type ThumbState struct {
+	File   *model.File
+	Status string
+	// URL 可直接访问的缩略图地址，为空时客户端应请求单文件缩略图接口
+	URL string
+}
+
+// thumbGenerating 正在后台生成缩略图的文件 ID，避免客户端轮询时重复生成
+var thumbGenerating sync.Map
+
+// GetThumbStates 批量获取文件的缩略图状态，尚未生成的缩略图会在后台异步生成。
+// 不存在或不属于当前用户的文件会被忽略，返回结果与 ids 的顺序一致。
+func (fs *FileSystem) GetThumbStates(ctx context.Context, ids []uint) ([]ThumbState, error) {
+	files, err := model.GetFilesByIDs(ids, fs.User.ID)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	filesMap := make(map[uint]*model.File, len(files))
+	for i := range files {
+		filesMap[files[i].ID] = &files[i]
+	}
+
+	res := make([]ThumbState, 0, len(files))
+	for _, id := range ids {
+		file, ok := filesMap[id]
+		if !ok {
+			continue
+		}
+
+		// 同一文件只返回一次
+		delete(filesMap, id)
+		fs.CleanTargets()
+		fs.SetTargetFile(&[]model.File{*file})
+		if err := fs.resetPolicyToFirstFile(ctx); err != nil {
+			res = append(res, ThumbState{File: file, Status: ThumbStateNotAvailable})
+			continue
+		}
+
+		file.Policy = *fs.Policy
+		state, generate := fs.thumbState(ctx, file)
+		if generate {
+			fs.generateThumbnailAsync(*file)
+		}
+
+		res = append(res, state)
+	}
+
+	fs.CleanTargets()
+
+	return res, nil
+}
+
+// thumbState 获取单个文件的缩略图状态，第二个返回值表示是否需要生成缩略图
+func (fs *FileSystem) thumbState(ctx context.Context, file *model.File) (ThumbState, bool) {
+	state := ThumbState{File: file, Status: ThumbStateNotAvailable}
+	if !file.ShouldLoadThumb() {
+		return state, false
+	}
+
+	if _, ok := thumbGenerating.Load(file.ID); ok {
+		state.Status = ThumbStatePending
+		return state, false
+	}
+
+	w, h := fs.GenerateThumbnailSize(0, 0)
+	ctx = context.WithValue(ctx, fsctx.ThumbSizeCtx, [2]uint{w, h})
+	ctx = context.WithValue(ctx, fsctx.FileModelCtx, *file)
+	res, err := fs.Handler.Thumb(ctx, file)
+	switch {
+	case err == nil:
+		state.Status = ThumbStateReady
+		if res.Redirect {
+			state.URL = res.URL
+		} else if res.Content != nil {
+			res.Content.Close()
+		}
+	case errors.Is(err, driver.ErrorThumbNotExist):
+		state.Status = ThumbStatePending
+		return state, true
+	case errors.Is(err, driver.ErrorThumbNotSupported):
+		if !fs.Policy.CouldProxyThumb() {
+			_ = updateThumbStatus(file, model.ThumbStatusNotAvailable)
+			return state, false
+		}
+
+		if file.MetadataSerialized[model.ThumbStatusMetadataKey] != model.ThumbStatusExist {
+			state.Status = ThumbStatePending
+			return state, true
+		}
+
+		if state.URL, err = fs.Handler.Source(ctx, file.ThumbFile(), int64(model.GetIntSetting("preview_timeout", 60)), false, 0); err == nil {
+			state.Status = ThumbStateReady
+		}
+	}
+
+	return state, false
+}
+
+// generateThumbnailAsync 在后台为文件生成缩略图
+func (fs *FileSystem) generateThumbnailAsync(file model.File) {
+	if _, loaded := thumbGenerating.LoadOrStore(file.ID, true); loaded {
+		return
+	}
+
+	user := fs.User
+	go func() {
+		defer thumbGenerating.Delete(file.ID)
+
+		asyncFS, err := NewFileSystem(user)
+		if err != nil {
+			util.Log().Warning("Failed to initialize filesystem for thumb generation: %s", err)
+			return
+		}
+		defer asyncFS.Recycle()
+
+		ctx := context.Background()
+		asyncFS.SetTargetFile(&[]model.File{file})
+		if err := asyncFS.resetPolicyToFirstFile(ctx); err != nil {
+			util.Log().Warning("Failed to dispatch policy for thumb generation: %s", err)
+			return
+		}
+
+		w, h := asyncFS.GenerateThumbnailSize(0, 0)
+		ctx = context.WithValue(ctx, fsctx.ThumbSizeCtx, [2]uint{w, h})
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, file)
+		if err := asyncFS.generateThumbnail(ctx, &file); err != nil {
+			util.Log().Debug("Failed to generate thumb for %q in background: %s", file.Name, err)
+		}
+	}()
+}
@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     图像实时处理
+   ================
+*/
+
+// ImageProcessCacheDir 返回图像处理结果在临时目录中的缓存目录
+func ImageProcessCacheDir() string {
+	return filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "image_process")
+}
+
+// ImageProcessCacheKey 返回文件当前内容版本按给定参数处理后的缓存标识
+func ImageProcessCacheKey(file *model.File, opts *thumb.ProcessOptions) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d/%s/%s/%s", file.ID, file.SourceName, file.Version(), opts.Key(file.Name))))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetProcessedImage 获取按参数处理后的图像，处理结果缓存在服务端临时目录中，
+// 适用于所有存储策略
+func (fs *FileSystem) GetProcessedImage(ctx context.Context, id uint, opts *thumb.ProcessOptions) (*os.File, error) {
+	if err := fs.resetFileIDIfNotExist(ctx, id); err != nil {
+		return nil, err
+	}
+
+	file := fs.FileTarget[0]
+	if file.Size > uint64(model.GetIntSetting("image_process_max_file_size", 52428800)) {
+		return nil, ErrFileSizeTooBig
+	}
+
+	key := ImageProcessCacheKey(&file, opts)
+	cachePath := filepath.Join(ImageProcessCacheDir(), key[:2], key+"."+opts.OutputFormat(file.Name))
+	if cached, err := os.Open(cachePath); err == nil {
+		// 更新修改时间，延后缓存的清理
+		now := time.Now()
+		_ = os.Chtimes(cachePath, now, now)
+		return cached, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return nil, ErrIO.WithError(err)
+	}
+
+	source, err := fs.GetContent(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	// 写入临时文件后再重命名，避免并发请求读取到未完成的结果
+	tempFile, err := os.CreateTemp(filepath.Dir(cachePath), key+"_*.tmp")
+	if err != nil {
+		return nil, ErrIO.WithError(err)
+	}
+
+	err = thumb.Process(ctx, source, file.Name, opts, tempFile)
+	tempFile.Close()
+	if err != nil {
+		os.Remove(tempFile.Name())
+		return nil, ErrImageProcessFailed.WithError(err)
+	}
+
+	if err := os.Rename(tempFile.Name(), cachePath); err != nil {
+		os.Remove(tempFile.Name())
+		return nil, ErrIO.WithError(err)
+	}
+
+	return os.Open(cachePath)
+}
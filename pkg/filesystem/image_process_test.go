@@ -0,0 +1,108 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func TestImageProcessCacheKey(t *testing.T) {
+	a := assert.New(t)
+	file := &model.File{Name: "a.png", SourceName: "1/a.png"}
+	file.ID = 1
+	opts := &thumb.ProcessOptions{Width: 100, Fit: thumb.FitInside, Quality: 85}
+
+	key := ImageProcessCacheKey(file, opts)
+	a.Len(key, 40)
+	a.Equal(key, ImageProcessCacheKey(file, opts))
+
+	// 参数不同
+	a.NotEqual(key, ImageProcessCacheKey(file, &thumb.ProcessOptions{Width: 200, Fit: thumb.FitInside, Quality: 85}))
+
+	// 文件内容版本不同
+	file.MetadataSerialized = map[string]string{model.ContentVersionMetadataKey: "2"}
+	a.NotEqual(key, ImageProcessCacheKey(file, opts))
+}
+
+func TestFileSystem_GetProcessedImage(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_temp_path", t.TempDir(), 0)
+	cache.Set("setting_image_process_max_file_size", "1024", 0)
+	defer cache.Deletes([]string{"temp_path", "image_process_max_file_size"}, "setting_")
+
+	var src bytes.Buffer
+	a.NoError(png.Encode(&src, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+	opts := &thumb.ProcessOptions{Width: 10, Fit: thumb.FitInside, Quality: 85}
+
+	newFs := func(size uint64) *FileSystem {
+		fs := &FileSystem{User: &model.User{}}
+		fs.SetTargetFile(&[]model.File{{Name: "a.png", SourceName: "1/a.png", Size: size, Policy: model.Policy{Type: "mock"}}})
+		fs.FileTarget[0].ID = 1
+		fs.FileTarget[0].Policy.ID = 1
+		return fs
+	}
+
+	// 文件过大
+	{
+		fs := newFs(1025)
+		res, err := fs.GetProcessedImage(context.Background(), 0, opts)
+		a.ErrorIs(err, ErrFileSizeTooBig)
+		a.Nil(res)
+	}
+
+	// 无法读取原始文件
+	{
+		fs := newFs(uint64(src.Len()))
+		handler := new(FileHeaderMock)
+		handler.On("Get", testMock.Anything, "1/a.png").Return(MockRSC{}, errors.New("error"))
+		fs.Handler = handler
+		res, err := fs.GetProcessedImage(context.Background(), 0, opts)
+		a.ErrorIs(err, ErrIO)
+		a.Nil(res)
+	}
+
+	// 原始文件不是图像
+	{
+		fs := newFs(uint64(src.Len()))
+		handler := new(FileHeaderMock)
+		handler.On("Get", testMock.Anything, "1/a.png").Return(MockRSC{rs: bytes.NewReader([]byte("text"))}, nil)
+		fs.Handler = handler
+		res, err := fs.GetProcessedImage(context.Background(), 0, opts)
+		a.ErrorIs(err, ErrImageProcessFailed)
+		a.Nil(res)
+	}
+
+	// 成功处理，再次获取时使用缓存
+	{
+		fs := newFs(uint64(src.Len()))
+		handler := new(FileHeaderMock)
+		handler.On("Get", testMock.Anything, "1/a.png").Return(MockRSC{rs: bytes.NewReader(src.Bytes())}, nil).Once()
+		fs.Handler = handler
+		res, err := fs.GetProcessedImage(context.Background(), 0, opts)
+		a.NoError(err)
+		config, format, err := image.DecodeConfig(res)
+		res.Close()
+		a.NoError(err)
+		a.Equal("png", format)
+		a.Equal(10, config.Width)
+		a.Equal(5, config.Height)
+
+		res, err = fs.GetProcessedImage(context.Background(), 0, opts)
+		a.NoError(err)
+		res.Close()
+		handler.AssertExpectations(t)
+
+		_, err = os.Stat(res.Name())
+		a.NoError(err)
+	}
+}
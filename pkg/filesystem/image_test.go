@@ -115,6 +115,30 @@ func TestFileSystem_GetThumb(t *testing.T) {
 		testHandller2.AssertExpectations(t)
 		mockGenerator.AssertExpectations(t)
 	}
+
+	// video thumbnails disabled by storage policy
+	{
+		mockGenerator := &thumbmock.GeneratorMock{}
+		thumb.Generators = []thumb.Generator{mockGenerator}
+		fs.CleanTargets()
+		fs.SetTargetFile(&[]model.File{{
+			Policy: model.Policy{Type: "mock", OptionsSerialized: model.PolicyOption{DisableVideoThumb: true}},
+		}})
+		cache.Set("setting_thumb_ffmpeg_enabled", "1", 0)
+		testHandller2 := new(FileHeaderMock)
+		testHandller2.On("Thumb", testMock.Anything, &fs.FileTarget[0]).Return(&response.ContentResponse{}, driver.ErrorThumbNotExist)
+		testHandller2.On("Get", testMock.Anything, "").Return(MockRSC{}, nil)
+		mockGenerator.On("Generate", testMock.Anything, testMock.Anything, testMock.Anything, testMock.Anything,
+			testMock.MatchedBy(func(options map[string]string) bool {
+				return options["thumb_ffmpeg_enabled"] == "0"
+			})).Return(&thumb.Result{Path: "not_exit_thumb"}, nil)
+
+		fs.Handler = testHandller2
+		fs.FileTarget[0].Policy.ID = 1
+		_, err := fs.GetThumb(context.Background(), 1)
+		a.Error(err)
+		mockGenerator.AssertExpectations(t)
+	}
 }
 
 func TestFileSystem_ThumbWorker(t *testing.T) {
@@ -125,3 +149,130 @@ func TestFileSystem_ThumbWorker(t *testing.T) {
 		getThumbWorker().releaseWorker()
 	})
 }
+
+func TestFileSystem_GetThumbStates(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
+	res, err := fs.GetThumbStates(context.Background(), []uint{1})
+	a.ErrorIs(err, ErrDBListObjects)
+	a.Nil(res)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestFileSystem_ThumbState(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}, Policy: &model.Policy{Type: "mock"}}
+	cache.Set("setting_thumb_width", "400", 0)
+	cache.Set("setting_thumb_height", "300", 0)
+
+	// thumb not available
+	{
+		file := &model.File{MetadataSerialized: map[string]string{
+			model.ThumbStatusMetadataKey: model.ThumbStatusNotAvailable,
+		}}
+		state, generate := fs.thumbState(context.Background(), file)
+		a.Equal(ThumbStateNotAvailable, state.Status)
+		a.False(generate)
+	}
+
+	// redirected thumb
+	{
+		file := &model.File{}
+		handler := new(FileHeaderMock)
+		handler.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{Redirect: true, URL: "https://cloudreve.org/thumb"}, nil)
+		fs.Handler = handler
+		state, generate := fs.thumbState(context.Background(), file)
+		a.Equal(ThumbStateReady, state.Status)
+		a.Equal("https://cloudreve.org/thumb", state.URL)
+		a.False(generate)
+		handler.AssertExpectations(t)
+	}
+
+	// thumb content served by master
+	{
+		file := &model.File{}
+		handler := new(FileHeaderMock)
+		handler.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{Content: MockRSC{}}, nil)
+		fs.Handler = handler
+		state, generate := fs.thumbState(context.Background(), file)
+		a.Equal(ThumbStateReady, state.Status)
+		a.Empty(state.URL)
+		a.False(generate)
+	}
+
+	// thumb not generated yet
+	{
+		file := &model.File{}
+		handler := new(FileHeaderMock)
+		handler.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{}, driver.ErrorThumbNotExist)
+		fs.Handler = handler
+		state, generate := fs.thumbState(context.Background(), file)
+		a.Equal(ThumbStatePending, state.Status)
+		a.True(generate)
+	}
+
+	// thumb being generated
+	{
+		file := &model.File{}
+		file.ID = 2
+		thumbGenerating.Store(uint(2), true)
+		defer thumbGenerating.Delete(uint(2))
+		state, generate := fs.thumbState(context.Background(), file)
+		a.Equal(ThumbStatePending, state.Status)
+		a.False(generate)
+	}
+}
+
+func TestFileSystem_RegenerateThumbnail(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	file := &model.File{Policy: model.Policy{Type: "mock"}}
+	file.Policy.ID = 1
+
+	// 由存储端生成
+	{
+		testHandller := new(FileHeaderMock)
+		testHandller.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{Redirect: true}, nil)
+		fs.Handler = testHandller
+		generated, err := fs.RegenerateThumbnail(context.Background(), file)
+		a.NoError(err)
+		a.False(generated)
+		a.Empty(fs.FileTarget)
+		testHandller.AssertExpectations(t)
+	}
+
+	// 存储端不支持且无法代理
+	{
+		testHandller := new(FileHeaderMock)
+		testHandller.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{}, driver.ErrorThumbNotSupported)
+		fs.Handler = testHandller
+		generated, err := fs.RegenerateThumbnail(context.Background(), file)
+		a.NoError(err)
+		a.False(generated)
+		testHandller.AssertExpectations(t)
+	}
+
+	// 获取失败
+	{
+		testHandller := new(FileHeaderMock)
+		testHandller.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{}, errors.New("error"))
+		fs.Handler = testHandller
+		generated, err := fs.RegenerateThumbnail(context.Background(), file)
+		a.Error(err)
+		a.False(generated)
+	}
+
+	// 生成失败
+	{
+		testHandller := new(FileHeaderMock)
+		testHandller.On("Thumb", testMock.Anything, file).Return(&response.ContentResponse{}, driver.ErrorThumbNotExist)
+		testHandller.On("Get", testMock.Anything, "").Return(MockRSC{}, errors.New("error"))
+		fs.Handler = testHandller
+		generated, err := fs.RegenerateThumbnail(context.Background(), file)
+		a.Error(err)
+		a.False(generated)
+		testHandller.AssertExpectations(t)
+	}
+}
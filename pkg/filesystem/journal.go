@@ -0,0 +1,82 @@
+package filesystem
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ===============
+     变更日志
+   ===============
+*/
+
+// ChangeHook 对象变更钩子，在文件或目录被创建、修改、删除、移动后触发
+type ChangeHook func(ctx context.Context, fs *FileSystem, changes []model.Change)
+
+// contextChangeHooks 由其他模块注册，注入到所有用户请求创建的文件系统的变更钩子
+var contextChangeHooks []ChangeHook
+
+// RegisterChangeHook 注册用户请求创建的文件系统的变更钩子，应在初始化时调用
+func RegisterChangeHook(hook ChangeHook) {
+	contextChangeHooks = append(contextChangeHooks, hook)
+}
+
+// OnChange 注入对象变更钩子
+func (fs *FileSystem) OnChange(hook ChangeHook) {
+	fs.ChangeHooks = append(fs.ChangeHooks, hook)
+}
+
+// watchingChanges 是否有需要接收变更的钩子，用于跳过仅为生成变更记录而进行的查询
+func (fs *FileSystem) watchingChanges() bool {
+	return len(fs.ChangeHooks) > 0
+}
+
+// emitChanges 触发对象变更钩子
+func (fs *FileSystem) emitChanges(ctx context.Context, changes ...model.Change) {
+	if !fs.watchingChanges() || len(changes) == 0 {
+		return
+	}
+
+	for i := range changes {
+		changes[i].UserID = fs.User.ID
+	}
+
+	for _, hook := range fs.ChangeHooks {
+		hook(ctx, fs, changes)
+	}
+}
+
+// HookRecordChanges 将对象变更写入用户的变更日志
+func HookRecordChanges(ctx context.Context, fs *FileSystem, changes []model.Change) {
+	if err := model.RecordChanges(changes); err != nil {
+		util.LogCtx(ctx).Warning("Failed to record changes: %s", err)
+	}
+}
+
+func fileChange(changeType string, file *model.File) model.Change {
+	return model.Change{
+		Type:        changeType,
+		ObjectType:  model.ChangeObjectFile,
+		ObjectID:    file.ID,
+		Name:        file.Name,
+		ParentID:    file.FolderID,
+		Size:        file.Size,
+		Placeholder: file.UploadSessionID != nil,
+	}
+}
+
+func folderChange(changeType string, folder *model.Folder) model.Change {
+	change := model.Change{
+		Type:       changeType,
+		ObjectType: model.ChangeObjectFolder,
+		ObjectID:   folder.ID,
+		Name:       folder.Name,
+	}
+	if folder.ParentID != nil {
+		change.ParentID = *folder.ParentID
+	}
+
+	return change
+}
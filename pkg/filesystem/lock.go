@@ -0,0 +1,95 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/pathlock"
+)
+
+// lockPaths 锁定当前用户文件树上的路径及其子树，返回释放锁的函数
+func (fs *FileSystem) lockPaths(ctx context.Context, paths ...string) (func(), error) {
+	if fs.User == nil || fs.User.ID == 0 {
+		return func() {}, nil
+	}
+
+	// 忽略大小写时，仅大小写不同的路径指向同一对象
+	if fs.CaseInsensitive() {
+		for i := range paths {
+			paths[i] = strings.ToLower(paths[i])
+		}
+	}
+
+	unlock, err := pathlock.Lock(ctx, fs.User.ID, paths)
+	if err != nil {
+		return nil, ErrObjectLocked.WithError(err)
+	}
+
+	return unlock, nil
+}
+
+// LockObjects 锁定 dirs、files 所在的路径，返回释放锁的函数
+func (fs *FileSystem) LockObjects(ctx context.Context, dirs, files []uint) (func(), error) {
+	return fs.lockPaths(ctx, fs.objectPaths(dirs, files)...)
+}
+
+// objectPaths 查找当前用户的 dirs、files 的完整路径，忽略不存在的对象
+func (fs *FileSystem) objectPaths(dirs, files []uint) []string {
+	paths := make([]string, 0, len(dirs)+len(files))
+	if fs.User == nil || fs.User.ID == 0 {
+		return paths
+	}
+
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for i := range folders {
+			if folders[i].TraceRoot() == nil {
+				paths = append(paths, path.Join(folders[i].Position, folders[i].Name))
+			}
+		}
+	}
+
+	if len(files) > 0 {
+		fileObjects, _ := model.GetFilesByIDs(files, fs.User.ID)
+		parents := make(map[uint]string)
+		for _, file := range fileObjects {
+			parent, ok := parents[file.FolderID]
+			if !ok {
+				if parent, ok = fs.folderPath(file.FolderID); !ok {
+					continue
+				}
+				parents[file.FolderID] = parent
+			}
+
+			paths = append(paths, path.Join(parent, file.Name))
+		}
+	}
+
+	return paths
+}
+
+// folderPath 查找当前用户目录的完整路径
+func (fs *FileSystem) folderPath(id uint) (string, bool) {
+	folders, _ := model.GetFoldersByIDs([]uint{id}, fs.User.ID)
+	if len(folders) == 0 || folders[0].TraceRoot() != nil {
+		return "", false
+	}
+
+	return path.Join(folders[0].Position, folders[0].Name), true
+}
+
+// lockRename 锁定 parent 目录下重命名前后的路径，返回释放锁的函数
+func (fs *FileSystem) lockRename(ctx context.Context, parent uint, old, new string) (func(), error) {
+	if fs.User == nil || fs.User.ID == 0 {
+		return func() {}, nil
+	}
+
+	parentPath, ok := fs.folderPath(parent)
+	if !ok {
+		return nil, ErrPathNotExist
+	}
+
+	return fs.lockPaths(ctx, path.Join(parentPath, old), path.Join(parentPath, new))
+}
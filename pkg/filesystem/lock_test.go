@@ -0,0 +1,79 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_LockPaths(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{
+		User:   &model.User{Model: gorm.Model{ID: 1}},
+		Policy: &model.Policy{OptionsSerialized: model.PolicyOption{CaseInsensitive: true}},
+	}
+
+	unlock, err := fs.lockPaths(context.Background(), "/Docs")
+	asserts.NoError(err)
+
+	// 子路径被占用，忽略大小写
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := fs.lockPaths(ctx, "/docs/report.pdf")
+		var appErr serializer.AppError
+		asserts.True(errors.As(err, &appErr))
+		asserts.Equal(serializer.CodeObjectLocked, appErr.Code)
+	}
+
+	// 移动操作等待锁
+	{
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		err := fs.Move(ctx, nil, []uint{1}, "/", "/docs")
+		asserts.Equal(serializer.CodeObjectLocked, err.(serializer.AppError).Code)
+	}
+
+	unlock()
+	unlock, err = fs.lockPaths(context.Background(), "/docs/report.pdf")
+	asserts.NoError(err)
+	unlock()
+
+	// 匿名用户不加锁
+	unlock, err = (&FileSystem{User: &model.User{}}).lockPaths(context.Background(), "/")
+	asserts.NoError(err)
+	unlock()
+}
+
+func TestFileSystem_ObjectPaths(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+
+	// 目录
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "sub", 2, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(2, "/", 1))
+	// 文件，同一父目录只查找一次
+	mock.ExpectQuery("SELECT(.+)files(.+)").
+		WithArgs(4, 5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(4, "a.txt", 3).AddRow(5, "b.txt", 3))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "sub", 2, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(2, "/", 1))
+
+	paths := fs.objectPaths([]uint{3}, []uint{4, 5})
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal([]string{"/sub", "/sub/a.txt", "/sub/b.txt"}, paths)
+}
@@ -2,17 +2,23 @@ package filesystem
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"path"
-	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mediameta"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
+// streamListBatch 流式列取目录时每批从数据库读取的对象数
+const streamListBatch = 500
+
 /* =================
 	 文件/目录管理
    =================
@@ -20,11 +26,17 @@ import (
 
 // Rename 重命名对象
 func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string) (err error) {
+	new = NormalizeName(new)
+
 	// 验证新名字
 	if !fs.ValidateLegalName(ctx, new) || (len(file) > 0 && !fs.ValidateExtension(ctx, new)) {
 		return ErrIllegalObjectName
 	}
 
+	if err := fs.CheckObjectsPermission(dir, file, model.ACLWrite); err != nil {
+		return err
+	}
+
 	// 如果源对象是文件
 	if len(file) > 0 {
 		fileObject, err := model.GetFilesByIDs([]uint{file[0]}, fs.User.ID)
@@ -32,10 +44,24 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		unlock, err := fs.lockRename(ctx, fileObject[0].FolderID, fileObject[0].Name, new)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if fs.CaseInsensitive() && fs.hasCaseConflict(fileObject[0].FolderID, fileObject[0].ID, false, new) {
+			return ErrFileExisted
+		}
+
+		change := fileChange(model.ChangeMove, &fileObject[0])
 		err = fileObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		change.OldParentID, change.OldName, change.Name = change.ParentID, change.Name, new
+		fs.emitChanges(ctx, change)
 		return nil
 	}
 
@@ -45,19 +71,50 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 			return ErrPathNotExist
 		}
 
+		if folderObject[0].ParentID != nil {
+			unlock, err := fs.lockRename(ctx, *folderObject[0].ParentID, folderObject[0].Name, new)
+			if err != nil {
+				return err
+			}
+			defer unlock()
+		}
+
+		if fs.CaseInsensitive() && folderObject[0].ParentID != nil &&
+			fs.hasCaseConflict(*folderObject[0].ParentID, folderObject[0].ID, true, new) {
+			return ErrFileExisted
+		}
+
+		change := folderChange(model.ChangeMove, &folderObject[0])
 		err = folderObject[0].Rename(new)
 		if err != nil {
 			return ErrFileExisted
 		}
+
+		change.OldParentID, change.OldName, change.Name = change.ParentID, change.Name, new
+		fs.emitChanges(ctx, change)
 		return nil
 	}
 
 	return ErrPathNotExist
 }
 
-// Copy 复制src目录下的文件或目录到dst，
-// 暂时只支持单文件
+// hasCaseConflict 忽略大小写时，parent 目录下是否已有与 name 同名的其他对象
+func (fs *FileSystem) hasCaseConflict(parent, self uint, isDir bool, name string) bool {
+	folder := &model.Folder{OwnerID: fs.User.ID}
+	folder.ID = parent
+
+	return folder.HasChildCaseInsensitive(name, isDir, self)
+}
+
+// Copy 复制src目录下的文件或目录到dst
 func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst string) error {
+	// 锁定源目录与目标目录，避免复制过程中源对象被删除
+	unlock, err := fs.lockPaths(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// 获取目的目录
 	isDstExist, dstFolder := fs.IsPathExist(dst)
 	isSrcExist, srcFolder := fs.IsPathExist(src)
@@ -66,40 +123,48 @@ func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrPathNotExist
 	}
 
-	// 记录复制的文件的总容量
-	var newUsedStorage uint64
+	if err := fs.CheckPermission(srcFolder, model.ACLRead); err != nil {
+		return err
+	}
+
+	if err := fs.CheckPermission(dstFolder, model.ACLWrite); err != nil {
+		return err
+	}
 
 	// 设置webdav目标名
 	if dstName, ok := ctx.Value(fsctx.WebdavDstName).(string); ok {
-		dstFolder.WebdavDstName = dstName
+		dstFolder.WebdavDstName = NormalizeName(dstName)
 	}
 
-	// 复制目录
-	if len(dirs) > 0 {
-		subFileSizes, err := srcFolder.CopyFolderTo(dirs[0], dstFolder)
-		if err != nil {
-			return ErrObjectNotExist.WithError(err)
-		}
-		newUsedStorage += subFileSizes
+	// 检查目标目录的容量配额
+	if err := fs.checkFolderQuota(dstFolder, nil, func() (uint64, error) {
+		return fs.objectsSize(dirs, files)
+	}); err != nil {
+		return err
 	}
 
-	// 复制文件
-	if len(files) > 0 {
-		subFileSizes, err := srcFolder.MoveOrCopyFileTo(files, dstFolder, true)
-		if err != nil {
-			return ErrObjectNotExist.WithError(err)
-		}
-		newUsedStorage += subFileSizes
+	// 在事务中复制目录及文件并增加已用容量，任一对象失败时全部回滚
+	newUsedStorage, err := srcFolder.CopyObjectsTo(dirs, files, dstFolder)
+	if err != nil {
+		return fs.rolledBackError(serializer.CodeParentNotExist, "Failed to copy objects", err)
 	}
+	fs.User.Storage += newUsedStorage
 
-	// 扣除容量
-	fs.User.IncreaseStorageWithoutCheck(newUsedStorage)
+	// 复制产生的新对象不逐一记录，标记目标目录已修改，由客户端重新列取
+	fs.emitChanges(ctx, folderChange(model.ChangeModify, dstFolder))
 
 	return nil
 }
 
 // Move 移动文件和目录, 将id列表dirs和files从src移动至dst
 func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst string) error {
+	// 锁定源目录与目标目录，避免与其他操作交错
+	unlock, err := fs.lockPaths(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	// 获取目的目录
 	isDstExist, dstFolder := fs.IsPathExist(dst)
 	isSrcExist, srcFolder := fs.IsPathExist(src)
@@ -108,37 +173,112 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrPathNotExist
 	}
 
+	// 移出源目录需要写入权限
+	if err := fs.CheckPermission(srcFolder, model.ACLWrite); err != nil {
+		return err
+	}
+
+	if err := fs.CheckPermission(dstFolder, model.ACLWrite); err != nil {
+		return err
+	}
+
 	// 设置webdav目标名
 	if dstName, ok := ctx.Value(fsctx.WebdavDstName).(string); ok {
-		dstFolder.WebdavDstName = dstName
+		dstFolder.WebdavDstName = NormalizeName(dstName)
 	}
 
-	// 处理目录及子文件移动
-	err := srcFolder.MoveFolderTo(dirs, dstFolder)
-	if err != nil {
-		return ErrFileExisted.WithError(err)
+	// 检查目标目录的容量配额，源目录与目标目录共同的配额目录不受影响
+	if err := fs.checkFolderQuota(dstFolder, srcFolder, func() (uint64, error) {
+		return fs.objectsSize(dirs, files)
+	}); err != nil {
+		return err
 	}
 
-	// 处理文件移动
-	_, err = srcFolder.MoveOrCopyFileTo(files, dstFolder, false)
-	if err != nil {
-		return ErrFileExisted.WithError(err)
+	// 记录移动前的对象信息
+	var changes []model.Change
+	if fs.watchingChanges() {
+		changes = fs.listMoveChanges(dirs, files, srcFolder, dstFolder)
+	}
+
+	// 在事务中移动目录及文件，任一对象失败时全部回滚
+	if err := srcFolder.MoveObjectsTo(dirs, files, dstFolder); err != nil {
+		return fs.rolledBackError(serializer.CodeObjectExist, "Failed to move objects", err)
+	}
+
+	fs.emitChanges(ctx, changes...)
+	return nil
+}
+
+// rolledBackError 将事务回滚的错误转换为指明出错对象的批量错误报告
+func (fs *FileSystem) rolledBackError(code int, msg string, err error) error {
+	var objectErr *model.ObjectError
+	if !errors.As(err, &objectErr) {
+		return serializer.NewError(serializer.CodeDBError, msg, err)
+	}
+
+	item := ItemError{IsDir: objectErr.IsDir, Error: objectErr.Err.Error()}
+	if objectErr.IsDir {
+		if folders, _ := model.GetFoldersByIDs([]uint{objectErr.ID}, fs.User.ID); len(folders) > 0 {
+			item.Name = folders[0].Name
+		}
+	} else if files, _ := model.GetFilesByIDs([]uint{objectErr.ID}, fs.User.ID); len(files) > 0 {
+		item.Name = files[0].Name
+	}
+
+	return &BatchError{
+		Code:       code,
+		Msg:        msg,
+		RolledBack: true,
+		Items:      []ItemError{item},
+		RawError:   err,
+	}
+}
+
+// listMoveChanges 生成将 dirs、files 从 src 移动到 dst 产生的变更记录
+func (fs *FileSystem) listMoveChanges(dirs, files []uint, src, dst *model.Folder) []model.Change {
+	var changes []model.Change
+	appendChange := func(change model.Change) {
+		change.OldParentID, change.ParentID, change.OldName = src.ID, dst.ID, change.Name
+		if dst.WebdavDstName != "" {
+			change.Name = dst.WebdavDstName
+		}
+		changes = append(changes, change)
+	}
+
+	if len(dirs) > 0 {
+		folders, _ := model.GetFoldersByIDs(dirs, fs.User.ID)
+		for i := range folders {
+			if folders[i].ParentID != nil && *folders[i].ParentID == src.ID {
+				appendChange(folderChange(model.ChangeMove, &folders[i]))
+			}
+		}
 	}
 
-	// 移动文件
+	if len(files) > 0 {
+		fileObjects, _ := model.GetFilesByIDs(files, fs.User.ID)
+		for i := range fileObjects {
+			if fileObjects[i].FolderID == src.ID {
+				appendChange(fileChange(model.ChangeMove, &fileObjects[i]))
+			}
+		}
+	}
 
-	return err
+	return changes
 }
 
 // Delete 递归删除对象, force 为 true 时强制删除文件记录，忽略物理删除是否成功;
 // unlink 为 true 时只删除虚拟文件系统的文件记录，不删除物理文件。
 func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unlink bool) error {
-	// 已删除的文件ID
-	var deletedFiles = make([]*model.File, 0, len(fs.FileTarget))
-	// 删除失败的文件的父目录ID
+	if err := fs.CheckObjectsPermission(dirs, files, model.ACLDelete); err != nil {
+		return err
+	}
 
-	// 所有文件的ID
-	var allFiles = make([]*model.File, 0, len(fs.FileTarget))
+	// 锁定待删除对象的路径，避免与移动等操作交错
+	unlock, err := fs.LockObjects(ctx, dirs, files)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	// 列出要删除的目录
 	if len(dirs) > 0 {
@@ -156,9 +296,29 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 		}
 	}
 
+	// 受保留规则保护的对象不能删除
+	if err := fs.checkRetention(); err != nil {
+		return err
+	}
+
+	return fs.deleteTargets(ctx, force, unlink)
+}
+
+// deleteTargets 删除当前的目标文件及目录
+func (fs *FileSystem) deleteTargets(ctx context.Context, force, unlink bool) error {
+	// 已删除的文件ID
+	var deletedFiles = make([]*model.File, 0, len(fs.FileTarget))
+
+	// 所有文件的ID
+	var allFiles = make([]*model.File, 0, len(fs.FileTarget))
+
+	// 删除文件记录前不允许其他文件引用待删除的物理文件
+	unlock := model.LockSources()
+
 	// 去除待删除文件中包含软连接的部分
 	filesToBeDelete, err := model.RemoveFilesWithSoftLinks(fs.FileTarget)
 	if err != nil {
+		unlock()
 		return ErrDBListObjects.WithError(err)
 	}
 
@@ -187,42 +347,60 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force, unl
 		deletedFiles = allFiles
 	}
 
-	// 删除文件记录
-	err = model.DeleteFiles(deletedFiles, fs.User.ID)
+	// 如果文件全部删除成功，继续删除目录
+	var deletedFolderIDs []uint
+	if len(deletedFiles) == len(allFiles) {
+		deletedFolderIDs = make([]uint, 0, len(fs.DirTarget))
+		for _, value := range fs.DirTarget {
+			deletedFolderIDs = append(deletedFolderIDs, value.ID)
+		}
+	}
+
+	// 在事务中删除文件、目录记录及对应的分享记录
+	// TODO 先取消分享再删除文件
+	err = model.DeleteObjects(deletedFiles, deletedFolderIDs, fs.User.ID)
+	unlock()
 	if err != nil {
+		if !unlink && len(deletedFiles) > 0 {
+			util.LogCtx(ctx).Warning("Physical files of user %d are deleted but records are kept: %s", fs.User.ID, err)
+		}
 		return ErrDBDeleteObjects.WithError(err)
 	}
 
-	// 删除文件记录对应的分享记录
-	// TODO 先取消分享再删除文件
-	deletedFileIDs := make([]uint, len(deletedFiles))
-	for k, file := range deletedFiles {
-		deletedFileIDs[k] = file.ID
+	// 删除已删除文件的历史版本
+	if len(deletedFiles) > 0 {
+		fs.deleteFileVersions(ctx, deletedFiles, unlink)
 	}
 
-	model.DeleteShareBySourceIDs(deletedFileIDs, false)
+	changes := make([]model.Change, 0, len(deletedFiles)+len(deletedFolderIDs))
+	for _, file := range deletedFiles {
+		changes = append(changes, fileChange(model.ChangeDelete, file))
+	}
 
-	// 如果文件全部删除成功，继续删除目录
-	if len(deletedFiles) == len(allFiles) {
-		var allFolderIDs = make([]uint, 0, len(fs.DirTarget))
-		for _, value := range fs.DirTarget {
-			allFolderIDs = append(allFolderIDs, value.ID)
+	if deletedFolderIDs != nil {
+		for i := range fs.DirTarget {
+			changes = append(changes, folderChange(model.ChangeDelete, &fs.DirTarget[i]))
 		}
-		err = model.DeleteFolderByIDs(allFolderIDs)
-		if err != nil {
-			return ErrDBDeleteObjects.WithError(err)
-		}
-
-		// 删除目录记录对应的分享记录
-		model.DeleteShareBySourceIDs(allFolderIDs, true)
 	}
 
+	fs.emitChanges(ctx, changes...)
+
 	if notDeleted := len(fs.FileTarget) - len(deletedFiles); notDeleted > 0 {
-		return serializer.NewError(
-			serializer.CodeNotFullySuccess,
-			fmt.Sprintf("Failed to delete %d file(s).", notDeleted),
-			nil,
-		)
+		items := make([]ItemError, 0, notDeleted)
+		for i := range fs.FileTarget {
+			if util.ContainsString(failed[fs.FileTarget[i].PolicyID], fs.FileTarget[i].SourceName) {
+				items = append(items, ItemError{
+					Name:  fs.FileTarget[i].Name,
+					Error: "Failed to delete physical file",
+				})
+			}
+		}
+
+		return &BatchError{
+			Code:  serializer.CodeNotFullySuccess,
+			Msg:   fmt.Sprintf("Failed to delete %d file(s).", notDeleted),
+			Items: items,
+		}
 	}
 
 	return nil
@@ -271,13 +449,30 @@ func (fs *FileSystem) ListDeleteFiles(ctx context.Context, ids []uint) error {
 // 有些情况下（如在分享页面列对象）时，
 // 路径需要截取掉被分享目录路径之前的部分。
 func (fs *FileSystem) List(ctx context.Context, dirPath string, pathProcessor func(string) string) ([]serializer.Object, error) {
-	// 获取父目录
+	folder, err := fs.ListTarget(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.ListFolder(ctx, folder, pathProcessor), nil
+}
+
+// ListTarget 获取要列出的目录并检查读取权限，不查询子项目
+func (fs *FileSystem) ListTarget(dirPath string) (*model.Folder, error) {
 	isExist, folder := fs.IsPathExist(dirPath)
 	if !isExist {
 		return nil, ErrPathNotExist
 	}
+
+	if err := fs.CheckPermission(folder, model.ACLRead); err != nil {
+		return nil, err
+	}
 	fs.SetTargetDir(&[]model.Folder{*folder})
+	return folder, nil
+}
 
+// ListFolder 列出由 ListTarget 获取的目录下的内容
+func (fs *FileSystem) ListFolder(ctx context.Context, folder *model.Folder, pathProcessor func(string) string) []serializer.Object {
 	var parentPath = path.Join(folder.Position, folder.Name)
 	var childFolders []model.Folder
 	var childFiles []model.File
@@ -288,7 +483,65 @@ func (fs *FileSystem) List(ctx context.Context, dirPath string, pathProcessor fu
 	// 获取子文件
 	childFiles, _ = folder.GetChildFiles()
 
-	return fs.listObjects(ctx, parentPath, childFiles, childFolders, pathProcessor), nil
+	return fs.listObjects(ctx, parentPath, childFiles, childFolders, pathProcessor)
+}
+
+// ListingETag 返回目录列表的弱 ETag，由目录、所有者的列表版本号及列取参数得到，
+// 无需查询子项目即可判断列表是否发生变化
+func (fs *FileSystem) ListingETag(ctx context.Context, folder *model.Folder) string {
+	shareKey, _ := ctx.Value(fsctx.ShareKeyCtx).(string)
+	var policyID uint
+	if fs.Policy != nil {
+		policyID = fs.Policy.ID
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d:%s:%d:%s:%s", folder.ID, path.Join(folder.Position, folder.Name),
+		policyID, shareKey, model.ListingVersion(folder.OwnerID))))
+	return `W/"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// StreamList 分批列出目录下满足条件的子项目，每个对象依次交由 emit 处理，
+// 内存占用与目录大小无关。objectType 为 file 或 dir 时仅列出对应类型的对象
+func (fs *FileSystem) StreamList(ctx context.Context, dirPath, objectType string, filter *model.ChildFilter, emit func(*serializer.Object) error) error {
+	isExist, folder := fs.IsPathExist(dirPath)
+	if !isExist {
+		return ErrPathNotExist
+	}
+
+	if err := fs.CheckPermission(folder, model.ACLRead); err != nil {
+		return err
+	}
+	fs.SetTargetDir(&[]model.Folder{*folder})
+
+	parentPath := path.Join(folder.Position, folder.Name)
+	emitAll := func(objects []serializer.Object) error {
+		for i := range objects {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := emit(&objects[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if objectType != "file" {
+		if err := folder.WalkChildFolders(streamListBatch, filter, func(folders []model.Folder) error {
+			return emitAll(fs.listObjects(ctx, parentPath, nil, folders, nil))
+		}); err != nil {
+			return err
+		}
+	}
+
+	if objectType != "dir" {
+		return folder.WalkChildFiles(streamListBatch, filter, func(files []model.File) error {
+			return emitAll(fs.listObjects(ctx, parentPath, files, nil, nil))
+		})
+	}
+
+	return nil
 }
 
 // ListPhysical 列出存储策略中的外部目录
@@ -351,7 +604,7 @@ func (fs *FileSystem) listObjects(ctx context.Context, parent string, files []mo
 			ID:         hashid.HashID(subFolder.ID, hashid.FolderID),
 			Name:       subFolder.Name,
 			Path:       processedPath,
-			Size:       0,
+			Size:       subFolder.Size,
 			Type:       "dir",
 			Date:       subFolder.UpdatedAt,
 			CreateDate: subFolder.CreatedAt,
@@ -375,9 +628,12 @@ func (fs *FileSystem) listObjects(ctx context.Context, parent string, files []mo
 				Thumb:         file.ShouldLoadThumb(),
 				Size:          file.Size,
 				Type:          "file",
+				Version:       file.Version(),
 				Date:          file.UpdatedAt,
 				SourceEnabled: file.GetPolicy().IsOriginLinkEnable,
 				CreateDate:    file.CreatedAt,
+				ScanStatus:    file.ScanStatus,
+				MediaMeta:     mediameta.Decode(file.MetadataSerialized[model.MediaMetaMetadataKey]),
 			}
 			if shareKey != "" {
 				newFile.Key = shareKey
@@ -405,11 +661,15 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 
 	// 获取要创建目录的父路径和目录名
 	fullPath = path.Clean(fullPath)
+	if err := fs.checkPathDepth(fullPath); err != nil {
+		return nil, err
+	}
+
 	base := path.Dir(fullPath)
 	dir := path.Base(fullPath)
 
-	// 去掉结尾空格
-	dir = strings.TrimRight(dir, " ")
+	// 规范化目录名
+	dir = NormalizeName(dir)
 
 	// 检查目录名是否合法
 	if !fs.ValidateLegalName(ctx, dir) {
@@ -426,11 +686,22 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		parent = newParent
 	}
 
+	if err := fs.CheckPermission(parent, model.ACLWrite); err != nil {
+		return nil, err
+	}
+
 	// 是否有同名文件
 	if ok, _ := fs.IsChildFileExist(parent, dir); ok {
 		return nil, ErrFileExisted
 	}
 
+	// 忽略大小写时沿用已有的同名目录
+	if fs.CaseInsensitive() {
+		if existing, err := parent.GetChildCaseInsensitive(dir); err == nil {
+			return existing, nil
+		}
+	}
+
 	// 创建目录
 	newFolder := model.Folder{
 		Name:     dir,
@@ -443,6 +714,7 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, fmt.Errorf("failed to create folder: %w", err)
 	}
 
+	fs.emitChanges(ctx, folderChange(model.ChangeCreate, &newFolder))
 	return &newFolder, nil
 }
 
@@ -66,6 +66,58 @@ func TestFileSystem_ListPhysical(t *testing.T) {
 	}
 }
 
+func TestFileSystem_StreamList(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{
+		Model: gorm.Model{
+			ID: 1,
+		},
+	}}
+	ctx := context.Background()
+
+	// 仅列出文件，跳过上传中的文件
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+		mock.ExpectQuery("SELECT(.+)file(.+)").
+			WithArgs(1, 0, "%.txt%").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "upload_session_id"}).
+				AddRow(6, "a.txt", nil).AddRow(7, "b.txt", "session"))
+		var names []string
+		err := fs.StreamList(ctx, "/", "file", &model.ChildFilter{Keyword: ".txt"}, func(object *serializer.Object) error {
+			asserts.Equal("/", object.Path)
+			names = append(names, object.Name)
+			return nil
+		})
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]string{"a.txt"}, names)
+	}
+
+	// 输出出错时终止
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+		mock.ExpectQuery("SELECT(.+)folder(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "sub"))
+		err := fs.StreamList(ctx, "/", "", nil, func(object *serializer.Object) error {
+			return errors.New("error")
+		})
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		err := fs.StreamList(ctx, "/", "", nil, nil)
+		asserts.Equal(ErrPathNotExist, err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
 func TestFileSystem_List(t *testing.T) {
 	asserts := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -200,6 +252,12 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 	_, err := fs.CreateDirectory(ctx, "/ad/a+?")
 	asserts.Equal(ErrIllegalObjectName, err)
 
+	// 超出最大目录层级
+	fs.User.Group.OptionsSerialized.MaxDepth = 1
+	_, err = fs.CreateDirectory(ctx, "/ad/ab")
+	asserts.Equal(ErrPathDepthExceeded, err)
+	fs.User.Group.OptionsSerialized.MaxDepth = 0
+
 	// 存在同名文件
 	// 根目录
 	mock.ExpectQuery("SELECT(.+)").
@@ -472,11 +530,15 @@ func TestFileSystem_Delete(t *testing.T) {
 					AddRow(4, "1.txt", "1.txt", 365, 1),
 			)
 		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id", "size"}).AddRow(1, "2.txt", "2.txt", 365, 2))
-		// 两次查询软连接
+		// 两次查询软连接及历史版本引用
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		// 查询上传策略
 		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(365, "local"))
 		// 删除文件记录
@@ -485,19 +547,13 @@ func TestFileSystem_Delete(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnResult(sqlmock.NewResult(0, 1))
-		mock.ExpectCommit()
 		// 删除对应分享
-		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
-		mock.ExpectCommit()
 		// 删除目录
-		mock.ExpectBegin()
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnResult(sqlmock.NewResult(0, 3))
-		mock.ExpectCommit()
 		// 删除对应分享
-		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
@@ -529,11 +585,15 @@ func TestFileSystem_Delete(t *testing.T) {
 					AddRow(4, "1.txt", "1.txt", 602, 1),
 			)
 		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id", "size"}).AddRow(1, "2.txt", "2.txt", 602, 2))
-		// 两次查询软连接
+		// 两次查询软连接及历史版本引用
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id", "source_name"}))
 		// 查询上传策略
 		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(602, "local"))
 		// 删除文件记录
@@ -542,19 +602,13 @@ func TestFileSystem_Delete(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnResult(sqlmock.NewResult(0, 1))
-		mock.ExpectCommit()
 		// 删除对应分享
-		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
-		mock.ExpectCommit()
 		// 删除目录
-		mock.ExpectBegin()
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnResult(sqlmock.NewResult(0, 3))
-		mock.ExpectCommit()
 		// 删除对应分享
-		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
@@ -662,6 +716,38 @@ func TestFileSystem_Move(t *testing.T) {
 		asserts.Error(err)
 		asserts.NoError(mock.ExpectationsWereMet())
 	}
+
+	// 移动失败，回滚并报告出错的对象
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1, 1, "dst").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1, 1, "src").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(3, 1))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)SUM(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)SUM(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("duplicated"))
+		mock.ExpectRollback()
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(5, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "report.pdf"))
+		err := fs.Move(ctx, []uint{4}, []uint{5}, "/src", "/dst")
+		asserts.NoError(mock.ExpectationsWereMet())
+		var batchErr *BatchError
+		asserts.True(errors.As(err, &batchErr))
+		asserts.True(batchErr.RolledBack)
+		asserts.Equal([]ItemError{{Name: "report.pdf", Error: "duplicated"}}, batchErr.Items)
+		asserts.Equal(serializer.CodeObjectExist, batchErr.Response().Code)
+	}
 }
 
 func TestFileSystem_Rename(t *testing.T) {
@@ -680,6 +766,10 @@ func TestFileSystem_Rename(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old.text"))
+		// 父目录路径
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(0, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "/"))
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)files(.+)SET(.+)").
 			WithArgs(sqlmock.AnyArg(), "new.txt", sqlmock.AnyArg(), 10).
@@ -690,6 +780,24 @@ func TestFileSystem_Rename(t *testing.T) {
 		asserts.NoError(err)
 	}
 
+	// 忽略大小写，与其他文件冲突
+	{
+		fs.Policy = &model.Policy{OptionsSerialized: model.PolicyOption{CaseInsensitive: true}}
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(10, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(10, "old.text", 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "/"))
+		mock.ExpectQuery("SELECT count(.+)files(.+)LOWER(.+)").
+			WithArgs(2, "New.txt", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		err := fs.Rename(ctx, []uint{}, []uint{10}, "New.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrFileExisted, err)
+		fs.Policy = &model.Policy{}
+	}
+
 	// 重命名文件 不存在
 	{
 		mock.ExpectQuery("SELECT(.+)files(.+)").
@@ -706,6 +814,10 @@ func TestFileSystem_Rename(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old.text"))
+		// 父目录路径
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(0, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "/"))
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)files(.+)SET(.+)").
 			WithArgs(sqlmock.AnyArg(), "new.txt", sqlmock.AnyArg(), 10).
@@ -0,0 +1,233 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+/* ================
+	 目录清单相关
+   ================
+*/
+
+// ManifestVersion 目录清单的格式版本
+const ManifestVersion = 1
+
+// Manifest 用户目录树的元数据清单，用于在 Cloudreve 实例之间迁移
+type Manifest struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Folders    []ManifestFolder `json:"folders"`
+	Files      []ManifestFile   `json:"files"`
+}
+
+// ManifestFolder 清单中的目录，路径相对于用户根目录
+type ManifestFolder struct {
+	Path      string          `json:"path"`
+	CreatedAt time.Time       `json:"created_at"`
+	Shares    []ManifestShare `json:"shares,omitempty"`
+}
+
+// ManifestFile 清单中的文件，SHA256 为空表示存储策略未开启去重，导入时无法秒传
+type ManifestFile struct {
+	Path      string          `json:"path"`
+	Size      uint64          `json:"size"`
+	SHA256    string          `json:"sha256,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Shares    []ManifestShare `json:"shares,omitempty"`
+}
+
+// ManifestShare 清单中对象的分享设置
+type ManifestShare struct {
+	Password        string     `json:"password,omitempty"`
+	RemainDownloads int        `json:"remain_downloads"`
+	Expires         *time.Time `json:"expires,omitempty"`
+	PreviewEnabled  bool       `json:"preview_enabled"`
+}
+
+// ManifestImportResult 导入目录清单的结果
+type ManifestImportResult struct {
+	Folders int `json:"folders"`
+	Files   int `json:"files"`
+	Shares  int `json:"shares"`
+	// Existed 目标路径已存在而跳过的文件
+	Existed []string `json:"existed"`
+	// Missing 未找到相同内容、需要客户端重新上传的文件
+	Missing []string    `json:"missing"`
+	Failed  []ItemError `json:"failed"`
+}
+
+// manifestShareKey 分享所属对象
+type manifestShareKey struct {
+	isDir bool
+	id    uint
+}
+
+// ExportManifest 导出用户完整目录树的元数据清单，上传中的文件不会导出
+func (fs *FileSystem) ExportManifest(ctx context.Context) (*Manifest, error) {
+	folders, err := model.GetFoldersByUserID(fs.User.ID)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	files, err := model.GetFilesByUserID(fs.User.ID)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	shares, err := model.GetSharesByUserID(fs.User.ID)
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	sharesByObject := make(map[manifestShareKey][]ManifestShare)
+	for _, share := range shares {
+		key := manifestShareKey{isDir: share.IsDir, id: share.SourceID}
+		sharesByObject[key] = append(sharesByObject[key], ManifestShare{
+			Password:        share.Password,
+			RemainDownloads: share.RemainDownloads,
+			Expires:         share.Expires,
+			PreviewEnabled:  share.PreviewEnabled,
+		})
+	}
+
+	manifest := &Manifest{
+		Version:    ManifestVersion,
+		ExportedAt: time.Now(),
+		Folders:    make([]ManifestFolder, 0, len(folders)),
+		Files:      make([]ManifestFile, 0, len(files)),
+	}
+
+	paths := model.FolderPaths(folders)
+	for _, folder := range folders {
+		// 根目录无需导出
+		if folder.ParentID == nil {
+			continue
+		}
+
+		manifest.Folders = append(manifest.Folders, ManifestFolder{
+			Path:      paths[folder.ID],
+			CreatedAt: folder.CreatedAt,
+			Shares:    sharesByObject[manifestShareKey{isDir: true, id: folder.ID}],
+		})
+	}
+
+	for _, file := range files {
+		if file.UploadSessionID != nil {
+			continue
+		}
+
+		parent, ok := paths[file.FolderID]
+		if !ok {
+			continue
+		}
+
+		manifest.Files = append(manifest.Files, ManifestFile{
+			Path:      path.Join(parent, file.Name),
+			Size:      file.Size,
+			SHA256:    file.SHA256,
+			CreatedAt: file.CreatedAt,
+			UpdatedAt: file.UpdatedAt,
+			Shares:    sharesByObject[manifestShareKey{id: file.ID}],
+		})
+	}
+
+	sort.Slice(manifest.Folders, func(i, j int) bool {
+		return manifest.Folders[i].Path < manifest.Folders[j].Path
+	})
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+
+	return manifest, nil
+}
+
+// ImportManifest 将目录清单导入到 dst 目录下：创建全部目录，当前存储策略中已有相同内容的
+// 文件通过秒传创建，其余文件需由客户端重新上传。用户组允许分享时同时恢复分享设置
+func (fs *FileSystem) ImportManifest(ctx context.Context, manifest *Manifest, dst string) (*ManifestImportResult, error) {
+	if manifest.Version != ManifestVersion {
+		return nil, serializer.NewError(serializer.CodeParamErr, "Unsupported manifest version", nil)
+	}
+
+	result := &ManifestImportResult{
+		Existed: []string{},
+		Missing: []string{},
+		Failed:  []ItemError{},
+	}
+
+	for _, item := range manifest.Folders {
+		folder, err := fs.CreateDirectory(ctx, path.Join(dst, item.Path))
+		if err != nil {
+			result.Failed = append(result.Failed, ItemError{Name: item.Path, IsDir: true, Error: err.Error()})
+			continue
+		}
+
+		result.Folders++
+		result.Shares += fs.importShares(item.Shares, true, folder.ID, folder.Name)
+	}
+
+	for _, item := range manifest.Files {
+		if item.SHA256 == "" {
+			result.Missing = append(result.Missing, item.Path)
+			continue
+		}
+
+		fullPath := path.Join(dst, item.Path)
+		lastModified := item.UpdatedAt
+		fs.CleanHooks("")
+		file, err := fs.InstantUpload(ctx, &fsctx.FileStream{
+			Size:         item.Size,
+			Name:         path.Base(fullPath),
+			VirtualPath:  path.Dir(fullPath),
+			LastModified: &lastModified,
+		}, item.SHA256)
+		switch {
+		case err == nil:
+			result.Files++
+			result.Shares += fs.importShares(item.Shares, false, file.ID, file.Name)
+		case errors.Is(err, ErrInstantUploadMiss):
+			result.Missing = append(result.Missing, item.Path)
+		case errors.Is(err, ErrFileExisted), errors.Is(err, ErrFileUploadSessionExisted):
+			result.Existed = append(result.Existed, item.Path)
+		default:
+			result.Failed = append(result.Failed, ItemError{Name: item.Path, Error: err.Error()})
+		}
+	}
+
+	fs.CleanHooks("")
+	return result, nil
+}
+
+// importShares 为导入的对象重新创建分享，返回创建成功的数量
+func (fs *FileSystem) importShares(shares []ManifestShare, isDir bool, id uint, name string) int {
+	if !fs.User.Group.ShareEnabled {
+		return 0
+	}
+
+	created := 0
+	for _, item := range shares {
+		share := model.Share{
+			Password:        item.Password,
+			IsDir:           isDir,
+			UserID:          fs.User.ID,
+			SourceID:        id,
+			RemainDownloads: item.RemainDownloads,
+			Expires:         item.Expires,
+			PreviewEnabled:  item.PreviewEnabled,
+			SourceName:      name,
+		}
+		if _, err := share.Create(); err == nil {
+			created++
+		}
+	}
+
+	return created
+}
@@ -0,0 +1,82 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_ExportManifest(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "parent_id"}).
+			AddRow(1, "/", nil).
+			AddRow(2, "docs", 1),
+	)
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "folder_id", "size", "sha256", "upload_session_id"}).
+			AddRow(1, "b.txt", 2, 5, "hash", nil).
+			AddRow(2, "a.txt", 1, 1, "", nil).
+			AddRow(3, "uploading.txt", 1, 0, "", "session"),
+	)
+	mock.ExpectQuery("SELECT(.+)shares(.+)").WithArgs(1).WillReturnRows(
+		sqlmock.NewRows([]string{"id", "is_dir", "source_id", "password", "remain_downloads"}).
+			AddRow(1, true, 2, "pwd", -1).
+			AddRow(2, false, 1, "", 3),
+	)
+
+	manifest, err := fs.ExportManifest(context.Background())
+	a.NoError(err)
+	a.NoError(mock.ExpectationsWereMet())
+	a.Equal(ManifestVersion, manifest.Version)
+	a.Len(manifest.Folders, 1)
+	a.Equal("/docs", manifest.Folders[0].Path)
+	a.Equal([]ManifestShare{{Password: "pwd", RemainDownloads: -1}}, manifest.Folders[0].Shares)
+	a.Len(manifest.Files, 2)
+	a.Equal("/a.txt", manifest.Files[0].Path)
+	a.Empty(manifest.Files[0].Shares)
+	a.Equal("/docs/b.txt", manifest.Files[1].Path)
+	a.Equal("hash", manifest.Files[1].SHA256)
+	a.EqualValues(5, manifest.Files[1].Size)
+	a.Equal([]ManifestShare{{RemainDownloads: 3}}, manifest.Files[1].Shares)
+}
+
+func TestFileSystem_ImportManifest(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{
+		User:   &model.User{Model: gorm.Model{ID: 1}},
+		Policy: &model.Policy{Type: "mock"},
+	}
+
+	// 不支持的版本
+	{
+		_, err := fs.ImportManifest(context.Background(), &Manifest{Version: 2}, "/")
+		a.Error(err)
+	}
+
+	// 目录名不合法，文件无法秒传
+	{
+		result, err := fs.ImportManifest(context.Background(), &Manifest{
+			Version: ManifestVersion,
+			Folders: []ManifestFolder{{Path: "/a|b"}},
+			Files: []ManifestFile{
+				{Path: "/a.txt", Size: 1},
+				{Path: "/b.txt", Size: 1, SHA256: helloSHA256},
+			},
+		}, "/")
+		a.NoError(err)
+		a.Equal(0, result.Folders)
+		a.Len(result.Failed, 1)
+		a.Equal("/a|b", result.Failed[0].Name)
+		a.True(result.Failed[0].IsDir)
+		a.Equal([]string{"/a.txt", "/b.txt"}, result.Missing)
+		a.Empty(result.Existed)
+		a.Nil(fs.Hooks)
+	}
+}
@@ -0,0 +1,232 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mediameta"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     媒体信息相关
+   ================
+*/
+
+// ExtractMediaMeta probes container metadata of given video file via ffprobe
+// and saves the result into file metadata.
+func (fs *FileSystem) ExtractMediaMeta(ctx context.Context, file *model.File) error {
+	opts := model.GetSettingByNames("media_meta_ffprobe_path", "media_meta_exts")
+	if !mediameta.ShouldProbe(opts["media_meta_exts"], file.Name) {
+		return nil
+	}
+
+	timeout := model.GetIntSetting("media_meta_timeout", 60)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	// Unencrypted local files are probed in place, others are read by ffprobe from source URL
+	// so that only the required parts of the video are downloaded.
+	var input string
+	if conf.SystemConfig.Mode == "slave" || (file.GetPolicy().Type == "local" && !file.GetPolicy().Encrypted()) {
+		input = util.RelativePath(file.SourceName)
+	} else {
+		source, err := fs.Handler.Source(ctx, file.SourceName, int64(timeout), false, 0)
+		if err != nil {
+			return fmt.Errorf("failed to get source url of %q: %w", file.Name, err)
+		}
+		input = source
+	}
+
+	meta, err := mediameta.Probe(ctx, opts["media_meta_ffprobe_path"], input)
+	if err != nil {
+		return fmt.Errorf("failed to probe media meta of %q: %w", file.Name, err)
+	}
+
+	return file.UpdateMetadata(map[string]string{
+		model.MediaMetaMetadataKey: meta.Encode(),
+	})
+}
+
+// HookExtractMediaMeta 上传完成后异步提取视频元信息
+func HookExtractMediaMeta(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || !model.IsTrueVal(model.GetSettingByName("media_meta_enabled")) {
+		return nil
+	}
+
+	if !mediameta.ShouldProbe(model.GetSettingByName("media_meta_exts"), file.Name) {
+		return nil
+	}
+
+	user := fs.User
+	go func() {
+		probeFs, err := NewFileSystem(user)
+		if err != nil {
+			util.LogCtx(ctx).Warning("Failed to initialize filesystem for media meta: %s", err)
+			return
+		}
+		defer probeFs.Recycle()
+
+		probeFs.Policy = file.GetPolicy()
+		if err := probeFs.DispatchHandler(); err != nil {
+			util.LogCtx(ctx).Warning("Failed to dispatch policy handler for media meta: %s", err)
+			return
+		}
+
+		if err := probeFs.ExtractMediaMeta(context.Background(), file); err != nil {
+			util.LogCtx(ctx).Warning("Failed to extract media meta: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// ExtractExif reads EXIF metadata of given image file and saves it as a photo record.
+// Only the leading part of the file holding the metadata is read from storage.
+func (fs *FileSystem) ExtractExif(ctx context.Context, file *model.File) error {
+	if !mediameta.ShouldProbe(model.GetSettingByName("exif_exts"), file.Name) {
+		return nil
+	}
+
+	source, err := fs.Handler.Get(ctx, file.SourceName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch original file %q: %w", file.SourceName, err)
+	}
+	defer source.Close()
+
+	meta, err := mediameta.ParseExif(source)
+	if err != nil {
+		return fmt.Errorf("failed to parse EXIF of %q: %w", file.Name, err)
+	}
+
+	photo := &model.Photo{
+		FileID:    file.ID,
+		UserID:    file.UserID,
+		TakenAt:   file.CreatedAt,
+		Latitude:  meta.Latitude,
+		Longitude: meta.Longitude,
+		Make:      meta.Make,
+		Model:     meta.Model,
+		LensModel: meta.LensModel,
+		Width:     meta.Width,
+		Height:    meta.Height,
+	}
+	if meta.TakenAt != nil {
+		photo.TakenAt = *meta.TakenAt
+	}
+
+	return model.SavePhoto(photo)
+}
+
+// HookExtractExif 上传完成后异步提取图像 EXIF 信息
+func HookExtractExif(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || !model.IsTrueVal(model.GetSettingByName("exif_enabled")) {
+		return nil
+	}
+
+	if !mediameta.ShouldProbe(model.GetSettingByName("exif_exts"), file.Name) {
+		return nil
+	}
+
+	user := fs.User
+	go func() {
+		exifFs, err := NewFileSystem(user)
+		if err != nil {
+			util.LogCtx(ctx).Warning("Failed to initialize filesystem for EXIF: %s", err)
+			return
+		}
+		defer exifFs.Recycle()
+
+		exifFs.Policy = file.GetPolicy()
+		if err := exifFs.DispatchHandler(); err != nil {
+			util.LogCtx(ctx).Warning("Failed to dispatch policy handler for EXIF: %s", err)
+			return
+		}
+
+		if err := exifFs.ExtractExif(context.Background(), file); err != nil {
+			util.LogCtx(ctx).Debug("Failed to extract EXIF: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// ExtractMusicMeta reads ID3v2 or FLAC tags of given music file and saves them as a track record.
+func (fs *FileSystem) ExtractMusicMeta(ctx context.Context, file *model.File) error {
+	if !mediameta.ShouldProbe(model.GetSettingByName("music_meta_exts"), file.Name) {
+		return nil
+	}
+
+	source, err := fs.Handler.Get(ctx, file.SourceName)
+	if err != nil {
+		return fmt.Errorf("failed to fetch original file %q: %w", file.SourceName, err)
+	}
+	defer source.Close()
+
+	meta, err := mediameta.ParseAudioTags(source)
+	if err != nil {
+		return fmt.Errorf("failed to parse music tags of %q: %w", file.Name, err)
+	}
+
+	// Fallback to file name for untitled tracks
+	title := meta.Title
+	if title == "" {
+		title = strings.TrimSuffix(file.Name, path.Ext(file.Name))
+	}
+
+	return model.SaveMusicTrack(&model.MusicTrack{
+		FileID:      file.ID,
+		UserID:      file.UserID,
+		Title:       title,
+		Artist:      meta.Artist,
+		Album:       meta.Album,
+		AlbumArtist: meta.AlbumArtist,
+		Genre:       meta.Genre,
+		Year:        meta.Year,
+		TrackNo:     meta.Track,
+		Disc:        meta.Disc,
+		HasCover:    len(meta.Cover) > 0,
+	})
+}
+
+// HookExtractMusicMeta 上传完成后异步提取音乐标签
+func HookExtractMusicMeta(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	file, ok := fileHeader.Info().Model.(*model.File)
+	if !ok || !model.IsTrueVal(model.GetSettingByName("music_meta_enabled")) {
+		return nil
+	}
+
+	if !mediameta.ShouldProbe(model.GetSettingByName("music_meta_exts"), file.Name) {
+		return nil
+	}
+
+	user := fs.User
+	go func() {
+		musicFs, err := NewFileSystem(user)
+		if err != nil {
+			util.LogCtx(ctx).Warning("Failed to initialize filesystem for music meta: %s", err)
+			return
+		}
+		defer musicFs.Recycle()
+
+		musicFs.Policy = file.GetPolicy()
+		if err := musicFs.DispatchHandler(); err != nil {
+			util.LogCtx(ctx).Warning("Failed to dispatch policy handler for music meta: %s", err)
+			return
+		}
+
+		if err := musicFs.ExtractMusicMeta(context.Background(), file); err != nil {
+			util.LogCtx(ctx).Debug("Failed to extract music meta: %s", err)
+		}
+	}()
+
+	return nil
+}
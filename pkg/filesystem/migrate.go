@@ -0,0 +1,75 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     存储策略迁移
+   ================
+*/
+
+// MigrateFile 将文件从所在存储策略迁移至 dst，返回文件是否被迁移。
+// 上传完成后才更新文件记录，失败时目标策略中已上传的文件会被删除，原文件不受影响
+func (fs *FileSystem) MigrateFile(ctx context.Context, file *model.File, dst *model.Policy) (bool, error) {
+	if file.PolicyID == dst.ID {
+		return false, nil
+	}
+
+	fs.Policy = file.GetPolicy()
+	if err := fs.DispatchHandler(); err != nil {
+		return false, err
+	}
+	srcHandler := fs.Handler
+
+	// 原策略中的物理文件及附属文件，迁移完成后删除
+	previous := append([]string{file.SourceName}, file.HLSFiles()...)
+	if model.IsTrueVal(file.MetadataSerialized[model.ThumbSidecarMetadataKey]) {
+		previous = append(previous, file.ThumbFile())
+	}
+
+	rs, err := srcHandler.Get(ctx, file.SourceName)
+	if err != nil {
+		return false, ErrIO.WithError(err)
+	}
+	defer rs.Close()
+
+	fs.Policy = dst
+	if err := fs.DispatchHandler(); err != nil {
+		return false, err
+	}
+
+	savePath := path.Join(
+		dst.GeneratePath(file.UserID, file.Position),
+		dst.GenerateFileName(file.UserID, file.Name),
+	)
+	if err := fs.Handler.Put(ctx, &fsctx.FileStream{
+		File:        rs,
+		Seeker:      rs,
+		Size:        file.Size,
+		Name:        file.Name,
+		VirtualPath: file.Position,
+		SavePath:    savePath,
+	}); err != nil {
+		return false, fmt.Errorf("failed to upload %q to policy %d: %w", file.Name, dst.ID, err)
+	}
+
+	if err := file.ChangePolicy(dst.ID, savePath); err != nil {
+		if _, delErr := fs.Handler.Delete(context.Background(), []string{savePath}); delErr != nil {
+			util.LogCtx(ctx).Warning("Failed to delete migrated file %q: %s", savePath, delErr)
+		}
+		return false, err
+	}
+
+	if failed, err := srcHandler.Delete(context.Background(), previous); err != nil {
+		util.LogCtx(ctx).Warning("Failed to delete original files %v of %q: %s", failed, file.Name, err)
+	}
+
+	return true, nil
+}
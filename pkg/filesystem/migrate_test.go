@@ -0,0 +1,107 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func TestFileSystem_MigrateFile(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	dst := &model.Policy{Type: "mock", DirNameRule: "dst"}
+	dst.ID = 2
+	newFile := func() *model.File {
+		file := &model.File{
+			Name:       "a.txt",
+			SourceName: "src/a.txt",
+			PolicyID:   1,
+			Policy:     model.Policy{Type: "mock"},
+			MetadataSerialized: map[string]string{
+				model.ThumbSidecarMetadataKey: "true",
+			},
+		}
+		file.ID = 1
+		file.Policy.ID = 1
+		return file
+	}
+
+	// 已位于目标存储策略
+	{
+		file := newFile()
+		file.PolicyID = 2
+		migrated, err := fs.MigrateFile(context.Background(), file, dst)
+		a.NoError(err)
+		a.False(migrated)
+	}
+
+	// 无法读取原文件
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "src/a.txt").Return(MockRSC{}, errors.New("error"))
+		fs.Handler = testHandler
+		migrated, err := fs.MigrateFile(context.Background(), newFile(), dst)
+		a.ErrorIs(err, ErrIO)
+		a.False(migrated)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 上传失败
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "src/a.txt").Return(MockRSC{rs: strings.NewReader("1")}, nil)
+		testHandler.On("Put", testMock.Anything, testMock.Anything).Return(errors.New("error"))
+		fs.Handler = testHandler
+		migrated, err := fs.MigrateFile(context.Background(), newFile(), dst)
+		a.Error(err)
+		a.False(migrated)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 更新记录失败，删除已上传的文件
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "src/a.txt").Return(MockRSC{rs: strings.NewReader("1")}, nil)
+		testHandler.On("Put", testMock.Anything, testMock.Anything).Return(nil)
+		testHandler.On("Delete", testMock.Anything, []string{"dst/a.txt"}).Return([]string{}, nil)
+		fs.Handler = testHandler
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+		file := newFile()
+		migrated, err := fs.MigrateFile(context.Background(), file, dst)
+		a.ErrorIs(err, model.ErrFileChanged)
+		a.False(migrated)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(1, file.PolicyID)
+		testHandler.AssertExpectations(t)
+	}
+
+	// 成功，删除原文件及缩略图
+	{
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "src/a.txt").Return(MockRSC{rs: strings.NewReader("1")}, nil)
+		testHandler.On("Put", testMock.Anything, testMock.Anything).Return(nil)
+		testHandler.On("Delete", testMock.Anything, []string{"src/a.txt", "src/a.txt._thumb"}).Return([]string{}, nil)
+		fs.Handler = testHandler
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "policy_id", "source_name"}).AddRow(1, 1, "src/a.txt"))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		file := newFile()
+		migrated, err := fs.MigrateFile(context.Background(), file, dst)
+		a.NoError(err)
+		a.True(migrated)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(2, file.PolicyID)
+		a.Equal("dst/a.txt", file.SourceName)
+		testHandler.AssertExpectations(t)
+	}
+}
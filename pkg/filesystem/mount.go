@@ -0,0 +1,108 @@
+package filesystem
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// VirtualRoots 根目录下的虚拟目录，其下挂载其他用户或团队的目录
+var VirtualRoots = []string{SharedRootName, TeamRootName}
+
+// Mount 虚拟目录下的挂载点
+type Mount struct {
+	Name   string
+	Folder *model.Folder
+	// Permissions 没有匹配的目录 ACL 时在挂载点中拥有的权限
+	Permissions int
+	// Open 创建以挂载目录为根目录的文件系统
+	Open func() (*FileSystem, error)
+}
+
+// splitVirtualPath 拆分位于给定虚拟目录下的路径，返回挂载名称和挂载点内的路径。
+// 路径为虚拟目录本身时 mount 为空，不在虚拟目录下时 ok 为 false
+func splitVirtualPath(root, p string) (mount, rest string, ok bool) {
+	prefix := "/" + root
+	if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+		return "", "", false
+	}
+
+	p = strings.Trim(strings.TrimPrefix(p, prefix), "/")
+	if p == "" {
+		return "", "/", true
+	}
+
+	if i := strings.Index(p, "/"); i >= 0 {
+		return p[:i], p[i:], true
+	}
+
+	return p, "/", true
+}
+
+// SplitVirtualPath 拆分位于任一虚拟目录下的路径，返回虚拟目录名称、挂载名称和挂载点内的路径
+func SplitVirtualPath(p string) (root, mount, rest string, ok bool) {
+	for _, root := range VirtualRoots {
+		if mount, rest, ok := splitVirtualPath(root, p); ok {
+			return root, mount, rest, true
+		}
+	}
+
+	return "", "", "", false
+}
+
+// ListMounts 列出用户在给定虚拟目录下的挂载点
+func ListMounts(root string, uid uint) ([]Mount, error) {
+	var mounts []Mount
+	switch root {
+	case SharedRootName:
+		collabs, err := model.ListSharedWithUser(uid)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range collabs {
+			collab := &collabs[i]
+			mounts = append(mounts, Mount{
+				Name:        collab.MountName,
+				Folder:      collab.Folder,
+				Permissions: collab.Permissions(),
+				Open:        func() (*FileSystem, error) { return NewCollaborationFileSystem(collab) },
+			})
+		}
+	case TeamRootName:
+		teams, err := model.ListTeamsByUser(uid)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range teams {
+			team := &teams[i]
+			account := &model.User{}
+			account.ID = team.UserID
+			folder, err := account.Root()
+			if err != nil {
+				continue
+			}
+
+			mounts = append(mounts, Mount{
+				Name:        team.MountName,
+				Folder:      folder,
+				Permissions: team.Permissions(),
+				Open:        func() (*FileSystem, error) { return NewTeamFileSystem(team) },
+			})
+		}
+	}
+
+	return mounts, nil
+}
+
+// FindMount 按名称查找挂载点
+func FindMount(mounts []Mount, name string) *Mount {
+	for i := range mounts {
+		if mounts[i].Name == name {
+			return &mounts[i]
+		}
+	}
+
+	return nil
+}
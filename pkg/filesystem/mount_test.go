@@ -0,0 +1,58 @@
+package filesystem
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitVirtualPath(t *testing.T) {
+	asserts := assert.New(t)
+
+	_, _, _, ok := SplitVirtualPath("/Documents/a")
+	asserts.False(ok)
+
+	root, mount, rest, ok := SplitVirtualPath("/Teams")
+	asserts.True(ok)
+	asserts.Equal(TeamRootName, root)
+	asserts.Equal("", mount)
+	asserts.Equal("/", rest)
+
+	root, mount, rest, ok = SplitVirtualPath("/Shared with me/Docs/a.txt")
+	asserts.True(ok)
+	asserts.Equal(SharedRootName, root)
+	asserts.Equal("Docs", mount)
+	asserts.Equal("/a.txt", rest)
+}
+
+func TestListMounts(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 团队
+	{
+		mock.ExpectQuery("SELECT(.+)team_members(.+)").WithArgs(2).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "team_id", "role"}).AddRow(1, 1, model.TeamViewer))
+		mock.ExpectQuery("SELECT(.+)teams(.+)").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name", "user_id"}).AddRow(1, "t", 5))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(5).WillReturnRows(
+			sqlmock.NewRows([]string{"id", "name"}).AddRow(9, "/"))
+		mounts, err := ListMounts(TeamRootName, 2)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(mounts, 1)
+		asserts.Equal("t", mounts[0].Name)
+		asserts.Equal(model.ACLRead, mounts[0].Permissions)
+		asserts.EqualValues(9, mounts[0].Folder.ID)
+		asserts.Equal(&mounts[0], FindMount(mounts, "t"))
+		asserts.Nil(FindMount(mounts, "x"))
+	}
+
+	// 未知的虚拟目录
+	{
+		mounts, err := ListMounts("unknown", 2)
+		asserts.NoError(err)
+		asserts.Len(mounts, 0)
+	}
+}
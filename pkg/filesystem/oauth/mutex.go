@@ -1,6 +1,21 @@
 package oauth
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
+)
+
+const (
+	// credentialLockTTL 集群中凭证锁的有效期，单位为秒，持有者异常退出时锁将自动释放
+	credentialLockTTL = 30
+
+	// credentialLockRetry 集群中重试获取凭证锁的间隔
+	credentialLockRetry = 100 * time.Millisecond
+)
 
 // CredentialLock 针对存储策略凭证的锁
 type CredentialLock interface {
@@ -10,16 +25,41 @@ type CredentialLock interface {
 
 var GlobalMutex = mutexMap{}
 
+// mutexMap 按存储策略加锁，集群模式下同时持有跨实例的锁，使凭证只由一个实例刷新
 type mutexMap struct {
-	locks sync.Map
+	locks  sync.Map
+	tokens sync.Map
 }
 
 func (m *mutexMap) Lock(id uint) {
 	lock, _ := m.locks.LoadOrStore(id, &sync.Mutex{})
 	lock.(*sync.Mutex).Lock()
+
+	if !instance.Enabled() {
+		return
+	}
+
+	// 超时仍未获取到时，视为持有者已失效，继续刷新凭证
+	deadline := time.Now().Add(credentialLockTTL * time.Second)
+	for time.Now().Before(deadline) {
+		if token, ok := cache.TryLock(lockName(id), credentialLockTTL); ok {
+			m.tokens.Store(id, token)
+			return
+		}
+
+		time.Sleep(credentialLockRetry)
+	}
 }
 
 func (m *mutexMap) Unlock(id uint) {
+	if token, ok := m.tokens.LoadAndDelete(id); ok {
+		_ = cache.Unlock(lockName(id), token.(string))
+	}
+
 	lock, _ := m.locks.LoadOrStore(id, &sync.Mutex{})
 	lock.(*sync.Mutex).Unlock()
 }
+
+func lockName(id uint) string {
+	return fmt.Sprintf("oauth_credential_%d", id)
+}
@@ -42,7 +42,7 @@ func (fs *FileSystem) IsPathExist(path string) (bool, *model.Folder) {
 				return false, nil
 			}
 		} else {
-			currentFolder, err = currentFolder.GetChild(folderName)
+			currentFolder, err = fs.getChild(currentFolder, folderName)
 			if err != nil {
 				return false, nil
 			}
@@ -52,6 +52,48 @@ func (fs *FileSystem) IsPathExist(path string) (bool, *model.Folder) {
 	return true, currentFolder
 }
 
+// UseRoot 将文件系统的根目录限定为 root，其后的路径均相对于该目录解析。
+// root 为空或 "/" 时不做限定，目录不存在时返回 ErrPathNotExist
+func (fs *FileSystem) UseRoot(root string) error {
+	if root == "" || root == "/" {
+		return nil
+	}
+
+	exist, folder := fs.IsPathExist(root)
+	if !exist {
+		return ErrPathNotExist
+	}
+
+	folder.Position = ""
+	folder.Name = "/"
+	fs.Root = folder
+	return nil
+}
+
+// checkPathDepth 检查 dir 目录的层级是否超出用户组限制，根目录的层级为0。
+// 已限定根目录时，层级从用户的根目录起算
+func (fs *FileSystem) checkPathDepth(dir string) error {
+	max := fs.User.Group.OptionsSerialized.MaxDepth
+	if max <= 0 {
+		return nil
+	}
+
+	depth := len(util.SplitPath(path.Clean(dir))) - 1
+	if fs.Root != nil && fs.Root.ParentID != nil {
+		ancestors, err := fs.Root.AncestorIDs()
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+		depth += len(ancestors) - 1
+	}
+
+	if depth > max {
+		return ErrPathDepthExceeded
+	}
+
+	return nil
+}
+
 // IsFileExist 返回给定路径的文件是否存在
 func (fs *FileSystem) IsFileExist(fullPath string) (bool, *model.File) {
 	basePath := path.Dir(fullPath)
@@ -63,13 +105,55 @@ func (fs *FileSystem) IsFileExist(fullPath string) (bool, *model.File) {
 		return false, nil
 	}
 
-	file, err := parent.GetChildFile(fileName)
+	file, err := fs.getChildFile(parent, fileName)
 
 	return err == nil, file
 }
 
 // IsChildFileExist 确定folder目录下是否有名为name的文件
 func (fs *FileSystem) IsChildFileExist(folder *model.Folder, name string) (bool, *model.File) {
-	file, err := folder.GetChildFile(name)
+	file, err := fs.getChildFile(folder, name)
 	return err == nil, file
 }
+
+// CaseInsensitive 当前存储策略是否按忽略大小写的方式解析路径
+func (fs *FileSystem) CaseInsensitive() bool {
+	return fs.Policy != nil && fs.Policy.OptionsSerialized.CaseInsensitive
+}
+
+// getChild 按当前的大小写模式查找子目录。名称保存时经过规范化，查找时同样先按规范化后的
+// 名称查找，未找到时再按原名称查找规范化之前保存的目录
+func (fs *FileSystem) getChild(folder *model.Folder, name string) (*model.Folder, error) {
+	child, err := fs.getChildByName(folder, NormalizeName(name))
+	if err != nil && NormalizeName(name) != name {
+		return fs.getChildByName(folder, name)
+	}
+
+	return child, err
+}
+
+func (fs *FileSystem) getChildByName(folder *model.Folder, name string) (*model.Folder, error) {
+	if fs.CaseInsensitive() {
+		return folder.GetChildCaseInsensitive(name)
+	}
+
+	return folder.GetChild(name)
+}
+
+// getChildFile 按当前的大小写模式查找子文件，名称的规范化与 getChild 相同
+func (fs *FileSystem) getChildFile(folder *model.Folder, name string) (*model.File, error) {
+	file, err := fs.getChildFileByName(folder, NormalizeName(name))
+	if err != nil && NormalizeName(name) != name {
+		return fs.getChildFileByName(folder, name)
+	}
+
+	return file, err
+}
+
+func (fs *FileSystem) getChildFileByName(folder *model.Folder, name string) (*model.File, error) {
+	if fs.CaseInsensitive() {
+		return folder.GetChildFileCaseInsensitive(name)
+	}
+
+	return folder.GetChildFile(name)
+}
@@ -57,6 +57,30 @@ func TestFileSystem_IsFileExist(t *testing.T) {
 	}
 }
 
+func TestFileSystem_IsFileExist_CaseInsensitive(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{
+		User:   &model.User{Model: gorm.Model{ID: 1}},
+		Policy: &model.Policy{OptionsSerialized: model.PolicyOption{CaseInsensitive: true}},
+	}
+
+	// 根目录
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "name"}).AddRow(1, 1, "/"))
+	mock.ExpectQuery("SELECT(.+)folders(.+)LOWER(.+)").
+		WithArgs(1, 1, "DOCS").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Docs"))
+	mock.ExpectQuery("SELECT(.+)files(.+)LOWER(.+)").
+		WithArgs(2, "REPORT.pdf").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "report.PDF"))
+	exist, file := fs.IsFileExist("/DOCS/REPORT.pdf")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.True(exist)
+	asserts.Equal("report.PDF", file.Name)
+	asserts.Equal("/Docs", file.Position)
+}
+
 func TestFileSystem_IsPathExist(t *testing.T) {
 	asserts := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -149,6 +173,71 @@ func TestFileSystem_IsPathExist(t *testing.T) {
 
 }
 
+func TestFileSystem_UseRoot(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不限定根目录
+	{
+		fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+		asserts.NoError(fs.UseRoot(""))
+		asserts.NoError(fs.UseRoot("/"))
+		asserts.Nil(fs.Root)
+	}
+
+	// 限定根目录为 /1
+	{
+		fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1, 1, "1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "name"}).AddRow(2, 1, "1"))
+		asserts.NoError(fs.UseRoot("/1"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, fs.Root.ID)
+		asserts.Equal("/", fs.Root.Name)
+		asserts.Equal("", fs.Root.Position)
+	}
+
+	// 根目录不存在
+	{
+		fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1, 1, "404").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+		asserts.Equal(ErrPathNotExist, fs.UseRoot("/404"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(fs.Root)
+	}
+}
+
+func TestFileSystem_CheckPathDepth(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 未设定限制
+	a.NoError(fs.checkPathDepth("/a/b/c"))
+
+	fs.User.Group.OptionsSerialized.MaxDepth = 2
+	a.NoError(fs.checkPathDepth("/"))
+	a.NoError(fs.checkPathDepth("/a/b/"))
+	a.ErrorIs(fs.checkPathDepth("/a/b/c"), ErrPathDepthExceeded)
+
+	// 已限定根目录时从用户根目录起算
+	{
+		parent := uint(1)
+		fs.Root = &model.Folder{Model: gorm.Model{ID: 2}, ParentID: &parent, OwnerID: 1}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		a.ErrorIs(fs.checkPathDepth("/a/b"), ErrPathDepthExceeded)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
 func TestFileSystem_IsChildFileExist(t *testing.T) {
 	asserts := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -0,0 +1,174 @@
+package filesystem
+
+import (
+	"path"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/dustin/go-humanize"
+)
+
+// FolderSizeCachePrefix 目录总大小缓存的前缀
+const FolderSizeCachePrefix = "folder_size_"
+
+// FolderSize 获取目录及其子目录下文件的总大小，优先使用缓存的结果
+func (fs *FileSystem) FolderSize(id uint) (uint64, error) {
+	if size, ok := cache.Get(FolderSizeCachePrefix + strconv.FormatUint(uint64(id), 10)); ok {
+		return size.(uint64), nil
+	}
+
+	size, err := model.GetFolderSize(id, fs.User.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	SetFolderSizeCache(id, size)
+	return size, nil
+}
+
+// SetFolderSizeCache 更新缓存的目录总大小
+func SetFolderSizeCache(id uint, size uint64) {
+	_ = cache.Set(FolderSizeCachePrefix+strconv.FormatUint(uint64(id), 10), size,
+		model.GetIntSetting("folder_props_timeout", 300))
+}
+
+// checkFolderQuota 检查向 dst 写入新内容后，dst 及其上级目录是否超出容量配额。
+// except 不为空时，同时包含 except 的目录不参与检查，用于同一配额目录内的移动。
+// size 在确有需要检查的配额时才会被调用。检查通过后新内容计入缓存的目录大小
+func (fs *FileSystem) checkFolderQuota(dst, except *model.Folder, size func() (uint64, error)) error {
+	if fs.User == nil || len(fs.User.OptionsSerialized.FolderQuotas) == 0 || dst == nil {
+		return nil
+	}
+
+	ancestors, err := dst.AncestorIDs()
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	excluded := make(map[uint]bool)
+	if except != nil {
+		exceptAncestors, err := except.AncestorIDs()
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		for _, id := range exceptAncestors {
+			excluded[id] = true
+		}
+	}
+
+	var (
+		incoming uint64
+		measured bool
+		usages   = make(map[uint]uint64)
+	)
+	for _, id := range ancestors {
+		quota, ok := fs.User.FolderQuota(id)
+		if !ok || excluded[id] {
+			continue
+		}
+
+		if !measured {
+			if incoming, err = size(); err != nil {
+				return ErrDBListObjects.WithError(err)
+			}
+			measured = true
+		}
+
+		if incoming == 0 {
+			return nil
+		}
+
+		usage, err := fs.FolderSize(id)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		if usage+incoming > quota {
+			return ErrFolderQuotaExceeded
+		}
+		usages[id] = usage
+	}
+
+	for id, usage := range usages {
+		SetFolderSizeCache(id, usage+incoming)
+	}
+
+	return nil
+}
+
+// checkUploadQuota 检查向 dir 上传 size 字节是否超出目录容量配额，dir 尚不存在时
+// 以最近的已存在上级目录为准
+func (fs *FileSystem) checkUploadQuota(dir string, size uint64) error {
+	if fs.User == nil || len(fs.User.OptionsSerialized.FolderQuotas) == 0 || size == 0 {
+		return nil
+	}
+
+	for {
+		if exist, folder := fs.IsPathExist(dir); exist {
+			return fs.checkFolderQuota(folder, nil, func() (uint64, error) { return size, nil })
+		}
+
+		if dir == "/" || dir == "." || dir == "" {
+			return nil
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// objectsSize 统计待复制或移动的目录和文件的总大小
+func (fs *FileSystem) objectsSize(dirs, files []uint) (uint64, error) {
+	var total uint64
+	if len(files) > 0 {
+		fileModels, err := model.GetFilesByIDs(files, fs.User.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		for i := range fileModels {
+			total += fileModels[i].Size
+		}
+	}
+
+	for _, dir := range dirs {
+		size, err := model.GetFolderSize(dir, fs.User.ID)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+
+	return total, nil
+}
+
+// notifyStorageUsage 用户已用容量达到 notify_quota_threshold 百分比时发送提醒，
+// notify_quota_interval 秒内只提醒一次
+func (fs *FileSystem) notifyStorageUsage() {
+	total := fs.User.MaxStorage()
+	if fs.User.ID == 0 || total == 0 {
+		return
+	}
+
+	threshold := model.GetIntSetting("notify_quota_threshold", 90)
+	percent := fs.User.Storage * 100 / total
+	if threshold <= 0 || percent < uint64(threshold) {
+		return
+	}
+
+	// 文件系统回收后 User 会被重置，此处复制一份
+	user := *fs.User
+	vars := map[string]string{
+		"percent": strconv.FormatUint(percent, 10),
+		"used":    humanize.IBytes(user.Storage),
+		"total":   humanize.IBytes(total),
+	}
+	interval := model.GetIntSetting("notify_quota_interval", 86400)
+	go func() {
+		if err := email.NotifyThrottled(&user, email.NotifyQuotaAlmostFull, "storage", interval, vars); err != nil {
+			util.Log().Warning("Failed to send storage usage notification: %s", err)
+		}
+	}()
+}
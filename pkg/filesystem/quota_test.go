@@ -0,0 +1,139 @@
+package filesystem
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_CheckFolderQuota(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	parentID := uint(2)
+	dst := &model.Folder{Model: gorm.Model{ID: 3}, ParentID: &parentID, OwnerID: 1}
+	size := func() (uint64, error) { return 50, nil }
+	expectAncestors := func() {
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	}
+
+	// 未设定配额
+	asserts.NoError(fs.checkFolderQuota(dst, nil, size))
+
+	fs.User.SetFolderQuota(2, 100)
+
+	// 超出上级目录的配额
+	{
+		asserts.NoError(cache.Set(FolderSizeCachePrefix+"2", uint64(60), 0))
+		expectAncestors()
+		asserts.Equal(ErrFolderQuotaExceeded, fs.checkFolderQuota(dst, nil, size))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未超出，计入缓存的目录大小
+	{
+		asserts.NoError(cache.Set(FolderSizeCachePrefix+"2", uint64(40), 0))
+		expectAncestors()
+		asserts.NoError(fs.checkFolderQuota(dst, nil, size))
+		asserts.NoError(mock.ExpectationsWereMet())
+		usage, err := fs.FolderSize(2)
+		asserts.NoError(err)
+		asserts.EqualValues(90, usage)
+	}
+
+	// 在同一配额目录内移动
+	{
+		src := &model.Folder{Model: gorm.Model{ID: 4}, ParentID: &parentID, OwnerID: 1}
+		expectAncestors()
+		expectAncestors()
+		asserts.NoError(fs.checkFolderQuota(dst, src, func() (uint64, error) {
+			t.Fatal("size should not be measured")
+			return 0, nil
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFileSystem_FolderSize(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	cache.Deletes([]string{"10"}, FolderSizeCachePrefix)
+
+	// 无缓存时统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(20))
+	size, err := fs.FolderSize(10)
+	asserts.NoError(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(20, size)
+
+	// 使用缓存
+	size, err = fs.FolderSize(10)
+	asserts.NoError(err)
+	asserts.EqualValues(20, size)
+}
+
+type mailMock struct {
+	mu sync.Mutex
+	to []string
+}
+
+func (m *mailMock) Close() {
+}
+
+func (m *mailMock) Send(to, title, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.to = append(m.to, to)
+	return nil
+}
+
+func (m *mailMock) sent() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.to)
+}
+
+func TestFileSystem_NotifyStorageUsage(t *testing.T) {
+	asserts := assert.New(t)
+	mailer := &mailMock{}
+	email.Client = mailer
+	defer func() {
+		email.Client = nil
+	}()
+	cache.Set("setting_notify_quota_threshold", "90", 0)
+	cache.Set("setting_notify_quota_interval", "3600", 0)
+	cache.Set("setting_siteName", "Cloudreve", 0)
+	cache.Set("setting_siteURL", "http://localhost", 0)
+	cache.Set("setting_siteTitle", "", 0)
+	cache.Set("setting_mail_quota_almost_full_template", "{percent}", 0)
+	cache.Deletes([]string{"quota_almost_full_10_storage"}, "notify_")
+
+	user := &model.User{Model: gorm.Model{ID: 10}, Email: "a@cloudreve.org"}
+	user.Group.MaxStorage = 100
+	fs := &FileSystem{User: user}
+
+	// 未达到阈值
+	user.Storage = 89
+	fs.notifyStorageUsage()
+
+	// 达到阈值，重复触发时只发送一次
+	user.Storage = 95
+	fs.notifyStorageUsage()
+	asserts.Eventually(func() bool {
+		return mailer.sent() == 1
+	}, time.Second, 10*time.Millisecond)
+	fs.notifyStorageUsage()
+	time.Sleep(50 * time.Millisecond)
+	asserts.Equal(1, mailer.sent())
+}
@@ -0,0 +1,67 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeContent(t *testing.T) {
+	asserts := assert.New(t)
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	etag := ETag(modTime, 10)
+
+	serve := func(header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/file", nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		ServeContent(w, req, "a.txt", modTime, etag, strings.NewReader("0123456789"))
+		return w
+	}
+
+	// 单段
+	{
+		w := serve(map[string]string{"Range": "bytes=2-4"})
+		asserts.Equal(http.StatusPartialContent, w.Code)
+		asserts.Equal("234", w.Body.String())
+		asserts.Equal(etag, w.Header().Get("ETag"))
+		asserts.Equal("bytes", w.Header().Get("Accept-Ranges"))
+	}
+
+	// 多段
+	{
+		w := serve(map[string]string{"Range": "bytes=0-1,8-"})
+		asserts.Equal(http.StatusPartialContent, w.Code)
+		asserts.True(strings.HasPrefix(w.Header().Get("Content-Type"), "multipart/byteranges"))
+		asserts.Contains(w.Body.String(), "01")
+		asserts.Contains(w.Body.String(), "89")
+	}
+
+	// If-Range 匹配
+	{
+		w := serve(map[string]string{"Range": "bytes=5-", "If-Range": etag})
+		asserts.Equal(http.StatusPartialContent, w.Code)
+		asserts.Equal("56789", w.Body.String())
+	}
+
+	// If-Range 不匹配时返回完整内容
+	{
+		w := serve(map[string]string{"Range": "bytes=5-", "If-Range": `"other"`})
+		asserts.Equal(http.StatusOK, w.Code)
+		asserts.Equal("0123456789", w.Body.String())
+	}
+
+	// 分段过多
+	{
+		ranges := strings.Repeat("0-0,", maxRanges) + "1-1"
+		w := serve(map[string]string{"Range": "bytes=" + ranges})
+		asserts.Equal(http.StatusOK, w.Code)
+		asserts.Equal("0123456789", w.Body.String())
+	}
+}
@@ -0,0 +1,50 @@
+package filesystem
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// checkRetention 检查待删除的对象是否受用户的保留规则保护
+func (fs *FileSystem) checkRetention() error {
+	if fs.User == nil || len(fs.User.OptionsSerialized.Retention) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	for i := range fs.User.OptionsSerialized.Retention {
+		rule := &fs.User.OptionsSerialized.Retention[i]
+
+		// 规则作用的目录范围，为 nil 时作用于所有目录
+		var scope map[uint]bool
+		if rule.FolderID > 0 {
+			folders, err := model.GetRecursiveChildFolder([]uint{rule.FolderID}, fs.User.ID, true)
+			if err != nil {
+				return ErrDBListObjects.WithError(err)
+			}
+
+			scope = make(map[uint]bool, len(folders))
+			for _, folder := range folders {
+				scope[folder.ID] = true
+			}
+		}
+
+		for _, file := range fs.FileTarget {
+			if (scope == nil || scope[file.FolderID]) && rule.Locks(file.CreatedAt, now) {
+				return ErrRetentionLocked
+			}
+		}
+
+		// 法律保全期间目录本身也不能删除
+		if rule.LegalHold {
+			for _, folder := range fs.DirTarget {
+				if scope == nil || scope[folder.ID] {
+					return ErrRetentionLocked
+				}
+			}
+		}
+	}
+
+	return nil
+}
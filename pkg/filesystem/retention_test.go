@@ -0,0 +1,50 @@
+package filesystem
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_CheckRetention(t *testing.T) {
+	a := assert.New(t)
+	newFile := model.File{Model: gorm.Model{CreatedAt: time.Now()}, FolderID: 2}
+	oldFile := model.File{Model: gorm.Model{CreatedAt: time.Now().AddDate(-1, 0, 0)}, FolderID: 3}
+
+	// 未设置规则
+	{
+		fs := &FileSystem{User: &model.User{}, FileTarget: []model.File{newFile}}
+		a.NoError(fs.checkRetention())
+	}
+
+	// 用户级保留期限
+	{
+		fs := &FileSystem{User: &model.User{}, FileTarget: []model.File{oldFile}}
+		fs.User.OptionsSerialized.Retention = []model.RetentionRule{{Days: 30}}
+		a.NoError(fs.checkRetention())
+
+		fs.FileTarget = append(fs.FileTarget, newFile)
+		a.Equal(ErrRetentionLocked, fs.checkRetention())
+	}
+
+	// 目录级法律保全
+	{
+		fs := &FileSystem{User: &model.User{}, FileTarget: []model.File{oldFile}}
+		fs.User.ID = 1
+		fs.User.OptionsSerialized.Retention = []model.RetentionRule{{FolderID: 2, LegalHold: true}}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		a.NoError(fs.checkRetention())
+		a.NoError(mock.ExpectationsWereMet())
+
+		fs.DirTarget = []model.Folder{{Model: gorm.Model{ID: 2}}}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		a.Equal(ErrRetentionLocked, fs.checkRetention())
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
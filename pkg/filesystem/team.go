@@ -0,0 +1,18 @@
+package filesystem
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// TeamRootName 「团队」虚拟目录的名称，其下挂载当前用户所在团队的根目录
+const TeamRootName = "Teams"
+
+// NewTeamFileSystem 以团队存储账户的身份创建文件系统
+func NewTeamFileSystem(team *model.Team) (*FileSystem, error) {
+	account, err := team.Account()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileSystem(&account)
+}
@@ -0,0 +1,168 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+	"sort"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/torrent"
+)
+
+/* ===============
+     种子制作
+   ===============
+*/
+
+// TorrentEntry 种子中包含的文件
+type TorrentEntry struct {
+	File model.File
+	// Path 文件在种子内的路径
+	Path []string
+}
+
+// ListTorrentEntries 列出给定目录和文件下所有待制作种子的文件，返回建议的种子名称。
+// 仅选择单个文件或单个目录时，种子名称为该对象名称，否则返回空名称。
+func (fs *FileSystem) ListTorrentEntries(ctx context.Context, folderIDs, fileIDs []uint) (string, []TorrentEntry, error) {
+	var (
+		folders []model.Folder
+		files   []model.File
+		err     error
+	)
+
+	if len(folderIDs) > 0 {
+		if folders, err = model.GetFoldersByIDs(folderIDs, fs.User.ID); err != nil {
+			return "", nil, ErrDBListObjects
+		}
+	}
+
+	if len(fileIDs) > 0 {
+		if files, err = model.GetFilesByIDs(fileIDs, fs.User.ID); err != nil {
+			return "", nil, ErrDBListObjects
+		}
+	}
+
+	if len(folders) != len(folderIDs) || len(files) != len(fileIDs) {
+		return "", nil, ErrObjectNotExist
+	}
+
+	var (
+		name    string
+		entries []TorrentEntry
+	)
+
+	// 选择单个目录时，种子内路径相对于该目录
+	if len(folders) == 1 && len(files) == 0 {
+		name = folders[0].Name
+		folders[0].Name = ""
+	}
+
+	if len(files) == 1 && len(folders) == 0 {
+		name = files[0].Name
+	}
+
+	for i := 0; i < len(files); i++ {
+		entries = append(entries, TorrentEntry{File: files[i], Path: []string{files[i].Name}})
+	}
+
+	for i := 0; i < len(folders); i++ {
+		folders[i].Position = ""
+		if err := fs.walkTorrentFolder(&folders[i], &entries); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if len(entries) == 0 {
+		return "", nil, torrent.ErrNoFiles
+	}
+
+	// 按照路径排序，保证相同的输入生成相同的种子
+	sort.Slice(entries, func(i, j int) bool {
+		return strings.Join(entries[i].Path, "/") < strings.Join(entries[j].Path, "/")
+	})
+
+	return name, entries, nil
+}
+
+func (fs *FileSystem) walkTorrentFolder(folder *model.Folder, entries *[]TorrentEntry) error {
+	subFiles, err := folder.GetChildFiles()
+	if err != nil {
+		return ErrDBListObjects
+	}
+
+	for i := 0; i < len(subFiles); i++ {
+		*entries = append(*entries, TorrentEntry{
+			File: subFiles[i],
+			Path: splitTorrentPath(path.Join(subFiles[i].Position, subFiles[i].Name)),
+		})
+	}
+
+	subFolders, err := folder.GetChildFolder()
+	if err != nil {
+		return ErrDBListObjects
+	}
+
+	for i := 0; i < len(subFolders); i++ {
+		if err := fs.walkTorrentFolder(&subFolders[i], entries); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func splitTorrentPath(p string) []string {
+	return strings.Split(strings.Trim(p, "/"), "/")
+}
+
+// CreateTorrent 读取给定文件的内容，生成种子文件。pieceLength 为 0 时自动选择分片大小。
+func (fs *FileSystem) CreateTorrent(ctx context.Context, name string, entries []TorrentEntry,
+	pieceLength int64, trackers []string, comment string) (*torrent.MetaInfo, error) {
+	if pieceLength == 0 {
+		var totalSize uint64
+		for _, entry := range entries {
+			totalSize += entry.File.Size
+		}
+		pieceLength = torrent.AutoPieceLength(int64(totalSize))
+	}
+
+	builder, err := torrent.NewBuilder(pieceLength)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		select {
+		case <-ctx.Done():
+			return nil, ErrClientCanceled
+		default:
+		}
+
+		if err := fs.addTorrentEntry(ctx, builder, &entries[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.Build(name, trackers, comment)
+}
+
+func (fs *FileSystem) addTorrentEntry(ctx context.Context, builder *torrent.Builder, entry *TorrentEntry) error {
+	// 切换存储策略
+	fs.Policy = entry.File.GetPolicy()
+	if err := fs.DispatchHandler(); err != nil {
+		return err
+	}
+
+	rs, err := fs.Handler.Get(
+		context.WithValue(ctx, fsctx.FileModelCtx, entry.File),
+		entry.File.SourceName,
+	)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	return builder.AddFile(entry.Path, int64(entry.File.Size), rs)
+}
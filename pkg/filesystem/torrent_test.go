@@ -0,0 +1,103 @@
+package filesystem
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/torrent"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_ListTorrentEntries(t *testing.T) {
+	asserts := assert.New(t)
+	ctx := context.Background()
+	fs := FileSystem{
+		User: &model.User{Model: gorm.Model{ID: 1}},
+	}
+
+	// 单个目录，路径相对于该目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "dataset"))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "size"}).AddRow(1, "b.txt", 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "sub"))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "size"}).AddRow(2, "a.txt", 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+		name, entries, err := fs.ListTorrentEntries(ctx, []uint{1}, []uint{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("dataset", name)
+		asserts.Len(entries, 2)
+		asserts.Equal([]string{"b.txt"}, entries[0].Path)
+		asserts.Equal([]string{"sub", "a.txt"}, entries[1].Path)
+	}
+
+	// 单个文件
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "1.txt"))
+
+		name, entries, err := fs.ListTorrentEntries(ctx, []uint{}, []uint{1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("1.txt", name)
+		asserts.Equal([]string{"1.txt"}, entries[0].Path)
+	}
+
+	// 对象不存在
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, 2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "1.txt"))
+
+		_, _, err := fs.ListTorrentEntries(ctx, []uint{}, []uint{1, 2})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrObjectNotExist, err)
+	}
+}
+
+func TestFileSystem_CreateTorrent(t *testing.T) {
+	asserts := assert.New(t)
+	ctx := context.Background()
+	fs := FileSystem{
+		User: &model.User{Model: gorm.Model{ID: 1}},
+	}
+	asserts.NoError(cache.Set("policy_1", model.Policy{Type: "local"}, -1))
+
+	// 无效的分片大小
+	{
+		_, err := fs.CreateTorrent(ctx, "test", []TorrentEntry{}, 1, nil, "")
+		asserts.ErrorIs(err, torrent.ErrInvalidPiece)
+	}
+
+	// 成功
+	{
+		src := filepath.Join(t.TempDir(), "file.txt")
+		asserts.NoError(os.WriteFile(src, []byte("content"), 0644))
+		entries := []TorrentEntry{
+			{File: model.File{Name: "file.txt", SourceName: src, Size: 7, PolicyID: 1}, Path: []string{"file.txt"}},
+		}
+		res, err := fs.CreateTorrent(ctx, "file.txt", entries, 0, []string{"udp://tracker.example.com:80"}, "")
+		asserts.NoError(err)
+		asserts.Equal("file.txt", res.Name)
+		asserts.Contains(string(res.Raw), "6:lengthi7e")
+		asserts.Len(res.InfoHash, 40)
+	}
+}
@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// TransferTo 将当前用户的目录与文件转移到 dst 用户的 dstPath 目录下，
+// 物理文件仍保留在原存储策略中，只变更归属与位置
+func (fs *FileSystem) TransferTo(ctx context.Context, dst *FileSystem, dstPath string, dirs, files []uint) error {
+	if fs.User.ID == dst.User.ID {
+		return ErrObjectNotExist.WithError(errors.New("cannot transfer objects to the same user"))
+	}
+
+	isExist, dstFolder := dst.IsPathExist(dstPath)
+	if !isExist {
+		return ErrPathNotExist
+	}
+
+	// 检查顶层对象
+	var (
+		topDirs  []model.Folder
+		topFiles []model.File
+		err      error
+	)
+	if len(dirs) > 0 {
+		if topDirs, err = model.GetFoldersByIDs(dirs, fs.User.ID); err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+	}
+	if len(files) > 0 {
+		if topFiles, err = model.GetFilesByIDs(files, fs.User.ID); err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+	}
+	if len(topDirs) != len(dirs) || len(topFiles) != len(files) {
+		return ErrObjectNotExist
+	}
+
+	for _, folder := range topDirs {
+		if folder.ParentID == nil {
+			return ErrRootProtected
+		}
+	}
+
+	// 检查目标目录下是否有同名对象
+	existed := make(map[string]bool)
+	childFolders, _ := dstFolder.GetChildFolder()
+	for _, folder := range childFolders {
+		existed[folder.Name] = true
+	}
+	childFiles, _ := dstFolder.GetChildFiles()
+	for _, file := range childFiles {
+		existed[file.Name] = true
+	}
+	for _, folder := range topDirs {
+		if existed[folder.Name] {
+			return ErrFileExisted
+		}
+	}
+	for _, file := range topFiles {
+		if existed[file.Name] {
+			return ErrFileExisted
+		}
+	}
+
+	// 列出所有子对象
+	fs.CleanTargets()
+	if len(dirs) > 0 {
+		if err := fs.ListDeleteDirs(ctx, dirs); err != nil {
+			return err
+		}
+	}
+	fs.SetTargetFile(&topFiles)
+
+	// 受保留规则保护的对象不能转移
+	if err := fs.checkRetention(); err != nil {
+		return err
+	}
+
+	var (
+		size     uint64
+		allFiles = make([]uint, 0, len(fs.FileTarget))
+		allDirs  = make([]uint, 0, len(fs.DirTarget))
+	)
+	for _, file := range fs.FileTarget {
+		size += file.Size
+		allFiles = append(allFiles, file.ID)
+	}
+	for _, folder := range fs.DirTarget {
+		allDirs = append(allDirs, folder.ID)
+	}
+
+	if err := model.TransferObjects(fs.User.ID, dstFolder, dirs, files, allDirs, allFiles, size); err != nil {
+		return ErrDBMoveObjects.WithError(err)
+	}
+
+	// 原用户的分享已无法访问这些对象
+	if len(allFiles) > 0 {
+		model.DeleteShareBySourceIDs(allFiles, false)
+	}
+	if len(allDirs) > 0 {
+		model.DeleteShareBySourceIDs(allDirs, true)
+	}
+
+	// 为双方生成变更记录，顶层对象在目标用户中位于新的目录下
+	srcChanges := make([]model.Change, 0, len(allFiles)+len(allDirs))
+	dstChanges := make([]model.Change, 0, len(allFiles)+len(allDirs))
+	for i := range fs.FileTarget {
+		file := fs.FileTarget[i]
+		srcChanges = append(srcChanges, fileChange(model.ChangeDelete, &file))
+		for _, id := range files {
+			if id == file.ID {
+				file.FolderID = dstFolder.ID
+			}
+		}
+		dstChanges = append(dstChanges, fileChange(model.ChangeCreate, &file))
+	}
+	for i := range fs.DirTarget {
+		folder := fs.DirTarget[i]
+		srcChanges = append(srcChanges, folderChange(model.ChangeDelete, &folder))
+		for _, id := range dirs {
+			if id == folder.ID {
+				folder.ParentID = &dstFolder.ID
+			}
+		}
+		dstChanges = append(dstChanges, folderChange(model.ChangeCreate, &folder))
+	}
+
+	fs.emitChanges(ctx, srcChanges...)
+	dst.emitChanges(ctx, dstChanges...)
+	return nil
+}
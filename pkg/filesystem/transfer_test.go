@@ -0,0 +1,74 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_TransferTo(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	src := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	dst := &FileSystem{User: &model.User{Model: gorm.Model{ID: 2}}}
+
+	// 同一用户
+	{
+		a.Error(src.TransferTo(ctx, src, "/", nil, []uint{1}))
+	}
+
+	// 目标目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		a.Equal(ErrPathNotExist, src.TransferTo(ctx, dst, "/", nil, []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 文件不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(10, 2))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		a.Equal(ErrObjectNotExist, src.TransferTo(ctx, dst, "/", nil, []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目标目录下存在同名对象
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(10, 2))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a.txt"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "a.txt"))
+		a.Equal(ErrFileExisted, src.TransferTo(ctx, dst, "/", nil, []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(10, 2))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "size"}).AddRow(1, "a.txt", 10))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY(.+)").WillReturnRows(sqlmock.NewRows([]string{"folder_id", "sum"}).AddRow(1, 0))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		a.NoError(src.TransferTo(ctx, dst, "/", nil, []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,248 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// TrashEnabled 当前用户所在用户组是否开启回收站
+func (fs *FileSystem) TrashEnabled() bool {
+	return fs.User.Group.OptionsSerialized.TrashRetention > 0
+}
+
+// Trash 将对象移入回收站，对象及其子对象不再可见，直至被恢复或清除；
+// 回收站中的文件仍计入用户已用容量。用户组未开启回收站时直接删除对象
+func (fs *FileSystem) Trash(ctx context.Context, dirs, files []uint) error {
+	if !fs.TrashEnabled() {
+		return fs.Delete(ctx, dirs, files, false, false)
+	}
+
+	if err := fs.CheckObjectsPermission(dirs, files, model.ACLDelete); err != nil {
+		return err
+	}
+
+	// 锁定待删除对象的路径，避免与移动等操作交错
+	unlock, err := fs.LockObjects(ctx, dirs, files)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 列出顶层对象
+	folders, err := model.GetFoldersByIDs(dirs, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	topFiles, err := model.GetFilesByIDs(files, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	// 列出全部子对象
+	if len(dirs) > 0 {
+		if err := fs.ListDeleteDirs(ctx, dirs); err != nil {
+			return err
+		}
+	}
+	fs.SetTargetFile(&topFiles)
+
+	// 受保留规则保护的对象不能删除
+	if err := fs.checkRetention(); err != nil {
+		return err
+	}
+
+	items := fs.trashItems(folders, topFiles)
+	if len(items) == 0 {
+		return nil
+	}
+
+	allDirs := make([]uint, len(fs.DirTarget))
+	for i, folder := range fs.DirTarget {
+		allDirs[i] = folder.ID
+	}
+
+	allFiles := make([]uint, len(fs.FileTarget))
+	for i, file := range fs.FileTarget {
+		allFiles[i] = file.ID
+	}
+
+	if err := model.TrashObjects(items, allDirs, allFiles); err != nil {
+		return ErrDBDeleteObjects.WithError(err)
+	}
+
+	changes := make([]model.Change, 0, len(fs.FileTarget)+len(fs.DirTarget))
+	for i := range fs.FileTarget {
+		changes = append(changes, fileChange(model.ChangeDelete, &fs.FileTarget[i]))
+	}
+	for i := range fs.DirTarget {
+		changes = append(changes, folderChange(model.ChangeDelete, &fs.DirTarget[i]))
+	}
+	fs.emitChanges(ctx, changes...)
+
+	return nil
+}
+
+// trashItems 为顶层对象生成回收站记录，记录原位置及包含的文件总大小
+func (fs *FileSystem) trashItems(folders []model.Folder, files []model.File) []model.Trash {
+	items := make([]model.Trash, 0, len(folders)+len(files))
+	parents := make(map[uint]string)
+	parentPath := func(id uint) string {
+		if p, ok := parents[id]; ok {
+			return p
+		}
+		p, _ := fs.folderPath(id)
+		parents[id] = p
+		return p
+	}
+
+	// 将子文件大小计入所在的顶层目录
+	tops := make(map[uint]uint64)
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			tops[folder.ID] = 0
+		}
+	}
+
+	folderParents := make(map[uint]uint)
+	for _, folder := range fs.DirTarget {
+		if folder.ParentID != nil {
+			folderParents[folder.ID] = *folder.ParentID
+		}
+	}
+
+	for _, file := range fs.FileTarget {
+		for id := file.FolderID; id != 0; id = folderParents[id] {
+			if _, ok := tops[id]; ok {
+				tops[id] += file.Size
+				break
+			}
+		}
+	}
+
+	for _, folder := range folders {
+		// 根目录不能删除
+		if folder.ParentID == nil {
+			continue
+		}
+
+		items = append(items, model.Trash{
+			UserID:     fs.User.ID,
+			ObjectType: model.TrashObjectFolder,
+			ObjectID:   folder.ID,
+			Name:       folder.Name,
+			ParentID:   *folder.ParentID,
+			Path:       parentPath(*folder.ParentID),
+			Size:       tops[folder.ID],
+		})
+	}
+
+	for _, file := range files {
+		items = append(items, model.Trash{
+			UserID:     fs.User.ID,
+			ObjectType: model.TrashObjectFile,
+			ObjectID:   file.ID,
+			Name:       file.Name,
+			ParentID:   file.FolderID,
+			Path:       parentPath(file.FolderID),
+			Size:       file.Size,
+		})
+	}
+
+	return items
+}
+
+// RestoreTrash 将回收站中的对象恢复到原目录，原目录已不存在时恢复到根目录
+func (fs *FileSystem) RestoreTrash(ctx context.Context, item *model.Trash) error {
+	parents, err := model.GetFoldersByIDs([]uint{item.ParentID}, fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	var parent *model.Folder
+	if len(parents) > 0 {
+		parent = &parents[0]
+	} else if parent, err = fs.User.Root(); err != nil {
+		return ErrPathNotExist.WithError(err)
+	}
+
+	parentPath, ok := fs.folderPath(parent.ID)
+	if !ok {
+		return ErrPathNotExist
+	}
+
+	unlock, err := fs.lockPaths(ctx, path.Join(parentPath, item.Name))
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 原目录中已有同名对象
+	if _, err := fs.getChild(parent, item.Name); err == nil {
+		return ErrFileExisted
+	}
+	if _, err := fs.getChildFile(parent, item.Name); err == nil {
+		return ErrFileExisted
+	}
+
+	folders, files, err := item.Objects()
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+
+	dirs := make([]uint, len(folders))
+	for i, folder := range folders {
+		dirs[i] = folder.ID
+	}
+
+	fileIDs := make([]uint, len(files))
+	for i, file := range files {
+		fileIDs[i] = file.ID
+	}
+
+	if err := item.Restore(parent.ID, dirs, fileIDs); err != nil {
+		return ErrDBMoveObjects.WithError(err)
+	}
+
+	changes := make([]model.Change, 0, len(folders)+len(files))
+	for i := range folders {
+		if folders[i].ID == item.ObjectID && item.ObjectType == model.TrashObjectFolder {
+			folders[i].Name, folders[i].ParentID = item.Name, &parent.ID
+		}
+		changes = append(changes, folderChange(model.ChangeCreate, &folders[i]))
+	}
+	for i := range files {
+		if files[i].ID == item.ObjectID && item.ObjectType == model.TrashObjectFile {
+			files[i].Name, files[i].FolderID = item.Name, parent.ID
+		}
+		changes = append(changes, fileChange(model.ChangeCreate, &files[i]))
+	}
+	fs.emitChanges(ctx, changes...)
+
+	return nil
+}
+
+// PurgeTrash 彻底删除回收站中的对象，并释放其占用的容量
+func (fs *FileSystem) PurgeTrash(ctx context.Context, items []model.Trash) error {
+	for i := range items {
+		folders, files, err := items[i].Objects()
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		fs.CleanTargets()
+		fs.SetTargetDir(&folders)
+		fs.SetTargetFile(&files)
+		if err := fs.deleteTargets(ctx, false, false); err != nil {
+			return err
+		}
+
+		if err := items[i].Delete(); err != nil {
+			return ErrDBDeleteObjects.WithError(err)
+		}
+	}
+
+	return nil
+}
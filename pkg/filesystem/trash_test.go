@@ -0,0 +1,115 @@
+package filesystem
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_TrashEnabled(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	a.False(fs.TrashEnabled())
+
+	fs.User.Group.OptionsSerialized.TrashRetention = 30
+	a.True(fs.TrashEnabled())
+}
+
+func TestFileSystem_TrashItems(t *testing.T) {
+	a := assert.New(t)
+	root := uint(1)
+	dir := uint(2)
+	fs := &FileSystem{
+		User: &model.User{Model: gorm.Model{ID: 1}},
+		DirTarget: []model.Folder{
+			{Model: gorm.Model{ID: 2}, Name: "dir", ParentID: &root},
+			{Model: gorm.Model{ID: 3}, Name: "sub", ParentID: &dir},
+		},
+		FileTarget: []model.File{
+			{Model: gorm.Model{ID: 4}, FolderID: 2, Size: 10},
+			{Model: gorm.Model{ID: 5}, FolderID: 3, Size: 5},
+			{Model: gorm.Model{ID: 6}, FolderID: 1, Size: 1, Name: "a.txt"},
+		},
+	}
+
+	// 原父目录路径只查询一次
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "/"))
+	items := fs.trashItems(fs.DirTarget[:1], fs.FileTarget[2:])
+	a.NoError(mock.ExpectationsWereMet())
+	a.Len(items, 2)
+	a.Equal(model.TrashObjectFolder, items[0].ObjectType)
+	a.EqualValues(2, items[0].ObjectID)
+	a.EqualValues(1, items[0].ParentID)
+	a.Equal("/", items[0].Path)
+	a.EqualValues(15, items[0].Size)
+	a.Equal(model.TrashObjectFile, items[1].ObjectType)
+	a.Equal("a.txt", items[1].Name)
+	a.EqualValues(1, items[1].Size)
+}
+
+func TestFileSystem_RestoreTrash(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	item := &model.Trash{Model: gorm.Model{ID: 5}, UserID: 1, ObjectType: model.TrashObjectFile, ObjectID: 3, Name: "a.txt", ParentID: 2}
+
+	// 原目录中已有同名文件
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "/"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "/"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		err := fs.RestoreTrash(context.Background(), item)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(ErrFileExisted, err)
+	}
+
+	// 原目录已不存在，恢复到根目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "/"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "/"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "5"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)deleted_at(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(1, "a.txt", 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("DELETE(.+)trashes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(fs.RestoreTrash(context.Background(), item))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFileSystem_PurgeTrash(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+
+	// 无记录
+	a.NoError(fs.PurgeTrash(context.Background(), nil))
+
+	// 对象已不存在时只删除记录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)trashes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		err := fs.PurgeTrash(context.Background(), []model.Trash{{Model: gorm.Model{ID: 5}, UserID: 1, ObjectType: model.TrashObjectFolder, ObjectID: 2}})
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"os"
 	"path"
+	"strconv"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/instance"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -30,8 +32,18 @@ const (
 
 // Upload 上传文件
 func (fs *FileSystem) Upload(ctx context.Context, file *fsctx.FileStream) (err error) {
+	// 新建文件时规范化文件名，更新已有文件时沿用原文件名
+	if originFile, ok := ctx.Value(fsctx.FileModelCtx).(model.File); !ok {
+		file.Name = NormalizeName(file.Name)
+		err = fs.applyPolicyHealth()
+	} else if !IsPolicyHealthy(originFile.PolicyID) && originFile.GetPolicy().OptionsSerialized.ReadonlyOnFailure {
+		err = ErrPolicyUnavailable
+	}
+
 	// 上传前的钩子
-	err = fs.Trigger(ctx, "BeforeUpload", file)
+	if err == nil {
+		err = fs.Trigger(ctx, "BeforeUpload", file)
+	}
 	if err != nil {
 		request.BlackHole(file)
 		return err
@@ -69,7 +81,7 @@ func (fs *FileSystem) Upload(ctx context.Context, file *fsctx.FileStream) (err e
 		followUpErr := fs.Trigger(ctx, "AfterValidateFailed", file)
 		// 失败后再失败...
 		if followUpErr != nil {
-			util.Log().Debug("AfterValidateFailed hook execution failed: %s", followUpErr)
+			util.LogCtx(ctx).Debug("AfterValidateFailed hook execution failed: %s", followUpErr)
 		}
 
 		return err
@@ -113,13 +125,13 @@ func (fs *FileSystem) CancelUpload(ctx context.Context, path string, file fsctx.
 			// 客户端正常关闭，不执行操作
 		default:
 			// 客户端取消上传，删除临时文件
-			util.Log().Debug("Client canceled upload.")
+			util.LogCtx(ctx).Debug("Client canceled upload.")
 			if fs.Hooks["AfterUploadCanceled"] == nil {
 				return
 			}
 			err := fs.Trigger(ctx, "AfterUploadCanceled", file)
 			if err != nil {
-				util.Log().Debug("AfterUploadCanceled hook execution failed: %s", err)
+				util.LogCtx(ctx).Debug("AfterUploadCanceled hook execution failed: %s", err)
 			}
 		}
 
@@ -142,6 +154,7 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 
 	fs.Use("BeforeUpload", HookValidateFile)
 	fs.Use("BeforeUpload", HookValidateCapacity)
+	fs.Use("BeforeUpload", HookValidateGroupLimits)
 
 	// 验证文件规格
 	if err := fs.Upload(ctx, file); err != nil {
@@ -158,6 +171,12 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 		SavePath:       file.SavePath,
 		LastModified:   file.LastModified,
 		CallbackSecret: util.RandStringRunes(32),
+		SpeedLimit:     fs.User.UploadSpeedLimit(),
+	}
+
+	// 本机存储策略的分片写入实例本地，后续分片需由同一实例处理
+	if fs.Policy.Type == "local" {
+		uploadSession.Instance = instance.ID()
 	}
 
 	// 获取上传凭证
@@ -168,6 +187,24 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 
 	// 创建占位符
 	if !fs.Policy.IsUploadPlaceholderWithSize() {
+		// 占位符不计入已用容量时，预留声明的文件大小，避免并发的上传会话共同超出容量
+		reserved, err := fs.User.ReserveStorage(fileSize)
+		if err != nil {
+			return nil, ErrDBListObjects.WithError(err)
+		}
+
+		if !reserved {
+			return nil, ErrInsufficientCapacity
+		}
+
+		if fileSize > 0 {
+			if file.Metadata == nil {
+				file.Metadata = make(map[string]string)
+			}
+			file.Metadata[model.UploadReservedMetadataKey] = strconv.FormatUint(fileSize, 10)
+			fs.Use("AfterValidateFailed", HookReleaseStorage(fileSize))
+		}
+
 		fs.Use("AfterUpload", HookClearFileHeaderSize)
 	}
 	fs.Use("AfterUpload", GenericAfterUpload)
@@ -208,6 +245,7 @@ func (fs *FileSystem) UploadFromStream(ctx context.Context, file *fsctx.FileStre
 	if fs.Hooks == nil {
 		fs.Use("BeforeUpload", HookValidateFile)
 		fs.Use("BeforeUpload", HookValidateCapacity)
+		fs.Use("BeforeUpload", HookValidateGroupLimits)
 		fs.Use("AfterUploadCanceled", HookDeleteTempFile)
 		fs.Use("AfterUpload", GenericAfterUpload)
 		fs.Use("AfterValidateFailed", HookDeleteTempFile)
@@ -3,8 +3,11 @@ package filesystem
 import (
 	"context"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"golang.org/x/text/unicode/norm"
 )
 
 /* ==========
@@ -15,6 +18,19 @@ import (
 // 文件/路径名保留字符
 var reservedCharacter = []string{"\\", "?", "*", "<", "\"", ":", ">", "/", "|"}
 
+// NormalizeName 规范化文件名/文件夹名：统一为 Unicode NFC 形式，去除控制字符，
+// 并去除 Windows 客户端无法处理的结尾空格和点
+func NormalizeName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(name))
+
+	return strings.TrimRight(name, " .")
+}
+
 // ValidateLegalName 验证文件名/文件夹名是否合法
 func (fs *FileSystem) ValidateLegalName(ctx context.Context, name string) bool {
 	// 是否包含保留字符
@@ -34,11 +50,33 @@ func (fs *FileSystem) ValidateLegalName(ctx context.Context, name string) bool {
 		return false
 	}
 
-	// 结尾不能是空格
-	if strings.HasSuffix(name, " ") {
+	// 结尾不能是空格或点
+	if strings.HasSuffix(name, " ") || strings.HasSuffix(name, ".") {
+		return false
+	}
+
+	// 不能包含控制字符
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
 		return false
 	}
 
+	if fs.Policy != nil {
+		// 存储策略的长度限制
+		if max := fs.Policy.OptionsSerialized.MaxNameLength; max > 0 && utf8.RuneCountInString(name) > max {
+			return false
+		}
+
+		// 存储策略的保留名称，与 Windows 一致，忽略大小写及扩展名
+		if len(fs.Policy.OptionsSerialized.ReservedNames) > 0 {
+			base := strings.ToLower(strings.SplitN(name, ".", 2)[0])
+			for _, reserved := range fs.Policy.OptionsSerialized.ReservedNames {
+				if base == strings.ToLower(reserved) {
+					return false
+				}
+			}
+		}
+	}
+
 	return true
 }
 
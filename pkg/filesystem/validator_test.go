@@ -41,6 +41,34 @@ func TestFileSystem_ValidateLegalName(t *testing.T) {
 	asserts.False(fs.ValidateLegalName(ctx, ""))
 	asserts.False(fs.ValidateLegalName(ctx, "1.tx t "))
 	asserts.True(fs.ValidateLegalName(ctx, "1.tx t"))
+	asserts.False(fs.ValidateLegalName(ctx, "1.txt."))
+	asserts.False(fs.ValidateLegalName(ctx, ".."))
+	asserts.False(fs.ValidateLegalName(ctx, "1\x00.txt"))
+
+	// 存储策略规则
+	fs.Policy = &model.Policy{OptionsSerialized: model.PolicyOption{
+		MaxNameLength: 6,
+		ReservedNames: []string{"CON", "nul"},
+	}}
+	asserts.True(fs.ValidateLegalName(ctx, "中文.txt"))
+	asserts.False(fs.ValidateLegalName(ctx, "中文中文.txt"))
+	asserts.False(fs.ValidateLegalName(ctx, "con"))
+	asserts.False(fs.ValidateLegalName(ctx, "NUL.md"))
+	asserts.True(fs.ValidateLegalName(ctx, "icon"))
+}
+
+func TestNormalizeName(t *testing.T) {
+	asserts := assert.New(t)
+
+	// NFD 转换为 NFC
+	asserts.Equal("caf\u00e9.txt", NormalizeName("cafe\u0301.txt"))
+	// 去除控制字符
+	asserts.Equal("report.txt", NormalizeName("re\x00po\trt.txt\n"))
+	// 去除结尾空格和点
+	asserts.Equal("report", NormalizeName("report. . "))
+	asserts.Equal("", NormalizeName(".."))
+	// 保留开头的点
+	asserts.Equal(".gitignore", NormalizeName(".gitignore"))
 }
 
 func TestFileSystem_ValidateCapacity(t *testing.T) {
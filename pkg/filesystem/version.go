@@ -0,0 +1,150 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// KeepVersion 覆盖 originFile 前调用，若其存储策略开启了历史版本保留，则将新内容
+// 写入新的源文件，上传完成后原内容记为历史版本，并替换上传失败时的处理钩子。
+// 应在分配其他钩子之后、将 originFile 存入上下文之前调用；有软链接的文件不应调用
+func (fs *FileSystem) KeepVersion(originFile *model.File, file *fsctx.FileStream) bool {
+	policy := originFile.GetPolicy()
+	if policy.OptionsSerialized.MaxVersions <= 0 || originFile.Size == 0 {
+		return false
+	}
+
+	previous := &model.FileVersion{
+		FileID:     originFile.ID,
+		UserID:     originFile.UserID,
+		PolicyID:   originFile.PolicyID,
+		SourceName: originFile.SourceName,
+		Size:       originFile.Size,
+	}
+
+	savePath := path.Join(path.Dir(previous.SourceName), policy.GenerateFileName(originFile.UserID, originFile.Name))
+	if savePath == previous.SourceName {
+		savePath = path.Join(path.Dir(savePath), util.RandStringRunes(16)+"_"+path.Base(savePath))
+	}
+	originFile.SourceName = savePath
+	file.Mode &= ^fsctx.Overwrite
+
+	// 原内容仍然保留，需要完整的新文件容量
+	fs.Use("BeforeUpload", func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+		if fs.User.GetRemainingCapacity() < file.Info().Size {
+			return ErrInsufficientCapacity
+		}
+		return nil
+	})
+	fs.Use("AfterUpload", HookArchiveVersion(previous, policy.OptionsSerialized.MaxVersions))
+
+	// 原内容未被改动，上传失败时只需删除新写入的内容
+	fs.CleanHooks("AfterUploadCanceled")
+	fs.CleanHooks("AfterValidateFailed")
+	fs.Use("AfterUploadFailed", HookDeleteTempFile)
+	fs.Use("AfterUploadCanceled", HookDeleteTempFile)
+	fs.Use("AfterUploadCanceled", HookCancelContext)
+	fs.Use("AfterValidateFailed", HookDeleteTempFile)
+	return true
+}
+
+// HookArchiveVersion 将文件指向新写入的内容，原内容记为历史版本，并清理超出保留数量的旧版本
+func HookArchiveVersion(previous *model.FileVersion, max int) Hook {
+	return func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+		if err := model.ArchiveFileVersion(previous, file.Info().SavePath); err != nil {
+			return ErrDBMoveObjects.WithError(err)
+		}
+
+		versions, err := model.GetFileVersions(previous.FileID)
+		if err != nil {
+			util.Log().Warning("Failed to list versions of file %d: %s", previous.FileID, err)
+			return nil
+		}
+
+		if len(versions) > max {
+			if err := fs.DeleteVersions(ctx, versions[max:]); err != nil {
+				util.Log().Warning("Failed to delete expired versions of file %d: %s", previous.FileID, err)
+			}
+		}
+
+		return nil
+	}
+}
+
+// RestoreVersion 将文件恢复为给定的历史版本，当前内容记为该历史版本
+func (fs *FileSystem) RestoreVersion(ctx context.Context, file *model.File, version *model.FileVersion) error {
+	unlock, err := fs.LockObjects(ctx, nil, []uint{file.ID})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := file.RestoreVersion(version); err != nil {
+		return ErrDBMoveObjects.WithError(err)
+	}
+
+	fs.emitChanges(ctx, fileChange(model.ChangeModify, file))
+	return nil
+}
+
+// DeleteVersions 删除历史版本及其内容，仍被其他文件引用的内容不会被删除
+func (fs *FileSystem) DeleteVersions(ctx context.Context, versions []model.FileVersion) error {
+	return fs.deleteVersions(ctx, versions, false)
+}
+
+// deleteVersions 删除历史版本，unlink 为 true 时只删除记录
+func (fs *FileSystem) deleteVersions(ctx context.Context, versions []model.FileVersion, unlink bool) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	unlock := model.LockSources()
+	defer unlock()
+
+	// 先删除记录，检查引用时不再计入待删除的历史版本
+	if err := model.DeleteFileVersions(versions); err != nil {
+		return ErrDBDeleteObjects.WithError(err)
+	}
+
+	if !unlink {
+		sources := make([]model.File, len(versions))
+		for i, version := range versions {
+			sources[i] = model.File{SourceName: version.SourceName, PolicyID: version.PolicyID}
+		}
+
+		sources, err := model.RemoveFilesWithSoftLinks(sources)
+		if err != nil {
+			return ErrDBListObjects.WithError(err)
+		}
+
+		for policyID, failed := range fs.deleteGroupedFile(ctx, fs.GroupFileByPolicy(ctx, sources)) {
+			if len(failed) > 0 {
+				util.LogCtx(ctx).Warning("Failed to delete %d version file(s) of policy %d.", len(failed), policyID)
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteFileVersions 删除已删除文件的全部历史版本
+func (fs *FileSystem) deleteFileVersions(ctx context.Context, files []*model.File, unlink bool) {
+	ids := make([]uint, len(files))
+	for i, file := range files {
+		ids[i] = file.ID
+	}
+
+	versions, err := model.GetFileVersionsByFileIDs(ids)
+	if err != nil {
+		util.LogCtx(ctx).Warning("Failed to list versions of deleted files: %s", err)
+		return
+	}
+
+	if err := fs.deleteVersions(ctx, versions, unlink); err != nil {
+		util.LogCtx(ctx).Warning("Failed to delete versions of deleted files: %s", err)
+	}
+}
@@ -0,0 +1,127 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_KeepVersion(t *testing.T) {
+	a := assert.New(t)
+
+	// 未开启历史版本
+	{
+		fs := &FileSystem{User: &model.User{}}
+		file := &model.File{Size: 10, SourceName: "a/b.txt", Policy: model.Policy{Model: gorm.Model{ID: 1}}}
+		a.False(fs.KeepVersion(file, &fsctx.FileStream{Mode: fsctx.Overwrite}))
+		a.Equal("a/b.txt", file.SourceName)
+		a.Empty(fs.Hooks)
+	}
+
+	// 空文件
+	{
+		fs := &FileSystem{User: &model.User{}}
+		file := &model.File{SourceName: "a/b.txt", Policy: model.Policy{Model: gorm.Model{ID: 1}}}
+		file.Policy.OptionsSerialized.MaxVersions = 2
+		a.False(fs.KeepVersion(file, &fsctx.FileStream{Mode: fsctx.Overwrite}))
+	}
+
+	// 开启历史版本
+	{
+		fs := &FileSystem{User: &model.User{}}
+		fs.Use("AfterUploadCanceled", HookClearFileSize)
+		fs.Use("AfterValidateFailed", HookClearFileSize)
+		file := &model.File{Name: "b.txt", Size: 10, SourceName: "a/b.txt", Policy: model.Policy{Model: gorm.Model{ID: 1}}}
+		file.Policy.OptionsSerialized.MaxVersions = 2
+		stream := &fsctx.FileStream{Mode: fsctx.Overwrite}
+		a.True(fs.KeepVersion(file, stream))
+		a.NotEqual("a/b.txt", file.SourceName)
+		a.Contains(file.SourceName, "a/")
+		a.Zero(stream.Mode & fsctx.Overwrite)
+		a.Len(fs.Hooks["BeforeUpload"], 1)
+		a.Len(fs.Hooks["AfterUpload"], 1)
+		a.Len(fs.Hooks["AfterUploadFailed"], 1)
+		a.Len(fs.Hooks["AfterUploadCanceled"], 2)
+		a.Len(fs.Hooks["AfterValidateFailed"], 1)
+	}
+}
+
+func TestHookArchiveVersion(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	file := &fsctx.FileStream{SavePath: "new"}
+
+	// 归档失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_versions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := HookArchiveVersion(&model.FileVersion{FileID: 1, UserID: 1, Size: 10}, 2)(context.Background(), fs, file)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Error(err)
+	}
+
+	// 归档成功，未超出保留数量
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		err := HookArchiveVersion(&model.FileVersion{FileID: 1, UserID: 1, Size: 10}, 2)(context.Background(), fs, file)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+}
+
+func TestFileSystem_DeleteVersions(t *testing.T) {
+	a := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+
+	// 无历史版本
+	{
+		a.NoError(fs.DeleteVersions(context.Background(), nil))
+	}
+
+	// 内容仍被其他文件引用，只删除记录
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name", "policy_id"}).AddRow(2, "a", 1))
+		err := fs.DeleteVersions(context.Background(), []model.FileVersion{{Model: gorm.Model{ID: 1}, UserID: 1, SourceName: "a", PolicyID: 1, Size: 10}})
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+
+	// 内容仍被其他历史版本引用，只删除记录
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_versions(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_versions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "source_name", "policy_id"}).AddRow(3, "a", 1))
+		err := fs.DeleteVersions(context.Background(), []model.FileVersion{{Model: gorm.Model{ID: 1}, UserID: 1, SourceName: "a", PolicyID: 1, Size: 10}})
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+	}
+
+	// 删除记录失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_versions(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := fs.DeleteVersions(context.Background(), []model.FileVersion{{Model: gorm.Model{ID: 1}, UserID: 1, SourceName: "a", PolicyID: 1, Size: 10}})
+		a.NoError(mock.ExpectationsWereMet())
+		a.ErrorIs(err, ErrDBDeleteObjects)
+	}
+}
@@ -0,0 +1,310 @@
+package ftp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// idleTimeout 控制连接的空闲超时
+	idleTimeout = 15 * time.Minute
+	// maxCommandLength 单条命令的最大长度
+	maxCommandLength = 4096
+)
+
+// conn 单个控制连接，命令按序处理
+type conn struct {
+	server *Server
+	ctrl   net.Conn
+	reader *bufio.Reader
+
+	user    string
+	account *account
+	cwd     string
+
+	secure    bool
+	protected bool
+	pasv      net.Listener
+
+	restOffset int64
+	renameFrom string
+}
+
+func newConn(server *Server, ctrl net.Conn) *conn {
+	return &conn{
+		server: server,
+		ctrl:   ctrl,
+		reader: bufio.NewReaderSize(ctrl, maxCommandLength),
+		cwd:    "/",
+	}
+}
+
+// reply 发送单行响应
+func (c *conn) reply(code int, msg string) {
+	fmt.Fprintf(c.ctrl, "%d %s\r\n", code, msg)
+}
+
+// replyLines 发送多行响应
+func (c *conn) replyLines(code int, first string, lines []string, last string) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d-%s\r\n", code, first)
+	for _, line := range lines {
+		b.WriteString(" " + line + "\r\n")
+	}
+	fmt.Fprintf(&b, "%d %s\r\n", code, last)
+	io.WriteString(c.ctrl, b.String())
+}
+
+// replyError 将文件系统错误转换为响应
+func (c *conn) replyError(err error) {
+	switch err {
+	case errNoSuchFile:
+		c.reply(550, "No such file or directory.")
+	case errPermissionDenied:
+		c.reply(550, "Permission denied.")
+	case filesystem.ErrInsufficientCapacity:
+		c.reply(552, "Insufficient storage space.")
+	default:
+		c.reply(550, err.Error())
+	}
+}
+
+func (c *conn) serve() {
+	defer c.close()
+	c.reply(220, "Cloudreve FTP server ready.")
+
+	for {
+		c.ctrl.SetReadDeadline(time.Now().Add(idleTimeout))
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			if err != io.EOF {
+				util.Log().Debug("FTP connection from %q closed: %s", c.ctrl.RemoteAddr(), err)
+			}
+			return
+		}
+
+		cmd, arg := parseCommand(line)
+		if cmd == "QUIT" {
+			c.reply(221, "Goodbye.")
+			return
+		}
+
+		c.handle(cmd, arg)
+	}
+}
+
+// close 关闭控制连接及未使用的被动模式监听
+func (c *conn) close() {
+	c.closePassive()
+	c.ctrl.Close()
+}
+
+// parseCommand 拆分命令与参数
+func parseCommand(line string) (string, string) {
+	line = strings.TrimRight(line, "\r\n")
+	parts := strings.SplitN(line, " ", 2)
+	cmd := strings.ToUpper(parts[0])
+	if len(parts) == 1 {
+		return cmd, ""
+	}
+	return cmd, parts[1]
+}
+
+// resolve 将参数转换为当前目录下的绝对路径，用户主目录即为根目录
+func (c *conn) resolve(arg string) string {
+	if strings.HasPrefix(arg, "/") {
+		return path.Clean(arg)
+	}
+	return path.Clean(path.Join(c.cwd, arg))
+}
+
+// handle 处理单条命令
+func (c *conn) handle(cmd, arg string) {
+	// 无需登录的命令
+	switch cmd {
+	case "USER":
+		c.user, c.account = arg, nil
+		c.reply(331, "Password required.")
+		return
+	case "PASS":
+		c.handlePass(arg)
+		return
+	case "AUTH":
+		c.handleAuth(arg)
+		return
+	case "PBSZ":
+		c.reply(200, "PBSZ=0")
+		return
+	case "PROT":
+		c.handleProt(arg)
+		return
+	case "FEAT":
+		c.handleFeat()
+		return
+	case "SYST":
+		c.reply(215, "UNIX Type: L8")
+		return
+	case "NOOP":
+		c.reply(200, "OK.")
+		return
+	case "OPTS":
+		if strings.EqualFold(arg, "UTF8 ON") {
+			c.reply(200, "UTF8 enabled.")
+		} else {
+			c.reply(501, "Option not understood.")
+		}
+		return
+	}
+
+	if c.account == nil {
+		c.reply(530, "Please login with USER and PASS.")
+		return
+	}
+
+	// REST 仅对紧随其后的传输命令生效
+	if cmd != "REST" && cmd != "RETR" && cmd != "STOR" {
+		c.restOffset = 0
+	}
+
+	switch cmd {
+	case "PWD", "XPWD":
+		c.reply(257, fmt.Sprintf("%q is the current directory.", c.cwd))
+	case "CWD", "XCWD":
+		c.handleCwd(c.resolve(arg))
+	case "CDUP", "XCUP":
+		c.handleCwd(path.Dir(c.cwd))
+	case "TYPE":
+		c.reply(200, "Type set to "+arg+".")
+	case "MODE":
+		c.handleOnly(arg, "S", "Mode")
+	case "STRU":
+		c.handleOnly(arg, "F", "Structure")
+	case "PASV":
+		c.handlePasv(false)
+	case "EPSV":
+		c.handlePasv(true)
+	case "PORT", "EPRT":
+		c.reply(502, "Active mode is not supported, please use passive mode.")
+	case "REST":
+		offset, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || offset < 0 {
+			c.reply(501, "Invalid offset.")
+			return
+		}
+		c.restOffset = offset
+		c.reply(350, fmt.Sprintf("Restarting at %d.", offset))
+	case "LIST", "NLST", "MLSD":
+		c.handleList(cmd, arg)
+	case "MLST":
+		c.handleMlst(c.resolve(arg))
+	case "SIZE":
+		c.handleSize(c.resolve(arg))
+	case "MDTM":
+		c.handleMdtm(c.resolve(arg))
+	case "RETR":
+		c.handleRetr(c.resolve(arg))
+	case "STOR", "APPE":
+		c.handleStor(c.resolve(arg), cmd == "APPE")
+	case "DELE":
+		c.handleDele(c.resolve(arg))
+	case "MKD", "XMKD":
+		c.handleMkd(c.resolve(arg))
+	case "RMD", "XRMD":
+		c.handleRmd(c.resolve(arg))
+	case "RNFR":
+		c.handleRnfr(c.resolve(arg))
+	case "RNTO":
+		c.handleRnto(c.resolve(arg))
+	default:
+		c.reply(502, "Command not implemented.")
+	}
+}
+
+func (c *conn) handlePass(arg string) {
+	if c.user == "" {
+		c.reply(503, "Login with USER first.")
+		return
+	}
+
+	account, err := authenticate(c.user, arg)
+	if err != nil {
+		c.reply(530, "Login incorrect.")
+		return
+	}
+
+	c.account = account
+	c.cwd = "/"
+	c.reply(230, "Login successful.")
+}
+
+func (c *conn) handleAuth(arg string) {
+	if c.server.tlsConfig == nil {
+		c.reply(502, "TLS is not configured.")
+		return
+	}
+
+	if mode := strings.ToUpper(arg); mode != "TLS" && mode != "SSL" {
+		c.reply(504, "Unsupported security mechanism.")
+		return
+	}
+
+	if c.secure {
+		c.reply(503, "Already using TLS.")
+		return
+	}
+
+	c.reply(234, "AUTH TLS successful.")
+	tlsConn := tls.Server(c.ctrl, c.server.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		util.Log().Debug("FTP TLS handshake with %q failed: %s", c.ctrl.RemoteAddr(), err)
+		c.ctrl.Close()
+		return
+	}
+
+	c.ctrl = tlsConn
+	c.reader = bufio.NewReaderSize(tlsConn, maxCommandLength)
+	c.secure = true
+}
+
+func (c *conn) handleProt(arg string) {
+	switch strings.ToUpper(arg) {
+	case "C":
+		c.protected = false
+		c.reply(200, "Protection level set to Clear.")
+	case "P":
+		if !c.secure {
+			c.reply(503, "PROT P requires AUTH TLS.")
+			return
+		}
+		c.protected = true
+		c.reply(200, "Protection level set to Private.")
+	default:
+		c.reply(504, "Unsupported protection level.")
+	}
+}
+
+func (c *conn) handleFeat() {
+	features := []string{"EPSV", "MDTM", "MLST type*;size*;modify*;", "PASV", "REST STREAM", "SIZE", "UTF8"}
+	if c.server.tlsConfig != nil {
+		features = append(features, "AUTH TLS", "PBSZ", "PROT")
+	}
+	c.replyLines(211, "Features:", features, "End")
+}
+
+func (c *conn) handleOnly(arg, supported, name string) {
+	if strings.EqualFold(arg, supported) {
+		c.reply(200, name+" set to "+supported+".")
+		return
+	}
+	c.reply(504, name+" not supported.")
+}
@@ -0,0 +1,177 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// dataTimeout 等待客户端建立数据连接的超时
+const dataTimeout = 30 * time.Second
+
+var errNoPassive = errors.New("use PASV or EPSV first")
+
+// parsePortRange 解析形如 "50000-50100" 的被动模式端口范围，为空时由系统分配
+func parsePortRange(value string) (int, int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, 0, nil
+	}
+
+	parts := strings.SplitN(value, "-", 2)
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := start
+	if len(parts) == 2 {
+		if end, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if start <= 0 || end > 65535 || start > end {
+		return 0, 0, fmt.Errorf("invalid port range %q", value)
+	}
+
+	return start, end, nil
+}
+
+// listenPassive 在被动模式端口范围内监听
+func listenPassive(host string) (net.Listener, error) {
+	start, end, err := parsePortRange(model.GetSettingByName("ftp_passive_ports"))
+	if err != nil {
+		return nil, err
+	}
+
+	if start == 0 {
+		return net.Listen("tcp", net.JoinHostPort(host, "0"))
+	}
+
+	// 从随机位置开始尝试，减少并发连接间的冲突
+	count := end - start + 1
+	offset := rand.Intn(count)
+	for i := 0; i < count; i++ {
+		port := start + (offset+i)%count
+		listener, err := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+		if err == nil {
+			return listener, nil
+		}
+	}
+
+	return nil, errors.New("no available passive port")
+}
+
+// closePassive 关闭被动模式监听
+func (c *conn) closePassive() {
+	if c.pasv != nil {
+		c.pasv.Close()
+		c.pasv = nil
+	}
+}
+
+func (c *conn) handlePasv(extended bool) {
+	c.closePassive()
+
+	localIP := c.ctrl.LocalAddr().(*net.TCPAddr).IP
+	listener, err := listenPassive(localIP.String())
+	if err != nil {
+		c.reply(425, "Cannot open passive connection: "+err.Error())
+		return
+	}
+
+	c.pasv = listener
+	port := listener.Addr().(*net.TCPAddr).Port
+	if extended {
+		c.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|).", port))
+		return
+	}
+
+	// PASV 只能返回 IPv4 地址，可在设置中指定对外的地址
+	ip := localIP.To4()
+	if publicHost := model.GetSettingByName("ftp_public_host"); publicHost != "" {
+		ip = nil
+		if addrs, err := net.LookupIP(publicHost); err == nil {
+			for _, addr := range addrs {
+				if ip = addr.To4(); ip != nil {
+					break
+				}
+			}
+		}
+	}
+
+	if ip == nil {
+		c.closePassive()
+		c.reply(425, "No IPv4 address available, please use EPSV.")
+		return
+	}
+
+	c.reply(227, fmt.Sprintf("Entering Passive Mode (%d,%d,%d,%d,%d,%d).", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xff))
+}
+
+// openData 接受客户端的数据连接，PROT P 时使用 TLS
+func (c *conn) openData() (net.Conn, error) {
+	if c.pasv == nil {
+		return nil, errNoPassive
+	}
+	defer c.closePassive()
+
+	if tcp, ok := c.pasv.(*net.TCPListener); ok {
+		tcp.SetDeadline(time.Now().Add(dataTimeout))
+	}
+
+	dataConn, err := c.pasv.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	// 数据连接须来自控制连接的客户端
+	remote := dataConn.RemoteAddr().(*net.TCPAddr).IP
+	if !remote.Equal(c.ctrl.RemoteAddr().(*net.TCPAddr).IP) {
+		dataConn.Close()
+		return nil, errors.New("data connection from unexpected address")
+	}
+
+	if c.protected {
+		tlsConn := tls.Server(dataConn, c.server.tlsConfig)
+		if err := tlsConn.Handshake(); err != nil {
+			dataConn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+
+	return dataConn, nil
+}
+
+// transfer 建立数据连接并执行 fn，完成后关闭连接并回复结果
+func (c *conn) transfer(fn func(dataConn net.Conn) error) {
+	if c.pasv == nil {
+		c.reply(425, "Use PASV or EPSV first.")
+		return
+	}
+
+	c.reply(150, "Opening data connection.")
+	dataConn, err := c.openData()
+	if err != nil {
+		c.reply(425, "Cannot open data connection: "+err.Error())
+		return
+	}
+
+	err = fn(dataConn)
+	dataConn.Close()
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+
+	c.reply(226, "Transfer complete.")
+}
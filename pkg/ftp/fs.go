@@ -0,0 +1,578 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+var (
+	errNoSuchFile       = errors.New("no such file or directory")
+	errPermissionDenied = errors.New("permission denied")
+	errFileExisted      = errors.New("file already exists")
+	errNotEmpty         = errors.New("directory not empty")
+)
+
+// entry 目录列表中的一项
+type entry struct {
+	name    string
+	isDir   bool
+	size    uint64
+	modTime time.Time
+}
+
+func folderEntry(folder *model.Folder) entry {
+	return entry{name: folder.Name, isDir: true, modTime: folder.UpdatedAt}
+}
+
+func fileEntry(file *model.File) entry {
+	return entry{name: file.Name, size: file.Size, modTime: file.UpdatedAt}
+}
+
+// listLine 生成 ls -l 格式的列表行
+func (e entry) listLine(readonly bool) string {
+	mode := "-rw-r--r--"
+	if e.isDir {
+		mode = "drwxr-xr-x"
+	}
+	if readonly {
+		mode = strings.ReplaceAll(mode, "w", "-")
+	}
+
+	layout := "Jan _2 15:04"
+	if e.modTime.Before(time.Now().AddDate(0, -6, 0)) {
+		layout = "Jan _2  2006"
+	}
+
+	return fmt.Sprintf("%s 1 cloudreve cloudreve %12d %s %s", mode, e.size, e.modTime.Format(layout), e.name)
+}
+
+// facts 生成 MLSD、MLST 格式的描述
+func (e entry) facts() string {
+	typ := "file"
+	if e.isDir {
+		typ = "dir"
+	}
+
+	return fmt.Sprintf("type=%s;size=%d;modify=%s; %s", typ, e.size, e.modTime.UTC().Format("20060102150405"), e.name)
+}
+
+// newFS 为当前用户初始化文件系统，每次操作重新读取用户以获取最新的容量及状态
+func (c *conn) newFS() (*filesystem.FileSystem, error) {
+	user, err := model.GetActiveUserByID(c.account.uid)
+	if err != nil {
+		return nil, errPermissionDenied
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		fs.Recycle()
+		return nil, err
+	}
+
+	// 重定根目录，根目录已不存在时拒绝访问
+	if err := fs.UseRoot(c.account.root); err != nil {
+		fs.Recycle()
+		return nil, errPermissionDenied
+	}
+
+	return fs, nil
+}
+
+// withFS 使用新的文件系统执行 fn，出错时回复错误
+func (c *conn) withFS(fn func(fs *filesystem.FileSystem) error) bool {
+	fs, err := c.newFS()
+	if err != nil {
+		c.replyError(err)
+		return false
+	}
+	defer fs.Recycle()
+
+	if err := fn(fs); err != nil {
+		c.replyError(err)
+		return false
+	}
+
+	return true
+}
+
+// writable 检查账户是否可写，不可写时回复错误
+func (c *conn) writable() bool {
+	if c.account.readonly {
+		c.replyError(errPermissionDenied)
+		return false
+	}
+	return true
+}
+
+func (c *conn) handleCwd(p string) {
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		if ok, _ := fs.IsPathExist(p); !ok {
+			return errNoSuchFile
+		}
+		return nil
+	}) {
+		c.cwd = p
+		c.reply(250, "Directory changed to "+p+".")
+	}
+}
+
+// listEntries 列出路径下的对象，路径为文件时只返回其自身
+func listEntries(fs *filesystem.FileSystem, p string) ([]entry, error) {
+	ok, folder := fs.IsPathExist(p)
+	if !ok {
+		if ok, file := fs.IsFileExist(p); ok {
+			return []entry{fileEntry(file)}, nil
+		}
+		return nil, errNoSuchFile
+	}
+
+	folders, err := folder.GetChildFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(folders)+len(files))
+	for i := range folders {
+		entries = append(entries, folderEntry(&folders[i]))
+	}
+	for i := range files {
+		entries = append(entries, fileEntry(&files[i]))
+	}
+
+	return entries, nil
+}
+
+func (c *conn) handleList(cmd, arg string) {
+	// 忽略 ls 风格的选项，如 LIST -la
+	fields := strings.Fields(arg)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "-") {
+		fields = fields[1:]
+	}
+	p := c.resolve(strings.Join(fields, " "))
+
+	var entries []entry
+	if !c.withFS(func(fs *filesystem.FileSystem) (err error) {
+		entries, err = listEntries(fs, p)
+		return err
+	}) {
+		return
+	}
+
+	c.transfer(func(dataConn net.Conn) error {
+		var b strings.Builder
+		for _, e := range entries {
+			switch cmd {
+			case "NLST":
+				b.WriteString(e.name)
+			case "MLSD":
+				b.WriteString(e.facts())
+			default:
+				b.WriteString(e.listLine(c.account.readonly))
+			}
+			b.WriteString("\r\n")
+		}
+
+		_, err := io.WriteString(dataConn, b.String())
+		return err
+	})
+}
+
+// stat 查找路径对应的对象
+func stat(fs *filesystem.FileSystem, p string) (entry, error) {
+	if ok, folder := fs.IsPathExist(p); ok {
+		e := folderEntry(folder)
+		e.name = path.Base(p)
+		return e, nil
+	}
+	if ok, file := fs.IsFileExist(p); ok {
+		return fileEntry(file), nil
+	}
+	return entry{}, errNoSuchFile
+}
+
+func (c *conn) handleMlst(p string) {
+	var e entry
+	if c.withFS(func(fs *filesystem.FileSystem) (err error) {
+		e, err = stat(fs, p)
+		return err
+	}) {
+		e.name = p
+		c.replyLines(250, "Listing "+p, []string{e.facts()}, "End")
+	}
+}
+
+func (c *conn) handleSize(p string) {
+	var e entry
+	if c.withFS(func(fs *filesystem.FileSystem) (err error) {
+		e, err = stat(fs, p)
+		return err
+	}) {
+		if e.isDir {
+			c.reply(550, "Not a regular file.")
+			return
+		}
+		c.reply(213, fmt.Sprintf("%d", e.size))
+	}
+}
+
+func (c *conn) handleMdtm(p string) {
+	var e entry
+	if c.withFS(func(fs *filesystem.FileSystem) (err error) {
+		e, err = stat(fs, p)
+		return err
+	}) {
+		c.reply(213, e.modTime.UTC().Format("20060102150405"))
+	}
+}
+
+func (c *conn) handleRetr(p string) {
+	offset := c.restOffset
+	c.restOffset = 0
+
+	fs, err := c.newFS()
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	defer fs.Recycle()
+
+	ok, file := fs.IsFileExist(p)
+	if !ok {
+		c.replyError(errNoSuchFile)
+		return
+	}
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(context.Background(), 0)
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	defer rs.Close()
+
+	if offset > 0 {
+		if _, err := rs.Seek(offset, io.SeekStart); err != nil {
+			c.replyError(err)
+			return
+		}
+	}
+
+	c.transfer(func(dataConn net.Conn) error {
+		_, err := io.Copy(dataConn, rs)
+		return err
+	})
+}
+
+func (c *conn) handleStor(p string, appendMode bool) {
+	offset := c.restOffset
+	c.restOffset = 0
+	if !c.writable() {
+		return
+	}
+
+	fs, err := c.newFS()
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	defer fs.Recycle()
+
+	if ok, _ := fs.IsPathExist(p); ok {
+		c.replyError(errFileExisted)
+		return
+	}
+
+	exist, file := fs.IsFileExist(p)
+	if !exist {
+		if ok, _ := fs.IsPathExist(path.Dir(p)); !ok {
+			c.replyError(errNoSuchFile)
+			return
+		}
+	}
+
+	tempDir := filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "ftp")
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		c.replyError(err)
+		return
+	}
+
+	temp, err := ioutil.TempFile(tempDir, "upload_")
+	if err != nil {
+		c.replyError(err)
+		return
+	}
+	defer func() {
+		temp.Close()
+		os.Remove(temp.Name())
+	}()
+
+	// 续传或追加时以原有内容为基础
+	limit := fs.User.GetRemainingCapacity()
+	if exist {
+		limit += file.Size
+		if appendMode || offset > 0 {
+			if err := copyContent(fs, file, temp); err != nil {
+				c.replyError(err)
+				return
+			}
+			if offset > 0 {
+				if err := temp.Truncate(offset); err != nil {
+					c.replyError(err)
+					return
+				}
+			}
+		}
+	}
+
+	if _, err := temp.Seek(0, io.SeekEnd); err != nil {
+		c.replyError(err)
+		return
+	}
+
+	c.transfer(func(dataConn net.Conn) error {
+		written, err := temp.Stat()
+		if err != nil {
+			return err
+		}
+
+		n, err := io.Copy(temp, io.LimitReader(dataConn, int64(limit)-written.Size()+1))
+		if err != nil {
+			return err
+		}
+		if uint64(written.Size()+n) > limit {
+			return filesystem.ErrInsufficientCapacity
+		}
+
+		if _, err := temp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		return c.upload(p, temp, uint64(written.Size()+n))
+	})
+}
+
+// copyContent 将文件内容复制到 dst
+func copyContent(fs *filesystem.FileSystem, file *model.File, dst io.Writer) error {
+	fs.SetTargetFile(&[]model.File{*file})
+	defer fs.CleanTargets()
+
+	rs, err := fs.GetDownloadContent(context.Background(), 0)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	_, err = io.Copy(dst, rs)
+	return err
+}
+
+// upload 将 content 上传至 fullPath，已存在的文件将被覆盖
+func (c *conn) upload(fullPath string, content io.Reader, size uint64) error {
+	fs, err := c.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	fileData := fsctx.FileStream{
+		File:        ioutil.NopCloser(content),
+		Size:        size,
+		Name:        path.Base(fullPath),
+		VirtualPath: path.Dir(fullPath),
+	}
+
+	// 判断文件是否已存在
+	exist, originFile := fs.IsFileExist(fullPath)
+	if exist {
+		// 检查此文件是否有软链接
+		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
+		if err == nil && len(fileList) == 0 {
+			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
+			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
+			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
+		}
+
+		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
+		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
+		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
+		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		// 保留历史版本时新内容写入新的源文件
+		if len(fileList) > 0 {
+			fs.KeepVersion(originFile, &fileData)
+		}
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
+		fileData.Mode |= fsctx.Overwrite
+	} else {
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		fs.Use("BeforeUpload", filesystem.HookValidateGroupLimits)
+		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
+		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
+	}
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
+
+	return fs.Upload(ctx, &fileData)
+}
+
+func (c *conn) handleDele(p string) {
+	if !c.writable() {
+		return
+	}
+
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		ok, file := fs.IsFileExist(p)
+		if !ok {
+			return errNoSuchFile
+		}
+		return fs.Trash(context.Background(), []uint{}, []uint{file.ID})
+	}) {
+		c.reply(250, "File deleted.")
+	}
+}
+
+func (c *conn) handleMkd(p string) {
+	if !c.writable() {
+		return
+	}
+
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		if _, err := stat(fs, p); err == nil {
+			return errFileExisted
+		}
+		if ok, _ := fs.IsPathExist(path.Dir(p)); !ok {
+			return errNoSuchFile
+		}
+		_, err := fs.CreateDirectory(context.Background(), p)
+		return err
+	}) {
+		c.reply(257, fmt.Sprintf("%q created.", p))
+	}
+}
+
+func (c *conn) handleRmd(p string) {
+	if !c.writable() {
+		return
+	}
+
+	if p == "/" {
+		c.replyError(errPermissionDenied)
+		return
+	}
+
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		ok, folder := fs.IsPathExist(p)
+		if !ok {
+			return errNoSuchFile
+		}
+
+		// 只删除空目录
+		if folders, err := folder.GetChildFolder(); err != nil || len(folders) > 0 {
+			return errNotEmpty
+		}
+		if files, err := folder.GetChildFiles(); err != nil || len(files) > 0 {
+			return errNotEmpty
+		}
+
+		return fs.Trash(context.Background(), []uint{folder.ID}, []uint{})
+	}) {
+		c.reply(250, "Directory removed.")
+	}
+}
+
+func (c *conn) handleRnfr(p string) {
+	c.renameFrom = ""
+	if !c.writable() {
+		return
+	}
+
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		_, err := stat(fs, p)
+		return err
+	}) {
+		c.renameFrom = p
+		c.reply(350, "Ready for RNTO.")
+	}
+}
+
+func (c *conn) handleRnto(dst string) {
+	src := c.renameFrom
+	c.renameFrom = ""
+	if src == "" {
+		c.reply(503, "Use RNFR first.")
+		return
+	}
+
+	if src == "/" || dst == "/" {
+		c.replyError(errPermissionDenied)
+		return
+	}
+
+	if c.withFS(func(fs *filesystem.FileSystem) error {
+		var (
+			fileIDs   []uint
+			folderIDs []uint
+		)
+		if ok, folder := fs.IsPathExist(src); ok {
+			folderIDs = []uint{folder.ID}
+		} else if ok, file := fs.IsFileExist(src); ok {
+			fileIDs = []uint{file.ID}
+		} else {
+			return errNoSuchFile
+		}
+
+		if _, err := stat(fs, dst); err == nil {
+			return errFileExisted
+		}
+
+		ctx := context.Background()
+		if path.Dir(src) != path.Dir(dst) {
+			return fs.Move(
+				context.WithValue(ctx, fsctx.WebdavDstName, path.Base(dst)),
+				folderIDs,
+				fileIDs,
+				path.Dir(src),
+				path.Dir(dst),
+			)
+		}
+
+		return fs.Rename(ctx, folderIDs, fileIDs, path.Base(dst))
+	}) {
+		c.reply(250, "Rename successful.")
+	}
+}
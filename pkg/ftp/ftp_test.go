@@ -0,0 +1,194 @@
+package ftp
+
+import (
+	"bufio"
+	"database/sql"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+// client 测试用的控制连接客户端
+type client struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newClient(t *testing.T, c *conn) *client {
+	server, clientConn := net.Pipe()
+	c.ctrl = server
+	c.reader = bufio.NewReader(server)
+	go c.serve()
+
+	cl := &client{t: t, conn: clientConn, reader: bufio.NewReader(clientConn)}
+	assert.True(t, strings.HasPrefix(cl.read(), "220 "))
+	return cl
+}
+
+// read 读取一条完整的响应
+func (cl *client) read() string {
+	cl.conn.SetReadDeadline(time.Now().Add(time.Second))
+	line, err := cl.reader.ReadString('\n')
+	assert.NoError(cl.t, err)
+	res := line
+
+	// 多行响应以 "xxx " 结束
+	if len(line) > 3 && line[3] == '-' {
+		for {
+			next, err := cl.reader.ReadString('\n')
+			assert.NoError(cl.t, err)
+			res += next
+			if strings.HasPrefix(next, line[:3]+" ") {
+				break
+			}
+		}
+	}
+	return res
+}
+
+func (cl *client) cmd(line string) string {
+	cl.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_, err := cl.conn.Write([]byte(line + "\r\n"))
+	assert.NoError(cl.t, err)
+	return cl.read()
+}
+
+func TestConn_Anonymous(t *testing.T) {
+	asserts := assert.New(t)
+	cl := newClient(t, newConn(&Server{}, nil))
+	defer cl.conn.Close()
+
+	asserts.True(strings.HasPrefix(cl.cmd("SYST"), "215 "))
+	asserts.True(strings.HasPrefix(cl.cmd("NOOP"), "200 "))
+	asserts.True(strings.HasPrefix(cl.cmd("opts utf8 on"), "200 "))
+
+	// 未配置证书
+	feat := cl.cmd("FEAT")
+	asserts.True(strings.HasPrefix(feat, "211-"))
+	asserts.Contains(feat, " EPSV\r\n")
+	asserts.NotContains(feat, "AUTH TLS")
+	asserts.True(strings.HasPrefix(cl.cmd("AUTH TLS"), "502 "))
+	asserts.True(strings.HasPrefix(cl.cmd("PROT P"), "503 "))
+
+	// 未登录
+	asserts.True(strings.HasPrefix(cl.cmd("LIST"), "530 "))
+	asserts.True(strings.HasPrefix(cl.cmd("PASS 123"), "503 "))
+
+	// 用户不存在
+	mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	asserts.True(strings.HasPrefix(cl.cmd("USER who@cloudreve.org"), "331 "))
+	asserts.True(strings.HasPrefix(cl.cmd("PASS 123"), "530 "))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	asserts.True(strings.HasPrefix(cl.cmd("QUIT"), "221 "))
+}
+
+func TestConn_Readonly(t *testing.T) {
+	asserts := assert.New(t)
+	c := newConn(&Server{}, nil)
+	c.account = &account{uid: 1, readonly: true}
+	cl := newClient(t, c)
+	defer cl.conn.Close()
+
+	asserts.Equal("257 \"/\" is the current directory.\r\n", cl.cmd("PWD"))
+	asserts.True(strings.HasPrefix(cl.cmd("TYPE I"), "200 "))
+	asserts.True(strings.HasPrefix(cl.cmd("MODE S"), "200 "))
+	asserts.True(strings.HasPrefix(cl.cmd("MODE B"), "504 "))
+	asserts.True(strings.HasPrefix(cl.cmd("PORT 127,0,0,1,4,1"), "502 "))
+	asserts.True(strings.HasPrefix(cl.cmd("SITE CHMOD 777 a"), "502 "))
+	asserts.True(strings.HasPrefix(cl.cmd("REST abc"), "501 "))
+	asserts.True(strings.HasPrefix(cl.cmd("REST 10"), "350 "))
+	asserts.EqualValues(10, c.restOffset)
+
+	// 只读账户无法修改
+	asserts.Equal("550 Permission denied.\r\n", cl.cmd("DELE a.txt"))
+	asserts.Equal("550 Permission denied.\r\n", cl.cmd("MKD a"))
+	asserts.Equal("550 Permission denied.\r\n", cl.cmd("RMD a"))
+	asserts.Equal("550 Permission denied.\r\n", cl.cmd("STOR a.txt"))
+	asserts.Equal("550 Permission denied.\r\n", cl.cmd("RNFR a.txt"))
+	asserts.True(strings.HasPrefix(cl.cmd("RNTO b.txt"), "503 "))
+	asserts.EqualValues(0, c.restOffset)
+}
+
+func TestParseCommand(t *testing.T) {
+	asserts := assert.New(t)
+
+	cmd, arg := parseCommand("retr my file.txt\r\n")
+	asserts.Equal("RETR", cmd)
+	asserts.Equal("my file.txt", arg)
+
+	cmd, arg = parseCommand("PWD\n")
+	asserts.Equal("PWD", cmd)
+	asserts.Equal("", arg)
+}
+
+func TestConn_Resolve(t *testing.T) {
+	asserts := assert.New(t)
+	c := &conn{cwd: "/a/b"}
+
+	asserts.Equal("/a/b", c.resolve(""))
+	asserts.Equal("/a/b/c", c.resolve("c"))
+	asserts.Equal("/a", c.resolve(".."))
+	asserts.Equal("/", c.resolve("../../.."))
+	asserts.Equal("/c", c.resolve("/c/"))
+}
+
+func TestParsePortRange(t *testing.T) {
+	asserts := assert.New(t)
+
+	start, end, err := parsePortRange("")
+	asserts.NoError(err)
+	asserts.Equal(0, start)
+	asserts.Equal(0, end)
+
+	start, end, err = parsePortRange(" 50000 - 50100 ")
+	asserts.NoError(err)
+	asserts.Equal(50000, start)
+	asserts.Equal(50100, end)
+
+	start, end, err = parsePortRange("2121")
+	asserts.NoError(err)
+	asserts.Equal(2121, start)
+	asserts.Equal(2121, end)
+
+	for _, value := range []string{"a-b", "100-1", "0-10", "1-70000", "1-x"} {
+		_, _, err = parsePortRange(value)
+		asserts.Error(err, value)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	asserts := assert.New(t)
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	file := entry{name: "a.txt", size: 10, modTime: modTime}
+	asserts.Equal("-rw-r--r-- 1 cloudreve cloudreve           10 Jan  2  2022 a.txt", file.listLine(false))
+	asserts.Equal("-r--r--r-- 1 cloudreve cloudreve           10 Jan  2  2022 a.txt", file.listLine(true))
+	asserts.Equal("type=file;size=10;modify=20220102030405; a.txt", file.facts())
+
+	dir := entry{name: "b", isDir: true, modTime: time.Now()}
+	asserts.True(strings.HasPrefix(dir.listLine(false), "drwxr-xr-x "))
+	asserts.True(strings.HasPrefix(dir.facts(), "type=dir;"))
+}
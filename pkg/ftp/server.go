@@ -0,0 +1,88 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/graceful"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ErrAuthFailed 用户名或密码错误
+var ErrAuthFailed = errors.New("invalid username or password")
+
+// Server 内置 FTP/FTPS 服务端，用户名为账户邮箱，密码为 WebDAV 应用密码，
+// 未开启两步验证的账户也可使用登录密码。用户组需开启 WebDAV。
+// 配置证书后支持通过 AUTH TLS 升级为显式 FTPS
+type Server struct {
+	tlsConfig *tls.Config
+}
+
+// NewServer 新建服务端，certPath 为空时不支持 FTPS
+func NewServer(certPath, keyPath string) (*Server, error) {
+	server := &Server{}
+	if certPath != "" {
+		cert, err := tls.LoadX509KeyPair(util.RelativePath(certPath), util.RelativePath(keyPath))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+
+		server.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	return server, nil
+}
+
+// account 认证通过的账户
+type account struct {
+	uid      uint
+	root     string
+	readonly bool
+}
+
+// authenticate 验证账户邮箱及密码
+func authenticate(email, password string) (*account, error) {
+	user, err := model.GetActiveUserByEmail(email)
+	if err != nil || !user.Group.WebDAVEnabled {
+		return nil, ErrAuthFailed
+	}
+
+	if webdav, err := model.GetWebdavByPassword(password, user.ID); err == nil {
+		return &account{uid: user.ID, root: webdav.Root, readonly: webdav.Readonly}, nil
+	}
+
+	// 开启两步验证的账户只能使用应用密码
+	if user.TwoFactor == "" {
+		if ok, _ := user.CheckPassword(password); ok {
+			return &account{uid: user.ID}, nil
+		}
+	}
+
+	return nil, ErrAuthFailed
+}
+
+// ListenAndServe 监听 addr 并处理连接
+func (server *Server) ListenAndServe(addr string) error {
+	listener, err := graceful.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(listener)
+}
+
+// Serve 处理 listener 上的连接
+func (server *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go newConn(server, conn).serve()
+	}
+}
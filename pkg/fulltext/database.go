@@ -0,0 +1,37 @@
+package fulltext
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// maxDatabaseContent 数据库后端保存的文本内容的最大字节数，与 MySQL TEXT 类型的容量一致
+const maxDatabaseContent = 65535
+
+// DatabaseIndexer 将文本内容保存在数据库中，以模糊匹配搜索的内置后端，无需额外部署
+type DatabaseIndexer struct{}
+
+// NewDatabaseIndexer 新建数据库索引后端
+func NewDatabaseIndexer() *DatabaseIndexer {
+	return &DatabaseIndexer{}
+}
+
+// Index 写入或替换文件的文本内容
+func (indexer *DatabaseIndexer) Index(ctx context.Context, doc *Document) error {
+	return model.SaveFileContent(&model.FileContent{
+		FileID:  doc.FileID,
+		UserID:  doc.UserID,
+		Content: truncate(doc.Content, maxDatabaseContent),
+	})
+}
+
+// Delete 删除文件的文本内容
+func (indexer *DatabaseIndexer) Delete(ctx context.Context, ids []uint) error {
+	return model.DeleteFileContents(ids)
+}
+
+// Search 搜索内容包含全部关键词的文件，按索引时间从新到旧排序
+func (indexer *DatabaseIndexer) Search(ctx context.Context, uid uint, keywords []string, limit int) ([]uint, error) {
+	return model.SearchFileContents(uid, keywords, limit)
+}
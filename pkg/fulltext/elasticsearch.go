@@ -0,0 +1,145 @@
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// ElasticsearchIndexer 使用 Elasticsearch 的索引后端
+type ElasticsearchIndexer struct {
+	IndexName string
+	Client    request.Client
+	header    http.Header
+}
+
+// esDocument 索引中的文件文档
+type esDocument struct {
+	UserID  uint   `json:"user_id"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// esSearchResult 搜索结果
+type esSearchResult struct {
+	Hits struct {
+		Hits []struct {
+			ID string `json:"_id"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// NewElasticsearchIndexer 新建 Elasticsearch 索引后端，user 为空时不进行认证
+func NewElasticsearchIndexer(endpoint, index, user, password string) *ElasticsearchIndexer {
+	header := http.Header{"Content-Type": {"application/json"}}
+	if user != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user+":"+password)))
+	}
+
+	return &ElasticsearchIndexer{
+		IndexName: index,
+		Client:    request.NewClient(request.WithEndpoint(endpoint), request.WithTimeout(30*time.Second)),
+		header:    header,
+	}
+}
+
+// Index 写入或替换文件的文档
+func (indexer *ElasticsearchIndexer) Index(ctx context.Context, doc *Document) error {
+	return indexer.do(ctx, "PUT", "_doc/"+strconv.FormatUint(uint64(doc.FileID), 10), &esDocument{
+		UserID:  doc.UserID,
+		Name:    doc.Name,
+		Content: doc.Content,
+	}, nil)
+}
+
+// Delete 删除文件的文档
+func (indexer *ElasticsearchIndexer) Delete(ctx context.Context, ids []uint) error {
+	values := make([]string, len(ids))
+	for i, id := range ids {
+		values[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	return indexer.do(ctx, "POST", "_delete_by_query", map[string]interface{}{
+		"query": map[string]interface{}{
+			"ids": map[string]interface{}{"values": values},
+		},
+	}, nil)
+}
+
+// Search 搜索内容包含全部关键词的文件，按相关度排序
+func (indexer *ElasticsearchIndexer) Search(ctx context.Context, uid uint, keywords []string, limit int) ([]uint, error) {
+	var res esSearchResult
+	if err := indexer.do(ctx, "POST", "_search", map[string]interface{}{
+		"size":    limit,
+		"_source": false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"user_id": uid}},
+				},
+				"must": []interface{}{
+					map[string]interface{}{"match": map[string]interface{}{
+						"content": map[string]interface{}{
+							"query":    strings.Join(keywords, " "),
+							"operator": "and",
+						},
+					}},
+				},
+			},
+		},
+	}, &res); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(res.Hits.Hits))
+	for _, hit := range res.Hits.Hits {
+		if id, err := strconv.ParseUint(hit.ID, 10, 64); err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+
+	return ids, nil
+}
+
+// do 发送请求，res 不为 nil 时解析响应正文
+func (indexer *ElasticsearchIndexer) do(ctx context.Context, method, api string, body, res interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	resp := indexer.Client.Request(method, url.PathEscape(indexer.IndexName)+"/"+api, bytes.NewReader(data),
+		request.WithContext(ctx),
+		request.WithHeader(indexer.header),
+		request.WithContentLength(int64(len(data))),
+	)
+	if resp.Err != nil {
+		return resp.Err
+	}
+	defer resp.Response.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Response.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.Response.StatusCode < 200 || resp.Response.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch returned status %d: %s", resp.Response.StatusCode, respBody)
+	}
+
+	if res != nil {
+		return json.Unmarshal(respBody, res)
+	}
+
+	return nil
+}
@@ -0,0 +1,322 @@
+package fulltext
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxContentLength 提取的文本内容的最大长度
+const maxContentLength = 1 << 20
+
+var (
+	// ErrUnsupportedType 不支持提取内容的文件类型
+	ErrUnsupportedType = errors.New("unsupported file type")
+
+	// textExts 按纯文本读取的扩展名
+	textExts = []string{".txt", ".md", ".csv", ".log", ".json", ".xml", ".ini", ".conf"}
+
+	// pdfStream 匹配 PDF 中的流对象，第一组为流字典
+	pdfStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n(.*?)\r?\nendstream`)
+)
+
+// Supported 是否支持提取文件的文本内容
+func Supported(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	switch ext {
+	case ".docx", ".pdf":
+		return true
+	}
+
+	for _, textExt := range textExts {
+		if ext == textExt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Extract 从文件内容中提取文本，支持纯文本、docx 及未加密的 pdf
+func Extract(name string, r io.Reader) (string, error) {
+	if !Supported(name) {
+		return "", ErrUnsupportedType
+	}
+
+	var (
+		content string
+		err     error
+	)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".docx":
+		content, err = extractDocx(r)
+	case ".pdf":
+		content, err = extractPDF(r)
+	default:
+		var data []byte
+		data, err = ioutil.ReadAll(io.LimitReader(r, maxContentLength))
+		content = string(data)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return truncate(strings.ToValidUTF8(content, ""), maxContentLength), nil
+}
+
+// extractDocx 提取 docx 文档 word/document.xml 中的文字，段落间以换行分隔
+func extractDocx(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+
+	for _, f := range archive.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+
+		doc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer doc.Close()
+
+		var (
+			builder strings.Builder
+			inText  bool
+		)
+		decoder := xml.NewDecoder(doc)
+		for builder.Len() < maxContentLength {
+			token, err := decoder.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "t":
+					inText = true
+				case "tab":
+					builder.WriteString("\t")
+				case "br":
+					builder.WriteString("\n")
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					builder.WriteString("\n")
+				}
+			case xml.CharData:
+				if inText {
+					builder.Write(t)
+				}
+			}
+		}
+
+		return builder.String(), nil
+	}
+
+	return "", errors.New("word/document.xml not found")
+}
+
+// extractPDF 提取 pdf 内容流中由 Tj、TJ 等文本操作符输出的字符串，
+// 不处理字体编码映射，仅适用于使用标准编码的文本
+func extractPDF(r io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return "", errors.New("invalid pdf header")
+	}
+
+	var builder strings.Builder
+	for _, match := range pdfStream.FindAllSubmatch(data, -1) {
+		if builder.Len() >= maxContentLength {
+			break
+		}
+
+		dict, stream := match[1], match[2]
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			zr, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			stream, err = ioutil.ReadAll(io.LimitReader(zr, 16*maxContentLength))
+			zr.Close()
+			if err != nil && len(stream) == 0 {
+				continue
+			}
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// 不支持的编码
+			continue
+		}
+
+		pdfText(stream, &builder)
+	}
+
+	return builder.String(), nil
+}
+
+// pdfText 解析内容流中的文本对象，将其中的字符串写入 builder
+func pdfText(stream []byte, builder *strings.Builder) {
+	var (
+		inText  bool
+		pending []string
+	)
+
+	for i := 0; i < len(stream); i++ {
+		switch c := stream[i]; {
+		case c == '(':
+			s, end := pdfString(stream, i)
+			if inText {
+				pending = append(pending, s)
+			}
+			i = end
+		case c == '%':
+			// 注释
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c):
+			start := i
+			for i < len(stream) && isPDFRegular(stream[i]) {
+				i++
+			}
+			op := string(stream[start:i])
+			i--
+
+			switch op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				builder.WriteString("\n")
+			case "Tj", "TJ":
+				builder.WriteString(strings.Join(pending, ""))
+				pending = pending[:0]
+			case "'", "\"", "T*":
+				builder.WriteString("\n")
+				builder.WriteString(strings.Join(pending, ""))
+				pending = pending[:0]
+			case "Td", "TD":
+				builder.WriteString(" ")
+			}
+		}
+	}
+}
+
+// pdfString 解析从 start 开始的字面量字符串，返回字符串及结尾括号的位置
+func pdfString(stream []byte, start int) (string, int) {
+	var (
+		buf   []byte
+		depth = 0
+		i     = start
+	)
+
+	for ; i < len(stream); i++ {
+		c := stream[i]
+		switch c {
+		case '\\':
+			i++
+			if i >= len(stream) {
+				return string(buf), i
+			}
+
+			switch e := stream[i]; e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// 续行
+			default:
+				if e >= '0' && e <= '7' {
+					value, n := 0, 0
+					for ; n < 3 && i < len(stream) && stream[i] >= '0' && stream[i] <= '7'; n++ {
+						value = value*8 + int(stream[i]-'0')
+						i++
+					}
+					i--
+					buf = append(buf, byte(value))
+				} else {
+					buf = append(buf, e)
+				}
+			}
+		case '(':
+			depth++
+			if depth > 1 {
+				buf = append(buf, c)
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfLatin1(buf), i
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+
+	return pdfLatin1(buf), i
+}
+
+// pdfLatin1 将非 UTF-8 的字节按 Latin-1 解码
+func pdfLatin1(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// isPDFRegular 是否为 PDF 中的常规字符，即非空白也非分隔符
+func isPDFRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+// truncate 将字符串截断到不超过 max 字节，不截断多字节字符
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,161 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ErrNotEnabled 未开启全文检索
+var ErrNotEnabled = errors.New("full-text search is not enabled")
+
+// Document 待索引的文件内容
+type Document struct {
+	FileID  uint
+	UserID  uint
+	Name    string
+	Content string
+}
+
+// Indexer 全文索引后端
+type Indexer interface {
+	// Index 写入或替换文件的索引
+	Index(ctx context.Context, doc *Document) error
+	// Delete 删除文件的索引
+	Delete(ctx context.Context, ids []uint) error
+	// Search 在用户的文件中搜索内容包含全部关键词的文件，返回最多 limit 个文件ID
+	Search(ctx context.Context, uid uint, keywords []string, limit int) ([]uint, error)
+}
+
+// Default 全局索引后端，未开启全文检索时为 nil
+var Default Indexer
+
+// Init 按配置初始化索引后端，并注册在文件变更后更新索引的钩子
+func Init() {
+	switch conf.FullTextConfig.Backend {
+	case "database":
+		Default = NewDatabaseIndexer()
+	case "elasticsearch":
+		Default = NewElasticsearchIndexer(conf.FullTextConfig.Endpoint, conf.FullTextConfig.Index,
+			conf.FullTextConfig.User, conf.FullTextConfig.Password)
+	default:
+		return
+	}
+
+	util.Log().Info("Full-text search enabled with %q backend.", conf.FullTextConfig.Backend)
+	filesystem.RegisterChangeHook(HookChanges)
+}
+
+// Search 在用户的文件中搜索内容，query 中以空白分隔的关键词需全部匹配
+func Search(ctx context.Context, uid uint, query string, limit int) ([]uint, error) {
+	if Default == nil {
+		return nil, ErrNotEnabled
+	}
+
+	keywords := strings.Fields(query)
+	if len(keywords) == 0 {
+		return []uint{}, nil
+	}
+
+	return Default.Search(ctx, uid, keywords, limit)
+}
+
+// HookChanges 文件创建、修改后更新索引，删除后移除索引
+func HookChanges(ctx context.Context, fs *filesystem.FileSystem, changes []model.Change) {
+	if Default == nil || fs.User == nil || fs.User.ID == 0 {
+		return
+	}
+
+	var indexed, deleted []uint
+	for _, change := range changes {
+		if change.ObjectType != model.ChangeObjectFile {
+			continue
+		}
+
+		switch change.Type {
+		case model.ChangeCreate, model.ChangeModify:
+			if Supported(change.Name) {
+				indexed = append(indexed, change.ObjectID)
+			}
+		case model.ChangeDelete:
+			deleted = append(deleted, change.ObjectID)
+		}
+	}
+
+	if len(indexed) == 0 && len(deleted) == 0 {
+		return
+	}
+
+	user := *fs.User
+	go run(&user, indexed, deleted)
+}
+
+// run 更新用户文件的索引
+func run(user *model.User, indexed, deleted []uint) {
+	ctx := context.Background()
+	if len(deleted) > 0 {
+		if err := Default.Delete(ctx, deleted); err != nil {
+			util.Log().Warning("Failed to delete full-text index: %s", err)
+		}
+	}
+
+	if len(indexed) == 0 {
+		return
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		util.Log().Warning("Failed to create filesystem for full-text indexing: %s", err)
+		return
+	}
+	defer fs.Recycle()
+
+	files, err := model.GetFilesByIDs(indexed, user.ID)
+	if err != nil {
+		util.Log().Warning("Failed to list files to index: %s", err)
+		return
+	}
+
+	for i := range files {
+		if err := IndexFile(ctx, fs, &files[i]); err != nil {
+			util.Log().Debug("Failed to index content of file %d: %s", files[i].ID, err)
+		}
+	}
+}
+
+// IndexFile 提取文件内容并写入索引，忽略不支持的类型、过大的文件及上传中的占位文件
+func IndexFile(ctx context.Context, fs *filesystem.FileSystem, file *model.File) error {
+	if Default == nil {
+		return ErrNotEnabled
+	}
+
+	if file.UploadSessionID != nil || !Supported(file.Name) ||
+		(conf.FullTextConfig.MaxSize > 0 && file.Size > conf.FullTextConfig.MaxSize) {
+		return nil
+	}
+
+	fs.CleanTargets()
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetContent(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	content, err := Extract(file.Name, rs)
+	if err != nil {
+		return err
+	}
+
+	return Default.Index(ctx, &Document{
+		FileID:  file.ID,
+		UserID:  file.UserID,
+		Name:    file.Name,
+		Content: content,
+	})
+}
@@ -0,0 +1,286 @@
+package fulltext
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mocks/requestmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestSupported(t *testing.T) {
+	a := assert.New(t)
+	a.True(Supported("a.txt"))
+	a.True(Supported("a.MD"))
+	a.True(Supported("a.docx"))
+	a.True(Supported("a.pdf"))
+	a.False(Supported("a.doc"))
+	a.False(Supported("a"))
+}
+
+func TestExtract(t *testing.T) {
+	a := assert.New(t)
+
+	// 不支持的类型
+	{
+		_, err := Extract("a.png", strings.NewReader(""))
+		a.Equal(ErrUnsupportedType, err)
+	}
+
+	// 纯文本
+	{
+		res, err := Extract("a.txt", strings.NewReader("hello 世界"))
+		a.NoError(err)
+		a.Equal("hello 世界", res)
+	}
+
+	// docx
+	{
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		w, _ := zw.Create("word/document.xml")
+		w.Write([]byte(`<?xml version="1.0"?><w:document xmlns:w="w"><w:body>` +
+			`<w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:tab/><w:t>World</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>第二段</w:t></w:r></w:p></w:body></w:document>`))
+		zw.Close()
+
+		res, err := Extract("a.docx", bytes.NewReader(buf.Bytes()))
+		a.NoError(err)
+		a.Equal("Hello\tWorld\n第二段\n", res)
+	}
+
+	// docx 缺少正文
+	{
+		buf := &bytes.Buffer{}
+		zw := zip.NewWriter(buf)
+		zw.Create("other.xml")
+		zw.Close()
+
+		_, err := Extract("a.docx", bytes.NewReader(buf.Bytes()))
+		a.Error(err)
+	}
+
+	// docx 不是压缩包
+	{
+		_, err := Extract("a.docx", strings.NewReader("not zip"))
+		a.Error(err)
+	}
+
+	// pdf 未压缩及 FlateDecode 压缩的内容流
+	{
+		compressed := &bytes.Buffer{}
+		zw := zlib.NewWriter(compressed)
+		zw.Write([]byte("BT /F1 12 Tf [(Second) -250 (Page)] TJ ET"))
+		zw.Close()
+
+		pdf := "%PDF-1.4\n" +
+			"4 0 obj\n<< /Length 44 >>\nstream\nBT /F1 12 Tf 72 712 Td (Hello \\(PDF\\)) Tj ET\nendstream\nendobj\n" +
+			"5 0 obj\n<< /Length 10 /Filter /FlateDecode >>\nstream\n" + compressed.String() + "\nendstream\nendobj\n" +
+			"6 0 obj\n<< /Filter /DCTDecode >>\nstream\nBT (Image) Tj ET\nendstream\nendobj\n%%EOF"
+		res, err := Extract("a.pdf", strings.NewReader(pdf))
+		a.NoError(err)
+		a.Contains(res, "Hello (PDF)")
+		a.Contains(res, "SecondPage")
+		a.NotContains(res, "Image")
+	}
+
+	// 无效 pdf
+	{
+		_, err := Extract("a.pdf", strings.NewReader("not pdf"))
+		a.Error(err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("abc", truncate("abc", 5))
+	a.Equal("ab", truncate("abc", 2))
+	a.Equal("a", truncate("a世界", 3))
+}
+
+func TestSearch(t *testing.T) {
+	a := assert.New(t)
+	Default = nil
+
+	// 未开启
+	{
+		_, err := Search(context.Background(), 1, "foo", 10)
+		a.Equal(ErrNotEnabled, err)
+	}
+
+	Default = NewDatabaseIndexer()
+	defer func() { Default = nil }()
+
+	// 关键词为空
+	{
+		res, err := Search(context.Background(), 1, "  ", 10)
+		a.NoError(err)
+		a.Empty(res)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)file_contents(.+)").
+			WithArgs(1, "%foo%", "%bar%").
+			WillReturnRows(sqlmock.NewRows([]string{"file_id"}).AddRow(2))
+		res, err := Search(context.Background(), 1, "foo  bar", 10)
+		a.NoError(mock.ExpectationsWereMet())
+		a.NoError(err)
+		a.Equal([]uint{2}, res)
+	}
+}
+
+func TestDatabaseIndexer(t *testing.T) {
+	a := assert.New(t)
+	indexer := NewDatabaseIndexer()
+
+	// Index 截断过长的内容
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT(.+)file_contents(.+)").
+			WithArgs(1, 2, strings.Repeat("a", maxDatabaseContent), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(indexer.Index(context.Background(), &Document{
+			FileID:  1,
+			UserID:  2,
+			Content: strings.Repeat("a", maxDatabaseContent+10),
+		}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// Delete
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_contents(.+)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		a.NoError(indexer.Delete(context.Background(), []uint{1}))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestElasticsearchIndexer(t *testing.T) {
+	a := assert.New(t)
+	indexer := NewElasticsearchIndexer("http://127.0.0.1:9200", "cloudreve", "user", "pwd")
+	a.Equal("Basic dXNlcjpwd2Q=", indexer.header.Get("Authorization"))
+
+	response := func(status int, body string) *request.Response {
+		return &request.Response{Response: &http.Response{
+			StatusCode: status,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}}
+	}
+
+	// Index
+	{
+		clientMock := &requestmock.RequestMock{}
+		indexer.Client = clientMock
+		clientMock.On("Request", "PUT", "cloudreve/_doc/1", testMock.Anything, testMock.Anything).
+			Return(response(201, `{"result":"created"}`))
+		a.NoError(indexer.Index(context.Background(), &Document{FileID: 1, UserID: 2, Content: "hello"}))
+		clientMock.AssertExpectations(t)
+	}
+
+	// Delete 返回错误状态
+	{
+		clientMock := &requestmock.RequestMock{}
+		indexer.Client = clientMock
+		clientMock.On("Request", "POST", "cloudreve/_delete_by_query", testMock.Anything, testMock.Anything).
+			Return(response(500, `{"error":"error"}`))
+		a.Error(indexer.Delete(context.Background(), []uint{1}))
+		clientMock.AssertExpectations(t)
+	}
+
+	// Search 请求失败
+	{
+		clientMock := &requestmock.RequestMock{}
+		indexer.Client = clientMock
+		clientMock.On("Request", "POST", "cloudreve/_search", testMock.Anything, testMock.Anything).
+			Return(&request.Response{Err: errors.New("error")})
+		_, err := indexer.Search(context.Background(), 2, []string{"hello"}, 10)
+		a.Error(err)
+		clientMock.AssertExpectations(t)
+	}
+
+	// Search 成功
+	{
+		clientMock := &requestmock.RequestMock{}
+		indexer.Client = clientMock
+		clientMock.On("Request", "POST", "cloudreve/_search", testMock.Anything, testMock.Anything).
+			Return(response(200, `{"hits":{"hits":[{"_id":"3"},{"_id":"invalid"},{"_id":"1"}]}}`))
+		res, err := indexer.Search(context.Background(), 2, []string{"hello"}, 10)
+		a.NoError(err)
+		a.Equal([]uint{3, 1}, res)
+		clientMock.AssertExpectations(t)
+	}
+}
+
+func TestHookChanges(t *testing.T) {
+	a := assert.New(t)
+	fs := &filesystem.FileSystem{User: &model.User{}}
+	fs.User.ID = 1
+
+	// 未开启时忽略
+	Default = nil
+	a.NotPanics(func() {
+		HookChanges(context.Background(), fs, []model.Change{{ObjectType: model.ChangeObjectFile, Type: model.ChangeCreate, Name: "a.txt"}})
+	})
+
+	// 目录及不支持的文件不触发索引
+	Default = NewDatabaseIndexer()
+	defer func() { Default = nil }()
+	HookChanges(context.Background(), fs, []model.Change{
+		{ObjectType: model.ChangeObjectFolder, Type: model.ChangeCreate, Name: "a.txt"},
+		{ObjectType: model.ChangeObjectFile, Type: model.ChangeCreate, Name: "a.png"},
+	})
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestIndexFile(t *testing.T) {
+	a := assert.New(t)
+	fs := &filesystem.FileSystem{User: &model.User{}}
+
+	// 未开启
+	Default = nil
+	a.Equal(ErrNotEnabled, IndexFile(context.Background(), fs, &model.File{Name: "a.txt"}))
+
+	Default = NewDatabaseIndexer()
+	defer func() { Default = nil }()
+
+	// 不支持的类型直接跳过
+	a.NoError(IndexFile(context.Background(), fs, &model.File{Name: "a.png"}))
+
+	// 上传中的占位文件直接跳过
+	sessionID := "session"
+	a.NoError(IndexFile(context.Background(), fs, &model.File{Name: "a.txt", UploadSessionID: &sessionID}))
+}
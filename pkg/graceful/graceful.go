@@ -0,0 +1,230 @@
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// envListeners 从父进程继承的监听地址，按顺序对应从 3 开始的文件描述符
+	envListeners = "CLOUDREVE_INHERITED_LISTENERS"
+	// envParentPipe 父进程持有写端的管道，父进程退出后读端收到 EOF
+	envParentPipe = "CLOUDREVE_PARENT_PIPE"
+)
+
+var (
+	// ErrRestarting 已在重启中
+	ErrRestarting = errors.New("a new process has already been started")
+	// ErrRestartUnsupported 当前平台不支持平滑重启
+	ErrRestartUnsupported = errors.New("graceful restart is not supported on this platform")
+)
+
+// ReusePort 新建监听时是否设置 SO_REUSEPORT，允许多个进程同时监听同一端口
+var ReusePort bool
+
+var (
+	mu sync.Mutex
+	// inherited 从父进程继承、尚未被使用的监听
+	inherited map[string]*os.File
+	// listeners 当前进程的所有监听，平滑重启时传递给新进程
+	listeners []namedListener
+	// parentPipe 通向父进程的管道读端，为 nil 表示不是由平滑重启启动的进程
+	parentPipe *os.File
+	// childPipe 通向新进程的管道写端，进程退出时自动关闭
+	childPipe *os.File
+	// closing 已关闭所有监听
+	closing bool
+)
+
+type namedListener struct {
+	key      string
+	listener net.Listener
+}
+
+func init() {
+	inherited = parseInherited(os.Getenv(envListeners), 3)
+	if fd, err := strconv.Atoi(os.Getenv(envParentPipe)); err == nil && fd > 2 {
+		parentPipe = os.NewFile(uintptr(fd), "parent")
+	}
+
+	// 避免再次启动的进程误用
+	os.Unsetenv(envListeners)
+	os.Unsetenv(envParentPipe)
+}
+
+// parseInherited 解析继承的监听地址列表
+func parseInherited(value string, firstFd int) map[string]*os.File {
+	res := make(map[string]*os.File)
+	if value == "" {
+		return res
+	}
+
+	for i, key := range strings.Split(value, ",") {
+		res[key] = os.NewFile(uintptr(firstFd+i), key)
+	}
+
+	return res
+}
+
+func listenerKey(network, address string) string {
+	return network + "://" + address
+}
+
+// Inherited 返回当前进程是否由平滑重启启动
+func Inherited() bool {
+	return parentPipe != nil
+}
+
+// IsInherited 返回给定地址的监听是否从父进程继承且尚未被使用
+func IsInherited(network, address string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := inherited[listenerKey(network, address)]
+	return ok
+}
+
+// Listen 监听给定地址，优先使用从父进程继承的监听
+func Listen(network, address string) (net.Listener, error) {
+	key := listenerKey(network, address)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if file, ok := inherited[key]; ok {
+		delete(inherited, key)
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err == nil {
+			util.Log().Info("Listener %q inherited from parent process.", key)
+			listeners = append(listeners, namedListener{key: key, listener: listener})
+			return listener, nil
+		}
+
+		util.Log().Warning("Failed to inherit listener %q: %s", key, err)
+	}
+
+	config := net.ListenConfig{}
+	if ReusePort {
+		config.Control = reusePortControl
+	}
+
+	listener, err := config.Listen(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	listeners = append(listeners, namedListener{key: key, listener: listener})
+	return listener, nil
+}
+
+// WaitParent 由平滑重启启动时，阻塞直到父进程退出
+func WaitParent() {
+	if parentPipe == nil {
+		return
+	}
+
+	_, _ = io.Copy(ioutil.Discard, parentPipe)
+	parentPipe.Close()
+}
+
+// Restarting 返回是否已启动了接替当前进程的新进程
+func Restarting() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return childPipe != nil
+}
+
+// Restart 以相同的参数启动新进程并传递当前的所有监听，新进程在当前进程退出后
+// 才会恢复任务队列。返回新进程的 PID
+func Restart() (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if childPipe != nil {
+		return 0, ErrRestarting
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	keys := make([]string, 0, len(listeners))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	for _, l := range listeners {
+		file, err := listenerFile(l.listener)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get file of listener %q: %w", l.key, err)
+		}
+
+		files = append(files, file)
+		keys = append(keys, l.key)
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	files = append(files, reader)
+
+	pid, err := startProcess(files, []string{
+		envListeners + "=" + strings.Join(keys, ","),
+		envParentPipe + "=" + strconv.Itoa(3+len(keys)),
+	})
+	if err != nil {
+		writer.Close()
+		return 0, err
+	}
+
+	// Unix Socket 的监听关闭时不再删除文件，由新进程继续使用
+	for _, l := range listeners {
+		if unixListener, ok := l.listener.(*net.UnixListener); ok {
+			unixListener.SetUnlinkOnClose(false)
+		}
+	}
+
+	childPipe = writer
+	return pid, nil
+}
+
+// listenerFile 复制监听的文件描述符
+func listenerFile(listener net.Listener) (*os.File, error) {
+	switch l := listener.(type) {
+	case *net.TCPListener:
+		return l.File()
+	case *net.UnixListener:
+		return l.File()
+	default:
+		return nil, fmt.Errorf("unsupported listener type %T", listener)
+	}
+}
+
+// CloseAll 关闭当前进程的所有监听，不再接受新连接，已建立的连接不受影响
+func CloseAll() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	closing = true
+	for _, l := range listeners {
+		_ = l.listener.Close()
+	}
+	listeners = nil
+}
+
+// Closing 返回是否已调用 CloseAll 关闭监听，此时监听返回的错误可以忽略
+func Closing() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return closing
+}
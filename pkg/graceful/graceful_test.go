@@ -0,0 +1,120 @@
+package graceful
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func resetListeners() {
+	mu.Lock()
+	defer mu.Unlock()
+	inherited = make(map[string]*os.File)
+	listeners = nil
+	closing = false
+}
+
+func TestParseInherited(t *testing.T) {
+	a := assert.New(t)
+	a.Empty(parseInherited("", 3))
+
+	res := parseInherited("tcp://:5212,unix:///run/cloudreve.sock", 3)
+	a.Len(res, 2)
+	a.EqualValues(3, res["tcp://:5212"].Fd())
+	a.EqualValues(4, res["unix:///run/cloudreve.sock"].Fd())
+}
+
+func TestListen(t *testing.T) {
+	a := assert.New(t)
+	resetListeners()
+	defer resetListeners()
+
+	// 新建监听
+	l, err := Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+	a.Len(listeners, 1)
+	addr := l.Addr().String()
+
+	// 未开启 SO_REUSEPORT 时无法重复监听
+	_, err = Listen("tcp", addr)
+	a.Error(err)
+
+	// 继承的监听
+	file, err := listenerFile(l)
+	a.NoError(err)
+	inherited["tcp://"+addr] = file
+	a.True(IsInherited("tcp", addr))
+	inheritedListener, err := Listen("tcp", addr)
+	a.NoError(err)
+	a.Equal(addr, inheritedListener.Addr().String())
+	a.False(IsInherited("tcp", addr))
+	a.Len(listeners, 2)
+
+	// 关闭所有监听
+	a.False(Closing())
+	CloseAll()
+	a.True(Closing())
+	a.Empty(listeners)
+	_, err = l.Accept()
+	a.Error(err)
+	_, err = inheritedListener.Accept()
+	a.Error(err)
+}
+
+func TestListen_ReusePort(t *testing.T) {
+	a := assert.New(t)
+	resetListeners()
+	ReusePort = true
+	defer func() {
+		ReusePort = false
+		CloseAll()
+		resetListeners()
+	}()
+
+	l, err := Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+	_, err = Listen("tcp", l.Addr().String())
+	a.NoError(err)
+
+	// Unix Socket 忽略 SO_REUSEPORT
+	_, err = Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	a.NoError(err)
+}
+
+func TestListenerFile(t *testing.T) {
+	a := assert.New(t)
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "test.sock"))
+	a.NoError(err)
+	defer l.Close()
+
+	file, err := listenerFile(l)
+	a.NoError(err)
+	file.Close()
+
+	_, err = listenerFile(struct{ net.Listener }{l})
+	a.Error(err)
+}
+
+func TestWaitParent(t *testing.T) {
+	a := assert.New(t)
+	a.False(Inherited())
+	a.NotPanics(WaitParent)
+
+	reader, writer, err := os.Pipe()
+	a.NoError(err)
+	parentPipe = reader
+	defer func() { parentPipe = nil }()
+	a.True(Inherited())
+
+	done := make(chan struct{})
+	go func() {
+		WaitParent()
+		close(done)
+	}()
+
+	writer.Close()
+	<-done
+}
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package graceful
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// RestartSignal 触发平滑重启的信号
+var RestartSignal os.Signal = syscall.SIGUSR2
+
+func startProcess(files []*os.File, env []string) (int, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(os.Environ(), env...)
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	// 新进程独立运行，不等待其退出
+	go cmd.Wait()
+	return cmd.Process.Pid, nil
+}
@@ -0,0 +1,12 @@
+package graceful
+
+import (
+	"os"
+)
+
+// RestartSignal 触发平滑重启的信号，Windows 下不支持平滑重启
+var RestartSignal os.Signal
+
+func startProcess(files []*os.File, env []string) (int, error) {
+	return 0, ErrRestartUnsupported
+}
@@ -0,0 +1,30 @@
+//go:build linux || darwin
+
+package graceful
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePortControl 在绑定地址前设置 SO_REUSEADDR 及 SO_REUSEPORT
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); sockErr != nil {
+			return
+		}
+		sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Unix Socket 不支持上述选项
+	if network == "unix" {
+		return nil
+	}
+
+	return sockErr
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package graceful
+
+import (
+	"syscall"
+)
+
+// reusePortControl 当前平台不支持 SO_REUSEPORT，忽略该选项
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -16,6 +16,10 @@ const (
 	TagID           // 标签ID
 	PolicyID        // 存储策略ID
 	SourceLinkID
+	ChangeID      // 变更记录ID
+	GroupID       // 用户组ID
+	FileVersionID // 文件历史版本ID
+	TrashID       // 回收站记录ID
 )
 
 var (
@@ -0,0 +1,108 @@
+package instance
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ForwardedHeader 标记请求已由集群中其他实例转发，避免循环转发
+	ForwardedHeader = "X-Cr-Forwarded-Instance"
+
+	// cachePrefix 实例地址的缓存前缀
+	cachePrefix = "instance_"
+
+	// heartbeatTTL 实例地址的有效期，单位为秒，超时未续期的实例视为离线
+	heartbeatTTL = 60
+
+	// heartbeatInterval 续期实例地址的间隔
+	heartbeatInterval = 20 * time.Second
+)
+
+var id string
+
+// Enabled 返回是否启用了集群模式
+func Enabled() bool {
+	return conf.SystemConfig.ClusterMode
+}
+
+// ID 返回本实例在集群中的标识，未启用集群模式时为空
+func ID() string {
+	if !Enabled() {
+		return ""
+	}
+
+	if id == "" {
+		id = conf.SystemConfig.InstanceID
+		if id == "" {
+			id, _ = os.Hostname()
+		}
+	}
+
+	return id
+}
+
+// Init 在集群中登记本实例的内部地址，并定期续期
+func Init() {
+	if !Enabled() {
+		return
+	}
+
+	util.Log().Info("Cluster mode enabled, registering instance %q at %q...", ID(), conf.SystemConfig.InternalURL)
+	if err := register(); err != nil {
+		util.Log().Warning("Failed to register instance %q: %s", ID(), err)
+	}
+
+	go func() {
+		for range time.Tick(heartbeatInterval) {
+			if err := register(); err != nil {
+				util.Log().Warning("Failed to renew instance %q: %s", ID(), err)
+			}
+		}
+	}()
+}
+
+func register() error {
+	return cache.Set(cachePrefix+ID(), conf.SystemConfig.InternalURL, heartbeatTTL)
+}
+
+// Lookup 查找在线实例的内部地址
+func Lookup(instanceID string) (*url.URL, bool) {
+	raw, ok := cache.Get(cachePrefix + instanceID)
+	if !ok {
+		return nil, false
+	}
+
+	target, err := url.Parse(raw.(string))
+	if err != nil {
+		return nil, false
+	}
+
+	return target, true
+}
+
+// Forward 将请求原样转发给集群中的其他实例处理
+func Forward(c *gin.Context, target *url.URL) {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+		req.Header.Set(ForwardedHeader, ID())
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		util.LogCtx(r.Context()).Warning("Failed to forward request to instance %q: %s", target, err)
+		c.JSON(200, serializer.Err(serializer.CodeNodeOffline, "Failed to forward request to instance", err))
+	}
+
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
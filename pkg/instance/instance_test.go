@@ -0,0 +1,112 @@
+package instance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// closeNotifyRecorder 反向代理要求 ResponseWriter 实现 http.CloseNotifier
+type closeNotifyRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (closeNotifyRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func enableCluster(instanceID string) func() {
+	conf.SystemConfig.ClusterMode = true
+	conf.SystemConfig.InstanceID = instanceID
+	conf.SystemConfig.InternalURL = "http://" + instanceID
+	id = ""
+	return func() {
+		conf.SystemConfig.ClusterMode = false
+		conf.SystemConfig.InstanceID = ""
+		conf.SystemConfig.InternalURL = ""
+		id = ""
+	}
+}
+
+func TestID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未启用集群模式
+	asserts.False(Enabled())
+	asserts.Empty(ID())
+
+	// 使用配置的标识
+	reset := enableCluster("node1")
+	asserts.True(Enabled())
+	asserts.Equal("node1", ID())
+	reset()
+
+	// 默认使用主机名
+	reset = enableCluster("")
+	defer reset()
+	hostname, _ := os.Hostname()
+	asserts.Equal(hostname, ID())
+}
+
+func TestInitAndLookup(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未启用集群模式，不登记
+	Init()
+	_, ok := Lookup("node1")
+	asserts.False(ok)
+
+	defer enableCluster("node1")()
+	Init()
+	target, ok := Lookup("node1")
+	asserts.True(ok)
+	asserts.Equal("http://node1", target.String())
+
+	// 无法解析的地址
+	cache.Set(cachePrefix+"node2", "http://[::1", 0)
+	_, ok = Lookup("node2")
+	asserts.False(ok)
+}
+
+func TestForward(t *testing.T) {
+	asserts := assert.New(t)
+	defer enableCluster("node1")()
+
+	var forwarded *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("forwarded"))
+	}))
+	defer server.Close()
+
+	// 转发成功
+	{
+		target, _ := url.Parse(server.URL)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(closeNotifyRecorder{rec})
+		c.Request, _ = http.NewRequest("PATCH", "/api/v3/file/tus/session", nil)
+		Forward(c, target)
+		asserts.Equal(http.StatusCreated, rec.Code)
+		asserts.Equal("forwarded", rec.Body.String())
+		asserts.Equal("/api/v3/file/tus/session", forwarded.URL.Path)
+		asserts.Equal("node1", forwarded.Header.Get(ForwardedHeader))
+	}
+
+	// 目标实例无法连接
+	{
+		target, _ := url.Parse("http://127.0.0.1:1")
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(closeNotifyRecorder{rec})
+		c.Request, _ = http.NewRequest("PATCH", "/api/v3/file/tus/session", nil)
+		Forward(c, target)
+		asserts.Contains(rec.Body.String(), "50010")
+	}
+}
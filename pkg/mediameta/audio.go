@@ -0,0 +1,468 @@
+package mediameta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrNoAudioTag is returned when the audio file does not carry supported tags
+var ErrNoAudioTag = errors.New("no ID3v2 or FLAC tag found")
+
+const (
+	// maxAudioTagSize is the largest ID3v2 tag or FLAC metadata block that will be read
+	maxAudioTagSize = 16 << 20
+)
+
+// AudioMeta tags of a music file
+type AudioMeta struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+
+	// Cover embedded cover art, front cover is preferred
+	Cover     []byte `json:"-"`
+	CoverMime string `json:"-"`
+	coverType byte
+}
+
+// ParseAudioTags reads ID3v2 tags of MP3 files or Vorbis comments of FLAC files.
+// Only the leading part of the stream that holds the tags is consumed.
+func ParseAudioTags(r io.Reader) (*AudioMeta, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, ErrNoAudioTag
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("ID3")):
+		return parseID3(br)
+	case bytes.Equal(magic, []byte("fLaC")):
+		return parseFLAC(br)
+	}
+
+	return nil, ErrNoAudioTag
+}
+
+// CoverExt returns file extension of the embedded cover art
+func (m *AudioMeta) CoverExt() string {
+	switch strings.ToLower(m.CoverMime) {
+	case "image/png", "png":
+		return ".png"
+	case "image/gif", "gif":
+		return ".gif"
+	default:
+		return ".jpg"
+	}
+}
+
+// setCover keeps the picture if no cover is set yet or the picture is the front cover
+func (m *AudioMeta) setCover(picType byte, mime string, data []byte) {
+	const frontCover = 3
+	if len(data) == 0 || (m.Cover != nil && (m.coverType == frontCover || picType != frontCover)) {
+		return
+	}
+
+	m.Cover, m.CoverMime, m.coverType = data, mime, picType
+}
+
+// setField maps textual tag values into AudioMeta
+func (m *AudioMeta) setField(field, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	switch field {
+	case "title":
+		m.Title = value
+	case "artist":
+		m.Artist = value
+	case "album":
+		m.Album = value
+	case "albumartist":
+		m.AlbumArtist = value
+	case "genre":
+		m.Genre = value
+	case "year":
+		if len(value) >= 4 {
+			m.Year, _ = strconv.Atoi(value[:4])
+		}
+	case "track":
+		m.Track = leadingNumber(value)
+	case "disc":
+		m.Disc = leadingNumber(value)
+	}
+}
+
+// id3Fields maps ID3v2.3/2.4 and ID3v2.2 frame IDs into AudioMeta fields
+var id3Fields = map[string]string{
+	"TIT2": "title", "TT2": "title",
+	"TPE1": "artist", "TP1": "artist",
+	"TALB": "album", "TAL": "album",
+	"TPE2": "albumartist", "TP2": "albumartist",
+	"TCON": "genre", "TCO": "genre",
+	"TYER": "year", "TDRC": "year", "TYE": "year",
+	"TRCK": "track", "TRK": "track",
+	"TPOS": "disc", "TPA": "disc",
+}
+
+func parseID3(r io.Reader) (*AudioMeta, error) {
+	var header [10]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, ErrNoAudioTag
+	}
+
+	version, flags := header[3], header[5]
+	if version < 2 || version > 4 {
+		return nil, fmt.Errorf("unsupported ID3v2 version %d: %w", version, ErrNoAudioTag)
+	}
+
+	size := syncsafe(header[6:10])
+	if size > maxAudioTagSize {
+		return nil, fmt.Errorf("ID3v2 tag is too large: %w", ErrNoAudioTag)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read ID3v2 tag: %w", err)
+	}
+
+	// Whole tag unsynchronisation, only used by ID3v2.2 and ID3v2.3
+	if flags&0x80 != 0 && version < 4 {
+		data = bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+
+	// Skip the extended header
+	if flags&0x40 != 0 && version > 2 && len(data) >= 4 {
+		extSize := int(binary.BigEndian.Uint32(data))
+		if version == 3 {
+			extSize += 4
+		} else {
+			extSize = int(syncsafe(data[:4]))
+		}
+		if extSize > len(data) {
+			return nil, ErrNoAudioTag
+		}
+		data = data[extSize:]
+	}
+
+	idLen, headerLen := 4, 10
+	if version == 2 {
+		idLen, headerLen = 3, 6
+	}
+
+	meta := &AudioMeta{}
+	for len(data) >= headerLen && data[0] != 0 {
+		id := string(data[:idLen])
+		var frameSize int
+		switch version {
+		case 2:
+			frameSize = int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(data[4:8]))
+		default:
+			frameSize = int(syncsafe(data[4:8]))
+		}
+
+		if frameSize < 0 || headerLen+frameSize > len(data) {
+			break
+		}
+
+		frame := data[headerLen : headerLen+frameSize]
+		var frameFlags byte
+		if version > 2 {
+			frameFlags = data[9]
+		}
+		data = data[headerLen+frameSize:]
+
+		// Compressed and encrypted frames are skipped
+		if (version == 3 && frameFlags&0xC0 != 0) || (version == 4 && frameFlags&0x0C != 0) {
+			continue
+		}
+
+		if version == 4 {
+			// Data length indicator
+			if frameFlags&0x01 != 0 {
+				if len(frame) < 4 {
+					continue
+				}
+				frame = frame[4:]
+			}
+
+			// Frame level unsynchronisation
+			if frameFlags&0x02 != 0 {
+				frame = bytes.ReplaceAll(frame, []byte{0xFF, 0x00}, []byte{0xFF})
+			}
+		}
+
+		if field, ok := id3Fields[id]; ok && len(frame) > 0 {
+			meta.setField(field, decodeID3Text(frame[0], frame[1:]))
+			continue
+		}
+
+		switch id {
+		case "APIC":
+			parseAPIC(meta, frame)
+		case "PIC":
+			parsePIC(meta, frame)
+		}
+	}
+
+	return meta, nil
+}
+
+// parseAPIC parses an ID3v2.3/2.4 attached picture frame
+func parseAPIC(meta *AudioMeta, frame []byte) {
+	if len(frame) < 2 {
+		return
+	}
+
+	encoding := frame[0]
+	mimeEnd := bytes.IndexByte(frame[1:], 0)
+	if mimeEnd < 0 || 1+mimeEnd+2 > len(frame) {
+		return
+	}
+
+	mime := string(frame[1 : 1+mimeEnd])
+	rest := frame[1+mimeEnd+1:]
+	picType := rest[0]
+	_, data := splitID3String(encoding, rest[1:])
+	meta.setCover(picType, mime, data)
+}
+
+// parsePIC parses an ID3v2.2 attached picture frame, which uses a three
+// letters image format instead of a MIME type
+func parsePIC(meta *AudioMeta, frame []byte) {
+	if len(frame) < 5 {
+		return
+	}
+
+	_, data := splitID3String(frame[0], frame[5:])
+	meta.setCover(frame[4], strings.ToLower(string(frame[1:4])), data)
+}
+
+// splitID3String splits a terminated string in given encoding from the following data
+func splitID3String(encoding byte, data []byte) (string, []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return decodeID3Text(encoding, data[:i]), data[i+2:]
+			}
+		}
+		return decodeID3Text(encoding, data), nil
+	}
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return decodeID3Text(encoding, data[:i]), data[i+1:]
+	}
+
+	return decodeID3Text(encoding, data), nil
+}
+
+// decodeID3Text decodes text in ISO-8859-1, UTF-16 with BOM, UTF-16BE or UTF-8.
+// Multiple values separated by NUL are joined with "/".
+func decodeID3Text(encoding byte, data []byte) string {
+	var res string
+	switch encoding {
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			if (data[0] == 0xFF && data[1] == 0xFE) || (data[0] == 0xFE && data[1] == 0xFF) {
+				data = data[2:]
+			}
+		}
+
+		units := make([]uint16, 0, len(data)/2)
+		for i := 0; i+1 < len(data); i += 2 {
+			units = append(units, order.Uint16(data[i:]))
+		}
+		res = string(utf16.Decode(units))
+	case 3:
+		res = string(data)
+	default:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		res = string(runes)
+	}
+
+	res = strings.TrimRight(res, "\x00")
+	res = strings.ReplaceAll(res, "\x00", "/")
+	return strings.ReplaceAll(res, "\ufeff", "")
+}
+
+// vorbisFields maps Vorbis comment names into AudioMeta fields
+var vorbisFields = map[string]string{
+	"TITLE":       "title",
+	"ARTIST":      "artist",
+	"ALBUM":       "album",
+	"ALBUMARTIST": "albumartist",
+	"GENRE":       "genre",
+	"DATE":        "year",
+	"TRACKNUMBER": "track",
+	"DISCNUMBER":  "disc",
+}
+
+func parseFLAC(r io.Reader) (*AudioMeta, error) {
+	if _, err := io.CopyN(io.Discard, r, 4); err != nil {
+		return nil, ErrNoAudioTag
+	}
+
+	meta := &AudioMeta{}
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("failed to read FLAC metadata block: %w", err)
+		}
+
+		last, blockType := header[0]&0x80 != 0, header[0]&0x7F
+		size := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		switch blockType {
+		case 4, 6:
+			if size > maxAudioTagSize {
+				return nil, fmt.Errorf("FLAC metadata block is too large: %w", ErrNoAudioTag)
+			}
+
+			block := make([]byte, size)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return nil, fmt.Errorf("failed to read FLAC metadata block: %w", err)
+			}
+
+			if blockType == 4 {
+				parseVorbisComment(meta, block)
+			} else {
+				parseFLACPicture(meta, block)
+			}
+		default:
+			if _, err := io.CopyN(io.Discard, r, size); err != nil {
+				return nil, fmt.Errorf("failed to skip FLAC metadata block: %w", err)
+			}
+		}
+
+		if last {
+			return meta, nil
+		}
+	}
+}
+
+// parseVorbisComment parses a little endian Vorbis comment block
+func parseVorbisComment(meta *AudioMeta, block []byte) {
+	next := func() ([]byte, bool) {
+		if len(block) < 4 {
+			return nil, false
+		}
+		size := uint64(binary.LittleEndian.Uint32(block))
+		if 4+size > uint64(len(block)) {
+			return nil, false
+		}
+		res := block[4 : 4+size]
+		block = block[4+size:]
+		return res, true
+	}
+
+	// vendor string
+	if _, ok := next(); !ok || len(block) < 4 {
+		return
+	}
+
+	count := binary.LittleEndian.Uint32(block)
+	block = block[4:]
+	for i := uint32(0); i < count; i++ {
+		comment, ok := next()
+		if !ok {
+			return
+		}
+
+		name, value, found := strings.Cut(string(comment), "=")
+		if !found {
+			continue
+		}
+
+		if field, ok := vorbisFields[strings.ToUpper(name)]; ok {
+			meta.setField(field, value)
+		}
+	}
+}
+
+// parseFLACPicture parses a big endian FLAC picture block
+func parseFLACPicture(meta *AudioMeta, block []byte) {
+	read := func(n uint64) ([]byte, bool) {
+		if n > uint64(len(block)) {
+			return nil, false
+		}
+		res := block[:n]
+		block = block[n:]
+		return res, true
+	}
+	readUint := func() (uint64, bool) {
+		b, ok := read(4)
+		if !ok {
+			return 0, false
+		}
+		return uint64(binary.BigEndian.Uint32(b)), true
+	}
+
+	picType, ok := readUint()
+	if !ok {
+		return
+	}
+
+	mimeLen, ok := readUint()
+	if !ok {
+		return
+	}
+	mime, ok := read(mimeLen)
+	if !ok {
+		return
+	}
+
+	descLen, ok := readUint()
+	if !ok {
+		return
+	}
+	// description, width, height, color depth and number of colors
+	if _, ok := read(descLen + 16); !ok {
+		return
+	}
+
+	dataLen, ok := readUint()
+	if !ok {
+		return
+	}
+	if data, ok := read(dataLen); ok {
+		meta.setCover(byte(picType), string(mime), data)
+	}
+}
+
+// syncsafe decodes a 28 bit synchsafe integer
+func syncsafe(b []byte) uint32 {
+	return uint32(b[0]&0x7F)<<21 | uint32(b[1]&0x7F)<<14 | uint32(b[2]&0x7F)<<7 | uint32(b[3]&0x7F)
+}
+
+// leadingNumber parses the number before "/" in values like "3/12"
+func leadingNumber(value string) int {
+	if i := strings.IndexByte(value, '/'); i >= 0 {
+		value = value[:i]
+	}
+
+	res, _ := strconv.Atoi(strings.TrimSpace(value))
+	return res
+}
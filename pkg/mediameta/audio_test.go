@@ -0,0 +1,143 @@
+package mediameta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// id3Frame builds an ID3v2.3 frame, or an ID3v2.4 frame with synchsafe size
+func id3Frame(id string, v4 bool, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	if v4 {
+		buf.Write(synchsafeBytes(len(body)))
+	} else {
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(body)))
+	}
+	buf.Write([]byte{0, 0})
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func synchsafeBytes(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+func id3Tag(version byte, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	// padding
+	body = append(body, make([]byte, 16)...)
+	return append(append([]byte{'I', 'D', '3', version, 0, 0}, synchsafeBytes(len(body))...), body...)
+}
+
+func flacBlock(blockType byte, last bool, body []byte) []byte {
+	if last {
+		blockType |= 0x80
+	}
+	return append([]byte{blockType, byte(len(body) >> 16), byte(len(body) >> 8), byte(len(body))}, body...)
+}
+
+func TestParseAudioTags(t *testing.T) {
+	a := assert.New(t)
+
+	// not an audio file
+	{
+		res, err := ParseAudioTags(bytes.NewReader([]byte("text")))
+		a.ErrorIs(err, ErrNoAudioTag)
+		a.Nil(res)
+	}
+
+	// unsupported ID3 version
+	{
+		res, err := ParseAudioTags(bytes.NewReader([]byte("ID3\x05\x00\x00\x00\x00\x00\x00")))
+		a.ErrorIs(err, ErrNoAudioTag)
+		a.Nil(res)
+	}
+
+	// ID3v2.3 with UTF-16 text and cover art
+	{
+		title := []byte{1, 0xFF, 0xFE, 'S', 0, 'o', 0, 'n', 0, 'g', 0}
+		cover := append([]byte{0}, []byte("image/png\x00\x03desc\x00PNGDATA")...)
+		other := append([]byte{0}, []byte("image/jpeg\x00\x04\x00BACK")...)
+		tag := id3Tag(3,
+			id3Frame("TIT2", false, title),
+			id3Frame("TPE1", false, []byte("\x00Artist")),
+			id3Frame("TALB", false, []byte("\x03Album")),
+			id3Frame("TYER", false, []byte("\x002019")),
+			id3Frame("TRCK", false, []byte("\x003/12")),
+			id3Frame("APIC", false, other),
+			id3Frame("APIC", false, cover),
+		)
+		res, err := ParseAudioTags(bytes.NewReader(append(tag, 0xFF, 0xFB)))
+		a.NoError(err)
+		a.Equal("Song", res.Title)
+		a.Equal("Artist", res.Artist)
+		a.Equal("Album", res.Album)
+		a.Equal(2019, res.Year)
+		a.Equal(3, res.Track)
+		a.Equal([]byte("PNGDATA"), res.Cover)
+		a.Equal(".png", res.CoverExt())
+	}
+
+	// ID3v2.4 with synchsafe frame size and UTF-8 text
+	{
+		long := bytes.Repeat([]byte("a"), 200)
+		tag := id3Tag(4,
+			id3Frame("TIT2", true, append([]byte{3}, long...)),
+			id3Frame("TPE2", true, []byte("\x03Various\x00Artists")),
+			id3Frame("TDRC", true, []byte("\x032020-05-01")),
+		)
+		res, err := ParseAudioTags(bytes.NewReader(tag))
+		a.NoError(err)
+		a.Equal(string(long), res.Title)
+		a.Equal("Various/Artists", res.AlbumArtist)
+		a.Equal(2020, res.Year)
+		a.Nil(res.Cover)
+	}
+
+	// FLAC with Vorbis comments and picture
+	{
+		var comments bytes.Buffer
+		writeString := func(s string) {
+			_ = binary.Write(&comments, binary.LittleEndian, uint32(len(s)))
+			comments.WriteString(s)
+		}
+		writeString("vendor")
+		_ = binary.Write(&comments, binary.LittleEndian, uint32(4))
+		writeString("title=Flac Song")
+		writeString("ARTIST=Flac Artist")
+		writeString("TRACKNUMBER=7")
+		writeString("invalid")
+
+		var picture bytes.Buffer
+		for _, v := range []interface{}{uint32(3), uint32(10), []byte("image/jpeg"), uint32(0),
+			uint32(1), uint32(1), uint32(24), uint32(0), uint32(4), []byte("JPEG")} {
+			_ = binary.Write(&picture, binary.BigEndian, v)
+		}
+
+		var flac bytes.Buffer
+		flac.WriteString("fLaC")
+		flac.Write(flacBlock(0, false, make([]byte, 34)))
+		flac.Write(flacBlock(4, false, comments.Bytes()))
+		flac.Write(flacBlock(6, true, picture.Bytes()))
+		flac.Write([]byte{0xFF, 0xF8})
+
+		res, err := ParseAudioTags(&flac)
+		a.NoError(err)
+		a.Equal("Flac Song", res.Title)
+		a.Equal("Flac Artist", res.Artist)
+		a.Equal(7, res.Track)
+		a.Equal([]byte("JPEG"), res.Cover)
+		a.Equal(".jpg", res.CoverExt())
+	}
+
+	// truncated FLAC
+	{
+		res, err := ParseAudioTags(bytes.NewReader([]byte("fLaC\x84\x00\x00\xff")))
+		a.Error(err)
+		a.Nil(res)
+	}
+}
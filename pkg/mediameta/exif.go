@@ -0,0 +1,287 @@
+package mediameta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNoExif is returned when the image does not carry EXIF data
+var ErrNoExif = errors.New("no EXIF data found")
+
+const (
+	// maxTIFFSize is the amount of data read from TIFF files when looking for EXIF tags
+	maxTIFFSize    = 4 << 20
+	exifTimeLayout = "2006:01:02 15:04:05"
+)
+
+// EXIF tags extracted from images
+const (
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetOriginal   = 0x9011
+	tagPixelWidth       = 0xA002
+	tagPixelHeight      = 0xA003
+	tagLensModel        = 0xA434
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+// typeSizes byte size of a single component of each TIFF field type
+var typeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8}
+
+// ExifMeta photo metadata extracted from EXIF
+type ExifMeta struct {
+	TakenAt     *time.Time `json:"taken_at,omitempty"`
+	Make        string     `json:"make,omitempty"`
+	Model       string     `json:"model,omitempty"`
+	LensModel   string     `json:"lens_model,omitempty"`
+	Orientation int        `json:"orientation,omitempty"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	Latitude    *float64   `json:"latitude,omitempty"`
+	Longitude   *float64   `json:"longitude,omitempty"`
+}
+
+// ParseExif reads EXIF metadata from a JPEG or TIFF stream. Only the leading
+// part of the stream that holds the metadata is consumed.
+func ParseExif(r io.Reader) (*ExifMeta, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, ErrNoExif
+	}
+
+	switch {
+	case magic[0] == 0xFF && magic[1] == 0xD8:
+		data, err := findJPEGExif(br)
+		if err != nil {
+			return nil, err
+		}
+		return parseTIFF(data)
+	case bytes.Equal(magic, []byte("II*\x00")) || bytes.Equal(magic, []byte("MM\x00*")):
+		data, err := io.ReadAll(io.LimitReader(br, maxTIFFSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TIFF data: %w", err)
+		}
+		return parseTIFF(data)
+	}
+
+	return nil, ErrNoExif
+}
+
+// findJPEGExif walks JPEG segments until the APP1 segment containing EXIF is found
+func findJPEGExif(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.Discard(2); err != nil {
+		return nil, ErrNoExif
+	}
+
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil || header[0] != 0xFF {
+			return nil, ErrNoExif
+		}
+
+		marker := header[1]
+		// Metadata segments always precede the image data
+		if marker == 0xD9 || marker == 0xDA {
+			return nil, ErrNoExif
+		}
+
+		length := int(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return nil, ErrNoExif
+		}
+
+		segment := make([]byte, length-2)
+		if _, err := io.ReadFull(r, segment); err != nil {
+			return nil, ErrNoExif
+		}
+
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:], nil
+		}
+	}
+}
+
+// tiffReader resolves IFD entries of a TIFF structure
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifdEntry a raw entry in an IFD
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func parseTIFF(data []byte) (*ExifMeta, error) {
+	if len(data) < 8 {
+		return nil, ErrNoExif
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, ErrNoExif
+	}
+
+	ifd0 := t.readIFD(t.order.Uint32(data[4:]))
+	if len(ifd0) == 0 {
+		return nil, ErrNoExif
+	}
+
+	meta := &ExifMeta{
+		Make:        t.string(ifd0[tagMake]),
+		Model:       t.string(ifd0[tagModel]),
+		Orientation: int(t.uint(ifd0[tagOrientation])),
+	}
+
+	takenAt, offset := t.string(ifd0[tagDateTime]), ""
+	if entry, ok := ifd0[tagExifIFD]; ok {
+		exif := t.readIFD(t.uint(entry))
+		if original := t.string(exif[tagDateTimeOriginal]); original != "" {
+			takenAt = original
+			offset = t.string(exif[tagOffsetOriginal])
+		}
+		meta.Width = int(t.uint(exif[tagPixelWidth]))
+		meta.Height = int(t.uint(exif[tagPixelHeight]))
+		meta.LensModel = t.string(exif[tagLensModel])
+	}
+
+	if takenAt != "" {
+		meta.TakenAt = parseExifTime(takenAt, offset)
+	}
+
+	if entry, ok := ifd0[tagGPSIFD]; ok {
+		gps := t.readIFD(t.uint(entry))
+		lat, latOk := t.coordinate(gps[tagGPSLatitude], t.string(gps[tagGPSLatitudeRef]), "S")
+		lng, lngOk := t.coordinate(gps[tagGPSLongitude], t.string(gps[tagGPSLongitudeRef]), "W")
+		if latOk && lngOk && lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180 {
+			meta.Latitude, meta.Longitude = &lat, &lng
+		}
+	}
+
+	return meta, nil
+}
+
+// readIFD reads entries of the IFD at offset, malformed entries are skipped
+func (t *tiffReader) readIFD(offset uint32) map[uint16]ifdEntry {
+	res := make(map[uint16]ifdEntry)
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return res
+	}
+
+	count := int(t.order.Uint16(t.data[offset:]))
+	pos := uint64(offset) + 2
+	for i := 0; i < count && pos+12 <= uint64(len(t.data)); i, pos = i+1, pos+12 {
+		raw := t.data[pos : pos+12]
+		entry := ifdEntry{typ: t.order.Uint16(raw[2:]), count: t.order.Uint32(raw[4:])}
+		size, ok := typeSizes[entry.typ]
+		if !ok {
+			continue
+		}
+
+		total := uint64(size) * uint64(entry.count)
+		if total <= 4 {
+			entry.value = raw[8 : 8+total]
+		} else {
+			valueOffset := uint64(t.order.Uint32(raw[8:]))
+			if valueOffset+total > uint64(len(t.data)) {
+				continue
+			}
+			entry.value = t.data[valueOffset : valueOffset+total]
+		}
+
+		res[t.order.Uint16(raw)] = entry
+	}
+
+	return res
+}
+
+// string returns an ASCII value without trailing NUL characters and spaces
+func (t *tiffReader) string(entry ifdEntry) string {
+	if entry.typ != 2 {
+		return ""
+	}
+
+	if i := bytes.IndexByte(entry.value, 0); i >= 0 {
+		return strings.TrimSpace(string(entry.value[:i]))
+	}
+
+	return strings.TrimSpace(string(entry.value))
+}
+
+// uint returns the first component of a SHORT or LONG value
+func (t *tiffReader) uint(entry ifdEntry) uint32 {
+	switch {
+	case entry.typ == 3 && len(entry.value) >= 2:
+		return uint32(t.order.Uint16(entry.value))
+	case entry.typ == 4 && len(entry.value) >= 4:
+		return t.order.Uint32(entry.value)
+	}
+
+	return 0
+}
+
+// coordinate converts a degrees/minutes/seconds GPS value into decimal degrees,
+// negative when ref equals negativeRef
+func (t *tiffReader) coordinate(entry ifdEntry, ref, negativeRef string) (float64, bool) {
+	if entry.typ != 5 || len(entry.value) < 24 {
+		return 0, false
+	}
+
+	var parts [3]float64
+	for i := range parts {
+		num, den := t.order.Uint32(entry.value[i*8:]), t.order.Uint32(entry.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+
+	res := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negativeRef) {
+		res = -res
+	}
+
+	return res, true
+}
+
+// parseExifTime parses EXIF date time with an optional "+08:00" style offset.
+// Local time zone of the server is assumed when offset is not available.
+func parseExifTime(value, offset string) *time.Time {
+	var (
+		res time.Time
+		err error
+	)
+	if offset != "" {
+		res, err = time.Parse(exifTimeLayout+"-07:00", value+offset)
+	} else {
+		res, err = time.ParseInLocation(exifTimeLayout, value, time.Local)
+	}
+
+	if err != nil || res.Year() < 1800 {
+		return nil
+	}
+
+	return &res
+}
@@ -0,0 +1,169 @@
+package mediameta
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testEntry an IFD entry used to build test TIFF data
+type testEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+func asciiEntry(tag uint16, s string) testEntry {
+	return testEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func longEntry(tag uint16, v uint32) testEntry {
+	value := make([]byte, 4)
+	binary.LittleEndian.PutUint32(value, v)
+	return testEntry{tag: tag, typ: 4, count: 1, value: value}
+}
+
+func rationalEntry(tag uint16, values ...[2]uint32) testEntry {
+	value := make([]byte, len(values)*8)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(value[i*8:], v[0])
+		binary.LittleEndian.PutUint32(value[i*8+4:], v[1])
+	}
+	return testEntry{tag: tag, typ: 5, count: uint32(len(values)), value: value}
+}
+
+// buildTIFF builds little endian TIFF data with IFD0, and optionally EXIF and GPS IFDs
+func buildTIFF(ifd0, exif, gps []testEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(8))
+
+	ifdSize := func(entries []testEntry) int { return 2 + len(entries)*12 + 4 }
+	exifOffset := 8 + ifdSize(ifd0) + 2*12
+	if exif != nil {
+		ifd0 = append(ifd0, longEntry(tagExifIFD, uint32(exifOffset)))
+	}
+	gpsOffset := exifOffset + ifdSize(exif)
+	if gps != nil {
+		ifd0 = append(ifd0, longEntry(tagGPSIFD, uint32(gpsOffset)))
+	}
+
+	// values are placed after all IFDs
+	valueOffset := gpsOffset + ifdSize(gps)
+	var values bytes.Buffer
+	writeIFD := func(entries []testEntry, start int) {
+		for buf.Len() < start {
+			buf.WriteByte(0)
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			_ = binary.Write(&buf, binary.LittleEndian, e.tag)
+			_ = binary.Write(&buf, binary.LittleEndian, e.typ)
+			_ = binary.Write(&buf, binary.LittleEndian, e.count)
+			if len(e.value) <= 4 {
+				buf.Write(append(e.value, make([]byte, 4-len(e.value))...))
+			} else {
+				_ = binary.Write(&buf, binary.LittleEndian, uint32(valueOffset+values.Len()))
+				values.Write(e.value)
+			}
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(0))
+	}
+
+	writeIFD(ifd0, 8)
+	writeIFD(exif, exifOffset)
+	writeIFD(gps, gpsOffset)
+	buf.Write(values.Bytes())
+	return buf.Bytes()
+}
+
+// wrapJPEG embeds TIFF data into the APP1 segment of a minimal JPEG stream
+func wrapJPEG(tiff []byte) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	// an unrelated APP0 segment before EXIF
+	buf.Write([]byte{0xFF, 0xE0, 0x00, 0x04, 0x00, 0x00})
+	buf.Write([]byte{0xFF, 0xE1})
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(tiff)+8))
+	buf.WriteString("Exif\x00\x00")
+	buf.Write(tiff)
+	buf.Write([]byte{0xFF, 0xDA, 0x00, 0x02, 0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func TestParseExif(t *testing.T) {
+	a := assert.New(t)
+
+	// not an image
+	{
+		res, err := ParseExif(bytes.NewReader([]byte("text")))
+		a.ErrorIs(err, ErrNoExif)
+		a.Nil(res)
+	}
+
+	// JPEG without EXIF
+	{
+		res, err := ParseExif(bytes.NewReader([]byte{0xFF, 0xD8, 0xFF, 0xDA, 0x00, 0x02}))
+		a.ErrorIs(err, ErrNoExif)
+		a.Nil(res)
+	}
+
+	// JPEG with full EXIF
+	{
+		tiff := buildTIFF(
+			[]testEntry{asciiEntry(tagMake, "Canon"), asciiEntry(tagModel, "EOS R5"),
+				asciiEntry(tagDateTime, "2020:01:01 00:00:00")},
+			[]testEntry{asciiEntry(tagDateTimeOriginal, "2021:06:15 08:30:00"),
+				asciiEntry(tagOffsetOriginal, "+08:00"), longEntry(tagPixelWidth, 8192),
+				longEntry(tagPixelHeight, 5464), asciiEntry(tagLensModel, "RF24-70mm")},
+			[]testEntry{asciiEntry(tagGPSLatitudeRef, "N"),
+				rationalEntry(tagGPSLatitude, [2]uint32{31, 1}, [2]uint32{30, 1}, [2]uint32{0, 1}),
+				asciiEntry(tagGPSLongitudeRef, "W"),
+				rationalEntry(tagGPSLongitude, [2]uint32{121, 1}, [2]uint32{15, 1}, [2]uint32{36, 1})},
+		)
+		res, err := ParseExif(bytes.NewReader(wrapJPEG(tiff)))
+		a.NoError(err)
+		a.Equal("Canon", res.Make)
+		a.Equal("EOS R5", res.Model)
+		a.Equal("RF24-70mm", res.LensModel)
+		a.Equal(8192, res.Width)
+		a.Equal(5464, res.Height)
+		a.True(res.TakenAt.Equal(time.Date(2021, 6, 15, 0, 30, 0, 0, time.UTC)))
+		a.InDelta(31.5, *res.Latitude, 1e-9)
+		a.InDelta(-121.26, *res.Longitude, 1e-9)
+	}
+
+	// TIFF without EXIF IFD, falls back to DateTime
+	{
+		tiff := buildTIFF([]testEntry{asciiEntry(tagDateTime, "2020:01:02 03:04:05")}, nil, nil)
+		res, err := ParseExif(bytes.NewReader(tiff))
+		a.NoError(err)
+		a.True(res.TakenAt.Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.Local)))
+		a.Nil(res.Latitude)
+		a.Nil(res.Longitude)
+	}
+
+	// invalid time and GPS values are ignored
+	{
+		tiff := buildTIFF(
+			[]testEntry{asciiEntry(tagDateTime, "0000:00:00 00:00:00")}, nil,
+			[]testEntry{rationalEntry(tagGPSLatitude, [2]uint32{31, 0}, [2]uint32{0, 1}, [2]uint32{0, 1}),
+				rationalEntry(tagGPSLongitude, [2]uint32{121, 1}, [2]uint32{0, 1}, [2]uint32{0, 1})},
+		)
+		res, err := ParseExif(bytes.NewReader(tiff))
+		a.NoError(err)
+		a.Nil(res.TakenAt)
+		a.Nil(res.Latitude)
+	}
+
+	// truncated IFD
+	{
+		res, err := ParseExif(bytes.NewReader([]byte("II*\x00\xff\x00\x00\x00")))
+		a.ErrorIs(err, ErrNoExif)
+		a.Nil(res)
+	}
+}
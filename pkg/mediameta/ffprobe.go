@@ -0,0 +1,142 @@
+package mediameta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// MediaMeta container level metadata of a video file
+type MediaMeta struct {
+	Format     string    `json:"format,omitempty"`
+	Duration   float64   `json:"duration"`
+	Bitrate    int64     `json:"bitrate,omitempty"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	VideoCodec string    `json:"video_codec,omitempty"`
+	AudioCodec string    `json:"audio_codec,omitempty"`
+	Chapters   []Chapter `json:"chapters,omitempty"`
+}
+
+// Chapter a chapter marker inside the container
+type Chapter struct {
+	Title string  `json:"title,omitempty"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// ffprobeOutput raw output of `ffprobe -print_format json`
+type ffprobeOutput struct {
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+}
+
+// Probe invokes ffprobe against input, which can be a local file path or
+// an URL that ffprobe is able to read from.
+func Probe(ctx context.Context, executable, input string) (*MediaMeta, error) {
+	cmd := exec.CommandContext(ctx, executable,
+		"-v", "quiet", "-print_format", "json",
+		"-show_format", "-show_streams", "-show_chapters", input)
+
+	var stdOut, stdErr bytes.Buffer
+	cmd.Stdout = &stdOut
+	cmd.Stderr = &stdErr
+
+	if err := cmd.Run(); err != nil {
+		util.Log().Warning("Failed to invoke ffprobe: %s", stdErr.String())
+		return nil, fmt.Errorf("failed to invoke ffprobe: %w", err)
+	}
+
+	return Parse(stdOut.Bytes())
+}
+
+// Parse parses JSON output of ffprobe into MediaMeta
+func Parse(raw []byte) (*MediaMeta, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	res := &MediaMeta{
+		Format:   out.Format.FormatName,
+		Duration: parseFloat(out.Format.Duration),
+	}
+	res.Bitrate, _ = strconv.ParseInt(out.Format.BitRate, 10, 64)
+
+	for _, stream := range out.Streams {
+		switch stream.CodecType {
+		case "video":
+			// Only the first video stream is taken into account, following
+			// ones are usually cover arts or thumbnails.
+			if res.VideoCodec == "" {
+				res.VideoCodec = stream.CodecName
+				res.Width = stream.Width
+				res.Height = stream.Height
+			}
+		case "audio":
+			if res.AudioCodec == "" {
+				res.AudioCodec = stream.CodecName
+			}
+		}
+	}
+
+	for _, chapter := range out.Chapters {
+		res.Chapters = append(res.Chapters, Chapter{
+			Title: chapter.Tags["title"],
+			Start: parseFloat(chapter.StartTime),
+			End:   parseFloat(chapter.EndTime),
+		})
+	}
+
+	return res, nil
+}
+
+// Encode serializes MediaMeta into the string stored in file metadata
+func (m *MediaMeta) Encode() string {
+	res, _ := json.Marshal(m)
+	return string(res)
+}
+
+// Decode deserializes MediaMeta from file metadata, returns nil if not available
+func Decode(raw string) *MediaMeta {
+	if raw == "" {
+		return nil
+	}
+
+	var res MediaMeta
+	if err := json.Unmarshal([]byte(raw), &res); err != nil {
+		return nil
+	}
+
+	return &res
+}
+
+// ShouldProbe returns if given file name is in the configured extension list
+func ShouldProbe(exts string, name string) bool {
+	return util.IsInExtensionList(strings.Split(exts, ","), name)
+}
+
+func parseFloat(s string) float64 {
+	res, _ := strconv.ParseFloat(s, 64)
+	return res
+}
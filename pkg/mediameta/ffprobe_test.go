@@ -0,0 +1,61 @@
+package mediameta
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	// invalid output
+	{
+		res, err := Parse([]byte("not json"))
+		a.Error(err)
+		a.Nil(res)
+	}
+
+	// success
+	{
+		res, err := Parse([]byte(`{
+	"streams": [
+		{"codec_type": "audio", "codec_name": "aac"},
+		{"codec_type": "video", "codec_name": "h264", "width": 1920, "height": 1080},
+		{"codec_type": "video", "codec_name": "mjpeg", "width": 320, "height": 240}
+	],
+	"chapters": [
+		{"start_time": "0.000000", "end_time": "60.500000", "tags": {"title": "Intro"}}
+	],
+	"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "120.250000", "bit_rate": "4000000"}
+}`))
+		a.NoError(err)
+		a.Equal("mov,mp4,m4a,3gp,3g2,mj2", res.Format)
+		a.Equal(120.25, res.Duration)
+		a.EqualValues(4000000, res.Bitrate)
+		a.Equal("h264", res.VideoCodec)
+		a.Equal(1920, res.Width)
+		a.Equal(1080, res.Height)
+		a.Equal("aac", res.AudioCodec)
+		a.Len(res.Chapters, 1)
+		a.Equal("Intro", res.Chapters[0].Title)
+		a.Equal(60.5, res.Chapters[0].End)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	a := assert.New(t)
+
+	a.Nil(Decode(""))
+	a.Nil(Decode("{"))
+
+	meta := &MediaMeta{Duration: 10, Width: 640, Height: 480, VideoCodec: "vp9"}
+	a.Equal(meta, Decode(meta.Encode()))
+}
+
+func TestShouldProbe(t *testing.T) {
+	a := assert.New(t)
+	a.True(ShouldProbe("mp4,mkv", "video.MP4"))
+	a.False(ShouldProbe("mp4,mkv", "image.png"))
+	a.False(ShouldProbe("mp4,mkv", "noext"))
+}
@@ -0,0 +1,105 @@
+package notification
+
+import (
+	"fmt"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 推送给客户端的事件类型
+const (
+	// EventNotification 新的站内通知
+	EventNotification = "notification"
+	// EventUnread 未读通知数，连接建立及通知标记为已读后推送
+	EventUnread = "unread"
+	// EventTask 任务状态、进度变更
+	EventTask = "task"
+	// EventDownload 离线下载状态更新
+	EventDownload = "download"
+)
+
+// announceBatchSize 发送公告时每批读取的用户数
+const announceBatchSize = 100
+
+// Event 推送给客户端的事件
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Topic 用户事件在消息队列中的主题
+func Topic(uid uint) string {
+	return fmt.Sprintf("user_events_%d", uid)
+}
+
+// Publish 向用户 uid 已连接的客户端推送事件
+func Publish(uid uint, event string, data interface{}) {
+	mq.GlobalMQ.Publish(Topic(uid), mq.Message{
+		TriggeredBy: strconv.FormatUint(uint64(uid), 10),
+		Event:       event,
+		Content:     data,
+	})
+}
+
+// PublishUnread 向用户推送最新的未读通知数
+func PublishUnread(uid uint) {
+	unread, err := model.CountUnreadNotifications(uid)
+	if err != nil {
+		util.Log().Warning("Failed to count unread notifications of user %d: %s", uid, err)
+		return
+	}
+
+	Publish(uid, EventUnread, unread)
+}
+
+// Send 为用户 uid 创建站内通知，并推送给已连接的客户端
+func Send(uid uint, kind, title, content, link string) (*model.Notification, error) {
+	notification := &model.Notification{
+		UserID:  uid,
+		Type:    kind,
+		Title:   title,
+		Content: content,
+		Link:    link,
+	}
+	if err := notification.Create(); err != nil {
+		return nil, err
+	}
+
+	Publish(uid, EventNotification, serializer.BuildNotification(notification))
+	return notification, nil
+}
+
+// Announce 向所有状态正常的用户发送公告，返回发送的用户数
+func Announce(title, content, link string) (int, error) {
+	var (
+		cursor uint
+		sent   int
+	)
+
+	for {
+		users, err := model.ListUsersAfter(cursor, announceBatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for i := range users {
+			if users[i].Status != model.Active {
+				continue
+			}
+
+			if _, err := Send(users[i].ID, model.NotificationAnnouncement, title, content, link); err != nil {
+				return sent, err
+			}
+			sent++
+		}
+
+		if len(users) < announceBatchSize {
+			return sent, nil
+		}
+		cursor = users[len(users)-1].ID
+	}
+}
@@ -0,0 +1,115 @@
+package notification
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestSend(t *testing.T) {
+	a := assert.New(t)
+	events := mq.GlobalMQ.Subscribe(Topic(1), 1)
+	defer mq.GlobalMQ.Unsubscribe(Topic(1), events)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(3, 1))
+		mock.ExpectCommit()
+		res, err := Send(1, model.NotificationShared, "title", "content", "/shared")
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(3, res.ID)
+
+		select {
+		case msg := <-events:
+			a.Equal(EventNotification, msg.Event)
+			a.EqualValues(3, msg.Content.(serializer.Notification).ID)
+		case <-time.After(time.Second):
+			t.Fatal("event not published")
+		}
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		_, err := Send(1, model.NotificationShared, "title", "content", "")
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestPublishUnread(t *testing.T) {
+	a := assert.New(t)
+	events := mq.GlobalMQ.Subscribe(Topic(2), 1)
+	defer mq.GlobalMQ.Unsubscribe(Topic(2), events)
+
+	mock.ExpectQuery("SELECT count(.+)notifications(.+)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	PublishUnread(2)
+	a.NoError(mock.ExpectationsWereMet())
+
+	select {
+	case msg := <-events:
+		a.Equal(EventUnread, msg.Event)
+		a.Equal(5, msg.Content)
+	case <-time.After(time.Second):
+		t.Fatal("event not published")
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	a := assert.New(t)
+
+	// 跳过非正常状态的用户
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(0).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).
+				AddRow(1, model.Active).
+				AddRow(2, model.Baned).
+				AddRow(3, model.Active))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		sent, err := Announce("title", "content", "")
+		a.NoError(err)
+		a.NoError(mock.ExpectationsWereMet())
+		a.Equal(2, sent)
+	}
+
+	// 读取用户失败
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		_, err := Announce("title", "content", "")
+		a.Error(err)
+		a.NoError(mock.ExpectationsWereMet())
+	}
+}
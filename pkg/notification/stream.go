@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// pingInterval 向客户端发送心跳的间隔
+	pingInterval = 30 * time.Second
+	// pongTimeout 超过此时长未收到客户端的任何消息则断开连接
+	pongTimeout = 2 * pingInterval
+	// writeTimeout 单次写入的超时时间
+	writeTimeout = 10 * time.Second
+	// subscribeBuffer 每个连接缓冲的事件数
+	subscribeBuffer = 16
+)
+
+// Stream 将用户 uid 的事件推送到 WebSocket 连接，initial 为连接建立后首先推送的事件，
+// 连接断开后返回。客户端发送的消息仅用于保持连接，内容会被忽略
+func Stream(conn *websocket.Conn, uid uint, initial ...Event) error {
+	defer conn.Close()
+
+	topic := Topic(uid)
+	events := mq.GlobalMQ.Subscribe(topic, subscribeBuffer)
+	defer mq.GlobalMQ.Unsubscribe(topic, events)
+
+	closed := make(chan error, 1)
+	go func() {
+		conn.SetReadLimit(512)
+		_ = conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(pongTimeout))
+		}
+	}()
+
+	for _, event := range initial {
+		if err := write(conn, event); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-events:
+			if err := write(conn, Event{Type: msg.Event, Data: msg.Content}); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return err
+			}
+		case err := <-closed:
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func write(conn *websocket.Conn, event Event) error {
+	_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return conn.WriteJSON(event)
+}
@@ -0,0 +1,52 @@
+package notification
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStream(t *testing.T) {
+	a := assert.New(t)
+	done := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			done <- err
+			return
+		}
+		done <- Stream(conn, 10, Event{Type: EventUnread, Data: 2})
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	a.NoError(err)
+
+	// 连接建立后推送初始事件
+	var event Event
+	a.NoError(conn.ReadJSON(&event))
+	a.Equal(EventUnread, event.Type)
+	a.EqualValues(2, event.Data)
+
+	// 推送发布的事件，初始事件送达时已完成订阅
+	Publish(10, EventNotification, "hello")
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	a.NoError(conn.ReadJSON(&event))
+	a.Equal(EventNotification, event.Type)
+	a.Equal("hello", event.Data)
+
+	// 客户端断开连接
+	a.NoError(conn.WriteMessage(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	select {
+	case err := <-done:
+		a.NoError(err)
+	case <-time.After(time.Second):
+		t.Fatal("stream not closed")
+	}
+}
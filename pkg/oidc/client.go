@@ -0,0 +1,247 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+const (
+	discoveryCachePrefix = "oidc_discovery_"
+	// discoveryCacheTTL 元数据缓存时间，单位为秒
+	discoveryCacheTTL = 3600
+)
+
+// Client OpenID Connect 客户端
+type Client struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURI  string
+	Scopes       []string
+
+	Request request.Client
+}
+
+// NewClientFromSettings 根据站点设置创建客户端，回调地址为 /api/v3/callback/oidc
+func NewClientFromSettings() (*Client, error) {
+	options := model.GetSettingByNames("oidc_issuer", "oidc_client_id", "oidc_client_secret", "oidc_scopes")
+	if options["oidc_issuer"] == "" || options["oidc_client_id"] == "" {
+		return nil, ErrNotConfigured
+	}
+
+	redirect, _ := url.Parse("/api/v3/callback/oidc")
+	return &Client{
+		Issuer:       strings.TrimSuffix(options["oidc_issuer"], "/"),
+		ClientID:     options["oidc_client_id"],
+		ClientSecret: options["oidc_client_secret"],
+		RedirectURI:  model.GetSiteURL().ResolveReference(redirect).String(),
+		Scopes:       strings.Fields(options["oidc_scopes"]),
+		Request:      request.NewClient(request.WithTimeout(10 * time.Second)),
+	}, nil
+}
+
+// Discover 获取身份提供方的元数据
+func (client *Client) Discover() (*Discovery, error) {
+	if discovery, ok := cache.Get(discoveryCachePrefix + client.Issuer); ok {
+		res := discovery.(Discovery)
+		return &res, nil
+	}
+
+	body, err := client.Request.Request("GET", client.Issuer+"/.well-known/openid-configuration", nil).
+		CheckHTTPResponse(http.StatusOK).GetResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch provider metadata: %w", err)
+	}
+
+	var discovery Discovery
+	if err := json.Unmarshal([]byte(body), &discovery); err != nil {
+		return nil, fmt.Errorf("failed to parse provider metadata: %w", err)
+	}
+
+	if strings.TrimSuffix(discovery.Issuer, "/") != client.Issuer || discovery.AuthorizationEndpoint == "" ||
+		discovery.TokenEndpoint == "" {
+		return nil, fmt.Errorf("unexpected provider metadata of issuer %q", discovery.Issuer)
+	}
+
+	_ = cache.Set(discoveryCachePrefix+client.Issuer, discovery, discoveryCacheTTL)
+	return &discovery, nil
+}
+
+// AuthURL 生成身份提供方的登录页面地址
+func (client *Client) AuthURL(state, nonce string) (string, error) {
+	discovery, err := client.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+	query.Set("client_id", client.ClientID)
+	query.Set("response_type", "code")
+	query.Set("scope", strings.Join(client.Scopes, " "))
+	query.Set("redirect_uri", client.RedirectURI)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+// Exchange 使用授权码兑换凭证
+func (client *Client) Exchange(code string) (*Token, error) {
+	discovery, err := client.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	body := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {client.RedirectURI},
+		"client_id":     {client.ClientID},
+		"client_secret": {client.ClientSecret},
+	}.Encode()
+	res := client.Request.Request(
+		"POST",
+		discovery.TokenEndpoint,
+		ioutil.NopCloser(strings.NewReader(body)),
+		request.WithHeader(http.Header{
+			"Content-Type": {"application/x-www-form-urlencoded"},
+			"Accept":       {"application/json"},
+		}),
+		request.WithContentLength(int64(len(body))),
+	)
+	if res.Err != nil {
+		return nil, res.Err
+	}
+
+	respBody, err := res.GetResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Response.StatusCode != http.StatusOK {
+		var errResp Error
+		if err := json.Unmarshal([]byte(respBody), &errResp); err != nil || errResp.ErrorType == "" {
+			return nil, fmt.Errorf("unexpected token response status %d", res.Response.StatusCode)
+		}
+		return nil, errResp
+	}
+
+	var token Token
+	if err := json.Unmarshal([]byte(respBody), &token); err != nil {
+		return nil, err
+	}
+
+	if token.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	return &token, nil
+}
+
+// Claims 校验 ID Token 并合并用户信息接口返回的声明。ID Token 由服务端通过 TLS 直接
+// 从令牌接口获取，按规范可免于校验签名，但仍需校验签发方、受众、有效期及 nonce
+func (client *Client) Claims(token *Token, nonce string) (Claims, error) {
+	discovery, err := client.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := parseIDToken(token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(claims.String("iss"), "/") != strings.TrimSuffix(discovery.Issuer, "/") {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidIDToken)
+	}
+
+	if !claims.hasAudience(client.ClientID) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidIDToken)
+	}
+
+	if claims.String("nonce") != nonce || claims.String("sub") == "" {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	if discovery.UserinfoEndpoint == "" || token.AccessToken == "" {
+		return claims, nil
+	}
+
+	body, err := client.Request.Request("GET", discovery.UserinfoEndpoint, nil, request.WithHeader(http.Header{
+		"Authorization": {"Bearer " + token.AccessToken},
+		"Accept":        {"application/json"},
+	})).CheckHTTPResponse(http.StatusOK).GetResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user info: %w", err)
+	}
+
+	var userinfo Claims
+	if err := json.Unmarshal([]byte(body), &userinfo); err != nil {
+		return nil, fmt.Errorf("failed to parse user info: %w", err)
+	}
+
+	// 用户信息须属于同一用户
+	if userinfo.String("sub") != claims.String("sub") {
+		return nil, fmt.Errorf("%w: subject mismatch", ErrInvalidIDToken)
+	}
+
+	for k, v := range userinfo {
+		claims[k] = v
+	}
+
+	return claims, nil
+}
+
+// parseIDToken 解析 ID Token 中的声明
+func parseIDToken(idToken string) (Claims, error) {
+	parts := strings.Split(idToken, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidIDToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidIDToken, err)
+	}
+
+	return claims, nil
+}
+
+// hasAudience 返回受众声明中是否包含 clientID
+func (claims Claims) hasAudience(clientID string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, item := range aud {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
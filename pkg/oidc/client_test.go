@@ -0,0 +1,181 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func testIDToken(claims map[string]interface{}) string {
+	payload, _ := json.Marshal(claims)
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+}
+
+func testProvider(t *testing.T, idToken string) (*httptest.Server, *Client) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(Discovery{
+				Issuer:                server.URL,
+				AuthorizationEndpoint: server.URL + "/auth",
+				TokenEndpoint:         server.URL + "/token",
+				UserinfoEndpoint:      server.URL + "/userinfo",
+			})
+		case "/token":
+			r.ParseForm()
+			if r.PostForm.Get("code") != "code" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"bad code"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(Token{AccessToken: "access", IDToken: idToken})
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"sub":"user1","email":"a@cloudreve.org","groups":["dev","ops"]}`))
+		}
+	}))
+
+	return server, &Client{
+		Issuer:      server.URL,
+		ClientID:    "client",
+		RedirectURI: "http://cloudreve.org/api/v3/callback/oidc",
+		Scopes:      []string{"openid", "email"},
+		Request:     request.NewClient(),
+	}
+}
+
+func TestNewClientFromSettings(t *testing.T) {
+	a := assert.New(t)
+
+	// 未配置
+	{
+		cache.Set("setting_oidc_issuer", "", 0)
+		cache.Set("setting_oidc_client_id", "", 0)
+		cache.Set("setting_oidc_client_secret", "", 0)
+		cache.Set("setting_oidc_scopes", "", 0)
+		_, err := NewClientFromSettings()
+		a.ErrorIs(err, ErrNotConfigured)
+	}
+
+	// 成功
+	{
+		cache.Set("setting_oidc_issuer", "https://sso.cloudreve.org/", 0)
+		cache.Set("setting_oidc_client_id", "client", 0)
+		cache.Set("setting_oidc_client_secret", "secret", 0)
+		cache.Set("setting_oidc_scopes", "openid email", 0)
+		cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+		client, err := NewClientFromSettings()
+		a.NoError(err)
+		a.Equal("https://sso.cloudreve.org", client.Issuer)
+		a.Equal("https://cloudreve.org/api/v3/callback/oidc", client.RedirectURI)
+		a.Equal([]string{"openid", "email"}, client.Scopes)
+	}
+}
+
+func TestClient_AuthURL(t *testing.T) {
+	a := assert.New(t)
+	server, client := testProvider(t, "")
+	defer server.Close()
+
+	res, err := client.AuthURL("state", "nonce")
+	a.NoError(err)
+	a.Contains(res, server.URL+"/auth?")
+	a.Contains(res, "state=state")
+	a.Contains(res, "nonce=nonce")
+	a.Contains(res, "scope=openid+email")
+
+	// 元数据已缓存
+	server.Close()
+	_, err = client.AuthURL("state", "nonce")
+	a.NoError(err)
+}
+
+func TestClient_Exchange(t *testing.T) {
+	a := assert.New(t)
+	server, client := testProvider(t, "id")
+	defer server.Close()
+
+	// 授权码无效
+	{
+		_, err := client.Exchange("bad")
+		a.EqualError(err, "bad code")
+	}
+
+	// 成功
+	{
+		token, err := client.Exchange("code")
+		a.NoError(err)
+		a.Equal("access", token.AccessToken)
+		a.Equal("id", token.IDToken)
+	}
+}
+
+func TestClient_Claims(t *testing.T) {
+	a := assert.New(t)
+	server, client := testProvider(t, "")
+	defer server.Close()
+
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   server.URL,
+			"aud":   []string{"client"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+			"sub":   "user1",
+		}
+	}
+
+	// 成功并合并用户信息
+	{
+		claims, err := client.Claims(&Token{AccessToken: "access", IDToken: testIDToken(valid())}, "nonce")
+		a.NoError(err)
+		a.Equal("a@cloudreve.org", claims.String("email"))
+		a.Equal([]string{"dev", "ops"}, claims.Values()["groups"])
+	}
+
+	// 各项校验失败
+	for key, value := range map[string]interface{}{
+		"iss":   "https://evil.org",
+		"aud":   "other",
+		"exp":   time.Now().Add(-time.Hour).Unix(),
+		"nonce": "other",
+	} {
+		claims := valid()
+		claims[key] = value
+		_, err := client.Claims(&Token{IDToken: testIDToken(claims)}, "nonce")
+		a.ErrorIs(err, ErrInvalidIDToken, key)
+	}
+
+	// 格式错误
+	{
+		_, err := client.Claims(&Token{IDToken: "invalid"}, "nonce")
+		a.ErrorIs(err, ErrInvalidIDToken)
+	}
+}
+
+func TestClaims_EmailVerified(t *testing.T) {
+	a := assert.New(t)
+
+	// 未提供声明
+	a.False(Claims{"email": "a@cloudreve.org"}.EmailVerified())
+
+	// 声明为否或类型不符
+	a.False(Claims{"email_verified": false}.EmailVerified())
+	a.False(Claims{"email_verified": "false"}.EmailVerified())
+	a.False(Claims{"email_verified": 1}.EmailVerified())
+
+	// 已验证
+	a.True(Claims{"email_verified": true}.EmailVerified())
+	a.True(Claims{"email_verified": "true"}.EmailVerified())
+}
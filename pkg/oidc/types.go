@@ -0,0 +1,94 @@
+package oidc
+
+import (
+	"encoding/gob"
+	"errors"
+)
+
+var (
+	ErrNotConfigured  = errors.New("OpenID Connect provider is not configured")
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Discovery 身份提供方的 OpenID Connect 元数据
+type Discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// Token 授权码兑换得到的凭证
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// Error 身份提供方返回的错误
+type Error struct {
+	ErrorType        string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Error 实现error接口
+func (err Error) Error() string {
+	if err.ErrorDescription != "" {
+		return err.ErrorDescription
+	}
+	return err.ErrorType
+}
+
+// Claims ID Token 及用户信息接口返回的声明
+type Claims map[string]interface{}
+
+// String 获取字符串类型的声明，不存在或类型不符时返回空字符串
+func (claims Claims) String(name string) string {
+	if value, ok := claims[name].(string); ok {
+		return value
+	}
+	return ""
+}
+
+// EmailVerified 身份提供方是否声明邮箱已验证，未提供 email_verified 声明时视为未验证
+func (claims Claims) EmailVerified() bool {
+	switch value := claims["email_verified"].(type) {
+	case bool:
+		return value
+	case string:
+		// 部分身份提供方以字符串形式返回布尔声明
+		return value == "true"
+	}
+	return false
+}
+
+// Values 将声明展开为字符串列表，用于用户组规则匹配
+func (claims Claims) Values() map[string][]string {
+	res := make(map[string][]string, len(claims))
+	for k, v := range claims {
+		switch value := v.(type) {
+		case string:
+			res[k] = []string{value}
+		case bool:
+			if value {
+				res[k] = []string{"true"}
+			} else {
+				res[k] = []string{"false"}
+			}
+		case []interface{}:
+			values := make([]string, 0, len(value))
+			for _, item := range value {
+				if str, ok := item.(string); ok {
+					values = append(values, str)
+				}
+			}
+			res[k] = values
+		}
+	}
+	return res
+}
+
+func init() {
+	// 注册缓存用到的复杂结构
+	gob.Register(Discovery{})
+}
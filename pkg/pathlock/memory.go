@@ -0,0 +1,72 @@
+package pathlock
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryLocker 锁存储于内存中的路径锁，用于单实例部署
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[uint]map[string]*heldLock
+	now   func() time.Time
+}
+
+type heldLock struct {
+	paths   []string
+	expires time.Time
+}
+
+// NewMemoryLocker 新建内存路径锁
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{
+		locks: make(map[uint]map[string]*heldLock),
+		now:   time.Now,
+	}
+}
+
+// TryLock 尝试同时锁定用户 uid 的多个路径
+func (l *MemoryLocker) TryLock(uid uint, token string, paths []string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	held, ok := l.locks[uid]
+	if !ok {
+		held = make(map[string]*heldLock)
+		l.locks[uid] = held
+	}
+
+	for heldToken, lock := range held {
+		if !lock.expires.After(now) {
+			delete(held, heldToken)
+			continue
+		}
+
+		for _, heldPath := range lock.paths {
+			for _, p := range paths {
+				if Conflict(heldPath, p) {
+					return false, nil
+				}
+			}
+		}
+	}
+
+	held[token] = &heldLock{paths: paths, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Unlock 释放 token 持有的锁
+func (l *MemoryLocker) Unlock(uid uint, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if held, ok := l.locks[uid]; ok {
+		delete(held, token)
+		if len(held) == 0 {
+			delete(l.locks, uid)
+		}
+	}
+
+	return nil
+}
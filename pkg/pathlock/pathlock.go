@@ -0,0 +1,98 @@
+package pathlock
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// TTL 锁的最长持有时间，防止进程异常退出后锁无法释放
+	TTL = 10 * time.Minute
+	// Wait 路径被占用时最长的等待时间
+	Wait = 10 * time.Second
+	// retryInterval 等待时重试的间隔
+	retryInterval = 50 * time.Millisecond
+)
+
+// ErrLocked 在等待时间内未能获得锁
+var ErrLocked = errors.New("path is locked by another operation")
+
+// Default 默认使用的路径锁
+var Default Locker = NewMemoryLocker()
+
+// Init 初始化路径锁，使用 Redis 缓存时锁存储于 Redis 中，以便多个实例之间互斥
+func Init() {
+	if store, ok := cache.Store.(*cache.RedisStore); ok {
+		Default = NewRedisLocker(store.Pool())
+	}
+}
+
+// Locker 用户文件树上的路径锁。持有某一路径的锁时，
+// 其他操作不能同时锁定此路径本身、其上级路径或下级路径
+type Locker interface {
+	// TryLock 尝试同时锁定用户 uid 的多个路径，有任一路径被占用时返回 false
+	TryLock(uid uint, token string, paths []string, ttl time.Duration) (bool, error)
+	// Unlock 释放 token 持有的锁
+	Unlock(uid uint, token string) error
+}
+
+// Lock 锁定用户 uid 的多个路径，被占用时在 Wait 时间内等待，返回释放锁的函数
+func Lock(ctx context.Context, uid uint, paths []string) (func(), error) {
+	paths = Clean(paths)
+	if len(paths) == 0 {
+		return func() {}, nil
+	}
+
+	token := util.RandStringRunes(16)
+	ctx, cancel := context.WithTimeout(ctx, Wait)
+	defer cancel()
+
+	for {
+		ok, err := Default.TryLock(uid, token, paths, TTL)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			return func() {
+				if err := Default.Unlock(uid, token); err != nil {
+					util.Log().Warning("Failed to release path lock of user %d: %s", uid, err)
+				}
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLocked
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// Clean 规范化并去重路径
+func Clean(paths []string) []string {
+	res := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = path.Clean("/" + p)
+		if !util.ContainsString(res, p) {
+			res = append(res, p)
+		}
+	}
+
+	return res
+}
+
+// Conflict 两个路径是否相同或互为上下级
+func Conflict(a, b string) bool {
+	return a == b || isAncestor(a, b) || isAncestor(b, a)
+}
+
+func isAncestor(ancestor, p string) bool {
+	return ancestor == "/" || strings.HasPrefix(p, ancestor+"/")
+}
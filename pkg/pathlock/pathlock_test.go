@@ -0,0 +1,87 @@
+package pathlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConflict(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(Conflict("/a", "/a"))
+	a.True(Conflict("/a", "/a/b"))
+	a.True(Conflict("/a/b/c", "/a"))
+	a.True(Conflict("/", "/a"))
+	a.False(Conflict("/a", "/ab"))
+	a.False(Conflict("/a/b", "/a/c"))
+}
+
+func TestClean(t *testing.T) {
+	a := assert.New(t)
+	a.Equal([]string{"/a", "/b/c", "/"}, Clean([]string{"/a/", "b/c", "/a", ""}))
+}
+
+func TestMemoryLocker(t *testing.T) {
+	a := assert.New(t)
+	l := NewMemoryLocker()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	// 加锁成功
+	ok, err := l.TryLock(1, "t1", []string{"/a/b"}, time.Minute)
+	a.NoError(err)
+	a.True(ok)
+
+	// 上级、下级路径冲突
+	ok, _ = l.TryLock(1, "t2", []string{"/a"}, time.Minute)
+	a.False(ok)
+	ok, _ = l.TryLock(1, "t2", []string{"/c", "/a/b/c"}, time.Minute)
+	a.False(ok)
+
+	// 不相关路径及其他用户不冲突
+	ok, _ = l.TryLock(1, "t2", []string{"/a/c"}, time.Minute)
+	a.True(ok)
+	ok, _ = l.TryLock(2, "t3", []string{"/a"}, time.Minute)
+	a.True(ok)
+
+	// 解锁后可以重新加锁
+	a.NoError(l.Unlock(1, "t1"))
+	ok, _ = l.TryLock(1, "t4", []string{"/a/b"}, time.Minute)
+	a.True(ok)
+
+	// 过期的锁自动释放
+	now = now.Add(2 * time.Minute)
+	ok, _ = l.TryLock(1, "t5", []string{"/"}, time.Minute)
+	a.True(ok)
+}
+
+func TestLock(t *testing.T) {
+	a := assert.New(t)
+	Default = NewMemoryLocker()
+
+	unlock, err := Lock(context.Background(), 1, []string{"/a"})
+	a.NoError(err)
+
+	// 等待超时
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Lock(ctx, 1, []string{"/a/b"})
+	a.Equal(ErrLocked, err)
+
+	// 等待锁释放
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		unlock()
+	}()
+	unlock2, err := Lock(context.Background(), 1, []string{"/a/b"})
+	a.NoError(err)
+	unlock2()
+
+	// 没有路径
+	unlock, err = Lock(context.Background(), 1, nil)
+	a.NoError(err)
+	unlock()
+}
@@ -0,0 +1,78 @@
+package pathlock
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// lockScript 在 Redis 中原子地检查冲突并加锁。每个用户的锁存储于一个 Hash 中，
+// 字段为 token，值为过期时间（毫秒）与换行分隔的路径
+var lockScript = redis.NewScript(1, `
+local now = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+local function ancestor(a, p)
+	return a == "/" or string.sub(p, 1, #a + 1) == a .. "/"
+end
+local held = redis.call("HGETALL", KEYS[1])
+for i = 1, #held, 2 do
+	local value = held[i + 1]
+	local sep = string.find(value, "\n", 1, true)
+	local expires = tonumber(string.sub(value, 1, sep - 1))
+	if expires <= now then
+		redis.call("HDEL", KEYS[1], held[i])
+	else
+		for p in string.gmatch(string.sub(value, sep + 1), "[^\n]+") do
+			for j = 4, #ARGV do
+				if p == ARGV[j] or ancestor(p, ARGV[j]) or ancestor(ARGV[j], p) then
+					return 0
+				end
+			end
+		end
+	end
+end
+local paths = {}
+for j = 4, #ARGV do
+	paths[#paths + 1] = ARGV[j]
+end
+redis.call("HSET", KEYS[1], ARGV[1], tostring(now + ttl) .. "\n" .. table.concat(paths, "\n"))
+redis.call("PEXPIRE", KEYS[1], ttl)
+return 1
+`)
+
+// RedisLocker 锁存储于 Redis 中的路径锁
+type RedisLocker struct {
+	pool *redis.Pool
+}
+
+// NewRedisLocker 新建 Redis 路径锁
+func NewRedisLocker(pool *redis.Pool) *RedisLocker {
+	return &RedisLocker{pool: pool}
+}
+
+func lockKey(uid uint) string {
+	return fmt.Sprintf("pathlock_%d", uid)
+}
+
+// TryLock 尝试同时锁定用户 uid 的多个路径
+func (l *RedisLocker) TryLock(uid uint, token string, paths []string, ttl time.Duration) (bool, error) {
+	rc := l.pool.Get()
+	defer rc.Close()
+
+	args := []interface{}{lockKey(uid), token, time.Now().UnixMilli(), ttl.Milliseconds()}
+	for _, p := range paths {
+		args = append(args, p)
+	}
+
+	return redis.Bool(lockScript.Do(rc, args...))
+}
+
+// Unlock 释放 token 持有的锁
+func (l *RedisLocker) Unlock(uid uint, token string) error {
+	rc := l.pool.Get()
+	defer rc.Close()
+
+	_, err := rc.Do("HDEL", lockKey(uid), token)
+	return err
+}
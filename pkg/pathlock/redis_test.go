@@ -0,0 +1,55 @@
+package pathlock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLocker(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	l := NewRedisLocker(pool)
+
+	// 成功
+	{
+		cmd := conn.GenericCommand("EVALSHA").Expect(int64(1))
+		ok, err := l.TryLock(1, "token", []string{"/a"}, time.Minute)
+		a.NoError(err)
+		a.True(ok)
+		a.Equal(1, conn.Stats(cmd))
+		conn.Clear()
+	}
+
+	// 冲突
+	{
+		conn.GenericCommand("EVALSHA").Expect(int64(0))
+		ok, err := l.TryLock(1, "token", []string{"/a"}, time.Minute)
+		a.NoError(err)
+		a.False(ok)
+		conn.Clear()
+	}
+
+	// 出错
+	{
+		conn.GenericCommand("EVALSHA").ExpectError(errors.New("error"))
+		_, err := l.TryLock(1, "token", []string{"/a"}, time.Minute)
+		a.Error(err)
+		conn.Clear()
+	}
+
+	// 解锁
+	{
+		cmd := conn.Command("HDEL", "pathlock_1", "token").Expect(int64(1))
+		a.NoError(l.Unlock(1, "token"))
+		a.Equal(1, conn.Stats(cmd))
+	}
+}
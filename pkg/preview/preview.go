@@ -0,0 +1,214 @@
+package preview
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// 内置的预览方式
+const (
+	// StrategyInline 由浏览器直接内联展示，如图片、视频、音频、PDF
+	StrategyInline = "inline"
+	// StrategyText 以纯文本展示
+	StrategyText = "text"
+	// StrategyCode 以代码展示，附带语法高亮语言
+	StrategyCode = "code"
+	// StrategyMarkdown 渲染 Markdown
+	StrategyMarkdown = "markdown"
+	// StrategyEpub 使用电子书阅读器展示
+	StrategyEpub = "epub"
+	// StrategyCAD 通过外部转换服务展示 CAD 图纸
+	StrategyCAD = "cad"
+)
+
+var (
+	// ErrUnknownStrategy 未注册的预览方式
+	ErrUnknownStrategy = errors.New("unknown preview strategy")
+	// ErrMissingURL 需要外部服务的预览方式未设置服务地址
+	ErrMissingURL = errors.New("converter url is required for this preview strategy")
+	// ErrNoMatcher 预览处理器未设置扩展名或 MIME 类型
+	ErrNoMatcher = errors.New("preview handler must match at least one extension or mime type")
+)
+
+// strategy 预览方式的属性
+type strategy struct {
+	// requireURL 是否需要外部转换服务地址
+	requireURL bool
+}
+
+var (
+	strategiesLock sync.RWMutex
+	strategies     = map[string]strategy{
+		StrategyInline:   {},
+		StrategyText:     {},
+		StrategyCode:     {},
+		StrategyMarkdown: {},
+		StrategyEpub:     {},
+		StrategyCAD:      {requireURL: true},
+	}
+)
+
+// RegisterStrategy 注册自定义预览方式，requireURL 为 true 时处理器必须设置外部服务地址
+func RegisterStrategy(name string, requireURL bool) {
+	strategiesLock.Lock()
+	defer strategiesLock.Unlock()
+	strategies[name] = strategy{requireURL: requireURL}
+}
+
+func getStrategy(name string) (strategy, bool) {
+	strategiesLock.RLock()
+	defer strategiesLock.RUnlock()
+	s, ok := strategies[name]
+	return s, ok
+}
+
+// Handler 预览处理器，将扩展名或 MIME 类型映射到预览方式
+type Handler struct {
+	// Exts 匹配的扩展名，不含点
+	Exts []string `json:"exts"`
+	// Mimes 匹配的 MIME 类型，支持如 text/* 的通配
+	Mimes []string `json:"mimes,omitempty"`
+	// Strategy 预览方式
+	Strategy string `json:"strategy"`
+	// Language 代码预览的语法高亮语言，为空时使用扩展名
+	Language string `json:"language,omitempty"`
+	// URL 外部转换服务地址，支持 {$src} {$srcB64} {$name} 占位符
+	URL string `json:"url,omitempty"`
+}
+
+// Match 匹配结果
+type Match struct {
+	Strategy string `json:"strategy"`
+	Language string `json:"language,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// Registry 预览处理器注册表，按顺序匹配，扩展名优先于 MIME 类型
+type Registry struct {
+	handlers []Handler
+}
+
+// NewRegistry 校验并创建预览处理器注册表
+func NewRegistry(handlers []Handler) (*Registry, error) {
+	res := make([]Handler, 0, len(handlers))
+	for i, handler := range handlers {
+		s, ok := getStrategy(handler.Strategy)
+		if !ok {
+			return nil, fmt.Errorf("handler #%d: %w: %q", i, ErrUnknownStrategy, handler.Strategy)
+		}
+
+		if s.requireURL && handler.URL == "" {
+			return nil, fmt.Errorf("handler #%d: %w", i, ErrMissingURL)
+		}
+
+		if len(handler.Exts) == 0 && len(handler.Mimes) == 0 {
+			return nil, fmt.Errorf("handler #%d: %w", i, ErrNoMatcher)
+		}
+
+		for j, ext := range handler.Exts {
+			handler.Exts[j] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), "."))
+		}
+		for j, mimeType := range handler.Mimes {
+			handler.Mimes[j] = strings.ToLower(strings.TrimSpace(mimeType))
+		}
+
+		res = append(res, handler)
+	}
+
+	return &Registry{handlers: res}, nil
+}
+
+// Parse 解析 JSON 格式的预览处理器配置
+func Parse(raw string) (*Registry, error) {
+	var handlers []Handler
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &handlers); err != nil {
+			return nil, fmt.Errorf("invalid preview handlers: %w", err)
+		}
+	}
+
+	return NewRegistry(handlers)
+}
+
+// FromSetting 根据站点设置创建预览处理器注册表
+func FromSetting() (*Registry, error) {
+	return Parse(model.GetSettingByName("preview_handlers"))
+}
+
+// Handlers 返回所有预览处理器
+func (r *Registry) Handlers() []Handler {
+	return r.handlers
+}
+
+// Match 为文件匹配预览方式，mimeType 为空时根据扩展名推断
+func (r *Registry) Match(name, mimeType string) (*Match, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if ext != "" {
+		for _, handler := range r.handlers {
+			for _, candidate := range handler.Exts {
+				if candidate == ext {
+					return handler.match(ext), true
+				}
+			}
+		}
+	}
+
+	if mimeType == "" && ext != "" {
+		mimeType = mime.TypeByExtension("." + ext)
+	}
+
+	// 去除 charset 等参数
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+
+	if mimeType == "" {
+		return nil, false
+	}
+
+	for _, handler := range r.handlers {
+		for _, candidate := range handler.Mimes {
+			if matchMime(candidate, mimeType) {
+				return handler.match(ext), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (handler *Handler) match(ext string) *Match {
+	res := &Match{
+		Strategy: handler.Strategy,
+		URL:      handler.URL,
+	}
+
+	if handler.Strategy == StrategyCode {
+		res.Language = handler.Language
+		if res.Language == "" {
+			res.Language = ext
+		}
+	}
+
+	return res
+}
+
+// matchMime 匹配 MIME 类型，pattern 支持 */* 与 type/* 通配
+func matchMime(pattern, mimeType string) bool {
+	if pattern == "*/*" || pattern == mimeType {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return false
+}
@@ -0,0 +1,155 @@
+package preview
+
+import (
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRegistry(t *testing.T) {
+	a := assert.New(t)
+
+	// 未知的预览方式
+	{
+		res, err := NewRegistry([]Handler{{Exts: []string{"txt"}, Strategy: "unknown"}})
+		a.ErrorIs(err, ErrUnknownStrategy)
+		a.Nil(res)
+	}
+
+	// CAD 未设置转换服务地址
+	{
+		res, err := NewRegistry([]Handler{{Exts: []string{"dwg"}, Strategy: StrategyCAD}})
+		a.ErrorIs(err, ErrMissingURL)
+		a.Nil(res)
+	}
+
+	// 未设置匹配条件
+	{
+		res, err := NewRegistry([]Handler{{Strategy: StrategyText}})
+		a.ErrorIs(err, ErrNoMatcher)
+		a.Nil(res)
+	}
+
+	// 扩展名被规范化
+	{
+		res, err := NewRegistry([]Handler{{Exts: []string{" .TXT"}, Mimes: []string{"Text/*"}, Strategy: StrategyText}})
+		a.NoError(err)
+		a.Equal([]string{"txt"}, res.Handlers()[0].Exts)
+		a.Equal([]string{"text/*"}, res.Handlers()[0].Mimes)
+	}
+
+	// 自定义预览方式
+	{
+		RegisterStrategy("custom", true)
+		res, err := NewRegistry([]Handler{{Exts: []string{"abc"}, Strategy: "custom", URL: "https://viewer/?src={$src}"}})
+		a.NoError(err)
+		a.Len(res.Handlers(), 1)
+	}
+}
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	// 空配置
+	{
+		res, err := Parse("")
+		a.NoError(err)
+		a.Empty(res.Handlers())
+	}
+
+	// 非法 JSON
+	{
+		res, err := Parse("{")
+		a.Error(err)
+		a.Nil(res)
+	}
+
+	// 从设置读取
+	{
+		cache.Set("setting_preview_handlers", `[{"exts":["md"],"strategy":"markdown"}]`, 0)
+		res, err := FromSetting()
+		a.NoError(err)
+		a.Len(res.Handlers(), 1)
+		a.Equal(StrategyMarkdown, res.Handlers()[0].Strategy)
+	}
+}
+
+func TestRegistry_Match(t *testing.T) {
+	a := assert.New(t)
+	registry, err := Parse(`[
+		{"exts":["md"],"strategy":"markdown"},
+		{"exts":["py"],"strategy":"code","language":"python"},
+		{"exts":["go","json"],"strategy":"code"},
+		{"exts":["dwg","dxf"],"strategy":"cad","url":"https://cad/?src={$src}"},
+		{"exts":["png"],"mimes":["image/*"],"strategy":"inline"},
+		{"exts":["txt"],"mimes":["text/*"],"strategy":"text"}
+	]`)
+	a.NoError(err)
+
+	// 按扩展名匹配，忽略大小写
+	{
+		res, ok := registry.Match("README.MD", "")
+		a.True(ok)
+		a.Equal(StrategyMarkdown, res.Strategy)
+		a.Empty(res.Language)
+	}
+
+	// 代码预览的语言
+	{
+		res, ok := registry.Match("main.py", "")
+		a.True(ok)
+		a.Equal("python", res.Language)
+
+		res, ok = registry.Match("main.go", "")
+		a.True(ok)
+		a.Equal(StrategyCode, res.Strategy)
+		a.Equal("go", res.Language)
+	}
+
+	// 外部转换服务
+	{
+		res, ok := registry.Match("plan.dxf", "")
+		a.True(ok)
+		a.Equal(StrategyCAD, res.Strategy)
+		a.Equal("https://cad/?src={$src}", res.URL)
+	}
+
+	// 按给定的 MIME 类型匹配
+	{
+		res, ok := registry.Match("photo", "image/heic")
+		a.True(ok)
+		a.Equal(StrategyInline, res.Strategy)
+
+		res, ok = registry.Match("notes", "text/plain; charset=utf-8")
+		a.True(ok)
+		a.Equal(StrategyText, res.Strategy)
+	}
+
+	// 根据扩展名推断 MIME 类型
+	{
+		res, ok := registry.Match("page.html", "")
+		a.True(ok)
+		a.Equal(StrategyText, res.Strategy)
+	}
+
+	// 无法匹配
+	{
+		res, ok := registry.Match("archive.zip", "")
+		a.False(ok)
+		a.Nil(res)
+
+		res, ok = registry.Match("noext", "")
+		a.False(ok)
+		a.Nil(res)
+	}
+}
+
+func TestMatchMime(t *testing.T) {
+	a := assert.New(t)
+	a.True(matchMime("*/*", "video/mp4"))
+	a.True(matchMime("video/*", "video/mp4"))
+	a.True(matchMime("video/mp4", "video/mp4"))
+	a.False(matchMime("video/*", "videos/mp4"))
+	a.False(matchMime("audio/mpeg", "audio/mp4"))
+}
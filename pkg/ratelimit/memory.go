@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// maxMemoryBuckets 内存中令牌桶数量超过此值时清理已恢复满的令牌桶
+const maxMemoryBuckets = 10000
+
+// MemoryLimiter 令牌桶存储于内存中的限流器，用于单实例部署
+type MemoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// NewMemoryLimiter 新建内存限流器
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take 从 key 对应的令牌桶中取出一个令牌
+func (l *MemoryLimiter) Take(key string, rate Rate) (*Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxMemoryBuckets {
+			l.gc(now)
+		}
+
+		b = &bucket{tokens: float64(rate.Limit), updated: now}
+		l.buckets[key] = b
+	}
+
+	var allowed bool
+	b.tokens, allowed = rate.take(b.tokens, now.Sub(b.updated))
+	b.updated = now
+	b.period = rate.Period
+	return rate.result(b.tokens, allowed), nil
+}
+
+// gc 删除已经恢复满的令牌桶
+func (l *MemoryLimiter) gc(now time.Time) {
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.period {
+			delete(l.buckets, key)
+		}
+	}
+}
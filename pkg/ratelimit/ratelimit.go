@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+)
+
+const (
+	// ClassAuth 登录、注册、重设密码等认证相关接口
+	ClassAuth = "auth"
+	// ClassAPI 其他一般接口
+	ClassAPI = "api"
+	// ClassGuest 公开目录的游客访问接口
+	ClassGuest = "guest"
+)
+
+// Default 默认使用的限流器
+var Default Limiter = NewMemoryLimiter()
+
+// Init 初始化限流器，使用 Redis 缓存时令牌桶存储于 Redis 中，以便多个实例共享配额
+func Init() {
+	if store, ok := cache.Store.(*cache.RedisStore); ok {
+		Default = NewRedisLimiter(store.Pool())
+	}
+}
+
+// Limiter 基于令牌桶的请求限流器
+type Limiter interface {
+	// Take 从 key 对应的令牌桶中取出一个令牌
+	Take(key string, rate Rate) (*Result, error)
+}
+
+// Rate 每个 Period 内最多允许 Limit 次请求，同时也是令牌桶的容量
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// PerMinute 每分钟允许 limit 次请求
+func PerMinute(limit int) Rate {
+	return Rate{Limit: limit, Period: time.Minute}
+}
+
+// Result 取令牌的结果
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset 令牌桶恢复为满所需的时间
+	Reset time.Duration
+	// RetryAfter 请求被拒绝时，下一个令牌可用所需的时间
+	RetryAfter time.Duration
+}
+
+// perToken 生成一个令牌所需的时间
+func (r Rate) perToken() time.Duration {
+	return r.Period / time.Duration(r.Limit)
+}
+
+// take 补充令牌桶中自上次更新后生成的令牌，并尝试取出一个令牌，返回剩余令牌与是否成功
+func (r Rate) take(tokens float64, elapsed time.Duration) (float64, bool) {
+	if elapsed > 0 {
+		tokens += float64(elapsed) / float64(r.perToken())
+	}
+
+	tokens = math.Min(tokens, float64(r.Limit))
+	if tokens < 1 {
+		return tokens, false
+	}
+
+	return tokens - 1, true
+}
+
+func (r Rate) result(tokens float64, allowed bool) *Result {
+	perToken := float64(r.perToken())
+	res := &Result{
+		Allowed:   allowed,
+		Limit:     r.Limit,
+		Remaining: int(tokens),
+		Reset:     time.Duration((float64(r.Limit) - tokens) * perToken),
+	}
+
+	if !allowed {
+		res.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+
+	return res
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInit(t *testing.T) {
+	a := assert.New(t)
+	origin := cache.Store
+	defer func() { cache.Store = origin; Default = NewMemoryLimiter() }()
+
+	cache.Store = cache.NewMemoStore()
+	Init()
+	a.IsType(&MemoryLimiter{}, Default)
+
+	cache.Store = cache.NewRedisStore(10, "tcp", "", "", "", "0")
+	Init()
+	a.IsType(&RedisLimiter{}, Default)
+}
+
+func TestRate_Take(t *testing.T) {
+	a := assert.New(t)
+	rate := PerMinute(60)
+
+	tokens, allowed := rate.take(0.5, 0)
+	a.False(allowed)
+	a.Equal(0.5, tokens)
+
+	tokens, allowed = rate.take(0.5, 500*time.Millisecond)
+	a.True(allowed)
+	a.InDelta(0, tokens, 0.0001)
+
+	// 不超过令牌桶容量
+	tokens, allowed = rate.take(10, time.Hour)
+	a.True(allowed)
+	a.Equal(float64(59), tokens)
+}
+
+func TestRate_Result(t *testing.T) {
+	a := assert.New(t)
+	rate := PerMinute(60)
+
+	res := rate.result(59, true)
+	a.True(res.Allowed)
+	a.Equal(60, res.Limit)
+	a.Equal(59, res.Remaining)
+	a.Equal(time.Second, res.Reset)
+	a.Zero(res.RetryAfter)
+
+	res = rate.result(0.25, false)
+	a.False(res.Allowed)
+	a.Equal(0, res.Remaining)
+	a.Equal(750*time.Millisecond, res.RetryAfter)
+}
+
+func TestMemoryLimiter_Take(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	rate := Rate{Limit: 2, Period: time.Second}
+
+	res, err := l.Take("key", rate)
+	a.NoError(err)
+	a.True(res.Allowed)
+	a.Equal(1, res.Remaining)
+
+	res, _ = l.Take("key", rate)
+	a.True(res.Allowed)
+	res, _ = l.Take("key", rate)
+	a.False(res.Allowed)
+	a.Equal(500*time.Millisecond, res.RetryAfter)
+
+	// 其他 key 不受影响
+	res, _ = l.Take("other", rate)
+	a.True(res.Allowed)
+
+	// 令牌恢复
+	now = now.Add(500 * time.Millisecond)
+	res, _ = l.Take("key", rate)
+	a.True(res.Allowed)
+}
+
+func TestMemoryLimiter_GC(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	l := NewMemoryLimiter()
+	l.now = func() time.Time { return now }
+	rate := Rate{Limit: 1, Period: time.Second}
+
+	l.Take("stale", rate)
+	now = now.Add(time.Second)
+	l.Take("fresh", rate)
+	l.gc(now)
+	a.Len(l.buckets, 1)
+	a.Contains(l.buckets, "fresh")
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// takeScript 在 Redis 中原子地补充并取出令牌，令牌数以千分之一为单位返回
+var takeScript = redis.NewScript(1, `
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(bucket[1]) or limit
+local updated = tonumber(bucket[2]) or now
+if now > updated then
+	tokens = tokens + (now - updated) * limit / period
+end
+if tokens > limit then
+	tokens = limit
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "updated", now)
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, math.floor(tokens * 1000)}
+`)
+
+// RedisLimiter 令牌桶存储于 Redis 中的限流器
+type RedisLimiter struct {
+	pool *redis.Pool
+}
+
+// NewRedisLimiter 新建 Redis 限流器
+func NewRedisLimiter(pool *redis.Pool) *RedisLimiter {
+	return &RedisLimiter{pool: pool}
+}
+
+// Take 从 key 对应的令牌桶中取出一个令牌
+func (l *RedisLimiter) Take(key string, rate Rate) (*Result, error) {
+	rc := l.pool.Get()
+	defer rc.Close()
+
+	values, err := redis.Int64s(takeScript.Do(rc, "ratelimit_"+key, rate.Limit,
+		rate.Period.Milliseconds(), time.Now().UnixMilli()))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 2 {
+		return nil, errors.New("unexpected rate limit script result")
+	}
+
+	return rate.result(float64(values[1])/1000, values[0] == 1), nil
+}
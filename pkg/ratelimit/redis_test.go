@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisLimiter_Take(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	l := NewRedisLimiter(pool)
+	rate := Rate{Limit: 10, Period: time.Second}
+
+	// 成功
+	{
+		cmd := conn.GenericCommand("EVALSHA").Expect([]interface{}{int64(1), int64(8500)})
+		res, err := l.Take("key", rate)
+		a.NoError(err)
+		a.True(res.Allowed)
+		a.Equal(8, res.Remaining)
+		a.Equal(150*time.Millisecond, res.Reset)
+		a.Equal(1, conn.Stats(cmd))
+		conn.Clear()
+	}
+
+	// 被拒绝
+	{
+		conn.GenericCommand("EVALSHA").Expect([]interface{}{int64(0), int64(500)})
+		res, err := l.Take("key", rate)
+		a.NoError(err)
+		a.False(res.Allowed)
+		a.Equal(50*time.Millisecond, res.RetryAfter)
+		conn.Clear()
+	}
+
+	// 出错
+	{
+		conn.GenericCommand("EVALSHA").ExpectError(errors.New("error"))
+		res, err := l.Take("key", rate)
+		a.Error(err)
+		a.Nil(res)
+		conn.Clear()
+	}
+
+	// 返回值错误
+	{
+		conn.GenericCommand("EVALSHA").Expect([]interface{}{int64(0)})
+		res, err := l.Take("key", rate)
+		a.Error(err)
+		a.Nil(res)
+		conn.Clear()
+	}
+}
@@ -0,0 +1,210 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// ParallelReader 使用多个并发的分段请求获取远程文件，并按顺序拼接为数据流，
+// 实现 response.RSCloser 接口。存储驱动代理下载时，若上下文中的文件大小已知，
+// 会先尝试通过 GetParallelReader 并行获取，不满足条件时回退到普通请求
+type ParallelReader struct {
+	ctx         context.Context
+	client      Client
+	target      string
+	size        int64
+	connections int
+	chunkSize   int64
+	opts        []Option
+
+	// http.ServeContent 会读取一小块以决定内容类型，此项为真时第一个read会返回假数据
+	ignoreFirst bool
+	offset      int64
+	cancel      context.CancelFunc
+	chunks      chan *parallelChunk
+	current     *bytes.Reader
+	err         error
+}
+
+type parallelChunk struct {
+	start, end int64
+	done       chan struct{}
+	data       []byte
+	err        error
+}
+
+// GetParallelReader 站点开启了并行下载且文件足够大时，返回使用多个分段请求的数据流，
+// 否则第二个返回值为 false，调用方应回退到普通请求
+func GetParallelReader(ctx context.Context, client Client, target string, size int64, opts ...Option) (*ParallelReader, bool) {
+	connections := model.GetIntSetting("proxy_parallel_connections", 1)
+	chunkSize := int64(model.GetIntSetting("proxy_parallel_chunk_size", 4<<20))
+	if connections < 2 || chunkSize <= 0 || size < 2*chunkSize {
+		return nil, false
+	}
+
+	return NewParallelReader(ctx, client, target, size, connections, chunkSize, opts...), true
+}
+
+// NewParallelReader 新建并行分段下载数据流，connections 为最大并发连接数
+func NewParallelReader(ctx context.Context, client Client, target string, size int64, connections int,
+	chunkSize int64, opts ...Option) *ParallelReader {
+	if connections < 1 {
+		connections = 1
+	}
+
+	return &ParallelReader{
+		ctx:         ctx,
+		client:      client,
+		target:      target,
+		size:        size,
+		connections: connections,
+		chunkSize:   chunkSize,
+		opts:        opts,
+	}
+}
+
+// SetFirstFakeChunk 开启第一次read返回空数据
+func (r *ParallelReader) SetFirstFakeChunk() {
+	r.ignoreFirst = true
+}
+
+// start 从当前偏移开始调度分段请求，同时进行中的分段不超过 connections 个
+func (r *ParallelReader) start() {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.cancel = cancel
+	r.chunks = make(chan *parallelChunk, r.connections-1)
+
+	go func(offset int64, chunks chan<- *parallelChunk) {
+		defer close(chunks)
+		for start := offset; start < r.size; start += r.chunkSize {
+			end := start + r.chunkSize
+			if end > r.size {
+				end = r.size
+			}
+
+			chunk := &parallelChunk{start: start, end: end, done: make(chan struct{})}
+			select {
+			case chunks <- chunk:
+			case <-ctx.Done():
+				return
+			}
+
+			go r.fetch(ctx, chunk)
+		}
+	}(r.offset, r.chunks)
+}
+
+func (r *ParallelReader) fetch(ctx context.Context, chunk *parallelChunk) {
+	defer close(chunk.done)
+
+	opts := make([]Option, 0, len(r.opts)+2)
+	opts = append(opts, r.opts...)
+	opts = append(opts,
+		WithContext(ctx),
+		WithHeader(http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", chunk.start, chunk.end-1)}}),
+	)
+	resp := r.client.Request("GET", r.target, nil, opts...).CheckHTTPResponse(http.StatusPartialContent)
+	if resp.Err != nil {
+		chunk.err = resp.Err
+		return
+	}
+	defer resp.Response.Body.Close()
+
+	chunk.data = make([]byte, chunk.end-chunk.start)
+	if _, err := io.ReadFull(resp.Response.Body, chunk.data); err != nil {
+		chunk.err = err
+	}
+}
+
+// Read 按顺序读取已下载的分段
+func (r *ParallelReader) Read(p []byte) (int, error) {
+	if r.ignoreFirst && len(p) == 512 {
+		return 0, io.EOF
+	}
+
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	for r.current == nil || r.current.Len() == 0 {
+		if r.offset >= r.size {
+			return 0, io.EOF
+		}
+
+		if r.chunks == nil {
+			r.start()
+		}
+
+		chunk, ok := <-r.chunks
+		if !ok {
+			r.err = r.ctx.Err()
+			if r.err == nil {
+				r.err = io.ErrUnexpectedEOF
+			}
+			return 0, r.err
+		}
+
+		select {
+		case <-chunk.done:
+		case <-r.ctx.Done():
+			r.err = r.ctx.Err()
+			return 0, r.err
+		}
+
+		if chunk.err != nil {
+			r.err = chunk.err
+			return 0, r.err
+		}
+
+		r.current = bytes.NewReader(chunk.data)
+	}
+
+	n, err := r.current.Read(p)
+	r.offset += int64(n)
+	return n, err
+}
+
+// Seek 移动读取位置，移动后会从新位置重新调度分段请求
+func (r *ParallelReader) Seek(offset int64, whence int) (int64, error) {
+	r.ignoreFirst = false
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+
+	if offset < 0 || offset > r.size {
+		return 0, errors.New("invalid seek offset")
+	}
+
+	if offset != r.offset {
+		r.stop()
+		r.offset = offset
+		r.current = nil
+		r.err = nil
+	}
+
+	return offset, nil
+}
+
+func (r *ParallelReader) stop() {
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+	r.chunks = nil
+}
+
+// Close 取消所有未完成的分段请求
+func (r *ParallelReader) Close() error {
+	r.stop()
+	return nil
+}
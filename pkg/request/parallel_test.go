@@ -0,0 +1,101 @@
+package request
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetParallelReader(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未开启
+	{
+		asserts.NoError(cache.Set("setting_proxy_parallel_connections", "1", 0))
+		_, ok := GetParallelReader(context.Background(), NewClient(), "http://127.0.0.1", 100)
+		asserts.False(ok)
+	}
+
+	// 文件过小
+	{
+		asserts.NoError(cache.Set("setting_proxy_parallel_connections", "4", 0))
+		asserts.NoError(cache.Set("setting_proxy_parallel_chunk_size", "100", 0))
+		_, ok := GetParallelReader(context.Background(), NewClient(), "http://127.0.0.1", 150)
+		asserts.False(ok)
+	}
+
+	// 开启
+	{
+		r, ok := GetParallelReader(context.Background(), NewClient(), "http://127.0.0.1", 200)
+		asserts.True(ok)
+		asserts.Equal(4, r.connections)
+		asserts.EqualValues(100, r.chunkSize)
+	}
+}
+
+func TestParallelReader_Read(t *testing.T) {
+	asserts := assert.New(t)
+	content := strings.Repeat("0123456789", 105)
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.ServeContent(w, r, "test.txt", time.Now(), strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	// 完整读取
+	{
+		r := NewParallelReader(context.Background(), NewClient(), server.URL, int64(len(content)), 3, 100)
+		res, err := ioutil.ReadAll(r)
+		asserts.NoError(err)
+		asserts.Equal(content, string(res))
+		asserts.EqualValues(11, atomic.LoadInt32(&requests))
+		asserts.NoError(r.Close())
+	}
+
+	// Seek 后读取
+	{
+		r := NewParallelReader(context.Background(), NewClient(), server.URL, int64(len(content)), 2, 100)
+		r.SetFirstFakeChunk()
+		n, err := r.Read(make([]byte, 512))
+		asserts.Equal(0, n)
+		asserts.Equal(io.EOF, err)
+
+		size, err := r.Seek(0, io.SeekEnd)
+		asserts.NoError(err)
+		asserts.EqualValues(len(content), size)
+
+		_, err = r.Seek(995, io.SeekStart)
+		asserts.NoError(err)
+		res, err := ioutil.ReadAll(r)
+		asserts.NoError(err)
+		asserts.Equal(content[995:], string(res))
+
+		_, err = r.Seek(-1, io.SeekStart)
+		asserts.Error(err)
+		asserts.NoError(r.Close())
+	}
+
+	// 服务端不支持分段请求
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(w, bytes.NewBufferString(content))
+		}))
+		defer server.Close()
+
+		r := NewParallelReader(context.Background(), NewClient(), server.URL, int64(len(content)), 2, 100)
+		_, err := ioutil.ReadAll(r)
+		asserts.Error(err)
+		asserts.NoError(r.Close())
+	}
+}
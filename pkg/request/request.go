@@ -15,6 +15,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -116,6 +117,21 @@ func (c *HTTPClient) Request(method, target string, body io.Reader, opts ...Opti
 		req.ContentLength = options.contentLength
 	}
 
+	// 向下游传递请求追踪信息
+	trace := util.TraceFromContext(options.ctx)
+	span := tracing.StartSpan(trace, method+" "+req.URL.Host, tracing.SpanKindClient)
+	if trace != nil && trace.TraceID != "" {
+		spanID := trace.SpanID
+		if span != nil {
+			spanID = span.SpanID
+			span.Attributes["http.method"] = method
+			span.Attributes["http.url"] = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+		}
+
+		req.Header.Set(tracing.RequestIDHeader, trace.RequestID)
+		req.Header.Set(tracing.TraceparentHeader, tracing.Traceparent(trace.TraceID, spanID))
+	}
+
 	// 签名请求
 	if options.sign != nil {
 		switch method {
@@ -135,9 +151,15 @@ func (c *HTTPClient) Request(method, target string, body io.Reader, opts ...Opti
 	// 发送请求
 	resp, err := client.Do(req)
 	if err != nil {
+		span.Finish(true)
 		return &Response{Err: err}
 	}
 
+	if span != nil {
+		span.Attributes["http.status_code"] = resp.StatusCode
+		span.Finish(resp.StatusCode >= 500)
+	}
+
 	return &Response{Err: nil, Response: resp}
 }
 
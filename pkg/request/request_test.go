@@ -7,11 +7,14 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/stretchr/testify/assert"
 	testMock "github.com/stretchr/testify/mock"
 )
@@ -89,6 +92,25 @@ func TestHTTPClient_Request(t *testing.T) {
 		asserts.Nil(resp.Response)
 	}
 
+	// 传递请求追踪信息
+	{
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+		}))
+		defer server.Close()
+
+		trace := &util.RequestTrace{RequestID: "req", TraceID: tracing.NewTraceID(), SpanID: tracing.NewSpanID()}
+		resp := client.Request(
+			"GET",
+			server.URL,
+			nil,
+			WithContext(util.WithRequestTrace(context.Background(), trace)),
+		)
+		asserts.NoError(resp.Err)
+		asserts.Equal("req", header.Get(tracing.RequestIDHeader))
+		asserts.Equal(tracing.Traceparent(trace.TraceID, trace.SpanID), header.Get(tracing.TraceparentHeader))
+	}
 }
 
 func TestResponse_GetResponse(t *testing.T) {
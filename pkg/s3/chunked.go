@@ -0,0 +1,133 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxChunkSize aws-chunked 编码中单个分块的最大长度
+const maxChunkSize = 16 << 20
+
+// chunkedReader 解码 aws-chunked 编码的正文，sig 不为空时逐块校验分块签名
+type chunkedReader struct {
+	reader  *bufio.Reader
+	sig     *Signature
+	key     []byte
+	prevSig string
+	buf     []byte
+	done    bool
+	err     error
+}
+
+func newChunkedReader(body io.Reader, sig *Signature, key []byte) *chunkedReader {
+	reader := &chunkedReader{reader: bufio.NewReader(body), sig: sig, key: key}
+	if sig != nil {
+		reader.prevSig = sig.Signature
+	}
+	return reader
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		if r.done {
+			return 0, io.EOF
+		}
+
+		r.err = r.nextChunk()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// nextChunk 读取并校验下一个分块
+func (r *chunkedReader) nextChunk() error {
+	header, err := r.readLine()
+	if err != nil {
+		return ErrIncompleteBody
+	}
+
+	sizeField, extension, _ := strings.Cut(header, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return ErrIncompleteBody.WithMessage("Invalid chunk size.")
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r.reader, data); err != nil {
+		return ErrIncompleteBody
+	}
+
+	if r.sig != nil {
+		signature := strings.TrimPrefix(strings.TrimSpace(extension), "chunk-signature=")
+		if !hmac.Equal([]byte(signature), []byte(r.chunkSignature(data))) {
+			return ErrSignatureDoesNotMatch
+		}
+		r.prevSig = signature
+	}
+
+	if size == 0 {
+		r.done = true
+		return r.skipTrailer()
+	}
+
+	if line, err := r.readLine(); err != nil || line != "" {
+		return ErrIncompleteBody.WithMessage("Malformed chunk.")
+	}
+
+	r.buf = data
+	return nil
+}
+
+// chunkSignature 计算分块签名
+func (r *chunkedReader) chunkSignature(data []byte) string {
+	hash := sha256.Sum256(data)
+	stringToSign := strings.Join([]string{
+		signAlgorithm + "-PAYLOAD",
+		r.sig.Date.Format(amzDateFormat),
+		r.sig.Scope,
+		r.prevSig,
+		emptySHA256,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+	return hex.EncodeToString(hmacSHA256(r.key, stringToSign))
+}
+
+// skipTrailer 跳过末尾分块后的尾部请求头，直至空行
+func (r *chunkedReader) skipTrailer() error {
+	for {
+		line, err := r.readLine()
+		if err == io.EOF || (err == nil && line == "") {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readLine 读取以 CRLF 结尾的一行
+func (r *chunkedReader) readLine() (string, error) {
+	line, err := r.reader.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			return "", io.EOF
+		}
+		return "", ErrIncompleteBody
+	}
+
+	return string(bytes.TrimRight(line, "\r\n")), nil
+}
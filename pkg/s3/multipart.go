@@ -0,0 +1,228 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// MaxPartNumber 分片序号的最大值
+	MaxPartNumber = 10000
+	// multipartMetaFile 分片上传信息文件名
+	multipartMetaFile = "upload.json"
+)
+
+// Multipart 暂存于临时目录中的分片上传，每个分片以 "<序号>.<MD5>" 命名
+type Multipart struct {
+	ID          string `json:"-"`
+	UserID      uint   `json:"uid"`
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	ContentType string `json:"content_type,omitempty"`
+
+	dir string
+}
+
+// CreateMultipart 在 root 下创建分片上传的暂存目录
+func CreateMultipart(root string, upload *Multipart) error {
+	upload.ID = uuid.Must(uuid.NewV4()).String()
+	upload.dir = filepath.Join(root, upload.ID)
+	if err := os.MkdirAll(upload.dir, 0700); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(upload.dir, multipartMetaFile), meta, 0600)
+}
+
+// GetMultipart 读取 root 下的分片上传，不存在时返回 ErrNoSuchUpload
+func GetMultipart(root, id string) (*Multipart, error) {
+	if _, err := uuid.FromString(id); err != nil {
+		return nil, ErrNoSuchUpload
+	}
+
+	dir := filepath.Join(root, id)
+	meta, err := os.ReadFile(filepath.Join(dir, multipartMetaFile))
+	if err != nil {
+		return nil, ErrNoSuchUpload
+	}
+
+	upload := &Multipart{ID: id, dir: dir}
+	if err := json.Unmarshal(meta, upload); err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// WritePart 写入分片，已存在的同序号分片会被替换，返回分片的 ETag
+func (upload *Multipart) WritePart(number int, body io.Reader) (string, error) {
+	if number < 1 || number > MaxPartNumber {
+		return "", ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and 10000.")
+	}
+
+	temp, err := os.CreateTemp(upload.dir, "part-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(temp, hash), body)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if old, ok := upload.part(number); ok {
+		os.Remove(old)
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if err := os.Rename(temp.Name(), filepath.Join(upload.dir, strconv.Itoa(number)+"."+sum)); err != nil {
+		return "", err
+	}
+
+	return `"` + sum + `"`, nil
+}
+
+// Size 返回已上传分片的总大小
+func (upload *Multipart) Size() (uint64, error) {
+	entries, err := os.ReadDir(upload.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	for _, entry := range entries {
+		if _, _, ok := parsePartName(entry.Name()); !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return 0, err
+		}
+		total += uint64(info.Size())
+	}
+
+	return total, nil
+}
+
+// Open 按 parts 的顺序打开分片，返回拼接后的内容、总大小及合并后的 ETag
+func (upload *Multipart) Open(parts []CompletePart) (io.ReadCloser, uint64, string, error) {
+	if len(parts) == 0 {
+		return nil, 0, "", ErrMalformedXML.WithMessage("You must specify at least one part.")
+	}
+
+	var (
+		size    uint64
+		digests []byte
+		files   = make(multiFile, 0, len(parts))
+	)
+	for i, part := range parts {
+		if i > 0 && part.PartNumber <= parts[i-1].PartNumber {
+			files.Close()
+			return nil, 0, "", ErrInvalidPartOrder
+		}
+
+		name, ok := upload.part(part.PartNumber)
+		_, sum, _ := parsePartName(filepath.Base(name))
+		if !ok || !strings.EqualFold(strings.Trim(part.ETag, `"`), sum) {
+			files.Close()
+			return nil, 0, "", ErrInvalidPart
+		}
+
+		file, err := os.Open(name)
+		if err != nil {
+			files.Close()
+			return nil, 0, "", err
+		}
+		files = append(files, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			files.Close()
+			return nil, 0, "", err
+		}
+		size += uint64(info.Size())
+
+		digest, _ := hex.DecodeString(sum)
+		digests = append(digests, digest...)
+	}
+
+	etag := md5.Sum(digests)
+	return newMultiFileReader(files), size, fmt.Sprintf(`"%s-%d"`, hex.EncodeToString(etag[:]), len(parts)), nil
+}
+
+// Remove 删除分片上传的暂存目录
+func (upload *Multipart) Remove() error {
+	return os.RemoveAll(upload.dir)
+}
+
+// part 返回给定序号的分片文件路径
+func (upload *Multipart) part(number int) (string, bool) {
+	matches, _ := filepath.Glob(filepath.Join(upload.dir, strconv.Itoa(number)+".*"))
+	for _, match := range matches {
+		if n, _, ok := parsePartName(filepath.Base(match)); ok && n == number {
+			return match, true
+		}
+	}
+
+	return "", false
+}
+
+// parsePartName 解析分片文件名中的序号与 MD5
+func parsePartName(name string) (int, string, bool) {
+	numberField, sum, ok := strings.Cut(name, ".")
+	if !ok || len(sum) != md5.Size*2 {
+		return 0, "", false
+	}
+
+	number, err := strconv.Atoi(numberField)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return number, sum, true
+}
+
+// multiFile 已打开的分片文件
+type multiFile []*os.File
+
+func (files multiFile) Close() error {
+	for _, file := range files {
+		file.Close()
+	}
+	return nil
+}
+
+// multiFileReader 依次读取多个文件，关闭时关闭所有文件
+type multiFileReader struct {
+	io.Reader
+	multiFile
+}
+
+func newMultiFileReader(files multiFile) *multiFileReader {
+	readers := make([]io.Reader, len(files))
+	for i, file := range files {
+		readers[i] = file
+	}
+
+	return &multiFileReader{Reader: io.MultiReader(readers...), multiFile: files}
+}
@@ -0,0 +1,309 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// signAlgorithm 支持的签名算法，仅支持 AWS Signature Version 4
+	signAlgorithm = "AWS4-HMAC-SHA256"
+	// amzDateFormat X-Amz-Date 的时间格式
+	amzDateFormat = "20060102T150405Z"
+	// maxClockSkew 允许的客户端与服务端时间差
+	maxClockSkew = 15 * time.Minute
+	// maxPresignExpires 预签名 URL 的最长有效期
+	maxPresignExpires = 7 * 24 * time.Hour
+)
+
+// 请求正文的签名方式，即 x-amz-content-sha256 的取值
+const (
+	UnsignedPayload          = "UNSIGNED-PAYLOAD"
+	StreamingPayload         = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	StreamingUnsignedTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+)
+
+// emptySHA256 空内容的 SHA256
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Signature 解析后的请求签名
+type Signature struct {
+	// AccessKey 访问密钥 ID
+	AccessKey string
+	// Scope 凭证范围，形如 20130524/us-east-1/s3/aws4_request
+	Scope         string
+	SignedHeaders []string
+	Signature     string
+	// Date 请求签名时间
+	Date time.Time
+	// PayloadHash 请求正文的 SHA256 或签名方式
+	PayloadHash string
+	// Presigned 是否为预签名 URL
+	Presigned bool
+}
+
+// ParseSignature 从 Authorization 请求头或预签名 URL 参数中解析签名，并检查签名时间是否有效
+func ParseSignature(r *http.Request, now time.Time) (*Signature, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != "" {
+		return parsePresigned(query, now)
+	}
+
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return nil, ErrAccessDenied
+	}
+
+	if !strings.HasPrefix(auth, signAlgorithm+" ") {
+		return nil, ErrAuthorizationHeaderMalformed.WithMessage("Only AWS Signature Version 4 is supported.")
+	}
+
+	sig := &Signature{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signAlgorithm+" "), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "Credential":
+			sig.AccessKey, sig.Scope = parseCredential(value)
+		case "SignedHeaders":
+			sig.SignedHeaders = strings.Split(value, ";")
+		case "Signature":
+			sig.Signature = value
+		}
+	}
+
+	if sig.AccessKey == "" || sig.Scope == "" || len(sig.SignedHeaders) == 0 || sig.Signature == "" {
+		return nil, ErrAuthorizationHeaderMalformed
+	}
+
+	date, err := time.Parse(amzDateFormat, r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, ErrMissingSecurityHeader.WithMessage("Missing or invalid X-Amz-Date header.")
+	}
+	sig.Date = date
+
+	if date.Sub(now) > maxClockSkew || now.Sub(date) > maxClockSkew {
+		return nil, ErrRequestTimeTooSkewed
+	}
+
+	sig.PayloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if sig.PayloadHash == "" {
+		return nil, ErrMissingSecurityHeader.WithMessage("Missing required header x-amz-content-sha256.")
+	}
+
+	return sig, nil
+}
+
+// parsePresigned 解析预签名 URL 参数
+func parsePresigned(query url.Values, now time.Time) (*Signature, error) {
+	if query.Get("X-Amz-Algorithm") != signAlgorithm {
+		return nil, ErrAuthorizationHeaderMalformed.WithMessage("Only AWS Signature Version 4 is supported.")
+	}
+
+	sig := &Signature{
+		SignedHeaders: strings.Split(query.Get("X-Amz-SignedHeaders"), ";"),
+		Signature:     query.Get("X-Amz-Signature"),
+		PayloadHash:   UnsignedPayload,
+		Presigned:     true,
+	}
+	sig.AccessKey, sig.Scope = parseCredential(query.Get("X-Amz-Credential"))
+	if sig.AccessKey == "" || sig.Scope == "" || sig.Signature == "" {
+		return nil, ErrAuthorizationHeaderMalformed
+	}
+
+	date, err := time.Parse(amzDateFormat, query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, ErrMissingSecurityHeader.WithMessage("Missing or invalid X-Amz-Date parameter.")
+	}
+	sig.Date = date
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 || time.Duration(expires)*time.Second > maxPresignExpires {
+		return nil, ErrAuthorizationHeaderMalformed.WithMessage("Invalid X-Amz-Expires parameter.")
+	}
+
+	if date.Sub(now) > maxClockSkew {
+		return nil, ErrRequestTimeTooSkewed
+	}
+
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return nil, ErrRequestExpired
+	}
+
+	return sig, nil
+}
+
+// parseCredential 解析凭证，返回访问密钥 ID 与凭证范围。访问密钥 ID 可能包含 '/'，
+// 凭证范围固定为最后四段
+func parseCredential(credential string) (string, string) {
+	parts := strings.Split(credential, "/")
+	if len(parts) < 5 || parts[len(parts)-1] != "aws4_request" {
+		return "", ""
+	}
+
+	return strings.Join(parts[:len(parts)-4], "/"), strings.Join(parts[len(parts)-4:], "/")
+}
+
+// Verify 使用密钥 secret 校验请求签名
+func (sig *Signature) Verify(r *http.Request, secret string) bool {
+	expected := hex.EncodeToString(hmacSHA256(sig.signingKey(secret), sig.stringToSign(r)))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(sig.Signature)) == 1
+}
+
+// Body 返回校验正文签名或摘要的请求正文，aws-chunked 编码的正文会被解码。
+// 正文与签名不符时，读取到末尾会返回 ErrBadDigest 或 ErrSignatureDoesNotMatch
+func (sig *Signature) Body(r *http.Request, secret string) (io.Reader, error) {
+	switch sig.PayloadHash {
+	case UnsignedPayload:
+		return r.Body, nil
+	case StreamingPayload:
+		return newChunkedReader(r.Body, sig, sig.signingKey(secret)), nil
+	case StreamingUnsignedTrailer:
+		return newChunkedReader(r.Body, nil, nil), nil
+	}
+
+	expected, err := hex.DecodeString(sig.PayloadHash)
+	if err != nil || len(expected) != sha256.Size {
+		return nil, ErrNotImplemented.WithMessage("Unsupported x-amz-content-sha256 value.")
+	}
+
+	return &digestReader{reader: r.Body, hash: sha256.New(), expected: expected}, nil
+}
+
+// DecodedLength 返回请求正文解码后的长度，未知时返回 -1
+func DecodedLength(r *http.Request) int64 {
+	switch r.Header.Get("X-Amz-Content-Sha256") {
+	case StreamingPayload, StreamingUnsignedTrailer:
+		length, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return -1
+		}
+		return length
+	}
+
+	return r.ContentLength
+}
+
+// signingKey 计算签名密钥
+func (sig *Signature) signingKey(secret string) []byte {
+	key := []byte("AWS4" + secret)
+	for _, part := range strings.Split(sig.Scope, "/") {
+		key = hmacSHA256(key, part)
+	}
+	return key
+}
+
+// stringToSign 计算待签名字符串
+func (sig *Signature) stringToSign(r *http.Request) string {
+	hash := sha256.Sum256([]byte(sig.canonicalRequest(r)))
+	return strings.Join([]string{
+		signAlgorithm,
+		sig.Date.Format(amzDateFormat),
+		sig.Scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+}
+
+// canonicalRequest 计算规范请求
+func (sig *Signature) canonicalRequest(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("X-Amz-Signature")
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	headers := make([]string, 0, len(sig.SignedHeaders))
+	for _, name := range sig.SignedHeaders {
+		headers = append(headers, name+":"+canonicalHeaderValue(r, name))
+	}
+
+	payload := sig.PayloadHash
+	if sig.Presigned {
+		payload = UnsignedPayload
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		uriEncode(r.URL.Path, false),
+		strings.Join(params, "&"),
+		strings.Join(headers, "\n") + "\n",
+		strings.Join(sig.SignedHeaders, ";"),
+		payload,
+	}, "\n")
+}
+
+// canonicalHeaderValue 返回规范化的请求头值
+func canonicalHeaderValue(r *http.Request, name string) string {
+	switch name {
+	case "host":
+		return r.Host
+	case "content-length":
+		if r.Header.Get("Content-Length") == "" && r.ContentLength >= 0 {
+			return strconv.FormatInt(r.ContentLength, 10)
+		}
+	}
+
+	values := make([]string, 0, 1)
+	for _, value := range r.Header.Values(name) {
+		values = append(values, strings.Join(strings.Fields(value), " "))
+	}
+	return strings.Join(values, ",")
+}
+
+// uriEncode 按 AWS 的规则编码 URI，除非保留字符外全部编码，encodeSlash 为假时保留 '/'
+func uriEncode(s string, encodeSlash bool) string {
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			builder.WriteByte(c)
+			continue
+		}
+		builder.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return builder.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// digestReader 读取到末尾时校验正文的 SHA256
+type digestReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	expected []byte
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && !hmac.Equal(r.hash.Sum(nil), r.expected) {
+		return n, ErrBadDigest
+	}
+	return n, err
+}
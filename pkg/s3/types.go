@@ -0,0 +1,276 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// XMLNS S3 响应的 XML 命名空间
+const XMLNS = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// TimeFormat S3 响应中时间的格式
+const TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// FormatTime 格式化 S3 响应中的时间
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// EncodeKey 按 encoding-type=url 编码列出的对象键
+func EncodeKey(key string) string {
+	return uriEncode(key, false)
+}
+
+// Owner 资源所有者
+type Owner struct {
+	ID          string `xml:"ID"`
+	DisplayName string `xml:"DisplayName"`
+}
+
+// Bucket 存储桶
+type Bucket struct {
+	Name         string `xml:"Name"`
+	CreationDate string `xml:"CreationDate"`
+}
+
+// ListAllMyBucketsResult ListBuckets 响应
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"ListAllMyBucketsResult"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Owner   Owner    `xml:"Owner"`
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+// LocationConstraint GetBucketLocation 响应
+type LocationConstraint struct {
+	XMLName  xml.Name `xml:"LocationConstraint"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:",chardata"`
+}
+
+// Object 列出的对象
+type Object struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         uint64 `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// CommonPrefix 按分隔符归并的公共前缀
+type CommonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
+
+// ListBucketResult ListObjects 响应
+type ListBucketResult struct {
+	XMLName        xml.Name       `xml:"ListBucketResult"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	Name           string         `xml:"Name"`
+	Prefix         string         `xml:"Prefix"`
+	Marker         string         `xml:"Marker"`
+	NextMarker     string         `xml:"NextMarker,omitempty"`
+	MaxKeys        int            `xml:"MaxKeys"`
+	Delimiter      string         `xml:"Delimiter,omitempty"`
+	IsTruncated    bool           `xml:"IsTruncated"`
+	EncodingType   string         `xml:"EncodingType,omitempty"`
+	Contents       []Object       `xml:"Contents"`
+	CommonPrefixes []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// ListBucketResultV2 ListObjectsV2 响应
+type ListBucketResultV2 struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	Xmlns                 string         `xml:"xmlns,attr"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	StartAfter            string         `xml:"StartAfter,omitempty"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	Delimiter             string         `xml:"Delimiter,omitempty"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	EncodingType          string         `xml:"EncodingType,omitempty"`
+	Contents              []Object       `xml:"Contents"`
+	CommonPrefixes        []CommonPrefix `xml:"CommonPrefixes"`
+}
+
+// CopyObjectResult CopyObject 响应
+type CopyObjectResult struct {
+	XMLName      xml.Name `xml:"CopyObjectResult"`
+	Xmlns        string   `xml:"xmlns,attr"`
+	LastModified string   `xml:"LastModified"`
+	ETag         string   `xml:"ETag"`
+}
+
+// ObjectIdentifier 待删除的对象
+type ObjectIdentifier struct {
+	Key string `xml:"Key"`
+}
+
+// Delete DeleteObjects 请求
+type Delete struct {
+	XMLName xml.Name           `xml:"Delete"`
+	Quiet   bool               `xml:"Quiet"`
+	Objects []ObjectIdentifier `xml:"Object"`
+}
+
+// DeleteError 删除失败的对象
+type DeleteError struct {
+	Key     string `xml:"Key"`
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// DeleteResult DeleteObjects 响应
+type DeleteResult struct {
+	XMLName xml.Name           `xml:"DeleteResult"`
+	Xmlns   string             `xml:"xmlns,attr"`
+	Deleted []ObjectIdentifier `xml:"Deleted"`
+	Errors  []DeleteError      `xml:"Error"`
+}
+
+// InitiateMultipartUploadResult CreateMultipartUpload 响应
+type InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+// CompletePart 合并的分片
+type CompletePart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// CompleteMultipartUpload CompleteMultipartUpload 请求
+type CompleteMultipartUpload struct {
+	XMLName xml.Name       `xml:"CompleteMultipartUpload"`
+	Parts   []CompletePart `xml:"Part"`
+}
+
+// CompleteMultipartUploadResult CompleteMultipartUpload 响应
+type CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+// Error S3 错误响应
+type Error struct {
+	XMLName   xml.Name `xml:"Error"`
+	Code      string   `xml:"Code"`
+	Message   string   `xml:"Message"`
+	Resource  string   `xml:"Resource,omitempty"`
+	RequestID string   `xml:"RequestId,omitempty"`
+}
+
+// Response 返回给 S3 客户端的响应，Status 为 0 时表示响应已直接写出
+type Response struct {
+	Status int
+	Body   interface{}
+}
+
+// OK 成功响应
+func OK(status int, body interface{}) Response {
+	return Response{Status: status, Body: body}
+}
+
+// Write 写出响应，HEAD 请求不写出正文
+func (res Response) Write(w http.ResponseWriter, r *http.Request) {
+	if res.Status == 0 {
+		return
+	}
+
+	if body, ok := res.Body.(*Error); ok {
+		body.Resource = r.URL.Path
+		body.RequestID = w.Header().Get("X-Amz-Request-Id")
+	}
+
+	if res.Body == nil || r.Method == http.MethodHead {
+		w.WriteHeader(res.Status)
+		return
+	}
+
+	content, err := xml.Marshal(res.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(res.Status)
+	w.Write([]byte(xml.Header))
+	w.Write(content)
+}
+
+// Err 错误响应，err 不是 *APIError 时作为内部错误返回
+func Err(err error) Response {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		apiErr = ErrInternalError.WithMessage(err.Error())
+	}
+
+	return Response{
+		Status: apiErr.Status,
+		Body: &Error{
+			Code:    apiErr.Code,
+			Message: apiErr.Message,
+		},
+	}
+}
+
+// APIError S3 错误
+type APIError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (err *APIError) Error() string {
+	return err.Message
+}
+
+// WithMessage 返回替换了错误信息的副本
+func (err *APIError) WithMessage(msg string) *APIError {
+	return &APIError{Status: err.Status, Code: err.Code, Message: msg}
+}
+
+// 常用错误，见 https://docs.aws.amazon.com/AmazonS3/latest/API/ErrorResponses.html
+var (
+	ErrAccessDenied                 = &APIError{http.StatusForbidden, "AccessDenied", "Access Denied."}
+	ErrAuthorizationHeaderMalformed = &APIError{http.StatusBadRequest, "AuthorizationHeaderMalformed", "The authorization header is malformed."}
+	ErrBadDigest                    = &APIError{http.StatusBadRequest, "XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed."}
+	ErrBucketAlreadyOwnedByYou      = &APIError{http.StatusConflict, "BucketAlreadyOwnedByYou", "Your previous request to create the named bucket succeeded and you already own it."}
+	ErrBucketNotEmpty               = &APIError{http.StatusConflict, "BucketNotEmpty", "The bucket you tried to delete is not empty."}
+	ErrEntityTooLarge               = &APIError{http.StatusBadRequest, "EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size."}
+	ErrIncompleteBody               = &APIError{http.StatusBadRequest, "IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header."}
+	ErrInternalError                = &APIError{http.StatusInternalServerError, "InternalError", "We encountered an internal error, please try again."}
+	ErrInvalidAccessKeyID           = &APIError{http.StatusForbidden, "InvalidAccessKeyId", "The access key ID you provided does not exist in our records."}
+	ErrInvalidArgument              = &APIError{http.StatusBadRequest, "InvalidArgument", "Invalid argument."}
+	ErrInvalidBucketName            = &APIError{http.StatusBadRequest, "InvalidBucketName", "The specified bucket is not valid."}
+	ErrInvalidPart                  = &APIError{http.StatusBadRequest, "InvalidPart", "One or more of the specified parts could not be found."}
+	ErrInvalidPartOrder             = &APIError{http.StatusBadRequest, "InvalidPartOrder", "The list of parts was not in ascending order."}
+	ErrInvalidRequest               = &APIError{http.StatusBadRequest, "InvalidRequest", "Invalid request."}
+	ErrMalformedXML                 = &APIError{http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema."}
+	ErrMethodNotAllowed             = &APIError{http.StatusMethodNotAllowed, "MethodNotAllowed", "The specified method is not allowed against this resource."}
+	ErrMissingContentLength         = &APIError{http.StatusLengthRequired, "MissingContentLength", "You must provide the Content-Length HTTP header."}
+	ErrMissingSecurityHeader        = &APIError{http.StatusBadRequest, "MissingSecurityHeader", "Your request is missing a required header."}
+	ErrNoSuchBucket                 = &APIError{http.StatusNotFound, "NoSuchBucket", "The specified bucket does not exist."}
+	ErrNoSuchKey                    = &APIError{http.StatusNotFound, "NoSuchKey", "The specified key does not exist."}
+	ErrNoSuchUpload                 = &APIError{http.StatusNotFound, "NoSuchUpload", "The specified multipart upload does not exist."}
+	ErrNotImplemented               = &APIError{http.StatusNotImplemented, "NotImplemented", "A header or query you provided implies functionality that is not implemented."}
+	ErrOperationAborted             = &APIError{http.StatusConflict, "OperationAborted", "A conflicting conditional operation is currently in progress against this resource."}
+	ErrQuotaExceeded                = &APIError{http.StatusForbidden, "QuotaExceeded", "Your storage quota has been exceeded."}
+	ErrRequestExpired               = &APIError{http.StatusForbidden, "AccessDenied", "Request has expired."}
+	ErrRequestTimeTooSkewed         = &APIError{http.StatusForbidden, "RequestTimeTooSkewed", "The difference between the request time and the server's time is too large."}
+	ErrSignatureDoesNotMatch        = &APIError{http.StatusForbidden, "SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided."}
+)
@@ -0,0 +1,45 @@
+package scim
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedFilter 不支持的过滤表达式
+var ErrUnsupportedFilter = errors.New("only filters in the form of 'attribute eq \"value\"' are supported")
+
+// Filter 过滤条件，仅支持身份提供方常用的相等比较
+type Filter struct {
+	// Attribute 属性名，已转为小写
+	Attribute string
+	Value     string
+}
+
+// ParseFilter 解析过滤表达式，如 userName eq "user@example.com"。
+// 表达式为空时返回 nil
+func ParseFilter(filter string) (*Filter, error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return nil, nil
+	}
+
+	parts := strings.SplitN(filter, " ", 3)
+	if len(parts) != 3 || !strings.EqualFold(parts[1], "eq") {
+		return nil, ErrUnsupportedFilter
+	}
+
+	value := strings.TrimSpace(parts[2])
+	if strings.HasPrefix(value, "\"") {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, ErrUnsupportedFilter
+		}
+		value = unquoted
+	}
+
+	return &Filter{
+		Attribute: strings.ToLower(parts[0]),
+		Value:     value,
+	}, nil
+}
@@ -0,0 +1,38 @@
+package scim
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// GroupRule SCIM 用户组到 Cloudreve 用户组的映射规则
+type GroupRule struct {
+	// Pattern 匹配 SCIM 用户组名称的通配符表达式，不区分大小写
+	Pattern string `json:"pattern"`
+	// Group 对应的 Cloudreve 用户组ID
+	Group uint `json:"group"`
+}
+
+// ParseGroupRules 解析映射规则设置
+func ParseGroupRules(raw string) ([]GroupRule, error) {
+	var rules []GroupRule
+	if strings.TrimSpace(raw) == "" {
+		return rules, nil
+	}
+
+	err := json.Unmarshal([]byte(raw), &rules)
+	return rules, err
+}
+
+// ResolveGroup 按顺序匹配规则，返回首个匹配的 Cloudreve 用户组ID
+func ResolveGroup(rules []GroupRule, displayName string) (uint, bool) {
+	name := strings.ToLower(displayName)
+	for _, rule := range rules {
+		if ok, _ := path.Match(strings.ToLower(rule.Pattern), name); ok {
+			return rule.Group, true
+		}
+	}
+
+	return 0, false
+}
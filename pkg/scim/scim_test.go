@@ -0,0 +1,103 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilter(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空表达式
+	{
+		filter, err := ParseFilter(" ")
+		asserts.NoError(err)
+		asserts.Nil(filter)
+	}
+
+	// 正常
+	{
+		filter, err := ParseFilter(`userName eq "user@cloudreve.org"`)
+		asserts.NoError(err)
+		asserts.Equal("username", filter.Attribute)
+		asserts.Equal("user@cloudreve.org", filter.Value)
+	}
+
+	// 包含空格的值
+	{
+		filter, err := ParseFilter(`displayName EQ "Dev Team"`)
+		asserts.NoError(err)
+		asserts.Equal("displayname", filter.Attribute)
+		asserts.Equal("Dev Team", filter.Value)
+	}
+
+	// 不支持的运算符
+	{
+		_, err := ParseFilter(`userName sw "user"`)
+		asserts.Equal(ErrUnsupportedFilter, err)
+	}
+
+	// 引号不完整
+	{
+		_, err := ParseFilter(`userName eq "user`)
+		asserts.Equal(ErrUnsupportedFilter, err)
+	}
+}
+
+func TestResolveGroup(t *testing.T) {
+	asserts := assert.New(t)
+
+	rules, err := ParseGroupRules(`[{"pattern":"admins","group":1},{"pattern":"eng-*","group":4}]`)
+	asserts.NoError(err)
+	asserts.Len(rules, 2)
+
+	group, ok := ResolveGroup(rules, "Admins")
+	asserts.True(ok)
+	asserts.EqualValues(1, group)
+
+	group, ok = ResolveGroup(rules, "eng-storage")
+	asserts.True(ok)
+	asserts.EqualValues(4, group)
+
+	_, ok = ResolveGroup(rules, "sales")
+	asserts.False(ok)
+
+	// 空设置
+	rules, err = ParseGroupRules("")
+	asserts.NoError(err)
+	asserts.Empty(rules)
+
+	// 格式错误
+	_, err = ParseGroupRules("{")
+	asserts.Error(err)
+}
+
+func TestParseBool(t *testing.T) {
+	asserts := assert.New(t)
+
+	value, err := ParseBool(json.RawMessage(`false`))
+	asserts.NoError(err)
+	asserts.False(value)
+
+	value, err = ParseBool(json.RawMessage(`"True"`))
+	asserts.NoError(err)
+	asserts.True(value)
+
+	_, err = ParseBool(json.RawMessage(`1`))
+	asserts.Error(err)
+}
+
+func TestErr(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := Err(http.StatusConflict, ErrUniqueness, "exists")
+	asserts.Equal(http.StatusConflict, res.Status)
+	asserts.Equal("409", res.Body.(*Error).Status)
+	asserts.Equal(ErrUniqueness, res.Body.(*Error).ScimType)
+
+	res = NotFound("not found")
+	asserts.Equal(http.StatusNotFound, res.Status)
+}
@@ -0,0 +1,34 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// FolderACL 目录 ACL 条目序列化
+type FolderACL struct {
+	ID          uint      `json:"id"`
+	Type        string    `json:"type"`
+	Principal   string    `json:"principal"`
+	Effect      string    `json:"effect"`
+	Permissions []string  `json:"permissions"`
+	CreateDate  time.Time `json:"create_date"`
+}
+
+// BuildFolderACLList 序列化目录 ACL 条目列表，principals 为各条目授权对象的可读名称
+func BuildFolderACLList(acls []model.FolderACL, principals map[uint]string) Response {
+	res := make([]FolderACL, 0, len(acls))
+	for _, acl := range acls {
+		res = append(res, FolderACL{
+			ID:          acl.ID,
+			Type:        acl.PrincipalType,
+			Principal:   principals[acl.ID],
+			Effect:      acl.Effect,
+			Permissions: acl.PermissionNames(),
+			CreateDate:  acl.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,26 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFolderACLList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildFolderACLList([]model.FolderACL{
+		{
+			Model:         gorm.Model{ID: 1},
+			PrincipalType: model.ACLPrincipalGroup,
+			Effect:        model.ACLDeny,
+			Permissions:   model.ACLWrite | model.ACLShare,
+		},
+	}, map[uint]string{1: "Users"})
+	items := res.Data.([]FolderACL)
+	a.Len(items, 1)
+	a.Equal("Users", items[0].Principal)
+	a.Equal(model.ACLDeny, items[0].Effect)
+	a.Equal([]string{"write", "share"}, items[0].Permissions)
+}
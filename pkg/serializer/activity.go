@@ -0,0 +1,84 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// LoginSession 登录会话序列化
+type LoginSession struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	LastActive time.Time `json:"last_active"`
+	CreateDate time.Time `json:"create_date"`
+	// Current 是否为发起请求的会话
+	Current bool `json:"current"`
+}
+
+// Activity 用户动态序列化
+type Activity struct {
+	Type       string    `json:"type"`
+	ObjectType string    `json:"object_type"`
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       uint64    `json:"size,omitempty"`
+	Share      string    `json:"share,omitempty"`
+	IP         string    `json:"ip"`
+	Date       time.Time `json:"date"`
+}
+
+// ActivityList 用户动态列表
+type ActivityList struct {
+	Total int        `json:"total"`
+	Items []Activity `json:"items"`
+}
+
+// BuildLoginSessionList 序列化登录会话列表，current 为当前请求的会话令牌
+func BuildLoginSessionList(sessions []model.LoginSession, current string) Response {
+	res := make([]LoginSession, 0, len(sessions))
+	for _, session := range sessions {
+		res = append(res, LoginSession{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			LastActive: session.LastActive,
+			CreateDate: session.CreatedAt,
+			Current:    current != "" && session.Token == current,
+		})
+	}
+
+	return Response{Data: res}
+}
+
+// BuildActivityList 序列化用户动态列表
+func BuildActivityList(activities []model.Activity, total int) Response {
+	res := ActivityList{Total: total, Items: make([]Activity, 0, len(activities))}
+	for _, activity := range activities {
+		idType := hashid.FileID
+		if activity.ObjectType == model.ChangeObjectFolder {
+			idType = hashid.FolderID
+		}
+
+		item := Activity{
+			Type:       activity.Type,
+			ObjectType: activity.ObjectType,
+			ID:         hashid.HashID(activity.ObjectID, idType),
+			Name:       activity.Name,
+			Size:       activity.Size,
+			IP:         activity.IP,
+			Date:       activity.CreatedAt,
+		}
+		if activity.ShareID > 0 {
+			item.Share = hashid.HashID(activity.ShareID, hashid.ShareID)
+		}
+
+		res.Items = append(res.Items, item)
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,39 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLoginSessionList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildLoginSessionList([]model.LoginSession{{Token: "a", Device: "Chrome on Windows"}, {Token: "b"}}, "b")
+	list := res.Data.([]LoginSession)
+	asserts.Len(list, 2)
+	asserts.Equal("Chrome on Windows", list[0].Device)
+	asserts.False(list[0].Current)
+	asserts.True(list[1].Current)
+
+	list = BuildLoginSessionList([]model.LoginSession{{}}, "").Data.([]LoginSession)
+	asserts.False(list[0].Current)
+}
+
+func TestBuildActivityList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildActivityList([]model.Activity{
+		{Type: model.ActivityUpload, ObjectType: model.ChangeObjectFile, ObjectID: 1, Name: "a.txt"},
+		{Type: model.ActivityShare, ObjectType: model.ChangeObjectFolder, ObjectID: 2, ShareID: 3},
+	}, 10)
+	list := res.Data.(ActivityList)
+	asserts.Equal(10, list.Total)
+	asserts.Len(list.Items, 2)
+	asserts.Equal(hashid.HashID(1, hashid.FileID), list.Items[0].ID)
+	asserts.Empty(list.Items[0].Share)
+	asserts.Equal(hashid.HashID(2, hashid.FolderID), list.Items[1].ID)
+	asserts.Equal(hashid.HashID(3, hashid.ShareID), list.Items[1].Share)
+}
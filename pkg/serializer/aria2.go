@@ -83,35 +83,43 @@ func BuildDownloadingResponse(tasks []model.Download, intervals map[uint]int) Re
 	resp := make([]DownloadListResponse, 0, len(tasks))
 
 	for i := 0; i < len(tasks); i++ {
-		fileName := ""
-		if len(tasks[i].StatusInfo.Files) > 0 {
-			fileName = path.Base(tasks[i].StatusInfo.Files[0].Path)
-		}
-
-		// 过滤敏感信息
-		tasks[i].StatusInfo.Dir = ""
-		for i2 := 0; i2 < len(tasks[i].StatusInfo.Files); i2++ {
-			tasks[i].StatusInfo.Files[i2].Path = path.Base(tasks[i].StatusInfo.Files[i2].Path)
-		}
-
 		interval := 10
 		if actualInterval, ok := intervals[tasks[i].ID]; ok {
 			interval = actualInterval
 		}
 
-		resp = append(resp, DownloadListResponse{
-			UpdateTime:     tasks[i].UpdatedAt,
-			UpdateInterval: interval,
-			Name:           fileName,
-			Status:         tasks[i].Status,
-			Dst:            tasks[i].Dst,
-			Total:          tasks[i].TotalSize,
-			Downloaded:     tasks[i].DownloadedSize,
-			Speed:          tasks[i].Speed,
-			Info:           tasks[i].StatusInfo,
-			NodeName:       tasks[i].NodeName,
-		})
+		resp = append(resp, BuildDownloading(&tasks[i], interval))
 	}
 
 	return Response{Data: resp}
 }
+
+// BuildDownloading 构建正在下载的任务条目，interval 为状态更新间隔（秒）
+func BuildDownloading(task *model.Download, interval int) DownloadListResponse {
+	fileName := ""
+	if len(task.StatusInfo.Files) > 0 {
+		fileName = path.Base(task.StatusInfo.Files[0].Path)
+	}
+
+	// 过滤敏感信息
+	info := task.StatusInfo
+	info.Dir = ""
+	info.Files = make([]rpc.FileInfo, len(task.StatusInfo.Files))
+	for i := range task.StatusInfo.Files {
+		info.Files[i] = task.StatusInfo.Files[i]
+		info.Files[i].Path = path.Base(info.Files[i].Path)
+	}
+
+	return DownloadListResponse{
+		UpdateTime:     task.UpdatedAt,
+		UpdateInterval: interval,
+		Name:           fileName,
+		Status:         task.Status,
+		Dst:            task.Dst,
+		Total:          task.TotalSize,
+		Downloaded:     task.DownloadedSize,
+		Speed:          task.Speed,
+		Info:           info,
+		NodeName:       task.NodeName,
+	}
+}
@@ -93,3 +93,27 @@ func TestBuildDownloadingResponse(t *testing.T) {
 	asserts.Equal("name1.txt", res[1].Info.Files[0].Path)
 	asserts.Equal("name2.txt", res[1].Info.Files[1].Path)
 }
+
+func TestBuildDownloading(t *testing.T) {
+	asserts := assert.New(t)
+	task := &model.Download{
+		Status:         1,
+		TotalSize:      10,
+		DownloadedSize: 5,
+		StatusInfo: rpc.StatusInfo{
+			Dir:   "/tmp/1",
+			Files: []rpc.FileInfo{{Path: "/tmp/1/name.txt"}},
+		},
+	}
+
+	res := BuildDownloading(task, 3)
+	asserts.Equal("name.txt", res.Name)
+	asserts.Equal(3, res.UpdateInterval)
+	asserts.EqualValues(5, res.Downloaded)
+	asserts.Empty(res.Info.Dir)
+	asserts.Equal("name.txt", res.Info.Files[0].Path)
+
+	// 不修改原有任务的状态信息
+	asserts.Equal("/tmp/1", task.StatusInfo.Dir)
+	asserts.Equal("/tmp/1/name.txt", task.StatusInfo.Files[0].Path)
+}
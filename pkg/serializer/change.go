@@ -0,0 +1,66 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// Change 对象变更记录
+type Change struct {
+	Type       string    `json:"type"`
+	ObjectType string    `json:"object_type"`
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Parent     string    `json:"parent,omitempty"`
+	OldParent  string    `json:"old_parent,omitempty"`
+	OldName    string    `json:"old_name,omitempty"`
+	Size       uint64    `json:"size,omitempty"`
+	Date       time.Time `json:"date"`
+}
+
+// ChangeList 增量变更列表
+type ChangeList struct {
+	Changes []Change `json:"changes"`
+	// Cursor 下次拉取时使用的游标
+	Cursor  string `json:"cursor"`
+	HasMore bool   `json:"has_more"`
+}
+
+// BuildChangeList 构建变更列表响应，cursor 为当前已拉取到的位置
+func BuildChangeList(changes []model.Change, cursor uint, hasMore bool) Response {
+	res := ChangeList{
+		Changes: make([]Change, 0, len(changes)),
+		HasMore: hasMore,
+	}
+
+	for _, change := range changes {
+		idType := hashid.FileID
+		if change.ObjectType == model.ChangeObjectFolder {
+			idType = hashid.FolderID
+		}
+
+		item := Change{
+			Type:       change.Type,
+			ObjectType: change.ObjectType,
+			ID:         hashid.HashID(change.ObjectID, idType),
+			Name:       change.Name,
+			OldName:    change.OldName,
+			Size:       change.Size,
+			Date:       change.CreatedAt,
+		}
+		if change.ParentID > 0 {
+			item.Parent = hashid.HashID(change.ParentID, hashid.FolderID)
+		}
+		if change.OldParentID > 0 {
+			item.OldParent = hashid.HashID(change.OldParentID, hashid.FolderID)
+		}
+
+		res.Changes = append(res.Changes, item)
+		cursor = change.ID
+	}
+
+	res.Cursor = hashid.HashID(cursor, hashid.ChangeID)
+	return Response{Data: res}
+}
@@ -0,0 +1,36 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildChangeList(t *testing.T) {
+	a := assert.New(t)
+
+	// 无变更时保留原游标
+	{
+		res := BuildChangeList(nil, 5, false).Data.(ChangeList)
+		a.Empty(res.Changes)
+		a.Equal(hashid.HashID(5, hashid.ChangeID), res.Cursor)
+	}
+
+	// 有变更
+	{
+		res := BuildChangeList([]model.Change{
+			{Model: gorm.Model{ID: 6}, Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile, ObjectID: 1, ParentID: 2},
+			{Model: gorm.Model{ID: 7}, Type: model.ChangeMove, ObjectType: model.ChangeObjectFolder, ObjectID: 3, ParentID: 2, OldParentID: 4},
+		}, 5, true).Data.(ChangeList)
+		a.Len(res.Changes, 2)
+		a.True(res.HasMore)
+		a.Equal(hashid.HashID(7, hashid.ChangeID), res.Cursor)
+		a.Equal(hashid.HashID(1, hashid.FileID), res.Changes[0].ID)
+		a.Empty(res.Changes[0].OldParent)
+		a.Equal(hashid.HashID(3, hashid.FolderID), res.Changes[1].ID)
+		a.Equal(hashid.HashID(4, hashid.FolderID), res.Changes[1].OldParent)
+	}
+}
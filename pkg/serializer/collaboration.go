@@ -0,0 +1,89 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// Collaboration 共享出去的目录序列化
+type Collaboration struct {
+	ID         uint      `json:"id"`
+	Folder     string    `json:"folder"`
+	FolderName string    `json:"folder_name"`
+	User       string    `json:"user"`
+	UserNick   string    `json:"user_nick"`
+	Permission string    `json:"permission"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// SharedFolder 共享给当前用户的目录序列化
+type SharedFolder struct {
+	ID         uint          `json:"id"`
+	Name       string        `json:"name"`
+	Folder     string        `json:"folder"`
+	Owner      *shareCreator `json:"owner"`
+	Permission string        `json:"permission"`
+	CreateDate time.Time     `json:"create_date"`
+}
+
+// BuildCollaborationList 序列化共享出去的目录列表，目录或协作者已不存在的记录不包含在内
+func BuildCollaborationList(collabs []model.Collaboration) Response {
+	res := make([]Collaboration, 0, len(collabs))
+	for _, collab := range collabs {
+		if collab.Folder == nil || collab.User == nil {
+			continue
+		}
+
+		res = append(res, Collaboration{
+			ID:         collab.ID,
+			Folder:     hashid.HashID(collab.FolderID, hashid.FolderID),
+			FolderName: collab.Folder.Name,
+			User:       collab.User.Email,
+			UserNick:   collab.User.Nick,
+			Permission: collab.Permission,
+			CreateDate: collab.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
+
+// BuildSharedFolderList 序列化「与我共享」下的目录列表
+func BuildSharedFolderList(collabs []model.Collaboration) Response {
+	res := make([]SharedFolder, 0, len(collabs))
+	for _, collab := range collabs {
+		res = append(res, SharedFolder{
+			ID:     collab.ID,
+			Name:   collab.MountName,
+			Folder: hashid.HashID(collab.FolderID, hashid.FolderID),
+			Owner: &shareCreator{
+				Key:       hashid.HashID(collab.Owner.ID, hashid.UserID),
+				Nick:      collab.Owner.Nick,
+				GroupName: collab.Owner.Group.Name,
+			},
+			Permission: collab.Permission,
+			CreateDate: collab.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
+
+// BuildSharedRootObjects 将「与我共享」下的目录作为虚拟目录的子目录序列化
+func BuildSharedRootObjects(collabs []model.Collaboration, root string) Response {
+	objects := make([]Object, 0, len(collabs))
+	for _, collab := range collabs {
+		objects = append(objects, Object{
+			ID:         hashid.HashID(collab.FolderID, hashid.FolderID),
+			Name:       collab.MountName,
+			Path:       root,
+			Type:       "dir",
+			Date:       collab.Folder.UpdatedAt,
+			CreateDate: collab.Folder.CreatedAt,
+		})
+	}
+
+	return Response{Data: ObjectList{Objects: objects}}
+}
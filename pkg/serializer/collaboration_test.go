@@ -0,0 +1,57 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildCollaborationList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildCollaborationList([]model.Collaboration{
+		{
+			Model:      gorm.Model{ID: 1},
+			FolderID:   2,
+			Permission: model.CollaborationWrite,
+			Folder:     &model.Folder{Name: "Docs"},
+			User:       &model.User{Email: "a@a.com", Nick: "a"},
+		},
+		// 目录已不存在
+		{Model: gorm.Model{ID: 2}, User: &model.User{}},
+	})
+	items := res.Data.([]Collaboration)
+	a.Len(items, 1)
+	a.Equal("Docs", items[0].FolderName)
+	a.Equal("a@a.com", items[0].User)
+	a.Equal(model.CollaborationWrite, items[0].Permission)
+}
+
+func TestBuildSharedFolderList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildSharedFolderList([]model.Collaboration{
+		{
+			Model:      gorm.Model{ID: 1},
+			Permission: model.CollaborationRead,
+			MountName:  "Docs (2)",
+			Owner:      &model.User{Nick: "b"},
+		},
+	})
+	items := res.Data.([]SharedFolder)
+	a.Len(items, 1)
+	a.Equal("Docs (2)", items[0].Name)
+	a.Equal("b", items[0].Owner.Nick)
+}
+
+func TestBuildSharedRootObjects(t *testing.T) {
+	a := assert.New(t)
+	res := BuildSharedRootObjects([]model.Collaboration{
+		{MountName: "Docs", Folder: &model.Folder{}},
+	}, "/Shared with me")
+	list := res.Data.(ObjectList)
+	a.Len(list.Objects, 1)
+	a.Equal("Docs", list.Objects[0].Name)
+	a.Equal("/Shared with me", list.Objects[0].Path)
+	a.Equal("dir", list.Objects[0].Type)
+}
@@ -0,0 +1,22 @@
+package serializer
+
+import (
+	"encoding/gob"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// DownloadSession 经签名地址下载文件的会话
+type DownloadSession struct {
+	File model.File
+	// IP 绑定的客户端 IP，为空时不限制
+	IP string
+	// OneTime 是否仅可下载一次
+	OneTime bool
+	// SpeedLimit 创建会话的用户的下载限速，单位为字节每秒，0 为不限制
+	SpeedLimit int
+}
+
+func init() {
+	gob.Register(DownloadSession{})
+}
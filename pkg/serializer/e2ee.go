@@ -0,0 +1,73 @@
+package serializer
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// 目录密钥信封类型
+const (
+	EnvelopeTypeUser  = "user"
+	EnvelopeTypeShare = "share"
+)
+
+// KeyPair 用户端到端加密密钥对序列化，其他用户只能获取公钥
+type KeyPair struct {
+	User       string `json:"user"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+// EncryptedFolder 端到端加密目录序列化
+type EncryptedFolder struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	Algorithm    string `json:"algorithm"`
+	Envelope     string `json:"envelope,omitempty"`
+	EnvelopeType string `json:"envelope_type,omitempty"`
+	// Files 文件 ID 与加密名称的映射
+	Files map[string]string `json:"files"`
+	// Folders 目录 ID 与加密名称的映射
+	Folders map[string]string `json:"folders"`
+}
+
+// BuildKeyPair 序列化密钥对，withPrivate 为 false 时不返回私钥
+func BuildKeyPair(pair *model.UserKeyPair, withPrivate bool) KeyPair {
+	res := KeyPair{
+		User:      hashid.HashID(pair.UserID, hashid.UserID),
+		PublicKey: pair.PublicKey,
+	}
+	if withPrivate {
+		res.PrivateKey = pair.PrivateKey
+	}
+	return res
+}
+
+// BuildEncryptedFolder 序列化加密目录，envelope 为当前访问者可用的目录密钥信封
+func BuildEncryptedFolder(folder *model.EncryptedFolder, envelope *model.FolderKeyEnvelope, names []model.EncryptedName) EncryptedFolder {
+	res := EncryptedFolder{
+		ID:        hashid.HashID(folder.FolderID, hashid.FolderID),
+		Owner:     hashid.HashID(folder.OwnerID, hashid.UserID),
+		Algorithm: folder.Algorithm,
+		Files:     make(map[string]string),
+		Folders:   make(map[string]string),
+	}
+
+	if envelope != nil {
+		res.Envelope = envelope.Envelope
+		res.EnvelopeType = EnvelopeTypeUser
+		if envelope.ShareID > 0 {
+			res.EnvelopeType = EnvelopeTypeShare
+		}
+	}
+
+	for _, name := range names {
+		if name.ObjectType == model.EncryptedNameFolder {
+			res.Folders[hashid.HashID(name.ObjectID, hashid.FolderID)] = name.Name
+		} else {
+			res.Files[hashid.HashID(name.ObjectID, hashid.FileID)] = name.Name
+		}
+	}
+
+	return res
+}
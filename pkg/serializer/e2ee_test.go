@@ -0,0 +1,46 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildKeyPair(t *testing.T) {
+	a := assert.New(t)
+	pair := &model.UserKeyPair{UserID: 1, PublicKey: "pub", PrivateKey: "priv"}
+
+	a.Equal("priv", BuildKeyPair(pair, true).PrivateKey)
+	res := BuildKeyPair(pair, false)
+	a.Equal("pub", res.PublicKey)
+	a.Empty(res.PrivateKey)
+}
+
+func TestBuildEncryptedFolder(t *testing.T) {
+	a := assert.New(t)
+	folder := &model.EncryptedFolder{FolderID: 2, OwnerID: 1, Algorithm: "AES-GCM"}
+	names := []model.EncryptedName{
+		{ObjectType: model.EncryptedNameFile, ObjectID: 3, Name: "file"},
+		{ObjectType: model.EncryptedNameFolder, ObjectID: 4, Name: "dir"},
+	}
+
+	// 用户信封
+	res := BuildEncryptedFolder(folder, &model.FolderKeyEnvelope{UserID: 1, Envelope: "envelope"}, names)
+	a.Equal(hashid.HashID(2, hashid.FolderID), res.ID)
+	a.Equal("envelope", res.Envelope)
+	a.Equal(EnvelopeTypeUser, res.EnvelopeType)
+	a.Equal("file", res.Files[hashid.HashID(3, hashid.FileID)])
+	a.Equal("dir", res.Folders[hashid.HashID(4, hashid.FolderID)])
+
+	// 分享信封
+	res = BuildEncryptedFolder(folder, &model.FolderKeyEnvelope{Model: gorm.Model{ID: 1}, ShareID: 5}, nil)
+	a.Equal(EnvelopeTypeShare, res.EnvelopeType)
+
+	// 无可用信封
+	res = BuildEncryptedFolder(folder, nil, nil)
+	a.Empty(res.EnvelopeType)
+	a.Empty(res.Files)
+}
@@ -56,6 +56,8 @@ const (
 	CodeNotFound = 404
 	// CodeConflict 资源冲突
 	CodeConflict = 409
+	// CodeTooManyRequests 请求过于频繁
+	CodeTooManyRequests = 429
 	// CodeUploadFailed 上传出错
 	CodeUploadFailed = 40002
 	// CodeCreateFolderFailed 目录创建失败
@@ -192,6 +194,58 @@ const (
 	CodeDisabledSharePreview = 40070
 	// 签名无效
 	CodeInvalidSign = 40071
+	// CodeRetentionLocked 对象受保留规则保护
+	CodeRetentionLocked = 40072
+	// CodeInvalidInviteCode 邀请码无效
+	CodeInvalidInviteCode = 40073
+	// CodeEmailDomainNotAllowed 邮箱域名不允许注册
+	CodeEmailDomainNotAllowed = 40074
+	// CodeInviteLimitExceeded 可用邀请码数量已达上限
+	CodeInviteLimitExceeded = 40075
+	// CodeRejectedByAutomation 文件被自动化规则拒绝
+	CodeRejectedByAutomation = 40076
+	// CodeObjectLocked 对象正在被其他操作修改
+	CodeObjectLocked = 40077
+	// CodeVersionConflict 文件在客户端读取后已被修改
+	CodeVersionConflict = 40078
+	// CodeFolderQuotaExceeded 超出目录容量配额
+	CodeFolderQuotaExceeded = 40079
+	// Code2FARequired 用户组要求开启二步验证
+	Code2FARequired = 40080
+	// CodeOIDCLoginFailed 单点登录失败
+	CodeOIDCLoginFailed = 40081
+	// CodeAPITokenLimitExceeded 访问令牌数量已达上限
+	CodeAPITokenLimitExceeded = 40082
+	// CodeWebhookLimitExceeded Webhook 数量已达上限
+	CodeWebhookLimitExceeded = 40083
+	// CodeFolderNotEmpty 目录不为空
+	CodeFolderNotEmpty = 40084
+	// CodeNotEncryptedFolder 目录未启用端到端加密
+	CodeNotEncryptedFolder = 40085
+	// CodeKeyPairNotSet 用户未设置端到端加密密钥对
+	CodeKeyPairNotSet = 40086
+	// CodeInstantUploadMiss 秒传时未找到内容相同的文件
+	CodeInstantUploadMiss = 40087
+	// CodeFileCountExceeded 超出用户组最大文件数
+	CodeFileCountExceeded = 40088
+	// CodePathDepthExceeded 超出用户组最大目录层级
+	CodePathDepthExceeded = 40089
+	// CodeSharePasswordLocked 分享密码错误次数过多
+	CodeSharePasswordLocked = 40090
+	// CodeShareLimitExceeded 超出分享的流量或下载次数限制
+	CodeShareLimitExceeded = 40091
+	// CodeTeamLimitReached 拥有的团队数量已达上限
+	CodeTeamLimitReached = 40092
+	// CodeImageProcessFailed 无法按给定参数处理图像
+	CodeImageProcessFailed = 40093
+	// CodeNoPreviewHandler 没有可用于该文件的预览处理器
+	CodeNoPreviewHandler = 40094
+	// CodeFileInfected 文件检出病毒
+	CodeFileInfected = 40095
+	// CodeFileQuarantined 文件因检出病毒被隔离
+	CodeFileQuarantined = 40096
+	// CodeFileDisabled 文件因举报被管理员禁用
+	CodeFileDisabled = 40097
 	// CodeDBError 数据库操作失败
 	CodeDBError = 50001
 	// CodeEncryptError 加密失败
@@ -4,6 +4,7 @@ import (
 	"encoding/gob"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mediameta"
 	"time"
 )
 
@@ -20,6 +21,10 @@ type ObjectProps struct {
 	ChildFolderNum int       `json:"child_folder_num"`
 	ChildFileNum   int       `json:"child_file_num"`
 	Path           string    `json:"path"`
+	// Quota 目录的容量配额，Size 即为已用容量
+	Quota uint64 `json:"quota,omitempty"`
+
+	MediaMeta *mediameta.MediaMeta `json:"media_meta,omitempty"`
 
 	QueryDate time.Time `json:"query_date"`
 }
@@ -43,6 +48,12 @@ type Object struct {
 	CreateDate    time.Time `json:"create_date"`
 	Key           string    `json:"key,omitempty"`
 	SourceEnabled bool      `json:"source_enabled"`
+	// Version 文件内容版本，覆盖写入时作为 If-Match 前置条件
+	Version string `json:"version,omitempty"`
+	// ScanStatus 病毒扫描状态
+	ScanStatus string `json:"scan_status,omitempty"`
+
+	MediaMeta *mediameta.MediaMeta `json:"media_meta,omitempty"`
 }
 
 // PolicySummary 用于前端组件使用的存储策略概况
@@ -77,6 +88,50 @@ func BuildObjectList(parent uint, objects []Object, policy *model.Policy) Object
 	return res
 }
 
+// ObjectStat 批量查询对象状态的结果
+type ObjectStat struct {
+	// Query 查询时使用的路径或 HashID
+	Query    string    `json:"query"`
+	Exist    bool      `json:"exist"`
+	ID       string    `json:"id,omitempty"`
+	Type     string    `json:"type,omitempty"`
+	Size     uint64    `json:"size"`
+	Date     time.Time `json:"date"`
+	Checksum string    `json:"checksum,omitempty"`
+	Shared   bool      `json:"shared"`
+	Shares   []string  `json:"shares,omitempty"`
+}
+
+// BuildFileStat 构建文件的状态
+func BuildFileStat(query string, file *model.File) ObjectStat {
+	return ObjectStat{
+		Query:    query,
+		Exist:    true,
+		ID:       hashid.HashID(file.ID, hashid.FileID),
+		Type:     "file",
+		Size:     file.Size,
+		Date:     file.UpdatedAt,
+		Checksum: file.MetadataSerialized[model.ChecksumMetadataKey],
+	}
+}
+
+// BuildFolderStat 构建目录的状态
+func BuildFolderStat(query string, folder *model.Folder) ObjectStat {
+	return ObjectStat{
+		Query: query,
+		Exist: true,
+		ID:    hashid.HashID(folder.ID, hashid.FolderID),
+		Type:  "dir",
+		Date:  folder.UpdatedAt,
+	}
+}
+
+// AddShare 将对象标记为已分享
+func (stat *ObjectStat) AddShare(share *model.Share) {
+	stat.Shared = true
+	stat.Shares = append(stat.Shares, hashid.HashID(share.ID, hashid.ShareID))
+}
+
 // Sources 获取外链的结果响应
 type Sources struct {
 	URL    string `json:"url"`
@@ -85,6 +140,28 @@ type Sources struct {
 	Error  string `json:"error,omitempty"`
 }
 
+// ThumbState 批量获取缩略图的结果响应
+type ThumbState struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// URL 为空时客户端应使用单文件缩略图接口获取
+	URL string `json:"url,omitempty"`
+}
+
+// ContentVersion 文件内容更新后的响应
+type ContentVersion struct {
+	// Version 服务端当前的文件内容版本
+	Version string `json:"version"`
+	// ConflictCopy 版本冲突时写入内容另存的副本
+	ConflictCopy *ConflictCopy `json:"conflict_copy,omitempty"`
+}
+
+// ConflictCopy 冲突副本
+type ConflictCopy struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
 // DocPreviewSession 文档预览会话响应
 type DocPreviewSession struct {
 	URL            string `json:"url"`
@@ -92,6 +169,19 @@ type DocPreviewSession struct {
 	AccessTokenTTL int64  `json:"access_token_ttl,omitempty"`
 }
 
+// PreviewSession 文件预览方式响应，需要外部转换服务时 URL 为转换服务地址
+type PreviewSession struct {
+	Strategy string `json:"strategy"`
+	Language string `json:"language,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// HLSSession 视频 HLS 转码状态响应，转码完成后 URL 为播放列表地址
+type HLSSession struct {
+	Status string `json:"status"`
+	URL    string `json:"url,omitempty"`
+}
+
 // WopiFileInfo Response for `CheckFileInfo`
 type WopiFileInfo struct {
 	// Required
@@ -129,4 +219,6 @@ type WopiFileInfo struct {
 	SupportsRename    bool
 	SupportsReviewing bool
 	SupportsUpdate    bool
+	SupportsLocks     bool
+	SupportsGetLock   bool
 }
@@ -13,3 +13,29 @@ func TestBuildObjectList(t *testing.T) {
 	a.NotNil(res.Policy)
 	a.Len(res.Objects, 2)
 }
+
+func TestBuildObjectStat(t *testing.T) {
+	a := assert.New(t)
+
+	file := &model.File{Size: 10, MetadataSerialized: map[string]string{model.ChecksumMetadataKey: "sha1:abc"}}
+	file.ID = 1
+	res := BuildFileStat("/a.txt", file)
+	a.True(res.Exist)
+	a.Equal("file", res.Type)
+	a.Equal("/a.txt", res.Query)
+	a.EqualValues(10, res.Size)
+	a.Equal("sha1:abc", res.Checksum)
+	a.NotEmpty(res.ID)
+	a.False(res.Shared)
+
+	res.AddShare(&model.Share{})
+	a.True(res.Shared)
+	a.Len(res.Shares, 1)
+
+	folder := &model.Folder{}
+	folder.ID = 1
+	res = BuildFolderStat("/dir", folder)
+	a.True(res.Exist)
+	a.Equal("dir", res.Type)
+	a.Empty(res.Checksum)
+}
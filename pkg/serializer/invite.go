@@ -0,0 +1,32 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// Invite 邀请码序列化
+type Invite struct {
+	Code       string     `json:"code"`
+	Uses       int        `json:"uses"`
+	MaxUses    int        `json:"max_uses"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	CreateDate time.Time  `json:"create_date"`
+}
+
+// BuildInviteList 序列化邀请码列表
+func BuildInviteList(invites []model.Invite) Response {
+	res := make([]Invite, 0, len(invites))
+	for _, invite := range invites {
+		res = append(res, Invite{
+			Code:       invite.Code,
+			Uses:       invite.Uses,
+			MaxUses:    invite.MaxUses,
+			Expires:    invite.ExpiresAt,
+			CreateDate: invite.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,22 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInviteList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildInviteList([]model.Invite{{Code: "a", MaxUses: 1}, {Code: "b", Uses: 2}})
+	list := res.Data.([]Invite)
+	asserts.Len(list, 2)
+	asserts.Equal("a", list[0].Code)
+	asserts.Equal(1, list[0].MaxUses)
+	asserts.Equal(2, list[1].Uses)
+
+	res = BuildInviteList(nil)
+	asserts.Empty(res.Data)
+}
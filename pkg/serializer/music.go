@@ -0,0 +1,139 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// MusicTrack 曲目序列化，带有封面的曲目可通过文件缩略图接口获取封面
+type MusicTrack struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Size        uint64 `json:"size"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	Album       string `json:"album"`
+	AlbumArtist string `json:"album_artist"`
+	Genre       string `json:"genre,omitempty"`
+	Year        int    `json:"year,omitempty"`
+	Track       int    `json:"track,omitempty"`
+	Disc        int    `json:"disc,omitempty"`
+	HasCover    bool   `json:"has_cover"`
+}
+
+// MusicArtist 艺术家序列化
+type MusicArtist struct {
+	Name   string `json:"name"`
+	Albums int    `json:"albums"`
+	Tracks int    `json:"tracks"`
+}
+
+// MusicAlbum 专辑序列化，Cover 为可用于获取封面缩略图的文件ID
+type MusicAlbum struct {
+	Name   string `json:"name"`
+	Artist string `json:"artist"`
+	Year   int    `json:"year,omitempty"`
+	Tracks int    `json:"tracks"`
+	Cover  string `json:"cover,omitempty"`
+}
+
+// Playlist 播放列表序列化
+type Playlist struct {
+	ID         uint         `json:"id"`
+	Name       string       `json:"name"`
+	Count      int          `json:"count"`
+	Tracks     []MusicTrack `json:"tracks,omitempty"`
+	CreateDate time.Time    `json:"create_date"`
+}
+
+// BuildMusicTrack 序列化曲目
+func BuildMusicTrack(track *model.MusicTrack) MusicTrack {
+	res := MusicTrack{
+		ID:          hashid.HashID(track.FileID, hashid.FileID),
+		Title:       track.Title,
+		Artist:      track.Artist,
+		Album:       track.Album,
+		AlbumArtist: track.AlbumArtist,
+		Genre:       track.Genre,
+		Year:        track.Year,
+		Track:       track.TrackNo,
+		Disc:        track.Disc,
+		HasCover:    track.HasCover,
+	}
+	if track.File != nil {
+		res.Name = track.File.Name
+		res.Size = track.File.Size
+	}
+
+	return res
+}
+
+// BuildMusicTrackList 序列化曲目列表
+func BuildMusicTrackList(tracks []model.MusicTrack) Response {
+	res := make([]MusicTrack, 0, len(tracks))
+	for i := range tracks {
+		res = append(res, BuildMusicTrack(&tracks[i]))
+	}
+
+	return Response{Data: res}
+}
+
+// BuildMusicArtistList 序列化艺术家列表
+func BuildMusicArtistList(artists []model.MusicArtist) Response {
+	res := make([]MusicArtist, 0, len(artists))
+	for _, artist := range artists {
+		res = append(res, MusicArtist{Name: artist.Artist, Albums: artist.Albums, Tracks: artist.Tracks})
+	}
+
+	return Response{Data: res}
+}
+
+// BuildMusicAlbumList 序列化专辑列表
+func BuildMusicAlbumList(albums []model.MusicAlbum) Response {
+	res := make([]MusicAlbum, 0, len(albums))
+	for _, album := range albums {
+		item := MusicAlbum{
+			Name:   album.Album,
+			Artist: album.AlbumArtist,
+			Year:   album.Year,
+			Tracks: album.Tracks,
+		}
+		if album.Cover > 0 {
+			item.Cover = hashid.HashID(album.Cover, hashid.FileID)
+		}
+		res = append(res, item)
+	}
+
+	return Response{Data: res}
+}
+
+// BuildPlaylist 序列化播放列表，tracks 为 nil 时不包含曲目
+func BuildPlaylist(playlist *model.Playlist, tracks []model.MusicTrack) Playlist {
+	res := Playlist{
+		ID:         playlist.ID,
+		Name:       playlist.Name,
+		Count:      len(playlist.FileIDs()),
+		CreateDate: playlist.CreatedAt,
+	}
+	if tracks != nil {
+		res.Count = len(tracks)
+		res.Tracks = make([]MusicTrack, 0, len(tracks))
+		for i := range tracks {
+			res.Tracks = append(res.Tracks, BuildMusicTrack(&tracks[i]))
+		}
+	}
+
+	return res
+}
+
+// BuildPlaylists 序列化播放列表的列表
+func BuildPlaylists(playlists []model.Playlist) Response {
+	res := make([]Playlist, 0, len(playlists))
+	for i := range playlists {
+		res = append(res, BuildPlaylist(&playlists[i], nil))
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,42 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMusicAlbumList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildMusicAlbumList([]model.MusicAlbum{
+		{Album: "a", AlbumArtist: "b", Tracks: 2, Cover: 3},
+		{Album: "c", Tracks: 1},
+	})
+	albums := res.Data.([]MusicAlbum)
+	a.Len(albums, 2)
+	a.Equal(hashid.HashID(3, hashid.FileID), albums[0].Cover)
+	a.Equal("b", albums[0].Artist)
+	a.Empty(albums[1].Cover)
+}
+
+func TestBuildPlaylist(t *testing.T) {
+	a := assert.New(t)
+	playlist := &model.Playlist{Name: "list", Files: "1,2,3"}
+
+	// 不包含曲目
+	res := BuildPlaylist(playlist, nil)
+	a.Equal(3, res.Count)
+	a.Nil(res.Tracks)
+
+	// 包含曲目
+	res = BuildPlaylist(playlist, []model.MusicTrack{
+		{FileID: 1, Title: "t", TrackNo: 2, File: &model.File{Name: "t.mp3", Size: 10}},
+	})
+	a.Equal(1, res.Count)
+	a.Len(res.Tracks, 1)
+	a.Equal("t.mp3", res.Tracks[0].Name)
+	a.Equal(2, res.Tracks[0].Track)
+	a.Equal(hashid.HashID(1, hashid.FileID), res.Tracks[0].ID)
+}
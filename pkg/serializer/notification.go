@@ -0,0 +1,48 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// Notification 站内通知序列化
+type Notification struct {
+	ID      uint      `json:"id"`
+	Type    string    `json:"type"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Link    string    `json:"link,omitempty"`
+	Read    bool      `json:"read"`
+	Date    time.Time `json:"date"`
+}
+
+// NotificationList 站内通知列表
+type NotificationList struct {
+	Total  int            `json:"total"`
+	Unread int            `json:"unread"`
+	Items  []Notification `json:"items"`
+}
+
+// BuildNotification 序列化站内通知
+func BuildNotification(notification *model.Notification) Notification {
+	return Notification{
+		ID:      notification.ID,
+		Type:    notification.Type,
+		Title:   notification.Title,
+		Content: notification.Content,
+		Link:    notification.Link,
+		Read:    notification.Read(),
+		Date:    notification.CreatedAt,
+	}
+}
+
+// BuildNotificationList 序列化站内通知列表，unread 为用户的未读通知总数
+func BuildNotificationList(notifications []model.Notification, total, unread int) Response {
+	res := NotificationList{Total: total, Unread: unread, Items: make([]Notification, 0, len(notifications))}
+	for i := range notifications {
+		res.Items = append(res.Items, BuildNotification(&notifications[i]))
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,26 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildNotificationList(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+
+	res := BuildNotificationList([]model.Notification{
+		{Type: model.NotificationShared, Title: "a", Link: "/shared"},
+		{Type: model.NotificationAnnouncement, ReadAt: &now},
+	}, 10, 1)
+	list := res.Data.(NotificationList)
+	asserts.Equal(10, list.Total)
+	asserts.Equal(1, list.Unread)
+	asserts.Len(list.Items, 2)
+	asserts.Equal("/shared", list.Items[0].Link)
+	asserts.False(list.Items[0].Read)
+	asserts.True(list.Items[1].Read)
+}
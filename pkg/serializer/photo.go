@@ -0,0 +1,110 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// 照片时间线分组粒度
+const (
+	PhotoGroupDay   = "day"
+	PhotoGroupMonth = "month"
+	PhotoGroupYear  = "year"
+)
+
+// Photo 照片序列化
+type Photo struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Size      uint64    `json:"size"`
+	TakenAt   time.Time `json:"taken_at"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	Make      string    `json:"make,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	LensModel string    `json:"lens_model,omitempty"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+}
+
+// PhotoGroup 时间线中同一时段的照片
+type PhotoGroup struct {
+	Date   string  `json:"date"`
+	Photos []Photo `json:"photos"`
+}
+
+// PhotoAlbum 按时段归档的相册
+type PhotoAlbum struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// PhotoDate 返回拍摄时间在给定粒度下所属的时段，以服务端时区划分
+func PhotoDate(t time.Time, group string) string {
+	t = t.Local()
+	switch group {
+	case PhotoGroupYear:
+		return t.Format("2006")
+	case PhotoGroupMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// BuildPhoto 序列化照片
+func BuildPhoto(photo *model.Photo) Photo {
+	res := Photo{
+		ID:        hashid.HashID(photo.FileID, hashid.FileID),
+		TakenAt:   photo.TakenAt,
+		Latitude:  photo.Latitude,
+		Longitude: photo.Longitude,
+		Make:      photo.Make,
+		Model:     photo.Model,
+		LensModel: photo.LensModel,
+		Width:     photo.Width,
+		Height:    photo.Height,
+	}
+	if photo.File != nil {
+		res.Name = photo.File.Name
+		res.Size = photo.File.Size
+	}
+
+	return res
+}
+
+// BuildPhotoTimeline 将按拍摄时间排序的照片按时段分组
+func BuildPhotoTimeline(photos []model.Photo, total int, group string) Response {
+	groups := make([]PhotoGroup, 0)
+	for i := range photos {
+		date := PhotoDate(photos[i].TakenAt, group)
+		if len(groups) == 0 || groups[len(groups)-1].Date != date {
+			groups = append(groups, PhotoGroup{Date: date, Photos: []Photo{}})
+		}
+
+		last := &groups[len(groups)-1]
+		last.Photos = append(last.Photos, BuildPhoto(&photos[i]))
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total":  total,
+		"groups": groups,
+	}}
+}
+
+// BuildPhotoAlbums 按时段统计照片数量，times 须按时间排序
+func BuildPhotoAlbums(times []time.Time, group string) Response {
+	albums := make([]PhotoAlbum, 0)
+	for _, t := range times {
+		date := PhotoDate(t, group)
+		if len(albums) == 0 || albums[len(albums)-1].Date != date {
+			albums = append(albums, PhotoAlbum{Date: date})
+		}
+
+		albums[len(albums)-1].Count++
+	}
+
+	return Response{Data: albums}
+}
@@ -0,0 +1,53 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPhotoDate(t *testing.T) {
+	a := assert.New(t)
+	date := time.Date(2021, 6, 15, 8, 30, 0, 0, time.Local)
+	a.Equal("2021-06-15", PhotoDate(date, PhotoGroupDay))
+	a.Equal("2021-06-15", PhotoDate(date, ""))
+	a.Equal("2021-06", PhotoDate(date, PhotoGroupMonth))
+	a.Equal("2021", PhotoDate(date, PhotoGroupYear))
+}
+
+func TestBuildPhotoTimeline(t *testing.T) {
+	a := assert.New(t)
+	photos := []model.Photo{
+		{FileID: 3, TakenAt: time.Date(2021, 6, 15, 20, 0, 0, 0, time.Local), File: &model.File{Name: "c.jpg", Size: 3}},
+		{FileID: 2, TakenAt: time.Date(2021, 6, 15, 8, 0, 0, 0, time.Local)},
+		{FileID: 1, TakenAt: time.Date(2021, 5, 1, 8, 0, 0, 0, time.Local)},
+	}
+
+	res := BuildPhotoTimeline(photos, 10, PhotoGroupMonth)
+	data := res.Data.(map[string]interface{})
+	a.Equal(10, data["total"])
+	groups := data["groups"].([]PhotoGroup)
+	a.Len(groups, 2)
+	a.Equal("2021-06", groups[0].Date)
+	a.Len(groups[0].Photos, 2)
+	a.Equal("c.jpg", groups[0].Photos[0].Name)
+	a.EqualValues(3, groups[0].Photos[0].Size)
+	a.Equal("", groups[0].Photos[1].Name)
+	a.Equal("2021-05", groups[1].Date)
+
+	// 空列表
+	res = BuildPhotoTimeline(nil, 0, PhotoGroupDay)
+	a.Len(res.Data.(map[string]interface{})["groups"], 0)
+}
+
+func TestBuildPhotoAlbums(t *testing.T) {
+	a := assert.New(t)
+	res := BuildPhotoAlbums([]time.Time{
+		time.Date(2021, 6, 15, 0, 0, 0, 0, time.Local),
+		time.Date(2021, 6, 1, 0, 0, 0, 0, time.Local),
+		time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local),
+	}, PhotoGroupYear)
+	a.Equal([]PhotoAlbum{{Date: "2021", Count: 2}, {Date: "2020", Count: 1}}, res.Data)
+}
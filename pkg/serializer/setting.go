@@ -2,31 +2,47 @@ package serializer
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/preview"
 	"time"
 )
 
 // SiteConfig 站点全局设置序列
 type SiteConfig struct {
-	SiteName             string   `json:"title"`
-	LoginCaptcha         bool     `json:"loginCaptcha"`
-	RegCaptcha           bool     `json:"regCaptcha"`
-	ForgetCaptcha        bool     `json:"forgetCaptcha"`
-	EmailActive          bool     `json:"emailActive"`
-	Themes               string   `json:"themes"`
-	DefaultTheme         string   `json:"defaultTheme"`
-	HomepageViewMethod   string   `json:"home_view_method"`
-	ShareViewMethod      string   `json:"share_view_method"`
-	Authn                bool     `json:"authn"`
-	User                 User     `json:"user"`
-	ReCaptchaKey         string   `json:"captcha_ReCaptchaKey"`
-	CaptchaType          string   `json:"captcha_type"`
-	TCaptchaCaptchaAppId string   `json:"tcaptcha_captcha_app_id"`
-	RegisterEnabled      bool     `json:"registerEnabled"`
-	AppPromotion         bool     `json:"app_promotion"`
-	WopiExts             []string `json:"wopi_exts"`
+	SiteName             string           `json:"title"`
+	LoginCaptcha         bool             `json:"loginCaptcha"`
+	RegCaptcha           bool             `json:"regCaptcha"`
+	ForgetCaptcha        bool             `json:"forgetCaptcha"`
+	EmailActive          bool             `json:"emailActive"`
+	Themes               string           `json:"themes"`
+	DefaultTheme         string           `json:"defaultTheme"`
+	HomepageViewMethod   string           `json:"home_view_method"`
+	ShareViewMethod      string           `json:"share_view_method"`
+	Authn                bool             `json:"authn"`
+	OIDC                 bool             `json:"oidc"`
+	OIDCDisplayName      string           `json:"oidc_display_name"`
+	E2EE                 bool             `json:"e2ee"`
+	User                 User             `json:"user"`
+	ReCaptchaKey         string           `json:"captcha_ReCaptchaKey"`
+	CaptchaType          string           `json:"captcha_type"`
+	TCaptchaCaptchaAppId string           `json:"tcaptcha_captcha_app_id"`
+	RegisterEnabled      bool             `json:"registerEnabled"`
+	RegisterInviteOnly   bool             `json:"registerInviteOnly"`
+	AppPromotion         bool             `json:"app_promotion"`
+	WopiExts             []string         `json:"wopi_exts"`
+	PreviewHandlers      []PreviewHandler `json:"preview_handlers"`
 }
 
-type task struct {
+// PreviewHandler 预览处理器序列化，外部服务地址通过预览会话获取，不在此返回
+type PreviewHandler struct {
+	Exts     []string `json:"exts"`
+	Mimes    []string `json:"mimes,omitempty"`
+	Strategy string   `json:"strategy"`
+	Language string   `json:"language,omitempty"`
+}
+
+// Task 任务条目
+type Task struct {
+	ID         uint      `json:"id"`
 	Status     int       `json:"status"`
 	Type       int       `json:"type"`
 	CreateDate time.Time `json:"create_date"`
@@ -34,17 +50,23 @@ type task struct {
 	Error      string    `json:"error"`
 }
 
+// BuildTask 构建任务条目
+func BuildTask(t *model.Task) Task {
+	return Task{
+		ID:         t.ID,
+		Status:     t.Status,
+		Type:       t.Type,
+		CreateDate: t.CreatedAt,
+		Progress:   t.Progress,
+		Error:      t.Error,
+	}
+}
+
 // BuildTaskList 构建任务列表响应
 func BuildTaskList(tasks []model.Task, total int) Response {
-	res := make([]task, 0, len(tasks))
-	for _, t := range tasks {
-		res = append(res, task{
-			Status:     t.Status,
-			Type:       t.Type,
-			CreateDate: t.CreatedAt,
-			Progress:   t.Progress,
-			Error:      t.Error,
-		})
+	res := make([]Task, 0, len(tasks))
+	for i := range tasks {
+		res = append(res, BuildTask(&tasks[i]))
 	}
 
 	return Response{Data: map[string]interface{}{
@@ -61,13 +83,24 @@ func checkSettingValue(setting map[string]string, key string) string {
 }
 
 // BuildSiteConfig 站点全局设置
-func BuildSiteConfig(settings map[string]string, user *model.User, wopiExts []string) Response {
+func BuildSiteConfig(settings map[string]string, user *model.User, wopiExts []string, previewHandlers []preview.Handler) Response {
 	var userRes User
 	if user != nil {
 		userRes = BuildUser(*user)
 	} else {
 		userRes = BuildUser(*model.NewAnonymousUser())
 	}
+
+	handlers := make([]PreviewHandler, 0, len(previewHandlers))
+	for _, handler := range previewHandlers {
+		handlers = append(handlers, PreviewHandler{
+			Exts:     handler.Exts,
+			Mimes:    handler.Mimes,
+			Strategy: handler.Strategy,
+			Language: handler.Language,
+		})
+	}
+
 	res := Response{
 		Data: SiteConfig{
 			SiteName:             checkSettingValue(settings, "siteName"),
@@ -80,13 +113,18 @@ func BuildSiteConfig(settings map[string]string, user *model.User, wopiExts []st
 			HomepageViewMethod:   checkSettingValue(settings, "home_view_method"),
 			ShareViewMethod:      checkSettingValue(settings, "share_view_method"),
 			Authn:                model.IsTrueVal(checkSettingValue(settings, "authn_enabled")),
+			OIDC:                 model.IsTrueVal(checkSettingValue(settings, "oidc_enabled")),
+			OIDCDisplayName:      checkSettingValue(settings, "oidc_display_name"),
+			E2EE:                 model.IsTrueVal(checkSettingValue(settings, "e2ee_enabled")),
 			User:                 userRes,
 			ReCaptchaKey:         checkSettingValue(settings, "captcha_ReCaptchaKey"),
 			CaptchaType:          checkSettingValue(settings, "captcha_type"),
 			TCaptchaCaptchaAppId: checkSettingValue(settings, "captcha_TCaptcha_CaptchaAppId"),
 			RegisterEnabled:      model.IsTrueVal(checkSettingValue(settings, "register_enabled")),
+			RegisterInviteOnly:   model.IsTrueVal(checkSettingValue(settings, "register_invite_only")),
 			AppPromotion:         model.IsTrueVal(checkSettingValue(settings, "show_app_promotion")),
 			WopiExts:             wopiExts,
+			PreviewHandlers:      handlers,
 		}}
 	return res
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/preview"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
@@ -18,19 +19,45 @@ func TestCheckSettingValue(t *testing.T) {
 func TestBuildSiteConfig(t *testing.T) {
 	asserts := assert.New(t)
 
-	res := BuildSiteConfig(map[string]string{"not exist": ""}, &model.User{}, nil)
+	res := BuildSiteConfig(map[string]string{"not exist": ""}, &model.User{}, nil, nil)
 	asserts.Equal("", res.Data.(SiteConfig).SiteName)
 
-	res = BuildSiteConfig(map[string]string{"siteName": "123"}, &model.User{}, nil)
+	res = BuildSiteConfig(map[string]string{"siteName": "123"}, &model.User{}, nil, nil)
 	asserts.Equal("123", res.Data.(SiteConfig).SiteName)
 
+	// 单点登录
+	res = BuildSiteConfig(map[string]string{"oidc_enabled": "1", "oidc_display_name": "SSO"}, &model.User{}, nil, nil)
+	asserts.True(res.Data.(SiteConfig).OIDC)
+	asserts.Equal("SSO", res.Data.(SiteConfig).OIDCDisplayName)
+
+	res = BuildSiteConfig(map[string]string{"e2ee_enabled": "1"}, &model.User{}, nil, nil)
+	asserts.True(res.Data.(SiteConfig).E2EE)
+
 	// 非空用户
 	res = BuildSiteConfig(map[string]string{"qq_login": "1"}, &model.User{
 		Model: gorm.Model{
 			ID: 5,
 		},
-	}, nil)
+	}, nil, nil)
 	asserts.Len(res.Data.(SiteConfig).User.ID, 4)
+
+	// 预览处理器不返回外部服务地址
+	res = BuildSiteConfig(map[string]string{}, nil, nil, []preview.Handler{
+		{Exts: []string{"dwg"}, Strategy: preview.StrategyCAD, URL: "https://cad/?src={$src}"},
+	})
+	asserts.Len(res.Data.(SiteConfig).PreviewHandlers, 1)
+	asserts.Equal([]string{"dwg"}, res.Data.(SiteConfig).PreviewHandlers[0].Exts)
+	asserts.Equal(preview.StrategyCAD, res.Data.(SiteConfig).PreviewHandlers[0].Strategy)
+}
+
+func TestBuildTask(t *testing.T) {
+	asserts := assert.New(t)
+	res := BuildTask(&model.Task{Model: gorm.Model{ID: 1}, Status: 1, Type: 2, Progress: 3, Error: "error"})
+	asserts.EqualValues(1, res.ID)
+	asserts.Equal(1, res.Status)
+	asserts.Equal(2, res.Type)
+	asserts.Equal(3, res.Progress)
+	asserts.Equal("error", res.Error)
 }
 
 func TestBuildTaskList(t *testing.T) {
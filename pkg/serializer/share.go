@@ -19,6 +19,14 @@ type Share struct {
 	Preview    bool          `json:"preview"`
 	Creator    *shareCreator `json:"creator,omitempty"`
 	Source     *shareSource  `json:"source,omitempty"`
+	Upload     *shareUpload  `json:"upload,omitempty"`
+}
+
+// shareUpload 投递分享的上传限制
+type shareUpload struct {
+	MaxSize uint64   `json:"max_size"`
+	Exts    []string `json:"exts"`
+	Limit   int      `json:"limit"`
 }
 
 type shareCreator struct {
@@ -43,9 +51,85 @@ type myShareItem struct {
 	Views           int          `json:"views"`
 	Expire          int64        `json:"expire"`
 	Preview         bool         `json:"preview"`
+	Traffic         uint64       `json:"traffic"`
+	MaxTraffic      uint64       `json:"max_traffic"`
+	FileDownloads   int          `json:"file_downloads"`
+	UploadOnly      bool         `json:"upload_only"`
+	Disabled        bool         `json:"disabled"`
 	Source          *shareSource `json:"source,omitempty"`
 }
 
+// ShareUsage 分享的流量和下载次数统计
+type ShareUsage struct {
+	Traffic       uint64           `json:"traffic"`
+	MaxTraffic    uint64           `json:"max_traffic"`
+	FileDownloads int              `json:"file_downloads"`
+	Files         []shareFileUsage `json:"files"`
+}
+
+type shareFileUsage struct {
+	Key       string `json:"key"`
+	Name      string `json:"name"`
+	Downloads int    `json:"downloads"`
+}
+
+// BuildShareUsage 构建分享用量统计响应，已删除的文件不包含在内
+func BuildShareUsage(share *model.Share, counters []model.ShareFileDownload, files []model.File) Response {
+	names := make(map[uint]string, len(files))
+	for _, file := range files {
+		names[file.ID] = file.Name
+	}
+
+	usage := ShareUsage{
+		Traffic:       share.Traffic,
+		MaxTraffic:    share.MaxTraffic,
+		FileDownloads: share.FileDownloads,
+		Files:         make([]shareFileUsage, 0, len(counters)),
+	}
+	for _, counter := range counters {
+		name, ok := names[counter.FileID]
+		if !ok {
+			continue
+		}
+
+		usage.Files = append(usage.Files, shareFileUsage{
+			Key:       hashid.HashID(counter.FileID, hashid.FileID),
+			Name:      name,
+			Downloads: counter.Downloads,
+		})
+	}
+
+	return Response{Data: usage}
+}
+
+// shareAccessLog 分享访问记录
+type shareAccessLog struct {
+	ID     uint      `json:"id"`
+	Action string    `json:"action"`
+	IP     string    `json:"ip"`
+	UA     string    `json:"ua"`
+	Date   time.Time `json:"date"`
+}
+
+// BuildShareAccessLogList 构建分享访问记录列表响应
+func BuildShareAccessLogList(logs []model.ShareAccessLog, total int) Response {
+	res := make([]shareAccessLog, 0, len(logs))
+	for _, log := range logs {
+		res = append(res, shareAccessLog{
+			ID:     log.ID,
+			Action: log.Action,
+			IP:     log.IP,
+			UA:     log.UA,
+			Date:   log.CreatedAt,
+		})
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
+
 // BuildShareList 构建我的分享列表响应
 func BuildShareList(shares []model.Share, total int) Response {
 	res := make([]myShareItem, 0, total)
@@ -61,6 +145,11 @@ func BuildShareList(shares []model.Share, total int) Response {
 			Preview:         shares[i].PreviewEnabled,
 			Expire:          -1,
 			RemainDownloads: shares[i].RemainDownloads,
+			Traffic:         shares[i].Traffic,
+			MaxTraffic:      shares[i].MaxTraffic,
+			FileDownloads:   shares[i].FileDownloads,
+			UploadOnly:      shares[i].UploadOnly,
+			Disabled:        shares[i].Disabled,
 		}
 		if shares[i].Expires != nil {
 			item.Expire = shares[i].Expires.Unix() - now
@@ -116,11 +205,21 @@ func BuildShareResponse(share *model.Share, unlocked bool) Share {
 		resp.Expire = share.Expires.Unix() - time.Now().Unix()
 	}
 
-	if share.IsDir {
+	if share.UploadOnly {
+		// 投递分享不展示目录内容的大小
+		resp.Source = &shareSource{
+			Name: share.SourceFolder().Name,
+		}
+		resp.Upload = &shareUpload{
+			MaxSize: share.UploadMaxSize,
+			Exts:    share.UploadExtList(),
+			Limit:   share.UploadLimit,
+		}
+	} else if share.IsDir {
 		source := share.SourceFolder()
 		resp.Source = &shareSource{
 			Name: source.Name,
-			Size: 0,
+			Size: source.Size,
 		}
 	} else {
 		source := share.SourceFile()
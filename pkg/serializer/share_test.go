@@ -31,6 +31,35 @@ func TestBuildShareList(t *testing.T) {
 	asserts.Equal(0, res.Code)
 }
 
+func TestBuildShareUsage(t *testing.T) {
+	a := assert.New(t)
+	res := BuildShareUsage(&model.Share{Traffic: 10, MaxTraffic: 20, FileDownloads: 3}, []model.ShareFileDownload{
+		{FileID: 1, Downloads: 2},
+		{FileID: 2, Downloads: 1},
+	}, []model.File{{Model: gorm.Model{ID: 1}, Name: "a.txt"}})
+	usage := res.Data.(ShareUsage)
+	a.EqualValues(10, usage.Traffic)
+	a.EqualValues(20, usage.MaxTraffic)
+	a.Equal(3, usage.FileDownloads)
+	// 已删除的文件不包含在内
+	a.Len(usage.Files, 1)
+	a.Equal("a.txt", usage.Files[0].Name)
+	a.Equal(2, usage.Files[0].Downloads)
+}
+
+func TestBuildShareAccessLogList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildShareAccessLogList([]model.ShareAccessLog{
+		{Model: gorm.Model{ID: 1}, Action: model.ShareAccessView, IP: "1.1.1.1", UA: "test"},
+	}, 3)
+	data := res.Data.(map[string]interface{})
+	a.Equal(3, data["total"])
+	items := data["items"].([]shareAccessLog)
+	a.Len(items, 1)
+	a.Equal(model.ShareAccessView, items[0].Action)
+	a.Equal("1.1.1.1", items[0].IP)
+}
+
 func TestBuildShareResponse(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -81,5 +110,27 @@ func TestBuildShareResponse(t *testing.T) {
 		asserts.False(res.Locked)
 		asserts.NotEmpty(res.Expire)
 		asserts.NotNil(res.Creator)
+		asserts.Nil(res.Upload)
+	}
+
+	// 已解锁，投递分享
+	{
+		share := &model.Share{
+			User: model.User{Model: gorm.Model{ID: 1}},
+			Folder: model.Folder{
+				Model: gorm.Model{ID: 1},
+				Name:  "homework",
+				Size:  100,
+			},
+			IsDir:         true,
+			UploadOnly:    true,
+			UploadMaxSize: 10,
+			UploadExts:    "pdf",
+			UploadLimit:   1,
+		}
+		res := BuildShareResponse(share, true)
+		asserts.Equal("homework", res.Source.Name)
+		asserts.EqualValues(0, res.Source.Size)
+		asserts.Equal(&shareUpload{MaxSize: 10, Exts: []string{"pdf"}, Limit: 1}, res.Upload)
 	}
 }
@@ -0,0 +1,41 @@
+package serializer
+
+import (
+	"net/url"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// StaticSite 静态网站序列化
+type StaticSite struct {
+	ID         uint      `json:"id"`
+	Slug       string    `json:"slug"`
+	Folder     string    `json:"folder"`
+	FolderName string    `json:"folder_name"`
+	URL        string    `json:"url"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// BuildStaticSiteList 序列化静态网站列表，目录已不存在的记录不包含在内
+func BuildStaticSiteList(sites []model.StaticSite) Response {
+	res := make([]StaticSite, 0, len(sites))
+	for _, site := range sites {
+		if site.Folder == nil {
+			continue
+		}
+
+		siteURL := model.GetSiteURL().ResolveReference(&url.URL{Path: "/site/" + site.Slug + "/"})
+		res = append(res, StaticSite{
+			ID:         site.ID,
+			Slug:       site.Slug,
+			Folder:     hashid.HashID(site.FolderID, hashid.FolderID),
+			FolderName: site.Folder.Name,
+			URL:        siteURL.String(),
+			CreateDate: site.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
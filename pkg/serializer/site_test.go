@@ -0,0 +1,23 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStaticSiteList(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+	res := BuildStaticSiteList([]model.StaticSite{
+		{Model: gorm.Model{ID: 1}, Slug: "blog", FolderID: 2, Folder: &model.Folder{Name: "public_html"}},
+		{Model: gorm.Model{ID: 2}, Slug: "gone", FolderID: 3},
+	})
+	items := res.Data.([]StaticSite)
+	a.Len(items, 1)
+	a.Equal("public_html", items[0].FolderName)
+	a.Equal("https://cloudreve.org/site/blog/", items[0].URL)
+}
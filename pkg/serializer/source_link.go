@@ -0,0 +1,58 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// SourceLink 文件外链序列化
+type SourceLink struct {
+	Key          string     `json:"key"`
+	File         string     `json:"file"`
+	FileName     string     `json:"file_name"`
+	URL          string     `json:"url"`
+	Slug         string     `json:"slug,omitempty"`
+	SlugURL      string     `json:"slug_url,omitempty"`
+	Expires      *time.Time `json:"expires"`
+	Referers     []string   `json:"referers"`
+	Downloads    int        `json:"downloads"`
+	LastAccessed *time.Time `json:"last_accessed"`
+	CreateDate   time.Time  `json:"create_date"`
+}
+
+// BuildSourceLink 序列化文件外链
+func BuildSourceLink(link *model.SourceLink) SourceLink {
+	res := SourceLink{
+		Key:          hashid.HashID(link.ID, hashid.SourceLinkID),
+		File:         hashid.HashID(link.FileID, hashid.FileID),
+		FileName:     link.File.Name,
+		SlugURL:      link.SlugLink(),
+		Expires:      link.Expires,
+		Referers:     link.RefererList(),
+		Downloads:    link.Downloads,
+		LastAccessed: link.LastAccessed,
+		CreateDate:   link.CreatedAt,
+	}
+	res.URL, _ = link.Link()
+	if link.Slug != nil {
+		res.Slug = *link.Slug
+	}
+
+	return res
+}
+
+// BuildSourceLinkList 序列化文件外链列表，文件已不存在的外链不包含在内
+func BuildSourceLinkList(links []model.SourceLink) Response {
+	res := make([]SourceLink, 0, len(links))
+	for i := range links {
+		if links[i].File.ID == 0 {
+			continue
+		}
+
+		res = append(res, BuildSourceLink(&links[i]))
+	}
+
+	return Response{Data: res}
+}
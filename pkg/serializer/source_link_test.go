@@ -0,0 +1,34 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSourceLinkList(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_siteURL", "https://cloudreve.org", 0)
+	slug := "logo"
+	res := BuildSourceLinkList([]model.SourceLink{
+		{
+			Model:     gorm.Model{ID: 1},
+			FileID:    2,
+			Slug:      &slug,
+			Referers:  "example.com",
+			Downloads: 3,
+			File:      model.File{Model: gorm.Model{ID: 2}, Name: "logo.png"},
+		},
+		{Model: gorm.Model{ID: 2}, FileID: 3},
+	})
+	items := res.Data.([]SourceLink)
+	a.Len(items, 1)
+	a.Equal("logo", items[0].Slug)
+	a.Equal("https://cloudreve.org/f/s/logo", items[0].SlugURL)
+	a.Contains(items[0].URL, "/logo.png")
+	a.Equal([]string{"example.com"}, items[0].Referers)
+	a.Equal(3, items[0].Downloads)
+}
@@ -0,0 +1,64 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// Team 团队空间序列化
+type Team struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	Role       string    `json:"role"`
+	Used       uint64    `json:"used"`
+	Total      uint64    `json:"total"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// TeamMember 团队成员序列化
+type TeamMember struct {
+	Key        string    `json:"key"`
+	Email      string    `json:"email"`
+	Nick       string    `json:"nick"`
+	Role       string    `json:"role"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// BuildTeamList 序列化用户所在的团队列表，used 为各团队存储账户的已用容量
+func BuildTeamList(teams []model.Team, used map[uint]uint64) Response {
+	res := make([]Team, 0, len(teams))
+	for _, team := range teams {
+		res = append(res, Team{
+			ID:         team.ID,
+			Name:       team.Name,
+			Role:       team.Role,
+			Used:       used[team.ID],
+			Total:      team.MaxStorage,
+			CreateDate: team.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
+
+// BuildTeamMemberList 序列化团队成员列表，用户已不存在的成员不包含在内
+func BuildTeamMemberList(members []model.TeamMember) Response {
+	res := make([]TeamMember, 0, len(members))
+	for _, member := range members {
+		if member.User == nil {
+			continue
+		}
+
+		res = append(res, TeamMember{
+			Key:        hashid.HashID(member.UserID, hashid.UserID),
+			Email:      member.User.Email,
+			Nick:       member.User.Nick,
+			Role:       member.Role,
+			CreateDate: member.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,35 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTeamList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildTeamList([]model.Team{
+		{Model: gorm.Model{ID: 1}, Name: "t", Role: model.TeamEditor, MaxStorage: 10},
+	}, map[uint]uint64{1: 3})
+	items := res.Data.([]Team)
+	a.Len(items, 1)
+	a.Equal("t", items[0].Name)
+	a.Equal(model.TeamEditor, items[0].Role)
+	a.EqualValues(3, items[0].Used)
+	a.EqualValues(10, items[0].Total)
+}
+
+func TestBuildTeamMemberList(t *testing.T) {
+	a := assert.New(t)
+	res := BuildTeamMemberList([]model.TeamMember{
+		{UserID: 1, Role: model.TeamOwner, User: &model.User{Email: "a@a.com"}},
+		// 用户已不存在
+		{UserID: 2, Role: model.TeamViewer},
+	})
+	items := res.Data.([]TeamMember)
+	a.Len(items, 1)
+	a.Equal("a@a.com", items[0].Email)
+	a.Equal(model.TeamOwner, items[0].Role)
+}
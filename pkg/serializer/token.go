@@ -0,0 +1,41 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// APIToken 访问令牌序列化，令牌明文仅在创建时返回
+type APIToken struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	Expires    *time.Time `json:"expires,omitempty"`
+	LastUsed   *time.Time `json:"last_used,omitempty"`
+	CreateDate time.Time  `json:"create_date"`
+}
+
+// BuildAPIToken 序列化访问令牌
+func BuildAPIToken(token *model.APIToken, plain string) APIToken {
+	return APIToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Token:      plain,
+		Scopes:     token.ScopeList(),
+		Expires:    token.ExpiresAt,
+		LastUsed:   token.LastUsedAt,
+		CreateDate: token.CreatedAt,
+	}
+}
+
+// BuildAPITokenList 序列化访问令牌列表
+func BuildAPITokenList(tokens []model.APIToken) Response {
+	res := make([]APIToken, 0, len(tokens))
+	for i := range tokens {
+		res = append(res, BuildAPIToken(&tokens[i], ""))
+	}
+
+	return Response{Data: res}
+}
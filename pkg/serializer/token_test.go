@@ -0,0 +1,22 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAPITokenList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildAPITokenList([]model.APIToken{{Name: "a", Scopes: "read,write"}, {Name: "b"}})
+	list := res.Data.([]APIToken)
+	asserts.Len(list, 2)
+	asserts.Equal("a", list[0].Name)
+	asserts.Equal([]string{"read", "write"}, list[0].Scopes)
+	asserts.Empty(list[0].Token)
+	asserts.Empty(list[1].Scopes)
+
+	asserts.Equal("crt_1", BuildAPIToken(&model.APIToken{}, "crt_1").Token)
+}
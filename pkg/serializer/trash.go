@@ -0,0 +1,43 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// Trash 回收站中的对象
+type Trash struct {
+	ID   string    `json:"id"`
+	Name string    `json:"name"`
+	Type string    `json:"type"`
+	Path string    `json:"path"`
+	Size uint64    `json:"size"`
+	Date time.Time `json:"date"`
+	// Expires 对象将被清除的时间
+	Expires time.Time `json:"expires"`
+}
+
+// BuildTrashList 构建回收站列表响应，retention 为保留天数
+func BuildTrashList(items []model.Trash, retention int) Response {
+	res := make([]Trash, 0, len(items))
+	for _, item := range items {
+		objectType := "file"
+		if item.ObjectType == model.TrashObjectFolder {
+			objectType = "dir"
+		}
+
+		res = append(res, Trash{
+			ID:      hashid.HashID(item.ID, hashid.TrashID),
+			Name:    item.Name,
+			Type:    objectType,
+			Path:    item.Path,
+			Size:    item.Size,
+			Date:    item.CreatedAt,
+			Expires: item.CreatedAt.AddDate(0, 0, retention),
+		})
+	}
+
+	return Response{Data: res}
+}
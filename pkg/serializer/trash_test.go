@@ -0,0 +1,27 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildTrashList(t *testing.T) {
+	a := assert.New(t)
+	deleted := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	res := BuildTrashList([]model.Trash{
+		{Model: gorm.Model{ID: 2, CreatedAt: deleted}, ObjectType: model.TrashObjectFolder, Name: "dir", Path: "/"},
+		{Model: gorm.Model{ID: 1, CreatedAt: deleted}, ObjectType: model.TrashObjectFile, Name: "a.txt", Size: 10},
+	}, 30).Data.([]Trash)
+	a.Len(res, 2)
+	a.Equal(hashid.HashID(2, hashid.TrashID), res[0].ID)
+	a.Equal("dir", res[0].Type)
+	a.Equal("file", res[1].Type)
+	a.EqualValues(10, res[1].Size)
+	a.Equal(deleted.AddDate(0, 0, 30), res[0].Expires)
+}
@@ -47,6 +47,8 @@ type UploadSession struct {
 	UploadURL      string
 	UploadID       string
 	Credential     string
+	SpeedLimit     int    // 上传限速，单位为字节每秒，0 为不限制
+	Instance       string // 集群模式下持有分片的实例，仅本机存储策略有效
 }
 
 // UploadCallback 上传回调正文
@@ -43,6 +43,7 @@ type group struct {
 	SourceBatchSize      int    `json:"sourceBatch"`
 	AdvanceDelete        bool   `json:"advanceDelete"`
 	AllowWebDAVProxy     bool   `json:"allowWebDAVProxy"`
+	AllowTorrentCreate   bool   `json:"allowTorrentCreate"`
 }
 
 type tag struct {
@@ -104,6 +105,7 @@ func BuildUser(user model.User) User {
 			AllowWebDAVProxy:     user.Group.OptionsSerialized.WebDAVProxy,
 			SourceBatchSize:      user.Group.OptionsSerialized.SourceBatchSize,
 			AdvanceDelete:        user.Group.OptionsSerialized.AdvanceDelete,
+			AllowTorrentCreate:   user.Group.OptionsSerialized.TorrentCreate,
 		},
 		Tags: buildTagRes(tags),
 	}
@@ -118,7 +120,7 @@ func BuildUserResponse(user model.User) Response {
 
 // BuildUserStorageResponse 序列化用户存储概况响应
 func BuildUserStorageResponse(user model.User) Response {
-	total := user.Group.MaxStorage
+	total := user.MaxStorage()
 	storageResp := storage{
 		Used:  user.Storage,
 		Free:  total - user.Storage,
@@ -0,0 +1,29 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// FileVersion 文件历史版本
+type FileVersion struct {
+	ID   string    `json:"id"`
+	Size uint64    `json:"size"`
+	Date time.Time `json:"date"`
+}
+
+// BuildFileVersions 构建文件历史版本列表响应
+func BuildFileVersions(versions []model.FileVersion) Response {
+	res := make([]FileVersion, 0, len(versions))
+	for _, version := range versions {
+		res = append(res, FileVersion{
+			ID:   hashid.HashID(version.ID, hashid.FileVersionID),
+			Size: version.Size,
+			Date: version.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,33 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFileVersions(t *testing.T) {
+	a := assert.New(t)
+
+	// 无历史版本
+	{
+		res := BuildFileVersions(nil).Data.([]FileVersion)
+		a.NotNil(res)
+		a.Empty(res)
+	}
+
+	// 有历史版本
+	{
+		res := BuildFileVersions([]model.FileVersion{
+			{Model: gorm.Model{ID: 2}, Size: 10},
+			{Model: gorm.Model{ID: 1}, Size: 5},
+		}).Data.([]FileVersion)
+		a.Len(res, 2)
+		a.Equal(hashid.HashID(2, hashid.FileVersionID), res[0].ID)
+		a.EqualValues(10, res[0].Size)
+		a.Equal(hashid.HashID(1, hashid.FileVersionID), res[1].ID)
+	}
+}
@@ -0,0 +1,74 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// Webhook Webhook 序列化，不返回签名密钥
+type Webhook struct {
+	ID         uint      `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Enabled    bool      `json:"enabled"`
+	Events     []string  `json:"events"`
+	HasSecret  bool      `json:"has_secret"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// WebhookDelivery 投递记录序列化
+type WebhookDelivery struct {
+	ID           uint       `json:"id"`
+	Event        string     `json:"event"`
+	Payload      string     `json:"payload"`
+	Status       string     `json:"status"`
+	Attempts     int        `json:"attempts"`
+	ResponseCode int        `json:"response_code"`
+	Error        string     `json:"error,omitempty"`
+	NextRetry    *time.Time `json:"next_retry,omitempty"`
+	CreateDate   time.Time  `json:"create_date"`
+}
+
+// BuildWebhook 序列化 Webhook
+func BuildWebhook(webhook *model.Webhook) Webhook {
+	return Webhook{
+		ID:         webhook.ID,
+		Name:       webhook.Name,
+		URL:        webhook.URL,
+		Enabled:    webhook.Enabled,
+		Events:     webhook.EventList(),
+		HasSecret:  webhook.Secret != "",
+		CreateDate: webhook.CreatedAt,
+	}
+}
+
+// BuildWebhookList 序列化 Webhook 列表
+func BuildWebhookList(webhooks []model.Webhook) Response {
+	res := make([]Webhook, 0, len(webhooks))
+	for i := range webhooks {
+		res = append(res, BuildWebhook(&webhooks[i]))
+	}
+
+	return Response{Data: res}
+}
+
+// BuildWebhookDeliveryList 序列化投递记录列表
+func BuildWebhookDeliveryList(deliveries []model.WebhookDelivery) Response {
+	res := make([]WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, WebhookDelivery{
+			ID:           delivery.ID,
+			Event:        delivery.Event,
+			Payload:      delivery.Payload,
+			Status:       delivery.Status,
+			Attempts:     delivery.Attempts,
+			ResponseCode: delivery.ResponseCode,
+			Error:        delivery.Error,
+			NextRetry:    delivery.NextRetryAt,
+			CreateDate:   delivery.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
@@ -0,0 +1,30 @@
+package serializer
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildWebhookList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildWebhookList([]model.Webhook{{Name: "a", Secret: "s", Events: "file.uploaded,share.created"}, {Name: "b"}})
+	list := res.Data.([]Webhook)
+	asserts.Len(list, 2)
+	asserts.True(list[0].HasSecret)
+	asserts.Equal([]string{"file.uploaded", "share.created"}, list[0].Events)
+	asserts.False(list[1].HasSecret)
+	asserts.Empty(list[1].Events)
+}
+
+func TestBuildWebhookDeliveryList(t *testing.T) {
+	asserts := assert.New(t)
+
+	res := BuildWebhookDeliveryList([]model.WebhookDelivery{{Event: "file.deleted", Status: "failed", Attempts: 5}})
+	list := res.Data.([]WebhookDelivery)
+	asserts.Len(list, 1)
+	asserts.Equal("file.deleted", list[0].Event)
+	asserts.Equal(5, list[0].Attempts)
+}
@@ -0,0 +1,227 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// SFTP 协议版本 3 的数据包类型，见 draft-ietf-secsh-filexfer-02
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpRead     = 5
+	fxpWrite    = 6
+	fxpLstat    = 7
+	fxpFstat    = 8
+	fxpSetstat  = 9
+	fxpFsetstat = 10
+	fxpOpendir  = 11
+	fxpReaddir  = 12
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpRmdir    = 15
+	fxpRealpath = 16
+	fxpStat     = 17
+	fxpRename   = 18
+	fxpReadlink = 19
+	fxpSymlink  = 20
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpData     = 103
+	fxpName     = 104
+	fxpAttrs    = 105
+	fxpExtended = 200
+)
+
+// 状态码
+const (
+	fxOK               = 0
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+	fxFailure          = 4
+	fxBadMessage       = 5
+	fxOpUnsupported    = 8
+)
+
+// 打开文件的标记
+const (
+	fxfRead   = 0x00000001
+	fxfWrite  = 0x00000002
+	fxfAppend = 0x00000004
+	fxfCreat  = 0x00000008
+	fxfTrunc  = 0x00000010
+	fxfExcl   = 0x00000020
+)
+
+// 文件属性标记
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+	attrExtended    = 0x80000000
+)
+
+// 文件类型权限位
+const (
+	modeDir     = 0040000
+	modeRegular = 0100000
+)
+
+const (
+	// protocolVersion 支持的协议版本
+	protocolVersion = 3
+	// maxPacketSize 接受的最大数据包长度
+	maxPacketSize = 256 << 10
+	// maxReadLength 单次读取的最大长度
+	maxReadLength = 32 << 10
+)
+
+var errShortPacket = errors.New("packet too short")
+
+// Attrs 文件属性
+type Attrs struct {
+	Flags       uint32
+	Size        uint64
+	Permissions uint32
+	Atime       uint32
+	Mtime       uint32
+}
+
+// readPacket 读取一个数据包，返回类型和去除类型后的正文
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > maxPacketSize {
+		return 0, nil, errors.New("invalid packet length")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	return data[0], data[1:], nil
+}
+
+// packet 待发送的数据包
+type packet []byte
+
+// newPacket 新建指定类型的数据包，长度在发送前写入
+func newPacket(typ byte) packet {
+	return packet{0, 0, 0, 0, typ}
+}
+
+func (p packet) uint32(v uint32) packet {
+	return append(p, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (p packet) uint64(v uint64) packet {
+	return p.uint32(uint32(v >> 32)).uint32(uint32(v))
+}
+
+func (p packet) string(s string) packet {
+	return append(p.uint32(uint32(len(s))), s...)
+}
+
+func (p packet) bytes(b []byte) packet {
+	return append(p.uint32(uint32(len(b))), b...)
+}
+
+func (p packet) attrs(a *Attrs) packet {
+	p = p.uint32(a.Flags)
+	if a.Flags&attrSize != 0 {
+		p = p.uint64(a.Size)
+	}
+	if a.Flags&attrPermissions != 0 {
+		p = p.uint32(a.Permissions)
+	}
+	if a.Flags&attrACModTime != 0 {
+		p = p.uint32(a.Atime).uint32(a.Mtime)
+	}
+	return p
+}
+
+// finish 写入数据包长度
+func (p packet) finish() []byte {
+	binary.BigEndian.PutUint32(p, uint32(len(p)-4))
+	return p
+}
+
+// decoder 数据包正文解析
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) uint32() uint32 {
+	if d.err != nil || len(d.data) < 4 {
+		d.err = errShortPacket
+		return 0
+	}
+
+	v := binary.BigEndian.Uint32(d.data)
+	d.data = d.data[4:]
+	return v
+}
+
+func (d *decoder) uint64() uint64 {
+	if d.err != nil || len(d.data) < 8 {
+		d.err = errShortPacket
+		return 0
+	}
+
+	v := binary.BigEndian.Uint64(d.data)
+	d.data = d.data[8:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.uint32()
+	if d.err != nil || uint32(len(d.data)) < n {
+		d.err = errShortPacket
+		return nil
+	}
+
+	v := d.data[:n]
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) attrs() *Attrs {
+	a := &Attrs{Flags: d.uint32()}
+	if a.Flags&attrSize != 0 {
+		a.Size = d.uint64()
+	}
+	if a.Flags&attrUIDGID != 0 {
+		d.uint32()
+		d.uint32()
+	}
+	if a.Flags&attrPermissions != 0 {
+		a.Permissions = d.uint32()
+	}
+	if a.Flags&attrACModTime != 0 {
+		a.Atime = d.uint32()
+		a.Mtime = d.uint32()
+	}
+	if a.Flags&attrExtended != 0 {
+		count := d.uint32()
+		for i := uint32(0); i < count && d.err == nil; i++ {
+			d.string()
+			d.string()
+		}
+	}
+	return a
+}
@@ -0,0 +1,84 @@
+package sftp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPacket_RoundTrip(t *testing.T) {
+	asserts := assert.New(t)
+	attrs := &Attrs{
+		Flags:       attrSize | attrPermissions | attrACModTime,
+		Size:        1 << 40,
+		Permissions: modeRegular | 0644,
+		Atime:       1,
+		Mtime:       2,
+	}
+
+	raw := newPacket(fxpName).uint32(7).uint64(8).string("name").bytes([]byte{1, 2}).attrs(attrs).finish()
+	typ, data, err := readPacket(bytes.NewReader(raw))
+	asserts.NoError(err)
+	asserts.EqualValues(fxpName, typ)
+
+	d := &decoder{data: data}
+	asserts.EqualValues(7, d.uint32())
+	asserts.EqualValues(8, d.uint64())
+	asserts.Equal("name", d.string())
+	asserts.Equal([]byte{1, 2}, d.bytes())
+	asserts.Equal(attrs, d.attrs())
+	asserts.NoError(d.err)
+	asserts.Empty(d.data)
+}
+
+func TestDecoder_Attrs(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 忽略 uid/gid 及扩展属性
+	{
+		data := packet{}.uint32(attrUIDGID | attrSize | attrExtended).uint64(10).
+			uint32(1000).uint32(1000).uint32(1).string("k").string("v")
+		d := &decoder{data: data}
+		attrs := d.attrs()
+		asserts.NoError(d.err)
+		asserts.EqualValues(10, attrs.Size)
+		asserts.Empty(d.data)
+	}
+
+	// 数据不足
+	{
+		d := &decoder{data: packet{}.uint32(attrSize)}
+		d.attrs()
+		asserts.Equal(errShortPacket, d.err)
+	}
+
+	// 字符串长度越界
+	{
+		d := &decoder{data: packet{}.uint32(10).uint32(1)}
+		d.string()
+		asserts.Equal(errShortPacket, d.err)
+	}
+}
+
+func TestReadPacket(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 长度为 0
+	{
+		_, _, err := readPacket(bytes.NewReader([]byte{0, 0, 0, 0}))
+		asserts.Error(err)
+	}
+
+	// 超出最大长度
+	{
+		_, _, err := readPacket(bytes.NewReader(packet{}.uint32(maxPacketSize + 1)))
+		asserts.Error(err)
+	}
+
+	// 正文不完整
+	{
+		_, _, err := readPacket(bytes.NewReader([]byte{0, 0, 0, 5, fxpInit}))
+		asserts.Error(err)
+	}
+}
@@ -0,0 +1,182 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/graceful"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"golang.org/x/crypto/ssh"
+)
+
+// 认证结果在 ssh.Permissions 中的键
+const (
+	permUserID   = "cr-user-id"
+	permRoot     = "cr-root"
+	permReadonly = "cr-readonly"
+)
+
+// ErrAuthFailed 用户名或密码错误
+var ErrAuthFailed = errors.New("invalid username or password")
+
+// Server 内置 SFTP 服务端，用户名为账户邮箱，密码为 WebDAV 应用密码，
+// 未开启两步验证的账户也可使用登录密码。用户组需开启 WebDAV
+type Server struct {
+	config *ssh.ServerConfig
+}
+
+// NewServer 使用 hostKeyPath 处的私钥新建服务端，文件不存在时生成新的私钥
+func NewServer(hostKeyPath string) (*Server, error) {
+	signer, err := loadHostKey(hostKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load host key: %w", err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return authenticate(conn.User(), string(password))
+		},
+		ServerVersion: "SSH-2.0-Cloudreve",
+	}
+	config.AddHostKey(signer)
+
+	return &Server{config: config}, nil
+}
+
+// loadHostKey 读取 PEM 格式的私钥，文件不存在时生成 Ed25519 私钥并保存
+func loadHostKey(path string) (ssh.Signer, error) {
+	path = util.RelativePath(path)
+	if data, err := ioutil.ReadFile(path); err == nil {
+		return ssh.ParsePrivateKey(data)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+
+	util.Log().Info("SFTP host key generated at %q.", path)
+	return ssh.NewSignerFromKey(key)
+}
+
+// authenticate 验证账户邮箱及密码
+func authenticate(email, password string) (*ssh.Permissions, error) {
+	user, err := model.GetActiveUserByEmail(email)
+	if err != nil || !user.Group.WebDAVEnabled {
+		return nil, ErrAuthFailed
+	}
+
+	perm := &ssh.Permissions{Extensions: map[string]string{
+		permUserID: strconv.FormatUint(uint64(user.ID), 10),
+	}}
+
+	if webdav, err := model.GetWebdavByPassword(password, user.ID); err == nil {
+		perm.Extensions[permRoot] = webdav.Root
+		if webdav.Readonly {
+			perm.Extensions[permReadonly] = "1"
+		}
+		return perm, nil
+	}
+
+	// 开启两步验证的账户只能使用应用密码
+	if user.TwoFactor == "" {
+		if ok, _ := user.CheckPassword(password); ok {
+			return perm, nil
+		}
+	}
+
+	return nil, ErrAuthFailed
+}
+
+// ListenAndServe 监听 addr 并处理连接
+func (server *Server) ListenAndServe(addr string) error {
+	listener, err := graceful.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return server.Serve(listener)
+}
+
+// Serve 处理 listener 上的连接
+func (server *Server) Serve(listener net.Listener) error {
+	defer listener.Close()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		go server.handleConn(conn)
+	}
+}
+
+// handleConn 完成 SSH 握手，并为每个请求 sftp 子系统的会话通道启动 SFTP 会话
+func (server *Server) handleConn(conn net.Conn) {
+	defer conn.Close()
+	sshConn, chans, reqs, err := ssh.NewServerConn(conn, server.config)
+	if err != nil {
+		util.Log().Debug("SFTP handshake with %q failed: %s", conn.RemoteAddr(), err)
+		return
+	}
+	defer sshConn.Close()
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			util.Log().Debug("Failed to accept SFTP channel: %s", err)
+			continue
+		}
+
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+				req.Reply(ok, nil)
+				if !ok {
+					continue
+				}
+
+				go func() {
+					defer channel.Close()
+					session, err := newSession(sshConn.Permissions)
+					if err != nil {
+						util.Log().Warning("Failed to initialize SFTP session: %s", err)
+						return
+					}
+					defer session.Close()
+
+					if err := session.Serve(channel); err != nil {
+						util.Log().Debug("SFTP session of %q closed: %s", sshConn.User(), err)
+					}
+				}()
+			}
+		}(requests)
+	}
+}
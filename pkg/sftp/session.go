@@ -0,0 +1,856 @@
+package sftp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	errNoSuchFile       = errors.New("no such file or directory")
+	errPermissionDenied = errors.New("permission denied")
+	errInvalidHandle    = errors.New("invalid handle")
+	errFileExisted      = errors.New("file already exists")
+	errNotEmpty         = errors.New("directory not empty")
+	errUnsupported      = errors.New("operation unsupported")
+)
+
+// handle 已打开的文件或目录
+type handle interface {
+	Close() error
+}
+
+// session 单个 SFTP 会话，请求按序处理
+type session struct {
+	uid      uint
+	root     string
+	readonly bool
+
+	out     io.Writer
+	handles map[string]handle
+	next    uint64
+}
+
+// newSession 根据认证结果新建会话
+func newSession(perm *ssh.Permissions) (*session, error) {
+	uid, err := strconv.ParseUint(perm.Extensions[permUserID], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session{
+		uid:      uint(uid),
+		root:     perm.Extensions[permRoot],
+		readonly: perm.Extensions[permReadonly] != "",
+		handles:  make(map[string]handle),
+	}, nil
+}
+
+// Serve 处理 rw 上的请求，直到连接关闭
+func (s *session) Serve(rw io.ReadWriter) error {
+	s.out = rw
+	for {
+		typ, data, err := readPacket(rw)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if typ == fxpInit {
+			if err := s.send(newPacket(fxpVersion).uint32(protocolVersion)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		d := &decoder{data: data}
+		id := d.uint32()
+		if d.err != nil {
+			return d.err
+		}
+
+		if err := s.send(s.handle(typ, id, d)); err != nil {
+			return err
+		}
+	}
+}
+
+// Close 关闭会话所有打开的句柄，连接中断时未关闭的写入将被丢弃
+func (s *session) Close() {
+	for key, h := range s.handles {
+		if wh, ok := h.(*writeHandle); ok {
+			wh.dirty = false
+		}
+		if err := h.Close(); err != nil {
+			util.Log().Warning("Failed to close SFTP handle: %s", err)
+		}
+		delete(s.handles, key)
+	}
+}
+
+func (s *session) send(p packet) error {
+	_, err := s.out.Write(p.finish())
+	return err
+}
+
+// handle 处理单个请求并返回响应
+func (s *session) handle(typ byte, id uint32, d *decoder) packet {
+	var resp packet
+	var err error
+
+	switch typ {
+	case fxpRealpath:
+		resp = s.realpath(id, d.string())
+	case fxpStat, fxpLstat:
+		resp, err = s.stat(id, d.string())
+	case fxpFstat:
+		resp, err = s.fstat(id, d.string())
+	case fxpOpendir:
+		resp, err = s.opendir(id, d.string())
+	case fxpReaddir:
+		resp, err = s.readdir(id, d.string())
+	case fxpOpen:
+		name := d.string()
+		flags := d.uint32()
+		d.attrs()
+		resp, err = s.open(id, name, flags)
+	case fxpRead:
+		key := d.string()
+		offset := d.uint64()
+		length := d.uint32()
+		resp, err = s.read(id, key, offset, length)
+	case fxpWrite:
+		key := d.string()
+		offset := d.uint64()
+		err = s.write(key, offset, d.bytes())
+	case fxpClose:
+		err = s.close(d.string())
+	case fxpSetstat:
+		name := d.string()
+		err = s.setstat(name, d.attrs())
+	case fxpFsetstat:
+		key := d.string()
+		err = s.fsetstat(key, d.attrs())
+	case fxpRemove:
+		err = s.remove(d.string())
+	case fxpMkdir:
+		name := d.string()
+		d.attrs()
+		err = s.mkdir(name)
+	case fxpRmdir:
+		err = s.rmdir(d.string())
+	case fxpRename:
+		src := d.string()
+		err = s.rename(src, d.string())
+	default:
+		err = errUnsupported
+	}
+
+	if d.err != nil {
+		return statusPacket(id, fxBadMessage, d.err.Error())
+	}
+	if err != nil {
+		return errorPacket(id, err)
+	}
+	if resp == nil {
+		return statusPacket(id, fxOK, "")
+	}
+	return resp
+}
+
+// statusPacket 构造状态响应
+func statusPacket(id uint32, code uint32, msg string) packet {
+	return newPacket(fxpStatus).uint32(id).uint32(code).string(msg).string("")
+}
+
+// errorPacket 将错误转换为状态响应
+func errorPacket(id uint32, err error) packet {
+	code := uint32(fxFailure)
+	switch err {
+	case io.EOF:
+		code = fxEOF
+	case errNoSuchFile:
+		code = fxNoSuchFile
+	case errPermissionDenied:
+		code = fxPermissionDenied
+	case errUnsupported:
+		code = fxOpUnsupported
+	}
+
+	var appErr serializer.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.Code {
+		case serializer.CodeParentNotExist, serializer.CodeNotFound:
+			code = fxNoSuchFile
+		case serializer.CodeRootProtected, serializer.CodeRetentionLocked, serializer.CodePolicyNotAllowed:
+			code = fxPermissionDenied
+		}
+	}
+
+	return statusPacket(id, code, err.Error())
+}
+
+// cleanPath 将客户端路径转换为绝对路径，用户主目录即为根目录
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// newFS 为当前用户初始化文件系统，每次操作重新读取用户以获取最新的容量及状态
+func (s *session) newFS() (*filesystem.FileSystem, error) {
+	user, err := model.GetActiveUserByID(s.uid)
+	if err != nil {
+		return nil, errPermissionDenied
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		fs.Recycle()
+		return nil, err
+	}
+
+	// 重定根目录，根目录已不存在时拒绝访问
+	if err := fs.UseRoot(s.root); err != nil {
+		fs.Recycle()
+		return nil, errPermissionDenied
+	}
+
+	return fs, nil
+}
+
+// lookup 查找路径对应的目录或文件
+func lookup(fs *filesystem.FileSystem, p string) (*model.Folder, *model.File, error) {
+	if ok, folder := fs.IsPathExist(p); ok {
+		return folder, nil, nil
+	}
+	if ok, file := fs.IsFileExist(p); ok {
+		return nil, file, nil
+	}
+	return nil, nil, errNoSuchFile
+}
+
+// folderAttrs 目录属性
+func (s *session) folderAttrs(folder *model.Folder) *Attrs {
+	perm := uint32(0755)
+	if s.readonly {
+		perm = 0555
+	}
+	mtime := uint32(folder.UpdatedAt.Unix())
+	return &Attrs{
+		Flags:       attrSize | attrPermissions | attrACModTime,
+		Permissions: modeDir | perm,
+		Atime:       mtime,
+		Mtime:       mtime,
+	}
+}
+
+// fileAttrs 文件属性
+func (s *session) fileAttrs(file *model.File) *Attrs {
+	perm := uint32(0644)
+	if s.readonly {
+		perm = 0444
+	}
+	mtime := uint32(file.UpdatedAt.Unix())
+	return &Attrs{
+		Flags:       attrSize | attrPermissions | attrACModTime,
+		Size:        file.Size,
+		Permissions: modeRegular | perm,
+		Atime:       mtime,
+		Mtime:       mtime,
+	}
+}
+
+// longName 生成 ls -l 格式的文件描述
+func longName(name string, a *Attrs) string {
+	mode := []byte("-rwxrwxrwx")
+	if a.Permissions&modeDir != 0 {
+		mode[0] = 'd'
+	}
+	for i := 0; i < 9; i++ {
+		if a.Permissions&(1<<uint(8-i)) == 0 {
+			mode[i+1] = '-'
+		}
+	}
+
+	mtime := time.Unix(int64(a.Mtime), 0).Format("Jan _2 15:04")
+	return fmt.Sprintf("%s 1 cloudreve cloudreve %8d %s %s", mode, a.Size, mtime, name)
+}
+
+func (s *session) realpath(id uint32, name string) packet {
+	return newPacket(fxpName).uint32(id).uint32(1).string(cleanPath(name)).string(cleanPath(name)).attrs(&Attrs{})
+}
+
+func (s *session) stat(id uint32, name string) (packet, error) {
+	fs, err := s.newFS()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	folder, file, err := lookup(fs, cleanPath(name))
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPacket(fxpAttrs).uint32(id)
+	if folder != nil {
+		return p.attrs(s.folderAttrs(folder)), nil
+	}
+	return p.attrs(s.fileAttrs(file)), nil
+}
+
+func (s *session) fstat(id uint32, key string) (packet, error) {
+	switch h := s.handles[key].(type) {
+	case *readHandle:
+		return newPacket(fxpAttrs).uint32(id).attrs(s.fileAttrs(h.file)), nil
+	case *writeHandle:
+		info, err := h.temp.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return newPacket(fxpAttrs).uint32(id).attrs(&Attrs{
+			Flags:       attrSize | attrPermissions | attrACModTime,
+			Size:        uint64(info.Size()),
+			Permissions: modeRegular | 0644,
+			Atime:       uint32(info.ModTime().Unix()),
+			Mtime:       uint32(info.ModTime().Unix()),
+		}), nil
+	case *dirHandle:
+		return newPacket(fxpAttrs).uint32(id).attrs(s.folderAttrs(h.folder)), nil
+	}
+
+	return nil, errInvalidHandle
+}
+
+// addHandle 保存句柄并返回句柄响应
+func (s *session) addHandle(id uint32, h handle) packet {
+	s.next++
+	key := strconv.FormatUint(s.next, 10)
+	s.handles[key] = h
+	return newPacket(fxpHandle).uint32(id).string(key)
+}
+
+func (s *session) close(key string) error {
+	h, ok := s.handles[key]
+	if !ok {
+		return errInvalidHandle
+	}
+
+	delete(s.handles, key)
+	return h.Close()
+}
+
+// dirHandle 已打开的目录，打开时列出全部子对象
+type dirHandle struct {
+	folder  *model.Folder
+	entries []packet
+}
+
+func (h *dirHandle) Close() error {
+	return nil
+}
+
+func (s *session) opendir(id uint32, name string) (packet, error) {
+	fs, err := s.newFS()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	ok, folder := fs.IsPathExist(cleanPath(name))
+	if !ok {
+		return nil, errNoSuchFile
+	}
+
+	folders, err := folder.GetChildFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &dirHandle{folder: folder, entries: make([]packet, 0, len(folders)+len(files))}
+	for i := range folders {
+		h.entries = append(h.entries, nameEntry(folders[i].Name, s.folderAttrs(&folders[i])))
+	}
+	for i := range files {
+		h.entries = append(h.entries, nameEntry(files[i].Name, s.fileAttrs(&files[i])))
+	}
+
+	return s.addHandle(id, h), nil
+}
+
+// nameEntry 构造目录列表中的一项，不含数据包头
+func nameEntry(name string, a *Attrs) packet {
+	return packet{}.string(name).string(longName(name, a)).attrs(a)
+}
+
+func (s *session) readdir(id uint32, key string) (packet, error) {
+	h, ok := s.handles[key].(*dirHandle)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+
+	if len(h.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	// 受数据包大小限制，分批返回
+	p := newPacket(fxpName).uint32(id).uint32(0)
+	count := uint32(0)
+	for len(h.entries) > 0 && (count == 0 || len(p)+len(h.entries[0]) < maxPacketSize-1024) {
+		p = append(p, h.entries[0]...)
+		h.entries = h.entries[1:]
+		count++
+	}
+
+	// 回填数据包头（长度、类型、请求 ID）之后的数量
+	binary.BigEndian.PutUint32(p[9:], count)
+	return p, nil
+}
+
+func (s *session) open(id uint32, name string, flags uint32) (packet, error) {
+	name = cleanPath(name)
+	if flags&(fxfWrite|fxfAppend|fxfCreat|fxfTrunc) == 0 {
+		return s.openRead(id, name)
+	}
+
+	if s.readonly {
+		return nil, errPermissionDenied
+	}
+
+	return s.openWrite(id, name, flags)
+}
+
+// readHandle 以读模式打开的文件
+type readHandle struct {
+	fs   *filesystem.FileSystem
+	file *model.File
+	rs   response.RSCloser
+	pos  int64
+}
+
+func (h *readHandle) Close() error {
+	defer h.fs.Recycle()
+	return h.rs.Close()
+}
+
+func (s *session) openRead(id uint32, name string) (packet, error) {
+	fs, err := s.newFS()
+	if err != nil {
+		return nil, err
+	}
+
+	ok, file := fs.IsFileExist(name)
+	if !ok {
+		fs.Recycle()
+		return nil, errNoSuchFile
+	}
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(context.Background(), 0)
+	if err != nil {
+		fs.Recycle()
+		return nil, err
+	}
+
+	return s.addHandle(id, &readHandle{fs: fs, file: file, rs: rs}), nil
+}
+
+func (s *session) read(id uint32, key string, offset uint64, length uint32) (packet, error) {
+	h, ok := s.handles[key].(*readHandle)
+	if !ok {
+		return nil, errInvalidHandle
+	}
+
+	if offset >= h.file.Size {
+		return nil, io.EOF
+	}
+
+	if h.pos != int64(offset) {
+		if _, err := h.rs.Seek(int64(offset), io.SeekStart); err != nil {
+			return nil, err
+		}
+		h.pos = int64(offset)
+	}
+
+	if length > maxReadLength {
+		length = maxReadLength
+	}
+
+	buf := make([]byte, length)
+	n, err := io.ReadFull(h.rs, buf)
+	h.pos += int64(n)
+	if n == 0 && err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	return newPacket(fxpData).uint32(id).bytes(buf[:n]), nil
+}
+
+// writeHandle 以写模式打开的文件，内容先写入临时文件，关闭时上传
+type writeHandle struct {
+	session *session
+	path    string
+	temp    *os.File
+	limit   uint64
+	dirty   bool
+}
+
+func (h *writeHandle) Close() error {
+	defer func() {
+		h.temp.Close()
+		os.Remove(h.temp.Name())
+	}()
+
+	if !h.dirty {
+		return nil
+	}
+
+	return h.session.upload(h)
+}
+
+func (s *session) openWrite(id uint32, name string, flags uint32) (packet, error) {
+	fs, err := s.newFS()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	if exist, _ := fs.IsPathExist(name); exist {
+		return nil, errFileExisted
+	}
+
+	exist, file := fs.IsFileExist(name)
+	if exist && flags&fxfExcl != 0 {
+		return nil, errFileExisted
+	}
+	if !exist {
+		if flags&fxfCreat == 0 {
+			return nil, errNoSuchFile
+		}
+		if ok, _ := fs.IsPathExist(path.Dir(name)); !ok {
+			return nil, errNoSuchFile
+		}
+	}
+
+	tempDir := filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "sftp")
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		return nil, err
+	}
+
+	temp, err := ioutil.TempFile(tempDir, "upload_")
+	if err != nil {
+		return nil, err
+	}
+
+	h := &writeHandle{
+		session: s,
+		path:    name,
+		temp:    temp,
+		limit:   fs.User.GetRemainingCapacity(),
+		dirty:   !exist || flags&fxfTrunc != 0,
+	}
+
+	// 未截断时以原有内容为基础修改
+	if exist {
+		h.limit += file.Size
+		if flags&fxfTrunc == 0 {
+			if err := copyContent(fs, file, temp); err != nil {
+				h.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return s.addHandle(id, h), nil
+}
+
+// copyContent 将文件内容复制到 dst
+func copyContent(fs *filesystem.FileSystem, file *model.File, dst io.Writer) error {
+	fs.SetTargetFile(&[]model.File{*file})
+	defer fs.CleanTargets()
+
+	rs, err := fs.GetDownloadContent(context.Background(), 0)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	_, err = io.Copy(dst, rs)
+	return err
+}
+
+func (s *session) write(key string, offset uint64, data []byte) error {
+	h, ok := s.handles[key].(*writeHandle)
+	if !ok {
+		return errInvalidHandle
+	}
+
+	if offset+uint64(len(data)) > h.limit {
+		return filesystem.ErrInsufficientCapacity
+	}
+
+	if _, err := h.temp.WriteAt(data, int64(offset)); err != nil {
+		return err
+	}
+
+	h.dirty = true
+	return nil
+}
+
+func (s *session) setstat(name string, a *Attrs) error {
+	if a.Flags&attrSize != 0 {
+		return errUnsupported
+	}
+
+	// 权限及时间无法修改，忽略
+	return nil
+}
+
+func (s *session) fsetstat(key string, a *Attrs) error {
+	h, ok := s.handles[key]
+	if !ok {
+		return errInvalidHandle
+	}
+
+	if a.Flags&attrSize == 0 {
+		return nil
+	}
+
+	wh, ok := h.(*writeHandle)
+	if !ok {
+		return errPermissionDenied
+	}
+
+	if a.Size > wh.limit {
+		return filesystem.ErrInsufficientCapacity
+	}
+
+	if err := wh.temp.Truncate(int64(a.Size)); err != nil {
+		return err
+	}
+
+	wh.dirty = true
+	return nil
+}
+
+// upload 上传写句柄的临时文件，已存在的文件将被覆盖
+func (s *session) upload(h *writeHandle) error {
+	info, err := h.temp.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.temp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	fs, err := s.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	fileData := fsctx.FileStream{
+		File:        ioutil.NopCloser(h.temp),
+		Size:        uint64(info.Size()),
+		Name:        path.Base(h.path),
+		VirtualPath: path.Dir(h.path),
+	}
+
+	// 判断文件是否已存在
+	exist, originFile := fs.IsFileExist(h.path)
+	if exist {
+		// 检查此文件是否有软链接
+		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
+		if err == nil && len(fileList) == 0 {
+			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
+			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
+			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
+		}
+
+		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
+		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
+		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
+		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		// 保留历史版本时新内容写入新的源文件
+		if len(fileList) > 0 {
+			fs.KeepVersion(originFile, &fileData)
+		}
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
+		fileData.Mode |= fsctx.Overwrite
+	} else {
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		fs.Use("BeforeUpload", filesystem.HookValidateGroupLimits)
+		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
+		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
+	}
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
+
+	return fs.Upload(ctx, &fileData)
+}
+
+func (s *session) remove(name string) error {
+	if s.readonly {
+		return errPermissionDenied
+	}
+
+	fs, err := s.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	ok, file := fs.IsFileExist(cleanPath(name))
+	if !ok {
+		return errNoSuchFile
+	}
+
+	return fs.Trash(context.Background(), []uint{}, []uint{file.ID})
+}
+
+func (s *session) mkdir(name string) error {
+	if s.readonly {
+		return errPermissionDenied
+	}
+
+	fs, err := s.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	name = cleanPath(name)
+	if _, _, err := lookup(fs, name); err == nil {
+		return errFileExisted
+	}
+	if ok, _ := fs.IsPathExist(path.Dir(name)); !ok {
+		return errNoSuchFile
+	}
+
+	_, err = fs.CreateDirectory(context.Background(), name)
+	return err
+}
+
+func (s *session) rmdir(name string) error {
+	if s.readonly {
+		return errPermissionDenied
+	}
+
+	name = cleanPath(name)
+	if name == "/" {
+		return errPermissionDenied
+	}
+
+	fs, err := s.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	ok, folder := fs.IsPathExist(name)
+	if !ok {
+		return errNoSuchFile
+	}
+
+	// 只删除空目录
+	if folders, err := folder.GetChildFolder(); err != nil || len(folders) > 0 {
+		return errNotEmpty
+	}
+	if files, err := folder.GetChildFiles(); err != nil || len(files) > 0 {
+		return errNotEmpty
+	}
+
+	return fs.Trash(context.Background(), []uint{folder.ID}, []uint{})
+}
+
+func (s *session) rename(src, dst string) error {
+	if s.readonly {
+		return errPermissionDenied
+	}
+
+	src, dst = cleanPath(src), cleanPath(dst)
+	if src == "/" || dst == "/" {
+		return errPermissionDenied
+	}
+
+	fs, err := s.newFS()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	folder, file, err := lookup(fs, src)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := lookup(fs, dst); err == nil {
+		return errFileExisted
+	}
+
+	var (
+		fileIDs   []uint
+		folderIDs []uint
+	)
+	if folder != nil {
+		folderIDs = []uint{folder.ID}
+	} else {
+		fileIDs = []uint{file.ID}
+	}
+
+	ctx := context.Background()
+	if path.Dir(src) != path.Dir(dst) {
+		return fs.Move(
+			context.WithValue(ctx, fsctx.WebdavDstName, path.Base(dst)),
+			folderIDs,
+			fileIDs,
+			path.Dir(src),
+			path.Dir(dst),
+		)
+	}
+
+	return fs.Rename(ctx, folderIDs, fileIDs, path.Base(dst))
+}
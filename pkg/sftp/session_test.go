@@ -0,0 +1,175 @@
+package sftp
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+type bufferRW struct {
+	*bytes.Reader
+	out *bytes.Buffer
+}
+
+func (rw bufferRW) Write(p []byte) (int, error) {
+	return rw.out.Write(p)
+}
+
+func statusCode(resp []byte) uint32 {
+	d := &decoder{data: resp[1:]}
+	d.uint32()
+	return d.uint32()
+}
+
+func TestSession_Serve(t *testing.T) {
+	asserts := assert.New(t)
+	s := &session{uid: 1, readonly: true, handles: make(map[string]handle)}
+
+	in := &bytes.Buffer{}
+	for _, req := range []packet{
+		newPacket(fxpInit).uint32(protocolVersion),
+		newPacket(fxpRealpath).uint32(1).string("a/../b/."),
+		newPacket(fxpReadlink).uint32(2).string("/a"),
+		newPacket(fxpRead).uint32(3).string("404").uint64(0).uint32(10),
+		newPacket(fxpRemove).uint32(4).string("/a"),
+		newPacket(fxpMkdir).uint32(5).string("/a").uint32(0),
+		newPacket(fxpRename).uint32(6).string("/a"),
+	} {
+		in.Write(req.finish())
+	}
+
+	out := &bytes.Buffer{}
+	asserts.NoError(s.Serve(bufferRW{Reader: bytes.NewReader(in.Bytes()), out: out}))
+
+	var res [][]byte
+	for out.Len() > 0 {
+		typ, data, err := readPacket(out)
+		asserts.NoError(err)
+		res = append(res, append([]byte{typ}, data...))
+	}
+	asserts.Len(res, 7)
+
+	// 版本协商
+	asserts.EqualValues(fxpVersion, res[0][0])
+	asserts.Equal([]byte{0, 0, 0, protocolVersion}, res[0][1:])
+
+	// 路径规范化
+	{
+		asserts.EqualValues(fxpName, res[1][0])
+		d := &decoder{data: res[1][1:]}
+		asserts.EqualValues(1, d.uint32())
+		asserts.EqualValues(1, d.uint32())
+		asserts.Equal("/b", d.string())
+	}
+
+	// 不支持的操作
+	asserts.EqualValues(fxpStatus, res[2][0])
+	asserts.EqualValues(fxOpUnsupported, statusCode(res[2]))
+
+	// 无效句柄
+	asserts.EqualValues(fxFailure, statusCode(res[3]))
+
+	// 只读账户
+	asserts.EqualValues(fxPermissionDenied, statusCode(res[4]))
+	asserts.EqualValues(fxPermissionDenied, statusCode(res[5]))
+
+	// 数据包不完整
+	asserts.EqualValues(fxBadMessage, statusCode(res[6]))
+}
+
+func TestSession_Readdir(t *testing.T) {
+	asserts := assert.New(t)
+	s := &session{handles: make(map[string]handle)}
+	s.handles["1"] = &dirHandle{entries: []packet{
+		nameEntry("a", &Attrs{Flags: attrPermissions, Permissions: modeDir | 0755}),
+		nameEntry("b", &Attrs{Flags: attrSize, Size: 5}),
+	}}
+
+	resp, err := s.readdir(1, "1")
+	asserts.NoError(err)
+	d := &decoder{data: resp.finish()[5:]}
+	asserts.EqualValues(1, d.uint32())
+	asserts.EqualValues(2, d.uint32())
+	asserts.Equal("a", d.string())
+	asserts.Contains(d.string(), "drwxr-xr-x")
+
+	// 已全部返回
+	_, err = s.readdir(2, "1")
+	asserts.Error(err)
+	asserts.EqualValues(fxEOF, statusCode(errorPacket(2, err).finish()[4:]))
+
+	_, err = s.readdir(3, "2")
+	asserts.Equal(errInvalidHandle, err)
+}
+
+func TestNewSession(t *testing.T) {
+	asserts := assert.New(t)
+
+	s, err := newSession(&ssh.Permissions{Extensions: map[string]string{
+		permUserID: "2", permRoot: "/dav", permReadonly: "1",
+	}})
+	asserts.NoError(err)
+	asserts.EqualValues(2, s.uid)
+	asserts.Equal("/dav", s.root)
+	asserts.True(s.readonly)
+
+	_, err = newSession(&ssh.Permissions{Extensions: map[string]string{}})
+	asserts.Error(err)
+}
+
+func TestAuthenticate(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 用户不存在
+	mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err := authenticate("who@cloudreve.org", "pass")
+	asserts.Equal(ErrAuthFailed, err)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestLoadHostKey(t *testing.T) {
+	asserts := assert.New(t)
+	dir, err := ioutil.TempDir("", "sftp")
+	asserts.NoError(err)
+	defer os.RemoveAll(dir)
+	keyPath := filepath.Join(dir, "host_key")
+
+	// 自动生成
+	signer, err := loadHostKey(keyPath)
+	asserts.NoError(err)
+	asserts.FileExists(keyPath)
+
+	// 读取已有私钥
+	loaded, err := loadHostKey(keyPath)
+	asserts.NoError(err)
+	asserts.Equal(signer.PublicKey().Marshal(), loaded.PublicKey().Marshal())
+
+	// 格式错误
+	asserts.NoError(ioutil.WriteFile(keyPath, []byte("invalid"), 0600))
+	_, err = loadHostKey(keyPath)
+	asserts.Error(err)
+}
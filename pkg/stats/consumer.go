@@ -0,0 +1,108 @@
+package stats
+
+import (
+	"errors"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// 用于排列用量最多用户的指标
+const (
+	RankStorage       = "storage"
+	RankUploads       = "uploads"
+	RankUploadBytes   = "upload_bytes"
+	RankDownloads     = "downloads"
+	RankDownloadBytes = "download_bytes"
+)
+
+// ErrUnknownRank 未知的排序指标
+var ErrUnknownRank = errors.New("unknown rank metric")
+
+// Consumer 用户的已用容量及一段时间内的用量
+type Consumer struct {
+	UserID        uint   `json:"user_id"`
+	Email         string `json:"email"`
+	Nick          string `json:"nick"`
+	Storage       uint64 `json:"storage"`
+	Uploads       uint64 `json:"uploads"`
+	UploadBytes   uint64 `json:"upload_bytes"`
+	Downloads     uint64 `json:"downloads"`
+	DownloadBytes uint64 `json:"download_bytes"`
+}
+
+// GetTopConsumers 获取截至 end 的 days 天内按 rank 排列的前 limit 个用户。
+// 按已用容量排列时取当前的容量，不受日期范围影响
+func GetTopConsumers(rank string, days int, end time.Time, limit int) ([]Consumer, error) {
+	from, to := model.StatDate(end.AddDate(0, 0, 1-days)), model.StatDate(end)
+	var (
+		users []model.User
+		sums  []model.UserStatSum
+		err   error
+	)
+
+	switch rank {
+	case RankStorage:
+		if users, err = model.ListUsersByStorage(limit); err != nil || len(users) == 0 {
+			return nil, err
+		}
+
+		uids := make([]uint, len(users))
+		for i := range users {
+			uids[i] = users[i].ID
+		}
+
+		if sums, err = model.SumUserStats(from, to, uids, "", 0); err != nil {
+			return nil, err
+		}
+	case RankUploads, RankUploadBytes, RankDownloads, RankDownloadBytes:
+		if sums, err = model.SumUserStats(from, to, nil, rank, limit); err != nil || len(sums) == 0 {
+			return nil, err
+		}
+
+		uids := make([]uint, len(sums))
+		for i := range sums {
+			uids[i] = sums[i].UserID
+		}
+
+		var found []model.User
+		if found, err = model.ListUsersByIDs(uids); err != nil {
+			return nil, err
+		}
+
+		// 按用量排列，已删除的用户只保留 ID
+		byID := make(map[uint]model.User, len(found))
+		for _, user := range found {
+			byID[user.ID] = user
+		}
+		for _, sum := range sums {
+			user := byID[sum.UserID]
+			user.ID = sum.UserID
+			users = append(users, user)
+		}
+	default:
+		return nil, ErrUnknownRank
+	}
+
+	usage := make(map[uint]model.UserStatSum, len(sums))
+	for _, sum := range sums {
+		usage[sum.UserID] = sum
+	}
+
+	consumers := make([]Consumer, 0, len(users))
+	for _, user := range users {
+		sum := usage[user.ID]
+		consumers = append(consumers, Consumer{
+			UserID:        user.ID,
+			Email:         user.Email,
+			Nick:          user.Nick,
+			Storage:       user.Storage,
+			Uploads:       sum.Uploads,
+			UploadBytes:   sum.UploadBytes,
+			Downloads:     sum.Downloads,
+			DownloadBytes: sum.DownloadBytes,
+		})
+	}
+
+	return consumers, nil
+}
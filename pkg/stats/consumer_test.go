@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTopConsumers(t *testing.T) {
+	asserts := assert.New(t)
+	end := time.Date(2021, 3, 10, 12, 0, 0, 0, time.Local)
+
+	// 未知的指标
+	{
+		_, err := GetTopConsumers("unknown", 7, end, 10)
+		asserts.ErrorIs(err, ErrUnknownRank)
+	}
+
+	// 按流量排列，已删除的用户只保留 ID
+	{
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)ORDER BY download_bytes desc LIMIT 2").
+			WithArgs("2021-03-04", "2021-03-10").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "downloads", "download_bytes"}).
+				AddRow(2, 1, 100).AddRow(3, 5, 50))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "storage"}).AddRow(2, "a@cloudreve.org", 10))
+		consumers, err := GetTopConsumers(RankDownloadBytes, 7, end, 2)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(consumers, 2)
+		asserts.EqualValues(2, consumers[0].UserID)
+		asserts.Equal("a@cloudreve.org", consumers[0].Email)
+		asserts.EqualValues(100, consumers[0].DownloadBytes)
+		asserts.EqualValues(10, consumers[0].Storage)
+		asserts.EqualValues(3, consumers[1].UserID)
+		asserts.Empty(consumers[1].Email)
+	}
+
+	// 按已用容量排列
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)ORDER BY storage desc").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(1, 200).AddRow(2, 10))
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").
+			WithArgs("2021-03-10", "2021-03-10", 1, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "uploads"}).AddRow(2, 4))
+		consumers, err := GetTopConsumers(RankStorage, 1, end, 2)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(consumers, 2)
+		asserts.EqualValues(200, consumers[0].Storage)
+		asserts.EqualValues(0, consumers[0].Uploads)
+		asserts.EqualValues(4, consumers[1].Uploads)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").WillReturnError(errors.New("error"))
+		_, err := GetTopConsumers(RankUploads, 7, end, 2)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
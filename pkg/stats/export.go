@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV 将按日统计数据以 CSV 格式写入 w，每行为一天
+func (s *Series) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"date", "active_users", "registrations", "uploads", "upload_bytes",
+		"downloads", "download_bytes", "share_hits", "storage", "storage_growth"}); err != nil {
+		return err
+	}
+
+	for i, date := range s.Dates {
+		if err := writer.Write([]string{
+			date,
+			formatUint(s.ActiveUsers[i]),
+			formatUint(s.Registrations[i]),
+			formatUint(s.Uploads[i]),
+			formatUint(s.UploadBytes[i]),
+			formatUint(s.Downloads[i]),
+			formatUint(s.DownloadBytes[i]),
+			formatUint(s.ShareHits[i]),
+			formatUint(s.Storage[i]),
+			strconv.FormatInt(s.StorageGrowth[i], 10),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteConsumersCSV 将用户用量排行以 CSV 格式写入 w
+func WriteConsumersCSV(w io.Writer, consumers []Consumer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"user_id", "email", "nick", "storage", "uploads", "upload_bytes",
+		"downloads", "download_bytes"}); err != nil {
+		return err
+	}
+
+	for _, consumer := range consumers {
+		if err := writer.Write([]string{
+			formatUint(uint64(consumer.UserID)),
+			consumer.Email,
+			consumer.Nick,
+			formatUint(consumer.Storage),
+			formatUint(consumer.Uploads),
+			formatUint(consumer.UploadBytes),
+			formatUint(consumer.Downloads),
+			formatUint(consumer.DownloadBytes),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
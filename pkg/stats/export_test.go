@@ -0,0 +1,40 @@
+package stats
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeries_WriteCSV(t *testing.T) {
+	asserts := assert.New(t)
+	series := &Series{
+		Dates:         []string{"2021-03-09", "2021-03-10"},
+		ActiveUsers:   []uint64{1, 2},
+		Registrations: []uint64{0, 1},
+		Uploads:       []uint64{3, 4},
+		UploadBytes:   []uint64{30, 40},
+		Downloads:     []uint64{5, 6},
+		DownloadBytes: []uint64{50, 60},
+		ShareHits:     []uint64{7, 8},
+		Storage:       []uint64{100, 90},
+		StorageGrowth: []int64{100, -10},
+	}
+
+	buf := &bytes.Buffer{}
+	asserts.NoError(series.WriteCSV(buf))
+	asserts.Equal("date,active_users,registrations,uploads,upload_bytes,downloads,download_bytes,share_hits,storage,storage_growth\n"+
+		"2021-03-09,1,0,3,30,5,50,7,100,100\n"+
+		"2021-03-10,2,1,4,40,6,60,8,90,-10\n", buf.String())
+}
+
+func TestWriteConsumersCSV(t *testing.T) {
+	asserts := assert.New(t)
+	buf := &bytes.Buffer{}
+	asserts.NoError(WriteConsumersCSV(buf, []Consumer{
+		{UserID: 1, Email: "a@cloudreve.org", Nick: "a,b", Storage: 10, Uploads: 1, UploadBytes: 2, Downloads: 3, DownloadBytes: 4},
+	}))
+	asserts.Equal("user_id,email,nick,storage,uploads,upload_bytes,downloads,download_bytes\n"+
+		"1,a@cloudreve.org,\"a,b\",10,1,2,3,4\n", buf.String())
+}
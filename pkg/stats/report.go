@@ -0,0 +1,115 @@
+package stats
+
+import (
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Series 按日期排列、可直接用于绘制图表的统计数据
+type Series struct {
+	Dates         []string `json:"dates"`
+	ActiveUsers   []uint64 `json:"active_users"`
+	Registrations []uint64 `json:"registrations"`
+	Uploads       []uint64 `json:"uploads"`
+	UploadBytes   []uint64 `json:"upload_bytes"`
+	Downloads     []uint64 `json:"downloads"`
+	DownloadBytes []uint64 `json:"download_bytes"`
+	ShareHits     []uint64 `json:"share_hits"`
+	Storage       []uint64 `json:"storage"`
+	// StorageGrowth 与前一天相比的存储用量变化
+	StorageGrowth []int64 `json:"storage_growth"`
+}
+
+// GetSeries 获取截至 end 的 days 天统计数据，policyID 为 0 时返回全站数据。
+// 没有记录的日期各项指标为 0，存储用量沿用前一天
+func GetSeries(policyID uint, days int, end time.Time) (*Series, error) {
+	// 多取一天用于计算第一天的存储增量
+	from := end.AddDate(0, 0, -days)
+	stats, err := model.ListDailyStats(policyID, model.StatDate(from), model.StatDate(end))
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]*model.DailyStat, len(stats))
+	for i := range stats {
+		byDate[stats[i].Date] = &stats[i]
+	}
+
+	series := &Series{}
+	var lastStorage uint64
+	if stat, ok := byDate[model.StatDate(from)]; ok {
+		lastStorage = stat.Storage
+	}
+
+	for i := 1; i <= days; i++ {
+		date := model.StatDate(from.AddDate(0, 0, i))
+		stat, ok := byDate[date]
+		if !ok {
+			stat = &model.DailyStat{Storage: lastStorage}
+		}
+
+		series.Dates = append(series.Dates, date)
+		series.ActiveUsers = append(series.ActiveUsers, stat.ActiveUsers)
+		series.Registrations = append(series.Registrations, stat.Registrations)
+		series.Uploads = append(series.Uploads, stat.Uploads)
+		series.UploadBytes = append(series.UploadBytes, stat.UploadBytes)
+		series.Downloads = append(series.Downloads, stat.Downloads)
+		series.DownloadBytes = append(series.DownloadBytes, stat.DownloadBytes)
+		series.ShareHits = append(series.ShareHits, stat.ShareHits)
+		series.Storage = append(series.Storage, stat.Storage)
+		series.StorageGrowth = append(series.StorageGrowth, int64(stat.Storage)-int64(lastStorage))
+		lastStorage = stat.Storage
+	}
+
+	return series, nil
+}
+
+// Summary 汇总统计数据
+func (s *Series) Summary() map[string]uint64 {
+	summary := map[string]uint64{}
+	for i := range s.Dates {
+		summary["active_users"] += s.ActiveUsers[i]
+		summary["registrations"] += s.Registrations[i]
+		summary["uploads"] += s.Uploads[i]
+		summary["upload_bytes"] += s.UploadBytes[i]
+		summary["downloads"] += s.Downloads[i]
+		summary["download_bytes"] += s.DownloadBytes[i]
+		summary["share_hits"] += s.ShareHits[i]
+	}
+
+	if len(s.Storage) > 0 {
+		summary["storage"] = s.Storage[len(s.Storage)-1]
+	}
+
+	return summary
+}
+
+// SendReport 向设定的收件人发送最近七天的统计报告，未设定收件人时不发送
+func SendReport() {
+	recipients := model.GetSettingByName("stats_report_to")
+	if strings.TrimSpace(recipients) == "" {
+		return
+	}
+
+	series, err := GetSeries(0, 7, time.Now().AddDate(0, 0, -1))
+	if err != nil {
+		util.Log().Warning("Failed to load statistics for report: %s", err)
+		return
+	}
+
+	title, body := email.NewStatsReportEmail(series.Dates[0], series.Dates[len(series.Dates)-1], series.Summary())
+	for _, to := range strings.Split(recipients, ",") {
+		to = strings.TrimSpace(to)
+		if to == "" {
+			continue
+		}
+
+		if err := email.Send(to, title, body); err != nil {
+			util.Log().Warning("Failed to send statistics report to %q: %s", to, err)
+		}
+	}
+}
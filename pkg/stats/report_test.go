@@ -0,0 +1,65 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSeries(t *testing.T) {
+	asserts := assert.New(t)
+	end := time.Date(2021, 3, 10, 12, 0, 0, 0, time.Local)
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnError(errors.New("error"))
+		series, err := GetSeries(0, 3, end)
+		asserts.Error(err)
+		asserts.Nil(series)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 缺失的日期补齐
+	{
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").
+			WithArgs(0, "2021-03-07", "2021-03-10").
+			WillReturnRows(sqlmock.NewRows([]string{"date", "uploads", "storage"}).
+				AddRow("2021-03-07", 1, 100).
+				AddRow("2021-03-08", 2, 150).
+				AddRow("2021-03-10", 3, 120))
+		series, err := GetSeries(0, 3, end)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal([]string{"2021-03-08", "2021-03-09", "2021-03-10"}, series.Dates)
+		asserts.Equal([]uint64{2, 0, 3}, series.Uploads)
+		asserts.Equal([]uint64{150, 150, 120}, series.Storage)
+		asserts.Equal([]int64{50, 0, -30}, series.StorageGrowth)
+
+		summary := series.Summary()
+		asserts.EqualValues(5, summary["uploads"])
+		asserts.EqualValues(120, summary["storage"])
+	}
+}
+
+func TestSendReport(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未设定收件人
+	{
+		cache.Set("setting_stats_report_to", "", 0)
+		SendReport()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		cache.Set("setting_stats_report_to", "admin@cloudreve.org", 0)
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnError(errors.New("error"))
+		SendReport()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
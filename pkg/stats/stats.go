@@ -0,0 +1,246 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 由请求触发累加的指标，取值为统计表中的字段名
+const (
+	MetricDownloads     = "downloads"
+	MetricDownloadBytes = "download_bytes"
+	MetricShareHits     = "share_hits"
+)
+
+// Collector 在内存中累计当日指标，定期写入数据库
+type Collector struct {
+	mu       sync.Mutex
+	counters map[string]map[string]uint64
+	// 每日各用户的指标
+	users map[string]map[uint]map[string]uint64
+	// 每日的活跃用户，值表示是否已计入数据库
+	active map[string]map[uint]bool
+}
+
+// Default 默认的统计收集器
+var Default = NewCollector()
+
+// NewCollector 新建统计收集器
+func NewCollector() *Collector {
+	return &Collector{
+		counters: make(map[string]map[string]uint64),
+		users:    make(map[string]map[uint]map[string]uint64),
+		active:   make(map[string]map[uint]bool),
+	}
+}
+
+// Incr 累加当日的指标
+func (c *Collector) Incr(metric string) {
+	c.Add(metric, 1)
+}
+
+// Add 在当日的指标上累加 delta
+func (c *Collector) Add(metric string, delta uint64) {
+	date := model.StatDate(time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counters[date] == nil {
+		c.counters[date] = make(map[string]uint64)
+	}
+	c.counters[date][metric] += delta
+}
+
+// Download 记录一次文件下载及按文件大小估算的流量，同时计入文件所有者的用量
+func (c *Collector) Download(owner uint, size uint64) {
+	date := model.StatDate(time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counters[date] == nil {
+		c.counters[date] = make(map[string]uint64)
+	}
+	c.counters[date][MetricDownloads]++
+	c.counters[date][MetricDownloadBytes] += size
+
+	if owner == 0 {
+		return
+	}
+
+	if c.users[date] == nil {
+		c.users[date] = make(map[uint]map[string]uint64)
+	}
+	if c.users[date][owner] == nil {
+		c.users[date][owner] = make(map[string]uint64)
+	}
+	c.users[date][owner][MetricDownloads]++
+	c.users[date][owner][MetricDownloadBytes] += size
+}
+
+// UserActive 记录用户当日活跃
+func (c *Collector) UserActive(uid uint) {
+	date := model.StatDate(time.Now())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active[date] == nil {
+		c.active[date] = make(map[uint]bool)
+	}
+	if _, ok := c.active[date][uid]; !ok {
+		c.active[date][uid] = false
+	}
+}
+
+// Flush 将累计的指标写入数据库。活跃用户按新出现的用户数累加，
+// 进程重启后同一用户在当日可能被重复计入。
+func (c *Collector) Flush() error {
+	today := model.StatDate(time.Now())
+	c.mu.Lock()
+	counters := c.counters
+	c.counters = make(map[string]map[string]uint64)
+	users := c.users
+	c.users = make(map[string]map[uint]map[string]uint64)
+
+	newActive := make(map[string]uint64)
+	for date, users := range c.active {
+		for uid, flushed := range users {
+			if !flushed {
+				newActive[date]++
+				users[uid] = true
+			}
+		}
+
+		// 只保留当日的活跃用户用于去重
+		if date != today {
+			delete(c.active, date)
+		}
+	}
+	c.mu.Unlock()
+
+	for date, active := range newActive {
+		if counters[date] == nil {
+			counters[date] = make(map[string]uint64)
+		}
+		counters[date]["active_users"] = active
+	}
+
+	for date, incr := range counters {
+		if err := model.SaveDailyStat(date, 0, incr, nil); err != nil {
+			return err
+		}
+	}
+
+	for date, byUser := range users {
+		for uid, incr := range byUser {
+			if err := model.SaveUserDailyStat(date, uid, incr, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Incr 在默认收集器中累加当日的指标
+func Incr(metric string) {
+	Default.Incr(metric)
+}
+
+// Download 在默认收集器中记录一次文件下载
+func Download(owner uint, size uint64) {
+	Default.Download(owner, size)
+}
+
+// UserActive 在默认收集器中记录用户当日活跃
+func UserActive(uid uint) {
+	Default.UserActive(uid)
+}
+
+// Collect 从数据库统计 day 当天的注册、上传及存储用量并写入统计表及用户统计表，
+// 存储用量为统计时的快照，只在统计当天时更新
+func Collect(day time.Time) error {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	date := model.StatDate(start)
+
+	registrations, err := model.CountUsersCreatedBetween(start, end)
+	if err != nil {
+		return err
+	}
+
+	uploads, err := model.SumFilesCreatedBetween(start, end)
+	if err != nil {
+		return err
+	}
+
+	site := map[string]interface{}{"registrations": registrations}
+	policies := make(map[uint]map[string]interface{})
+	var uploadCount, uploadBytes uint64
+	for _, stat := range uploads {
+		policies[stat.PolicyID] = map[string]interface{}{"uploads": stat.Count, "upload_bytes": stat.Size}
+		uploadCount += stat.Count
+		uploadBytes += stat.Size
+	}
+	site["uploads"] = uploadCount
+	site["upload_bytes"] = uploadBytes
+
+	if date == model.StatDate(time.Now()) {
+		storage, err := model.SumFilesByPolicy()
+		if err != nil {
+			return err
+		}
+
+		var total uint64
+		for _, stat := range storage {
+			if policies[stat.PolicyID] == nil {
+				policies[stat.PolicyID] = map[string]interface{}{"uploads": 0, "upload_bytes": 0}
+			}
+			policies[stat.PolicyID]["storage"] = stat.Size
+			total += stat.Size
+		}
+		site["storage"] = total
+	}
+
+	if err := model.SaveDailyStat(date, 0, nil, site); err != nil {
+		return err
+	}
+
+	for policyID, values := range policies {
+		if err := model.SaveDailyStat(date, policyID, nil, values); err != nil {
+			return err
+		}
+	}
+
+	userUploads, err := model.SumUserFilesCreatedBetween(start, end)
+	if err != nil {
+		return err
+	}
+
+	for _, stat := range userUploads {
+		if err := model.SaveUserDailyStat(date, stat.UserID, nil, map[string]interface{}{
+			"uploads":      stat.Count,
+			"upload_bytes": stat.Size,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run 写入内存中的指标，并重新统计前一天与当天的数据
+func Run() {
+	if err := Default.Flush(); err != nil {
+		util.Log().Warning("Failed to flush statistics: %s", err)
+	}
+
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := Collect(day); err != nil {
+			util.Log().Warning("Failed to collect statistics of %s: %s", model.StatDate(day), err)
+		}
+	}
+}
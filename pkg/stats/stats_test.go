@@ -0,0 +1,157 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestCollector_Flush(t *testing.T) {
+	asserts := assert.New(t)
+	collector := NewCollector()
+
+	// 无数据
+	{
+		asserts.NoError(collector.Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入指标与活跃用户
+	{
+		collector.Incr(MetricDownloads)
+		collector.Incr(MetricDownloads)
+		collector.UserActive(1)
+		collector.UserActive(1)
+		collector.UserActive(2)
+
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)active_users(.+)downloads(.+)").
+			WithArgs(2, 2, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collector.Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已计入的活跃用户不再重复累加
+	{
+		collector.UserActive(1)
+		asserts.NoError(collector.Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 下载计入全站及文件所有者的用量
+	{
+		collector.Download(1, 10)
+		collector.Download(0, 5)
+
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)download_bytes(.+)downloads(.+)").
+			WithArgs(15, 2, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)user_daily_stats(.+)download_bytes(.+)downloads(.+)").
+			WithArgs(10, 1, sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(collector.Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		collector.Incr(MetricShareHits)
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(collector.Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCollect(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 统计注册用户失败
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(Collect(time.Now()))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 统计前一天，不更新存储用量
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"policy_id", "count", "size"}).AddRow(1, 2, 20))
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)").
+			WithArgs(3, sqlmock.AnyArg(), 20, 2, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)").
+			WithArgs(sqlmock.AnyArg(), 20, 2, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY user_id").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "count", "size"}).AddRow(1, 2, 20))
+		mock.ExpectQuery("SELECT(.+)user_daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)user_daily_stats(.+)").
+			WithArgs(sqlmock.AnyArg(), 20, 2, 3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(Collect(time.Now().AddDate(0, 0, -1)))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 统计当天，更新存储用量
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"policy_id", "count", "size"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"policy_id", "count", "size"}).AddRow(1, 5, 100))
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)storage(.+)").
+			WithArgs(0, 100, sqlmock.AnyArg(), 0, 0, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)daily_stats(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)daily_stats(.+)storage(.+)").
+			WithArgs(100, sqlmock.AnyArg(), 0, 0, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY user_id").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "count", "size"}))
+		asserts.NoError(Collect(time.Now()))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
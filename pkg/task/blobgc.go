@@ -0,0 +1,191 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// BlobGCTask 孤立物理文件回收任务
+type BlobGCTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps BlobGCProps
+	Err       *JobError
+}
+
+// BlobGCProps 孤立物理文件回收任务属性
+type BlobGCProps struct {
+	// 扫描的存储策略，为 0 时扫描全部存储策略
+	PolicyID      uint `json:"policy_id,omitempty"`
+	DryRun        bool `json:"dry_run,omitempty"`
+	RemoveMissing bool `json:"remove_missing,omitempty"`
+	// GracePeriod 忽略最近 GracePeriod 秒内修改过的物理文件及记录
+	GracePeriod int `json:"grace_period"`
+
+	// 执行进度，Cursor 为最后扫描的存储策略 ID，任务恢复后从此处继续
+	Cursor  uint                     `json:"cursor"`
+	Reports []*filesystem.BlobReport `json:"reports,omitempty"`
+}
+
+// Props 获取任务属性
+func (job *BlobGCTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *BlobGCTask) Type() int {
+	return BlobGCTaskType
+}
+
+// Creator 获取创建者ID
+func (job *BlobGCTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *BlobGCTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *BlobGCTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *BlobGCTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *BlobGCTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *BlobGCTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *BlobGCTask) Do() {
+	job.TaskModel.SetProgress(ListingProgress)
+
+	var policies []model.Policy
+	if job.TaskProps.PolicyID > 0 {
+		policy, err := model.GetPolicyByID(job.TaskProps.PolicyID)
+		if err != nil {
+			job.SetErrorMsg("Storage policy not exist.", err)
+			return
+		}
+		policies = append(policies, policy)
+	} else {
+		var err error
+		if policies, err = model.GetPolicies(); err != nil {
+			job.SetErrorMsg("Failed to list storage policies.", err)
+			return
+		}
+	}
+
+	fs, err := filesystem.NewAnonymousFileSystem()
+	if err != nil {
+		job.SetErrorMsg("Failed to initialize filesystem.", err)
+		return
+	}
+	defer fs.Recycle()
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].ID < policies[j].ID
+	})
+
+	for i := range policies {
+		if policies[i].ID <= job.TaskProps.Cursor {
+			continue
+		}
+
+		// 每个存储策略扫描完成后记录报告
+		job.TaskProps.Reports = append(job.TaskProps.Reports, job.collect(fs, &policies[i]))
+		job.TaskProps.Cursor = policies[i].ID
+		job.TaskModel.SetProps(job.Props())
+	}
+
+	failed := false
+	for _, report := range job.TaskProps.Reports {
+		failed = failed || report.Error != ""
+	}
+
+	if failed {
+		job.SetErrorMsg("Some storage policies failed to scan.", nil)
+	}
+}
+
+// collect 扫描单个存储策略并返回报告
+func (job *BlobGCTask) collect(fs *filesystem.FileSystem, policy *model.Policy) *filesystem.BlobReport {
+	report, err := fs.CollectOrphanBlobs(context.Background(), policy, filesystem.BlobGCOptions{
+		DryRun:        job.TaskProps.DryRun,
+		RemoveMissing: job.TaskProps.RemoveMissing,
+		GracePeriod:   time.Duration(job.TaskProps.GracePeriod) * time.Second,
+	})
+	if err != nil {
+		util.Log().Warning("Blob GC task %d failed to scan policy %q: %s", job.TaskModel.ID, policy.Name, err)
+		report.Error = err.Error()
+		return report
+	}
+
+	util.Log().Info("Blob GC task %d: policy %q, %d scanned, %d orphan(s), %d missing, %d deleted, %d removed, %d failed.",
+		job.TaskModel.ID, policy.Name, report.Scanned, report.Orphans, report.Missing, report.Deleted, report.Removed, report.Failed)
+	return report
+}
+
+// NewBlobGCTask 新建孤立物理文件回收任务
+func NewBlobGCTask(user *model.User, policyID uint, dryRun, removeMissing bool, gracePeriod int) (Job, error) {
+	newTask := &BlobGCTask{
+		User: user,
+		TaskProps: BlobGCProps{
+			PolicyID:      policyID,
+			DryRun:        dryRun,
+			RemoveMissing: removeMissing,
+			GracePeriod:   gracePeriod,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewBlobGCTaskFromModel 从数据库记录中恢复孤立物理文件回收任务
+func NewBlobGCTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &BlobGCTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
@@ -0,0 +1,144 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlobGCTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &BlobGCTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(BlobGCTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestBlobGCTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &BlobGCTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("error"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("error", task.GetError().Error)
+}
+
+func TestBlobGCTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 存储策略不存在
+	{
+		task := &BlobGCTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: BlobGCProps{PolicyID: 404},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 扫描全部存储策略，跳过已扫描的策略，无法确定根目录的策略记入报告
+	{
+		task := &BlobGCTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: BlobGCProps{Cursor: 2},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnRows(
+			sqlmock.NewRows([]string{"id", "type", "dir_name_rule"}).
+				AddRow(3, "mock", "{uid}").
+				AddRow(2, "mock", "uploads/{uid}"))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+		asserts.EqualValues(3, task.TaskProps.Cursor)
+		asserts.Len(task.TaskProps.Reports, 1)
+		asserts.EqualValues(3, task.TaskProps.Reports[0].PolicyID)
+		asserts.NotEmpty(task.TaskProps.Reports[0].Error)
+	}
+}
+
+func TestNewBlobGCTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewBlobGCTask(&model.User{}, 1, true, false, 3600)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(job.(*BlobGCTask).TaskProps.DryRun)
+		asserts.Equal(3600, job.(*BlobGCTask).TaskProps.GracePeriod)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewBlobGCTask(&model.User{}, 0, false, false, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewBlobGCTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewBlobGCTaskFromModel(&model.Task{Props: `{"policy_id":1,"cursor":1,"reports":[{"policy_id":1}]}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, job.(*BlobGCTask).TaskProps.Cursor)
+		asserts.Len(job.(*BlobGCTask).TaskProps.Reports, 1)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewBlobGCTaskFromModel(&model.Task{Props: "x"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
@@ -28,6 +30,12 @@ type CompressProps struct {
 	Dirs  []uint `json:"dirs"`
 	Files []uint `json:"files"`
 	Dst   string `json:"dst"`
+	// NotBefore 最早开始执行的时间戳，为 0 时立即执行
+	NotBefore int64 `json:"not_before,omitempty"`
+	// Notify 完成后是否发送邮件通知
+	Notify bool `json:"notify,omitempty"`
+	// Archive 已完成压缩的临时文件路径，任务中断恢复后直接上传
+	Archive string `json:"archive,omitempty"`
 }
 
 // Props 获取任务属性
@@ -46,6 +54,14 @@ func (job *CompressTask) Creator() uint {
 	return job.User.ID
 }
 
+// NotifyUser 获取任务结束后需通知的用户，已预约压缩完成邮件的任务不再重复通知
+func (job *CompressTask) NotifyUser() *model.User {
+	if job.TaskProps.Notify {
+		return nil
+	}
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *CompressTask) Model() *model.Task {
 	return job.TaskModel
@@ -56,6 +72,11 @@ func (job *CompressTask) SetStatus(status int) {
 	job.TaskModel.SetStatus(status)
 }
 
+// NotBefore 返回任务最早的执行时间
+func (job *CompressTask) NotBefore() time.Time {
+	return time.Unix(job.TaskProps.NotBefore, 0)
+}
+
 // SetError 设定任务失败信息
 func (job *CompressTask) SetError(err *JobError) {
 	job.Err = err
@@ -93,36 +114,48 @@ func (job *CompressTask) Do() {
 		return
 	}
 
-	util.Log().Debug("Starting compress file...")
-	job.TaskModel.SetProgress(CompressingProgress)
-
-	// 创建临时压缩文件
-	saveFolder := "compress"
-	zipFilePath := filepath.Join(
-		util.RelativePath(model.GetSettingByName("temp_path")),
-		saveFolder,
-		fmt.Sprintf("archive_%d.zip", time.Now().UnixNano()),
-	)
-	zipFile, err := util.CreatNestedFile(zipFilePath)
-	if err != nil {
-		util.Log().Warning("%s", err)
-		job.SetErrorMsg(err.Error())
-		return
-	}
+	ctx := context.Background()
+	zipFilePath := job.TaskProps.Archive
+	if zipFilePath != "" && util.Exists(zipFilePath) {
+		// 中断前已完成压缩
+		job.zipPath = zipFilePath
+		util.Log().Debug("Resume uploading compressed file %q...", zipFilePath)
+	} else {
+		util.Log().Debug("Starting compress file...")
+		job.TaskModel.SetProgress(CompressingProgress)
+
+		// 创建临时压缩文件
+		saveFolder := "compress"
+		zipFilePath = filepath.Join(
+			util.RelativePath(model.GetSettingByName("temp_path")),
+			saveFolder,
+			fmt.Sprintf("archive_%d.zip", time.Now().UnixNano()),
+		)
+		zipFile, err := util.CreatNestedFile(zipFilePath)
+		if err != nil {
+			util.Log().Warning("%s", err)
+			job.SetErrorMsg(err.Error())
+			return
+		}
 
-	defer zipFile.Close()
+		defer zipFile.Close()
 
-	// 开始压缩
-	ctx := context.Background()
-	err = fs.Compress(ctx, zipFile, job.TaskProps.Dirs, job.TaskProps.Files, false)
-	if err != nil {
-		job.SetErrorMsg(err.Error())
-		return
+		// 开始压缩
+		err = fs.Compress(ctx, zipFile, job.TaskProps.Dirs, job.TaskProps.Files, false)
+		if err != nil {
+			job.SetErrorMsg(err.Error())
+			return
+		}
+
+		job.zipPath = zipFilePath
+		zipFile.Close()
+
+		// 记录检查点
+		job.TaskProps.Archive = zipFilePath
+		job.TaskModel.SetProps(job.Props())
+		util.Log().Debug("Compressed file saved to %q, start uploading it...", zipFilePath)
 	}
 
-	job.zipPath = zipFilePath
-	zipFile.Close()
-	util.Log().Debug("Compressed file saved to %q, start uploading it...", zipFilePath)
 	job.TaskModel.SetProgress(TransferringProgress)
 
 	// 上传文件
@@ -133,17 +166,34 @@ func (job *CompressTask) Do() {
 	}
 
 	job.removeZipFile()
+
+	if job.TaskProps.Notify {
+		job.notify()
+	}
+}
+
+// notify 发送压缩完成通知邮件
+func (job *CompressTask) notify() {
+	title, body := email.NewArchiveReadyEmail(job.User.Nick, path.Base(job.TaskProps.Dst))
+	if err := email.Send(job.User.Email, title, body); err != nil {
+		util.Log().Warning("Failed to send archive ready notification to %q: %s", job.User.Email, err)
+	}
 }
 
 // NewCompressTask 新建压缩任务
 func NewCompressTask(user *model.User, dst string, dirs, files []uint) (Job, error) {
+	return NewScheduledCompressTask(user, CompressProps{
+		Dirs:  dirs,
+		Files: files,
+		Dst:   dst,
+	})
+}
+
+// NewScheduledCompressTask 使用给定属性新建压缩任务，可指定最早执行时间及完成通知
+func NewScheduledCompressTask(user *model.User, props CompressProps) (Job, error) {
 	newTask := &CompressTask{
-		User: user,
-		TaskProps: CompressProps{
-			Dirs:  dirs,
-			Files: files,
-			Dst:   dst,
-		},
+		User:      user,
+		TaskProps: props,
 	}
 
 	record, err := Record(newTask)
@@ -23,6 +23,15 @@ func TestCompressTask_Props(t *testing.T) {
 	asserts.Nil(task.Model())
 }
 
+func TestCompressTask_NotBefore(t *testing.T) {
+	asserts := assert.New(t)
+	task := &CompressTask{
+		User:      &model.User{},
+		TaskProps: CompressProps{NotBefore: 1640995200},
+	}
+	asserts.EqualValues(1640995200, task.NotBefore().Unix())
+}
+
 func TestCompressTask_SetStatus(t *testing.T) {
 	asserts := assert.New(t)
 	task := &CompressTask{
@@ -136,6 +145,16 @@ func TestCompressTask_Do(t *testing.T) {
 		// 查找子目录
 		mock.ExpectQuery("SELECT(.+)folders").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		// 记录检查点
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
+		// 更新进度
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
 		// 更新错误
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
@@ -145,6 +164,31 @@ func TestCompressTask_Do(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 		asserts.True(util.IsEmpty(util.RelativePath("test/compress")))
+		asserts.NotEmpty(task.TaskProps.Archive)
+	}
+
+	// 中断前已完成压缩，直接上传
+	{
+		archive := util.RelativePath("test/compress/archive_resume.zip")
+		file, err := util.CreatNestedFile(archive)
+		asserts.NoError(err)
+		file.Close()
+		task.TaskProps.Archive = archive
+		task.Err = nil
+		// 更新进度
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
+		// 更新错误
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEmpty(task.GetError().Msg)
+		asserts.False(util.Exists(archive))
 	}
 }
 
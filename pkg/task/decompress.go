@@ -41,6 +41,11 @@ func (job *DecompressTask) Creator() uint {
 	return job.User.ID
 }
 
+// NotifyUser 获取任务结束后需通知的用户
+func (job *DecompressTask) NotifyUser() *model.User {
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *DecompressTask) Model() *model.Task {
 	return job.TaskModel
@@ -83,6 +88,7 @@ func (job *DecompressTask) Do() {
 
 	job.TaskModel.SetProgress(DecompressingProgress)
 
+	// 任务中断恢复后重新解压，中断前已解压的文件因重名会被跳过
 	err = fs.Decompress(context.Background(), job.TaskProps.Src, job.TaskProps.Dst, job.TaskProps.Encoding)
 	if err != nil {
 		job.SetErrorMsg("Failed to decompress file.", err)
@@ -0,0 +1,434 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// streamKey 分发任务使用的 Redis Stream
+	streamKey = "task_stream"
+	// streamGroup 所有 Worker 共用的消费组，每条任务只会被一个 Worker 认领
+	streamGroup = "task_workers"
+	// streamMaxLen Stream 中保留的最大消息数
+	streamMaxLen = 10000
+	// workerListKey 已注册 Worker 的信息
+	workerListKey = "task_worker_list"
+	// workerHeartbeatPrefix Worker 心跳记录，过期后视为离线
+	workerHeartbeatPrefix = "task_worker_heartbeat_"
+	// workerReadBlock 等待新任务时阻塞的最长时间
+	workerReadBlock = 5 * time.Second
+	// workerReclaimBatch 每次检查的待确认任务数
+	workerReclaimBatch = 100
+)
+
+var (
+	// WorkerHeartbeatInterval Worker 发送心跳的间隔
+	WorkerHeartbeatInterval = 10 * time.Second
+	// WorkerHeartbeatTTL 超过此时间未发送心跳的 Worker 视为离线，其已认领未完成的任务会被其他 Worker 重新认领
+	WorkerHeartbeatTTL = 30 * time.Second
+)
+
+// ParseTaskTypes 解析逗号分隔的任务类型
+func ParseTaskTypes(types string) []int {
+	res := make([]int, 0)
+	for _, value := range strings.Split(types, ",") {
+		if taskType, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+			res = append(res, taskType)
+		}
+	}
+	return res
+}
+
+// DistributedPool 将指定类型的任务通过 Redis Stream 分发给独立的 Worker 进程执行，其余任务仍在本地任务池执行
+type DistributedPool struct {
+	local Pool
+	pool  *redis.Pool
+	types map[int]bool
+}
+
+// NewDistributedPool 新建分布式任务池
+func NewDistributedPool(local Pool, pool *redis.Pool, types []int) *DistributedPool {
+	p := &DistributedPool{
+		local: local,
+		pool:  pool,
+		types: make(map[int]bool, len(types)),
+	}
+	for _, taskType := range types {
+		p.types[taskType] = true
+	}
+	return p
+}
+
+// Remote 返回给定类型的任务是否分发给 Worker 执行
+func (p *DistributedPool) Remote(taskType int) bool {
+	return p.types[taskType]
+}
+
+// Drain 等待本地任务池中执行中的任务，分发给 Worker 的任务由 Worker 自行处理
+func (p *DistributedPool) Drain(ctx context.Context) error {
+	if local, ok := p.local.(drainer); ok {
+		return local.Drain(ctx)
+	}
+
+	return nil
+}
+
+// Add 增加本地任务池的 Worker 数量
+func (p *DistributedPool) Add(num int) {
+	p.local.Add(num)
+}
+
+// Submit 提交任务，分发失败时在本地执行
+func (p *DistributedPool) Submit(job Job) {
+	if !p.Remote(job.Type()) || job.Model() == nil {
+		p.local.Submit(job)
+		return
+	}
+
+	rc := p.pool.Get()
+	defer rc.Close()
+
+	if _, err := rc.Do("XADD", streamKey, "MAXLEN", "~", streamMaxLen, "*", "task", job.Model().ID); err != nil {
+		util.Log().Warning("Failed to dispatch task %d to workers, executing it locally: %s", job.Model().ID, err)
+		p.local.Submit(job)
+	}
+}
+
+// WorkerInfo Worker 注册信息
+type WorkerInfo struct {
+	ID          string    `json:"id"`
+	Host        string    `json:"host"`
+	Concurrency int       `json:"concurrency"`
+	StartedAt   time.Time `json:"started_at"`
+	// LastHeartbeat 最后一次心跳时间，为空表示已离线
+	LastHeartbeat *time.Time `json:"last_heartbeat,omitempty"`
+}
+
+// StreamWorker 从 Redis Stream 认领并执行任务的独立 Worker
+type StreamWorker struct {
+	Info WorkerInfo
+
+	pool  *redis.Pool
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+// streamMessage Stream 中的一条任务消息
+type streamMessage struct {
+	ID     string
+	TaskID uint
+}
+
+// NewStreamWorker 新建 Worker，id 为空时随机生成
+func NewStreamWorker(pool *redis.Pool, id string, concurrency int) *StreamWorker {
+	host, _ := os.Hostname()
+	if id == "" {
+		id = fmt.Sprintf("%s-%s", host, util.RandStringRunes(6))
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	return &StreamWorker{
+		Info: WorkerInfo{
+			ID:          id,
+			Host:        host,
+			Concurrency: concurrency,
+			StartedAt:   time.Now(),
+		},
+		pool:  pool,
+		slots: make(chan struct{}, concurrency),
+	}
+}
+
+// Run 注册 Worker 并开始认领任务，直到 ctx 被取消。退出前等待执行中的任务完成
+func (w *StreamWorker) Run(ctx context.Context) error {
+	if err := w.register(); err != nil {
+		return err
+	}
+	defer w.deregister()
+
+	util.Log().Info("Task worker %q started with concurrency %d.", w.Info.ID, w.Info.Concurrency)
+	go w.keepalive(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.wg.Wait()
+			return nil
+		case w.slots <- struct{}{}:
+		}
+
+		messages, err := w.read()
+		if err != nil {
+			<-w.slots
+			util.Log().Warning("Task worker failed to read from stream: %s", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		if len(messages) == 0 {
+			<-w.slots
+			continue
+		}
+
+		w.dispatch(messages[0])
+	}
+}
+
+// register 注册 Worker 并确保消费组存在
+func (w *StreamWorker) register() error {
+	rc := w.pool.Get()
+	defer rc.Close()
+
+	if _, err := rc.Do("XGROUP", "CREATE", streamKey, streamGroup, "0", "MKSTREAM"); err != nil &&
+		!strings.Contains(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	info, _ := json.Marshal(w.Info)
+	if _, err := rc.Do("HSET", workerListKey, w.Info.ID, string(info)); err != nil {
+		return fmt.Errorf("failed to register worker: %w", err)
+	}
+
+	return w.heartbeat(rc)
+}
+
+// deregister 注销 Worker
+func (w *StreamWorker) deregister() {
+	rc := w.pool.Get()
+	defer rc.Close()
+
+	rc.Do("HDEL", workerListKey, w.Info.ID)
+	rc.Do("DEL", workerHeartbeatPrefix+w.Info.ID)
+}
+
+func (w *StreamWorker) heartbeat(rc redis.Conn) error {
+	_, err := rc.Do("SET", workerHeartbeatPrefix+w.Info.ID, time.Now().Unix(), "EX", int(WorkerHeartbeatTTL.Seconds()))
+	return err
+}
+
+// keepalive 定时发送心跳，并认领离线 Worker 未完成的任务
+func (w *StreamWorker) keepalive(ctx context.Context) {
+	ticker := time.NewTicker(WorkerHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		rc := w.pool.Get()
+		if err := w.heartbeat(rc); err != nil {
+			util.Log().Warning("Task worker failed to send heartbeat: %s", err)
+		}
+		rc.Close()
+
+		w.reclaim()
+	}
+}
+
+// read 阻塞读取一条新任务
+func (w *StreamWorker) read() ([]streamMessage, error) {
+	rc := w.pool.Get()
+	defer rc.Close()
+
+	reply, err := rc.Do("XREADGROUP", "GROUP", streamGroup, w.Info.ID, "COUNT", 1,
+		"BLOCK", workerReadBlock.Milliseconds(), "STREAMS", streamKey, ">")
+	if err != nil || reply == nil {
+		return nil, err
+	}
+
+	streams, err := redis.Values(reply, nil)
+	if err != nil || len(streams) == 0 {
+		return nil, err
+	}
+
+	stream, err := redis.Values(streams[0], nil)
+	if err != nil || len(stream) < 2 {
+		return nil, err
+	}
+
+	return parseStreamMessages(stream[1])
+}
+
+// reclaim 认领心跳超时的 Worker 未确认的任务，每次最多认领空闲执行槽的数量
+func (w *StreamWorker) reclaim() {
+	rc := w.pool.Get()
+	defer rc.Close()
+
+	pending, err := redis.Values(rc.Do("XPENDING", streamKey, streamGroup, "-", "+", workerReclaimBatch))
+	if err != nil {
+		util.Log().Debug("Task worker failed to list pending tasks: %s", err)
+		return
+	}
+
+	dead := make(map[string]bool)
+	for _, value := range pending {
+		entry, err := redis.Values(value, nil)
+		if err != nil || len(entry) < 3 {
+			continue
+		}
+
+		id, _ := redis.String(entry[0], nil)
+		consumer, _ := redis.String(entry[1], nil)
+		idle, _ := redis.Int64(entry[2], nil)
+		if consumer == w.Info.ID || time.Duration(idle)*time.Millisecond < WorkerHeartbeatTTL {
+			continue
+		}
+
+		isDead, checked := dead[consumer]
+		if !checked {
+			alive, err := redis.Bool(rc.Do("EXISTS", workerHeartbeatPrefix+consumer))
+			isDead = err == nil && !alive
+			dead[consumer] = isDead
+		}
+		if !isDead {
+			continue
+		}
+
+		select {
+		case w.slots <- struct{}{}:
+		default:
+			return
+		}
+
+		reply, err := rc.Do("XCLAIM", streamKey, streamGroup, w.Info.ID, WorkerHeartbeatTTL.Milliseconds(), id)
+		messages, parseErr := parseStreamMessages(reply)
+		if err != nil || parseErr != nil || len(messages) == 0 {
+			// 已被其他 Worker 认领
+			<-w.slots
+			continue
+		}
+
+		util.Log().Info("Task worker reclaimed task %d from offline worker %q.", messages[0].TaskID, consumer)
+		w.dispatch(messages[0])
+	}
+}
+
+// dispatch 在已占用的执行槽中执行任务
+func (w *StreamWorker) dispatch(message streamMessage) {
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.slots
+			w.wg.Done()
+		}()
+		w.execute(message)
+	}()
+}
+
+// execute 执行任务并确认消息，已结束的任务直接确认
+func (w *StreamWorker) execute(message streamMessage) {
+	defer w.ack(message.ID)
+
+	record, err := model.GetTasksByID(message.TaskID)
+	if err != nil {
+		util.Log().Warning("Task worker cannot find task %d: %s", message.TaskID, err)
+		return
+	}
+
+	switch record.Status {
+	case Queued:
+	case Processing:
+		// 原 Worker 执行过程中离线
+		if !recoverInterrupted(record) {
+			return
+		}
+	default:
+		return
+	}
+
+	job, err := GetJobFromModel(record)
+	if err != nil {
+		util.Log().Warning("Task worker failed to resume task %d: %s", message.TaskID, err)
+		return
+	}
+
+	util.Log().Debug("Task worker %q start executing task %d.", w.Info.ID, message.TaskID)
+	(&GeneralWorker{}).Do(job)
+}
+
+func (w *StreamWorker) ack(id string) {
+	rc := w.pool.Get()
+	defer rc.Close()
+
+	if _, err := rc.Do("XACK", streamKey, streamGroup, id); err != nil {
+		util.Log().Warning("Task worker failed to ack message %q: %s", id, err)
+	}
+}
+
+// parseStreamMessages 解析 XREADGROUP、XCLAIM 返回的消息列表
+func parseStreamMessages(reply interface{}) ([]streamMessage, error) {
+	entries, err := redis.Values(reply, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]streamMessage, 0, len(entries))
+	for _, value := range entries {
+		entry, err := redis.Values(value, nil)
+		if err != nil || len(entry) < 2 {
+			continue
+		}
+
+		id, _ := redis.String(entry[0], nil)
+		fields, _ := redis.StringMap(entry[1], nil)
+		taskID, err := strconv.ParseUint(fields["task"], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		messages = append(messages, streamMessage{ID: id, TaskID: uint(taskID)})
+	}
+
+	return messages, nil
+}
+
+// ListWorkers 列出已注册的 Worker 及其心跳状态
+func ListWorkers(pool *redis.Pool) ([]WorkerInfo, error) {
+	rc := pool.Get()
+	defer rc.Close()
+
+	values, err := redis.StringMap(rc.Do("HGETALL", workerListKey))
+	if err != nil {
+		return nil, err
+	}
+
+	workers := make([]WorkerInfo, 0, len(values))
+	for _, value := range values {
+		var info WorkerInfo
+		if err := json.Unmarshal([]byte(value), &info); err != nil {
+			continue
+		}
+
+		if last, err := redis.Int64(rc.Do("GET", workerHeartbeatPrefix+info.ID)); err == nil {
+			heartbeat := time.Unix(last, 0)
+			info.LastHeartbeat = &heartbeat
+		}
+
+		workers = append(workers, info)
+	}
+
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	return workers, nil
+}
@@ -0,0 +1,217 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jinzhu/gorm"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func testRedisPool(conn *redigomock.Conn) *redis.Pool {
+	return &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+}
+
+func TestParseTaskTypes(t *testing.T) {
+	a := assert.New(t)
+	a.Empty(ParseTaskTypes(""))
+	a.Equal([]int{0, 1, 10}, ParseTaskTypes("0, 1,x,10"))
+}
+
+func TestDistributedPool_Submit(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	local := &taskPoolMock{}
+	local.On("Submit", testMock.Anything)
+	local.On("Add", 2)
+	p := NewDistributedPool(local, testRedisPool(conn), []int{CompressTaskType})
+	p.Add(2)
+
+	// 本地执行
+	{
+		p.Submit(&TransferTask{TaskModel: &model.Task{Model: gorm.Model{ID: 1}}})
+		a.Empty(conn.Errors)
+	}
+
+	// 分发给 Worker
+	{
+		cmd := conn.Command("XADD", streamKey, "MAXLEN", "~", streamMaxLen, "*", "task", uint(2)).Expect("1-0")
+		p.Submit(&CompressTask{TaskModel: &model.Task{Model: gorm.Model{ID: 2}}})
+		a.Equal(1, conn.Stats(cmd))
+	}
+
+	// 分发失败，本地执行
+	{
+		conn.Clear()
+		cmd := conn.Command("XADD", streamKey, "MAXLEN", "~", streamMaxLen, "*", "task", uint(3)).ExpectError(errors.New("error"))
+		p.Submit(&CompressTask{TaskModel: &model.Task{Model: gorm.Model{ID: 3}}})
+		a.Equal(1, conn.Stats(cmd))
+	}
+
+	a.True(p.Remote(CompressTaskType))
+	a.False(p.Remote(TransferTaskType))
+}
+
+func TestResume_Distributed(t *testing.T) {
+	a := assert.New(t)
+	p := NewDistributedPool(&taskPoolMock{}, testRedisPool(redigomock.NewConn()), []int{CompressTaskType})
+
+	// 分发给 Worker 的任务不在本地恢复
+	mock.ExpectQuery("SELECT(.+)").WithArgs(Queued, Processing).WillReturnRows(sqlmock.NewRows([]string{"id", "status", "type"}).AddRow(1, Processing, CompressTaskType))
+	Resume(p)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestStreamWorker_Register(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	w := NewStreamWorker(testRedisPool(conn), "worker", 0)
+	a.Equal(1, w.Info.Concurrency)
+	a.NotEmpty(NewStreamWorker(nil, "", 2).Info.ID)
+
+	// 消费组已存在
+	{
+		conn.Command("XGROUP", "CREATE", streamKey, streamGroup, "0", "MKSTREAM").ExpectError(redis.Error("BUSYGROUP Consumer Group name already exists"))
+		conn.GenericCommand("HSET").Expect(int64(1))
+		heartbeat := conn.GenericCommand("SET").Expect("OK")
+		a.NoError(w.register())
+		a.Equal(1, conn.Stats(heartbeat))
+	}
+
+	// 无法创建消费组
+	{
+		conn.Clear()
+		conn.Command("XGROUP", "CREATE", streamKey, streamGroup, "0", "MKSTREAM").ExpectError(errors.New("error"))
+		a.Error(w.register())
+	}
+
+	// 注销
+	{
+		conn.Clear()
+		hdel := conn.Command("HDEL", workerListKey, "worker").Expect(int64(1))
+		del := conn.Command("DEL", workerHeartbeatPrefix+"worker").Expect(int64(1))
+		w.deregister()
+		a.Equal(1, conn.Stats(hdel))
+		a.Equal(1, conn.Stats(del))
+	}
+}
+
+func TestStreamWorker_Run(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	w := NewStreamWorker(testRedisPool(conn), "worker", 1)
+
+	conn.Command("XGROUP", "CREATE", streamKey, streamGroup, "0", "MKSTREAM").Expect("OK")
+	conn.GenericCommand("HSET").Expect(int64(1))
+	conn.GenericCommand("SET").Expect("OK")
+	conn.GenericCommand("HDEL").Expect(int64(1))
+	conn.GenericCommand("DEL").Expect(int64(1))
+	conn.GenericCommand("XREADGROUP").ExpectSlice(
+		[]interface{}{[]byte(streamKey), []interface{}{
+			[]interface{}{[]byte("1-0"), []interface{}{[]byte("task"), []byte("1")}},
+		}},
+	).Expect(nil)
+	ack := conn.Command("XACK", streamKey, streamGroup, "1-0").Expect(int64(1))
+
+	// 已完成的任务直接确认
+	mock.ExpectQuery("SELECT(.+)tasks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, Complete))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for conn.Stats(ack) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	a.NoError(<-done)
+	a.GreaterOrEqual(conn.Stats(ack), 1)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestStreamWorker_Reclaim(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+	w := NewStreamWorker(testRedisPool(conn), "worker", 1)
+
+	conn.GenericCommand("XPENDING").ExpectSlice(
+		// 自身认领的任务
+		[]interface{}{[]byte("1-0"), []byte("worker"), int64(60000), int64(1)},
+		// 刚被认领的任务
+		[]interface{}{[]byte("2-0"), []byte("alive"), int64(10), int64(1)},
+		// 离线 Worker 的任务
+		[]interface{}{[]byte("3-0"), []byte("dead"), int64(60000), int64(1)},
+	)
+	conn.Command("EXISTS", workerHeartbeatPrefix+"dead").Expect(int64(0))
+	claim := conn.Command("XCLAIM", streamKey, streamGroup, "worker", WorkerHeartbeatTTL.Milliseconds(), "3-0").ExpectSlice(
+		[]interface{}{[]byte("3-0"), []interface{}{[]byte("task"), []byte("3")}},
+	)
+	ack := conn.Command("XACK", streamKey, streamGroup, "3-0").Expect(int64(1))
+
+	// 中断次数超过上限
+	cache.Set("setting_task_max_retry", "3", 0)
+	mock.ExpectQuery("SELECT(.+)tasks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "status", "retries"}).AddRow(3, Processing, 3))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)error(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)status(.+)").WithArgs(Error, sqlmock.AnyArg(), 3).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w.reclaim()
+	w.wg.Wait()
+	a.Equal(1, conn.Stats(claim))
+	a.Equal(1, conn.Stats(ack))
+	a.Len(w.slots, 0)
+	a.NoError(mock.ExpectationsWereMet())
+}
+
+func TestParseStreamMessages(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := parseStreamMessages("invalid")
+	a.Error(err)
+
+	messages, err := parseStreamMessages([]interface{}{
+		[]interface{}{[]byte("1-0"), []interface{}{[]byte("task"), []byte("1")}},
+		[]interface{}{[]byte("2-0"), []interface{}{[]byte("task"), []byte("invalid")}},
+		[]interface{}{[]byte("3-0")},
+	})
+	a.NoError(err)
+	a.Equal([]streamMessage{{ID: "1-0", TaskID: 1}}, messages)
+}
+
+func TestListWorkers(t *testing.T) {
+	a := assert.New(t)
+	conn := redigomock.NewConn()
+
+	conn.Command("HGETALL", workerListKey).ExpectMap(map[string]string{
+		"b":       `{"id":"b","concurrency":2}`,
+		"a":       `{"id":"a","concurrency":1}`,
+		"invalid": `{`,
+	})
+	conn.Command("GET", workerHeartbeatPrefix+"a").Expect(int64(1700000000))
+	conn.Command("GET", workerHeartbeatPrefix+"b").Expect(nil)
+
+	workers, err := ListWorkers(testRedisPool(conn))
+	a.NoError(err)
+	a.Len(workers, 2)
+	a.Equal("a", workers[0].ID)
+	a.EqualValues(1700000000, workers[0].LastHeartbeat.Unix())
+	a.Equal("b", workers[1].ID)
+	a.Nil(workers[1].LastHeartbeat)
+}
@@ -0,0 +1,596 @@
+package task
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// exportChangeBatchSize 导出变更记录时每批读取的条数
+	exportChangeBatchSize = 1000
+	// exportMetadataName 归档中元数据文件的名称
+	exportMetadataName = "metadata.json"
+)
+
+// ExportTask 用户数据导出任务
+type ExportTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps ExportProps
+	Err       *JobError
+}
+
+// ExportProps 用户数据导出任务属性
+type ExportProps struct {
+	// 是否打包文件内容
+	IncludeFiles bool `json:"include_files"`
+	// 是否已通过管理员审核
+	Approved bool `json:"approved"`
+	// 单个分卷的大小上限
+	PartSize int64 `json:"part_size"`
+
+	// 执行结果
+	Parts   []ExportPart `json:"parts,omitempty"`
+	Missing []string     `json:"missing,omitempty"`
+	Expires *time.Time   `json:"expires,omitempty"`
+}
+
+// ExportPart 导出归档的分卷，每个分卷都是独立的 zip 文件
+type ExportPart struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// ExportMetadata 导出归档中的元数据
+type ExportMetadata struct {
+	User      exportUser       `json:"user"`
+	Folders   []exportFolder   `json:"folders"`
+	Files     []exportFile     `json:"files"`
+	Shares    []exportShare    `json:"shares"`
+	Tasks     []exportTask     `json:"tasks"`
+	Downloads []exportDownload `json:"downloads"`
+	Changes   []exportChange   `json:"changes"`
+	// 未能打包内容的文件路径
+	Missing    []string  `json:"missing,omitempty"`
+	ExportedAt time.Time `json:"exported_at"`
+}
+
+type exportUser struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"email"`
+	Nick      string    `json:"nick"`
+	GroupID   uint      `json:"group_id"`
+	Storage   uint64    `json:"storage"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportFolder struct {
+	ID        uint      `json:"id"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportFile struct {
+	ID        uint      `json:"id"`
+	Path      string    `json:"path"`
+	Size      uint64    `json:"size"`
+	PolicyID  uint      `json:"policy_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type exportShare struct {
+	Key             string     `json:"key"`
+	IsDir           bool       `json:"is_dir"`
+	SourceID        uint       `json:"source_id"`
+	SourceName      string     `json:"source_name"`
+	Locked          bool       `json:"locked"`
+	Views           int        `json:"views"`
+	Downloads       int        `json:"downloads"`
+	RemainDownloads int        `json:"remain_downloads"`
+	Expires         *time.Time `json:"expires,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+type exportTask struct {
+	ID        uint      `json:"id"`
+	Type      int       `json:"type"`
+	Status    int       `json:"status"`
+	Props     string    `json:"props"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportDownload struct {
+	ID        uint      `json:"id"`
+	Source    string    `json:"source"`
+	Status    int       `json:"status"`
+	TotalSize uint64    `json:"total_size"`
+	Dst       string    `json:"dst"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type exportChange struct {
+	Type       string    `json:"type"`
+	ObjectType string    `json:"object_type"`
+	ObjectID   uint      `json:"object_id"`
+	Name       string    `json:"name"`
+	OldName    string    `json:"old_name,omitempty"`
+	Size       uint64    `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ExportPath 返回导出任务分卷的存放目录
+func ExportPath(taskID uint) string {
+	return filepath.Join(
+		util.RelativePath(model.GetSettingByName("temp_path")),
+		"export",
+		fmt.Sprintf("export_%d", taskID),
+	)
+}
+
+// Props 获取任务属性
+func (job *ExportTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *ExportTask) Type() int {
+	return ExportTaskType
+}
+
+// Creator 获取创建者ID
+func (job *ExportTask) Creator() uint {
+	return job.User.ID
+}
+
+// NotifyUser 获取任务结束后需通知的用户
+func (job *ExportTask) NotifyUser() *model.User {
+	return job.User
+}
+
+// Model 获取任务的数据库模型
+func (job *ExportTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *ExportTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *ExportTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *ExportTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *ExportTask) GetError() *JobError {
+	return job.Err
+}
+
+// Pending 未通过审核的任务需等待管理员操作，不进入执行队列
+func (job *ExportTask) Pending() bool {
+	return !job.TaskProps.Approved
+}
+
+// Approve 通过审核
+func (job *ExportTask) Approve() error {
+	job.TaskProps.Approved = true
+	return job.TaskModel.SetProps(job.Props())
+}
+
+// Do 开始执行任务
+func (job *ExportTask) Do() {
+	if job.Pending() {
+		job.SetErrorMsg("Export is not approved.", nil)
+		return
+	}
+
+	// 任务恢复时重新生成所有分卷
+	dir := ExportPath(job.TaskModel.ID)
+	os.RemoveAll(dir)
+	job.TaskProps.Parts = nil
+	job.TaskProps.Missing = nil
+	job.TaskProps.Expires = nil
+
+	job.TaskModel.SetProgress(ListingProgress)
+	metadata, files, err := job.collect()
+	if err != nil {
+		job.SetErrorMsg("Failed to collect user data.", err)
+		return
+	}
+
+	archive := newExportArchive(dir, job.TaskProps.PartSize)
+	if job.TaskProps.IncludeFiles {
+		job.TaskModel.SetProgress(CompressingProgress)
+		if err := job.packFiles(archive, files); err != nil {
+			archive.Close()
+			job.SetErrorMsg("Failed to pack files.", err)
+			return
+		}
+	}
+
+	metadata.Missing = job.TaskProps.Missing
+	if err := archive.AddJSON(exportMetadataName, metadata); err != nil {
+		archive.Close()
+		job.SetErrorMsg("Failed to write metadata.", err)
+		return
+	}
+
+	if err := archive.Close(); err != nil {
+		job.SetErrorMsg("Failed to finish archive.", err)
+		return
+	}
+
+	expires := time.Now().Add(time.Duration(model.GetIntSetting("export_expires", 604800)) * time.Second)
+	job.TaskProps.Parts = archive.parts
+	job.TaskProps.Expires = &expires
+	job.TaskModel.SetProps(job.Props())
+}
+
+// exportItem 待打包的文件及其在用户空间中的路径
+type exportItem struct {
+	file *model.File
+	path string
+}
+
+// collect 读取用户的元数据
+func (job *ExportTask) collect() (*ExportMetadata, []exportItem, error) {
+	uid := job.User.ID
+	metadata := &ExportMetadata{
+		User: exportUser{
+			ID:        uid,
+			Email:     job.User.Email,
+			Nick:      job.User.Nick,
+			GroupID:   job.User.GroupID,
+			Storage:   job.User.Storage,
+			CreatedAt: job.User.CreatedAt,
+		},
+		Folders:    []exportFolder{},
+		Files:      []exportFile{},
+		Shares:     []exportShare{},
+		Tasks:      []exportTask{},
+		Downloads:  []exportDownload{},
+		Changes:    []exportChange{},
+		ExportedAt: time.Now(),
+	}
+
+	folders, err := model.GetFoldersByUserID(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	paths := model.FolderPaths(folders)
+	for _, folder := range folders {
+		metadata.Folders = append(metadata.Folders, exportFolder{
+			ID:        folder.ID,
+			Path:      paths[folder.ID],
+			CreatedAt: folder.CreatedAt,
+			UpdatedAt: folder.UpdatedAt,
+		})
+	}
+
+	files, err := model.GetFilesByUserID(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]exportItem, 0, len(files))
+	for i := range files {
+		// 上传中的文件不导出
+		if files[i].UploadSessionID != nil {
+			continue
+		}
+
+		filePath := path.Join(paths[files[i].FolderID], files[i].Name)
+		metadata.Files = append(metadata.Files, exportFile{
+			ID:        files[i].ID,
+			Path:      filePath,
+			Size:      files[i].Size,
+			PolicyID:  files[i].PolicyID,
+			CreatedAt: files[i].CreatedAt,
+			UpdatedAt: files[i].UpdatedAt,
+		})
+		items = append(items, exportItem{file: &files[i], path: filePath})
+	}
+
+	shares, err := model.GetSharesByUserID(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, share := range shares {
+		metadata.Shares = append(metadata.Shares, exportShare{
+			Key:             hashid.HashID(share.ID, hashid.ShareID),
+			IsDir:           share.IsDir,
+			SourceID:        share.SourceID,
+			SourceName:      share.SourceName,
+			Locked:          share.Password != "",
+			Views:           share.Views,
+			Downloads:       share.Downloads,
+			RemainDownloads: share.RemainDownloads,
+			Expires:         share.Expires,
+			CreatedAt:       share.CreatedAt,
+		})
+	}
+
+	tasks, err := model.GetTasksByUserID(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, task := range tasks {
+		metadata.Tasks = append(metadata.Tasks, exportTask{
+			ID:        task.ID,
+			Type:      task.Type,
+			Status:    task.Status,
+			Props:     task.Props,
+			Error:     task.Error,
+			CreatedAt: task.CreatedAt,
+		})
+	}
+
+	downloads, err := model.GetDownloadsByUserID(uid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, download := range downloads {
+		metadata.Downloads = append(metadata.Downloads, exportDownload{
+			ID:        download.ID,
+			Source:    download.Source,
+			Status:    download.Status,
+			TotalSize: download.TotalSize,
+			Dst:       download.Dst,
+			Error:     download.Error,
+			CreatedAt: download.CreatedAt,
+		})
+	}
+
+	var cursor uint
+	for {
+		changes, err := model.ListChanges(uid, cursor, exportChangeBatchSize)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, change := range changes {
+			metadata.Changes = append(metadata.Changes, exportChange{
+				Type:       change.Type,
+				ObjectType: change.ObjectType,
+				ObjectID:   change.ObjectID,
+				Name:       change.Name,
+				OldName:    change.OldName,
+				Size:       change.Size,
+				CreatedAt:  change.CreatedAt,
+			})
+		}
+
+		if len(changes) < exportChangeBatchSize {
+			break
+		}
+		cursor = changes[len(changes)-1].ID
+	}
+
+	return metadata, items, nil
+}
+
+// packFiles 将文件内容写入归档，无法读取的文件记入 Missing
+func (job *ExportTask) packFiles(archive *exportArchive, items []exportItem) error {
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	ctx := context.Background()
+	for _, item := range items {
+		fs.Policy = item.file.GetPolicy()
+		if err := fs.DispatchHandler(); err != nil {
+			util.Log().Warning("Failed to dispatch handler for file %q: %s", item.path, err)
+			job.TaskProps.Missing = append(job.TaskProps.Missing, item.path)
+			continue
+		}
+
+		content, err := fs.Handler.Get(context.WithValue(ctx, fsctx.FileModelCtx, *item.file), item.file.SourceName)
+		if err != nil {
+			util.Log().Debug("Failed to open %q: %s", item.path, err)
+			job.TaskProps.Missing = append(job.TaskProps.Missing, item.path)
+			continue
+		}
+
+		err = archive.AddFile(path.Join("files", item.path), item.file, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportArchive 按大小切分的归档，超过分卷大小后在下一个条目开始新分卷
+type exportArchive struct {
+	dir      string
+	partSize int64
+	parts    []ExportPart
+
+	file    *os.File
+	zip     *zip.Writer
+	written int64
+	entries int
+}
+
+func newExportArchive(dir string, partSize int64) *exportArchive {
+	return &exportArchive{dir: dir, partSize: partSize}
+}
+
+// Write 写入当前分卷并统计大小
+func (a *exportArchive) Write(p []byte) (int, error) {
+	n, err := a.file.Write(p)
+	a.written += int64(n)
+	return n, err
+}
+
+// create 新建条目，当前分卷放不下时切换到新的分卷
+func (a *exportArchive) create(header *zip.FileHeader) (io.Writer, error) {
+	// 写出缓冲区中的数据，使统计的分卷大小准确
+	if a.zip != nil {
+		if err := a.zip.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.zip == nil || (a.partSize > 0 && a.entries > 0 &&
+		a.written+int64(header.UncompressedSize64) > a.partSize) {
+		if err := a.next(); err != nil {
+			return nil, err
+		}
+	}
+
+	a.entries++
+	return a.zip.CreateHeader(header)
+}
+
+// next 结束当前分卷并打开新的分卷
+func (a *exportArchive) next() error {
+	if err := a.finish(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("part-%03d.zip", len(a.parts)+1)
+	file, err := util.CreatNestedFile(filepath.Join(a.dir, name))
+	if err != nil {
+		return err
+	}
+
+	a.file = file
+	a.zip = zip.NewWriter(a)
+	a.written = 0
+	a.entries = 0
+	a.parts = append(a.parts, ExportPart{Name: name})
+	return nil
+}
+
+// finish 结束当前分卷
+func (a *exportArchive) finish() error {
+	if a.zip == nil {
+		return nil
+	}
+
+	err := a.zip.Close()
+	a.file.Close()
+	a.parts[len(a.parts)-1].Size = a.written
+	a.zip = nil
+	a.file = nil
+	return err
+}
+
+// AddFile 写入文件内容，以存储方式打包
+func (a *exportArchive) AddFile(name string, file *model.File, r io.Reader) error {
+	writer, err := a.create(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Store,
+		Modified:           file.UpdatedAt,
+		UncompressedSize64: file.Size,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, r)
+	return err
+}
+
+// AddJSON 写入 JSON 序列化后的数据
+func (a *exportArchive) AddJSON(name string, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	writer, err := a.create(&zip.FileHeader{
+		Name:               name,
+		Method:             zip.Deflate,
+		Modified:           time.Now(),
+		UncompressedSize64: uint64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(content)
+	return err
+}
+
+// Close 结束归档
+func (a *exportArchive) Close() error {
+	return a.finish()
+}
+
+// NewExportTask 新建用户数据导出任务
+func NewExportTask(user *model.User, includeFiles, approved bool) (Job, error) {
+	newTask := &ExportTask{
+		User: user,
+		TaskProps: ExportProps{
+			IncludeFiles: includeFiles,
+			Approved:     approved,
+			PartSize:     int64(model.GetIntSetting("export_part_size", 1<<30)),
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewExportTaskFromModel 从数据库记录中恢复用户数据导出任务
+func NewExportTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &ExportTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
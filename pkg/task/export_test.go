@@ -0,0 +1,137 @@
+package task
+
+import (
+	"archive/zip"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &ExportTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(ExportTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+	asserts.True(task.Pending())
+}
+
+func TestExportTask_Approve(t *testing.T) {
+	asserts := assert.New(t)
+	task := &ExportTask{
+		User:      &model.User{},
+		TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.Approve())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.False(task.Pending())
+}
+
+func TestExportTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+	tempPath := t.TempDir()
+	cache.Set("setting_temp_path", tempPath, 0)
+	cache.Set("setting_export_expires", "3600", 0)
+
+	// 未通过审核
+	{
+		task := &ExportTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 读取目录失败
+	{
+		task := &ExportTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: ExportProps{Approved: true},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 仅导出元数据
+	{
+		task := &ExportTask{
+			User:      &model.User{Model: gorm.Model{ID: 1}},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 2}},
+			TaskProps: ExportProps{Approved: true},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(1, "/", nil).AddRow(2, "docs", 1))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id", "size"}).AddRow(1, "a.txt", 2, 10))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "password"}).AddRow(1, "pwd"))
+		mock.ExpectQuery("SELECT(.+)tasks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)downloads(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)changes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+		asserts.Len(task.TaskProps.Parts, 1)
+		asserts.NotNil(task.TaskProps.Expires)
+
+		reader, err := zip.OpenReader(filepath.Join(ExportPath(2), task.TaskProps.Parts[0].Name))
+		asserts.NoError(err)
+		defer reader.Close()
+		asserts.Len(reader.File, 1)
+		asserts.Equal(exportMetadataName, reader.File[0].Name)
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	asserts := assert.New(t)
+	dir := t.TempDir()
+	archive := newExportArchive(dir, 15)
+
+	file := &model.File{Size: 10}
+	asserts.NoError(archive.AddFile("files/a.txt", file, strings.NewReader("0123456789")))
+	asserts.NoError(archive.AddFile("files/b.txt", file, strings.NewReader("0123456789")))
+	asserts.NoError(archive.AddJSON(exportMetadataName, map[string]string{"k": "v"}))
+	asserts.NoError(archive.Close())
+
+	asserts.Len(archive.parts, 3)
+	for _, part := range archive.parts {
+		reader, err := zip.OpenReader(filepath.Join(dir, part.Name))
+		asserts.NoError(err)
+		asserts.Len(reader.File, 1)
+		reader.Close()
+		asserts.NotZero(part.Size)
+	}
+}
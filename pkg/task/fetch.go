@@ -0,0 +1,359 @@
+package task
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// fetchProgressInterval 下载进度写入数据库的最短间隔
+	fetchProgressInterval = time.Second
+	// fetchMaxRedirects 最多跟随的重定向次数
+	fetchMaxRedirects = 10
+)
+
+var (
+	ErrFetchPrivateAddress = errors.New("fetching from private network address is not allowed")
+	ErrUnknownChecksum     = errors.New("unsupported checksum, should be in format of md5:<hex>, sha1:<hex> or sha256:<hex>")
+)
+
+// FetchTask 服务端直接从 URL 下载文件到用户空间的任务，无需 aria2
+type FetchTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps FetchProps
+	Err       *JobError
+}
+
+// FetchProps 从 URL 下载任务属性
+type FetchProps struct {
+	URL string `json:"url"`
+	Dst string `json:"dst"`
+	// Name 保存的文件名，为空时从响应头或 URL 中推断
+	Name string `json:"name,omitempty"`
+	// Checksum 下载完成后校验的摘要，格式为 算法:十六进制摘要
+	Checksum string `json:"checksum,omitempty"`
+
+	// 下载进度，Total 为 -1 表示大小未知
+	Total      int64 `json:"total"`
+	Downloaded int64 `json:"downloaded"`
+}
+
+// Props 获取任务属性
+func (job *FetchTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *FetchTask) Type() int {
+	return FetchTaskType
+}
+
+// Creator 获取创建者ID
+func (job *FetchTask) Creator() uint {
+	return job.User.ID
+}
+
+// NotifyUser 获取任务结束后需通知的用户
+func (job *FetchTask) NotifyUser() *model.User {
+	return job.User
+}
+
+// Model 获取任务的数据库模型
+func (job *FetchTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *FetchTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *FetchTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *FetchTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *FetchTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *FetchTask) Do() {
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		job.SetErrorMsg("Failed to create filesystem.", err)
+		return
+	}
+	defer fs.Recycle()
+
+	hasher, expected, err := ParseChecksum(job.TaskProps.Checksum)
+	if err != nil {
+		job.SetErrorMsg("Invalid checksum.", err)
+		return
+	}
+
+	timeout := time.Duration(model.GetIntSetting("fetch_timeout", 3600)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	job.TaskModel.SetProgress(DownloadingProgress)
+	req, err := http.NewRequestWithContext(ctx, "GET", job.TaskProps.URL, nil)
+	if err != nil {
+		job.SetErrorMsg("Invalid URL.", err)
+		return
+	}
+
+	resp, err := fetchClient().Do(req)
+	if err != nil {
+		job.SetErrorMsg("Failed to request URL.", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		job.SetErrorMsg(fmt.Sprintf("Unexpected status code %d.", resp.StatusCode), nil)
+		return
+	}
+
+	// 大小已知时提前检查容量及存储策略的单文件大小限制
+	remaining := fs.User.GetRemainingCapacity()
+	if resp.ContentLength >= 0 {
+		if uint64(resp.ContentLength) > remaining {
+			job.SetErrorMsg("File size exceeds remaining capacity.", filesystem.ErrInsufficientCapacity)
+			return
+		}
+
+		if !fs.ValidateFileSize(ctx, uint64(resp.ContentLength)) {
+			job.SetErrorMsg("File size exceeds the limit of storage policy.", filesystem.ErrFileSizeTooBig)
+			return
+		}
+	}
+
+	name := job.TaskProps.Name
+	if name == "" {
+		name = fetchFileName(resp)
+	}
+
+	tempPath := filepath.Join(
+		util.RelativePath(model.GetSettingByName("temp_path")),
+		"fetch",
+		strconv.FormatUint(uint64(job.TaskModel.ID), 10),
+	)
+	tempFile, err := util.CreatNestedFile(tempPath)
+	if err != nil {
+		job.SetErrorMsg("Failed to create temp file.", err)
+		return
+	}
+	defer os.Remove(tempPath)
+
+	job.TaskProps.Total = resp.ContentLength
+	job.TaskProps.Downloaded = 0
+	progress := &fetchProgress{job: job}
+
+	// 大小未知时最多读取剩余容量 + 1 字节，用于判断是否超出容量
+	written, err := io.Copy(io.MultiWriter(tempFile, hasher, progress), io.LimitReader(resp.Body, int64(remaining)+1))
+	tempFile.Close()
+	progress.flush()
+	if err != nil {
+		job.SetErrorMsg("Failed to download file.", err)
+		return
+	}
+
+	if uint64(written) > remaining {
+		job.SetErrorMsg("File size exceeds remaining capacity.", filesystem.ErrInsufficientCapacity)
+		return
+	}
+
+	if expected != "" && hex.EncodeToString(hasher.Sum(nil)) != expected {
+		job.SetErrorMsg("Checksum mismatch.", nil)
+		return
+	}
+
+	job.TaskModel.SetProgress(TransferringProgress)
+	if err := fs.UploadFromPath(ctx, tempPath, path.Join(job.TaskProps.Dst, name), 0); err != nil {
+		job.SetErrorMsg("Failed to upload file.", err)
+		return
+	}
+}
+
+// fetchProgress 统计已下载的字节数，并定期写入任务属性
+type fetchProgress struct {
+	job  *FetchTask
+	last time.Time
+}
+
+func (p *fetchProgress) Write(b []byte) (int, error) {
+	p.job.TaskProps.Downloaded += int64(len(b))
+	if time.Since(p.last) >= fetchProgressInterval {
+		p.flush()
+	}
+	return len(b), nil
+}
+
+func (p *fetchProgress) flush() {
+	p.last = time.Now()
+	p.job.TaskModel.SetProps(p.job.Props())
+}
+
+// ParseChecksum 解析 算法:十六进制摘要 格式的校验值，为空时不校验
+func ParseChecksum(checksum string) (hash.Hash, string, error) {
+	if checksum == "" {
+		return sha256.New(), "", nil
+	}
+
+	algorithm, digest, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return nil, "", ErrUnknownChecksum
+	}
+
+	var (
+		hasher hash.Hash
+		size   int
+	)
+	switch strings.ToLower(algorithm) {
+	case "md5":
+		hasher, size = md5.New(), md5.Size
+	case "sha1":
+		hasher, size = sha1.New(), sha1.Size
+	case "sha256":
+		hasher, size = sha256.New(), sha256.Size
+	default:
+		return nil, "", ErrUnknownChecksum
+	}
+
+	digest = strings.ToLower(digest)
+	if raw, err := hex.DecodeString(digest); err != nil || len(raw) != size {
+		return nil, "", ErrUnknownChecksum
+	}
+
+	return hasher, digest, nil
+}
+
+// fetchFileName 从响应头或 URL 中推断文件名
+func fetchFileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := filesystem.NormalizeName(path.Base(strings.ReplaceAll(params["filename"], "\\", "/"))); name != "" && name != "/" {
+			return name
+		}
+	}
+
+	if name, err := url.PathUnescape(path.Base(resp.Request.URL.Path)); err == nil {
+		if name = filesystem.NormalizeName(name); name != "" && name != "/" {
+			return name
+		}
+	}
+
+	return "download"
+}
+
+// fetchClient 返回下载使用的 HTTP 客户端，默认拒绝连接内网地址
+func fetchClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if !model.IsTrueVal(model.GetSettingByName("fetch_allow_private")) {
+		dialer.Control = denyPrivateAddress
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= fetchMaxRedirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("unsupported redirect scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// denyPrivateAddress 在建立连接前检查解析后的地址，避免通过 DNS 或重定向访问内网
+func denyPrivateAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return ErrFetchPrivateAddress
+	}
+
+	return nil
+}
+
+// NewFetchTask 新建从 URL 下载任务
+func NewFetchTask(user *model.User, props FetchProps) (Job, error) {
+	newTask := &FetchTask{
+		User:      user,
+		TaskProps: props,
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewFetchTaskFromModel 从数据库记录中恢复从 URL 下载任务
+func NewFetchTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &FetchTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
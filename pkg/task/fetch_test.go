@@ -0,0 +1,270 @@
+package task
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &FetchTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(FetchTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestFetchTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &FetchTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("detail"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("detail", task.GetError().Error)
+}
+
+func TestParseChecksum(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未指定
+	{
+		hasher, expected, err := ParseChecksum("")
+		asserts.NoError(err)
+		asserts.NotNil(hasher)
+		asserts.Empty(expected)
+	}
+
+	// 成功
+	{
+		hasher, expected, err := ParseChecksum("MD5:5D41402ABC4B2A76B9719D911017C592")
+		asserts.NoError(err)
+		asserts.Equal(16, hasher.Size())
+		asserts.Equal("5d41402abc4b2a76b9719d911017c592", expected)
+	}
+
+	// 格式错误
+	for _, checksum := range []string{
+		"5d41402abc4b2a76b9719d911017c592",
+		"crc32:3610a686",
+		"sha1:5d41402abc4b2a76b9719d911017c592",
+		"sha256:xyz",
+	} {
+		_, _, err := ParseChecksum(checksum)
+		asserts.ErrorIs(err, ErrUnknownChecksum, checksum)
+	}
+}
+
+func TestFetchFileName(t *testing.T) {
+	asserts := assert.New(t)
+	newResp := func(rawURL, disposition string) *http.Response {
+		u, _ := url.Parse(rawURL)
+		resp := &http.Response{Header: http.Header{}, Request: &http.Request{URL: u}}
+		if disposition != "" {
+			resp.Header.Set("Content-Disposition", disposition)
+		}
+		return resp
+	}
+
+	asserts.Equal("a.txt", fetchFileName(newResp("http://example.com/dl?id=1", `attachment; filename="a.txt"`)))
+	asserts.Equal("b.txt", fetchFileName(newResp("http://example.com/dl", `attachment; filename="..\b.txt"`)))
+	asserts.Equal("中文.txt", fetchFileName(newResp("http://example.com/%E4%B8%AD%E6%96%87.txt", "")))
+	asserts.Equal("download", fetchFileName(newResp("http://example.com/", "")))
+	asserts.Equal("download", fetchFileName(newResp("http://example.com/..", "")))
+}
+
+func TestDenyPrivateAddress(t *testing.T) {
+	asserts := assert.New(t)
+
+	for _, address := range []string{
+		"127.0.0.1:80",
+		"10.0.0.1:80",
+		"192.168.1.1:443",
+		"169.254.169.254:80",
+		"0.0.0.0:80",
+		"[::1]:80",
+		"[fd00::1]:80",
+	} {
+		asserts.ErrorIs(denyPrivateAddress("tcp", address, nil), ErrFetchPrivateAddress, address)
+	}
+
+	asserts.NoError(denyPrivateAddress("tcp", net.JoinHostPort("1.1.1.1", "443"), nil))
+	asserts.Error(denyPrivateAddress("tcp", "1.1.1.1", nil))
+}
+
+func TestFetchTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_fetch_allow_private", "1", 0)
+	cache.Set("setting_fetch_timeout", "60", 0)
+	defer cache.Set("setting_fetch_allow_private", "0", 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	task := &FetchTask{
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	// 无法创建文件系统
+	{
+		task.User = &model.User{
+			Policy: model.Policy{
+				Type: "unknown",
+			},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEmpty(task.GetError().Msg)
+	}
+
+	task.User = &model.User{
+		Policy: model.Policy{
+			Type: "mock",
+		},
+		Group: model.Group{
+			MaxStorage: 10,
+		},
+	}
+
+	// 校验值格式错误
+	{
+		task.TaskProps = FetchProps{URL: server.URL, Dst: "/", Checksum: "md5"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("Invalid checksum.", task.GetError().Msg)
+	}
+
+	// 响应状态码错误
+	{
+		task.TaskProps = FetchProps{URL: server.URL + "/missing", Dst: "/"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)progress(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)error(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("Unexpected status code 404.", task.GetError().Msg)
+	}
+
+	// 容量不足
+	{
+		task.User.Group.MaxStorage = 1
+		task.TaskProps = FetchProps{URL: server.URL + "/a.txt", Dst: "/"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)progress(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)error(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("File size exceeds remaining capacity.", task.GetError().Msg)
+		task.User.Group.MaxStorage = 10
+	}
+
+	// 校验失败
+	{
+		task.TaskProps = FetchProps{URL: server.URL + "/a.txt", Dst: "/", Checksum: "md5:00000000000000000000000000000000"}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)progress(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		for i := 0; i < 2; i++ {
+			mock.ExpectBegin()
+			mock.ExpectExec("UPDATE(.+)props(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)error(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("Checksum mismatch.", task.GetError().Msg)
+		asserts.EqualValues(5, task.TaskProps.Downloaded)
+		asserts.EqualValues(5, task.TaskProps.Total)
+	}
+}
+
+func TestNewFetchTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewFetchTask(&model.User{}, FetchProps{URL: "http://example.com", Dst: "/"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(job)
+		asserts.NoError(err)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewFetchTask(&model.User{}, FetchProps{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewFetchTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewFetchTaskFromModel(&model.Task{Props: `{"url":"http://example.com","dst":"/"}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("http://example.com", job.(*FetchTask).TaskProps.URL)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewFetchTaskFromModel(&model.Task{Props: "?"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
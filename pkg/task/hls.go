@@ -0,0 +1,133 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// HLSTask 视频 HLS 转码任务
+type HLSTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps HLSProps
+	Err       *JobError
+}
+
+// HLSProps 视频 HLS 转码任务属性
+type HLSProps struct {
+	FileID uint `json:"file_id"`
+}
+
+// Props 获取任务属性
+func (job *HLSTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *HLSTask) Type() int {
+	return HLSTaskType
+}
+
+// Creator 获取创建者ID
+func (job *HLSTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *HLSTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *HLSTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *HLSTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *HLSTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *HLSTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *HLSTask) Do() {
+	files, err := model.GetFilesByIDs([]uint{job.TaskProps.FileID}, job.User.ID)
+	if err != nil || len(files) == 0 {
+		job.SetErrorMsg("File not exist.", err)
+		return
+	}
+
+	file := &files[0]
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		job.SetErrorMsg("Failed to create filesystem.", err)
+		return
+	}
+	defer fs.Recycle()
+
+	job.TaskModel.SetProgress(TranscodingProgress)
+	if err := fs.TranscodeHLS(context.Background(), file); err != nil {
+		if statusErr := filesystem.SetHLSStatus(file, model.HLSStatusFailed); statusErr != nil {
+			util.Log().Warning("Failed to update HLS status of file %d: %s", file.ID, statusErr)
+		}
+		job.SetErrorMsg("Failed to transcode video.", err)
+		return
+	}
+}
+
+// NewHLSTask 新建视频 HLS 转码任务
+func NewHLSTask(user *model.User, fileID uint) (Job, error) {
+	newTask := &HLSTask{
+		User: user,
+		TaskProps: HLSProps{
+			FileID: fileID,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewHLSTaskFromModel 从数据库记录中恢复视频 HLS 转码任务
+func NewHLSTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &HLSTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
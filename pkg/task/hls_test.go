@@ -0,0 +1,108 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHLSTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &HLSTask{
+		User:      &model.User{},
+		TaskProps: HLSProps{FileID: 1},
+	}
+	asserts.Equal(`{"file_id":1}`, task.Props())
+	asserts.Equal(HLSTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestHLSTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &HLSTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("error"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("error", task.GetError().Error)
+}
+
+func TestHLSTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 文件不存在
+	task := &HLSTask{
+		User:      &model.User{Model: gorm.Model{ID: 1}},
+		TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		TaskProps: HLSProps{FileID: 2},
+	}
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2, 1).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	task.Do()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("File not exist.", task.GetError().Msg)
+}
+
+func TestNewHLSTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewHLSTask(&model.User{}, 1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, job.(*HLSTask).TaskProps.FileID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewHLSTask(&model.User{}, 1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewHLSTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewHLSTaskFromModel(&model.Task{Props: `{"file_id":5}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(5, job.(*HLSTask).TaskProps.FileID)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewHLSTaskFromModel(&model.Task{Props: "x"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
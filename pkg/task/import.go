@@ -103,6 +103,7 @@ func (job *ImportTask) Do() {
 	// 注册钩子
 	fs.Use("BeforeAddFile", filesystem.HookValidateFile)
 	fs.Use("BeforeAddFile", filesystem.HookValidateCapacity)
+	fs.Use("BeforeAddFile", filesystem.HookValidateGroupLimits)
 
 	// 列取目录、对象
 	job.TaskModel.SetProgress(ListingProgress)
@@ -181,11 +181,19 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectCommit()
+		// 记录目录变更
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		// 插入文件记录
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectExec("UPDATE(.+)users(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectCommit()
+		// 记录文件变更
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)changes(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
 
 		task.Do()
 
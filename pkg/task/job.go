@@ -1,6 +1,8 @@
 package task
 
 import (
+	"encoding/json"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
@@ -17,6 +19,24 @@ const (
 	ImportTaskType
 	// RecycleTaskType 回收任务
 	RecycleTaskType
+	// TorrentTaskType 种子制作任务
+	TorrentTaskType
+	// QuotaTaskType 容量重新计算任务
+	QuotaTaskType
+	// ExportTaskType 用户数据导出任务
+	ExportTaskType
+	// AccountPurgeTaskType 账户注销清理任务
+	AccountPurgeTaskType
+	// ThumbTaskType 缩略图重新生成任务
+	ThumbTaskType
+	// HLSTaskType 视频 HLS 转码任务
+	HLSTaskType
+	// MigrateTaskType 存储策略迁移任务
+	MigrateTaskType
+	// FetchTaskType 从 URL 下载任务
+	FetchTaskType
+	// BlobGCTaskType 孤立物理文件回收任务
+	BlobGCTaskType
 )
 
 // 任务状态
@@ -49,6 +69,14 @@ const (
 	ListingProgress
 	// InsertingProgress 插入中
 	InsertingProgress
+	// HashingProgress 计算分片哈希中
+	HashingProgress
+	// GeneratingProgress 生成中
+	GeneratingProgress
+	// TranscodingProgress 转码中
+	TranscodingProgress
+	// MigratingProgress 迁移中
+	MigratingProgress
 )
 
 // Job 任务接口
@@ -63,6 +91,17 @@ type Job interface {
 	GetError() *JobError // 获取任务执行结果，返回nil表示成功完成执行
 }
 
+// pendingJob 可能需要等待外部操作才能执行的任务
+type pendingJob interface {
+	Pending() bool
+}
+
+// notifiableJob 结束后需要通知用户的任务
+type notifiableJob interface {
+	// NotifyUser 返回需通知的用户，为空时不通知
+	NotifyUser() *model.User
+}
+
 // JobError 任务失败信息
 type JobError struct {
 	Msg   string `json:"msg,omitempty"`
@@ -91,19 +130,54 @@ func Resume(p Pool) {
 	}
 	util.Log().Info("Resume %d unfinished task(s) from database.", len(tasks))
 
+	distributed, _ := p.(*DistributedPool)
 	for i := 0; i < len(tasks); i++ {
+		// 分发给 Worker 的任务保存在 Stream 中，由 Worker 认领恢复
+		if distributed != nil && distributed.Remote(tasks[i].Type) {
+			continue
+		}
+
+		// 上次运行时被中断的任务
+		if tasks[i].Status == Processing && !recoverInterrupted(&tasks[i]) {
+			continue
+		}
+
 		job, err := GetJobFromModel(&tasks[i])
 		if err != nil {
 			util.Log().Warning("Failed to resume task: %s", err)
 			continue
 		}
 
+		// 等待审核等外部操作的任务不提交执行
+		if pending, ok := job.(pendingJob); ok && pending.Pending() {
+			continue
+		}
+
 		if job != nil {
-			p.Submit(job)
+			SubmitDeferred(p, job)
 		}
 	}
 }
 
+// recoverInterrupted 将中断的任务重新放入队列，任务根据属性中的检查点继续执行。
+// 中断次数超过上限时将任务标记为失败，返回是否需要重新执行
+func recoverInterrupted(task *model.Task) bool {
+	if task.Retries >= model.GetIntSetting("task_max_retry", 3) {
+		util.Log().Warning("Task %d has been interrupted %d time(s), marked as failed.", task.ID, task.Retries+1)
+		res, _ := json.Marshal(&JobError{Msg: "Task interrupted by server restart too many times."})
+		task.SetError(string(res))
+		task.SetStatus(Error)
+		return false
+	}
+
+	if err := task.IncreaseRetries(); err != nil {
+		util.Log().Warning("Failed to update retries of task %d: %s", task.ID, err)
+	}
+	task.SetStatus(Queued)
+	task.Status = Queued
+	return true
+}
+
 // GetJobFromModel 从数据库给定模型获取任务
 func GetJobFromModel(task *model.Task) (Job, error) {
 	switch task.Type {
@@ -117,6 +191,24 @@ func GetJobFromModel(task *model.Task) (Job, error) {
 		return NewImportTaskFromModel(task)
 	case RecycleTaskType:
 		return NewRecycleTaskFromModel(task)
+	case TorrentTaskType:
+		return NewTorrentTaskFromModel(task)
+	case QuotaTaskType:
+		return NewQuotaTaskFromModel(task)
+	case ExportTaskType:
+		return NewExportTaskFromModel(task)
+	case AccountPurgeTaskType:
+		return NewAccountPurgeTaskFromModel(task)
+	case ThumbTaskType:
+		return NewThumbTaskFromModel(task)
+	case HLSTaskType:
+		return NewHLSTaskFromModel(task)
+	case MigrateTaskType:
+		return NewMigrateTaskFromModel(task)
+	case FetchTaskType:
+		return NewFetchTaskFromModel(task)
+	case BlobGCTaskType:
+		return NewBlobGCTaskFromModel(task)
 	default:
 		return nil, ErrUnknownTaskType
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/stretchr/testify/assert"
 	testMock "github.com/stretchr/testify/mock"
 )
@@ -26,17 +27,17 @@ type taskPoolMock struct {
 	testMock.Mock
 }
 
-func (t taskPoolMock) Add(num int) {
+func (t *taskPoolMock) Add(num int) {
 	t.Called(num)
 }
 
-func (t taskPoolMock) Submit(job Job) {
+func (t *taskPoolMock) Submit(job Job) {
 	t.Called(job)
 }
 
 func TestResume(t *testing.T) {
 	asserts := assert.New(t)
-	mockPool := taskPoolMock{}
+	mockPool := &taskPoolMock{}
 
 	// 没有任务
 	{
@@ -62,6 +63,38 @@ func TestResume(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 		mockPool.AssertExpectations(t)
 	}
+
+	// 中断的任务，重新放入队列
+	{
+		cache.Set("setting_task_max_retry", "3", 0)
+		mock.ExpectQuery("SELECT(.+)").WithArgs(Queued, Processing).WillReturnRows(sqlmock.NewRows([]string{"id", "status", "type", "props", "retries"}).AddRow(1, Processing, CompressTaskType, "{}", 2))
+		// 更新重试次数
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)retries(.+)").WithArgs(3, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// 更新状态
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)status(.+)").WithArgs(Queued, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		Resume(mockPool)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 中断次数超过上限，标记为失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(Queued, Processing).WillReturnRows(sqlmock.NewRows([]string{"id", "status", "type", "props", "retries"}).AddRow(1, Processing, CompressTaskType, "{}", 3))
+		// 更新错误
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)error(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// 更新状态
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)status(.+)").WithArgs(Error, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Resume(mockPool)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
 }
 
 func TestGetJobFromModel(t *testing.T) {
@@ -115,4 +148,52 @@ func TestGetJobFromModel(t *testing.T) {
 		asserts.Nil(job)
 		asserts.Error(err)
 	}
+	// TorrentTaskType
+	{
+		task := &model.Task{
+			Status: 0,
+			Type:   TorrentTaskType,
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		job, err := GetJobFromModel(task)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+	// QuotaTaskType
+	{
+		task := &model.Task{
+			Status: 0,
+			Type:   QuotaTaskType,
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		job, err := GetJobFromModel(task)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+	// ExportTaskType
+	{
+		task := &model.Task{
+			Status: 0,
+			Type:   ExportTaskType,
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		job, err := GetJobFromModel(task)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+	// AccountPurgeTaskType
+	{
+		task := &model.Task{
+			Status: 0,
+			Type:   AccountPurgeTaskType,
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		job, err := GetJobFromModel(task)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
 }
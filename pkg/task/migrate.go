@@ -0,0 +1,182 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// migrateScanBatchSize 迁移文件时每批读取的文件数
+	migrateScanBatchSize = 100
+	// migrateMaxFailedFiles 最多记录的迁移失败文件数
+	migrateMaxFailedFiles = 100
+)
+
+// MigrateTask 存储策略迁移任务
+type MigrateTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps MigrateProps
+	Err       *JobError
+}
+
+// MigrateProps 存储策略迁移任务属性
+type MigrateProps struct {
+	SrcPolicyID uint `json:"src_policy_id"`
+	DstPolicyID uint `json:"dst_policy_id"`
+	// 筛选条件，为 0 时迁移所有用户的文件
+	UserID uint `json:"uid,omitempty"`
+
+	// 执行进度，Cursor 为最后处理的文件 ID，任务恢复后从此处继续
+	Cursor   uint `json:"cursor"`
+	Scanned  int  `json:"scanned"`
+	Migrated int  `json:"migrated"`
+	Failed   int  `json:"failed"`
+	// 迁移失败的文件 ID，最多记录 migrateMaxFailedFiles 个
+	FailedFiles []uint `json:"failed_files,omitempty"`
+}
+
+// Props 获取任务属性
+func (job *MigrateTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *MigrateTask) Type() int {
+	return MigrateTaskType
+}
+
+// Creator 获取创建者ID
+func (job *MigrateTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *MigrateTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *MigrateTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *MigrateTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *MigrateTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *MigrateTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *MigrateTask) Do() {
+	job.TaskModel.SetProgress(MigratingProgress)
+
+	dst, err := model.GetPolicyByID(job.TaskProps.DstPolicyID)
+	if err != nil {
+		job.SetErrorMsg("Destination policy not exist.", err)
+		return
+	}
+
+	// 文件的读写只经由存储策略适配器，无需区分所属用户
+	fs, err := filesystem.NewAnonymousFileSystem()
+	if err != nil {
+		job.SetErrorMsg("Failed to initialize filesystem.", err)
+		return
+	}
+	defer fs.Recycle()
+
+	for {
+		files, err := model.ListFilesAfter(job.TaskProps.Cursor, migrateScanBatchSize,
+			job.TaskProps.SrcPolicyID, job.TaskProps.UserID, nil)
+		if err != nil {
+			job.SetErrorMsg("Failed to list files.", err)
+			return
+		}
+
+		for i := range files {
+			job.TaskProps.Scanned++
+			if _, err := fs.MigrateFile(context.Background(), &files[i], &dst); err != nil {
+				util.Log().Warning("Migrate task %d failed to migrate file %d: %s", job.TaskModel.ID, files[i].ID, err)
+				job.TaskProps.Failed++
+				if len(job.TaskProps.FailedFiles) < migrateMaxFailedFiles {
+					job.TaskProps.FailedFiles = append(job.TaskProps.FailedFiles, files[i].ID)
+				}
+			} else {
+				job.TaskProps.Migrated++
+			}
+
+			job.TaskProps.Cursor = files[i].ID
+		}
+
+		// 每批处理完成后记录进度
+		job.TaskModel.SetProps(job.Props())
+		util.Log().Info("Migrate task %d: %d scanned, %d migrated, %d failed.", job.TaskModel.ID,
+			job.TaskProps.Scanned, job.TaskProps.Migrated, job.TaskProps.Failed)
+		if len(files) < migrateScanBatchSize {
+			break
+		}
+	}
+
+	if job.TaskProps.Failed > 0 {
+		job.SetErrorMsg("Some files failed to migrate.", nil)
+	}
+}
+
+// NewMigrateTask 新建存储策略迁移任务
+func NewMigrateTask(user *model.User, srcPolicyID, dstPolicyID, uid uint) (Job, error) {
+	newTask := &MigrateTask{
+		User: user,
+		TaskProps: MigrateProps{
+			SrcPolicyID: srcPolicyID,
+			DstPolicyID: dstPolicyID,
+			UserID:      uid,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewMigrateTaskFromModel 从数据库记录中恢复存储策略迁移任务
+func NewMigrateTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &MigrateTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
@@ -0,0 +1,144 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &MigrateTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(MigrateTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestMigrateTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &MigrateTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("error"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("error", task.GetError().Error)
+}
+
+func TestMigrateTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 目标存储策略不存在
+	{
+		task := &MigrateTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: MigrateProps{SrcPolicyID: 1, DstPolicyID: 404},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 原文件无法读取，计入失败并记录进度
+	{
+		cache.Set("policy_2", model.Policy{Type: "mock"}, 0)
+		cache.Set("policy_3", model.Policy{Type: "unknown"}, 0)
+		task := &MigrateTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: MigrateProps{SrcPolicyID: 3, DstPolicyID: 2, Cursor: 4},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(4, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policy_id"}).AddRow(5, 3).AddRow(6, 3))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+		asserts.EqualValues(6, task.TaskProps.Cursor)
+		asserts.Equal(2, task.TaskProps.Scanned)
+		asserts.Equal(2, task.TaskProps.Failed)
+		asserts.Equal([]uint{5, 6}, task.TaskProps.FailedFiles)
+	}
+}
+
+func TestNewMigrateTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewMigrateTask(&model.User{}, 1, 2, 3)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, job.(*MigrateTask).TaskProps.DstPolicyID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewMigrateTask(&model.User{}, 1, 2, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewMigrateTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewMigrateTaskFromModel(&model.Task{Props: `{"src_policy_id":1,"dst_policy_id":2,"cursor":5}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(5, job.(*MigrateTask).TaskProps.Cursor)
+		asserts.EqualValues(2, job.(*MigrateTask).TaskProps.DstPolicyID)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewMigrateTaskFromModel(&model.Task{Props: "x"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
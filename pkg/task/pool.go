@@ -1,8 +1,13 @@
 package task
 
 import (
+	"context"
+	"sync"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/graceful"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -14,10 +19,23 @@ type Pool interface {
 	Submit(job Job)
 }
 
+// drainer 可在停止服务前等待执行中任务的任务池
+type drainer interface {
+	Drain(ctx context.Context) error
+}
+
 // AsyncPool 带有最大配额的任务池
 type AsyncPool struct {
 	// 容量
 	idleWorker chan int
+
+	mu sync.Mutex
+	// running 执行中的任务
+	running map[uint64]Job
+	nextID  uint64
+	// draining 停止中，不再开始执行新任务
+	draining bool
+	wg       sync.WaitGroup
 }
 
 // Add 增加可用Worker数量
@@ -47,12 +65,81 @@ func (pool *AsyncPool) Submit(job Job) {
 		util.Log().Debug("Waiting for Worker.")
 		worker := pool.obtainWorker()
 		util.Log().Debug("Worker obtained.")
-		worker.Do(job)
+		if id, ok := pool.start(job); ok {
+			worker.Do(job)
+			pool.finish(id)
+		}
 		util.Log().Debug("Worker released.")
 		pool.freeWorker()
 	}()
 }
 
+// start 登记开始执行的任务，停止中时返回 false，任务保持排队状态，下次启动时恢复
+func (pool *AsyncPool) start(job Job) (uint64, bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if pool.draining {
+		util.Log().Debug("Task pool is draining, task left in queue.")
+		return 0, false
+	}
+
+	if pool.running == nil {
+		pool.running = make(map[uint64]Job)
+	}
+	pool.nextID++
+	pool.running[pool.nextID] = job
+	pool.wg.Add(1)
+	return pool.nextID, true
+}
+
+func (pool *AsyncPool) finish(id uint64) {
+	pool.mu.Lock()
+	delete(pool.running, id)
+	pool.mu.Unlock()
+	pool.wg.Done()
+}
+
+// Drain 停止执行新任务，并等待执行中的任务完成。ctx 结束时仍未完成的任务
+// 被放回队列，下次启动时根据属性中的检查点继续执行，不计入中断次数
+func (pool *AsyncPool) Drain(ctx context.Context) error {
+	pool.mu.Lock()
+	pool.draining = true
+	pool.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		pool.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, job := range pool.running {
+		if record := job.Model(); record != nil {
+			util.Log().Info("Task %d is still running, checkpointed for next start.", record.ID)
+			record.SetStatus(Queued)
+		}
+	}
+
+	return ctx.Err()
+}
+
+// Shutdown 停止全局任务池，等待执行中的任务完成或 ctx 结束
+func Shutdown(ctx context.Context) error {
+	if pool, ok := TaskPoll.(drainer); ok {
+		return pool.Drain(ctx)
+	}
+
+	return nil
+}
+
 // Init 初始化任务池
 func Init() {
 	maxWorker := model.GetIntSetting("max_worker_num", 10)
@@ -61,8 +148,36 @@ func Init() {
 	}
 	TaskPoll.Add(maxWorker)
 	util.Log().Info("Initialize task queue with WorkerNum = %d", maxWorker)
+	model.RegisterTaskHook(publishProgress)
 
 	if conf.SystemConfig.Mode == "master" {
-		Resume(TaskPoll)
+		initDistributed()
+		if !graceful.Inherited() {
+			Resume(TaskPoll)
+			return
+		}
+
+		// 平滑重启时，待旧进程完成或保存执行中的任务后再恢复
+		go func() {
+			graceful.WaitParent()
+			Resume(TaskPoll)
+		}()
+	}
+}
+
+// initDistributed 设定了交给独立 Worker 执行的任务类型时，通过 Redis 分发这些任务
+func initDistributed() {
+	types := ParseTaskTypes(model.GetSettingByName("task_worker_types"))
+	if len(types) == 0 {
+		return
+	}
+
+	store, ok := cache.Store.(*cache.RedisStore)
+	if !ok {
+		util.Log().Warning("Distributed task workers require Redis, all tasks will be executed locally.")
+		return
 	}
+
+	TaskPoll = NewDistributedPool(TaskPoll, store.Pool(), types)
+	util.Log().Info("Task type(s) %v will be dispatched to task workers.", types)
 }
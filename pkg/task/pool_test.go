@@ -1,8 +1,10 @@
 package task
 
 import (
+	"context"
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -50,3 +52,72 @@ func TestPool_Submit(t *testing.T) {
 		pool.Submit(job)
 	})
 }
+
+type checkpointJobMock struct {
+	MockJob
+	record *model.Task
+}
+
+func (job *checkpointJobMock) Model() *model.Task {
+	return job.record
+}
+
+func TestPool_Drain(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 任务在等待期间完成
+	{
+		pool := &AsyncPool{idleWorker: make(chan int, 1)}
+		pool.Add(1)
+		finished := make(chan struct{})
+		pool.Submit(&MockJob{DoFunc: func() {
+			time.Sleep(10 * time.Millisecond)
+			close(finished)
+		}})
+		time.Sleep(time.Millisecond)
+		asserts.NoError(pool.Drain(context.Background()))
+		<-finished
+
+		// 停止后提交的任务不再执行
+		executed := make(chan struct{}, 1)
+		pool.Submit(&MockJob{DoFunc: func() { executed <- struct{}{} }})
+		time.Sleep(10 * time.Millisecond)
+		asserts.Len(executed, 0)
+	}
+
+	// 等待超时，放回队列
+	{
+		pool := &AsyncPool{idleWorker: make(chan int, 1)}
+		pool.Add(1)
+		release := make(chan struct{})
+		started := make(chan struct{})
+		job := &checkpointJobMock{
+			MockJob: MockJob{DoFunc: func() {
+				close(started)
+				<-release
+			}},
+			record: &model.Task{},
+		}
+		job.record.ID = 1
+		pool.Submit(job)
+		<-started
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)status(.+)").WithArgs(Queued, sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		asserts.ErrorIs(pool.Drain(ctx), context.DeadlineExceeded)
+		asserts.NoError(mock.ExpectationsWereMet())
+		close(release)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	asserts := assert.New(t)
+	TaskPoll = &taskPoolMock{}
+	asserts.NoError(Shutdown(context.Background()))
+
+	TaskPoll = &AsyncPool{idleWorker: make(chan int, 1)}
+	asserts.NoError(Shutdown(context.Background()))
+}
@@ -0,0 +1,65 @@
+package task
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// progressInterval 同一任务仅进度或属性变化时推送的最短间隔
+const progressInterval = time.Second
+
+// lastPublished 各任务最近一次推送进度的时间
+var lastPublished sync.Map
+
+// Progress 推送给客户端的任务状态
+type Progress struct {
+	serializer.Task
+	// Detail 任务类型相关的进度详情
+	Detail interface{} `json:"detail,omitempty"`
+}
+
+// FetchDetail 从 URL 下载任务的进度详情
+type FetchDetail struct {
+	Total      int64 `json:"total"`
+	Downloaded int64 `json:"downloaded"`
+}
+
+// BuildProgress 构建推送给客户端的任务状态
+func BuildProgress(task *model.Task) Progress {
+	progress := Progress{Task: serializer.BuildTask(task)}
+	if task.Type == FetchTaskType {
+		var props FetchProps
+		if err := json.Unmarshal([]byte(task.Props), &props); err == nil {
+			progress.Detail = FetchDetail{Total: props.Total, Downloaded: props.Downloaded}
+		}
+	}
+
+	return progress
+}
+
+// publishProgress 任务更新后向创建者已连接的客户端推送最新状态，
+// 仅进度或属性变化时按 progressInterval 限制推送频率
+func publishProgress(task *model.Task, column string) {
+	if task.UserID == 0 {
+		return
+	}
+
+	if column == "progress" || column == "props" {
+		if last, ok := lastPublished.Load(task.ID); ok && time.Since(last.(time.Time)) < progressInterval {
+			return
+		}
+	}
+
+	if task.Status == Complete || task.Status == Error || task.Status == Canceled {
+		lastPublished.Delete(task.ID)
+	} else {
+		lastPublished.Store(task.ID, time.Now())
+	}
+
+	notification.Publish(task.UserID, notification.EventTask, BuildProgress(task))
+}
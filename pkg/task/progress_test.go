@@ -0,0 +1,68 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildProgress(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 从 URL 下载任务附带已下载字节数
+	{
+		res := BuildProgress(&model.Task{Type: FetchTaskType, Props: `{"total":10,"downloaded":5}`})
+		asserts.Equal(FetchDetail{Total: 10, Downloaded: 5}, res.Detail)
+	}
+
+	// 其他任务
+	{
+		res := BuildProgress(&model.Task{Model: gorm.Model{ID: 1}, Type: TransferTaskType, Progress: 2})
+		asserts.EqualValues(1, res.ID)
+		asserts.Equal(2, res.Progress)
+		asserts.Nil(res.Detail)
+	}
+}
+
+func TestPublishProgress(t *testing.T) {
+	asserts := assert.New(t)
+	task := &model.Task{Model: gorm.Model{ID: 100}, UserID: 100, Status: Processing}
+	events := mq.GlobalMQ.Subscribe(notification.Topic(100), 10)
+	defer mq.GlobalMQ.Unsubscribe(notification.Topic(100), events)
+	receive := func() *Progress {
+		select {
+		case msg := <-events:
+			asserts.Equal(notification.EventTask, msg.Event)
+			progress := msg.Content.(Progress)
+			return &progress
+		case <-time.After(100 * time.Millisecond):
+			return nil
+		}
+	}
+
+	// 系统任务不推送
+	publishProgress(&model.Task{Model: gorm.Model{ID: 101}}, "status")
+	asserts.Nil(receive())
+
+	// 首次推送
+	publishProgress(task, "progress")
+	asserts.NotNil(receive())
+
+	// 间隔内的进度变化不推送
+	publishProgress(task, "progress")
+	asserts.Nil(receive())
+
+	// 状态变化始终推送
+	task.Status = Complete
+	publishProgress(task, "status")
+	res := receive()
+	asserts.NotNil(res)
+	asserts.Equal(Complete, res.Status)
+	_, ok := lastPublished.Load(task.ID)
+	asserts.False(ok)
+}
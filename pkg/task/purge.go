@@ -0,0 +1,246 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+)
+
+// 账户注销清理阶段
+const (
+	PurgeStageShares  = "shares"
+	PurgeStageWebDAV  = "webdav"
+	PurgeStageFiles   = "files"
+	PurgeStageRecords = "records"
+	PurgeStageUser    = "user"
+)
+
+// AccountPurgeTask 账户注销后的数据清理任务
+type AccountPurgeTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps AccountPurgeProps
+	Err       *JobError
+}
+
+// AccountPurgeProps 账户注销清理任务属性
+type AccountPurgeProps struct {
+	// 注销冷静期结束时间，在此之前不会清理数据
+	NotBefore time.Time `json:"not_before"`
+
+	// 已完成的清理阶段
+	Stages []PurgeStage `json:"stages,omitempty"`
+}
+
+// PurgeStage 已完成的清理阶段及清理的记录数
+type PurgeStage struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// Props 获取任务属性
+func (job *AccountPurgeTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *AccountPurgeTask) Type() int {
+	return AccountPurgeTaskType
+}
+
+// Creator 获取创建者ID
+func (job *AccountPurgeTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *AccountPurgeTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *AccountPurgeTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *AccountPurgeTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *AccountPurgeTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *AccountPurgeTask) GetError() *JobError {
+	return job.Err
+}
+
+// NotBefore 冷静期结束后才开始清理
+func (job *AccountPurgeTask) NotBefore() time.Time {
+	return job.TaskProps.NotBefore
+}
+
+// Do 开始执行任务
+func (job *AccountPurgeTask) Do() {
+	// 冷静期内管理员可能已恢复该账户
+	user, err := model.GetUserByID(job.User.ID)
+	if err != nil {
+		job.SetErrorMsg("User not found.", err)
+		return
+	}
+
+	if user.Status != model.PendingDeletion {
+		job.SetErrorMsg("Account deletion is canceled.", nil)
+		return
+	}
+
+	job.User = &user
+	job.TaskProps.Stages = nil
+	model.RecordAudit(user.ID, "account.purge.start", model.AuditTargetUser, user.ID, nil)
+
+	// 删除分享
+	n, err := model.DeleteSharesByUserID(user.ID)
+	if err != nil {
+		job.SetErrorMsg("Failed to delete shares.", err)
+		return
+	}
+	job.stage(PurgeStageShares, n)
+
+	// 删除 WebDAV 账号
+	n, err = model.DeleteWebDAVAccountsByUserID(user.ID)
+	if err != nil {
+		job.SetErrorMsg("Failed to delete WebDAV accounts.", err)
+		return
+	}
+	job.stage(PurgeStageWebDAV, n)
+
+	// 按存储策略删除物理文件，失败时保留账户以便重试
+	n, err = job.deleteFiles()
+	if err != nil {
+		job.SetErrorMsg("Failed to delete files.", err)
+		return
+	}
+	job.stage(PurgeStageFiles, n)
+
+	// 删除标签、播放列表、离线下载、任务、变更记录、用户动态、站内通知与登录会话
+	var total int64
+	for _, purge := range []func(uint) (int64, error){
+		model.DeleteTagsByUserID,
+		model.DeletePlaylistsByUserID,
+		model.DeleteDownloadsByUserID,
+		model.DeleteChangesByUserID,
+		model.DeleteActivitiesByUserID,
+		model.DeleteNotificationsByUserID,
+		model.DeleteLoginSessionsByUserID,
+		func(uid uint) (int64, error) { return model.DeleteTasksByUserID(uid, job.TaskModel.ID) },
+	} {
+		n, err = purge(user.ID)
+		if err != nil {
+			job.SetErrorMsg("Failed to delete records.", err)
+			return
+		}
+		total += n
+	}
+	job.stage(PurgeStageRecords, total)
+
+	// 最后删除用户
+	if err := user.Delete(); err != nil {
+		job.SetErrorMsg("Failed to delete user.", err)
+		return
+	}
+	job.stage(PurgeStageUser, 1)
+}
+
+// deleteFiles 删除用户所有文件，返回删除前的文件数
+func (job *AccountPurgeTask) deleteFiles() (int64, error) {
+	files, err := model.GetFilesByUserID(job.User.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	root, err := job.User.Root()
+	if err != nil {
+		// 根目录不存在时没有需要删除的文件
+		return 0, nil
+	}
+
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		return 0, err
+	}
+	defer fs.Recycle()
+
+	// 回收站中的对象不在目录树中，需单独清除
+	trash, err := model.GetTrashByUserID(job.User.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := fs.PurgeTrash(context.Background(), trash); err != nil {
+		return 0, err
+	}
+
+	if err := fs.Delete(context.Background(), []uint{root.ID}, []uint{}, false, false); err != nil {
+		return 0, err
+	}
+
+	return int64(len(files)), nil
+}
+
+// stage 记录已完成的清理阶段，并写入审计日志
+func (job *AccountPurgeTask) stage(name string, count int64) {
+	job.TaskProps.Stages = append(job.TaskProps.Stages, PurgeStage{Name: name, Count: count})
+	job.TaskModel.SetProps(job.Props())
+	model.RecordAudit(job.User.ID, "account.purge."+name, model.AuditTargetUser, job.User.ID,
+		map[string]int64{"count": count})
+}
+
+// NewAccountPurgeTask 新建账户注销清理任务，在 notBefore 之后执行
+func NewAccountPurgeTask(user *model.User, notBefore time.Time) (Job, error) {
+	newTask := &AccountPurgeTask{
+		User: user,
+		TaskProps: AccountPurgeProps{
+			NotBefore: notBefore,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewAccountPurgeTaskFromModel 从数据库记录中恢复账户注销清理任务
+func NewAccountPurgeTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &AccountPurgeTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
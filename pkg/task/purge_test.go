@@ -0,0 +1,121 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccountPurgeTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	notBefore := time.Now().Add(time.Hour)
+	task := &AccountPurgeTask{
+		User:      &model.User{},
+		TaskProps: AccountPurgeProps{NotBefore: notBefore},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(AccountPurgeTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+	asserts.Equal(notBefore, task.NotBefore())
+}
+
+func expectPurgeStage(table string) {
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)" + table + "(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func expectPurgeRecord() {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+}
+
+func TestAccountPurgeTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 注销已被取消
+	{
+		task := &AccountPurgeTask{
+			User:      &model.User{Model: gorm.Model{ID: 2}},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(2, model.Active))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 删除分享失败
+	{
+		task := &AccountPurgeTask{
+			User:      &model.User{Model: gorm.Model{ID: 2}},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(2, model.PendingDeletion))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)shares(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+		asserts.Len(task.TaskProps.Stages, 0)
+	}
+
+	// 成功，用户没有根目录
+	{
+		task := &AccountPurgeTask{
+			User:      &model.User{Model: gorm.Model{ID: 2}},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(2, model.PendingDeletion))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		expectPurgeStage("shares")
+		expectPurgeRecord()
+		expectPurgeStage("webdavs")
+		expectPurgeRecord()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(gorm.ErrRecordNotFound)
+		expectPurgeRecord()
+		expectPurgeStage("tags")
+		expectPurgeStage("playlists")
+		expectPurgeStage("downloads")
+		expectPurgeStage("changes")
+		expectPurgeStage("activities")
+		expectPurgeStage("notifications")
+		expectPurgeStage("login_sessions")
+		expectPurgeStage("tasks")
+		expectPurgeRecord()
+		expectPurgeStage("users")
+		expectPurgeRecord()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+		asserts.Len(task.TaskProps.Stages, 5)
+		asserts.Equal(PurgeStageUser, task.TaskProps.Stages[4].Name)
+		asserts.EqualValues(8, task.TaskProps.Stages[3].Count)
+	}
+}
@@ -0,0 +1,314 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// quotaScanBatchSize 遍历所有用户时每批读取的用户数
+const quotaScanBatchSize = 100
+
+// QuotaTask 容量重新计算任务
+type QuotaTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps QuotaProps
+	Err       *JobError
+}
+
+// QuotaProps 容量重新计算任务属性
+type QuotaProps struct {
+	// 要计算的用户 ID，为空时计算所有用户
+	UserIDs []uint `json:"uids,omitempty"`
+	// 是否列取存储端文件，校验文件是否存在
+	Verify bool `json:"verify"`
+	// 是否将计算结果写回用户已用容量
+	Apply bool `json:"apply"`
+	// 是否同时重新统计用户所有目录的总大小
+	Folders bool `json:"folders,omitempty"`
+
+	// 执行结果
+	Scanned int          `json:"scanned"`
+	Drifts  []QuotaDrift `json:"drifts,omitempty"`
+}
+
+// QuotaDrift 已用容量记录与实际值不一致的用户
+type QuotaDrift struct {
+	UserID   uint   `json:"uid"`
+	Recorded uint64 `json:"recorded"`
+	Actual   uint64 `json:"actual"`
+	// 存储端缺失的文件数及总大小，仅在开启校验时统计
+	Missing     int    `json:"missing,omitempty"`
+	MissingSize uint64 `json:"missing_size,omitempty"`
+	Corrected   bool   `json:"corrected,omitempty"`
+	// 总大小记录不一致的目录数及已修正的目录数，仅在统计目录时记录
+	FolderDrifts     int `json:"folder_drifts,omitempty"`
+	FoldersCorrected int `json:"folders_corrected,omitempty"`
+}
+
+// Props 获取任务属性
+func (job *QuotaTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *QuotaTask) Type() int {
+	return QuotaTaskType
+}
+
+// Creator 获取创建者ID
+func (job *QuotaTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *QuotaTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *QuotaTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *QuotaTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *QuotaTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *QuotaTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *QuotaTask) Do() {
+	job.TaskModel.SetProgress(ListingProgress)
+	job.TaskProps.Scanned = 0
+	job.TaskProps.Drifts = nil
+
+	if len(job.TaskProps.UserIDs) > 0 {
+		for _, uid := range job.TaskProps.UserIDs {
+			user, err := model.GetUserByID(uid)
+			if err != nil {
+				util.Log().Warning("Quota task %d cannot find user %d: %s", job.TaskModel.ID, uid, err)
+				continue
+			}
+
+			if err := job.check(&user); err != nil {
+				job.SetErrorMsg("Failed to calculate user storage.", err)
+				return
+			}
+		}
+	} else {
+		var cursor uint
+		for {
+			users, err := model.ListUsersAfter(cursor, quotaScanBatchSize)
+			if err != nil {
+				job.SetErrorMsg("Failed to list users.", err)
+				return
+			}
+
+			for i := range users {
+				if err := job.check(&users[i]); err != nil {
+					job.SetErrorMsg("Failed to calculate user storage.", err)
+					return
+				}
+			}
+
+			if len(users) < quotaScanBatchSize {
+				break
+			}
+			cursor = users[len(users)-1].ID
+		}
+	}
+
+	job.TaskModel.SetProps(job.Props())
+}
+
+// check 重新计算单个用户的已用容量，与记录不一致时记入报告
+func (job *QuotaTask) check(user *model.User) error {
+	job.TaskProps.Scanned++
+	actual, err := model.SumFileSizeByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	drift := QuotaDrift{
+		UserID:   user.ID,
+		Recorded: user.Storage,
+		Actual:   actual,
+	}
+
+	if job.TaskProps.Verify {
+		drift.Missing, drift.MissingSize = job.verify(user)
+	}
+
+	if job.TaskProps.Folders {
+		if err := job.checkFolders(user, &drift); err != nil {
+			return err
+		}
+	}
+
+	if drift.Recorded == drift.Actual && drift.Missing == 0 && drift.FolderDrifts == 0 {
+		return nil
+	}
+
+	if job.TaskProps.Apply && drift.Recorded != drift.Actual {
+		if err := user.Update(map[string]interface{}{"storage": actual}); err != nil {
+			util.Log().Warning("Failed to correct storage of user %d: %s", user.ID, err)
+		} else {
+			drift.Corrected = true
+		}
+	}
+
+	job.TaskProps.Drifts = append(job.TaskProps.Drifts, drift)
+	return nil
+}
+
+// checkFolders 重新统计用户所有目录的总大小，开启修正时写回不一致的记录
+func (job *QuotaTask) checkFolders(user *model.User, drift *QuotaDrift) error {
+	folders, err := model.GetFolderSizeDrifts(user.ID)
+	if err != nil {
+		return err
+	}
+
+	drift.FolderDrifts = len(folders)
+	if !job.TaskProps.Apply {
+		return nil
+	}
+
+	for _, folder := range folders {
+		if err := model.FixFolderSize(folder); err != nil {
+			util.Log().Warning("Failed to correct size of folder %d: %s", folder.ID, err)
+			continue
+		}
+		drift.FoldersCorrected++
+	}
+
+	return nil
+}
+
+// verify 按目录列取存储端文件，统计数据库中存在但存储端缺失的文件
+func (job *QuotaTask) verify(user *model.User) (int, uint64) {
+	files, err := model.GetFilesByUserID(user.ID)
+	if err != nil {
+		util.Log().Warning("Failed to list files of user %d: %s", user.ID, err)
+		return 0, 0
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		util.Log().Warning("Failed to initialize filesystem for user %d: %s", user.ID, err)
+		return 0, 0
+	}
+	defer fs.Recycle()
+
+	// 按存储策略与所在目录分组，每个目录只列取一次
+	type dirKey struct {
+		policy uint
+		dir    string
+	}
+	groups := make(map[dirKey][]*model.File)
+	for i := range files {
+		// 上传中的文件不进行校验
+		if files[i].UploadSessionID != nil {
+			continue
+		}
+
+		key := dirKey{files[i].PolicyID, path.Dir(files[i].SourceName)}
+		groups[key] = append(groups[key], &files[i])
+	}
+
+	var (
+		missing     int
+		missingSize uint64
+	)
+	ctx := context.Background()
+	for key, group := range groups {
+		fs.Policy = group[0].GetPolicy()
+		if err := fs.DispatchHandler(); err != nil {
+			util.Log().Warning("Failed to dispatch handler for policy %d: %s", key.policy, err)
+			continue
+		}
+
+		objects, err := fs.Handler.List(ctx, key.dir, false)
+		if err != nil {
+			util.Log().Warning("Failed to list %q in policy %d: %s", key.dir, key.policy, err)
+			continue
+		}
+
+		existed := make(map[string]bool, len(objects))
+		for _, object := range objects {
+			if !object.IsDir {
+				existed[object.Name] = true
+			}
+		}
+
+		for _, file := range group {
+			if !existed[path.Base(file.SourceName)] {
+				missing++
+				missingSize += file.Size
+			}
+		}
+	}
+
+	return missing, missingSize
+}
+
+// NewQuotaTask 新建容量重新计算任务
+func NewQuotaTask(user *model.User, uids []uint, verify, apply, folders bool) (Job, error) {
+	newTask := &QuotaTask{
+		User: user,
+		TaskProps: QuotaProps{
+			UserIDs: uids,
+			Verify:  verify,
+			Apply:   apply,
+			Folders: folders,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewQuotaTaskFromModel 从数据库记录中恢复容量重新计算任务
+func NewQuotaTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &QuotaTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
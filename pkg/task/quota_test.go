@@ -0,0 +1,188 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuotaTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &QuotaTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(QuotaTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestQuotaTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &QuotaTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("error"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("error", task.GetError().Error)
+}
+
+func TestQuotaTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 列取用户失败
+	{
+		task := &QuotaTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 发现并修正偏差
+	{
+		task := &QuotaTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: QuotaProps{Apply: true},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(1, 10).AddRow(2, 20))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(15))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(20))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+		asserts.Equal(2, task.TaskProps.Scanned)
+		asserts.Len(task.TaskProps.Drifts, 1)
+		asserts.Equal(QuotaDrift{UserID: 1, Recorded: 10, Actual: 15, Corrected: true}, task.TaskProps.Drifts[0])
+	}
+
+	// 统计并修正目录总大小
+	{
+		task := &QuotaTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: QuotaProps{UserIDs: []uint{1}, Apply: true, Folders: true},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(1, 4))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(4))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size"}).AddRow(1, nil, 4).AddRow(2, 1, 5).AddRow(3, 1, 0))
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"folder_id", "sum"}).AddRow(2, 3).AddRow(1, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+		asserts.Equal(QuotaDrift{UserID: 1, Recorded: 4, Actual: 4, FolderDrifts: 1, FoldersCorrected: 1}, task.TaskProps.Drifts[0])
+	}
+
+	// 统计失败
+	{
+		task := &QuotaTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(1, 10))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+}
+
+func TestNewQuotaTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewQuotaTask(&model.User{}, []uint{1}, true, false, false)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(job.(*QuotaTask).TaskProps.Verify)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewQuotaTask(&model.User{}, nil, false, false, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewQuotaTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewQuotaTaskFromModel(&model.Task{Props: `{"apply":true}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(job.(*QuotaTask).TaskProps.Apply)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewQuotaTaskFromModel(&model.Task{Props: "x"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
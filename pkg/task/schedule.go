@@ -0,0 +1,74 @@
+package task
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// windowLayout 时间窗口的时间格式
+const windowLayout = "15:04"
+
+// ErrInvalidWindow 时间窗口格式不正确
+var ErrInvalidWindow = errors.New("invalid time window, expect HH:MM")
+
+// Deferrable 可推迟执行的任务
+type Deferrable interface {
+	// NotBefore 返回任务最早的执行时间
+	NotBefore() time.Time
+}
+
+// SubmitDeferred 提交任务，若任务指定了最早执行时间，则在到达该时间后再加入任务池
+func SubmitDeferred(p Pool, job Job) {
+	if deferrable, ok := job.(Deferrable); ok {
+		if wait := time.Until(deferrable.NotBefore()); wait > 0 {
+			util.Log().Debug("Task deferred for %s.", wait)
+			time.AfterFunc(wait, func() {
+				p.Submit(job)
+			})
+			return
+		}
+	}
+
+	p.Submit(job)
+}
+
+// NextWindowStart 返回给定每日时间窗口 [start, end) 的下一次开始时间，格式为 HH:MM，
+// 结束时间早于开始时间表示窗口跨越零点。当前时间已在窗口内时直接返回 now。
+func NextWindowStart(now time.Time, start, end string) (time.Time, error) {
+	startTime, err := time.Parse(windowLayout, start)
+	if err != nil {
+		return now, ErrInvalidWindow
+	}
+
+	endTime, err := time.Parse(windowLayout, end)
+	if err != nil {
+		return now, ErrInvalidWindow
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	startMinutes := startTime.Hour()*60 + startTime.Minute()
+	endMinutes := endTime.Hour()*60 + endTime.Minute()
+
+	var inWindow bool
+	switch {
+	case startMinutes == endMinutes:
+		inWindow = true
+	case startMinutes < endMinutes:
+		inWindow = minutes >= startMinutes && minutes < endMinutes
+	default:
+		inWindow = minutes >= startMinutes || minutes < endMinutes
+	}
+
+	if inWindow {
+		return now, nil
+	}
+
+	next := time.Date(now.Year(), now.Month(), now.Day(), startTime.Hour(), startTime.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+
+	return next, nil
+}
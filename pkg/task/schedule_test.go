@@ -0,0 +1,96 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type deferredJobMock struct {
+	MockJob
+	notBefore time.Time
+}
+
+func (job *deferredJobMock) NotBefore() time.Time {
+	return job.notBefore
+}
+
+type recordPoolMock struct {
+	submitted chan Job
+}
+
+func (pool *recordPoolMock) Add(num int) {}
+
+func (pool *recordPoolMock) Submit(job Job) {
+	pool.submitted <- job
+}
+
+func TestSubmitDeferred(t *testing.T) {
+	asserts := assert.New(t)
+	pool := &recordPoolMock{submitted: make(chan Job, 1)}
+
+	// 普通任务立即提交
+	{
+		SubmitDeferred(pool, &CompressTask{User: &model.User{}})
+		asserts.Len(pool.submitted, 1)
+		<-pool.submitted
+	}
+
+	// 推迟的任务
+	{
+		SubmitDeferred(pool, &deferredJobMock{notBefore: time.Now().Add(50 * time.Millisecond)})
+		asserts.Len(pool.submitted, 0)
+		select {
+		case <-pool.submitted:
+		case <-time.After(time.Second):
+			asserts.Fail("deferred job not submitted")
+		}
+	}
+}
+
+func TestNextWindowStart(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Date(2022, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	// 格式错误
+	{
+		_, err := NextWindowStart(now, "1", "02:00")
+		asserts.Equal(ErrInvalidWindow, err)
+		_, err = NextWindowStart(now, "01:00", "25:00")
+		asserts.Equal(ErrInvalidWindow, err)
+	}
+
+	// 已在窗口内
+	{
+		res, err := NextWindowStart(now, "12:00", "13:00")
+		asserts.NoError(err)
+		asserts.Equal(now, res)
+	}
+
+	// 今天稍后开始
+	{
+		res, err := NextWindowStart(now, "22:00", "23:00")
+		asserts.NoError(err)
+		asserts.Equal(time.Date(2022, 1, 1, 22, 0, 0, 0, time.UTC), res)
+	}
+
+	// 跨越零点的窗口
+	{
+		res, err := NextWindowStart(now, "23:00", "06:00")
+		asserts.NoError(err)
+		asserts.Equal(time.Date(2022, 1, 1, 23, 0, 0, 0, time.UTC), res)
+
+		res, err = NextWindowStart(time.Date(2022, 1, 2, 3, 0, 0, 0, time.UTC), "23:00", "06:00")
+		asserts.NoError(err)
+		asserts.Equal(time.Date(2022, 1, 2, 3, 0, 0, 0, time.UTC), res)
+	}
+
+	// 明天开始
+	{
+		res, err := NextWindowStart(now, "02:00", "05:00")
+		asserts.NoError(err)
+		asserts.Equal(time.Date(2022, 1, 2, 2, 0, 0, 0, time.UTC), res)
+	}
+}
@@ -0,0 +1,285 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleHistorySize 每个定时任务保留的执行记录数
+const ScheduleHistorySize = 20
+
+var (
+	ErrScheduleNotFound = errors.New("scheduled job not found")
+	ErrScheduleRunning  = errors.New("scheduled job is still running")
+)
+
+// DefaultScheduler 全局定时任务调度器
+var DefaultScheduler = NewScheduler()
+
+// ScheduleRun 定时任务的一次执行记录
+type ScheduleRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	// Manual 是否为手动触发
+	Manual bool   `json:"manual"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ScheduledJob 定时任务的当前状态
+type ScheduledJob struct {
+	Name    string `json:"name"`
+	Spec    string `json:"spec"`
+	Paused  bool   `json:"paused"`
+	Running bool   `json:"running"`
+	// Next 下一次执行时间，暂停或未设定日程时为空
+	Next    *time.Time   `json:"next,omitempty"`
+	LastRun *ScheduleRun `json:"last_run,omitempty"`
+	// Error 日程格式错误信息
+	Error string `json:"error,omitempty"`
+}
+
+type scheduleEntry struct {
+	name     string
+	spec     string
+	run      func() error
+	schedule cron.Schedule
+	specErr  error
+
+	registered bool
+	paused     bool
+	running    bool
+	history    []ScheduleRun
+}
+
+// Scheduler 按 cron 表达式周期执行后台任务，同一任务不会并发执行。
+// 重新加载时任务的暂停状态和执行记录会保留。
+type Scheduler struct {
+	lock    sync.Mutex
+	cron    *cron.Cron
+	entries map[string]*scheduleEntry
+}
+
+// NewScheduler 新建定时任务调度器
+func NewScheduler() *Scheduler {
+	return &Scheduler{entries: make(map[string]*scheduleEntry)}
+}
+
+// Register 注册定时任务，spec 为空时仅可手动触发
+func (s *Scheduler) Register(name, spec string, run func() error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.entries[name]
+	if !ok {
+		entry = &scheduleEntry{name: name}
+		s.entries[name] = entry
+	}
+
+	entry.spec = spec
+	entry.run = run
+	entry.registered = true
+	entry.schedule = nil
+	entry.specErr = nil
+	if spec != "" {
+		entry.schedule, entry.specErr = cron.ParseStandard(spec)
+	}
+
+	return entry.specErr
+}
+
+// Start 开始调度已注册的任务
+func (s *Scheduler) Start() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cron != nil {
+		s.cron.Stop()
+	}
+
+	s.cron = cron.New()
+	for _, entry := range s.entries {
+		if !entry.registered || entry.schedule == nil {
+			continue
+		}
+
+		entry := entry
+		s.cron.Schedule(entry.schedule, cron.FuncJob(func() {
+			if s.begin(entry, false) {
+				s.execute(entry, false)
+			}
+		}))
+	}
+	s.cron.Start()
+}
+
+// Reset 停止调度并注销全部任务，用于重新加载日程设置
+func (s *Scheduler) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.cron != nil {
+		s.cron.Stop()
+		s.cron = nil
+	}
+
+	for _, entry := range s.entries {
+		entry.registered = false
+	}
+}
+
+// List 列出已注册的定时任务
+func (s *Scheduler) List() []ScheduledJob {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	res := make([]ScheduledJob, 0, len(s.entries))
+	for _, entry := range s.entries {
+		if !entry.registered {
+			continue
+		}
+
+		job := ScheduledJob{
+			Name:    entry.name,
+			Spec:    entry.spec,
+			Paused:  entry.paused,
+			Running: entry.running,
+		}
+		if entry.specErr != nil {
+			job.Error = entry.specErr.Error()
+		}
+		if entry.schedule != nil && !entry.paused {
+			next := entry.schedule.Next(now)
+			job.Next = &next
+		}
+		if len(entry.history) > 0 {
+			last := entry.history[len(entry.history)-1]
+			job.LastRun = &last
+		}
+
+		res = append(res, job)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// Trigger 立即在后台执行一次任务，暂停中的任务也可手动触发
+func (s *Scheduler) Trigger(name string) error {
+	entry, err := s.get(name)
+	if err != nil {
+		return err
+	}
+
+	if !s.begin(entry, true) {
+		return ErrScheduleRunning
+	}
+
+	go s.execute(entry, true)
+	return nil
+}
+
+// Pause 暂停任务的定时执行
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume 恢复任务的定时执行
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+// History 返回任务的执行记录，最近的在前
+func (s *Scheduler) History(name string) ([]ScheduleRun, error) {
+	entry, err := s.get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]ScheduleRun, len(entry.history))
+	for i, run := range entry.history {
+		res[len(res)-1-i] = run
+	}
+	return res, nil
+}
+
+func (s *Scheduler) get(name string) (*scheduleEntry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry, ok := s.entries[name]
+	if !ok || !entry.registered {
+		return nil, ErrScheduleNotFound
+	}
+	return entry, nil
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	entry, err := s.get(name)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	entry.paused = paused
+	s.lock.Unlock()
+	return nil
+}
+
+// begin 标记任务开始执行，返回是否可以执行
+func (s *Scheduler) begin(entry *scheduleEntry, manual bool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if entry.running || (!manual && entry.paused) {
+		return false
+	}
+
+	entry.running = true
+	return true
+}
+
+// execute 执行任务并记录结果
+func (s *Scheduler) execute(entry *scheduleEntry, manual bool) {
+	s.lock.Lock()
+	run := entry.run
+	s.lock.Unlock()
+
+	record := ScheduleRun{StartedAt: time.Now(), Manual: manual}
+	if err := safeRun(run); err != nil {
+		record.Error = err.Error()
+		util.Log().Warning("Scheduled job %q failed: %s", entry.name, err)
+	}
+	record.FinishedAt = time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	entry.running = false
+	entry.history = append(entry.history, record)
+	if len(entry.history) > ScheduleHistorySize {
+		entry.history = entry.history[len(entry.history)-ScheduleHistorySize:]
+	}
+}
+
+// safeRun 执行任务，将 panic 转换为错误
+func safeRun(run func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return run()
+}
@@ -0,0 +1,132 @@
+package task
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitHistory 等待任务执行记录达到指定数量
+func waitHistory(t *testing.T, s *Scheduler, name string, count int) []ScheduleRun {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		history, err := s.History(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) >= count {
+			return history
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expect %d runs, got %d", count, len(history))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestScheduler_Register(t *testing.T) {
+	a := assert.New(t)
+	s := NewScheduler()
+
+	a.NoError(s.Register("b", "@hourly", func() error { return nil }))
+	a.NoError(s.Register("a", "", func() error { return nil }))
+	a.Error(s.Register("c", "invalid", func() error { return nil }))
+
+	jobs := s.List()
+	a.Len(jobs, 3)
+	a.Equal("a", jobs[0].Name)
+	a.Nil(jobs[0].Next)
+	a.Equal("b", jobs[1].Name)
+	a.NotNil(jobs[1].Next)
+	a.Empty(jobs[1].Error)
+	a.NotEmpty(jobs[2].Error)
+
+	// 重置后注销
+	s.Reset()
+	a.Empty(s.List())
+	a.ErrorIs(s.Trigger("a"), ErrScheduleNotFound)
+	_, err := s.History("a")
+	a.ErrorIs(err, ErrScheduleNotFound)
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	a := assert.New(t)
+	s := NewScheduler()
+	release := make(chan struct{})
+	calls := 0
+	a.NoError(s.Register("job", "", func() error {
+		calls++
+		if calls == 1 {
+			<-release
+			return errors.New("error")
+		}
+		panic("panic")
+	}))
+
+	// 执行中不可重复触发
+	a.NoError(s.Trigger("job"))
+	a.ErrorIs(s.Trigger("job"), ErrScheduleRunning)
+	a.True(s.List()[0].Running)
+	close(release)
+
+	history := waitHistory(t, s, "job", 1)
+	a.True(history[0].Manual)
+	a.Equal("error", history[0].Error)
+	a.False(history[0].FinishedAt.Before(history[0].StartedAt))
+
+	// panic 记录为错误，最近的记录在前
+	a.NoError(s.Trigger("job"))
+	history = waitHistory(t, s, "job", 2)
+	a.Equal("panic: panic", history[0].Error)
+	a.Equal("error", history[1].Error)
+	a.Equal("panic: panic", s.List()[0].LastRun.Error)
+}
+
+func TestScheduler_Pause(t *testing.T) {
+	a := assert.New(t)
+	s := NewScheduler()
+	a.NoError(s.Register("job", "@every 1s", func() error { return nil }))
+	a.ErrorIs(s.Pause("not_exist"), ErrScheduleNotFound)
+
+	a.NoError(s.Pause("job"))
+	job := s.List()[0]
+	a.True(job.Paused)
+	a.Nil(job.Next)
+
+	// 暂停时不按日程执行，但可手动触发
+	entry, _ := s.get("job")
+	a.False(s.begin(entry, false))
+	a.NoError(s.Trigger("job"))
+	waitHistory(t, s, "job", 1)
+
+	// 重新加载后保留暂停状态和执行记录
+	s.Reset()
+	a.NoError(s.Register("job", "@every 1s", func() error { return nil }))
+	a.True(s.List()[0].Paused)
+	a.Len(waitHistory(t, s, "job", 1), 1)
+
+	// 恢复后按日程执行
+	a.NoError(s.Resume("job"))
+	s.Start()
+	defer s.Reset()
+	history := waitHistory(t, s, "job", 2)
+	a.False(history[0].Manual)
+}
+
+func TestScheduler_HistorySize(t *testing.T) {
+	a := assert.New(t)
+	s := NewScheduler()
+	a.NoError(s.Register("job", "", func() error { return nil }))
+	entry, _ := s.get("job")
+
+	for i := 0; i < ScheduleHistorySize+5; i++ {
+		a.True(s.begin(entry, true))
+		s.execute(entry, true)
+	}
+
+	history, err := s.History("job")
+	a.NoError(err)
+	a.Len(history, ScheduleHistorySize)
+}
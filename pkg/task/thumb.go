@@ -0,0 +1,219 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// thumbScanBatchSize 重新生成缩略图时每批读取的文件数
+	thumbScanBatchSize = 100
+	// thumbMaxFileSystems 同时保留的用户文件系统数，超出后全部回收
+	thumbMaxFileSystems = 64
+)
+
+// ThumbTask 缩略图重新生成任务
+type ThumbTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps ThumbProps
+	Err       *JobError
+}
+
+// ThumbProps 缩略图重新生成任务属性
+type ThumbProps struct {
+	// 筛选条件，为空时不参与筛选
+	PolicyID   uint     `json:"policy_id,omitempty"`
+	UserID     uint     `json:"uid,omitempty"`
+	Extensions []string `json:"exts,omitempty"`
+	// 每秒最多处理的文件数，为 0 时不限制
+	Rate int `json:"rate,omitempty"`
+
+	// 执行进度，Cursor 为最后处理的文件 ID，任务恢复后从此处继续
+	Cursor      uint `json:"cursor"`
+	Scanned     int  `json:"scanned"`
+	Regenerated int  `json:"regenerated"`
+	Skipped     int  `json:"skipped"`
+	Failed      int  `json:"failed"`
+}
+
+// Props 获取任务属性
+func (job *ThumbTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *ThumbTask) Type() int {
+	return ThumbTaskType
+}
+
+// Creator 获取创建者ID
+func (job *ThumbTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *ThumbTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *ThumbTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *ThumbTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *ThumbTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *ThumbTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *ThumbTask) Do() {
+	job.TaskModel.SetProgress(GeneratingProgress)
+
+	var ticker *time.Ticker
+	if job.TaskProps.Rate > 0 {
+		ticker = time.NewTicker(time.Second / time.Duration(job.TaskProps.Rate))
+		defer ticker.Stop()
+	}
+
+	filesystems := make(map[uint]*filesystem.FileSystem)
+	defer recycleFileSystems(filesystems)
+
+	for {
+		files, err := model.ListFilesAfter(job.TaskProps.Cursor, thumbScanBatchSize,
+			job.TaskProps.PolicyID, job.TaskProps.UserID, job.TaskProps.Extensions)
+		if err != nil {
+			job.SetErrorMsg("Failed to list files.", err)
+			return
+		}
+
+		for i := range files {
+			if ticker != nil {
+				<-ticker.C
+			}
+
+			job.regenerate(filesystems, &files[i])
+			job.TaskProps.Cursor = files[i].ID
+		}
+
+		// 每批处理完成后记录进度
+		job.TaskModel.SetProps(job.Props())
+		util.Log().Info("Thumb task %d: %d scanned, %d regenerated, %d skipped, %d failed.", job.TaskModel.ID,
+			job.TaskProps.Scanned, job.TaskProps.Regenerated, job.TaskProps.Skipped, job.TaskProps.Failed)
+		if len(files) < thumbScanBatchSize {
+			return
+		}
+	}
+}
+
+// regenerate 重新生成单个文件的缩略图并计入进度
+func (job *ThumbTask) regenerate(filesystems map[uint]*filesystem.FileSystem, file *model.File) {
+	job.TaskProps.Scanned++
+
+	fs, ok := filesystems[file.UserID]
+	if !ok {
+		if len(filesystems) >= thumbMaxFileSystems {
+			recycleFileSystems(filesystems)
+		}
+
+		user, err := model.GetUserByID(file.UserID)
+		if err == nil {
+			fs, err = filesystem.NewFileSystem(&user)
+		}
+
+		if err != nil {
+			util.Log().Warning("Thumb task %d cannot initialize filesystem for user %d: %s", job.TaskModel.ID, file.UserID, err)
+			fs = nil
+		}
+		filesystems[file.UserID] = fs
+	}
+
+	if fs == nil {
+		job.TaskProps.Failed++
+		return
+	}
+
+	generated, err := fs.RegenerateThumbnail(context.Background(), file)
+	switch {
+	case err != nil:
+		util.Log().Debug("Failed to regenerate thumb for file %d: %s", file.ID, err)
+		job.TaskProps.Failed++
+	case generated:
+		job.TaskProps.Regenerated++
+	default:
+		job.TaskProps.Skipped++
+	}
+}
+
+// recycleFileSystems 回收并清空缓存的用户文件系统
+func recycleFileSystems(filesystems map[uint]*filesystem.FileSystem) {
+	for uid, fs := range filesystems {
+		if fs != nil {
+			fs.Recycle()
+		}
+		delete(filesystems, uid)
+	}
+}
+
+// NewThumbTask 新建缩略图重新生成任务
+func NewThumbTask(user *model.User, policyID, uid uint, exts []string, rate int) (Job, error) {
+	newTask := &ThumbTask{
+		User: user,
+		TaskProps: ThumbProps{
+			PolicyID:   policyID,
+			UserID:     uid,
+			Extensions: exts,
+			Rate:       rate,
+		},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewThumbTaskFromModel 从数据库记录中恢复缩略图重新生成任务
+func NewThumbTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &ThumbTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
@@ -0,0 +1,136 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &ThumbTask{
+		User: &model.User{},
+	}
+	asserts.NotEmpty(task.Props())
+	asserts.Equal(ThumbTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+}
+
+func TestThumbTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &ThumbTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error", errors.New("error"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("error", task.GetError().Msg)
+	asserts.Equal("error", task.GetError().Error)
+}
+
+func TestThumbTask_Do(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 列取文件失败
+	{
+		task := &ThumbTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(task.GetError())
+	}
+
+	// 文件所有者不存在，计入失败并记录进度
+	{
+		task := &ThumbTask{
+			User:      &model.User{},
+			TaskModel: &model.Task{Model: gorm.Model{ID: 1}},
+			TaskProps: ThumbProps{Cursor: 2, PolicyID: 3, Extensions: []string{"jpg"}},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(2, 3, "%.jpg").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1).AddRow(6, 1))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)tasks(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+		asserts.EqualValues(6, task.TaskProps.Cursor)
+		asserts.Equal(2, task.TaskProps.Scanned)
+		asserts.Equal(2, task.TaskProps.Failed)
+	}
+}
+
+func TestNewThumbTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewThumbTask(&model.User{}, 1, 2, []string{"jpg"}, 10)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(10, job.(*ThumbTask).TaskProps.Rate)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewThumbTask(&model.User{}, 0, 0, nil, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewThumbTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewThumbTaskFromModel(&model.Task{Props: `{"cursor":5,"exts":["png"]}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(5, job.(*ThumbTask).TaskProps.Cursor)
+		asserts.Equal([]string{"png"}, job.(*ThumbTask).TaskProps.Extensions)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewThumbTaskFromModel(&model.Task{Props: "x"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
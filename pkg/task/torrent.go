@@ -0,0 +1,271 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/torrent"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// TorrentTask 种子制作任务
+type TorrentTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps TorrentProps
+	Err       *JobError
+
+	torrentPath string
+}
+
+// TorrentProps 种子制作任务属性
+type TorrentProps struct {
+	Dirs        []uint   `json:"dirs"`
+	Files       []uint   `json:"files"`
+	Dst         string   `json:"dst"`
+	Name        string   `json:"name"`
+	Trackers    []string `json:"trackers,omitempty"`
+	PieceLength int64    `json:"piece_length,omitempty"`
+	Seed        bool     `json:"seed,omitempty"`
+
+	// 任务结果
+	InfoHash string `json:"info_hash,omitempty"`
+	Magnet   string `json:"magnet,omitempty"`
+	SeedGID  string `json:"seed_gid,omitempty"`
+}
+
+// Props 获取任务属性
+func (job *TorrentTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务状态
+func (job *TorrentTask) Type() int {
+	return TorrentTaskType
+}
+
+// Creator 获取创建者ID
+func (job *TorrentTask) Creator() uint {
+	return job.User.ID
+}
+
+// NotifyUser 获取任务结束后需通知的用户
+func (job *TorrentTask) NotifyUser() *model.User {
+	return job.User
+}
+
+// Model 获取任务的数据库模型
+func (job *TorrentTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *TorrentTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *TorrentTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+
+	job.removeTorrentFile()
+}
+
+func (job *TorrentTask) removeTorrentFile() {
+	if job.torrentPath != "" {
+		if err := os.Remove(job.torrentPath); err != nil {
+			util.Log().Warning("Failed to delete temp torrent file %q: %s", job.torrentPath, err)
+		}
+	}
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *TorrentTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *TorrentTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *TorrentTask) Do() {
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		job.SetErrorMsg("Failed to create filesystem.", err)
+		return
+	}
+	defer fs.Recycle()
+
+	ctx := context.Background()
+	name, entries, err := fs.ListTorrentEntries(ctx, job.TaskProps.Dirs, job.TaskProps.Files)
+	if err != nil {
+		job.SetErrorMsg("Failed to list files.", err)
+		return
+	}
+	if name == "" {
+		name = job.TaskProps.Name
+	}
+
+	// 计算分片哈希
+	util.Log().Debug("Start hashing pieces of torrent %q...", name)
+	job.TaskModel.SetProgress(HashingProgress)
+	meta, err := fs.CreateTorrent(ctx, name, entries, job.TaskProps.PieceLength, job.TaskProps.Trackers, "")
+	if err != nil {
+		job.SetErrorMsg("Failed to create torrent.", err)
+		return
+	}
+
+	// 保存种子文件到临时目录
+	torrentPath := filepath.Join(
+		util.RelativePath(model.GetSettingByName("temp_path")),
+		"torrent",
+		meta.InfoHash+".torrent",
+	)
+	torrentFile, err := util.CreatNestedFile(torrentPath)
+	if err != nil {
+		job.SetErrorMsg("Failed to create temp torrent file.", err)
+		return
+	}
+
+	job.torrentPath = torrentPath
+	_, err = torrentFile.Write(meta.Raw)
+	torrentFile.Close()
+	if err != nil {
+		job.SetErrorMsg("Failed to write temp torrent file.", err)
+		return
+	}
+
+	// 上传种子文件
+	job.TaskModel.SetProgress(TransferringProgress)
+	err = fs.UploadFromPath(ctx, torrentPath, path.Join(job.TaskProps.Dst, name+".torrent"), 0)
+	if err != nil {
+		job.SetErrorMsg("Failed to upload torrent file.", err)
+		return
+	}
+
+	job.TaskProps.InfoHash = meta.InfoHash
+	job.TaskProps.Magnet = meta.Magnet()
+
+	if job.TaskProps.Seed {
+		gid, err := job.seed(meta, entries)
+		if err != nil {
+			job.SetErrorMsg("Failed to start seeding.", err)
+			return
+		}
+		job.TaskProps.SeedGID = gid
+	}
+
+	job.TaskModel.SetProps(job.Props())
+	job.removeTorrentFile()
+}
+
+// seed 在临时目录中按照种子结构链接本地文件，并交由主机 aria2 做种
+func (job *TorrentTask) seed(meta *torrent.MetaInfo, entries []filesystem.TorrentEntry) (string, error) {
+	node, err := masterAria2Node()
+	if err != nil {
+		return "", err
+	}
+
+	seedDir := filepath.Join(
+		util.RelativePath(model.GetSettingByName("temp_path")),
+		"torrent",
+		"seed",
+		meta.InfoHash,
+	)
+
+	for _, entry := range entries {
+		if entry.File.GetPolicy().Type != "local" {
+			return "", errors.New("only files stored in local policy can be seeded")
+		}
+
+		if entry.File.GetPolicy().Encrypted() {
+			return "", errors.New("files stored in encrypted policy cannot be seeded")
+		}
+
+		dst := filepath.Join(seedDir, meta.Name)
+		if len(entries) != 1 || len(entry.Path) != 1 || entry.Path[0] != meta.Name {
+			dst = filepath.Join(append([]string{dst}, entry.Path...)...)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0744); err != nil {
+			return "", err
+		}
+
+		if err := os.Symlink(util.RelativePath(entry.File.SourceName), dst); err != nil && !os.IsExist(err) {
+			return "", err
+		}
+	}
+
+	return node.GetAria2Instance().Seed(job.torrentPath, seedDir)
+}
+
+// masterAria2Node 返回开启离线下载的主机节点
+func masterAria2Node() (cluster.Node, error) {
+	nodes, err := model.GetNodesByStatus(model.NodeActive)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nodes {
+		if n.Type != model.MasterNodeType {
+			continue
+		}
+
+		if node := cluster.Default.GetNodeByID(n.ID); node != nil && node.IsFeatureEnabled("aria2") {
+			return node, nil
+		}
+	}
+
+	return nil, errors.New("aria2 is not enabled on master node")
+}
+
+// NewTorrentTask 新建种子制作任务
+func NewTorrentTask(user *model.User, props TorrentProps) (Job, error) {
+	newTask := &TorrentTask{
+		User:      user,
+		TaskProps: props,
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewTorrentTaskFromModel 从数据库记录中恢复种子制作任务
+func NewTorrentTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &TorrentTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
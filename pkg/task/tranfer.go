@@ -35,6 +35,10 @@ type TransferProps struct {
 	TrimPath bool `json:"trim_path"`
 	// 负责处理中专任务的节点ID
 	NodeID uint `json:"node_id"`
+	// 转存使用的存储策略ID，为 0 时使用用户默认策略
+	PolicyID uint `json:"policy_id,omitempty"`
+	// 已完成中转的原始文件，任务中断恢复后跳过
+	Completed []string `json:"completed,omitempty"`
 }
 
 // Props 获取任务属性
@@ -53,6 +57,11 @@ func (job *TransferTask) Creator() uint {
 	return job.User.ID
 }
 
+// NotifyUser 获取任务结束后需通知的用户
+func (job *TransferTask) NotifyUser() *model.User {
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *TransferTask) Model() *model.Task {
 	return job.TaskModel
@@ -94,9 +103,28 @@ func (job *TransferTask) Do() {
 		return
 	}
 
-	successCount := 0
+	// 指定了存储策略
+	if job.TaskProps.PolicyID != 0 {
+		policy, err := model.GetPolicyByID(job.TaskProps.PolicyID)
+		if err != nil {
+			job.SetErrorMsg("Policy not exist.", err)
+			return
+		}
+
+		fs.Policy = &policy
+		if err := fs.DispatchHandler(); err != nil {
+			job.SetErrorMsg("Failed to dispatch policy.", err)
+			return
+		}
+	}
+
+	successCount := len(job.TaskProps.Completed)
 	errorList := make([]string, 0, len(job.TaskProps.Src))
 	for _, file := range job.TaskProps.Src {
+		if util.ContainsString(job.TaskProps.Completed, file) {
+			continue
+		}
+
 		dst := path.Join(job.TaskProps.Dst, filepath.Base(file))
 		if job.TaskProps.TrimPath {
 			// 保留原始目录
@@ -132,6 +160,8 @@ func (job *TransferTask) Do() {
 			errorList = append(errorList, err.Error())
 		} else {
 			successCount++
+			job.TaskProps.Completed = append(job.TaskProps.Completed, file)
+			job.TaskModel.SetProps(job.Props())
 			job.TaskModel.SetProgress(successCount)
 		}
 	}
@@ -143,7 +173,7 @@ func (job *TransferTask) Do() {
 }
 
 // NewTransferTask 新建中转任务
-func NewTransferTask(user uint, src []string, dst, parent string, trim bool, node uint, sizes map[string]uint64) (Job, error) {
+func NewTransferTask(user uint, src []string, dst, parent string, trim bool, node uint, sizes map[string]uint64, policy uint) (Job, error) {
 	creator, err := model.GetActiveUserByID(user)
 	if err != nil {
 		return nil, err
@@ -158,6 +188,7 @@ func NewTransferTask(user uint, src []string, dst, parent string, trim bool, nod
 			TrimPath: trim,
 			NodeID:   node,
 			SrcSizes: sizes,
+			PolicyID: policy,
 		},
 	}
 
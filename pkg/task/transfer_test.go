@@ -117,6 +117,47 @@ func TestTransferTask_Do(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(task.GetError().Msg)
 	}
+
+	// 指定的存储策略不存在
+	{
+		task.User = &model.User{
+			Policy: model.Policy{
+				Type: "mock",
+			},
+		}
+		task.TaskProps.PolicyID = 536
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(errors.New("not found"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("Policy not exist.", task.GetError().Msg)
+	}
+
+	// 使用指定的存储策略，上传出错
+	{
+		task.TaskProps.PolicyID = 537
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(537, "mock"))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
+			1))
+		mock.ExpectCommit()
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("Failed to transfer one or more file(s).", task.GetError().Msg)
+	}
+
+	// 跳过中断前已完成的文件
+	{
+		task.TaskProps.PolicyID = 0
+		task.TaskProps.Completed = []string{"test/not_exist"}
+		task.Err = nil
+		task.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(task.GetError())
+	}
 }
 
 func TestNewTransferTask(t *testing.T) {
@@ -128,7 +169,7 @@ func TestNewTransferTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		job, err := NewTransferTask(1, []string{}, "/", "/", false, 0, nil)
+		job, err := NewTransferTask(1, []string{}, "/", "/", false, 0, nil, 0)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotNil(job)
 		asserts.NoError(err)
@@ -140,7 +181,7 @@ func TestNewTransferTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
-		job, err := NewTransferTask(1, []string{}, "/", "/", false, 0, nil)
+		job, err := NewTransferTask(1, []string{}, "/", "/", false, 0, nil, 0)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(job)
 		asserts.Error(err)
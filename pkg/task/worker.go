@@ -2,6 +2,10 @@ package task
 
 import (
 	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -14,6 +18,16 @@ type Worker interface {
 type GeneralWorker struct {
 }
 
+// typeNames 任务类型在通知邮件中的名称
+var typeNames = map[int]string{
+	CompressTaskType:   "压缩",
+	DecompressTaskType: "解压缩",
+	TransferTaskType:   "离线下载",
+	TorrentTaskType:    "种子制作",
+	ExportTaskType:     "数据导出",
+	FetchTaskType:      "URL 下载",
+}
+
 // Do 执行任务
 func (worker *GeneralWorker) Do(job Job) {
 	util.Log().Debug("Start executing task.")
@@ -25,6 +39,7 @@ func (worker *GeneralWorker) Do(job Job) {
 			util.Log().Debug("Failed to execute task: %s", err)
 			job.SetError(&JobError{Msg: "Fatal error.", Error: fmt.Sprintf("%s", err)})
 			job.SetStatus(Error)
+			notifyFinished(job)
 		}
 	}()
 
@@ -35,10 +50,44 @@ func (worker *GeneralWorker) Do(job Job) {
 	if err := job.GetError(); err != nil {
 		util.Log().Debug("Failed to execute task.")
 		job.SetStatus(Error)
+		notifyFinished(job)
 		return
 	}
 
 	util.Log().Debug("Task finished.")
 	// 执行完成
 	job.SetStatus(Complete)
+	notifyFinished(job)
+}
+
+// notifyFinished 向用户发送任务结束的站内通知，并向订阅了任务完成通知的用户发送邮件
+func notifyFinished(job Job) {
+	notifiable, ok := job.(notifiableJob)
+	if !ok {
+		return
+	}
+
+	user := notifiable.NotifyUser()
+	if user == nil {
+		return
+	}
+
+	vars := map[string]string{
+		"taskType": typeNames[job.Type()],
+		"status":   "完成",
+		"detail":   "",
+	}
+	if err := job.GetError(); err != nil {
+		vars["status"] = "失败"
+		vars["detail"] = "失败原因：" + err.Msg
+	}
+
+	title := fmt.Sprintf("%s任务已%s", vars["taskType"], vars["status"])
+	if _, err := notification.Send(user.ID, model.NotificationTaskFinished, title, vars["detail"], "/tasks"); err != nil {
+		util.Log().Warning("Failed to create task finished notification for user %d: %s", user.ID, err)
+	}
+
+	if err := email.Notify(user, email.NotifyTaskFinished, vars); err != nil {
+		util.Log().Warning("Failed to send task finished notification to %q: %s", user.Email, err)
+	}
 }
@@ -1,9 +1,13 @@
 package task
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -79,3 +83,84 @@ func TestGeneralWorker_Do(t *testing.T) {
 	}
 
 }
+
+type notifiableMockJob struct {
+	MockJob
+	user *model.User
+}
+
+func (job *notifiableMockJob) Type() int {
+	return CompressTaskType
+}
+
+func (job *notifiableMockJob) NotifyUser() *model.User {
+	return job.user
+}
+
+type mailMock struct {
+	to     []string
+	bodies []string
+}
+
+func (m *mailMock) Close() {
+}
+
+func (m *mailMock) Send(to, title, body string) error {
+	m.to = append(m.to, to)
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+func TestGeneralWorker_DoNotify(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	mailer := &mailMock{}
+	email.Client = mailer
+	defer func() {
+		email.Client = nil
+	}()
+	cache.Set("setting_siteName", "Cloudreve", 0)
+	cache.Set("setting_siteURL", "http://localhost", 0)
+	cache.Set("setting_siteTitle", "", 0)
+	cache.Set("setting_mail_task_finished_template", "{taskType}:{status}:{detail}", 0)
+
+	// 用户未订阅，仅发送站内通知
+	{
+		job := &notifiableMockJob{user: &model.User{Email: "a@cloudreve.org"}}
+		job.DoFunc = func() {}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		worker.Do(job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Complete, job.Status)
+		asserts.Empty(mailer.to)
+	}
+
+	// 已订阅，任务失败
+	{
+		user := &model.User{Email: "a@cloudreve.org"}
+		user.OptionsSerialized.Notifications = map[string]bool{email.NotifyTaskFinished: true}
+		job := &notifiableMockJob{user: user}
+		job.DoFunc = func() {}
+		job.Err = &JobError{Msg: "<error>"}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		worker.Do(job)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(Error, job.Status)
+		asserts.Equal([]string{"a@cloudreve.org"}, mailer.to)
+		asserts.True(strings.HasPrefix(mailer.bodies[0], "压缩:失败:"))
+		asserts.Contains(mailer.bodies[0], "&lt;error&gt;")
+	}
+
+	// 无需通知
+	{
+		job := &notifiableMockJob{}
+		job.DoFunc = func() {}
+		worker.Do(job)
+		asserts.Equal(Complete, job.Status)
+		asserts.Len(mailer.to, 1)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 func init() {
@@ -21,6 +23,33 @@ func init() {
 type FfmpegGenerator struct {
 	exts        []string
 	lastRawExts string
+
+	// workers limits the number of concurrent ffmpeg processes
+	mu           sync.Mutex
+	workers      chan struct{}
+	lastMaxTasks int
+}
+
+// acquire waits for an available ffmpeg worker slot, returns a func to release it.
+func (f *FfmpegGenerator) acquire(ctx context.Context, maxTasks int) (func(), error) {
+	if maxTasks <= 0 {
+		maxTasks = runtime.GOMAXPROCS(0)
+	}
+
+	f.mu.Lock()
+	if f.workers == nil || f.lastMaxTasks != maxTasks {
+		f.workers = make(chan struct{}, maxTasks)
+		f.lastMaxTasks = maxTasks
+	}
+	workers := f.workers
+	f.mu.Unlock()
+
+	select {
+	case workers <- struct{}{}:
+		return func() { <-workers }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
 }
 
 func (f *FfmpegGenerator) Generate(ctx context.Context, file io.Reader, src, name string, options map[string]string) (*Result, error) {
@@ -65,6 +94,12 @@ func (f *FfmpegGenerator) Generate(ctx context.Context, file io.Reader, src, nam
 		tempInputFile.Close()
 	}
 
+	release, err := f.acquire(ctx, model.GetIntSetting("thumb_ffmpeg_max_task_count", 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wait for ffmpeg worker: %w", err)
+	}
+	defer release()
+
 	// Invoke ffmpeg
 	scaleOpt := fmt.Sprintf("scale=%s:%s:force_original_aspect_ratio=decrease", options["thumb_width"], options["thumb_height"])
 	cmd := exec.CommandContext(ctx,
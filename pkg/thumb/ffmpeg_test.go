@@ -0,0 +1,57 @@
+package thumb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFfmpegGenerator_Acquire(t *testing.T) {
+	a := assert.New(t)
+	generator := &FfmpegGenerator{}
+
+	// the second task waits until the first one is released
+	release, err := generator.acquire(context.Background(), 1)
+	a.NoError(err)
+
+	acquired := make(chan func())
+	go func() {
+		next, err := generator.acquire(context.Background(), 1)
+		a.NoError(err)
+		acquired <- next
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired more workers than limited")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case next := <-acquired:
+		next()
+	case <-time.After(time.Second):
+		t.Fatal("worker not released")
+	}
+
+	// waiting is canceled with the context
+	release, err = generator.acquire(context.Background(), 1)
+	a.NoError(err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = generator.acquire(ctx, 1)
+	a.ErrorIs(err, context.DeadlineExceeded)
+	release()
+
+	// pool is rebuilt when the limit changes
+	release1, err := generator.acquire(context.Background(), 2)
+	a.NoError(err)
+	release2, err := generator.acquire(context.Background(), 2)
+	a.NoError(err)
+	a.Equal(2, cap(generator.workers))
+	release1()
+	release2()
+}
@@ -0,0 +1,73 @@
+package thumb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mediameta"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+func init() {
+	RegisterGenerator(&MusicCoverGenerator{})
+}
+
+// MusicCoverGenerator extracts the cover art embedded in music files, the
+// cover is then resized by following image generators.
+type MusicCoverGenerator struct{}
+
+func (m *MusicCoverGenerator) Generate(ctx context.Context, file io.Reader, src string, name string, options map[string]string) (*Result, error) {
+	coverOpts := model.GetSettingByNames("thumb_music_cover_exts", "temp_path")
+	if !util.IsInExtensionList(strings.Split(coverOpts["thumb_music_cover_exts"], ","), name) {
+		return nil, fmt.Errorf("unsupported music format: %w", ErrPassThrough)
+	}
+
+	meta, err := mediameta.ParseAudioTags(file)
+	if err != nil {
+		if errors.Is(err, mediameta.ErrNoAudioTag) {
+			return nil, fmt.Errorf("failed to read music tags: %w", ErrNotAvailable)
+		}
+		return nil, fmt.Errorf("failed to read music tags: %w", err)
+	}
+
+	if len(meta.Cover) == 0 {
+		return nil, fmt.Errorf("no embedded cover art: %w", ErrNotAvailable)
+	}
+
+	tempPath := filepath.Join(
+		util.RelativePath(coverOpts["temp_path"]),
+		"thumb",
+		fmt.Sprintf("cover_%s%s", uuid.Must(uuid.NewV4()).String(), meta.CoverExt()),
+	)
+
+	coverFile, err := util.CreatNestedFile(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	defer coverFile.Close()
+	if _, err := coverFile.Write(meta.Cover); err != nil {
+		return nil, fmt.Errorf("failed to write cover art: %w", err)
+	}
+
+	return &Result{
+		Path:     tempPath,
+		Continue: true,
+		Cleanup:  []func(){func() { _ = os.Remove(tempPath) }},
+	}, nil
+}
+
+func (m *MusicCoverGenerator) Priority() int {
+	return 40
+}
+
+func (m *MusicCoverGenerator) EnableFlag() string {
+	return "thumb_music_cover_enabled"
+}
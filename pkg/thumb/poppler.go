@@ -0,0 +1,90 @@
+package thumb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	RegisterGenerator(&PopplerGenerator{})
+}
+
+// PopplerGenerator renders the first page of PDF files using pdftoppm.
+type PopplerGenerator struct {
+	exts        []string
+	lastRawExts string
+}
+
+func (p *PopplerGenerator) Generate(ctx context.Context, file io.Reader, src, name string, options map[string]string) (*Result, error) {
+	popplerOpts := model.GetSettingByNames("thumb_poppler_path", "thumb_poppler_exts", "thumb_encode_method", "temp_path")
+
+	if p.lastRawExts != popplerOpts["thumb_poppler_exts"] {
+		p.exts = strings.Split(popplerOpts["thumb_poppler_exts"], ",")
+	}
+
+	if !util.IsInExtensionList(p.exts, name) {
+		return nil, fmt.Errorf("unsupported document format: %w", ErrPassThrough)
+	}
+
+	formatOpt, ext := "-png", "png"
+	if popplerOpts["thumb_encode_method"] == "jpg" {
+		formatOpt, ext = "-jpeg", "jpg"
+	}
+
+	tempOutputDir := filepath.Join(
+		util.RelativePath(popplerOpts["temp_path"]),
+		"thumb",
+		fmt.Sprintf("poppler_%s", uuid.Must(uuid.NewV4()).String()),
+	)
+	if err := os.MkdirAll(tempOutputDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create temp folder: %w", err)
+	}
+
+	cleanup := func() { _ = os.RemoveAll(tempOutputDir) }
+
+	// pdftoppm reads from stdin if input file is "-"
+	input := src
+	if input == "" {
+		input = "-"
+	}
+
+	// Render the first page only, scaled to thumb width. The result will be
+	// resized to final thumb size by following generators.
+	outputPrefix := filepath.Join(tempOutputDir, "thumb")
+	cmd := exec.CommandContext(ctx, popplerOpts["thumb_poppler_path"], "-f", "1", "-l", "1", "-singlefile",
+		formatOpt, "-scale-to-x", options["thumb_width"], "-scale-to-y", "-1", input, outputPrefix)
+
+	// Redirect IO
+	var stdErr bytes.Buffer
+	cmd.Stdin = file
+	cmd.Stderr = &stdErr
+
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		util.Log().Warning("Failed to invoke pdftoppm: %s", stdErr.String())
+		return nil, fmt.Errorf("failed to invoke pdftoppm: %w", err)
+	}
+
+	return &Result{
+		Path:     outputPrefix + "." + ext,
+		Continue: true,
+		Cleanup:  []func(){cleanup},
+	}, nil
+}
+
+func (p *PopplerGenerator) Priority() int {
+	return 60
+}
+
+func (p *PopplerGenerator) EnableFlag() string {
+	return "thumb_poppler_enabled"
+}
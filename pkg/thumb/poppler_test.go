@@ -0,0 +1,77 @@
+package thumb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeExecutable writes a shell script used in place of external converters
+func fakeExecutable(t *testing.T, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "fake")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPopplerGenerator_Generate(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_thumb_poppler_exts", "pdf", 0)
+	cache.Set("setting_thumb_encode_method", "png", 0)
+	cache.Set("setting_temp_path", t.TempDir(), 0)
+	options := map[string]string{"thumb_width": "400"}
+
+	// unsupported format
+	{
+		generator := &PopplerGenerator{}
+		cache.Set("setting_thumb_poppler_path", "pdftoppm", 0)
+		_, err := generator.Generate(context.Background(), strings.NewReader(""), "", "a.docx", options)
+		a.ErrorIs(err, ErrPassThrough)
+	}
+
+	// pdftoppm failed
+	{
+		generator := &PopplerGenerator{}
+		cache.Set("setting_thumb_poppler_path", fakeExecutable(t, "exit 1\n"), 0)
+		_, err := generator.Generate(context.Background(), strings.NewReader(""), "", "a.pdf", options)
+		a.Error(err)
+	}
+
+	// first page rendered, output prefix is the last argument
+	{
+		generator := &PopplerGenerator{}
+		cache.Set("setting_thumb_poppler_path", fakeExecutable(t, `for last; do :; done; touch "$last.png"`+"\n"), 0)
+		res, err := generator.Generate(context.Background(), strings.NewReader(""), "", "a.pdf", options)
+		a.NoError(err)
+		a.True(res.Continue)
+		a.Equal(".png", filepath.Ext(res.Path))
+		a.FileExists(res.Path)
+
+		for _, cleanup := range res.Cleanup {
+			cleanup()
+		}
+		a.NoFileExists(res.Path)
+	}
+}
+
+func TestTestGenerator_Poppler(t *testing.T) {
+	a := assert.New(t)
+
+	version, err := TestGenerator(context.Background(), "poppler", fakeExecutable(t, "echo 'pdftoppm version 22.02.0' >&2\n"))
+	a.NoError(err)
+	a.Contains(version, "pdftoppm")
+
+	_, err = TestGenerator(context.Background(), "poppler", fakeExecutable(t, "echo 'unknown' >&2\n"))
+	a.ErrorIs(err, ErrUnknownOutput)
+}
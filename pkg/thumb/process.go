@@ -0,0 +1,277 @@
+package thumb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Fit modes of image processing.
+const (
+	// FitInside scales the image down to fit within the box, preserving aspect ratio.
+	FitInside = "inside"
+	// FitCover scales the image to fill the box and crops the overflow around the center.
+	FitCover = "cover"
+	// FitFill stretches the image to exactly the given size.
+	FitFill = "fill"
+)
+
+var (
+	ErrProcessFormatNotSupported = errors.New("image format not supported for processing")
+	ErrImageTooLarge             = errors.New("image is too large to process")
+)
+
+// processContentTypes maps output formats to their MIME types.
+var processContentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+	"avif": "image/avif",
+}
+
+// ProcessOptions describes an on-the-fly transformation of an image.
+type ProcessOptions struct {
+	Width   int
+	Height  int
+	Fit     string
+	Format  string
+	Quality int
+}
+
+// ParseProcessOptions reads w, h, fit, format and quality from query. It returns nil
+// if none of them is present. Width and height cannot exceed maxSize.
+func ParseProcessOptions(query url.Values, maxSize int) (*ProcessOptions, error) {
+	if query.Get("w") == "" && query.Get("h") == "" && query.Get("format") == "" {
+		return nil, nil
+	}
+
+	opts := &ProcessOptions{
+		Fit:     strings.ToLower(query.Get("fit")),
+		Format:  strings.ToLower(query.Get("format")),
+		Quality: model.GetIntSetting("thumb_encode_quality", 85),
+	}
+
+	for key, dst := range map[string]*int{"w": &opts.Width, "h": &opts.Height, "quality": &opts.Quality} {
+		if raw := query.Get(key); raw != "" {
+			val, err := strconv.Atoi(raw)
+			if err != nil || val < 0 {
+				return nil, fmt.Errorf("invalid %s %q", key, raw)
+			}
+			*dst = val
+		}
+	}
+
+	if opts.Width > maxSize || opts.Height > maxSize {
+		return nil, fmt.Errorf("width and height cannot exceed %d", maxSize)
+	}
+
+	if opts.Quality < 1 || opts.Quality > 100 {
+		return nil, fmt.Errorf("quality must be between 1 and 100")
+	}
+
+	switch opts.Fit {
+	case "":
+		opts.Fit = FitInside
+	case FitInside, FitCover, FitFill:
+	default:
+		return nil, fmt.Errorf("unknown fit %q", opts.Fit)
+	}
+
+	if opts.Format == "jpg" {
+		opts.Format = "jpeg"
+	}
+
+	if _, ok := processContentTypes[opts.Format]; opts.Format != "" && !ok {
+		return nil, fmt.Errorf("unknown format %q", opts.Format)
+	}
+
+	return opts, nil
+}
+
+// Key returns a string identifying the derivative produced by the options for source file name.
+func (opts *ProcessOptions) Key(name string) string {
+	return fmt.Sprintf("w%d_h%d_%s_q%d.%s", opts.Width, opts.Height, opts.Fit, opts.Quality, opts.OutputFormat(name))
+}
+
+// OutputFormat returns the format of the derivative. Without an explicit format, PNG and GIF
+// sources are encoded as PNG and everything else as JPEG.
+func (opts *ProcessOptions) OutputFormat(name string) string {
+	if opts.Format != "" {
+		return opts.Format
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".png", ".gif":
+		return "png"
+	default:
+		return "jpeg"
+	}
+}
+
+// ContentType returns the MIME type of the derivative.
+func (opts *ProcessOptions) ContentType(name string) string {
+	return processContentTypes[opts.OutputFormat(name)]
+}
+
+// Process transforms the image read from file and writes the result to w. WebP and AVIF output,
+// as well as sources the builtin decoder cannot read, require the vips generator to be enabled.
+func Process(ctx context.Context, file io.Reader, name string, opts *ProcessOptions, w io.Writer) error {
+	format := opts.OutputFormat(name)
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	builtinSource := util.ContainsString([]string{"jpg", "jpeg", "png", "gif", "webp"}, ext)
+	if builtinSource && (format == "jpeg" || format == "png") {
+		return processBuiltin(file, opts, format, w)
+	}
+
+	settings := model.GetSettingByNames("thumb_vips_enabled", "thumb_vips_path", "thumb_vips_exts")
+	if !model.IsTrueVal(settings["thumb_vips_enabled"]) ||
+		!util.IsInExtensionList(strings.Split(settings["thumb_vips_exts"], ","), name) {
+		return ErrProcessFormatNotSupported
+	}
+
+	return processVips(ctx, settings["thumb_vips_path"], file, opts, format, w)
+}
+
+// processBuiltin transforms the image using the pure Go codecs.
+func processBuiltin(file io.Reader, opts *ProcessOptions, format string, w io.Writer) error {
+	// Check dimensions before decoding to avoid allocating huge bitmaps.
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(file, &header))
+	if err != nil {
+		return fmt.Errorf("failed to parse image: %w", err)
+	}
+
+	if config.Width*config.Height > model.GetIntSetting("image_process_max_pixels", 40000000) {
+		return ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(io.MultiReader(&header, file))
+	if err != nil {
+		return fmt.Errorf("failed to parse image: %w", err)
+	}
+
+	img = transform(img, opts)
+	if format == "png" {
+		return png.Encode(w, img)
+	}
+
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: opts.Quality})
+}
+
+// transform resizes and crops img according to opts. Images are never scaled up
+// except in fill mode.
+func transform(img image.Image, opts *ProcessOptions) image.Image {
+	bounds := img.Bounds()
+	origWidth, origHeight := bounds.Dx(), bounds.Dy()
+
+	switch {
+	case opts.Width == 0 && opts.Height == 0:
+		return img
+	case opts.Fit == FitFill:
+		width, height := opts.Width, opts.Height
+		if width == 0 {
+			width = origWidth
+		}
+		if height == 0 {
+			height = origHeight
+		}
+		return Resize(uint(width), uint(height), img)
+	case opts.Fit == FitCover && opts.Width > 0 && opts.Height > 0:
+		// Scale so that the box is fully covered, then crop the center.
+		scale := float64(opts.Width) / float64(origWidth)
+		if heightScale := float64(opts.Height) / float64(origHeight); heightScale > scale {
+			scale = heightScale
+		}
+		if scale > 1 {
+			scale = 1
+		}
+		scaledWidth := atLeastOne(int(float64(origWidth)*scale + 0.5))
+		scaledHeight := atLeastOne(int(float64(origHeight)*scale + 0.5))
+		scaled := Resize(uint(scaledWidth), uint(scaledHeight), img)
+
+		cropWidth, cropHeight := opts.Width, opts.Height
+		if cropWidth > scaledWidth {
+			cropWidth = scaledWidth
+		}
+		if cropHeight > scaledHeight {
+			cropHeight = scaledHeight
+		}
+		offsetX, offsetY := (scaledWidth-cropWidth)/2, (scaledHeight-cropHeight)/2
+		dst := image.NewRGBA(image.Rect(0, 0, cropWidth, cropHeight))
+		draw.Draw(dst, dst.Rect, scaled, image.Pt(offsetX, offsetY), draw.Src)
+		return dst
+	default:
+		maxWidth, maxHeight := opts.Width, opts.Height
+		if maxWidth == 0 {
+			maxWidth = origWidth
+		}
+		if maxHeight == 0 {
+			maxHeight = origHeight
+		}
+		return Thumbnail(uint(maxWidth), uint(maxHeight), img)
+	}
+}
+
+func atLeastOne(val int) int {
+	if val < 1 {
+		return 1
+	}
+
+	return val
+}
+
+// processVips transforms the image by invoking vips thumbnail_source.
+func processVips(ctx context.Context, vipsPath string, file io.Reader, opts *ProcessOptions, format string, w io.Writer) error {
+	// vips requires a target width, use a large bound when only height is given.
+	width, height := opts.Width, opts.Height
+	if width == 0 {
+		width = 100000
+	}
+	if height == 0 {
+		height = 100000
+	}
+
+	output := fmt.Sprintf(".%s[Q=%d]", format, opts.Quality)
+	if format == "png" {
+		output = ".png"
+	}
+
+	args := []string{"thumbnail_source", "[descriptor=0]", output, strconv.Itoa(width), "--height", strconv.Itoa(height)}
+	switch {
+	case opts.Fit == FitFill && opts.Width > 0 && opts.Height > 0:
+		args = append(args, "--size", "force")
+	case opts.Fit == FitCover && opts.Width > 0 && opts.Height > 0:
+		args = append(args, "--size", "down", "--crop", "centre")
+	default:
+		args = append(args, "--size", "down")
+	}
+
+	var vipsErr bytes.Buffer
+	cmd := exec.CommandContext(ctx, vipsPath, args...)
+	cmd.Stdin = file
+	cmd.Stdout = w
+	cmd.Stderr = &vipsErr
+
+	if err := cmd.Run(); err != nil {
+		util.Log().Warning("Failed to invoke vips: %s", vipsErr.String())
+		return fmt.Errorf("failed to invoke vips: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package torrent
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Encode encodes given value into bencode format. Supported types are
+// string, []byte, int, int64, []interface{}, []string and map[string]interface{}.
+func Encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch val := v.(type) {
+	case string:
+		buf.WriteString(strconv.Itoa(len(val)))
+		buf.WriteByte(':')
+		buf.WriteString(val)
+	case []byte:
+		buf.WriteString(strconv.Itoa(len(val)))
+		buf.WriteByte(':')
+		buf.Write(val)
+	case int:
+		buf.WriteString("i" + strconv.Itoa(val) + "e")
+	case int64:
+		buf.WriteString("i" + strconv.FormatInt(val, 10) + "e")
+	case []string:
+		buf.WriteByte('l')
+		for _, item := range val {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case []interface{}:
+		buf.WriteByte('l')
+		for _, item := range val {
+			if err := encode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		// Keys must appear in sorted order
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('d')
+		for _, k := range keys {
+			if err := encode(buf, k); err != nil {
+				return err
+			}
+			if err := encode(buf, val[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("unsupported bencode type %T", v)
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package torrent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	a := assert.New(t)
+
+	// 基础类型
+	{
+		res, err := Encode("spam")
+		a.NoError(err)
+		a.Equal("4:spam", string(res))
+
+		res, err = Encode(int64(-3))
+		a.NoError(err)
+		a.Equal("i-3e", string(res))
+
+		res, err = Encode([]string{"spam", "eggs"})
+		a.NoError(err)
+		a.Equal("l4:spam4:eggse", string(res))
+	}
+
+	// 字典按键排序
+	{
+		res, err := Encode(map[string]interface{}{
+			"spam": []interface{}{"a", 1},
+			"cow":  []byte("moo"),
+		})
+		a.NoError(err)
+		a.Equal("d3:cow3:moo4:spaml1:ai1eee", string(res))
+	}
+
+	// 不支持的类型
+	{
+		_, err := Encode(1.5)
+		a.Error(err)
+	}
+}
@@ -0,0 +1,208 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/url"
+	"time"
+)
+
+const (
+	minPieceLength = 16 << 10
+	maxPieceLength = 16 << 20
+	// targetPieces expected number of pieces when piece length is picked automatically
+	targetPieces = 1500
+)
+
+var (
+	ErrNoFiles        = errors.New("no files added to torrent")
+	ErrInvalidPiece   = errors.New("piece length must be power of two between 16KiB and 16MiB")
+	ErrSizeMismatched = errors.New("file size does not match with the declared one")
+)
+
+// File a file inside a torrent
+type File struct {
+	Path   []string
+	Length int64
+}
+
+// Builder calculates piece hashes over a series of files and generates
+// meta info of the torrent.
+type Builder struct {
+	pieceLength int64
+	files       []File
+	pieces      []byte
+
+	hasher  hash.Hash
+	written int64
+}
+
+// NewBuilder creates a builder with given piece length, use AutoPieceLength
+// if not sure which value to choose.
+func NewBuilder(pieceLength int64) (*Builder, error) {
+	if err := CheckPieceLength(pieceLength); err != nil {
+		return nil, err
+	}
+
+	return &Builder{
+		pieceLength: pieceLength,
+		hasher:      sha1.New(),
+	}, nil
+}
+
+// CheckPieceLength checks if given piece length is acceptable
+func CheckPieceLength(pieceLength int64) error {
+	if pieceLength < minPieceLength || pieceLength > maxPieceLength || pieceLength&(pieceLength-1) != 0 {
+		return ErrInvalidPiece
+	}
+
+	return nil
+}
+
+// AutoPieceLength picks a piece length for given total size, which results
+// in around 1500 pieces.
+func AutoPieceLength(totalSize int64) int64 {
+	length := int64(minPieceLength)
+	for length < maxPieceLength && totalSize/length > targetPieces {
+		length <<= 1
+	}
+
+	return length
+}
+
+// AddFile reads all content of r and appends it to the torrent as a file
+// located at path. Files must be added in the same order they are listed.
+func (b *Builder) AddFile(path []string, size int64, r io.Reader) error {
+	n, err := io.Copy(pieceWriterFunc(b.write), r)
+	if err != nil {
+		return fmt.Errorf("failed to read file %q: %w", path, err)
+	}
+
+	if n != size {
+		return ErrSizeMismatched
+	}
+
+	b.files = append(b.files, File{Path: path, Length: n})
+	return nil
+}
+
+func (b *Builder) write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		remain := b.pieceLength - b.written%b.pieceLength
+		chunk := p
+		if int64(len(chunk)) > remain {
+			chunk = p[:remain]
+		}
+
+		b.hasher.Write(chunk)
+		b.written += int64(len(chunk))
+		p = p[len(chunk):]
+
+		if b.written%b.pieceLength == 0 {
+			b.pieces = b.hasher.Sum(b.pieces)
+			b.hasher.Reset()
+		}
+	}
+
+	return total, nil
+}
+
+// Build generates meta info of the torrent. If only one file is added and its
+// path is exactly the torrent name, a single file torrent is created.
+func (b *Builder) Build(name string, trackers []string, comment string) (*MetaInfo, error) {
+	if len(b.files) == 0 {
+		return nil, ErrNoFiles
+	}
+
+	pieces := append([]byte{}, b.pieces...)
+	if b.written%b.pieceLength != 0 {
+		pieces = b.hasher.Sum(pieces)
+	}
+
+	info := map[string]interface{}{
+		"name":         name,
+		"piece length": b.pieceLength,
+		"pieces":       pieces,
+	}
+
+	if len(b.files) == 1 && len(b.files[0].Path) == 1 && b.files[0].Path[0] == name {
+		info["length"] = b.files[0].Length
+	} else {
+		files := make([]interface{}, 0, len(b.files))
+		for _, f := range b.files {
+			files = append(files, map[string]interface{}{
+				"length": f.Length,
+				"path":   f.Path,
+			})
+		}
+		info["files"] = files
+	}
+
+	rawInfo, err := Encode(info)
+	if err != nil {
+		return nil, err
+	}
+
+	torrent := map[string]interface{}{
+		"info":          info,
+		"creation date": time.Now().Unix(),
+		"created by":    "Cloudreve",
+	}
+
+	if comment != "" {
+		torrent["comment"] = comment
+	}
+
+	if len(trackers) > 0 {
+		torrent["announce"] = trackers[0]
+		tiers := make([]interface{}, 0, len(trackers))
+		for _, tracker := range trackers {
+			tiers = append(tiers, []string{tracker})
+		}
+		torrent["announce-list"] = tiers
+	}
+
+	raw, err := Encode(torrent)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha1.Sum(rawInfo)
+	return &MetaInfo{
+		Name:     name,
+		Trackers: trackers,
+		InfoHash: hex.EncodeToString(hash[:]),
+		Raw:      raw,
+	}, nil
+}
+
+// MetaInfo generated torrent
+type MetaInfo struct {
+	Name     string
+	Trackers []string
+	InfoHash string
+	// Raw bencoded content of .torrent file
+	Raw []byte
+}
+
+// Magnet returns magnet link of the torrent
+func (m *MetaInfo) Magnet() string {
+	query := url.Values{}
+	query.Set("dn", m.Name)
+	for _, tracker := range m.Trackers {
+		query.Add("tr", tracker)
+	}
+
+	return "magnet:?xt=urn:btih:" + m.InfoHash + "&" + query.Encode()
+}
+
+type pieceWriterFunc func(p []byte) (int, error)
+
+func (f pieceWriterFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
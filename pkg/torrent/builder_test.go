@@ -0,0 +1,81 @@
+package torrent
+
+import (
+	"crypto/sha1"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBuilder(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewBuilder(1000)
+	a.ErrorIs(err, ErrInvalidPiece)
+	_, err = NewBuilder(32 << 20)
+	a.ErrorIs(err, ErrInvalidPiece)
+
+	b, err := NewBuilder(16 << 10)
+	a.NoError(err)
+	a.NotNil(b)
+}
+
+func TestAutoPieceLength(t *testing.T) {
+	a := assert.New(t)
+	a.EqualValues(16<<10, AutoPieceLength(1024))
+	a.EqualValues(1<<20, AutoPieceLength(1<<30))
+	a.EqualValues(16<<20, AutoPieceLength(1<<50))
+}
+
+func TestBuilder_Build(t *testing.T) {
+	a := assert.New(t)
+
+	// 未添加文件
+	{
+		b, _ := NewBuilder(16 << 10)
+		_, err := b.Build("empty", nil, "")
+		a.ErrorIs(err, ErrNoFiles)
+	}
+
+	// 文件大小不一致
+	{
+		b, _ := NewBuilder(16 << 10)
+		a.ErrorIs(b.AddFile([]string{"a.txt"}, 10, strings.NewReader("abc")), ErrSizeMismatched)
+	}
+
+	// 单文件，跨越多个分片
+	{
+		content := strings.Repeat("a", 16<<10) + "tail"
+		b, _ := NewBuilder(16 << 10)
+		a.NoError(b.AddFile([]string{"a.txt"}, int64(len(content)), strings.NewReader(content)))
+		res, err := b.Build("a.txt", []string{"udp://tracker.example.com:80"}, "")
+		a.NoError(err)
+
+		first := sha1.Sum([]byte(content[:16<<10]))
+		second := sha1.Sum([]byte("tail"))
+		raw := string(res.Raw)
+		a.Contains(raw, "6:lengthi16388e")
+		a.Contains(raw, "6:pieces40:"+string(first[:])+string(second[:]))
+		a.Contains(raw, "8:announce28:udp://tracker.example.com:80")
+		a.Len(res.InfoHash, 40)
+		a.True(strings.HasPrefix(res.Magnet(), "magnet:?xt=urn:btih:"+res.InfoHash))
+		a.Contains(res.Magnet(), "tr=udp%3A%2F%2Ftracker.example.com%3A80")
+	}
+
+	// 多文件，分片跨越文件边界
+	{
+		b, _ := NewBuilder(16 << 10)
+		a.NoError(b.AddFile([]string{"dir", "1.txt"}, 3, strings.NewReader("abc")))
+		a.NoError(b.AddFile([]string{"2.txt"}, 3, strings.NewReader("def")))
+		res, err := b.Build("folder", nil, "comment")
+		a.NoError(err)
+
+		piece := sha1.Sum([]byte("abcdef"))
+		raw := string(res.Raw)
+		a.Contains(raw, "5:filesld6:lengthi3e4:pathl3:dir5:1.txteed6:lengthi3e4:pathl5:2.txteee")
+		a.Contains(raw, "6:pieces20:"+string(piece[:]))
+		a.Contains(raw, "7:comment7:comment")
+		a.NotContains(raw, "announce")
+	}
+}
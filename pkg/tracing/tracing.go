@@ -0,0 +1,240 @@
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// RequestIDHeader 请求 ID 的请求、响应头
+	RequestIDHeader = "X-Request-ID"
+	// TraceparentHeader W3C Trace Context 的请求头
+	TraceparentHeader = "traceparent"
+)
+
+// Span 的类型，与 OpenTelemetry 一致
+const (
+	SpanKindServer = 2
+	SpanKindClient = 3
+)
+
+const (
+	// 单次导出的最大 Span 数
+	batchSize = 128
+	// 未满一批时的最长导出间隔
+	flushInterval = 5 * time.Second
+	// 待导出队列的长度，队列满时丢弃新的 Span
+	queueSize = 2048
+)
+
+// Default 全局的追踪数据收集器，为 nil 时表示未启用追踪导出
+var Default *Tracer
+
+// Span 追踪中的一次操作
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Kind         int
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{}
+	// Failed 操作是否失败
+	Failed bool
+}
+
+// Exporter 追踪数据的导出器
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+// Tracer 追踪数据收集器，在后台批量导出记录的 Span
+type Tracer struct {
+	exporter Exporter
+	queue    chan *Span
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Init 初始化全局的追踪数据收集器，endpoint 为空时不启用
+func Init(endpoint, serviceName string) {
+	if endpoint == "" {
+		return
+	}
+
+	Default = NewTracer(NewOTLPExporter(endpoint, serviceName))
+	util.Log().Info("Exporting traces to %q.", endpoint)
+}
+
+// Enabled 返回是否启用了追踪导出
+func Enabled() bool {
+	return Default != nil
+}
+
+// NewTracer 新建追踪数据收集器并开始后台导出
+func NewTracer(exporter Exporter) *Tracer {
+	tracer := &Tracer{
+		exporter: exporter,
+		queue:    make(chan *Span, queueSize),
+		done:     make(chan struct{}),
+	}
+
+	go tracer.run()
+	return tracer
+}
+
+// Record 记录已结束的 Span，不会阻塞调用方
+func (t *Tracer) Record(span *Span) {
+	if t == nil {
+		return
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return
+	}
+
+	select {
+	case t.queue <- span:
+	default:
+		util.Log().Debug("Trace queue is full, span %q dropped.", span.Name)
+	}
+}
+
+// Shutdown 停止收集，并导出队列中剩余的 Span
+func (t *Tracer) Shutdown() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+	t.mu.Unlock()
+
+	<-t.done
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		if err := t.exporter.Export(batch); err != nil {
+			util.Log().Warning("Failed to export %d spans: %s", len(batch), err)
+		}
+		batch = make([]*Span, 0, batchSize)
+	}
+
+	for {
+		select {
+		case span, ok := <-t.queue:
+			if !ok {
+				flush()
+				return
+			}
+
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// StartSpan 在请求的追踪中开始子 Span，未启用追踪导出或 trace 为空时返回 nil
+func StartSpan(trace *util.RequestTrace, name string, kind int) *Span {
+	if !Enabled() || trace == nil || trace.TraceID == "" {
+		return nil
+	}
+
+	return &Span{
+		TraceID:      trace.TraceID,
+		SpanID:       NewSpanID(),
+		ParentSpanID: trace.SpanID,
+		Name:         name,
+		Kind:         kind,
+		Start:        time.Now(),
+		Attributes:   make(map[string]interface{}),
+	}
+}
+
+// Finish 结束 Span 并交由全局收集器导出，span 为 nil 时忽略
+func (span *Span) Finish(failed bool) {
+	if span == nil {
+		return
+	}
+
+	span.End = time.Now()
+	span.Failed = failed
+	Default.Record(span)
+}
+
+// NewTraceID 生成随机的 Trace ID
+func NewTraceID() string {
+	return randomHex(16)
+}
+
+// NewSpanID 生成随机的 Span ID
+func NewSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Traceparent 生成 W3C Trace Context 的 traceparent 头
+func Traceparent(traceID, spanID string) string {
+	return "00-" + traceID + "-" + spanID + "-01"
+}
+
+// ParseTraceparent 解析 traceparent 头，返回上游的 Trace ID 及 Span ID
+func ParseTraceparent(header string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return "", "", false
+	}
+
+	traceID, spanID = strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !validID(traceID, 32) || !validID(spanID, 16) {
+		return "", "", false
+	}
+
+	return traceID, spanID, true
+}
+
+// validID 检查 ID 是否为给定长度、且不全为 0 的十六进制字符串
+func validID(id string, length int) bool {
+	if len(id) != length {
+		return false
+	}
+
+	if _, err := hex.DecodeString(id); err != nil {
+		return false
+	}
+
+	return strings.Trim(id, "0") != ""
+}
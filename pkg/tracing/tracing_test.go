@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type exporterMock struct {
+	mu    sync.Mutex
+	spans []*Span
+	err   error
+}
+
+func (e *exporterMock) Export(spans []*Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return e.err
+}
+
+func TestParseTraceparent(t *testing.T) {
+	a := assert.New(t)
+
+	traceID, spanID, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	a.True(ok)
+	a.Equal("4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	a.Equal("00f067aa0ba902b7", spanID)
+
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+	} {
+		_, _, ok := ParseTraceparent(header)
+		a.False(ok, header)
+	}
+
+	traceID, spanID = NewTraceID(), NewSpanID()
+	a.Len(traceID, 32)
+	a.Len(spanID, 16)
+	parsedTrace, parsedSpan, ok := ParseTraceparent(Traceparent(traceID, spanID))
+	a.True(ok)
+	a.Equal(traceID, parsedTrace)
+	a.Equal(spanID, parsedSpan)
+}
+
+func TestTracer(t *testing.T) {
+	a := assert.New(t)
+	exporter := &exporterMock{err: errors.New("error")}
+	Default = NewTracer(exporter)
+	defer func() { Default = nil }()
+
+	trace := &util.RequestTrace{RequestID: "req", TraceID: NewTraceID(), SpanID: NewSpanID()}
+	a.Nil(StartSpan(nil, "test", SpanKindClient))
+
+	span := StartSpan(trace, "test", SpanKindClient)
+	a.Equal(trace.TraceID, span.TraceID)
+	a.Equal(trace.SpanID, span.ParentSpanID)
+	a.NotEqual(trace.SpanID, span.SpanID)
+	span.Finish(true)
+
+	// 关闭时导出队列中剩余的 Span，之后记录的 Span 被忽略
+	Default.Shutdown()
+	Default.Shutdown()
+	StartSpan(trace, "closed", SpanKindClient).Finish(false)
+	a.Len(exporter.spans, 1)
+	a.True(exporter.spans[0].Failed)
+	a.False(exporter.spans[0].End.IsZero())
+}
+
+func TestTracer_Disabled(t *testing.T) {
+	a := assert.New(t)
+	a.False(Enabled())
+	a.Nil(StartSpan(&util.RequestTrace{TraceID: NewTraceID()}, "test", SpanKindClient))
+	a.NotPanics(func() {
+		var span *Span
+		span.Finish(false)
+		Default.Shutdown()
+	})
+
+	Init("", "")
+	a.False(Enabled())
+}
+
+func TestOTLPExporter_Export(t *testing.T) {
+	a := assert.New(t)
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Equal("/v1/traces", r.URL.Path)
+		a.Equal("application/json", r.Header.Get("Content-Type"))
+		a.NoError(json.NewDecoder(r.Body).Decode(&payload))
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL+"/", "")
+	start := time.Unix(1, 0)
+	a.NoError(exporter.Export([]*Span{{
+		TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:     "00f067aa0ba902b7",
+		Name:       "GET /api/v3/site/ping",
+		Kind:       SpanKindServer,
+		Start:      start,
+		End:        start.Add(time.Second),
+		Attributes: map[string]interface{}{"http.status_code": 200, "http.method": "GET", "ok": true},
+		Failed:     true,
+	}}))
+
+	resource := payload["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	a.Contains(resource["resource"].(map[string]interface{})["attributes"], map[string]interface{}{
+		"key":   "service.name",
+		"value": map[string]interface{}{"stringValue": "cloudreve"},
+	})
+	span := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+	a.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span["traceId"])
+	a.Equal("1000000000", span["startTimeUnixNano"])
+	a.Equal("2000000000", span["endTimeUnixNano"])
+	a.EqualValues(2, span["status"].(map[string]interface{})["code"])
+	a.Equal([]interface{}{
+		map[string]interface{}{"key": "http.method", "value": map[string]interface{}{"stringValue": "GET"}},
+		map[string]interface{}{"key": "http.status_code", "value": map[string]interface{}{"intValue": "200"}},
+		map[string]interface{}{"key": "ok", "value": map[string]interface{}{"boolValue": true}},
+	}, span["attributes"])
+
+	// 接收端返回错误
+	failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Equal("/otlp/v1/traces", r.URL.Path)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failed.Close()
+	a.Error(NewOTLPExporter(failed.URL+"/otlp/v1/traces", "svc").Export(nil))
+	a.Error(NewOTLPExporter("http://127.0.0.1:1", "svc").Export(nil))
+}
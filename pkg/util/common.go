@@ -58,6 +58,28 @@ func ContainsString(s []string, e string) bool {
 	return false
 }
 
+// MatchEmailDomain 返回邮箱是否属于给定的域名或其子域名，域名不区分大小写
+func MatchEmailDomain(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	host := strings.ToLower(email[at+1:])
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" {
+			continue
+		}
+
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Replace 根据替换表执行批量替换
 func Replace(table map[string]string, s string) string {
 	for key, value := range table {
@@ -40,6 +40,17 @@ func TestContainsString(t *testing.T) {
 	asserts.False(ContainsString([]string{"", "1"}, " "))
 }
 
+func TestMatchEmailDomain(t *testing.T) {
+	asserts := assert.New(t)
+	domains := []string{"university.edu", " @Example.com", ""}
+	asserts.True(MatchEmailDomain("a@university.edu", domains))
+	asserts.True(MatchEmailDomain("a@cs.University.edu", domains))
+	asserts.True(MatchEmailDomain("a@example.com", domains))
+	asserts.False(MatchEmailDomain("a@fakeuniversity.edu", domains))
+	asserts.False(MatchEmailDomain("invalid", domains))
+	asserts.False(MatchEmailDomain("a@university.edu", nil))
+}
+
 func TestReplace(t *testing.T) {
 	asserts := assert.New(t)
 
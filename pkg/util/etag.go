@@ -0,0 +1,32 @@
+package util
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotModified 设置响应的 ETag，客户端携带的 If-None-Match 与之匹配时返回 304，
+// 返回值表示是否已经响应，调用方应不再输出响应体
+func NotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	if ETagMatch(c.GetHeader("If-None-Match"), etag) {
+		c.AbortWithStatus(http.StatusNotModified)
+		return true
+	}
+
+	return false
+}
+
+// ETagMatch 使用弱比较判断 If-None-Match 是否包含给定的 ETag
+func ETagMatch(ifNoneMatch, etag string) bool {
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (tag != "" && strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/")) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMatch(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.True(ETagMatch(`W/"1"`, `W/"1"`))
+	asserts.True(ETagMatch(`"1"`, `W/"1"`))
+	asserts.True(ETagMatch(`"2", W/"1"`, `W/"1"`))
+	asserts.True(ETagMatch(`*`, `W/"1"`))
+	asserts.False(ETagMatch(`W/"2"`, `W/"1"`))
+	asserts.False(ETagMatch(``, `W/"1"`))
+}
+
+func TestNotModified(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不匹配
+	{
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		asserts.False(NotModified(c, `W/"1"`))
+		asserts.Equal(`W/"1"`, w.Header().Get("ETag"))
+		asserts.False(c.Writer.Written())
+	}
+
+	// 匹配
+	{
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/", nil)
+		c.Request.Header.Set("If-None-Match", `W/"1"`)
+		asserts.True(NotModified(c, `W/"1"`))
+		asserts.Equal(http.StatusNotModified, w.Code)
+		asserts.True(c.Writer.Written())
+	}
+}
@@ -1,10 +1,16 @@
 package util
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"github.com/fatih/color"
+	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/fatih/color"
 )
 
 const (
@@ -18,13 +24,38 @@ const (
 	LevelDebug
 )
 
+const (
+	// LogFormatText 带颜色的文本格式
+	LogFormatText = "text"
+	// LogFormatJSON 每行一个 JSON 对象，便于日志采集
+	LogFormatJSON = "json"
+)
+
 var GloablLogger *Logger
 var Level = LevelDebug
 
+// Format 新建日志对象的输出格式
+var Format = LogFormatText
+
+// 日志等级名称，下标即等级
+var levelNames = []string{"error", "warning", "info", "debug"}
+
 // Logger 日志
 type Logger struct {
-	level int
-	mu    sync.Mutex
+	level  int32
+	mu     sync.Mutex
+	format string
+	out    io.Writer
+	// root 派生出此日志对象的根日志对象，日志等级、输出格式均以根日志对象为准
+	root *Logger
+	// fields 附加到每条日志的结构化字段
+	fields []LogField
+}
+
+// LogField 日志的结构化字段
+type LogField struct {
+	Key   string
+	Value interface{}
 }
 
 // 日志颜色
@@ -45,28 +76,85 @@ var spaces = map[string]string{
 	"Debug":   "  ",
 }
 
+// base 返回根日志对象
+func (ll *Logger) base() *Logger {
+	if ll.root != nil {
+		return ll.root
+	}
+	return ll
+}
+
+// enabled 返回是否输出给定等级的日志
+func (ll *Logger) enabled(level int) bool {
+	return level <= int(atomic.LoadInt32(&ll.base().level))
+}
+
+// WithField 返回附加了结构化字段的日志对象
+func (ll *Logger) WithField(key string, value interface{}) *Logger {
+	fields := make([]LogField, len(ll.fields), len(ll.fields)+1)
+	copy(fields, ll.fields)
+	return &Logger{
+		root:   ll.base(),
+		fields: append(fields, LogField{Key: key, Value: value}),
+	}
+}
+
 // Println 打印
 func (ll *Logger) Println(prefix string, msg string) {
-	// TODO Release时去掉
-	// color.NoColor = false
+	root := ll.base()
+	root.mu.Lock()
+	defer root.mu.Unlock()
 
-	c := color.New()
+	out := root.out
+	if out == nil {
+		out = color.Output
+	}
 
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
+	if root.format == LogFormatJSON {
+		_, _ = out.Write(append(ll.jsonLine(prefix, msg), '\n'))
+		return
+	}
+
+	var fields strings.Builder
+	for _, field := range ll.fields {
+		fmt.Fprintf(&fields, " %s=%v", field.Key, field.Value)
+	}
 
-	_, _ = c.Printf(
-		"%s%s %s %s\n",
+	_, _ = fmt.Fprintf(
+		out,
+		"%s%s %s %s%s\n",
 		colors[prefix]("["+prefix+"]"),
 		spaces[prefix],
 		time.Now().Format("2006-01-02 15:04:05"),
 		msg,
+		fields.String(),
 	)
 }
 
+// jsonLine 将日志编码为 JSON，字段无法编码时以文本形式输出
+func (ll *Logger) jsonLine(prefix string, msg string) []byte {
+	entry := make(map[string]interface{}, len(ll.fields)+3)
+	for _, field := range ll.fields {
+		entry[field.Key] = field.Value
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = strings.ToLower(prefix)
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		for _, field := range ll.fields {
+			entry[field.Key] = fmt.Sprint(field.Value)
+		}
+		line, _ = json.Marshal(entry)
+	}
+
+	return line
+}
+
 // Panic 极端错误
 func (ll *Logger) Panic(format string, v ...interface{}) {
-	if LevelError > ll.level {
+	if !ll.enabled(LevelError) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -76,7 +164,7 @@ func (ll *Logger) Panic(format string, v ...interface{}) {
 
 // Error 错误
 func (ll *Logger) Error(format string, v ...interface{}) {
-	if LevelError > ll.level {
+	if !ll.enabled(LevelError) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -85,7 +173,7 @@ func (ll *Logger) Error(format string, v ...interface{}) {
 
 // Warning 警告
 func (ll *Logger) Warning(format string, v ...interface{}) {
-	if LevelWarning > ll.level {
+	if !ll.enabled(LevelWarning) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -94,7 +182,7 @@ func (ll *Logger) Warning(format string, v ...interface{}) {
 
 // Info 信息
 func (ll *Logger) Info(format string, v ...interface{}) {
-	if LevelInformational > ll.level {
+	if !ll.enabled(LevelInformational) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -103,7 +191,7 @@ func (ll *Logger) Info(format string, v ...interface{}) {
 
 // Debug 校验
 func (ll *Logger) Debug(format string, v ...interface{}) {
-	if LevelDebug > ll.level {
+	if !ll.enabled(LevelDebug) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -119,21 +207,26 @@ func (ll *Logger) Debug(format string, v ...interface{}) {
 //	ll.Println(msg)
 //}
 
+// ParseLogLevel 解析日志等级名称
+func ParseLogLevel(level string) (int, error) {
+	for i, name := range levelNames {
+		if name == level {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown log level %q", level)
+}
+
 // BuildLogger 构建logger
 func BuildLogger(level string) {
-	intLevel := LevelError
-	switch level {
-	case "error":
+	intLevel, err := ParseLogLevel(level)
+	if err != nil {
 		intLevel = LevelError
-	case "warning":
-		intLevel = LevelWarning
-	case "info":
-		intLevel = LevelInformational
-	case "debug":
-		intLevel = LevelDebug
 	}
 	l := Logger{
-		level: intLevel,
+		level:  int32(intLevel),
+		format: Format,
 	}
 	GloablLogger = &l
 }
@@ -142,9 +235,60 @@ func BuildLogger(level string) {
 func Log() *Logger {
 	if GloablLogger == nil {
 		l := Logger{
-			level: Level,
+			level:  int32(Level),
+			format: Format,
 		}
 		GloablLogger = &l
 	}
 	return GloablLogger
 }
+
+// LogLevel 返回全局日志对象当前的日志等级名称
+func LogLevel() string {
+	level := int(atomic.LoadInt32(&Log().level))
+	if level < 0 || level >= len(levelNames) {
+		return levelNames[LevelError]
+	}
+	return levelNames[level]
+}
+
+// SetLogLevel 在运行时调整全局日志对象的日志等级
+func SetLogLevel(level string) error {
+	intLevel, err := ParseLogLevel(level)
+	if err != nil {
+		return err
+	}
+
+	Level = intLevel
+	atomic.StoreInt32(&Log().level, int32(intLevel))
+	return nil
+}
+
+// SetLogFormat 设置全局日志对象的输出格式
+func SetLogFormat(format string) error {
+	if format != LogFormatText && format != LogFormatJSON {
+		return fmt.Errorf("unknown log format %q", format)
+	}
+
+	Format = format
+	l := Log()
+	l.mu.Lock()
+	l.format = format
+	l.mu.Unlock()
+	return nil
+}
+
+// LogCtx 返回附加了上下文中请求 ID 和追踪 ID 的全局日志对象
+func LogCtx(ctx context.Context) *Logger {
+	trace := TraceFromContext(ctx)
+	if trace == nil {
+		return Log()
+	}
+
+	l := Log().WithField("request_id", trace.RequestID)
+	if trace.TraceID != "" {
+		l = l.WithField("trace_id", trace.TraceID)
+	}
+
+	return l
+}
@@ -3,6 +3,9 @@
 package util
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -101,3 +104,85 @@ func TestLogger_Panic(t *testing.T) {
 		l.Error("123")
 	})
 }
+
+func TestLogger_JSON(t *testing.T) {
+	asserts := assert.New(t)
+	var buf bytes.Buffer
+	l := Logger{
+		level:  LevelDebug,
+		format: LogFormatJSON,
+		out:    &buf,
+	}
+
+	l.WithField("request_id", "abc").WithField("size", 1).Warning("hello %s", "world")
+	var entry map[string]interface{}
+	asserts.NoError(json.Unmarshal(buf.Bytes(), &entry))
+	asserts.Equal("warning", entry["level"])
+	asserts.Equal("hello world", entry["msg"])
+	asserts.Equal("abc", entry["request_id"])
+	asserts.EqualValues(1, entry["size"])
+	asserts.NotEmpty(entry["time"])
+
+	// 无法编码的字段
+	buf.Reset()
+	l.WithField("ch", make(chan int)).Info("123")
+	asserts.NoError(json.Unmarshal(buf.Bytes(), &entry))
+	asserts.IsType("", entry["ch"])
+
+	// 派生的日志对象跟随根日志对象的等级
+	buf.Reset()
+	derived := l.WithField("a", "b")
+	l.level = LevelError
+	derived.Info("123")
+	asserts.Empty(buf.String())
+}
+
+func TestLogger_TextFields(t *testing.T) {
+	asserts := assert.New(t)
+	var buf bytes.Buffer
+	l := Logger{
+		level: LevelDebug,
+		out:   &buf,
+	}
+
+	l.WithField("request_id", "abc").Info("hello")
+	asserts.Contains(buf.String(), "hello request_id=abc\n")
+}
+
+func TestSetLogLevel(t *testing.T) {
+	asserts := assert.New(t)
+	GloablLogger = nil
+	defer func() { GloablLogger = nil }()
+
+	asserts.Error(SetLogLevel("verbose"))
+	asserts.NoError(SetLogLevel("warning"))
+	asserts.Equal("warning", LogLevel())
+	asserts.False(Log().enabled(LevelInformational))
+	asserts.NoError(SetLogLevel("debug"))
+	asserts.Equal("debug", LogLevel())
+	asserts.True(Log().enabled(LevelDebug))
+}
+
+func TestSetLogFormat(t *testing.T) {
+	asserts := assert.New(t)
+	GloablLogger = nil
+	defer func() {
+		Format = LogFormatText
+		GloablLogger = nil
+	}()
+
+	asserts.Error(SetLogFormat("xml"))
+	asserts.NoError(SetLogFormat(LogFormatJSON))
+	asserts.Equal(LogFormatJSON, Log().format)
+}
+
+func TestLogCtx(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无追踪信息
+	asserts.Equal(Log(), LogCtx(context.Background()))
+
+	ctx := WithRequestTrace(context.Background(), &RequestTrace{RequestID: "req", TraceID: "trace"})
+	l := LogCtx(ctx)
+	asserts.Equal([]LogField{{"request_id", "req"}, {"trace_id", "trace"}}, l.fields)
+}
@@ -0,0 +1,56 @@
+package util
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTraceKey 请求追踪信息在 gin 上下文中的键
+const RequestTraceKey = "request_trace"
+
+type requestTraceCtx struct{}
+
+// RequestTrace 请求的追踪信息，随上下文传递至文件系统及存储驱动的调用中
+type RequestTrace struct {
+	// RequestID 请求 ID，会在日志及响应头中返回
+	RequestID string
+	// TraceID、SpanID 分布式追踪中当前请求所在的 Trace 及 Span
+	TraceID string
+	SpanID  string
+}
+
+// WithRequestTrace 返回携带请求追踪信息的上下文
+func WithRequestTrace(ctx context.Context, trace *RequestTrace) context.Context {
+	return context.WithValue(ctx, requestTraceCtx{}, trace)
+}
+
+// TraceFromContext 从上下文或 gin 上下文中取得请求追踪信息，不存在时返回 nil
+func TraceFromContext(ctx context.Context) *RequestTrace {
+	if c, ok := ctx.(*gin.Context); ok {
+		if c == nil {
+			return nil
+		}
+
+		value, _ := c.Get(RequestTraceKey)
+		trace, _ := value.(*RequestTrace)
+		return trace
+	}
+
+	if ctx == nil {
+		return nil
+	}
+
+	trace, _ := ctx.Value(requestTraceCtx{}).(*RequestTrace)
+	return trace
+}
+
+// RequestContext 返回携带请求追踪信息的新上下文，不会随请求结束而取消
+func RequestContext(c *gin.Context) context.Context {
+	ctx := context.Background()
+	if trace := TraceFromContext(c); trace != nil {
+		ctx = WithRequestTrace(ctx, trace)
+	}
+
+	return ctx
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceFromContext(t *testing.T) {
+	asserts := assert.New(t)
+	trace := &RequestTrace{RequestID: "req"}
+
+	// 空上下文
+	asserts.Nil(TraceFromContext(nil))
+	asserts.Nil(TraceFromContext(context.Background()))
+	asserts.Nil(TraceFromContext((*gin.Context)(nil)))
+
+	// 普通上下文
+	asserts.Equal(trace, TraceFromContext(WithRequestTrace(context.Background(), trace)))
+
+	// gin 上下文
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	asserts.Nil(TraceFromContext(c))
+	c.Set(RequestTraceKey, trace)
+	asserts.Equal(trace, TraceFromContext(c))
+}
+
+func TestRequestContext(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	// 无追踪信息
+	asserts.Nil(TraceFromContext(RequestContext(c)))
+
+	trace := &RequestTrace{RequestID: "req"}
+	c.Set(RequestTraceKey, trace)
+	ctx := RequestContext(c)
+	asserts.Equal(trace, TraceFromContext(ctx))
+	asserts.Nil(ctx.Done())
+}
@@ -0,0 +1,54 @@
+package util
+
+import "strings"
+
+// userAgentBrowsers 浏览器及客户端特征，按匹配优先级排列
+var userAgentBrowsers = []struct{ keyword, name string }{
+	{"Cloudreve", "Cloudreve"},
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+}
+
+// userAgentSystems 操作系统特征，按匹配优先级排列
+var userAgentSystems = []struct{ keyword, name string }{
+	{"Windows", "Windows"},
+	{"Android", "Android"},
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "Chrome OS"},
+	{"Linux", "Linux"},
+}
+
+// DescribeUserAgent 根据 User-Agent 返回便于识别的设备描述，如 "Chrome on Windows"，
+// 无法识别时返回空字符串
+func DescribeUserAgent(ua string) string {
+	browser, system := "", ""
+	for _, b := range userAgentBrowsers {
+		if strings.Contains(ua, b.keyword) {
+			browser = b.name
+			break
+		}
+	}
+
+	for _, s := range userAgentSystems {
+		if strings.Contains(ua, s.keyword) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	default:
+		return system
+	}
+}
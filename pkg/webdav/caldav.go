@@ -0,0 +1,398 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	ixml "github.com/cloudreve/Cloudreve/v3/pkg/webdav/internal/xml"
+)
+
+// CalDAV/CardDAV 处理器的集合类型
+const (
+	CollectionCalendar    = "calendar"
+	CollectionAddressbook = "addressbook"
+)
+
+const (
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCardDAV        = "urn:ietf:params:xml:ns:carddav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// collectionCtx 请求上下文中的 CalDAV/CardDAV 处理器
+type collectionCtx struct{}
+
+// collectionProp CalDAV/CardDAV 处理器额外支持的属性，优先于 liveProps
+type collectionProp struct {
+	// kind 适用的集合类型，为空时适用于所有类型
+	kind string
+	// applies 属性是否适用于给定资源
+	applies func(fs *filesystem.FileSystem, fi FileInfo) bool
+	findFn  func(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error)
+	// hidden 是否在 propname、allprop 中隐藏，用于需要读取文件内容的属性
+	hidden bool
+}
+
+var collectionProps = map[xml.Name]collectionProp{
+	{Space: "DAV:", Local: "current-user-principal"}: {
+		applies: anyResource,
+		findFn:  findHomeHref,
+	},
+	{Space: "DAV:", Local: "principal-URL"}: {
+		applies: anyResource,
+		findFn:  findHomeHref,
+	},
+	{Space: "DAV:", Local: "resourcetype"}: {
+		applies: isCollection,
+		findFn:  findCollectionType,
+	},
+	{Space: "DAV:", Local: "getcontenttype"}: {
+		applies: isObject,
+		findFn:  findObjectType,
+	},
+	{Space: nsCalDAV, Local: "calendar-home-set"}: {
+		kind:    CollectionCalendar,
+		applies: anyResource,
+		findFn:  findHomeHref,
+	},
+	{Space: nsCardDAV, Local: "addressbook-home-set"}: {
+		kind:    CollectionAddressbook,
+		applies: anyResource,
+		findFn:  findHomeHref,
+	},
+	{Space: nsCalDAV, Local: "supported-calendar-component-set"}: {
+		kind:    CollectionCalendar,
+		applies: isCollection,
+		findFn: func(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+			return `<C:comp xmlns:C="` + nsCalDAV + `" name="VEVENT"/>` +
+				`<C:comp xmlns:C="` + nsCalDAV + `" name="VTODO"/>`, nil
+		},
+	},
+	{Space: nsCardDAV, Local: "supported-address-data"}: {
+		kind:    CollectionAddressbook,
+		applies: isCollection,
+		findFn: func(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+			return `<CR:address-data-type xmlns:CR="` + nsCardDAV + `" content-type="text/vcard" version="3.0"/>`, nil
+		},
+	},
+	{Space: nsCalendarServer, Local: "getctag"}: {
+		applies: isCollection,
+		findFn:  findCTag,
+	},
+	{Space: nsCalDAV, Local: "calendar-data"}: {
+		kind:    CollectionCalendar,
+		applies: isReadableObject,
+		findFn:  findObjectData,
+		hidden:  true,
+	},
+	{Space: nsCardDAV, Local: "address-data"}: {
+		kind:    CollectionAddressbook,
+		applies: isReadableObject,
+		findFn:  findObjectData,
+		hidden:  true,
+	},
+}
+
+// findCollectionProp 查找 CalDAV/CardDAV 处理器额外支持的属性，属性不适用时返回 false
+func findCollectionProp(ctx context.Context, fs *filesystem.FileSystem, pn xml.Name, fi FileInfo) (string, bool, error) {
+	h, ok := ctx.Value(collectionCtx{}).(*Handler)
+	if !ok {
+		return "", false, nil
+	}
+
+	prop, ok := collectionProps[pn]
+	if !ok || (prop.kind != "" && prop.kind != h.Collection) || !prop.applies(fs, fi) {
+		return "", false, nil
+	}
+
+	innerXML, err := prop.findFn(ctx, fs, h, fi)
+	return innerXML, err == nil, err
+}
+
+// collectionPropnames 列出适用于资源且不隐藏的 CalDAV/CardDAV 属性
+func collectionPropnames(ctx context.Context, fs *filesystem.FileSystem, fi FileInfo) []xml.Name {
+	h, ok := ctx.Value(collectionCtx{}).(*Handler)
+	if !ok {
+		return nil
+	}
+
+	var pnames []xml.Name
+	for pn, prop := range collectionProps {
+		if !prop.hidden && (prop.kind == "" || prop.kind == h.Collection) && prop.applies(fs, fi) {
+			pnames = append(pnames, pn)
+		}
+	}
+	return pnames
+}
+
+func anyResource(fs *filesystem.FileSystem, fi FileInfo) bool {
+	return true
+}
+
+// isCollection 资源是否为日历或通讯录，即集合根目录下的一级目录
+func isCollection(fs *filesystem.FileSystem, fi FileInfo) bool {
+	folder, ok := modelFolder(fi)
+	return ok && fs.Root != nil && folder.ParentID != nil && *folder.ParentID == fs.Root.ID
+}
+
+// isObject 资源是否为日程或联系人文件
+func isObject(fs *filesystem.FileSystem, fi FileInfo) bool {
+	_, ok := modelFile(fi)
+	return ok
+}
+
+// isReadableObject 资源是否为可读取内容的日程或联系人文件，文件大小不能超过在线编辑的限制
+func isReadableObject(fs *filesystem.FileSystem, fi FileInfo) bool {
+	file, ok := modelFile(fi)
+	return ok && file.Size <= uint64(model.GetIntSetting("maxEditSize", 52428800))
+}
+
+func modelFolder(fi FileInfo) (*model.Folder, bool) {
+	switch folder := fi.(type) {
+	case *model.Folder:
+		return folder, true
+	case *FolderDeadProps:
+		return folder.Folder, true
+	}
+	return nil, false
+}
+
+func modelFile(fi FileInfo) (*model.File, bool) {
+	switch file := fi.(type) {
+	case *model.File:
+		return file, true
+	case *FileDeadProps:
+		return file.File, true
+	}
+	return nil, false
+}
+
+func findHomeHref(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+	href := (&url.URL{Path: h.Prefix + "/"}).EscapedPath()
+	return `<D:href xmlns:D="DAV:">` + escape(href) + `</D:href>`, nil
+}
+
+func findCollectionType(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+	if h.Collection == CollectionCalendar {
+		return `<D:collection xmlns:D="DAV:"/><C:calendar xmlns:C="` + nsCalDAV + `"/>`, nil
+	}
+	return `<D:collection xmlns:D="DAV:"/><CR:addressbook xmlns:CR="` + nsCardDAV + `"/>`, nil
+}
+
+func findObjectType(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+	if h.Collection == CollectionCalendar {
+		return "text/calendar; charset=utf-8", nil
+	}
+	return "text/vcard; charset=utf-8", nil
+}
+
+// findCTag 根据集合内文件的数量与最近修改时间生成集合标签，内容变化时标签随之改变
+func findCTag(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+	folder, _ := modelFolder(fi)
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return "", err
+	}
+
+	latest := folder.ModTime()
+	for i := range files {
+		if files[i].ModTime().After(latest) {
+			latest = files[i].ModTime()
+		}
+	}
+
+	return fmt.Sprintf("%x-%x", latest.UnixNano(), len(files)), nil
+}
+
+// findObjectData 读取日程或联系人文件的内容
+func findObjectData(ctx context.Context, fs *filesystem.FileSystem, h *Handler, fi FileInfo) (string, error) {
+	file, _ := modelFile(fi)
+	fs.CleanTargets()
+	defer fs.CleanTargets()
+	fs.SetTargetFile(&[]model.File{*file})
+
+	rs, err := fs.GetContent(ctx, file.ID)
+	if err != nil {
+		return "", err
+	}
+	defer rs.Close()
+
+	content, err := io.ReadAll(rs)
+	if err != nil {
+		return "", err
+	}
+
+	return escape(string(content)), nil
+}
+
+// report CalDAV/CardDAV REPORT 请求，支持 calendar-query、calendar-multiget、
+// addressbook-query、addressbook-multiget
+type report struct {
+	XMLName ixml.Name
+	Prop    reportProps `xml:"DAV: prop"`
+	Hrefs   []string    `xml:"DAV: href"`
+}
+
+// reportProps REPORT 请求的属性列表，与 propfindProps 不同的是允许属性包含子元素，
+// 如 calendar-data 中用于筛选组件的 comp 元素，子元素会被忽略
+type reportProps []xml.Name
+
+func (pn *reportProps) UnmarshalXML(d *ixml.Decoder, start ixml.StartElement) error {
+	for {
+		t, err := next(d)
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case ixml.EndElement:
+			return nil
+		case ixml.StartElement:
+			*pn = append(*pn, xml.Name(t.Name))
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func readReport(r io.Reader) (rp report, status int, err error) {
+	if err = ixml.NewDecoder(r).Decode(&rp); err != nil {
+		return report{}, http.StatusBadRequest, errInvalidReport
+	}
+
+	switch rp.XMLName {
+	case ixml.Name{Space: nsCalDAV, Local: "calendar-query"}, ixml.Name{Space: nsCalDAV, Local: "calendar-multiget"},
+		ixml.Name{Space: nsCardDAV, Local: "addressbook-query"}, ixml.Name{Space: nsCardDAV, Local: "addressbook-multiget"}:
+		return rp, 0, nil
+	}
+
+	return report{}, http.StatusNotImplemented, errUnsupportedReport
+}
+
+// handleReport 处理 REPORT 请求。query 类请求不处理筛选条件，返回集合内的所有对象，
+// 由客户端自行筛选
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
+	defer fs.Recycle()
+
+	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	if err != nil {
+		return status, err
+	}
+
+	ctx := r.Context()
+	ok, fi := isPathExist(ctx, fs, reqPath)
+	if !ok {
+		return http.StatusNotFound, nil
+	}
+
+	rp, status, err := readReport(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	mw := multistatusWriter{w: w}
+	writeObject := func(objectPath string, info FileInfo) error {
+		pstats, err := props(ctx, fs, ls, info, rp.Prop)
+		if err != nil {
+			return err
+		}
+		return mw.write(makePropstatResponse(path.Join(h.Prefix, objectPath), pstats))
+	}
+
+	switch rp.XMLName.Local {
+	case "calendar-multiget", "addressbook-multiget":
+		for _, href := range rp.Hrefs {
+			if err = h.writeMultigetObject(ctx, fs, href, &mw, writeObject); err != nil {
+				break
+			}
+		}
+	default:
+		if !fi.IsDir() {
+			err = writeObject(reqPath, fi)
+			break
+		}
+
+		files, _ := fi.(*model.Folder).GetChildFiles()
+		for i := range files {
+			if err = writeObject(path.Join(reqPath, files[i].Name), &files[i]); err != nil {
+				break
+			}
+		}
+	}
+
+	closeErr := mw.close()
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	if closeErr != nil {
+		return http.StatusInternalServerError, closeErr
+	}
+	return 0, nil
+}
+
+// writeMultigetObject 写入 multiget 请求的单个对象，对象不存在时写入 404 状态
+func (h *Handler) writeMultigetObject(ctx context.Context, fs *filesystem.FileSystem, href string, mw *multistatusWriter,
+	writeObject func(objectPath string, info FileInfo) error) error {
+	objectPath := href
+	if u, err := url.Parse(href); err == nil {
+		objectPath = u.Path
+	}
+
+	if objectPath, _, err := h.stripPrefix(objectPath, fs.User.ID); err == nil {
+		if ok, info := isPathExist(ctx, fs, objectPath); ok && !info.IsDir() {
+			return writeObject(objectPath, info)
+		}
+	}
+
+	return mw.write(&response{
+		Href:   []string{href},
+		Status: fmt.Sprintf("HTTP/1.1 %d %s", http.StatusNotFound, StatusText(http.StatusNotFound)),
+	})
+}
+
+// handleMkcalendar 处理 MKCALENDAR 请求，日历只能创建在集合根目录下，请求中的属性被忽略
+func (h *Handler) handleMkcalendar(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+	defer fs.Recycle()
+
+	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	if err != nil {
+		return status, err
+	}
+
+	if path.Dir(reqPath) != "/" {
+		return http.StatusForbidden, errInvalidCollection
+	}
+
+	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	if err != nil {
+		return status, err
+	}
+	defer release()
+
+	if exist, _ := isPathExist(r.Context(), fs, reqPath); exist {
+		return http.StatusMethodNotAllowed, nil
+	}
+
+	if _, err := fs.CreateDirectory(r.Context(), reqPath); err != nil {
+		return http.StatusConflict, err
+	}
+	return http.StatusCreated, nil
+}
+
+// collectionCompliance 返回 DAV 响应头中集合类型对应的兼容等级
+func (h *Handler) collectionCompliance() string {
+	switch h.Collection {
+	case CollectionCalendar:
+		return ", calendar-access"
+	case CollectionAddressbook:
+		return ", addressbook"
+	}
+	return ""
+}
@@ -0,0 +1,194 @@
+package webdav
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/gofrs/uuid"
+	"github.com/jinzhu/gorm"
+)
+
+// maxLockDuration 锁的最长有效期，客户端请求永不过期或更长的锁时使用此值，
+// 避免客户端异常退出后资源被长期锁定
+const maxLockDuration = time.Hour
+
+// tempLockDuration 请求期间创建的临时锁的有效期，进程异常退出时临时锁在此之后自动失效
+const tempLockDuration = time.Minute
+
+// dbLS 基于数据库的 LockSystem，每个用户的锁相互独立。锁保存在数据库中，
+// Confirm 在请求期间不会独占锁，返回的 release 不做任何操作。
+// 锁以用户文件系统中的绝对路径保存，根目录不同的应用密码、CalDAV/CardDAV 之间的锁互相可见
+type dbLS struct {
+	uid  uint
+	root *model.Folder
+	// 根目录的完整路径，首次使用时计算
+	prefix string
+}
+
+// NewDBLS 返回文件系统所有者的数据库 LockSystem
+func NewDBLS(fs *filesystem.FileSystem) LockSystem {
+	return &dbLS{uid: fs.User.ID, root: fs.Root}
+}
+
+// rootPath 返回根目录在用户文件系统中的完整路径
+func (l *dbLS) rootPath() (string, error) {
+	if l.root == nil {
+		return "/", nil
+	}
+
+	if l.prefix == "" {
+		// 文件系统中的根目录已被重定为 "/"，需重新查找其完整路径
+		folders, err := model.GetFoldersByIDs([]uint{l.root.ID}, l.root.OwnerID)
+		if err != nil {
+			return "", err
+		}
+		if len(folders) == 0 {
+			return "", filesystem.ErrPathNotExist
+		}
+
+		if err := folders[0].TraceRoot(); err != nil {
+			return "", err
+		}
+		l.prefix = path.Join(folders[0].Position, folders[0].Name)
+	}
+
+	return l.prefix, nil
+}
+
+// absPath 将相对根目录的 name 转换为绝对路径
+func (l *dbLS) absPath(name string) (string, error) {
+	prefix, err := l.rootPath()
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(prefix, slashClean(name)), nil
+}
+
+// relPath 将锁的绝对路径转换为相对根目录的路径，根目录之外的锁原样返回
+func (l *dbLS) relPath(name string) string {
+	prefix, err := l.rootPath()
+	if err != nil || prefix == "/" {
+		return name
+	}
+
+	if name == prefix {
+		return "/"
+	}
+
+	if strings.HasPrefix(name, prefix+"/") {
+		return strings.TrimPrefix(name, prefix)
+	}
+
+	return name
+}
+
+// Confirm 检查给定的条件是否持有作用于 name0、name1 的锁，没有锁作用于资源时视为通过
+func (l *dbLS) Confirm(now time.Time, name0, name1 string, conditions ...Condition) (func(), error) {
+	locks, err := model.GetActiveWebdavLocks(l.uid, now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{name0, name1} {
+		if name == "" {
+			continue
+		}
+
+		name, err := l.absPath(name)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range locks {
+			if locks[i].Covers(name) && !holdsToken(conditions, locks[i].Token) {
+				return nil, ErrConfirmationFailed
+			}
+		}
+	}
+
+	return func() {}, nil
+}
+
+func (l *dbLS) Create(now time.Time, details LockDetails) (string, error) {
+	root, err := l.absPath(details.Root)
+	if err != nil {
+		return "", err
+	}
+
+	lock := &model.WebdavLock{
+		Token:     "opaquelocktoken:" + uuid.Must(uuid.NewV4()).String(),
+		UserID:    l.uid,
+		Root:      root,
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		ExpiresAt: lockExpiry(now, details.Duration),
+	}
+
+	if err := model.CreateWebdavLock(lock, now); err != nil {
+		if err == model.ErrWebdavLocked {
+			return "", ErrLocked
+		}
+		return "", err
+	}
+
+	return lock.Token, nil
+}
+
+func (l *dbLS) Refresh(now time.Time, token string, duration time.Duration) (LockDetails, error) {
+	lock, err := model.GetActiveWebdavLockByToken(l.uid, token, now)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return LockDetails{}, ErrNoSuchLock
+		}
+		return LockDetails{}, err
+	}
+
+	if err := lock.Refresh(lockExpiry(now, duration)); err != nil {
+		return LockDetails{}, err
+	}
+
+	return LockDetails{
+		Root:      l.relPath(lock.Root),
+		Duration:  duration,
+		OwnerXML:  lock.OwnerXML,
+		ZeroDepth: lock.ZeroDepth,
+	}, nil
+}
+
+func (l *dbLS) Unlock(now time.Time, token string) error {
+	found, err := model.DeleteWebdavLock(l.uid, token, now)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrNoSuchLock
+	}
+
+	return nil
+}
+
+// holdsToken 条件中是否包含给定的锁令牌
+func holdsToken(conditions []Condition, token string) bool {
+	for _, c := range conditions {
+		if !c.Not && c.Token == token {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lockExpiry 计算锁的过期时间，duration 为负数时返回空
+func lockExpiry(now time.Time, duration time.Duration) *time.Time {
+	if duration < 0 {
+		return nil
+	}
+
+	expiresAt := now.Add(duration)
+	return &expiresAt
+}
@@ -0,0 +1,159 @@
+package webdav
+
+import (
+	"net/http"
+	"path"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+)
+
+// virtualRoot 根目录下的虚拟目录
+func virtualRoot(name string) *model.Folder {
+	folder := &model.Folder{Name: name}
+	folder.UpdatedAt = time.Now()
+	return folder
+}
+
+// serveMounts 处理「与我共享」「团队」等虚拟目录下的请求，请求挂载点内的对象时交由以
+// 挂载目录为根目录的处理器处理。返回 false 表示请求不在虚拟目录下
+func (h *Handler) serveMounts(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) bool {
+	if !h.SharedMount || fs.Root != nil || fs.User.ID == 0 {
+		return false
+	}
+	actor := fs.User
+
+	reqPath, _, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	if err != nil {
+		return false
+	}
+
+	root, name, _, ok := filesystem.SplitVirtualPath(reqPath)
+	if !ok {
+		return false
+	}
+
+	mounts, err := filesystem.ListMounts(root, fs.User.ID)
+	if err != nil {
+		fs.Recycle()
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+
+	// 虚拟目录本身只可列出
+	if name == "" {
+		status := 0
+		switch r.Method {
+		case "OPTIONS":
+			w.Header().Set("Allow", "OPTIONS, PROPFIND")
+			w.Header().Set("DAV", "1, 2")
+			fs.Recycle()
+		case "PROPFIND":
+			status, err = h.handleMountsPropfind(w, r, fs, root, mounts)
+		default:
+			fs.Recycle()
+			status = http.StatusMethodNotAllowed
+		}
+
+		if status != 0 {
+			w.WriteHeader(status)
+			w.Write([]byte(StatusText(status)))
+		}
+		if h.Logger != nil {
+			h.Logger(r, err)
+		}
+		return true
+	}
+
+	fs.Recycle()
+	mount := filesystem.FindMount(mounts, name)
+	if mount == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return true
+	}
+
+	// 增删改由文件系统按目录 ACL 检查，属性修改只依据挂载点的权限
+	if r.Method == "PROPPATCH" && mount.Permissions&model.ACLWrite == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		return true
+	}
+
+	mountFs, err := mount.Open()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return true
+	}
+	mountFs.UseActor(actor, mount.Permissions)
+
+	sub := &Handler{
+		Prefix:     path.Join(h.Prefix, root, name),
+		LockSystem: h.LockSystem,
+		Logger:     h.Logger,
+	}
+	sub.ServeHTTP(w, r, mountFs)
+	return true
+}
+
+// handleMountsPropfind 列出虚拟目录及其下的挂载点
+func (h *Handler) handleMountsPropfind(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, root string, mounts []filesystem.Mount) (int, error) {
+	defer fs.Recycle()
+
+	depth := infiniteDepth
+	if hdr := r.Header.Get("Depth"); hdr != "" {
+		depth = parseDepth(hdr)
+		if depth == invalidDepth {
+			return http.StatusBadRequest, errInvalidDepth
+		}
+	}
+
+	pf, status, err := readPropfind(r.Body)
+	if err != nil {
+		return status, err
+	}
+
+	ls := h.LockSystem(fs)
+	mw := multistatusWriter{w: w}
+	write := func(href string, info FileInfo) error {
+		var pstats []Propstat
+		if pf.Propname != nil {
+			pnames, err := propnames(r.Context(), fs, ls, info)
+			if err != nil {
+				return err
+			}
+			pstat := Propstat{Status: http.StatusOK}
+			for _, xmlname := range pnames {
+				pstat.Props = append(pstat.Props, Property{XMLName: xmlname})
+			}
+			pstats = append(pstats, pstat)
+		} else if pf.Allprop != nil {
+			pstats, err = allprop(r.Context(), fs, ls, info, pf.Prop)
+		} else {
+			pstats, err = props(r.Context(), fs, ls, info, pf.Prop)
+		}
+		if err != nil {
+			return err
+		}
+		return mw.write(makePropstatResponse(href+"/", pstats))
+	}
+
+	href := path.Join(h.Prefix, root)
+	if err := write(href, virtualRoot(root)); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	if depth != 0 {
+		for _, mount := range mounts {
+			folder := *mount.Folder
+			folder.Name = mount.Name
+			if err := write(path.Join(href, mount.Name), &folder); err != nil {
+				return http.StatusInternalServerError, err
+			}
+		}
+	}
+
+	if err := mw.close(); err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return 0, nil
+}
@@ -26,35 +26,21 @@ type FileDeadProps struct {
 
 // 实现 webdav.DeadPropsHolder 接口，不能在models.file里面定义
 func (file *FileDeadProps) DeadProps() (map[xml.Name]Property, error) {
-	return map[xml.Name]Property{
-		xml.Name{Space: "http://owncloud.org/ns", Local: "checksums"}: {
-			XMLName: xml.Name{
-				Space: "http://owncloud.org/ns", Local: "checksums",
-			},
-			InnerXML: []byte("<checksum>" + file.MetadataSerialized[model.ChecksumMetadataKey] + "</checksum>"),
-		},
-	}, nil
+	props, err := loadDeadProps(file.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := xml.Name{Space: "http://owncloud.org/ns", Local: "checksums"}
+	props[checksums] = Property{
+		XMLName:  checksums,
+		InnerXML: []byte("<checksum>" + file.MetadataSerialized[model.ChecksumMetadataKey] + "</checksum>"),
+	}
+	return props, nil
 }
 
 func (file *FileDeadProps) Patch(proppatches []Proppatch) ([]Propstat, error) {
-	var (
-		stat Propstat
-		err  error
-	)
-	stat.Status = http.StatusOK
-	for _, patch := range proppatches {
-		for _, prop := range patch.Props {
-			stat.Props = append(stat.Props, Property{XMLName: prop.XMLName})
-			if prop.XMLName.Space == "DAV:" && prop.XMLName.Local == "lastmodified" {
-				var modtimeUnix int64
-				modtimeUnix, err = strconv.ParseInt(string(prop.InnerXML), 10, 64)
-				if err == nil {
-					err = model.DB.Model(file.File).UpdateColumn("updated_at", time.Unix(modtimeUnix, 0)).Error
-				}
-			}
-		}
-	}
-	return []Propstat{stat}, err
+	return patchDeadProps(file.ID, false, file.File, proppatches)
 }
 
 type FolderDeadProps struct {
@@ -62,28 +48,81 @@ type FolderDeadProps struct {
 }
 
 func (folder *FolderDeadProps) DeadProps() (map[xml.Name]Property, error) {
-	return nil, nil
+	return loadDeadProps(folder.ID, true)
 }
 
 func (folder *FolderDeadProps) Patch(proppatches []Proppatch) ([]Propstat, error) {
+	return patchDeadProps(folder.ID, true, folder.Folder, proppatches)
+}
+
+// loadDeadProps 读取文件或目录保存的自定义属性
+func loadDeadProps(objectID uint, isFolder bool) (map[xml.Name]Property, error) {
+	stored, err := model.GetWebdavProps(objectID, isFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	props := make(map[xml.Name]Property, len(stored)+1)
+	for _, prop := range stored {
+		name := xml.Name{Space: prop.Namespace, Local: prop.Name}
+		props[name] = Property{
+			XMLName:  name,
+			Lang:     prop.Lang,
+			InnerXML: []byte(prop.InnerXML),
+		}
+	}
+	return props, nil
+}
+
+// patchDeadProps 保存文件或目录的自定义属性变更。DAV:lastmodified 用于修改
+// object 的修改时间，不作为自定义属性保存
+func patchDeadProps(objectID uint, isFolder bool, object interface{}, proppatches []Proppatch) ([]Propstat, error) {
 	var (
-		stat Propstat
-		err  error
+		stat    Propstat
+		patches []model.WebdavPropPatch
+		modtime *time.Time
 	)
 	stat.Status = http.StatusOK
 	for _, patch := range proppatches {
 		for _, prop := range patch.Props {
 			stat.Props = append(stat.Props, Property{XMLName: prop.XMLName})
 			if prop.XMLName.Space == "DAV:" && prop.XMLName.Local == "lastmodified" {
-				var modtimeUnix int64
-				modtimeUnix, err = strconv.ParseInt(string(prop.InnerXML), 10, 64)
-				if err == nil {
-					err = model.DB.Model(folder.Folder).UpdateColumn("updated_at", time.Unix(modtimeUnix, 0)).Error
+				if !patch.Remove {
+					modtimeUnix, err := strconv.ParseInt(string(prop.InnerXML), 10, 64)
+					if err != nil {
+						return nil, err
+					}
+					t := time.Unix(modtimeUnix, 0)
+					modtime = &t
 				}
+				continue
 			}
+
+			patches = append(patches, model.WebdavPropPatch{
+				Remove: patch.Remove,
+				Prop: model.WebdavProp{
+					Namespace: prop.XMLName.Space,
+					Name:      prop.XMLName.Local,
+					Lang:      prop.Lang,
+					InnerXML:  string(prop.InnerXML),
+				},
+			})
 		}
 	}
-	return []Propstat{stat}, err
+
+	if len(patches) > 0 {
+		if err := model.PatchWebdavProps(objectID, isFolder, patches); err != nil {
+			return nil, err
+		}
+	}
+
+	if modtime != nil {
+		if err := model.DB.Model(object).UpdateColumn("updated_at", *modtime).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return []Propstat{stat}, nil
 }
 
 type FileInfo interface {
@@ -244,12 +283,11 @@ var liveProps = map[xml.Name]struct {
 // of one Propstat element.
 func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, pnames []xml.Name) ([]Propstat, error) {
 	isDir := fi.IsDir()
-	if !isDir {
-		fi = &FileDeadProps{fi.(*model.File)}
-	}
+	fi = withDeadProps(fi)
 
+	// 仅请求活属性时无需读取自定义属性
 	var deadProps map[xml.Name]Property
-	if dph, ok := fi.(DeadPropsHolder); ok {
+	if dph, ok := fi.(DeadPropsHolder); ok && !onlyLiveProps(pnames) {
 		var err error
 		deadProps, err = dph.DeadProps()
 		if err != nil {
@@ -265,6 +303,16 @@ func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi Fil
 			pstatOK.Props = append(pstatOK.Props, dp)
 			continue
 		}
+		// CalDAV/CardDAV 处理器额外支持的属性
+		if innerXML, ok, err := findCollectionProp(ctx, fs, pn, fi); err != nil {
+			return nil, err
+		} else if ok {
+			pstatOK.Props = append(pstatOK.Props, Property{
+				XMLName:  pn,
+				InnerXML: []byte(innerXML),
+			})
+			continue
+		}
 		// Otherwise, it must either be a live property or we don't know it.
 		if prop := liveProps[pn]; prop.findFn != nil && (prop.dir || !isDir) {
 			innerXML, err := prop.findFn(ctx, fs, ls, fi.GetName(), fi)
@@ -287,9 +335,7 @@ func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi Fil
 // Propnames returns the property names defined for resource name.
 func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo) ([]xml.Name, error) {
 	isDir := fi.IsDir()
-	if !isDir {
-		fi = &FileDeadProps{fi.(*model.File)}
-	}
+	fi = withDeadProps(fi)
 
 	var deadProps map[xml.Name]Property
 	if dph, ok := fi.(DeadPropsHolder); ok {
@@ -309,9 +355,34 @@ func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi
 	for pn := range deadProps {
 		pnames = append(pnames, pn)
 	}
+	for _, pn := range collectionPropnames(ctx, fs, fi) {
+		if _, ok := liveProps[pn]; !ok {
+			pnames = append(pnames, pn)
+		}
+	}
 	return pnames, nil
 }
 
+// withDeadProps 包装文件或目录，使其实现 DeadPropsHolder
+func withDeadProps(fi FileInfo) FileInfo {
+	if fi.IsDir() {
+		return &FolderDeadProps{fi.(*model.Folder)}
+	}
+	return &FileDeadProps{fi.(*model.File)}
+}
+
+// onlyLiveProps 给定的属性是否均为活属性
+func onlyLiveProps(pnames []xml.Name) bool {
+	for _, pn := range pnames {
+		_, live := liveProps[pn]
+		_, collection := collectionProps[pn]
+		if !live && !collection {
+			return false
+		}
+	}
+	return true
+}
+
 // Allprop returns the properties defined for resource name and the properties
 // named in include.
 //
@@ -374,13 +445,7 @@ loop:
 	// very unlikely to be false
 	exist, info := isPathExist(ctx, fs, name)
 	if exist {
-		var dph DeadPropsHolder
-		if info.IsDir() {
-			dph = &FolderDeadProps{info.(*model.Folder)}
-		} else {
-			dph = &FileDeadProps{info.(*model.File)}
-		}
-		ret, err := dph.Patch(patches)
+		ret, err := withDeadProps(info).(DeadPropsHolder).Patch(patches)
 		if err != nil {
 			return nil, err
 		}
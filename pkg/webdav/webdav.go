@@ -15,7 +15,6 @@ import (
 	"path"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -27,12 +26,16 @@ import (
 type Handler struct {
 	// Prefix is the URL path prefix to strip from WebDAV resource paths.
 	Prefix string
-	// LockSystem is the lock management system.
-	LockSystem map[uint]LockSystem
+	// Collection 不为空时作为 CalDAV/CardDAV 处理器，取值为 CollectionCalendar
+	// 或 CollectionAddressbook
+	Collection string
+	// LockSystem 返回文件系统所有者的锁管理器
+	LockSystem func(fs *filesystem.FileSystem) LockSystem
+	// SharedMount 是否在根目录下挂载「与我共享」「团队」虚拟目录
+	SharedMount bool
 	// Logger is an optional error logger. If non-nil, it will be called
 	// for all HTTP requests.
 	Logger func(*http.Request, error)
-	Mutex  *sync.Mutex
 }
 
 func (h *Handler) stripPrefix(p string, uid uint) (string, int, error) {
@@ -63,18 +66,15 @@ func isPathExist(ctx context.Context, fs *filesystem.FileSystem, path string) (b
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) {
 	status, err := http.StatusBadRequest, errUnsupportedMethod
-	h.Mutex.Lock()
 	if h.LockSystem == nil {
-		h.Mutex.Unlock()
 		status, err = http.StatusInternalServerError, errNoLockSystem
+	} else if h.serveMounts(w, r, fs) {
+		return
 	} else {
-		// 检查并新建 LockSystem
-		ls, ok := h.LockSystem[fs.User.ID]
-		if !ok {
-			h.LockSystem[fs.User.ID] = NewMemLS()
-			ls = h.LockSystem[fs.User.ID]
+		ls := h.LockSystem(fs)
+		if h.Collection != "" {
+			r = r.WithContext(context.WithValue(r.Context(), collectionCtx{}, h))
 		}
-		h.Mutex.Unlock()
 
 		switch r.Method {
 		case "OPTIONS":
@@ -97,6 +97,14 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 			status, err = h.handlePropfind(w, r, fs, ls)
 		case "PROPPATCH":
 			status, err = h.handleProppatch(w, r, fs, ls)
+		case "REPORT":
+			if h.Collection != "" {
+				status, err = h.handleReport(w, r, fs, ls)
+			}
+		case "MKCALENDAR":
+			if h.Collection == CollectionCalendar {
+				status, err = h.handleMkcalendar(w, r, fs)
+			}
 		}
 	}
 
@@ -113,110 +121,97 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 
 // OK
 func (h *Handler) lock(now time.Time, root string, fs *filesystem.FileSystem, ls LockSystem) (token string, status int, err error) {
-	//token, err = ls.Create(now, LockDetails{
-	//	Root:      root,
-	//	Duration:  infiniteTimeout,
-	//	ZeroDepth: true,
-	//})
-	//if err != nil {
-	//	if err == ErrLocked {
-	//		return "", StatusLocked, err
-	//	}
-	//	return "", http.StatusInternalServerError, err
-	//}
+	token, err = ls.Create(now, LockDetails{
+		Root:      root,
+		Duration:  tempLockDuration,
+		ZeroDepth: true,
+	})
+	if err != nil {
+		if err == ErrLocked {
+			return "", StatusLocked, err
+		}
+		return "", http.StatusInternalServerError, err
+	}
 
-	return fmt.Sprintf("%d", time.Now().Unix()), 0, nil
+	return token, 0, nil
 }
 
 // ok
 func (h *Handler) confirmLocks(r *http.Request, src, dst string, fs *filesystem.FileSystem) (release func(), status int, err error) {
+	hdr := r.Header.Get("If")
+	ls := h.LockSystem(fs)
 
-	//hdr := r.Header.Get("If")
-	//h.Mutex.Lock()
-	//ls,ok := h.LockSystem[fs.User.ID]
-	//h.Mutex.Unlock()
-	//if !ok{
-	//	return nil, http.StatusInternalServerError, errNoLockSystem
-	//}
-	//
-	//if hdr == "" {
-	//	// An empty If header means that the client hasn't previously created locks.
-	//	// Even if this client doesn't care about locks, we still need to check that
-	//	// the resources aren't locked by another client, so we create temporary
-	//	// locks that would conflict with another client's locks. These temporary
-	//	// locks are unlocked at the end of the HTTP request.
-	//	now, srcToken, dstToken := time.Now(), "", ""
-	//	if src != "" {
-	//		srcToken, status, err = h.lock(now, src, fs,ls)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	if dst != "" {
-	//		dstToken, status, err = h.lock(now, dst, fs,ls)
-	//		if err != nil {
-	//			if srcToken != "" {
-	//				ls.Unlock(now, srcToken)
-	//			}
-	//			return nil, status, err
-	//		}
-	//	}
-	//
-	//	return func() {
-	//		if dstToken != "" {
-	//			ls.Unlock(now, dstToken)
-	//		}
-	//		if srcToken != "" {
-	//			ls.Unlock(now, srcToken)
-	//		}
-	//	}, 0, nil
-	//}
-	//
-	//ih, ok := parseIfHeader(hdr)
-	//if !ok {
-	//	return nil, http.StatusBadRequest, errInvalidIfHeader
-	//}
-	//// ih is a disjunction (OR) of ifLists, so any ifList will do.
-	//for _, l := range ih.lists {
-	//	lsrc := l.resourceTag
-	//	if lsrc == "" {
-	//		lsrc = src
-	//	} else {
-	//		u, err := url.Parse(lsrc)
-	//		if err != nil {
-	//			continue
-	//		}
-	//		//if u.Host != r.Host {
-	//		//	continue
-	//		//}
-	//		lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	release, err = ls.Confirm(
-	//		time.Now(),
-	//		lsrc,
-	//		dst,
-	//		l.conditions...,
-	//	)
-	//	if err == ErrConfirmationFailed {
-	//		continue
-	//	}
-	//	if err != nil {
-	//		return nil, http.StatusInternalServerError, err
-	//	}
-	//	return release, 0, nil
-	//}
-	//// Section 10.4.1 says that "If this header is evaluated and all state lists
-	//// fail, then the request must fail with a 412 (Precondition Failed) status."
-	//// We follow the spec even though the cond_put_corrupt_token test case from
-	//// the litmus test warns on seeing a 412 instead of a 423 (Locked).
-	//return nil, http.StatusPreconditionFailed, ErrLocked
+	if hdr == "" {
+		// An empty If header means that the client hasn't previously created locks.
+		// Even if this client doesn't care about locks, we still need to check that
+		// the resources aren't locked by another client, so we create temporary
+		// locks that would conflict with another client's locks. These temporary
+		// locks are unlocked at the end of the HTTP request.
+		now, srcToken, dstToken := time.Now(), "", ""
+		if src != "" {
+			srcToken, status, err = h.lock(now, src, fs, ls)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+		if dst != "" {
+			dstToken, status, err = h.lock(now, dst, fs, ls)
+			if err != nil {
+				if srcToken != "" {
+					ls.Unlock(now, srcToken)
+				}
+				return nil, status, err
+			}
+		}
 
-	return func() {
+		return func() {
+			if dstToken != "" {
+				ls.Unlock(now, dstToken)
+			}
+			if srcToken != "" {
+				ls.Unlock(now, srcToken)
+			}
+		}, 0, nil
+	}
 
-	}, 0, nil
+	ih, ok := parseIfHeader(hdr)
+	if !ok {
+		return nil, http.StatusBadRequest, errInvalidIfHeader
+	}
+	// ih is a disjunction (OR) of ifLists, so any ifList will do.
+	for _, l := range ih.lists {
+		lsrc := l.resourceTag
+		if lsrc == "" {
+			lsrc = src
+		} else {
+			u, err := url.Parse(lsrc)
+			if err != nil {
+				continue
+			}
+			lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+		release, err = ls.Confirm(
+			time.Now(),
+			lsrc,
+			dst,
+			l.conditions...,
+		)
+		if err == ErrConfirmationFailed {
+			continue
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return release, 0, nil
+	}
+	// Section 10.4.1 says that "If this header is evaluated and all state lists
+	// fail, then the request must fail with a 412 (Precondition Failed) status."
+	// We follow the spec even though the cond_put_corrupt_token test case from
+	// the litmus test warns on seeing a 412 instead of a 423 (Locked).
+	return nil, http.StatusPreconditionFailed, ErrLocked
 }
 
 // OK
@@ -234,9 +229,15 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request, fs *file
 			allow = "OPTIONS, LOCK, GET, HEAD, POST, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND, PUT"
 		}
 	}
+	if h.Collection != "" {
+		allow += ", REPORT"
+		if h.Collection == CollectionCalendar {
+			allow += ", MKCALENDAR"
+		}
+	}
 	w.Header().Set("Allow", allow)
 	// http://www.webdav.org/specs/rfc4918.html#dav.compliance.classes
-	w.Header().Set("DAV", "1, 2")
+	w.Header().Set("DAV", "1, 2"+h.collectionCompliance())
 	// http://msdn.microsoft.com/en-au/library/cc250217.aspx
 	w.Header().Set("MS-Author-Via", "DAV")
 	return 0, nil
@@ -281,6 +282,9 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 		if err == filesystem.ErrObjectNotExist {
 			return http.StatusNotFound, err
 		}
+		if err == filesystem.ErrACLDenied {
+			return http.StatusForbidden, err
+		}
 		return http.StatusInternalServerError, err
 	}
 
@@ -337,7 +341,7 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 
 	// 尝试作为文件删除
 	if ok, file := fs.IsFileExist(reqPath); ok {
-		if err := fs.Delete(ctx, []uint{}, []uint{file.ID}, false, false); err != nil {
+		if err := fs.Trash(ctx, []uint{}, []uint{file.ID}); err != nil {
 			return http.StatusMethodNotAllowed, err
 		}
 		return http.StatusNoContent, nil
@@ -345,7 +349,7 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 
 	// 尝试作为目录删除
 	if ok, folder := fs.IsPathExist(reqPath); ok {
-		if err := fs.Delete(ctx, []uint{folder.ID}, []uint{}, false, false); err != nil {
+		if err := fs.Trash(ctx, []uint{folder.ID}, []uint{}); err != nil {
 			return http.StatusMethodNotAllowed, err
 		}
 		return http.StatusNoContent, nil
@@ -380,7 +384,7 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 	filePath := path.Dir(reqPath)
 	fileData := fsctx.FileStream{
 		MimeType:    r.Header.Get("Content-Type"),
-		File:        r.Body,
+		File:        filesystem.WithUploadSpeedLimit(r.Body, fs.User.UploadSpeedLimit()),
 		Size:        fileSize,
 		Name:        fileName,
 		VirtualPath: filePath,
@@ -411,12 +415,17 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
 		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
 		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		// 保留历史版本时新内容写入新的源文件
+		if len(fileList) > 0 {
+			fs.KeepVersion(originFile, &fileData)
+		}
 		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
 		fileData.Mode |= fsctx.Overwrite
 	} else {
 		// 给文件系统分配钩子
 		fs.Use("BeforeUpload", filesystem.HookValidateFile)
 		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		fs.Use("BeforeUpload", filesystem.HookValidateGroupLimits)
 		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
 		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
 		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
@@ -425,6 +434,11 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 
 	// rclone 请求
 	fs.Use("AfterUpload", filesystem.NewWebdavAfterUploadHook(r))
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
 
 	// 执行上传
 	err = fs.Upload(ctx, &fileData)
@@ -578,126 +592,100 @@ func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request, fs *filesys
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
+	if duration < 0 || duration > maxLockDuration {
+		duration = maxLockDuration
+	}
 
-	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	li, status, err := readLockInfo(r.Body)
 	if err != nil {
 		return status, err
 	}
 
-	////ctx := r.Context()
-	//token, ld, now, created := "", LockDetails{}, time.Now(), false
-	//if li == (lockInfo{}) {
-	//	// An empty lockInfo means to refresh the lock.
-	//	ih, ok := parseIfHeader(r.Header.Get("If"))
-	//	if !ok {
-	//		return http.StatusBadRequest, errInvalidIfHeader
-	//	}
-	//	if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
-	//		token = ih.lists[0].conditions[0].Token
-	//	}
-	//	if token == "" {
-	//		return http.StatusBadRequest, errInvalidLockToken
-	//	}
-	//	ld, err = ls.Refresh(now, token, duration)
-	//	if err != nil {
-	//		if err == ErrNoSuchLock {
-	//			return http.StatusPreconditionFailed, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//
-	//} else {
-	//	// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
-	//	// then the request MUST act as if a "Depth:infinity" had been submitted."
-	//	depth := infiniteDepth
-	//	if hdr := r.Header.Get("Depth"); hdr != "" {
-	//		depth = parseDepth(hdr)
-	//		if depth != 0 && depth != infiniteDepth {
-	//			// Section 9.10.3 says that "Values other than 0 or infinity must not be
-	//			// used with the Depth header on a LOCK method".
-	//			return http.StatusBadRequest, errInvalidDepth
-	//		}
-	//	}
-	//	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
-	//	if err != nil {
-	//		return status, err
-	//	}
-	//	ld = LockDetails{
-	//		Root:      reqPath,
-	//		Duration:  duration,
-	//		OwnerXML:  li.Owner.InnerXML,
-	//		ZeroDepth: depth == 0,
-	//	}
-	//	token, err = ls.Create(now, ld)
-	//	if err != nil {
-	//		if err == ErrLocked {
-	//			return StatusLocked, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//	defer func() {
-	//		if retErr != nil {
-	//			ls.Unlock(now, token)
-	//		}
-	//	}()
-	//
-	//	// Create the resource if it didn't previously exist.
-	//	//if _, err := h.FileSystem.Stat(ctx, reqPath); err != nil {
-	//	//	f, err := h.FileSystem.OpenFile(ctx, reqPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	//	//	if err != nil {
-	//	//		// TODO: detect missing intermediate dirs and return http.StatusConflict?
-	//	//		return http.StatusInternalServerError, err
-	//	//	}
-	//	//	f.Close()
-	//	//	created = true
-	//	//}
-	//
-	//	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//	// Lock-Token value is a Coded-URL. We add angle brackets.
-	//	w.Header().Set("Lock-Token", "<"+token+">")
-	//}
-	//
-	//w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	//if created {
-	//	// This is "w.WriteHeader(http.StatusCreated)" and not "return
-	//	// http.StatusCreated, nil" because we write our own (XML) response to w
-	//	// and Handler.ServeHTTP would otherwise write "Created".
-	//	w.WriteHeader(http.StatusCreated)
-	//}
+	token, ld, now := "", LockDetails{}, time.Now()
+	if li == (lockInfo{}) {
+		// An empty lockInfo means to refresh the lock.
+		ih, ok := parseIfHeader(r.Header.Get("If"))
+		if !ok {
+			return http.StatusBadRequest, errInvalidIfHeader
+		}
+		if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
+			token = ih.lists[0].conditions[0].Token
+		}
+		if token == "" {
+			return http.StatusBadRequest, errInvalidLockToken
+		}
+		ld, err = ls.Refresh(now, token, duration)
+		if err != nil {
+			if err == ErrNoSuchLock {
+				return http.StatusPreconditionFailed, err
+			}
+			return http.StatusInternalServerError, err
+		}
 
-	writeLockInfo(w, fmt.Sprintf("%d", time.Now().UnixNano()), LockDetails{
-		Duration: duration,
-		OwnerXML: fs.User.Email,
-		Root:     reqPath,
-	})
+	} else {
+		// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
+		// then the request MUST act as if a "Depth:infinity" had been submitted."
+		depth := infiniteDepth
+		if hdr := r.Header.Get("Depth"); hdr != "" {
+			depth = parseDepth(hdr)
+			if depth != 0 && depth != infiniteDepth {
+				// Section 9.10.3 says that "Values other than 0 or infinity must not be
+				// used with the Depth header on a LOCK method".
+				return http.StatusBadRequest, errInvalidDepth
+			}
+		}
+		reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+		if err != nil {
+			return status, err
+		}
+		ld = LockDetails{
+			Root:      reqPath,
+			Duration:  duration,
+			OwnerXML:  li.Owner.InnerXML,
+			ZeroDepth: depth == 0,
+		}
+		token, err = ls.Create(now, ld)
+		if err != nil {
+			if err == ErrLocked {
+				return StatusLocked, err
+			}
+			return http.StatusInternalServerError, err
+		}
+
+		// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+		// Lock-Token value is a Coded-URL. We add angle brackets.
+		w.Header().Set("Lock-Token", "<"+token+">")
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writeLockInfo(w, token, ld)
 	return 0, nil
 }
 
 // OK
 func (h *Handler) handleUnlock(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
-	return http.StatusNoContent, err
 
-	//// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//// Lock-Token value is a Coded-URL. We strip its angle brackets.
-	//t := r.Header.Get("Lock-Token")
-	//if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
-	//	return http.StatusBadRequest, errInvalidLockToken
-	//}
-	//t = t[1 : len(t)-1]
-	//
-	//switch err = ls.Unlock(time.Now(), t); err {
-	//case nil:
-	//	return http.StatusNoContent, err
-	//case ErrForbidden:
-	//	return http.StatusForbidden, err
-	//case ErrLocked:
-	//	return StatusLocked, err
-	//case ErrNoSuchLock:
-	//	return http.StatusConflict, err
-	//default:
-	//	return http.StatusInternalServerError, err
-	//}
+	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+	// Lock-Token value is a Coded-URL. We strip its angle brackets.
+	t := r.Header.Get("Lock-Token")
+	if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
+		return http.StatusBadRequest, errInvalidLockToken
+	}
+	t = t[1 : len(t)-1]
+
+	switch err = ls.Unlock(time.Now(), t); err {
+	case nil:
+		return http.StatusNoContent, err
+	case ErrForbidden:
+		return http.StatusForbidden, err
+	case ErrLocked:
+		return StatusLocked, err
+	case ErrNoSuchLock:
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, err
+	}
 }
 
 // OK
@@ -760,6 +748,17 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 	}
 
 	walkErr := walkFS(ctx, fs, depth, reqPath, fi, walkFn)
+
+	// 根目录下列出有挂载点的虚拟目录
+	if walkErr == nil && depth != 0 && reqPath == "/" && h.SharedMount && fs.Root == nil {
+		for _, root := range filesystem.VirtualRoots {
+			if mounts, err := filesystem.ListMounts(root, fs.User.ID); err == nil && len(mounts) > 0 {
+				if walkErr = walkFn(path.Join("/", root), virtualRoot(root), nil); walkErr != nil {
+					break
+				}
+			}
+		}
+	}
 	closeErr := mw.close()
 	if walkErr != nil {
 		return http.StatusInternalServerError, walkErr
@@ -883,6 +882,7 @@ func StatusText(code int) string {
 var (
 	errDestinationEqualsSource = errors.New("webdav: destination equals source")
 	errDirectoryNotEmpty       = errors.New("webdav: directory not empty")
+	errInvalidCollection       = errors.New("webdav: collections must be created in the root folder")
 	errInvalidDepth            = errors.New("webdav: invalid depth")
 	errInvalidDestination      = errors.New("webdav: invalid destination")
 	errInvalidIfHeader         = errors.New("webdav: invalid If header")
@@ -890,6 +890,7 @@ var (
 	errInvalidLockToken        = errors.New("webdav: invalid lock token")
 	errInvalidPropfind         = errors.New("webdav: invalid propfind")
 	errInvalidProppatch        = errors.New("webdav: invalid proppatch")
+	errInvalidReport           = errors.New("webdav: invalid report")
 	errInvalidResponse         = errors.New("webdav: invalid response")
 	errInvalidTimeout          = errors.New("webdav: invalid timeout")
 	errNoFileSystem            = errors.New("webdav: no file system")
@@ -899,4 +900,5 @@ var (
 	errRecursionTooDeep        = errors.New("webdav: recursion too deep")
 	errUnsupportedLockInfo     = errors.New("webdav: unsupported lock info")
 	errUnsupportedMethod       = errors.New("webdav: unsupported method")
+	errUnsupportedReport       = errors.New("webdav: unsupported report")
 )
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// HookChanges 将用户发起的文件上传、删除转换为事件，在后台投递
+func HookChanges(ctx context.Context, fs *filesystem.FileSystem, changes []model.Change) {
+	if fs.User == nil || fs.User.ID == 0 {
+		return
+	}
+
+	files := make([]model.Change, 0, len(changes))
+	for _, change := range changes {
+		if change.ObjectType == model.ChangeObjectFile && changeEvent(change) != "" {
+			files = append(files, change)
+		}
+	}
+
+	if len(files) > 0 {
+		go dispatchChanges(fs.User.ID, files)
+	}
+}
+
+// changeEvent 返回变更对应的事件
+func changeEvent(change model.Change) string {
+	switch change.Type {
+	case model.ChangeCreate, model.ChangeModify:
+		return model.WebhookEventUpload
+	case model.ChangeDelete:
+		return model.WebhookEventDelete
+	}
+
+	return ""
+}
+
+func dispatchChanges(uid uint, changes []model.Change) {
+	for _, change := range changes {
+		// 上传会话创建的占位文件在上传完成后才触发事件
+		if change.Type == model.ChangeCreate {
+			files, err := model.GetFilesByIDs([]uint{change.ObjectID}, uid)
+			if err != nil || len(files) == 0 || files[0].UploadSessionID != nil {
+				continue
+			}
+		}
+
+		Dispatch(changeEvent(change), uid, &FileData{
+			ID:   hashid.HashID(change.ObjectID, hashid.FileID),
+			Name: change.Name,
+			Size: change.Size,
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// EventHeader 事件名请求头
+	EventHeader = auth.CrHeaderPrefix + "Webhook-Event"
+	// DeliveryHeader 投递记录 ID 请求头，重试时保持不变
+	DeliveryHeader = auth.CrHeaderPrefix + "Webhook-Delivery"
+	// SignatureHeader 签名请求头，值为请求正文的 HMAC-SHA256
+	SignatureHeader = auth.CrHeaderPrefix + "Webhook-Signature"
+
+	// httpTimeout 投递请求的超时时间
+	httpTimeout = 10 * time.Second
+	// retryBatchSize 每次重试的投递记录数
+	retryBatchSize = 100
+)
+
+// client 发送投递请求的客户端
+var client request.Client = request.NewClient()
+
+// Payload 投递的事件内容
+type Payload struct {
+	Event string      `json:"event"`
+	User  string      `json:"user"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// FileData 文件事件的数据
+type FileData struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Size uint64 `json:"size"`
+}
+
+// ShareData 分享事件的数据
+type ShareData struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	IsDir  bool   `json:"is_dir"`
+	Source string `json:"source"`
+	// Path 目录分享中被下载文件的路径
+	Path string `json:"path,omitempty"`
+	// Visitor 下载者，匿名用户为空
+	Visitor string `json:"visitor,omitempty"`
+}
+
+// Init 注册文件事件使用的文件系统钩子
+func Init() {
+	filesystem.RegisterChangeHook(HookChanges)
+}
+
+// Sign 计算请求正文签名
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch 为订阅事件的 Webhook 创建用户 uid 的事件投递记录，并在后台投递
+func Dispatch(event string, uid uint, data interface{}) {
+	webhooks, err := model.GetSubscribedWebhooks(uid, event)
+	if err != nil {
+		util.Log().Warning("Failed to list webhooks: %s", err)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(&Payload{
+		Event: event,
+		User:  hashid.HashID(uid, hashid.UserID),
+		Time:  time.Now(),
+		Data:  data,
+	})
+	if err != nil {
+		util.Log().Warning("Failed to encode webhook payload: %s", err)
+		return
+	}
+
+	for i := range webhooks {
+		// 预先设定重试时间，投递前进程退出时仍可由定时任务重试
+		next := time.Now().Add(backoff(1))
+		delivery := &model.WebhookDelivery{
+			WebhookID:   webhooks[i].ID,
+			Event:       event,
+			Payload:     string(body),
+			Status:      model.WebhookDeliveryPending,
+			NextRetryAt: &next,
+		}
+		if err := delivery.Create(); err != nil {
+			util.Log().Warning("Failed to create webhook delivery: %s", err)
+			continue
+		}
+
+		go deliver(&webhooks[i], delivery)
+	}
+}
+
+// backoff 返回第 attempts 次投递失败后的重试间隔
+func backoff(attempts int) time.Duration {
+	interval := time.Duration(model.GetIntSetting("webhook_retry_interval", 30)) * time.Second
+	return interval << uint(attempts-1)
+}
+
+// deliver 投递一次事件并记录结果
+func deliver(webhook *model.Webhook, delivery *model.WebhookDelivery) {
+	code, err := send(webhook, delivery)
+
+	var next *time.Time
+	if err != nil && delivery.Attempts+1 < model.GetIntSetting("webhook_max_attempts", 5) {
+		retry := time.Now().Add(backoff(delivery.Attempts + 1))
+		next = &retry
+	}
+
+	if err := delivery.Finish(code, err, next); err != nil {
+		util.Log().Warning("Failed to update webhook delivery %d: %s", delivery.ID, err)
+	}
+}
+
+// send 发送投递请求，返回响应状态码
+func send(webhook *model.Webhook, delivery *model.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	header := http.Header{
+		"Content-Type": {"application/json"},
+		EventHeader:    {delivery.Event},
+		DeliveryHeader: {strconv.FormatUint(uint64(delivery.ID), 10)},
+	}
+	if webhook.Secret != "" {
+		header.Set(SignatureHeader, Sign(webhook.Secret, body))
+	}
+
+	resp := client.Request("POST", webhook.URL, bytes.NewReader(body),
+		request.WithContext(context.Background()),
+		request.WithTimeout(httpTimeout),
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(body))),
+	)
+	if resp.Err != nil {
+		return 0, resp.Err
+	}
+	resp.Response.Body.Close()
+
+	code := resp.Response.StatusCode
+	if code < 200 || code >= 300 {
+		return code, fmt.Errorf("unexpected response status %d", code)
+	}
+
+	return code, nil
+}
+
+// RetryPending 重试到达重试时间的投递，并清除过期的投递记录
+func RetryPending() {
+	deliveries, err := model.GetDueWebhookDeliveries(time.Now(), retryBatchSize)
+	if err != nil {
+		util.Log().Warning("Failed to list webhook deliveries: %s", err)
+		return
+	}
+
+	// 并发投递，避免耗时超过定时任务间隔导致重复投递
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		webhook, err := model.GetWebhookByID(deliveries[i].WebhookID)
+		if err != nil || !webhook.Enabled {
+			// Webhook 已被删除或禁用，不再重试
+			if err := deliveries[i].Finish(0, fmt.Errorf("webhook is not available"), nil); err != nil {
+				util.Log().Warning("Failed to update webhook delivery %d: %s", deliveries[i].ID, err)
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			deliver(&webhook, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	retention := time.Duration(model.GetIntSetting("webhook_log_retention", 604800)) * time.Second
+	if _, err := model.DeleteWebhookDeliveriesBefore(time.Now().Add(-retention)); err != nil {
+		util.Log().Warning("Failed to delete expired webhook deliveries: %s", err)
+	}
+}
@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestSend(t *testing.T) {
+	asserts := assert.New(t)
+	var received http.Header
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		body, _ = io.ReadAll(r.Body)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	delivery := &model.WebhookDelivery{Model: gorm.Model{ID: 3}, Event: model.WebhookEventUpload, Payload: `{"event":"file.uploaded"}`}
+
+	// 成功，带签名
+	{
+		code, err := send(&model.Webhook{URL: server.URL, Secret: "secret"}, delivery)
+		asserts.NoError(err)
+		asserts.Equal(200, code)
+		asserts.Equal(delivery.Payload, string(body))
+		asserts.Equal(Sign("secret", body), received.Get(SignatureHeader))
+		asserts.Equal("file.uploaded", received.Get(EventHeader))
+		asserts.Equal("3", received.Get(DeliveryHeader))
+	}
+
+	// 响应状态码错误
+	{
+		code, err := send(&model.Webhook{URL: server.URL + "/fail"}, delivery)
+		asserts.Error(err)
+		asserts.Equal(500, code)
+		asserts.Empty(received.Get(SignatureHeader))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_webhook_retry_interval", "30", 0)
+
+	asserts.Equal(30*time.Second, backoff(1))
+	asserts.Equal(60*time.Second, backoff(2))
+	asserts.Equal(240*time.Second, backoff(4))
+}
+
+func TestDeliver(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_webhook_retry_interval", "30", 0)
+	cache.Set("setting_webhook_max_attempts", "2", 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	webhook := &model.Webhook{URL: server.URL}
+	delivery := &model.WebhookDelivery{Model: gorm.Model{ID: 1}, Status: model.WebhookDeliveryPending}
+
+	// 首次失败，等待重试
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		deliver(webhook, delivery)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(model.WebhookDeliveryPending, delivery.Status)
+		asserts.Equal(1, delivery.Attempts)
+		asserts.Equal(502, delivery.ResponseCode)
+		asserts.NotNil(delivery.NextRetryAt)
+	}
+
+	// 达到最大次数
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)webhook_deliveries(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		deliver(webhook, delivery)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(model.WebhookDeliveryFailed, delivery.Status)
+		asserts.Equal(2, delivery.Attempts)
+		asserts.Nil(delivery.NextRetryAt)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").WillReturnError(errors.New("error"))
+		Dispatch(model.WebhookEventDelete, 1, &FileData{})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 无订阅
+	{
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).AddRow(1, model.WebhookEventUpload))
+		Dispatch(model.WebhookEventDelete, 1, &FileData{})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 创建投递记录失败
+	{
+		mock.ExpectQuery("SELECT(.+)webhooks(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).AddRow(1, "file.uploaded,file.deleted"))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)webhook_deliveries(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		Dispatch(model.WebhookEventDelete, 1, &FileData{})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestChangeEvent(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal(model.WebhookEventUpload, changeEvent(model.Change{Type: model.ChangeCreate}))
+	asserts.Equal(model.WebhookEventUpload, changeEvent(model.Change{Type: model.ChangeModify}))
+	asserts.Equal(model.WebhookEventDelete, changeEvent(model.Change{Type: model.ChangeDelete}))
+	asserts.Empty(changeEvent(model.Change{Type: model.ChangeMove}))
+}
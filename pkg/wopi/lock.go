@@ -0,0 +1,151 @@
+package wopi
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	LockHeader    = wopiHeaderPrefix + "Lock"
+	OldLockHeader = wopiHeaderPrefix + "OldLock"
+
+	MethodGetLock = "GET_LOCK"
+
+	lockCachePrefix = "wopi_lock_"
+	// WOPI locks automatically expire after 30 minutes if not refreshed.
+	lockDuration = 30 * 60
+
+	// Lock operations of the same file are serialized with a short-lived mutex,
+	// which expires by itself if the holder crashed.
+	mutexTTL      = 10
+	mutexWait     = 5 * time.Second
+	mutexInterval = 20 * time.Millisecond
+)
+
+var (
+	ErrLockMismatch = errors.New("lock mismatch")
+	ErrLockBusy     = errors.New("lock is busy")
+)
+
+func lockKey(fileID uint) string {
+	return fmt.Sprintf("%s%d", lockCachePrefix, fileID)
+}
+
+// GetLock returns current lock ID of given file, empty string if not locked.
+func GetLock(store cache.Driver, fileID uint) string {
+	if lock, ok := store.Get(lockKey(fileID)); ok {
+		return lock.(string)
+	}
+
+	return ""
+}
+
+// exclusive runs fn while holding the mutex of given file, so that checking
+// and updating its lock is atomic across all instances sharing the store.
+func exclusive(store cache.Driver, fileID uint, fn func() (string, error)) (string, error) {
+	locker, ok := store.(cache.Locker)
+	if !ok {
+		return fn()
+	}
+
+	key := cache.LockPrefix + lockKey(fileID)
+	token := util.RandStringRunes(16)
+	deadline := time.Now().Add(mutexWait)
+	for {
+		locked, err := locker.Lock(key, token, mutexTTL)
+		if err != nil {
+			return "", err
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			return "", ErrLockBusy
+		}
+
+		time.Sleep(mutexInterval)
+	}
+
+	defer locker.Unlock(key, token)
+	return fn()
+}
+
+// Lock locks given file with lock ID. Locking a file already locked with
+// the same ID refreshes the lock. Current lock ID is returned on conflict.
+func Lock(store cache.Driver, fileID uint, lock string) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		if current := GetLock(store, fileID); current != "" && current != lock {
+			return current, ErrLockMismatch
+		}
+
+		return "", store.Set(lockKey(fileID), lock, lockDuration)
+	})
+}
+
+// RefreshLock resets the expiration of an existing lock.
+func RefreshLock(store cache.Driver, fileID uint, lock string) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		if current := GetLock(store, fileID); current != lock {
+			return current, ErrLockMismatch
+		}
+
+		return "", store.Set(lockKey(fileID), lock, lockDuration)
+	})
+}
+
+// UnlockAndRelock replaces an existing lock with a new lock ID.
+func UnlockAndRelock(store cache.Driver, fileID uint, oldLock, lock string) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		if current := GetLock(store, fileID); current != oldLock {
+			return current, ErrLockMismatch
+		}
+
+		return "", store.Set(lockKey(fileID), lock, lockDuration)
+	})
+}
+
+// Unlock releases an existing lock.
+func Unlock(store cache.Driver, fileID uint, lock string) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		if current := GetLock(store, fileID); current != lock {
+			return current, ErrLockMismatch
+		}
+
+		return "", store.Delete([]string{lockKey(fileID)}, "")
+	})
+}
+
+// CheckLock validates if a write operation is permitted under current lock.
+// Unlocked files can be written by any client with write access.
+func CheckLock(store cache.Driver, fileID uint, lock string) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		if current := GetLock(store, fileID); current != "" && current != lock {
+			return current, ErrLockMismatch
+		}
+
+		return "", nil
+	})
+}
+
+// CheckPutLock validates if PutFile is permitted under current lock. As
+// required by WOPI, only empty files can be written without a lock.
+func CheckPutLock(store cache.Driver, fileID uint, lock string, size uint64) (string, error) {
+	return exclusive(store, fileID, func() (string, error) {
+		current := GetLock(store, fileID)
+		if current == "" && size == 0 {
+			return "", nil
+		}
+
+		if current == "" || current != lock {
+			return current, ErrLockMismatch
+		}
+
+		return "", nil
+	})
+}
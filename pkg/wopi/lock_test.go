@@ -0,0 +1,115 @@
+package wopi
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLock(t *testing.T) {
+	a := assert.New(t)
+	store := cache.NewMemoStore()
+
+	// Not locked
+	{
+		a.Equal("", GetLock(store, 1))
+		current, err := CheckLock(store, 1, "")
+		a.NoError(err)
+		a.Equal("", current)
+		current, err = RefreshLock(store, 1, "lock1")
+		a.ErrorIs(err, ErrLockMismatch)
+		a.Equal("", current)
+		_, err = Unlock(store, 1, "lock1")
+		a.ErrorIs(err, ErrLockMismatch)
+	}
+
+	// Lock and relock with same ID
+	{
+		_, err := Lock(store, 1, "lock1")
+		a.NoError(err)
+		_, err = Lock(store, 1, "lock1")
+		a.NoError(err)
+		a.Equal("lock1", GetLock(store, 1))
+		a.Equal("", GetLock(store, 2))
+		_, err = RefreshLock(store, 1, "lock1")
+		a.NoError(err)
+	}
+
+	// Conflict
+	{
+		current, err := Lock(store, 1, "lock2")
+		a.ErrorIs(err, ErrLockMismatch)
+		a.Equal("lock1", current)
+		current, err = CheckLock(store, 1, "lock2")
+		a.ErrorIs(err, ErrLockMismatch)
+		a.Equal("lock1", current)
+		current, err = UnlockAndRelock(store, 1, "lock2", "lock3")
+		a.ErrorIs(err, ErrLockMismatch)
+		a.Equal("lock1", current)
+	}
+
+	// Relock and unlock
+	{
+		_, err := UnlockAndRelock(store, 1, "lock1", "lock2")
+		a.NoError(err)
+		_, err = CheckLock(store, 1, "lock2")
+		a.NoError(err)
+		_, err = Unlock(store, 1, "lock2")
+		a.NoError(err)
+		a.Equal("", GetLock(store, 1))
+	}
+}
+
+func TestCheckPutLock(t *testing.T) {
+	a := assert.New(t)
+	store := cache.NewMemoStore()
+
+	// Empty files can be written without lock
+	_, err := CheckPutLock(store, 1, "", 0)
+	a.NoError(err)
+
+	// Non-empty unlocked files cannot be written
+	current, err := CheckPutLock(store, 1, "", 10)
+	a.ErrorIs(err, ErrLockMismatch)
+	a.Equal("", current)
+	_, err = CheckPutLock(store, 1, "lock1", 10)
+	a.ErrorIs(err, ErrLockMismatch)
+
+	// Locked files can only be written by lock holder
+	_, err = Lock(store, 1, "lock1")
+	a.NoError(err)
+	_, err = CheckPutLock(store, 1, "lock1", 10)
+	a.NoError(err)
+	current, err = CheckPutLock(store, 1, "lock2", 0)
+	a.ErrorIs(err, ErrLockMismatch)
+	a.Equal("lock1", current)
+}
+
+func TestLock_Concurrent(t *testing.T) {
+	a := assert.New(t)
+	store := cache.NewMemoStore()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		granted []string
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(lock string) {
+			defer wg.Done()
+			if _, err := Lock(store, 1, lock); err == nil {
+				mu.Lock()
+				granted = append(granted, lock)
+				mu.Unlock()
+			}
+		}(fmt.Sprintf("lock%d", i))
+	}
+	wg.Wait()
+
+	a.Len(granted, 1)
+	a.Equal(granted[0], GetLock(store, 1))
+}
@@ -0,0 +1,35 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncChanges(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	router := InitMasterRouter()
+	defer mockLogin(t, 1)()
+
+	cursor := hashid.HashID(model.GetLatestChangeID(1), hashid.ChangeID)
+	asserts.NoError(model.RecordChanges([]model.Change{{UserID: 1, Type: model.ChangeCreate, ObjectType: model.ChangeObjectFile, Name: "sync.txt"}}))
+
+	list := func(target string) string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", target+"?cursor="+cursor, nil)
+		router.ServeHTTP(w, req)
+		asserts.Equal(200, w.Code)
+		return w.Body.String()
+	}
+
+	// 与 file/changes 返回相同的增量变更
+	res := list("/api/v3/sync/changes")
+	asserts.Contains(res, `"code":0`)
+	asserts.Contains(res, "sync.txt")
+	asserts.Equal(list("/api/v3/file/changes"), res)
+}
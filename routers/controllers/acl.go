@@ -0,0 +1,27 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/acl"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateFolderACL 为目录添加 ACL 条目
+func CreateFolderACL(c *gin.Context) {
+	var service acl.CreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListFolderACLs 列出目录上设置的 ACL 条目
+func ListFolderACLs(c *gin.Context) {
+	c.JSON(200, acl.List(c, CurrentUser(c), c.Param("folder")))
+}
+
+// DeleteFolderACL 删除目录上的 ACL 条目
+func DeleteFolderACL(c *gin.Context) {
+	c.JSON(200, acl.Delete(c, CurrentUser(c), c.Param("id")))
+}
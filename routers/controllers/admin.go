@@ -2,12 +2,15 @@ package controllers
 
 import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/crontab"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"io"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
+	"github.com/cloudreve/Cloudreve/v3/pkg/directory"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/plugin"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/wopi"
@@ -26,6 +29,52 @@ func AdminSummary(c *gin.Context) {
 	}
 }
 
+// AdminStats 获取站点统计数据
+func AdminStats(c *gin.Context) {
+	var service admin.StatsService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Stats()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminTopConsumers 获取用量最多的用户
+func AdminTopConsumers(c *gin.Context) {
+	var service admin.TopConsumersService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Top()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminExportStats 以 CSV 格式导出统计数据
+func AdminExportStats(c *gin.Context) {
+	var service admin.StatsExportService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Export(c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminTestGroupRules 测试用户组分配规则
+func AdminTestGroupRules(c *gin.Context) {
+	var service admin.GroupRuleTestService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Test()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminNews 获取社区新闻
 func AdminNews(c *gin.Context) {
 	tag := "announcements"
@@ -43,7 +92,24 @@ func AdminNews(c *gin.Context) {
 func AdminChangeSetting(c *gin.Context) {
 	var service admin.BatchSettingChangeService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Change()
+		res := service.Change(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGetLogLevel 获取日志配置
+func AdminGetLogLevel(c *gin.Context) {
+	var service admin.NoParamService
+	c.JSON(200, service.GetLogLevel())
+}
+
+// AdminChangeLogLevel 调整日志等级
+func AdminChangeLogLevel(c *gin.Context) {
+	var service admin.LogLevelService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Change(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -82,6 +148,12 @@ func AdminReloadService(c *gin.Context) {
 		aria2.Init(true, cluster.Default, mq.GlobalMQ)
 	case "wopi":
 		wopi.Init()
+	case "directory":
+		go directory.Sync()
+	case "plugin":
+		plugin.Default.Kill()
+	case "crontab":
+		crontab.Reload()
 	}
 
 	c.JSON(200, serializer.Response{})
@@ -137,6 +209,13 @@ func AdminListPolicy(c *gin.Context) {
 	}
 }
 
+// AdminListDriverPlugins 列出可用的存储驱动插件
+func AdminListDriverPlugins(c *gin.Context) {
+	var service admin.NoParamService
+	res := service.DriverPlugins()
+	c.JSON(200, res)
+}
+
 // AdminTestPath 测试本地路径可用性
 func AdminTestPath(c *gin.Context) {
 	var service admin.PathTestService
@@ -163,7 +242,7 @@ func AdminTestSlave(c *gin.Context) {
 func AdminAddPolicy(c *gin.Context) {
 	var service admin.AddPolicyService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -216,11 +295,44 @@ func AdminGetPolicy(c *gin.Context) {
 	}
 }
 
+// AdminGetPolicyUsage 获取存储策略本月用量
+func AdminGetPolicyUsage(c *gin.Context) {
+	var service admin.PolicyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Usage()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGetPolicyHealth 获取存储策略健康检查结果
+func AdminGetPolicyHealth(c *gin.Context) {
+	var service admin.PolicyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Health(c.Query("refresh") == "true")
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRotatePolicyKey 轮换存储策略的加密主密钥
+func AdminRotatePolicyKey(c *gin.Context) {
+	var service admin.PolicyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.RotateEncryptionKey(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminDeletePolicy 删除存储策略
 func AdminDeletePolicy(c *gin.Context) {
 	var service admin.PolicyService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -242,7 +354,7 @@ func AdminListGroup(c *gin.Context) {
 func AdminAddGroup(c *gin.Context) {
 	var service admin.AddGroupService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -253,7 +365,7 @@ func AdminAddGroup(c *gin.Context) {
 func AdminDeleteGroup(c *gin.Context) {
 	var service admin.GroupService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -286,7 +398,7 @@ func AdminListUser(c *gin.Context) {
 func AdminAddUser(c *gin.Context) {
 	var service admin.AddUserService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -304,11 +416,71 @@ func AdminGetUser(c *gin.Context) {
 	}
 }
 
+// AdminGetUserRetention 获取用户保留规则
+func AdminGetUserRetention(c *gin.Context) {
+	var service admin.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Retention()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUpdateUserRetention 设置用户保留规则
+func AdminUpdateUserRetention(c *gin.Context) {
+	var (
+		user    admin.UserService
+		service admin.UserRetentionService
+	)
+	if err := c.ShouldBindUri(&user); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, user.ID, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGetUserSpeedLimit 获取用户单独设定的限速
+func AdminGetUserSpeedLimit(c *gin.Context) {
+	var service admin.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.SpeedLimit()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUpdateUserSpeedLimit 设置用户单独的限速
+func AdminUpdateUserSpeedLimit(c *gin.Context) {
+	var (
+		user    admin.UserService
+		service admin.UserSpeedLimitService
+	)
+	if err := c.ShouldBindUri(&user); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, user.ID, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminDeleteUser 批量删除用户
 func AdminDeleteUser(c *gin.Context) {
 	var service admin.UserBatchService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -319,7 +491,7 @@ func AdminDeleteUser(c *gin.Context) {
 func AdminBanUser(c *gin.Context) {
 	var service admin.UserService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Ban()
+		res := service.Ban(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -367,6 +539,50 @@ func AdminDeleteFile(c *gin.Context) {
 	}
 }
 
+// AdminReleaseFiles 解除文件的病毒隔离状态
+func AdminReleaseFiles(c *gin.Context) {
+	var service admin.FileBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Release(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListReport 列出举报
+func AdminListReport(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Reports()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminHandleReport 处理举报
+func AdminHandleReport(c *gin.Context) {
+	var service admin.ReportHandleService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Handle(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAudit 列出审计日志
+func AdminListAudit(c *gin.Context) {
+	var service admin.AuditListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Audits()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminListShare 列出分享
 func AdminListShare(c *gin.Context) {
 	var service admin.AdminListService
@@ -378,6 +594,148 @@ func AdminListShare(c *gin.Context) {
 	}
 }
 
+// AdminListInvite 列出邀请码
+func AdminListInvite(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Invites()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddInvite 批量生成邀请码
+func AdminAddInvite(c *gin.Context) {
+	var service admin.AddInviteService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteInvite 删除邀请码
+func AdminDeleteInvite(c *gin.Context) {
+	var service admin.InviteService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListPublicFolders 列出公开目录
+func AdminListPublicFolders(c *gin.Context) {
+	var service admin.NoParamService
+	res := service.PublicFolders()
+	c.JSON(200, res)
+}
+
+// AdminAddPublicFolder 添加公开目录
+func AdminAddPublicFolder(c *gin.Context) {
+	var service admin.AddPublicFolderService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeletePublicFolder 取消目录公开
+func AdminDeletePublicFolder(c *gin.Context) {
+	var service admin.PublicFolderService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAutomations 列出自动化规则
+func AdminListAutomations(c *gin.Context) {
+	var service admin.NoParamService
+	res := service.Automations()
+	c.JSON(200, res)
+}
+
+// AdminAddAutomation 创建或保存自动化规则
+func AdminAddAutomation(c *gin.Context) {
+	var service admin.AddAutomationService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteAutomation 删除自动化规则
+func AdminDeleteAutomation(c *gin.Context) {
+	var service admin.AutomationService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListSchedules 列出定时任务
+func AdminListSchedules(c *gin.Context) {
+	var service admin.NoParamService
+	res := service.Schedules()
+	c.JSON(200, res)
+}
+
+// AdminTriggerSchedule 立即执行定时任务
+func AdminTriggerSchedule(c *gin.Context) {
+	var service admin.ScheduleService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Trigger(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminPauseSchedule 暂停定时任务
+func AdminPauseSchedule(c *gin.Context) {
+	var service admin.ScheduleService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Pause(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminResumeSchedule 恢复定时任务
+func AdminResumeSchedule(c *gin.Context) {
+	var service admin.ScheduleService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Resume(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListScheduleHistory 列出定时任务的执行记录
+func AdminListScheduleHistory(c *gin.Context) {
+	var service admin.ScheduleService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.History()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminDeleteShare 批量删除分享
 func AdminDeleteShare(c *gin.Context) {
 	var service admin.ShareBatchService
@@ -433,6 +791,79 @@ func AdminDeleteTask(c *gin.Context) {
 	}
 }
 
+// AdminCreateQuotaTask 新建容量重新计算任务
+func AdminCreateQuotaTask(c *gin.Context) {
+	var service admin.QuotaTaskService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminCreateThumbTask 新建缩略图重新生成任务
+func AdminCreateThumbTask(c *gin.Context) {
+	var service admin.ThumbTaskService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminCreateMigrateTask 新建存储策略迁移任务
+func AdminCreateMigrateTask(c *gin.Context) {
+	var service admin.MigrateTaskService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminCreateBlobGCTask 新建孤立物理文件回收任务
+func AdminCreateBlobGCTask(c *gin.Context) {
+	var service admin.BlobGCTaskService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAnnounce 向所有用户发送站内公告
+func AdminAnnounce(c *gin.Context) {
+	var service admin.AnnounceService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Announce(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListTaskWorkers 列出独立任务 Worker
+func AdminListTaskWorkers(c *gin.Context) {
+	var service admin.NoParamService
+	res := service.Workers()
+	c.JSON(200, res)
+}
+
+// AdminApproveExportTask 通过用户数据导出任务
+func AdminApproveExportTask(c *gin.Context) {
+	var service admin.ExportApproveService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Approve(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminCreateImportTask 新建文件导入任务
 func AdminCreateImportTask(c *gin.Context) {
 	var service admin.ImportTaskService
@@ -448,7 +879,29 @@ func AdminCreateImportTask(c *gin.Context) {
 func AdminListFolders(c *gin.Context) {
 	var service admin.ListFolderService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.List(c)
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminSearchUserFiles 搜索指定用户的文件
+func AdminSearchUserFiles(c *gin.Context) {
+	var service admin.UserFileSearchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Search(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminTransferFiles 在用户之间转移文件
+func AdminTransferFiles(c *gin.Context) {
+	var service admin.FileTransferService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Transfer(c, CurrentUser(c))
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -470,7 +923,7 @@ func AdminListNodes(c *gin.Context) {
 func AdminAddNode(c *gin.Context) {
 	var service admin.AddNodeService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -481,7 +934,7 @@ func AdminAddNode(c *gin.Context) {
 func AdminToggleNode(c *gin.Context) {
 	var service admin.ToggleNodeService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Toggle()
+		res := service.Toggle(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -492,7 +945,7 @@ func AdminToggleNode(c *gin.Context) {
 func AdminDeleteNode(c *gin.Context) {
 	var service admin.NodeService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -138,3 +138,14 @@ func S3Callback(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// B2Callback Backblaze B2上传完成客户端回调
+func B2Callback(c *gin.Context) {
+	var callbackBody callback.B2Callback
+	if err := c.ShouldBindQuery(&callbackBody); err == nil {
+		res := callbackBody.PreProcess(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/collaboration"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateCollaboration 将目录共享给其他用户
+func CreateCollaboration(c *gin.Context) {
+	var service collaboration.CreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListCollaborations 列出共享出去的目录
+func ListCollaborations(c *gin.Context) {
+	c.JSON(200, collaboration.List(c, CurrentUser(c)))
+}
+
+// ListSharedWithMe 列出共享给我的目录
+func ListSharedWithMe(c *gin.Context) {
+	c.JSON(200, collaboration.Shared(c, CurrentUser(c)))
+}
+
+// DeleteCollaboration 取消目录共享
+func DeleteCollaboration(c *gin.Context) {
+	var service collaboration.Service
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListSharedDirectory 列出「与我共享」虚拟目录下的内容
+func ListSharedDirectory(c *gin.Context) {
+	var service collaboration.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetSharedDownload 获取「与我共享」下文件的下载地址
+func GetSharedDownload(c *gin.Context) {
+	var service collaboration.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UploadToShared 上传文件到「与我共享」下的目录
+func UploadToShared(c *gin.Context) {
+	var service collaboration.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Upload(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateSharedDirectory 在「与我共享」下的目录中创建目录
+func CreateSharedDirectory(c *gin.Context) {
+	var service collaboration.PathService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.CreateDirectory(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteSharedObject 删除「与我共享」下的对象
+func DeleteSharedObject(c *gin.Context) {
+	var service collaboration.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
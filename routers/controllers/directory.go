@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"encoding/json"
+
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
 )
@@ -16,12 +18,49 @@ func CreateDirectory(c *gin.Context) {
 	}
 }
 
+// SetFolderQuota 设定目录容量配额
+func SetFolderQuota(c *gin.Context) {
+	var service explorer.FolderQuotaService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// StreamDirectory 以 NDJSON 格式流式列出目录下内容
+func StreamDirectory(c *gin.Context) {
+	var service explorer.StreamDirectoryService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	res := service.Stream(c)
+	if res.Code != 0 {
+		if c.Writer.Written() {
+			// 已开始输出对象，以最后一行告知错误
+			_ = json.NewEncoder(c.Writer).Encode(res)
+			return
+		}
+		c.JSON(200, res)
+	}
+}
+
 // ListDirectory 列出目录下内容
 func ListDirectory(c *gin.Context) {
 	var service explorer.DirectoryService
 	if err := c.ShouldBindUri(&service); err == nil {
 		res := service.ListDirectory(c)
-		c.JSON(200, res)
+		if !c.Writer.Written() {
+			c.JSON(200, res)
+		}
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// GetKeyPair 获取当前用户的端到端加密密钥对
+func GetKeyPair(c *gin.Context) {
+	c.JSON(200, explorer.GetKeyPair(CurrentUser(c)))
+}
+
+// SaveKeyPair 保存当前用户的端到端加密密钥对
+func SaveKeyPair(c *gin.Context) {
+	var service explorer.KeyPairService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Save(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetPublicKey 获取其他用户的公钥
+func GetPublicKey(c *gin.Context) {
+	var service explorer.PublicKeyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Get()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// EnableFolderEncryption 为目录启用端到端加密
+func EnableFolderEncryption(c *gin.Context) {
+	var service explorer.EnableEncryptionService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Enable(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetEncryptedFolder 获取加密目录信息
+func GetEncryptedFolder(c *gin.Context) {
+	var service explorer.EncryptedFolderService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Get(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GrantFolderKey 向其他用户分发目录密钥
+func GrantFolderKey(c *gin.Context) {
+	var service explorer.GrantEncryptionService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Grant(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// RevokeFolderKey 撤销其他用户的目录密钥
+func RevokeFolderKey(c *gin.Context) {
+	var service explorer.RevokeEncryptionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Revoke(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SetEncryptedName 设置对象的加密名称
+func SetEncryptedName(c *gin.Context) {
+	var service explorer.EncryptedNameService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SetShareEnvelope 设置分享链接使用的目录密钥
+func SetShareEnvelope(c *gin.Context) {
+	var service explorer.ShareEnvelopeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetShareEncryption 获取分享中的加密目录信息
+func GetShareEncryption(c *gin.Context) {
+	var service explorer.ShareEncryptionService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Get(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -9,13 +9,14 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
 )
 
 func DownloadArchive(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ArchiveService
@@ -28,7 +29,7 @@ func DownloadArchive(c *gin.Context) {
 
 func Archive(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemIDService
@@ -62,10 +63,148 @@ func Decompress(c *gin.Context) {
 	}
 }
 
+// ListChanges 列出用户空间的增量变更
+func ListChanges(c *gin.Context) {
+	var service explorer.ChangeListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ExportManifest 导出目录清单
+func ExportManifest(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	c.JSON(200, explorer.ExportManifest(ctx, c))
+}
+
+// ImportManifest 导入目录清单
+func ImportManifest(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.ManifestImportService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Import(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListFileVersions 列出文件历史版本
+func ListFileVersions(c *gin.Context) {
+	c.JSON(200, explorer.ListFileVersions(c))
+}
+
+// RestoreFileVersion 恢复文件历史版本
+func RestoreFileVersion(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.FileVersionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Restore(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteFileVersion 删除文件历史版本
+func DeleteFileVersion(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.FileVersionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListTrash 列出回收站中的对象
+func ListTrash(c *gin.Context) {
+	c.JSON(200, explorer.ListTrash(c, CurrentUser(c)))
+}
+
+// RestoreTrash 恢复回收站中的对象
+func RestoreTrash(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.TrashService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Restore(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateTorrent 创建种子制作任务
+func CreateTorrent(c *gin.Context) {
+	var service explorer.ItemTorrentService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.CreateTorrentTask(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateFetchTask 创建从 URL 下载任务
+func CreateFetchTask(c *gin.Context) {
+	var service explorer.ItemFetchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.CreateFetchTask(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// PublicFolderList 游客列出公开目录
+func PublicFolderList(c *gin.Context) {
+	var service explorer.PublicFolderService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.List(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// PublicFolderDownload 游客下载公开目录中的文件，成功时重定向至下载地址
+func PublicFolderDownload(c *gin.Context) {
+	var service explorer.PublicFolderService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+			return
+		}
+		c.Redirect(http.StatusFound, res.Data.(string))
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AnonymousGetContent 匿名获取文件资源
 func AnonymousGetContent(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileAnonymousGetService
@@ -82,7 +221,7 @@ func AnonymousGetContent(c *gin.Context) {
 // AnonymousPermLink Deprecated 文件签名后的永久链接
 func AnonymousPermLinkDeprecated(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileAnonymousGetService
@@ -105,7 +244,7 @@ func AnonymousPermLinkDeprecated(c *gin.Context) {
 // AnonymousPermLink 文件中转后的永久直链接
 func AnonymousPermLink(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	sourceLinkRaw, ok := c.Get("source_link")
@@ -137,7 +276,7 @@ func AnonymousPermLink(c *gin.Context) {
 
 func GetSource(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemIDService
@@ -149,10 +288,25 @@ func GetSource(c *gin.Context) {
 	}
 }
 
+// BatchThumb 批量获取文件缩略图状态
+func BatchThumb(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.ItemIDService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Thumbs(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // Thumb 获取文件缩略图
 func Thumb(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	fs, err := filesystem.NewFileSystemFromContext(c)
@@ -190,7 +344,7 @@ func Thumb(c *gin.Context) {
 // Preview 预览文件
 func Preview(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileIDService
@@ -213,7 +367,7 @@ func Preview(c *gin.Context) {
 // PreviewText 预览文本文件
 func PreviewText(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileIDService
@@ -231,7 +385,7 @@ func PreviewText(c *gin.Context) {
 // GetDocPreview 获取DOC文件预览地址
 func GetDocPreview(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileIDService
@@ -243,10 +397,47 @@ func GetDocPreview(c *gin.Context) {
 	}
 }
 
+// GetPreviewSession 获取文件的预览方式
+func GetPreviewSession(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.FileIDService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.CreatePreviewSession(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateHLSSession 获取视频 HLS 播放地址，必要时创建转码任务
+func CreateHLSSession(c *gin.Context) {
+	c.JSON(200, explorer.CreateHLSSession(c))
+}
+
+// GetHLSContent 获取视频 HLS 播放列表或分片
+func GetHLSContent(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.HLSContentService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Serve(ctx, c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // CreateDownloadSession 创建文件下载会话
 func CreateDownloadSession(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileIDService
@@ -261,7 +452,7 @@ func CreateDownloadSession(c *gin.Context) {
 // Download 文件下载
 func Download(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.DownloadService
@@ -278,12 +469,16 @@ func Download(c *gin.Context) {
 // PutContent 更新文件内容
 func PutContent(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.FileIDService
 	if err := c.ShouldBindUri(&service); err == nil {
 		res := service.PutContent(ctx, c)
+		if res.Code == serializer.CodeVersionConflict {
+			c.JSON(http.StatusPreconditionFailed, res)
+			return
+		}
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -293,7 +488,7 @@ func PutContent(c *gin.Context) {
 // FileUpload 本地策略文件上传
 func FileUpload(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.UploadService
@@ -355,7 +550,7 @@ func FileUpload(c *gin.Context) {
 // DeleteUploadSession 删除上传会话
 func DeleteUploadSession(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.UploadSessionService
@@ -370,7 +565,7 @@ func DeleteUploadSession(c *gin.Context) {
 // DeleteAllUploadSession 删除全部上传会话
 func DeleteAllUploadSession(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	res := explorer.DeleteAllUploadSession(ctx, c)
@@ -380,7 +575,7 @@ func DeleteAllUploadSession(c *gin.Context) {
 // GetUploadSession 创建上传会话
 func GetUploadSession(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.CreateUploadSessionService
@@ -392,6 +587,21 @@ func GetUploadSession(c *gin.Context) {
 	}
 }
 
+// InstantUpload 秒传文件
+func InstantUpload(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.InstantUploadService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Upload(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // SearchFile 搜索文件
 func SearchFile(c *gin.Context) {
 	var service explorer.ItemSearchService
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListMusicArtists 列出艺术家
+func ListMusicArtists(c *gin.Context) {
+	var service explorer.MusicListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Artists(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListMusicAlbums 列出专辑
+func ListMusicAlbums(c *gin.Context) {
+	var service explorer.MusicListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Albums(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListMusicTracks 列出曲目
+func ListMusicTracks(c *gin.Context) {
+	var service explorer.MusicListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Tracks(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreatePlaylist 创建播放列表
+func CreatePlaylist(c *gin.Context) {
+	var service explorer.PlaylistService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListPlaylists 列出播放列表
+func ListPlaylists(c *gin.Context) {
+	c.JSON(200, explorer.ListPlaylists(c, CurrentUser(c)))
+}
+
+// GetPlaylist 获取播放列表中的曲目
+func GetPlaylist(c *gin.Context) {
+	var service explorer.PlaylistIDService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Get(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UpdatePlaylist 更新播放列表
+func UpdatePlaylist(c *gin.Context) {
+	var id explorer.PlaylistIDService
+	if err := c.ShouldBindUri(&id); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	var service explorer.PlaylistService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c), id.ID)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeletePlaylist 删除播放列表
+func DeletePlaylist(c *gin.Context) {
+	var service explorer.PlaylistIDService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
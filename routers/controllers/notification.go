@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/notification"
+	"github.com/gin-gonic/gin"
+)
+
+// ListNotifications 列出站内通知
+func ListNotifications(c *gin.Context) {
+	var service notification.ListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ReadNotifications 将站内通知标记为已读
+func ReadNotifications(c *gin.Context) {
+	var service notification.ReadService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Read(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteNotification 删除站内通知
+func DeleteNotification(c *gin.Context) {
+	var service notification.Service
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// StreamNotifications 通过 WebSocket 推送站内通知等事件
+func StreamNotifications(c *gin.Context) {
+	res := notification.Stream(c, CurrentUser(c))
+	if res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
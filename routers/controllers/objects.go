@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
 )
@@ -10,7 +11,7 @@ import (
 // Delete 删除文件或目录
 func Delete(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemIDService
@@ -25,7 +26,7 @@ func Delete(c *gin.Context) {
 // Move 移动文件或目录
 func Move(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemMoveService
@@ -40,7 +41,7 @@ func Move(c *gin.Context) {
 // Copy 复制文件或目录
 func Copy(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemMoveService
@@ -55,7 +56,7 @@ func Copy(c *gin.Context) {
 // Rename 重命名文件或目录
 func Rename(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemRenameService
@@ -67,10 +68,25 @@ func Rename(c *gin.Context) {
 	}
 }
 
+// StatObjects 批量获取对象状态
+func StatObjects(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.ItemStatService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Stat(ctx, c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // Rename 重命名文件或目录
 func GetProperty(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service explorer.ItemPropertyService
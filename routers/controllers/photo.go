@@ -0,0 +1,28 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// PhotoTimeline 按时间线列出照片
+func PhotoTimeline(c *gin.Context) {
+	var service explorer.PhotoTimelineService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Timeline(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// PhotoAlbums 列出按时段归档的相册
+func PhotoAlbums(c *gin.Context) {
+	var service explorer.PhotoFilterService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Albums(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	s3Service "github.com/cloudreve/Cloudreve/v3/service/s3"
+	"github.com/gin-gonic/gin"
+)
+
+// 未实现的存储桶子资源
+var s3BucketSubresources = []string{"acl", "cors", "encryption", "lifecycle", "logging", "notification",
+	"object-lock", "policy", "replication", "tagging", "versioning", "versions", "website"}
+
+// 未实现的对象子资源
+var s3ObjectSubresources = []string{"acl", "legal-hold", "retention", "tagging", "torrent"}
+
+// s3Response 写出 S3 响应
+func s3Response(c *gin.Context, res s3.Response) {
+	res.Write(c.Writer, c.Request)
+}
+
+// hasS3Query 请求是否带有任一查询参数
+func hasS3Query(c *gin.Context, names ...string) bool {
+	for _, name := range names {
+		if _, ok := c.GetQuery(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// S3Root 处理服务级别的 S3 请求
+func S3Root(c *gin.Context) {
+	if c.Request.Method != http.MethodGet {
+		s3Response(c, s3.Err(s3.ErrMethodNotAllowed))
+		return
+	}
+
+	s3Response(c, s3Service.ListBuckets(c))
+}
+
+// S3Bucket 处理存储桶级别的 S3 请求
+func S3Bucket(c *gin.Context) {
+	var service s3Service.BucketService
+	if err := c.ShouldBindUri(&service); err != nil {
+		s3Response(c, s3.Err(s3.ErrInvalidRequest.WithMessage(err.Error())))
+		return
+	}
+
+	if hasS3Query(c, s3BucketSubresources...) {
+		s3Response(c, s3.Err(s3.ErrNotImplemented))
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodGet:
+		if hasS3Query(c, "location") {
+			s3Response(c, service.Location(c))
+			return
+		}
+
+		if hasS3Query(c, "uploads") {
+			s3Response(c, s3.Err(s3.ErrNotImplemented))
+			return
+		}
+
+		var list s3Service.ListObjectsService
+		if err := c.ShouldBindQuery(&list); err != nil {
+			s3Response(c, s3.Err(s3.ErrInvalidRequest.WithMessage(err.Error())))
+			return
+		}
+		list.Bucket = service.Bucket
+		s3Response(c, list.List(c))
+	case http.MethodHead:
+		s3Response(c, service.Head(c))
+	case http.MethodPut:
+		s3Response(c, service.Create(c))
+	case http.MethodDelete:
+		s3Response(c, service.Delete(c))
+	case http.MethodPost:
+		if !hasS3Query(c, "delete") {
+			s3Response(c, s3.Err(s3.ErrMethodNotAllowed))
+			return
+		}
+		s3Response(c, service.DeleteObjects(c))
+	default:
+		s3Response(c, s3.Err(s3.ErrMethodNotAllowed))
+	}
+}
+
+// S3Object 处理对象级别的 S3 请求
+func S3Object(c *gin.Context) {
+	// 路径形如 /bucket/ 时为存储桶请求
+	if strings.TrimPrefix(c.Param("key"), "/") == "" {
+		S3Bucket(c)
+		return
+	}
+
+	var service s3Service.MultipartService
+	if err := c.ShouldBindUri(&service); err != nil {
+		s3Response(c, s3.Err(s3.ErrInvalidRequest.WithMessage(err.Error())))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err != nil {
+		s3Response(c, s3.Err(s3.ErrInvalidRequest.WithMessage(err.Error())))
+		return
+	}
+
+	if hasS3Query(c, s3ObjectSubresources...) {
+		s3Response(c, s3.Err(s3.ErrNotImplemented))
+		return
+	}
+
+	multipart := hasS3Query(c, "uploadId")
+	switch c.Request.Method {
+	case http.MethodGet:
+		if multipart {
+			s3Response(c, s3.Err(s3.ErrNotImplemented))
+			return
+		}
+		s3Response(c, service.Get(c))
+	case http.MethodHead:
+		s3Response(c, service.Head(c))
+	case http.MethodPut:
+		if multipart {
+			s3Response(c, service.UploadPart(c))
+			return
+		}
+		s3Response(c, service.Put(c))
+	case http.MethodPost:
+		if hasS3Query(c, "uploads") {
+			s3Response(c, service.Create(c))
+			return
+		}
+
+		if !multipart {
+			s3Response(c, s3.Err(s3.ErrMethodNotAllowed))
+			return
+		}
+		s3Response(c, service.Complete(c))
+	case http.MethodDelete:
+		if multipart {
+			s3Response(c, service.Abort(c))
+			return
+		}
+		s3Response(c, service.Delete(c))
+	default:
+		s3Response(c, s3.Err(s3.ErrMethodNotAllowed))
+	}
+}
@@ -0,0 +1,175 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/scim"
+	scimService "github.com/cloudreve/Cloudreve/v3/service/scim"
+	"github.com/gin-gonic/gin"
+)
+
+// scimResponse 返回 SCIM 响应
+func scimResponse(c *gin.Context, res scim.Response) {
+	if res.Body == nil {
+		c.Status(res.Status)
+		return
+	}
+
+	c.JSON(res.Status, res.Body)
+}
+
+// scimBindError 请求格式错误
+func scimBindError(c *gin.Context, err error) {
+	scimResponse(c, scim.Err(http.StatusBadRequest, scim.ErrInvalidSyntax, err.Error()))
+}
+
+// SCIMListUsers 列出用户
+func SCIMListUsers(c *gin.Context) {
+	var service scimService.ListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		scimResponse(c, service.Users())
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMCreateUser 创建用户
+func SCIMCreateUser(c *gin.Context) {
+	var service scimService.UserService
+	var resource scim.User
+	if err := c.ShouldBindJSON(&resource); err == nil {
+		scimResponse(c, service.Create(&resource))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMGetUser 获取用户
+func SCIMGetUser(c *gin.Context) {
+	var service scimService.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		scimResponse(c, service.Get())
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMReplaceUser 替换用户信息
+func SCIMReplaceUser(c *gin.Context) {
+	var service scimService.UserService
+	var resource scim.User
+	if err := c.ShouldBindUri(&service); err != nil {
+		scimBindError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&resource); err == nil {
+		scimResponse(c, service.Replace(&resource))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMPatchUser 修改用户信息
+func SCIMPatchUser(c *gin.Context) {
+	var service scimService.UserService
+	var req scim.PatchRequest
+	if err := c.ShouldBindUri(&service); err != nil {
+		scimBindError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err == nil {
+		scimResponse(c, service.Patch(&req))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMDeleteUser 注销用户
+func SCIMDeleteUser(c *gin.Context) {
+	var service scimService.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		scimResponse(c, service.Delete())
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMListGroups 列出用户组
+func SCIMListGroups(c *gin.Context) {
+	var service scimService.ListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		scimResponse(c, service.Groups())
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMCreateGroup 关联用户组
+func SCIMCreateGroup(c *gin.Context) {
+	var service scimService.GroupService
+	var resource scim.Group
+	if err := c.ShouldBindJSON(&resource); err == nil {
+		scimResponse(c, service.Create(&resource))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMGetGroup 获取用户组
+func SCIMGetGroup(c *gin.Context) {
+	var service scimService.GroupService
+	if err := c.ShouldBindUri(&service); err != nil {
+		scimBindError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err == nil {
+		scimResponse(c, service.Get())
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMReplaceGroup 替换用户组成员
+func SCIMReplaceGroup(c *gin.Context) {
+	var service scimService.GroupService
+	var resource scim.Group
+	if err := c.ShouldBindUri(&service); err != nil {
+		scimBindError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&resource); err == nil {
+		scimResponse(c, service.Replace(&resource))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMPatchGroup 修改用户组成员
+func SCIMPatchGroup(c *gin.Context) {
+	var service scimService.GroupService
+	var req scim.PatchRequest
+	if err := c.ShouldBindUri(&service); err != nil {
+		scimBindError(c, err)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err == nil {
+		scimResponse(c, service.Patch(&req))
+	} else {
+		scimBindError(c, err)
+	}
+}
+
+// SCIMDeleteGroup 解除用户组关联
+func SCIMDeleteGroup(c *gin.Context) {
+	var service scimService.GroupService
+	if err := c.ShouldBindUri(&service); err == nil {
+		scimResponse(c, service.Delete())
+	} else {
+		scimBindError(c, err)
+	}
+}
@@ -34,6 +34,17 @@ func GetShare(c *gin.Context) {
 	}
 }
 
+// ReportShare 举报分享
+func ReportShare(c *gin.Context) {
+	var service share.ReportService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Report(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // ListShare 列出分享
 func ListShare(c *gin.Context) {
 	var service share.ShareListService
@@ -67,6 +78,39 @@ func UpdateShare(c *gin.Context) {
 	}
 }
 
+// ListShareAccessLogs 列出分享的访问记录
+func ListShareAccessLogs(c *gin.Context) {
+	var service share.ShareAccessLogService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetShareUsage 获取分享的流量和下载次数统计
+func GetShareUsage(c *gin.Context) {
+	var service share.Service
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Usage(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UploadToShare 向投递分享上传文件
+func UploadToShare(c *gin.Context) {
+	var service share.UploadService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Upload(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // DeleteShare 删除分享
 func DeleteShare(c *gin.Context) {
 	var service share.Service
@@ -92,7 +136,7 @@ func GetShareDownload(c *gin.Context) {
 // PreviewShare 预览分享文件内容
 func PreviewShare(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service share.Service
@@ -115,7 +159,7 @@ func PreviewShare(c *gin.Context) {
 // PreviewShareText 预览文本文件
 func PreviewShareText(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service share.Service
@@ -133,7 +177,7 @@ func PreviewShareText(c *gin.Context) {
 // PreviewShareReadme 预览文本自述文件
 func PreviewShareReadme(c *gin.Context) {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	var service share.Service
@@ -173,12 +217,25 @@ func GetShareDocPreview(c *gin.Context) {
 	}
 }
 
+// GetSharePreviewSession 获取分享文件的预览方式
+func GetSharePreviewSession(c *gin.Context) {
+	var service share.Service
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.CreatePreviewSession(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // ListSharedFolder 列出分享的目录下的对象
 func ListSharedFolder(c *gin.Context) {
 	var service share.Service
 	if err := c.ShouldBindUri(&service); err == nil {
 		res := service.List(c)
-		c.JSON(200, res)
+		if !c.Writer.Written() {
+			c.JSON(200, res)
+		}
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
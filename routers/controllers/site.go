@@ -3,6 +3,7 @@ package controllers
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/preview"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/pkg/wopi"
@@ -24,10 +25,14 @@ func SiteConfig(c *gin.Context) {
 		"home_view_method",
 		"share_view_method",
 		"authn_enabled",
+		"oidc_enabled",
+		"oidc_display_name",
+		"e2ee_enabled",
 		"captcha_ReCaptchaKey",
 		"captcha_type",
 		"captcha_TCaptcha_CaptchaAppId",
 		"register_enabled",
+		"register_invite_only",
 		"show_app_promotion",
 	)
 
@@ -36,14 +41,21 @@ func SiteConfig(c *gin.Context) {
 		wopiExts = wopi.Default.AvailableExts()
 	}
 
+	var previewHandlers []preview.Handler
+	if registry, err := preview.FromSetting(); err == nil {
+		previewHandlers = registry.Handlers()
+	} else {
+		util.Log().Warning("Failed to parse preview handlers: %s", err)
+	}
+
 	// 如果已登录，则同时返回用户信息和标签
 	user, _ := c.Get("user")
 	if user, ok := user.(*model.User); ok {
-		c.JSON(200, serializer.BuildSiteConfig(siteConfig, user, wopiExts))
+		c.JSON(200, serializer.BuildSiteConfig(siteConfig, user, wopiExts, previewHandlers))
 		return
 	}
 
-	c.JSON(200, serializer.BuildSiteConfig(siteConfig, nil, wopiExts))
+	c.JSON(200, serializer.BuildSiteConfig(siteConfig, nil, wopiExts, previewHandlers))
 }
 
 // Ping 状态检查页面
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSourceLink 为文件创建外链
+func CreateSourceLink(c *gin.Context) {
+	var service explorer.SourceLinkService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListSourceLinks 列出文件外链
+func ListSourceLinks(c *gin.Context) {
+	c.JSON(200, explorer.ListSourceLinks(c, CurrentUser(c)))
+}
+
+// UpdateSourceLink 更新外链设置
+func UpdateSourceLink(c *gin.Context) {
+	var service explorer.SourceLinkService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteSourceLink 撤销外链
+func DeleteSourceLink(c *gin.Context) {
+	c.JSON(200, explorer.DeleteSourceLink(c, CurrentUser(c)))
+}
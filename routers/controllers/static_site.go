@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateStaticSite 将目录发布为静态网站
+func CreateStaticSite(c *gin.Context) {
+	var service explorer.StaticSiteCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListStaticSites 列出发布的静态网站
+func ListStaticSites(c *gin.Context) {
+	c.JSON(200, explorer.ListStaticSites(c, CurrentUser(c)))
+}
+
+// DeleteStaticSite 取消发布静态网站
+func DeleteStaticSite(c *gin.Context) {
+	var service explorer.StaticSiteIDService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ServeStaticSite 游客访问静态网站，出错时返回纯文本
+func ServeStaticSite(c *gin.Context) {
+	var service explorer.StaticSiteService
+	if err := c.ShouldBindUri(&service); err == nil {
+		if res := service.Serve(c); res.Code != 0 {
+			c.String(http.StatusInternalServerError, res.Msg)
+		}
+	} else {
+		c.String(http.StatusBadRequest, err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/acl"
+	"github.com/cloudreve/Cloudreve/v3/service/share"
+	"github.com/cloudreve/Cloudreve/v3/service/team"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTeam 创建团队
+func CreateTeam(c *gin.Context) {
+	var service team.CreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListTeams 列出所在的团队
+func ListTeams(c *gin.Context) {
+	c.JSON(200, team.List(c, CurrentUser(c)))
+}
+
+// DeleteTeam 删除团队
+func DeleteTeam(c *gin.Context) {
+	c.JSON(200, team.Delete(c, CurrentUser(c)))
+}
+
+// ListTeamMembers 列出团队成员
+func ListTeamMembers(c *gin.Context) {
+	c.JSON(200, team.ListMembers(c, CurrentUser(c)))
+}
+
+// SetTeamMember 添加团队成员或更新其角色
+func SetTeamMember(c *gin.Context) {
+	var service team.MemberService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Set(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// RemoveTeamMember 移除团队成员
+func RemoveTeamMember(c *gin.Context) {
+	c.JSON(200, team.RemoveMember(c, CurrentUser(c)))
+}
+
+// ListTeamDirectory 列出团队空间中的目录
+func ListTeamDirectory(c *gin.Context) {
+	var service team.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetTeamDownload 获取团队空间中文件的下载地址
+func GetTeamDownload(c *gin.Context) {
+	var service team.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UploadToTeam 上传文件到团队空间
+func UploadToTeam(c *gin.Context) {
+	var service team.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Upload(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateTeamDirectory 在团队空间中创建目录
+func CreateTeamDirectory(c *gin.Context) {
+	var service team.PathService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.CreateDirectory(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteTeamObject 删除团队空间中的对象
+func DeleteTeamObject(c *gin.Context) {
+	var service team.PathService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateTeamShare 分享团队空间中的对象
+func CreateTeamShare(c *gin.Context) {
+	var service share.ShareCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := team.CreateShare(c, CurrentUser(c), &service)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListTeamShares 列出团队的分享
+func ListTeamShares(c *gin.Context) {
+	var service share.ShareListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := team.ListShares(c, CurrentUser(c), &service)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteTeamShare 删除团队的分享
+func DeleteTeamShare(c *gin.Context) {
+	c.JSON(200, team.DeleteShare(c, CurrentUser(c)))
+}
+
+// CreateTeamACL 为团队空间中的目录添加 ACL 条目
+func CreateTeamACL(c *gin.Context) {
+	var service acl.CreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := team.CreateACL(c, CurrentUser(c), &service)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListTeamACLs 列出团队空间中目录上设置的 ACL 条目
+func ListTeamACLs(c *gin.Context) {
+	c.JSON(200, team.ListACLs(c, CurrentUser(c)))
+}
+
+// DeleteTeamACL 删除团队空间中目录上的 ACL 条目
+func DeleteTeamACL(c *gin.Context) {
+	c.JSON(200, team.DeleteACL(c, CurrentUser(c)))
+}
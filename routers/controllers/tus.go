@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// tusResponse 将服务响应转换为 tus 协议的 HTTP 状态码，成功时返回 success
+func tusResponse(c *gin.Context, res serializer.Response, success int) {
+	status := http.StatusInternalServerError
+	switch res.Code {
+	case 0:
+		c.Status(success)
+		return
+	case serializer.CodeParamErr, serializer.CodeInvalidContentLength:
+		status = http.StatusBadRequest
+	case serializer.CodeUploadSessionExpired:
+		status = http.StatusNotFound
+	case serializer.CodeInvalidChunkIndex:
+		status = http.StatusConflict
+	case serializer.CodePolicyNotAllowed:
+		status = http.StatusForbidden
+	case serializer.CodeFileTooLarge, serializer.CodeInsufficientCapacity, serializer.CodeFolderQuotaExceeded:
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	if c.Request.Method == http.MethodHead {
+		c.Status(status)
+		return
+	}
+
+	c.String(status, res.Msg)
+}
+
+// TusOptions 返回服务端支持的 tus 协议扩展
+func TusOptions(c *gin.Context) {
+	c.Header("Tus-Extension", "creation,termination,expiration")
+	if user := CurrentUser(c); user != nil && user.Policy.MaxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatUint(user.Policy.MaxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate 创建 tus 可续传上传
+func TusCreate(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.TusCreateService
+	if err := c.ShouldBindHeader(&service); err == nil {
+		tusResponse(c, service.Create(ctx, c), http.StatusCreated)
+	} else {
+		tusResponse(c, serializer.ParamErr("Upload-Length is required", err), 0)
+	}
+}
+
+// TusHead 查询 tus 可续传上传的进度
+func TusHead(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.TusUploadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		tusResponse(c, service.Head(ctx, c), http.StatusOK)
+	} else {
+		tusResponse(c, serializer.ParamErr("", err), 0)
+	}
+}
+
+// TusPatch 上传 tus 可续传上传的数据
+func TusPatch(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+	defer request.BlackHole(c.Request.Body)
+
+	if c.ContentType() != explorer.TusOffsetContentType {
+		c.String(http.StatusUnsupportedMediaType, "Content-Type must be %s", explorer.TusOffsetContentType)
+		return
+	}
+
+	var service explorer.TusUploadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		tusResponse(c, service.Patch(ctx, c), http.StatusNoContent)
+	} else {
+		tusResponse(c, serializer.ParamErr("", err), 0)
+	}
+}
+
+// TusDelete 终止 tus 可续传上传
+func TusDelete(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	var service explorer.TusUploadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		tusResponse(c, service.Delete(ctx, c), http.StatusNoContent)
+	} else {
+		tusResponse(c, serializer.ParamErr("", err), 0)
+	}
+}
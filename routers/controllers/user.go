@@ -3,6 +3,8 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"path"
+	"strconv"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/authn"
@@ -69,16 +71,18 @@ func FinishLoginAuthn(c *gin.Context) {
 		return
 	}
 
-	_, err = instance.FinishLogin(expectedUser, sessionData, c.Request)
+	credential, err := instance.FinishLogin(expectedUser, sessionData, c.Request)
 
 	if err != nil {
 		c.JSON(200, serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err))
 		return
 	}
 
-	util.SetSession(c, map[string]interface{}{
-		"user_id": expectedUser.ID,
-	})
+	if err := expectedUser.UpdateAuthn(credential); err != nil {
+		util.Log().Warning("Failed to update authenticator of user %d: %s", expectedUser.ID, err)
+	}
+
+	user.SetLoginSession(c, expectedUser.ID)
 	c.JSON(200, serializer.BuildUserResponse(expectedUser))
 }
 
@@ -147,6 +151,48 @@ func FinishRegAuthn(c *gin.Context) {
 	})
 }
 
+// Start2FAAuthn 开始使用 WebAuthn 验证器进行二步验证
+func Start2FAAuthn(c *gin.Context) {
+	var service user.Authn2FAService
+	res := service.Start(c)
+	c.JSON(200, res)
+}
+
+// Finish2FAAuthn 使用 WebAuthn 验证器完成二步验证登录
+func Finish2FAAuthn(c *gin.Context) {
+	var service user.Authn2FAService
+	res := service.Finish(c)
+	c.JSON(200, res)
+}
+
+// StartOIDCLogin 获取单点登录地址
+func StartOIDCLogin(c *gin.Context) {
+	var service user.OIDCLoginService
+	res := service.Start(c)
+	c.JSON(200, res)
+}
+
+// OIDCCallback 单点登录回调，完成后跳转至前端页面
+func OIDCCallback(c *gin.Context) {
+	var service user.OIDCCallbackService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Callback(c)
+		redirect := model.GetSiteURL()
+		if res.Code == 0 {
+			redirect.Path = path.Join(redirect.Path, "/home")
+		} else {
+			redirect.Path = path.Join(redirect.Path, "/login")
+			queries := redirect.Query()
+			queries.Add("code", strconv.Itoa(res.Code))
+			queries.Add("msg", res.Msg)
+			redirect.RawQuery = queries.Encode()
+		}
+		c.Redirect(303, redirect.String())
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserLogin 用户登录
 func UserLogin(c *gin.Context) {
 	var service user.UserLoginService
@@ -215,8 +261,99 @@ func UserActivate(c *gin.Context) {
 
 // UserSignOut 用户退出登录
 func UserSignOut(c *gin.Context) {
-	util.DeleteSession(c, "user_id")
-	c.JSON(200, serializer.Response{})
+	c.JSON(200, user.SignOut(c))
+}
+
+// UserListLoginSessions 列出登录会话
+func UserListLoginSessions(c *gin.Context) {
+	res := user.ListLoginSessions(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// UserRevokeLoginSession 吊销登录会话
+func UserRevokeLoginSession(c *gin.Context) {
+	var service user.LoginSessionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Revoke(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserListActivities 列出用户动态
+func UserListActivities(c *gin.Context) {
+	var service user.ActivityListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserDeleteAccount 注销当前账户
+func UserDeleteAccount(c *gin.Context) {
+	var service user.AccountDeleteService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserListInvites 列出用户创建的邀请码
+func UserListInvites(c *gin.Context) {
+	var service user.InviteService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// UserCreateInvite 创建邀请码
+func UserCreateInvite(c *gin.Context) {
+	var service user.InviteService
+	res := service.Create(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// UserDeleteInvite 删除邀请码
+func UserDeleteInvite(c *gin.Context) {
+	var service user.InviteService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserListAPITokens 列出用户创建的访问令牌
+func UserListAPITokens(c *gin.Context) {
+	res := user.ListAPITokens(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// UserCreateAPIToken 创建访问令牌
+func UserCreateAPIToken(c *gin.Context) {
+	var service user.CreateAPITokenService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserDeleteAPIToken 吊销访问令牌
+func UserDeleteAPIToken(c *gin.Context) {
+	var service user.APITokenService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
 }
 
 // UserMe 获取当前登录的用户
@@ -244,6 +381,30 @@ func UserTasks(c *gin.Context) {
 	}
 }
 
+// UserCreateExport 创建用户数据导出任务
+func UserCreateExport(c *gin.Context) {
+	var service user.ExportService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserDownloadExport 下载导出分卷
+func UserDownloadExport(c *gin.Context) {
+	var service user.ExportDownloadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(c, CurrentUser(c))
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserSetting 获取用户设定
 func UserSetting(c *gin.Context) {
 	var service user.SettingService
@@ -349,6 +510,8 @@ func UpdateOption(c *gin.Context) {
 			subService = &user.DeleteWebAuthn{}
 		case "theme":
 			subService = &user.ThemeChose{}
+		case "notification":
+			subService = &user.NotificationChange{}
 		default:
 			subService = &user.ChangerNick{}
 		}
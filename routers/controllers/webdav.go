@@ -10,16 +10,29 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/service/setting"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"sync"
 )
 
-var handler *webdav.Handler
+var (
+	handler        *webdav.Handler
+	calDAVHandler  *webdav.Handler
+	cardDAVHandler *webdav.Handler
+)
 
 func init() {
 	handler = &webdav.Handler{
-		Prefix:     "/dav",
-		LockSystem: make(map[uint]webdav.LockSystem),
-		Mutex:      &sync.Mutex{},
+		Prefix:      "/dav",
+		LockSystem:  webdav.NewDBLS,
+		SharedMount: true,
+	}
+	calDAVHandler = &webdav.Handler{
+		Prefix:     "/caldav",
+		Collection: webdav.CollectionCalendar,
+		LockSystem: webdav.NewDBLS,
+	}
+	cardDAVHandler = &webdav.Handler{
+		Prefix:     "/carddav",
+		Collection: webdav.CollectionAddressbook,
+		LockSystem: webdav.NewDBLS,
 	}
 }
 
@@ -34,29 +47,88 @@ func ServeWebDAV(c *gin.Context) {
 	if webdavCtx, ok := c.Get("webdav"); ok {
 		application := webdavCtx.(*model.Webdav)
 
-		// 重定根目录
-		if application.Root != "/" {
-			if exist, root := fs.IsPathExist(application.Root); exist {
-				root.Position = ""
-				root.Name = "/"
-				fs.Root = root
-			}
+		// 重定根目录，根目录已不存在时拒绝访问
+		if err := fs.UseRoot(application.Root); err != nil {
+			fs.Recycle()
+			c.Status(http.StatusForbidden)
+			return
+		}
+
+		if !allowWebDAVMethod(c, application) {
+			return
+		}
+	}
+
+	handler.ServeHTTP(c.Writer, c.Request, fs)
+}
+
+// ServeCalDAV 处理CalDAV相关请求
+func ServeCalDAV(c *gin.Context) {
+	serveCollection(c, calDAVHandler, "caldav_root")
+}
+
+// ServeCardDAV 处理CardDAV相关请求
+func ServeCardDAV(c *gin.Context) {
+	serveCollection(c, cardDAVHandler, "carddav_root")
+}
+
+// serveCollection 以设置项 rootSetting 指定的用户目录为根目录处理CalDAV/CardDAV请求，目录不存在时自动创建
+func serveCollection(c *gin.Context, h *webdav.Handler, rootSetting string) {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		util.Log().Warning("Failed to initialize filesystem for %s，%s", h.Prefix, err)
+		return
+	}
+
+	if webdavCtx, ok := c.Get("webdav"); ok {
+		if !allowWebDAVMethod(c, webdavCtx.(*model.Webdav)) {
+			fs.Recycle()
+			return
 		}
+	}
 
-		// 检查是否只读
-		if application.Readonly {
-			switch c.Request.Method {
-			case "DELETE", "PUT", "MKCOL", "COPY", "MOVE":
-				c.Status(http.StatusForbidden)
+	// OPTIONS 请求未经鉴权，无需定位根目录
+	if fs.User.ID > 0 {
+		rootPath := model.GetSettingByName(rootSetting)
+		exist, root := fs.IsPathExist(rootPath)
+		if !exist {
+			if root, err = fs.CreateDirectory(c, rootPath); err != nil {
+				util.Log().Warning("Failed to create %s root folder %q: %s", h.Prefix, rootPath, err)
+				fs.Recycle()
+				c.Status(http.StatusInternalServerError)
 				return
 			}
 		}
 
-		// 更新Context
-		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), fsctx.WebDAVCtx, application))
+		root.Position = ""
+		root.Name = "/"
+		fs.Root = root
 	}
 
-	handler.ServeHTTP(c.Writer, c.Request, fs)
+	h.ServeHTTP(c.Writer, c.Request, fs)
+}
+
+// RedirectDAVDiscovery 将CalDAV/CardDAV服务发现请求重定向至服务地址
+func RedirectDAVDiscovery(target string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, target)
+	}
+}
+
+// allowWebDAVMethod 检查WebDAV账户是否允许当前请求并更新Context，只读账户不能修改文件
+func allowWebDAVMethod(c *gin.Context, application *model.Webdav) bool {
+	// 检查是否只读
+	if application.Readonly {
+		switch c.Request.Method {
+		case "DELETE", "PUT", "MKCOL", "COPY", "MOVE", "PROPPATCH", "MKCALENDAR", "LOCK", "UNLOCK":
+			c.Status(http.StatusForbidden)
+			return false
+		}
+	}
+
+	// 更新Context
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), fsctx.WebDAVCtx, application))
+	return true
 }
 
 // GetWebDAVAccounts 获取webdav账号列表
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// UserListWebhooks 列出用户的 Webhook
+func UserListWebhooks(c *gin.Context) {
+	c.JSON(200, webhook.List(c, CurrentUser(c).ID))
+}
+
+// UserCreateWebhook 创建用户的 Webhook
+func UserCreateWebhook(c *gin.Context) {
+	createWebhook(c, CurrentUser(c).ID, model.GetIntSetting("webhook_user_max", 10))
+}
+
+// UserUpdateWebhook 更新用户的 Webhook
+func UserUpdateWebhook(c *gin.Context) {
+	updateWebhook(c, CurrentUser(c).ID)
+}
+
+// UserDeleteWebhook 删除用户的 Webhook
+func UserDeleteWebhook(c *gin.Context) {
+	deleteWebhook(c, CurrentUser(c).ID)
+}
+
+// UserListWebhookDeliveries 列出用户 Webhook 的投递记录
+func UserListWebhookDeliveries(c *gin.Context) {
+	listWebhookDeliveries(c, CurrentUser(c).ID)
+}
+
+// AdminListWebhooks 列出管理员创建的 Webhook
+func AdminListWebhooks(c *gin.Context) {
+	c.JSON(200, webhook.List(c, 0))
+}
+
+// AdminCreateWebhook 创建接收所有用户事件的 Webhook
+func AdminCreateWebhook(c *gin.Context) {
+	createWebhook(c, 0, 0)
+}
+
+// AdminUpdateWebhook 更新管理员创建的 Webhook
+func AdminUpdateWebhook(c *gin.Context) {
+	updateWebhook(c, 0)
+}
+
+// AdminDeleteWebhook 删除管理员创建的 Webhook
+func AdminDeleteWebhook(c *gin.Context) {
+	deleteWebhook(c, 0)
+}
+
+// AdminListWebhookDeliveries 列出管理员 Webhook 的投递记录
+func AdminListWebhookDeliveries(c *gin.Context) {
+	listWebhookDeliveries(c, 0)
+}
+
+func createWebhook(c *gin.Context, uid uint, max int) {
+	var service webhook.SaveService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, uid, max)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+func updateWebhook(c *gin.Context, uid uint) {
+	var target webhook.Service
+	if err := c.ShouldBindUri(&target); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	var service webhook.SaveService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, target.ID, uid)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+func deleteWebhook(c *gin.Context, uid uint) {
+	var service webhook.Service
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, uid)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+func listWebhookDeliveries(c *gin.Context, uid uint) {
+	var service webhook.Service
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Deliveries(c, uid)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/pkg/wopi"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
@@ -35,9 +37,15 @@ func GetFile(c *gin.Context) {
 
 // PutFile Puts file content
 func PutFile(c *gin.Context) {
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
+	var wopiService explorer.WopiService
+	if current, err := wopiService.CheckPutLock(c); err != nil {
+		lockConflict(c, current, err)
+		return
+	}
+
 	service := &explorer.FileIDService{}
 	res := service.PutContent(ctx, c)
 	switch res.Code {
@@ -59,11 +67,26 @@ func PutFile(c *gin.Context) {
 func ModifyFile(c *gin.Context) {
 	action := c.GetHeader(wopi.OverwriteHeader)
 	switch action {
-	case wopi.MethodLock, wopi.MethodRefreshLock, wopi.MethodUnlock:
+	case wopi.MethodLock, wopi.MethodRefreshLock, wopi.MethodUnlock, wopi.MethodGetLock:
+		var service explorer.WopiService
+		current, err := service.Lock(c, action)
+		if err != nil {
+			lockConflict(c, current, err)
+			return
+		}
+
+		if action == wopi.MethodGetLock {
+			c.Header(wopi.LockHeader, current)
+		}
 		c.Status(http.StatusOK)
 		return
 	case wopi.MethodRename:
 		var service explorer.WopiService
+		if current, err := service.CheckLock(c); err != nil {
+			lockConflict(c, current, err)
+			return
+		}
+
 		err := service.Rename(c)
 		if err != nil {
 			c.Status(http.StatusInternalServerError)
@@ -75,3 +98,16 @@ func ModifyFile(c *gin.Context) {
 		return
 	}
 }
+
+// lockConflict responds with current lock ID when lock validation failed
+func lockConflict(c *gin.Context, current string, err error) {
+	if errors.Is(err, wopi.ErrLockMismatch) {
+		// Lock header is kept with empty value when file is not locked
+		c.Writer.Header().Set(wopi.LockHeader, current)
+		c.Status(http.StatusConflict)
+		return
+	}
+
+	c.Status(http.StatusInternalServerError)
+	c.Header(wopi.ServerErrorHeader, err.Error())
+}
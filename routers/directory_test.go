@@ -0,0 +1,55 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListDirectoryETag(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	router := InitMasterRouter()
+
+	user, err := model.GetUserByID(1)
+	asserts.NoError(err)
+	root, err := user.Root()
+	asserts.NoError(err)
+	folder := &model.Folder{Name: "etag", ParentID: &root.ID, OwnerID: 1}
+	_, err = folder.Create()
+	asserts.NoError(err)
+	defer mockLogin(t, 1)()
+
+	list := func(etag string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v3/directory/etag", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 首次列取，返回 ETag
+	w := list("")
+	asserts.Equal(200, w.Code)
+	asserts.Contains(w.Body.String(), `"code":0`)
+	etag := w.Header().Get("ETag")
+	asserts.NotEmpty(etag)
+
+	// ETag 匹配，返回 304
+	w = list(etag)
+	asserts.Equal(http.StatusNotModified, w.Code)
+	asserts.Empty(w.Body.String())
+
+	// 子项目变化后返回新的 ETag
+	asserts.NoError(model.DB.Create(&model.File{Name: "a.txt", UserID: 1, FolderID: folder.ID, PolicyID: 1}).Error)
+	w = list(etag)
+	asserts.Equal(200, w.Code)
+	asserts.Contains(w.Body.String(), "a.txt")
+	asserts.NotEmpty(w.Header().Get("ETag"))
+	asserts.NotEqual(etag, w.Header().Get("ETag"))
+}
@@ -0,0 +1,50 @@
+package routers
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizedNameLookup(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	user, err := model.GetUserByID(1)
+	asserts.NoError(err)
+	fs, err := filesystem.NewFileSystem(&user)
+	asserts.NoError(err)
+	defer fs.Recycle()
+	defer os.RemoveAll(util.RelativePath("uploads"))
+
+	// macOS 客户端以 NFD 形式提交的名称
+	dir := "/Cafe\u0301"
+	name := "re\u0301sume\u0301.txt"
+	_, err = fs.CreateDirectory(context.Background(), dir)
+	asserts.NoError(err)
+	asserts.NoError(fs.UploadFromStream(context.Background(), &fsctx.FileStream{
+		File:        io.NopCloser(strings.NewReader("hello")),
+		Size:        5,
+		Name:        name,
+		VirtualPath: dir,
+	}, true))
+
+	exist, file := fs.IsFileExist(dir + "/" + name)
+	asserts.True(exist)
+	asserts.Equal("r\u00e9sum\u00e9.txt", file.Name)
+
+	// 保存时去除了末尾的点
+	exist, _ = fs.IsFileExist("/Caf\u00e9./r\u00e9sum\u00e9.txt.")
+	asserts.True(exist)
+
+	exist, folder := fs.IsPathExist(dir)
+	asserts.True(exist)
+	asserts.Equal("Caf\u00e9", folder.Name)
+}
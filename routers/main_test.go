@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/middleware"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -45,3 +46,14 @@ func switchToMemDB() {
 func switchToMockDB() {
 	model.DB = mockDB
 }
+
+// mockLogin 以用户 uid 的身份登录，返回的函数用于退出登录
+func mockLogin(t *testing.T, uid uint) func() {
+	session, err := model.NewLoginSession(uid, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	middleware.SessionMock = map[string]interface{}{"user_id": uid, model.LoginSessionKey: session.Token}
+	return func() { middleware.SessionMock = map[string]interface{}{} }
+}
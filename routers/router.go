@@ -7,6 +7,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	wopi2 "github.com/cloudreve/Cloudreve/v3/pkg/wopi"
 	"github.com/cloudreve/Cloudreve/v3/routers/controllers"
@@ -29,6 +30,8 @@ func InitRouter() *gin.Engine {
 // InitSlaveRouter 初始化从机模式路由
 func InitSlaveRouter() *gin.Engine {
 	r := gin.Default()
+	// 请求追踪
+	r.Use(middleware.RequestTracing())
 	// 跨域相关
 	InitCORS(r)
 	v3 := r.Group("/api/v3/slave")
@@ -61,6 +64,7 @@ func InitSlaveRouter() *gin.Engine {
 		}
 		// 下载
 		v3.GET("download/:speed/:path/:name", controllers.SlaveDownload)
+		v3.HEAD("download/:speed/:path/:name", controllers.SlaveDownload)
 		// 预览 / 外链
 		v3.GET("source/:speed/:path/:name", controllers.SlavePreview)
 		// 缩略图
@@ -117,11 +121,13 @@ func InitCORS(router *gin.Engine) {
 // InitMasterRouter 初始化主机模式路由
 func InitMasterRouter() *gin.Engine {
 	r := gin.Default()
+	// 请求追踪
+	r.Use(middleware.RequestTracing())
 
 	/*
 		静态资源
 	*/
-	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/"})))
+	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/", "/s3/"})))
 	r.Use(middleware.FrontendFileHandler())
 	r.GET("manifest.json", controllers.Manifest)
 
@@ -139,6 +145,8 @@ func InitMasterRouter() *gin.Engine {
 	}
 	// 用户会话
 	v3.Use(middleware.CurrentUser())
+	// 请求频率限制
+	v3.Use(middleware.RateLimit(ratelimit.ClassAPI))
 
 	// 禁止缓存
 	v3.Use(middleware.CacheControl())
@@ -154,6 +162,20 @@ func InitMasterRouter() *gin.Engine {
 				middleware.HashID(hashid.SourceLinkID),
 				middleware.ValidateSourceLink(),
 				controllers.AnonymousPermLink)
+			// 自定义标识的外链
+			source.GET("s/:slug",
+				middleware.ValidateSourceLinkSlug(),
+				controllers.AnonymousPermLink)
+		}
+
+		// 静态网站游客访问
+		staticSite := r.Group("site/:slug",
+			middleware.RateLimit(ratelimit.ClassGuest),
+			middleware.StaticSiteAvailable(),
+		)
+		{
+			staticSite.GET("*path", controllers.ServeStaticSite)
+			staticSite.HEAD("*path", controllers.ServeStaticSite)
 		}
 
 		// 全局设置相关
@@ -170,20 +192,37 @@ func InitMasterRouter() *gin.Engine {
 		// 用户相关路由
 		user := v3.Group("user")
 		{
+			authLimit := middleware.RateLimit(ratelimit.ClassAuth)
 			// 用户登录
-			user.POST("session", middleware.CaptchaRequired("login_captcha"), controllers.UserLogin)
+			user.POST("session", authLimit, middleware.CaptchaRequired("login_captcha"), controllers.UserLogin)
 			// 用户注册
 			user.POST("",
+				authLimit,
 				middleware.IsFunctionEnabled("register_enabled"),
 				middleware.CaptchaRequired("reg_captcha"),
 				controllers.UserRegister,
 			)
 			// 用二步验证户登录
-			user.POST("2fa", controllers.User2FALogin)
+			user.POST("2fa", authLimit, controllers.User2FALogin)
+			// 使用 WebAuthn 验证器进行二步验证
+			user.GET("2fa/authn",
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.Start2FAAuthn,
+			)
+			user.POST("2fa/authn",
+				authLimit,
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.Finish2FAAuthn,
+			)
+			// 单点登录
+			user.GET("oidc",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.StartOIDCLogin,
+			)
 			// 发送密码重设邮件
-			user.POST("reset", middleware.CaptchaRequired("forget_captcha"), controllers.UserSendReset)
+			user.POST("reset", authLimit, middleware.CaptchaRequired("forget_captcha"), controllers.UserSendReset)
 			// 通过邮件里的链接重设密码
-			user.PATCH("reset", controllers.UserReset)
+			user.PATCH("reset", authLimit, controllers.UserReset)
 			// 邮件激活
 			user.GET("activate/:id",
 				middleware.SignRequired(auth.General),
@@ -197,6 +236,7 @@ func InitMasterRouter() *gin.Engine {
 			)
 			// WebAuthn登陆
 			user.POST("authn/finish/:username",
+				authLimit,
 				middleware.IsFunctionEnabled("authn_enabled"),
 				controllers.FinishLoginAuthn,
 			)
@@ -232,6 +272,10 @@ func InitMasterRouter() *gin.Engine {
 					middleware.StaticResourceCache(),
 					controllers.Download,
 				)
+				file.HEAD("download/:id",
+					middleware.StaticResourceCache(),
+					controllers.Download,
+				)
 				// 打包并下载文件
 				file.GET("archive/:sessionID/archive.zip", controllers.DownloadArchive)
 			}
@@ -300,6 +344,7 @@ func InitMasterRouter() *gin.Engine {
 				// 文件上传完成
 				onedrive.POST(
 					"finish/:sessionID",
+					middleware.Idempotent(),
 					middleware.UseUploadSession("onedrive"),
 					middleware.OneDriveCallbackAuth(),
 					controllers.OneDriveCallback,
@@ -319,6 +364,12 @@ func InitMasterRouter() *gin.Engine {
 					controllers.GoogleDriveOAuth,
 				)
 			}
+			// 单点登录完成
+			callback.GET(
+				"oidc",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.OIDCCallback,
+			)
 			// 腾讯云COS策略上传回调
 			callback.GET(
 				"cos/:sessionID",
@@ -331,6 +382,12 @@ func InitMasterRouter() *gin.Engine {
 				middleware.UseUploadSession("s3"),
 				controllers.S3Callback,
 			)
+			// Backblaze B2策略上传回调
+			callback.GET(
+				"b2/:sessionID",
+				middleware.UseUploadSession("b2"),
+				controllers.B2Callback,
+			)
 		}
 
 		// 分享相关
@@ -338,9 +395,16 @@ func InitMasterRouter() *gin.Engine {
 		{
 			// 获取分享
 			share.GET("info/:id", controllers.GetShare)
+			// 举报分享
+			share.POST("report/:id",
+				middleware.IsFunctionEnabled("abuse_report_enabled"),
+				middleware.RateLimit(ratelimit.ClassGuest),
+				controllers.ReportShare,
+			)
 			// 创建文件下载会话
 			share.PUT("download/:id",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.BeforeShareDownload(),
 				controllers.GetShareDownload,
 			)
@@ -348,6 +412,7 @@ func InitMasterRouter() *gin.Engine {
 			share.GET("preview/:id",
 				middleware.CSRFCheck(),
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.ShareCanPreview(),
 				middleware.BeforeShareDownload(),
 				controllers.PreviewShare,
@@ -355,43 +420,71 @@ func InitMasterRouter() *gin.Engine {
 			// 取得Office文档预览地址
 			share.GET("doc/:id",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.ShareCanPreview(),
 				middleware.BeforeShareDownload(),
 				controllers.GetShareDocPreview,
 			)
+			// 取得分享文件的预览方式
+			share.GET("preview_session/:id",
+				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
+				middleware.ShareCanPreview(),
+				middleware.BeforeShareDownload(),
+				controllers.GetSharePreviewSession,
+			)
 			// 获取文本文件内容
 			share.GET("content/:id",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.BeforeShareDownload(),
 				controllers.PreviewShareText,
 			)
 			// 分享目录列文件
 			share.GET("list/:id/*path",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				controllers.ListSharedFolder,
 			)
 			// 分享目录搜索
 			share.GET("search/:id/:type/:keywords",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				controllers.SearchSharedFolder,
 			)
 			// 归档打包下载
 			share.POST("archive/:id",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.BeforeShareDownload(),
 				controllers.ArchiveShare,
 			)
 			// 获取README文本文件内容
 			share.GET("readme/:id",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				controllers.PreviewShareReadme,
 			)
 			// 获取缩略图
 			share.GET("thumb/:id/:file",
 				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
 				middleware.ShareCanPreview(),
 				controllers.ShareThumb,
 			)
+			// 向投递分享上传文件
+			share.PUT("upload/:id",
+				middleware.RateLimit(ratelimit.ClassGuest),
+				middleware.CheckShareUnlocked(),
+				controllers.UploadToShare,
+			)
+			// 获取端到端加密目录的密钥信封及加密名称
+			share.GET("e2ee/:id",
+				middleware.IsFunctionEnabled("e2ee_enabled"),
+				middleware.CheckShareUnlocked(),
+				middleware.ShareReadable(),
+				controllers.GetShareEncryption,
+			)
 			// 搜索公共分享
 			v3.Group("share").GET("search", controllers.SearchShare)
 		}
@@ -412,21 +505,70 @@ func InitMasterRouter() *gin.Engine {
 			wopi.POST("files/:id", middleware.WopiWriteAccess(), controllers.ModifyFile)
 		}
 
+		// 公开目录游客只读访问
+		public := v3.Group("public/:slug",
+			middleware.RateLimit(ratelimit.ClassGuest),
+			middleware.PublicFolderAvailable(),
+		)
+		{
+			// 列出目录
+			public.GET("list/*path", controllers.PublicFolderList)
+			// 下载文件
+			public.GET("download/*path", controllers.PublicFolderDownload)
+		}
+
+		// SCIM 2.0 用户同步
+		scim := v3.Group("scim/v2", middleware.SCIMAuth())
+		{
+			scim.GET("Users", controllers.SCIMListUsers)
+			scim.POST("Users", controllers.SCIMCreateUser)
+			scim.GET("Users/:id", controllers.SCIMGetUser)
+			scim.PUT("Users/:id", controllers.SCIMReplaceUser)
+			scim.PATCH("Users/:id", controllers.SCIMPatchUser)
+			scim.DELETE("Users/:id", controllers.SCIMDeleteUser)
+			scim.GET("Groups", controllers.SCIMListGroups)
+			scim.POST("Groups", controllers.SCIMCreateGroup)
+			scim.GET("Groups/:id", controllers.SCIMGetGroup)
+			scim.PUT("Groups/:id", controllers.SCIMReplaceGroup)
+			scim.PATCH("Groups/:id", controllers.SCIMPatchGroup)
+			scim.DELETE("Groups/:id", controllers.SCIMDeleteGroup)
+		}
+
 		// 需要登录保护的
 		auth := v3.Group("")
-		auth.Use(middleware.AuthRequired())
+		auth.Use(middleware.AuthRequired(), middleware.TwoFactorRequired(
+			"/api/v3/user/setting",
+			"/api/v3/user/setting/2fa",
+			"/api/v3/user/setting/:option",
+			"/api/v3/user/authn",
+			"/api/v3/user/authn/finish",
+			"GET /api/v3/user/me",
+			"DELETE /api/v3/user/session",
+		))
 		{
 			// 管理
 			admin := auth.Group("admin", middleware.IsAdmin())
 			{
 				// 获取站点概况
 				admin.GET("summary", controllers.AdminSummary)
+				// 获取站点统计数据
+				admin.GET("stats", controllers.AdminStats)
+				// 获取用量最多的用户
+				admin.GET("stats/top", controllers.AdminTopConsumers)
+				// 导出统计数据
+				admin.GET("stats/export", controllers.AdminExportStats)
 				// 获取社区新闻
 				admin.GET("news", controllers.AdminNews)
+				// 发送站内公告
+				admin.POST("announcement", controllers.AdminAnnounce)
 				// 更改设置
 				admin.PATCH("setting", controllers.AdminChangeSetting)
 				// 获取设置
 				admin.POST("setting", controllers.AdminGetSetting)
+				// 获取日志配置
+				admin.GET("log", controllers.AdminGetLogLevel)
+				// 调整日志等级
+				admin.PATCH("log", controllers.AdminChangeLogLevel)
 				// 获取用户组列表
 				admin.GET("groups", controllers.AdminGetGroups)
 				// 重新加载子服务
@@ -462,6 +604,8 @@ func InitMasterRouter() *gin.Engine {
 					policy.POST("cors", controllers.AdminAddCORS)
 					// 创建COS回调函数
 					policy.POST("scf", controllers.AdminAddSCF)
+					// 列出可用的存储驱动插件
+					policy.GET("plugins", controllers.AdminListDriverPlugins)
 					// 获取 OneDrive OAuth URL
 					oauth := policy.Group(":id/oauth")
 					{
@@ -473,6 +617,12 @@ func InitMasterRouter() *gin.Engine {
 
 					// 获取 存储策略
 					policy.GET(":id", controllers.AdminGetPolicy)
+					// 获取 存储策略本月用量
+					policy.GET(":id/usage", controllers.AdminGetPolicyUsage)
+					// 获取 存储策略健康检查结果
+					policy.GET(":id/health", controllers.AdminGetPolicyHealth)
+					// 轮换 存储策略加密主密钥
+					policy.POST(":id/encryption/rotate", controllers.AdminRotatePolicyKey)
 					// 删除 存储策略
 					policy.DELETE(":id", controllers.AdminDeletePolicy)
 				}
@@ -488,6 +638,8 @@ func InitMasterRouter() *gin.Engine {
 					group.POST("", controllers.AdminAddGroup)
 					// 删除
 					group.DELETE(":id", controllers.AdminDeleteGroup)
+					// 测试用户组分配规则
+					group.POST("rules/test", controllers.AdminTestGroupRules)
 				}
 
 				user := admin.Group("user")
@@ -502,6 +654,12 @@ func InitMasterRouter() *gin.Engine {
 					user.POST("delete", controllers.AdminDeleteUser)
 					// 封禁/解封用户
 					user.PATCH("ban/:id", controllers.AdminBanUser)
+					// 获取/设置保留规则
+					user.GET(":id/retention", controllers.AdminGetUserRetention)
+					user.PUT(":id/retention", controllers.AdminUpdateUserRetention)
+					// 获取/设置用户单独的限速
+					user.GET(":id/speed", controllers.AdminGetUserSpeedLimit)
+					user.PUT(":id/speed", controllers.AdminUpdateUserSpeedLimit)
 				}
 
 				file := admin.Group("file")
@@ -512,9 +670,73 @@ func InitMasterRouter() *gin.Engine {
 					file.GET("preview/:id", middleware.Sandbox(), controllers.AdminGetFile)
 					// 删除
 					file.POST("delete", controllers.AdminDeleteFile)
+					// 解除病毒隔离
+					file.POST("release", controllers.AdminReleaseFiles)
 					// 列出用户或外部文件系统目录
 					file.GET("folders/:type/:id/*path",
 						controllers.AdminListFolders)
+					// 搜索用户文件
+					file.POST("search", controllers.AdminSearchUserFiles)
+					// 在用户之间转移文件
+					file.POST("transfer", controllers.AdminTransferFiles)
+				}
+
+				public := admin.Group("public")
+				{
+					// 列出公开目录
+					public.GET("", controllers.AdminListPublicFolders)
+					// 添加公开目录
+					public.POST("", controllers.AdminAddPublicFolder)
+					// 取消目录公开
+					public.DELETE(":id", controllers.AdminDeletePublicFolder)
+				}
+
+				automation := admin.Group("automation")
+				{
+					// 列出自动化规则
+					automation.GET("", controllers.AdminListAutomations)
+					// 创建/保存自动化规则
+					automation.POST("", controllers.AdminAddAutomation)
+					// 删除自动化规则
+					automation.DELETE(":id", controllers.AdminDeleteAutomation)
+				}
+
+				schedule := admin.Group("schedule")
+				{
+					// 列出定时任务
+					schedule.GET("", controllers.AdminListSchedules)
+					// 立即执行
+					schedule.POST(":name/trigger", controllers.AdminTriggerSchedule)
+					// 暂停
+					schedule.POST(":name/pause", controllers.AdminPauseSchedule)
+					// 恢复
+					schedule.POST(":name/resume", controllers.AdminResumeSchedule)
+					// 列出执行记录
+					schedule.GET(":name/history", controllers.AdminListScheduleHistory)
+				}
+
+				hook := admin.Group("webhook")
+				{
+					// 列出 Webhook
+					hook.GET("", controllers.AdminListWebhooks)
+					// 创建 Webhook
+					hook.POST("", controllers.AdminCreateWebhook)
+					// 更新 Webhook
+					hook.PUT(":id", controllers.AdminUpdateWebhook)
+					// 删除 Webhook
+					hook.DELETE(":id", controllers.AdminDeleteWebhook)
+					// 列出投递记录
+					hook.GET(":id/deliveries", controllers.AdminListWebhookDeliveries)
+				}
+
+				invite := admin.Group("invite")
+				{
+					// 列出邀请码
+					invite.POST("list", controllers.AdminListInvite)
+					// 批量生成邀请码
+					invite.POST("", controllers.AdminAddInvite)
+					// 删除邀请码
+					invite.DELETE(":id", controllers.AdminDeleteInvite)
 				}
 
 				share := admin.Group("share")
@@ -525,6 +747,20 @@ func InitMasterRouter() *gin.Engine {
 					share.POST("delete", controllers.AdminDeleteShare)
 				}
 
+				report := admin.Group("report")
+				{
+					// 列出举报
+					report.POST("list", controllers.AdminListReport)
+					// 处理举报
+					report.POST("handle", controllers.AdminHandleReport)
+				}
+
+				audit := admin.Group("audit")
+				{
+					// 列出审计日志
+					audit.POST("list", controllers.AdminListAudit)
+				}
+
 				download := admin.Group("download")
 				{
 					// 列出任务
@@ -541,6 +777,18 @@ func InitMasterRouter() *gin.Engine {
 					task.POST("delete", controllers.AdminDeleteTask)
 					// 新建文件导入任务
 					task.POST("import", controllers.AdminCreateImportTask)
+					// 新建容量重新计算任务
+					task.POST("quota", controllers.AdminCreateQuotaTask)
+					// 新建缩略图重新生成任务
+					task.POST("thumb", controllers.AdminCreateThumbTask)
+					// 新建存储策略迁移任务
+					task.POST("migrate", controllers.AdminCreateMigrateTask)
+					// 新建孤立物理文件回收任务
+					task.POST("blob_gc", controllers.AdminCreateBlobGCTask)
+					// 通过用户数据导出任务
+					task.PATCH("export/:id", controllers.AdminApproveExportTask)
+					// 列出独立任务 Worker
+					task.GET("workers", controllers.AdminListTaskWorkers)
 				}
 
 				node := admin.Group("node")
@@ -568,11 +816,41 @@ func InitMasterRouter() *gin.Engine {
 				user.GET("me", controllers.UserMe)
 				// 存储信息
 				user.GET("storage", controllers.UserStorage)
+				// 列出邀请码
+				user.GET("invites", controllers.UserListInvites)
+				// 创建邀请码
+				user.POST("invites", controllers.UserCreateInvite)
+				// 删除邀请码
+				user.DELETE("invites/:code", controllers.UserDeleteInvite)
+				// 列出访问令牌
+				user.GET("tokens", controllers.UserListAPITokens)
+				// 创建访问令牌
+				user.POST("tokens", controllers.UserCreateAPIToken)
+				// 吊销访问令牌
+				user.DELETE("tokens/:id", controllers.UserDeleteAPIToken)
+				// 列出登录会话
+				user.GET("sessions", controllers.UserListLoginSessions)
+				// 吊销登录会话
+				user.DELETE("sessions/:id", controllers.UserRevokeLoginSession)
+				// 列出用户动态
+				user.GET("activities", controllers.UserListActivities)
+
+				// Webhook
+				hook := user.Group("webhooks", middleware.IsFunctionEnabled("webhook_user_enabled"))
+				{
+					hook.GET("", controllers.UserListWebhooks)
+					hook.POST("", controllers.UserCreateWebhook)
+					hook.PUT(":id", controllers.UserUpdateWebhook)
+					hook.DELETE(":id", controllers.UserDeleteWebhook)
+					hook.GET(":id/deliveries", controllers.UserListWebhookDeliveries)
+				}
 				// 退出登录
 				user.DELETE("session", controllers.UserSignOut)
 				// Generate temp URL for copying client-side session, used in adding accounts
 				// for mobile App.
 				user.GET("session", controllers.UserPrepareCopySession)
+				// 注销账户
+				user.DELETE("", controllers.UserDeleteAccount)
 
 				// WebAuthn 注册相关
 				authn := user.Group("authn",
@@ -597,6 +875,10 @@ func InitMasterRouter() *gin.Engine {
 					setting.PATCH(":option", controllers.UpdateOption)
 					// 获得二步验证初始化信息
 					setting.GET("2fa", controllers.UserInit2FA)
+					// 创建用户数据导出任务
+					setting.POST("export", controllers.UserCreateExport)
+					// 下载导出分卷
+					setting.GET("export/:id/:part", controllers.UserDownloadExport)
 				}
 			}
 
@@ -607,14 +889,30 @@ func InitMasterRouter() *gin.Engine {
 				upload := file.Group("upload")
 				{
 					// 文件上传
-					upload.POST(":sessionId/:index", controllers.FileUpload)
+					upload.POST(":sessionId/:index", middleware.RouteUploadSession(), controllers.FileUpload)
 					// 创建上传会话
 					upload.PUT("", controllers.GetUploadSession)
+					// 秒传
+					upload.PUT("instant", controllers.InstantUpload)
 					// 删除给定上传会话
-					upload.DELETE(":sessionId", controllers.DeleteUploadSession)
+					upload.DELETE(":sessionId", middleware.RouteUploadSession(), controllers.DeleteUploadSession)
 					// 删除全部上传会话
 					upload.DELETE("", controllers.DeleteAllUploadSession)
 				}
+				// tus 可续传上传
+				tus := file.Group("tus", middleware.TusResumable())
+				{
+					// 查询支持的协议扩展
+					tus.OPTIONS("", controllers.TusOptions)
+					// 创建可续传上传
+					tus.POST("", controllers.TusCreate)
+					// 查询上传进度
+					tus.HEAD(":sessionId", middleware.RouteUploadSession(), controllers.TusHead)
+					// 上传数据
+					tus.PATCH(":sessionId", middleware.RouteUploadSession(), controllers.TusPatch)
+					// 终止上传
+					tus.DELETE(":sessionId", middleware.RouteUploadSession(), controllers.TusDelete)
+				}
 				// 更新文件
 				file.PUT("update/:id", controllers.PutContent)
 				// 创建空白文件
@@ -627,8 +925,16 @@ func InitMasterRouter() *gin.Engine {
 				file.GET("content/:id", middleware.Sandbox(), controllers.PreviewText)
 				// 取得Office文档预览地址
 				file.GET("doc/:id", controllers.GetDocPreview)
+				// 取得文件的预览方式
+				file.GET("preview_session/:id", controllers.GetPreviewSession)
+				// 取得视频 HLS 播放地址
+				file.PUT("hls/:id", controllers.CreateHLSSession)
+				// 获取视频 HLS 播放列表或分片
+				file.GET("hls/:id/:name", controllers.GetHLSContent)
 				// 获取缩略图
 				file.GET("thumb/:id", controllers.Thumb)
+				// 批量获取缩略图状态
+				file.POST("thumbs", controllers.BatchThumb)
 				// 取得文件外链
 				file.POST("source", controllers.GetSource)
 				// 打包要下载的文件
@@ -637,6 +943,18 @@ func InitMasterRouter() *gin.Engine {
 				file.POST("compress", controllers.Compress)
 				// 创建文件解压缩任务
 				file.POST("decompress", controllers.Decompress)
+				// 创建种子制作任务
+				file.POST("torrent", controllers.CreateTorrent)
+				// 创建从 URL 下载任务
+				file.POST("fetch", controllers.CreateFetchTask)
+				// 列出增量变更
+				file.GET("changes", controllers.ListChanges)
+				// 列出文件历史版本
+				file.GET("versions/:id", controllers.ListFileVersions)
+				// 恢复文件历史版本
+				file.POST("versions/:id/:version", controllers.RestoreFileVersion)
+				// 删除文件历史版本
+				file.DELETE("versions/:id/:version", controllers.DeleteFileVersion)
 				// 创建文件解压缩任务
 				file.GET("search/:type/:keywords", controllers.SearchFile)
 			}
@@ -662,31 +980,203 @@ func InitMasterRouter() *gin.Engine {
 			directory := auth.Group("directory")
 			{
 				// 创建目录
-				directory.PUT("", controllers.CreateDirectory)
+				directory.PUT("", middleware.Idempotent(), controllers.CreateDirectory)
 				// 列出目录下内容
 				directory.GET("*path", controllers.ListDirectory)
+				// 设定目录容量配额
+				directory.PUT("quota", controllers.SetFolderQuota)
+			}
+
+			// 站内通知
+			notify := auth.Group("notification")
+			{
+				// 列出站内通知
+				notify.GET("", controllers.ListNotifications)
+				// 标记为已读
+				notify.PATCH("read", controllers.ReadNotifications)
+				// 删除通知
+				notify.DELETE(":id", controllers.DeleteNotification)
+				// 通过 WebSocket 接收站内通知、任务及离线下载状态的推送
+				notify.GET("ws", controllers.StreamNotifications)
+			}
+
+			// 与其他用户直接共享目录
+			collab := auth.Group("collaboration")
+			{
+				// 将目录共享给其他用户
+				collab.POST("", controllers.CreateCollaboration)
+				// 列出共享出去的目录
+				collab.GET("", controllers.ListCollaborations)
+				// 列出共享给我的目录
+				collab.GET("shared", controllers.ListSharedWithMe)
+				// 取消共享
+				collab.DELETE(":id", controllers.DeleteCollaboration)
+				// 列出「与我共享」虚拟目录下的内容
+				collab.GET("directory/*path", controllers.ListSharedDirectory)
+				// 在共享的目录中创建目录
+				collab.PUT("directory", controllers.CreateSharedDirectory)
+				// 获取共享的文件的下载地址
+				collab.PUT("download/*path", controllers.GetSharedDownload)
+				// 上传文件到共享的目录
+				collab.PUT("upload/*path", controllers.UploadToShared)
+				// 删除共享的目录中的对象
+				collab.DELETE("object/*path", controllers.DeleteSharedObject)
+			}
+
+			// 团队空间
+			teams := auth.Group("team")
+			{
+				// 创建团队
+				teams.POST("", controllers.CreateTeam)
+				// 列出所在的团队
+				teams.GET("", controllers.ListTeams)
+				// 删除团队
+				teams.DELETE(":id", controllers.DeleteTeam)
+				// 列出团队成员
+				teams.GET(":id/member", controllers.ListTeamMembers)
+				// 添加团队成员或更新其角色
+				teams.PUT(":id/member", controllers.SetTeamMember)
+				// 移除团队成员或退出团队
+				teams.DELETE(":id/member/:uid", controllers.RemoveTeamMember)
+				// 列出团队空间中的目录
+				teams.GET(":id/directory/*path", controllers.ListTeamDirectory)
+				// 在团队空间中创建目录
+				teams.PUT(":id/directory", controllers.CreateTeamDirectory)
+				// 获取团队空间中文件的下载地址
+				teams.PUT(":id/download/*path", controllers.GetTeamDownload)
+				// 上传文件到团队空间
+				teams.PUT(":id/upload/*path", controllers.UploadToTeam)
+				// 删除团队空间中的对象
+				teams.DELETE(":id/object/*path", controllers.DeleteTeamObject)
+				// 分享团队空间中的对象
+				teams.POST(":id/share", controllers.CreateTeamShare)
+				// 列出团队的分享
+				teams.GET(":id/share", controllers.ListTeamShares)
+				// 删除团队的分享
+				teams.DELETE(":id/share/:share", controllers.DeleteTeamShare)
+				// 为团队空间中的目录添加 ACL 条目
+				teams.POST(":id/acl", controllers.CreateTeamACL)
+				// 列出团队空间中目录上的 ACL 条目
+				teams.GET(":id/acl/:folder", controllers.ListTeamACLs)
+				// 删除团队空间中目录上的 ACL 条目
+				teams.DELETE(":id/acl/:acl", controllers.DeleteTeamACL)
+			}
+
+			// 目录访问控制
+			acls := auth.Group("acl")
+			{
+				// 为目录添加 ACL 条目
+				acls.POST("", controllers.CreateFolderACL)
+				// 列出目录上的 ACL 条目
+				acls.GET("folder/:folder", controllers.ListFolderACLs)
+				// 删除目录上的 ACL 条目
+				acls.DELETE(":id", controllers.DeleteFolderACL)
+			}
+
+			// 文件外链管理
+			links := auth.Group("source_link")
+			{
+				// 为文件创建外链
+				links.POST("", controllers.CreateSourceLink)
+				// 列出文件外链
+				links.GET("", controllers.ListSourceLinks)
+				// 更新外链设置
+				links.PUT(":id", middleware.HashID(hashid.SourceLinkID), controllers.UpdateSourceLink)
+				// 撤销外链
+				links.DELETE(":id", middleware.HashID(hashid.SourceLinkID), controllers.DeleteSourceLink)
+			}
+
+			// 照片
+			photos := auth.Group("photo")
+			{
+				// 按时间线列出照片
+				photos.GET("timeline", controllers.PhotoTimeline)
+				// 列出按时段归档的相册
+				photos.GET("albums", controllers.PhotoAlbums)
+			}
+
+			// 音乐
+			music := auth.Group("music")
+			{
+				// 列出艺术家
+				music.GET("artists", controllers.ListMusicArtists)
+				// 列出专辑
+				music.GET("albums", controllers.ListMusicAlbums)
+				// 列出曲目
+				music.GET("tracks", controllers.ListMusicTracks)
+				// 创建播放列表
+				music.POST("playlist", controllers.CreatePlaylist)
+				// 列出播放列表
+				music.GET("playlist", controllers.ListPlaylists)
+				// 获取播放列表中的曲目
+				music.GET("playlist/:id", controllers.GetPlaylist)
+				// 更新播放列表
+				music.PUT("playlist/:id", controllers.UpdatePlaylist)
+				// 删除播放列表
+				music.DELETE("playlist/:id", controllers.DeletePlaylist)
+			}
+
+			// 静态网站
+			sites := auth.Group("static_site", middleware.IsFunctionEnabled("static_site_enabled"))
+			{
+				// 将目录发布为静态网站
+				sites.POST("", controllers.CreateStaticSite)
+				// 列出发布的静态网站
+				sites.GET("", controllers.ListStaticSites)
+				// 取消发布静态网站
+				sites.DELETE(":id", controllers.DeleteStaticSite)
+			}
+
+			// 回收站
+			trash := auth.Group("trash")
+			{
+				// 列出回收站中的对象
+				trash.GET("", controllers.ListTrash)
+				// 恢复回收站中的对象
+				trash.POST(":id", controllers.RestoreTrash)
+			}
+
+			// 同步客户端使用的增量变更，与 file/changes 相同
+			auth.GET("sync/changes", controllers.ListChanges)
+
+			// 目录清单
+			manifest := auth.Group("manifest")
+			{
+				// 导出用户完整目录树
+				manifest.GET("", controllers.ExportManifest)
+				// 导入目录清单
+				manifest.POST("", controllers.ImportManifest)
+			}
+
+			// 流式列取
+			stream := auth.Group("stream")
+			{
+				// 以 NDJSON 格式列出目录下内容
+				stream.GET("directory/*path", controllers.StreamDirectory)
 			}
 
 			// 对象，文件和目录的抽象
 			object := auth.Group("object")
 			{
 				// 删除对象
-				object.DELETE("", controllers.Delete)
+				object.DELETE("", middleware.Idempotent(), controllers.Delete)
 				// 移动对象
-				object.PATCH("", controllers.Move)
+				object.PATCH("", middleware.Idempotent(), controllers.Move)
 				// 复制对象
-				object.POST("copy", controllers.Copy)
+				object.POST("copy", middleware.Idempotent(), controllers.Copy)
 				// 重命名对象
-				object.POST("rename", controllers.Rename)
+				object.POST("rename", middleware.Idempotent(), controllers.Rename)
 				// 获取对象属性
 				object.GET("property/:id", controllers.GetProperty)
+				// 批量获取对象状态
+				object.POST("stat", controllers.StatObjects)
 			}
 
 			// 分享
 			share := auth.Group("share")
 			{
 				// 创建新分享
-				share.POST("", controllers.CreateShare)
+				share.POST("", middleware.Idempotent(), controllers.CreateShare)
 				// 列出我的分享
 				share.GET("", controllers.ListShare)
 				// 更新分享属性
@@ -699,6 +1189,38 @@ func InitMasterRouter() *gin.Engine {
 				share.DELETE(":id",
 					controllers.DeleteShare,
 				)
+				// 列出分享的访问记录
+				share.GET(":id/access", controllers.ListShareAccessLogs)
+				// 获取分享的流量和下载次数统计
+				share.GET(":id/usage", controllers.GetShareUsage)
+				// 设置分享链接的端到端加密目录密钥
+				share.PUT(":id/e2ee",
+					middleware.IsFunctionEnabled("e2ee_enabled"),
+					middleware.ShareAvailable(),
+					middleware.ShareOwner(),
+					controllers.SetShareEnvelope,
+				)
+			}
+
+			// 端到端加密
+			e2ee := auth.Group("e2ee", middleware.IsFunctionEnabled("e2ee_enabled"))
+			{
+				// 获取当前用户的密钥对
+				e2ee.GET("key", controllers.GetKeyPair)
+				// 保存当前用户的密钥对
+				e2ee.PUT("key", controllers.SaveKeyPair)
+				// 获取其他用户的公钥
+				e2ee.GET("key/:id", controllers.GetPublicKey)
+				// 为目录启用端到端加密
+				e2ee.POST("folder", controllers.EnableFolderEncryption)
+				// 获取加密目录信息
+				e2ee.GET("folder/:id", controllers.GetEncryptedFolder)
+				// 向其他用户分发目录密钥
+				e2ee.PUT("folder/:id/envelope", controllers.GrantFolderKey)
+				// 撤销其他用户的目录密钥
+				e2ee.DELETE("folder/:id/envelope/:user", controllers.RevokeFolderKey)
+				// 设置对象的加密名称
+				e2ee.PUT("name", controllers.SetEncryptedName)
 			}
 
 			// 用户标签
@@ -731,9 +1253,37 @@ func InitMasterRouter() *gin.Engine {
 
 	// 初始化WebDAV相关路由
 	initWebDAV(r.Group("dav"))
+
+	// 初始化CalDAV/CardDAV相关路由
+	initCollectionDAV(r.Group("caldav"), controllers.ServeCalDAV)
+	initCollectionDAV(r.Group("carddav"), controllers.ServeCardDAV)
+	r.Any("/.well-known/caldav", controllers.RedirectDAVDiscovery("/caldav/"))
+	r.Handle("PROPFIND", "/.well-known/caldav", controllers.RedirectDAVDiscovery("/caldav/"))
+	r.Any("/.well-known/carddav", controllers.RedirectDAVDiscovery("/carddav/"))
+	r.Handle("PROPFIND", "/.well-known/carddav", controllers.RedirectDAVDiscovery("/carddav/"))
+
+	// 初始化S3兼容接口相关路由
+	initS3(r.Group("s3"))
+	return r
+}
+
+// InitS3Router 初始化独立监听的S3兼容接口路由，存储桶位于根路径下
+func InitS3Router() *gin.Engine {
+	r := gin.Default()
+	r.Use(middleware.RequestTracing())
+	initS3(r.Group(""))
 	return r
 }
 
+// initS3 初始化S3兼容接口相关路由
+func initS3(group *gin.RouterGroup) {
+	group.Use(middleware.S3Auth())
+
+	group.Any("/", controllers.S3Root)
+	group.Any("/:bucket", controllers.S3Bucket)
+	group.Any("/:bucket/*key", controllers.S3Object)
+}
+
 // initWebDAV 初始化WebDAV相关路由
 func initWebDAV(group *gin.RouterGroup) {
 	{
@@ -752,3 +1302,15 @@ func initWebDAV(group *gin.RouterGroup) {
 
 	}
 }
+
+// initCollectionDAV 初始化CalDAV/CardDAV相关路由
+func initCollectionDAV(group *gin.RouterGroup, handler gin.HandlerFunc) {
+	group.Use(middleware.WebDAVAuth())
+
+	group.Any("/*path", handler)
+	group.Any("", handler)
+	for _, method := range []string{"PROPFIND", "PROPPATCH", "REPORT", "MKCOL", "MKCALENDAR", "LOCK", "UNLOCK", "COPY", "MOVE"} {
+		group.Handle(method, "/*path", handler)
+		group.Handle(method, "", handler)
+	}
+}
@@ -0,0 +1,60 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCIMGroupMapping(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	router := InitMasterRouter()
+	cache.Set("setting_scim_token", "secret", 0)
+	cache.Set("setting_scim_group_mapping", "[]", 0)
+	defer cache.Set("setting_scim_token", "", 0)
+	defer cache.Set("setting_scim_group_mapping", "[]", 0)
+
+	user := model.NewUser()
+	user.Email = "scim-member@cloudreve.org"
+	user.GroupID = 2
+	asserts.NoError(model.DB.Create(&user).Error)
+
+	request := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/api/v3/scim/v2/"+target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Content-Type", "application/scim+json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	addMember := `{"Operations":[{"op":"add","path":"members","value":[{"value":"` + hashid.HashID(user.ID, hashid.UserID) + `"}]}]}`
+
+	// 没有映射规则时，不按名称匹配用户组
+	{
+		asserts.Equal(400, request("POST", "Groups", `{"displayName":"Admin"}`).Code)
+		res := request("GET", "Groups", "")
+		asserts.Equal(200, res.Code)
+		asserts.Contains(res.Body.String(), `"totalResults":0`)
+	}
+
+	// 未被规则映射的管理员组不可修改
+	{
+		cache.Set("setting_scim_group_mapping", `[{"pattern":"eng","group":2}]`, 0)
+		asserts.Equal(404, request("PATCH", "Groups/"+hashid.HashID(1, hashid.GroupID), addMember).Code)
+		asserts.Equal(404, request("GET", "Groups/"+hashid.HashID(1, hashid.GroupID), "").Code)
+		member, err := model.GetUserByID(user.ID)
+		asserts.NoError(err)
+		asserts.EqualValues(2, member.GroupID)
+
+		res := request("GET", "Groups", "")
+		asserts.Contains(res.Body.String(), `"totalResults":1`)
+		asserts.Equal(200, request("GET", "Groups/"+hashid.HashID(2, hashid.GroupID), "").Code)
+	}
+}
@@ -0,0 +1,48 @@
+package routers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/middleware"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokedLoginSession(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	router := InitMasterRouter()
+	defer mockLogin(t, 1)()
+
+	storage := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/api/v3/user/storage", nil)
+		router.ServeHTTP(w, req)
+		asserts.Equal(200, w.Code)
+		return w.Body.String()
+	}
+
+	asserts.Contains(storage(), `"code":0`)
+
+	// 吊销后立即清理，已吊销的记录仍需保留
+	token := middleware.SessionMock[model.LoginSessionKey].(string)
+	session, err := model.GetLoginSession(token)
+	asserts.NoError(err)
+	asserts.NoError(session.Revoke())
+	now := time.Now()
+	_, err = model.DeleteInactiveLoginSessions(now, now.Add(-model.LoginSessionMaxAge))
+	asserts.NoError(err)
+	_, err = model.GetLoginSession(token)
+	asserts.NoError(err)
+	asserts.Contains(storage(), `"code":401`)
+
+	// Cookie 过期后记录被彻底删除，携带同一令牌的请求仍需登录
+	_, err = model.DeleteInactiveLoginSessions(now, now.Add(time.Minute))
+	asserts.NoError(err)
+	_, err = model.GetLoginSession(token)
+	asserts.Error(err)
+	asserts.Contains(storage(), `"code":401`)
+}
@@ -0,0 +1,77 @@
+package routers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webdav"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebDAVReadonlyAccount(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	router := InitMasterRouter()
+
+	account := &model.Webdav{Name: "readonly", Password: "readonly-password", UserID: 1, Root: "/", Readonly: true}
+	_, err := account.Create()
+	asserts.NoError(err)
+	defer model.DeleteWebDAVAccountByID(account.ID, 1)
+
+	serve := func(method, body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/dav/file.txt", strings.NewReader(body))
+		req.SetBasicAuth("admin@cloudreve.org", account.Password)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	lockInfo := `<?xml version="1.0" encoding="utf-8"?><D:lockinfo xmlns:D="DAV:"><D:lockscope><D:exclusive/></D:lockscope><D:locktype><D:write/></D:locktype></D:lockinfo>`
+	asserts.Equal(http.StatusForbidden, serve("PUT", "content"))
+	asserts.Equal(http.StatusForbidden, serve("LOCK", lockInfo))
+	asserts.Equal(http.StatusForbidden, serve("UNLOCK", ""))
+}
+
+func TestWebDAVLocksAcrossRoots(t *testing.T) {
+	switchToMemDB()
+	asserts := assert.New(t)
+	user, err := model.GetUserByID(1)
+	asserts.NoError(err)
+
+	full, err := filesystem.NewFileSystem(&user)
+	asserts.NoError(err)
+	defer full.Recycle()
+	_, err = full.CreateDirectory(context.Background(), "/calendars/work")
+	asserts.NoError(err)
+
+	// 以 /calendars 为根目录的应用密码或 CalDAV 处理器
+	rooted, err := filesystem.NewFileSystem(&user)
+	asserts.NoError(err)
+	defer rooted.Recycle()
+	asserts.NoError(rooted.UseRoot("/calendars"))
+
+	now := time.Now()
+	token, err := webdav.NewDBLS(rooted).Create(now, webdav.LockDetails{Root: "/work", Duration: time.Minute})
+	asserts.NoError(err)
+	defer webdav.NewDBLS(rooted).Unlock(now, token)
+
+	// 锁以绝对路径保存，另一根目录下的同一资源同样被锁定
+	_, err = webdav.NewDBLS(full).Confirm(now, "/calendars/work", "")
+	asserts.Equal(webdav.ErrConfirmationFailed, err)
+	_, err = webdav.NewDBLS(full).Create(now, webdav.LockDetails{Root: "/calendars", Duration: time.Minute})
+	asserts.Equal(webdav.ErrLocked, err)
+
+	// 相对路径相同的其他资源不受影响
+	_, err = webdav.NewDBLS(full).Confirm(now, "/work", "")
+	asserts.NoError(err)
+
+	details, err := webdav.NewDBLS(rooted).Refresh(now, token, time.Minute)
+	asserts.NoError(err)
+	asserts.Equal("/work", details.Root)
+}
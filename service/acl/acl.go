@@ -0,0 +1,127 @@
+package acl
+
+import (
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateService 为目录添加 ACL 条目服务
+type CreateService struct {
+	Folder string `json:"folder" binding:"required"`
+	Type   string `json:"type" binding:"required,eq=user|eq=group"`
+	// Principal 授权对象，类型为 user 时为用户邮箱，为 group 时为用户组 ID
+	Principal   string   `json:"principal" binding:"required"`
+	Effect      string   `json:"effect" binding:"required,eq=allow|eq=deny"`
+	Permissions []string `json:"permissions" binding:"required,min=1"`
+}
+
+// getFolder 获取 owner 拥有的目录
+func getFolder(owner *model.User, id string) (*model.Folder, error) {
+	folderID, err := hashid.DecodeHashID(id, hashid.FolderID)
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeNotFound, "", err)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{folderID}, owner.ID)
+	if err != nil || len(folders) == 0 {
+		return nil, serializer.NewError(serializer.CodeParentNotExist, "", err)
+	}
+
+	return &folders[0], nil
+}
+
+// Create 为 owner 拥有的目录添加 ACL 条目
+func (service *CreateService) Create(c *gin.Context, owner *model.User) serializer.Response {
+	folder, err := getFolder(owner, service.Folder)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	perms, err := model.ParseACLPermissions(service.Permissions)
+	if err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
+	acl := &model.FolderACL{
+		FolderID:      folder.ID,
+		OwnerID:       owner.ID,
+		PrincipalType: service.Type,
+		Effect:        service.Effect,
+		Permissions:   perms,
+	}
+
+	if service.Type == model.ACLPrincipalUser {
+		target, err := model.GetActiveUserByEmail(service.Principal)
+		if err != nil {
+			return serializer.Err(serializer.CodeUserNotFound, "", err)
+		}
+		acl.PrincipalID = target.ID
+	} else {
+		groupID, err := strconv.ParseUint(service.Principal, 10, 32)
+		if err != nil {
+			return serializer.ParamErr("Invalid group ID", err)
+		}
+
+		group, err := model.GetGroupByID(groupID)
+		if err != nil {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+		acl.PrincipalID = group.ID
+	}
+
+	id, err := acl.Create()
+	if err != nil {
+		return serializer.DBErr("Failed to create folder ACL", err)
+	}
+
+	return serializer.Response{Data: id}
+}
+
+// List 列出 owner 拥有的目录上设置的 ACL 条目
+func List(c *gin.Context, owner *model.User, folderID string) serializer.Response {
+	folder, err := getFolder(owner, folderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	acls, err := model.ListFolderACLs(folder.ID, owner.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list folder ACLs", err)
+	}
+
+	principals := make(map[uint]string, len(acls))
+	for _, acl := range acls {
+		if acl.PrincipalType == model.ACLPrincipalUser {
+			if user, err := model.GetUserByID(acl.PrincipalID); err == nil {
+				principals[acl.ID] = user.Email
+			}
+		} else if group, err := model.GetGroupByID(acl.PrincipalID); err == nil {
+			principals[acl.ID] = group.Name
+		}
+	}
+
+	return serializer.BuildFolderACLList(acls, principals)
+}
+
+// Delete 删除 owner 目录上的 ACL 条目
+func Delete(c *gin.Context, owner *model.User, id string) serializer.Response {
+	aclID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Folder ACL not found", err)
+	}
+
+	affected, err := model.DeleteFolderACL(uint(aclID), owner.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete folder ACL", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Folder ACL not found", nil)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,92 @@
+package admin
+
+import (
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditListService 审计日志列表服务
+type AuditListService struct {
+	AdminListService
+	// Since、Until 按记录时间筛选，Unix 时间戳，为 0 时不限制
+	Since int64 `json:"since" binding:"min=0"`
+	Until int64 `json:"until" binding:"min=0"`
+}
+
+// recordAudit 记录管理面板中的变更操作，操作者和来源 IP 取自当前请求
+func recordAudit(c *gin.Context, action, targetType string, targetID uint, detail, before, after interface{}) {
+	var actor uint
+	if user, ok := c.Get("user"); ok {
+		if u, ok := user.(*model.User); ok {
+			actor = u.ID
+		}
+	}
+
+	model.RecordAuditChange(actor, c.ClientIP(), action, targetType, targetID, detail, before, after)
+}
+
+// Audits 列出审计日志
+func (service *AuditListService) Audits() serializer.Response {
+	var res []model.AuditLog
+	total := 0
+
+	tx := model.DB.Model(&model.AuditLog{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	if service.Since > 0 {
+		tx = tx.Where("created_at >= ?", time.Unix(service.Since, 0))
+	}
+
+	if service.Until > 0 {
+		tx = tx.Where("created_at <= ?", time.Unix(service.Until, 0))
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	// 查询操作者
+	users := make(map[uint]model.User)
+	for _, log := range res {
+		users[log.ActorID] = model.User{}
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for k := range users {
+		userIDs = append(userIDs, k)
+	}
+
+	var userList []model.User
+	model.DB.Where("id in (?)", userIDs).Find(&userList)
+
+	for _, v := range userList {
+		users[v.ID] = v
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+		"users": users,
+	}}
+}
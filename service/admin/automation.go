@@ -0,0 +1,75 @@
+package admin
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/automation"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// AddAutomationService 自动化规则添加/保存服务
+type AddAutomationService struct {
+	ID       uint                   `json:"id"`
+	Name     string                 `json:"name" binding:"required,max=255"`
+	Enabled  bool                   `json:"enabled"`
+	Event    string                 `json:"event" binding:"required"`
+	GroupID  uint                   `json:"group_id"`
+	Priority int                    `json:"priority"`
+	Script   model.AutomationScript `json:"script"`
+}
+
+// AutomationService 自动化规则ID服务
+type AutomationService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// Add 创建或保存自动化规则
+func (service *AddAutomationService) Add(admin *model.User) serializer.Response {
+	if err := automation.Validate(service.Event, &service.Script, true); err != nil {
+		return serializer.ParamErr(err.Error(), nil)
+	}
+
+	rule := model.Automation{}
+	if service.ID > 0 {
+		existing, err := model.GetAutomationByID(service.ID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "Automation not exist", err)
+		}
+		rule = existing
+	}
+
+	rule.Name = service.Name
+	rule.Enabled = service.Enabled
+	rule.Event = service.Event
+	rule.GroupID = service.GroupID
+	rule.Priority = service.Priority
+	rule.ScriptSerialized = service.Script
+	if err := model.DB.Save(&rule).Error; err != nil {
+		return serializer.DBErr("Failed to save automation", err)
+	}
+
+	model.RecordAudit(admin.ID, "automation.save", model.AuditTargetAutomation, rule.ID, map[string]interface{}{
+		"name":    rule.Name,
+		"enabled": rule.Enabled,
+	})
+	return serializer.Response{Data: rule.ID}
+}
+
+// Delete 删除自动化规则
+func (service *AutomationService) Delete(admin *model.User) serializer.Response {
+	if err := model.DeleteAutomation(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete automation", err)
+	}
+
+	model.RecordAudit(admin.ID, "automation.delete", model.AuditTargetAutomation, service.ID, nil)
+	return serializer.Response{}
+}
+
+// Automations 列出自动化规则
+func (service *NoParamService) Automations() serializer.Response {
+	automations, err := model.ListAutomations()
+	if err != nil {
+		return serializer.DBErr("Failed to list automations", err)
+	}
+
+	return serializer.Response{Data: automations}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
 )
@@ -31,8 +32,24 @@ type ListFolderService struct {
 	Type string `uri:"type" binding:"eq=policy|eq=user"`
 }
 
+// UserFileSearchService 在指定用户的文件中搜索
+type UserFileSearchService struct {
+	UID      uint   `json:"uid" binding:"required"`
+	Keywords string `json:"keywords" binding:"required,min=1,max=255"`
+	Path     string `json:"path" binding:"max=65535"`
+}
+
+// FileTransferService 在用户之间转移文件
+type FileTransferService struct {
+	SrcUID uint   `json:"src_uid" binding:"required"`
+	DstUID uint   `json:"dst_uid" binding:"required"`
+	Dirs   []uint `json:"dirs"`
+	Items  []uint `json:"items"`
+	Dst    string `json:"dst" binding:"required,min=1,max=65535"`
+}
+
 // List 列出指定路径下的目录
-func (service *ListFolderService) List(c *gin.Context) serializer.Response {
+func (service *ListFolderService) List(c *gin.Context, admin *model.User) serializer.Response {
 	if service.Type == "policy" {
 		// 列取存储策略中的目录
 		policy, err := model.GetPolicyByID(service.ID)
@@ -80,11 +97,87 @@ func (service *ListFolderService) List(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeListFilesError, "", err)
 	}
 
+	model.RecordAudit(admin.ID, "admin.file.browse", model.AuditTargetUser, user.ID,
+		map[string]string{"path": service.Path})
+
 	return serializer.Response{
 		Data: serializer.BuildObjectList(0, res, nil),
 	}
 }
 
+// Search 搜索指定用户的文件
+func (service *UserFileSearchService) Search(c *gin.Context, admin *model.User) serializer.Response {
+	user, err := model.GetUserByID(service.UID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if service.Path != "" {
+		ok, parent := fs.IsPathExist(service.Path)
+		if !ok {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		fs.Root = parent
+	}
+
+	objects, err := fs.Search(c.Request.Context(), "%"+service.Keywords+"%")
+	if err != nil {
+		return serializer.Err(serializer.CodeListFilesError, "", err)
+	}
+
+	model.RecordAudit(admin.ID, "admin.file.search", model.AuditTargetUser, user.ID,
+		map[string]string{"path": service.Path, "keywords": service.Keywords})
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"parent":  0,
+			"objects": objects,
+		},
+	}
+}
+
+// Transfer 将文件与目录转移给其他用户
+func (service *FileTransferService) Transfer(c *gin.Context, admin *model.User) serializer.Response {
+	if len(service.Dirs) == 0 && len(service.Items) == 0 {
+		return serializer.ParamErr("No objects to transfer", nil)
+	}
+
+	src, err := model.GetUserByID(service.SrcUID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	dst, err := model.GetUserByID(service.DstUID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	srcFs, err := filesystem.NewFileSystem(&src)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer srcFs.Recycle()
+
+	dstFs, err := filesystem.NewFileSystem(&dst)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer dstFs.Recycle()
+
+	if err := srcFs.TransferTo(c.Request.Context(), dstFs, service.Dst, service.Dirs, service.Items); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	model.RecordAudit(admin.ID, "admin.file.transfer", model.AuditTargetUser, src.ID, service)
+	return serializer.Response{}
+}
+
 // Delete 删除文件
 func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 	files, err := model.GetFilesByIDs(service.ID, 0)
@@ -95,6 +188,11 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 	// 根据用户分组
 	userFile := make(map[uint][]model.File)
 	for i := 0; i < len(files); i++ {
+		recordAudit(c, "file.delete", model.AuditTargetFile, files[i].ID, map[string]bool{
+			"force":       service.Force,
+			"unlink_only": service.UnlinkOnly,
+		}, files[i], nil)
+
 		if _, ok := userFile[files[i].UserID]; !ok {
 			userFile[files[i].UserID] = []model.File{}
 		}
@@ -129,7 +227,7 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 			}
 
 			// 执行删除
-			fs.Delete(context.Background(), []uint{}, ids, service.Force, service.UnlinkOnly)
+			fs.Delete(util.RequestContext(c), []uint{}, ids, service.Force, service.UnlinkOnly)
 			fs.Recycle()
 		}
 	}(userFile)
@@ -139,6 +237,21 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 
 }
 
+// Release 解除文件的病毒隔离状态
+func (service *FileBatchService) Release(c *gin.Context) serializer.Response {
+	affected, err := model.ReleaseQuarantinedFiles(service.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to release quarantined files", err)
+	}
+
+	recordAudit(c, "file.release", model.AuditTargetFile, 0, map[string]interface{}{
+		"ids":      service.ID,
+		"affected": affected,
+	}, nil, nil)
+
+	return serializer.Response{Data: affected}
+}
+
 // Get 预览文件
 func (service *FileService) Get(c *gin.Context) serializer.Response {
 	file, err := model.GetFilesByIDs([]uint{service.ID}, 0)
@@ -146,7 +259,7 @@ func (service *FileService) Get(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeFileNotFound, "", err)
 	}
 
-	ctx := context.WithValue(context.Background(), fsctx.FileModelCtx, &file[0])
+	ctx := context.WithValue(util.RequestContext(c), fsctx.FileModelCtx, &file[0])
 	var subService explorer.FileIDService
 	res := subService.PreviewContent(ctx, c, false)
 
@@ -170,7 +283,7 @@ func (service *AdminListService) Files() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += k + " like '%" + v + "%' OR "
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
@@ -2,7 +2,9 @@ package admin
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/directory"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 	"strconv"
 )
 
@@ -27,7 +29,7 @@ func (service *GroupService) Get() serializer.Response {
 }
 
 // Delete 删除用户组
-func (service *GroupService) Delete() serializer.Response {
+func (service *GroupService) Delete(c *gin.Context) serializer.Response {
 	// 查找用户组
 	group, err := model.GetGroupByID(service.ID)
 	if err != nil {
@@ -49,20 +51,26 @@ func (service *GroupService) Delete() serializer.Response {
 	}
 
 	model.DB.Delete(&group)
+	recordAudit(c, "group.delete", model.AuditTargetGroup, group.ID, nil, group, nil)
 
 	return serializer.Response{}
 }
 
 // Add 添加用户组
-func (service *AddGroupService) Add() serializer.Response {
+func (service *AddGroupService) Add(c *gin.Context) serializer.Response {
 	if service.Group.ID > 0 {
+		old, _ := model.GetGroupByID(service.Group.ID)
 		if err := model.DB.Save(&service.Group).Error; err != nil {
 			return serializer.DBErr("Failed to save group record", err)
 		}
+
+		recordAudit(c, "group.update", model.AuditTargetGroup, service.Group.ID, nil, old, service.Group)
 	} else {
 		if err := model.DB.Create(&service.Group).Error; err != nil {
 			return serializer.DBErr("Failed to create group record", err)
 		}
+
+		recordAudit(c, "group.create", model.AuditTargetGroup, service.Group.ID, nil, nil, service.Group)
 	}
 
 	return serializer.Response{Data: service.Group.ID}
@@ -115,3 +123,27 @@ func (service *AdminListService) Groups() serializer.Response {
 		"policies": policies,
 	}}
 }
+
+// GroupRuleTestService 用户组分配规则测试服务
+type GroupRuleTestService struct {
+	// Rules 待测试的规则，为空时使用已保存的规则
+	Rules  string                `json:"rules"`
+	Claims model.DirectoryClaims `json:"claims" binding:"required"`
+}
+
+// Test 返回给定属性按规则分配到的用户组ID，0 表示保持原用户组
+func (service *GroupRuleTestService) Test() serializer.Response {
+	raw := service.Rules
+	if raw == "" {
+		raw = model.GetSettingByName("directory_group_rules")
+	}
+
+	rules, err := directory.ParseRules(raw)
+	if err != nil {
+		return serializer.ParamErr("Invalid group rules", err)
+	}
+
+	return serializer.Response{
+		Data: directory.Evaluate(rules, &service.Claims, uint(model.GetIntSetting("directory_default_group", 0))),
+	}
+}
@@ -0,0 +1,107 @@
+package admin
+
+import (
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AddInviteService 批量生成邀请码服务
+type AddInviteService struct {
+	Count        int    `json:"count" binding:"required,min=1,max=100"`
+	GroupID      uint   `json:"group_id"`
+	ExtraStorage uint64 `json:"extra_storage"`
+	MaxUses      int    `json:"max_uses" binding:"min=0"`
+	// Expires 有效期（秒），为 0 时永久有效
+	Expires int `json:"expires" binding:"min=0"`
+}
+
+// InviteService 邀请码ID服务
+type InviteService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// Add 批量生成邀请码
+func (service *AddInviteService) Add(c *gin.Context, admin *model.User) serializer.Response {
+	if service.GroupID > 0 {
+		if _, err := model.GetGroupByID(service.GroupID); err != nil {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+	}
+
+	var expires *time.Time
+	if service.Expires > 0 {
+		t := time.Now().Add(time.Duration(service.Expires) * time.Second)
+		expires = &t
+	}
+
+	invites := make([]model.Invite, 0, service.Count)
+	for i := 0; i < service.Count; i++ {
+		invite := model.Invite{
+			Code:         util.RandStringRunes(16),
+			CreatorID:    admin.ID,
+			GroupID:      service.GroupID,
+			ExtraStorage: service.ExtraStorage,
+			MaxUses:      service.MaxUses,
+			ExpiresAt:    expires,
+		}
+		if err := invite.Create(); err != nil {
+			return serializer.DBErr("Failed to create invitation code", err)
+		}
+		invites = append(invites, invite)
+	}
+
+	recordAudit(c, "invite.create", model.AuditTargetInvite, 0, service, nil, nil)
+
+	return serializer.Response{Data: invites}
+}
+
+// Delete 删除邀请码
+func (service *InviteService) Delete(c *gin.Context) serializer.Response {
+	if _, err := model.DeleteInvite(service.ID, 0); err != nil {
+		return serializer.DBErr("Failed to delete invitation code", err)
+	}
+
+	recordAudit(c, "invite.delete", model.AuditTargetInvite, service.ID, nil, nil, nil)
+
+	return serializer.Response{}
+}
+
+// Invites 列出邀请码
+func (service *AdminListService) Invites() serializer.Response {
+	var res []model.Invite
+	total := 0
+
+	tx := model.DB.Model(&model.Invite{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
@@ -4,6 +4,7 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 	"strings"
 )
 
@@ -13,15 +14,20 @@ type AddNodeService struct {
 }
 
 // Add 添加节点
-func (service *AddNodeService) Add() serializer.Response {
+func (service *AddNodeService) Add(c *gin.Context) serializer.Response {
 	if service.Node.ID > 0 {
+		old, _ := model.GetNodeByID(service.Node.ID)
 		if err := model.DB.Save(&service.Node).Error; err != nil {
 			return serializer.DBErr("Failed to save node record", err)
 		}
+
+		recordAudit(c, "node.update", model.AuditTargetNode, service.Node.ID, nil, old, service.Node)
 	} else {
 		if err := model.DB.Create(&service.Node).Error; err != nil {
 			return serializer.DBErr("Failed to create node record", err)
 		}
+
+		recordAudit(c, "node.create", model.AuditTargetNode, service.Node.ID, nil, nil, service.Node)
 	}
 
 	if service.Node.Status == model.NodeActive {
@@ -48,7 +54,7 @@ func (service *AdminListService) Nodes() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += k + " like '%" + v + "%' OR "
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
@@ -81,7 +87,7 @@ type ToggleNodeService struct {
 }
 
 // Toggle 开关节点
-func (service *ToggleNodeService) Toggle() serializer.Response {
+func (service *ToggleNodeService) Toggle(c *gin.Context) serializer.Response {
 	node, err := model.GetNodeByID(service.ID)
 	if err != nil {
 		return serializer.DBErr("Node not found", err)
@@ -92,10 +98,13 @@ func (service *ToggleNodeService) Toggle() serializer.Response {
 		return serializer.Err(serializer.CodeInvalidActionOnSystemNode, "", err)
 	}
 
+	old := node
 	if err = node.SetStatus(service.Desired); err != nil {
 		return serializer.DBErr("Failed to change node status", err)
 	}
 
+	recordAudit(c, "node.toggle", model.AuditTargetNode, node.ID, nil, old, node)
+
 	if service.Desired == model.NodeActive {
 		cluster.Default.Add(&node)
 	} else {
@@ -111,7 +120,7 @@ type NodeService struct {
 }
 
 // Delete 删除节点
-func (service *NodeService) Delete() serializer.Response {
+func (service *NodeService) Delete(c *gin.Context) serializer.Response {
 	// 查找用户组
 	node, err := model.GetNodeByID(service.ID)
 	if err != nil {
@@ -128,6 +137,7 @@ func (service *NodeService) Delete() serializer.Response {
 		return serializer.DBErr("Failed to delete node record", err)
 	}
 
+	recordAudit(c, "node.delete", model.AuditTargetNode, node.ID, nil, node, nil)
 	return serializer.Response{}
 }
 
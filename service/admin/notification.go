@@ -0,0 +1,31 @@
+package admin
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnounceService 发送站内公告服务
+type AnnounceService struct {
+	Title   string `json:"title" binding:"required,min=1,max=255"`
+	Content string `json:"content" binding:"required,max=65535"`
+	Link    string `json:"link" binding:"max=255"`
+}
+
+// Announce 在后台向所有用户发送站内公告
+func (service *AnnounceService) Announce(c *gin.Context) serializer.Response {
+	go func(title, content, link string) {
+		sent, err := notification.Announce(title, content, link)
+		if err != nil {
+			util.Log().Warning("Announcement %q stopped after %d user(s): %s", title, sent, err)
+			return
+		}
+		util.Log().Info("Announcement %q sent to %d user(s).", title, sent)
+	}(service.Title, service.Content, service.Link)
+
+	recordAudit(c, "notification.announce", model.AuditTargetNotification, 0, service, nil, nil)
+	return serializer.Response{}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/googledrive"
 	"net/http"
@@ -18,9 +19,13 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/b2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/cos"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/encrypt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/onedrive"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/oss"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/plugin"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/s3"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
@@ -57,7 +62,7 @@ type PolicyService struct {
 }
 
 // Delete 删除存储策略
-func (service *PolicyService) Delete() serializer.Response {
+func (service *PolicyService) Delete(c *gin.Context) serializer.Response {
 	// 禁止删除默认策略
 	if service.ID == 1 {
 		return serializer.Err(serializer.CodeDeleteDefaultPolicy, "", nil)
@@ -90,6 +95,7 @@ func (service *PolicyService) Delete() serializer.Response {
 
 	model.DB.Delete(&policy)
 	policy.ClearCache()
+	recordAudit(c, "policy.delete", model.AuditTargetPolicy, policy.ID, nil, policy, nil)
 
 	return serializer.Response{}
 }
@@ -104,6 +110,50 @@ func (service *PolicyService) Get() serializer.Response {
 	return serializer.Response{Data: policy}
 }
 
+// Usage 获取存储策略本月用量
+func (service *PolicyService) Usage() serializer.Response {
+	policy, err := model.GetPolicyByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	usage, err := model.GetPolicyUsage(policy.ID, model.UsageMonth(time.Now()))
+	if err != nil {
+		return serializer.DBErr("Failed to query policy usage", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"month":          usage.Month,
+		"traffic":        usage.Traffic,
+		"requests":       usage.Requests,
+		"traffic_budget": policy.OptionsSerialized.TrafficBudget,
+		"request_budget": policy.OptionsSerialized.RequestBudget,
+		"percent":        policy.BudgetPercent(usage),
+		"exhausted":      policy.BudgetExhausted(usage),
+	}}
+}
+
+// Health 获取存储策略的健康检查结果，refresh 为 true 或尚未检查时立即探测
+func (service *PolicyService) Health(refresh bool) serializer.Response {
+	policy, err := model.GetPolicyByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	health, ok := filesystem.GetPolicyHealth(policy.ID)
+	if refresh || !ok {
+		fs, err := filesystem.NewAnonymousFileSystem()
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+
+		health = fs.CheckPolicyHealth(context.Background(), &policy)
+	}
+
+	return serializer.Response{Data: health}
+}
+
 // GetOAuth 获取 OneDrive OAuth 地址
 func (service *PolicyService) GetOAuth(c *gin.Context, policyType string) serializer.Response {
 	policy, err := model.GetPolicyByID(service.ID)
@@ -123,7 +173,7 @@ func (service *PolicyService) GetOAuth(c *gin.Context, policyType string) serial
 			return serializer.Err(serializer.CodeInternalSetting, "Failed to initialize OneDrive client", err)
 		}
 
-		redirect = client.OAuthURL(context.Background(), []string{
+		redirect = client.OAuthURL(util.RequestContext(c), []string{
 			"offline_access",
 			"files.readwrite.all",
 		})
@@ -133,7 +183,7 @@ func (service *PolicyService) GetOAuth(c *gin.Context, policyType string) serial
 			return serializer.Err(serializer.CodeInternalSetting, "Failed to initialize Google Drive client", err)
 		}
 
-		redirect = client.OAuthURL(context.Background(), googledrive.RequiredScope)
+		redirect = client.OAuthURL(util.RequestContext(c), googledrive.RequiredScope)
 	}
 
 	// Delete token cache
@@ -195,6 +245,11 @@ func (service *PolicyService) AddCORS() serializer.Response {
 			return serializer.Err(serializer.CodeAddCORS, "", err)
 		}
 
+		if err := handler.CORS(); err != nil {
+			return serializer.Err(serializer.CodeAddCORS, "", err)
+		}
+	case "b2":
+		handler, _ := b2.NewDriver(&policy)
 		if err := handler.CORS(); err != nil {
 			return serializer.Err(serializer.CodeAddCORS, "", err)
 		}
@@ -275,19 +330,47 @@ func (service *SlaveTestService) Test() serializer.Response {
 }
 
 // Add 添加存储策略
-func (service *AddPolicyService) Add() serializer.Response {
+func (service *AddPolicyService) Add(c *gin.Context) serializer.Response {
 	if service.Policy.Type != "local" && service.Policy.Type != "remote" {
 		service.Policy.DirNameRule = strings.TrimPrefix(service.Policy.DirNameRule, "/")
 	}
 
+	if service.Policy.Type == "plugin" {
+		available, err := plugin.Default.Available()
+		if err != nil {
+			return serializer.Err(serializer.CodeInternalSetting, "Failed to list driver plugins", err)
+		}
+
+		if !util.ContainsString(available, service.Policy.OptionsSerialized.Plugin) {
+			return serializer.ParamErr(fmt.Sprintf("Driver plugin %q not found", service.Policy.OptionsSerialized.Plugin), nil)
+		}
+	}
+
+	if service.Policy.Type == "b2" {
+		// 清除旧凭证缓存，并验证应用密钥能否访问存储桶
+		cache.Deletes([]string{service.Policy.AccessKey}, b2.CredentialCachePrefix)
+		if err := b2.NewClient(&service.Policy).Authorize(util.RequestContext(c)); err != nil {
+			return serializer.ParamErr("Failed to access B2 bucket: "+err.Error(), err)
+		}
+	}
+
+	if err := service.prepareEncryption(); err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
 	if service.Policy.ID > 0 {
+		old, _ := model.GetPolicyByID(service.Policy.ID)
 		if err := model.DB.Save(&service.Policy).Error; err != nil {
 			return serializer.DBErr("Failed to save policy", err)
 		}
+
+		recordAudit(c, "policy.update", model.AuditTargetPolicy, service.Policy.ID, nil, old, service.Policy)
 	} else {
 		if err := model.DB.Create(&service.Policy).Error; err != nil {
 			return serializer.DBErr("Failed to create policy", err)
 		}
+
+		recordAudit(c, "policy.create", model.AuditTargetPolicy, service.Policy.ID, nil, nil, service.Policy)
 	}
 
 	service.Policy.ClearCache()
@@ -295,6 +378,79 @@ func (service *AddPolicyService) Add() serializer.Response {
 	return serializer.Response{Data: service.Policy.ID}
 }
 
+// prepareEncryption 检查静态加密设置，并为新启用加密的存储策略生成主密钥
+func (service *AddPolicyService) prepareEncryption() error {
+	options := &service.Policy.OptionsSerialized
+
+	// 加密适配器需要读写完整的文件块，目前仅支持本机存储策略
+	if options.Encryption && service.Policy.Type != "local" {
+		return fmt.Errorf("encryption is not supported by %q storage policy", service.Policy.Type)
+	}
+
+	if service.Policy.ID > 0 {
+		old, err := model.GetPolicyByID(service.Policy.ID)
+		if err == nil {
+			// 主密钥只能通过轮换追加，避免保存时丢失旧密钥
+			options.EncryptionKeys = old.OptionsSerialized.EncryptionKeys
+
+			if old.Encrypted() != service.Policy.Encrypted() {
+				total := 0
+				model.DB.Model(&model.File{}).Where("policy_id = ?", old.ID).Count(&total)
+				if total > 0 {
+					return errors.New("encryption cannot be changed for a policy that already contains files")
+				}
+			}
+		}
+	}
+
+	if !service.Policy.Encrypted() {
+		return nil
+	}
+
+	if options.ChunkSize%encrypt.ChunkSize != 0 {
+		return fmt.Errorf("chunk size of encrypted policy must be a multiple of %d", encrypt.ChunkSize)
+	}
+
+	if len(options.EncryptionKeys) == 0 {
+		key, err := encrypt.GenerateKey()
+		if err != nil {
+			return err
+		}
+		options.EncryptionKeys = []string{key}
+	}
+
+	return nil
+}
+
+// RotateEncryptionKey 为存储策略生成新的主密钥，新上传的文件使用新密钥加密，
+// 已有文件仍使用旧密钥解密
+func (service *PolicyService) RotateEncryptionKey(c *gin.Context) serializer.Response {
+	policy, err := model.GetPolicyByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	if !policy.Encrypted() {
+		return serializer.ParamErr("Encryption is not enabled for this policy", nil)
+	}
+
+	key, err := encrypt.GenerateKey()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate master key", err)
+	}
+
+	policy.OptionsSerialized.EncryptionKeys = append(policy.OptionsSerialized.EncryptionKeys, key)
+	if err := model.DB.Save(&policy).Error; err != nil {
+		return serializer.DBErr("Failed to save policy", err)
+	}
+
+	policy.ClearCache()
+	recordAudit(c, "policy.rotate_key", model.AuditTargetPolicy, policy.ID,
+		map[string]int{"keys": len(policy.OptionsSerialized.EncryptionKeys)}, nil, nil)
+
+	return serializer.Response{Data: len(policy.OptionsSerialized.EncryptionKeys)}
+}
+
 // Test 测试本地路径
 func (service *PathTestService) Test() serializer.Response {
 	policy := model.Policy{DirNameRule: service.Path}
@@ -311,6 +467,16 @@ func (service *PathTestService) Test() serializer.Response {
 	return serializer.Response{}
 }
 
+// DriverPlugins 列出插件目录下可用的存储驱动插件
+func (service *NoParamService) DriverPlugins() serializer.Response {
+	available, err := plugin.Default.Available()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to list driver plugins", err)
+	}
+
+	return serializer.Response{Data: available}
+}
+
 // Policies 列出存储策略
 func (service *AdminListService) Policies() serializer.Response {
 	var res []model.Policy
@@ -349,9 +515,18 @@ func (service *AdminListService) Policies() serializer.Response {
 		statics[policyId] = total
 	}
 
+	// 最近一次健康检查结果
+	health := make(map[uint]filesystem.PolicyHealth, len(res))
+	for i := 0; i < len(res); i++ {
+		if h, ok := filesystem.GetPolicyHealth(res[i].ID); ok {
+			health[res[i].ID] = h
+		}
+	}
+
 	return serializer.Response{Data: map[string]interface{}{
 		"total":   total,
 		"items":   res,
 		"statics": statics,
+		"health":  health,
 	}}
 }
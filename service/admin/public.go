@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"regexp"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// publicSlug 公开目录标识允许的格式
+var publicSlug = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// AddPublicFolderService 公开目录添加服务
+type AddPublicFolderService struct {
+	FolderID uint   `json:"folder_id" binding:"required"`
+	Slug     string `json:"slug" binding:"required,max=64"`
+}
+
+// PublicFolderService 公开目录ID服务
+type PublicFolderService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// Add 将目录设为游客可只读浏览
+func (service *AddPublicFolderService) Add(admin *model.User) serializer.Response {
+	if !publicSlug.MatchString(service.Slug) {
+		return serializer.ParamErr("Slug can only contain letters, numbers, '-' and '_'", nil)
+	}
+
+	var folder model.Folder
+	if err := model.DB.First(&folder, service.FolderID).Error; err != nil {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	public := &model.PublicFolder{
+		Slug:     service.Slug,
+		FolderID: folder.ID,
+		OwnerID:  folder.OwnerID,
+	}
+	if err := public.Create(); err != nil {
+		return serializer.DBErr("Failed to create public folder, the slug or folder may already be in use", err)
+	}
+
+	model.RecordAudit(admin.ID, "public_folder.create", model.AuditTargetFolder, folder.ID, map[string]string{"slug": service.Slug})
+	return serializer.Response{Data: public.ID}
+}
+
+// Delete 取消目录公开
+func (service *PublicFolderService) Delete(admin *model.User) serializer.Response {
+	if err := model.DeletePublicFolder(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete public folder", err)
+	}
+
+	model.RecordAudit(admin.ID, "public_folder.delete", model.AuditTargetPublicFolder, service.ID, nil)
+	return serializer.Response{}
+}
+
+// PublicFolders 列出公开目录
+func (service *NoParamService) PublicFolders() serializer.Response {
+	publics, err := model.ListPublicFolders()
+	if err != nil {
+		return serializer.DBErr("Failed to list public folders", err)
+	}
+
+	return serializer.Response{Data: publics}
+}
@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportHandleService 处理举报服务
+type ReportHandleService struct {
+	ID     uint   `json:"id" binding:"required"`
+	Action string `json:"action" binding:"required,eq=disable|eq=clear"`
+	Note   string `json:"note" binding:"max=4096"`
+	// Notify 是否发送邮件通知分享者
+	Notify bool `json:"notify"`
+}
+
+// Reports 列出举报
+func (service *AdminListService) Reports() serializer.Response {
+	var res []model.AbuseReport
+	total := 0
+
+	tx := model.DB.Model(&model.AbuseReport{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	// 查询对应的分享者和分享
+	users := make(map[uint]model.User)
+	shares := make(map[uint]model.Share)
+	hashIDs := make(map[uint]string, len(res))
+	for _, report := range res {
+		users[report.OwnerID] = model.User{}
+		shares[report.ShareID] = model.Share{}
+		hashIDs[report.ShareID] = hashid.HashID(report.ShareID, hashid.ShareID)
+	}
+
+	userIDs := make([]uint, 0, len(users))
+	for k := range users {
+		userIDs = append(userIDs, k)
+	}
+
+	shareIDs := make([]uint, 0, len(shares))
+	for k := range shares {
+		shareIDs = append(shareIDs, k)
+	}
+
+	var userList []model.User
+	model.DB.Where("id in (?)", userIDs).Find(&userList)
+	for _, v := range userList {
+		users[v.ID] = v
+	}
+
+	var shareList []model.Share
+	model.DB.Where("id in (?)", shareIDs).Find(&shareList)
+	for _, v := range shareList {
+		shares[v.ID] = v
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total":  total,
+		"items":  res,
+		"users":  users,
+		"shares": shares,
+		"ids":    hashIDs,
+	}}
+}
+
+// Handle 处理举报：禁用被举报的分享及文件，或驳回举报并恢复已禁用的分享。
+// 同一分享其他待处理的举报一并标记为相同的处理结果
+func (service *ReportHandleService) Handle(c *gin.Context) serializer.Response {
+	report, err := model.GetAbuseReportByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Report not found", err)
+	}
+
+	share, err := model.GetShareByID(report.ShareID)
+	if err != nil {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", err)
+	}
+
+	status := model.AbuseReportCleared
+	disabled := service.Action == "disable"
+	if disabled {
+		status = model.AbuseReportDisabled
+	}
+
+	// 仅在分享状态改变时通知分享者
+	changed := share.Disabled != disabled
+	if changed {
+		if err := model.SetShareDisabled(share, disabled); err != nil {
+			return serializer.DBErr("Failed to update share", err)
+		}
+	}
+
+	if err := model.ResolveAbuseReports(share.ID, report.ID, status, service.Note); err != nil {
+		return serializer.DBErr("Failed to update report", err)
+	}
+
+	recordAudit(c, "report.handle", model.AuditTargetReport, report.ID, service, nil, nil)
+	if changed && service.Notify {
+		go notifyShareModerated(share, disabled, service.Note)
+	}
+
+	return serializer.Response{}
+}
+
+// notifyShareModerated 向分享者发送分享被禁用或恢复的通知
+func notifyShareModerated(share *model.Share, disabled bool, note string) {
+	owner := share.Creator()
+	if owner.ID == 0 {
+		return
+	}
+
+	title, body := email.NewModerationEmail(owner.Nick, share.SourceName, disabled, note)
+	if err := email.Send(owner.Email, title, body); err != nil {
+		util.Log().Warning("Failed to send moderation notification to user %d: %s", owner.ID, err)
+	}
+}
@@ -0,0 +1,76 @@
+package admin
+
+import (
+	"errors"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+)
+
+// ScheduleService 定时任务服务
+type ScheduleService struct {
+	Name string `uri:"name" binding:"required"`
+}
+
+// Schedules 列出定时任务
+func (service *NoParamService) Schedules() serializer.Response {
+	return serializer.Response{Data: task.DefaultScheduler.List()}
+}
+
+// Trigger 立即执行定时任务
+func (service *ScheduleService) Trigger(admin *model.User) serializer.Response {
+	if err := task.DefaultScheduler.Trigger(service.Name); err != nil {
+		return scheduleErr(err)
+	}
+
+	model.RecordAudit(admin.ID, "schedule.trigger", model.AuditTargetSchedule, 0, map[string]interface{}{
+		"name": service.Name,
+	})
+	return serializer.Response{}
+}
+
+// Pause 暂停定时任务
+func (service *ScheduleService) Pause(admin *model.User) serializer.Response {
+	if err := task.DefaultScheduler.Pause(service.Name); err != nil {
+		return scheduleErr(err)
+	}
+
+	model.RecordAudit(admin.ID, "schedule.pause", model.AuditTargetSchedule, 0, map[string]interface{}{
+		"name": service.Name,
+	})
+	return serializer.Response{}
+}
+
+// Resume 恢复定时任务
+func (service *ScheduleService) Resume(admin *model.User) serializer.Response {
+	if err := task.DefaultScheduler.Resume(service.Name); err != nil {
+		return scheduleErr(err)
+	}
+
+	model.RecordAudit(admin.ID, "schedule.resume", model.AuditTargetSchedule, 0, map[string]interface{}{
+		"name": service.Name,
+	})
+	return serializer.Response{}
+}
+
+// History 列出定时任务的执行记录
+func (service *ScheduleService) History() serializer.Response {
+	history, err := task.DefaultScheduler.History(service.Name)
+	if err != nil {
+		return scheduleErr(err)
+	}
+
+	return serializer.Response{Data: history}
+}
+
+func scheduleErr(err error) serializer.Response {
+	switch {
+	case errors.Is(err, task.ErrScheduleNotFound):
+		return serializer.Err(serializer.CodeNotFound, "Scheduled job not exist", err)
+	case errors.Is(err, task.ErrScheduleRunning):
+		return serializer.Err(serializer.CodeConflict, "Scheduled job is still running", err)
+	default:
+		return serializer.Err(serializer.CodeInternalSetting, "", err)
+	}
+}
@@ -16,9 +16,16 @@ type ShareBatchService struct {
 
 // Delete 删除文件
 func (service *ShareBatchService) Delete(c *gin.Context) serializer.Response {
+	var shares []model.Share
+	model.DB.Where("id in (?)", service.ID).Find(&shares)
+
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Share{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete share record", err)
 	}
+
+	for _, share := range shares {
+		recordAudit(c, "share.delete", model.AuditTargetShare, share.ID, nil, share, nil)
+	}
 	return serializer.Response{}
 }
 
@@ -39,7 +46,7 @@ func (service *AdminListService) Shares() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += k + " like '%" + v + "%' OR "
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
@@ -2,14 +2,19 @@ package admin
 
 import (
 	"encoding/gob"
+	"fmt"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/preview"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -58,11 +63,27 @@ func (service *BatchSettingGet) Get() serializer.Response {
 }
 
 // Change 批量更改站点设定
-func (service *BatchSettingChangeService) Change() serializer.Response {
+func (service *BatchSettingChangeService) Change(c *gin.Context) serializer.Response {
 	cacheClean := make([]string, 0, len(service.Options))
+	names := make([]string, 0, len(service.Options))
+	changed := make(map[string]string, len(service.Options))
+	for _, setting := range service.Options {
+		names = append(names, setting.Key)
+		changed[setting.Key] = setting.Value
+	}
+
+	old := model.GetSettingByNames(names...)
 	tx := model.DB.Begin()
 
 	for _, setting := range service.Options {
+		// 预览处理器配置需为合法的 JSON
+		if setting.Key == "preview_handlers" {
+			if _, err := preview.Parse(setting.Value); err != nil {
+				cache.Deletes(cacheClean, "setting_")
+				tx.Rollback()
+				return serializer.ParamErr("Invalid preview handlers: "+err.Error(), err)
+			}
+		}
 
 		if err := tx.Model(&model.Setting{}).Where("name = ?", setting.Key).Update("value", setting.Value).Error; err != nil {
 			cache.Deletes(cacheClean, "setting_")
@@ -78,6 +99,7 @@ func (service *BatchSettingChangeService) Change() serializer.Response {
 	}
 
 	cache.Deletes(cacheClean, "setting_")
+	recordAudit(c, "setting.update", model.AuditTargetSetting, 0, nil, old, changed)
 
 	return serializer.Response{}
 }
@@ -163,3 +185,125 @@ func (s *ThumbGeneratorTestService) Test(c *gin.Context) serializer.Response {
 		Data: version,
 	}
 }
+
+// StatsService 站点统计数据服务
+type StatsService struct {
+	Days   int  `form:"days" binding:"omitempty,min=1,max=366"`
+	Policy uint `form:"policy"`
+}
+
+// Stats 获取按日统计的时间序列数据
+func (service *StatsService) Stats() serializer.Response {
+	days := service.Days
+	if days == 0 {
+		days = 30
+	}
+
+	series, err := stats.GetSeries(service.Policy, days, time.Now())
+	if err != nil {
+		return serializer.DBErr("Failed to load statistics", err)
+	}
+
+	return serializer.Response{Data: series}
+}
+
+// TopConsumersService 用户用量排行服务
+type TopConsumersService struct {
+	Days  int    `form:"days" binding:"omitempty,min=1,max=366"`
+	Rank  string `form:"rank" binding:"omitempty,eq=storage|eq=uploads|eq=upload_bytes|eq=downloads|eq=download_bytes"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// Top 获取用量最多的用户
+func (service *TopConsumersService) Top() serializer.Response {
+	consumers, err := service.consumers()
+	if err != nil {
+		return serializer.DBErr("Failed to load statistics", err)
+	}
+
+	return serializer.Response{Data: consumers}
+}
+
+func (service *TopConsumersService) consumers() ([]stats.Consumer, error) {
+	days, rank, limit := service.Days, service.Rank, service.Limit
+	if days == 0 {
+		days = 30
+	}
+	if rank == "" {
+		rank = stats.RankStorage
+	}
+	if limit == 0 {
+		limit = 10
+	}
+
+	return stats.GetTopConsumers(rank, days, time.Now(), limit)
+}
+
+// StatsExportService 导出统计数据服务
+type StatsExportService struct {
+	// Type 导出的数据，daily 为按日统计数据，consumers 为用户用量排行
+	Type string `form:"type" binding:"required,eq=daily|eq=consumers"`
+	StatsService
+	Rank  string `form:"rank" binding:"omitempty,eq=storage|eq=uploads|eq=upload_bytes|eq=downloads|eq=download_bytes"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// Export 以 CSV 格式导出统计数据
+func (service *StatsExportService) Export(c *gin.Context) serializer.Response {
+	var write func() error
+	if service.Type == "consumers" {
+		top := &TopConsumersService{Days: service.Days, Rank: service.Rank, Limit: service.Limit}
+		consumers, err := top.consumers()
+		if err != nil {
+			return serializer.DBErr("Failed to load statistics", err)
+		}
+		write = func() error { return stats.WriteConsumersCSV(c.Writer, consumers) }
+	} else {
+		days := service.Days
+		if days == 0 {
+			days = 30
+		}
+
+		series, err := stats.GetSeries(service.Policy, days, time.Now())
+		if err != nil {
+			return serializer.DBErr("Failed to load statistics", err)
+		}
+		write = func() error { return series.WriteCSV(c.Writer) }
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"stats_%s_%s.csv\"",
+		service.Type, model.StatDate(time.Now())))
+	if err := write(); err != nil {
+		util.Log().Warning("Failed to export statistics: %s", err)
+	}
+
+	return serializer.Response{}
+}
+
+// LogLevelService 运行时日志等级调整服务，仅对当前进程生效，重启后恢复为配置文件中的等级
+type LogLevelService struct {
+	Level string `json:"level" binding:"required,eq=error|eq=warning|eq=info|eq=debug"`
+}
+
+// GetLogLevel 获取当前的日志配置
+func (service *NoParamService) GetLogLevel() serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"level":   util.LogLevel(),
+		"format":  util.Format,
+		"tracing": tracing.Enabled(),
+	}}
+}
+
+// Change 调整日志等级
+func (service *LogLevelService) Change(c *gin.Context) serializer.Response {
+	old := util.LogLevel()
+	if err := util.SetLogLevel(service.Level); err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
+	util.Log().Info("Log level changed from %q to %q.", old, service.Level)
+	recordAudit(c, "log_level.update", model.AuditTargetSetting, 0, nil,
+		map[string]string{"level": old}, map[string]string{"level": service.Level})
+	return serializer.Response{Data: service.Level}
+}
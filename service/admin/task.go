@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/gin-gonic/gin"
@@ -31,6 +32,126 @@ func (service *ImportTaskService) Create(c *gin.Context, user *model.User) seria
 		return serializer.DBErr("Failed to create task record.", err)
 	}
 	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.import", model.AuditTargetTask, 0, service, nil, nil)
+	return serializer.Response{}
+}
+
+// QuotaTaskService 容量重新计算任务
+type QuotaTaskService struct {
+	UIDs    []uint `json:"uids"`
+	Verify  bool   `json:"verify"`
+	Apply   bool   `json:"apply"`
+	Folders bool   `json:"folders"`
+}
+
+// Create 新建容量重新计算任务
+func (service *QuotaTaskService) Create(c *gin.Context, user *model.User) serializer.Response {
+	job, err := task.NewQuotaTask(user, service.UIDs, service.Verify, service.Apply, service.Folders)
+	if err != nil {
+		return serializer.DBErr("Failed to create task record.", err)
+	}
+	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.quota", model.AuditTargetTask, 0, service, nil, nil)
+	return serializer.Response{}
+}
+
+// ThumbTaskService 缩略图重新生成任务
+type ThumbTaskService struct {
+	PolicyID   uint     `json:"policy_id"`
+	UID        uint     `json:"uid"`
+	Extensions []string `json:"exts"`
+	Rate       int      `json:"rate" binding:"min=0"`
+}
+
+// Create 新建缩略图重新生成任务
+func (service *ThumbTaskService) Create(c *gin.Context, user *model.User) serializer.Response {
+	job, err := task.NewThumbTask(user, service.PolicyID, service.UID, service.Extensions, service.Rate)
+	if err != nil {
+		return serializer.DBErr("Failed to create task record.", err)
+	}
+	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.thumb", model.AuditTargetTask, 0, service, nil, nil)
+	return serializer.Response{}
+}
+
+// MigrateTaskService 存储策略迁移任务
+type MigrateTaskService struct {
+	SrcPolicyID uint `json:"src_policy_id" binding:"required"`
+	DstPolicyID uint `json:"dst_policy_id" binding:"required,nefield=SrcPolicyID"`
+	UID         uint `json:"uid"`
+}
+
+// Create 新建存储策略迁移任务
+func (service *MigrateTaskService) Create(c *gin.Context, user *model.User) serializer.Response {
+	for _, id := range []uint{service.SrcPolicyID, service.DstPolicyID} {
+		if _, err := model.GetPolicyByID(id); err != nil {
+			return serializer.Err(serializer.CodePolicyNotExist, "", err)
+		}
+	}
+
+	job, err := task.NewMigrateTask(user, service.SrcPolicyID, service.DstPolicyID, service.UID)
+	if err != nil {
+		return serializer.DBErr("Failed to create task record.", err)
+	}
+	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.migrate", model.AuditTargetTask, 0, service, nil, nil)
+	return serializer.Response{}
+}
+
+// BlobGCTaskService 孤立物理文件回收任务
+type BlobGCTaskService struct {
+	PolicyID      uint `json:"policy_id"`
+	DryRun        bool `json:"dry_run"`
+	RemoveMissing bool `json:"remove_missing"`
+	// GracePeriod 忽略最近修改过的物理文件及记录，单位为秒
+	GracePeriod int `json:"grace_period" binding:"min=0"`
+}
+
+// Create 新建孤立物理文件回收任务
+func (service *BlobGCTaskService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if service.PolicyID > 0 {
+		if _, err := model.GetPolicyByID(service.PolicyID); err != nil {
+			return serializer.Err(serializer.CodePolicyNotExist, "", err)
+		}
+	}
+
+	job, err := task.NewBlobGCTask(user, service.PolicyID, service.DryRun, service.RemoveMissing, service.GracePeriod)
+	if err != nil {
+		return serializer.DBErr("Failed to create task record.", err)
+	}
+	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.blob_gc", model.AuditTargetTask, 0, service, nil, nil)
+	return serializer.Response{}
+}
+
+// ExportApproveService 审核用户数据导出任务
+type ExportApproveService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// Approve 通过用户数据导出任务，并提交执行
+func (service *ExportApproveService) Approve(c *gin.Context) serializer.Response {
+	record, err := model.GetTasksByID(service.ID)
+	if err != nil || record.Type != task.ExportTaskType {
+		return serializer.Err(serializer.CodeNotFound, "Task not found", err)
+	}
+
+	job, err := task.NewExportTaskFromModel(record)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+
+	export := job.(*task.ExportTask)
+	if record.Status != task.Queued || !export.Pending() {
+		return serializer.Err(serializer.CodeConflict, "Task is already approved", nil)
+	}
+
+	if err := export.Approve(); err != nil {
+		return serializer.DBErr("Failed to update task record.", err)
+	}
+
+	task.TaskPoll.Submit(job)
+	recordAudit(c, "task.export.approve", model.AuditTargetTask, record.ID, nil, nil, nil)
 	return serializer.Response{}
 }
 
@@ -39,6 +160,8 @@ func (service *TaskBatchService) Delete(c *gin.Context) serializer.Response {
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Download{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete task records", err)
 	}
+
+	recordAudit(c, "download.delete", model.AuditTargetTask, 0, map[string][]uint{"ids": service.ID}, nil, nil)
 	return serializer.Response{}
 }
 
@@ -47,9 +170,26 @@ func (service *TaskBatchService) DeleteGeneral(c *gin.Context) serializer.Respon
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Task{}).Error; err != nil {
 		return serializer.DBErr("Failed to delete task records", err)
 	}
+
+	recordAudit(c, "task.delete", model.AuditTargetTask, 0, map[string][]uint{"ids": service.ID}, nil, nil)
 	return serializer.Response{}
 }
 
+// Workers 列出已注册的独立任务 Worker
+func (service *NoParamService) Workers() serializer.Response {
+	store, ok := cache.Store.(*cache.RedisStore)
+	if !ok {
+		return serializer.Response{Data: []task.WorkerInfo{}}
+	}
+
+	workers, err := task.ListWorkers(store.Pool())
+	if err != nil {
+		return serializer.Err(serializer.CodeCacheOperation, "Failed to list task workers", err)
+	}
+
+	return serializer.Response{Data: workers}
+}
+
 // Tasks 列出常规任务
 func (service *AdminListService) Tasks() serializer.Response {
 	var res []model.Task
@@ -67,7 +207,7 @@ func (service *AdminListService) Tasks() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += k + " like '%" + v + "%' OR "
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
@@ -121,7 +261,7 @@ func (service *AdminListService) Downloads() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += k + " like '%" + v + "%' OR "
+			search += k + " " + model.LikeOperator() + " '%" + v + "%' OR "
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
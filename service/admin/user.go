@@ -1,12 +1,13 @@
 package admin
 
 import (
-	"context"
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
 )
 
 // AddUserService 用户添加服务
@@ -20,13 +21,24 @@ type UserService struct {
 	ID uint `uri:"id" json:"id" binding:"required"`
 }
 
+// UserRetentionService 用户保留规则设置服务
+type UserRetentionService struct {
+	Rules []model.RetentionRule `json:"rules"`
+}
+
+// UserSpeedLimitService 用户限速设置服务，单位为字节每秒，0 为使用用户组设定
+type UserSpeedLimitService struct {
+	Download int `json:"download" binding:"min=0"`
+	Upload   int `json:"upload" binding:"min=0"`
+}
+
 // UserBatchService 用户批量操作服务
 type UserBatchService struct {
 	ID []uint `json:"id" binding:"min=1"`
 }
 
 // Ban 封禁/解封用户
-func (service *UserService) Ban() serializer.Response {
+func (service *UserService) Ban(c *gin.Context) serializer.Response {
 	user, err := model.GetUserByID(service.ID)
 	if err != nil {
 		return serializer.Err(serializer.CodeUserNotFound, "", err)
@@ -36,17 +48,19 @@ func (service *UserService) Ban() serializer.Response {
 		return serializer.Err(serializer.CodeInvalidActionOnDefaultUser, "", err)
 	}
 
+	old := user
 	if user.Status == model.Active {
 		user.SetStatus(model.Baned)
 	} else {
 		user.SetStatus(model.Active)
 	}
 
+	recordAudit(c, "user.ban", model.AuditTargetUser, user.ID, nil, old, user)
 	return serializer.Response{Data: user.Status}
 }
 
 // Delete 删除用户
-func (service *UserBatchService) Delete() serializer.Response {
+func (service *UserBatchService) Delete(c *gin.Context) serializer.Response {
 	for _, uid := range service.ID {
 		user, err := model.GetUserByID(uid)
 		if err != nil {
@@ -66,7 +80,10 @@ func (service *UserBatchService) Delete() serializer.Response {
 		if err != nil {
 			return serializer.Err(serializer.CodeInternalSetting, "User's root folder not exist", err)
 		}
-		fs.Delete(context.Background(), []uint{root.ID}, []uint{}, false, false)
+		if trash, err := model.GetTrashByUserID(uid); err == nil {
+			fs.PurgeTrash(util.RequestContext(c), trash)
+		}
+		fs.Delete(util.RequestContext(c), []uint{root.ID}, []uint{}, false, false)
 
 		// 删除相关任务
 		model.DB.Where("user_id = ?", uid).Delete(&model.Download{})
@@ -80,6 +97,7 @@ func (service *UserBatchService) Delete() serializer.Response {
 
 		// 删除此用户
 		model.DB.Unscoped().Delete(user)
+		recordAudit(c, "user.delete", model.AuditTargetUser, uid, nil, user, nil)
 
 	}
 	return serializer.Response{}
@@ -95,11 +113,80 @@ func (service *UserService) Get() serializer.Response {
 	return serializer.Response{Data: group}
 }
 
+// Retention 获取用户的保留规则
+func (service *UserService) Retention() serializer.Response {
+	user, err := model.GetUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	return serializer.Response{Data: user.OptionsSerialized.Retention}
+}
+
+// Update 替换用户的保留规则
+func (service *UserRetentionService) Update(c *gin.Context, uid uint, admin *model.User) serializer.Response {
+	user, err := model.GetUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	for _, rule := range service.Rules {
+		if rule.Days < 0 {
+			return serializer.ParamErr("Retention days cannot be negative", nil)
+		}
+
+		if rule.FolderID > 0 {
+			if folders, err := model.GetFoldersByIDs([]uint{rule.FolderID}, user.ID); err != nil || len(folders) == 0 {
+				return serializer.Err(serializer.CodeParentNotExist, "", err)
+			}
+		}
+	}
+
+	user.OptionsSerialized.Retention = service.Rules
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update user preferences", err)
+	}
+
+	model.RecordAudit(admin.ID, "user.retention", model.AuditTargetUser, user.ID, service.Rules)
+	return serializer.Response{Data: user.OptionsSerialized.Retention}
+}
+
+// SpeedLimit 获取用户单独设定的限速
+func (service *UserService) SpeedLimit() serializer.Response {
+	user, err := model.GetUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	return serializer.Response{Data: UserSpeedLimitService{
+		Download: user.OptionsSerialized.SpeedLimit,
+		Upload:   user.OptionsSerialized.UploadSpeedLimit,
+	}}
+}
+
+// Update 设置用户单独的下载、上传限速
+func (service *UserSpeedLimitService) Update(c *gin.Context, uid uint, admin *model.User) serializer.Response {
+	user, err := model.GetUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	user.OptionsSerialized.SpeedLimit = service.Download
+	user.OptionsSerialized.UploadSpeedLimit = service.Upload
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update user preferences", err)
+	}
+
+	model.RecordAudit(admin.ID, "user.speed_limit", model.AuditTargetUser, user.ID, service)
+	return serializer.Response{Data: *service}
+}
+
 // Add 添加用户
-func (service *AddUserService) Add() serializer.Response {
+func (service *AddUserService) Add(c *gin.Context) serializer.Response {
 	if service.User.ID > 0 {
 
 		user, _ := model.GetUserByID(service.User.ID)
+		old := user
 		if service.Password != "" {
 			user.SetPassword(service.Password)
 		}
@@ -124,11 +211,17 @@ func (service *AddUserService) Add() serializer.Response {
 		if err := model.DB.Save(&user).Error; err != nil {
 			return serializer.DBErr("Failed to save user record", err)
 		}
+
+		// 密码不参与序列化，单独记录是否修改
+		recordAudit(c, "user.update", model.AuditTargetUser, user.ID,
+			map[string]bool{"password_changed": service.Password != ""}, old, user)
 	} else {
 		service.User.SetPassword(service.Password)
 		if err := model.DB.Create(&service.User).Error; err != nil {
 			return serializer.DBErr("Failed to create user record", err)
 		}
+
+		recordAudit(c, "user.create", model.AuditTargetUser, service.User.ID, nil, nil, service.User)
 	}
 
 	return serializer.Response{Data: service.User.ID}
@@ -151,7 +244,7 @@ func (service *AdminListService) Users() serializer.Response {
 	if len(service.Searches) > 0 {
 		search := ""
 		for k, v := range service.Searches {
-			search += (k + " like '%" + v + "%' OR ")
+			search += (k + " " + model.LikeOperator() + " '%" + v + "%' OR ")
 		}
 		search = strings.TrimSuffix(search, " OR ")
 		tx = tx.Where(search)
@@ -1,12 +1,15 @@
 package aria2
 
 import (
+	"errors"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
 	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/monitor"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -17,6 +20,8 @@ import (
 type BatchAddURLService struct {
 	URLs []string `json:"url" binding:"required"`
 	Dst  string   `json:"dst" binding:"required,min=1"`
+	// PolicyID 转存使用的存储策略，仅管理员可指定
+	PolicyID string `json:"policy_id"`
 }
 
 // Add 主机批量创建新的链接离线下载任务
@@ -44,11 +49,17 @@ func (service *BatchAddURLService) Add(c *gin.Context, taskType int) serializer.
 		return serializer.Err(serializer.CodeBatchAria2Size, "", nil)
 	}
 
+	// 检查指定的存储策略
+	if _, errRes := targetPolicy(fs.User, service.PolicyID); errRes != nil {
+		return *errRes
+	}
+
 	res := make([]serializer.Response, 0, len(service.URLs))
 	for _, target := range service.URLs {
 		subService := &AddURLService{
-			URL: target,
-			Dst: service.Dst,
+			URL:      target,
+			Dst:      service.Dst,
+			PolicyID: service.PolicyID,
 		}
 
 		addRes := subService.Add(c, fs, taskType)
@@ -62,6 +73,8 @@ func (service *BatchAddURLService) Add(c *gin.Context, taskType int) serializer.
 type AddURLService struct {
 	URL string `json:"url" binding:"required"`
 	Dst string `json:"dst" binding:"required,min=1"`
+	// PolicyID 转存使用的存储策略，仅管理员可指定
+	PolicyID string `json:"policy_id"`
 }
 
 // Add 主机创建新的链接离线下载任务
@@ -92,13 +105,19 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 		return serializer.Err(serializer.CodeBatchAria2Size, "", nil)
 	}
 
+	policyID, errRes := targetPolicy(fs.User, service.PolicyID)
+	if errRes != nil {
+		return *errRes
+	}
+
 	// 创建任务
 	task := &model.Download{
-		Status: common.Ready,
-		Type:   taskType,
-		Dst:    service.Dst,
-		UserID: fs.User.ID,
-		Source: service.URL,
+		Status:   common.Ready,
+		Type:     taskType,
+		Dst:      service.Dst,
+		UserID:   fs.User.ID,
+		Source:   service.URL,
+		PolicyID: policyID,
 	}
 
 	// 获取 Aria2 负载均衡器
@@ -113,8 +132,13 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 	// 创建任务
 	gid, err := node.GetAria2Instance().CreateTask(task, fs.User.Group.OptionsSerialized.Aria2Options)
 	if err != nil {
+		// 节点下载器未就绪，暂时不再分配新任务
+		if errors.Is(err, common.ErrNotEnabled) {
+			common.Health.MarkUnhealthy(node.ID())
+		}
 		return serializer.Err(serializer.CodeCreateTaskError, "", err)
 	}
+	common.Health.Succeed(node.ID())
 
 	task.GID = gid
 	task.NodeID = node.ID()
@@ -129,6 +153,31 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 	return serializer.Response{}
 }
 
+// targetPolicy 解析任务指定的存储策略，未指定时返回 0 即使用用户默认策略
+func targetPolicy(user *model.User, hashID string) (uint, *serializer.Response) {
+	if hashID == "" {
+		return 0, nil
+	}
+
+	if user.Group.ID != 1 && user.ID != 1 {
+		res := serializer.Err(serializer.CodeNoPermissionErr, "Only administrators can specify storage policy", nil)
+		return 0, &res
+	}
+
+	id, err := hashid.DecodeHashID(hashID, hashid.PolicyID)
+	if err != nil {
+		res := serializer.Err(serializer.CodePolicyNotExist, "", err)
+		return 0, &res
+	}
+
+	if _, err := model.GetPolicyByID(id); err != nil {
+		res := serializer.Err(serializer.CodePolicyNotExist, "", err)
+		return 0, &res
+	}
+
+	return id, nil
+}
+
 // Add 从机创建新的链接离线下载任务
 func Add(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
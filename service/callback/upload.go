@@ -1,17 +1,18 @@
 package callback
 
 import (
-	"context"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"strings"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/b2"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/cos"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/onedrive"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/s3"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -63,6 +64,10 @@ type COSCallback struct {
 type S3Callback struct {
 }
 
+// B2Callback Backblaze B2 客户端回调正文
+type B2Callback struct {
+}
+
 // GetBody 返回回调正文
 func (service UpyunCallbackService) GetBody() serializer.UploadCallback {
 	res := serializer.UploadCallback{}
@@ -105,6 +110,13 @@ func (service S3Callback) GetBody() serializer.UploadCallback {
 	}
 }
 
+// GetBody 返回回调正文
+func (service B2Callback) GetBody() serializer.UploadCallback {
+	return serializer.UploadCallback{
+		PicInfo: "",
+	}
+}
+
 // ProcessCallback 处理上传结果回调
 func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.Response {
 	callbackBody := service.GetBody()
@@ -135,15 +147,22 @@ func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.
 		LastModified: uploadSession.LastModified,
 	}
 
-	// 占位符未扣除容量需要校验和扣除
+	// 占位符未扣除容量需要校验和扣除，创建会话时已预留容量的无需再次校验
 	if !fs.Policy.IsUploadPlaceholderWithSize() {
-		fs.Use("AfterUpload", filesystem.HookValidateCapacity)
+		if file.ReservedSize() == 0 {
+			fs.Use("AfterUpload", filesystem.HookValidateCapacity)
+		}
 		fs.Use("AfterUpload", filesystem.HookChunkUploaded)
 	}
 
 	fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(callbackBody.PicInfo))
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
 	fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
-	err = fs.Upload(context.Background(), &fileData)
+	err = fs.Upload(util.RequestContext(c), &fileData)
 	if err != nil {
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
@@ -164,7 +183,7 @@ func (service *OneDriveCallback) PreProcess(c *gin.Context) serializer.Response
 	uploadSession := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
 
 	// 获取文件信息
-	info, err := fs.Handler.(onedrive.Driver).Client.Meta(context.Background(), "", uploadSession.SavePath)
+	info, err := fs.Handler.(onedrive.Driver).Client.Meta(util.RequestContext(c), "", uploadSession.SavePath)
 	if err != nil {
 		return serializer.Err(serializer.CodeQueryMetaFailed, "", err)
 	}
@@ -180,7 +199,7 @@ func (service *OneDriveCallback) PreProcess(c *gin.Context) serializer.Response
 	}
 
 	if isSizeCheckFailed || !strings.EqualFold(info.GetSourcePath(), actualPath) {
-		fs.Handler.(onedrive.Driver).Client.Delete(context.Background(), []string{info.GetSourcePath()})
+		fs.Handler.(onedrive.Driver).Client.Delete(util.RequestContext(c), []string{info.GetSourcePath()})
 		return serializer.Err(serializer.CodeMetaMismatch, "", err)
 	}
 	service.Meta = info
@@ -200,7 +219,7 @@ func (service *COSCallback) PreProcess(c *gin.Context) serializer.Response {
 	uploadSession := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
 
 	// 获取文件信息
-	info, err := fs.Handler.(cos.Driver).Meta(context.Background(), uploadSession.SavePath)
+	info, err := fs.Handler.(cos.Driver).Meta(util.RequestContext(c), uploadSession.SavePath)
 	if err != nil {
 		return serializer.Err(serializer.CodeMetaMismatch, "", err)
 	}
@@ -226,7 +245,7 @@ func (service *S3Callback) PreProcess(c *gin.Context) serializer.Response {
 	uploadSession := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
 
 	// 获取文件信息
-	info, err := fs.Handler.(*s3.Driver).Meta(context.Background(), uploadSession.SavePath)
+	info, err := fs.Handler.(*s3.Driver).Meta(util.RequestContext(c), uploadSession.SavePath)
 	if err != nil {
 		return serializer.Err(serializer.CodeMetaMismatch, "", err)
 	}
@@ -239,6 +258,38 @@ func (service *S3Callback) PreProcess(c *gin.Context) serializer.Response {
 	return ProcessCallback(service, c)
 }
 
+// PreProcess 对Backblaze B2客户端回调进行预处理
+func (service *B2Callback) PreProcess(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromCallback(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 获取回调会话
+	uploadSession := c.MustGet(filesystem.UploadSessionCtx).(*serializer.UploadSession)
+	handler := fs.Handler.(*b2.Driver)
+
+	// 完成分片上传
+	if err := handler.CompleteUpload(util.RequestContext(c), uploadSession); err != nil {
+		return serializer.Err(serializer.CodeMetaMismatch, "Failed to finish large file", err)
+	}
+
+	// 获取文件信息
+	info, err := handler.Meta(util.RequestContext(c), uploadSession.SavePath)
+	if err != nil {
+		return serializer.Err(serializer.CodeMetaMismatch, "", err)
+	}
+
+	// 验证实际文件信息与回调会话中是否一致
+	if uploadSession.Size != info.ContentLength {
+		return serializer.Err(serializer.CodeMetaMismatch, "", err)
+	}
+
+	return ProcessCallback(service, c)
+}
+
 // PreProcess 对从机客户端回调进行预处理验证
 func (service *UploadCallbackService) PreProcess(c *gin.Context) serializer.Response {
 	// 创建文件系统
@@ -253,7 +304,7 @@ func (service *UploadCallbackService) PreProcess(c *gin.Context) serializer.Resp
 
 	// 验证文件大小
 	if uploadSession.Size != service.Size {
-		fs.Handler.Delete(context.Background(), []string{uploadSession.SavePath})
+		fs.Handler.Delete(util.RequestContext(c), []string{uploadSession.SavePath})
 		return serializer.Err(serializer.CodeMetaMismatch, "", err)
 	}
 
@@ -0,0 +1,279 @@
+package collaboration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateService 将目录共享给其他用户服务
+type CreateService struct {
+	Folder     string `json:"folder" binding:"required"`
+	User       string `json:"user" binding:"required,email"`
+	Permission string `json:"permission" binding:"required,eq=read|eq=write"`
+}
+
+// Service 共享记录服务
+type Service struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// PathService 访问「与我共享」虚拟目录下的对象服务
+type PathService struct {
+	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
+}
+
+// Create 将目录共享给给定邮箱的用户，已共享时更新权限
+func (service *CreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.ShareEnabled {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	folderID, err := hashid.DecodeHashID(service.Folder, hashid.FolderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{folderID}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	target, err := model.GetActiveUserByEmail(service.User)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if target.ID == user.ID {
+		return serializer.ParamErr("Cannot share a folder with yourself", nil)
+	}
+
+	collab := &model.Collaboration{
+		FolderID:   folderID,
+		OwnerID:    user.ID,
+		UserID:     target.ID,
+		Permission: service.Permission,
+	}
+	if err := collab.Create(); err != nil {
+		return serializer.DBErr("Failed to create collaboration", err)
+	}
+
+	link := "/home?path=" + url.QueryEscape("/"+filesystem.SharedRootName)
+	if _, err := notification.Send(target.ID, model.NotificationShared, fmt.Sprintf("%s 与您共享了目录", user.Nick),
+		folders[0].Name, link); err != nil {
+		util.Log().Warning("Failed to notify user %d of shared folder: %s", target.ID, err)
+	}
+
+	return serializer.Response{Data: collab.ID}
+}
+
+// List 列出用户共享出去的目录
+func List(c *gin.Context, user *model.User) serializer.Response {
+	collabs, err := model.ListCollaborationsByOwner(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list collaborations", err)
+	}
+
+	for i := range collabs {
+		if folders, err := model.GetFoldersByIDs([]uint{collabs[i].FolderID}, user.ID); err == nil && len(folders) > 0 {
+			collabs[i].Folder = &folders[0]
+		}
+		if target, err := model.GetUserByID(collabs[i].UserID); err == nil {
+			collabs[i].User = &target
+		}
+	}
+
+	return serializer.BuildCollaborationList(collabs)
+}
+
+// Shared 列出共享给用户的目录
+func Shared(c *gin.Context, user *model.User) serializer.Response {
+	collabs, err := model.ListSharedWithUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list shared folders", err)
+	}
+
+	return serializer.BuildSharedFolderList(collabs)
+}
+
+// Delete 取消共享，所有者和协作者均可取消
+func (service *Service) Delete(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.DeleteCollaboration(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete collaboration", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Collaboration not found", nil)
+	}
+
+	return serializer.Response{}
+}
+
+// prepareFs 解析「与我共享」下的路径，返回以共享目录为根目录的文件系统、挂载名称和挂载点内的路径。
+// 文件系统以当前用户的身份访问，操作受目录 ACL 和共享权限限制
+func (service *PathService) prepareFs(user *model.User) (*filesystem.FileSystem, string, string, error) {
+	mount, rest, ok := filesystem.SplitSharedPath(path.Clean("/" + service.Path))
+	if !ok || mount == "" {
+		return nil, "", "", filesystem.ErrPathNotExist
+	}
+
+	collabs, err := model.ListSharedWithUser(user.ID)
+	if err != nil {
+		return nil, "", "", serializer.NewError(serializer.CodeDBError, "Failed to list shared folders", err)
+	}
+
+	collab := filesystem.FindCollaboration(collabs, mount)
+	if collab == nil {
+		return nil, "", "", filesystem.ErrPathNotExist
+	}
+
+	fs, err := filesystem.NewCollaborationFileSystem(collab)
+	if err != nil {
+		return nil, "", "", serializer.NewError(serializer.CodeCreateFSError, "", err)
+	}
+
+	fs.UseActor(user, collab.Permissions())
+	return fs, mount, rest, nil
+}
+
+// List 列出「与我共享」虚拟目录或其下共享目录中的内容
+func (service *PathService) List(c *gin.Context, user *model.User) serializer.Response {
+	fullPath := path.Clean("/" + service.Path)
+	if mount, _, ok := filesystem.SplitSharedPath(fullPath); ok && mount == "" {
+		collabs, err := model.ListSharedWithUser(user.ID)
+		if err != nil {
+			return serializer.DBErr("Failed to list shared folders", err)
+		}
+
+		return serializer.BuildSharedRootObjects(collabs, fullPath)
+	}
+
+	fs, mount, rest, err := service.prepareFs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	objects, err := fs.List(ctx, rest, nil)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	// 返回包含虚拟目录的完整路径
+	for i := range objects {
+		objects[i].Path = path.Join("/", filesystem.SharedRootName, mount, objects[i].Path)
+	}
+
+	return serializer.Response{Data: serializer.BuildObjectList(0, objects, fs.Policy)}
+}
+
+// Download 获取共享目录下文件的下载地址
+func (service *PathService) Download(c *gin.Context, user *model.User) serializer.Response {
+	fs, _, rest, err := service.prepareFs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx := util.RequestContext(c)
+	if err := fs.ResetFileIfNotExist(ctx, rest); err != nil {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	ctx = filesystem.WithDownloadOptions(ctx, c)
+	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: downloadURL}
+}
+
+// CreateDirectory 在共享目录下创建目录
+func (service *PathService) CreateDirectory(c *gin.Context, user *model.User) serializer.Response {
+	fs, _, rest, err := service.prepareFs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	if _, err := fs.CreateDirectory(util.RequestContext(c), rest); err != nil {
+		return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Upload 将请求正文上传为共享目录下的文件
+func (service *PathService) Upload(c *gin.Context, user *model.User) serializer.Response {
+	if c.Request.ContentLength < 0 {
+		return serializer.Err(serializer.CodeInvalidContentLength, "", nil)
+	}
+
+	fs, _, rest, err := service.prepareFs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, c.Request.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	file := &fsctx.FileStream{
+		File:        filesystem.WithUploadSpeedLimit(c.Request.Body, fs.User.UploadSpeedLimit()),
+		Size:        uint64(c.Request.ContentLength),
+		Name:        path.Base(rest),
+		MimeType:    c.GetHeader("Content-Type"),
+		VirtualPath: path.Dir(rest),
+	}
+	if err := fs.UploadFromStream(ctx, file, true); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 删除共享目录下的对象，所有者开启回收站时移入其回收站
+func (service *PathService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	fs, _, rest, err := service.prepareFs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	// 不能删除共享目录本身
+	if rest == "/" {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Cannot delete the shared folder itself", nil)
+	}
+
+	var dirs, files []uint
+	if exist, folder := fs.IsPathExist(rest); exist {
+		dirs = append(dirs, folder.ID)
+	} else if exist, file := fs.IsFileExist(rest); exist {
+		files = append(files, file.ID)
+	} else {
+		return serializer.Err(serializer.CodeNotFound, "", filesystem.ErrObjectNotExist)
+	}
+
+	if err := fs.Trash(util.RequestContext(c), dirs, files); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,46 @@
+package explorer
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ChangeListService 增量变更列取服务
+type ChangeListService struct {
+	// Cursor 上次拉取返回的游标，为空时返回当前最新游标
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"min=0,max=1000"`
+}
+
+// List 列出用户空间在游标之后发生的变更
+func (service *ChangeListService) List(c *gin.Context, user *model.User) serializer.Response {
+	// 首次同步，客户端应在获取游标后完整列取一次目录树
+	if service.Cursor == "" {
+		return serializer.BuildChangeList(nil, model.GetLatestChangeID(user.ID), false)
+	}
+
+	cursor, err := hashid.DecodeHashID(service.Cursor, hashid.ChangeID)
+	if err != nil {
+		return serializer.ParamErr("Invalid cursor", err)
+	}
+
+	limit := service.Limit
+	if limit == 0 {
+		limit = 200
+	}
+
+	// 多取一条以判断是否还有更多变更
+	changes, err := model.ListChanges(user.ID, cursor, limit+1)
+	if err != nil {
+		return serializer.DBErr("Failed to list changes", err)
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	return serializer.BuildChangeList(changes, cursor, hasMore)
+}
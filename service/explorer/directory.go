@@ -2,9 +2,13 @@ package explorer
 
 import (
 	"context"
+	"encoding/json"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,6 +17,25 @@ type DirectoryService struct {
 	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
 }
 
+// StreamDirectoryService 流式列出目录内容服务
+type StreamDirectoryService struct {
+	Path    string `uri:"path" binding:"required,min=1,max=65535"`
+	Type    string `form:"type" binding:"omitempty,eq=file|eq=dir"`
+	Keyword string `form:"keyword" binding:"max=255"`
+	MinSize uint64 `form:"min_size"`
+	MaxSize uint64 `form:"max_size"`
+}
+
+// FolderQuotaService 目录容量配额设置服务
+type FolderQuotaService struct {
+	ID string `json:"id" binding:"required"`
+	// Size 配额大小，为 0 时取消配额
+	Size uint64 `json:"size"`
+}
+
+// streamFlushInterval 流式列取时每输出多少个对象刷新一次缓冲
+const streamFlushInterval = 100
+
 // ListDirectory 列出目录内容
 func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Response {
 	// 创建文件系统
@@ -23,26 +46,76 @@ func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Respon
 	defer fs.Recycle()
 
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
-	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	folder, err := fs.ListTarget(service.Path)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
-	var parentID uint
-	if len(fs.DirTarget) > 0 {
-		parentID = fs.DirTarget[0].ID
+	// 列表未变化时直接返回 304，不再查询子项目
+	if util.NotModified(c, fs.ListingETag(ctx, folder)) {
+		return serializer.Response{}
 	}
 
+	// 获取子项目
+	objects := fs.ListFolder(ctx, folder, nil)
+
 	return serializer.Response{
 		Code: 0,
-		Data: serializer.BuildObjectList(parentID, objects, fs.Policy),
+		Data: serializer.BuildObjectList(folder.ID, objects, fs.Policy),
 	}
 }
 
+// Stream 以 NDJSON 格式逐行输出目录下满足条件的对象，适用于超大目录
+func (service *StreamDirectoryService) Stream(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	filter := &model.ChildFilter{
+		Keyword: service.Keyword,
+		MinSize: service.MinSize,
+		MaxSize: service.MaxSize,
+	}
+
+	// 首个对象输出前才写入响应头，以便路径不存在时仍可返回普通 JSON 错误
+	writeHeader := func() {
+		if !c.Writer.Written() {
+			c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+			c.Header("Cache-Control", "no-cache")
+			c.Status(200)
+			c.Writer.WriteHeaderNow()
+		}
+	}
+
+	count := 0
+	encoder := json.NewEncoder(c.Writer)
+	err = fs.StreamList(c.Request.Context(), service.Path, service.Type, filter, func(object *serializer.Object) error {
+		writeHeader()
+		if err := encoder.Encode(object); err != nil {
+			return err
+		}
+
+		count++
+		if count%streamFlushInterval == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	writeHeader()
+	c.Writer.Flush()
+	return serializer.Response{}
+}
+
 // CreateDirectory 创建目录
 func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Response {
 	// 创建文件系统
@@ -53,7 +126,7 @@ func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Resp
 	defer fs.Recycle()
 
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 创建目录
@@ -66,3 +139,28 @@ func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Resp
 	}
 
 }
+
+// Set 设定目录的容量配额
+func (service *FolderQuotaService) Set(user *model.User) serializer.Response {
+	id, err := hashid.DecodeHashID(service.ID, hashid.FolderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{id}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	// 根目录受用户容量限制，无需单独设定配额
+	if folders[0].ParentID == nil {
+		return serializer.ParamErr("Cannot set quota on the root folder", nil)
+	}
+
+	user.SetFolderQuota(id, service.Size)
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update user preferences", err)
+	}
+
+	return serializer.Response{}
+}
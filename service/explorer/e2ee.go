@@ -0,0 +1,441 @@
+package explorer
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyPairService 保存用户端到端加密密钥对服务
+type KeyPairService struct {
+	PublicKey  string `json:"public_key" binding:"required,max=65535"`
+	PrivateKey string `json:"private_key" binding:"required,max=65535"`
+}
+
+// PublicKeyService 获取其他用户公钥服务
+type PublicKeyService struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// EnableEncryptionService 为目录启用端到端加密服务
+type EnableEncryptionService struct {
+	ID        string `json:"id" binding:"required"`
+	Algorithm string `json:"algorithm" binding:"required,max=64"`
+	// Envelope 使用创建者公钥包装的目录密钥
+	Envelope string `json:"envelope" binding:"required,max=65535"`
+}
+
+// EncryptedFolderService 加密目录服务
+type EncryptedFolderService struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// GrantEncryptionService 向其他用户分发目录密钥服务
+type GrantEncryptionService struct {
+	ID   string `uri:"id" binding:"required"`
+	User string `json:"user" binding:"required"`
+	// Envelope 使用接收方公钥包装的目录密钥
+	Envelope string `json:"envelope" binding:"required,max=65535"`
+}
+
+// RevokeEncryptionService 撤销其他用户的目录密钥服务
+type RevokeEncryptionService struct {
+	ID   string `uri:"id" binding:"required"`
+	User string `uri:"user" binding:"required"`
+}
+
+// EncryptedNameService 设置对象加密名称服务
+type EncryptedNameService struct {
+	ID   string `json:"id" binding:"required"`
+	Type string `json:"type" binding:"required,eq=file|eq=dir"`
+	Name string `json:"name" binding:"required,max=65535"`
+}
+
+// ShareEnvelopeService 设置分享链接目录密钥服务
+type ShareEnvelopeService struct {
+	// Envelope 使用分享密钥包装的目录密钥，分享密钥由客户端放在链接中，不会发送至服务端
+	Envelope string `json:"envelope" binding:"required,max=65535"`
+}
+
+// ShareEncryptionService 获取分享中加密目录信息服务
+type ShareEncryptionService struct {
+	// Folder 分享目录下需要获取加密名称的子目录，为空时使用分享的目录
+	Folder string `form:"folder"`
+}
+
+// GetKeyPair 获取当前用户的密钥对
+func GetKeyPair(user *model.User) serializer.Response {
+	pair, err := model.GetUserKeyPair(user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeKeyPairNotSet, "", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildKeyPair(&pair, true)}
+}
+
+// Save 创建或替换当前用户的密钥对，替换后客户端需重新分发已有的目录密钥
+func (service *KeyPairService) Save(user *model.User) serializer.Response {
+	pair, err := model.SaveUserKeyPair(user.ID, service.PublicKey, service.PrivateKey)
+	if err != nil {
+		return serializer.DBErr("Failed to save key pair", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildKeyPair(&pair, true)}
+}
+
+// Get 获取用户的公钥
+func (service *PublicKeyService) Get() serializer.Response {
+	uid, err := hashid.DecodeHashID(service.ID, hashid.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	pair, err := model.GetUserKeyPair(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeKeyPairNotSet, "", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildKeyPair(&pair, false)}
+}
+
+// Enable 为空目录启用端到端加密，启用后服务端只保存客户端加密后的内容
+func (service *EnableEncryptionService) Enable(user *model.User) serializer.Response {
+	id, err := hashid.DecodeHashID(service.ID, hashid.FolderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{id}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	folder := &folders[0]
+	if folder.ParentID == nil {
+		return serializer.ParamErr("Cannot enable encryption on the root folder", nil)
+	}
+
+	if _, err := model.GetUserKeyPair(user.ID); err != nil {
+		return serializer.Err(serializer.CodeKeyPairNotSet, "", err)
+	}
+
+	ancestors, err := model.GetFolderAncestors(id)
+	if err != nil {
+		return serializer.DBErr("Failed to list parent folders", err)
+	}
+
+	if _, err := model.GetEncryptedFolderInAncestors(ancestors); err == nil {
+		return serializer.ParamErr("Folder is already end-to-end encrypted", nil)
+	}
+
+	// 已有的明文内容无法由服务端加密，只允许为空目录启用
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return serializer.DBErr("Failed to list files", err)
+	}
+
+	children, err := folder.GetChildFolder()
+	if err != nil {
+		return serializer.DBErr("Failed to list folders", err)
+	}
+
+	if len(files) > 0 || len(children) > 0 {
+		return serializer.Err(serializer.CodeFolderNotEmpty, "", nil)
+	}
+
+	encrypted := &model.EncryptedFolder{
+		FolderID:  id,
+		OwnerID:   user.ID,
+		Algorithm: service.Algorithm,
+	}
+	if err := encrypted.Create(); err != nil {
+		return serializer.DBErr("Failed to create encrypted folder", err)
+	}
+
+	if err := model.SaveFolderKeyEnvelope(encrypted.ID, user.ID, 0, service.Envelope); err != nil {
+		return serializer.DBErr("Failed to save folder key", err)
+	}
+
+	envelope := &model.FolderKeyEnvelope{UserID: user.ID, Envelope: service.Envelope}
+	return serializer.Response{Data: serializer.BuildEncryptedFolder(encrypted, envelope, nil)}
+}
+
+// Get 获取目录所在的加密目录信息、当前用户的目录密钥及子对象的加密名称
+func (service *EncryptedFolderService) Get(user *model.User) serializer.Response {
+	folder, encrypted, res := userEncryptedFolder(service.ID, user)
+	if res != nil {
+		return *res
+	}
+
+	var envelope *model.FolderKeyEnvelope
+	if userEnvelope, err := model.GetFolderKeyEnvelope(encrypted.ID, user.ID, 0); err == nil {
+		envelope = &userEnvelope
+	}
+
+	names, err := childEncryptedNames(folder)
+	if err != nil {
+		return serializer.DBErr("Failed to list encrypted names", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildEncryptedFolder(encrypted, envelope, names)}
+}
+
+// Grant 向其他用户分发目录密钥
+func (service *GrantEncryptionService) Grant(user *model.User) serializer.Response {
+	_, encrypted, res := userEncryptedFolder(service.ID, user)
+	if res != nil {
+		return *res
+	}
+
+	uid, err := hashid.DecodeHashID(service.User, hashid.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if _, err := model.GetActiveUserByID(uid); err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if _, err := model.GetUserKeyPair(uid); err != nil {
+		return serializer.Err(serializer.CodeKeyPairNotSet, "", err)
+	}
+
+	if err := model.SaveFolderKeyEnvelope(encrypted.ID, uid, 0, service.Envelope); err != nil {
+		return serializer.DBErr("Failed to save folder key", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Revoke 撤销其他用户的目录密钥，已获取密钥的客户端仍可解密，需要时应轮换目录密钥
+func (service *RevokeEncryptionService) Revoke(user *model.User) serializer.Response {
+	_, encrypted, res := userEncryptedFolder(service.ID, user)
+	if res != nil {
+		return *res
+	}
+
+	uid, err := hashid.DecodeHashID(service.User, hashid.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if uid == user.ID {
+		return serializer.ParamErr("Cannot revoke folder key of the owner", nil)
+	}
+
+	affected, err := model.DeleteFolderKeyEnvelope(encrypted.ID, uid, 0)
+	if err != nil {
+		return serializer.DBErr("Failed to delete folder key", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Folder key not found", nil)
+	}
+
+	return serializer.Response{}
+}
+
+// Set 设置加密目录中对象的加密名称
+func (service *EncryptedNameService) Set(user *model.User) serializer.Response {
+	var (
+		objectID uint
+		parentID uint
+		err      error
+	)
+
+	if service.Type == model.EncryptedNameFile {
+		objectID, err = hashid.DecodeHashID(service.ID, hashid.FileID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "", err)
+		}
+
+		files, err := model.GetFilesByIDs([]uint{objectID}, user.ID)
+		if err != nil || len(files) == 0 {
+			return serializer.Err(serializer.CodeFileNotFound, "", err)
+		}
+		parentID = files[0].FolderID
+	} else {
+		objectID, err = hashid.DecodeHashID(service.ID, hashid.FolderID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "", err)
+		}
+
+		folders, err := model.GetFoldersByIDs([]uint{objectID}, user.ID)
+		if err != nil || len(folders) == 0 {
+			return serializer.Err(serializer.CodeParentNotExist, "", err)
+		}
+
+		if folders[0].ParentID == nil {
+			return serializer.Err(serializer.CodeNotEncryptedFolder, "", nil)
+		}
+		parentID = *folders[0].ParentID
+	}
+
+	encrypted, err := model.GetEncryptedFolderByFolderID(parentID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotEncryptedFolder, "", err)
+	}
+
+	if err := model.SaveEncryptedName(encrypted.ID, service.Type, objectID, service.Name); err != nil {
+		return serializer.DBErr("Failed to save encrypted name", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Set 设置分享链接使用的目录密钥
+func (service *ShareEnvelopeService) Set(c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+	encrypted, err := shareEncryptedFolder(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotEncryptedFolder, "", err)
+	}
+
+	if err := model.SaveFolderKeyEnvelope(encrypted.ID, 0, share.ID, service.Envelope); err != nil {
+		return serializer.DBErr("Failed to save folder key", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Get 获取分享中的加密目录信息，登录用户已获得目录密钥时优先返回此用户的信封，
+// 否则返回分享链接的信封
+func (service *ShareEncryptionService) Get(c *gin.Context) serializer.Response {
+	share := c.MustGet("share").(*model.Share)
+	encrypted, err := shareEncryptedFolder(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotEncryptedFolder, "", err)
+	}
+
+	var envelope *model.FolderKeyEnvelope
+	if user, ok := c.Get("user"); ok && !user.(*model.User).IsAnonymous() {
+		if userEnvelope, err := model.GetFolderKeyEnvelope(encrypted.ID, user.(*model.User).ID, 0); err == nil {
+			envelope = &userEnvelope
+		}
+	}
+
+	if envelope == nil {
+		if shareEnvelope, err := model.GetFolderKeyEnvelope(encrypted.ID, 0, share.ID); err == nil {
+			envelope = &shareEnvelope
+		}
+	}
+
+	var names []model.EncryptedName
+	if !share.IsDir {
+		names, err = model.GetEncryptedNames(model.EncryptedNameFile, []uint{share.SourceID})
+	} else {
+		folder, res := service.sharedFolder(share)
+		if res != nil {
+			return *res
+		}
+		names, err = childEncryptedNames(folder)
+	}
+
+	if err != nil {
+		return serializer.DBErr("Failed to list encrypted names", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildEncryptedFolder(&encrypted, envelope, names)}
+}
+
+// sharedFolder 获取分享目录下需要列出加密名称的目录
+func (service *ShareEncryptionService) sharedFolder(share *model.Share) (*model.Folder, *serializer.Response) {
+	id := share.SourceID
+	if service.Folder != "" {
+		folderID, err := hashid.DecodeHashID(service.Folder, hashid.FolderID)
+		if err != nil {
+			res := serializer.Err(serializer.CodeNotFound, "", err)
+			return nil, &res
+		}
+
+		// 只允许获取分享目录及其子目录
+		ancestors, err := model.GetFolderAncestors(folderID)
+		if err != nil || !util.ContainsUint(ancestors, share.SourceID) {
+			res := serializer.Err(serializer.CodeParentNotExist, "", err)
+			return nil, &res
+		}
+		id = folderID
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{id}, share.UserID)
+	if err != nil || len(folders) == 0 {
+		res := serializer.Err(serializer.CodeParentNotExist, "", err)
+		return nil, &res
+	}
+
+	return &folders[0], nil
+}
+
+// userEncryptedFolder 获取用户目录及其所在的加密目录，非加密目录创建者时返回错误
+func userEncryptedFolder(hashID string, user *model.User) (*model.Folder, *model.EncryptedFolder, *serializer.Response) {
+	id, err := hashid.DecodeHashID(hashID, hashid.FolderID)
+	if err != nil {
+		res := serializer.Err(serializer.CodeNotFound, "", err)
+		return nil, nil, &res
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{id}, user.ID)
+	if err != nil || len(folders) == 0 {
+		res := serializer.Err(serializer.CodeParentNotExist, "", err)
+		return nil, nil, &res
+	}
+
+	encrypted, err := model.GetEncryptedFolderByFolderID(id)
+	if err != nil || encrypted.OwnerID != user.ID {
+		res := serializer.Err(serializer.CodeNotEncryptedFolder, "", err)
+		return nil, nil, &res
+	}
+
+	return &folders[0], &encrypted, nil
+}
+
+// shareEncryptedFolder 获取分享对象所在的加密目录
+func shareEncryptedFolder(share *model.Share) (model.EncryptedFolder, error) {
+	folderID := share.SourceID
+	if !share.IsDir {
+		file := share.SourceFile()
+		if file.ID == 0 {
+			return model.EncryptedFolder{}, serializer.NewError(serializer.CodeFileNotFound, "", nil)
+		}
+		folderID = file.FolderID
+	}
+
+	return model.GetEncryptedFolderByFolderID(folderID)
+}
+
+// childEncryptedNames 获取目录下直接子对象的加密名称
+func childEncryptedNames(folder *model.Folder) ([]model.EncryptedName, error) {
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := folder.GetChildFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	fileIDs := make([]uint, 0, len(files))
+	for _, file := range files {
+		fileIDs = append(fileIDs, file.ID)
+	}
+
+	folderIDs := make([]uint, 0, len(children))
+	for _, child := range children {
+		folderIDs = append(folderIDs, child.ID)
+	}
+
+	names, err := model.GetEncryptedNames(model.EncryptedNameFile, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	folderNames, err := model.GetEncryptedNames(model.EncryptedNameFolder, folderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(names, folderNames...), nil
+}
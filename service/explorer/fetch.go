@@ -0,0 +1,66 @@
+package explorer
+
+import (
+	"net/url"
+	"path"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ItemFetchService 从 URL 下载任务服务
+type ItemFetchService struct {
+	URL      string `json:"url" binding:"required,url"`
+	Dst      string `json:"dst" binding:"required,min=1,max=65535"`
+	Name     string `json:"name" binding:"max=255"`
+	Checksum string `json:"checksum"`
+}
+
+// CreateFetchTask 创建从 URL 下载任务
+func (service *ItemFetchService) CreateFetchTask(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 检查用户组权限
+	if !fs.User.Group.OptionsSerialized.URLFetch {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	if u, err := url.Parse(service.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return serializer.ParamErr("Only HTTP and HTTPS URLs are supported", err)
+	}
+
+	if service.Name != "" && !fs.ValidateLegalName(util.RequestContext(c), service.Name) {
+		return serializer.Err(serializer.CodeIllegalObjectName, "", nil)
+	}
+
+	if _, _, err := task.ParseChecksum(service.Checksum); err != nil {
+		return serializer.ParamErr(err.Error(), nil)
+	}
+
+	// 存放目录是否存在
+	if exist, _ := fs.IsPathExist(service.Dst); !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	// 创建任务
+	job, err := task.NewFetchTask(fs.User, task.FetchProps{
+		URL:      service.URL,
+		Dst:      path.Clean(service.Dst),
+		Name:     service.Name,
+		Checksum: service.Checksum,
+	})
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+	task.TaskPoll.Submit(job)
+
+	return serializer.Response{}
+}
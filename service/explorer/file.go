@@ -17,7 +17,10 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/cloudreve/Cloudreve/v3/pkg/wopi"
 	"github.com/gin-gonic/gin"
 )
@@ -57,7 +60,7 @@ func (service *SingleFileService) Create(c *gin.Context) serializer.Response {
 	defer fs.Recycle()
 
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 给文件系统分配钩子
@@ -89,7 +92,7 @@ func (service *SlaveListService) List(c *gin.Context) serializer.Response {
 	}
 	defer fs.Recycle()
 
-	objects, err := fs.Handler.List(context.Background(), service.Path, service.Recursive)
+	objects, err := fs.Handler.List(util.RequestContext(c), service.Path, service.Recursive)
 	if err != nil {
 		return serializer.Err(serializer.CodeIOFailed, "Cannot list files", err)
 	}
@@ -119,14 +122,16 @@ func (service *ArchiveService) DownloadArchived(ctx context.Context, c *gin.Cont
 		return serializer.Err(serializer.CodeNotFound, "Archive session not exist", nil)
 	}
 
-	// 开始打包
+	// 开始打包，压缩内容边读取边写入响应，禁止反向代理缓冲
 	c.Header("Content-Disposition", "attachment;")
 	c.Header("Content-Type", "application/zip")
+	c.Header("X-Accel-Buffering", "no")
 	itemService := archiveSession.(ItemIDService)
 	items := itemService.Raw()
 	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
 	err = fs.Compress(ctx, c.Writer, items.Dirs, items.Items, true)
 	if err != nil {
+		util.Log().Debug("Archive download %q aborted: %s", service.ID, err)
 		return serializer.Err(serializer.CodeNotSet, "Failed to compress file", err)
 	}
 
@@ -157,7 +162,8 @@ func (service *FileAnonymousGetService) Download(ctx context.Context, c *gin.Con
 	}
 
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, service.Name, fs.FileTarget[0].UpdatedAt, rs)
+	response.ServeContent(c.Writer, c.Request, service.Name, fs.FileTarget[0].UpdatedAt,
+		response.ETag(fs.FileTarget[0].UpdatedAt, fs.FileTarget[0].Size), rs)
 
 	return serializer.Response{
 		Code: 0,
@@ -192,15 +198,8 @@ func (service *FileAnonymousGetService) Source(ctx context.Context, c *gin.Conte
 	}
 }
 
-// CreateDocPreviewSession 创建DOC文件预览会话，返回预览地址
-func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gin.Context, editable bool) serializer.Response {
-	// 创建文件系统
-	fs, err := filesystem.NewFileSystemFromContext(c)
-	if err != nil {
-		return serializer.Err(serializer.CodePolicyNotAllowed, err.Error(), err)
-	}
-	defer fs.Recycle()
-
+// resetPreviewTarget 根据上下文中的分享对象重设预览目标，返回待预览的文件ID
+func resetPreviewTarget(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem) (uint, error) {
 	// 获取对象id
 	objectID, _ := c.Get("object_id")
 
@@ -216,15 +215,19 @@ func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gi
 		path := ctx.Value(fsctx.PathCtx).(string)
 		err := fs.ResetFileIfNotExist(ctx, path)
 		if err != nil {
-			return serializer.Err(serializer.CodeNotFound, err.Error(), err)
+			return 0, serializer.NewError(serializer.CodeNotFound, err.Error(), err)
 		}
 		objectID = uint(0)
 	}
 
-	// 获取文件临时下载地址
-	downloadURL, err := fs.GetDownloadURL(ctx, objectID.(uint), "doc_preview_timeout")
+	return objectID.(uint), nil
+}
+
+// previewSourceURL 获取供外部预览服务访问的文件临时下载地址
+func previewSourceURL(ctx context.Context, fs *filesystem.FileSystem, objectID uint) (string, error) {
+	downloadURL, err := fs.GetDownloadURL(ctx, objectID, "doc_preview_timeout")
 	if err != nil {
-		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		return "", err
 	}
 
 	// For newer version of Cloudreve - Local Policy
@@ -232,11 +235,46 @@ func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gi
 	if strings.HasPrefix(downloadURL, "/") {
 		downloadURI, err := url.Parse(downloadURL)
 		if err != nil {
-			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+			return "", err
 		}
 		downloadURL = model.GetSiteURL().ResolveReference(downloadURI).String()
 	}
 
+	return downloadURL, nil
+}
+
+// buildPreviewerURL 将文件下载地址填入外部预览服务地址模板
+func buildPreviewerURL(template, downloadURL, name string) string {
+	srcB64 := base64.StdEncoding.EncodeToString([]byte(downloadURL))
+	srcEncoded := url.QueryEscape(downloadURL)
+	srcB64Encoded := url.QueryEscape(srcB64)
+	return util.Replace(map[string]string{
+		"{$src}":    srcEncoded,
+		"{$srcB64}": srcB64Encoded,
+		"{$name}":   url.QueryEscape(name),
+	}, template)
+}
+
+// CreateDocPreviewSession 创建DOC文件预览会话，返回预览地址
+func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gin.Context, editable bool) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotAllowed, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	objectID, err := resetPreviewTarget(ctx, c, fs)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, err.Error(), err)
+	}
+
+	// 获取文件临时下载地址
+	downloadURL, err := previewSourceURL(ctx, fs, objectID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
 	var resp serializer.DocPreviewSession
 
 	// Use WOPI preview if available
@@ -266,14 +304,7 @@ func (service *FileIDService) CreateDocPreviewSession(ctx context.Context, c *gi
 	}
 
 	// 生成最终的预览器地址
-	srcB64 := base64.StdEncoding.EncodeToString([]byte(downloadURL))
-	srcEncoded := url.QueryEscape(downloadURL)
-	srcB64Encoded := url.QueryEscape(srcB64)
-	resp.URL = util.Replace(map[string]string{
-		"{$src}":    srcEncoded,
-		"{$srcB64}": srcB64Encoded,
-		"{$name}":   url.QueryEscape(fs.FileTarget[0].Name),
-	}, model.GetSettingByName("office_preview_service"))
+	resp.URL = buildPreviewerURL(model.GetSettingByName("office_preview_service"), downloadURL, fs.FileTarget[0].Name)
 
 	return serializer.Response{
 		Code: 0,
@@ -294,10 +325,12 @@ func (service *FileIDService) CreateDownloadSession(ctx context.Context, c *gin.
 	objectID, _ := c.Get("object_id")
 
 	// 获取下载地址
+	ctx = filesystem.WithDownloadOptions(ctx, c)
 	downloadURL, err := fs.GetDownloadURL(ctx, objectID.(uint), "download_timeout")
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
+	stats.Download(fs.FileTarget[0].UserID, fs.FileTarget[0].Size)
 
 	return serializer.Response{
 		Code: 0,
@@ -314,31 +347,59 @@ func (service *DownloadService) Download(ctx context.Context, c *gin.Context) se
 	}
 	defer fs.Recycle()
 
-	// 查找打包的临时文件
-	file, exist := cache.Get("download_" + service.ID)
+	// 查找下载会话
+	sessionRaw, exist := cache.Get("download_" + service.ID)
 	if !exist {
 		return serializer.Err(serializer.CodeNotFound, "Download session not exist", nil)
 	}
-	fs.FileTarget = []model.File{file.(model.File)}
+	session := sessionRaw.(serializer.DownloadSession)
+	if session.IP != "" && session.IP != c.ClientIP() {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Download link is bound to another client", nil)
+	}
+	fs.FileTarget = []model.File{session.File}
+
+	// HEAD 请求仅用于下载工具探测文件信息，不消耗一次性下载会话
+	if (session.OneTime || fs.User.Group.OptionsSerialized.OneTimeDownload) && c.Request.Method != http.MethodHead {
+		// 清理资源，删除临时文件
+		_ = cache.Deletes([]string{service.ID}, "download_")
+	}
 
-	// 开始处理下载
+	// 指定了图像处理参数时由服务端输出处理后的图像
 	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
-	rs, err := fs.GetDownloadContent(ctx, 0)
+	opts, err := imageProcessOptions(c)
+	if err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
+	if opts != nil {
+		return serveProcessedImage(ctx, c, fs, 0, opts, true)
+	}
+
+	// 不由服务端输出的存储策略重定向至存储端地址
+	if !fs.FileTarget[0].GetPolicy().IsRelayedDownload() {
+		redirectURL, err := fs.GetRedirectedDownloadURL(ctx, session.SpeedLimit)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+
+		c.Redirect(http.StatusFound, redirectURL)
+		return serializer.Response{}
+	}
+
+	// 开始处理下载，按创建会话的用户限速
+	rs, err := fs.GetContent(ctx, 0)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
+	rs = filesystem.WithDownloadSpeedLimit(rs, session.SpeedLimit)
 	defer rs.Close()
 
 	// 设置文件名
 	c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(fs.FileTarget[0].Name)+"\"")
 
-	if fs.User.Group.OptionsSerialized.OneTimeDownload {
-		// 清理资源，删除临时文件
-		_ = cache.Deletes([]string{service.ID}, "download_")
-	}
-
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt,
+		response.ETag(fs.FileTarget[0].UpdatedAt, fs.FileTarget[0].Size), rs)
 
 	return serializer.Response{
 		Code: 0,
@@ -375,6 +436,18 @@ func (service *FileIDService) PreviewContent(ctx context.Context, c *gin.Context
 		objectID = uint(0)
 	}
 
+	// 指定了图像处理参数时输出处理后的图像
+	if !isText {
+		opts, err := imageProcessOptions(c)
+		if err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+
+		if opts != nil {
+			return serveProcessedImage(ctx, c, fs, objectID.(uint), opts, false)
+		}
+	}
+
 	// 获取文件预览响应
 	resp, err := fs.Preview(ctx, objectID.(uint), isText)
 	if err != nil {
@@ -395,9 +468,11 @@ func (service *FileIDService) PreviewContent(ctx context.Context, c *gin.Context
 
 	if isText {
 		c.Header("Cache-Control", "no-cache")
+		c.Header("ETag", `"`+fs.FileTarget[0].Version()+`"`)
 	}
 
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, resp.Content)
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt,
+		response.ETag(fs.FileTarget[0].UpdatedAt, fs.FileTarget[0].Size), resp.Content)
 
 	return serializer.Response{
 		Code: 0,
@@ -407,7 +482,7 @@ func (service *FileIDService) PreviewContent(ctx context.Context, c *gin.Context
 // PutContent 更新文件内容
 func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) serializer.Response {
 	// 创建上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 取得文件大小
@@ -430,15 +505,43 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 		return serializer.Err(serializer.CodeCreateFSError, "", err)
 	}
 	uploadCtx := context.WithValue(ctx, fsctx.GinCtx, c)
+	fileData.File = filesystem.WithUploadSpeedLimit(fileData.File, fs.User.UploadSpeedLimit())
 
-	// 取得现有文件
+	// 锁定文件，避免并发的覆盖写入交错
 	fileID, _ := c.Get("object_id")
+	unlock, err := fs.LockObjects(ctx, nil, []uint{fileID.(uint)})
+	if err != nil {
+		return serializer.Err(serializer.CodeObjectLocked, err.Error(), err)
+	}
+	defer unlock()
+
+	// 取得现有文件
 	originFile, _ := model.GetFilesByIDs([]uint{fileID.(uint)}, fs.User.ID)
 	if len(originFile) == 0 {
 		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 	fileData.Name = originFile[0].Name
 
+	// 客户端读取后文件已被修改，将写入的内容另存为冲突副本
+	if expected := requestedVersion(c); expected != "" && expected != originFile[0].Version() {
+		copied, err := fs.SaveConflictCopy(uploadCtx, &originFile[0], &fileData)
+		if err != nil {
+			return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+		}
+
+		return serializer.Response{
+			Code: serializer.CodeVersionConflict,
+			Msg:  "File has been modified since it was read, your changes are saved as a conflict copy",
+			Data: serializer.ContentVersion{
+				Version: originFile[0].Version(),
+				ConflictCopy: &serializer.ConflictCopy{
+					ID:   hashid.HashID(copied.ID, hashid.FileID),
+					Name: copied.Name,
+				},
+			},
+		}
+	}
+
 	// 检查此文件是否有软链接
 	fileList, err := model.RemoveFilesWithSoftLinks([]model.File{originFile[0]})
 	if err == nil && len(fileList) == 0 {
@@ -460,6 +563,12 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 	fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
 	fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
 
+	// 保留历史版本时新内容写入新的源文件
+	if len(fileList) > 0 {
+		fs.KeepVersion(&originFile[0], &fileData)
+	}
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
+
 	// 执行上传
 	uploadCtx = context.WithValue(uploadCtx, fsctx.FileModelCtx, originFile[0])
 	err = fs.Upload(uploadCtx, &fileData)
@@ -467,9 +576,30 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
 	}
 
+	version := originFile[0].Version()
+	if updated, ok := fileData.Model.(*model.File); ok {
+		version = updated.Version()
+	}
+	c.Header("ETag", `"`+version+`"`)
+
 	return serializer.Response{
 		Code: 0,
+		Data: serializer.ContentVersion{Version: version},
+	}
+}
+
+// requestedVersion 客户端要求的文件内容版本，取自 If-Match 请求头或 version 参数
+func requestedVersion(c *gin.Context) string {
+	version := strings.TrimSpace(c.GetHeader("If-Match"))
+	if version == "" {
+		return c.Query("version")
+	}
+
+	if version == "*" {
+		return ""
 	}
+
+	return strings.Trim(strings.TrimPrefix(version, "W/"), `"`)
 }
 
 // Sources 批量获取对象的外链
@@ -532,3 +662,39 @@ func (s *ItemIDService) Sources(ctx context.Context, c *gin.Context) serializer.
 		Data: res,
 	}
 }
+
+// maxBatchThumbSize 单次批量获取缩略图状态的最大文件数
+const maxBatchThumbSize = 500
+
+// Thumbs 批量获取文件缩略图状态，尚未生成的缩略图将在后台生成，
+// 客户端可对状态为 pending 的文件稍后重新查询
+func (s *ItemIDService) Thumbs(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if len(s.Raw().Items) > maxBatchThumbSize {
+		return serializer.ParamErr(fmt.Sprintf("At most %d files are allowed", maxBatchThumbSize), nil)
+	}
+
+	states, err := fs.GetThumbStates(ctx, s.Raw().Items)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "Failed to get thumbnails", err)
+	}
+
+	res := make([]serializer.ThumbState, 0, len(states))
+	for _, state := range states {
+		res = append(res, serializer.ThumbState{
+			ID:     hashid.HashID(state.File.ID, hashid.FileID),
+			Status: state.Status,
+			URL:    state.URL,
+		})
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: res,
+	}
+}
@@ -0,0 +1,94 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/gin-gonic/gin"
+)
+
+// HLSContentService 获取视频 HLS 转码结果服务
+type HLSContentService struct {
+	Name string `uri:"name" binding:"required"`
+}
+
+// CreateHLSSession 获取视频的 HLS 播放地址，尚未转码时创建转码任务
+func CreateHLSSession(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	file, res := versionedFile(c, fs)
+	if file == nil {
+		return res
+	}
+
+	if !filesystem.CanTranscodeHLS(file) {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "HLS transcoding is not available for this file", nil)
+	}
+
+	status := file.HLSStatus()
+	switch status {
+	case model.HLSStatusReady:
+		return serializer.Response{Data: serializer.HLSSession{
+			Status: status,
+			URL: fmt.Sprintf("/api/v3/file/hls/%s/%s", hashid.HashID(file.ID, hashid.FileID),
+				model.HLSPlaylistName),
+		}}
+	case model.HLSStatusProcessing:
+		return serializer.Response{Data: serializer.HLSSession{Status: status}}
+	}
+
+	// 尚未转码或上次转码失败，创建转码任务
+	if err := filesystem.SetHLSStatus(file, model.HLSStatusProcessing); err != nil {
+		return serializer.DBErr("Failed to update file metadata", err)
+	}
+
+	job, err := task.NewHLSTask(fs.User, file.ID)
+	if err != nil {
+		_ = filesystem.SetHLSStatus(file, model.HLSStatusFailed)
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+	task.TaskPoll.Submit(job)
+
+	return serializer.Response{Data: serializer.HLSSession{Status: model.HLSStatusProcessing}}
+}
+
+// Serve 输出视频的 HLS 播放列表或分片
+func (service *HLSContentService) Serve(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	file, res := versionedFile(c, fs)
+	if file == nil {
+		return res
+	}
+
+	content, err := fs.GetHLSContent(ctx, file, service.Name)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, err.Error(), err)
+	}
+	defer content.Close()
+
+	if service.Name == model.HLSPlaylistName {
+		c.Header("Content-Type", "application/vnd.apple.mpegurl")
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Content-Type", "video/mp2t")
+		c.Header("Cache-Control", "private, max-age=86400")
+	}
+
+	http.ServeContent(c.Writer, c.Request, service.Name, file.UpdatedAt, content)
+	return serializer.Response{}
+}
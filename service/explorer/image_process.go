@@ -0,0 +1,48 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/gin-gonic/gin"
+)
+
+// imageProcessOptions 解析请求中的图像处理参数，未开启图像处理或未指定参数时返回 nil
+func imageProcessOptions(c *gin.Context) (*thumb.ProcessOptions, error) {
+	if !model.IsTrueVal(model.GetSettingByName("image_process_enabled")) {
+		return nil, nil
+	}
+
+	return thumb.ParseProcessOptions(c.Request.URL.Query(), model.GetIntSetting("image_process_max_size", 4096))
+}
+
+// serveProcessedImage 输出按参数处理后的图像，attachment 为真时以附件形式下载
+func serveProcessedImage(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem, id uint,
+	opts *thumb.ProcessOptions, attachment bool) serializer.Response {
+	processed, err := fs.GetProcessedImage(ctx, id, opts)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer processed.Close()
+
+	file := fs.FileTarget[0]
+	name := strings.TrimSuffix(file.Name, path.Ext(file.Name)) + "." + opts.OutputFormat(file.Name)
+	if attachment {
+		c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(name)+"\"")
+	}
+
+	c.Header("Content-Type", opts.ContentType(file.Name))
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", model.GetIntSetting("preview_timeout", 60)))
+	response.ServeContent(c.Writer, c.Request, name, file.UpdatedAt,
+		`"`+filesystem.ImageProcessCacheKey(&file, opts)+`"`, processed)
+
+	return serializer.Response{}
+}
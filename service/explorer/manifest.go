@@ -0,0 +1,55 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ManifestImportService 导入目录清单服务
+type ManifestImportService struct {
+	// Path 清单导入到的目录，不存在时自动创建
+	Path     string              `json:"path" binding:"required"`
+	Manifest filesystem.Manifest `json:"manifest"`
+}
+
+// ExportManifest 导出用户完整目录树的元数据清单
+func ExportManifest(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	manifest, err := fs.ExportManifest(ctx)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: manifest}
+}
+
+// Import 导入目录清单
+func (service *ManifestImportService) Import(ctx context.Context, c *gin.Context) serializer.Response {
+	limit := model.GetIntSetting("manifest_max_entries", 100000)
+	if total := len(service.Manifest.Folders) + len(service.Manifest.Files); limit > 0 && total > limit {
+		return serializer.ParamErr(fmt.Sprintf("Manifest contains too many entries (max %d)", limit), nil)
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	result, err := fs.ImportManifest(ctx, &service.Manifest, service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: result}
+}
@@ -0,0 +1,222 @@
+package explorer
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// MusicListService 按艺术家、专辑浏览音乐服务
+type MusicListService struct {
+	Artist string `form:"artist" binding:"max=255"`
+	Album  string `form:"album" binding:"max=255"`
+}
+
+// PlaylistService 创建、更新播放列表服务
+type PlaylistService struct {
+	Name  string   `json:"name" binding:"required,min=1,max=255"`
+	Files []string `json:"files" binding:"max=1000"`
+}
+
+// PlaylistIDService 播放列表ID服务
+type PlaylistIDService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// attachTrackFiles 为曲目填充对应的文件，文件不存在的曲目被移除
+func attachTrackFiles(tracks []model.MusicTrack, uid uint) ([]model.MusicTrack, error) {
+	if len(tracks) == 0 {
+		return tracks, nil
+	}
+
+	ids := make([]uint, 0, len(tracks))
+	for _, track := range tracks {
+		ids = append(ids, track.FileID)
+	}
+
+	files, err := model.GetFilesByIDs(ids, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	fileMap := make(map[uint]*model.File, len(files))
+	for i := range files {
+		fileMap[files[i].ID] = &files[i]
+	}
+
+	res := make([]model.MusicTrack, 0, len(tracks))
+	for _, track := range tracks {
+		if file, ok := fileMap[track.FileID]; ok {
+			track.File = file
+			res = append(res, track)
+		}
+	}
+
+	return res, nil
+}
+
+// Artists 列出艺术家
+func (service *MusicListService) Artists(c *gin.Context, user *model.User) serializer.Response {
+	artists, err := model.ListMusicArtists(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list artists", err)
+	}
+
+	return serializer.BuildMusicArtistList(artists)
+}
+
+// Albums 列出专辑，可按艺术家筛选
+func (service *MusicListService) Albums(c *gin.Context, user *model.User) serializer.Response {
+	albums, err := model.ListMusicAlbums(user.ID, service.Artist)
+	if err != nil {
+		return serializer.DBErr("Failed to list albums", err)
+	}
+
+	return serializer.BuildMusicAlbumList(albums)
+}
+
+// Tracks 列出曲目，可按艺术家和专辑筛选
+func (service *MusicListService) Tracks(c *gin.Context, user *model.User) serializer.Response {
+	tracks, err := model.ListMusicTracks(user.ID, service.Artist, service.Album)
+	if err != nil {
+		return serializer.DBErr("Failed to list tracks", err)
+	}
+
+	tracks, err = attachTrackFiles(tracks, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list files of tracks", err)
+	}
+
+	return serializer.BuildMusicTrackList(tracks)
+}
+
+// fileIDs 解析并校验播放列表中的文件，文件须属于当前用户
+func (service *PlaylistService) fileIDs(user *model.User) ([]uint, error) {
+	ids := make([]uint, 0, len(service.Files))
+	for _, hash := range service.Files {
+		id, err := hashid.DecodeHashID(hash, hashid.FileID)
+		if err != nil {
+			return nil, serializer.NewError(serializer.CodeFileNotFound, "", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) > 0 {
+		files, err := model.GetFilesByIDs(ids, user.ID)
+		if err != nil {
+			return nil, serializer.NewError(serializer.CodeDBError, "Failed to list files", err)
+		}
+
+		found := make(map[uint]bool, len(files))
+		for _, file := range files {
+			found[file.ID] = true
+		}
+
+		for _, id := range ids {
+			if !found[id] {
+				return nil, serializer.NewError(serializer.CodeFileNotFound, "", nil)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// Create 创建播放列表
+func (service *PlaylistService) Create(c *gin.Context, user *model.User) serializer.Response {
+	ids, err := service.fileIDs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	playlist := &model.Playlist{UserID: user.ID, Name: service.Name}
+	playlist.SetFileIDs(ids)
+	if err := playlist.Create(); err != nil {
+		return serializer.DBErr("Failed to create playlist", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildPlaylist(playlist, nil)}
+}
+
+// Update 更新播放列表的名称和曲目
+func (service *PlaylistService) Update(c *gin.Context, user *model.User, id uint) serializer.Response {
+	playlist, err := model.GetPlaylistByID(id, user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Playlist not found", err)
+	}
+
+	ids, err := service.fileIDs(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	playlist.Name = service.Name
+	playlist.SetFileIDs(ids)
+	if err := playlist.Update(); err != nil {
+		return serializer.DBErr("Failed to update playlist", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildPlaylist(playlist, nil)}
+}
+
+// ListPlaylists 列出用户的播放列表
+func ListPlaylists(c *gin.Context, user *model.User) serializer.Response {
+	playlists, err := model.ListPlaylists(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list playlists", err)
+	}
+
+	return serializer.BuildPlaylists(playlists)
+}
+
+// Get 按顺序列出播放列表中的曲目，已删除的文件被跳过
+func (service *PlaylistIDService) Get(c *gin.Context, user *model.User) serializer.Response {
+	playlist, err := model.GetPlaylistByID(service.ID, user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Playlist not found", err)
+	}
+
+	ids := playlist.FileIDs()
+	tracks := make([]model.MusicTrack, 0, len(ids))
+	if len(ids) > 0 {
+		known, err := model.GetMusicTracksByFileIDs(ids, user.ID)
+		if err != nil {
+			return serializer.DBErr("Failed to list tracks", err)
+		}
+
+		trackMap := make(map[uint]model.MusicTrack, len(known))
+		for _, track := range known {
+			trackMap[track.FileID] = track
+		}
+
+		// 没有标签信息的文件也可加入播放列表
+		for _, id := range ids {
+			track, ok := trackMap[id]
+			if !ok {
+				track = model.MusicTrack{FileID: id, UserID: user.ID}
+			}
+			tracks = append(tracks, track)
+		}
+
+		if tracks, err = attachTrackFiles(tracks, user.ID); err != nil {
+			return serializer.DBErr("Failed to list files of tracks", err)
+		}
+	}
+
+	return serializer.Response{Data: serializer.BuildPlaylist(playlist, tracks)}
+}
+
+// Delete 删除播放列表
+func (service *PlaylistIDService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.DeletePlaylist(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete playlist", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Playlist not found", nil)
+	}
+
+	return serializer.Response{}
+}
@@ -3,6 +3,7 @@ package explorer
 import (
 	"context"
 	"encoding/gob"
+	"errors"
 	"fmt"
 	"math"
 	"path"
@@ -14,10 +15,12 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mediameta"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 // ItemMoveService 处理多文件/目录移动
@@ -53,12 +56,21 @@ type ItemCompressService struct {
 	Src  ItemIDService `json:"src"`
 	Dst  string        `json:"dst" binding:"required,min=1,max=65535"`
 	Name string        `json:"name" binding:"required,min=1,max=255"`
+	// Window 可选的每日执行时间窗口，任务将等待至窗口开启后执行
+	Window *ScheduleWindow `json:"window,omitempty"`
+	Notify bool            `json:"notify"`
 }
 
-// ItemDecompressService 文件解压缩任务服务
+// ScheduleWindow 每日时间窗口，格式为 HH:MM
+type ScheduleWindow struct {
+	Start string `json:"start" binding:"required"`
+	End   string `json:"end" binding:"required"`
+}
+
+// ItemDecompressService 文件解压缩任务服务，未指定 Dst 时解压至压缩包所在目录
 type ItemDecompressService struct {
 	Src      string `json:"src"`
-	Dst      string `json:"dst" binding:"required,min=1,max=65535"`
+	Dst      string `json:"dst" binding:"max=65535"`
 	Encoding string `json:"encoding"`
 }
 
@@ -69,6 +81,13 @@ type ItemPropertyService struct {
 	IsFolder  bool   `form:"is_folder"`
 }
 
+// ItemStatService 批量获取对象状态服务，可按路径或 HashID 查询
+type ItemStatService struct {
+	Paths []string `json:"paths" binding:"max=500,dive,min=1,max=65535"`
+	Items []string `json:"items" binding:"max=500"`
+	Dirs  []string `json:"dirs" binding:"max=500"`
+}
+
 func init() {
 	gob.Register(ItemIDService{})
 }
@@ -113,6 +132,11 @@ func (service *ItemDecompressService) CreateDecompressTask(c *gin.Context) seria
 		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
 	}
 
+	// 解压至当前目录
+	if service.Dst == "" {
+		service.Dst = path.Dir(service.Src)
+	}
+
 	// 存放目录是否存在
 	if exist, _ := fs.IsPathExist(service.Dst); !exist {
 		return serializer.Err(serializer.CodeParentNotExist, "", nil)
@@ -175,6 +199,8 @@ func (service *ItemCompressService) CreateCompressTask(c *gin.Context) serialize
 		service.Name += ".zip"
 	}
 
+	service.Name = filesystem.NormalizeName(service.Name)
+
 	// 存放目录是否存在，是否重名
 	if exist, _ := fs.IsPathExist(service.Dst); !exist {
 		return serializer.Err(serializer.CodeParentNotExist, "", nil)
@@ -184,10 +210,10 @@ func (service *ItemCompressService) CreateCompressTask(c *gin.Context) serialize
 	}
 
 	// 检查文件名合法性
-	if !fs.ValidateLegalName(context.Background(), service.Name) {
+	if !fs.ValidateLegalName(util.RequestContext(c), service.Name) {
 		return serializer.Err(serializer.CodeIllegalObjectName, "", nil)
 	}
-	if !fs.ValidateExtension(context.Background(), service.Name) {
+	if !fs.ValidateExtension(util.RequestContext(c), service.Name) {
 		return serializer.Err(serializer.CodeFileTypeNotAllowed, "", nil)
 	}
 
@@ -222,13 +248,27 @@ func (service *ItemCompressService) CreateCompressTask(c *gin.Context) serialize
 		return serializer.Err(serializer.CodeInsufficientCapacity, "", err)
 	}
 
+	// 计算最早执行时间
+	props := task.CompressProps{
+		Dirs:   service.Src.Raw().Dirs,
+		Files:  service.Src.Raw().Items,
+		Dst:    path.Join(service.Dst, service.Name),
+		Notify: service.Notify,
+	}
+	if service.Window != nil {
+		start, err := task.NextWindowStart(time.Now(), service.Window.Start, service.Window.End)
+		if err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+		props.NotBefore = start.Unix()
+	}
+
 	// 创建任务
-	job, err := task.NewCompressTask(fs.User, path.Join(service.Dst, service.Name), service.Src.Raw().Dirs,
-		service.Src.Raw().Items)
+	job, err := task.NewScheduledCompressTask(fs.User, props)
 	if err != nil {
 		return serializer.Err(serializer.CodeCreateTaskError, "", err)
 	}
-	task.TaskPoll.Submit(job)
+	task.SubmitDeferred(task.TaskPoll, job)
 
 	return serializer.Response{}
 
@@ -280,11 +320,15 @@ func (service *ItemIDService) Delete(ctx context.Context, c *gin.Context) serial
 		unlink = service.UnlinkOnly
 	}
 
-	// 删除对象
+	// 删除对象，未要求强制删除时移入回收站
 	items := service.Raw()
-	err = fs.Delete(ctx, items.Dirs, items.Items, force, unlink)
+	if force || unlink {
+		err = fs.Delete(ctx, items.Dirs, items.Items, force, unlink)
+	} else {
+		err = fs.Trash(ctx, items.Dirs, items.Items)
+	}
 	if err != nil {
-		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		return batchErrorResponse(err)
 	}
 
 	return serializer.Response{
@@ -306,7 +350,7 @@ func (service *ItemMoveService) Move(ctx context.Context, c *gin.Context) serial
 	items := service.Src.Raw()
 	err = fs.Move(ctx, items.Dirs, items.Items, service.SrcDir, service.Dst)
 	if err != nil {
-		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		return batchErrorResponse(err)
 	}
 
 	return serializer.Response{
@@ -332,7 +376,7 @@ func (service *ItemMoveService) Copy(ctx context.Context, c *gin.Context) serial
 	// 复制对象
 	err = fs.Copy(ctx, service.Src.Raw().Dirs, service.Src.Raw().Items, service.SrcDir, service.Dst)
 	if err != nil {
-		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		return batchErrorResponse(err)
 	}
 
 	return serializer.Response{
@@ -390,6 +434,7 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		props.UpdatedAt = file[0].UpdatedAt
 		props.Policy = file[0].GetPolicy().Name
 		props.Size = file[0].Size
+		props.MediaMeta = mediameta.Decode(file[0].MetadataSerialized[model.MediaMetaMetadataKey])
 
 		// 查找父目录
 		if service.TraceRoot {
@@ -418,11 +463,14 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		props.CreatedAt = folder[0].CreatedAt
 		props.UpdatedAt = folder[0].UpdatedAt
 
+		props.Quota, _ = user.FolderQuota(folder[0].ID)
+
 		// 如果对象是目录, 先尝试返回缓存结果
 		if cacheRes, ok := cache.Get(fmt.Sprintf("folder_props_%d", res)); ok {
 			res := cacheRes.(serializer.ObjectProps)
 			res.CreatedAt = props.CreatedAt
 			res.UpdatedAt = props.UpdatedAt
+			res.Quota = props.Quota
 			return serializer.Response{Data: res}
 		}
 
@@ -458,6 +506,7 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		// 如果列取对象是目录，则缓存结果
 		cache.Set(fmt.Sprintf("folder_props_%d", res), props,
 			model.GetIntSetting("folder_props_timeout", 300))
+		filesystem.SetFolderSizeCache(res, props.Size)
 	}
 
 	return serializer.Response{
@@ -465,3 +514,118 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		Data: props,
 	}
 }
+
+// Stat 批量获取对象状态，结果按路径、文件、目录的顺序与查询一一对应
+func (service *ItemStatService) Stat(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	res := make([]serializer.ObjectStat, 0, len(service.Paths)+len(service.Items)+len(service.Dirs))
+	// 对象 ID 到结果下标的映射，用于回填分享状态
+	fileIndex := make(map[uint][]int)
+	folderIndex := make(map[uint][]int)
+	addFile := func(query string, file *model.File) {
+		fileIndex[file.ID] = append(fileIndex[file.ID], len(res))
+		res = append(res, serializer.BuildFileStat(query, file))
+	}
+	addFolder := func(query string, folder *model.Folder) {
+		folderIndex[folder.ID] = append(folderIndex[folder.ID], len(res))
+		res = append(res, serializer.BuildFolderStat(query, folder))
+	}
+
+	for _, p := range service.Paths {
+		if exist, file := fs.IsFileExist(p); exist {
+			addFile(p, file)
+		} else if exist, folder := fs.IsPathExist(p); exist {
+			addFolder(p, folder)
+		} else {
+			res = append(res, serializer.ObjectStat{Query: p})
+		}
+	}
+
+	fileIDs := make([]uint, len(service.Items))
+	for i, item := range service.Items {
+		fileIDs[i], _ = hashid.DecodeHashID(item, hashid.FileID)
+	}
+
+	var files []model.File
+	if len(fileIDs) > 0 {
+		if files, err = model.GetFilesByIDs(fileIDs, fs.User.ID); err != nil {
+			return serializer.DBErr("Failed to query file records", err)
+		}
+	}
+
+	filesMap := make(map[uint]*model.File, len(files))
+	for i := range files {
+		filesMap[files[i].ID] = &files[i]
+	}
+
+	for i, item := range service.Items {
+		if file, ok := filesMap[fileIDs[i]]; ok {
+			addFile(item, file)
+		} else {
+			res = append(res, serializer.ObjectStat{Query: item})
+		}
+	}
+
+	folderIDs := make([]uint, len(service.Dirs))
+	for i, dir := range service.Dirs {
+		folderIDs[i], _ = hashid.DecodeHashID(dir, hashid.FolderID)
+	}
+
+	var folders []model.Folder
+	if len(folderIDs) > 0 {
+		if folders, err = model.GetFoldersByIDs(folderIDs, fs.User.ID); err != nil {
+			return serializer.DBErr("Failed to query folder records", err)
+		}
+	}
+
+	foldersMap := make(map[uint]*model.Folder, len(folders))
+	for i := range folders {
+		foldersMap[folders[i].ID] = &folders[i]
+	}
+
+	for i, dir := range service.Dirs {
+		if folder, ok := foldersMap[folderIDs[i]]; ok {
+			addFolder(dir, folder)
+		} else {
+			res = append(res, serializer.ObjectStat{Query: dir})
+		}
+	}
+
+	// 回填分享状态
+	for isDir, index := range map[bool]map[uint][]int{false: fileIndex, true: folderIndex} {
+		if len(index) == 0 {
+			continue
+		}
+
+		shares, err := model.GetSharesBySourceIDs(fs.User.ID, lo.Keys(index), isDir)
+		if err != nil {
+			return serializer.DBErr("Failed to query share records", err)
+		}
+
+		for i := range shares {
+			for _, j := range index[shares[i].SourceID] {
+				res[j].AddShare(&shares[i])
+			}
+		}
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: res,
+	}
+}
+
+// batchErrorResponse 批量操作出错时返回的响应，包含逐项错误报告
+func batchErrorResponse(err error) serializer.Response {
+	var batchErr *filesystem.BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Response()
+	}
+
+	return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+}
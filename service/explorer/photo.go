@@ -0,0 +1,97 @@
+package explorer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// PhotoFilterService 照片筛选参数，时间为 Unix 时间戳（秒），经纬度限定矩形区域
+type PhotoFilterService struct {
+	Group  string   `form:"group" binding:"omitempty,eq=day|eq=month|eq=year"`
+	From   int64    `form:"from" binding:"min=0"`
+	To     int64    `form:"to" binding:"min=0"`
+	MinLat *float64 `form:"min_lat" binding:"omitempty,min=-90,max=90"`
+	MaxLat *float64 `form:"max_lat" binding:"omitempty,min=-90,max=90"`
+	MinLng *float64 `form:"min_lng" binding:"omitempty,min=-180,max=180"`
+	MaxLng *float64 `form:"max_lng" binding:"omitempty,min=-180,max=180"`
+}
+
+// PhotoTimelineService 照片时间线服务
+type PhotoTimelineService struct {
+	PhotoFilterService
+	Page     int `form:"page" binding:"required,min=1"`
+	PageSize int `form:"page_size" binding:"required,min=1,max=500"`
+}
+
+// filter 将请求参数转换为数据库筛选条件
+func (service *PhotoFilterService) filter() *model.PhotoFilter {
+	filter := &model.PhotoFilter{
+		MinLat: service.MinLat,
+		MaxLat: service.MaxLat,
+		MinLng: service.MinLng,
+		MaxLng: service.MaxLng,
+	}
+	if service.From > 0 {
+		from := time.Unix(service.From, 0)
+		filter.From = &from
+	}
+	if service.To > 0 {
+		to := time.Unix(service.To, 0)
+		filter.To = &to
+	}
+
+	return filter
+}
+
+// group 返回分组粒度，未指定时使用 fallback
+func (service *PhotoFilterService) group(fallback string) string {
+	if service.Group == "" {
+		return fallback
+	}
+
+	return service.Group
+}
+
+// Timeline 按拍摄时间倒序分页列出照片，并按时段分组
+func (service *PhotoTimelineService) Timeline(c *gin.Context, user *model.User) serializer.Response {
+	photos, total, err := model.ListPhotos(user.ID, service.filter(), service.Page, service.PageSize)
+	if err != nil {
+		return serializer.DBErr("Failed to list photos", err)
+	}
+
+	ids := make([]uint, 0, len(photos))
+	for _, photo := range photos {
+		ids = append(ids, photo.FileID)
+	}
+
+	if len(ids) > 0 {
+		files, err := model.GetFilesByIDs(ids, user.ID)
+		if err != nil {
+			return serializer.DBErr("Failed to list files of photos", err)
+		}
+
+		fileMap := make(map[uint]*model.File, len(files))
+		for i := range files {
+			fileMap[files[i].ID] = &files[i]
+		}
+
+		for i := range photos {
+			photos[i].File = fileMap[photos[i].FileID]
+		}
+	}
+
+	return serializer.BuildPhotoTimeline(photos, total, service.group(serializer.PhotoGroupDay))
+}
+
+// Albums 按时段统计照片数量，默认按月归档
+func (service *PhotoFilterService) Albums(c *gin.Context, user *model.User) serializer.Response {
+	times, err := model.ListPhotoTimes(user.ID, service.filter())
+	if err != nil {
+		return serializer.DBErr("Failed to list photos", err)
+	}
+
+	return serializer.BuildPhotoAlbums(times, service.group(serializer.PhotoGroupMonth))
+}
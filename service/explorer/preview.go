@@ -0,0 +1,58 @@
+package explorer
+
+import (
+	"context"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/preview"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// CreatePreviewSession 根据站点配置的预览处理器为文件匹配预览方式，
+// 需要外部转换服务的预览方式同时返回转换服务地址
+func (service *FileIDService) CreatePreviewSession(ctx context.Context, c *gin.Context) serializer.Response {
+	registry, err := preview.FromSetting()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Invalid preview handlers", err)
+	}
+
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotAllowed, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	objectID, err := resetPreviewTarget(ctx, c, fs)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, err.Error(), err)
+	}
+
+	file, err := fs.GetTargetFile(ctx, objectID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, err.Error(), err)
+	}
+
+	match, ok := registry.Match(file.Name, "")
+	if !ok {
+		return serializer.Err(serializer.CodeNoPreviewHandler, "No preview handler for this file", nil)
+	}
+
+	resp := serializer.PreviewSession{
+		Strategy: match.Strategy,
+		Language: match.Language,
+	}
+
+	// 外部转换服务需要可公开访问的文件地址
+	if match.URL != "" {
+		downloadURL, err := previewSourceURL(ctx, fs, objectID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+		}
+
+		resp.URL = buildPreviewerURL(match.URL, downloadURL, file.Name)
+	}
+
+	return serializer.Response{Data: resp}
+}
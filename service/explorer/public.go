@@ -0,0 +1,77 @@
+package explorer
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// PublicFolderService 公开目录游客访问服务
+type PublicFolderService struct {
+	Path string `uri:"path" binding:"required"`
+}
+
+// prepareFs 创建以公开目录为根目录的文件系统
+func (service *PublicFolderService) prepareFs(c *gin.Context) (*filesystem.FileSystem, error) {
+	public := c.MustGet("public_folder").(*model.PublicFolder)
+	fs, err := filesystem.NewFileSystem(public.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.Root = public.Folder
+	fs.Root.Name = "/"
+	service.Path = path.Clean("/" + service.Path)
+	return fs, nil
+}
+
+// List 列出公开目录下的内容
+func (service *PublicFolderService) List(c *gin.Context) serializer.Response {
+	fs, err := service.prepareFs(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	objects, err := fs.List(ctx, service.Path, nil)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: serializer.BuildObjectList(0, objects, nil),
+	}
+}
+
+// Download 获取公开目录下文件的下载地址
+func (service *PublicFolderService) Download(c *gin.Context) serializer.Response {
+	fs, err := service.prepareFs(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	ctx := util.RequestContext(c)
+	if err := fs.ResetFileIfNotExist(ctx, service.Path); err != nil {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	ctx = filesystem.WithDownloadOptions(ctx, c)
+	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	stats.Download(fs.FileTarget[0].UserID, fs.FileTarget[0].Size)
+
+	return serializer.Response{Data: downloadURL}
+}
@@ -2,15 +2,21 @@ package explorer
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/fulltext"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
+// contentSearchLimit 按内容搜索时最多返回的文件数
+const contentSearchLimit = 100
+
 // ItemSearchService 文件搜索服务
 type ItemSearchService struct {
 	Type     string `uri:"type" binding:"required"`
@@ -47,6 +53,8 @@ func (service *ItemSearchService) Search(c *gin.Context) serializer.Response {
 		return service.SearchKeywords(c, fs, "%.mp3", "%.flac", "%.ape", "%.wav", "%.acc", "%.ogg", "%.midi", "%.mid")
 	case "doc":
 		return service.SearchKeywords(c, fs, "%.txt", "%.md", "%.pdf", "%.doc", "%.docx", "%.ppt", "%.pptx", "%.xls", "%.xlsx", "%.pub")
+	case "content":
+		return service.SearchContent(c, fs)
 	case "tag":
 		if tid, err := hashid.DecodeHashID(service.Keywords, hashid.TagID); err == nil {
 			if tag, err := model.GetTagsByID(tid, fs.User.ID); err == nil {
@@ -69,7 +77,7 @@ func (service *ItemSearchService) Search(c *gin.Context) serializer.Response {
 // SearchKeywords 根据关键字搜索文件
 func (service *ItemSearchService) SearchKeywords(c *gin.Context, fs *filesystem.FileSystem, keywords ...interface{}) serializer.Response {
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 获取子项目
@@ -86,3 +94,31 @@ func (service *ItemSearchService) SearchKeywords(c *gin.Context, fs *filesystem.
 		},
 	}
 }
+
+// SearchContent 根据文件内容搜索文件
+func (service *ItemSearchService) SearchContent(c *gin.Context, fs *filesystem.FileSystem) serializer.Response {
+	// 上下文
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	ids, err := fulltext.Search(ctx, fs.User.ID, service.Keywords, contentSearchLimit)
+	if err != nil {
+		if errors.Is(err, fulltext.ErrNotEnabled) {
+			return serializer.Err(serializer.CodeFeatureNotEnabled, "Full-text search is not enabled", err)
+		}
+		return serializer.Err(serializer.CodeIOFailed, "Failed to search file contents", err)
+	}
+
+	objects, err := fs.SearchByIDs(ctx, ids)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Code: 0,
+		Data: map[string]interface{}{
+			"parent":  0,
+			"objects": objects,
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package explorer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// slugPattern 自定义标识允许的格式
+var slugPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// staticSiteMimeTypes 系统 MIME 数据库可能缺失或不一致的常见网页资源类型
+var staticSiteMimeTypes = map[string]string{
+	".html":        "text/html; charset=utf-8",
+	".htm":         "text/html; charset=utf-8",
+	".css":         "text/css; charset=utf-8",
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".xml":         "application/xml",
+	".txt":         "text/plain; charset=utf-8",
+	".svg":         "image/svg+xml",
+	".ico":         "image/x-icon",
+	".wasm":        "application/wasm",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+}
+
+// StaticSiteCreateService 将目录发布为静态网站服务
+type StaticSiteCreateService struct {
+	Folder string `json:"folder" binding:"required"`
+	Slug   string `json:"slug" binding:"required,max=64"`
+}
+
+// StaticSiteIDService 静态网站ID服务
+type StaticSiteIDService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// StaticSiteService 静态网站游客访问服务
+type StaticSiteService struct {
+	Path string `uri:"path"`
+}
+
+// Create 将用户的目录发布为静态网站
+func (service *StaticSiteCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.ShareEnabled {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	if !slugPattern.MatchString(service.Slug) {
+		return serializer.ParamErr("Slug can only contain letters, numbers, '-' and '_'", nil)
+	}
+
+	folderID, err := hashid.DecodeHashID(service.Folder, hashid.FolderID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{folderID}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	site := &model.StaticSite{
+		Slug:     service.Slug,
+		FolderID: folderID,
+		OwnerID:  user.ID,
+	}
+	if err := site.Create(); err != nil {
+		return serializer.DBErr("Failed to create static site, the slug or folder may already be in use", err)
+	}
+
+	return serializer.Response{Data: site.ID}
+}
+
+// ListStaticSites 列出用户发布的静态网站
+func ListStaticSites(c *gin.Context, user *model.User) serializer.Response {
+	sites, err := model.ListStaticSitesByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list static sites", err)
+	}
+
+	for i := range sites {
+		if folders, err := model.GetFoldersByIDs([]uint{sites[i].FolderID}, user.ID); err == nil && len(folders) > 0 {
+			sites[i].Folder = &folders[0]
+		}
+	}
+
+	return serializer.BuildStaticSiteList(sites)
+}
+
+// Delete 取消发布静态网站
+func (service *StaticSiteIDService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.DeleteStaticSite(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete static site", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Static site not found", nil)
+	}
+
+	return serializer.Response{}
+}
+
+// Serve 输出静态网站中的文件。路径指向目录时输出其中的 index.html，
+// 不存在的路径依次尝试追加 .html 扩展名和站点根目录的 404.html
+func (service *StaticSiteService) Serve(c *gin.Context) serializer.Response {
+	site := c.MustGet("static_site").(*model.StaticSite)
+	fs, err := filesystem.NewFileSystem(site.Owner)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fs.Root = site.Folder
+	fs.Root.Name = "/"
+
+	reqPath := path.Clean("/" + service.Path)
+	if exist, file := fs.IsFileExist(reqPath); exist {
+		return serveStaticSiteFile(c, fs, file, http.StatusOK)
+	}
+
+	if exist, _ := fs.IsPathExist(reqPath); exist {
+		// 目录须以 / 结尾，页面中的相对路径才能正确解析
+		if !strings.HasSuffix(service.Path, "/") {
+			target := *c.Request.URL
+			target.Path += "/"
+			c.Redirect(http.StatusMovedPermanently, target.String())
+			return serializer.Response{}
+		}
+
+		if exist, file := fs.IsFileExist(path.Join(reqPath, "index.html")); exist {
+			return serveStaticSiteFile(c, fs, file, http.StatusOK)
+		}
+	} else if exist, file := fs.IsFileExist(reqPath + ".html"); exist {
+		return serveStaticSiteFile(c, fs, file, http.StatusOK)
+	}
+
+	if exist, file := fs.IsFileExist("/404.html"); exist {
+		return serveStaticSiteFile(c, fs, file, http.StatusNotFound)
+	}
+
+	c.String(http.StatusNotFound, "Not found")
+	return serializer.Response{}
+}
+
+// serveStaticSiteFile 以正确的类型与缓存策略输出静态网站文件
+func serveStaticSiteFile(c *gin.Context, fs *filesystem.FileSystem, file *model.File, status int) serializer.Response {
+	fs.SetTargetFile(&[]model.File{*file})
+	ctx := context.WithValue(util.RequestContext(c), fsctx.GinCtx, c)
+	rs, err := fs.GetDownloadContent(ctx, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer rs.Close()
+
+	contentType := staticSiteMimeType(file.Name)
+	c.Header("Content-Type", contentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	// 站点与 Cloudreve 同源，以沙箱隔离为不透明来源，页面脚本无法读取用户的会话
+	c.Header("Content-Security-Policy", "sandbox allow-scripts allow-forms allow-popups allow-popups-to-escape-sandbox")
+
+	// 页面每次验证是否更新，其他资源按设定时长缓存
+	if strings.HasPrefix(contentType, "text/html") {
+		c.Header("Cache-Control", "public, max-age=0, must-revalidate")
+	} else {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", model.GetIntSetting("static_site_cache_max_age", 600)))
+	}
+
+	if status == http.StatusOK {
+		response.ServeContent(c.Writer, c.Request, file.Name, file.UpdatedAt, response.ETag(file.UpdatedAt, file.Size), rs)
+		return serializer.Response{}
+	}
+
+	c.Header("Content-Length", strconv.FormatUint(file.Size, 10))
+	c.Status(status)
+	if c.Request.Method != http.MethodHead {
+		io.Copy(c.Writer, rs)
+	}
+
+	return serializer.Response{}
+}
+
+// staticSiteMimeType 根据文件扩展名获取 MIME 类型
+func staticSiteMimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := staticSiteMimeTypes[ext]; ok {
+		return contentType
+	}
+
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+
+	return "application/octet-stream"
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"os"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -14,6 +15,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task/slavetask"
@@ -88,8 +90,14 @@ func (service *SlaveDownloadService) ServeFile(ctx context.Context, c *gin.Conte
 		c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(fs.FileTarget[0].Name)+"\"")
 	}
 
+	// 使用文件实际的修改时间，使 If-Range 及分段续传可以正确判断文件是否变更
+	modTime, etag := time.Now(), ""
+	if info, err := os.Stat(util.RelativePath(file.SourceName)); err == nil {
+		modTime, etag = info.ModTime(), response.ETag(info.ModTime(), uint64(info.Size()))
+	}
+
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, time.Now(), rs)
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, modTime, etag, rs)
 
 	return serializer.Response{}
 }
@@ -0,0 +1,138 @@
+package explorer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// refererHostPattern 来源白名单允许的域名格式，可使用 *. 前缀匹配子域名
+var refererHostPattern = regexp.MustCompile(`^(\*\.)?[a-zA-Z0-9-]+(\.[a-zA-Z0-9-]+)*$`)
+
+// SourceLinkService 外链设置服务
+type SourceLinkService struct {
+	// File 外链对应的文件，仅创建时使用
+	File string `json:"file"`
+	Slug string `json:"slug" binding:"max=64"`
+	// Expire 有效时长（秒），为 0 时永久有效
+	Expire   int      `json:"expire" binding:"min=0"`
+	Referers []string `json:"referers" binding:"max=32,dive,max=255"`
+}
+
+// apply 校验设置并写入外链
+func (service *SourceLinkService) apply(link *model.SourceLink) error {
+	link.Slug = nil
+	if service.Slug != "" {
+		if !slugPattern.MatchString(service.Slug) {
+			return serializer.NewError(serializer.CodeParamErr, "Slug can only contain letters, numbers, '-' and '_'", nil)
+		}
+
+		slug := service.Slug
+		link.Slug = &slug
+	}
+
+	link.Expires = nil
+	if service.Expire > 0 {
+		expires := time.Now().Add(time.Duration(service.Expire) * time.Second)
+		link.Expires = &expires
+	}
+
+	for _, host := range service.Referers {
+		if !refererHostPattern.MatchString(host) {
+			return serializer.NewError(serializer.CodeParamErr, "Invalid referer host: "+host, nil)
+		}
+	}
+
+	link.Referers = strings.Join(service.Referers, ",")
+	return nil
+}
+
+// getSourceLink 获取路径参数指定的外链，外链对应的文件须属于当前用户
+func getSourceLink(c *gin.Context, user *model.User) (*model.SourceLink, error) {
+	link, err := model.GetSourceLinkByID(c.MustGet("object_id"))
+	if err != nil || link.File.ID == 0 || link.File.UserID != user.ID {
+		return nil, serializer.NewError(serializer.CodeNotFound, "Source link not found", err)
+	}
+
+	return link, nil
+}
+
+// Create 为用户的文件创建外链
+func (service *SourceLinkService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.OptionsSerialized.RedirectedSource {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	fileID, err := hashid.DecodeHashID(service.File, hashid.FileID)
+	if err != nil {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	files, err := model.GetFilesByIDs([]uint{fileID}, user.ID)
+	if err != nil || len(files) == 0 {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	if !files[0].GetPolicy().IsOriginLinkEnable {
+		return serializer.Err(serializer.CodePolicyNotAllowed, "This policy is not enabled for getting source link", nil)
+	}
+
+	link := &model.SourceLink{FileID: files[0].ID, Name: files[0].Name}
+	if err := service.apply(link); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if err := link.Create(); err != nil {
+		return serializer.DBErr("Failed to create source link, the slug may already be in use", err)
+	}
+
+	link.File = files[0]
+	return serializer.Response{Data: serializer.BuildSourceLink(link)}
+}
+
+// ListSourceLinks 列出用户文件的外链及其访问统计
+func ListSourceLinks(c *gin.Context, user *model.User) serializer.Response {
+	links, err := model.ListSourceLinksByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list source links", err)
+	}
+
+	return serializer.BuildSourceLinkList(links)
+}
+
+// Update 更新外链的标识、有效期和来源白名单
+func (service *SourceLinkService) Update(c *gin.Context, user *model.User) serializer.Response {
+	link, err := getSourceLink(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if err := service.apply(link); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if err := link.Update(); err != nil {
+		return serializer.DBErr("Failed to update source link, the slug may already be in use", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildSourceLink(link)}
+}
+
+// DeleteSourceLink 撤销外链
+func DeleteSourceLink(c *gin.Context, user *model.User) serializer.Response {
+	link, err := getSourceLink(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if err := link.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete source link", err)
+	}
+
+	return serializer.Response{}
+}
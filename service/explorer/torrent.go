@@ -0,0 +1,99 @@
+package explorer
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/torrent"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ItemTorrentService 种子制作任务服务
+type ItemTorrentService struct {
+	Src         ItemIDService `json:"src"`
+	Dst         string        `json:"dst" binding:"required,min=1,max=65535"`
+	Name        string        `json:"name" binding:"max=255"`
+	Trackers    []string      `json:"trackers" binding:"max=50"`
+	PieceLength int64         `json:"piece_length"`
+	Seed        bool          `json:"seed"`
+}
+
+// CreateTorrentTask 创建种子制作任务
+func (service *ItemTorrentService) CreateTorrentTask(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 检查用户组权限
+	if !fs.User.Group.OptionsSerialized.TorrentCreate {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	src := service.Src.Raw()
+	if len(src.Dirs)+len(src.Items) == 0 {
+		return serializer.ParamErr("No file selected", nil)
+	}
+
+	// 选择多个对象时必须指定种子名称
+	if len(src.Dirs)+len(src.Items) > 1 && service.Name == "" {
+		return serializer.ParamErr("Torrent name is required", nil)
+	}
+	if service.Name != "" && !fs.ValidateLegalName(util.RequestContext(c), service.Name) {
+		return serializer.Err(serializer.CodeIllegalObjectName, "", nil)
+	}
+
+	if service.PieceLength != 0 {
+		if err := torrent.CheckPieceLength(service.PieceLength); err != nil {
+			return serializer.ParamErr(err.Error(), nil)
+		}
+	}
+
+	// 未指定 Tracker 时使用站点默认配置
+	trackers := service.Trackers
+	if len(trackers) == 0 {
+		trackers = strings.Split(model.GetSettingByName("torrent_trackers"), "\n")
+	}
+	validTrackers := make([]string, 0, len(trackers))
+	for _, tracker := range trackers {
+		tracker = strings.TrimSpace(tracker)
+		if tracker == "" {
+			continue
+		}
+
+		if u, err := url.Parse(tracker); err != nil || u.Scheme == "" || u.Host == "" {
+			return serializer.ParamErr("Invalid tracker "+tracker, err)
+		}
+		validTrackers = append(validTrackers, tracker)
+	}
+
+	// 存放目录是否存在
+	if exist, _ := fs.IsPathExist(service.Dst); !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	// 创建任务
+	job, err := task.NewTorrentTask(fs.User, task.TorrentProps{
+		Dirs:        src.Dirs,
+		Files:       src.Items,
+		Dst:         path.Clean(service.Dst),
+		Name:        service.Name,
+		Trackers:    validTrackers,
+		PieceLength: service.PieceLength,
+		Seed:        service.Seed,
+	})
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+	task.TaskPoll.Submit(job)
+
+	return serializer.Response{}
+}
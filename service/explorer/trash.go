@@ -0,0 +1,51 @@
+package explorer
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// TrashService 回收站对象服务
+type TrashService struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// ListTrash 列出用户回收站中的对象
+func ListTrash(c *gin.Context, user *model.User) serializer.Response {
+	items, err := model.GetTrashByUserID(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list trash", err)
+	}
+
+	return serializer.BuildTrashList(items, user.Group.OptionsSerialized.TrashRetention)
+}
+
+// Restore 将回收站中的对象恢复到原位置
+func (service *TrashService) Restore(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	id, err := hashid.DecodeHashID(service.ID, hashid.TrashID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Object not exist", err)
+	}
+
+	item, err := model.GetTrash(id, fs.User.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Object not exist", err)
+	}
+
+	if err := fs.RestoreTrash(ctx, item); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,297 @@
+package explorer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// TusOffsetContentType tus 协议上传数据请求的 Content-Type
+const TusOffsetContentType = "application/offset+octet-stream"
+
+// TusCreateService 创建 tus 可续传上传服务
+type TusCreateService struct {
+	Length   string `header:"Upload-Length" binding:"required"`
+	Metadata string `header:"Upload-Metadata"`
+}
+
+// TusUploadService tus 可续传上传服务
+type TusUploadService struct {
+	ID string `uri:"sessionId" binding:"required"`
+}
+
+// parseTusMetadata 解析 Upload-Metadata 请求头，值为 base64 编码
+func parseTusMetadata(raw string) (map[string]string, error) {
+	res := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metadata %q: %w", key, err)
+		}
+		res[key] = string(value)
+	}
+
+	return res, nil
+}
+
+// tusExpires 计算可续传上传的过期时间
+func tusExpires() time.Time {
+	return time.Now().Add(time.Duration(model.GetIntSetting("upload_session_timeout", 86400)) * time.Second)
+}
+
+// setTusExpires 设置 Upload-Expires 响应头
+func setTusExpires(c *gin.Context, expires time.Time) {
+	c.Header("Upload-Expires", expires.UTC().Format(http.TimeFormat))
+}
+
+// Create 创建可续传上传，文件名及目录由 Upload-Metadata 中的 filename 和 path 指定
+func (service *TusCreateService) Create(ctx context.Context, c *gin.Context) serializer.Response {
+	length, err := strconv.ParseUint(service.Length, 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid Upload-Length", err)
+	}
+
+	meta, err := parseTusMetadata(service.Metadata)
+	if err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+
+	if meta["filename"] == "" {
+		return serializer.ParamErr("Filename is required in Upload-Metadata", nil)
+	}
+
+	dir := meta["path"]
+	if dir == "" {
+		dir = "/"
+	}
+
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 仅数据经由本机中转的存储策略可使用可续传上传
+	if !fs.Policy.IsTransitUpload(length) {
+		return serializer.Err(serializer.CodePolicyNotAllowed, "Resumable upload is not supported by current storage policy", nil)
+	}
+
+	file := &fsctx.FileStream{
+		Size:        length,
+		Name:        meta["filename"],
+		VirtualPath: dir,
+		File:        ioutil.NopCloser(strings.NewReader("")),
+		MimeType:    meta["filetype"],
+	}
+	if lastModified, err := strconv.ParseInt(meta["last_modified"], 10, 64); err == nil && lastModified > 0 {
+		modified := time.UnixMilli(lastModified)
+		file.LastModified = &modified
+	}
+
+	credential, err := fs.CreateUploadSession(ctx, file)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	sessionRaw, ok := cache.Get(filesystem.UploadSessionCachePrefix + credential.SessionID)
+	if !ok {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", nil)
+	}
+
+	session, err := json.Marshal(sessionRaw.(serializer.UploadSession))
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to serialize upload session", err)
+	}
+
+	upload := &model.ResumableUpload{
+		SessionID: credential.SessionID,
+		UserID:    fs.User.ID,
+		Length:    length,
+		Session:   string(session),
+		ExpiresAt: tusExpires(),
+	}
+	if err := upload.Create(); err != nil {
+		return serializer.DBErr("Failed to create resumable upload", err)
+	}
+
+	c.Header("Location", path.Join(c.Request.URL.Path, credential.SessionID))
+	setTusExpires(c, upload.ExpiresAt)
+	return serializer.Response{}
+}
+
+// Head 查询可续传上传的进度
+func (service *TusUploadService) Head(ctx context.Context, c *gin.Context) serializer.Response {
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	upload, err := model.GetResumableUpload(service.ID, user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	c.Header("Upload-Offset", strconv.FormatUint(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatUint(upload.Length, 10))
+	c.Header("Cache-Control", "no-store")
+	setTusExpires(c, upload.ExpiresAt)
+	return serializer.Response{}
+}
+
+// Patch 从 Upload-Offset 处继续写入文件数据，单次写入的数据量不超过存储策略的分片大小
+func (service *TusUploadService) Patch(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	upload, err := model.GetResumableUpload(service.ID, fs.User.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	offset, err := strconv.ParseUint(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset != upload.Offset {
+		return serializer.Err(serializer.CodeInvalidChunkIndex,
+			fmt.Sprintf("Upload-Offset mismatch (expected: %d)", upload.Offset), err)
+	}
+
+	length, err := strconv.ParseUint(c.GetHeader("Content-Length"), 10, 64)
+	if err != nil {
+		return serializer.Err(serializer.CodeInvalidContentLength, "Invalid Content-Length", err)
+	}
+
+	var session serializer.UploadSession
+	if err := json.Unmarshal([]byte(upload.Session), &session); err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	// 查找上传会话创建的占位文件
+	file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	// 重设 fs 存储策略
+	fs.Policy = &session.Policy
+	if err := fs.DispatchHandler(); err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	// 本次接收的数据量不超过剩余大小及分片大小
+	size := upload.Length - offset
+	if length < size {
+		size = length
+	}
+	if chunkSize := session.Policy.OptionsSerialized.ChunkSize; chunkSize > 0 && chunkSize < size {
+		size = chunkSize
+	}
+
+	isLastChunk := offset+size == upload.Length
+	if size == 0 && !isLastChunk {
+		c.Header("Upload-Offset", strconv.FormatUint(offset, 10))
+		return serializer.Response{}
+	}
+
+	mode := fsctx.Append
+	if offset > 0 {
+		mode |= fsctx.Overwrite
+	}
+
+	fileData := fsctx.FileStream{
+		File:         filesystem.WithUploadSpeedLimit(ioutil.NopCloser(io.LimitReader(c.Request.Body, int64(size))), session.SpeedLimit),
+		Size:         size,
+		Name:         session.Name,
+		VirtualPath:  session.VirtualPath,
+		SavePath:     session.SavePath,
+		Mode:         mode,
+		AppendStart:  offset,
+		Model:        file,
+		LastModified: session.LastModified,
+	}
+
+	// 给文件系统分配钩子
+	fs.Use("AfterUploadCanceled", filesystem.HookTruncateFileTo(offset))
+	fs.Use("AfterValidateFailed", filesystem.HookTruncateFileTo(offset))
+	// 创建会话时已预留容量的无需再次校验
+	if file.ReservedSize() == 0 {
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+	}
+	fs.Use("AfterUpload", filesystem.HookChunkUploaded)
+	fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
+	if isLastChunk {
+		fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(""))
+		fs.Use("AfterUpload", filesystem.HookDeleteUploadSession(session.Key))
+		fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+		fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+		fs.Use("AfterUpload", filesystem.HookExtractExif)
+		fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+		fs.Use("AfterUpload", filesystem.HookDeduplicate)
+	}
+
+	// 执行上传
+	uploadCtx := context.WithValue(ctx, fsctx.GinCtx, c)
+	if err := fs.Upload(uploadCtx, &fileData); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	if isLastChunk {
+		err = model.DeleteResumableUpload(service.ID)
+	} else {
+		err = upload.UpdateOffset(offset+size, tusExpires())
+		setTusExpires(c, upload.ExpiresAt)
+	}
+	if err != nil {
+		return serializer.DBErr("Failed to update resumable upload", err)
+	}
+
+	c.Header("Upload-Offset", strconv.FormatUint(offset+size, 10))
+	return serializer.Response{}
+}
+
+// Delete 终止可续传上传，删除已上传的数据
+func (service *TusUploadService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if _, err := model.GetResumableUpload(service.ID, fs.User.ID); err != nil {
+		return serializer.Err(serializer.CodeUploadSessionExpired, "", err)
+	}
+
+	// 查找上传会话创建的占位文件，占位文件已不存在时仅删除记录
+	if file, err := model.GetFilesByUploadSession(service.ID, fs.User.ID); err == nil {
+		if err := fs.Delete(ctx, []uint{}, []uint{file.ID}, false, false); err != nil {
+			return serializer.Err(serializer.CodeInternalSetting, "Failed to delete upload session", err)
+		}
+	}
+
+	if err := model.DeleteResumableUpload(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete resumable upload", err)
+	}
+
+	return serializer.Response{}
+}
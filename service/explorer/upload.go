@@ -69,6 +69,59 @@ func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Co
 	}
 }
 
+// InstantUploadService 秒传服务
+type InstantUploadService struct {
+	Path         string `json:"path" binding:"required"`
+	Size         uint64 `json:"size" binding:"min=0"`
+	Name         string `json:"name" binding:"required"`
+	PolicyID     string `json:"policy_id" binding:"required"`
+	SHA256       string `json:"sha256" binding:"required,len=64,hexadecimal"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// Upload 使用已有的相同内容创建文件，未找到时客户端应改为正常上传
+func (service *InstantUploadService) Upload(ctx context.Context, c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 取得存储策略的ID
+	rawID, err := hashid.DecodeHashID(service.PolicyID, hashid.PolicyID)
+	if err != nil {
+		return serializer.Err(serializer.CodePolicyNotExist, "", err)
+	}
+
+	if fs.Policy.ID != rawID {
+		return serializer.Err(serializer.CodePolicyNotAllowed, "存储策略发生变化，请刷新文件列表并重新添加此任务", nil)
+	}
+
+	file := &fsctx.FileStream{
+		Size:        service.Size,
+		Name:        service.Name,
+		VirtualPath: service.Path,
+	}
+	if service.LastModified > 0 {
+		lastModified := time.UnixMilli(service.LastModified)
+		file.LastModified = &lastModified
+	}
+
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	newFile, err := fs.InstantUpload(ctx, file, strings.ToLower(service.SHA256))
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{
+		Data: hashid.HashID(newFile.ID, hashid.FileID),
+	}
+}
+
 // UploadService 本机及从机策略上传服务
 type UploadService struct {
 	ID    string `uri:"sessionId" binding:"required"`
@@ -177,7 +230,7 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 
 	fileData := fsctx.FileStream{
 		MimeType:     c.Request.Header.Get("Content-Type"),
-		File:         c.Request.Body,
+		File:         filesystem.WithUploadSpeedLimit(c.Request.Body, session.SpeedLimit),
 		Size:         fileSize,
 		Name:         session.Name,
 		VirtualPath:  session.VirtualPath,
@@ -193,12 +246,20 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 	fs.Use("AfterValidateFailed", filesystem.HookTruncateFileTo(fileData.AppendStart))
 
 	if file != nil {
-		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		// 创建会话时已预留容量的无需再次校验
+		if file.ReservedSize() == 0 {
+			fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		}
 		fs.Use("AfterUpload", filesystem.HookChunkUploaded)
 		fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
 		if isLastChunk {
 			fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(""))
 			fs.Use("AfterUpload", filesystem.HookDeleteUploadSession(session.Key))
+			fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+			fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+			fs.Use("AfterUpload", filesystem.HookExtractExif)
+			fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+			fs.Use("AfterUpload", filesystem.HookDeduplicate)
 		}
 	} else {
 		if isLastChunk {
@@ -0,0 +1,108 @@
+package explorer
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FileVersionService 文件历史版本服务
+type FileVersionService struct {
+	Version string `uri:"version" binding:"required"`
+}
+
+// ListFileVersions 列出文件的历史版本
+func ListFileVersions(c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	file, res := versionedFile(c, fs)
+	if file == nil {
+		return res
+	}
+
+	versions, err := model.GetFileVersions(file.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list file versions", err)
+	}
+
+	return serializer.BuildFileVersions(versions)
+}
+
+// Restore 将文件恢复为指定的历史版本
+func (service *FileVersionService) Restore(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	file, version, res := service.find(c, fs)
+	if version == nil {
+		return res
+	}
+
+	if err := fs.RestoreVersion(ctx, file, version); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 删除指定的历史版本
+func (service *FileVersionService) Delete(ctx context.Context, c *gin.Context) serializer.Response {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	_, version, res := service.find(c, fs)
+	if version == nil {
+		return res
+	}
+
+	if err := fs.DeleteVersions(ctx, []model.FileVersion{*version}); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// find 查找当前用户文件的指定历史版本
+func (service *FileVersionService) find(c *gin.Context, fs *filesystem.FileSystem) (*model.File, *model.FileVersion, serializer.Response) {
+	file, res := versionedFile(c, fs)
+	if file == nil {
+		return nil, nil, res
+	}
+
+	id, err := hashid.DecodeHashID(service.Version, hashid.FileVersionID)
+	if err != nil {
+		return nil, nil, serializer.Err(serializer.CodeNotFound, "Version not exist", err)
+	}
+
+	version, err := model.GetFileVersion(id, file.ID)
+	if err != nil {
+		return nil, nil, serializer.Err(serializer.CodeNotFound, "Version not exist", err)
+	}
+
+	return file, version, serializer.Response{}
+}
+
+// versionedFile 取得路由中指定的当前用户文件
+func versionedFile(c *gin.Context, fs *filesystem.FileSystem) (*model.File, serializer.Response) {
+	fileID, _ := c.Get("object_id")
+	files, _ := model.GetFilesByIDs([]uint{fileID.(uint)}, fs.User.ID)
+	if len(files) == 0 {
+		return nil, serializer.Err(serializer.CodeFileNotFound, "", nil)
+	}
+
+	return &files[0], serializer.Response{}
+}
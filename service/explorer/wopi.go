@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/middleware"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
@@ -28,6 +29,50 @@ func (service *WopiService) Rename(c *gin.Context) error {
 	return fs.Rename(c, []uint{}, []uint{c.MustGet("object_id").(uint)}, c.GetHeader(wopi.RenameRequestHeader))
 }
 
+// Lock handles lock related operations of given WOPI method. Current lock ID is
+// returned for GET_LOCK, and for other methods when lock mismatches.
+func (service *WopiService) Lock(c *gin.Context, method string) (string, error) {
+	fileID := c.MustGet("object_id").(uint)
+	lock := c.GetHeader(wopi.LockHeader)
+	switch method {
+	case wopi.MethodGetLock:
+		return wopi.GetLock(cache.Store, fileID), nil
+	case wopi.MethodLock:
+		// LOCK with X-WOPI-OldLock header is an UnlockAndRelock operation
+		if oldLock := c.GetHeader(wopi.OldLockHeader); oldLock != "" {
+			return wopi.UnlockAndRelock(cache.Store, fileID, oldLock, lock)
+		}
+		return wopi.Lock(cache.Store, fileID, lock)
+	case wopi.MethodRefreshLock:
+		return wopi.RefreshLock(cache.Store, fileID, lock)
+	case wopi.MethodUnlock:
+		return wopi.Unlock(cache.Store, fileID, lock)
+	}
+
+	return "", wopi.ErrActionNotSupported
+}
+
+// CheckLock validates if current request holds the lock of target file.
+func (service *WopiService) CheckLock(c *gin.Context) (string, error) {
+	return wopi.CheckLock(cache.Store, c.MustGet("object_id").(uint), c.GetHeader(wopi.LockHeader))
+}
+
+// CheckPutLock validates if current request is permitted to overwrite content
+// of target file. Non-empty files must be locked by current request.
+func (service *WopiService) CheckPutLock(c *gin.Context) (string, error) {
+	fileID := c.MustGet("object_id").(uint)
+	files, err := model.GetFilesByIDs([]uint{fileID}, 0)
+	if err != nil {
+		return "", err
+	}
+
+	if len(files) == 0 {
+		return "", errors.New("file not found")
+	}
+
+	return wopi.CheckPutLock(cache.Store, fileID, c.GetHeader(wopi.LockHeader), files[0].Size)
+}
+
 func (service *WopiService) GetFile(c *gin.Context) error {
 	fs, _, err := service.prepareFs(c)
 	if err != nil {
@@ -103,6 +148,8 @@ func (service *WopiService) FileInfo(c *gin.Context) (*serializer.WopiFileInfo,
 		info.SupportsRename = true
 		info.SupportsReviewing = true
 		info.SupportsUpdate = true
+		info.SupportsLocks = true
+		info.SupportsGetLock = true
 		info.UserFriendlyName = fs.User.Nick
 		info.UserId = hashid.HashID(fs.User.ID, hashid.UserID)
 		info.UserCanRename = true
@@ -0,0 +1,123 @@
+package notification
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notification"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// ListService 列出站内通知服务
+type ListService struct {
+	Page     int  `form:"page" binding:"required,min=1"`
+	PageSize int  `form:"page_size" binding:"required,min=1,max=100"`
+	Unread   bool `form:"unread"`
+}
+
+// ReadService 标记通知为已读服务
+type ReadService struct {
+	// IDs 为空时标记全部通知
+	IDs []uint `json:"ids"`
+}
+
+// Service 单条通知服务
+type Service struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: checkOrigin,
+}
+
+// List 列出用户的站内通知
+func (service *ListService) List(c *gin.Context, user *model.User) serializer.Response {
+	notifications, total, err := model.ListNotifications(user.ID, service.Unread, service.Page, service.PageSize)
+	if err != nil {
+		return serializer.DBErr("Failed to list notifications", err)
+	}
+
+	unread, err := model.CountUnreadNotifications(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to count unread notifications", err)
+	}
+
+	return serializer.BuildNotificationList(notifications, total, unread)
+}
+
+// Read 将通知标记为已读，并向用户的其他客户端推送最新的未读数
+func (service *ReadService) Read(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.MarkNotificationsRead(user.ID, service.IDs)
+	if err != nil {
+		return serializer.DBErr("Failed to update notifications", err)
+	}
+
+	if affected > 0 {
+		go notification.PublishUnread(user.ID)
+	}
+
+	return serializer.Response{Data: affected}
+}
+
+// Delete 删除通知
+func (service *Service) Delete(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.DeleteNotification(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete notification", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Notification not found", nil)
+	}
+
+	go notification.PublishUnread(user.ID)
+	return serializer.Response{}
+}
+
+// Stream 将连接升级为 WebSocket 并推送用户的站内通知、任务及离线下载状态，连接建立后首先推送未读通知数
+func Stream(c *gin.Context, user *model.User) serializer.Response {
+	unread, err := model.CountUnreadNotifications(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to count unread notifications", err)
+	}
+
+	// 升级失败时 Upgrader 已写入错误响应
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		util.Log().Debug("Failed to upgrade notification connection: %s", err)
+		return serializer.Response{}
+	}
+
+	if err := notification.Stream(conn, user.ID, notification.Event{Type: notification.EventUnread, Data: unread}); err != nil {
+		util.Log().Debug("Notification connection of user %d closed: %s", user.ID, err)
+	}
+
+	return serializer.Response{}
+}
+
+// checkOrigin 允许同源及跨域配置中允许的来源建立连接
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	allowed := conf.CORSConfig.AllowOrigins
+	return len(allowed) > 0 && allowed[0] != "UNSET" &&
+		(util.ContainsString(allowed, "*") || util.ContainsString(allowed, origin))
+}
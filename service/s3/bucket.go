@@ -0,0 +1,367 @@
+package s3
+
+import (
+	"encoding/base64"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	"github.com/gin-gonic/gin"
+)
+
+// maxListKeys 单次列出对象的最大数量
+const maxListKeys = 1000
+
+// BucketService 存储桶服务，存储桶对应根目录下的顶层目录
+type BucketService struct {
+	Bucket string `uri:"bucket"`
+}
+
+// ListObjectsService 列出对象服务，同时支持 ListObjects 与 ListObjectsV2
+type ListObjectsService struct {
+	Bucket            string `uri:"bucket"`
+	ListType          string `form:"list-type"`
+	Prefix            string `form:"prefix"`
+	Delimiter         string `form:"delimiter"`
+	Marker            string `form:"marker"`
+	ContinuationToken string `form:"continuation-token"`
+	StartAfter        string `form:"start-after"`
+	MaxKeys           string `form:"max-keys"`
+	EncodingType      string `form:"encoding-type"`
+}
+
+// ListBuckets 列出所有存储桶
+func ListBuckets(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	root := fs.Root
+	if root == nil {
+		if root, err = fs.User.Root(); err != nil {
+			return s3.Err(fsError(err))
+		}
+	}
+
+	folders, err := root.GetChildFolder()
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	res := &s3.ListAllMyBucketsResult{
+		Xmlns: s3.XMLNS,
+		Owner: s3.Owner{
+			ID:          hashid.HashID(fs.User.ID, hashid.UserID),
+			DisplayName: fs.User.Nick,
+		},
+		Buckets: make([]s3.Bucket, 0, len(folders)),
+	}
+	for _, folder := range folders {
+		res.Buckets = append(res.Buckets, s3.Bucket{
+			Name:         folder.Name,
+			CreationDate: s3.FormatTime(folder.CreatedAt),
+		})
+	}
+	sort.Slice(res.Buckets, func(i, j int) bool {
+		return res.Buckets[i].Name < res.Buckets[j].Name
+	})
+
+	return s3.OK(http.StatusOK, res)
+}
+
+// Create 创建存储桶
+func (service *BucketService) Create(c *gin.Context) s3.Response {
+	if !validBucket(service.Bucket) {
+		return s3.Err(s3.ErrInvalidBucketName)
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if exist, _ := fs.IsPathExist(path.Join("/", service.Bucket)); exist {
+		return s3.Err(s3.ErrBucketAlreadyOwnedByYou)
+	}
+
+	if _, err := fs.CreateDirectory(c, path.Join("/", service.Bucket)); err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	c.Header("Location", "/"+service.Bucket)
+	return s3.OK(http.StatusOK, nil)
+}
+
+// Head 检查存储桶是否存在
+func (service *BucketService) Head(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	return s3.OK(http.StatusOK, nil)
+}
+
+// Location 获取存储桶所在区域，始终为默认区域
+func (service *BucketService) Location(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	return s3.OK(http.StatusOK, &s3.LocationConstraint{Xmlns: s3.XMLNS})
+}
+
+// Delete 删除空存储桶
+func (service *BucketService) Delete(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	bucket, err := getBucket(fs, service.Bucket)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	folders, err := bucket.GetChildFolder()
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	files, err := bucket.GetChildFiles()
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	if len(folders) > 0 || len(files) > 0 {
+		return s3.Err(s3.ErrBucketNotEmpty)
+	}
+
+	if err := fs.Delete(c, []uint{bucket.ID}, []uint{}, false, false); err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	return s3.OK(http.StatusNoContent, nil)
+}
+
+// listEntry 列出的对象或公共前缀，file 为 nil 时为公共前缀
+type listEntry struct {
+	key  string
+	file *model.File
+}
+
+// List 列出存储桶中的对象
+func (service *ListObjectsService) List(c *gin.Context) s3.Response {
+	maxKeys := maxListKeys
+	if service.MaxKeys != "" {
+		var err error
+		if maxKeys, err = strconv.Atoi(service.MaxKeys); err != nil || maxKeys < 0 {
+			return s3.Err(s3.ErrInvalidArgument.WithMessage("Invalid max-keys."))
+		}
+		if maxKeys > maxListKeys {
+			maxKeys = maxListKeys
+		}
+	}
+
+	if service.EncodingType != "" && service.EncodingType != "url" {
+		return s3.Err(s3.ErrInvalidArgument.WithMessage("Invalid encoding-type."))
+	}
+
+	after := service.Marker
+	if service.ListType == "2" {
+		after = service.StartAfter
+		if service.ContinuationToken != "" {
+			token, err := base64.StdEncoding.DecodeString(service.ContinuationToken)
+			if err != nil {
+				return s3.Err(s3.ErrInvalidArgument.WithMessage("The continuation token provided is incorrect."))
+			}
+			after = string(token)
+		}
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	bucket, err := getBucket(fs, service.Bucket)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	entries, err := service.walk(fs, bucket)
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key > after })
+	entries = entries[start:]
+	truncated := len(entries) > maxKeys
+	if truncated {
+		entries = entries[:maxKeys]
+	}
+
+	encode := func(s string) string { return s }
+	if service.EncodingType == "url" {
+		encode = s3.EncodeKey
+	}
+
+	var (
+		contents = make([]s3.Object, 0, len(entries))
+		prefixes = make([]s3.CommonPrefix, 0)
+	)
+	for _, entry := range entries {
+		if entry.file == nil {
+			prefixes = append(prefixes, s3.CommonPrefix{Prefix: encode(entry.key)})
+			continue
+		}
+
+		contents = append(contents, s3.Object{
+			Key:          encode(entry.key),
+			LastModified: s3.FormatTime(entry.file.UpdatedAt),
+			ETag:         objectETag(entry.file),
+			Size:         entry.file.Size,
+			StorageClass: "STANDARD",
+		})
+	}
+
+	var last string
+	if truncated && len(entries) > 0 {
+		last = entries[len(entries)-1].key
+	}
+
+	if service.ListType == "2" {
+		res := &s3.ListBucketResultV2{
+			Xmlns:             s3.XMLNS,
+			Name:              service.Bucket,
+			Prefix:            encode(service.Prefix),
+			StartAfter:        encode(service.StartAfter),
+			ContinuationToken: service.ContinuationToken,
+			KeyCount:          len(entries),
+			MaxKeys:           maxKeys,
+			Delimiter:         encode(service.Delimiter),
+			IsTruncated:       truncated,
+			EncodingType:      service.EncodingType,
+			Contents:          contents,
+			CommonPrefixes:    prefixes,
+		}
+		if last != "" {
+			res.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		}
+		return s3.OK(http.StatusOK, res)
+	}
+
+	return s3.OK(http.StatusOK, &s3.ListBucketResult{
+		Xmlns:          s3.XMLNS,
+		Name:           service.Bucket,
+		Prefix:         encode(service.Prefix),
+		Marker:         encode(service.Marker),
+		NextMarker:     encode(last),
+		MaxKeys:        maxKeys,
+		Delimiter:      encode(service.Delimiter),
+		IsTruncated:    truncated,
+		EncodingType:   service.EncodingType,
+		Contents:       contents,
+		CommonPrefixes: prefixes,
+	})
+}
+
+// walk 按键排序返回前缀匹配的对象与公共前缀。分隔符为 "/" 时只需列出前缀所在的目录，
+// 否则逐层遍历前缀所在目录下的所有子目录
+func (service *ListObjectsService) walk(fs *filesystem.FileSystem, bucket *model.Folder) ([]listEntry, error) {
+	prefix, delimiter := service.Prefix, service.Delimiter
+	dir := prefix[:strings.LastIndex(prefix, "/")+1]
+
+	start := bucket
+	if dir != "" {
+		exist, folder := fs.IsPathExist(path.Join("/", service.Bucket, dir))
+		if !exist {
+			return nil, nil
+		}
+		start = folder
+	}
+
+	var (
+		entries  []listEntry
+		prefixes = make(map[string]bool)
+		queue    = []*model.Folder{start}
+		keys     = map[*model.Folder]string{start: dir}
+	)
+	for len(queue) > 0 {
+		folder := queue[0]
+		queue = queue[1:]
+		base := keys[folder]
+
+		files, err := folder.GetChildFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range files {
+			key := base + files[i].Name
+			if files[i].UploadSessionID != nil || !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			if delimiter != "" {
+				if idx := strings.Index(key[len(prefix):], delimiter); idx >= 0 {
+					prefixes[key[:len(prefix)+idx+len(delimiter)]] = true
+					continue
+				}
+			}
+
+			entries = append(entries, listEntry{key: key, file: &files[i]})
+		}
+
+		folders, err := folder.GetChildFolder()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range folders {
+			key := base + folders[i].Name + "/"
+			if delimiter == "/" {
+				if strings.HasPrefix(key, prefix) {
+					prefixes[key] = true
+				}
+				continue
+			}
+
+			if strings.HasPrefix(key, prefix) || strings.HasPrefix(prefix, key) {
+				keys[&folders[i]] = key
+				queue = append(queue, &folders[i])
+			}
+		}
+	}
+
+	for key := range prefixes {
+		entries = append(entries, listEntry{key: key})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	return entries, nil
+}
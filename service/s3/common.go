@@ -0,0 +1,98 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// newFileSystem 创建当前用户的文件系统，并按 WebDAV 账户设定重定根目录
+func newFileSystem(c *gin.Context) (*filesystem.FileSystem, error) {
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if webdavCtx, ok := c.Get("webdav"); ok {
+		// 根目录已不存在时拒绝访问，避免暴露用户的全部文件
+		if err := fs.UseRoot(webdavCtx.(*model.Webdav).Root); err != nil {
+			fs.Recycle()
+			return nil, s3.ErrAccessDenied
+		}
+	}
+
+	return fs, nil
+}
+
+// validBucket 存储桶名称是否可映射为顶层目录
+func validBucket(bucket string) bool {
+	return bucket != "" && bucket != "." && bucket != ".." && !strings.Contains(bucket, "/")
+}
+
+// objectPath 返回对象在文件系统中的路径，对象键无法映射为路径时返回错误
+func objectPath(bucket, key string) (string, error) {
+	if !validBucket(bucket) {
+		return "", s3.ErrInvalidBucketName
+	}
+
+	trimmed := strings.TrimSuffix(key, "/")
+	if trimmed == "" || path.Clean("/"+trimmed) != "/"+trimmed {
+		return "", s3.ErrInvalidArgument.WithMessage("Object key cannot be mapped to a file path.")
+	}
+
+	return path.Join("/", bucket, trimmed), nil
+}
+
+// getBucket 获取存储桶对应的顶层目录
+func getBucket(fs *filesystem.FileSystem, bucket string) (*model.Folder, error) {
+	if !validBucket(bucket) {
+		return nil, s3.ErrInvalidBucketName
+	}
+
+	exist, folder := fs.IsPathExist(path.Join("/", bucket))
+	if !exist {
+		return nil, s3.ErrNoSuchBucket
+	}
+
+	return folder, nil
+}
+
+// objectETag 返回已存储文件的 ETag，随文件内容版本变化
+func objectETag(file *model.File) string {
+	return fmt.Sprintf(`"%x-%s"`, file.ID, file.Version())
+}
+
+// fsError 将文件系统错误转换为 S3 错误
+func fsError(err error) error {
+	var apiErr *s3.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var appErr serializer.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.Code {
+		case serializer.CodeFileTooLarge:
+			return s3.ErrEntityTooLarge
+		case serializer.CodeInsufficientCapacity, serializer.CodeFolderQuotaExceeded:
+			return s3.ErrQuotaExceeded
+		case serializer.CodeFileTypeNotAllowed, serializer.CodeRetentionLocked, serializer.CodeRootProtected:
+			return s3.ErrAccessDenied.WithMessage(appErr.Msg)
+		case serializer.CodeIllegalObjectName, serializer.CodeObjectExist:
+			return s3.ErrInvalidArgument.WithMessage(appErr.Msg)
+		case serializer.CodeParentNotExist:
+			return s3.ErrNoSuchKey
+		case serializer.CodeObjectLocked:
+			return s3.ErrOperationAborted.WithMessage(appErr.Msg)
+		}
+	}
+
+	return s3.ErrInternalError.WithMessage(err.Error())
+}
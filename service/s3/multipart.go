@@ -0,0 +1,187 @@
+package s3
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// MultipartRoot 返回分片上传暂存目录
+func MultipartRoot() string {
+	return filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "s3")
+}
+
+// MultipartService 分片上传服务，分片暂存于服务端临时目录，合并时作为一个文件上传
+type MultipartService struct {
+	ObjectService
+	UploadID   string `form:"uploadId"`
+	PartNumber string `form:"partNumber"`
+}
+
+// Create 创建分片上传
+func (service *MultipartService) Create(c *gin.Context) s3.Response {
+	if _, err := service.prepare(c); err != nil {
+		return s3.Err(err)
+	}
+
+	session := &s3.Multipart{
+		UserID:      c.MustGet("user").(*model.User).ID,
+		Bucket:      service.Bucket,
+		Key:         service.key(),
+		ContentType: c.GetHeader("Content-Type"),
+	}
+	if err := s3.CreateMultipart(MultipartRoot(), session); err != nil {
+		return s3.Err(err)
+	}
+
+	return s3.OK(http.StatusOK, &s3.InitiateMultipartUploadResult{
+		Xmlns:    s3.XMLNS,
+		Bucket:   service.Bucket,
+		Key:      service.key(),
+		UploadID: session.ID,
+	})
+}
+
+// UploadPart 上传分片
+func (service *MultipartService) UploadPart(c *gin.Context) s3.Response {
+	number, err := strconv.Atoi(service.PartNumber)
+	if err != nil {
+		return s3.Err(s3.ErrInvalidArgument.WithMessage("Part number must be an integer between 1 and 10000."))
+	}
+
+	size := s3.DecodedLength(c.Request)
+	if size < 0 {
+		return s3.Err(s3.ErrMissingContentLength)
+	}
+
+	session, err := service.get(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	// 已暂存的分片同样计入用户容量
+	uploaded, err := session.Size()
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	user := c.MustGet("user").(*model.User)
+	if uploaded+uint64(size) > user.GetRemainingCapacity() {
+		return s3.Err(s3.ErrQuotaExceeded)
+	}
+
+	etag, err := session.WritePart(number, c.Request.Body)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	c.Header("ETag", etag)
+	return s3.OK(http.StatusOK, nil)
+}
+
+// Complete 合并分片并保存为对象
+func (service *MultipartService) Complete(c *gin.Context) s3.Response {
+	var req s3.CompleteMultipartUpload
+	if err := xml.NewDecoder(io.LimitReader(c.Request.Body, maxRequestXML)).Decode(&req); err != nil {
+		return s3.Err(s3.ErrMalformedXML)
+	}
+
+	session, err := service.get(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	content, size, etag, err := session.Open(req.Parts)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer content.Close()
+
+	fullPath, err := service.prepare(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := upload(c, fs, fullPath, content, size, session.ContentType); err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	content.Close()
+	if err := session.Remove(); err != nil {
+		util.Log().Warning("Failed to remove S3 multipart upload %q: %s", session.ID, err)
+	}
+
+	return s3.OK(http.StatusOK, &s3.CompleteMultipartUploadResult{
+		Xmlns:    s3.XMLNS,
+		Location: "/" + service.Bucket + "/" + service.key(),
+		Bucket:   service.Bucket,
+		Key:      service.key(),
+		ETag:     etag,
+	})
+}
+
+// Abort 取消分片上传并删除已暂存的分片
+func (service *MultipartService) Abort(c *gin.Context) s3.Response {
+	session, err := service.get(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	if err := session.Remove(); err != nil {
+		return s3.Err(err)
+	}
+
+	return s3.OK(http.StatusNoContent, nil)
+}
+
+// prepare 检查对象键及所在存储桶，返回对象在文件系统中的路径
+func (service *MultipartService) prepare(c *gin.Context) (string, error) {
+	if strings.HasSuffix(service.key(), "/") {
+		return "", s3.ErrInvalidArgument.WithMessage("Objects ending with '/' cannot be uploaded in parts.")
+	}
+
+	fullPath, err := objectPath(service.Bucket, service.key())
+	if err != nil {
+		return "", err
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return "", err
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return "", err
+	}
+
+	return fullPath, nil
+}
+
+// get 获取属于当前用户与对象的分片上传
+func (service *MultipartService) get(c *gin.Context) (*s3.Multipart, error) {
+	session, err := s3.GetMultipart(MultipartRoot(), service.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserID != c.MustGet("user").(*model.User).ID || session.Bucket != service.Bucket || session.Key != service.key() {
+		return nil, s3.ErrNoSuchUpload
+	}
+
+	return session, nil
+}
@@ -0,0 +1,417 @@
+package s3
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxDeleteObjects 单次批量删除的最大对象数
+	maxDeleteObjects = 1000
+	// maxRequestXML 请求正文中 XML 的最大长度
+	maxRequestXML = 2 << 20
+)
+
+// emptyETag 空对象的 ETag
+var emptyETag = `"` + hex.EncodeToString(md5.New().Sum(nil)) + `"`
+
+// ObjectService 对象服务，对象键以 "/" 结尾时对应目录
+type ObjectService struct {
+	Bucket string `uri:"bucket"`
+	Key    string `uri:"key"`
+}
+
+// key 返回去除路由前导 "/" 的对象键
+func (service *ObjectService) key() string {
+	return strings.TrimPrefix(service.Key, "/")
+}
+
+// Head 获取对象元数据
+func (service *ObjectService) Head(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	file, err := service.find(fs)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	if file == nil {
+		c.Header("Content-Length", "0")
+		c.Header("ETag", emptyETag)
+		return s3.OK(http.StatusOK, nil)
+	}
+
+	contentType := mime.TypeByExtension(path.Ext(file.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Length", strconv.FormatUint(file.Size, 10))
+	c.Header("Content-Type", contentType)
+	c.Header("ETag", objectETag(file))
+	c.Header("Last-Modified", file.UpdatedAt.UTC().Format(http.TimeFormat))
+	return s3.OK(http.StatusOK, nil)
+}
+
+// Get 获取对象内容，内容始终经由服务端中转
+func (service *ObjectService) Get(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	file, err := service.find(fs)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	if file == nil {
+		c.Header("ETag", emptyETag)
+		return s3.OK(http.StatusOK, nil)
+	}
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(c, 0)
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+	defer rs.Close()
+
+	c.Header("ETag", objectETag(file))
+	http.ServeContent(c.Writer, c.Request, file.Name, file.UpdatedAt, rs)
+	return s3.Response{}
+}
+
+// find 查找对象对应的文件，对象为已存在的目录时返回 nil
+func (service *ObjectService) find(fs *filesystem.FileSystem) (*model.File, error) {
+	fullPath, err := objectPath(service.Bucket, service.key())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(service.key(), "/") {
+		if exist, _ := fs.IsPathExist(fullPath); exist {
+			return nil, nil
+		}
+		return nil, s3.ErrNoSuchKey
+	}
+
+	exist, file := fs.IsFileExist(fullPath)
+	if !exist || file.UploadSessionID != nil {
+		return nil, s3.ErrNoSuchKey
+	}
+
+	return file, nil
+}
+
+// Put 上传对象，请求带有 x-amz-copy-source 时复制已有对象
+func (service *ObjectService) Put(c *gin.Context) s3.Response {
+	if source := c.GetHeader("X-Amz-Copy-Source"); source != "" {
+		return service.copy(c, source)
+	}
+
+	fullPath, err := objectPath(service.Bucket, service.key())
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	size := s3.DecodedLength(c.Request)
+	if size < 0 {
+		return s3.Err(s3.ErrMissingContentLength)
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	// 以 "/" 结尾的空对象视为创建目录
+	if strings.HasSuffix(service.key(), "/") {
+		if size > 0 {
+			return s3.Err(s3.ErrInvalidArgument.WithMessage("Objects ending with '/' must be empty."))
+		}
+
+		if _, err := fs.CreateDirectory(c, fullPath); err != nil {
+			return s3.Err(fsError(err))
+		}
+
+		c.Header("ETag", emptyETag)
+		return s3.OK(http.StatusOK, nil)
+	}
+
+	hash := md5.New()
+	if _, err := upload(c, fs, fullPath, io.TeeReader(c.Request.Body, hash), uint64(size), c.GetHeader("Content-Type")); err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	c.Header("ETag", `"`+hex.EncodeToString(hash.Sum(nil))+`"`)
+	return s3.OK(http.StatusOK, nil)
+}
+
+// copy 将 source 指定的对象复制为当前对象
+func (service *ObjectService) copy(c *gin.Context, source string) s3.Response {
+	source, _, _ = strings.Cut(source, "?")
+	source, err := url.PathUnescape(source)
+	if err != nil {
+		return s3.Err(s3.ErrInvalidArgument.WithMessage("Invalid copy source."))
+	}
+
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	src := &ObjectService{Bucket: srcBucket, Key: srcKey}
+	if strings.HasSuffix(srcKey, "/") {
+		return s3.Err(s3.ErrInvalidRequest.WithMessage("Copying directories is not supported."))
+	}
+
+	srcFs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer srcFs.Recycle()
+
+	file, err := src.find(srcFs)
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	// 复制到自身时不做任何变更，常用于修改元数据
+	if src.Bucket == service.Bucket && src.key() == service.key() {
+		return s3.OK(http.StatusOK, &s3.CopyObjectResult{
+			Xmlns:        s3.XMLNS,
+			LastModified: s3.FormatTime(file.UpdatedAt),
+			ETag:         objectETag(file),
+		})
+	}
+
+	fullPath, err := objectPath(service.Bucket, service.key())
+	if err != nil {
+		return s3.Err(err)
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	srcFs.SetTargetFile(&[]model.File{*file})
+	rs, err := srcFs.GetDownloadContent(c, 0)
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+	defer rs.Close()
+
+	hash := md5.New()
+	copied, err := upload(c, fs, fullPath, io.TeeReader(rs, hash), file.Size, "")
+	if err != nil {
+		return s3.Err(fsError(err))
+	}
+
+	return s3.OK(http.StatusOK, &s3.CopyObjectResult{
+		Xmlns:        s3.XMLNS,
+		LastModified: s3.FormatTime(copied.UpdatedAt),
+		ETag:         `"` + hex.EncodeToString(hash.Sum(nil)) + `"`,
+	})
+}
+
+// Delete 删除对象，对象不存在时同样视为成功
+func (service *ObjectService) Delete(c *gin.Context) s3.Response {
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	if err := deleteObject(c, fs, service.Bucket, service.key()); err != nil {
+		return s3.Err(err)
+	}
+
+	return s3.OK(http.StatusNoContent, nil)
+}
+
+// DeleteObjects 批量删除存储桶中的对象
+func (service *BucketService) DeleteObjects(c *gin.Context) s3.Response {
+	var req s3.Delete
+	if err := xml.NewDecoder(io.LimitReader(c.Request.Body, maxRequestXML)).Decode(&req); err != nil {
+		return s3.Err(s3.ErrMalformedXML)
+	}
+
+	if len(req.Objects) == 0 || len(req.Objects) > maxDeleteObjects {
+		return s3.Err(s3.ErrMalformedXML)
+	}
+
+	fs, err := newFileSystem(c)
+	if err != nil {
+		return s3.Err(err)
+	}
+	defer fs.Recycle()
+
+	if _, err := getBucket(fs, service.Bucket); err != nil {
+		return s3.Err(err)
+	}
+
+	res := &s3.DeleteResult{Xmlns: s3.XMLNS}
+	for _, object := range req.Objects {
+		fs.CleanTargets()
+		if err := deleteObject(c, fs, service.Bucket, object.Key); err != nil {
+			apiErr := s3.Err(err).Body.(*s3.Error)
+			res.Errors = append(res.Errors, s3.DeleteError{Key: object.Key, Code: apiErr.Code, Message: apiErr.Message})
+			continue
+		}
+
+		if !req.Quiet {
+			res.Deleted = append(res.Deleted, object)
+		}
+	}
+
+	return s3.OK(http.StatusOK, res)
+}
+
+// deleteObject 删除对象，对象键以 "/" 结尾时仅删除空目录
+func deleteObject(ctx context.Context, fs *filesystem.FileSystem, bucket, key string) error {
+	fullPath, err := objectPath(bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if strings.HasSuffix(key, "/") {
+		exist, folder := fs.IsPathExist(fullPath)
+		if !exist {
+			return nil
+		}
+
+		folders, err := folder.GetChildFolder()
+		if err != nil {
+			return fsError(err)
+		}
+
+		files, err := folder.GetChildFiles()
+		if err != nil {
+			return fsError(err)
+		}
+
+		if len(folders) > 0 || len(files) > 0 {
+			return nil
+		}
+
+		if err := fs.Delete(ctx, []uint{folder.ID}, []uint{}, false, false); err != nil {
+			return fsError(err)
+		}
+		return nil
+	}
+
+	exist, file := fs.IsFileExist(fullPath)
+	if !exist {
+		return nil
+	}
+
+	if err := fs.Trash(ctx, []uint{}, []uint{file.ID}); err != nil {
+		return fsError(err)
+	}
+
+	return nil
+}
+
+// upload 将 body 写入 fullPath，已存在的文件将被覆盖，所需的父目录会被自动创建
+func upload(c *gin.Context, fs *filesystem.FileSystem, fullPath string, body io.Reader, size uint64, mimeType string) (*model.File, error) {
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, c.Request.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	fileData := fsctx.FileStream{
+		MimeType:    mimeType,
+		File:        filesystem.WithUploadSpeedLimit(ioutil.NopCloser(body), fs.User.UploadSpeedLimit()),
+		Size:        size,
+		Name:        path.Base(fullPath),
+		VirtualPath: path.Dir(fullPath),
+	}
+
+	// 判断文件是否已存在
+	exist, originFile := fs.IsFileExist(fullPath)
+	if exist {
+		// 检查此文件是否有软链接
+		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
+		if err == nil && len(fileList) == 0 {
+			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
+			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
+			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
+			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
+		}
+
+		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
+		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
+		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
+		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
+		// 保留历史版本时新内容写入新的源文件
+		if len(fileList) > 0 {
+			fs.KeepVersion(originFile, &fileData)
+		}
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
+		fileData.Mode |= fsctx.Overwrite
+	} else {
+		fs.Use("BeforeUpload", filesystem.HookValidateFile)
+		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+		fs.Use("BeforeUpload", filesystem.HookValidateGroupLimits)
+		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
+		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
+		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
+		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
+	}
+	fs.Use("AfterUpload", filesystem.HookAntivirusScan)
+	fs.Use("AfterUpload", filesystem.HookExtractMediaMeta)
+	fs.Use("AfterUpload", filesystem.HookExtractExif)
+	fs.Use("AfterUpload", filesystem.HookExtractMusicMeta)
+	fs.Use("AfterUpload", filesystem.HookDeduplicate)
+
+	if err := fs.Upload(ctx, &fileData); err != nil {
+		return nil, err
+	}
+
+	return fileData.Model.(*model.File), nil
+}
@@ -0,0 +1,342 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/scim"
+)
+
+// anonymousGroupID 游客用户组，不通过 SCIM 暴露
+const anonymousGroupID = 3
+
+// memberFilterPath 形如 members[value eq "id"] 的成员路径
+var memberFilterPath = regexp.MustCompile(`(?i)^members\[value eq "([^"]+)"\]$`)
+
+// GroupService 单个用户组资源服务
+type GroupService struct {
+	ID                 string `uri:"id" binding:"required"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+// Groups 列出用户组
+func (service *ListService) Groups() scim.Response {
+	filter, err := scim.ParseFilter(service.Filter)
+	if err != nil {
+		return scim.Err(http.StatusBadRequest, scim.ErrInvalidFilter, err.Error())
+	}
+
+	var groups []model.Group
+	if filter != nil {
+		switch filter.Attribute {
+		case "displayname":
+			if group, ok := resolveGroup(filter.Value); ok {
+				groups = append(groups, group)
+			}
+		case "id":
+			id, _ := hashid.DecodeHashID(filter.Value, hashid.GroupID)
+			if group, err := model.GetGroupByID(id); err == nil && mappedGroups()[group.ID] {
+				groups = append(groups, group)
+			}
+		default:
+			return scim.Err(http.StatusBadRequest, scim.ErrInvalidFilter, "unsupported filter attribute")
+		}
+	} else if ids := mappedGroupIDs(); len(ids) > 0 {
+		if err := model.DB.Where("id in (?)", ids).Order("id").Find(&groups).Error; err != nil {
+			return scim.Err(http.StatusInternalServerError, "", err.Error())
+		}
+	}
+
+	total := len(groups)
+	start, count := service.page()
+	if start-1 < len(groups) {
+		groups = groups[start-1:]
+	} else {
+		groups = nil
+	}
+	if len(groups) > count {
+		groups = groups[:count]
+	}
+
+	withMembers := !excludesMembers(service.ExcludedAttributes)
+	resources := make([]scim.Group, 0, len(groups))
+	for i := range groups {
+		resources = append(resources, groupResource(&groups[i], withMembers))
+	}
+
+	return scim.OK(http.StatusOK, scim.NewListResponse(resources, total, start, len(resources)))
+}
+
+// Get 获取用户组
+func (service *GroupService) Get() scim.Response {
+	group, res, ok := service.group()
+	if !ok {
+		return res
+	}
+
+	return scim.OK(http.StatusOK, groupResource(group, !excludesMembers(service.ExcludedAttributes)))
+}
+
+// Create 将身份提供方的用户组关联到映射的 Cloudreve 用户组，并同步成员。
+// Cloudreve 用户组本身不会被创建
+func (service *GroupService) Create(resource *scim.Group) scim.Response {
+	group, ok := resolveGroup(resource.DisplayName)
+	if !ok {
+		return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, "no group is mapped to "+resource.DisplayName)
+	}
+
+	if err := group.AddMembers(managedMembers(memberIDs(resource.Members))); err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	model.RecordAudit(0, "scim.group.link", model.AuditTargetGroup, group.ID, map[string]string{"display_name": resource.DisplayName})
+	return scim.OK(http.StatusCreated, groupResource(&group, true))
+}
+
+// Replace 以请求中的成员列表替换用户组成员
+func (service *GroupService) Replace(resource *scim.Group) scim.Response {
+	group, res, ok := service.group()
+	if !ok {
+		return res
+	}
+
+	if err := replaceMembers(group, memberIDs(resource.Members)); err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	return scim.OK(http.StatusOK, groupResource(group, true))
+}
+
+// Patch 按修改操作增减用户组成员，其他属性的修改会被忽略
+func (service *GroupService) Patch(req *scim.PatchRequest) scim.Response {
+	group, res, ok := service.group()
+	if !ok {
+		return res
+	}
+
+	fallback := uint(model.GetIntSetting("default_group", 2))
+	for _, op := range req.Operations {
+		var members []scim.MultiValue
+		path := strings.ToLower(op.Path)
+		if path == "" {
+			// 无路径时值为包含 members 的对象
+			var values struct {
+				Members []scim.MultiValue `json:"members"`
+			}
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+			}
+			if values.Members == nil {
+				continue
+			}
+			members = values.Members
+		} else if match := memberFilterPath.FindStringSubmatch(op.Path); match != nil {
+			members = []scim.MultiValue{{Value: match[1]}}
+		} else if path == "members" {
+			if len(op.Value) > 0 {
+				if err := json.Unmarshal(op.Value, &members); err != nil {
+					return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+				}
+			}
+		} else {
+			continue
+		}
+
+		var err error
+		switch strings.ToLower(op.Op) {
+		case "add":
+			err = group.AddMembers(managedMembers(memberIDs(members)))
+		case "remove":
+			uids := memberIDs(members)
+			if path == "members" && len(op.Value) == 0 {
+				// 未指定成员时移出所有成员
+				uids = nil
+			}
+			err = group.RemoveMembers(uids, fallback)
+		case "replace":
+			err = replaceMembers(group, memberIDs(members))
+		default:
+			return scim.Err(http.StatusBadRequest, scim.ErrInvalidSyntax, "unsupported patch operation")
+		}
+
+		if err != nil {
+			return scim.Err(http.StatusInternalServerError, "", err.Error())
+		}
+	}
+
+	model.RecordAudit(0, "scim.group.update", model.AuditTargetGroup, group.ID, nil)
+	return scim.OK(http.StatusOK, groupResource(group, true))
+}
+
+// Delete 解除与身份提供方的关联，成员移入默认用户组，Cloudreve 用户组本身保留
+func (service *GroupService) Delete() scim.Response {
+	group, res, ok := service.group()
+	if !ok {
+		return res
+	}
+
+	if err := group.RemoveMembers(nil, uint(model.GetIntSetting("default_group", 2))); err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	model.RecordAudit(0, "scim.group.unlink", model.AuditTargetGroup, group.ID, nil)
+	return scim.OK(http.StatusNoContent, nil)
+}
+
+// group 获取请求的用户组，只能获取映射规则指向的用户组
+func (service *GroupService) group() (*model.Group, scim.Response, bool) {
+	id, err := hashid.DecodeHashID(service.ID, hashid.GroupID)
+	if err != nil || !mappedGroups()[id] {
+		return nil, scim.NotFound("group not found"), false
+	}
+
+	group, err := model.GetGroupByID(id)
+	if err != nil {
+		return nil, scim.NotFound("group not found"), false
+	}
+
+	return &group, scim.Response{}, true
+}
+
+// resolveGroup 按映射规则查找身份提供方用户组对应的 Cloudreve 用户组
+func resolveGroup(displayName string) (model.Group, bool) {
+	id, ok := scim.ResolveGroup(groupRules(), displayName)
+	if !ok || id == anonymousGroupID {
+		return model.Group{}, false
+	}
+
+	group, err := model.GetGroupByID(id)
+	return group, err == nil
+}
+
+// groupRules 读取用户组映射规则，设置有误时视为没有规则
+func groupRules() []scim.GroupRule {
+	rules, err := scim.ParseGroupRules(model.GetSettingByName("scim_group_mapping"))
+	if err != nil {
+		return nil
+	}
+
+	return rules
+}
+
+// mappedGroups 映射规则指向的 Cloudreve 用户组，SCIM 只能查看和修改这些用户组
+func mappedGroups() map[uint]bool {
+	groups := make(map[uint]bool)
+	for _, rule := range groupRules() {
+		if rule.Group != anonymousGroupID {
+			groups[rule.Group] = true
+		}
+	}
+
+	return groups
+}
+
+// mappedGroupIDs 按 ID 排序的 mappedGroups
+func mappedGroupIDs() []uint {
+	groups := mappedGroups()
+	ids := make([]uint, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// managedMembers 筛选出位于默认用户组或映射的用户组中的用户，
+// 其他用户组（如管理员组）的成员由管理员手动维护，不会被 SCIM 移动
+func managedMembers(uids []uint) []uint {
+	if len(uids) == 0 {
+		return uids
+	}
+
+	groups := append(mappedGroupIDs(), uint(model.GetIntSetting("default_group", 2)))
+	managed := make([]uint, 0, len(uids))
+	model.DB.Model(&model.User{}).Where("id in (?) and group_id in (?)", uids, groups).Pluck("id", &managed)
+	return managed
+}
+
+// replaceMembers 将用户组成员替换为 uids，原有成员移入默认用户组
+func replaceMembers(group *model.Group, uids []uint) error {
+	var current []uint
+	if err := model.DB.Model(&model.User{}).Where("group_id = ?", group.ID).Pluck("id", &current).Error; err != nil {
+		return err
+	}
+
+	keep := make(map[uint]bool, len(uids))
+	for _, uid := range uids {
+		keep[uid] = true
+	}
+
+	removed := make([]uint, 0, len(current))
+	for _, uid := range current {
+		if !keep[uid] {
+			removed = append(removed, uid)
+		}
+	}
+
+	if err := group.RemoveMembers(removed, uint(model.GetIntSetting("default_group", 2))); err != nil {
+		return err
+	}
+
+	return group.AddMembers(managedMembers(uids))
+}
+
+// memberIDs 解析成员列表中的用户ID，忽略无法识别的成员
+func memberIDs(members []scim.MultiValue) []uint {
+	uids := make([]uint, 0, len(members))
+	for _, member := range members {
+		if uid, err := hashid.DecodeHashID(member.Value, hashid.UserID); err == nil {
+			uids = append(uids, uid)
+		}
+	}
+
+	return uids
+}
+
+// excludesMembers 请求是否排除了成员列表
+func excludesMembers(excluded string) bool {
+	for _, attr := range strings.Split(excluded, ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// groupResource 将用户组模型转换为 SCIM 用户组资源
+func groupResource(group *model.Group, withMembers bool) scim.Group {
+	id := hashid.HashID(group.ID, hashid.GroupID)
+	created, modified := group.CreatedAt, group.UpdatedAt
+	resource := scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		DisplayName: group.Name,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     location("Groups", id),
+		},
+	}
+
+	if withMembers {
+		var users []model.User
+		model.DB.Select("id, email").Where("group_id = ?", group.ID).Find(&users)
+		resource.Members = make([]scim.MultiValue, 0, len(users))
+		for _, user := range users {
+			resource.Members = append(resource.Members, scim.MultiValue{
+				Value:   hashid.HashID(user.ID, hashid.UserID),
+				Display: user.Email,
+			})
+		}
+	}
+
+	return resource
+}
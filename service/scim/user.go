@@ -0,0 +1,355 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/scim"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ListService 列出资源服务
+type ListService struct {
+	Filter             string `form:"filter"`
+	StartIndex         int    `form:"startIndex" binding:"omitempty,min=1"`
+	Count              int    `form:"count" binding:"omitempty,min=0,max=1000"`
+	ExcludedAttributes string `form:"excludedAttributes"`
+}
+
+// UserService 单个用户资源服务
+type UserService struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+// page 返回分页的起始位置与数量
+func (service *ListService) page() (int, int) {
+	start, count := service.StartIndex, service.Count
+	if start == 0 {
+		start = 1
+	}
+	if count == 0 {
+		count = 100
+	}
+	return start, count
+}
+
+// Users 列出用户
+func (service *ListService) Users() scim.Response {
+	filter, err := scim.ParseFilter(service.Filter)
+	if err != nil {
+		return scim.Err(http.StatusBadRequest, scim.ErrInvalidFilter, err.Error())
+	}
+
+	tx := model.DB.Model(&model.User{})
+	if filter != nil {
+		switch filter.Attribute {
+		case "username", "emails.value", "emails":
+			tx = tx.Where(model.EqualFold("email"), filter.Value)
+		case "externalid":
+			tx = tx.Where("external_id = ?", filter.Value)
+		case "id":
+			id, _ := hashid.DecodeHashID(filter.Value, hashid.UserID)
+			tx = tx.Where("id = ?", id)
+		default:
+			return scim.Err(http.StatusBadRequest, scim.ErrInvalidFilter, "unsupported filter attribute")
+		}
+	}
+
+	start, count := service.page()
+	total := 0
+	var users []model.User
+	tx.Count(&total)
+	if err := tx.Set("gorm:auto_preload", true).Order("id").Limit(count).Offset(start - 1).Find(&users).Error; err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	resources := make([]scim.User, 0, len(users))
+	for i := range users {
+		resources = append(resources, userResource(&users[i]))
+	}
+
+	return scim.OK(http.StatusOK, scim.NewListResponse(resources, total, start, len(resources)))
+}
+
+// Get 获取用户
+func (service *UserService) Get() scim.Response {
+	user, res, ok := service.user()
+	if !ok {
+		return res
+	}
+
+	return scim.OK(http.StatusOK, userResource(user))
+}
+
+// Create 创建用户，新用户位于默认用户组，密码未指定时随机生成
+func (service *UserService) Create(resource *scim.User) scim.Response {
+	user := model.NewUser()
+	user.Status = model.Active
+	user.GroupID = uint(model.GetIntSetting("default_group", 2))
+	if resource.Password == "" {
+		resource.Password = util.RandStringRunes(32)
+	}
+
+	if res, ok := applyUser(&user, resource); !ok {
+		return res
+	}
+
+	if _, err := model.GetUserByEmail(user.Email); err == nil {
+		return scim.Err(http.StatusConflict, scim.ErrUniqueness, "userName is already in use")
+	}
+
+	if err := model.DB.Create(&user).Error; err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	model.RecordAudit(0, "scim.user.create", model.AuditTargetUser, user.ID, map[string]string{"external_id": user.ExternalID})
+	created, _ := model.GetUserByID(user.ID)
+	return scim.OK(http.StatusCreated, userResource(&created))
+}
+
+// Replace 以请求中的资源替换用户信息
+func (service *UserService) Replace(resource *scim.User) scim.Response {
+	user, res, ok := service.user()
+	if !ok {
+		return res
+	}
+
+	if res, ok := applyUser(user, resource); !ok {
+		return res
+	}
+
+	return service.save(user)
+}
+
+// Patch 按修改操作更新用户信息，不支持的属性会被忽略
+func (service *UserService) Patch(req *scim.PatchRequest) scim.Response {
+	user, res, ok := service.user()
+	if !ok {
+		return res
+	}
+
+	for _, op := range req.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path == "" {
+				var values map[string]json.RawMessage
+				if err := json.Unmarshal(op.Value, &values); err != nil {
+					return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, err.Error())
+				}
+				for path, value := range values {
+					if res, ok := patchUser(user, path, value); !ok {
+						return res
+					}
+				}
+				continue
+			}
+
+			if res, ok := patchUser(user, op.Path, op.Value); !ok {
+				return res
+			}
+		case "remove":
+			if strings.EqualFold(op.Path, "externalId") {
+				user.ExternalID = ""
+			}
+		default:
+			return scim.Err(http.StatusBadRequest, scim.ErrInvalidSyntax, "unsupported patch operation")
+		}
+	}
+
+	return service.save(user)
+}
+
+// Delete 注销用户，用户立即停用，冷静期结束后由后台任务清理所有数据
+func (service *UserService) Delete() scim.Response {
+	user, res, ok := service.user()
+	if !ok {
+		return res
+	}
+
+	if user.ID == 1 {
+		return scim.Err(http.StatusBadRequest, scim.ErrMutability, "the initial user cannot be deleted")
+	}
+
+	if user.Status != model.PendingDeletion {
+		grace := time.Duration(model.GetIntSetting("account_deletion_grace", 604800)) * time.Second
+		job, err := task.NewAccountPurgeTask(user, time.Now().Add(grace))
+		if err != nil {
+			return scim.Err(http.StatusInternalServerError, "", err.Error())
+		}
+
+		user.SetStatus(model.PendingDeletion)
+		model.RecordAudit(0, "scim.user.delete", model.AuditTargetUser, user.ID, nil)
+		task.SubmitDeferred(task.TaskPoll, job)
+	}
+
+	return scim.OK(http.StatusNoContent, nil)
+}
+
+// user 获取请求的用户
+func (service *UserService) user() (*model.User, scim.Response, bool) {
+	id, err := hashid.DecodeHashID(service.ID, hashid.UserID)
+	if err != nil {
+		return nil, scim.NotFound("user not found"), false
+	}
+
+	user, err := model.GetUserByID(id)
+	if err != nil {
+		return nil, scim.NotFound("user not found"), false
+	}
+
+	return &user, scim.Response{}, true
+}
+
+// save 保存修改后的用户
+func (service *UserService) save(user *model.User) scim.Response {
+	if existed, err := model.GetUserByEmail(user.Email); err == nil && existed.ID != user.ID {
+		return scim.Err(http.StatusConflict, scim.ErrUniqueness, "userName is already in use")
+	}
+
+	if user.ID == 1 && user.Status != model.Active {
+		return scim.Err(http.StatusBadRequest, scim.ErrMutability, "the initial user cannot be deactivated")
+	}
+
+	if err := model.DB.Save(user).Error; err != nil {
+		return scim.Err(http.StatusInternalServerError, "", err.Error())
+	}
+
+	model.RecordAudit(0, "scim.user.update", model.AuditTargetUser, user.ID, map[string]interface{}{"status": user.Status})
+	return scim.OK(http.StatusOK, userResource(user))
+}
+
+// applyUser 将 SCIM 用户资源写入用户模型
+func applyUser(user *model.User, resource *scim.User) (scim.Response, bool) {
+	email := resource.UserName
+	if !strings.Contains(email, "@") {
+		email = ""
+		for _, e := range resource.Emails {
+			if email == "" || e.Primary {
+				email = e.Value
+			}
+		}
+	}
+
+	if !strings.Contains(email, "@") {
+		return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, "userName or emails must contain an email address"), false
+	}
+	user.Email = email
+
+	user.Nick = resource.DisplayName
+	if user.Nick == "" && resource.Name != nil {
+		user.Nick = resource.Name.Formatted
+		if user.Nick == "" {
+			user.Nick = strings.TrimSpace(resource.Name.GivenName + " " + resource.Name.FamilyName)
+		}
+	}
+	if user.Nick == "" {
+		user.Nick = strings.Split(email, "@")[0]
+	}
+
+	if resource.ExternalID != "" {
+		user.ExternalID = resource.ExternalID
+	}
+
+	if resource.Password != "" {
+		user.SetPassword(resource.Password)
+	}
+
+	if resource.Active != nil {
+		setActive(user, *resource.Active)
+	}
+
+	return scim.Response{}, true
+}
+
+// patchUser 修改用户的单个属性
+func patchUser(user *model.User, path string, value json.RawMessage) (scim.Response, bool) {
+	var err error
+	switch strings.ToLower(path) {
+	case "active":
+		var active bool
+		if active, err = scim.ParseBool(value); err == nil {
+			setActive(user, active)
+		}
+	case "username", "emails[type eq \"work\"].value":
+		var email string
+		if email, err = scim.ParseString(value); err == nil {
+			if !strings.Contains(email, "@") {
+				return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, "userName must be an email address"), false
+			}
+			user.Email = email
+		}
+	case "displayname", "name.formatted":
+		user.Nick, err = scim.ParseString(value)
+	case "externalid":
+		user.ExternalID, err = scim.ParseString(value)
+	case "password":
+		var password string
+		if password, err = scim.ParseString(value); err == nil {
+			user.SetPassword(password)
+		}
+	}
+
+	if err != nil {
+		return scim.Err(http.StatusBadRequest, scim.ErrInvalidValue, err.Error()), false
+	}
+
+	return scim.Response{}, true
+}
+
+// setActive 启用或停用用户。因超额被封禁或等待注销的用户不会被重新启用
+func setActive(user *model.User, active bool) {
+	if active {
+		if user.Status == model.Baned || user.Status == model.NotActivicated {
+			user.Status = model.Active
+		}
+		return
+	}
+
+	if user.Status == model.Active || user.Status == model.NotActivicated {
+		user.Status = model.Baned
+	}
+}
+
+// userResource 将用户模型转换为 SCIM 用户资源
+func userResource(user *model.User) scim.User {
+	id := hashid.HashID(user.ID, hashid.UserID)
+	active := user.Status == model.Active
+	created, modified := user.CreatedAt, user.UpdatedAt
+	resource := scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		Name:        &scim.Name{Formatted: user.Nick},
+		DisplayName: user.Nick,
+		Emails:      []scim.MultiValue{{Value: user.Email, Primary: true, Type: "work"}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     location("Users", id),
+		},
+	}
+
+	if user.GroupID > 0 {
+		resource.Groups = []scim.MultiValue{{
+			Value:   hashid.HashID(user.GroupID, hashid.GroupID),
+			Display: user.Group.Name,
+		}}
+	}
+
+	return resource
+}
+
+// location 返回资源的访问地址
+func location(resourceType, id string) string {
+	controller, _ := url.Parse("/api/v3/scim/v2/" + resourceType + "/" + id)
+	return model.GetSiteURL().ResolveReference(controller).String()
+}
@@ -1,7 +1,10 @@
 package setting
 
 import (
+	"path"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -16,10 +19,11 @@ type WebDAVAccountService struct {
 	ID uint `uri:"id" binding:"required,min=1"`
 }
 
-// WebDAVAccountCreateService WebDAV 账号创建服务
+// WebDAVAccountCreateService WebDAV 账号创建服务，账户只能访问 Path 目录下的文件
 type WebDAVAccountCreateService struct {
-	Path string `json:"path" binding:"required,min=1,max=65535"`
-	Name string `json:"name" binding:"required,min=1,max=255"`
+	Path     string `json:"path" binding:"required,min=1,max=65535"`
+	Name     string `json:"name" binding:"required,min=1,max=255"`
+	Readonly bool   `json:"readonly"`
 }
 
 // WebDAVAccountUpdateService WebDAV 修改只读性和是否使用代理服务
@@ -37,11 +41,24 @@ type WebDAVMountCreateService struct {
 
 // Create 创建WebDAV账户
 func (service *WebDAVAccountCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 根目录须为已存在的目录
+	root := path.Clean("/" + service.Path)
+	if exist, _ := fs.IsPathExist(root); !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
 	account := model.Webdav{
 		Name:     service.Name,
 		Password: util.RandStringRunes(32),
 		UserID:   user.ID,
-		Root:     service.Path,
+		Root:     root,
+		Readonly: service.Readonly,
 	}
 
 	if _, err := account.Create(); err != nil {
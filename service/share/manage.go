@@ -2,11 +2,14 @@ package share
 
 import (
 	"net/url"
+	"strconv"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 	"github.com/gin-gonic/gin"
 )
 
@@ -18,14 +21,65 @@ type ShareCreateService struct {
 	RemainDownloads int    `json:"downloads"`
 	Expire          int    `json:"expire"`
 	Preview         bool   `json:"preview"`
+	MaxTraffic      uint64 `json:"max_traffic"`
+	FileDownloads   int    `json:"file_downloads" binding:"min=0"`
+	UploadOnly      bool   `json:"upload_only"`
+	UploadMaxSize   uint64 `json:"upload_max_size"`
+	UploadExts      string `json:"upload_exts" binding:"max=255"`
+	UploadLimit     int    `json:"upload_limit" binding:"min=0"`
 }
 
 // ShareUpdateService 分享更新服务
 type ShareUpdateService struct {
-	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled"`
+	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled|eq=max_traffic|eq=file_downloads"`
 	Value string `json:"value" binding:"max=255"`
 }
 
+// ShareAccessLogService 列出分享访问记录服务
+type ShareAccessLogService struct {
+	Page uint `form:"page" binding:"required,min=1"`
+}
+
+// List 列出分享的访问记录，仅分享创建者可查看
+func (service *ShareAccessLogService) List(c *gin.Context, user *model.User) serializer.Response {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	logs, total, err := model.ListShareAccessLogs(share.ID, int(service.Page), 50)
+	if err != nil {
+		return serializer.DBErr("Failed to list share access logs", err)
+	}
+
+	return serializer.BuildShareAccessLogList(logs, total)
+}
+
+// Usage 获取分享的流量和各文件下载次数，仅分享创建者可查看
+func (service *Service) Usage(c *gin.Context, user *model.User) serializer.Response {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	counters, err := model.GetShareFileDownloads(share.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list share downloads", err)
+	}
+
+	ids := make([]uint, 0, len(counters))
+	for _, counter := range counters {
+		ids = append(ids, counter.FileID)
+	}
+
+	files, err := model.GetFilesByIDs(ids, share.UserID)
+	if err != nil {
+		return serializer.DBErr("Failed to list files", err)
+	}
+
+	return serializer.BuildShareUsage(share, counters, files)
+}
+
 // Delete 删除分享
 func (service *Service) Delete(c *gin.Context, user *model.User) serializer.Response {
 	share := model.GetShareByHashID(c.Param("id"))
@@ -60,6 +114,22 @@ func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 		return serializer.Response{
 			Data: value,
 		}
+	case "max_traffic":
+		value, err := strconv.ParseUint(service.Value, 10, 64)
+		if err != nil {
+			return serializer.ParamErr("Invalid traffic limit", err)
+		}
+		if err := share.Update(map[string]interface{}{"max_traffic": value}); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+	case "file_downloads":
+		value, err := strconv.Atoi(service.Value)
+		if err != nil || value < 0 {
+			return serializer.ParamErr("Invalid download limit", err)
+		}
+		if err := share.Update(map[string]interface{}{"file_downloads": value}); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
 	}
 	return serializer.Response{
 		Data: service.Value,
@@ -69,8 +139,11 @@ func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 // Create 创建新分享
 func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 	userCtx, _ := c.Get("user")
-	user := userCtx.(*model.User)
+	return service.CreateAs(c, userCtx.(*model.User))
+}
 
+// CreateAs 以给定用户的身份创建分享
+func (service *ShareCreateService) CreateAs(c *gin.Context, user *model.User) serializer.Response {
 	// 是否拥有权限
 	if !user.Group.ShareEnabled {
 		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
@@ -112,6 +185,11 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeNotFound, "", nil)
 	}
 
+	// 投递分享只能针对目录
+	if service.UploadOnly && !service.IsDir {
+		return serializer.ParamErr("Only folders can be shared for uploading", nil)
+	}
+
 	newShare := model.Share{
 		Password:        service.Password,
 		IsDir:           service.IsDir,
@@ -120,6 +198,12 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		RemainDownloads: -1,
 		PreviewEnabled:  service.Preview,
 		SourceName:      sourceName,
+		MaxTraffic:      service.MaxTraffic,
+		FileDownloads:   service.FileDownloads,
+		UploadOnly:      service.UploadOnly,
+		UploadMaxSize:   service.UploadMaxSize,
+		UploadExts:      service.UploadExts,
+		UploadLimit:     service.UploadLimit,
 	}
 
 	// 如果开启了自动过期
@@ -135,8 +219,32 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		return serializer.DBErr("Failed to create share link record", err)
 	}
 
+	objectType := model.ChangeObjectFile
+	if service.IsDir {
+		objectType = model.ChangeObjectFolder
+	}
+
+	if err := model.RecordActivities([]model.Activity{{
+		UserID:     user.ID,
+		Type:       model.ActivityShare,
+		ObjectType: objectType,
+		ObjectID:   sourceID,
+		Name:       sourceName,
+		ShareID:    id,
+		IP:         c.ClientIP(),
+	}}); err != nil {
+		util.Log().Warning("Failed to record activities: %s", err)
+	}
+
 	// 获取分享的唯一id
 	uid := hashid.HashID(id, hashid.ShareID)
+	go webhook.Dispatch(model.WebhookEventShareCreate, user.ID, &webhook.ShareData{
+		ID:     uid,
+		Name:   sourceName,
+		IsDir:  service.IsDir,
+		Source: service.SourceID,
+	})
+
 	// 最终得到分享链接
 	siteURL := model.GetSiteURL()
 	sharePath, _ := url.Parse("/s/" + uid)
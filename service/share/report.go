@@ -0,0 +1,45 @@
+package share
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportService 举报分享服务
+type ReportService struct {
+	Reason      string `json:"reason" binding:"required,eq=copyright|eq=illegal|eq=porn|eq=spam|eq=other"`
+	Description string `json:"description" binding:"max=4096"`
+	Contact     string `json:"contact" binding:"max=255"`
+}
+
+// Report 举报分享，同一 IP 对同一分享尚未处理的举报只记录一次
+func (service *ReportService) Report(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	ip := c.ClientIP()
+	pending, err := model.CountPendingAbuseReportsByIP(share.ID, ip)
+	if err != nil {
+		return serializer.DBErr("Failed to count reports", err)
+	}
+
+	if pending > 0 {
+		return serializer.Response{}
+	}
+
+	report := &model.AbuseReport{
+		ShareID:     share.ID,
+		OwnerID:     share.UserID,
+		Reason:      service.Reason,
+		Description: service.Description,
+		Contact:     service.Contact,
+		IP:          ip,
+		Status:      model.AbuseReportPending,
+	}
+	if err := report.Create(); err != nil {
+		return serializer.DBErr("Failed to create report", err)
+	}
+
+	return serializer.Response{}
+}
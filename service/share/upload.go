@@ -0,0 +1,98 @@
+package share
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadService 向投递分享上传文件服务
+type UploadService struct {
+	Name string `form:"name" binding:"required,max=255"`
+}
+
+// visitor 返回当前访客的标识，登录用户按用户统计，游客按 IP 统计
+func visitor(c *gin.Context, user *model.User) string {
+	if !user.IsAnonymous() {
+		return fmt.Sprintf("user_%d", user.ID)
+	}
+
+	return "ip_" + c.ClientIP()
+}
+
+// Upload 将请求正文作为文件保存到投递分享的目录下，重名时自动重命名
+func (service *UploadService) Upload(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	if !share.UploadOnly || !share.IsDir {
+		return serializer.ParamErr("This share does not accept uploads", nil)
+	}
+
+	if c.Request.ContentLength < 0 {
+		return serializer.Err(serializer.CodeInvalidContentLength, "", nil)
+	}
+
+	name := path.Base(service.Name)
+	size := uint64(c.Request.ContentLength)
+	if err := share.ValidateUpload(name, size); err != nil {
+		if errors.Is(err, model.ErrShareUploadTooLarge) {
+			return serializer.Err(serializer.CodeFileTooLarge, err.Error(), err)
+		}
+		return serializer.Err(serializer.CodeFileTypeNotAllowed, err.Error(), err)
+	}
+
+	visitorID := visitor(c, user)
+	counter, err := share.CheckUploadLimit(visitorID)
+	if err != nil {
+		if errors.Is(err, model.ErrShareUploadLimitExceeded) {
+			return serializer.Err(serializer.CodeShareLimitExceeded, err.Error(), err)
+		}
+		return serializer.DBErr("Failed to query upload count", err)
+	}
+
+	// 以分享者的身份写入分享的目录
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fs.Root = share.SourceFolder()
+	if fs.Root.ID == 0 {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, c.Request.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	file := &fsctx.FileStream{
+		File:        filesystem.WithUploadSpeedLimit(c.Request.Body, fs.User.UploadSpeedLimit()),
+		Size:        size,
+		Name:        fs.AvailableName("/", name),
+		MimeType:    c.GetHeader("Content-Type"),
+		VirtualPath: "/",
+	}
+	if err := fs.UploadFromStream(ctx, file, true); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	if err := share.UploadedBy(&counter, visitorID); err != nil {
+		util.Log().Warning("Failed to update upload count of share %d: %s", share.ID, err)
+	}
+	model.RecordShareAccess(c, share.ID, model.ShareAccessUpload)
+
+	return serializer.Response{Data: file.Name}
+}
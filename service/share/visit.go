@@ -2,16 +2,23 @@ package share
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"path"
+	"strconv"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ratelimit"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/webhook"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
 )
@@ -126,15 +133,37 @@ func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 		unlocked = util.GetSession(c, sessionKey) != nil
 		if !unlocked && service.Password != "" {
 			// 如果未解锁，且指定了密码，则尝试解锁
-			if service.Password == share.Password {
+			if limit := model.GetIntSetting("share_password_rate_limit", 10); limit > 0 {
+				key := fmt.Sprintf("share_password_%d_%s", share.ID, c.ClientIP())
+				res, err := ratelimit.Default.Take(key, ratelimit.PerMinute(limit))
+				if err == nil && !res.Allowed {
+					return serializer.Err(serializer.CodeTooManyRequests, "Too many requests, please try again later", nil)
+				}
+			}
+
+			ok, err := share.TryPassword(service.Password, c.ClientIP())
+			if err != nil {
+				model.RecordShareAccess(c, share.ID, model.ShareAccessLocked)
+				return serializer.Err(serializer.CodeSharePasswordLocked, "Too many incorrect password attempts, please try again later", err)
+			}
+
+			if ok {
 				unlocked = true
 				util.SetSession(c, map[string]interface{}{sessionKey: true})
+				model.RecordShareAccess(c, share.ID, model.ShareAccessUnlock)
+			} else {
+				model.RecordShareAccess(c, share.ID, model.ShareAccessPasswordFailed)
 			}
 		}
 	}
 
 	if unlocked {
 		share.Viewed()
+		stats.Incr(stats.MetricShareHits)
+		model.RecordShareAccess(c, share.ID, model.ShareAccessView)
+		userCtx, _ := c.Get("user")
+		visitor, _ := userCtx.(*model.User)
+		notifyShareAccessed(share, visitor)
 	}
 
 	return serializer.Response{
@@ -143,6 +172,32 @@ func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 	}
 }
 
+// notifyShareAccessed 通知创建者分享被访问，同一分享在 notify_share_interval 秒内只通知一次
+func notifyShareAccessed(share *model.Share, visitor *model.User) {
+	if visitor != nil && visitor.ID == share.UserID {
+		return
+	}
+
+	name := "匿名用户"
+	if visitor != nil && !visitor.IsAnonymous() {
+		name = visitor.Nick
+	}
+
+	owner := share.Creator()
+	vars := map[string]string{
+		"shareName": share.SourceName,
+		"visitor":   name,
+		"time":      time.Now().Format("2006-01-02 15:04:05"),
+	}
+	key := strconv.FormatUint(uint64(share.ID), 10)
+	interval := model.GetIntSetting("notify_share_interval", 3600)
+	go func() {
+		if err := email.NotifyThrottled(owner, email.NotifyShareAccessed, key, interval, vars); err != nil {
+			util.Log().Warning("Failed to send share accessed notification: %s", err)
+		}
+	}()
+}
+
 // CreateDownloadSession 创建下载会话
 func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
@@ -163,7 +218,7 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 		return serializer.Err(serializer.CodeFileNotFound, "", err)
 	}
 
-	ctx := context.Background()
+	ctx := util.RequestContext(c)
 
 	// 重设根目录
 	if share.IsDir {
@@ -177,11 +232,37 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 	}
 
 	// 取得下载地址
+	ctx = filesystem.WithDownloadOptions(ctx, c)
 	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	// 计入分享的流量和文件下载次数
+	if err := share.ConsumeDownload(&fs.FileTarget[0]); err != nil {
+		if errors.Is(err, model.ErrShareTrafficExceeded) || errors.Is(err, model.ErrShareFileDownloadsExceeded) {
+			return serializer.Err(serializer.CodeShareLimitExceeded, err.Error(), err)
+		}
+		return serializer.DBErr("Failed to update share record", err)
+	}
+	stats.Download(fs.FileTarget[0].UserID, fs.FileTarget[0].Size)
+	model.RecordShareAccess(c, share.ID, model.ShareAccessDownload)
+
+	data := &webhook.ShareData{
+		ID:     hashid.HashID(share.ID, hashid.ShareID),
+		Name:   share.SourceName,
+		IsDir:  share.IsDir,
+		Source: hashid.HashID(share.SourceID, hashid.FileID),
+	}
+	if share.IsDir {
+		data.Source = hashid.HashID(share.SourceID, hashid.FolderID)
+		data.Path = service.Path
+	}
+	if !user.IsAnonymous() {
+		data.Visitor = hashid.HashID(user.ID, hashid.UserID)
+	}
+	go webhook.Dispatch(model.WebhookEventShareDownload, share.UserID, data)
+
 	return serializer.Response{
 		Code: 0,
 		Data: downloadURL,
@@ -212,7 +293,7 @@ func (service *Service) CreateDocPreviewSession(c *gin.Context) serializer.Respo
 	share := shareCtx.(*model.Share)
 
 	// 用于调下层service
-	ctx := context.Background()
+	ctx := util.RequestContext(c)
 	if share.IsDir {
 		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
 		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
@@ -224,6 +305,24 @@ func (service *Service) CreateDocPreviewSession(c *gin.Context) serializer.Respo
 	return subService.CreateDocPreviewSession(ctx, c, false)
 }
 
+// CreatePreviewSession 为分享的文件匹配预览方式
+func (service *Service) CreatePreviewSession(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	// 用于调下层service
+	ctx := util.RequestContext(c)
+	if share.IsDir {
+		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
+		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
+	} else {
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, share.Source())
+	}
+	subService := explorer.FileIDService{}
+
+	return subService.CreatePreviewSession(ctx, c)
+}
+
 // List 列出分享的目录下的对象
 func (service *Service) List(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
@@ -245,7 +344,7 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	defer fs.Recycle()
 
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 重设根目录
@@ -255,12 +354,19 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	// 分享Key上下文
 	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
 
-	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	folder, err := fs.ListTarget(service.Path)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	// 列表未变化时直接返回 304，不再查询子项目
+	if util.NotModified(c, fs.ListingETag(ctx, folder)) {
+		return serializer.Response{}
+	}
+
+	// 获取子项目
+	objects := fs.ListFolder(ctx, folder, nil)
+
 	return serializer.Response{
 		Code: 0,
 		Data: serializer.BuildObjectList(0, objects, nil),
@@ -292,7 +398,7 @@ func (service *Service) Thumb(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeParentNotExist, "", nil)
 	}
 
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
+	ctx := context.WithValue(util.RequestContext(c), fsctx.LimitParentCtx, parent)
 
 	// 获取文件ID
 	fileID, err := hashid.DecodeHashID(c.Param("file"), hashid.FileID)
@@ -335,6 +441,11 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 		return serializer.ParamErr("This share cannot be batch downloaded", nil)
 	}
 
+	// 打包下载无法统计流量和单个文件的下载次数
+	if share.Limited() {
+		return serializer.Err(serializer.CodeShareLimitExceeded, "Batch download is not available for shares with download limits", nil)
+	}
+
 	// 创建文件系统
 	fs, err := filesystem.NewFileSystem(user)
 	if err != nil {
@@ -352,7 +463,7 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 	}
 
 	// 限制操作范围为父目录下
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
+	ctx := context.WithValue(util.RequestContext(c), fsctx.LimitParentCtx, parent)
 
 	// 用于调下层service
 	tempUser := share.Creator()
@@ -393,7 +504,7 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 	defer fs.Recycle()
 
 	// 上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
 	defer cancel()
 
 	// 重设根目录
@@ -411,5 +522,9 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 	// 分享Key上下文
 	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
 
+	if service.Type == "content" {
+		return service.SearchContent(c, fs)
+	}
+
 	return service.SearchKeywords(c, fs, "%"+service.Keywords+"%")
 }
@@ -0,0 +1,53 @@
+package team
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/acl"
+	"github.com/gin-gonic/gin"
+)
+
+// managedAccount 获取当前用户管理的团队的存储账户，团队目录的 ACL 归属于此账户
+func managedAccount(c *gin.Context, user *model.User) (*model.User, error) {
+	team, err := getManagedTeam(c, user)
+	if err != nil {
+		return nil, err
+	}
+
+	account, err := team.Account()
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeUserNotFound, "", err)
+	}
+
+	return &account, nil
+}
+
+// CreateACL 为团队空间中的目录添加 ACL 条目
+func CreateACL(c *gin.Context, user *model.User, service *acl.CreateService) serializer.Response {
+	account, err := managedAccount(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return service.Create(c, account)
+}
+
+// ListACLs 列出团队空间中目录上设置的 ACL 条目
+func ListACLs(c *gin.Context, user *model.User) serializer.Response {
+	account, err := managedAccount(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return acl.List(c, account, c.Param("folder"))
+}
+
+// DeleteACL 删除团队空间中目录上的 ACL 条目
+func DeleteACL(c *gin.Context, user *model.User) serializer.Response {
+	account, err := managedAccount(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return acl.Delete(c, account, c.Param("acl"))
+}
@@ -0,0 +1,149 @@
+package team
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// PathService 访问团队空间中对象的服务
+type PathService struct {
+	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
+}
+
+// prepareFs 创建团队存储账户的文件系统，以当前用户的身份访问，操作受目录 ACL 和团队角色限制
+func (service *PathService) prepareFs(c *gin.Context, user *model.User) (*filesystem.FileSystem, string, error) {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fs, err := filesystem.NewTeamFileSystem(team)
+	if err != nil {
+		return nil, "", serializer.NewError(serializer.CodeCreateFSError, "", err)
+	}
+
+	fs.UseActor(user, team.Permissions())
+	return fs, path.Clean("/" + service.Path), nil
+}
+
+// List 列出团队空间中的目录
+func (service *PathService) List(c *gin.Context, user *model.User) serializer.Response {
+	fs, p, err := service.prepareFs(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+
+	objects, err := fs.List(ctx, p, nil)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: serializer.BuildObjectList(0, objects, fs.Policy)}
+}
+
+// Download 获取团队空间中文件的下载地址
+func (service *PathService) Download(c *gin.Context, user *model.User) serializer.Response {
+	fs, p, err := service.prepareFs(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx := util.RequestContext(c)
+	if err := fs.ResetFileIfNotExist(ctx, p); err != nil {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	ctx = filesystem.WithDownloadOptions(ctx, c)
+	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{Data: downloadURL}
+}
+
+// CreateDirectory 在团队空间中创建目录
+func (service *PathService) CreateDirectory(c *gin.Context, user *model.User) serializer.Response {
+	fs, p, err := service.prepareFs(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	if _, err := fs.CreateDirectory(util.RequestContext(c), p); err != nil {
+		return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Upload 将请求正文上传为团队空间中的文件，占用团队容量
+func (service *PathService) Upload(c *gin.Context, user *model.User) serializer.Response {
+	if c.Request.ContentLength < 0 {
+		return serializer.Err(serializer.CodeInvalidContentLength, "", nil)
+	}
+
+	fs, p, err := service.prepareFs(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	ctx, cancel := context.WithCancel(util.RequestContext(c))
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, c.Request.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	file := &fsctx.FileStream{
+		File:        filesystem.WithUploadSpeedLimit(c.Request.Body, user.UploadSpeedLimit()),
+		Size:        uint64(c.Request.ContentLength),
+		Name:        path.Base(p),
+		MimeType:    c.GetHeader("Content-Type"),
+		VirtualPath: path.Dir(p),
+	}
+	if err := fs.UploadFromStream(ctx, file, true); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 删除团队空间中的对象，团队的回收站开启时移入回收站
+func (service *PathService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	fs, p, err := service.prepareFs(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	if p == "/" {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Cannot delete the team root folder", nil)
+	}
+
+	var dirs, files []uint
+	if exist, folder := fs.IsPathExist(p); exist {
+		dirs = append(dirs, folder.ID)
+	} else if exist, file := fs.IsFileExist(p); exist {
+		files = append(files, file.ID)
+	} else {
+		return serializer.Err(serializer.CodeNotFound, "", filesystem.ErrObjectNotExist)
+	}
+
+	if err := fs.Trash(util.RequestContext(c), dirs, files); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,81 @@
+package team
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/share"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// CreateShare 以团队存储账户的身份分享团队空间中的对象，需要对其拥有分享权限
+func CreateShare(c *gin.Context, user *model.User, service *share.ShareCreateService) serializer.Response {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	fs, err := filesystem.NewTeamFileSystem(team)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 检查对分享对象的分享权限
+	var dirs, files []uint
+	if service.IsDir {
+		id, err := hashid.DecodeHashID(service.SourceID, hashid.FolderID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "", err)
+		}
+		dirs = append(dirs, id)
+	} else {
+		id, err := hashid.DecodeHashID(service.SourceID, hashid.FileID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "", err)
+		}
+		files = append(files, id)
+	}
+
+	fs.UseActor(user, team.Permissions())
+	if err := fs.CheckObjectsPermission(dirs, files, model.ACLShare); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return service.CreateAs(c, fs.User)
+}
+
+// ListShares 列出团队的分享
+func ListShares(c *gin.Context, user *model.User, service *share.ShareListService) serializer.Response {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return service.List(c, &model.User{Model: gorm.Model{ID: team.UserID}})
+}
+
+// DeleteShare 删除团队的分享，查看者不能删除分享
+func DeleteShare(c *gin.Context, user *model.User) serializer.Response {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	if !team.CanWrite() {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Viewers cannot delete team shares", nil)
+	}
+
+	s := model.GetShareByHashID(c.Param("share"))
+	if s == nil || s.UserID != team.UserID {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	if err := s.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete share record", err)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,181 @@
+package team
+
+import (
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateService 创建团队服务
+type CreateService struct {
+	Name string `json:"name" binding:"required,min=1,max=255"`
+}
+
+// MemberService 添加或更新团队成员服务
+type MemberService struct {
+	User string `json:"user" binding:"required,email"`
+	Role string `json:"role" binding:"required,eq=editor|eq=viewer"`
+}
+
+// getTeam 获取路径参数指定的团队，当前用户须为团队成员
+func getTeam(c *gin.Context, user *model.User) (*model.Team, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeNotFound, "Team not found", err)
+	}
+
+	team, err := model.GetTeamByMember(uint(id), user.ID)
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeNotFound, "Team not found", err)
+	}
+
+	return team, nil
+}
+
+// getManagedTeam 获取路径参数指定的团队，当前用户须为团队所有者
+func getManagedTeam(c *gin.Context, user *model.User) (*model.Team, error) {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return nil, err
+	}
+
+	if !team.CanManage() {
+		return nil, serializer.NewError(serializer.CodeNoPermissionErr, "Only the team owner can perform this action", nil)
+	}
+
+	return team, nil
+}
+
+// Create 创建团队，创建者成为团队所有者
+func (service *CreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	team := &model.Team{Name: service.Name}
+	if err := team.Create(user); err != nil {
+		if err == model.ErrTeamLimitReached {
+			return serializer.Err(serializer.CodeTeamLimitReached, err.Error(), err)
+		}
+
+		return serializer.DBErr("Failed to create team", err)
+	}
+
+	return serializer.Response{Data: team.ID}
+}
+
+// List 列出用户所在的团队
+func List(c *gin.Context, user *model.User) serializer.Response {
+	teams, err := model.ListTeamsByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list teams", err)
+	}
+
+	used := make(map[uint]uint64, len(teams))
+	for _, team := range teams {
+		if account, err := model.GetUserByID(team.UserID); err == nil {
+			used[team.ID] = account.Storage
+		}
+	}
+
+	return serializer.BuildTeamList(teams, used)
+}
+
+// Delete 删除团队，团队存储账户中的数据由后台任务清理
+func Delete(c *gin.Context, user *model.User) serializer.Response {
+	team, err := getManagedTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	account, err := team.Account()
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	job, err := task.NewAccountPurgeTask(&account, time.Now())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+
+	if err := team.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete team", err)
+	}
+
+	account.SetStatus(model.PendingDeletion)
+	task.SubmitDeferred(task.TaskPoll, job)
+	return serializer.Response{}
+}
+
+// ListMembers 列出团队成员
+func ListMembers(c *gin.Context, user *model.User) serializer.Response {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	members, err := team.Members()
+	if err != nil {
+		return serializer.DBErr("Failed to list team members", err)
+	}
+
+	for i := range members {
+		if member, err := model.GetUserByID(members[i].UserID); err == nil {
+			members[i].User = &member
+		}
+	}
+
+	return serializer.BuildTeamMemberList(members)
+}
+
+// Set 将给定邮箱的用户添加为团队成员，已是成员时更新角色
+func (service *MemberService) Set(c *gin.Context, user *model.User) serializer.Response {
+	team, err := getManagedTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	target, err := model.GetActiveUserByEmail(service.User)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if target.ID == team.OwnerID {
+		return serializer.ParamErr("Cannot change the role of the team owner", nil)
+	}
+
+	if err := team.SetMember(target.ID, service.Role); err != nil {
+		return serializer.DBErr("Failed to update team member", err)
+	}
+
+	return serializer.Response{}
+}
+
+// RemoveMember 移除团队成员，所有者可移除其他成员，成员可移除自己以退出团队
+func RemoveMember(c *gin.Context, user *model.User) serializer.Response {
+	team, err := getTeam(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	uid, err := hashid.DecodeHashID(c.Param("uid"), hashid.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if uid != user.ID && !team.CanManage() {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Only the team owner can remove other members", nil)
+	}
+
+	affected, err := team.RemoveMember(uid)
+	if err != nil {
+		return serializer.DBErr("Failed to remove team member", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Team member not found", nil)
+	}
+
+	return serializer.Response{}
+}
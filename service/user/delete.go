@@ -0,0 +1,49 @@
+package user
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// AccountDeleteService 注销账户服务
+type AccountDeleteService struct {
+	Password string `json:"password" binding:"required,min=4,max=64"`
+	Code     string `json:"code"`
+}
+
+// Delete 申请注销账户，账户立即停用，冷静期结束后由后台任务清理所有数据
+func (service *AccountDeleteService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	// 不能注销初始用户
+	if user.ID == 1 {
+		return serializer.Err(serializer.CodeInvalidActionOnDefaultUser, "", nil)
+	}
+
+	if ok, _ := user.CheckPassword(service.Password); !ok {
+		return serializer.Err(serializer.CodeIncorrectPassword, "", nil)
+	}
+
+	if user.TwoFactor != "" && !totp.Validate(service.Code, user.TwoFactor) {
+		return serializer.Err(serializer.Code2FACodeErr, "", nil)
+	}
+
+	grace := time.Duration(model.GetIntSetting("account_deletion_grace", 604800)) * time.Second
+	notBefore := time.Now().Add(grace)
+	job, err := task.NewAccountPurgeTask(user, notBefore)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+
+	user.SetStatus(model.PendingDeletion)
+	model.RecordAudit(user.ID, "account.delete.request", model.AuditTargetUser, user.ID,
+		map[string]interface{}{"not_before": notBefore, "ip": c.ClientIP()})
+	task.SubmitDeferred(task.TaskPoll, job)
+
+	util.DeleteSession(c, "user_id")
+	return serializer.Response{Data: notBefore}
+}
@@ -0,0 +1,77 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportService 用户数据导出服务
+type ExportService struct {
+	IncludeFiles bool `json:"include_files"`
+}
+
+// ExportDownloadService 下载导出分卷服务
+type ExportDownloadService struct {
+	ID   uint `uri:"id" binding:"required"`
+	Part int  `uri:"part" binding:"required,min=1"`
+}
+
+// Create 创建用户数据导出任务，站点要求审核时需等待管理员通过后才会执行
+func (service *ExportService) Create(c *gin.Context, user *model.User) serializer.Response {
+	approved := !model.IsTrueVal(model.GetSettingByName("export_require_approval"))
+	job, err := task.NewExportTask(user, service.IncludeFiles, approved)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+
+	if approved {
+		task.TaskPoll.Submit(job)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"id":       job.Model().ID,
+		"approved": approved,
+	}}
+}
+
+// Download 下载已生成的导出分卷，支持断点续传
+func (service *ExportDownloadService) Download(c *gin.Context, user *model.User) serializer.Response {
+	record, err := model.GetTasksByID(service.ID)
+	if err != nil || record.UserID != user.ID || record.Type != task.ExportTaskType {
+		return serializer.Err(serializer.CodeNotFound, "Export not found", err)
+	}
+
+	if record.Status != task.Complete {
+		return serializer.Err(serializer.CodeNotFound, "Export is not ready", nil)
+	}
+
+	var props task.ExportProps
+	if err := json.Unmarshal([]byte(record.Props), &props); err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Export not found", err)
+	}
+
+	if props.Expires == nil || time.Now().After(*props.Expires) {
+		return serializer.Err(serializer.CodeNotFound, "Export expired", nil)
+	}
+
+	if service.Part > len(props.Parts) {
+		return serializer.Err(serializer.CodeNotFound, "Part not found", nil)
+	}
+
+	part := props.Parts[service.Part-1]
+	partPath := filepath.Join(task.ExportPath(record.ID), part.Name)
+	if !util.Exists(partPath) {
+		return serializer.Err(serializer.CodeNotFound, "Export expired", nil)
+	}
+
+	c.FileAttachment(partPath, fmt.Sprintf("export_%d_%s", record.ID, part.Name))
+	return serializer.Response{}
+}
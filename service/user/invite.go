@@ -0,0 +1,70 @@
+package user
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// InviteService 用户邀请码服务
+type InviteService struct {
+	Code string `uri:"code"`
+}
+
+// List 列出用户创建的邀请码
+func (service *InviteService) List(c *gin.Context, user *model.User) serializer.Response {
+	invites, err := model.ListInvitesByCreator(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list invitation codes", err)
+	}
+
+	return serializer.BuildInviteList(invites)
+}
+
+// Create 创建一次性邀请码，受用户可持有的有效邀请码数量限制
+func (service *InviteService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !model.IsTrueVal(model.GetSettingByName("invite_user_enabled")) {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Users are not allowed to create invitation codes", nil)
+	}
+
+	now := time.Now()
+	count, err := model.CountActiveInvitesByCreator(user.ID, now)
+	if err != nil {
+		return serializer.DBErr("Failed to count invitation codes", err)
+	}
+
+	if count >= model.GetIntSetting("invite_user_max", 5) {
+		return serializer.Err(serializer.CodeInviteLimitExceeded, "", nil)
+	}
+
+	expires := now.Add(time.Duration(model.GetIntSetting("invite_user_expires", 604800)) * time.Second)
+	invite := &model.Invite{
+		Code:      util.RandStringRunes(16),
+		CreatorID: user.ID,
+		MaxUses:   1,
+		ExpiresAt: &expires,
+	}
+
+	if err := invite.Create(); err != nil {
+		return serializer.DBErr("Failed to create invitation code", err)
+	}
+
+	return serializer.BuildInviteList([]model.Invite{*invite})
+}
+
+// Delete 删除用户创建的邀请码
+func (service *InviteService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	invite, err := model.GetInviteByCode(service.Code)
+	if err != nil || invite.CreatorID != user.ID {
+		return serializer.Err(serializer.CodeNotFound, "Invitation code not found", err)
+	}
+
+	if _, err := model.DeleteInvite(invite.ID, user.ID); err != nil {
+		return serializer.DBErr("Failed to delete invitation code", err)
+	}
+
+	return serializer.Response{}
+}
@@ -1,14 +1,18 @@
 package user
 
 import (
+	"encoding/json"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/authn"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/directory"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
 	"github.com/pquerna/otp/totp"
@@ -71,6 +75,9 @@ func (service *UserResetEmailService) Reset(c *gin.Context) serializer.Response
 		if user.Status == model.Baned || user.Status == model.OveruseBaned {
 			return serializer.Err(serializer.CodeUserBaned, "This user is banned", nil)
 		}
+		if user.Status == model.PendingDeletion {
+			return serializer.Err(serializer.CodeUserBaned, "This account is scheduled for deletion", nil)
+		}
 		if user.Status == model.NotActivicated {
 			return serializer.Err(serializer.CodeUserNotActivated, "This user is not activated", nil)
 		}
@@ -106,16 +113,14 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 			return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
 		}
 
-		// 验证二步验证代码
-		if !totp.Validate(service.Code, expectedUser.TwoFactor) {
+		// 验证二步验证代码，仅使用 WebAuthn 验证器的用户不可通过此方式验证
+		if expectedUser.TwoFactor == "" || !totp.Validate(service.Code, expectedUser.TwoFactor) {
 			return serializer.Err(serializer.Code2FACodeErr, "2FA code not correct", nil)
 		}
 
 		//登陆成功，清空并设置session
 		util.DeleteSession(c, "2fa_user_id")
-		util.SetSession(c, map[string]interface{}{
-			"user_id": expectedUser.ID,
-		})
+		SetLoginSession(c, expectedUser.ID)
 
 		return serializer.BuildUserResponse(expectedUser)
 	}
@@ -123,6 +128,89 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 	return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
 }
 
+// Authn2FAService 使用 WebAuthn 验证器完成二步验证的服务
+type Authn2FAService struct {
+}
+
+// Start 开始使用 WebAuthn 验证器进行二步验证
+func (service *Authn2FAService) Start(c *gin.Context) serializer.Response {
+	uid, ok := util.GetSession(c, "2fa_user_id").(uint)
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	expectedUser, err := model.GetActiveUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
+	}
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	options, sessionData, err := instance.BeginLogin(expectedUser)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "No available authenticator", err)
+	}
+
+	val, err := json.Marshal(sessionData)
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to encode authn session", err)
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		"2fa_authn_session": val,
+	})
+	return serializer.Response{Data: options}
+}
+
+// Finish 校验 WebAuthn 验证器的签名并完成登录
+func (service *Authn2FAService) Finish(c *gin.Context) serializer.Response {
+	uid, ok := util.GetSession(c, "2fa_user_id").(uint)
+	sessionDataJSON, sessionOk := util.GetSession(c, "2fa_authn_session").([]byte)
+	if !ok || !sessionOk {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	expectedUser, err := model.GetActiveUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
+	}
+
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal(sessionDataJSON, &sessionData); err != nil {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", err)
+	}
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	// 每次验证须重新获取挑战
+	util.DeleteSession(c, "2fa_authn_session")
+	credential, err := instance.FinishLogin(expectedUser, sessionData, c.Request)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+
+	// 签名计数未递增，验证器可能已被复制
+	if credential.Authenticator.CloneWarning {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Authenticator may be cloned", nil)
+	}
+
+	if err := expectedUser.UpdateAuthn(credential); err != nil {
+		util.Log().Warning("Failed to update authenticator of user %d: %s", expectedUser.ID, err)
+	}
+
+	//登陆成功，清空并设置session
+	util.DeleteSession(c, "2fa_user_id")
+	SetLoginSession(c, expectedUser.ID)
+
+	return serializer.BuildUserResponse(expectedUser)
+}
+
 // Login 用户登录函数
 func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
 	expectedUser, err := model.GetUserByEmail(service.UserName)
@@ -136,22 +224,30 @@ func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
 	if expectedUser.Status == model.Baned || expectedUser.Status == model.OveruseBaned {
 		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
 	}
+	if expectedUser.Status == model.PendingDeletion {
+		return serializer.Err(serializer.CodeUserBaned, "This account is scheduled for deletion", nil)
+	}
 	if expectedUser.Status == model.NotActivicated {
 		return serializer.Err(serializer.CodeUserNotActivated, "This account is not activated", nil)
 	}
 
-	if expectedUser.TwoFactor != "" {
-		// 需要二步验证
+	// 按最新规则重新分配外部目录用户的用户组
+	if changed, err := directory.Reevaluate(&expectedUser); err != nil {
+		util.Log().Warning("Failed to re-evaluate group of user %d: %s", expectedUser.ID, err)
+	} else if changed {
+		expectedUser.Group, _ = model.GetGroupByID(expectedUser.GroupID)
+	}
+
+	if methods := expectedUser.TwoFactorMethods(); len(methods) > 0 {
+		// 需要二步验证，返回可用的验证方式
 		util.SetSession(c, map[string]interface{}{
 			"2fa_user_id": expectedUser.ID,
 		})
-		return serializer.Response{Code: 203}
+		return serializer.Response{Code: 203, Data: methods}
 	}
 
 	//登陆成功，清空并设置session
-	util.SetSession(c, map[string]interface{}{
-		"user_id": expectedUser.ID,
-	})
+	SetLoginSession(c, expectedUser.ID)
 
 	return serializer.BuildUserResponse(expectedUser)
 
@@ -195,9 +291,7 @@ func (s *CopySessionService) Copy(c *gin.Context) serializer.Response {
 	}
 
 	cache.Deletes([]string{cacheKey}, "")
-	util.SetSession(c, map[string]interface{}{
-		"user_id": uid.(uint),
-	})
+	SetLoginSession(c, uid.(uint))
 
 	return serializer.Response{}
 }
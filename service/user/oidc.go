@@ -0,0 +1,181 @@
+package user
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/directory"
+	"github.com/cloudreve/Cloudreve/v3/pkg/oidc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLoginService 发起单点登录的服务
+type OIDCLoginService struct {
+}
+
+// OIDCCallbackService 单点登录回调服务
+type OIDCCallbackService struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+}
+
+// Start 生成身份提供方的登录地址
+func (service *OIDCLoginService) Start(c *gin.Context) serializer.Response {
+	client, err := oidc.NewClientFromSettings()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Single sign-on is not configured", err)
+	}
+
+	state := util.RandStringRunes(32)
+	nonce := util.RandStringRunes(32)
+	authURL, err := client.AuthURL(state, nonce)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Failed to connect to identity provider", err)
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		"oidc_state": state,
+		"oidc_nonce": nonce,
+	})
+	return serializer.Response{Data: authURL}
+}
+
+// Callback 校验身份提供方的回调并完成登录，首次登录的用户会被关联或自动创建
+func (service *OIDCCallbackService) Callback(c *gin.Context) serializer.Response {
+	state, ok := util.GetSession(c, "oidc_state").(string)
+	nonce, _ := util.GetSession(c, "oidc_nonce").(string)
+	util.DeleteSession(c, "oidc_state")
+	util.DeleteSession(c, "oidc_nonce")
+	if !ok || state == "" || state != service.State {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	if service.Error != "" {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, service.ErrorDescription, oidc.Error{
+			ErrorType:        service.Error,
+			ErrorDescription: service.ErrorDescription,
+		})
+	}
+
+	client, err := oidc.NewClientFromSettings()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Single sign-on is not configured", err)
+	}
+
+	token, err := client.Exchange(service.Code)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Failed to exchange authorization code", err)
+	}
+
+	claims, err := client.Claims(token, nonce)
+	if err != nil {
+		return serializer.Err(serializer.CodeOIDCLoginFailed, "Invalid ID token", err)
+	}
+
+	user, res := service.resolveUser(claims)
+	if res != nil {
+		return *res
+	}
+
+	if res := checkOIDCUserStatus(user); res != nil {
+		return *res
+	}
+
+	// 保存身份提供方返回的属性，并按外部目录规则分配用户组
+	if err := directory.Login(user, &model.DirectoryClaims{Source: "oidc", Values: claims.Values()}); err != nil {
+		util.Log().Warning("Failed to apply directory claims of user %d: %s", user.ID, err)
+	}
+
+	SetLoginSession(c, user.ID)
+	return serializer.Response{}
+}
+
+// resolveUser 查找声明对应的用户，依次按外部标识、邮箱匹配，均未找到时按设置自动注册
+func (service *OIDCCallbackService) resolveUser(claims oidc.Claims) (*model.User, *serializer.Response) {
+	options := model.GetSettingByNames("oidc_email_claim", "oidc_nick_claim", "oidc_auto_register", "oidc_default_group")
+	subject := claims.String("sub")
+
+	if user, err := model.GetUserByExternalID(subject); err == nil {
+		return &user, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.String(options["oidc_email_claim"])))
+	if email == "" {
+		res := serializer.Err(serializer.CodeOIDCLoginFailed, "Email address is not provided by identity provider", nil)
+		return nil, &res
+	}
+
+	// 只有身份提供方明确声明已验证的邮箱才可用于关联或创建账号
+	if !claims.EmailVerified() {
+		res := serializer.Err(serializer.CodeOIDCLoginFailed, "Email address is not verified", nil)
+		return nil, &res
+	}
+
+	if user, err := model.GetUserByEmail(email); err == nil {
+		if user.ExternalID != "" {
+			res := serializer.Err(serializer.CodeOIDCLoginFailed, "This account is linked to another identity", nil)
+			return nil, &res
+		}
+
+		// 不可用的账号不进行关联
+		if res := checkOIDCUserStatus(&user); res != nil {
+			return nil, res
+		}
+
+		if err := user.Update(map[string]interface{}{"external_id": subject}); err != nil {
+			res := serializer.DBErr("Failed to link account", err)
+			return nil, &res
+		}
+
+		user.ExternalID = subject
+		return &user, nil
+	}
+
+	if !model.IsTrueVal(options["oidc_auto_register"]) {
+		res := serializer.Err(serializer.CodeUserNotFound, "No account is linked to this identity", nil)
+		return nil, &res
+	}
+
+	group := model.GetIntSetting("oidc_default_group", 0)
+	if group == 0 {
+		group = model.GetIntSetting("default_group", 2)
+	}
+
+	user := model.NewUser()
+	user.Email = email
+	user.Nick = claims.String(options["oidc_nick_claim"])
+	if user.Nick == "" {
+		user.Nick = strings.Split(email, "@")[0]
+	}
+	user.SetPassword(util.RandStringRunes(32))
+	user.Status = model.Active
+	user.GroupID = uint(group)
+	user.ExternalID = subject
+	if err := model.DB.Create(&user).Error; err != nil {
+		res := serializer.DBErr("Failed to create user", err)
+		return nil, &res
+	}
+
+	user.Group, _ = model.GetGroupByID(user.GroupID)
+	return &user, nil
+}
+
+// checkOIDCUserStatus 检查用户状态是否允许单点登录
+func checkOIDCUserStatus(user *model.User) *serializer.Response {
+	var res serializer.Response
+	switch user.Status {
+	case model.Baned, model.OveruseBaned:
+		res = serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
+	case model.PendingDeletion:
+		res = serializer.Err(serializer.CodeUserBaned, "This account is scheduled for deletion", nil)
+	case model.NotActivicated:
+		res = serializer.Err(serializer.CodeUserNotActivated, "This user is not activated", nil)
+	default:
+		return nil
+	}
+	return &res
+}
@@ -3,12 +3,14 @@ package user
 import (
 	"net/url"
 	"strings"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,17 +19,36 @@ type UserRegisterService struct {
 	//TODO 细致调整验证规则
 	UserName string `form:"userName" json:"userName" binding:"required,email"`
 	Password string `form:"Password" json:"Password" binding:"required,min=4,max=64"`
+	// InviteCode 邀请码，仅限邀请注册时必填
+	InviteCode string `form:"inviteCode" json:"inviteCode"`
 }
 
 // Register 新用户注册
 func (service *UserRegisterService) Register(c *gin.Context) serializer.Response {
 	// 相关设定
-	options := model.GetSettingByNames("email_active")
+	options := model.GetSettingByNames("email_active", "register_invite_only",
+		"register_email_domain_allow", "register_email_domain_deny")
 
 	// 相关设定
 	isEmailRequired := model.IsTrueVal(options["email_active"])
 	defaultGroup := model.GetIntSetting("default_group", 2)
 
+	// 校验邀请码，使用邀请码注册时不受邮箱域名规则限制
+	var invite *model.Invite
+	if service.InviteCode != "" || model.IsTrueVal(options["register_invite_only"]) {
+		expectedInvite, err := model.GetInviteByCode(service.InviteCode)
+		if err != nil || !expectedInvite.Usable(time.Now()) {
+			return serializer.Err(serializer.CodeInvalidInviteCode, "Invalid invitation code", err)
+		}
+		invite = &expectedInvite
+	} else {
+		allow := options["register_email_domain_allow"]
+		if util.MatchEmailDomain(service.UserName, strings.Split(options["register_email_domain_deny"], ",")) ||
+			(strings.TrimSpace(allow) != "" && !util.MatchEmailDomain(service.UserName, strings.Split(allow, ","))) {
+			return serializer.Err(serializer.CodeEmailDomainNotAllowed, "Registration with this email domain is not allowed", nil)
+		}
+	}
+
 	// 创建新的用户对象
 	user := model.NewUser()
 	user.Email = service.UserName
@@ -38,9 +59,26 @@ func (service *UserRegisterService) Register(c *gin.Context) serializer.Response
 		user.Status = model.NotActivicated
 	}
 	user.GroupID = uint(defaultGroup)
+
+	// 占用邀请码，并应用其用户组与附加容量
+	if invite != nil {
+		if ok, err := invite.Use(); !ok {
+			return serializer.Err(serializer.CodeInvalidInviteCode, "Invalid invitation code", err)
+		}
+
+		if invite.GroupID > 0 {
+			user.GroupID = invite.GroupID
+		}
+		user.OptionsSerialized.ExtraStorage = invite.ExtraStorage
+	}
+
 	userNotActivated := false
 	// 创建用户
 	if err := model.DB.Create(&user).Error; err != nil {
+		if invite != nil {
+			invite.Release()
+		}
+
 		//检查已存在使用者是否尚未激活
 		expectedUser, err := model.GetUserByEmail(service.UserName)
 		if expectedUser.Status == model.NotActivicated {
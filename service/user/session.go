@@ -0,0 +1,83 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// LoginSessionService 登录会话服务
+type LoginSessionService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// ActivityListService 用户动态列表服务
+type ActivityListService struct {
+	Page     int    `form:"page" binding:"required,min=1"`
+	PageSize int    `form:"page_size" binding:"required,min=1,max=100"`
+	Type     string `form:"type" binding:"omitempty,eq=upload|eq=delete|eq=share"`
+}
+
+// SetLoginSession 登记新的登录会话，并将用户写入会话
+func SetLoginSession(c *gin.Context, uid uint) {
+	values := map[string]interface{}{"user_id": uid}
+	ua := c.Request.UserAgent()
+	if record, err := model.NewLoginSession(uid, c.ClientIP(), ua, util.DescribeUserAgent(ua)); err == nil {
+		values[model.LoginSessionKey] = record.Token
+	} else {
+		util.Log().Warning("Failed to create login session: %s", err)
+	}
+
+	util.SetSession(c, values)
+}
+
+// ListLoginSessions 列出用户的登录会话
+func ListLoginSessions(c *gin.Context, user *model.User) serializer.Response {
+	sessions, err := model.ListLoginSessionsByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list login sessions", err)
+	}
+
+	current, _ := util.GetSession(c, model.LoginSessionKey).(string)
+	return serializer.BuildLoginSessionList(sessions, current)
+}
+
+// Revoke 吊销用户的登录会话，对应设备在下次请求时退出登录
+func (service *LoginSessionService) Revoke(c *gin.Context, user *model.User) serializer.Response {
+	session, err := model.GetLoginSessionByID(service.ID, user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Login session not found", err)
+	}
+
+	if err := session.Revoke(); err != nil {
+		return serializer.DBErr("Failed to revoke login session", err)
+	}
+
+	return serializer.Response{}
+}
+
+// SignOut 退出登录，并吊销当前的登录会话
+func SignOut(c *gin.Context) serializer.Response {
+	if token, ok := util.GetSession(c, model.LoginSessionKey).(string); ok && token != "" {
+		if session, err := model.GetLoginSession(token); err == nil && !session.Revoked() {
+			if err := session.Revoke(); err != nil {
+				util.Log().Warning("Failed to revoke login session: %s", err)
+			}
+		}
+		util.DeleteSession(c, model.LoginSessionKey)
+	}
+
+	util.DeleteSession(c, "user_id")
+	return serializer.Response{}
+}
+
+// List 列出用户动态
+func (service *ActivityListService) List(c *gin.Context, user *model.User) serializer.Response {
+	activities, total, err := model.ListActivities(user.ID, service.Type, service.Page, service.PageSize)
+	if err != nil {
+		return serializer.DBErr("Failed to list activities", err)
+	}
+
+	return serializer.BuildActivityList(activities, total)
+}
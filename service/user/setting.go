@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -32,7 +33,7 @@ type AvatarService struct {
 
 // SettingUpdateService 设定更改服务
 type SettingUpdateService struct {
-	Option string `uri:"option" binding:"required,eq=nick|eq=theme|eq=homepage|eq=vip|eq=qq|eq=policy|eq=password|eq=2fa|eq=authn"`
+	Option string `uri:"option" binding:"required,eq=nick|eq=theme|eq=homepage|eq=vip|eq=qq|eq=policy|eq=password|eq=2fa|eq=authn|eq=notification"`
 }
 
 // OptionsChangeHandler 属性更改接口
@@ -71,6 +72,12 @@ type DeleteWebAuthn struct {
 	ID string `json:"id" binding:"required"`
 }
 
+// NotificationChange 更改邮件通知订阅
+type NotificationChange struct {
+	Type    string `json:"type" binding:"required"`
+	Enabled bool   `json:"enabled"`
+}
+
 // ThemeChose 主题选择
 type ThemeChose struct {
 	Theme string `json:"theme" binding:"required,hexcolor|rgb|rgba|hsl"`
@@ -86,6 +93,23 @@ func (service *ThemeChose) Update(c *gin.Context, user *model.User) serializer.R
 	return serializer.Response{}
 }
 
+// Update 更新邮件通知订阅
+func (service *NotificationChange) Update(c *gin.Context, user *model.User) serializer.Response {
+	if _, ok := email.GetNotification(service.Type); !ok {
+		return serializer.ParamErr("Unknown notification type", email.ErrUnknownNotification)
+	}
+
+	if user.OptionsSerialized.Notifications == nil {
+		user.OptionsSerialized.Notifications = make(map[string]bool)
+	}
+	user.OptionsSerialized.Notifications[service.Type] = service.Enabled
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update user preferences", err)
+	}
+
+	return serializer.Response{}
+}
+
 // Update 删除凭证
 func (service *DeleteWebAuthn) Update(c *gin.Context, user *model.User) serializer.Response {
 	user.RemoveAuthn(service.ID)
@@ -251,6 +275,11 @@ func (service *SettingService) Settings(c *gin.Context, user *model.User) serial
 			"prefer_theme": user.OptionsSerialized.PreferredTheme,
 			"themes":       model.GetSettingByName("themes"),
 			"authn":        serializer.BuildWebAuthnList(user.WebAuthnCredentials()),
+			"require_2fa":  user.Group.OptionsSerialized.Require2FA,
+			"notifications": map[string]interface{}{
+				"types":   email.Notifications,
+				"enabled": email.NotificationPreferences(user),
+			},
 		},
 	}
 }
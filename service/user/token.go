@@ -0,0 +1,95 @@
+package user
+
+import (
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenService 访问令牌服务
+type APITokenService struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// CreateAPITokenService 创建访问令牌服务
+type CreateAPITokenService struct {
+	Name   string   `json:"name" binding:"required,max=255"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write share admin"`
+	// Expires 有效期，单位为秒，为 0 时永不过期
+	Expires int `json:"expires" binding:"min=0"`
+}
+
+// ListAPITokens 列出用户创建的访问令牌
+func ListAPITokens(c *gin.Context, user *model.User) serializer.Response {
+	tokens, err := model.ListAPITokensByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to list API tokens", err)
+	}
+
+	return serializer.BuildAPITokenList(tokens)
+}
+
+// Create 创建访问令牌，令牌明文仅在此时返回
+func (service *CreateAPITokenService) Create(c *gin.Context, user *model.User) serializer.Response {
+	// 仅管理员可创建具有 admin 权限范围的令牌
+	scopes := make([]string, 0, len(service.Scopes))
+	for _, scope := range service.Scopes {
+		if scope == model.APITokenScopeAdmin && user.Group.ID != 1 && user.ID != 1 {
+			return serializer.Err(serializer.CodeNoPermissionErr, "Only administrators can create tokens with admin scope", nil)
+		}
+
+		if !util.ContainsString(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	count, err := model.CountAPITokensByUser(user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to count API tokens", err)
+	}
+
+	if count >= model.GetIntSetting("api_token_max", 10) {
+		return serializer.Err(serializer.CodeAPITokenLimitExceeded, "", nil)
+	}
+
+	plain, err := model.GenerateAPIToken()
+	if err != nil {
+		return serializer.Err(serializer.CodeEncryptError, "Failed to generate API token", err)
+	}
+
+	token := &model.APIToken{
+		UserID: user.ID,
+		Name:   service.Name,
+		Digest: model.HashAPIToken(plain),
+		Scopes: strings.Join(scopes, ","),
+	}
+
+	if service.Expires > 0 {
+		expires := time.Now().Add(time.Duration(service.Expires) * time.Second)
+		token.ExpiresAt = &expires
+	}
+
+	if err := token.Create(); err != nil {
+		return serializer.DBErr("Failed to create API token", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildAPIToken(token, plain)}
+}
+
+// Delete 吊销用户的访问令牌
+func (service *APITokenService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	affected, err := model.DeleteAPIToken(service.ID, user.ID)
+	if err != nil {
+		return serializer.DBErr("Failed to delete API token", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "API token not found", nil)
+	}
+
+	return serializer.Response{}
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// deliveryListSize 返回的投递记录条数
+const deliveryListSize = 50
+
+// Service Webhook 服务，uid 为 0 时操作管理员创建的 Webhook
+type Service struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// SaveService 创建、更新 Webhook 服务
+type SaveService struct {
+	Name    string   `json:"name" binding:"required,max=255"`
+	URL     string   `json:"url" binding:"required,url,startswith=http"`
+	Secret  string   `json:"secret" binding:"max=255"`
+	Enabled bool     `json:"enabled"`
+	Events  []string `json:"events" binding:"required,min=1,dive,oneof=file.uploaded file.deleted share.created share.downloaded"`
+}
+
+// List 列出 Webhook
+func List(c *gin.Context, uid uint) serializer.Response {
+	webhooks, err := model.ListWebhooksByUser(uid)
+	if err != nil {
+		return serializer.DBErr("Failed to list webhooks", err)
+	}
+
+	return serializer.BuildWebhookList(webhooks)
+}
+
+// events 返回去重后的订阅事件
+func (service *SaveService) events() string {
+	events := make([]string, 0, len(service.Events))
+	for _, event := range model.WebhookEvents {
+		for _, e := range service.Events {
+			if e == event {
+				events = append(events, event)
+				break
+			}
+		}
+	}
+	return strings.Join(events, ",")
+}
+
+// Create 创建 Webhook，max 为用户可创建的最大数量，为 0 时不限制
+func (service *SaveService) Create(c *gin.Context, uid uint, max int) serializer.Response {
+	if max > 0 {
+		count, err := model.CountWebhooksByUser(uid)
+		if err != nil {
+			return serializer.DBErr("Failed to count webhooks", err)
+		}
+
+		if count >= max {
+			return serializer.Err(serializer.CodeWebhookLimitExceeded, "", nil)
+		}
+	}
+
+	webhook := &model.Webhook{
+		UserID:  uid,
+		Name:    service.Name,
+		URL:     service.URL,
+		Secret:  service.Secret,
+		Enabled: service.Enabled,
+		Events:  service.events(),
+	}
+	if err := webhook.Create(); err != nil {
+		return serializer.DBErr("Failed to create webhook", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildWebhook(webhook)}
+}
+
+// Update 更新 Webhook，签名密钥为空时保持不变
+func (service *SaveService) Update(c *gin.Context, id, uid uint) serializer.Response {
+	webhook, err := model.GetUserWebhook(id, uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not found", err)
+	}
+
+	props := map[string]interface{}{
+		"name":    service.Name,
+		"url":     service.URL,
+		"enabled": service.Enabled,
+		"events":  service.events(),
+	}
+	if service.Secret != "" {
+		props["secret"] = service.Secret
+	}
+
+	if err := model.DB.Model(&webhook).Updates(props).Error; err != nil {
+		return serializer.DBErr("Failed to update webhook", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildWebhook(&webhook)}
+}
+
+// Delete 删除 Webhook
+func (service *Service) Delete(c *gin.Context, uid uint) serializer.Response {
+	affected, err := model.DeleteWebhook(service.ID, uid)
+	if err != nil {
+		return serializer.DBErr("Failed to delete webhook", err)
+	}
+
+	if affected == 0 {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not found", nil)
+	}
+
+	return serializer.Response{}
+}
+
+// Deliveries 列出 Webhook 最近的投递记录
+func (service *Service) Deliveries(c *gin.Context, uid uint) serializer.Response {
+	if _, err := model.GetUserWebhook(service.ID, uid); err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Webhook not found", err)
+	}
+
+	deliveries, err := model.ListWebhookDeliveries(service.ID, deliveryListSize)
+	if err != nil {
+		return serializer.DBErr("Failed to list webhook deliveries", err)
+	}
+
+	return serializer.BuildWebhookDeliveryList(deliveries)
+}